@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Azure/aks-mcp/internal/loadtest"
+	flag "github.com/spf13/pflag"
+)
+
+// runLoadTest runs the loadtest developer subcommand and returns its exit code. The server logs are discarded
+// unless --verbose is set, so they do not interleave with the report.
+func runLoadTest(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts := loadtest.Options{}
+	flags.StringVar(&opts.Transport, "transport", loadtest.TransportStdio, "Transport to drive (stdio, sse or streamable-http)")
+	flags.StringVar(&opts.Tool, "tool", loadtest.DefaultTool, "Tool to call")
+	arguments := flags.String("args", "", "Tool arguments as a JSON object (default: an az aks show for the default tool)")
+	flags.IntVar(&opts.Concurrency, "concurrency", loadtest.DefaultConcurrency, "Number of concurrent callers; each has its own session over sse and streamable-http")
+	flags.IntVar(&opts.Calls, "calls", loadtest.DefaultCalls, "Number of measured calls")
+	flags.DurationVar(&opts.Latency, "latency", 0, "Latency of each az CLI command answered by the mock executor")
+	flags.StringVar(&opts.Output, "output", loadtest.DefaultOutput, "Output of each az CLI command answered by the mock executor")
+	flags.StringVar(&opts.AccessLevel, "access-level", "readonly", "Access level of the server (readonly, readwrite, admin)")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	verbose := flags.Bool("verbose", false, "Keep the server logs")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: %s loadtest [flags]\n\nDrives concurrent synthetic tool calls through the server with a mock az CLI and reports latencies, allocations and goroutines.\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	var err error
+	if opts.Arguments, err = loadtest.ParseArguments(*arguments); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+	report, err := loadtest.Run(context.Background(), opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Load test error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.Write(stdout)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Failed to write report: %v\n", err)
+		return 1
+	}
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
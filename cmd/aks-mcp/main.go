@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -10,10 +11,16 @@ import (
 
 	"github.com/Azure/aks-mcp/internal/config"
 	"github.com/Azure/aks-mcp/internal/server"
+	"github.com/Azure/aks-mcp/internal/store"
 	"github.com/Azure/aks-mcp/internal/version"
 )
 
 func main() {
+	// The loadtest developer subcommand drives a server of its own and has its own flags
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(runLoadTest(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Create configuration instance and parse command line arguments
 	cfg := config.NewConfig()
 	cfg.ParseFlags()
@@ -34,6 +41,23 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// The self-test registers the tools with mock executors, reports on them and exits. The server logs are
+	// discarded unless --verbose is set, so they do not interleave with the report.
+	if cfg.SelfTest {
+		if !cfg.Verbose {
+			log.SetOutput(io.Discard)
+		}
+		summary, err := server.SelfTest(ctx, cfg, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Self-test error: %v\n", err)
+			os.Exit(1)
+		}
+		if summary.Failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize telemetry service in config
 	cfg.InitializeTelemetry(ctx, "aks-mcp", version.GetVersion())
 
@@ -46,6 +70,11 @@ func main() {
 		}
 	}()
 
+	// A replay keeps its state in memory so it never changes the state of a real server
+	if cfg.Replay != "" {
+		cfg.StateStore = store.KindMemory
+	}
+
 	// Create and initialize the service
 	service := server.NewService(cfg)
 	if err := service.Initialize(); err != nil {
@@ -53,6 +82,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Replay a recorded session instead of serving
+	if cfg.Replay != "" {
+		summary, err := service.Replay(ctx, os.Stdout)
+		service.Shutdown()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Replay error: %v\n", err)
+			os.Exit(1)
+		}
+		if summary.Changed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Start service in a goroutine
 	errChan := make(chan error, 1)
 	go func() {
@@ -62,8 +105,10 @@ func main() {
 	// Wait for shutdown signal or service error
 	select {
 	case <-sigChan:
+		service.Shutdown()
 		cancel()
 	case err := <-errChan:
+		service.Shutdown()
 		if err != nil {
 			log.Fatalf("Service error: %v\n", err)
 		}
@@ -0,0 +1,176 @@
+// Package apikey authenticates HTTP clients with API keys. Each key has its own access level and
+// component allowlist, so one hosted server can give clients different permissions.
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// HeaderAPIKey carries the API key of a request. Without session credentials the key may also be sent
+// in the Authorization header as "Bearer <key>".
+const HeaderAPIKey = "X-API-Key"
+
+// AccessLevels lists the access levels from least to most privileged
+var AccessLevels = []string{"readonly", "readwrite", "admin"}
+
+type contextKey struct{}
+
+// Key is an API key entry of the keys file. Only the SHA-256 of the key is kept, so the file holds no secrets.
+type Key struct {
+	// Name identifies the client in audit records and logs
+	Name string `json:"name"`
+	// SHA256 is the hex-encoded SHA-256 of the key
+	SHA256 string `json:"sha256"`
+	// AccessLevel is the most privileged access level calls made with the key get
+	AccessLevel string `json:"accessLevel"`
+	// Components are the tool components the key may use (empty means all enabled components)
+	Components []string `json:"components,omitempty"`
+
+	hash []byte
+}
+
+// Keyring holds the API keys a server accepts
+type Keyring struct {
+	keys []*Key
+}
+
+// Load reads a keys file of the form {"keys": [{"name": ..., "sha256": ..., "accessLevel": ..., "components": [...]}]}
+func Load(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	keyring, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// Parse parses and validates the contents of a keys file
+func Parse(data []byte) (*Keyring, error) {
+	var file struct {
+		Keys []*Key `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Keys) == 0 {
+		return nil, fmt.Errorf("no keys defined")
+	}
+	names := make(map[string]bool, len(file.Keys))
+	hashes := make(map[string]bool, len(file.Keys))
+	for i, key := range file.Keys {
+		key.Name = strings.TrimSpace(key.Name)
+		if key.Name == "" {
+			return nil, fmt.Errorf("key %d has no name", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("duplicate key name '%s'", key.Name)
+		}
+		names[key.Name] = true
+
+		key.SHA256 = strings.ToLower(strings.TrimSpace(key.SHA256))
+		hash, err := hex.DecodeString(key.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("key '%s': sha256 must be the 64 hex digit SHA-256 of the key", key.Name)
+		}
+		if hashes[key.SHA256] {
+			return nil, fmt.Errorf("key '%s' has the same key as another entry", key.Name)
+		}
+		hashes[key.SHA256] = true
+		key.hash = hash
+
+		if !slices.Contains(AccessLevels, key.AccessLevel) {
+			return nil, fmt.Errorf("key '%s': invalid access level '%s' (must be %s)", key.Name, key.AccessLevel, strings.Join(AccessLevels, ", "))
+		}
+		for j, component := range key.Components {
+			key.Components[j] = strings.ToLower(strings.TrimSpace(component))
+		}
+	}
+	return &Keyring{keys: file.Keys}, nil
+}
+
+// Keys returns the keys of the keyring
+func (k *Keyring) Keys() []*Key {
+	return k.keys
+}
+
+// Authenticate returns the key matching the secret, or nil when none does
+func (k *Keyring) Authenticate(secret string) *Key {
+	if secret == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(secret))
+	var match *Key
+	// Every key is compared so the time taken does not depend on which key matched
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare(sum[:], key.hash) == 1 {
+			match = key
+		}
+	}
+	return match
+}
+
+// Hash returns the hex-encoded SHA-256 of a key, as written in the keys file
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// FromRequest returns the API key sent with a request, or "" when there is none. The Authorization
+// header is only read when bearer is true, as session credential mode uses it for the Azure access token.
+func FromRequest(r *http.Request, bearer bool) string {
+	if key := strings.TrimSpace(r.Header.Get(HeaderAPIKey)); key != "" {
+		return key
+	}
+	if !bearer {
+		return ""
+	}
+	token, found := strings.CutPrefix(strings.TrimSpace(r.Header.Get("Authorization")), "Bearer ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// WithKey returns a context carrying the key a request was authenticated with
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key stored in the context, if any
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
+
+// AllowsComponent reports whether the key may use the tools of a component. Tools outside any
+// component, such as the audit log check, are allowed for every key.
+func (k *Key) AllowsComponent(component string) bool {
+	return component == "" || len(k.Components) == 0 || slices.Contains(k.Components, component)
+}
+
+// AllowsAccessLevel reports whether the key's access level includes the given one
+func (k *Key) AllowsAccessLevel(level string) bool {
+	return LevelIncludes(k.AccessLevel, level)
+}
+
+// LevelIncludes reports whether the granted access level is at least as privileged as the required one.
+// An empty required level means readonly.
+func LevelIncludes(granted, required string) bool {
+	if required == "" {
+		required = AccessLevels[0]
+	}
+	grantedRank, requiredRank := slices.Index(AccessLevels, granted), slices.Index(AccessLevels, required)
+	return grantedRank >= 0 && requiredRank >= 0 && grantedRank >= requiredRank
+}
@@ -0,0 +1,84 @@
+package apikey
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	keyring, err := Parse([]byte(`{"keys": [
+		{"name": "monitoring-bot", "sha256": "` + strings.ToUpper(Hash("bot-key")) + `", "accessLevel": "readonly", "components": [" Monitor "]},
+		{"name": "sre-assistant", "sha256": "` + Hash("sre-key") + `", "accessLevel": "readwrite"}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	bot := keyring.Authenticate("bot-key")
+	if bot == nil || bot.Name != "monitoring-bot" {
+		t.Fatalf("Expected the bot key, got %+v", bot)
+	}
+	if !bot.AllowsComponent("monitor") || bot.AllowsComponent("azaks") || !bot.AllowsComponent("") {
+		t.Errorf("Unexpected component scope %v", bot.Components)
+	}
+	sre := keyring.Authenticate("sre-key")
+	if sre == nil || !sre.AllowsComponent("azaks") || !sre.AllowsAccessLevel("readwrite") || sre.AllowsAccessLevel("admin") {
+		t.Errorf("Unexpected scope of the sre key %+v", sre)
+	}
+	if keyring.Authenticate("other") != nil || keyring.Authenticate("") != nil {
+		t.Error("Expected unknown and empty keys to be rejected")
+	}
+
+	for name, data := range map[string]string{
+		"no keys":       `{"keys": []}`,
+		"no name":       `{"keys": [{"sha256": "` + Hash("a") + `", "accessLevel": "readonly"}]}`,
+		"short hash":    `{"keys": [{"name": "a", "sha256": "abc", "accessLevel": "readonly"}]}`,
+		"access level":  `{"keys": [{"name": "a", "sha256": "` + Hash("a") + `", "accessLevel": "root"}]}`,
+		"duplicate":     `{"keys": [{"name": "a", "sha256": "` + Hash("a") + `", "accessLevel": "readonly"}, {"name": "a", "sha256": "` + Hash("b") + `", "accessLevel": "readonly"}]}`,
+		"same key":      `{"keys": [{"name": "a", "sha256": "` + Hash("a") + `", "accessLevel": "readonly"}, {"name": "b", "sha256": "` + Hash("a") + `", "accessLevel": "admin"}]}`,
+		"invalid json":  `{"keys": `,
+		"missing field": `{}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	req.Header.Set("Authorization", "Bearer bearer-key")
+	if got := FromRequest(req, true); got != "bearer-key" {
+		t.Errorf("Expected the bearer key, got %q", got)
+	}
+	if got := FromRequest(req, false); got != "" {
+		t.Errorf("Expected the Authorization header to be ignored, got %q", got)
+	}
+	req.Header.Set(HeaderAPIKey, " header-key ")
+	if got := FromRequest(req, true); got != "header-key" {
+		t.Errorf("Expected the %s header to take precedence, got %q", HeaderAPIKey, got)
+	}
+
+	key := &Key{Name: "a"}
+	if FromContext(WithKey(context.Background(), key)) != key || FromContext(context.Background()) != nil {
+		t.Error("Expected the key to round trip through the context")
+	}
+}
+
+func TestLevelIncludes(t *testing.T) {
+	for _, tc := range []struct {
+		granted, required string
+		want              bool
+	}{
+		{"admin", "readwrite", true},
+		{"readwrite", "readwrite", true},
+		{"readonly", "", true},
+		{"readonly", "readwrite", false},
+		{"readwrite", "admin", false},
+		{"", "readonly", false},
+	} {
+		if got := LevelIncludes(tc.granted, tc.required); got != tc.want {
+			t.Errorf("LevelIncludes(%q, %q) = %v, want %v", tc.granted, tc.required, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package approval keeps pending confirmations of changes a tool has previewed, such as a manifest
+// diff, so the change is only made once the caller confirms exactly what was previewed. Approvals are
+// single use, expire after a TTL and are persisted in the state store.
+package approval
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// Bucket is the state store bucket pending approvals are kept in
+const Bucket = "approvals"
+
+// DefaultTTL is how long a previewed change can be confirmed
+const DefaultTTL = 15 * time.Minute
+
+var (
+	// ErrNotFound is returned for unknown, used or foreign approvals
+	ErrNotFound = errors.New("approval not found or already used; preview the change again to get a new approval")
+	// ErrExpired is returned for approvals confirmed after their TTL
+	ErrExpired = errors.New("approval has expired; preview the change again to get a new approval")
+	// ErrMismatch is returned when the confirmed change differs from the previewed one
+	ErrMismatch = errors.New("the change does not match the previewed change; preview it again to get a new approval")
+)
+
+// Approval is a previewed change waiting for confirmation
+type Approval struct {
+	ID   string `json:"id"`
+	Tool string `json:"tool"`
+	// Digest identifies the previewed change, see Digest
+	Digest  string `json:"digest"`
+	Summary string `json:"summary"`
+	// Owner is the session that may confirm the change (empty means any caller)
+	Owner   string    `json:"owner,omitempty"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// Manager issues and confirms approvals
+type Manager struct {
+	mu   sync.Mutex
+	repo *store.Repository[Approval]
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewManager creates a manager whose approvals expire after ttl (DefaultTTL when ttl is not positive).
+// A nil store keeps approvals in memory.
+func NewManager(s store.Store, ttl time.Duration) *Manager {
+	if s == nil {
+		s = store.NewMemoryStore()
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Manager{repo: store.NewRepository[Approval](s, Bucket), ttl: ttl, now: time.Now}
+}
+
+// Digest returns the SHA-256 of the parts that define a change, such as a manifest and its target namespace
+func Digest(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Request records a previewed change of a tool and returns the approval that confirms it
+func (m *Manager) Request(tool, digest, summary, owner string) (Approval, error) {
+	id, err := newID()
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to create approval: %w", err)
+	}
+	now := m.now().UTC()
+	approval := Approval{
+		ID:      id,
+		Tool:    tool,
+		Digest:  digest,
+		Summary: summary,
+		Owner:   owner,
+		Created: now,
+		Expires: now.Add(m.ttl),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(now)
+	if err := m.repo.Save(id, approval); err != nil {
+		return Approval{}, fmt.Errorf("failed to save approval: %w", err)
+	}
+	return approval, nil
+}
+
+// Consume confirms a change with its approval. The approval must have been issued to the same tool and
+// owner for the same digest; it is removed once used or expired, so a change is confirmed at most once.
+func (m *Manager) Consume(id, tool, digest, owner string) (Approval, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	approval, err := m.repo.Load(id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && (approval.Tool != tool || approval.Owner != owner)) {
+		return Approval{}, ErrNotFound
+	}
+	if err != nil {
+		return Approval{}, err
+	}
+	if !m.now().Before(approval.Expires) {
+		_ = m.repo.Delete(id)
+		return Approval{}, ErrExpired
+	}
+	// A mismatched change keeps the approval, so the previewed change can still be confirmed
+	if approval.Digest != digest {
+		return Approval{}, ErrMismatch
+	}
+	if err := m.repo.Delete(id); err != nil {
+		return Approval{}, fmt.Errorf("failed to remove approval: %w", err)
+	}
+	return approval, nil
+}
+
+// sweepLocked removes expired approvals. Failures are ignored; the next request sweeps again.
+func (m *Manager) sweepLocked(now time.Time) {
+	approvals, err := m.repo.List()
+	if err != nil {
+		return
+	}
+	for _, approval := range approvals {
+		if !now.Before(approval.Expires) {
+			_ = m.repo.Delete(approval.ID)
+		}
+	}
+}
+
+// newID returns an unguessable approval ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,67 @@
+package approval
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+func TestConsumeOnce(t *testing.T) {
+	st := store.NewMemoryStore()
+	manager := NewManager(st, time.Minute)
+	digest := Digest("kind: ConfigMap", "shop")
+
+	approval, err := manager.Request("k8s_apply", digest, "ConfigMap shop/settings", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(approval.ID) != 32 || !approval.Expires.Equal(approval.Created.Add(time.Minute)) {
+		t.Errorf("Unexpected approval %+v", approval)
+	}
+
+	if _, err := manager.Consume(approval.ID, "k8s_apply", Digest("kind: ConfigMap", "other"), ""); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected a changed manifest to be rejected, got %v", err)
+	}
+	if _, err := manager.Consume(approval.ID, "aks_node_drain", digest, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another tool's approval to be rejected, got %v", err)
+	}
+	if _, err := manager.Consume(approval.ID, "k8s_apply", digest, "session-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another session's approval to be rejected, got %v", err)
+	}
+
+	// A restarted server confirms approvals persisted by the previous process
+	restarted := NewManager(st, time.Minute)
+	if _, err := restarted.Consume(approval.ID, "k8s_apply", digest, ""); err != nil {
+		t.Fatalf("Expected the previewed change to be confirmed, got %v", err)
+	}
+	if _, err := restarted.Consume(approval.ID, "k8s_apply", digest, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an approval to be usable once, got %v", err)
+	}
+}
+
+func TestExpiredApprovals(t *testing.T) {
+	st := store.NewMemoryStore()
+	manager := NewManager(st, 0)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	stale, err := manager.Request("k8s_apply", "d1", "", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now = now.Add(DefaultTTL)
+	if _, err := manager.Consume(stale.ID, "k8s_apply", "d1", ""); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected the approval to expire after the default TTL, got %v", err)
+	}
+
+	old, _ := manager.Request("k8s_apply", "d2", "", "")
+	now = now.Add(DefaultTTL)
+	if _, err := manager.Request("k8s_apply", "d3", "", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := st.Get(Bucket, old.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected expired approvals to be swept by new requests, got %v", err)
+	}
+}
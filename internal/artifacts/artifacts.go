@@ -0,0 +1,255 @@
+// Package artifacts keeps large tool outputs, such as full detector payloads, exported logs and
+// topology graphs, as ephemeral MCP resources, so a tool result can carry a short preview and a URI
+// instead of the whole payload. Artifacts live on the replica that created them, in memory up to the
+// spool threshold and in a temporary file beyond it, and expire after a TTL. Large artifacts are read
+// in pages.
+package artifacts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/spool"
+)
+
+// URIPrefix is the prefix of every artifact URI; the rest of the URI is the artifact ID
+const URIPrefix = "aks-mcp://artifacts/"
+
+// URITemplate is the MCP resource template artifacts are read through
+const URITemplate = URIPrefix + "{id}"
+
+// pagesSegment separates an artifact URI from a page number in a page URI
+const pagesSegment = "/pages/"
+
+// PageURITemplate is the MCP resource template pages of large artifacts are read through
+const PageURITemplate = URITemplate + pagesSegment + "{page}"
+
+// PageBytes is the size of an artifact page; artifacts larger than one page are read page by page
+const PageBytes = 1 << 20
+
+// DefaultTTL is how long an artifact can be read after it was created unless --artifact-ttl is set
+const DefaultTTL = 30 * time.Minute
+
+// maxStoreBytes bounds the memory held by artifacts; the oldest are evicted first when it is exceeded
+const maxStoreBytes = 256 << 20
+
+// Artifact is a stored tool output
+type Artifact struct {
+	URI      string
+	Name     string
+	MIMEType string
+	Size     int64
+	// Owner is the session that may read the artifact (empty means any caller)
+	Owner   string
+	Created time.Time
+	Expires time.Time
+	content *spool.Writer
+}
+
+// Pages returns the number of pages of the artifact
+func (a Artifact) Pages() int {
+	return spool.Pages(a.Size, PageBytes)
+}
+
+// PageURI returns the URI of page n (from 0) of the artifact
+func (a Artifact) PageURI(n int) string {
+	return a.URI + pagesSegment + strconv.Itoa(n)
+}
+
+// Read returns the whole content of the artifact
+func (a Artifact) Read() (string, error) {
+	if a.content == nil {
+		return "", spool.ErrClosed
+	}
+	return a.content.String()
+}
+
+// ReadPage returns page n (from 0) of the artifact. Pages end at a character boundary.
+func (a Artifact) ReadPage(n int) (string, error) {
+	if a.content == nil {
+		return "", spool.ErrClosed
+	}
+	text, _, err := a.content.Page(n, PageBytes)
+	return text, err
+}
+
+// SplitPageURI splits a page URI into the artifact URI and the page number.
+// It returns false for URIs that do not name a page.
+func SplitPageURI(uri string) (string, int, bool) {
+	base, page, ok := strings.Cut(strings.TrimPrefix(uri, URIPrefix), pagesSegment)
+	if !ok || !strings.HasPrefix(uri, URIPrefix) {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(page)
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return URIPrefix + base, n, true
+}
+
+// Store holds artifacts until they expire. A nil Store stores nothing.
+type Store struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]*Artifact
+	size  int64
+	now   func() time.Time
+}
+
+// NewStore creates a store whose artifacts expire after ttl (DefaultTTL when ttl is not positive)
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{ttl: ttl, items: make(map[string]*Artifact), now: time.Now}
+}
+
+// Put stores content under a new unguessable URI and returns the artifact. Content beyond the spool
+// threshold is kept on disk. It returns false when the store is nil or the content alone exceeds the
+// store's capacity.
+func (s *Store) Put(name, mimeType, content, owner string) (Artifact, bool) {
+	if s == nil || len(content) > maxStoreBytes {
+		return Artifact{}, false
+	}
+	w, err := spool.FromString(content, spool.DefaultThreshold)
+	if err != nil {
+		return Artifact{}, false
+	}
+	return s.PutSpool(name, mimeType, w, owner)
+}
+
+// PutSpool stores spooled content under a new unguessable URI and returns the artifact. The store takes
+// ownership of w and closes it when the artifact is removed, or right away when it is not stored.
+func (s *Store) PutSpool(name, mimeType string, w *spool.Writer, owner string) (Artifact, bool) {
+	size := w.Len()
+	if s == nil || size > maxStoreBytes {
+		_ = w.Close()
+		return Artifact{}, false
+	}
+	id, err := newID()
+	if err != nil {
+		_ = w.Close()
+		return Artifact{}, false
+	}
+	now := s.now()
+	artifact := &Artifact{
+		URI:      URIPrefix + id,
+		Name:     name,
+		MIMEType: mimeType,
+		Size:     size,
+		Owner:    owner,
+		Created:  now,
+		Expires:  now.Add(s.ttl),
+		content:  w,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked(now)
+	s.evictLocked(maxStoreBytes - size)
+	s.items[id] = artifact
+	s.size += size
+	return *artifact, true
+}
+
+// Get returns an unexpired artifact by URI. Artifacts with an owner are only returned to that owner.
+func (s *Store) Get(uri, owner string) (Artifact, bool) {
+	if s == nil {
+		return Artifact{}, false
+	}
+	id, ok := strings.CutPrefix(uri, URIPrefix)
+	if !ok {
+		return Artifact{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	artifact, ok := s.items[id]
+	if !ok || !s.now().Before(artifact.Expires) || (artifact.Owner != "" && artifact.Owner != owner) {
+		return Artifact{}, false
+	}
+	return *artifact, true
+}
+
+// Sweep removes expired artifacts and returns how many were removed
+func (s *Store) Sweep() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sweepLocked(s.now())
+}
+
+// ReleaseOwner removes the artifacts of a session when it closes
+func (s *Store) ReleaseOwner(owner string) {
+	if s == nil || owner == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, artifact := range s.items {
+		if artifact.Owner == owner {
+			s.removeLocked(id)
+		}
+	}
+}
+
+// Len returns the number of stored artifacts, including expired ones not yet swept
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func (s *Store) sweepLocked(now time.Time) int {
+	removed := 0
+	for id, artifact := range s.items {
+		if !now.Before(artifact.Expires) {
+			s.removeLocked(id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// evictLocked removes the oldest artifacts until at most limit bytes are held
+func (s *Store) evictLocked(limit int64) {
+	if s.size <= limit {
+		return
+	}
+	ids := make([]string, 0, len(s.items))
+	for id := range s.items {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.items[ids[i]].Created.Before(s.items[ids[j]].Created) })
+	for _, id := range ids {
+		if s.size <= limit {
+			return
+		}
+		s.removeLocked(id)
+	}
+}
+
+func (s *Store) removeLocked(id string) {
+	artifact := s.items[id]
+	s.size -= artifact.Size
+	_ = artifact.content.Close()
+	delete(s.items, id)
+}
+
+// newID returns a random 128-bit hex ID, so artifact URIs cannot be guessed
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,114 @@
+package artifacts
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorePutGet(t *testing.T) {
+	store := NewStore(time.Minute)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	artifact, ok := store.Put("run_detectors output", "application/json", `{"a":1}`, "session-1")
+	if !ok || !strings.HasPrefix(artifact.URI, URIPrefix) || len(artifact.URI) != len(URIPrefix)+32 {
+		t.Fatalf("Unexpected artifact %+v", artifact)
+	}
+	if got, ok := store.Get(artifact.URI, "session-1"); !ok || mustRead(t, got) != `{"a":1}` {
+		t.Errorf("Expected the owner to read the artifact, got %+v", got)
+	}
+	if _, ok := store.Get(artifact.URI, "session-2"); ok {
+		t.Error("Expected another session to be refused")
+	}
+	if _, ok := store.Get("aks-mcp://other/"+artifact.URI[len(URIPrefix):], "session-1"); ok {
+		t.Error("Expected a URI outside the artifact prefix to be refused")
+	}
+
+	// Artifacts expire after the TTL and are removed by Sweep
+	now = now.Add(time.Minute)
+	if _, ok := store.Get(artifact.URI, "session-1"); ok {
+		t.Error("Expected the artifact to have expired")
+	}
+	if removed := store.Sweep(); removed != 1 || store.Len() != 0 {
+		t.Errorf("Expected the sweep to remove the artifact, removed %d, %d left", removed, store.Len())
+	}
+}
+
+func TestStoreReleaseOwnerAndEviction(t *testing.T) {
+	store := NewStore(0)
+	store.Put("a", "text/plain", "a", "session-1")
+	shared, _ := store.Put("b", "text/plain", "b", "")
+	store.ReleaseOwner("session-1")
+	if store.Len() != 1 {
+		t.Errorf("Expected only the session's artifacts to be released, %d left", store.Len())
+	}
+	if _, ok := store.Get(shared.URI, "anyone"); !ok {
+		t.Error("Expected an artifact without owner to be readable by any caller")
+	}
+
+	// The oldest artifacts are evicted once the store is full
+	big := strings.Repeat("x", maxStoreBytes/2+1)
+	first, _ := store.Put("first", "text/plain", big, "")
+	store.now = func() time.Time { return time.Now().Add(time.Second) }
+	if _, ok := store.Put("second", "text/plain", big, ""); !ok {
+		t.Fatal("Expected the second artifact to be stored")
+	}
+	if _, ok := store.Get(first.URI, ""); ok {
+		t.Error("Expected the oldest artifact to be evicted")
+	}
+
+	var nilStore *Store
+	if _, ok := nilStore.Put("a", "text/plain", "a", ""); ok || nilStore.Sweep() != 0 {
+		t.Error("Expected a nil store to store nothing")
+	}
+}
+
+func mustRead(t *testing.T, artifact Artifact) string {
+	t.Helper()
+	content, err := artifact.Read()
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	return content
+}
+
+func TestStorePages(t *testing.T) {
+	store := NewStore(time.Minute)
+	// A two-byte character straddles the first page boundary
+	content := strings.Repeat("a", PageBytes-1) + "é" + strings.Repeat("b", PageBytes)
+	artifact, ok := store.Put("logs", "text/plain", content, "")
+	if !ok || artifact.Size != int64(len(content)) || artifact.Pages() != 3 {
+		t.Fatalf("Unexpected artifact %+v", artifact)
+	}
+
+	first, err := artifact.ReadPage(0)
+	if err != nil || len(first) != PageBytes-1 {
+		t.Fatalf("Expected the first page to end before the split character, got %d bytes (%v)", len(first), err)
+	}
+	second, _ := artifact.ReadPage(1)
+	third, _ := artifact.ReadPage(2)
+	if !strings.HasPrefix(second, "é") || first+second+third != content {
+		t.Error("Expected the pages to add up to the content")
+	}
+	if _, err := artifact.ReadPage(3); err == nil {
+		t.Error("Expected a page past the end to be rejected")
+	}
+
+	uri, page, ok := SplitPageURI(artifact.PageURI(2))
+	if !ok || uri != artifact.URI || page != 2 {
+		t.Errorf("Unexpected split of %s: %s %d", artifact.PageURI(2), uri, page)
+	}
+	if _, _, ok := SplitPageURI(artifact.URI); ok {
+		t.Error("Expected an artifact URI not to name a page")
+	}
+
+	// Removing the artifact releases its spooled content
+	store.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if store.Sweep() != 1 {
+		t.Fatal("Expected the expired artifact to be swept")
+	}
+	if _, err := artifact.Read(); err == nil {
+		t.Error("Expected the content of a removed artifact to be released")
+	}
+}
@@ -0,0 +1,146 @@
+// Package audit records privileged tool invocations, including denied attempts,
+// so operators can review what was run against their clusters. Records are hash chained,
+// and optionally signed, so changes to the persisted log can be detected.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// Bucket is the state store bucket audit records are kept in
+const Bucket = "audit"
+
+// Outcomes of an audited action
+const (
+	OutcomeDenied    = "denied"
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+// Record describes a single audited action
+type Record struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Tool      string    `json:"tool"`
+	Action    string    `json:"action"`
+	Namespace string    `json:"namespace,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	// Client is the name of the API key the action was requested with, when API keys are configured
+	Client string `json:"client,omitempty"`
+	// PrevHash is the Hash of the record written before this one
+	PrevHash string `json:"prevHash,omitempty"`
+	// Hash is the SHA-256 of the record without Hash and Signature
+	Hash string `json:"hash,omitempty"`
+	// Signature is the HMAC-SHA256 of Hash with the signing key, when one is configured
+	Signature string `json:"signature,omitempty"`
+}
+
+// Logger writes audit records to the server log and persists them in the state store
+type Logger struct {
+	mu         sync.Mutex
+	seq        int
+	lastHash   string
+	signingKey []byte
+	repo       *store.Repository[Record]
+	// checkpoints holds the checkpoint of the last prune
+	checkpoints *store.Repository[Checkpoint]
+	// retention is how long records are kept; zero keeps every record
+	retention time.Duration
+	now       func() time.Time
+	// observer receives every record once it is persisted
+	observer func(Record)
+}
+
+// Option configures a Logger
+type Option func(*Logger)
+
+// WithSigningKey signs every record with an HMAC of its hash, so records cannot be rewritten
+// and re-chained without the key
+func WithSigningKey(key []byte) Option {
+	return func(l *Logger) {
+		l.signingKey = key
+	}
+}
+
+// WithObserver passes every record to observe after it is persisted, for example to export it.
+// observe runs on the goroutine of the audited action, outside the logger's lock.
+func WithObserver(observe func(Record)) Option {
+	return func(l *Logger) {
+		l.observer = observe
+	}
+}
+
+// NewLogger creates an audit logger. A nil store only writes records to the server log.
+// The hash chain continues from the last persisted record, or from the last pruned record when every
+// record was pruned.
+func NewLogger(s store.Store, opts ...Option) *Logger {
+	l := &Logger{now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if s != nil {
+		l.repo = store.NewRepository[Record](s, Bucket)
+		l.checkpoints = store.NewRepository[Checkpoint](s, CheckpointBucket)
+		records, err := l.repo.List()
+		if err != nil {
+			log.Printf("Failed to read audit records, starting a new hash chain: %v", err)
+		} else if len(records) > 0 {
+			l.lastHash = records[len(records)-1].Hash
+		} else if checkpoint, found, err := l.loadCheckpoint(); err != nil {
+			log.Printf("%v, starting a new hash chain", err)
+		} else if found {
+			l.lastHash = checkpoint.LastHash
+		}
+	}
+	return l
+}
+
+// Log stamps the record with an ID and time, then writes and persists it.
+// Audit failures are logged but never fail the audited action.
+func (l *Logger) Log(record Record) Record {
+	l.mu.Lock()
+	l.seq++
+	record.Time = l.now().UTC()
+	// IDs sort in the order records were written
+	record.ID = fmt.Sprintf("%020d-%06d", record.Time.UnixNano(), l.seq)
+	record.PrevHash = l.lastHash
+	record.Hash = recordHash(record)
+	record.Signature = ""
+	if l.signingKey != nil {
+		record.Signature = sign(l.signingKey, record.Hash)
+	}
+	l.lastHash = record.Hash
+
+	// Records are persisted under the lock so the stored chain follows the order of the hashes
+	if l.repo != nil {
+		if err := l.repo.Save(record.ID, record); err != nil {
+			log.Printf("Failed to persist audit record %s: %v", record.ID, err)
+		}
+	}
+	l.mu.Unlock()
+
+	if data, err := json.Marshal(record); err == nil {
+		log.Printf("[AUDIT] %s", data)
+	}
+	if l.observer != nil {
+		l.observer(record)
+	}
+	return record
+}
+
+// Records returns the persisted audit records, oldest first
+func (l *Logger) Records() ([]Record, error) {
+	if l.repo == nil {
+		return nil, nil
+	}
+	return l.repo.List()
+}
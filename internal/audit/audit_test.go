@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+func TestLoggerPersistsRecordsInOrder(t *testing.T) {
+	st := store.NewMemoryStore()
+	logger := NewLogger(st)
+	fixed := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.now = func() time.Time { return fixed }
+
+	first := logger.Log(Record{Tool: "aks_pod_exec", Action: "exec", Outcome: OutcomeDenied})
+	logger.Log(Record{Tool: "aks_pod_exec", Action: "exec", Outcome: OutcomeSucceeded})
+
+	if first.ID == "" || !first.Time.Equal(fixed) {
+		t.Errorf("Expected the record to be stamped, got %+v", first)
+	}
+
+	records, err := logger.Records()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].Outcome != OutcomeDenied || records[1].Outcome != OutcomeSucceeded {
+		t.Errorf("Expected both records in write order, got %+v", records)
+	}
+}
+
+func TestLoggerWithoutStore(t *testing.T) {
+	logger := NewLogger(nil)
+	logger.Log(Record{Tool: "aks_port_forward", Action: "start", Outcome: OutcomeSucceeded})
+
+	records, err := logger.Records()
+	if err != nil || records != nil {
+		t.Errorf("Expected no persisted records, got %v (%v)", records, err)
+	}
+}
+
+func TestLoggerChainsAndSignsRecords(t *testing.T) {
+	st := store.NewMemoryStore()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	logger := NewLogger(st, WithSigningKey(key))
+
+	first := logger.Log(Record{Tool: "aks_pod_exec", Action: "exec", Command: "ls", Outcome: OutcomeSucceeded})
+	second := logger.Log(Record{Tool: "aks_port_forward", Action: "start", Outcome: OutcomeSucceeded})
+	if first.Hash == "" || first.PrevHash != "" || second.PrevHash != first.Hash || second.Signature == "" {
+		t.Fatalf("Expected chained and signed records, got %+v and %+v", first, second)
+	}
+
+	// A logger opened on the same store continues the chain
+	third := NewLogger(st, WithSigningKey(key)).Log(Record{Tool: "aks_pod_exec", Action: "exec", Outcome: OutcomeDenied})
+	if third.PrevHash != second.Hash {
+		t.Errorf("Expected the chain to continue after a restart, got prevHash %q", third.PrevHash)
+	}
+
+	report, err := logger.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Valid || report.Records != 3 || !report.Signed {
+		t.Errorf("Expected an intact signed log, got %+v", report)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	logger := NewLogger(store.NewMemoryStore(), WithSigningKey(key))
+	for _, cmd := range []string{"ls", "cat /etc/hosts", "ps"} {
+		logger.Log(Record{Tool: "aks_pod_exec", Action: "exec", Command: cmd, Outcome: OutcomeSucceeded})
+	}
+	records, err := logger.Records()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	clone := func() []Record { return append([]Record(nil), records...) }
+	edited := clone()
+	edited[1].Command = "ls"
+	removed := append(clone()[:1], records[2])
+	rehashed := clone()
+	rehashed[1].Command = "ls"
+	rehashed[1].Hash = recordHash(rehashed[1])
+	rehashed[2].PrevHash = rehashed[1].Hash
+	rehashed[2].Hash = recordHash(rehashed[2])
+
+	tests := []struct {
+		name    string
+		records []Record
+		key     []byte
+		want    string
+	}{
+		{"edited", edited, key, "was modified"},
+		{"removed", removed, key, "a record was removed"},
+		{"rehashed with signatures checked", rehashed, key, "invalid signature"},
+		{"wrong key", clone(), []byte("another key that is long enough!!"), "invalid signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Verify(tt.records, nil, tt.key)
+			if report.Valid || len(report.Problems) == 0 || !strings.Contains(strings.Join(report.Problems, "\n"), tt.want) {
+				t.Errorf("Expected a problem containing %q, got %+v", tt.want, report)
+			}
+		})
+	}
+
+	// Without a key a consistent rewrite cannot be detected, which the report says
+	report := Verify(rehashed, nil, nil)
+	if !report.Valid || !strings.Contains(report.Note, "No signing key") {
+		t.Errorf("Expected an unsigned rewrite to verify with a warning, got %+v", report)
+	}
+
+	// Records written before chaining are counted but not checked
+	legacy := append([]Record{{ID: "0", Tool: "aks_pod_exec"}}, records...)
+	legacy[1].PrevHash = ""
+	legacy[1].Hash = recordHash(legacy[1])
+	legacy[1].Signature = sign(key, legacy[1].Hash)
+	if report := Verify(legacy[:2], nil, key); !report.Valid || report.Unchained != 1 {
+		t.Errorf("Expected legacy records to be skipped, got %+v", report)
+	}
+}
+
+func TestLoggerObserver(t *testing.T) {
+	var observed []Record
+	logger := NewLogger(store.NewMemoryStore(), WithObserver(func(record Record) {
+		observed = append(observed, record)
+	}))
+	record := logger.Log(Record{Tool: "aks_node_drain", Action: "drain", Outcome: OutcomeSucceeded})
+
+	if len(observed) != 1 || observed[0].ID != record.ID || observed[0].Hash == "" {
+		t.Errorf("Expected the observer to receive the stamped record, got %+v", observed)
+	}
+}
+
+func TestLoggerPruneKeepsChainVerifiable(t *testing.T) {
+	st := store.NewMemoryStore()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	logger := NewLogger(st, WithSigningKey(key), WithRetention(24*time.Hour))
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger.now = func() time.Time { return now }
+	for day := 0; day < 3; day++ {
+		logger.Log(Record{Tool: "aks_pod_exec", Action: "exec", Outcome: OutcomeSucceeded})
+		now = now.Add(24 * time.Hour)
+	}
+
+	// Two days later the two oldest records are past the retention
+	pruned, err := logger.Prune()
+	if err != nil || pruned != 2 {
+		t.Fatalf("Expected two records to be pruned, got %d (%v)", pruned, err)
+	}
+	report, err := logger.Verify()
+	if err != nil || !report.Valid || report.Records != 1 || report.Pruned != 2 {
+		t.Errorf("Expected the remaining record to verify from the checkpoint, got %+v (%v)", report, err)
+	}
+
+	// Pruning every record and restarting continues the chain from the checkpoint
+	now = now.Add(48 * time.Hour)
+	if pruned, err := logger.Prune(); err != nil || pruned != 1 {
+		t.Fatalf("Expected the last record to be pruned, got %d (%v)", pruned, err)
+	}
+	restarted := NewLogger(st, WithSigningKey(key))
+	restarted.Log(Record{Tool: "aks_port_forward", Action: "start", Outcome: OutcomeSucceeded})
+	if report, err := restarted.Verify(); err != nil || !report.Valid || report.Records != 1 || report.Pruned != 3 {
+		t.Errorf("Expected the chain to continue after every record was pruned, got %+v (%v)", report, err)
+	}
+
+	// A checkpoint forged without the key is reported
+	records, _ := restarted.Records()
+	forged := &Checkpoint{Pruned: 3, LastID: "0", LastHash: records[0].PrevHash, Signature: "forged"}
+	if report := Verify(records, forged, key); report.Valid || !strings.Contains(strings.Join(report.Problems, "\n"), "checkpoint") {
+		t.Errorf("Expected a forged checkpoint to be reported, got %+v", report)
+	}
+}
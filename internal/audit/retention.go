@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// CheckpointBucket is the state store bucket holding the checkpoint of pruned records, under checkpointKey
+const CheckpointBucket = "audit-checkpoint"
+
+const checkpointKey = "checkpoint"
+
+// Checkpoint records where the audit log was pruned, so the chain of the remaining records still verifies
+// from the hash of the last record removed
+type Checkpoint struct {
+	// Pruned counts every record removed since the log was started
+	Pruned   int       `json:"pruned"`
+	LastID   string    `json:"lastId"`
+	LastHash string    `json:"lastHash"`
+	Time     time.Time `json:"time"`
+	// Signature is the HMAC-SHA256 of LastHash with the signing key, when one is configured
+	Signature string `json:"signature,omitempty"`
+}
+
+// WithRetention keeps persisted records for maxAge; older records are removed by Prune
+func WithRetention(maxAge time.Duration) Option {
+	return func(l *Logger) {
+		l.retention = maxAge
+	}
+}
+
+// Retention returns how long persisted records are kept, or zero when every record is kept
+func (l *Logger) Retention() time.Duration {
+	return l.retention
+}
+
+// Prune removes the persisted records older than the retention and returns how many were removed. The
+// checkpoint is written before the records are removed, so an interrupted prune still verifies.
+func (l *Logger) Prune() (int, error) {
+	if l.repo == nil || l.retention <= 0 {
+		return 0, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.repo.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read audit records: %w", err)
+	}
+	cutoff := l.now().Add(-l.retention)
+	expired := 0
+	for expired < len(records) && records[expired].Time.Before(cutoff) {
+		expired++
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+
+	checkpoint, _, err := l.loadCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+	last := records[expired-1]
+	checkpoint = &Checkpoint{
+		Pruned:   checkpoint.Pruned + expired,
+		LastID:   last.ID,
+		LastHash: last.Hash,
+		Time:     l.now().UTC(),
+	}
+	if l.signingKey != nil && last.Hash != "" {
+		checkpoint.Signature = sign(l.signingKey, last.Hash)
+	}
+	if err := l.checkpoints.Save(checkpointKey, *checkpoint); err != nil {
+		return 0, fmt.Errorf("failed to save the audit checkpoint: %w", err)
+	}
+	for _, record := range records[:expired] {
+		if err := l.repo.Delete(record.ID); err != nil {
+			return 0, fmt.Errorf("failed to remove audit record %s: %w", record.ID, err)
+		}
+	}
+	log.Printf("Pruned %d audit records older than %s", expired, cutoff.UTC().Format(time.RFC3339))
+	return expired, nil
+}
+
+// loadCheckpoint returns the checkpoint of the last prune, and false when the log was never pruned
+func (l *Logger) loadCheckpoint() (*Checkpoint, bool, error) {
+	checkpoint, err := l.checkpoints.Load(checkpointKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return &Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the audit checkpoint: %w", err)
+	}
+	return &checkpoint, true, nil
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterVerifyAuditLogTool registers the verify_audit_log tool
+func RegisterVerifyAuditLogTool() mcp.Tool {
+	return mcp.NewTool(
+		"verify_audit_log",
+		mcp.WithDescription(`Check the persisted audit log of privileged tool calls (aks_pod_exec, aks_port_forward) for tampering.
+
+Every audit record holds the hash of the record before it, and an HMAC signature when the server runs with
+--audit-signing-key-file. The check recomputes each hash and signature and reports records that were modified,
+removed, reordered or inserted. Record contents are not returned.`),
+	)
+}
+
+// GetVerifyAuditLogHandler returns a handler for the verify_audit_log command
+func GetVerifyAuditLogHandler(logger *Logger) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(_ map[string]interface{}, _ *config.ConfigData) (string, error) {
+		report, err := logger.Verify()
+		if err != nil {
+			return "", fmt.Errorf("failed to read audit records: %v", err)
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal verification report: %v", err)
+		}
+		return string(data), nil
+	})
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// maxReportedProblems bounds the problems listed in a verification report
+const maxReportedProblems = 20
+
+// VerifyReport is the result of checking the audit log for tampering
+type VerifyReport struct {
+	Records int `json:"records"`
+	// Pruned counts records removed by the retention, which are no longer checked
+	Pruned int `json:"prunedRecords,omitempty"`
+	// Unchained counts records written before hash chaining, which cannot be verified
+	Unchained int      `json:"unchainedRecords,omitempty"`
+	Signed    bool     `json:"signatureChecked"`
+	Valid     bool     `json:"valid"`
+	Problems  []string `json:"problems,omitempty"`
+	Note      string   `json:"note"`
+}
+
+// recordHash returns the SHA-256 of the record's JSON without its Hash and Signature
+func recordHash(record Record) string {
+	record.Hash = ""
+	record.Signature = ""
+	// Record only holds strings and a UTC time, which always marshal
+	data, _ := json.Marshal(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sign returns the HMAC-SHA256 of a record hash
+func sign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that records, oldest first, form an unbroken hash chain and, when key is set,
+// that every record is signed with it. Edited records fail their hash, and deleted or reordered
+// records break the chain at the next record. When the log was pruned, checkpoint holds the last
+// pruned record and the chain must continue from it; records it covers that were not removed yet
+// are skipped.
+func Verify(records []Record, checkpoint *Checkpoint, key []byte) VerifyReport {
+	report := VerifyReport{Signed: key != nil}
+	problem := func(format string, args ...interface{}) {
+		if len(report.Problems) < maxReportedProblems {
+			report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+		} else if len(report.Problems) == maxReportedProblems {
+			report.Problems = append(report.Problems, "further problems omitted")
+		}
+	}
+
+	prevHash := ""
+	chained := false
+	if checkpoint != nil {
+		report.Pruned = checkpoint.Pruned
+		if checkpoint.LastHash != "" {
+			chained = true
+			prevHash = checkpoint.LastHash
+			if key != nil && !hmac.Equal([]byte(sign(key, checkpoint.LastHash)), []byte(checkpoint.Signature)) {
+				problem("the checkpoint of the pruned records has a missing or invalid signature")
+			}
+		}
+	}
+	for _, record := range records {
+		if checkpoint != nil && record.ID <= checkpoint.LastID {
+			continue
+		}
+		report.Records++
+		if record.Hash == "" {
+			if chained {
+				problem("record %s has no hash but follows chained records", record.ID)
+			} else {
+				report.Unchained++
+			}
+			continue
+		}
+		if !chained {
+			chained = true
+			// The first chained record may follow records written before chaining
+			prevHash = record.PrevHash
+			if report.Unchained == 0 && prevHash != "" {
+				problem("record %s refers to a previous record that is missing", record.ID)
+			}
+		}
+
+		if record.PrevHash != prevHash {
+			problem("record %s does not follow the record before it: a record was removed, reordered or inserted", record.ID)
+		}
+		if recordHash(record) != record.Hash {
+			problem("record %s was modified after it was written", record.ID)
+		}
+		if key != nil {
+			if record.Signature == "" {
+				problem("record %s is not signed", record.ID)
+			} else if !hmac.Equal([]byte(sign(key, record.Hash)), []byte(record.Signature)) {
+				problem("record %s has an invalid signature", record.ID)
+			}
+		}
+		prevHash = record.Hash
+	}
+
+	report.Valid = len(report.Problems) == 0
+	report.Note = "The chain detects edits, insertions and removals before the newest record. Removing the newest records is only detectable by comparing the count with an external copy, such as the [AUDIT] lines in the server log."
+	if key == nil {
+		report.Note += " No signing key is configured, so a rewritten and re-hashed log would still verify."
+	}
+	return report
+}
+
+// Verify checks the persisted audit records for tampering
+func (l *Logger) Verify() (VerifyReport, error) {
+	records, err := l.Records()
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	if l.checkpoints == nil {
+		return Verify(records, nil, l.signingKey), nil
+	}
+	checkpoint, found, err := l.loadCheckpoint()
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	if !found {
+		checkpoint = nil
+	}
+	return Verify(records, checkpoint, l.signingKey), nil
+}
@@ -0,0 +1,335 @@
+package azcli
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/security"
+	"github.com/google/shlex"
+)
+
+// uncacheableCommands are read operations whose output must never be served from cache,
+// either because they change CLI state, because their results are time dependent, or because
+// they run live diagnostics against the cluster.
+var uncacheableCommands = []string{
+	"login",
+	"logout",
+	"account set",
+	"cloud set",
+	"config",
+	"monitor",
+	"aks operation",
+	"aks check-network",
+	"aks check-acr",
+}
+
+// stateResetCommands change the CLI context, so every cached entry is dropped after they run.
+var stateResetCommands = []string{
+	"login",
+	"logout",
+	"account set",
+	"cloud set",
+}
+
+// ResourceScope identifies the resource a command targets.
+// Segments hold lower-cased names from the resource group down to the most specific child resource.
+type ResourceScope struct {
+	Subscription string
+	Segments     []string
+}
+
+// Overlaps reports whether two scopes refer to the same resource or one contains the other.
+// An empty subscription matches any subscription since the CLI default is unknown.
+func (r ResourceScope) Overlaps(other ResourceScope) bool {
+	if r.Subscription != "" && other.Subscription != "" && r.Subscription != other.Subscription {
+		return false
+	}
+	n := len(r.Segments)
+	if len(other.Segments) < n {
+		n = len(other.Segments)
+	}
+	for i := 0; i < n; i++ {
+		if r.Segments[i] != other.Segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// outputCacheEntry is a cached command output tagged with the resource it targets
+type outputCacheEntry struct {
+	output     string
+	scope      ResourceScope
+	expiration time.Time
+}
+
+// OutputCache caches az read command output and invalidates it when writes touch the same resources.
+type OutputCache struct {
+	mu      sync.Mutex
+	entries map[string]outputCacheEntry
+}
+
+// NewOutputCache creates an empty output cache
+func NewOutputCache() *OutputCache {
+	return &OutputCache{entries: make(map[string]outputCacheEntry)}
+}
+
+// defaultOutputCache is shared by all az executors in the process
+var defaultOutputCache = NewOutputCache()
+
+// Get returns the cached output for a command if present and not expired
+func (c *OutputCache) Get(cmd string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cmd]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expiration) {
+		delete(c.entries, cmd)
+		return "", false
+	}
+	return entry.output, true
+}
+
+// Set stores command output tagged with the resource scope parsed from the command
+func (c *OutputCache) Set(cmd, output string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cmd] = outputCacheEntry{
+		output:     output,
+		scope:      ParseResourceScope(cmd),
+		expiration: time.Now().Add(ttl),
+	}
+}
+
+// Invalidate removes every entry whose scope overlaps the given scope and returns the number removed
+func (c *OutputCache) Invalidate(scope ResourceScope) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, entry := range c.entries {
+		if entry.scope.Overlaps(scope) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Clear removes all entries
+func (c *OutputCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]outputCacheEntry)
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *OutputCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// RunWithCache runs an az command through proc, serving read operations from the shared output cache
+// when --az-cache-ttl is set and invalidating overlapping cached reads after a successful write operation. In review mode write
+// operations are deferred to the call's review plan instead of run.
+// args is the command without the leading "az". With the process-wide login, commands are not run
+// while the az CLI is unauthenticated; an AuthRequiredError describing how to authenticate is returned instead.
+func RunWithCache(proc Proc, args string, cfg *config.ConfigData) (string, error) {
+	if cfg != nil && cfg.Review != nil {
+		// In review mode write commands are returned as artifacts instead of run
+		trimmed := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), "az "))
+		if !security.NewValidator(cfg.SecurityConfig).IsReadOperation("az "+trimmed, security.CommandTypeAz) {
+			return "", cfg.Review.Defer(review.AzChange(trimmed))
+		}
+	}
+	if !needsAuthPreflight(cfg) {
+		return runWithOutputCache(defaultOutputCache, proc, args, cfg)
+	}
+	args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), "az "))
+	if err := defaultAuthPreflight.check(proc, args); err != nil {
+		cfg.Explain.Record(explain.KindAz, "az "+args, err)
+		return "", err
+	}
+	output, err := runWithOutputCache(defaultOutputCache, proc, args, cfg)
+	if err == nil && hasCommandPrefix(args, stateResetCommands) {
+		defaultAuthPreflight.reset()
+	}
+	return output, err
+}
+
+// runWithOutputCache is the testable implementation of RunWithCache
+func runWithOutputCache(cache *OutputCache, proc Proc, args string, cfg *config.ConfigData) (string, error) {
+	args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), "az "))
+	if cfg != nil && cfg.Session != nil {
+		sessionProc, sessionCache, err := prepareSessionProc(proc, cfg.Session, cfg.CloudEnvironment().Name)
+		if err != nil {
+			return "", err
+		}
+		proc, cache = sessionProc, sessionCache
+	}
+	if cfg == nil || cfg.AzCacheTTL <= 0 {
+		output, err := proc.Run(args)
+		if cfg != nil {
+			cfg.Explain.Record(explain.KindAz, "az "+args, err)
+		}
+		return output, err
+	}
+
+	validator := security.NewValidator(cfg.SecurityConfig)
+	isRead := validator.IsReadOperation("az "+args, security.CommandTypeAz)
+	cacheable := isRead && !hasCommandPrefix(args, uncacheableCommands)
+
+	if cacheable {
+		if output, ok := cache.Get(args); ok {
+			if cfg.Verbose {
+				log.Printf("[AZCLI] Cache hit: az %s", args)
+			}
+			cfg.Explain.RecordCached(explain.KindAz, "az "+args)
+			return output, nil
+		}
+	}
+
+	output, err := proc.Run(args)
+	cfg.Explain.Record(explain.KindAz, "az "+args, err)
+	if err != nil {
+		return output, err
+	}
+
+	switch {
+	case hasCommandPrefix(args, stateResetCommands):
+		cache.Clear()
+	case cacheable:
+		cache.Set(args, output, cfg.AzCacheTTL)
+	case !isRead:
+		if removed := cache.Invalidate(ParseResourceScope(args)); removed > 0 && cfg.Verbose {
+			log.Printf("[AZCLI] Invalidated %d cached entries after: az %s", removed, args)
+		}
+	}
+
+	return output, nil
+}
+
+// hasCommandPrefix reports whether the command words of args start with any of the given prefixes
+func hasCommandPrefix(args string, prefixes []string) bool {
+	words := commandWords(args)
+	for _, prefix := range prefixes {
+		prefixWords := strings.Fields(prefix)
+		if len(prefixWords) > len(words) {
+			continue
+		}
+		match := true
+		for i, w := range prefixWords {
+			if words[i] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// commandWords returns the leading non-flag words of a command (e.g. "aks nodepool scale")
+func commandWords(args string) []string {
+	var words []string
+	for _, part := range strings.Fields(args) {
+		if strings.HasPrefix(part, "-") {
+			break
+		}
+		words = append(words, part)
+	}
+	return words
+}
+
+// ParseResourceScope extracts the targeted resource from an az command's flags.
+// Recognised flags are --subscription, --resource-group/-g, --cluster-name, --name/-n,
+// --nodepool-name and --ids.
+func ParseResourceScope(args string) ResourceScope {
+	parts, err := shlex.Split(args)
+	if err != nil {
+		parts = strings.Fields(args)
+	}
+
+	flags := make(map[string]string)
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if !strings.HasPrefix(part, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(part, "=")
+		if !hasValue && i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "-") {
+			value = parts[i+1]
+			i++
+		}
+		flags[name] = strings.ToLower(value)
+	}
+
+	scope := ResourceScope{Subscription: flags["--subscription"]}
+
+	if ids := flags["--ids"]; ids != "" {
+		return scopeFromResourceID(ids)
+	}
+
+	rg := firstNonEmpty(flags["--resource-group"], flags["-g"])
+	if rg == "" {
+		return scope
+	}
+	scope.Segments = append(scope.Segments, rg)
+
+	name := firstNonEmpty(flags["--name"], flags["-n"])
+	if cluster := flags["--cluster-name"]; cluster != "" {
+		scope.Segments = append(scope.Segments, cluster)
+		name = firstNonEmpty(flags["--nodepool-name"], name)
+	}
+	if name != "" {
+		scope.Segments = append(scope.Segments, name)
+	}
+	return scope
+}
+
+// scopeFromResourceID converts an ARM resource ID into a ResourceScope
+func scopeFromResourceID(resourceID string) ResourceScope {
+	parts := strings.Split(strings.Trim(strings.ToLower(resourceID), "/"), "/")
+	var scope ResourceScope
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "subscriptions":
+			scope.Subscription = parts[i+1]
+			i++
+		case "resourcegroups":
+			scope.Segments = append(scope.Segments, parts[i+1])
+			i++
+		case "providers":
+			// Skip the provider namespace, then take every other element as a resource name
+			for j := i + 3; j < len(parts); j += 2 {
+				scope.Segments = append(scope.Segments, parts[j])
+			}
+			return scope
+		}
+	}
+	return scope
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package azcli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// countingProc records how many times each command was run
+type countingProc struct {
+	calls map[string]int
+}
+
+func (p *countingProc) Run(cmd string) (string, error) {
+	if p.calls == nil {
+		p.calls = make(map[string]int)
+	}
+	p.calls[cmd]++
+	return "output of " + cmd, nil
+}
+
+func newCacheTestConfig(accessLevel string) *config.ConfigData {
+	cfg := config.NewConfig()
+	cfg.AccessLevel = accessLevel
+	cfg.SecurityConfig.AccessLevel = accessLevel
+	cfg.AzCacheTTL = time.Minute
+	return cfg
+}
+
+func TestParseResourceScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected []string
+		sub      string
+	}{
+		{"cluster show", "aks show -g RG -n Cluster", []string{"rg", "cluster"}, ""},
+		{"nodepool scale", "aks nodepool scale --resource-group rg --cluster-name c --name np --node-count 3", []string{"rg", "c", "np"}, ""},
+		{"nodepool by flag", "aks nodepool show -g rg --cluster-name c --nodepool-name np", []string{"rg", "c", "np"}, ""},
+		{"list in subscription", "aks list --subscription SUB", nil, "sub"},
+		{"equals syntax", "aks show --resource-group=rg --name=c", []string{"rg", "c"}, ""},
+		{"resource ids", "vmss restart --ids /subscriptions/s/resourceGroups/MC_rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss1/virtualMachines/0", []string{"mc_rg", "vmss1", "0"}, "s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := ParseResourceScope(tt.args)
+			if scope.Subscription != tt.sub {
+				t.Errorf("expected subscription %q, got %q", tt.sub, scope.Subscription)
+			}
+			if len(scope.Segments) != len(tt.expected) {
+				t.Fatalf("expected segments %v, got %v", tt.expected, scope.Segments)
+			}
+			for i := range tt.expected {
+				if scope.Segments[i] != tt.expected[i] {
+					t.Errorf("expected segments %v, got %v", tt.expected, scope.Segments)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceScopeOverlaps(t *testing.T) {
+	cluster := ResourceScope{Segments: []string{"rg", "c"}}
+	pool := ResourceScope{Segments: []string{"rg", "c", "np"}}
+	otherCluster := ResourceScope{Segments: []string{"rg", "other"}}
+	subList := ResourceScope{Subscription: "sub"}
+	otherSub := ResourceScope{Subscription: "sub2", Segments: []string{"rg", "c"}}
+
+	if !cluster.Overlaps(pool) || !pool.Overlaps(cluster) {
+		t.Error("expected cluster and node pool scopes to overlap")
+	}
+	if cluster.Overlaps(otherCluster) {
+		t.Error("expected different clusters not to overlap")
+	}
+	if !subList.Overlaps(pool) {
+		t.Error("expected subscription-wide list to overlap any resource")
+	}
+	if subList.Overlaps(otherSub) {
+		t.Error("expected different subscriptions not to overlap")
+	}
+}
+
+func TestRunWithCache_ServesReadsFromCache(t *testing.T) {
+	cache := NewOutputCache()
+	proc := &countingProc{}
+	cfg := newCacheTestConfig("readonly")
+
+	for i := 0; i < 3; i++ {
+		if _, err := runWithOutputCache(cache, proc, "aks show -g rg -n c", cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if proc.calls["aks show -g rg -n c"] != 1 {
+		t.Errorf("expected command to run once, ran %d times", proc.calls["aks show -g rg -n c"])
+	}
+}
+
+func TestRunWithCache_WriteInvalidatesOverlappingReads(t *testing.T) {
+	cache := NewOutputCache()
+	proc := &countingProc{}
+	cfg := newCacheTestConfig("readwrite")
+
+	reads := []string{
+		"aks show -g rg -n c",
+		"aks nodepool list -g rg --cluster-name c",
+		"aks list",
+		"aks show -g rg -n other",
+	}
+	for _, cmd := range reads {
+		if _, err := runWithOutputCache(cache, proc, cmd, cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if cache.Len() != len(reads) {
+		t.Fatalf("expected %d cached entries, got %d", len(reads), cache.Len())
+	}
+
+	if _, err := runWithOutputCache(cache, proc, "aks nodepool scale -g rg --cluster-name c -n np --node-count 5", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the unrelated cluster should remain cached
+	if cache.Len() != 1 {
+		t.Errorf("expected 1 cached entry after invalidation, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("aks show -g rg -n other"); !ok {
+		t.Error("expected unrelated cluster read to stay cached")
+	}
+}
+
+func TestRunWithCache_SkipsUncacheableCommands(t *testing.T) {
+	cache := NewOutputCache()
+	proc := &countingProc{}
+	cfg := newCacheTestConfig("readonly")
+
+	for i := 0; i < 2; i++ {
+		_, _ = runWithOutputCache(cache, proc, "monitor activity-log list --resource-group rg", cfg)
+	}
+	if proc.calls["monitor activity-log list --resource-group rg"] != 2 {
+		t.Error("expected monitor commands not to be cached")
+	}
+
+	for _, cmd := range []string{
+		"aks check-network outbound -g rg -n c",
+		"aks check-acr -g rg -n c --acr myacr.azurecr.io",
+	} {
+		_, _ = runWithOutputCache(cache, proc, cmd, cfg)
+		_, _ = runWithOutputCache(cache, proc, cmd, cfg)
+		if proc.calls[cmd] != 2 {
+			t.Errorf("expected live diagnostic %q not to be cached", cmd)
+		}
+	}
+
+	_, _ = runWithOutputCache(cache, proc, "aks show -g rg -n c", cfg)
+	_, _ = runWithOutputCache(cache, proc, "account set --subscription other", cfg)
+	if cache.Len() != 0 {
+		t.Errorf("expected account set to clear the cache, got %d entries", cache.Len())
+	}
+}
+
+func TestRunWithCache_DisabledWithoutTimeout(t *testing.T) {
+	cache := NewOutputCache()
+	proc := &countingProc{}
+	// The cache is off unless --az-cache-ttl is set
+	cfg := config.NewConfig()
+
+	_, _ = runWithOutputCache(cache, proc, "aks show -g rg -n c", cfg)
+	_, _ = runWithOutputCache(cache, proc, "aks show -g rg -n c", cfg)
+	if proc.calls["aks show -g rg -n c"] != 2 || cache.Len() != 0 {
+		t.Error("expected caching to be disabled without --az-cache-ttl")
+	}
+}
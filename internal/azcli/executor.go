@@ -57,7 +57,7 @@ func (e *AzExecutor) Execute(params map[string]interface{}, cfg *config.ConfigDa
 
 	// Execute the command
 	process := command.NewShellProcess(binaryName, cfg.Timeout)
-	return process.Run(cmdArgs)
+	return RunWithCache(process, cmdArgs, cfg)
 }
 
 // ExecuteSpecificCommand executes a specific az command with the given arguments
@@ -101,7 +101,7 @@ func (e *AzExecutor) ExecuteSpecificCommand(cmd string, params map[string]interf
 
 	// Execute the command
 	process := command.NewShellProcess(binaryName, cfg.Timeout)
-	return process.Run(cmdArgs)
+	return RunWithCache(process, cmdArgs, cfg)
 }
 
 // CreateCommandExecutorFunc creates a CommandExecutor for a specific az command
@@ -0,0 +1,114 @@
+package azcli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flags shared by every az command
+var globalFlags = []string{"subscription", "query", "output", "only-show-errors"}
+
+// BuildFlags converts a structured parameters object into quoted az CLI flags.
+// Keys may be written as resource_group, resource-group or --resource-group and must
+// appear in allowed or be a global flag. String and number values become --key 'value',
+// true becomes a bare --key, false omits the flag, and arrays become space separated values.
+func BuildFlags(parameters map[string]interface{}, allowed []string) (string, error) {
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var flags []string
+	for _, key := range keys {
+		name := normalizeFlagName(key)
+		if !slices.Contains(allowed, name) && !slices.Contains(globalFlags, name) {
+			return "", fmt.Errorf("parameter '%s' is not allowed for this operation. Allowed parameters: %s",
+				key, strings.Join(append(append([]string{}, allowed...), globalFlags...), ", "))
+		}
+
+		switch value := parameters[key].(type) {
+		case nil:
+		case bool:
+			if value {
+				flags = append(flags, "--"+name)
+			}
+		case []interface{}:
+			if len(value) == 0 {
+				continue
+			}
+			parts := []string{"--" + name}
+			for _, item := range value {
+				s, err := scalarString(item)
+				if err != nil {
+					return "", fmt.Errorf("parameter '%s': %w", key, err)
+				}
+				parts = append(parts, QuoteArg(s))
+			}
+			flags = append(flags, strings.Join(parts, " "))
+		default:
+			s, err := scalarString(value)
+			if err != nil {
+				return "", fmt.Errorf("parameter '%s': %w", key, err)
+			}
+			flags = append(flags, "--"+name+" "+QuoteArg(s))
+		}
+	}
+	return strings.Join(flags, " "), nil
+}
+
+// QuoteArg single-quotes a value so the command runner passes it as one argument, whatever it contains
+func QuoteArg(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// normalizeFlagName turns resource_group or --resource-group into resource-group
+func normalizeFlagName(key string) string {
+	return strings.ReplaceAll(strings.TrimLeft(strings.TrimSpace(key), "-"), "_", "-")
+}
+
+// scalarString formats a JSON string, number or boolean value
+func scalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// ResolveArgs returns the CLI arguments of an az-backed tool call: the raw args string
+// followed by the flags built from the optional parameters object
+func ResolveArgs(params map[string]interface{}, allowed []string) (string, error) {
+	args, _ := params["args"].(string)
+	args = strings.TrimSpace(args)
+
+	raw, ok := params["parameters"]
+	if !ok || raw == nil {
+		return args, nil
+	}
+	parameters, ok := raw.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid 'parameters': expected an object of flag names to values")
+	}
+	flags, err := BuildFlags(parameters, allowed)
+	if err != nil {
+		return "", err
+	}
+	if args == "" {
+		return flags, nil
+	}
+	if flags == "" {
+		return args, nil
+	}
+	return args + " " + flags, nil
+}
@@ -0,0 +1,84 @@
+package azcli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/shlex"
+)
+
+func TestBuildFlags(t *testing.T) {
+	allowed := []string{"name", "resource-group", "node-count", "zones", "yes"}
+	parameters := map[string]interface{}{
+		"name":             "my cluster",
+		"resource_group":   "myRG",
+		"--node-count":     float64(3),
+		"zones":            []interface{}{"1", float64(2)},
+		"yes":              true,
+		"query":            "agentPoolProfiles[?name=='np1'].count",
+		"only_show_errors": false,
+	}
+
+	flags, err := BuildFlags(parameters, allowed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The flags must survive the shell splitting done by the command runner
+	words, err := shlex.Split(flags)
+	if err != nil {
+		t.Fatalf("Failed to split %q: %v", flags, err)
+	}
+	want := []string{
+		"--node-count", "3",
+		"--name", "my cluster",
+		"--query", "agentPoolProfiles[?name=='np1'].count",
+		"--resource-group", "myRG",
+		"--yes",
+		"--zones", "1", "2",
+	}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("Expected %q, got %q", want, words)
+	}
+}
+
+func TestBuildFlagsRejectsUnknownKeys(t *testing.T) {
+	_, err := BuildFlags(map[string]interface{}{"scripts": "rm -rf /"}, []string{"name"})
+	if err == nil || !strings.Contains(err.Error(), "'scripts' is not allowed") {
+		t.Fatalf("Expected an allowlist error, got %v", err)
+	}
+
+	_, err = BuildFlags(map[string]interface{}{"name": map[string]interface{}{"a": "b"}}, []string{"name"})
+	if err == nil {
+		t.Fatal("Expected an error for object values")
+	}
+}
+
+func TestResolveArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"args only", map[string]interface{}{"args": " --name c1 "}, "--name c1"},
+		{"parameters only", map[string]interface{}{"parameters": map[string]interface{}{"name": "c1"}}, "--name 'c1'"},
+		{"both", map[string]interface{}{"args": "--name c1", "parameters": map[string]interface{}{"query": "id"}}, "--name c1 --query 'id'"},
+		{"neither", map[string]interface{}{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveArgs(tt.params, []string{"name"})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ResolveArgs(map[string]interface{}{"parameters": "--name c1"}, nil); err == nil {
+		t.Error("Expected an error when parameters is not an object")
+	}
+}
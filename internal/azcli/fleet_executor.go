@@ -2,6 +2,8 @@ package azcli
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/Azure/aks-mcp/internal/components/fleet/kubernetes"
@@ -88,17 +90,39 @@ func (e *FleetExecutor) Execute(params map[string]interface{}, cfg *config.Confi
 	return e.AzExecutor.Execute(execParams, cfg)
 }
 
-// validateCombination validates if the operation/resource combination is valid
-func (e *FleetExecutor) validateCombination(operation, resource string) error {
-	validCombinations := map[string][]string{
-		"fleet":          {"list", "show", "create", "update", "delete", "get-credentials"},
-		"member":         {"list", "show", "create", "update", "delete"},
-		"updaterun":      {"list", "show", "create", "start", "stop", "delete"},
-		"updatestrategy": {"list", "show", "create", "delete"},
+// FleetOperations maps each az_fleet resource to the operations valid on it
+var FleetOperations = map[string][]string{
+	"fleet":                    {"list", "show", "create", "update", "delete", "get-credentials"},
+	"member":                   {"list", "show", "create", "update", "delete"},
+	"updaterun":                {"list", "show", "create", "start", "stop", "delete"},
+	"updatestrategy":           {"list", "show", "create", "delete"},
+	"clusterresourceplacement": {"list", "show", "get", "create", "delete"},
+}
+
+// FleetResources returns the resources of FleetOperations, sorted
+func FleetResources() []string {
+	resources := make([]string, 0, len(FleetOperations))
+	for resource := range FleetOperations {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// FleetOperationNames returns the operations valid on any resource of FleetOperations, sorted
+func FleetOperationNames() []string {
+	var operations []string
+	for _, ops := range FleetOperations {
+		operations = append(operations, ops...)
 	}
+	sort.Strings(operations)
+	return slices.Compact(operations)
+}
 
-	validOps, exists := validCombinations[resource]
-	if !exists {
+// validateCombination validates if the operation/resource combination is valid
+func (e *FleetExecutor) validateCombination(operation, resource string) error {
+	validOps, exists := FleetOperations[resource]
+	if !exists || resource == "clusterresourceplacement" {
 		return fmt.Errorf("invalid resource type: %s", resource)
 	}
 
@@ -156,6 +180,11 @@ func (e *FleetExecutor) executeKubernetesClusterResourcePlacement(operation, arg
 		return "", err
 	}
 
+	// The Kubernetes client uses the server kubeconfig, which must not serve session credential callers
+	if cfg.SessionCredentials {
+		return "", fmt.Errorf("clusterresourceplacement operations are not available in session credential mode")
+	}
+
 	// Initialize Kubernetes client if needed
 	if !e.k8sClientInitialized {
 		if err := e.initializeKubernetesClient(); err != nil {
@@ -249,7 +278,7 @@ func (e *FleetExecutor) initializeKubernetesClient() error {
 
 // validateClusterResourcePlacementCombination validates clusterresourceplacement operations
 func (e *FleetExecutor) validateClusterResourcePlacementCombination(operation string) error {
-	validOps := []string{"list", "show", "get", "create", "delete"}
+	validOps := FleetOperations["clusterresourceplacement"]
 
 	for _, validOp := range validOps {
 		if operation == validOp {
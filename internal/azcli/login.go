@@ -7,8 +7,10 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
+	"github.com/Azure/aks-mcp/internal/cloudenv"
 	"github.com/Azure/aks-mcp/internal/command"
 	"github.com/Azure/aks-mcp/internal/config"
 )
@@ -22,20 +24,62 @@ const (
 	AuthTypeSystemAssignedManagedID = "system_assigned_managed_identity"
 )
 
-// validateFederatedTokenFile only allows the fixed AKS identity token path.
-func validateFederatedTokenFile(filePath string) (string, error) {
-	const allowedTokenPath = "/var/run/secrets/azure/tokens/azure-identity-token" // #nosec G101 -- not a credential, this is a fixed AKS token path
-	if filePath != allowedTokenPath {
-		return "", fmt.Errorf("federated token file path must be exactly %s", allowedTokenPath)
+// DefaultFederatedTokenPath is the projected service account token path used by AKS workload identity.
+// It is always allowed; additional paths can be allowed with --federated-token-paths.
+const DefaultFederatedTokenPath = "/var/run/secrets/azure/tokens/azure-identity-token" // #nosec G101 -- not a credential, this is a fixed AKS token path
+
+// allowedFederatedTokenPaths returns the default token path plus any configured paths
+func allowedFederatedTokenPaths(cfg *config.ConfigData) []string {
+	paths := []string{DefaultFederatedTokenPath}
+	if cfg != nil {
+		paths = append(paths, cfg.FederatedTokenPaths...)
+	}
+	return paths
+}
+
+// validateFederatedTokenFile only allows token files on the allowlist. The path must be absolute and
+// clean, may only resolve (through symlinks such as Kubernetes projected volume links) to a file inside
+// its own directory, and the resolved file must be a regular file owned by root or the current user
+// that is not writable by group or others. It returns the resolved path.
+func validateFederatedTokenFile(filePath string, allowedPaths []string) (string, error) {
+	if !filepath.IsAbs(filePath) || filepath.Clean(filePath) != filePath {
+		return "", fmt.Errorf("federated token file path must be an absolute, clean path: %s", filePath)
+	}
+	allowed := false
+	for _, path := range allowedPaths {
+		if filepath.Clean(path) == filePath {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("federated token file path %s is not allowed; allowed paths: %s (add paths with --federated-token-paths)",
+			filePath, strings.Join(allowedPaths, ", "))
+	}
+
+	resolved, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve federated token file %s: %w", filePath, err)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(filePath))
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve federated token directory %s: %w", filepath.Dir(filePath), err)
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("federated token file %s resolves outside its directory: %s", filePath, resolved)
 	}
-	fileInfo, err := os.Stat(allowedTokenPath)
+
+	fileInfo, err := os.Stat(resolved)
 	if err != nil {
-		return "", fmt.Errorf("cannot stat federated token file %s: %w", allowedTokenPath, err)
+		return "", fmt.Errorf("cannot stat federated token file %s: %w", resolved, err)
 	}
 	if !fileInfo.Mode().IsRegular() {
-		return "", fmt.Errorf("federated token file is not a regular file: %s", allowedTokenPath)
+		return "", fmt.Errorf("federated token file is not a regular file: %s", resolved)
+	}
+	if err := checkTokenFileOwnership(fileInfo); err != nil {
+		return "", fmt.Errorf("federated token file %s: %w", resolved, err)
 	}
-	return allowedTokenPath, nil
+	return resolved, nil
 }
 
 // Proc is a minimal interface used by this package so tests can inject a fake process.
@@ -66,6 +110,11 @@ var NewShellProc = func(timeout int) Proc {
 
 // EnsureAzCliLoginWithProc is the testable implementation that uses an injected Proc.
 func EnsureAzCliLoginWithProc(proc Proc, cfg *config.ConfigData) (string, error) {
+	// Point az CLI at the configured cloud before any login attempt
+	if err := setCloud(proc, cfg.CloudEnvironment().Name); err != nil {
+		return "", err
+	}
+
 	// Read environment variables to determine which auth methods to try
 	tenantID := os.Getenv("AZURE_TENANT_ID")
 	clientID := os.Getenv("AZURE_CLIENT_ID")
@@ -91,13 +140,13 @@ func EnsureAzCliLoginWithProc(proc Proc, cfg *config.ConfigData) (string, error)
 	// 2) Workload Identity (federated token)
 	if clientID != "" && tenantID != "" && federatedTokenFile != "" {
 		// Validate the federated token file path for security and get canonical path
-		validatedPath, err := validateFederatedTokenFile(federatedTokenFile)
+		validatedPath, err := validateFederatedTokenFile(federatedTokenFile, allowedFederatedTokenPaths(cfg))
 		if err != nil {
 			return "", fmt.Errorf("federated token file validation failed: %w", err)
 		}
 
-		// Open the only allowed federated token file (fixed path, safe)
-		f, err := os.Open(validatedPath) // #nosec G304 -- validated fixed path, not user-controlled
+		// Open the validated federated token file
+		f, err := os.Open(validatedPath) // #nosec G304 -- path validated against the allowlist above
 		if err != nil {
 			return "", fmt.Errorf("failed to open federated token file %s: %w", validatedPath, err)
 		}
@@ -180,6 +229,21 @@ func runLoginCommand(proc Proc, cmd string, loginMethod string) error {
 	return nil
 }
 
+// Selects the az CLI cloud when it is not the public cloud (the az CLI default).
+func setCloud(proc Proc, cloudName string) error {
+	if cloudName == "" || cloudName == cloudenv.AzurePublicCloud {
+		return nil
+	}
+	out, err := proc.Run(fmt.Sprintf("cloud set --name %s", cloudName))
+	if strings.HasPrefix(strings.TrimSpace(out), "ERROR:") {
+		return fmt.Errorf("failed to select az cloud %s: %s", cloudName, out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to select az cloud %s: %w", cloudName, err)
+	}
+	return nil
+}
+
 // Sets the subscription when provided and wraps errors with context.
 func setSubscription(proc Proc, subscriptionID, loginMethod string) error {
 	if subscriptionID == "" {
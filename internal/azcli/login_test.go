@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/Azure/aks-mcp/internal/cloudenv"
 	"github.com/Azure/aks-mcp/internal/config"
 )
 
@@ -80,6 +83,35 @@ func TestEnsureAzCliLogin_ServicePrincipal(t *testing.T) {
 	}
 }
 
+func TestEnsureAzCliLogin_SovereignCloud(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Cloud, _ = cloudenv.Lookup("usgov")
+	t.Setenv("AZURE_CLIENT_ID", "dummy-client-id")
+	t.Setenv("AZURE_CLIENT_SECRET", "dummy-client-secret")
+	t.Setenv("AZURE_TENANT_ID", "dummy-tenant-id")
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "")
+	p := &loginCommands{resp: []loginCommandResponses{
+		{cmd: "cloud set --name AzureUSGovernment", out: "", err: nil},
+		{cmd: "login --service-principal -u dummy-client-id", out: "", err: nil},
+		{cmd: "account show --query id -o tsv", out: "sub-id", err: nil},
+	}}
+	got, err := EnsureAzCliLoginWithProc(p, cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != "service_principal" {
+		t.Fatalf("unexpected result: %s", got)
+	}
+
+	// A failure to select the cloud aborts login
+	p = &loginCommands{resp: []loginCommandResponses{
+		{cmd: "cloud set --name AzureUSGovernment", out: "ERROR: cloud not registered", err: nil},
+	}}
+	if _, err := EnsureAzCliLoginWithProc(p, cfg); err == nil {
+		t.Fatal("expected error when az cloud set fails")
+	}
+}
+
 func TestEnsureAzCliLogin_ServicePrincipal_ErrorOutput(t *testing.T) {
 	cfg := config.NewConfig()
 	t.Setenv("AZURE_CLIENT_ID", "dummy-client-id")
@@ -257,6 +289,105 @@ func TestEnsureAzCliLogin_Federated_Success(t *testing.T) {
 	}
 }
 
+func TestEnsureAzCliLogin_Federated_ConfiguredPath(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("configured-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	cfg.FederatedTokenPaths = []string{tokenPath}
+	t.Setenv("AZURE_CLIENT_ID", "dummy-client-id")
+	t.Setenv("AZURE_TENANT_ID", "dummy-tenant-id")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", tokenPath)
+
+	p := &loginCommands{resp: []loginCommandResponses{
+		{cmd: "login --service-principal -u dummy-client-id --tenant dummy-tenant-id --federated-token configured-token", out: "", err: nil},
+		{cmd: "account show --query id -o tsv", out: "sub-id", err: nil},
+	}}
+
+	got, err := EnsureAzCliLoginWithProc(p, cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != AuthTypeFederatedToken {
+		t.Fatalf("unexpected result: %s", got)
+	}
+}
+
+func TestValidateFederatedTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("token"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	t.Run("not allowlisted", func(t *testing.T) {
+		if _, err := validateFederatedTokenFile(tokenPath, []string{DefaultFederatedTokenPath}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Fatalf("expected allowlist error, got %v", err)
+		}
+	})
+
+	t.Run("relative or unclean path", func(t *testing.T) {
+		for _, path := range []string{"token", dir + "/../" + filepath.Base(dir) + "/token"} {
+			if _, err := validateFederatedTokenFile(path, []string{path}); err == nil {
+				t.Errorf("expected error for path %s", path)
+			}
+		}
+	})
+
+	t.Run("allowlisted file", func(t *testing.T) {
+		got, err := validateFederatedTokenFile(tokenPath, []string{tokenPath})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want, _ := filepath.EvalSymlinks(tokenPath); got != want {
+			t.Fatalf("expected resolved path %s, got %s", want, got)
+		}
+	})
+
+	t.Run("symlink within directory", func(t *testing.T) {
+		link := filepath.Join(dir, "link")
+		if err := os.Symlink("token", link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+		if _, err := validateFederatedTokenFile(link, []string{link}); err != nil {
+			t.Fatalf("expected symlink inside the directory to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("symlink escaping directory", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		link := filepath.Join(dir, "escape")
+		if err := os.Symlink(outside, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+		if _, err := validateFederatedTokenFile(link, []string{link}); err == nil || !strings.Contains(err.Error(), "outside its directory") {
+			t.Fatalf("expected symlink escape error, got %v", err)
+		}
+	})
+
+	t.Run("group writable file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("permission bits are not checked on Windows")
+		}
+		writable := filepath.Join(dir, "writable")
+		if err := os.WriteFile(writable, []byte("token"), 0o600); err != nil {
+			t.Fatalf("failed to write token: %v", err)
+		}
+		if err := os.Chmod(writable, 0o666); err != nil {
+			t.Fatalf("failed to chmod: %v", err)
+		}
+		if _, err := validateFederatedTokenFile(writable, []string{writable}); err == nil || !strings.Contains(err.Error(), "writable") {
+			t.Fatalf("expected permission error, got %v", err)
+		}
+	})
+}
+
 func TestEnsureAzCliLogin_ManagedIdentity_UserAssigned(t *testing.T) {
 	cfg := config.NewConfig()
 	t.Setenv("AZURE_CLIENT_ID", "dummy-managed-identity-client-id")
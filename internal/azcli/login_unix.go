@@ -0,0 +1,25 @@
+//go:build !windows
+
+package azcli
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkTokenFileOwnership requires the token file to be owned by root or the current user
+// and not writable by group or others
+func checkTokenFileOwnership(info os.FileInfo) error {
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("file must not be writable by group or others (mode %s)", info.Mode().Perm())
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := int(stat.Uid); uid != 0 && uid != os.Getuid() {
+		return fmt.Errorf("file must be owned by root or the current user, owned by uid %d", uid)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package azcli
+
+import (
+	"os"
+)
+
+// checkTokenFileOwnership is a no-op on Windows, where Unix ownership and permission bits do not apply
+func checkTokenFileOwnership(_ os.FileInfo) error {
+	return nil
+}
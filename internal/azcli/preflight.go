@@ -0,0 +1,185 @@
+package azcli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// authCheckTTL is how long a successful authentication check is trusted before az is asked again.
+// unauthenticatedCheckTTL is shorter so a login made outside the server is picked up quickly.
+const (
+	authCheckTTL            = 5 * time.Minute
+	unauthenticatedCheckTTL = 30 * time.Second
+)
+
+// authExemptCommands run without an authenticated account, so the preflight never blocks them
+var authExemptCommands = []string{
+	"login",
+	"logout",
+	"version",
+	"cloud",
+	"config",
+	"extension",
+	"account clear",
+}
+
+// nonInteractiveLoginFlags make az login authenticate without prompting; any other az login
+// falls back to the browser or device code flow, which blocks until someone completes it
+var nonInteractiveLoginFlags = []string{"--service-principal", "--identity", "--federated-token"}
+
+// AuthMethod describes how to enable one of the authentication methods the server supports
+type AuthMethod struct {
+	Method string `json:"method"`
+	// EnvVars must all be set for the method to be used; Flags are server flags the method needs
+	EnvVars []string `json:"envVars,omitempty"`
+	Flags   []string `json:"flags,omitempty"`
+	// Missing lists the required environment variables that are not set in the server's environment
+	Missing []string `json:"missing,omitempty"`
+	Notes   string   `json:"notes"`
+}
+
+// AuthRequiredError is returned instead of running an az command when the az CLI has no usable credentials
+type AuthRequiredError struct {
+	Command string       `json:"command"`
+	Reason  string       `json:"reason"`
+	Methods []AuthMethod `json:"methods"`
+}
+
+// Error lists each supported authentication method with the environment variables and flags enabling it
+func (e *AuthRequiredError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "az CLI is not authenticated, so 'az %s' was not run: %s\n", e.Command, e.Reason)
+	b.WriteString("Enable one of the supported authentication methods and restart the server:")
+	for _, m := range e.Methods {
+		fmt.Fprintf(&b, "\n- %s:", m.Method)
+		if len(m.EnvVars) > 0 {
+			fmt.Fprintf(&b, " set %s", strings.Join(m.EnvVars, ", "))
+		}
+		if len(m.Flags) > 0 {
+			fmt.Fprintf(&b, " pass %s", strings.Join(m.Flags, " "))
+		}
+		if len(m.Missing) > 0 {
+			fmt.Fprintf(&b, " (missing: %s)", strings.Join(m.Missing, ", "))
+		}
+		fmt.Fprintf(&b, ". %s", m.Notes)
+	}
+	return b.String()
+}
+
+// AuthGuidance returns the supported authentication methods in the order EnsureAzCliLogin tries them,
+// with the required environment variables missing from the current environment
+func AuthGuidance() []AuthMethod {
+	methods := []AuthMethod{
+		{
+			Method:  AuthTypeServicePrincipal,
+			EnvVars: []string{"AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET", "AZURE_TENANT_ID"},
+			Notes:   "Optionally set AZURE_SUBSCRIPTION_ID to select the subscription.",
+		},
+		{
+			Method:  AuthTypeFederatedToken,
+			EnvVars: []string{"AZURE_CLIENT_ID", "AZURE_TENANT_ID", "AZURE_FEDERATED_TOKEN_FILE"},
+			Notes: fmt.Sprintf("Set by AKS workload identity; token files outside %s must be allowed with --federated-token-paths.",
+				DefaultFederatedTokenPath),
+		},
+		{
+			Method:  AuthTypeUserAssignedManagedID,
+			EnvVars: []string{"AZURE_CLIENT_ID"},
+			Notes:   "Only on Azure compute with the identity assigned; AZURE_CLIENT_ID is the identity's client ID.",
+		},
+		{
+			Method:  AuthTypeSystemAssignedManagedID,
+			EnvVars: []string{"AZURE_MANAGED_IDENTITY=system"},
+			Notes:   "Only on Azure compute with a system-assigned identity.",
+		},
+		{
+			Method: AuthTypeExisting,
+			Notes: "Run az login (for example az login --use-device-code) in a terminal as the user running the server, " +
+				"or point AZURE_CONFIG_DIR at a logged-in az CLI configuration directory.",
+		},
+		{
+			Method: "session_credentials",
+			Flags:  []string{"--session-credentials"},
+			Notes:  "Each MCP session supplies its own tenant ID, client ID and federated token in request headers.",
+		},
+	}
+	for i := range methods {
+		for _, envVar := range methods[i].EnvVars {
+			name, value, hasValue := strings.Cut(envVar, "=")
+			if actual := os.Getenv(name); actual == "" || (hasValue && actual != value) {
+				methods[i].Missing = append(methods[i].Missing, name)
+			}
+		}
+	}
+	return methods
+}
+
+// authPreflight remembers whether the process-wide az CLI login was usable the last time it was checked
+type authPreflight struct {
+	mu            sync.Mutex
+	checked       time.Time
+	authenticated bool
+	reason        string
+}
+
+// defaultAuthPreflight is shared by all az executors in the process
+var defaultAuthPreflight = &authPreflight{}
+
+// check short-circuits args with an AuthRequiredError when the az CLI has no authenticated account,
+// and rejects az login invocations that would wait for an interactive browser or device code login
+func (p *authPreflight) check(proc Proc, args string) error {
+	if hasCommandPrefix(args, []string{"login"}) {
+		for _, flag := range nonInteractiveLoginFlags {
+			if strings.Contains(args, flag) {
+				return nil
+			}
+		}
+		return &AuthRequiredError{
+			Command: args,
+			Reason: "interactive az login falls back to the browser or device code flow, which blocks because the server " +
+				"cannot show the prompt; log in with a non-interactive method instead",
+			Methods: AuthGuidance(),
+		}
+	}
+	if hasCommandPrefix(args, authExemptCommands) {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ttl := authCheckTTL
+	if !p.authenticated {
+		ttl = unauthenticatedCheckTTL
+	}
+	if p.checked.IsZero() || time.Since(p.checked) > ttl {
+		out, err := proc.Run("account show --query id -o tsv")
+		p.checked = time.Now()
+		p.authenticated = err == nil && !strings.HasPrefix(strings.TrimSpace(out), "ERROR:")
+		p.reason = strings.TrimSpace(out)
+		if p.reason == "" && err != nil {
+			p.reason = err.Error()
+		}
+	}
+	if p.authenticated {
+		return nil
+	}
+	return &AuthRequiredError{Command: args, Reason: p.reason, Methods: AuthGuidance()}
+}
+
+// reset forgets the last check, so the next command checks the login again
+func (p *authPreflight) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checked = time.Time{}
+	p.authenticated = false
+}
+
+// needsAuthPreflight reports whether commands run with the process-wide login. Session credential
+// mode logs in per session and replay mode answers commands from a recording.
+func needsAuthPreflight(cfg *config.ConfigData) bool {
+	return cfg != nil && cfg.Session == nil && !cfg.SessionCredentials && !cfg.ReplayMock
+}
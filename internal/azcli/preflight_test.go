@@ -0,0 +1,117 @@
+package azcli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// accountProc answers az account show with the configured login state and records the other commands
+type accountProc struct {
+	loggedIn bool
+	checks   int
+	ran      []string
+}
+
+func (p *accountProc) Run(cmd string) (string, error) {
+	if strings.HasPrefix(cmd, "account show") {
+		p.checks++
+		if !p.loggedIn {
+			return "ERROR: Please run 'az login' to setup account.", fmt.Errorf("exit status 1")
+		}
+		return "00000000-0000-0000-0000-000000000000", nil
+	}
+	p.ran = append(p.ran, cmd)
+	return "ok", nil
+}
+
+func TestAuthPreflight_ShortCircuitsUnauthenticated(t *testing.T) {
+	t.Setenv("AZURE_CLIENT_ID", "client")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+	t.Setenv("AZURE_MANAGED_IDENTITY", "user")
+
+	preflight := &authPreflight{}
+	proc := &accountProc{}
+	err := preflight.check(proc, "aks list")
+	var authErr *AuthRequiredError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected an AuthRequiredError, got %v", err)
+	}
+	if len(proc.ran) != 0 {
+		t.Errorf("expected the command not to run, ran %v", proc.ran)
+	}
+	if !strings.Contains(authErr.Reason, "Please run 'az login'") {
+		t.Errorf("expected the az output as the reason, got %q", authErr.Reason)
+	}
+
+	missing := map[string]string{}
+	for _, m := range authErr.Methods {
+		missing[m.Method] = strings.Join(m.Missing, ",")
+	}
+	if missing[AuthTypeServicePrincipal] != "AZURE_CLIENT_SECRET,AZURE_TENANT_ID" {
+		t.Errorf("unexpected missing service principal variables %q", missing[AuthTypeServicePrincipal])
+	}
+	if missing[AuthTypeUserAssignedManagedID] != "" || missing[AuthTypeSystemAssignedManagedID] != "AZURE_MANAGED_IDENTITY" {
+		t.Errorf("unexpected missing managed identity variables %v", missing)
+	}
+	for _, want := range []string{"set AZURE_CLIENT_ID, AZURE_CLIENT_SECRET, AZURE_TENANT_ID (missing: AZURE_CLIENT_SECRET, AZURE_TENANT_ID)",
+		"pass --session-credentials", "--federated-token-paths"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got:\n%s", want, err.Error())
+		}
+	}
+
+	// The unauthenticated state is remembered, so the next command does not check again
+	if err := preflight.check(proc, "aks show -g rg -n c"); err == nil || proc.checks != 1 {
+		t.Errorf("expected a cached unauthenticated result, got %v after %d checks", err, proc.checks)
+	}
+}
+
+func TestAuthPreflight_AuthenticatedAndExempt(t *testing.T) {
+	preflight := &authPreflight{}
+	proc := &accountProc{}
+	if err := preflight.check(proc, "version"); err != nil || proc.checks != 0 {
+		t.Errorf("expected az version to skip the check, got %v after %d checks", err, proc.checks)
+	}
+	if err := preflight.check(proc, "login --identity"); err != nil {
+		t.Errorf("expected a non-interactive login to be allowed, got %v", err)
+	}
+	if err := preflight.check(proc, "login --use-device-code"); err == nil || !strings.Contains(err.Error(), "device code") {
+		t.Errorf("expected an interactive login to be rejected, got %v", err)
+	}
+
+	proc.loggedIn = true
+	for i := 0; i < 3; i++ {
+		if err := preflight.check(proc, "aks list"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if proc.checks != 1 {
+		t.Errorf("expected the login to be checked once, checked %d times", proc.checks)
+	}
+	preflight.reset()
+	if err := preflight.check(proc, "aks list"); err != nil || proc.checks != 2 {
+		t.Errorf("expected a reset to check the login again, got %v after %d checks", err, proc.checks)
+	}
+}
+
+func TestNeedsAuthPreflight(t *testing.T) {
+	cfg := config.NewConfig()
+	if !needsAuthPreflight(cfg) {
+		t.Error("expected the process-wide login to be checked")
+	}
+	cfg.ReplayMock = true
+	if needsAuthPreflight(cfg) {
+		t.Error("expected replayed commands not to be checked")
+	}
+	cfg.ReplayMock = false
+	cfg.SessionCredentials = true
+	if needsAuthPreflight(cfg) || needsAuthPreflight(nil) {
+		t.Error("expected session credentials and a nil config not to be checked")
+	}
+}
@@ -0,0 +1,144 @@
+package azcli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/Azure/aks-mcp/internal/session"
+)
+
+// sessionState holds the az CLI state isolated to a single session.
+// mu serializes login and setup for the session without blocking other sessions.
+type sessionState struct {
+	mu              sync.Mutex
+	sessionID       string
+	configDir       string
+	cache           *OutputCache
+	federatedToken  string
+	lastUsed        time.Time
+	removed         bool
+	loggedInToCloud string
+}
+
+var (
+	// sessionMu guards sessionStates only; it is never held while az runs
+	sessionMu     sync.Mutex
+	sessionStates = make(map[string]*sessionState)
+)
+
+// sessionConfigRoot is the parent directory of per-session az CLI configuration directories
+var sessionConfigRoot = filepath.Join(os.TempDir(), "aks-mcp-sessions")
+
+// prepareSessionProc binds proc to the session's isolated az CLI configuration directory,
+// selecting cloudName and logging in with the session's federated token on first use or
+// whenever the session supplies a new federated token.
+// It returns the proc and the output cache to use for the session.
+func prepareSessionProc(proc Proc, cred *session.Credential, cloudName string) (Proc, *OutputCache, error) {
+	if !cred.SupportsAzCli() {
+		return nil, nil, fmt.Errorf("az CLI commands require session az credentials: supply %s, %s and %s headers",
+			session.HeaderTenantID, session.HeaderClientID, session.HeaderFederatedToken)
+	}
+
+	// The IDs and token are formatted into the login command, so anything but a GUID or JWT is refused
+	if err := cred.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	state, err := lookupSessionState(cred)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.removed {
+		return nil, nil, fmt.Errorf("session was closed while the command was starting, please retry")
+	}
+
+	if shell, ok := proc.(*command.ShellProcess); ok {
+		sessionShell := *shell
+		sessionShell.Env = append(append([]string{}, shell.Env...), "AZURE_CONFIG_DIR="+state.configDir)
+		proc = &sessionShell
+	}
+
+	if state.loggedInToCloud != cloudName || state.federatedToken != cred.FederatedToken {
+		if err := setCloud(proc, cloudName); err != nil {
+			return nil, nil, err
+		}
+		loginCmd := fmt.Sprintf("login --service-principal -u %s --tenant %s --federated-token %s --allow-no-subscriptions",
+			cred.ClientID, cred.TenantID, cred.FederatedToken)
+		if err := runLoginCommand(proc, loginCmd, "session federated token"); err != nil {
+			return nil, nil, err
+		}
+		state.loggedInToCloud = cloudName
+		state.federatedToken = cred.FederatedToken
+		state.cache.Clear()
+	}
+
+	return proc, state.cache, nil
+}
+
+// lookupSessionState returns the state for the credential's session, creating it on first use
+func lookupSessionState(cred *session.Credential) (*sessionState, error) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+
+	key := cred.Key()
+	if state, ok := sessionStates[key]; ok {
+		state.lastUsed = time.Now()
+		return state, nil
+	}
+
+	dir := filepath.Join(sessionConfigRoot, key)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session az config directory: %w", err)
+	}
+	state := &sessionState{
+		sessionID: cred.SessionID,
+		configDir: dir,
+		cache:     NewOutputCache(),
+		lastUsed:  time.Now(),
+	}
+	sessionStates[key] = state
+	return state, nil
+}
+
+// ReleaseSession removes the az CLI state of a closed session, including its configuration
+// directory and the tokens az stored there
+func ReleaseSession(sessionID string) {
+	removeSessionStates(func(state *sessionState) bool { return state.sessionID == sessionID })
+}
+
+// SweepSessions removes the az CLI state of sessions idle for longer than idleTimeout
+func SweepSessions(idleTimeout time.Duration) {
+	cutoff := time.Now().Add(-idleTimeout)
+	removeSessionStates(func(state *sessionState) bool { return state.lastUsed.Before(cutoff) })
+}
+
+// removeSessionStates drops matching session states and deletes their configuration directories
+func removeSessionStates(match func(*sessionState) bool) {
+	var removed []*sessionState
+	sessionMu.Lock()
+	for key, state := range sessionStates {
+		if match(state) {
+			delete(sessionStates, key)
+			removed = append(removed, state)
+		}
+	}
+	sessionMu.Unlock()
+
+	for _, state := range removed {
+		// Wait for any in-flight login to finish before deleting the directory it writes to
+		state.mu.Lock()
+		state.removed = true
+		if err := os.RemoveAll(state.configDir); err != nil {
+			log.Printf("[AZCLI] Failed to remove session az config directory %s: %v", state.configDir, err)
+		}
+		state.mu.Unlock()
+	}
+}
@@ -0,0 +1,112 @@
+package azcli
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/session"
+)
+
+func newSessionTestCred(sessionID, accessToken, federatedToken string) *session.Credential {
+	return &session.Credential{
+		SessionID:      sessionID,
+		AccessToken:    accessToken,
+		TenantID:       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+		ClientID:       "00000000-0000-0000-0000-000000000001",
+		FederatedToken: federatedToken,
+	}
+}
+
+func countLogins(proc *countingProc) int {
+	logins := 0
+	for cmd, n := range proc.calls {
+		if strings.HasPrefix(cmd, "login ") {
+			logins += n
+		}
+	}
+	return logins
+}
+
+func TestPrepareSessionProc_ReusesStateAcrossTokenRefresh(t *testing.T) {
+	sessionConfigRoot = t.TempDir()
+	proc := &countingProc{}
+
+	_, cacheA, err := prepareSessionProc(proc, newSessionTestCred("s1", "token-1", "fed-1"), "AzureCloud")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, cacheB, err := prepareSessionProc(proc, newSessionTestCred("s1", "token-2", "fed-1"), "AzureCloud")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cacheA != cacheB {
+		t.Error("Expected an access token refresh to reuse the session state")
+	}
+	if countLogins(proc) != 1 {
+		t.Errorf("Expected a single login, got %d", countLogins(proc))
+	}
+
+	if _, _, err := prepareSessionProc(proc, newSessionTestCred("s1", "token-2", "fed-2"), "AzureCloud"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if countLogins(proc) != 2 {
+		t.Errorf("Expected a new federated token to log in again, got %d logins", countLogins(proc))
+	}
+
+	ReleaseSession("s1")
+}
+
+func TestPrepareSessionProc_RejectsInvalidIdentity(t *testing.T) {
+	sessionConfigRoot = t.TempDir()
+	proc := &countingProc{}
+
+	for name, modify := range map[string]func(*session.Credential){
+		"client":          func(c *session.Credential) { c.ClientID = "x --password secret" },
+		"tenant":          func(c *session.Credential) { c.TenantID = "contoso --allow-no-subscriptions" },
+		"federated token": func(c *session.Credential) { c.FederatedToken = "a.b.c --debug" },
+	} {
+		cred := newSessionTestCred("bad", "t", "f")
+		modify(cred)
+		if _, _, err := prepareSessionProc(proc, cred, "AzureCloud"); err == nil {
+			t.Errorf("%s: expected an invalid identity to be rejected", name)
+		}
+	}
+	if len(proc.calls) != 0 {
+		t.Errorf("Expected no az commands, got %v", proc.calls)
+	}
+}
+
+func TestReleaseAndSweepSessions_RemoveConfigDirectories(t *testing.T) {
+	sessionConfigRoot = t.TempDir()
+	proc := &countingProc{}
+
+	closed := newSessionTestCred("closed", "t", "f")
+	idle := newSessionTestCred("idle", "t", "f")
+	for _, cred := range []*session.Credential{closed, idle} {
+		if _, _, err := prepareSessionProc(proc, cred, "AzureCloud"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	closedDir := sessionStates[closed.Key()].configDir
+	idleDir := sessionStates[idle.Key()].configDir
+
+	ReleaseSession("closed")
+	if _, err := os.Stat(closedDir); !os.IsNotExist(err) {
+		t.Error("Expected closed session directory to be removed")
+	}
+	if _, ok := sessionStates[closed.Key()]; ok {
+		t.Error("Expected closed session state to be removed")
+	}
+
+	SweepSessions(time.Hour)
+	if _, ok := sessionStates[idle.Key()]; !ok {
+		t.Fatal("Expected recently used session to survive the sweep")
+	}
+	sessionStates[idle.Key()].lastUsed = time.Now().Add(-2 * time.Hour)
+	SweepSessions(time.Hour)
+	if _, err := os.Stat(idleDir); !os.IsNotExist(err) {
+		t.Error("Expected idle session directory to be removed")
+	}
+}
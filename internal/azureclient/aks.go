@@ -0,0 +1,86 @@
+package azureclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+// ListAKSClusters lists the AKS clusters of a subscription, or of one resource group when resourceGroup is set.
+func (c *AzureClient) ListAKSClusters(ctx context.Context, subscriptionID, resourceGroup string) ([]*armcontainerservice.ManagedCluster, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters []*armcontainerservice.ManagedCluster
+	if resourceGroup != "" {
+		pager := clients.ContainerServiceClient.NewListByResourceGroupPager(resourceGroup, nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list AKS clusters: %v", err)
+			}
+			clusters = append(clusters, page.Value...)
+		}
+		return clusters, nil
+	}
+
+	pager := clients.ContainerServiceClient.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list AKS clusters: %v", err)
+		}
+		clusters = append(clusters, page.Value...)
+	}
+	return clusters, nil
+}
+
+// GetAKSUpgradeProfile retrieves the control plane and node pool versions a cluster can upgrade to.
+func (c *AzureClient) GetAKSUpgradeProfile(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedClusterUpgradeProfile, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := clients.ContainerServiceClient.GetUpgradeProfile(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AKS upgrade profile: %v", err)
+	}
+	return &resp.ManagedClusterUpgradeProfile, nil
+}
+
+// ListAgentPools lists the node pools of an AKS cluster.
+func (c *AzureClient) ListAgentPools(ctx context.Context, subscriptionID, resourceGroup, clusterName string) ([]*armcontainerservice.AgentPool, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pools []*armcontainerservice.AgentPool
+	pager := clients.AgentPoolsClient.NewListPager(resourceGroup, clusterName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list node pools: %v", err)
+		}
+		pools = append(pools, page.Value...)
+	}
+	return pools, nil
+}
+
+// GetAgentPool retrieves a single node pool of an AKS cluster.
+func (c *AzureClient) GetAgentPool(ctx context.Context, subscriptionID, resourceGroup, clusterName, agentPoolName string) (*armcontainerservice.AgentPool, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := clients.AgentPoolsClient.Get(ctx, resourceGroup, clusterName, agentPoolName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node pool: %v", err)
+	}
+	return &resp.AgentPool, nil
+}
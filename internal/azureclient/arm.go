@@ -0,0 +1,139 @@
+package azureclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// MakeARMAPICall sends an authenticated request to the Azure Resource Manager API
+func (c *AzureClient) MakeARMAPICall(ctx context.Context, method, url string) (*http.Response, error) {
+	return c.makeARMRequest(ctx, method, url, nil)
+}
+
+// makeARMRequest sends an authenticated request with an optional JSON body. Write requests of review clients
+// are deferred to their review plan instead.
+func (c *AzureClient) makeARMRequest(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	if c.review != nil && review.IsARMWrite(method, url) {
+		var data []byte
+		if body != nil {
+			var err error
+			if data, err = io.ReadAll(body); err != nil {
+				return nil, fmt.Errorf("failed to read request body: %v", err)
+			}
+		}
+		return nil, c.review.Defer(review.ARMChange(method, url, data))
+	}
+
+	// Create HTTP client with Azure authentication, bounded by the client timeout including the body read
+	client := &http.Client{Timeout: c.timeout}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Get access token for the request
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{c.Cloud().ResourceManagerScope()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %v", err)
+	}
+
+	// Set headers
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AKS-MCP")
+
+	// Make the request
+	resp, err := client.Do(req)
+	if err != nil {
+		c.explain.Record(explain.KindARM, explain.ARMCommand(method, url), err)
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+	var statusErr error
+	if resp.StatusCode >= http.StatusBadRequest {
+		statusErr = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	c.explain.Record(explain.KindARM, explain.ARMCommand(method, url), statusErr)
+
+	return resp, nil
+}
+
+// CallARM sends a request for an ARM path (or an absolute nextLink URL) and returns the response body.
+// Any 2xx status is treated as success.
+func (c *AzureClient) CallARM(ctx context.Context, method, path string) ([]byte, error) {
+	return c.CallARMWithBody(ctx, method, path, nil)
+}
+
+// CallARMWithBody is CallARM with a request body marshalled as JSON. A nil body sends no body.
+func (c *AzureClient) CallARMWithBody(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	body, _, err := c.callARM(ctx, method, path, payload)
+	return body, err
+}
+
+// CallARMWithHeaders is CallARM that also returns the response headers, such as the
+// x-ms-ratelimit-remaining-* request quota counters
+func (c *AzureClient) CallARMWithHeaders(ctx context.Context, method, path string) ([]byte, http.Header, error) {
+	return c.callARM(ctx, method, path, nil)
+}
+
+// callARM sends a request with an optional JSON body and returns the response body and headers
+func (c *AzureClient) callARM(ctx context.Context, method, path string, payload interface{}) ([]byte, http.Header, error) {
+	url := path
+	if !strings.HasPrefix(path, "https://") {
+		url = c.Cloud().ResourceManagerURL(path)
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	resp, err := c.makeARMRequest(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Warning: failed to close response body: %v", err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, resp.Header, armAPIError(resp.StatusCode, body)
+	}
+	return body, resp.Header, nil
+}
+
+// armAPIError formats an ARM error response, preferring the error message from the body
+func armAPIError(statusCode int, body []byte) error {
+	var errorMsg map[string]interface{}
+	if err := json.Unmarshal(body, &errorMsg); err == nil {
+		if msg, ok := errorMsg["error"].(map[string]interface{}); ok {
+			if message, ok := msg["message"].(string); ok {
+				return fmt.Errorf("API error (%d): %s", statusCode, message)
+			}
+		}
+	}
+	return fmt.Errorf("API error (%d): %s", statusCode, string(body))
+}
@@ -4,10 +4,19 @@ package azureclient
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"sync"
+	"time"
 
+	"github.com/Azure/aks-mcp/internal/cloudenv"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
@@ -19,6 +28,7 @@ import (
 type SubscriptionClients struct {
 	SubscriptionID           string
 	ContainerServiceClient   *armcontainerservice.ManagedClustersClient
+	AgentPoolsClient         *armcontainerservice.AgentPoolsClient
 	VNetClient               *armnetwork.VirtualNetworksClient
 	SubnetsClient            *armnetwork.SubnetsClient
 	RouteTableClient         *armnetwork.RouteTablesClient
@@ -37,15 +47,29 @@ type AzureClient struct {
 	// Mutex to ensure thread safety when accessing the map
 	mu sync.RWMutex
 	// Shared credential for all clients
-	credential *azidentity.DefaultAzureCredential
+	credential azcore.TokenCredential
 	// Cache for Azure resources
 	cache *AzureCache
+	// Session-scoped clients keyed by session credential key (session credential mode only)
+	sessionClients map[string]*sessionClientEntry
+	// Azure cloud environment the clients talk to
+	cloud *cloudenv.Environment
+	// Records ARM requests of the current tool call (explain clients only)
+	explain *explain.Trace
+	// Collects ARM write requests of the current tool call instead of sending them (review clients only)
+	review *review.Plan
+	// Bound on each ARM request (zero means none)
+	timeout time.Duration
 }
 
 // NewAzureClient creates a new Azure client using default credentials and the provided configuration.
 func NewAzureClient(cfg *config.ConfigData) (*AzureClient, error) {
-	// Create a credential using DefaultAzureCredential
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	env := cfg.CloudEnvironment()
+
+	// Create a credential using DefaultAzureCredential against the configured cloud
+	cred, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+		ClientOptions: policy.ClientOptions{Cloud: env.Configuration()},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create credential: %v", err)
 	}
@@ -54,9 +78,214 @@ func NewAzureClient(cfg *config.ConfigData) (*AzureClient, error) {
 		clientsMap: make(map[string]*SubscriptionClients),
 		credential: cred,
 		cache:      NewAzureCache(cfg.CacheTimeout),
+		cloud:      env,
+		timeout:    time.Duration(cfg.Timeout) * time.Second,
 	}, nil
 }
 
+// sessionCredential lets a session client pick up refreshed access tokens without rebuilding its SDK clients
+type sessionCredential struct {
+	mu   sync.RWMutex
+	cred *session.Credential
+}
+
+// GetToken implements azcore.TokenCredential with the most recent token supplied by the session
+func (s *sessionCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	s.mu.RLock()
+	cred := s.cred
+	s.mu.RUnlock()
+	return cred.GetToken(ctx, opts)
+}
+
+// set replaces the session credential
+func (s *sessionCredential) set(cred *session.Credential) {
+	s.mu.Lock()
+	s.cred = cred
+	s.mu.Unlock()
+}
+
+// sessionClientEntry is a session-scoped client with the bookkeeping needed to evict it
+type sessionClientEntry struct {
+	client     *AzureClient
+	credential *sessionCredential
+	sessionID  string
+	lastUsed   time.Time
+}
+
+// ForSession returns an Azure client that authenticates with the given session credential.
+// Session clients never share SDK clients or cached resources with the process-wide client
+// or with each other. A nil credential returns the receiver unchanged.
+func (c *AzureClient) ForSession(cred *session.Credential) (*AzureClient, error) {
+	if cred == nil {
+		return c, nil
+	}
+	if cred.Expired() {
+		return nil, fmt.Errorf("session access token has expired")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessionClients == nil {
+		c.sessionClients = make(map[string]*sessionClientEntry)
+	}
+
+	key := cred.Key()
+	if entry, ok := c.sessionClients[key]; ok {
+		entry.credential.set(cred)
+		entry.lastUsed = time.Now()
+		return entry.client, nil
+	}
+
+	credential := &sessionCredential{cred: cred}
+	client := &AzureClient{
+		clientsMap: make(map[string]*SubscriptionClients),
+		credential: credential,
+		cache:      NewAzureCache(c.cache.defaultTimeout),
+		cloud:      c.cloud,
+		timeout:    c.timeout,
+	}
+	c.sessionClients[key] = &sessionClientEntry{
+		client:     client,
+		credential: credential,
+		sessionID:  cred.SessionID,
+		lastUsed:   time.Now(),
+	}
+	return client, nil
+}
+
+// ForExplain returns an Azure client that records its ARM requests in the given trace.
+// It shares the receiver's credential and resource cache but builds its own SDK clients,
+// so the recording policy never reaches other tool calls. A nil trace returns the receiver unchanged.
+func (c *AzureClient) ForExplain(trace *explain.Trace) *AzureClient {
+	if trace == nil || c == nil {
+		return c
+	}
+	return &AzureClient{
+		clientsMap: make(map[string]*SubscriptionClients),
+		credential: c.credential,
+		cache:      c.cache,
+		cloud:      c.cloud,
+		explain:    trace,
+		review:     c.review,
+		timeout:    c.timeout,
+	}
+}
+
+// ForReview returns an Azure client that defers its ARM write requests to the given review plan instead
+// of sending them. Like ForExplain it builds its own SDK clients. A nil plan returns the receiver unchanged.
+func (c *AzureClient) ForReview(plan *review.Plan) *AzureClient {
+	if plan == nil || c == nil {
+		return c
+	}
+	return &AzureClient{
+		clientsMap: make(map[string]*SubscriptionClients),
+		credential: c.credential,
+		cache:      c.cache,
+		cloud:      c.cloud,
+		explain:    c.explain,
+		review:     plan,
+		timeout:    c.timeout,
+	}
+}
+
+// ForTimeout returns an Azure client whose ARM requests time out after the given seconds.
+// Like ForExplain it shares the receiver's credential, cache and trace but builds its own SDK clients.
+// The receiver is returned when it already uses that timeout.
+func (c *AzureClient) ForTimeout(seconds int) *AzureClient {
+	timeout := time.Duration(seconds) * time.Second
+	if c == nil || c.timeout == timeout {
+		return c
+	}
+	return &AzureClient{
+		clientsMap: make(map[string]*SubscriptionClients),
+		credential: c.credential,
+		cache:      c.cache,
+		cloud:      c.cloud,
+		explain:    c.explain,
+		review:     c.review,
+		timeout:    timeout,
+	}
+}
+
+// ReleaseSession drops the clients and cached resources of a closed session
+func (c *AzureClient) ReleaseSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.sessionClients {
+		if entry.sessionID == sessionID {
+			delete(c.sessionClients, key)
+		}
+	}
+}
+
+// SweepSessions drops the clients of sessions idle for longer than idleTimeout
+func (c *AzureClient) SweepSessions(idleTimeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-idleTimeout)
+	for key, entry := range c.sessionClients {
+		if entry.lastUsed.Before(cutoff) {
+			delete(c.sessionClients, key)
+		}
+	}
+}
+
+// Cloud returns the Azure cloud environment used by the client
+func (c *AzureClient) Cloud() *cloudenv.Environment {
+	if c.cloud == nil {
+		return cloudenv.Public()
+	}
+	return c.cloud
+}
+
+// armClientOptions returns the SDK client options that target the client's cloud
+func (c *AzureClient) armClientOptions() *arm.ClientOptions {
+	options := &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{Cloud: c.Cloud().Configuration()},
+	}
+	if c.explain != nil {
+		options.PerCallPolicies = append(options.PerCallPolicies, explainPolicy{trace: c.explain})
+	}
+	if c.review != nil {
+		options.PerCallPolicies = append(options.PerCallPolicies, reviewPolicy{plan: c.review})
+	}
+	if c.timeout > 0 {
+		options.PerCallPolicies = append(options.PerCallPolicies, timeoutPolicy{timeout: c.timeout})
+	}
+	return options
+}
+
+// timeoutPolicy bounds each request made by SDK clients, including its retries
+type timeoutPolicy struct {
+	timeout time.Duration
+}
+
+// Do implements policy.Policy
+func (p timeoutPolicy) Do(req *policy.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Raw().Context(), p.timeout)
+	defer cancel()
+	// The response body has been read by the download policy before the context is cancelled
+	return req.WithContext(ctx).Next()
+}
+
+// explainPolicy records the requests made by SDK clients
+type explainPolicy struct {
+	trace *explain.Trace
+}
+
+// Do implements policy.Policy
+func (p explainPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	p.trace.Record(explain.KindARM, explain.ARMCommand(req.Raw().Method, req.Raw().URL.String()), err)
+	return resp, err
+}
+
 // GetOrCreateClientsForSubscription gets existing clients for a subscription or creates new ones.
 func (c *AzureClient) GetOrCreateClientsForSubscription(subscriptionID string) (*SubscriptionClients, error) {
 	// First try to get existing clients with a read lock
@@ -78,52 +307,57 @@ func (c *AzureClient) GetOrCreateClientsForSubscription(subscriptionID string) (
 	}
 
 	// Create new clients for this subscription
-	containerServiceClient, err := armcontainerservice.NewManagedClustersClient(subscriptionID, c.credential, nil)
+	containerServiceClient, err := armcontainerservice.NewManagedClustersClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container service client for subscription %s: %v", subscriptionID, err)
 	}
 
-	vnetClient, err := armnetwork.NewVirtualNetworksClient(subscriptionID, c.credential, nil)
+	agentPoolsClient, err := armcontainerservice.NewAgentPoolsClient(subscriptionID, c.credential, c.armClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent pools client for subscription %s: %v", subscriptionID, err)
+	}
+
+	vnetClient, err := armnetwork.NewVirtualNetworksClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create virtual network client for subscription %s: %v", subscriptionID, err)
 	}
 
-	routeTableClient, err := armnetwork.NewRouteTablesClient(subscriptionID, c.credential, nil)
+	routeTableClient, err := armnetwork.NewRouteTablesClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create route table client for subscription %s: %v", subscriptionID, err)
 	}
 
-	nsgClient, err := armnetwork.NewSecurityGroupsClient(subscriptionID, c.credential, nil)
+	nsgClient, err := armnetwork.NewSecurityGroupsClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create network security group client for subscription %s: %v", subscriptionID, err)
 	}
 
-	subnetsClient, err := armnetwork.NewSubnetsClient(subscriptionID, c.credential, nil)
+	subnetsClient, err := armnetwork.NewSubnetsClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create subnets client for subscription %s: %v", subscriptionID, err)
 	}
 
-	loadBalancerClient, err := armnetwork.NewLoadBalancersClient(subscriptionID, c.credential, nil)
+	loadBalancerClient, err := armnetwork.NewLoadBalancersClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create load balancer client for subscription %s: %v", subscriptionID, err)
 	}
 
-	privateEndpointsClient, err := armnetwork.NewPrivateEndpointsClient(subscriptionID, c.credential, nil)
+	privateEndpointsClient, err := armnetwork.NewPrivateEndpointsClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create private endpoints client for subscription %s: %v", subscriptionID, err)
 	}
 
-	vmssClient, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, c.credential, nil)
+	vmssClient, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create VMSS client for subscription %s: %v", subscriptionID, err)
 	}
 
-	vmssVMsClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, c.credential, nil)
+	vmssVMsClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create VMSS VMs client for subscription %s: %v", subscriptionID, err)
 	}
 
-	diagnosticSettingsClient, err := armmonitor.NewDiagnosticSettingsClient(c.credential, nil)
+	diagnosticSettingsClient, err := armmonitor.NewDiagnosticSettingsClient(c.credential, c.armClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create diagnostic settings client for subscription %s: %v", subscriptionID, err)
 	}
@@ -132,6 +366,7 @@ func (c *AzureClient) GetOrCreateClientsForSubscription(subscriptionID string) (
 	clients = &SubscriptionClients{
 		SubscriptionID:           subscriptionID,
 		ContainerServiceClient:   containerServiceClient,
+		AgentPoolsClient:         agentPoolsClient,
 		VNetClient:               vnetClient,
 		SubnetsClient:            subnetsClient,
 		RouteTableClient:         routeTableClient,
@@ -468,3 +703,24 @@ func (c *AzureClient) GetDiagnosticSettings(ctx context.Context, subscriptionID,
 
 	return diagnosticSettings, nil
 }
+
+// reviewPolicy defers the write requests made by SDK clients to a review plan instead of sending them
+type reviewPolicy struct {
+	plan *review.Plan
+}
+
+// Do implements policy.Policy
+func (p reviewPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	if !review.IsARMWrite(raw.Method, raw.URL.String()) {
+		return req.Next()
+	}
+	var body []byte
+	if req.Body() != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body()); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	return nil, p.plan.Defer(review.ARMChange(raw.Method, raw.URL.String(), body))
+}
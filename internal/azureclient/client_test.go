@@ -1,10 +1,13 @@
 package azureclient
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 )
 
 func TestNewAzureClientWithConfigurableTimeout(t *testing.T) {
@@ -40,3 +43,83 @@ func TestNewAzureClientWithConfigurableTimeout(t *testing.T) {
 		t.Errorf("Expected custom cache timeout to be 5 minutes, got %v", customClient.cache.defaultTimeout)
 	}
 }
+
+func TestForSessionIsolatesClients(t *testing.T) {
+	client, err := NewAzureClient(config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create Azure client: %v", err)
+	}
+
+	same, err := client.ForSession(nil)
+	if err != nil || same != client {
+		t.Fatalf("Expected nil session to return the shared client")
+	}
+
+	credA := &session.Credential{SessionID: "a", AccessToken: "token-a", ExpiresOn: time.Now().Add(time.Hour)}
+	credB := &session.Credential{SessionID: "b", AccessToken: "token-b", ExpiresOn: time.Now().Add(time.Hour)}
+
+	clientA, err := client.ForSession(credA)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clientB, err := client.ForSession(credB)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if clientA == client || clientB == client || clientA == clientB {
+		t.Error("Expected each session to get its own Azure client")
+	}
+	if clientA.cache == client.cache || clientA.cache == clientB.cache {
+		t.Error("Expected session clients not to share caches")
+	}
+
+	refreshed := &session.Credential{SessionID: "a", AccessToken: "token-a2", ExpiresOn: time.Now().Add(time.Hour)}
+	again, _ := client.ForSession(refreshed)
+	if again != clientA {
+		t.Error("Expected a token refresh within the session to reuse its client")
+	}
+	token, err := clientA.credential.GetToken(context.Background(), policy.TokenRequestOptions{})
+	if err != nil || token.Token != "token-a2" {
+		t.Errorf("Expected session client to use the refreshed token, got %q (%v)", token.Token, err)
+	}
+
+	client.ReleaseSession("b")
+	if len(client.sessionClients) != 1 {
+		t.Errorf("Expected released session to be evicted, got %d session clients", len(client.sessionClients))
+	}
+	client.SweepSessions(time.Hour)
+	if len(client.sessionClients) != 1 {
+		t.Error("Expected recently used session to survive the sweep")
+	}
+	client.SweepSessions(0)
+	if len(client.sessionClients) != 0 {
+		t.Errorf("Expected idle sessions to be swept, got %d session clients", len(client.sessionClients))
+	}
+
+	expired := &session.Credential{SessionID: "c", AccessToken: "token-c", ExpiresOn: time.Now().Add(-time.Minute)}
+	if _, err := client.ForSession(expired); err == nil {
+		t.Error("Expected an error for an expired session credential")
+	}
+}
+
+func TestForTimeout(t *testing.T) {
+	client, err := NewAzureClient(config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create Azure client: %v", err)
+	}
+	if client.timeout != 60*time.Second {
+		t.Errorf("Expected the configured timeout, got %v", client.timeout)
+	}
+	if client.ForTimeout(60) != client {
+		t.Error("Expected the same timeout to return the receiver")
+	}
+
+	long := client.ForTimeout(1800)
+	if long == client || long.timeout != 30*time.Minute || long.cache != client.cache {
+		t.Errorf("Expected a client with its own timeout sharing the cache, got %+v", long)
+	}
+	if len(long.armClientOptions().PerCallPolicies) != 1 {
+		t.Error("Expected SDK clients to get the timeout policy")
+	}
+}
@@ -0,0 +1,152 @@
+package azureclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+)
+
+// maxSerialLogBytes bounds the serial console log downloaded from boot diagnostics
+const maxSerialLogBytes = 64 << 20
+
+// EnableVMSSBootDiagnostics turns on boot diagnostics with managed storage for a scale set and applies the
+// model to one of its instances, waiting for both updates to finish.
+func (c *AzureClient) EnableVMSSBootDiagnostics(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) error {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	update := armcompute.VirtualMachineScaleSetUpdate{
+		Properties: &armcompute.VirtualMachineScaleSetUpdateProperties{
+			VirtualMachineProfile: &armcompute.VirtualMachineScaleSetUpdateVMProfile{
+				DiagnosticsProfile: &armcompute.DiagnosticsProfile{
+					BootDiagnostics: &armcompute.BootDiagnostics{Enabled: to.Ptr(true)},
+				},
+			},
+		},
+	}
+	poller, err := clients.VMSSClient.BeginUpdate(ctx, resourceGroup, vmssName, update, nil)
+	if err != nil {
+		return fmt.Errorf("failed to enable boot diagnostics: %v", err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("failed to enable boot diagnostics: %v", err)
+	}
+	c.cache.Delete(fmt.Sprintf("resource:vmss:%s:%s:%s", subscriptionID, resourceGroup, vmssName))
+
+	instances := armcompute.VirtualMachineScaleSetVMInstanceRequiredIDs{InstanceIDs: []*string{to.Ptr(instanceID)}}
+	instancePoller, err := clients.VMSSClient.BeginUpdateInstances(ctx, resourceGroup, vmssName, instances, nil)
+	if err != nil {
+		return fmt.Errorf("failed to apply boot diagnostics to instance %s: %v", instanceID, err)
+	}
+	if _, err := instancePoller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("failed to apply boot diagnostics to instance %s: %v", instanceID, err)
+	}
+	return nil
+}
+
+// GetVMSSSerialLog downloads the serial console log of a scale set instance from boot diagnostics.
+// Boot diagnostics must be enabled on the instance.
+func (c *AzureClient) GetVMSSSerialLog(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (string, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := clients.VMSSVMsClient.RetrieveBootDiagnosticsData(ctx, resourceGroup, vmssName, instanceID,
+		&armcompute.VirtualMachineScaleSetVMsClientRetrieveBootDiagnosticsDataOptions{SasURIExpirationTimeInMinutes: to.Ptr[int32](5)})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve boot diagnostics data: %v", err)
+	}
+	if resp.SerialConsoleLogBlobURI == nil || *resp.SerialConsoleLogBlobURI == "" {
+		return "", fmt.Errorf("no serial console log is available for instance %s", instanceID)
+	}
+
+	// The blob URI carries a SAS token, so it is fetched without ARM authentication
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *resp.SerialConsoleLogBlobURI, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	httpResp, err := (&http.Client{Timeout: c.timeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download serial console log: %v", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("failed to download serial console log: status %d", httpResp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxSerialLogBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read serial console log: %v", err)
+	}
+	return string(body), nil
+}
+
+// GetVMSSInstanceView reads the instance view of a scale set instance, including the provisioning state and the
+// status of each VM extension
+func (c *AzureClient) GetVMSSInstanceView(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (*armcompute.VirtualMachineScaleSetVMInstanceView, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := clients.VMSSVMsClient.GetInstanceView(ctx, resourceGroup, vmssName, instanceID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance view of %s instance %s: %v", vmssName, instanceID, err)
+	}
+	return &resp.VirtualMachineScaleSetVMInstanceView, nil
+}
+
+// GetVMSSInstance reads a scale set instance, including its OS profile with the computer name
+func (c *AzureClient) GetVMSSInstance(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := clients.VMSSVMsClient.Get(ctx, resourceGroup, vmssName, instanceID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s instance %s: %v", vmssName, instanceID, err)
+	}
+	return &resp.VirtualMachineScaleSetVM, nil
+}
+
+// RunVMSSShellScript runs a shell script on a Linux scale set instance with the RunShellScript run command,
+// waiting for it to finish, and returns the run command output message
+func (c *AzureClient) RunVMSSShellScript(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, script string) (string, error) {
+	return c.runVMSSCommand(ctx, subscriptionID, resourceGroup, vmssName, instanceID, "RunShellScript", script)
+}
+
+// RunVMSSPowerShellScript runs a PowerShell script on a Windows scale set instance with the RunPowerShellScript
+// run command, waiting for it to finish, and returns the run command output messages
+func (c *AzureClient) RunVMSSPowerShellScript(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, script string) (string, error) {
+	return c.runVMSSCommand(ctx, subscriptionID, resourceGroup, vmssName, instanceID, "RunPowerShellScript", script)
+}
+
+// runVMSSCommand runs a script on a scale set instance with a built-in run command and joins its output messages
+func (c *AzureClient) runVMSSCommand(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, commandID, script string) (string, error) {
+	clients, err := c.GetOrCreateClientsForSubscription(subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	input := armcompute.RunCommandInput{CommandID: to.Ptr(commandID), Script: []*string{to.Ptr(script)}}
+	poller, err := clients.VMSSVMsClient.BeginRunCommand(ctx, resourceGroup, vmssName, instanceID, input, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on %s instance %s: %v", vmssName, instanceID, err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on %s instance %s: %v", vmssName, instanceID, err)
+	}
+	var messages []string
+	for _, status := range resp.Value {
+		if status != nil && status.Message != nil {
+			messages = append(messages, *status.Message)
+		}
+	}
+	return strings.Join(messages, "\n"), nil
+}
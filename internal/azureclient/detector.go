@@ -2,47 +2,16 @@ package azureclient
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
-
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 )
 
 // MakeDetectorAPICall makes an HTTP request to Azure Management API for detector operations
 func (c *AzureClient) MakeDetectorAPICall(ctx context.Context, url string, subscriptionID string) (*http.Response, error) {
-	// Create HTTP client with Azure authentication
-	client := &http.Client{}
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	// Get access token for the request
-	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{
-		Scopes: []string{"https://management.azure.com/.default"},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %v", err)
-	}
-
-	// Set headers
-	req.Header.Set("Authorization", "Bearer "+token.Token)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "AKS-MCP")
-
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
-	}
-
-	return resp, nil
+	return c.MakeARMAPICall(ctx, http.MethodGet, url)
 }
 
 // ParseResourceID extracts subscription, resource group, and cluster name from AKS resource ID
@@ -76,15 +45,7 @@ func HandleDetectorAPIResponse(resp *http.Response) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errorMsg map[string]interface{}
-		if err := json.Unmarshal(body, &errorMsg); err == nil {
-			if msg, ok := errorMsg["error"].(map[string]interface{}); ok {
-				if message, ok := msg["message"].(string); ok {
-					return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, message)
-				}
-			}
-		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, armAPIError(resp.StatusCode, body)
 	}
 
 	return body, nil
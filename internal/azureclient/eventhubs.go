@@ -0,0 +1,20 @@
+package azureclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// eventHubsScope is the Entra ID scope of Event Hubs data plane requests in every cloud
+const eventHubsScope = "https://eventhubs.azure.net/.default"
+
+// EventHubsToken returns an Entra ID access token for sending events to Azure Event Hubs with the server's credential
+func (c *AzureClient) EventHubsToken(ctx context.Context) (string, error) {
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{eventHubsScope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Event Hubs access token: %v", err)
+	}
+	return token.Token, nil
+}
@@ -0,0 +1,56 @@
+package azureclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// maxGraphBytes bounds the Microsoft Graph responses read, which are directory object lookups
+const maxGraphBytes = 16 << 20
+
+// CallGraph sends a request for a Microsoft Graph path (such as /v1.0/directoryObjects/getByIds) with a body
+// marshalled as JSON and returns the response body. The token is requested for the Graph scope of the cloud,
+// so the credential needs directory read permissions in addition to its ARM roles. A nil body sends no body.
+func (c *AzureClient) CallGraph(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Cloud().MicrosoftGraphURL(path), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{c.Cloud().MicrosoftGraphScope()}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Microsoft Graph access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AKS-MCP")
+
+	resp, err := (&http.Client{Timeout: c.timeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make Microsoft Graph request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGraphBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Microsoft Graph response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Graph errors use the same {"error": {"message": ...}} shape as ARM
+		return nil, armAPIError(resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,51 @@
+package azureclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	resourceGraphPath       = "/providers/Microsoft.ResourceGraph/resources?api-version=2021-03-01"
+	maxResourceGraphPages   = 10
+	resourceGraphPageRecord = 1000
+)
+
+// QueryResourceGraph runs an Azure Resource Graph query across the given subscriptions, or across
+// every subscription the credential can read when none are given, and returns the result rows
+func (c *AzureClient) QueryResourceGraph(ctx context.Context, query string, subscriptions []string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	skipToken := ""
+	for page := 0; page < maxResourceGraphPages; page++ {
+		request := map[string]interface{}{
+			"query":   query,
+			"options": map[string]interface{}{"resultFormat": "objectArray", "$top": resourceGraphPageRecord},
+		}
+		if len(subscriptions) > 0 {
+			request["subscriptions"] = subscriptions
+		}
+		if skipToken != "" {
+			request["options"].(map[string]interface{})["$skipToken"] = skipToken
+		}
+
+		body, err := c.CallARMWithBody(ctx, http.MethodPost, resourceGraphPath, request)
+		if err != nil {
+			return nil, fmt.Errorf("resource graph query failed: %w", err)
+		}
+		var result struct {
+			Data      []map[string]interface{} `json:"data"`
+			SkipToken string                   `json:"$skipToken"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse resource graph response: %w", err)
+		}
+		rows = append(rows, result.Data...)
+		if result.SkipToken == "" {
+			break
+		}
+		skipToken = result.SkipToken
+	}
+	return rows, nil
+}
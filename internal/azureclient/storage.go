@@ -0,0 +1,103 @@
+package azureclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	// storageScope is the Entra ID scope of Azure Storage data plane requests in every cloud
+	storageScope = "https://storage.azure.com/.default"
+	// storageAPIVersion is the Blob service REST version sent with data plane requests
+	storageAPIVersion = "2022-11-02"
+	// maxBlobServiceBytes bounds the Blob service responses read, which are listings and delegation keys
+	maxBlobServiceBytes = 16 << 20
+)
+
+// StorageError is a Blob service request that failed
+type StorageError struct {
+	StatusCode int
+	// Code is the Blob service error code, such as AuthorizationPermissionMismatch
+	Code    string
+	Message string
+}
+
+func (e *StorageError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("storage request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("storage request failed with status %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// CallBlobService sends a request authenticated with an Entra ID token to a Blob service URL and returns the
+// response body. Failed requests return a *StorageError.
+func (c *AzureClient) CallBlobService(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	return c.sendBlobRequest(req)
+}
+
+// PutBlockBlob uploads content as a block blob to a Blob service URL with an Entra ID token, replacing an existing
+// blob of the same name. Failed requests return a *StorageError.
+func (c *AzureClient) PutBlockBlob(ctx context.Context, url string, content []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-blob-content-type", contentType)
+	req.Header.Set("Content-Type", contentType)
+	_, err = c.sendBlobRequest(req)
+	return err
+}
+
+// sendBlobRequest authenticates a Blob service request with an Entra ID token, sends it and returns the response body
+func (c *AzureClient) sendBlobRequest(req *http.Request) ([]byte, error) {
+	token, err := c.credential.GetToken(req.Context(), policy.TokenRequestOptions{Scopes: []string{storageScope}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	req.Header.Set("x-ms-version", storageAPIVersion)
+	req.Header.Set("User-Agent", "AKS-MCP")
+
+	resp, err := (&http.Client{Timeout: c.timeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make storage request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobServiceBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage response: %v", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		storageErr := &StorageError{StatusCode: resp.StatusCode, Code: resp.Header.Get("x-ms-error-code")}
+		var parsed struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.Unmarshal(data, &parsed) == nil {
+			if storageErr.Code == "" {
+				storageErr.Code = parsed.Code
+			}
+			storageErr.Message = parsed.Message
+		}
+		return nil, storageErr
+	}
+	return data, nil
+}
@@ -0,0 +1,231 @@
+// Package cloudenv describes the Azure cloud environment (public, US Government or China)
+// and the service endpoints aks-mcp uses in it.
+package cloudenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// Well-known az CLI cloud names
+const (
+	AzurePublicCloud     = "AzureCloud"
+	AzureUSGovernment    = "AzureUSGovernment"
+	AzureChinaCloud      = "AzureChinaCloud"
+	metadataAPIVersion   = "2020-06-01"
+	metadataFetchTimeout = 30 * time.Second
+)
+
+// Environment holds the endpoints of an Azure cloud
+type Environment struct {
+	// Name is the az CLI cloud name (as used by az cloud set)
+	Name string
+	// ResourceManagerEndpoint is the ARM endpoint without a trailing slash
+	ResourceManagerEndpoint string
+	// ResourceManagerAudience is the token audience for ARM
+	ResourceManagerAudience string
+	// ActiveDirectoryAuthorityHost is the Microsoft Entra ID authority host
+	ActiveDirectoryAuthorityHost string
+	// AppInsightsIngestionEndpoint is the Application Insights telemetry ingestion URL
+	AppInsightsIngestionEndpoint string
+	// MicrosoftGraphEndpoint is the Microsoft Graph endpoint without a trailing slash
+	MicrosoftGraphEndpoint string
+}
+
+var knownEnvironments = map[string]Environment{
+	AzurePublicCloud: {
+		Name:                         AzurePublicCloud,
+		ResourceManagerEndpoint:      "https://management.azure.com",
+		ResourceManagerAudience:      "https://management.core.windows.net/",
+		ActiveDirectoryAuthorityHost: "https://login.microsoftonline.com/",
+		AppInsightsIngestionEndpoint: "https://dc.services.visualstudio.com/v2/track",
+		MicrosoftGraphEndpoint:       "https://graph.microsoft.com",
+	},
+	AzureUSGovernment: {
+		Name:                         AzureUSGovernment,
+		ResourceManagerEndpoint:      "https://management.usgovcloudapi.net",
+		ResourceManagerAudience:      "https://management.core.usgovcloudapi.net/",
+		ActiveDirectoryAuthorityHost: "https://login.microsoftonline.us/",
+		AppInsightsIngestionEndpoint: "https://dc.applicationinsights.us/v2/track",
+		MicrosoftGraphEndpoint:       "https://graph.microsoft.us",
+	},
+	AzureChinaCloud: {
+		Name:                         AzureChinaCloud,
+		ResourceManagerEndpoint:      "https://management.chinacloudapi.cn",
+		ResourceManagerAudience:      "https://management.core.chinacloudapi.cn/",
+		ActiveDirectoryAuthorityHost: "https://login.chinacloudapi.cn/",
+		AppInsightsIngestionEndpoint: "https://dc.applicationinsights.azure.cn/v2/track",
+		MicrosoftGraphEndpoint:       "https://microsoftgraph.chinacloudapi.cn",
+	},
+}
+
+// cloudAliases maps accepted user input to az CLI cloud names
+var cloudAliases = map[string]string{
+	"azurecloud":        AzurePublicCloud,
+	"public":            AzurePublicCloud,
+	"azurepubliccloud":  AzurePublicCloud,
+	"azureusgovernment": AzureUSGovernment,
+	"usgovernment":      AzureUSGovernment,
+	"usgov":             AzureUSGovernment,
+	"azurechinacloud":   AzureChinaCloud,
+	"china":             AzureChinaCloud,
+}
+
+// Public returns the public Azure cloud environment
+func Public() *Environment {
+	env := knownEnvironments[AzurePublicCloud]
+	return &env
+}
+
+// Lookup returns the well-known environment for a cloud name or alias (case-insensitive)
+func Lookup(name string) (*Environment, bool) {
+	canonical, ok := cloudAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, false
+	}
+	env := knownEnvironments[canonical]
+	return &env, true
+}
+
+// Resolve returns the environment for a cloud name, alias or ARM endpoint URL.
+// An empty value selects the public cloud. ARM endpoint URLs are resolved through
+// the ARM metadata endpoint.
+func Resolve(value string) (*Environment, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Public(), nil
+	}
+	if env, ok := Lookup(value); ok {
+		return env, nil
+	}
+	if strings.HasPrefix(strings.ToLower(value), "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), metadataFetchTimeout)
+		defer cancel()
+		return Discover(ctx, http.DefaultClient, value)
+	}
+	return nil, fmt.Errorf("unknown cloud '%s': expected %s, %s, %s or an ARM endpoint URL",
+		value, AzurePublicCloud, AzureUSGovernment, AzureChinaCloud)
+}
+
+// armMetadata is the subset of the ARM metadata/endpoints response used by aks-mcp
+type armMetadata struct {
+	Name                     string `json:"name"`
+	ResourceManager          string `json:"resourceManager"`
+	MicrosoftGraphResourceID string `json:"microsoftGraphResourceId"`
+	Authentication           struct {
+		LoginEndpoint string   `json:"loginEndpoint"`
+		Audiences     []string `json:"audiences"`
+	} `json:"authentication"`
+}
+
+// Discover fetches the cloud endpoints from the ARM metadata endpoint of armEndpoint.
+// Endpoints of well-known clouds fill in values the metadata does not describe.
+func Discover(ctx context.Context, client *http.Client, armEndpoint string) (*Environment, error) {
+	armEndpoint = strings.TrimRight(strings.TrimSpace(armEndpoint), "/")
+	metadataURL := fmt.Sprintf("%s/metadata/endpoints?api-version=%s", armEndpoint, metadataAPIVersion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud metadata request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cloud metadata from %s: %w", metadataURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud metadata: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloud metadata request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var metadata armMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud metadata: %w", err)
+	}
+	if metadata.Authentication.LoginEndpoint == "" || len(metadata.Authentication.Audiences) == 0 {
+		return nil, fmt.Errorf("cloud metadata from %s is missing authentication endpoints", metadataURL)
+	}
+
+	env := &Environment{}
+	if known, ok := Lookup(metadata.Name); ok {
+		env = known
+	}
+	env.Name = metadata.Name
+	env.ResourceManagerEndpoint = armEndpoint
+	if metadata.ResourceManager != "" {
+		env.ResourceManagerEndpoint = strings.TrimRight(metadata.ResourceManager, "/")
+	}
+	if metadata.MicrosoftGraphResourceID != "" {
+		env.MicrosoftGraphEndpoint = strings.TrimRight(metadata.MicrosoftGraphResourceID, "/")
+	}
+	env.ResourceManagerAudience = metadata.Authentication.Audiences[0]
+	env.ActiveDirectoryAuthorityHost = metadata.Authentication.LoginEndpoint
+	if !strings.HasSuffix(env.ActiveDirectoryAuthorityHost, "/") {
+		env.ActiveDirectoryAuthorityHost += "/"
+	}
+	return env, nil
+}
+
+// IsPublic reports whether the environment is the public Azure cloud
+func (e *Environment) IsPublic() bool {
+	return e == nil || e.Name == AzurePublicCloud
+}
+
+// Configuration returns the Azure SDK cloud configuration for the environment
+func (e *Environment) Configuration() cloud.Configuration {
+	if e == nil {
+		return cloud.AzurePublic
+	}
+	return cloud.Configuration{
+		ActiveDirectoryAuthorityHost: e.ActiveDirectoryAuthorityHost,
+		Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {
+				Audience: e.ResourceManagerAudience,
+				Endpoint: e.ResourceManagerEndpoint,
+			},
+		},
+	}
+}
+
+// ResourceManagerScope returns the OAuth scope for ARM requests
+func (e *Environment) ResourceManagerScope() string {
+	if e == nil {
+		return Public().ResourceManagerScope()
+	}
+	return strings.TrimRight(e.ResourceManagerAudience, "/") + "/.default"
+}
+
+// MicrosoftGraphScope returns the OAuth scope for Microsoft Graph requests
+func (e *Environment) MicrosoftGraphScope() string {
+	if e == nil || e.MicrosoftGraphEndpoint == "" {
+		return Public().MicrosoftGraphScope()
+	}
+	return e.MicrosoftGraphEndpoint + "/.default"
+}
+
+// MicrosoftGraphURL joins a Graph path (starting with "/") onto the Graph endpoint
+func (e *Environment) MicrosoftGraphURL(path string) string {
+	if e == nil || e.MicrosoftGraphEndpoint == "" {
+		return Public().MicrosoftGraphURL(path)
+	}
+	return e.MicrosoftGraphEndpoint + path
+}
+
+// ResourceManagerURL joins an ARM path (starting with "/") onto the ARM endpoint
+func (e *Environment) ResourceManagerURL(path string) string {
+	if e == nil {
+		return Public().ResourceManagerURL(path)
+	}
+	return e.ResourceManagerEndpoint + path
+}
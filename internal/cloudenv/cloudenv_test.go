@@ -0,0 +1,111 @@
+package cloudenv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", AzurePublicCloud},
+		{"AzureCloud", AzurePublicCloud},
+		{"usgov", AzureUSGovernment},
+		{"AzureUSGovernment", AzureUSGovernment},
+		{" azurechinacloud ", AzureChinaCloud},
+		{"china", AzureChinaCloud},
+	}
+	for _, tt := range tests {
+		env, err := Resolve(tt.input)
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", tt.input, err)
+		}
+		if env.Name != tt.want {
+			t.Errorf("Resolve(%q) = %s, want %s", tt.input, env.Name, tt.want)
+		}
+	}
+
+	if _, err := Resolve("AzureGermanCloud"); err == nil {
+		t.Error("Expected error for unknown cloud name")
+	}
+}
+
+func TestEnvironmentEndpoints(t *testing.T) {
+	gov, _ := Lookup("usgov")
+	if gov.IsPublic() {
+		t.Error("Expected US Government cloud not to be public")
+	}
+	if got := gov.ResourceManagerScope(); got != "https://management.core.usgovcloudapi.net/.default" {
+		t.Errorf("Unexpected ARM scope: %s", got)
+	}
+	if got := gov.ResourceManagerURL("/subscriptions/sub"); got != "https://management.usgovcloudapi.net/subscriptions/sub" {
+		t.Errorf("Unexpected ARM URL: %s", got)
+	}
+
+	if got := gov.MicrosoftGraphScope(); got != "https://graph.microsoft.us/.default" {
+		t.Errorf("Unexpected Graph scope: %s", got)
+	}
+	if got := gov.MicrosoftGraphURL("/v1.0/directoryObjects/getByIds"); got != "https://graph.microsoft.us/v1.0/directoryObjects/getByIds" {
+		t.Errorf("Unexpected Graph URL: %s", got)
+	}
+
+	conf := gov.Configuration()
+	if conf.ActiveDirectoryAuthorityHost != "https://login.microsoftonline.us/" {
+		t.Errorf("Unexpected authority host: %s", conf.ActiveDirectoryAuthorityHost)
+	}
+	if conf.Services[cloud.ResourceManager].Endpoint != "https://management.usgovcloudapi.net" {
+		t.Errorf("Unexpected ARM endpoint: %s", conf.Services[cloud.ResourceManager].Endpoint)
+	}
+
+	var nilEnv *Environment
+	if !nilEnv.IsPublic() || nilEnv.ResourceManagerURL("/x") != "https://management.azure.com/x" ||
+		nilEnv.MicrosoftGraphScope() != "https://graph.microsoft.com/.default" {
+		t.Error("Expected nil environment to behave as the public cloud")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/endpoints" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"name": "AzureChinaCloud",
+			"resourceManager": "https://management.chinacloudapi.cn/",
+			"authentication": {
+				"loginEndpoint": "https://login.chinacloudapi.cn",
+				"audiences": ["https://management.core.chinacloudapi.cn/", "https://management.chinacloudapi.cn/"]
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	env, err := Discover(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if env.Name != AzureChinaCloud || env.ResourceManagerEndpoint != "https://management.chinacloudapi.cn" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+	if env.ActiveDirectoryAuthorityHost != "https://login.chinacloudapi.cn/" {
+		t.Errorf("Unexpected authority host: %s", env.ActiveDirectoryAuthorityHost)
+	}
+	// Endpoints not described by the metadata come from the known cloud
+	if env.AppInsightsIngestionEndpoint != "https://dc.applicationinsights.azure.cn/v2/track" {
+		t.Errorf("Unexpected Application Insights endpoint: %s", env.AppInsightsIngestionEndpoint)
+	}
+	if env.MicrosoftGraphEndpoint != "https://microsoftgraph.chinacloudapi.cn" {
+		t.Errorf("Unexpected Graph endpoint: %s", env.MicrosoftGraphEndpoint)
+	}
+
+	if _, err := Discover(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Expected error for failed metadata request")
+	}
+}
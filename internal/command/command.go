@@ -3,8 +3,10 @@ package command
 import (
 	"bytes"
 	"context"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/shlex"
@@ -16,6 +18,8 @@ type ShellProcess struct {
 	StripNewlines   bool
 	ReturnErrOutput bool
 	Timeout         int // in seconds
+	// Env holds additional environment variables (KEY=value) for the process
+	Env []string
 }
 
 // NewShellProcess creates a new ShellProcess
@@ -40,8 +44,49 @@ func (s *ShellProcess) Run(args string) (string, error) {
 	return s.Exec(commands)
 }
 
+// Interceptor observes or answers the commands run by shell processes, for recording and replaying sessions
+type Interceptor interface {
+	// Intercept returns the output of a command and true to answer it without running it
+	Intercept(command string) (string, bool, error)
+	// Observe is called with the output of each command that was run
+	Observe(command, output string, err error)
+}
+
+var (
+	interceptorMu sync.RWMutex
+	interceptor   Interceptor
+)
+
+// SetInterceptor installs the interceptor of every shell process command. A nil interceptor removes it.
+func SetInterceptor(i Interceptor) {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+	interceptor = i
+}
+
+// currentInterceptor returns the installed interceptor, or nil
+func currentInterceptor() Interceptor {
+	interceptorMu.RLock()
+	defer interceptorMu.RUnlock()
+	return interceptor
+}
+
 // Exec runs the commands and returns the output
 func (s *ShellProcess) Exec(commands string) (string, error) {
+	i := currentInterceptor()
+	if i == nil {
+		return s.exec(commands)
+	}
+	if output, handled, err := i.Intercept(commands); handled {
+		return output, err
+	}
+	output, err := s.exec(commands)
+	i.Observe(commands, output, err)
+	return output, err
+}
+
+// exec runs the commands in a subprocess
+func (s *ShellProcess) exec(commands string) (string, error) {
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout)*time.Second)
 	defer cancel()
@@ -67,12 +112,26 @@ func (s *ShellProcess) Exec(commands string) (string, error) {
 		return "", nil
 	}
 
+	if len(s.Env) > 0 {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	// On timeout, kill the whole process tree rather than only the subprocess, whose children would
+	// otherwise leak and could keep the output open past the timeout
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return tracker.kill(cmd.Process) }
+	cmd.WaitDelay = killGrace
+
 	// Execute the command
-	err = cmd.Run()
+	if err = cmd.Start(); err == nil {
+		id := tracker.add(parts[0], cmd.Process)
+		err = cmd.Wait()
+		tracker.remove(id, ctx.Err() == context.DeadlineExceeded)
+	}
 
 	// Check for timeout
 	if ctx.Err() == context.DeadlineExceeded {
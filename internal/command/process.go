@@ -0,0 +1,100 @@
+package command
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// killGrace bounds how long Wait keeps reading the output of a killed subprocess. A child that escaped the
+// process group can hold the output pipes open, which would otherwise hang the command past its timeout.
+const killGrace = 5 * time.Second
+
+// ProcessStats counts the shell subprocesses run by this server
+type ProcessStats struct {
+	// Running counts the live subprocesses by command, such as az or kubectl
+	Running map[string]int
+	// Started counts the subprocesses started
+	Started uint64
+	// TimedOut counts the subprocesses that ran past their timeout
+	TimedOut uint64
+	// Killed counts the process trees killed on timeout or shutdown
+	Killed uint64
+}
+
+// liveProcess is a running subprocess
+type liveProcess struct {
+	name    string
+	process *os.Process
+}
+
+// processTracker tracks the live subprocesses, so they can be counted and killed on shutdown
+type processTracker struct {
+	mu       sync.Mutex
+	nextID   uint64
+	live     map[uint64]liveProcess
+	started  uint64
+	timedOut uint64
+	killed   uint64
+}
+
+var tracker = &processTracker{live: make(map[uint64]liveProcess)}
+
+// add tracks a started subprocess and returns its tracking ID
+func (t *processTracker) add(name string, process *os.Process) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.started++
+	t.live[t.nextID] = liveProcess{name: name, process: process}
+	return t.nextID
+}
+
+// remove stops tracking a subprocess once it has been waited for
+func (t *processTracker) remove(id uint64, timedOut bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.live, id)
+	if timedOut {
+		t.timedOut++
+	}
+}
+
+// kill kills the process tree of a subprocess
+func (t *processTracker) kill(process *os.Process) error {
+	t.mu.Lock()
+	t.killed++
+	t.mu.Unlock()
+	return killProcessTree(process)
+}
+
+// Stats returns the subprocess counts
+func Stats() ProcessStats {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	stats := ProcessStats{
+		Running:  make(map[string]int),
+		Started:  tracker.started,
+		TimedOut: tracker.timedOut,
+		Killed:   tracker.killed,
+	}
+	for _, p := range tracker.live {
+		stats.Running[p.name]++
+	}
+	return stats
+}
+
+// KillAll kills the process trees of every live subprocess, so none outlive the server, and returns how many
+// were killed
+func KillAll() int {
+	tracker.mu.Lock()
+	processes := make([]*os.Process, 0, len(tracker.live))
+	for _, p := range tracker.live {
+		processes = append(processes, p.process)
+	}
+	tracker.mu.Unlock()
+	for _, process := range processes {
+		_ = tracker.kill(process)
+	}
+	return len(processes)
+}
@@ -0,0 +1,72 @@
+//go:build !windows
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestExecKillsProcessTreeOnTimeout tests that a timed out subprocess is killed with the children it started,
+// including a child holding its output open
+func TestExecKillsProcessTreeOnTimeout(t *testing.T) {
+	before := Stats()
+	marker := filepath.Join(t.TempDir(), "child-survived")
+	process := NewShellProcess("sh", 1)
+
+	started := time.Now()
+	_, err := process.Run(`-c "(sleep 3; touch ` + marker + `) & sleep 30"`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > killGrace {
+		t.Errorf("Expected the command to return soon after its timeout, took %s", elapsed)
+	}
+
+	after := Stats()
+	if after.TimedOut != before.TimedOut+1 || after.Killed != before.Killed+1 || after.Started != before.Started+1 {
+		t.Errorf("Unexpected stats %+v after %+v", after, before)
+	}
+	if after.Running["sh"] != 0 {
+		t.Errorf("Expected no running subprocess, got %v", after.Running)
+	}
+
+	// The background child was in the killed process group, so it never gets to create the marker
+	time.Sleep(3 * time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected the child of the timed out subprocess to be killed")
+	}
+}
+
+// TestKillAll tests killing the live subprocesses, as done on shutdown
+func TestKillAll(t *testing.T) {
+	process := NewShellProcess("sleep", 30)
+	done := make(chan error, 1)
+	go func() {
+		_, err := process.Run("30")
+		done <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for Stats().Running["sleep"] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the subprocess to be tracked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if killed := KillAll(); killed != 1 {
+		t.Errorf("Expected one subprocess killed, got %d", killed)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the killed subprocess to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the killed subprocess to return")
+	}
+}
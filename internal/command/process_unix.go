@@ -0,0 +1,24 @@
+//go:build !windows
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the subprocess as the leader of a new process group, which its children join
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessTree kills the process group led by the subprocess, including the children it started
+func killProcessTree(process *os.Process) error {
+	// A negative PID signals every process of the group
+	if err := syscall.Kill(-process.Pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
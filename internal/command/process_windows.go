@@ -0,0 +1,25 @@
+//go:build windows
+
+package command
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the subprocess in a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessTree kills the subprocess and the children it started, falling back to the subprocess alone
+// when taskkill is unavailable
+func killProcessTree(process *os.Process) error {
+	// #nosec G204: the PID is the subprocess started by this server
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid)).Run(); err != nil {
+		return process.Kill()
+	}
+	return nil
+}
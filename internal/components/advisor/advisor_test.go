@@ -1,6 +1,7 @@
 package advisor
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Azure/aks-mcp/internal/config"
@@ -280,3 +281,31 @@ func containsInMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestApplyClusterSKUs(t *testing.T) {
+	automaticID := "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/auto-cluster"
+	baseID := "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/base-cluster"
+	skus := parseClusterSKUs(strings.ToLower(automaticID) + "\tAutomatic\n" + baseID + "\tBase\n")
+
+	summaries := []AKSRecommendationSummary{
+		{ID: "1", ResourceID: automaticID + "/agentPools/nodepool1", Severity: "High", Status: "Active", Description: "Use ephemeral OS disks"},
+		{ID: "2", ResourceID: automaticID, Severity: "High", Status: "Active", Description: "Enable the cluster autoscaler"},
+		{ID: "3", ResourceID: automaticID, Severity: "Medium", Status: "Active", Description: "Enable Microsoft Defender for Containers"},
+		{ID: "4", ResourceID: baseID, Severity: "Low", Status: "Active", Description: "Enable the cluster autoscaler"},
+	}
+	applyClusterSKUs(summaries, skus)
+
+	for i, want := range []string{automaticStatus, automaticStatus, "Active", "Active"} {
+		if summaries[i].Status != want {
+			t.Errorf("Recommendation %s: expected status %s, got %s", summaries[i].ID, want, summaries[i].Status)
+		}
+	}
+	if summaries[0].AKSSpecific.ClusterSKU != "Automatic" || summaries[3].AKSSpecific.ClusterSKU != "Base" {
+		t.Errorf("Unexpected cluster SKUs %q and %q", summaries[0].AKSSpecific.ClusterSKU, summaries[3].AKSSpecific.ClusterSKU)
+	}
+
+	actionItems := generateActionItems(summaries)
+	if len(actionItems) != 2 || actionItems[0].RecommendationID != "3" || actionItems[1].RecommendationID != "4" {
+		t.Errorf("Expected action items only for recommendations that can be acted on, got %+v", actionItems)
+	}
+}
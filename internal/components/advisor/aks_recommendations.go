@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,6 +22,10 @@ func HandleAdvisorRecommendation(params map[string]interface{}, cfg *config.Conf
 
 	log.Printf("[ADVISOR] Handling operation: %s", operation)
 
+	if category, _ := params["category"].(string); category != "" && !slices.ContainsFunc(Categories, func(c string) bool { return strings.EqualFold(c, category) }) {
+		return "", fmt.Errorf("invalid category: %s. Allowed values: %s", category, strings.Join(Categories, ", "))
+	}
+
 	switch operation {
 	case "list":
 		return handleAKSAdvisorRecommendationList(params, cfg)
@@ -28,7 +33,7 @@ func HandleAdvisorRecommendation(params map[string]interface{}, cfg *config.Conf
 		return handleAKSAdvisorRecommendationReport(params, cfg)
 	default:
 		log.Printf("[ADVISOR] Invalid operation: %s", operation)
-		return "", fmt.Errorf("invalid operation: %s. Allowed values: list, report", operation)
+		return "", fmt.Errorf("invalid operation: %s. Allowed values: %s", operation, strings.Join(Operations, ", "))
 	}
 }
 
@@ -85,6 +90,7 @@ func handleAKSAdvisorRecommendationList(params map[string]interface{}, cfg *conf
 
 	// Convert to AKS recommendation summaries
 	summaries := convertToAKSRecommendationSummaries(aksRecommendations)
+	annotateClusterSKUs(summaries, subscriptionID, resourceGroup, cfg)
 
 	// Return JSON response
 	result, err := json.MarshalIndent(summaries, "", "  ")
@@ -120,6 +126,7 @@ func handleAKSAdvisorRecommendationReport(params map[string]interface{}, cfg *co
 	// Filter for AKS-related recommendations
 	aksRecommendations := filterAKSRecommendationsFromCLI(recommendations)
 	summaries := convertToAKSRecommendationSummaries(aksRecommendations)
+	annotateClusterSKUs(summaries, subscriptionID, resourceGroup, cfg)
 
 	// Generate report
 	report := generateAKSAdvisorReport(subscriptionID, summaries, format)
@@ -347,6 +354,10 @@ func generateActionItems(recommendations []AKSRecommendationSummary) []AKSAction
 
 	// Create action items in priority order
 	for _, rec := range append(append(highPriority, mediumPriority...), lowPriority...) {
+		// AKS Automatic acts on these itself
+		if rec.Status == automaticStatus {
+			continue
+		}
 		actionItems = append(actionItems, AKSActionItem{
 			Priority:         priority,
 			RecommendationID: rec.ID,
@@ -0,0 +1,105 @@
+package advisor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// automaticStatus is the status of recommendations about settings AKS Automatic manages itself
+const automaticStatus = "ManagedByAKSAutomatic"
+
+// automaticManagedTopics are lowercase fragments of recommendations about node pools, node scaling and upgrades,
+// which AKS Automatic manages itself
+var automaticManagedTopics = []string{
+	"node pool", "nodepool", "agent pool", "autoscal", "availability zone", "upgrade channel", "auto-upgrade",
+	"automatic upgrade", "ephemeral os disk", "vm size", "virtual machine size",
+}
+
+// listClusterSKUsViaCLI returns the SKU name of each cluster in the subscription or resource group by lowercase
+// resource ID
+func listClusterSKUsViaCLI(subscriptionID, resourceGroup string, cfg *config.ConfigData) (map[string]string, error) {
+	command := "az aks list --subscription " + subscriptionID
+	if resourceGroup != "" {
+		command += " --resource-group " + resourceGroup
+	}
+	command += " --query [].[id,sku.name] --output tsv"
+
+	output, err := azcli.NewExecutor().Execute(map[string]interface{}{"command": command}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster SKUs: %w", err)
+	}
+	return parseClusterSKUs(output), nil
+}
+
+// parseClusterSKUs reads the tab-separated resource ID and SKU name of each cluster
+func parseClusterSKUs(output string) map[string]string {
+	skus := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) == 2 && fields[0] != "" {
+			skus[strings.ToLower(fields[0])] = fields[1]
+		}
+	}
+	return skus
+}
+
+// clusterResourceID returns the lowercase ID of the cluster a recommendation's resource belongs to
+func clusterResourceID(resourceID string) string {
+	lower := strings.ToLower(resourceID)
+	const marker = "/providers/microsoft.containerservice/managedclusters/"
+	i := strings.Index(lower, marker)
+	if i < 0 {
+		return ""
+	}
+	rest := lower[i+len(marker):]
+	if j := strings.Index(rest, "/"); j >= 0 {
+		rest = rest[:j]
+	}
+	return lower[:i+len(marker)] + rest
+}
+
+// applyClusterSKUs records each recommendation's cluster SKU. Recommendations about node pools, scaling and
+// upgrades of AKS Automatic clusters are marked as managed by AKS, since they can't be acted on.
+func applyClusterSKUs(summaries []AKSRecommendationSummary, skus map[string]string) {
+	for i := range summaries {
+		rec := &summaries[i]
+		rec.AKSSpecific.ClusterSKU = skus[clusterResourceID(rec.ResourceID)]
+		if !common.IsAutomaticSKU(rec.AKSSpecific.ClusterSKU) || !isAutomaticManaged(*rec) {
+			continue
+		}
+		rec.Status = automaticStatus
+		rec.Description += " (AKS Automatic manages node pools, node scaling and upgrades for this cluster, so no action is needed)"
+	}
+}
+
+// isAutomaticManaged reports whether a recommendation concerns settings AKS Automatic manages itself
+func isAutomaticManaged(rec AKSRecommendationSummary) bool {
+	if strings.Contains(strings.ToLower(rec.ResourceID), "/agentpools/") {
+		return true
+	}
+	description := strings.ToLower(rec.Description)
+	for _, topic := range automaticManagedTopics {
+		if strings.Contains(description, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotateClusterSKUs looks up the cluster SKUs and applies them. A failed lookup only loses the annotation.
+func annotateClusterSKUs(summaries []AKSRecommendationSummary, subscriptionID, resourceGroup string, cfg *config.ConfigData) {
+	if len(summaries) == 0 {
+		return
+	}
+	skus, err := listClusterSKUsViaCLI(subscriptionID, resourceGroup, cfg)
+	if err != nil {
+		log.Printf("[ADVISOR] Failed to read cluster SKUs: %v", err)
+		return
+	}
+	applyClusterSKUs(summaries, skus)
+}
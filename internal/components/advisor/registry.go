@@ -6,13 +6,22 @@ import (
 
 // Advisory-related tool registrations
 
+// Operations are the operations of the az_advisor_recommendation tool
+var Operations = []string{"list", "report"}
+
+// Categories are the Azure Advisor recommendation categories the tool filters by
+var Categories = []string{"Cost", "HighAvailability", "Performance", "Security"}
+
 // RegisterAdvisorRecommendationTool registers the az_advisor_recommendation tool
 func RegisterAdvisorRecommendationTool() mcp.Tool {
 	return mcp.NewTool(
 		"az_advisor_recommendation",
-		mcp.WithDescription("Retrieve and manage Azure Advisor recommendations for AKS clusters"),
+		mcp.WithDescription("Retrieve and manage Azure Advisor recommendations for AKS clusters. Each recommendation carries its cluster's SKU; "+
+			"recommendations about node pools, node scaling and upgrades of AKS Automatic clusters, which AKS manages itself, have the status "+
+			"ManagedByAKSAutomatic and are left out of report action items"),
 		mcp.WithString("operation",
 			mcp.Description("Operation to perform: list or report"),
+			mcp.Enum(Operations...),
 			mcp.Required(),
 		),
 		mcp.WithString("subscription_id",
@@ -26,7 +35,8 @@ func RegisterAdvisorRecommendationTool() mcp.Tool {
 			mcp.Description("Comma-separated list of specific AKS cluster names to filter recommendations"),
 		),
 		mcp.WithString("category",
-			mcp.Description("Filter by recommendation category: Cost, HighAvailability, Performance, Security"),
+			mcp.Description("Filter by recommendation category"),
+			mcp.Enum(Categories...),
 		),
 		mcp.WithString("severity",
 			mcp.Description("Filter by severity level: High, Medium, Low"),
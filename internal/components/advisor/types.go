@@ -26,6 +26,7 @@ type AKSRecommendationDetails struct {
 	NodePoolNames     []string `json:"node_pool_names,omitempty"`
 	WorkloadType      string   `json:"workload_type,omitempty"`
 	ConfigurationArea string   `json:"configuration_area,omitempty"` // networking, compute, storage, security
+	ClusterSKU        string   `json:"cluster_sku,omitempty"`        // Base or Automatic
 }
 
 // CostSavings represents potential cost savings information
@@ -0,0 +1,226 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/approval"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+const testManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: blue
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+`
+
+// fakeKubectl records the commands run and the manifests they were given, and returns canned output
+type fakeKubectl struct {
+	commands  []string
+	manifests []string
+	context   string
+	diff      Output
+	apply     Output
+}
+
+func (f *fakeKubectl) Run(_ context.Context, args []string) (Output, error) {
+	f.commands = append(f.commands, strings.Join(args, " "))
+	switch args[0] {
+	case "config":
+		return Output{Stdout: f.context + "\n"}, nil
+	case "diff", "apply":
+		manifest, err := os.ReadFile(args[4])
+		if err != nil {
+			return Output{}, err
+		}
+		f.manifests = append(f.manifests, string(manifest))
+		if args[0] == "diff" {
+			return f.diff, nil
+		}
+		return f.apply, nil
+	}
+	return Output{}, errors.New("unexpected command")
+}
+
+func newTestKubectl() *fakeKubectl {
+	return &fakeKubectl{
+		context: "aks-prod",
+		diff:    Output{Stdout: "-  mode: green\n+  mode: blue\n", ExitCode: 1},
+		apply:   Output{Stdout: "configmap/settings serverside-applied\ndeployment.apps/web serverside-applied\n"},
+	}
+}
+
+func runApply(t *testing.T, params map[string]interface{}, kubectl Kubectl, approvals *approval.Manager, logger *audit.Logger, cfg *config.ConfigData) Result {
+	t.Helper()
+	output, err := HandleApply(params, kubectl, approvals, logger, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	return result
+}
+
+func TestRegisterApplyTool(t *testing.T) {
+	tool := RegisterApplyTool()
+	if tool.Name != "k8s_apply" {
+		t.Errorf("Expected tool name 'k8s_apply', got '%s'", tool.Name)
+	}
+	for _, param := range []string{"operation", "manifest"} {
+		found := false
+		for _, required := range tool.InputSchema.Required {
+			found = found || required == param
+		}
+		if !found {
+			t.Errorf("Expected %s to be required", param)
+		}
+	}
+}
+
+// TestDiffThenApply tests that a diff issues an approval that applies the same manifest once
+func TestDiffThenApply(t *testing.T) {
+	kubectl := newTestKubectl()
+	approvals := approval.NewManager(nil, 0)
+	logger := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	params := map[string]interface{}{"operation": OpDiff, "manifest": testManifest, "namespace": "shop"}
+
+	preview := runApply(t, params, kubectl, approvals, logger, cfg)
+	if !preview.Changed || preview.ApprovalID == "" || preview.ExpiresAt == nil || !strings.Contains(preview.Diff, "+  mode: blue") {
+		t.Fatalf("Expected a diff with an approval, got %+v", preview)
+	}
+	if len(preview.Objects) != 2 || preview.Objects[0].Namespace != "shop" || preview.Context != "aks-prod" {
+		t.Errorf("Unexpected objects %+v", preview.Objects)
+	}
+	if kubectl.commands[1] != "diff --server-side --field-manager=aks-mcp -f "+strings.Fields(kubectl.commands[1])[4]+" --namespace shop" {
+		t.Errorf("Unexpected diff command %q", kubectl.commands[1])
+	}
+
+	params["operation"] = OpApply
+	params["approval_id"] = preview.ApprovalID
+	applied := runApply(t, params, kubectl, approvals, logger, cfg)
+	if len(applied.Applied) != 2 || applied.Applied[1] != "deployment.apps/web serverside-applied" {
+		t.Errorf("Unexpected apply result %+v", applied)
+	}
+	if kubectl.manifests[1] != testManifest {
+		t.Errorf("Expected the previewed manifest to be applied, got %q", kubectl.manifests[1])
+	}
+
+	if _, err := HandleApply(params, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrNotFound) {
+		t.Errorf("Expected an approval to apply once, got %v", err)
+	}
+	records, _ := logger.Records()
+	if len(records) != 2 || records[0].Outcome != audit.OutcomeSucceeded || records[1].Outcome != audit.OutcomeDenied {
+		t.Fatalf("Expected a succeeded and a denied apply to be audited, got %+v", records)
+	}
+	if records[0].Target != "ConfigMap shop/settings, Deployment shop/web" {
+		t.Errorf("Unexpected audit target %q", records[0].Target)
+	}
+}
+
+// TestApplyRequiresMatchingApproval tests that changed manifests, contexts and missing approvals are refused
+func TestApplyRequiresMatchingApproval(t *testing.T) {
+	kubectl := newTestKubectl()
+	approvals := approval.NewManager(nil, 0)
+	logger := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	preview := runApply(t, map[string]interface{}{"operation": OpDiff, "manifest": testManifest}, kubectl, approvals, logger, cfg)
+
+	changed := strings.Replace(testManifest, "blue", "red", 1)
+	if _, err := HandleApply(map[string]interface{}{"operation": OpApply, "manifest": changed, "approval_id": preview.ApprovalID}, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrMismatch) {
+		t.Errorf("Expected a changed manifest to be refused, got %v", err)
+	}
+	kubectl.context = "aks-dev"
+	if _, err := HandleApply(map[string]interface{}{"operation": OpApply, "manifest": testManifest, "approval_id": preview.ApprovalID}, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrMismatch) {
+		t.Errorf("Expected another kubeconfig context to be refused, got %v", err)
+	}
+	if _, err := HandleApply(map[string]interface{}{"operation": OpApply, "manifest": testManifest}, kubectl, approvals, logger, cfg); err == nil {
+		t.Error("Expected apply without an approval to be refused")
+	}
+	for _, cmd := range kubectl.commands {
+		if strings.HasPrefix(cmd, "apply") {
+			t.Errorf("Expected nothing to be applied, got %q", cmd)
+		}
+	}
+	records, _ := logger.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 audited attempts, got %d", len(records))
+	}
+	for _, record := range records {
+		if record.Outcome != audit.OutcomeDenied {
+			t.Errorf("Expected a denied attempt, got %+v", record)
+		}
+	}
+}
+
+// TestDiffWithoutChanges tests that no approval is issued when the live objects match
+func TestDiffWithoutChanges(t *testing.T) {
+	kubectl := newTestKubectl()
+	kubectl.diff = Output{}
+	result := runApply(t, map[string]interface{}{"operation": OpDiff, "manifest": testManifest}, kubectl, approval.NewManager(nil, 0), audit.NewLogger(nil), config.NewConfig())
+	if result.Changed || result.ApprovalID != "" {
+		t.Errorf("Expected no approval without changes, got %+v", result)
+	}
+
+	kubectl.diff = Output{Stderr: "error: unable to recognize", ExitCode: 2}
+	if _, err := HandleApply(map[string]interface{}{"operation": OpDiff, "manifest": testManifest}, kubectl, approval.NewManager(nil, 0), audit.NewLogger(nil), config.NewConfig()); err == nil || !strings.Contains(err.Error(), "unable to recognize") {
+		t.Errorf("Expected a failed diff to return kubectl's error, got %v", err)
+	}
+}
+
+// TestParseManifest tests manifest validation and the namespace restrictions
+func TestParseManifest(t *testing.T) {
+	restricted := config.NewConfig()
+	restricted.AllowNamespaces = "shop"
+	clusterScoped := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n"
+
+	tests := []struct {
+		name      string
+		manifest  string
+		namespace string
+		cfg       *config.ConfigData
+		wantErr   string
+	}{
+		{name: "empty", manifest: "  ", cfg: config.NewConfig(), wantErr: "missing manifest"},
+		{name: "missing name", manifest: "apiVersion: v1\nkind: ConfigMap\n", cfg: config.NewConfig(), wantErr: "metadata.name"},
+		{name: "list", manifest: "apiVersion: v1\nkind: List\nmetadata:\n  name: all\n", cfg: config.NewConfig(), wantErr: "not supported"},
+		{name: "namespace conflict", manifest: testManifest, namespace: "default", cfg: config.NewConfig(), wantErr: "not default"},
+		{name: "invalid namespace", manifest: testManifest, namespace: "shop;rm", cfg: config.NewConfig(), wantErr: "invalid namespace"},
+		{name: "denied namespace", manifest: testManifest, namespace: "", cfg: restricted, wantErr: "has no namespace"},
+		{name: "cluster scoped", manifest: clusterScoped, cfg: restricted, wantErr: "has no namespace"},
+		{name: "allowed", manifest: testManifest, namespace: "shop", cfg: restricted},
+		{name: "cluster scoped unrestricted", manifest: clusterScoped, cfg: config.NewConfig()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseManifest(tt.manifest, tt.namespace, tt.cfg)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	other := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: x\n  namespace: kube-system\n"
+	if _, err := ParseManifest(other, "", restricted); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected a disallowed namespace to be refused, got %v", err)
+	}
+}
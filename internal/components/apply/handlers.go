@@ -0,0 +1,401 @@
+// Package apply applies generated Kubernetes manifests with server-side apply. A change is first
+// previewed with a server-side diff; it is applied only when the caller confirms that exact diff
+// with the approval issued for it. Every apply attempt, including denied ones, is audited.
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/approval"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Operations of the k8s_apply tool
+const (
+	OpDiff  = "diff"
+	OpApply = "apply"
+)
+
+const (
+	// toolName is the name approvals and audit records are issued under
+	toolName = "k8s_apply"
+	// fieldManager owns the fields set by server-side apply
+	fieldManager = "aks-mcp"
+	// maxManifestBytes and maxObjects bound the manifests accepted in one call
+	maxManifestBytes = 256 * 1024
+	maxObjects       = 50
+)
+
+// Output is the result of a kubectl run
+type Output struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Kubectl runs kubectl. Run returns an error only when kubectl could not be run or timed out; a non-zero
+// exit code is reported in the output, because kubectl diff exits with 1 when it found differences.
+type Kubectl interface {
+	Run(ctx context.Context, args []string) (Output, error)
+}
+
+// Object identifies one object of a manifest
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// String returns the kind and the namespaced name of the object
+func (o Object) String() string {
+	if o.Namespace == "" {
+		return o.Kind + " " + o.Name
+	}
+	return o.Kind + " " + o.Namespace + "/" + o.Name
+}
+
+// Result is the result returned by the k8s_apply tool
+type Result struct {
+	Operation string   `json:"operation"`
+	Context   string   `json:"context"`
+	Objects   []Object `json:"objects"`
+	// Changed reports whether the diff found differences with the live objects (diff only)
+	Changed    bool       `json:"changed"`
+	Diff       string     `json:"diff,omitempty"`
+	ApprovalID string     `json:"approvalId,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	// Applied lists kubectl's report for each applied object (apply only)
+	Applied []string `json:"applied,omitempty"`
+	Next    string   `json:"next,omitempty"`
+}
+
+// GetApplyHandler returns a handler for the k8s_apply command
+func GetApplyHandler(approvals *approval.Manager, auditLog *audit.Logger, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		kubectl := execKubectl{timeout: time.Duration(cfg.Timeout) * time.Second}
+		return HandleApply(params, kubectl, approvals, auditLog, cfg)
+	})
+}
+
+// HandleApply previews a manifest with a server-side diff and issues an approval for it, or applies a
+// previewed manifest with server-side apply once its approval is given
+func HandleApply(params map[string]interface{}, kubectl Kubectl, approvals *approval.Manager, auditLog *audit.Logger, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	manifest, _ := params["manifest"].(string)
+	namespace, _ := params["namespace"].(string)
+	if operation != OpDiff && operation != OpApply {
+		return "", fmt.Errorf("invalid operation %q: expected %s or %s", operation, OpDiff, OpApply)
+	}
+
+	// Approvals issued to a session can only be confirmed by that session
+	owner := ""
+	if cfg.Session != nil {
+		owner = cfg.Session.SessionID
+	}
+	ctx := context.Background()
+	objects, err := ParseManifest(manifest, namespace, cfg)
+	if cfg.Review != nil {
+		// In review mode nothing is applied, so no approval is issued either
+		if err != nil {
+			return "", err
+		}
+		return "", deferForReview(ctx, kubectl, manifest, namespace, cfg.Review)
+	}
+	if operation == OpDiff {
+		if err != nil {
+			return "", err
+		}
+		return diff(ctx, kubectl, approvals, manifest, namespace, owner, objects)
+	}
+
+	record := audit.Record{Tool: toolName, Action: OpApply, Namespace: namespace, Target: describeObjects(objects), Client: cfg.ClientName()}
+	if err != nil {
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+		auditLog.Log(record)
+		return "", err
+	}
+	output, err := apply(ctx, kubectl, approvals, params, manifest, namespace, owner, objects, &record)
+	if err != nil {
+		if record.Outcome == "" {
+			record.Outcome = audit.OutcomeFailed
+		}
+		record.Error = err.Error()
+		auditLog.Log(record)
+		return "", err
+	}
+	record.Outcome = audit.OutcomeSucceeded
+	auditLog.Log(record)
+	return output, nil
+}
+
+// diff runs a server-side diff of the manifest and issues an approval when it would change the cluster
+func diff(ctx context.Context, kubectl Kubectl, approvals *approval.Manager, manifest, namespace, owner string, objects []Object) (string, error) {
+	kubeContext, err := currentContext(ctx, kubectl)
+	if err != nil {
+		return "", err
+	}
+	out, err := runWithManifest(ctx, kubectl, "diff", manifest, namespace)
+	if err != nil {
+		return "", err
+	}
+	// kubectl diff exits with 0 without differences, 1 with differences and above 1 on errors
+	if out.ExitCode > 1 {
+		return "", fmt.Errorf("server-side diff failed: %s", kubectlError(out))
+	}
+
+	result := Result{Operation: OpDiff, Context: kubeContext, Objects: objects, Changed: out.ExitCode == 1}
+	if !result.Changed {
+		result.Next = "The live objects already match the manifest; there is nothing to apply."
+		return marshal(result)
+	}
+	result.Diff = out.Stdout
+	issued, err := approvals.Request(toolName, approval.Digest(manifest, namespace, kubeContext), describeObjects(objects), owner)
+	if err != nil {
+		return "", err
+	}
+	result.ApprovalID = issued.ID
+	result.ExpiresAt = &issued.Expires
+	result.Next = fmt.Sprintf("Show the diff to the user and ask for confirmation. To apply it, call k8s_apply with operation apply, "+
+		"the same manifest and namespace, and approval_id %s before %s.", issued.ID, issued.Expires.Format(time.RFC3339))
+	return marshal(result)
+}
+
+// deferForReview defers the apply of a manifest to the review plan, with its server-side diff against the live objects
+func deferForReview(ctx context.Context, kubectl Kubectl, manifest, namespace string, plan *review.Plan) error {
+	out, err := runWithManifest(ctx, kubectl, "diff", manifest, namespace)
+	if err != nil {
+		return err
+	}
+	if out.ExitCode > 1 {
+		return fmt.Errorf("server-side diff failed: %s", kubectlError(out))
+	}
+	command := "kubectl " + strings.Join(applyArgs("apply", "manifest.yaml", namespace), " ")
+	change := review.Change{
+		Kind:    review.KindKubectl,
+		Command: command,
+		Artifacts: []review.Artifact{
+			{Format: review.FormatManifest, Description: "Commit to the repository your pipeline applies", Content: manifest},
+			{Format: review.FormatScript, Description: "Run from a pipeline with access to the cluster, with the manifest saved as manifest.yaml", Content: review.Script(command)},
+		},
+	}
+	if out.ExitCode == 1 {
+		change.Diff = strings.Split(strings.TrimRight(out.Stdout, "\n"), "\n")
+	} else {
+		change.Notes = []string{"The live objects already match the manifest"}
+	}
+	return plan.Defer(change)
+}
+
+// apply applies a previewed manifest once its approval is confirmed. Approval failures are recorded as denied.
+func apply(ctx context.Context, kubectl Kubectl, approvals *approval.Manager, params map[string]interface{}, manifest, namespace, owner string, objects []Object, record *audit.Record) (string, error) {
+	approvalID, _ := params["approval_id"].(string)
+	if approvalID == "" {
+		record.Outcome = audit.OutcomeDenied
+		return "", fmt.Errorf("approval_id is required: preview the change with operation diff first")
+	}
+	kubeContext, err := currentContext(ctx, kubectl)
+	if err != nil {
+		return "", err
+	}
+	// The context is part of the digest, so a diff previewed on one cluster cannot be applied to another
+	if _, err := approvals.Consume(approvalID, toolName, approval.Digest(manifest, namespace, kubeContext), owner); err != nil {
+		record.Outcome = audit.OutcomeDenied
+		return "", err
+	}
+
+	out, err := runWithManifest(ctx, kubectl, "apply", manifest, namespace)
+	record.Command = strings.Join(applyArgs("apply", "<manifest>", namespace), " ")
+	if err != nil {
+		return "", err
+	}
+	if out.ExitCode != 0 {
+		message := kubectlError(out)
+		if strings.Contains(message, "conflict") {
+			message += " (another field manager owns these fields; change them with the tool that manages them or remove them from the manifest)"
+		}
+		return "", fmt.Errorf("server-side apply failed: %s", message)
+	}
+
+	result := Result{Operation: OpApply, Context: kubeContext, Objects: objects, Changed: true}
+	for _, line := range strings.Split(strings.TrimSpace(out.Stdout), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result.Applied = append(result.Applied, line)
+		}
+	}
+	return marshal(result)
+}
+
+// ParseManifest returns the objects of a YAML or JSON manifest with one or more documents. Objects take the
+// namespace parameter when they name none. When the server is restricted with --allow-namespaces every
+// object must be in an allowed namespace, so cluster-scoped objects are refused.
+func ParseManifest(manifest, namespace string, cfg *config.ConfigData) ([]Object, error) {
+	if strings.TrimSpace(manifest) == "" {
+		return nil, fmt.Errorf("missing manifest parameter")
+	}
+	if len(manifest) > maxManifestBytes {
+		return nil, fmt.Errorf("manifest is %d bytes; at most %d bytes are accepted", len(manifest), maxManifestBytes)
+	}
+	if namespace != "" && !common.NamespacePattern.MatchString(namespace) {
+		return nil, fmt.Errorf("invalid namespace parameter: %s", namespace)
+	}
+
+	securityConfig := k8s.ConvertConfig(cfg).SecurityConfig
+	restricted := strings.TrimSpace(cfg.AllowNamespaces) != ""
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	var objects []Object
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid manifest: %v", err)
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		object := Object{}
+		object.APIVersion, _ = doc["apiVersion"].(string)
+		object.Kind, _ = doc["kind"].(string)
+		metadata, _ := doc["metadata"].(map[string]interface{})
+		object.Name, _ = metadata["name"].(string)
+		object.Namespace, _ = metadata["namespace"].(string)
+		if object.APIVersion == "" || object.Kind == "" || object.Name == "" {
+			return nil, fmt.Errorf("manifest document %d needs apiVersion, kind and metadata.name", len(objects)+1)
+		}
+		if strings.HasSuffix(object.Kind, "List") {
+			return nil, fmt.Errorf("%s is not supported; put each object in its own document separated by ---", object.Kind)
+		}
+		if object.Namespace == "" {
+			object.Namespace = namespace
+		} else if namespace != "" && object.Namespace != namespace {
+			return nil, fmt.Errorf("%s is in namespace %s, not %s", object, object.Namespace, namespace)
+		}
+		if restricted && object.Namespace == "" {
+			return nil, fmt.Errorf("%s has no namespace; the server is restricted with --allow-namespaces, so every object needs an allowed namespace", object)
+		}
+		if object.Namespace != "" && !securityConfig.IsNamespaceAllowed(object.Namespace) {
+			return nil, fmt.Errorf("access to namespace '%s' is denied by security configuration", object.Namespace)
+		}
+		objects = append(objects, object)
+		if len(objects) > maxObjects {
+			return nil, fmt.Errorf("manifest has more than %d objects", maxObjects)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("manifest has no objects")
+	}
+	return objects, nil
+}
+
+// currentContext returns the kubeconfig context kubectl acts on
+func currentContext(ctx context.Context, kubectl Kubectl) (string, error) {
+	out, err := kubectl.Run(ctx, []string{"config", "current-context"})
+	if err != nil {
+		return "", err
+	}
+	if out.ExitCode != 0 {
+		return "", fmt.Errorf("failed to read the current kubeconfig context: %s", kubectlError(out))
+	}
+	return strings.TrimSpace(out.Stdout), nil
+}
+
+// runWithManifest writes the manifest to a temporary file and runs kubectl diff or apply on it
+func runWithManifest(ctx context.Context, kubectl Kubectl, verb, manifest, namespace string) (Output, error) {
+	file, err := os.CreateTemp("", "k8s-apply-*.yaml")
+	if err != nil {
+		return Output{}, fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err := file.WriteString(manifest); err != nil {
+		_ = file.Close()
+		return Output{}, fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return Output{}, fmt.Errorf("failed to close manifest file: %w", err)
+	}
+	return kubectl.Run(ctx, applyArgs(verb, file.Name(), namespace))
+}
+
+// applyArgs returns the kubectl arguments of a server-side diff or apply of a manifest file
+func applyArgs(verb, path, namespace string) []string {
+	args := []string{verb, "--server-side", "--field-manager=" + fieldManager, "-f", path}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	return args
+}
+
+// kubectlError returns kubectl's error message
+func kubectlError(out Output) string {
+	if message := strings.TrimSpace(out.Stderr); message != "" {
+		return message
+	}
+	if message := strings.TrimSpace(out.Stdout); message != "" {
+		return message
+	}
+	return fmt.Sprintf("kubectl exited with code %d", out.ExitCode)
+}
+
+// describeObjects lists the objects of a manifest for approvals and audit records
+func describeObjects(objects []Object) string {
+	names := make([]string, len(objects))
+	for i, object := range objects {
+		names[i] = object.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func marshal(result Result) (string, error) {
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal apply result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// execKubectl runs the kubectl binary with the server kubeconfig
+type execKubectl struct {
+	timeout time.Duration
+}
+
+// Run runs kubectl without a shell and returns its output and exit code
+func (k execKubectl) Run(ctx context.Context, args []string) (Output, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+	// #nosec G204: arguments are validated and passed without a shell
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Output{}, fmt.Errorf("kubectl %s timed out after %s", args[0], k.timeout)
+	}
+	out := Output{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		out.ExitCode = exitErr.ExitCode()
+		return out, nil
+	}
+	if err != nil {
+		return Output{}, fmt.Errorf("failed to run kubectl: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,41 @@
+package apply
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterApplyTool registers the k8s_apply tool
+func RegisterApplyTool() mcp.Tool {
+	description := `Apply generated Kubernetes manifests with server-side apply after a confirmed diff.
+
+Applying is a two step operation:
+- diff: runs a server-side dry-run diff of the manifest against the live objects and returns the diff with an approval_id
+- apply: applies the same manifest with server-side apply once the user has confirmed the diff, given its approval_id
+
+An approval can be used once, expires after 15 minutes and only applies the exact manifest, namespace and kubeconfig
+context that were diffed; a changed manifest must be diffed again. Fields are applied with the aks-mcp field manager,
+so fields owned by other managers are reported as conflicts instead of being overwritten. Uses the current kubeconfig context.
+
+Requires readwrite or admin access. Every apply attempt, including denied ones, is audited.
+With --allow-namespaces every object must be in an allowed namespace, so cluster-scoped objects are refused.`
+
+	return mcp.NewTool(
+		"k8s_apply",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform: diff to preview the change, apply to apply a confirmed diff"),
+			mcp.Enum(OpDiff, OpApply),
+			mcp.Required(),
+		),
+		mcp.WithString("manifest",
+			mcp.Description("YAML or JSON manifest; separate multiple objects with ---"),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace for objects that do not set metadata.namespace"),
+		),
+		mcp.WithString("approval_id",
+			mcp.Description("Approval returned by the diff operation (required for apply)"),
+		),
+	)
+}
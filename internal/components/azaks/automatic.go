@@ -0,0 +1,89 @@
+package azaks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+)
+
+// automaticBlockedOperations are the operations that change what AKS Automatic manages itself, with what to do instead
+var automaticBlockedOperations = map[string]string{
+	string(OpNodepoolAdd): "nodes are created by node auto provisioning; to add capacity with specific VM sizes, zones or labels, " +
+		"apply a NodePool custom resource (karpenter.sh) with kubectl instead",
+	string(OpNodepoolScale): "node counts follow the pending pods through node auto provisioning; change the workload's replicas " +
+		"or the limits of its NodePool custom resource instead",
+	string(OpNodepoolDelete): "node pools are managed by AKS; delete or restrict the NodePool custom resource with kubectl instead",
+	string(OpNodepoolUpgrade): "node images are upgraded automatically through the node OS upgrade channel; " +
+		"use a maintenance configuration to control when upgrades happen",
+	string(OpClusterScale): "the node count is managed by node auto provisioning; change the workload's replicas or " +
+		"the limits of its NodePool custom resource instead",
+}
+
+// automaticBlockedUpdateFlag is an update flag that turns off a feature AKS Automatic requires. An empty
+// values list blocks the flag whatever its value.
+type automaticBlockedUpdateFlag struct {
+	flag   string
+	values []string
+	reason string
+}
+
+var automaticBlockedUpdateFlags = []automaticBlockedUpdateFlag{
+	{flag: "--enable-cluster-autoscaler", reason: "node auto provisioning replaces the cluster autoscaler"},
+	{flag: "--disable-cluster-autoscaler", reason: "node auto provisioning replaces the cluster autoscaler"},
+	{flag: "--update-cluster-autoscaler", reason: "node auto provisioning replaces the cluster autoscaler"},
+	{flag: "--node-provisioning-mode", values: []string{"manual"}, reason: "node auto provisioning can't be turned off"},
+	{flag: "--auto-upgrade-channel", values: []string{"none"}, reason: "automatic cluster upgrades can't be turned off"},
+	{flag: "--node-os-upgrade-channel", values: []string{"none", "unmanaged"}, reason: "automatic node OS upgrades can't be turned off"},
+	{flag: "--safeguards-level", values: []string{"off"}, reason: "deployment safeguards are always enforced"},
+}
+
+// automaticRestriction returns why an operation would fail on an AKS Automatic cluster, or an empty string
+func automaticRestriction(operation string, flags map[string]string) string {
+	if reason, ok := automaticBlockedOperations[operation]; ok {
+		return reason
+	}
+	if operation != string(OpClusterUpdate) {
+		return ""
+	}
+	for _, blocked := range automaticBlockedUpdateFlags {
+		value, ok := flags[blocked.flag]
+		if !ok {
+			continue
+		}
+		if len(blocked.values) == 0 {
+			return fmt.Sprintf("%s is not supported: %s", blocked.flag, blocked.reason)
+		}
+		for _, v := range blocked.values {
+			if strings.EqualFold(value, v) {
+				return fmt.Sprintf("%s %s is not supported: %s", blocked.flag, value, blocked.reason)
+			}
+		}
+	}
+	return ""
+}
+
+// CheckAutomaticRestrictions rejects operations that AKS Automatic clusters don't allow, such as node pool changes,
+// before the az command runs. The cluster SKU is only looked up for operations that could be restricted. When it
+// can't be read the operation goes ahead, so the az command reports the underlying problem.
+func CheckAutomaticRestrictions(operation, args string, run AzRunner) error {
+	flags := commandFlags(args)
+	reason := automaticRestriction(operation, flags)
+	if reason == "" {
+		return nil
+	}
+
+	clusterName := firstFlag(flags, "--name", "-n")
+	if strings.HasPrefix(operation, "nodepool-") {
+		clusterName = flags["--cluster-name"]
+	}
+	resourceGroup := firstFlag(flags, "--resource-group", "-g")
+	if clusterName == "" || resourceGroup == "" {
+		return nil
+	}
+	sku, err := common.ClusterSKU(run, flags["--subscription"], resourceGroup, clusterName)
+	if err != nil || !common.IsAutomaticSKU(sku) {
+		return nil
+	}
+	return fmt.Errorf("cluster %s is an AKS Automatic cluster and %s is not allowed: %s", clusterName, operation, reason)
+}
@@ -0,0 +1,56 @@
+package azaks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAutomaticRestrictions(t *testing.T) {
+	var calls []string
+	run := fakeAzRunner(map[string]string{
+		"aks show --resource-group 'rg' --name 'auto'": "Automatic\n",
+		"aks show --resource-group 'rg' --name 'base'": "Base\n",
+	}, &calls)
+
+	tests := []struct {
+		operation string
+		args      string
+		want      string
+	}{
+		{"nodepool-add", "--cluster-name auto --resource-group rg --name gpu", "NodePool custom resource"},
+		{"nodepool-scale", "--cluster-name 'auto' -g rg --name np1 --node-count 5", "node auto provisioning"},
+		{"scale", "--name auto --resource-group rg --node-count 5", "node count is managed"},
+		{"update", "--name auto --resource-group rg --enable-cluster-autoscaler --min-count 1 --max-count 3", "--enable-cluster-autoscaler is not supported"},
+		{"update", "--name auto --resource-group rg --auto-upgrade-channel=none", "--auto-upgrade-channel none is not supported"},
+		{"update", "--name auto --resource-group rg --auto-upgrade-channel rapid", ""},
+		{"nodepool-add", "--cluster-name base --resource-group rg --name gpu", ""},
+	}
+	for _, tt := range tests {
+		err := CheckAutomaticRestrictions(tt.operation, tt.args, run)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s %s: unexpected error %v", tt.operation, tt.args, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "AKS Automatic") {
+			t.Errorf("%s %s: expected error containing %q, got %v", tt.operation, tt.args, tt.want, err)
+		}
+	}
+
+	// Operations that can't be restricted don't look up the cluster
+	calls = nil
+	for _, operation := range []string{"show", "upgrade", "nodepool-list"} {
+		if err := CheckAutomaticRestrictions(operation, "--name auto --cluster-name auto --resource-group rg", run); err != nil {
+			t.Errorf("%s: unexpected error %v", operation, err)
+		}
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected no SKU lookups, got %v", calls)
+	}
+
+	// A cluster whose SKU can't be read is not blocked
+	if err := CheckAutomaticRestrictions("nodepool-delete", "--cluster-name missing --resource-group rg --name np1", run); err != nil {
+		t.Errorf("Expected the operation to go ahead, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package azaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/google/shlex"
+)
+
+// maxInvokeCommands bounds the kubectl commands batched into one command invoke session
+const maxInvokeCommands = 20
+
+// invokeScriptName is the name of the batch script attached to the command invoke session
+const invokeScriptName = "aks-mcp-batch.sh"
+
+// Markers the batch script prints around each command's output
+const (
+	invokeStartMarker = "==aks-mcp-command:"
+	invokeExitMarker  = "==aks-mcp-exit:"
+)
+
+// adminKubectlVerbs are the kubectl commands that need admin access, as with aks_pod_exec and aks_node_drain
+var adminKubectlVerbs = []string{"exec", "attach", "cp", "debug", "port-forward", "proxy", "drain", "cordon", "uncordon", "taint", "certificate"}
+
+// kubectlValueFlags are the kubectl global flags that take their value as the next argument. Their values
+// are skipped when looking for the verb so that "kubectl -v 6 drain node1" is seen as a drain.
+var kubectlValueFlags = []string{
+	"-s", "-v", "--as", "--as-group", "--as-uid", "--cache-dir", "--certificate-authority", "--client-certificate",
+	"--client-key", "--cluster", "--context", "--kubeconfig", "--kuberc", "--log-backtrace-at", "--log-dir", "--log-file",
+	"--log-file-max-size", "--log-flush-frequency", "--password", "--profile", "--profile-output", "--request-timeout",
+	"--server", "--stderrthreshold", "--tls-server-name", "--token", "--user", "--username", "--v", "--vmodule",
+}
+
+// InvokedCommand is the result of one kubectl command of a command invoke batch
+type InvokedCommand struct {
+	Command string `json:"command"`
+	// Completed is false when the session ended before the command finished
+	Completed bool   `json:"completed"`
+	ExitCode  int    `json:"exitCode"`
+	Output    string `json:"output"`
+}
+
+// CommandInvokeResult is the result of the command-invoke operation
+type CommandInvokeResult struct {
+	ClusterName       string           `json:"clusterName"`
+	ProvisioningState string           `json:"provisioningState,omitempty"`
+	StartedAt         string           `json:"startedAt,omitempty"`
+	FinishedAt        string           `json:"finishedAt,omitempty"`
+	Commands          []InvokedCommand `json:"commands"`
+	Failed            int              `json:"failed"`
+}
+
+// InvokeCommands runs the kubectl commands given with --command in a single az aks command invoke session, so
+// clusters without direct API server access pay the session start-up once per batch instead of once per command.
+// The commands are written to a script attached to the session, each argument quoted so it is passed to kubectl
+// as is, and the combined log is split back into the output and exit code of every command.
+func InvokeCommands(args string, run AzRunner, cfg *config.ConfigData) (string, error) {
+	words, err := shlex.Split(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	var clusterName, resourceGroup, subscription string
+	var commands []string
+	for i := 0; i < len(words); i++ {
+		name, value, hasValue := strings.Cut(words[i], "=")
+		switch name {
+		case "--name", "-n", "--resource-group", "-g", "--subscription":
+			if !hasValue {
+				if i+1 >= len(words) {
+					return "", fmt.Errorf("flag %s requires a value", name)
+				}
+				i++
+				value = words[i]
+			}
+			switch name {
+			case "--name", "-n":
+				clusterName = value
+			case "--resource-group", "-g":
+				resourceGroup = value
+			default:
+				subscription = value
+			}
+		case "--command":
+			if hasValue {
+				commands = append(commands, value)
+			}
+			// A single --command may be followed by several commands, as the parameters object passes arrays
+			for i+1 < len(words) && !strings.HasPrefix(words[i+1], "-") {
+				i++
+				commands = append(commands, words[i])
+			}
+		default:
+			return "", fmt.Errorf("unsupported argument '%s' for command-invoke; use --name, --resource-group, --subscription and --command", words[i])
+		}
+	}
+	if clusterName == "" || resourceGroup == "" {
+		return "", fmt.Errorf("command-invoke requires --name and --resource-group")
+	}
+	if len(commands) == 0 {
+		return "", fmt.Errorf("command-invoke requires at least one --command")
+	}
+	if len(commands) > maxInvokeCommands {
+		return "", fmt.Errorf("command-invoke accepts at most %d commands per call, got %d", maxInvokeCommands, len(commands))
+	}
+
+	script, err := BuildInvokeScript(commands, cfg)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "aks-command-invoke-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create batch script directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	scriptPath := filepath.Join(dir, invokeScriptName)
+	if err := os.WriteFile(scriptPath, []byte(script), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write batch script: %w", err)
+	}
+
+	invokeArgs := fmt.Sprintf("aks command invoke --name %s --resource-group %s --command %s --file %s -o json",
+		azcli.QuoteArg(clusterName), azcli.QuoteArg(resourceGroup), azcli.QuoteArg("sh "+invokeScriptName), azcli.QuoteArg(scriptPath))
+	if subscription != "" {
+		invokeArgs += " --subscription " + azcli.QuoteArg(subscription)
+	}
+	output, err := run(invokeArgs)
+	if err != nil {
+		return "", fmt.Errorf("command invoke on cluster %s failed: %w", clusterName, err)
+	}
+
+	var session struct {
+		ProvisioningState string `json:"provisioningState"`
+		StartedAt         string `json:"startedAt"`
+		FinishedAt        string `json:"finishedAt"`
+		Logs              string `json:"logs"`
+	}
+	if err := json.Unmarshal([]byte(output), &session); err != nil {
+		return "", fmt.Errorf("failed to parse command invoke result: %w", err)
+	}
+	result := CommandInvokeResult{
+		ClusterName:       clusterName,
+		ProvisioningState: session.ProvisioningState,
+		StartedAt:         session.StartedAt,
+		FinishedAt:        session.FinishedAt,
+		Commands:          SplitInvokeLogs(commands, session.Logs),
+	}
+	for _, command := range result.Commands {
+		if !command.Completed || command.ExitCode != 0 {
+			result.Failed++
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal command invoke result to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// BuildInvokeScript checks the commands against the access level and allowed namespaces, and returns a shell
+// script running them in order with markers around each command's output and exit code
+func BuildInvokeScript(commands []string, cfg *config.ConfigData) (string, error) {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	for i, command := range commands {
+		argv, err := shlex.Split(command)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse command '%s': %w", command, err)
+		}
+		if len(argv) < 2 || argv[0] != "kubectl" {
+			return "", fmt.Errorf("command '%s' must be a kubectl command", command)
+		}
+		if err := checkKubectlAccess(argv, cfg); err != nil {
+			return "", fmt.Errorf("command '%s': %w", command, err)
+		}
+		quoted := make([]string, len(argv))
+		for j, arg := range argv {
+			quoted[j] = azcli.QuoteArg(arg)
+		}
+		fmt.Fprintf(&script, "echo '%s%d=='\n", invokeStartMarker, i)
+		fmt.Fprintf(&script, "%s 2>&1\n", strings.Join(quoted, " "))
+		fmt.Fprintf(&script, "echo \"%s%d:$?==\"\n", invokeExitMarker, i)
+	}
+	return script.String(), nil
+}
+
+// checkKubectlAccess rejects kubectl commands needing admin access without it, and commands outside the
+// allowed namespaces
+func checkKubectlAccess(argv []string, cfg *config.ConfigData) error {
+	verb := ""
+	namespace := "default"
+	for i := 1; i < len(argv); i++ {
+		arg := argv[i]
+		switch {
+		case arg == "-A" || arg == "--all-namespaces" || arg == "--all-namespaces=true":
+			namespace = ""
+		case arg == "-n" || arg == "--namespace":
+			if i+1 < len(argv) {
+				i++
+				namespace = argv[i]
+			}
+		case strings.HasPrefix(arg, "--namespace="):
+			namespace = strings.TrimPrefix(arg, "--namespace=")
+		case strings.HasPrefix(arg, "-n") && len(arg) > 2:
+			namespace = strings.TrimPrefix(arg[2:], "=")
+		case verb == "" && slices.Contains(kubectlValueFlags, arg):
+			i++
+		case verb == "" && !strings.HasPrefix(arg, "-"):
+			verb = arg
+		}
+	}
+	for _, adminVerb := range adminKubectlVerbs {
+		if verb == adminVerb && cfg.AccessLevel != "admin" {
+			return fmt.Errorf("kubectl %s requires admin access level", verb)
+		}
+	}
+	if cfg.SecurityConfig == nil || cfg.SecurityConfig.AllowedNamespaces == "" {
+		return nil
+	}
+	if namespace == "" {
+		return fmt.Errorf("--all-namespaces is not allowed when the server is restricted to namespaces %s", cfg.SecurityConfig.AllowedNamespaces)
+	}
+	if !cfg.SecurityConfig.IsNamespaceAllowed(namespace) {
+		return fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+	}
+	return nil
+}
+
+// SplitInvokeLogs splits the combined log of a batch into the output and exit code of each command.
+// Commands whose exit marker is missing did not finish before the session ended.
+func SplitInvokeLogs(commands []string, logs string) []InvokedCommand {
+	results := make([]InvokedCommand, len(commands))
+	for i, command := range commands {
+		results[i] = InvokedCommand{Command: command, ExitCode: -1}
+	}
+	current := -1
+	var output []string
+	flush := func() {
+		if current >= 0 && current < len(results) {
+			results[current].Output = strings.TrimRight(strings.Join(output, "\n"), "\n")
+		}
+		output = nil
+	}
+	for _, line := range strings.Split(logs, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, invokeStartMarker) && strings.HasSuffix(trimmed, "=="):
+			flush()
+			index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(trimmed, invokeStartMarker), "=="))
+			if err != nil {
+				index = -1
+			}
+			current = index
+		case strings.HasPrefix(trimmed, invokeExitMarker) && strings.HasSuffix(trimmed, "=="):
+			flush()
+			indexText, codeText, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(trimmed, invokeExitMarker), "=="), ":")
+			index, err := strconv.Atoi(indexText)
+			code, codeErr := strconv.Atoi(codeText)
+			if err == nil && codeErr == nil && index >= 0 && index < len(results) {
+				results[index].Completed = true
+				results[index].ExitCode = code
+			}
+			current = -1
+		default:
+			if current >= 0 {
+				output = append(output, line)
+			}
+		}
+	}
+	flush()
+	return results
+}
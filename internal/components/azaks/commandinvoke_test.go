@@ -0,0 +1,125 @@
+package azaks
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/security"
+	"github.com/google/shlex"
+)
+
+func TestInvokeCommands(t *testing.T) {
+	logs := "==aks-mcp-command:0==\nNAME STATUS\naks-np1-0 Ready\n==aks-mcp-exit:0:0==\n" +
+		"==aks-mcp-command:1==\nError from server (NotFound): namespaces \"shop\" not found\n==aks-mcp-exit:1:1==\n" +
+		"==aks-mcp-command:2==\npartial output\n"
+	session, _ := json.Marshal(map[string]interface{}{
+		"provisioningState": "Succeeded", "startedAt": "2024-05-01T10:00:00Z", "finishedAt": "2024-05-01T10:00:31Z", "logs": logs,
+	})
+
+	var invoked, script string
+	run := func(args string) (string, error) {
+		invoked = args
+		words, err := shlex.Split(args)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", args, err)
+		}
+		for i, word := range words {
+			if word == "--file" {
+				data, err := os.ReadFile(words[i+1])
+				if err != nil {
+					t.Fatalf("Expected the batch script to be attached: %v", err)
+				}
+				script = string(data)
+			}
+		}
+		return string(session), nil
+	}
+	args := "--name myCluster --resource-group rg --command 'kubectl get nodes' 'kubectl get pods -n shop' --command \"kubectl logs web -n shop --selector 'app=web;x'\""
+	output, err := InvokeCommands(args, run, &config.ConfigData{AccessLevel: "readwrite", SecurityConfig: security.NewSecurityConfig()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(invoked, "aks command invoke --name 'myCluster' --resource-group 'rg' --command 'sh aks-mcp-batch.sh' --file ") {
+		t.Errorf("Expected a single command invoke session, got %s", invoked)
+	}
+	if strings.Count(script, "==aks-mcp-command:") != 3 || !strings.Contains(script, "'kubectl' 'logs' 'web' '-n' 'shop' '--selector' 'app=web;x' 2>&1") {
+		t.Errorf("Expected each command quoted in the script, got %s", script)
+	}
+
+	var result CommandInvokeResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(result.Commands) != 3 || result.Failed != 2 || result.FinishedAt != "2024-05-01T10:00:31Z" {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if first := result.Commands[0]; !first.Completed || first.ExitCode != 0 || first.Output != "NAME STATUS\naks-np1-0 Ready" {
+		t.Errorf("Unexpected first command %+v", first)
+	}
+	if second := result.Commands[1]; second.ExitCode != 1 || !strings.Contains(second.Output, "NotFound") {
+		t.Errorf("Unexpected second command %+v", second)
+	}
+	if third := result.Commands[2]; third.Completed || third.Output != "partial output" {
+		t.Errorf("Expected the last command to be reported as unfinished, got %+v", third)
+	}
+}
+
+func TestInvokeCommandsRejected(t *testing.T) {
+	restricted := &config.ConfigData{AccessLevel: "readwrite", SecurityConfig: &security.SecurityConfig{AccessLevel: "readwrite", AllowedNamespaces: "shop"}}
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"--name aks --command 'kubectl get pods'", "requires --name and --resource-group"},
+		{"--name aks --resource-group rg", "at least one --command"},
+		{"--name aks --resource-group rg --command 'helm list'", "must be a kubectl command"},
+		{"--name aks --resource-group rg --command 'kubectl exec -n shop web -- ls'", "kubectl exec requires admin access level"},
+		{"--name aks --resource-group rg --command 'kubectl --request-timeout 5s exec -n shop web -- id'", "kubectl exec requires admin access level"},
+		{"--name aks --resource-group rg --command 'kubectl -v 6 drain node1'", "kubectl drain requires admin access level"},
+		{"--name aks --resource-group rg --command 'kubectl --context aks -s https://aks:443 cordon node1'", "kubectl cordon requires admin access level"},
+		{"--name aks --resource-group rg --command 'kubectl --as admin -n shop debug web'", "kubectl debug requires admin access level"},
+		{"--name aks --resource-group rg --command 'kubectl get pods -A'", "--all-namespaces is not allowed"},
+		{"--name aks --resource-group rg --command 'kubectl get pods --namespace=kube-system'", "access to namespace 'kube-system' is denied"},
+		{"--name aks --resource-group rg --command 'kubectl get pods' --watch", "unsupported argument '--watch'"},
+		{"--name aks --resource-group rg --command " + strings.Repeat("'kubectl get pods -n shop' ", maxInvokeCommands+1), "at most 20 commands"},
+	}
+	for _, tt := range tests {
+		_, err := InvokeCommands(tt.args, func(string) (string, error) {
+			t.Fatalf("Expected %s to be rejected before running", tt.args)
+			return "", nil
+		}, restricted)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %s, got %v", tt.want, tt.args, err)
+		}
+	}
+}
+
+func TestCommandInvokeParameters(t *testing.T) {
+	args, err := azcli.ResolveArgs(map[string]interface{}{"parameters": map[string]interface{}{
+		"name": "aks", "resource_group": "rg", "command": []interface{}{"kubectl get nodes", "kubectl top nodes"},
+	}}, GetOperationParameters(string(OpClusterCommandInvoke)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	script := ""
+	_, _ = InvokeCommands(args, func(invokeArgs string) (string, error) {
+		words, _ := shlex.Split(invokeArgs)
+		for i, word := range words {
+			if word == "--file" {
+				data, _ := os.ReadFile(words[i+1])
+				script = string(data)
+			}
+		}
+		return `{"logs": ""}`, nil
+	}, &config.ConfigData{AccessLevel: "readwrite"})
+	if !strings.Contains(script, "'kubectl' 'get' 'nodes'") || !strings.Contains(script, "'kubectl' 'top' 'nodes'") {
+		t.Errorf("Expected both commands from the parameters array in the script, got %s", script)
+	}
+	if GetOperationAccessLevel(string(OpClusterCommandInvoke)) != "readwrite" {
+		t.Error("Expected command-invoke to require readwrite access")
+	}
+}
@@ -2,8 +2,11 @@ package azaks
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/command"
 	"github.com/Azure/aks-mcp/internal/config"
 	"github.com/Azure/aks-mcp/internal/security"
@@ -24,11 +27,8 @@ func (e *AksOperationsExecutor) Execute(params map[string]interface{}, cfg *conf
 	if !ok {
 		return "", fmt.Errorf("missing or invalid 'operation' parameter")
 	}
-
-	// Parse args parameter
-	args, ok := params["args"].(string)
-	if !ok {
-		args = ""
+	if resourceType, _ := params["resource_type"].(string); resourceType != "" && !slices.Contains(ResourceTypes, resourceType) {
+		return "", fmt.Errorf("invalid resource_type '%s'. Valid values: %s", resourceType, strings.Join(ResourceTypes, ", "))
 	}
 
 	// Validate access for this operation
@@ -36,6 +36,26 @@ func (e *AksOperationsExecutor) Execute(params map[string]interface{}, cfg *conf
 		return "", err
 	}
 
+	// Combine the raw args with flags built from the structured parameters
+	args, err := azcli.ResolveArgs(params, GetOperationParameters(operation))
+	if err != nil {
+		return "", err
+	}
+
+	// nodepool-config runs several az commands and reports on their combined output
+	if operation == string(OpNodepoolConfig) {
+		return InspectNodePoolConfig(args, newAzRunner(cfg), cfg)
+	}
+	// command-invoke batches its kubectl commands into a single command invoke session
+	if operation == string(OpClusterCommandInvoke) {
+		return InvokeCommands(args, newAzRunner(cfg), cfg)
+	}
+
+	// AKS Automatic clusters reject changes to what AKS manages for them
+	if err := CheckAutomaticRestrictions(operation, args, newAzRunner(cfg)); err != nil {
+		return "", err
+	}
+
 	// Map operation to Azure CLI command
 	baseCommand, err := MapOperationToCommand(operation)
 	if err != nil {
@@ -64,11 +84,8 @@ func (e *AksOperationsExecutor) Execute(params map[string]interface{}, cfg *conf
 	// Use the first part as the binary name
 	binaryName := cmdParts[0]
 
-	// The rest of the command becomes the arguments
-	cmdArgs := ""
-	if len(cmdParts) > 1 {
-		cmdArgs = strings.Join(cmdParts[1:], " ")
-	}
+	// The rest of the command becomes the arguments, keeping whitespace inside quoted values
+	cmdArgs := strings.TrimSpace(strings.TrimPrefix(fullCommand, binaryName))
 
 	// If the command is not an az command, return an error
 	if binaryName != "az" {
@@ -77,7 +94,32 @@ func (e *AksOperationsExecutor) Execute(params map[string]interface{}, cfg *conf
 
 	// Execute the command
 	process := command.NewShellProcess(binaryName, cfg.Timeout)
-	return process.Run(cmdArgs)
+	started := time.Now()
+	output, err := azcli.RunWithCache(process, cmdArgs, cfg)
+	if err != nil {
+		return output, err
+	}
+
+	result := output
+	if summarizedOperations[operation] {
+		result = SummarizeProvisioning(output)
+	}
+	if structuredOperations[operation] {
+		opResult := BuildOperationResult(operation, cmdArgs, output, started, time.Since(started))
+		result = FormatOperationResult(opResult, result)
+	}
+	return result, nil
+}
+
+// newAzRunner returns an AzRunner that validates each command against the security settings before running it
+func newAzRunner(cfg *config.ConfigData) AzRunner {
+	validator := security.NewValidator(cfg.SecurityConfig)
+	return func(args string) (string, error) {
+		if err := validator.ValidateCommand("az "+args, security.CommandTypeAz); err != nil {
+			return "", err
+		}
+		return azcli.RunWithCache(command.NewShellProcess("az", cfg.Timeout), args, cfg)
+	}
 }
 
 // ExecuteSpecificCommand executes a specific operation with the given arguments (for backward compatibility)
@@ -0,0 +1,583 @@
+package azaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// AzRunner runs an az command given without the leading "az" and returns its output
+type AzRunner func(args string) (string, error)
+
+// Finding severities of the nodepool-config operation
+const (
+	ConfigSeverityError   = "error"
+	ConfigSeverityWarning = "warning"
+	ConfigSeverityInfo    = "info"
+)
+
+// Default max pods per node set by AKS when a pool is created without --max-pods
+const (
+	defaultMaxPodsAzureCNI = 30
+	defaultMaxPodsKubenet  = 110
+	defaultMaxPodsOverlay  = 250
+)
+
+// nodeSetting is a kubelet or Linux OS setting AKS lets pools customize, with the value nodes get by default.
+// Node is the sysctl or kubelet flag the setting is applied as, used to verify it on a node.
+type nodeSetting struct {
+	Field   string
+	Node    string
+	Default string
+}
+
+// kubeletSettings are the fields of a pool's kubeletConfig
+var kubeletSettings = []nodeSetting{
+	{"cpuManagerPolicy", "cpu-manager-policy", "none"},
+	{"cpuCfsQuota", "cpu-cfs-quota", "true"},
+	{"cpuCfsQuotaPeriod", "cpu-cfs-quota-period", "100ms"},
+	{"imageGcHighThreshold", "image-gc-high-threshold", "85"},
+	{"imageGcLowThreshold", "image-gc-low-threshold", "80"},
+	{"topologyManagerPolicy", "topology-manager-policy", "none"},
+	{"allowedUnsafeSysctls", "allowed-unsafe-sysctls", ""},
+	{"failSwapOn", "fail-swap-on", "true"},
+	{"containerLogMaxSizeMb", "container-log-max-size", "50"},
+	{"containerLogMaxFiles", "container-log-max-files", "5"},
+	{"podMaxPids", "pod-max-pids", "-1"},
+}
+
+// linuxOSSettings are the fields of a pool's linuxOsConfig other than sysctls
+var linuxOSSettings = []nodeSetting{
+	{"transparentHugePageEnabled", "", "always"},
+	{"transparentHugePageDefrag", "", "madvise"},
+	{"swapFileSizeMb", "", "0"},
+}
+
+// sysctlSettings are the fields of linuxOsConfig.sysctls
+var sysctlSettings = []nodeSetting{
+	{"netCoreSomaxconn", "net.core.somaxconn", "16384"},
+	{"netCoreNetdevMaxBacklog", "net.core.netdev_max_backlog", "1000"},
+	{"netCoreRmemDefault", "net.core.rmem_default", "212992"},
+	{"netCoreRmemMax", "net.core.rmem_max", "212992"},
+	{"netCoreWmemDefault", "net.core.wmem_default", "212992"},
+	{"netCoreWmemMax", "net.core.wmem_max", "212992"},
+	{"netCoreOptmemMax", "net.core.optmem_max", "20480"},
+	{"netIpv4TcpMaxSynBacklog", "net.ipv4.tcp_max_syn_backlog", "16384"},
+	{"netIpv4TcpMaxTwBuckets", "net.ipv4.tcp_max_tw_buckets", "32768"},
+	{"netIpv4TcpFinTimeout", "net.ipv4.tcp_fin_timeout", "60"},
+	{"netIpv4TcpKeepaliveTime", "net.ipv4.tcp_keepalive_time", "7200"},
+	{"netIpv4TcpKeepaliveProbes", "net.ipv4.tcp_keepalive_probes", "9"},
+	{"netIpv4TcpkeepaliveIntvl", "net.ipv4.tcp_keepalive_intvl", "75"},
+	{"netIpv4TcpTwReuse", "net.ipv4.tcp_tw_reuse", "false"},
+	{"netIpv4IpLocalPortRange", "net.ipv4.ip_local_port_range", "32768 60999"},
+	{"netIpv4NeighDefaultGcThresh1", "net.ipv4.neigh.default.gc_thresh1", "4096"},
+	{"netIpv4NeighDefaultGcThresh2", "net.ipv4.neigh.default.gc_thresh2", "8192"},
+	{"netIpv4NeighDefaultGcThresh3", "net.ipv4.neigh.default.gc_thresh3", "16384"},
+	{"netNetfilterNfConntrackMax", "net.netfilter.nf_conntrack_max", "131072"},
+	{"netNetfilterNfConntrackBuckets", "net.netfilter.nf_conntrack_buckets", "65536"},
+	{"fsInotifyMaxUserWatches", "fs.inotify.max_user_watches", "1048576"},
+	{"fsFileMax", "fs.file-max", "709620"},
+	{"fsAioMaxNr", "fs.aio-max-nr", "65536"},
+	{"fsNrOpen", "fs.nr_open", "1048576"},
+	{"kernelThreadsMax", "kernel.threads-max", "55601"},
+	{"vmMaxMapCount", "vm.max_map_count", "65530"},
+	{"vmSwappiness", "vm.swappiness", "60"},
+	{"vmVfsCachePressure", "vm.vfs_cache_pressure", "100"},
+}
+
+// capacitySysctls are sysctls whose value caps connections, files or table sizes, so lowering them below the
+// AKS default tends to surface as dropped connections or "too many open files" errors under load
+var capacitySysctls = map[string]bool{
+	"netCoreSomaxconn":             true,
+	"netIpv4TcpMaxSynBacklog":      true,
+	"netIpv4NeighDefaultGcThresh1": true,
+	"netIpv4NeighDefaultGcThresh2": true,
+	"netIpv4NeighDefaultGcThresh3": true,
+	"netNetfilterNfConntrackMax":   true,
+	"fsInotifyMaxUserWatches":      true,
+	"fsFileMax":                    true,
+	"fsNrOpen":                     true,
+}
+
+// ConfigSetting is a kubelet or Linux OS setting set on a node pool
+type ConfigSetting struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	Default    string `json:"default,omitempty"`
+	Customized bool   `json:"customized"`
+}
+
+// ConfigFinding is a node pool setting known to cause problems
+type ConfigFinding struct {
+	Severity string `json:"severity"`
+	Setting  string `json:"setting"`
+	Message  string `json:"message"`
+}
+
+// NodeVerification holds the effective values read from one node of a pool with run-command
+type NodeVerification struct {
+	VMSS       string            `json:"vmss"`
+	InstanceID string            `json:"instanceId"`
+	MaxPods    string            `json:"maxPods,omitempty"`
+	Sysctls    map[string]string `json:"sysctls,omitempty"`
+	Mismatches []string          `json:"mismatches,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// NodePoolConfig is the custom configuration of one node pool
+type NodePoolConfig struct {
+	Name           string            `json:"name"`
+	Mode           string            `json:"mode,omitempty"`
+	OSType         string            `json:"osType,omitempty"`
+	VMSize         string            `json:"vmSize,omitempty"`
+	Count          int               `json:"count"`
+	MaxPods        int               `json:"maxPods"`
+	DefaultMaxPods int               `json:"defaultMaxPods"`
+	KubeletConfig  []ConfigSetting   `json:"kubeletConfig,omitempty"`
+	LinuxOSConfig  []ConfigSetting   `json:"linuxOsConfig,omitempty"`
+	Findings       []ConfigFinding   `json:"findings,omitempty"`
+	Node           *NodeVerification `json:"node,omitempty"`
+}
+
+// NodePoolConfigReport is the result of the nodepool-config operation
+type NodePoolConfigReport struct {
+	ClusterName       string           `json:"clusterName"`
+	ResourceGroup     string           `json:"resourceGroup"`
+	NetworkPlugin     string           `json:"networkPlugin,omitempty"`
+	NetworkPluginMode string           `json:"networkPluginMode,omitempty"`
+	NodePools         []NodePoolConfig `json:"nodePools"`
+	FindingCount      int              `json:"findingCount"`
+	Warnings          []string         `json:"warnings,omitempty"`
+}
+
+// InspectNodePoolConfig reports the kubelet and Linux OS configuration of a cluster's node pools, or of the pool
+// given with --name, against the AKS defaults and flags settings known to cause problems. With --verify-on-node
+// it reads the effective values from one node of each Linux pool with run-command, which needs readwrite access.
+func InspectNodePoolConfig(args string, run AzRunner, cfg *config.ConfigData) (string, error) {
+	flags := commandFlags(args)
+	clusterName := flags["--cluster-name"]
+	resourceGroup := firstFlag(flags, "--resource-group", "-g")
+	if clusterName == "" || resourceGroup == "" {
+		return "", fmt.Errorf("nodepool-config requires --cluster-name and --resource-group")
+	}
+	scope := fmt.Sprintf("--resource-group %s", resourceGroup)
+	if subscription := flags["--subscription"]; subscription != "" {
+		scope += " --subscription " + subscription
+	}
+
+	clusterOutput, err := run(fmt.Sprintf("aks show --name %s %s -o json", clusterName, scope))
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+	var cluster struct {
+		Sku struct {
+			Name string `json:"name"`
+		} `json:"sku"`
+		NodeResourceGroup string `json:"nodeResourceGroup"`
+		NetworkProfile    struct {
+			NetworkPlugin     string `json:"networkPlugin"`
+			NetworkPluginMode string `json:"networkPluginMode"`
+		} `json:"networkProfile"`
+	}
+	if err := json.Unmarshal([]byte(clusterOutput), &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster %s: %w", clusterName, err)
+	}
+
+	var pools []map[string]interface{}
+	if name := firstFlag(flags, "--name", "-n"); name != "" {
+		output, err := run(fmt.Sprintf("aks nodepool show --cluster-name %s --name %s %s -o json", clusterName, name, scope))
+		if err != nil {
+			return "", fmt.Errorf("failed to get node pool %s: %w", name, err)
+		}
+		var pool map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &pool); err != nil {
+			return "", fmt.Errorf("failed to parse node pool %s: %w", name, err)
+		}
+		pools = append(pools, pool)
+	} else {
+		output, err := run(fmt.Sprintf("aks nodepool list --cluster-name %s %s -o json", clusterName, scope))
+		if err != nil {
+			return "", fmt.Errorf("failed to list node pools: %w", err)
+		}
+		if err := json.Unmarshal([]byte(output), &pools); err != nil {
+			return "", fmt.Errorf("failed to parse node pools: %w", err)
+		}
+	}
+
+	report := NodePoolConfigReport{
+		ClusterName:       clusterName,
+		ResourceGroup:     resourceGroup,
+		NetworkPlugin:     cluster.NetworkProfile.NetworkPlugin,
+		NetworkPluginMode: cluster.NetworkProfile.NetworkPluginMode,
+		NodePools:         []NodePoolConfig{},
+	}
+	if common.IsAutomaticSKU(cluster.Sku.Name) {
+		report.Warnings = append(report.Warnings, "this is an AKS Automatic cluster: nodes created by node auto provisioning don't belong to "+
+			"the node pools listed here and take their configuration from NodePool and AKSNodeClass custom resources")
+	}
+	_, verify := flags["--verify-on-node"]
+	if verify && cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+		report.Warnings = append(report.Warnings, "--verify-on-node uses run-command on the node pool's scale set and requires readwrite or admin access; only the configured values are reported")
+		verify = false
+	}
+
+	for _, raw := range pools {
+		pool := AnalyzeNodePoolConfig(raw, report.NetworkPlugin, report.NetworkPluginMode)
+		if verify && strings.EqualFold(pool.OSType, "Linux") {
+			pool.Node = verifyOnNode(run, cluster.NodeResourceGroup, flags["--subscription"], raw, pool)
+		}
+		report.FindingCount += len(pool.Findings)
+		report.NodePools = append(report.NodePools, pool)
+	}
+
+	formatted, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal node pool configuration: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// AnalyzeNodePoolConfig reads the custom configuration of an agent pool from its az JSON and flags settings
+// known to cause problems on a cluster with the given network plugin
+func AnalyzeNodePoolConfig(raw map[string]interface{}, networkPlugin, networkPluginMode string) NodePoolConfig {
+	pool := NodePoolConfig{
+		Name:    stringField(raw, "name"),
+		Mode:    stringField(raw, "mode"),
+		OSType:  stringField(raw, "osType"),
+		VMSize:  stringField(raw, "vmSize"),
+		Count:   int(numberField(raw, "count")),
+		MaxPods: int(numberField(raw, "maxPods")),
+	}
+	podSubnet := stringField(raw, "podSubnetId") != ""
+	azureCNINodeSubnet := strings.EqualFold(networkPlugin, "azure") && !strings.EqualFold(networkPluginMode, "overlay") && !podSubnet
+	switch {
+	case azureCNINodeSubnet:
+		pool.DefaultMaxPods = defaultMaxPodsAzureCNI
+	case strings.EqualFold(networkPluginMode, "overlay") || podSubnet:
+		pool.DefaultMaxPods = defaultMaxPodsOverlay
+	default:
+		pool.DefaultMaxPods = defaultMaxPodsKubenet
+	}
+
+	kubelet, _ := field(raw, "kubeletConfig").(map[string]interface{})
+	linuxOS, _ := field(raw, "linuxOsConfig").(map[string]interface{})
+	sysctls, _ := field(linuxOS, "sysctls").(map[string]interface{})
+	pool.KubeletConfig = configuredSettings(kubelet, kubeletSettings)
+	pool.LinuxOSConfig = append(configuredSettings(linuxOS, linuxOSSettings), configuredSettings(sysctls, sysctlSettings)...)
+
+	finding := func(severity, setting, format string, args ...interface{}) {
+		pool.Findings = append(pool.Findings, ConfigFinding{Severity: severity, Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !strings.EqualFold(pool.OSType, "Linux") && pool.OSType != "" && (len(pool.LinuxOSConfig) > 0 || len(pool.KubeletConfig) > 0) {
+		finding(ConfigSeverityInfo, "linuxOsConfig", "Custom kubelet and Linux OS configuration is not applied to %s node pools", pool.OSType)
+	}
+
+	// Max pods
+	if strings.EqualFold(pool.Mode, "System") && pool.MaxPods > 0 && pool.MaxPods < 30 {
+		finding(ConfigSeverityWarning, "maxPods", "System node pools need at least 30 pods per node for system pods and daemonsets; %d leaves little room and blocks scheduling of add-ons", pool.MaxPods)
+	}
+	if azureCNINodeSubnet && pool.MaxPods > 0 && pool.MaxPods < defaultMaxPodsAzureCNI {
+		finding(ConfigSeverityWarning, "maxPods", "With Azure CNI each node reserves its max pods as subnet IPs, and about 10 of %d pods go to daemonsets and system pods; workloads fit on few nodes and max pods can only be raised by creating a new node pool", pool.MaxPods)
+	}
+	if azureCNINodeSubnet && pool.MaxPods > defaultMaxPodsKubenet {
+		finding(ConfigSeverityInfo, "maxPods", "With Azure CNI every node takes %d subnet IPs up front, plus as many for each surge node during upgrades; check the subnet has room for (node count + max surge) x %d addresses", pool.MaxPods+1, pool.MaxPods+1)
+	}
+
+	// Kubelet
+	high, highSet := numberValue(kubelet, "imageGcHighThreshold")
+	low, lowSet := numberValue(kubelet, "imageGcLowThreshold")
+	if highSet || lowSet {
+		if !highSet {
+			high = 85
+		}
+		if !lowSet {
+			low = 80
+		}
+		if low >= high {
+			finding(ConfigSeverityError, "imageGcLowThreshold", "Image GC low threshold %.0f%% must be below the high threshold %.0f%%", low, high)
+		}
+		if high >= 90 {
+			finding(ConfigSeverityWarning, "imageGcHighThreshold", "Image GC starts at %.0f%% disk usage, at or past the kubelet's 90%% nodefs eviction threshold, so pods are evicted for disk pressure before unused images are removed", high)
+		}
+		if low < 50 {
+			finding(ConfigSeverityInfo, "imageGcLowThreshold", "Image GC frees disk down to %.0f%%, which removes cached images that pods then pull again", low)
+		}
+	}
+	if pids, ok := numberValue(kubelet, "podMaxPids"); ok && pids > 0 && pids < 1024 {
+		finding(ConfigSeverityWarning, "podMaxPids", "Pods are limited to %.0f processes; runtimes such as the JVM, Node.js worker pools or nginx workers fail with \"cannot allocate memory\" or \"resource temporarily unavailable\"", pids)
+	}
+	if quota, ok := field(kubelet, "cpuCfsQuota").(bool); ok && !quota {
+		finding(ConfigSeverityInfo, "cpuCfsQuota", "CPU limits are not enforced, so a container can use more CPU than its limit")
+	}
+	topology := stringField(kubelet, "topologyManagerPolicy")
+	if (topology == "single-numa-node" || topology == "restricted") && stringField(kubelet, "cpuManagerPolicy") != "static" {
+		finding(ConfigSeverityWarning, "topologyManagerPolicy", "Topology manager policy %s only aligns CPUs with the static CPU manager policy; pods may be rejected with TopologyAffinityError", topology)
+	}
+	if unsafe := stringList(field(kubelet, "allowedUnsafeSysctls")); len(unsafe) > 0 {
+		finding(ConfigSeverityWarning, "allowedUnsafeSysctls", "Pods may set unsafe sysctls %s, which can affect other pods on the node; restrict them with a policy", strings.Join(unsafe, ", "))
+	}
+	swap, _ := numberValue(linuxOS, "swapFileSizeMb")
+	failSwapOn, failSwapOnSet := field(kubelet, "failSwapOn").(bool)
+	if swap > 0 && (!failSwapOnSet || failSwapOn) {
+		finding(ConfigSeverityError, "failSwapOn", "A %.0f MB swap file is configured but failSwapOn is not false, so the kubelet fails to start on the pool's nodes", swap)
+	}
+
+	// Sysctls
+	for _, setting := range sysctlSettings {
+		value, ok := numberValue(sysctls, setting.Field)
+		if !ok || !capacitySysctls[setting.Field] {
+			continue
+		}
+		def, _ := strconv.ParseFloat(setting.Default, 64)
+		if value < def {
+			finding(ConfigSeverityWarning, setting.Field, "%s is %.0f, below the AKS default of %s; busy nodes can drop connections or run out of file handles", setting.Node, value, setting.Default)
+		}
+	}
+	if portRange := stringField(sysctls, "netIpv4IpLocalPortRange"); portRange != "" {
+		if start, err := strconv.Atoi(strings.Fields(portRange)[0]); err == nil && start <= 32767 {
+			finding(ConfigSeverityWarning, "netIpv4IpLocalPortRange", "The local port range %q overlaps the NodePort range 30000-32767; outbound connections can take ports NodePort services need", portRange)
+		}
+	}
+	if maxMap, ok := numberValue(sysctls, "vmMaxMapCount"); ok && maxMap < 65530 {
+		finding(ConfigSeverityWarning, "vmMaxMapCount", "vm.max_map_count is %.0f, below the default of 65530; Elasticsearch and other memory-mapped workloads need 262144", maxMap)
+	}
+
+	return pool
+}
+
+// verifyOnNode reads the effective max pods and sysctls from the first instance of the pool's scale set
+func verifyOnNode(run AzRunner, nodeResourceGroup, subscription string, raw map[string]interface{}, pool NodePoolConfig) *NodeVerification {
+	verification := &NodeVerification{}
+	scope := "--resource-group " + nodeResourceGroup
+	if subscription != "" {
+		scope += " --subscription " + subscription
+	}
+
+	output, err := run(fmt.Sprintf("vmss list %s --query \"[].{name:name, tags:tags}\" -o json", scope))
+	if err != nil {
+		verification.Error = fmt.Sprintf("failed to list scale sets in %s: %v", nodeResourceGroup, err)
+		return verification
+	}
+	var scaleSets []struct {
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(output), &scaleSets); err != nil {
+		verification.Error = fmt.Sprintf("failed to parse scale sets: %v", err)
+		return verification
+	}
+	for _, vmss := range scaleSets {
+		if vmss.Tags["aks-managed-poolName"] == pool.Name {
+			verification.VMSS = vmss.Name
+			break
+		}
+	}
+	if verification.VMSS == "" {
+		verification.Error = fmt.Sprintf("no scale set of node pool %s found in %s", pool.Name, nodeResourceGroup)
+		return verification
+	}
+
+	output, err = run(fmt.Sprintf("vmss list-instances --name %s %s --query \"[0].instanceId\" -o tsv", verification.VMSS, scope))
+	if err != nil || strings.TrimSpace(output) == "" {
+		verification.Error = fmt.Sprintf("no instance of scale set %s to inspect", verification.VMSS)
+		return verification
+	}
+	verification.InstanceID = strings.TrimSpace(output)
+
+	sysctlNames := make([]string, 0, len(sysctlSettings))
+	for _, setting := range sysctlSettings {
+		sysctlNames = append(sysctlNames, setting.Node)
+	}
+	scripts := []string{
+		"echo ==kubelet==", "ps -o args= -C kubelet",
+		"echo ==kubeletconfig==", "cat /etc/default/kubeletconfig.json",
+		"echo ==sysctl==", "sysctl -e " + strings.Join(sysctlNames, " "),
+	}
+	output, err = run(fmt.Sprintf("vmss run-command invoke --name %s %s --instance-id %s --command-id RunShellScript --scripts \"%s\" -o json",
+		verification.VMSS, scope, verification.InstanceID, strings.Join(scripts, "\" \"")))
+	if err != nil {
+		verification.Error = fmt.Sprintf("run-command failed on %s instance %s: %v", verification.VMSS, verification.InstanceID, err)
+		return verification
+	}
+
+	verification.MaxPods, verification.Sysctls = ParseNodeConfigOutput(output)
+	if verification.MaxPods != "" && pool.MaxPods > 0 && verification.MaxPods != strconv.Itoa(pool.MaxPods) {
+		verification.Mismatches = append(verification.Mismatches, fmt.Sprintf("max pods is %s on the node but %d on the pool", verification.MaxPods, pool.MaxPods))
+	}
+	linuxOS, _ := field(raw, "linuxOsConfig").(map[string]interface{})
+	sysctls, _ := field(linuxOS, "sysctls").(map[string]interface{})
+	for _, setting := range sysctlSettings {
+		configured := field(sysctls, setting.Field)
+		actual, ok := verification.Sysctls[setting.Node]
+		if configured == nil || !ok {
+			continue
+		}
+		if want := formatValue(configured); strings.Join(strings.Fields(actual), " ") != sysctlValue(want) {
+			verification.Mismatches = append(verification.Mismatches, fmt.Sprintf("%s is %s on the node but %s on the pool", setting.Node, actual, want))
+		}
+	}
+	if len(verification.Mismatches) > 0 {
+		verification.Mismatches = append(verification.Mismatches, "Nodes created before the configuration changed keep their old settings until they are reimaged or the pool is upgraded")
+	}
+	return verification
+}
+
+// ParseNodeConfigOutput reads the kubelet max pods and sysctl values from the output of the run-command script
+// used by --verify-on-node
+func ParseNodeConfigOutput(output string) (string, map[string]string) {
+	message := output
+	var result struct {
+		Value []struct {
+			Message string `json:"message"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err == nil && len(result.Value) > 0 {
+		message = result.Value[0].Message
+	}
+	if start := strings.Index(message, "[stdout]"); start >= 0 {
+		message = message[start+len("[stdout]"):]
+	}
+	if end := strings.Index(message, "[stderr]"); end >= 0 {
+		message = message[:end]
+	}
+
+	maxPods := ""
+	sysctls := make(map[string]string)
+	section := ""
+	var kubeletConfig strings.Builder
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "==") && strings.HasSuffix(line, "==") {
+			section = strings.Trim(line, "=")
+			continue
+		}
+		switch section {
+		case "kubelet":
+			for _, arg := range strings.Fields(line) {
+				if value, ok := strings.CutPrefix(arg, "--max-pods="); ok {
+					maxPods = value
+				}
+			}
+		case "kubeletconfig":
+			kubeletConfig.WriteString(line)
+		case "sysctl":
+			if name, value, ok := strings.Cut(line, "="); ok {
+				sysctls[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), " ")
+			}
+		}
+	}
+	if maxPods == "" && kubeletConfig.Len() > 0 {
+		var fileConfig struct {
+			MaxPods json.Number `json:"maxPods"`
+		}
+		if err := json.Unmarshal([]byte(kubeletConfig.String()), &fileConfig); err == nil {
+			maxPods = fileConfig.MaxPods.String()
+		}
+	}
+	return maxPods, sysctls
+}
+
+// configuredSettings lists the known settings set in a configuration object, followed by unknown ones
+func configuredSettings(object map[string]interface{}, known []nodeSetting) []ConfigSetting {
+	var settings []ConfigSetting
+	seen := make(map[string]bool)
+	for _, setting := range known {
+		seen[strings.ToLower(setting.Field)] = true
+		value := field(object, setting.Field)
+		if value == nil {
+			continue
+		}
+		formatted := formatValue(value)
+		settings = append(settings, ConfigSetting{
+			Name:       setting.Field,
+			Value:      formatted,
+			Default:    setting.Default,
+			Customized: formatted != setting.Default,
+		})
+	}
+	var unknown []string
+	for name, value := range object {
+		if _, nested := value.(map[string]interface{}); !seen[strings.ToLower(name)] && value != nil && !nested {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		settings = append(settings, ConfigSetting{Name: name, Value: formatValue(object[name]), Customized: true})
+	}
+	return settings
+}
+
+// field returns a value of a JSON object by case-insensitive name, since az output and ARM JSON differ in case
+func field(object map[string]interface{}, name string) interface{} {
+	if value, ok := object[name]; ok {
+		return value
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+func stringField(object map[string]interface{}, name string) string {
+	s, _ := field(object, name).(string)
+	return s
+}
+
+func numberField(object map[string]interface{}, name string) float64 {
+	n, _ := numberValue(object, name)
+	return n
+}
+
+// numberValue returns a numeric setting and whether it is set
+func numberValue(object map[string]interface{}, name string) (float64, bool) {
+	n, ok := field(object, name).(float64)
+	return n, ok
+}
+
+// stringList returns the strings of a JSON array
+func stringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// formatValue renders a JSON value the way AKS documents setting values
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		return strings.Join(stringList(v), ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sysctlValue renders a configured value the way sysctl prints it
+func sysctlValue(value string) string {
+	switch value {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,131 @@
+package azaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// fakeAzRunner answers az commands by the first response whose key the command starts with
+func fakeAzRunner(responses map[string]string, calls *[]string) AzRunner {
+	return func(args string) (string, error) {
+		*calls = append(*calls, args)
+		for prefix, output := range responses {
+			if strings.HasPrefix(args, prefix) {
+				return output, nil
+			}
+		}
+		return "", fmt.Errorf("unexpected command: %s", args)
+	}
+}
+
+func hasFinding(pool NodePoolConfig, setting, severity string) bool {
+	for _, finding := range pool.Findings {
+		if finding.Setting == setting && finding.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzeNodePoolConfig(t *testing.T) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"name": "user1", "mode": "User", "osType": "Linux", "vmSize": "Standard_D4s_v5", "count": 3, "maxPods": 15,
+		"kubeletConfig": {"imageGcHighThreshold": 92, "imageGcLowThreshold": 95, "podMaxPids": 512, "cpuCfsQuota": null,
+			"topologyManagerPolicy": "single-numa-node", "allowedUnsafeSysctls": ["net.*"]},
+		"linuxOsConfig": {"swapFileSizeMb": 1024, "transparentHugePageEnabled": "always",
+			"sysctls": {"netCoreSomaxconn": 4096, "netIpv4IpLocalPortRange": "30000 60999", "vmMaxMapCount": 262144}}
+	}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := AnalyzeNodePoolConfig(raw, "azure", "")
+	if pool.DefaultMaxPods != 30 || pool.MaxPods != 15 || pool.Count != 3 {
+		t.Errorf("Unexpected pool %+v", pool)
+	}
+	for _, expected := range []struct{ setting, severity string }{
+		{"maxPods", ConfigSeverityWarning},
+		{"imageGcLowThreshold", ConfigSeverityError},
+		{"imageGcHighThreshold", ConfigSeverityWarning},
+		{"podMaxPids", ConfigSeverityWarning},
+		{"topologyManagerPolicy", ConfigSeverityWarning},
+		{"allowedUnsafeSysctls", ConfigSeverityWarning},
+		{"failSwapOn", ConfigSeverityError},
+		{"netCoreSomaxconn", ConfigSeverityWarning},
+		{"netIpv4IpLocalPortRange", ConfigSeverityWarning},
+	} {
+		if !hasFinding(pool, expected.setting, expected.severity) {
+			t.Errorf("Expected a %s finding for %s, got %+v", expected.severity, expected.setting, pool.Findings)
+		}
+	}
+	if hasFinding(pool, "vmMaxMapCount", ConfigSeverityWarning) {
+		t.Error("Expected no finding for a raised vm.max_map_count")
+	}
+	for _, setting := range pool.LinuxOSConfig {
+		if setting.Name == "transparentHugePageEnabled" && setting.Customized {
+			t.Error("Expected the default transparent huge page setting not to be customized")
+		}
+	}
+
+	// The same max pods is fine with Azure CNI Overlay
+	pool = AnalyzeNodePoolConfig(map[string]interface{}{"name": "user1", "mode": "User", "osType": "Linux", "maxPods": 15.0}, "azure", "overlay")
+	if pool.DefaultMaxPods != 250 || len(pool.Findings) != 0 {
+		t.Errorf("Expected no findings with overlay, got %+v", pool)
+	}
+}
+
+func TestInspectNodePoolConfig(t *testing.T) {
+	runOutput := `{"value": [{"code": "ProvisioningState/succeeded", "message": "Enable succeeded: \n[stdout]\n==kubelet==\n/usr/local/bin/kubelet --node-ip=10.224.0.4 --max-pods=30 --v=2\n==kubeletconfig==\ncat: /etc/default/kubeletconfig.json: No such file or directory\n==sysctl==\nnet.core.somaxconn = 16384\nnet.ipv4.ip_local_port_range = 32768\t60999\n\n[stderr]\n"}]}`
+	responses := map[string]string{
+		"aks show":            `{"nodeResourceGroup": "MC_rg", "networkProfile": {"networkPlugin": "azure"}}`,
+		"aks nodepool list":   `[{"name": "np1", "mode": "System", "osType": "Linux", "maxPods": 30, "linuxOsConfig": {"sysctls": {"netCoreSomaxconn": 32768}}}]`,
+		"vmss list ":          `[{"name": "aks-np1-123-vmss", "tags": {"aks-managed-poolName": "np1"}}]`,
+		"vmss list-instances": "0\n",
+		"vmss run-command":    runOutput,
+	}
+
+	var calls []string
+	output, err := InspectNodePoolConfig("--cluster-name myCluster --resource-group rg --verify-on-node", fakeAzRunner(responses, &calls), &config.ConfigData{AccessLevel: "readwrite"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report NodePoolConfigReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(report.NodePools) != 1 || report.NodePools[0].Node == nil {
+		t.Fatalf("Expected one verified pool, got %s", output)
+	}
+	node := report.NodePools[0].Node
+	if node.VMSS != "aks-np1-123-vmss" || node.InstanceID != "0" || node.MaxPods != "30" {
+		t.Errorf("Unexpected verification %+v", node)
+	}
+	if len(node.Mismatches) != 2 || !strings.Contains(node.Mismatches[0], "net.core.somaxconn is 16384") {
+		t.Errorf("Expected a somaxconn mismatch, got %v", node.Mismatches)
+	}
+
+	// readonly reports the configuration without running commands on nodes
+	calls = nil
+	output, err = InspectNodePoolConfig("--cluster-name myCluster --resource-group rg --verify-on-node", fakeAzRunner(responses, &calls), &config.ConfigData{AccessLevel: "readonly"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calls) != 2 || !strings.Contains(output, "requires readwrite") {
+		t.Errorf("Expected only the cluster and node pool reads with a warning, got calls %v and %s", calls, output)
+	}
+
+	if _, err := InspectNodePoolConfig("--resource-group rg", fakeAzRunner(responses, &calls), &config.ConfigData{}); err == nil {
+		t.Error("Expected an error without --cluster-name")
+	}
+}
+
+func TestParseNodeConfigOutput(t *testing.T) {
+	maxPods, sysctls := ParseNodeConfigOutput("==kubelet==\n/usr/local/bin/kubelet --config=/etc/default/kubeletconfig.json\n==kubeletconfig==\n{\n\"maxPods\": 110\n}\n==sysctl==\nvm.max_map_count = 65530\n")
+	if maxPods != "110" || sysctls["vm.max_map_count"] != "65530" {
+		t.Errorf("Unexpected values %q and %v", maxPods, sysctls)
+	}
+}
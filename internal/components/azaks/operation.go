@@ -0,0 +1,199 @@
+package azaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+)
+
+// structuredOperations are the write operations whose result starts with an OperationResult block
+var structuredOperations = map[string]bool{
+	string(OpClusterCreate):                  true,
+	string(OpClusterDelete):                  true,
+	string(OpClusterScale):                   true,
+	string(OpClusterStart):                   true,
+	string(OpClusterStop):                    true,
+	string(OpClusterUpdate):                  true,
+	string(OpClusterUpgrade):                 true,
+	string(OpNodepoolAdd):                    true,
+	string(OpNodepoolDelete):                 true,
+	string(OpNodepoolScale):                  true,
+	string(OpNodepoolUpgrade):                true,
+	string(OpSnapshotCreate):                 true,
+	string(OpSnapshotDelete):                 true,
+	string(OpExtensionCreate):                true,
+	string(OpTrustedAccessRoleBindingCreate): true,
+}
+
+// deleteOperations print nothing when they succeed
+var deleteOperations = map[string]bool{
+	string(OpClusterDelete):  true,
+	string(OpNodepoolDelete): true,
+	string(OpSnapshotDelete): true,
+}
+
+// OperationResult is the outcome of a write operation in a form later tool calls can use without
+// parsing az output. ResourceID is empty when az printed no resource and --subscription was not given.
+type OperationResult struct {
+	Operation         string  `json:"operation"`
+	ResourceID        string  `json:"resourceId,omitempty"`
+	ResourceGroup     string  `json:"resourceGroup,omitempty"`
+	ClusterName       string  `json:"clusterName,omitempty"`
+	Name              string  `json:"name,omitempty"`
+	ProvisioningState string  `json:"provisioningState"`
+	PowerState        string  `json:"powerState,omitempty"`
+	StartedAt         string  `json:"startedAt"`
+	CompletedAt       string  `json:"completedAt"`
+	DurationSeconds   float64 `json:"durationSeconds"`
+	// NoWait is true when --no-wait returned before the operation finished
+	NoWait bool   `json:"noWait,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// StructuredOutput puts the OperationResult of a write operation ahead of the az output it was read from
+type StructuredOutput struct {
+	OperationResult OperationResult `json:"operationResult"`
+	Result          interface{}     `json:"result,omitempty"`
+}
+
+// BuildOperationResult reads the resource ID and provisioning state from az JSON output, falling back to the
+// command's flags for operations that print nothing, such as deletes and start, stop or --no-wait calls
+func BuildOperationResult(operation, args, output string, started time.Time, elapsed time.Duration) OperationResult {
+	flags := commandFlags(args)
+	result := OperationResult{
+		Operation:       operation,
+		ResourceGroup:   firstFlag(flags, "--resource-group", "-g"),
+		ClusterName:     flags["--cluster-name"],
+		Name:            firstFlag(flags, "--name", "-n"),
+		StartedAt:       started.UTC().Format(time.RFC3339),
+		CompletedAt:     started.Add(elapsed).UTC().Format(time.RFC3339),
+		DurationSeconds: float64(elapsed.Milliseconds()) / 1000,
+	}
+	_, result.NoWait = flags["--no-wait"]
+
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &object); err == nil {
+		props, _ := object["properties"].(map[string]interface{})
+		str := func(name string) string {
+			if s, ok := object[name].(string); ok && s != "" {
+				return s
+			}
+			s, _ := props[name].(string)
+			return s
+		}
+		result.ResourceID = str("id")
+		result.ProvisioningState = str("provisioningState")
+		if power, ok := object["powerState"].(map[string]interface{}); ok {
+			result.PowerState, _ = power["code"].(string)
+		}
+		if name := str("name"); name != "" {
+			result.Name = name
+		}
+	}
+
+	if result.ResourceID == "" {
+		result.ResourceID = resourceIDFromFlags(operation, flags["--subscription"], result.ResourceGroup, result.ClusterName, result.Name)
+	}
+	if result.ProvisioningState == "" {
+		switch {
+		case result.NoWait:
+			result.ProvisioningState = "Accepted"
+			result.Note = "The operation was accepted and continues in the background; show the resource to follow its provisioning state"
+		case deleteOperations[operation]:
+			result.ProvisioningState = "Deleted"
+		default:
+			result.ProvisioningState = "Succeeded"
+		}
+	}
+	return result
+}
+
+// FormatOperationResult renders the OperationResult ahead of the operation's output. JSON output is embedded
+// as JSON; any other output, such as the result of -o tsv, is embedded as a string.
+func FormatOperationResult(result OperationResult, output string) string {
+	structured := StructuredOutput{OperationResult: result}
+	trimmed := strings.TrimSpace(output)
+	if trimmed != "" {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
+			structured.Result = parsed
+		} else {
+			structured.Result = output
+		}
+	}
+	formatted, err := json.MarshalIndent(structured, "", "  ")
+	if err != nil {
+		return output
+	}
+	return string(formatted)
+}
+
+// resourceIDFromFlags builds the ID of the resource an operation targets. The subscription is only known
+// when --subscription was given, since the az CLI default is not read.
+func resourceIDFromFlags(operation, subscription, resourceGroup, clusterName, name string) string {
+	if subscription == "" || resourceGroup == "" {
+		return ""
+	}
+	base := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService", subscription, resourceGroup)
+	switch operation {
+	case string(OpNodepoolAdd), string(OpNodepoolDelete), string(OpNodepoolScale), string(OpNodepoolUpgrade):
+		if clusterName == "" || name == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/managedClusters/%s/agentPools/%s", base, clusterName, name)
+	case string(OpSnapshotCreate), string(OpSnapshotDelete):
+		if name == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/snapshots/%s", base, name)
+	case string(OpExtensionCreate):
+		if clusterName == "" || name == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/managedClusters/%s/providers/Microsoft.KubernetesConfiguration/extensions/%s", base, clusterName, name)
+	case string(OpTrustedAccessRoleBindingCreate):
+		if clusterName == "" || name == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/managedClusters/%s/trustedAccessRoleBindings/%s", base, clusterName, name)
+	default:
+		if name == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/managedClusters/%s", base, name)
+	}
+}
+
+// commandFlags returns the flags of an az command with their values, keeping their case. Bare flags map to "".
+func commandFlags(args string) map[string]string {
+	parts, err := shlex.Split(args)
+	if err != nil {
+		parts = strings.Fields(args)
+	}
+	flags := make(map[string]string)
+	for i := 0; i < len(parts); i++ {
+		if !strings.HasPrefix(parts[i], "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(parts[i], "=")
+		if !hasValue && i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "-") {
+			value = parts[i+1]
+			i++
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// firstFlag returns the value of the first of the given flags that is set
+func firstFlag(flags map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := flags[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package azaks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildOperationResult(t *testing.T) {
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	output := `{"id": "/subscriptions/sub/resourceGroups/myRG/providers/Microsoft.ContainerService/managedClusters/myCluster/agentPools/np1",
+		"name": "np1", "provisioningState": "Succeeded", "powerState": {"code": "Running"}, "count": 5}`
+
+	result := BuildOperationResult(string(OpNodepoolScale), "aks nodepool scale --cluster-name myCluster -g myRG --name np1 --node-count 5", output, started, 312500*time.Millisecond)
+	if result.ResourceID != "/subscriptions/sub/resourceGroups/myRG/providers/Microsoft.ContainerService/managedClusters/myCluster/agentPools/np1" {
+		t.Errorf("Expected the resource ID from the output, got %q", result.ResourceID)
+	}
+	if result.ProvisioningState != "Succeeded" || result.PowerState != "Running" || result.ResourceGroup != "myRG" || result.ClusterName != "myCluster" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if result.DurationSeconds != 312.5 || result.CompletedAt != "2024-05-01T10:05:12Z" {
+		t.Errorf("Unexpected timing %+v", result)
+	}
+
+	tests := []struct {
+		operation string
+		args      string
+		id        string
+		state     string
+	}{
+		{string(OpClusterDelete), "aks delete --name myCluster --resource-group myRG --subscription sub --yes",
+			"/subscriptions/sub/resourceGroups/myRG/providers/Microsoft.ContainerService/managedClusters/myCluster", "Deleted"},
+		{string(OpNodepoolAdd), "aks nodepool add --cluster-name myCluster --resource-group myRG --name np2 --subscription sub --no-wait",
+			"/subscriptions/sub/resourceGroups/myRG/providers/Microsoft.ContainerService/managedClusters/myCluster/agentPools/np2", "Accepted"},
+		{string(OpSnapshotDelete), "aks nodepool snapshot delete --name=snap --resource-group myRG --subscription sub --yes",
+			"/subscriptions/sub/resourceGroups/myRG/providers/Microsoft.ContainerService/snapshots/snap", "Deleted"},
+		{string(OpClusterStop), "aks stop --name myCluster --resource-group myRG", "", "Succeeded"},
+	}
+	for _, tt := range tests {
+		result := BuildOperationResult(tt.operation, tt.args, "", started, time.Second)
+		if result.ResourceID != tt.id || result.ProvisioningState != tt.state {
+			t.Errorf("%s: expected %q and %s, got %+v", tt.operation, tt.id, tt.state, result)
+		}
+	}
+}
+
+func TestFormatOperationResult(t *testing.T) {
+	result := OperationResult{Operation: string(OpClusterScale), ProvisioningState: "Succeeded"}
+
+	var structured struct {
+		OperationResult OperationResult `json:"operationResult"`
+		Result          interface{}     `json:"result"`
+	}
+	formatted := FormatOperationResult(result, `{"name": "myCluster"}`)
+	if err := json.Unmarshal([]byte(formatted), &structured); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if structured.OperationResult.ProvisioningState != "Succeeded" || structured.Result.(map[string]interface{})["name"] != "myCluster" {
+		t.Errorf("Unexpected structured output %s", formatted)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(formatted[1:]), `"operationResult"`) {
+		t.Errorf("Expected the operation result first, got %s", formatted)
+	}
+
+	formatted = FormatOperationResult(result, "Succeeded\n")
+	if err := json.Unmarshal([]byte(formatted), &structured); err != nil || structured.Result != "Succeeded\n" {
+		t.Errorf("Expected non-JSON output to be embedded as a string, got %s", formatted)
+	}
+	if formatted = FormatOperationResult(result, ""); strings.Contains(formatted, `"result"`) {
+		t.Errorf("Expected no result for empty output, got %s", formatted)
+	}
+}
@@ -0,0 +1,135 @@
+package azaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// summarizedOperations are the operations whose output is reduced to a provisioning summary
+var summarizedOperations = map[string]bool{
+	string(OpExtensionList):                  true,
+	string(OpExtensionShow):                  true,
+	string(OpExtensionCreate):                true,
+	string(OpTrustedAccessRoleBindingList):   true,
+	string(OpTrustedAccessRoleBindingShow):   true,
+	string(OpTrustedAccessRoleBindingCreate): true,
+}
+
+// ProvisioningItem is the provisioning state of a cluster extension or trusted access role binding
+type ProvisioningItem struct {
+	Name              string   `json:"name"`
+	Type              string   `json:"type,omitempty"`
+	Version           string   `json:"version,omitempty"`
+	SourceResourceID  string   `json:"sourceResourceId,omitempty"`
+	Roles             []string `json:"roles,omitempty"`
+	ProvisioningState string   `json:"provisioningState"`
+	Errors            []string `json:"errors,omitempty"`
+}
+
+// ProvisioningSummary lists provisioning states and counts the items that failed
+type ProvisioningSummary struct {
+	Items       []ProvisioningItem `json:"items"`
+	FailedCount int                `json:"failedCount"`
+	Note        string             `json:"note,omitempty"`
+}
+
+// SummarizeProvisioning reduces az k8s-extension or trusted access role binding output to each
+// item's provisioning state and error messages. Output that is not a JSON object or array of
+// objects, such as the result of a --query, is returned unchanged.
+func SummarizeProvisioning(output string) string {
+	trimmed := strings.TrimSpace(output)
+	var objects []map[string]interface{}
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal([]byte(trimmed), &objects); err != nil {
+			return output
+		}
+	} else {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &object); err != nil {
+			return output
+		}
+		objects = []map[string]interface{}{object}
+	}
+
+	summary := ProvisioningSummary{Items: []ProvisioningItem{}}
+	for _, object := range objects {
+		item := provisioningItem(object)
+		if strings.EqualFold(item.ProvisioningState, "Failed") || len(item.Errors) > 0 {
+			summary.FailedCount++
+		}
+		summary.Items = append(summary.Items, item)
+	}
+	if summary.FailedCount > 0 {
+		summary.Note = "Failed installs usually need the error resolved and the extension deleted and created again; check the error messages and the extension's pods in its release namespace"
+	}
+
+	result, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return output
+	}
+	return string(result)
+}
+
+// provisioningItem reads an item whose fields may be flattened or nested under properties
+func provisioningItem(object map[string]interface{}) ProvisioningItem {
+	props, _ := object["properties"].(map[string]interface{})
+	field := func(name string) interface{} {
+		if value, ok := object[name]; ok && value != nil {
+			return value
+		}
+		return props[name]
+	}
+	str := func(name string) string {
+		s, _ := field(name).(string)
+		return s
+	}
+
+	item := ProvisioningItem{
+		Name:              str("name"),
+		Type:              str("extensionType"),
+		Version:           str("version"),
+		SourceResourceID:  str("sourceResourceId"),
+		ProvisioningState: str("provisioningState"),
+	}
+	if roles, ok := field("roles").([]interface{}); ok {
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				item.Roles = append(item.Roles, s)
+			}
+		}
+	}
+	if info, ok := field("errorInfo").(map[string]interface{}); ok {
+		if message := errorMessage(info); message != "" {
+			item.Errors = append(item.Errors, message)
+		}
+	}
+	if statuses, ok := field("statuses").([]interface{}); ok {
+		for _, raw := range statuses {
+			status, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if level, _ := status["level"].(string); strings.EqualFold(level, "Error") {
+				if message := errorMessage(status); message != "" {
+					item.Errors = append(item.Errors, message)
+				}
+			}
+		}
+	}
+	return item
+}
+
+// errorMessage formats a code and message pair
+func errorMessage(info map[string]interface{}) string {
+	code, _ := info["code"].(string)
+	message, _ := info["message"].(string)
+	switch {
+	case code != "" && message != "":
+		return fmt.Sprintf("%s: %s", code, message)
+	case message != "":
+		return message
+	default:
+		return code
+	}
+}
@@ -0,0 +1,43 @@
+package azaks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSummarizeProvisioning(t *testing.T) {
+	output := `[
+		{"name": "flux", "extensionType": "microsoft.flux", "version": "1.8.2", "provisioningState": "Succeeded", "statuses": []},
+		{"name": "azure-aks-backup", "extensionType": "microsoft.dataprotection.kubernetes", "provisioningState": "Failed",
+		 "errorInfo": {"code": "ExtensionOperationFailed", "message": "helm install timed out"},
+		 "statuses": [{"code": "InstallFailed", "level": "Error", "message": "pod dataprotection-microsoft-kubernetes crashlooping"}, {"level": "Information", "message": "ok"}]},
+		{"name": "aml", "properties": {"provisioningState": "Succeeded", "roles": ["Microsoft.MachineLearningServices/workspaces/mlworkload"], "sourceResourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.MachineLearningServices/workspaces/ws"}}
+	]`
+
+	var summary ProvisioningSummary
+	if err := json.Unmarshal([]byte(SummarizeProvisioning(output)), &summary); err != nil {
+		t.Fatalf("Expected a JSON summary: %v", err)
+	}
+	if len(summary.Items) != 3 || summary.FailedCount != 1 || summary.Note == "" {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+	backup := summary.Items[1]
+	if len(backup.Errors) != 2 || backup.Errors[0] != "ExtensionOperationFailed: helm install timed out" || backup.Errors[1] != "InstallFailed: pod dataprotection-microsoft-kubernetes crashlooping" {
+		t.Errorf("Unexpected errors: %v", backup.Errors)
+	}
+	binding := summary.Items[2]
+	if binding.ProvisioningState != "Succeeded" || len(binding.Roles) != 1 || binding.SourceResourceID == "" {
+		t.Errorf("Expected nested role binding properties, got %+v", binding)
+	}
+
+	single := SummarizeProvisioning(`{"name": "dapr", "extensionType": "microsoft.dapr", "provisioningState": "Succeeded"}`)
+	if err := json.Unmarshal([]byte(single), &summary); err != nil || len(summary.Items) != 1 || summary.FailedCount != 0 {
+		t.Errorf("Unexpected single item summary: %s", single)
+	}
+
+	for _, raw := range []string{`"Succeeded"`, "", `["flux", "dapr"]`} {
+		if got := SummarizeProvisioning(raw); got != raw {
+			t.Errorf("Expected %q to be returned unchanged, got %q", raw, got)
+		}
+	}
+}
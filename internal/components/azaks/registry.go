@@ -23,8 +23,10 @@ const (
 	OpClusterUpdate         AksOperationType = "update"
 	OpClusterUpgrade        AksOperationType = "upgrade"
 	OpClusterGetVersions    AksOperationType = "get-versions"
+	OpClusterGetUpgrades    AksOperationType = "get-upgrades"
 	OpClusterCheckNetwork   AksOperationType = "check-network"
 	OpClusterGetCredentials AksOperationType = "get-credentials"
+	OpClusterCommandInvoke  AksOperationType = "command-invoke"
 
 	// Nodepool operations
 	OpNodepoolList    AksOperationType = "nodepool-list"
@@ -33,6 +35,24 @@ const (
 	OpNodepoolDelete  AksOperationType = "nodepool-delete"
 	OpNodepoolScale   AksOperationType = "nodepool-scale"
 	OpNodepoolUpgrade AksOperationType = "nodepool-upgrade"
+	OpNodepoolConfig  AksOperationType = "nodepool-config"
+
+	// Snapshot operations
+	OpSnapshotList   AksOperationType = "snapshot-list"
+	OpSnapshotShow   AksOperationType = "snapshot-show"
+	OpSnapshotCreate AksOperationType = "snapshot-create"
+	OpSnapshotDelete AksOperationType = "snapshot-delete"
+
+	// Cluster extension operations
+	OpExtensionList   AksOperationType = "extension-list"
+	OpExtensionShow   AksOperationType = "extension-show"
+	OpExtensionCreate AksOperationType = "extension-create"
+
+	// Trusted access operations
+	OpTrustedAccessRoleList          AksOperationType = "trustedaccess-role-list"
+	OpTrustedAccessRoleBindingList   AksOperationType = "trustedaccess-rolebinding-list"
+	OpTrustedAccessRoleBindingShow   AksOperationType = "trustedaccess-rolebinding-show"
+	OpTrustedAccessRoleBindingCreate AksOperationType = "trustedaccess-rolebinding-create"
 
 	// Account operations
 	OpAccountList AksOperationType = "account-list"
@@ -44,17 +64,23 @@ const (
 func generateToolDescription(accessLevel string) string {
 	baseDesc := "Unified tool for managing Azure Kubernetes Service (AKS) clusters and related operations.\n\nSupported operations:\n"
 
-	var clusterOps, nodepoolOps, accountOps []string
+	var clusterOps, nodepoolOps, snapshotOps, extensionOps, trustedAccessOps, accountOps []string
 
 	// Add read-only operations for all access levels
-	clusterOps = append(clusterOps, "show", "list", "get-versions", "check-network")
-	nodepoolOps = append(nodepoolOps, "nodepool-list", "nodepool-show")
+	clusterOps = append(clusterOps, "show", "list", "get-versions", "get-upgrades", "check-network")
+	nodepoolOps = append(nodepoolOps, "nodepool-list", "nodepool-show", "nodepool-config")
+	snapshotOps = append(snapshotOps, "snapshot-list", "snapshot-show")
+	extensionOps = append(extensionOps, "extension-list", "extension-show")
+	trustedAccessOps = append(trustedAccessOps, "trustedaccess-role-list", "trustedaccess-rolebinding-list", "trustedaccess-rolebinding-show")
 	accountOps = append(accountOps, "account-list")
 
 	// Add read-write operations for readwrite and admin
 	if accessLevel == "readwrite" || accessLevel == "admin" {
-		clusterOps = append(clusterOps, "create", "delete", "scale", "update", "upgrade", "start", "stop")
+		clusterOps = append(clusterOps, "create", "delete", "scale", "update", "upgrade", "start", "stop", "command-invoke")
 		nodepoolOps = append(nodepoolOps, "nodepool-add", "nodepool-delete", "nodepool-scale", "nodepool-upgrade")
+		snapshotOps = append(snapshotOps, "snapshot-create", "snapshot-delete")
+		extensionOps = append(extensionOps, "extension-create")
+		trustedAccessOps = append(trustedAccessOps, "trustedaccess-rolebinding-create")
 		accountOps = append(accountOps, "account-set", "login")
 	}
 
@@ -67,16 +93,37 @@ func generateToolDescription(accessLevel string) string {
 	desc := baseDesc
 	desc += fmt.Sprintf("- Cluster: %s\n", joinOps(clusterOps))
 	desc += fmt.Sprintf("- Nodepool: %s\n", joinOps(nodepoolOps))
+	desc += fmt.Sprintf("- Snapshot (node pool configuration snapshots): %s\n", joinOps(snapshotOps))
+	desc += fmt.Sprintf("- Extension (cluster extensions such as Backup, Flux and Dapr): %s\n", joinOps(extensionOps))
+	desc += fmt.Sprintf("- Trusted access (role bindings for integrations such as Backup and Azure Machine Learning): %s\n", joinOps(trustedAccessOps))
+	desc += "nodepool-config reports each pool's kubelet and Linux OS custom configuration (sysctls, max pods) against the AKS defaults and flags settings known to cause problems"
+	if accessLevel == "readwrite" || accessLevel == "admin" {
+		desc += "; with --verify-on-node it reads the effective values from one node of each Linux pool with run-command"
+	}
+	desc += ".\n"
+	desc += "Extension and trusted access results are summarized with each item's provisioning state and error messages.\n"
+	if accessLevel == "readwrite" || accessLevel == "admin" {
+		desc += fmt.Sprintf("command-invoke runs up to %d kubectl commands (--command, repeatable) in one az aks command invoke session, for clusters without direct API server access, and returns the output and exit code of each command; exec, cp, drain and similar commands need admin access.\n", maxInvokeCommands)
+		desc += "Write operations (except account-set, login and command-invoke) return {\"operationResult\": {resourceId, provisioningState, startedAt, completedAt, durationSeconds}, \"result\": <az output>}.\n"
+		desc += "On AKS Automatic clusters (sku.name Automatic) AKS manages nodes with node auto provisioning, so nodepool-add, nodepool-delete, nodepool-scale, nodepool-upgrade, scale and update flags that turn off managed features (cluster autoscaler, upgrade channels, deployment safeguards) are rejected; manage capacity with NodePool custom resources through kubectl instead.\n"
+	}
 	desc += fmt.Sprintf("- Account: %s\n", joinOps(accountOps))
 
 	// Add examples based on access level
 	desc += "\nExamples:\n"
 	desc += "- Show cluster: operation=\"show\", args=\"--name myCluster --resource-group myRG\"\n"
 	desc += "- List nodepools: operation=\"nodepool-list\", args=\"--cluster-name myCluster --resource-group myRG\"\n"
+	desc += "- Inspect node configuration: operation=\"nodepool-config\", parameters={\"cluster_name\": \"myCluster\", \"resource_group\": \"myRG\"}\n"
+	desc += "- List extensions: operation=\"extension-list\", parameters={\"cluster_name\": \"myCluster\", \"resource_group\": \"myRG\"}\n"
+	desc += "- Query with parameters: operation=\"show\", parameters={\"name\": \"myCluster\", \"resource_group\": \"myRG\", \"query\": \"powerState.code\"}\n"
 
 	// Only show write operation examples if access level allows it
 	if accessLevel == "readwrite" || accessLevel == "admin" {
 		desc += "- Scale cluster: operation=\"scale\", args=\"--name myCluster --resource-group myRG --node-count 5\"\n"
+		desc += "- Run kubectl without API server access: operation=\"command-invoke\", parameters={\"name\": \"myCluster\", \"resource_group\": \"myRG\", \"command\": [\"kubectl get nodes\", \"kubectl get pods -n kube-system\"]}\n"
+		desc += "- Snapshot a node pool: operation=\"snapshot-create\", parameters={\"name\": \"knownGood\", \"resource_group\": \"myRG\", \"nodepool_id\": \"<agent pool resource ID>\"}\n"
+		desc += "- Enable the Backup extension: operation=\"extension-create\", parameters={\"cluster_name\": \"myCluster\", \"resource_group\": \"myRG\", \"name\": \"azure-aks-backup\", \"extension_type\": \"microsoft.dataprotection.kubernetes\", \"configuration_settings\": [\"blobContainer=backups\", \"storageAccount=mysa\", \"storageAccountResourceGroup=myRG\", \"storageAccountSubscriptionId=<sub-id>\"]}\n"
+		desc += "- Create a pool from a snapshot: operation=\"nodepool-add\", parameters={\"cluster_name\": \"otherCluster\", \"resource_group\": \"myRG\", \"name\": \"np2\", \"snapshot_id\": \"<snapshot resource ID>\"}\n"
 	}
 
 	return desc
@@ -103,37 +150,60 @@ func RegisterAzAksOperations(cfg *config.ConfigData) mcp.Tool {
 		mcp.WithString("operation",
 			mcp.Required(),
 			mcp.Description("The operation to perform"),
+			mcp.Enum(AllowedOperations(cfg.AccessLevel)...),
 		),
 		mcp.WithString("resource_type",
-			mcp.Description("The resource type (cluster, nodepool, account). Can be inferred from operation."),
+			mcp.Description("The resource type (cluster, nodepool, snapshot, extension, trustedaccess, account). Can be inferred from operation."),
+			mcp.Enum(ResourceTypes...),
 		),
 		mcp.WithString("args",
-			mcp.Required(),
-			mcp.Description("Arguments for the operation"),
+			mcp.Description("Arguments for the operation as a raw CLI string. Either args or parameters is required."),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("Arguments as an object of flag names to values, converted to escaped CLI flags, e.g. {\"name\": \"myCluster\", \"resource_group\": \"myRG\", \"query\": \"agentPoolProfiles[].name\"}. true adds a bare flag, arrays become space separated values. Keys are validated against the flags allowed for the operation."),
 		),
 	)
 }
 
-// GetOperationAccessLevel returns the required access level for an operation
-func GetOperationAccessLevel(operation string) string {
-	readOnlyOps := []string{
+// Operations by the access level they require. The tool's operation enum and access checks are built from them.
+var (
+	readOnlyOps = []string{
 		string(OpClusterShow), string(OpClusterList), string(OpClusterGetVersions),
-		string(OpClusterCheckNetwork), string(OpNodepoolList), string(OpNodepoolShow),
-		string(OpAccountList),
+		string(OpClusterGetUpgrades), string(OpClusterCheckNetwork), string(OpNodepoolList), string(OpNodepoolShow),
+		string(OpNodepoolConfig), string(OpSnapshotList), string(OpSnapshotShow), string(OpExtensionList),
+		string(OpExtensionShow), string(OpTrustedAccessRoleList), string(OpTrustedAccessRoleBindingList),
+		string(OpTrustedAccessRoleBindingShow), string(OpAccountList),
 	}
-
-	readWriteOps := []string{
+	readWriteOps = []string{
 		string(OpClusterCreate), string(OpClusterDelete), string(OpClusterScale),
 		string(OpClusterUpdate), string(OpClusterUpgrade), string(OpClusterStart),
-		string(OpClusterStop), string(OpNodepoolAdd), string(OpNodepoolDelete),
-		string(OpNodepoolScale), string(OpNodepoolUpgrade), string(OpAccountSet),
-		string(OpLogin),
+		string(OpClusterStop), string(OpClusterCommandInvoke), string(OpNodepoolAdd), string(OpNodepoolDelete),
+		string(OpNodepoolScale), string(OpNodepoolUpgrade), string(OpSnapshotCreate),
+		string(OpSnapshotDelete), string(OpExtensionCreate), string(OpTrustedAccessRoleBindingCreate),
+		string(OpAccountSet), string(OpLogin),
 	}
-
-	adminOps := []string{
+	adminOps = []string{
 		string(OpClusterGetCredentials),
 	}
+)
 
+// ResourceTypes lists the resource types of AKS operations
+var ResourceTypes = []string{"cluster", "nodepool", "snapshot", "extension", "trustedaccess", "account"}
+
+// AllowedOperations returns the operations the access level may perform
+func AllowedOperations(accessLevel string) []string {
+	ops := slices.Clone(readOnlyOps)
+	if accessLevel == "readwrite" || accessLevel == "admin" {
+		ops = append(ops, readWriteOps...)
+	}
+	if accessLevel == "admin" {
+		ops = append(ops, adminOps...)
+	}
+	return ops
+}
+
+// GetOperationAccessLevel returns the required access level for an operation
+func GetOperationAccessLevel(operation string) string {
 	if slices.Contains(readOnlyOps, operation) {
 		return "readonly"
 	}
@@ -185,8 +255,10 @@ func MapOperationToCommand(operation string) (string, error) {
 		string(OpClusterUpdate):         "az aks update",
 		string(OpClusterUpgrade):        "az aks upgrade",
 		string(OpClusterGetVersions):    "az aks get-versions",
+		string(OpClusterGetUpgrades):    "az aks get-upgrades",
 		string(OpClusterCheckNetwork):   "az aks check-network outbound",
 		string(OpClusterGetCredentials): "az aks get-credentials",
+		string(OpClusterCommandInvoke):  "az aks command invoke",
 
 		// Nodepool operations
 		string(OpNodepoolList):    "az aks nodepool list",
@@ -195,6 +267,24 @@ func MapOperationToCommand(operation string) (string, error) {
 		string(OpNodepoolDelete):  "az aks nodepool delete",
 		string(OpNodepoolScale):   "az aks nodepool scale",
 		string(OpNodepoolUpgrade): "az aks nodepool upgrade",
+		string(OpNodepoolConfig):  "az aks nodepool show",
+
+		// Snapshot operations
+		string(OpSnapshotList):   "az aks nodepool snapshot list",
+		string(OpSnapshotShow):   "az aks nodepool snapshot show",
+		string(OpSnapshotCreate): "az aks nodepool snapshot create",
+		string(OpSnapshotDelete): "az aks nodepool snapshot delete",
+
+		// Cluster extension operations
+		string(OpExtensionList):   "az k8s-extension list --cluster-type managedClusters",
+		string(OpExtensionShow):   "az k8s-extension show --cluster-type managedClusters",
+		string(OpExtensionCreate): "az k8s-extension create --cluster-type managedClusters",
+
+		// Trusted access operations
+		string(OpTrustedAccessRoleList):          "az aks trustedaccess role list",
+		string(OpTrustedAccessRoleBindingList):   "az aks trustedaccess rolebinding list",
+		string(OpTrustedAccessRoleBindingShow):   "az aks trustedaccess rolebinding show",
+		string(OpTrustedAccessRoleBindingCreate): "az aks trustedaccess rolebinding create",
 
 		// Account operations
 		string(OpAccountList): "az account list",
@@ -210,6 +300,89 @@ func MapOperationToCommand(operation string) (string, error) {
 	return cmd, nil
 }
 
+// operationParameters lists the flags accepted in the structured parameters object of each operation.
+// Global flags such as --subscription and --query are accepted for every operation.
+var operationParameters = map[string][]string{
+	// Cluster operations
+	string(OpClusterShow): {"name", "resource-group"},
+	string(OpClusterList): {"resource-group"},
+	string(OpClusterCreate): {
+		"name", "resource-group", "location", "kubernetes-version", "node-count", "node-vm-size",
+		"network-plugin", "network-plugin-mode", "network-policy", "network-dataplane", "pod-cidr",
+		"service-cidr", "dns-service-ip", "vnet-subnet-id", "max-pods", "zones", "tier",
+		"enable-cluster-autoscaler", "min-count", "max-count", "enable-managed-identity",
+		"generate-ssh-keys", "snapshot-id", "tags", "no-wait", "yes",
+	},
+	string(OpClusterDelete): {"name", "resource-group", "no-wait", "yes"},
+	string(OpClusterScale):  {"name", "resource-group", "node-count", "nodepool-name", "no-wait"},
+	string(OpClusterStart):  {"name", "resource-group", "no-wait"},
+	string(OpClusterStop):   {"name", "resource-group", "no-wait"},
+	string(OpClusterUpdate): {
+		"name", "resource-group", "enable-cluster-autoscaler", "disable-cluster-autoscaler",
+		"update-cluster-autoscaler", "min-count", "max-count", "tier", "attach-acr", "detach-acr",
+		"auto-upgrade-channel", "node-os-upgrade-channel", "tags", "no-wait", "yes",
+	},
+	string(OpClusterUpgrade): {
+		"name", "resource-group", "kubernetes-version", "control-plane-only", "node-image-only",
+		"no-wait", "yes",
+	},
+	string(OpClusterGetVersions):    {"location"},
+	string(OpClusterGetUpgrades):    {"name", "resource-group"},
+	string(OpClusterCheckNetwork):   {"name", "resource-group", "node-name", "custom-endpoints"},
+	string(OpClusterGetCredentials): {"name", "resource-group", "admin", "file", "context", "overwrite-existing"},
+	string(OpClusterCommandInvoke):  {"name", "resource-group", "command"},
+
+	// Nodepool operations
+	string(OpNodepoolList):   {"cluster-name", "resource-group"},
+	string(OpNodepoolShow):   {"cluster-name", "resource-group", "name"},
+	string(OpNodepoolConfig): {"cluster-name", "resource-group", "name", "verify-on-node"},
+	string(OpNodepoolAdd): {
+		"cluster-name", "resource-group", "name", "mode", "node-count", "node-vm-size", "os-type",
+		"os-sku", "kubernetes-version", "zones", "max-pods", "labels", "node-taints", "priority",
+		"vnet-subnet-id", "enable-cluster-autoscaler", "min-count", "max-count", "max-surge", "snapshot-id",
+		"tags", "no-wait",
+	},
+	string(OpNodepoolDelete): {"cluster-name", "resource-group", "name", "no-wait"},
+	string(OpNodepoolScale):  {"cluster-name", "resource-group", "name", "node-count", "no-wait"},
+	string(OpNodepoolUpgrade): {
+		"cluster-name", "resource-group", "name", "kubernetes-version", "node-image-only", "max-surge",
+		"snapshot-id", "no-wait", "yes",
+	},
+
+	// Snapshot operations
+	string(OpSnapshotList):   {"resource-group"},
+	string(OpSnapshotShow):   {"name", "resource-group"},
+	string(OpSnapshotCreate): {"name", "resource-group", "nodepool-id", "location", "tags", "no-wait"},
+	string(OpSnapshotDelete): {"name", "resource-group", "no-wait", "yes"},
+
+	// Cluster extension operations
+	string(OpExtensionList): {"cluster-name", "resource-group"},
+	string(OpExtensionShow): {"cluster-name", "resource-group", "name"},
+	string(OpExtensionCreate): {
+		"cluster-name", "resource-group", "name", "extension-type", "version", "release-train",
+		"auto-upgrade-minor-version", "scope", "release-namespace", "target-namespace",
+		"configuration-settings", "config-protected", "no-wait",
+	},
+
+	// Trusted access operations
+	string(OpTrustedAccessRoleList):        {"location"},
+	string(OpTrustedAccessRoleBindingList): {"cluster-name", "resource-group"},
+	string(OpTrustedAccessRoleBindingShow): {"cluster-name", "resource-group", "name"},
+	string(OpTrustedAccessRoleBindingCreate): {
+		"cluster-name", "resource-group", "name", "source-resource-id", "roles",
+	},
+
+	// Account operations
+	string(OpAccountList): {"all", "refresh"},
+	string(OpAccountSet):  {},
+	string(OpLogin):       {"tenant", "identity", "username", "use-device-code", "allow-no-subscriptions"},
+}
+
+// GetOperationParameters returns the flags accepted in the parameters object of an operation
+func GetOperationParameters(operation string) []string {
+	return operationParameters[operation]
+}
+
 // GetSupportedOperations returns a list of all supported operations
 func GetSupportedOperations() []string {
 	return []string{
@@ -217,10 +390,18 @@ func GetSupportedOperations() []string {
 		string(OpClusterShow), string(OpClusterList), string(OpClusterCreate),
 		string(OpClusterDelete), string(OpClusterScale), string(OpClusterStart),
 		string(OpClusterStop), string(OpClusterUpdate), string(OpClusterUpgrade),
-		string(OpClusterGetVersions), string(OpClusterCheckNetwork), string(OpClusterGetCredentials),
+		string(OpClusterGetVersions), string(OpClusterGetUpgrades), string(OpClusterCheckNetwork),
+		string(OpClusterGetCredentials), string(OpClusterCommandInvoke),
 		// Nodepool operations
 		string(OpNodepoolList), string(OpNodepoolShow), string(OpNodepoolAdd),
-		string(OpNodepoolDelete), string(OpNodepoolScale), string(OpNodepoolUpgrade),
+		string(OpNodepoolDelete), string(OpNodepoolScale), string(OpNodepoolUpgrade), string(OpNodepoolConfig),
+		// Snapshot operations
+		string(OpSnapshotList), string(OpSnapshotShow), string(OpSnapshotCreate), string(OpSnapshotDelete),
+		// Cluster extension operations
+		string(OpExtensionList), string(OpExtensionShow), string(OpExtensionCreate),
+		// Trusted access operations
+		string(OpTrustedAccessRoleList), string(OpTrustedAccessRoleBindingList),
+		string(OpTrustedAccessRoleBindingShow), string(OpTrustedAccessRoleBindingCreate),
 		// Account operations
 		string(OpAccountList), string(OpAccountSet), string(OpLogin),
 	}
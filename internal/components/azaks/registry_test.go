@@ -1,6 +1,7 @@
 package azaks
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/Azure/aks-mcp/internal/config"
@@ -27,6 +28,9 @@ func TestGetSupportedOperations_ContainsExpectedOps(t *testing.T) {
 	expectedOps := []string{
 		"show", "list", "create", "delete", "scale", "start", "stop", "update", "upgrade",
 		"nodepool-list", "nodepool-show", "nodepool-add", "nodepool-delete",
+		"snapshot-list", "snapshot-show", "snapshot-create", "snapshot-delete",
+		"extension-list", "extension-show", "extension-create",
+		"trustedaccess-role-list", "trustedaccess-rolebinding-list", "trustedaccess-rolebinding-show", "trustedaccess-rolebinding-create",
 		"account-list", "account-set", "login", "get-credentials",
 	}
 
@@ -65,6 +69,15 @@ func TestValidateOperationAccess_ChecksAccessLevels(t *testing.T) {
 		{"get-credentials", "readonly", false},
 		{"get-credentials", "readwrite", false},
 		{"get-credentials", "admin", true},
+		{"snapshot-list", "readonly", true},
+		{"snapshot-create", "readonly", false},
+		{"snapshot-create", "readwrite", true},
+		{"snapshot-delete", "readonly", false},
+		{"extension-list", "readonly", true},
+		{"extension-create", "readonly", false},
+		{"extension-create", "readwrite", true},
+		{"trustedaccess-rolebinding-list", "readonly", true},
+		{"trustedaccess-rolebinding-create", "readonly", false},
 	}
 
 	for _, tc := range testCases {
@@ -78,3 +91,51 @@ func TestValidateOperationAccess_ChecksAccessLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestGetOperationParameters_CoversSupportedOperations(t *testing.T) {
+	for _, op := range GetSupportedOperations() {
+		if _, ok := operationParameters[op]; !ok {
+			t.Errorf("Expected a parameters allowlist for operation '%s'", op)
+		}
+	}
+
+	if !slices.Contains(GetOperationParameters("nodepool-scale"), "node-count") {
+		t.Error("Expected nodepool-scale to accept node-count")
+	}
+	if !slices.Contains(GetOperationParameters("nodepool-add"), "snapshot-id") {
+		t.Error("Expected nodepool-add to accept snapshot-id for creating pools from snapshots")
+	}
+}
+
+func TestMapOperationToCommand_Snapshots(t *testing.T) {
+	cmd, err := MapOperationToCommand("snapshot-create")
+	if err != nil || cmd != "az aks nodepool snapshot create" {
+		t.Errorf("Expected snapshot-create to map to az aks nodepool snapshot create, got %q (%v)", cmd, err)
+	}
+}
+
+func TestRegisterAzAksOperations_OperationEnum(t *testing.T) {
+	for _, tc := range []struct {
+		accessLevel string
+		operation   string
+		listed      bool
+	}{
+		{"readonly", "show", true},
+		{"readonly", "create", false},
+		{"readwrite", "create", true},
+		{"readwrite", "get-credentials", false},
+		{"admin", "get-credentials", true},
+	} {
+		tool := RegisterAzAksOperations(&config.ConfigData{AccessLevel: tc.accessLevel})
+		enum, _ := tool.InputSchema.Properties["operation"].(map[string]any)["enum"].([]string)
+		if slices.Contains(enum, tc.operation) != tc.listed {
+			t.Errorf("Expected operation '%s' listed=%v at access level '%s', got enum %v", tc.operation, tc.listed, tc.accessLevel, enum)
+		}
+	}
+
+	for _, op := range AllowedOperations("admin") {
+		if _, err := MapOperationToCommand(op); err != nil {
+			t.Errorf("Expected enum operation '%s' to map to a command: %v", op, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package azaks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+	"github.com/google/shlex"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ClusterReader reads AKS cluster metadata through the Azure SDK
+type ClusterReader interface {
+	GetAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error)
+	ListAKSClusters(ctx context.Context, subscriptionID, resourceGroup string) ([]*armcontainerservice.ManagedCluster, error)
+	GetAKSUpgradeProfile(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedClusterUpgradeProfile, error)
+	ListAgentPools(ctx context.Context, subscriptionID, resourceGroup, clusterName string) ([]*armcontainerservice.AgentPool, error)
+	GetAgentPool(ctx context.Context, subscriptionID, resourceGroup, clusterName, agentPoolName string) (*armcontainerservice.AgentPool, error)
+}
+
+// SDKOperations are the read operations served without the Azure CLI
+var SDKOperations = []string{
+	string(OpClusterShow), string(OpClusterList), string(OpClusterGetUpgrades),
+	string(OpNodepoolList), string(OpNodepoolShow),
+}
+
+// sdkFlagAliases maps the short az flags accepted in args to their long names
+var sdkFlagAliases = map[string]string{
+	"-g": "resource-group",
+	"-n": "name",
+	"-o": "output",
+}
+
+// RegisterAzAksSDKOperations registers the AKS operations tool for servers running without the Azure CLI
+func RegisterAzAksSDKOperations() mcp.Tool {
+	desc := "Reads Azure Kubernetes Service (AKS) cluster metadata through the Azure SDK (the server runs without the Azure CLI).\n\nSupported operations:\n"
+	desc += "- Cluster: show, list, get-upgrades\n"
+	desc += "- Nodepool: nodepool-list, nodepool-show\n"
+	desc += "Results are the Azure Resource Manager JSON of the resources. --query is not supported, and the subscription " +
+		"defaults to AZURE_SUBSCRIPTION_ID when --subscription is not given.\n"
+	desc += "\nExamples:\n"
+	desc += "- Show cluster: operation=\"show\", args=\"--name myCluster --resource-group myRG\"\n"
+	desc += "- List nodepools: operation=\"nodepool-list\", parameters={\"cluster_name\": \"myCluster\", \"resource_group\": \"myRG\"}\n"
+	desc += "- Available upgrades: operation=\"get-upgrades\", parameters={\"name\": \"myCluster\", \"resource_group\": \"myRG\", \"subscription\": \"<sub-id>\"}\n"
+
+	return mcp.NewTool("az_aks_operations",
+		mcp.WithDescription(desc),
+		mcp.WithString("operation",
+			mcp.Required(),
+			mcp.Description("The operation to perform"),
+			mcp.Enum(SDKOperations...),
+		),
+		mcp.WithString("args",
+			mcp.Description("Arguments for the operation as a CLI style flag string. Either args or parameters is required."),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("Arguments as an object of flag names to values, e.g. {\"name\": \"myCluster\", \"resource_group\": \"myRG\"}. Keys are validated against the flags allowed for the operation."),
+		),
+	)
+}
+
+// GetAksSDKOperationsHandler returns the handler of the AKS operations tool that reads clusters through the Azure SDK
+func GetAksSDKOperationsHandler(client ClusterReader, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleSDKOperation(params, client)
+	})
+}
+
+// HandleSDKOperation runs a read operation of az_aks_operations with the Azure SDK instead of the Azure CLI
+func HandleSDKOperation(params map[string]interface{}, client ClusterReader) (string, error) {
+	operation, ok := params["operation"].(string)
+	if !ok {
+		return "", fmt.Errorf("missing or invalid 'operation' parameter")
+	}
+	if !slices.Contains(SDKOperations, operation) {
+		return "", fmt.Errorf("operation '%s' requires the Azure CLI, which this server runs without (--no-azcli). Supported operations: %s",
+			operation, strings.Join(SDKOperations, ", "))
+	}
+
+	allowed := GetOperationParameters(operation)
+	args, err := azcli.ResolveArgs(params, allowed)
+	if err != nil {
+		return "", err
+	}
+	flags, err := parseSDKFlags(args, append([]string{"subscription"}, allowed...))
+	if err != nil {
+		return "", err
+	}
+
+	subscriptionID := flags["subscription"]
+	if subscriptionID == "" {
+		subscriptionID = os.Getenv("AZURE_SUBSCRIPTION_ID")
+	}
+	if subscriptionID == "" {
+		return "", fmt.Errorf("--subscription is required when AZURE_SUBSCRIPTION_ID is not set")
+	}
+
+	ctx := context.Background()
+	var result interface{}
+	switch AksOperationType(operation) {
+	case OpClusterShow:
+		if err := requireSDKFlags(flags, "name", "resource-group"); err != nil {
+			return "", err
+		}
+		result, err = client.GetAKSCluster(ctx, subscriptionID, flags["resource-group"], flags["name"])
+	case OpClusterList:
+		result, err = client.ListAKSClusters(ctx, subscriptionID, flags["resource-group"])
+	case OpClusterGetUpgrades:
+		if err := requireSDKFlags(flags, "name", "resource-group"); err != nil {
+			return "", err
+		}
+		result, err = client.GetAKSUpgradeProfile(ctx, subscriptionID, flags["resource-group"], flags["name"])
+	case OpNodepoolList:
+		if err := requireSDKFlags(flags, "cluster-name", "resource-group"); err != nil {
+			return "", err
+		}
+		result, err = client.ListAgentPools(ctx, subscriptionID, flags["resource-group"], flags["cluster-name"])
+	case OpNodepoolShow:
+		if err := requireSDKFlags(flags, "cluster-name", "resource-group", "name"); err != nil {
+			return "", err
+		}
+		result, err = client.GetAgentPool(ctx, subscriptionID, flags["resource-group"], flags["cluster-name"], flags["name"])
+	}
+	if err != nil {
+		return "", err
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(output), nil
+}
+
+// parseSDKFlags parses a CLI style flag string into flag values keyed by long flag name.
+// Only the allowed flags are accepted, since there is no Azure CLI to interpret the others.
+func parseSDKFlags(args string, allowed []string) (map[string]string, error) {
+	words, err := shlex.Split(args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid args: %w", err)
+	}
+
+	flags := make(map[string]string)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "-") {
+			return nil, fmt.Errorf("unexpected argument %q", word)
+		}
+		name, value, hasValue := strings.Cut(word, "=")
+		if long, ok := sdkFlagAliases[name]; ok {
+			name = long
+		} else {
+			name = strings.TrimPrefix(name, "--")
+		}
+		if !hasValue && i+1 < len(words) && !strings.HasPrefix(words[i+1], "-") {
+			value = words[i+1]
+			i++
+		}
+
+		switch {
+		case name == "query":
+			return nil, fmt.Errorf("--query requires the Azure CLI and is not supported with --no-azcli")
+		case name == "output" || name == "only-show-errors":
+			// Results are always JSON and errors are returned on their own
+			continue
+		case !slices.Contains(allowed, name):
+			return nil, fmt.Errorf("flag --%s is not supported for this operation without the Azure CLI. Supported flags: --%s",
+				name, strings.Join(allowed, ", --"))
+		}
+		flags[name] = value
+	}
+	return flags, nil
+}
+
+// requireSDKFlags checks that the named flags have values
+func requireSDKFlags(flags map[string]string, names ...string) error {
+	for _, name := range names {
+		if flags[name] == "" {
+			return fmt.Errorf("--%s is required", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package azaks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+// fakeClusterReader records the last read and returns fixed resources
+type fakeClusterReader struct {
+	call string
+}
+
+func (f *fakeClusterReader) GetAKSCluster(_ context.Context, sub, rg, name string) (*armcontainerservice.ManagedCluster, error) {
+	f.call = "show " + sub + "/" + rg + "/" + name
+	return &armcontainerservice.ManagedCluster{Name: to.Ptr(name)}, nil
+}
+
+func (f *fakeClusterReader) ListAKSClusters(_ context.Context, sub, rg string) ([]*armcontainerservice.ManagedCluster, error) {
+	f.call = "list " + sub + "/" + rg
+	return []*armcontainerservice.ManagedCluster{{Name: to.Ptr("c1")}, {Name: to.Ptr("c2")}}, nil
+}
+
+func (f *fakeClusterReader) GetAKSUpgradeProfile(_ context.Context, sub, rg, name string) (*armcontainerservice.ManagedClusterUpgradeProfile, error) {
+	f.call = "get-upgrades " + sub + "/" + rg + "/" + name
+	return &armcontainerservice.ManagedClusterUpgradeProfile{Name: to.Ptr("default")}, nil
+}
+
+func (f *fakeClusterReader) ListAgentPools(_ context.Context, sub, rg, cluster string) ([]*armcontainerservice.AgentPool, error) {
+	f.call = "nodepool-list " + sub + "/" + rg + "/" + cluster
+	return []*armcontainerservice.AgentPool{{Name: to.Ptr("nodepool1")}}, nil
+}
+
+func (f *fakeClusterReader) GetAgentPool(_ context.Context, sub, rg, cluster, pool string) (*armcontainerservice.AgentPool, error) {
+	f.call = "nodepool-show " + sub + "/" + rg + "/" + cluster + "/" + pool
+	return &armcontainerservice.AgentPool{Name: to.Ptr(pool)}, nil
+}
+
+func TestHandleSDKOperation(t *testing.T) {
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "env-sub")
+
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		wantCall string
+		wantOut  string
+	}{
+		{
+			name:     "show with short flags",
+			params:   map[string]interface{}{"operation": "show", "args": "-n myCluster -g myRG -o json"},
+			wantCall: "show env-sub/myRG/myCluster",
+			wantOut:  `"name": "myCluster"`,
+		},
+		{
+			name:     "list with subscription",
+			params:   map[string]interface{}{"operation": "list", "args": "--subscription other-sub"},
+			wantCall: "list other-sub/",
+			wantOut:  `"name": "c2"`,
+		},
+		{
+			name:     "get-upgrades with parameters",
+			params:   map[string]interface{}{"operation": "get-upgrades", "parameters": map[string]interface{}{"name": "myCluster", "resource_group": "myRG"}},
+			wantCall: "get-upgrades env-sub/myRG/myCluster",
+			wantOut:  `"name": "default"`,
+		},
+		{
+			name:     "nodepool-list",
+			params:   map[string]interface{}{"operation": "nodepool-list", "args": "--cluster-name=myCluster --resource-group myRG"},
+			wantCall: "nodepool-list env-sub/myRG/myCluster",
+			wantOut:  `"name": "nodepool1"`,
+		},
+		{
+			name:     "nodepool-show",
+			params:   map[string]interface{}{"operation": "nodepool-show", "parameters": map[string]interface{}{"cluster_name": "myCluster", "resource_group": "myRG", "name": "np2"}},
+			wantCall: "nodepool-show env-sub/myRG/myCluster/np2",
+			wantOut:  `"name": "np2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &fakeClusterReader{}
+			out, err := HandleSDKOperation(tt.params, reader)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if reader.call != tt.wantCall {
+				t.Errorf("Expected call %q, got %q", tt.wantCall, reader.call)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("Expected output to contain %s, got %s", tt.wantOut, out)
+			}
+		})
+	}
+}
+
+func TestHandleSDKOperationErrors(t *testing.T) {
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "env-sub")
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"write operation", map[string]interface{}{"operation": "scale", "args": "--name c --resource-group rg"}, "requires the Azure CLI"},
+		{"query", map[string]interface{}{"operation": "show", "args": "--name c --resource-group rg --query powerState"}, "--query requires the Azure CLI"},
+		{"unsupported flag", map[string]interface{}{"operation": "list", "args": "--location eastus"}, "flag --location is not supported"},
+		{"missing name", map[string]interface{}{"operation": "show", "args": "--resource-group rg"}, "--name is required"},
+		{"positional argument", map[string]interface{}{"operation": "list", "args": "rg"}, "unexpected argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleSDKOperation(tt.params, &fakeClusterReader{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHandleSDKOperationRequiresSubscription(t *testing.T) {
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "")
+
+	_, err := HandleSDKOperation(map[string]interface{}{"operation": "list"}, &fakeClusterReader{})
+	if err == nil || !strings.Contains(err.Error(), "--subscription is required") {
+		t.Errorf("Expected a missing subscription error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package azrest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+type fakeARM struct {
+	calls    []string
+	payloads []interface{}
+	response string
+	err      error
+}
+
+func (f *fakeARM) CallARMWithBody(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.calls = append(f.calls, method+" "+path)
+	f.payloads = append(f.payloads, payload)
+	return []byte(f.response), f.err
+}
+
+const testCluster = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+
+func TestValidateURL(t *testing.T) {
+	allowed := []string{"Microsoft.ContainerService/*", "Microsoft.Insights/diagnosticSettings/*"}
+	valid := map[string]string{
+		testCluster + "?api-version=2025-05-02-preview":                                                                      testCluster + "?api-version=2025-05-02-preview",
+		"https://management.azure.com" + testCluster + "/agentPools?api-version=2024-05-01":                                  testCluster + "/agentPools?api-version=2024-05-01",
+		"/subscriptions/sub/providers/microsoft.containerservice/locations/eastus/kubernetesVersions?api-version=2024-05-01": "/subscriptions/sub/providers/microsoft.containerservice/locations/eastus/kubernetesVersions?api-version=2024-05-01",
+		testCluster + "/providers/Microsoft.Insights/diagnosticSettings/logs?api-version=2021-05-01-preview":                 testCluster + "/providers/Microsoft.Insights/diagnosticSettings/logs?api-version=2021-05-01-preview",
+	}
+	for rawURL, expected := range valid {
+		got, err := ValidateURL(rawURL, "https://management.azure.com", allowed)
+		if err != nil || got != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", rawURL, expected, got, err)
+		}
+	}
+
+	for _, rawURL := range []string{
+		testCluster,
+		"https://evil.example.com" + testCluster + "?api-version=1",
+		"http://management.azure.com" + testCluster + "?api-version=1",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss?api-version=1",
+		testCluster + "/providers/Microsoft.Authorization/roleAssignments/x?api-version=1",
+		testCluster + "/../../Microsoft.Compute/disks/d?api-version=1",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService%2Fx?api-version=1",
+		"/subscriptions/sub/resourceGroups/rg?api-version=1",
+		"/providers/Microsoft.ContainerService/operations?api-version=1",
+	} {
+		if _, err := ValidateURL(rawURL, "https://management.azure.com", allowed); err == nil {
+			t.Errorf("%s: expected an error", rawURL)
+		}
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	path := testCluster + "/maintenanceConfigurations/default?api-version=2024-05-01"
+	valid := []struct{ method, body string }{
+		{MethodGet, ""},
+		{MethodPost, ""},
+		{MethodPost, `{"command": "kubectl get nodes"}`},
+		{MethodPut, `{"name": "Default", "properties": {"timeInWeek": []}}`},
+	}
+	for _, tc := range valid {
+		if _, err := ValidateBody(tc.method, path, tc.body); err != nil {
+			t.Errorf("%s %s: unexpected error %v", tc.method, tc.body, err)
+		}
+	}
+
+	invalid := []struct{ method, path, body string }{
+		{MethodGet, path, `{}`},
+		{MethodPut, path, ""},
+		{MethodPut, path, `[1]`},
+		{MethodPut, path, `{"properties": {}, "script": "x"}`},
+		{MethodPut, path, `{"name": "other"}`},
+		{MethodPut, path, `{"id": "/subscriptions/other", "properties": {}}`},
+		{MethodPut, path, `{"properties": "x"}`},
+		{MethodPost, path, `"text"`},
+		{MethodPost, testCluster + "/listClusterAdminCredential?api-version=2024-05-01", ""},
+		{MethodPost, path, `{"data": "` + strings.Repeat("x", maxBodyBytes) + `"}`},
+	}
+	for _, tc := range invalid {
+		if _, err := ValidateBody(tc.method, tc.path, tc.body); err == nil {
+			t.Errorf("%s %s %.40s: expected an error", tc.method, tc.path, tc.body)
+		}
+	}
+}
+
+func TestHandleRest(t *testing.T) {
+	auditLog := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readonly"
+	arm := &fakeARM{response: `{"name": "aks"}`}
+
+	output, err := HandleRest(context.Background(), map[string]interface{}{"method": "get", "url": testCluster + "?api-version=2024-05-01"}, arm, auditLog, cfg)
+	if err != nil {
+		t.Fatalf("HandleRest failed: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(output), &result); err != nil || result.Method != MethodGet || !strings.Contains(string(result.Response), `"aks"`) {
+		t.Errorf("Unexpected result %s (%v)", output, err)
+	}
+
+	// Writes need readwrite access; denied and failed calls are audited too
+	put := map[string]interface{}{"method": "PUT", "url": testCluster + "?api-version=2024-05-01", "body": `{"location": "eastus"}`}
+	if _, err := HandleRest(context.Background(), put, arm, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "readwrite") {
+		t.Errorf("Expected PUT to be denied at readonly, got %v", err)
+	}
+	cfg.AccessLevel = "readwrite"
+	arm.err = fmt.Errorf("Conflict")
+	if _, err := HandleRest(context.Background(), put, arm, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "Conflict") {
+		t.Errorf("Expected the ARM error, got %v", err)
+	}
+	if len(arm.calls) != 2 || string(arm.payloads[1].(json.RawMessage)) != `{"location": "eastus"}` || arm.payloads[0] != nil {
+		t.Errorf("Unexpected calls %v %v", arm.calls, arm.payloads)
+	}
+
+	records, err := auditLog.Records()
+	if err != nil {
+		t.Fatalf("Records failed: %v", err)
+	}
+	var outcomes []string
+	for _, record := range records {
+		if record.Tool != toolName || record.Target != testCluster {
+			t.Errorf("Unexpected record %+v", record)
+		}
+		outcomes = append(outcomes, record.Action+"/"+record.Outcome)
+	}
+	if strings.Join(outcomes, ",") != "get/succeeded,put/denied,put/failed" {
+		t.Errorf("Unexpected audit records %v", outcomes)
+	}
+}
+
+func TestHandleRestCancelled(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readonly"
+	arm := &fakeARM{response: `{"name": "aks"}`}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	params := map[string]interface{}{"method": "GET", "url": testCluster + "?api-version=2024-05-01"}
+	if _, err := HandleRest(ctx, params, arm, audit.NewLogger(store.NewMemoryStore()), cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled call context to stop the request, got %v", err)
+	}
+	if len(arm.calls) != 0 {
+		t.Errorf("Expected no ARM call, got %v", arm.calls)
+	}
+}
+
+// TestHandleRestRefusesRunCommand tests that the cluster run command API is refused even at readwrite and admin
+func TestHandleRestRefusesRunCommand(t *testing.T) {
+	auditLog := audit.NewLogger(store.NewMemoryStore())
+	arm := &fakeARM{}
+	for _, level := range []string{"readwrite", "admin"} {
+		cfg := config.NewConfig()
+		cfg.AccessLevel = level
+		for _, params := range []map[string]interface{}{
+			{"method": "POST", "url": testCluster + "/runCommand?api-version=2024-05-01", "body": `{"command": "kubectl get secrets -A"}`},
+			{"method": "POST", "url": testCluster + "/RunCommand?api-version=2024-05-01", "body": `{"command": "kubectl get pods"}`},
+			{"method": "GET", "url": testCluster + "/commandResults/abc?api-version=2024-05-01"},
+		} {
+			if _, err := HandleRest(context.Background(), params, arm, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "command-invoke") {
+				t.Errorf("%s: expected %v to be refused, got %v", level, params["url"], err)
+			}
+		}
+	}
+	if len(arm.calls) != 0 {
+		t.Errorf("Expected no ARM calls, got %v", arm.calls)
+	}
+	records, _ := auditLog.Records()
+	for _, record := range records {
+		if record.Outcome != audit.OutcomeDenied {
+			t.Errorf("Expected a denied record, got %+v", record)
+		}
+	}
+}
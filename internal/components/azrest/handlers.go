@@ -0,0 +1,249 @@
+// Package azrest calls Azure Resource Manager APIs that have no az command or tool yet. Requests are limited
+// to ARM management endpoints whose provider path matches an allowlist, request bodies are checked against
+// the shape of the method, and every call, including denied ones, is audited.
+package azrest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// Methods of the az_rest tool
+const (
+	MethodGet  = http.MethodGet
+	MethodPut  = http.MethodPut
+	MethodPost = http.MethodPost
+)
+
+const (
+	// toolName is the name audit records are issued under
+	toolName = "az_rest"
+	// maxBodyBytes bounds the request bodies accepted in one call
+	maxBodyBytes = 64 * 1024
+)
+
+// envelopeFields are the top-level fields a PUT body may set on an ARM resource
+var envelopeFields = map[string]bool{
+	"id": true, "name": true, "type": true, "location": true, "tags": true, "properties": true, "sku": true,
+	"identity": true, "kind": true, "zones": true, "extendedLocation": true, "plan": true,
+}
+
+// envelopeFieldNames describes envelopeFields in errors
+const envelopeFieldNames = "properties, location, tags, sku, identity, kind, zones, extendedLocation, plan, and id and name matching the url"
+
+// commandPathPattern matches the managed cluster run command API, which runs arbitrary commands in the cluster
+// outside the kubectl allowlist and access gating of az_aks_operations command-invoke
+var commandPathPattern = regexp.MustCompile(`(?i)/(runCommand|commandResults)(/|$)`)
+
+// secretActionPattern matches POST actions that return credentials, such as listClusterAdminCredential
+var secretActionPattern = regexp.MustCompile(`(?i)^list.*(credential|key|secret)`)
+
+// Result is the result of the az_rest tool
+type Result struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Response is the JSON response body, or the body as a string when it is not JSON
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// GetRestHandler returns a handler for the az_rest tool
+func GetRestHandler(azClient *azureclient.AzureClient, auditLog *audit.Logger, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleRest(ctx, params, azClient, auditLog, cfg)
+	})
+}
+
+// HandleRest checks a request against the allowed paths and the access level, sends it to ARM and audits it
+func HandleRest(ctx context.Context, params map[string]interface{}, api common.ARMBodyCaller, auditLog *audit.Logger, cfg *config.ConfigData) (string, error) {
+	method, _ := params["method"].(string)
+	method = strings.ToUpper(strings.TrimSpace(method))
+	rawURL, _ := params["url"].(string)
+	body, _ := params["body"].(string)
+
+	record := audit.Record{Tool: toolName, Action: strings.ToLower(method), Target: strings.SplitN(rawURL, "?", 2)[0], Command: method + " " + rawURL, Client: cfg.ClientName()}
+	deny := func(err error) (string, error) {
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+		auditLog.Log(record)
+		return "", err
+	}
+	if err := checkAccess(method, cfg.AccessLevel); err != nil {
+		return deny(err)
+	}
+	requestPath, err := ValidateURL(rawURL, cfg.CloudEnvironment().ResourceManagerEndpoint, cfg.RestAllowedPaths)
+	if err != nil {
+		return deny(err)
+	}
+	record.Target = strings.SplitN(requestPath, "?", 2)[0]
+	payload, err := ValidateBody(method, requestPath, body)
+	if err != nil {
+		return deny(err)
+	}
+
+	var requestBody interface{}
+	if payload != nil {
+		requestBody = payload
+	}
+	response, err := api.CallARMWithBody(ctx, method, requestPath, requestBody)
+	if err != nil {
+		if errors.Is(err, review.ErrDeferred) {
+			// Writes deferred in review mode are audited when the pipeline applies them
+			return "", err
+		}
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+		auditLog.Log(record)
+		return "", fmt.Errorf("%s %s failed: %w", method, record.Target, err)
+	}
+	record.Outcome = audit.OutcomeSucceeded
+	auditLog.Log(record)
+
+	result := Result{Method: method, URL: requestPath}
+	if trimmed := strings.TrimSpace(string(response)); trimmed != "" {
+		if json.Valid([]byte(trimmed)) {
+			result.Response = json.RawMessage(trimmed)
+		} else {
+			result.Response, _ = json.Marshal(trimmed)
+		}
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(data), nil
+}
+
+// checkAccess checks that the access level allows the method: GET at every level, PUT and POST at readwrite or admin
+func checkAccess(method, accessLevel string) error {
+	switch method {
+	case MethodGet:
+		return nil
+	case MethodPut, MethodPost:
+		if accessLevel != "readwrite" && accessLevel != "admin" {
+			return fmt.Errorf("method %s requires readwrite or admin access level", method)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid method %q: expected %s, %s or %s", method, MethodGet, MethodPut, MethodPost)
+	}
+}
+
+// ValidateURL checks that rawURL is a request to the ARM endpoint whose provider path, the part after its last
+// /providers/ segment, matches one of the allowed patterns, and returns its path and query. In patterns, which
+// match case-insensitively, * stands for any run of characters, including /. The cluster run command API
+// (runCommand and commandResults) is refused whatever the patterns allow.
+func ValidateURL(rawURL, armEndpoint string, allowed []string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Scheme != "" || u.Host != "" {
+		endpoint, err := url.Parse(armEndpoint)
+		if err != nil {
+			return "", fmt.Errorf("invalid ARM endpoint %s: %v", armEndpoint, err)
+		}
+		if u.Scheme != "https" || !strings.EqualFold(u.Host, endpoint.Host) {
+			return "", fmt.Errorf("url must be on the ARM endpoint %s", armEndpoint)
+		}
+	}
+	if u.User != nil || u.Fragment != "" || u.RawPath != "" {
+		return "", fmt.Errorf("url must be a plain ARM path without credentials, fragment or encoded characters")
+	}
+	if !strings.HasPrefix(strings.ToLower(u.Path), "/subscriptions/") || path.Clean(u.Path) != u.Path {
+		return "", fmt.Errorf("url must be an ARM path starting with /subscriptions/")
+	}
+	if u.Query().Get("api-version") == "" {
+		return "", fmt.Errorf("url must include api-version")
+	}
+	i := strings.LastIndex(strings.ToLower(u.Path), "/providers/")
+	if i < 0 || i+len("/providers/") == len(u.Path) {
+		return "", fmt.Errorf("url must name a resource provider path, such as /providers/Microsoft.ContainerService/...")
+	}
+	providerPath := u.Path[i+len("/providers/"):]
+	if commandPathPattern.MatchString("/" + providerPath) {
+		return "", fmt.Errorf("the cluster run command API is not allowed: use az_aks_operations command-invoke, which checks the kubectl commands")
+	}
+	for _, pattern := range allowed {
+		if globPattern(pattern).MatchString(providerPath) {
+			return u.Path + "?" + u.RawQuery, nil
+		}
+	}
+	return "", fmt.Errorf("provider path %s is not allowed: allowed patterns are %s", providerPath, strings.Join(allowed, ", "))
+}
+
+// ValidateBody checks the request body of the method and returns it, or nil when there is none. GET requests
+// take no body, POST requests an optional JSON object and PUT requests a JSON object with ARM resource envelope
+// fields only, whose id and name, if set, match the request path.
+func ValidateBody(method, requestPath, body string) (json.RawMessage, error) {
+	body = strings.TrimSpace(body)
+	resourcePath := strings.SplitN(requestPath, "?", 2)[0]
+	if method == MethodPost {
+		action := resourcePath[strings.LastIndex(resourcePath, "/")+1:]
+		if secretActionPattern.MatchString(action) {
+			return nil, fmt.Errorf("action %s returns credentials and is not allowed", action)
+		}
+	}
+	if body == "" {
+		if method == MethodPut {
+			return nil, fmt.Errorf("PUT requires a body")
+		}
+		return nil, nil
+	}
+	if method == MethodGet {
+		return nil, fmt.Errorf("GET takes no body")
+	}
+	if len(body) > maxBodyBytes {
+		return nil, fmt.Errorf("body is %d bytes, larger than the limit of %d", len(body), maxBodyBytes)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return nil, fmt.Errorf("body must be a JSON object: %v", err)
+	}
+	if method == MethodPut {
+		for field, value := range fields {
+			if !envelopeFields[field] {
+				return nil, fmt.Errorf("field %s is not an ARM resource field: a PUT body may set %s", field, envelopeFieldNames)
+			}
+			var s string
+			switch field {
+			case "id":
+				if err := json.Unmarshal(value, &s); err != nil || !strings.EqualFold(s, resourcePath) {
+					return nil, fmt.Errorf("body id %s does not match the url %s", value, resourcePath)
+				}
+			case "name":
+				if err := json.Unmarshal(value, &s); err != nil || !strings.EqualFold(s, path.Base(resourcePath)) {
+					return nil, fmt.Errorf("body name %s does not match the url %s", value, resourcePath)
+				}
+			case "properties", "tags", "sku", "identity", "extendedLocation", "plan":
+				if !isObject(value) {
+					return nil, fmt.Errorf("body field %s must be a JSON object", field)
+				}
+			}
+		}
+	}
+	return json.RawMessage(body), nil
+}
+
+// isObject reports whether value is a JSON object
+func isObject(value json.RawMessage) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal(value, &object) == nil && object != nil
+}
+
+// globPattern compiles an allowed path pattern into a case-insensitive regular expression
+func globPattern(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(strings.Trim(strings.TrimSpace(pattern), "/"))
+	return regexp.MustCompile(`(?i)^` + strings.ReplaceAll(quoted, `\*`, `.*`) + `$`)
+}
@@ -0,0 +1,50 @@
+package azrest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterRestTool registers the az_rest tool. allowedPaths are the provider path patterns requests may target.
+func RegisterRestTool(allowedPaths []string) mcp.Tool {
+	description := fmt.Sprintf(`Call an Azure Resource Manager API directly, for APIs that have no az command or tool yet, such as preview features.
+
+Requests are limited to ARM management endpoints whose provider path matches an allowed pattern. The provider path is
+the part of the URL after its last /providers/, for example Microsoft.ContainerService/managedClusters/<cluster>.
+Allowed patterns: %s
+
+Methods:
+- GET: Read a resource or list resources
+- PUT: Create or replace a resource (requires readwrite); the body must be a JSON object with the ARM resource
+  envelope fields only (properties, location, tags, sku, identity, kind, zones, extendedLocation, plan)
+- POST: Run a resource action (requires readwrite); the body, if any, must be a JSON object. Actions that list
+  credentials, keys or secrets are not allowed, nor is the cluster run command API (runCommand, commandResults);
+  use az_aks_operations command-invoke instead.
+
+The url must include api-version. Every call is recorded in the audit log.
+
+Examples:
+- Read a cluster with a preview API: method="GET", url="/subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.ContainerService/managedClusters/<cluster>?api-version=2025-05-02-preview"
+- List available Kubernetes versions: method="GET", url="/subscriptions/<sub>/providers/Microsoft.ContainerService/locations/eastus/kubernetesVersions?api-version=2024-05-01"
+- Abort the running operation: method="POST", url="/subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.ContainerService/managedClusters/<cluster>/abort?api-version=2024-05-01"`,
+		strings.Join(allowedPaths, ", "))
+
+	return mcp.NewTool(
+		"az_rest",
+		mcp.WithDescription(description),
+		mcp.WithString("method",
+			mcp.Description("HTTP method of the request"),
+			mcp.Enum(MethodGet, MethodPut, MethodPost),
+			mcp.Required(),
+		),
+		mcp.WithString("url",
+			mcp.Description("ARM path starting with /subscriptions/, or the full https URL on the ARM endpoint, including api-version"),
+			mcp.Required(),
+		),
+		mcp.WithString("body",
+			mcp.Description("JSON object sent as the request body (PUT and POST only)"),
+		),
+	)
+}
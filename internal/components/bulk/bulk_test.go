@@ -0,0 +1,254 @@
+package bulk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/approval"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+const testDeployments = `{"items":[
+	{"metadata":{"name":"web"},"spec":{"replicas":3}},
+	{"metadata":{"name":"worker"},"spec":{"replicas":1}},
+	{"metadata":{"name":"idle","annotations":{"aks-mcp/replicas-before-scale-to-zero":"2"}},"spec":{"replicas":0}}
+]}`
+
+// fakeKubectl returns canned output by command prefix and records the commands run
+type fakeKubectl struct {
+	responses map[string]string
+	failures  map[string]bool
+	commands  []string
+}
+
+func (f *fakeKubectl) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix := range f.failures {
+		if strings.HasPrefix(cmd, prefix) {
+			return "error: the server is unavailable", fmt.Errorf("exit status 1")
+		}
+	}
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return "", nil
+}
+
+func (f *fakeKubectl) writes() []string {
+	var writes []string
+	for _, cmd := range f.commands {
+		if !strings.HasPrefix(cmd, "get ") {
+			writes = append(writes, cmd)
+		}
+	}
+	return writes
+}
+
+func runBulk(t *testing.T, params map[string]interface{}, kubectl *fakeKubectl, approvals *approval.Manager, logger *audit.Logger, cfg *config.ConfigData) Result {
+	t.Helper()
+	output, err := HandleNamespaceBulk(params, kubectl, approvals, logger, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	return result
+}
+
+func TestRegisterNamespaceBulkTool(t *testing.T) {
+	tool := RegisterNamespaceBulkTool()
+	if tool.Name != "aks_namespace_bulk" {
+		t.Errorf("Expected tool name 'aks_namespace_bulk', got '%s'", tool.Name)
+	}
+	if strings.Join(tool.InputSchema.Required, ",") != "operation,namespace" {
+		t.Errorf("Unexpected required parameters %v", tool.InputSchema.Required)
+	}
+}
+
+// TestPreviewThenRun tests that a preview issues an approval that runs the same changes once
+func TestPreviewThenRun(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{"get deployments": testDeployments}}
+	approvals := approval.NewManager(nil, 0)
+	logger := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	params := map[string]interface{}{"operation": OpScaleToZero, "namespace": "shop", "label_selector": "tier=batch"}
+
+	preview := runBulk(t, params, kubectl, approvals, logger, cfg)
+	if preview.Executed || preview.ApprovalID == "" || len(preview.Changes) != 2 || len(preview.Skipped) != 1 {
+		t.Fatalf("Expected a preview with an approval, got %+v", preview)
+	}
+	if kubectl.commands[0] != "get deployments -n shop -o json -l tier=batch" || len(kubectl.writes()) != 0 {
+		t.Errorf("Expected only the deployments to be read, got %v", kubectl.commands)
+	}
+	if change := preview.Changes[0]; change.Name != "web" || change.Current != "3 replicas" || len(change.Commands) != 2 ||
+		change.Commands[0] != "annotate deployment/web aks-mcp/replicas-before-scale-to-zero=3 --overwrite -n shop" {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	params["approval_id"] = preview.ApprovalID
+	run := runBulk(t, params, kubectl, approvals, logger, cfg)
+	if !run.Executed || !run.Succeeded || !run.Changes[0].Succeeded || !run.Changes[1].Succeeded {
+		t.Errorf("Expected the changes to run, got %+v", run)
+	}
+	if writes := kubectl.writes(); len(writes) != 4 || writes[1] != "scale deployment/web --replicas=0 -n shop" {
+		t.Errorf("Unexpected commands %v", writes)
+	}
+
+	if _, err := HandleNamespaceBulk(params, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrNotFound) {
+		t.Errorf("Expected an approval to run once, got %v", err)
+	}
+	records, _ := logger.Records()
+	if len(records) != 2 || records[0].Outcome != audit.OutcomeSucceeded || records[1].Outcome != audit.OutcomeDenied {
+		t.Fatalf("Expected a succeeded and a denied run to be audited, got %+v", records)
+	}
+	if records[0].Target != "Deployment web, Deployment worker" || records[0].Namespace != "shop" {
+		t.Errorf("Unexpected audit record %+v", records[0])
+	}
+}
+
+// TestRunRequiresMatchingPlan tests that objects changed since the preview make the approval not match
+func TestRunRequiresMatchingPlan(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{"get deployments": testDeployments}}
+	approvals := approval.NewManager(nil, 0)
+	logger := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	params := map[string]interface{}{"operation": OpRestartDeployments, "namespace": "shop"}
+
+	preview := runBulk(t, params, kubectl, approvals, logger, cfg)
+	if len(preview.Changes) != 2 || preview.Skipped[0].Name != "idle" {
+		t.Fatalf("Unexpected preview %+v", preview)
+	}
+	kubectl.responses["get deployments"] = strings.Replace(testDeployments, `"name":"worker"`, `"name":"api"`, 1)
+	params["approval_id"] = preview.ApprovalID
+	if _, err := HandleNamespaceBulk(params, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrMismatch) {
+		t.Errorf("Expected a changed namespace to need a new preview, got %v", err)
+	}
+	params["operation"] = OpScaleToZero
+	if _, err := HandleNamespaceBulk(params, kubectl, approvals, logger, cfg); !errors.Is(err, approval.ErrMismatch) {
+		t.Errorf("Expected an approval of another operation to be refused, got %v", err)
+	}
+	if records, _ := logger.Records(); len(records) != 2 || records[1].Outcome != audit.OutcomeDenied {
+		t.Errorf("Expected the refused runs to be audited, got %+v", records)
+	}
+	if writes := kubectl.writes(); len(writes) != 0 {
+		t.Errorf("Expected nothing to run, got %v", writes)
+	}
+}
+
+func TestRestoreAndFailures(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{"get deployments": testDeployments}, failures: map[string]bool{"scale deployment/idle": true}}
+	approvals := approval.NewManager(nil, 0)
+	logger := audit.NewLogger(store.NewMemoryStore())
+	cfg := config.NewConfig()
+	params := map[string]interface{}{"operation": OpRestoreReplicas, "namespace": "shop"}
+
+	preview := runBulk(t, params, kubectl, approvals, logger, cfg)
+	if len(preview.Changes) != 1 || preview.Changes[0].Planned != "2 replicas" || len(preview.Skipped) != 2 {
+		t.Fatalf("Unexpected preview %+v", preview)
+	}
+	params["approval_id"] = preview.ApprovalID
+	run := runBulk(t, params, kubectl, approvals, logger, cfg)
+	if run.Succeeded || !strings.Contains(run.Changes[0].Error, "server is unavailable") || run.Next == "" {
+		t.Errorf("Expected the failed scale to be reported, got %+v", run)
+	}
+	// The annotation is only removed once the deployment is scaled back
+	if writes := kubectl.writes(); len(writes) != 1 {
+		t.Errorf("Expected the run to stop at the failed command, got %v", writes)
+	}
+	if records, _ := logger.Records(); len(records) != 1 || records[0].Outcome != audit.OutcomeFailed {
+		t.Errorf("Expected a failed run to be audited, got %+v", records)
+	}
+}
+
+func TestDeleteCompletedJobs(t *testing.T) {
+	old := time.Now().Add(-10 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	jobs := fmt.Sprintf(`{"items":[
+		{"metadata":{"name":"old"},"status":{"completionTime":%q,"conditions":[{"type":"Complete","status":"True"}]}},
+		{"metadata":{"name":"recent"},"status":{"completionTime":%q,"conditions":[{"type":"Complete","status":"True"}]}},
+		{"metadata":{"name":"failed"},"status":{"conditions":[{"type":"Failed","status":"True"}]}}
+	]}`, old, recent)
+	kubectl := &fakeKubectl{responses: map[string]string{"get jobs": jobs}}
+	params := map[string]interface{}{"operation": OpDeleteCompletedJobs, "namespace": "batch"}
+
+	preview := runBulk(t, params, kubectl, approval.NewManager(nil, 0), nil, config.NewConfig())
+	if len(preview.Changes) != 1 || preview.Changes[0].Commands[0] != "delete job/old -n batch" || len(preview.Skipped) != 2 {
+		t.Errorf("Unexpected preview %+v", preview)
+	}
+
+	params["older_than_days"] = float64(30)
+	preview = runBulk(t, params, kubectl, approval.NewManager(nil, 0), nil, config.NewConfig())
+	if len(preview.Changes) != 0 || preview.ApprovalID != "" || preview.Next == "" {
+		t.Errorf("Expected nothing to confirm, got %+v", preview)
+	}
+}
+
+func TestReviewModeDefersEveryChange(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{"get deployments": testDeployments}}
+	plan := review.New()
+	cfg := config.NewConfig().ForReview(plan)
+	deferring := deferringKubectl{kubectl, plan}
+	output, err := HandleNamespaceBulk(map[string]interface{}{"operation": OpRestartDeployments, "namespace": "shop"}, deferring, approval.NewManager(nil, 0), nil, cfg)
+	if err != nil || output != "" {
+		t.Fatalf("Unexpected result %q (%v)", output, err)
+	}
+	if changes := plan.Changes(); len(changes) != 2 || changes[1].Command != "kubectl rollout restart deployment/worker -n shop" {
+		t.Errorf("Expected both restarts to be deferred, got %+v", changes)
+	}
+}
+
+// deferringKubectl defers writes to a review plan like the kubectl executor adapter does in review mode
+type deferringKubectl struct {
+	*fakeKubectl
+	plan *review.Plan
+}
+
+func (d deferringKubectl) Execute(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	if cmd, _ := params["command"].(string); review.IsClusterWrite(cmd) {
+		return "", d.plan.Defer(review.Change{Kind: review.KindKubectl, Command: "kubectl " + cmd})
+	}
+	return d.fakeKubectl.Execute(params, cfg)
+}
+
+func TestNamespaceBulkValidation(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "shop,batch"
+	for _, params := range []map[string]interface{}{
+		{"operation": "drain", "namespace": "shop"},
+		{"operation": OpRestartDeployments},
+		{"operation": OpRestartDeployments, "namespace": "Shop"},
+		{"operation": OpRestartDeployments, "namespace": "kube-system"},
+		{"operation": OpRestartDeployments, "namespace": "payments"},
+		{"operation": OpRestartDeployments, "namespace": "shop", "label_selector": "app=web; rm -rf /"},
+		{"operation": OpRestartDeployments, "namespace": "shop", "older_than_days": float64(3)},
+		{"operation": OpDeleteCompletedJobs, "namespace": "batch", "older_than_days": float64(0)},
+		{"operation": OpDeleteCompletedJobs, "namespace": "batch", "older_than_days": 1.5},
+	} {
+		kubectl := &fakeKubectl{}
+		if _, err := HandleNamespaceBulk(params, kubectl, approval.NewManager(nil, 0), nil, cfg); err == nil || len(kubectl.commands) != 0 {
+			t.Errorf("Expected %v to be rejected before running commands, got %v %v", params, err, kubectl.commands)
+		}
+	}
+
+	// Plans larger than the limit must be narrowed
+	items := make([]string, maxTargets+1)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"metadata":{"name":"web-%d"},"spec":{"replicas":1}}`, i)
+	}
+	kubectl := &fakeKubectl{responses: map[string]string{"get deployments": `{"items":[` + strings.Join(items, ",") + `]}`}}
+	if _, err := HandleNamespaceBulk(map[string]interface{}{"operation": OpRestartDeployments, "namespace": "shop"}, kubectl, approval.NewManager(nil, 0), nil, cfg); err == nil || !strings.Contains(err.Error(), "label_selector") {
+		t.Errorf("Expected too many objects to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+// Package bulk runs guard-railed operations on all matching objects of a namespace: restarting its
+// deployments, scaling them to zero and back, and deleting its old completed jobs. An operation is first
+// previewed with the changes it would make; it runs only when the caller confirms that exact preview with
+// the approval issued for it. Every confirmed run, including denied ones, is audited.
+package bulk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/approval"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// Operations of the aks_namespace_bulk tool
+const (
+	OpRestartDeployments  = "restart-deployments"
+	OpScaleToZero         = "scale-to-zero"
+	OpRestoreReplicas     = "restore-replicas"
+	OpDeleteCompletedJobs = "delete-completed-jobs"
+)
+
+// Operations lists the operations of the aks_namespace_bulk tool
+var Operations = []string{OpRestartDeployments, OpScaleToZero, OpRestoreReplicas, OpDeleteCompletedJobs}
+
+const (
+	// toolName is the name approvals and audit records are issued under
+	toolName = "aks_namespace_bulk"
+	// ReplicasAnnotation records on a deployment scaled to zero the replicas restore-replicas scales it back to
+	ReplicasAnnotation = "aks-mcp/replicas-before-scale-to-zero"
+	// maxTargets bounds the objects one call changes
+	maxTargets = 100
+	// defaultJobAgeDays and maxJobAgeDays bound older_than_days
+	defaultJobAgeDays = 7
+	maxJobAgeDays     = 3650
+)
+
+// protectedNamespaces hold the cluster's own components, which bulk operations must not touch
+var protectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Change is one object an operation changes
+type Change struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Current and Planned describe the object before and after the change
+	Current string `json:"current"`
+	Planned string `json:"planned"`
+	// Commands are the kubectl commands that make the change
+	Commands []string `json:"commands"`
+	// Succeeded and Error report the outcome of a confirmed run
+	Succeeded bool   `json:"succeeded,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Skipped is an object the operation matched but leaves alone
+type Skipped struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Result is the result returned by the aks_namespace_bulk tool
+type Result struct {
+	Operation     string `json:"operation"`
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Executed is false for a preview and true once the changes were run
+	Executed   bool       `json:"executed"`
+	Succeeded  bool       `json:"succeeded,omitempty"`
+	Changes    []Change   `json:"changes"`
+	Skipped    []Skipped  `json:"skipped,omitempty"`
+	ApprovalID string     `json:"approvalId,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	Next       string     `json:"next,omitempty"`
+}
+
+// options holds the parsed tool parameters
+type options struct {
+	operation  string
+	namespace  string
+	selector   string
+	jobAgeDays int
+	approvalID string
+}
+
+// GetNamespaceBulkHandler returns a handler for the aks_namespace_bulk command
+func GetNamespaceBulkHandler(approvals *approval.Manager, auditLog *audit.Logger, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNamespaceBulk(params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), approvals, auditLog, cfg)
+	})
+}
+
+// HandleNamespaceBulk previews a bulk operation on a namespace and issues an approval for it, or runs a
+// previewed operation once its approval is given. The changes are planned again from the live objects when
+// the operation is confirmed, so objects that changed since the preview make the approval not match.
+func HandleNamespaceBulk(params map[string]interface{}, executor tools.CommandExecutor, approvals *approval.Manager, auditLog *audit.Logger, cfg *config.ConfigData) (string, error) {
+	opts, err := parseOptions(params, cfg)
+	if err != nil {
+		return "", err
+	}
+	kubectlRun := func(command string) (string, error) {
+		return executor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+
+	result := Result{Operation: opts.operation, Namespace: opts.namespace, LabelSelector: opts.selector}
+	result.Changes, result.Skipped, err = plan(kubectlRun, opts, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(result.Changes) > maxTargets {
+		return "", fmt.Errorf("%s would change %d objects; at most %d are changed in one call, narrow it with label_selector", opts.operation, len(result.Changes), maxTargets)
+	}
+	digest := approval.Digest(append([]string{opts.operation, opts.namespace, opts.selector}, describeChanges(result.Changes)...)...)
+
+	// Approvals issued to a session can only be confirmed by that session
+	owner := ""
+	if cfg.Session != nil {
+		owner = cfg.Session.SessionID
+	}
+	if cfg.Review != nil && len(result.Changes) > 0 {
+		// In review mode the commands are deferred instead of run, so no approval is issued either
+		return "", deferForReview(kubectlRun, result.Changes)
+	}
+	if opts.approvalID == "" {
+		return preview(approvals, result, digest, owner)
+	}
+
+	record := audit.Record{Tool: toolName, Action: opts.operation, Namespace: opts.namespace, Target: describeTargets(result.Changes), Client: cfg.ClientName()}
+	if _, err := approvals.Consume(opts.approvalID, toolName, digest, owner); err != nil {
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+		auditLog.Log(record)
+		return "", err
+	}
+	result.Executed, result.Succeeded = true, true
+	var commands, failures []string
+	for i := range result.Changes {
+		change := &result.Changes[i]
+		commands = append(commands, change.Commands...)
+		if err := runChange(kubectlRun, change); err != nil {
+			change.Error = err.Error()
+			failures = append(failures, fmt.Sprintf("%s %s: %v", change.Kind, change.Name, err))
+			result.Succeeded = false
+			continue
+		}
+		change.Succeeded = true
+	}
+	record.Command = strings.Join(commands, "; ")
+	record.Outcome = audit.OutcomeSucceeded
+	if !result.Succeeded {
+		record.Outcome, record.Error = audit.OutcomeFailed, strings.Join(failures, "; ")
+		result.Next = "Some changes failed; see the errors of the changes. Preview the operation again to retry the objects that were not changed."
+	}
+	auditLog.Log(record)
+	return marshal(result)
+}
+
+// preview returns the planned changes with an approval that confirms them
+func preview(approvals *approval.Manager, result Result, digest, owner string) (string, error) {
+	if len(result.Changes) == 0 {
+		result.Next = "No objects need to change; there is nothing to confirm."
+		return marshal(result)
+	}
+	issued, err := approvals.Request(toolName, digest, fmt.Sprintf("%s in namespace %s: %s", result.Operation, result.Namespace, describeTargets(result.Changes)), owner)
+	if err != nil {
+		return "", err
+	}
+	result.ApprovalID = issued.ID
+	result.ExpiresAt = &issued.Expires
+	result.Next = fmt.Sprintf("Show the changes to the user and ask for confirmation. To run them, call aks_namespace_bulk again with "+
+		"the same operation, namespace, label_selector and older_than_days, and approval_id %s before %s.", issued.ID, issued.Expires.Format(time.RFC3339))
+	return marshal(result)
+}
+
+// runChange runs the commands of a change in order, stopping at the first that fails
+func runChange(kubectlRun func(string) (string, error), change *Change) error {
+	for _, command := range change.Commands {
+		if output, err := kubectlRun(command); err != nil {
+			if message := strings.TrimSpace(output); message != "" {
+				return fmt.Errorf("%s: %s", err, message)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// deferForReview defers the commands of every change to the call's review plan. The executor defers
+// each write and returns review.ErrDeferred in its place; other errors stop the call.
+func deferForReview(kubectlRun func(string) (string, error), changes []Change) error {
+	for _, change := range changes {
+		for _, command := range change.Commands {
+			if _, err := kubectlRun(command); err != nil && !errors.Is(err, review.ErrDeferred) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseOptions validates the tool parameters before any command runs
+func parseOptions(params map[string]interface{}, cfg *config.ConfigData) (options, error) {
+	var opts options
+	opts.operation, _ = params["operation"].(string)
+	if !slices.Contains(Operations, opts.operation) {
+		return opts, fmt.Errorf("invalid operation %q: expected one of %s", opts.operation, strings.Join(Operations, ", "))
+	}
+	opts.namespace, _ = params["namespace"].(string)
+	if opts.namespace == "" {
+		return opts, fmt.Errorf("missing namespace parameter")
+	}
+	if !common.NamespacePattern.MatchString(opts.namespace) {
+		return opts, fmt.Errorf("invalid namespace parameter: %s", opts.namespace)
+	}
+	if slices.Contains(protectedNamespaces, opts.namespace) {
+		return opts, fmt.Errorf("namespace %s holds cluster components and is not changed by bulk operations; use kubectl for individual objects", opts.namespace)
+	}
+	if !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(opts.namespace) {
+		return opts, fmt.Errorf("access to namespace '%s' is denied by security configuration", opts.namespace)
+	}
+	opts.selector, _ = params["label_selector"].(string)
+	if opts.selector != "" && !common.LabelSelectorPattern.MatchString(opts.selector) {
+		return opts, fmt.Errorf("invalid label_selector %q: use equality-based selectors such as app=web,tier!=cache", opts.selector)
+	}
+
+	opts.jobAgeDays = defaultJobAgeDays
+	if value, ok := params["older_than_days"]; ok && value != nil {
+		days, ok := value.(float64)
+		if !ok || days != float64(int(days)) || days < 1 || days > maxJobAgeDays {
+			return opts, fmt.Errorf("invalid older_than_days %v: expected a whole number of days from 1 to %d", value, maxJobAgeDays)
+		}
+		if opts.operation != OpDeleteCompletedJobs {
+			return opts, fmt.Errorf("older_than_days only applies to %s", OpDeleteCompletedJobs)
+		}
+		opts.jobAgeDays = int(days)
+	}
+	opts.approvalID, _ = params["approval_id"].(string)
+	return opts, nil
+}
+
+// deployment holds the fields of a deployment the operations read
+type deployment struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+	} `json:"spec"`
+}
+
+// job holds the fields of a job delete-completed-jobs reads
+type job struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		CompletionTime *time.Time `json:"completionTime"`
+		Conditions     []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// plan lists the objects of the namespace and returns the changes the operation makes and the objects it skips
+func plan(kubectlRun func(string) (string, error), opts options, now time.Time) ([]Change, []Skipped, error) {
+	resource := "deployments"
+	if opts.operation == OpDeleteCompletedJobs {
+		resource = "jobs"
+	}
+	command := fmt.Sprintf("get %s -n %s -o json", resource, opts.namespace)
+	if opts.selector != "" {
+		command += " -l " + opts.selector
+	}
+	output, err := kubectlRun(command)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %v", resource, err)
+	}
+
+	changes, skipped := []Change{}, []Skipped{}
+	if opts.operation == OpDeleteCompletedJobs {
+		var list struct {
+			Items []job `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(output), &list); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse jobs: %v", err)
+		}
+		cutoff := now.Add(-time.Duration(opts.jobAgeDays) * 24 * time.Hour)
+		for _, item := range list.Items {
+			change, reason := planJob(item, opts.namespace, cutoff, opts.jobAgeDays)
+			if reason != "" {
+				skipped = append(skipped, Skipped{Kind: "Job", Name: item.Metadata.Name, Reason: reason})
+				continue
+			}
+			changes = append(changes, change)
+		}
+		return changes, skipped, nil
+	}
+
+	var list struct {
+		Items []deployment `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse deployments: %v", err)
+	}
+	for _, item := range list.Items {
+		change, reason := planDeployment(item, opts.operation, opts.namespace)
+		if reason != "" {
+			skipped = append(skipped, Skipped{Kind: "Deployment", Name: item.Metadata.Name, Reason: reason})
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes, skipped, nil
+}
+
+// planDeployment returns the change an operation makes to a deployment, or the reason it is skipped
+func planDeployment(item deployment, operation, namespace string) (Change, string) {
+	name := item.Metadata.Name
+	replicas := 1
+	if item.Spec.Replicas != nil {
+		replicas = *item.Spec.Replicas
+	}
+	saved, hasSaved := item.Metadata.Annotations[ReplicasAnnotation]
+	current := fmt.Sprintf("%d replicas", replicas)
+	change := Change{Kind: "Deployment", Name: name, Current: current}
+
+	switch operation {
+	case OpRestartDeployments:
+		if replicas == 0 {
+			return change, "scaled to zero, so there are no pods to restart"
+		}
+		change.Planned = fmt.Sprintf("%d replicas replaced by a rolling restart", replicas)
+		change.Commands = []string{fmt.Sprintf("rollout restart deployment/%s -n %s", name, namespace)}
+	case OpScaleToZero:
+		if replicas == 0 {
+			return change, "already scaled to zero"
+		}
+		change.Planned = fmt.Sprintf("0 replicas, with %d recorded in the %s annotation", replicas, ReplicasAnnotation)
+		change.Commands = []string{
+			fmt.Sprintf("annotate deployment/%s %s=%d --overwrite -n %s", name, ReplicasAnnotation, replicas, namespace),
+			fmt.Sprintf("scale deployment/%s --replicas=0 -n %s", name, namespace),
+		}
+	case OpRestoreReplicas:
+		if !hasSaved {
+			return change, "was not scaled to zero by scale-to-zero"
+		}
+		target, err := strconv.Atoi(saved)
+		if err != nil || target < 1 {
+			return change, fmt.Sprintf("has an invalid %s annotation %q", ReplicasAnnotation, saved)
+		}
+		if replicas != 0 {
+			return change, fmt.Sprintf("was scaled to %d replicas since scale-to-zero; remove the %s annotation or scale it yourself", replicas, ReplicasAnnotation)
+		}
+		change.Planned = fmt.Sprintf("%d replicas", target)
+		change.Commands = []string{
+			fmt.Sprintf("scale deployment/%s --replicas=%d -n %s", name, target, namespace),
+			fmt.Sprintf("annotate deployment/%s %s- -n %s", name, ReplicasAnnotation, namespace),
+		}
+	}
+	return change, ""
+}
+
+// planJob returns the deletion of a job that completed before the cutoff, or the reason it is skipped
+func planJob(item job, namespace string, cutoff time.Time, days int) (Change, string) {
+	complete := false
+	for _, condition := range item.Status.Conditions {
+		if condition.Type == "Complete" && condition.Status == "True" {
+			complete = true
+		}
+	}
+	if !complete || item.Status.CompletionTime == nil {
+		return Change{}, "has not completed successfully"
+	}
+	if item.Status.CompletionTime.After(cutoff) {
+		return Change{}, fmt.Sprintf("completed less than %d days ago", days)
+	}
+	return Change{
+		Kind:     "Job",
+		Name:     item.Metadata.Name,
+		Current:  "completed at " + item.Status.CompletionTime.UTC().Format(time.RFC3339),
+		Planned:  "deleted with its pods",
+		Commands: []string{fmt.Sprintf("delete job/%s -n %s", item.Metadata.Name, namespace)},
+	}, ""
+}
+
+// describeChanges returns one line per change for the approval digest
+func describeChanges(changes []Change) []string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = strings.Join(change.Commands, "\n")
+	}
+	return lines
+}
+
+// describeTargets lists the objects of the changes for approvals
+func describeTargets(changes []Change) string {
+	names := make([]string, len(changes))
+	for i, change := range changes {
+		names[i] = change.Kind + " " + change.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func marshal(result Result) (string, error) {
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bulk operation result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
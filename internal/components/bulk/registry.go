@@ -0,0 +1,54 @@
+package bulk
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterNamespaceBulkTool registers the aks_namespace_bulk tool
+func RegisterNamespaceBulkTool() mcp.Tool {
+	description := fmt.Sprintf(`Run one operation on all matching deployments or jobs of a namespace instead of one kubectl call per object.
+
+Operations:
+- restart-deployments: rolling restart of every deployment with replicas (kubectl rollout restart)
+- scale-to-zero: scales every deployment to zero, recording its replicas in the %s annotation
+- restore-replicas: scales the deployments scale-to-zero stopped back to their recorded replicas and removes the annotation
+- delete-completed-jobs: deletes the jobs that completed successfully more than older_than_days ago, with their pods
+
+Running an operation is a two step process:
+- without approval_id: previews the objects that would change, their current and planned state, the kubectl commands
+  and the objects that are skipped and why, and returns an approval_id
+- with approval_id: runs the previewed changes once the user has confirmed them, with the same operation, namespace,
+  label_selector and older_than_days
+
+An approval can be used once and expires after 15 minutes. The changes are planned again from the live objects when they
+are confirmed, so objects that changed since the preview require a new preview. At most %d objects are changed in one
+call; narrow larger namespaces with label_selector. kube-system, kube-public and kube-node-lease are refused, as are
+namespaces outside --allow-namespaces.
+
+Requires readwrite or admin access. Every confirmed run, including denied ones, is audited.`, ReplicasAnnotation, maxTargets)
+
+	return mcp.NewTool(
+		"aks_namespace_bulk",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Bulk operation to preview or run"),
+			mcp.Enum(Operations...),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace whose deployments or jobs are changed"),
+			mcp.Required(),
+		),
+		mcp.WithString("label_selector",
+			mcp.Description("Only objects matching this equality-based label selector, e.g. app=web,tier!=cache"),
+		),
+		mcp.WithNumber("older_than_days",
+			mcp.Description(fmt.Sprintf("delete-completed-jobs only: minimum days since a job completed (default: %d)", defaultJobAgeDays)),
+		),
+		mcp.WithString("approval_id",
+			mcp.Description("Approval returned by the preview; runs the previewed changes"),
+		),
+	)
+}
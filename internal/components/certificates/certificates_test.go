@@ -0,0 +1,136 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// makeCertPEM creates a self-signed certificate PEM expiring at notAfter
+func makeCertPEM(t *testing.T, commonName string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func b64(data []byte) string { return base64.StdEncoding.EncodeToString(data) }
+
+func TestRegisterCheckCertificateExpiryTool(t *testing.T) {
+	tool := RegisterCheckCertificateExpiryTool()
+
+	if tool.Name != "check_certificate_expiry" {
+		t.Errorf("Expected tool name 'check_certificate_expiry', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 3 {
+		t.Errorf("Expected 3 required parameters, got %v", tool.InputSchema.Required)
+	}
+	if _, ok := tool.InputSchema.Properties["window_days"]; !ok {
+		t.Error("Expected window_days parameter")
+	}
+}
+
+func TestParseTLSSecretsAndIngressReferences(t *testing.T) {
+	now := time.Now().UTC()
+	soon := makeCertPEM(t, "soon.example.com", now.Add(5*24*time.Hour))
+	secrets := fmt.Sprintf("apps   web-tls   %s\napps   broken    bm90IGEgY2VydA==\napps   empty     <none>\n", b64(soon))
+
+	certs, problems, err := ParseTLSSecrets(secrets, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(certs) != 1 || certs[0].Name != "web-tls" || certs[0].DaysRemaining != 4 {
+		t.Errorf("Unexpected certificates: %+v", certs)
+	}
+	if len(problems) != 2 {
+		t.Errorf("Expected problems for the broken and empty secrets, got %v", problems)
+	}
+	if _, _, err := ParseTLSSecrets("unexpected output", now); err == nil {
+		t.Error("Expected an error for output that is not in the column format")
+	}
+
+	refs, err := ParseIngressTLSReferences(`{"items":[{"metadata":{"name":"web","namespace":"apps"},"spec":{"tls":[{"secretName":"web-tls"}]}}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := refs["apps/web-tls"]; len(got) != 1 || got[0] != "ingress/web" {
+		t.Errorf("Expected ingress reference, got %v", refs)
+	}
+}
+
+func TestParseWebhookCABundles(t *testing.T) {
+	now := time.Now().UTC()
+	ca := makeCertPEM(t, "webhook-ca", now.Add(-time.Hour))
+	output := fmt.Sprintf(`{"items":[{"kind":"ValidatingWebhookConfiguration","metadata":{"name":"policy"},
+		"webhooks":[{"name":"validate.policy.io","clientConfig":{"caBundle":"%s"}},{"name":"no-bundle","clientConfig":{}}]}]}`, b64(ca))
+
+	certs, err := ParseWebhookCABundles(output, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(certs) != 1 || !certs[0].Expired || certs[0].UsedBy[0] != "ValidatingWebhookConfiguration/policy" {
+		t.Errorf("Unexpected webhook certificates: %+v", certs)
+	}
+}
+
+func TestParseBootstrapTokensAndCSRs(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiration := b64([]byte("2026-01-03T00:00:00Z"))
+	tokens, err := ParseBootstrapTokens(fmt.Sprintf(`{"items":[{"metadata":{"name":"bootstrap-token-abc","namespace":"kube-system"},"data":{"expiration":"%s"}}]}`, expiration), now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].DaysRemaining != 2 || tokens[0].Source != SourceBootstrapToken {
+		t.Errorf("Unexpected tokens: %+v", tokens)
+	}
+
+	csrs := `{"items":[
+		{"metadata":{"name":"csr-pending","creationTimestamp":"2025-12-31T23:00:00Z"},"spec":{"signerName":"kubernetes.io/kubelet-serving","username":"system:node:aks-1"},"status":{}},
+		{"metadata":{"name":"csr-approved","creationTimestamp":"2025-12-31T23:00:00Z"},"spec":{},"status":{"conditions":[{"type":"Approved"}]}}
+	]}`
+	pending, err := ParsePendingCSRs(csrs, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Name != "csr-pending" || pending[0].AgeMinutes != 60 {
+		t.Errorf("Unexpected pending CSRs: %+v", pending)
+	}
+}
+
+func TestExpiringWithin(t *testing.T) {
+	now := time.Now().UTC()
+	certs := []CertificateInfo{
+		{Name: "later", NotAfter: now.Add(20 * 24 * time.Hour)},
+		{Name: "outside", NotAfter: now.Add(90 * 24 * time.Hour)},
+		{Name: "expired", NotAfter: now.Add(-time.Hour)},
+	}
+
+	expiring := ExpiringWithin(certs, 30*24*time.Hour, now)
+	if len(expiring) != 2 {
+		t.Fatalf("Expected 2 certificates within the window, got %d", len(expiring))
+	}
+	if expiring[0].Name != "expired" || expiring[1].Name != "later" {
+		t.Errorf("Expected certificates sorted soonest first, got %s, %s", expiring[0].Name, expiring[1].Name)
+	}
+}
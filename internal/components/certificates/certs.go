@@ -0,0 +1,323 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Certificate sources reported by the checker
+const (
+	SourceAPIServer      = "apiserver"
+	SourceBootstrapToken = "bootstrap-token"
+	SourceTLSSecret      = "tls-secret"
+	SourceWebhook        = "webhook"
+)
+
+// CertificateInfo describes a certificate (or token) and its expiry
+type CertificateInfo struct {
+	Source        string    `json:"source"`
+	Name          string    `json:"name"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Subject       string    `json:"subject,omitempty"`
+	Issuer        string    `json:"issuer,omitempty"`
+	DNSNames      []string  `json:"dnsNames,omitempty"`
+	UsedBy        []string  `json:"usedBy,omitempty"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining int       `json:"daysRemaining"`
+	Expired       bool      `json:"expired"`
+}
+
+// PendingCSR is a certificate signing request that has not been approved or issued
+type PendingCSR struct {
+	Name       string `json:"name"`
+	SignerName string `json:"signerName"`
+	Username   string `json:"username,omitempty"`
+	AgeMinutes int    `json:"ageMinutes"`
+}
+
+// newCertificateInfo builds a CertificateInfo for an x509 certificate relative to now
+func newCertificateInfo(source, name, namespace string, cert *x509.Certificate, now time.Time) CertificateInfo {
+	info := CertificateInfo{
+		Source:    source,
+		Name:      name,
+		Namespace: namespace,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotAfter:  cert.NotAfter.UTC(),
+	}
+	info.setRemaining(now)
+	return info
+}
+
+// setRemaining fills in the days remaining and expired flag
+func (c *CertificateInfo) setRemaining(now time.Time) {
+	c.DaysRemaining = int(math.Floor(c.NotAfter.Sub(now).Hours() / 24))
+	c.Expired = !c.NotAfter.After(now)
+}
+
+// ExpiringWithin returns the certificates expiring before now+window, soonest first
+func ExpiringWithin(certs []CertificateInfo, window time.Duration, now time.Time) []CertificateInfo {
+	cutoff := now.Add(window)
+	expiring := []CertificateInfo{}
+	for _, cert := range certs {
+		if cert.NotAfter.Before(cutoff) {
+			expiring = append(expiring, cert)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool { return expiring[i].NotAfter.Before(expiring[j].NotAfter) })
+	return expiring
+}
+
+// parsePEMCertificates decodes every CERTIFICATE block in PEM data
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return certs, nil
+}
+
+// kubeList is the subset of a kubectl list response used by the checker
+type kubeList struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// kubeMetadata is the object metadata used by the checker
+type kubeMetadata struct {
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+}
+
+// TLSSecretColumns is the kubectl output format used to list TLS secrets. It selects only the namespace,
+// name and certificate of each secret so private keys are never read from the API server.
+const TLSSecretColumns = `custom-columns=NAMESPACE:.metadata.namespace,NAME:.metadata.name,CERT:.data.tls\.crt`
+
+// ParseTLSSecrets extracts the leaf certificates from kubectl get secrets output in the
+// TLSSecretColumns format (without headers).
+func ParseTLSSecrets(output string, now time.Time) ([]CertificateInfo, []string, error) {
+	var certs []CertificateInfo
+	var problems []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("failed to parse secrets: unexpected line %q", line)
+		}
+		namespace, name, encoded := fields[0], fields[1], fields[2]
+		ref := namespace + "/" + name
+		if encoded == "<none>" {
+			problems = append(problems, fmt.Sprintf("secret %s: missing tls.crt", ref))
+			continue
+		}
+
+		pemData, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("secret %s: invalid tls.crt encoding", ref))
+			continue
+		}
+		parsed, err := parsePEMCertificates(pemData)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("secret %s: %v", ref, err))
+			continue
+		}
+		certs = append(certs, newCertificateInfo(SourceTLSSecret, name, namespace, parsed[0], now))
+	}
+	return certs, problems, nil
+}
+
+// ParseIngressTLSReferences maps "namespace/secret" to the ingresses that reference it
+func ParseIngressTLSReferences(output string) (map[string][]string, error) {
+	var list kubeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse ingresses: %v", err)
+	}
+
+	refs := make(map[string][]string)
+	for _, raw := range list.Items {
+		var ingress struct {
+			Metadata kubeMetadata `json:"metadata"`
+			Spec     struct {
+				TLS []struct {
+					SecretName string `json:"secretName"`
+				} `json:"tls"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(raw, &ingress); err != nil {
+			continue
+		}
+		for _, tlsSpec := range ingress.Spec.TLS {
+			if tlsSpec.SecretName == "" {
+				continue
+			}
+			key := ingress.Metadata.Namespace + "/" + tlsSpec.SecretName
+			refs[key] = append(refs[key], "ingress/"+ingress.Metadata.Name)
+		}
+	}
+	return refs, nil
+}
+
+// ParseWebhookCABundles extracts the CA certificates from validating and mutating webhook configurations
+func ParseWebhookCABundles(output string, now time.Time) ([]CertificateInfo, error) {
+	var list kubeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook configurations: %v", err)
+	}
+
+	var certs []CertificateInfo
+	for _, raw := range list.Items {
+		var config struct {
+			Kind     string       `json:"kind"`
+			Metadata kubeMetadata `json:"metadata"`
+			Webhooks []struct {
+				Name         string `json:"name"`
+				ClientConfig struct {
+					CABundle string `json:"caBundle"`
+				} `json:"clientConfig"`
+			} `json:"webhooks"`
+		}
+		if err := json.Unmarshal(raw, &config); err != nil {
+			continue
+		}
+		for _, webhook := range config.Webhooks {
+			pemData, err := base64.StdEncoding.DecodeString(webhook.ClientConfig.CABundle)
+			if err != nil || len(pemData) == 0 {
+				continue
+			}
+			parsed, err := parsePEMCertificates(pemData)
+			if err != nil {
+				continue
+			}
+			for _, cert := range parsed {
+				info := newCertificateInfo(SourceWebhook, webhook.Name, "", cert, now)
+				info.UsedBy = []string{config.Kind + "/" + config.Metadata.Name}
+				certs = append(certs, info)
+			}
+		}
+	}
+	return certs, nil
+}
+
+// ParseBootstrapTokens reads the expiration of bootstrap.kubernetes.io/token secrets
+func ParseBootstrapTokens(output string, now time.Time) ([]CertificateInfo, error) {
+	var list kubeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap tokens: %v", err)
+	}
+
+	var tokens []CertificateInfo
+	for _, raw := range list.Items {
+		var secret struct {
+			Metadata kubeMetadata      `json:"metadata"`
+			Data     map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &secret); err != nil {
+			continue
+		}
+		encoded, ok := secret.Data["expiration"]
+		if !ok {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		expiration, err := time.Parse(time.RFC3339, string(value))
+		if err != nil {
+			continue
+		}
+		token := CertificateInfo{
+			Source:    SourceBootstrapToken,
+			Name:      secret.Metadata.Name,
+			Namespace: secret.Metadata.Namespace,
+			NotAfter:  expiration.UTC(),
+		}
+		token.setRemaining(now)
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// ParsePendingCSRs returns certificate signing requests that have no Approved or Denied condition
+func ParsePendingCSRs(output string, now time.Time) ([]PendingCSR, error) {
+	var list kubeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate signing requests: %v", err)
+	}
+
+	pending := []PendingCSR{}
+	for _, raw := range list.Items {
+		var csr struct {
+			Metadata kubeMetadata `json:"metadata"`
+			Spec     struct {
+				SignerName string `json:"signerName"`
+				Username   string `json:"username"`
+			} `json:"spec"`
+			Status struct {
+				Conditions []struct {
+					Type string `json:"type"`
+				} `json:"conditions"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &csr); err != nil {
+			continue
+		}
+		if len(csr.Status.Conditions) > 0 {
+			continue
+		}
+		pending = append(pending, PendingCSR{
+			Name:       csr.Metadata.Name,
+			SignerName: csr.Spec.SignerName,
+			Username:   csr.Spec.Username,
+			AgeMinutes: int(now.Sub(csr.Metadata.CreationTimestamp).Minutes()),
+		})
+	}
+	return pending, nil
+}
+
+// fetchServerCertificate dials host:443 and returns the leaf certificate presented by the server.
+// The chain is not verified so that expired or self-signed certificates can still be inspected.
+var fetchServerCertificate = func(host string, timeout time.Duration) (*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, // #nosec G402 -- only the presented certificate is inspected
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	peers := conn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("server presented no certificates")
+	}
+	return peers[0], nil
+}
@@ -0,0 +1,147 @@
+// Package certificates provides tools for checking certificate expiry and TLS health of AKS clusters.
+package certificates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// defaultWindowDays is the expiry window used when window_days is not provided
+const defaultWindowDays = 30
+
+// apiServerDialTimeout bounds the TLS handshake with the API server
+const apiServerDialTimeout = 10 * time.Second
+
+// CertificateReport is the result returned by the check_certificate_expiry tool
+type CertificateReport struct {
+	ClusterName  string            `json:"clusterName"`
+	WindowDays   int               `json:"windowDays"`
+	CheckedCount int               `json:"checkedCount"`
+	Expiring     []CertificateInfo `json:"expiring"`
+	PendingCSRs  []PendingCSR      `json:"pendingCertificateSigningRequests"`
+	Warnings     []string          `json:"warnings,omitempty"`
+}
+
+// GetCheckCertificateExpiryHandler returns a handler for the check_certificate_expiry command
+func GetCheckCertificateExpiryHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleCheckCertificateExpiry(params, client, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleCheckCertificateExpiry collects certificates from the API server and the cluster and reports those expiring soon
+func HandleCheckCertificateExpiry(params map[string]interface{}, client *azureclient.AzureClient, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	windowDays := defaultWindowDays
+	if value, ok := params["window_days"].(string); ok && value != "" {
+		windowDays, err = strconv.Atoi(value)
+		if err != nil || windowDays < 0 {
+			return "", fmt.Errorf("invalid window_days parameter: %s", value)
+		}
+	}
+
+	cluster, err := common.GetClusterDetails(context.Background(), client, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+
+	now := time.Now().UTC()
+	report := CertificateReport{ClusterName: clusterName, WindowDays: windowDays}
+	var certs []CertificateInfo
+
+	// API server serving certificate
+	host := ""
+	if cluster.Properties != nil {
+		if cluster.Properties.Fqdn != nil && *cluster.Properties.Fqdn != "" {
+			host = *cluster.Properties.Fqdn
+		} else if cluster.Properties.PrivateFQDN != nil {
+			host = *cluster.Properties.PrivateFQDN
+		}
+	}
+	if host == "" {
+		report.Warnings = append(report.Warnings, "cluster has no API server FQDN; API server certificate was not checked")
+	} else if cert, err := fetchServerCertificate(host, apiServerDialTimeout); err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to read API server certificate from %s: %v", host, err))
+	} else {
+		certs = append(certs, newCertificateInfo(SourceAPIServer, host, "", cert, now))
+	}
+
+	// In-cluster checks; each is best effort so one denied or failing query does not hide the others
+	runKubectl := func(description, command string) (string, bool) {
+		output, err := kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list %s: %v", description, err))
+			return "", false
+		}
+		return output, true
+	}
+
+	if output, ok := runKubectl("bootstrap tokens", "get secrets --namespace kube-system --field-selector type=bootstrap.kubernetes.io/token -o json"); ok {
+		if tokens, err := ParseBootstrapTokens(output, now); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			certs = append(certs, tokens...)
+		}
+	}
+
+	report.PendingCSRs = []PendingCSR{}
+	if output, ok := runKubectl("certificate signing requests", "get certificatesigningrequests -o json"); ok {
+		if pending, err := ParsePendingCSRs(output, now); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			report.PendingCSRs = pending
+		}
+	}
+
+	ingressRefs := map[string][]string{}
+	if output, ok := runKubectl("ingresses", "get ingresses --all-namespaces -o json"); ok {
+		if refs, err := ParseIngressTLSReferences(output); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			ingressRefs = refs
+		}
+	}
+
+	if output, ok := runKubectl("TLS secrets", "get secrets --all-namespaces --field-selector type=kubernetes.io/tls --no-headers -o '"+TLSSecretColumns+"'"); ok {
+		secrets, problems, err := ParseTLSSecrets(output, now)
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		}
+		report.Warnings = append(report.Warnings, problems...)
+		for i := range secrets {
+			secrets[i].UsedBy = ingressRefs[secrets[i].Namespace+"/"+secrets[i].Name]
+		}
+		certs = append(certs, secrets...)
+	}
+
+	if output, ok := runKubectl("admission webhooks", "get validatingwebhookconfigurations,mutatingwebhookconfigurations -o json"); ok {
+		if webhooks, err := ParseWebhookCABundles(output, now); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			certs = append(certs, webhooks...)
+		}
+	}
+
+	report.CheckedCount = len(certs)
+	report.Expiring = ExpiringWithin(certs, time.Duration(windowDays)*24*time.Hour, now)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal certificate report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
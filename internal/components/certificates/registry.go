@@ -0,0 +1,38 @@
+package certificates
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterCheckCertificateExpiryTool registers the check_certificate_expiry tool
+func RegisterCheckCertificateExpiryTool() mcp.Tool {
+	description := `Inspect certificate expiry and TLS health for an AKS cluster.
+
+Checks performed:
+- API server serving certificate presented on the cluster FQDN
+- Node bootstrap token expiry and pending kubelet certificate signing requests (certificate rotation state)
+- kubernetes.io/tls secrets, including the ones referenced by Ingress resources
+- CA bundles of validating and mutating admission webhooks
+
+In-cluster checks use the current kubeconfig context. Returns the certificates expiring within the window, soonest first.`
+
+	return mcp.NewTool(
+		"check_certificate_expiry",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("window_days",
+			mcp.Description("Report certificates expiring within this many days (default: 30)"),
+		),
+	)
+}
@@ -0,0 +1,219 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeExecutor struct {
+	responses map[string]string
+	commands  []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return `{"items":[]}`, nil
+}
+
+type fakeARM struct {
+	body string
+	err  error
+}
+
+func (f *fakeARM) CallARM(_ context.Context, _, _ string) ([]byte, error) {
+	return []byte(f.body), f.err
+}
+
+func ago(d time.Duration) string {
+	return time.Now().UTC().Add(-d).Format(time.RFC3339)
+}
+
+func testReplicaSet(name, revision, image string, age time.Duration) string {
+	return fmt.Sprintf(`{
+  "metadata": {"name": %q, "namespace": "shop", "creationTimestamp": %q,
+    "annotations": {"deployment.kubernetes.io/revision": %q},
+    "ownerReferences": [{"kind": "Deployment", "name": "web"}]},
+  "spec": {"template": {"spec": {"containers": [{"name": "app", "image": %q}]}}}
+}`, name, ago(age), revision, image)
+}
+
+func newTestExecutor() *fakeExecutor {
+	return &fakeExecutor{responses: map[string]string{
+		"get replicasets": `{"items":[` + testReplicaSet("web-1", "1", "shop/web:1.0", 72*time.Hour) + `,` +
+			testReplicaSet("web-2", "2", "shop/web:1.1", 2*time.Hour) + `]}`,
+		"get controllerrevisions": `{"items":[{
+  "metadata": {"name": "agent-1", "namespace": "shop", "creationTimestamp": "` + ago(40*time.Minute) + `",
+    "ownerReferences": [{"kind": "DaemonSet", "name": "agent"}]},
+  "revision": 1,
+  "data": {"spec": {"template": {"spec": {"containers": [{"name": "agent", "image": "agent:2"}]}}}}
+}]}`,
+		"get secrets": "shop   web-chart   3   deployed   " + ago(3*time.Hour) + "\nshop   web-chart   2   superseded   " + ago(50*time.Hour) + "\n",
+		"get events": `{"items":[{"involvedObject": {"kind": "Node", "name": "aks-pool-old"}, "reason": "RemovingNode",
+  "lastTimestamp": "` + ago(30*time.Minute) + `", "source": {"component": "node-controller"}}]}`,
+		"get nodes": `{"items":[{"metadata": {"name": "aks-pool-new", "creationTimestamp": "` + ago(20*time.Minute) + `",
+  "labels": {"kubernetes.azure.com/agentpool": "pool", "node.kubernetes.io/instance-type": "Standard_D4s_v5"}}}]}`,
+	}}
+}
+
+const testActivityLog = `{"value":[{
+  "caller": "ops@contoso.com",
+  "correlationId": "c1",
+  "eventTimestamp": "%s",
+  "resourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks/agentPools/pool",
+  "operationName": {"value": "Microsoft.ContainerService/managedClusters/agentPools/write"},
+  "status": {"value": "Succeeded"},
+  "properties": {"requestbody": "{\"properties\":{\"count\":5}}"}
+}]}`
+
+func testParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+}
+
+func runRecentChanges(t *testing.T, params map[string]interface{}, executor *fakeExecutor, api *fakeARM) ChangesReport {
+	t.Helper()
+	output, err := HandleRecentChanges(params, api, executor, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report ChangesReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestRecentChanges tests that every source contributes changes, newest first
+func TestRecentChanges(t *testing.T) {
+	api := &fakeARM{body: fmt.Sprintf(testActivityLog, ago(4*time.Hour))}
+	report := runRecentChanges(t, testParams(), newTestExecutor(), api)
+
+	want := map[string]int{CategoryRollout: 2, CategoryNode: 2, CategoryHelm: 1, CategoryARM: 1, "imageChanges": 1}
+	for category, count := range want {
+		if report.Counts[category] != count {
+			t.Errorf("Expected %d %s changes, got counts %v", count, category, report.Counts)
+		}
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("Unexpected warnings %v", report.Warnings)
+	}
+	for i := 1; i < len(report.Changes); i++ {
+		if report.Changes[i].Time.After(report.Changes[i-1].Time) {
+			t.Fatalf("Expected changes newest first, got %+v", report.Changes)
+		}
+	}
+
+	byResource := map[string]Change{}
+	for _, change := range report.Changes {
+		byResource[change.Resource] = change
+	}
+	rollout := byResource["Deployment/web"]
+	if len(rollout.Images) != 1 || rollout.Images[0].From != "shop/web:1.0" || rollout.Images[0].To != "shop/web:1.1" {
+		t.Errorf("Expected the image change of the web rollout, got %+v", rollout)
+	}
+	if !strings.Contains(byResource["DaemonSet/agent"].Summary, "created") {
+		t.Errorf("Expected the first DaemonSet revision to be reported as created, got %+v", byResource["DaemonSet/agent"])
+	}
+	if !strings.Contains(byResource["HelmRelease/web-chart"].Summary, "upgraded to revision 3") {
+		t.Errorf("Unexpected Helm change %+v", byResource["HelmRelease/web-chart"])
+	}
+	if !strings.Contains(byResource["Node/aks-pool-new"].Summary, "node pool pool (Standard_D4s_v5)") ||
+		!strings.Contains(byResource["Node/aks-pool-old"].Summary, "removed") {
+		t.Errorf("Unexpected node changes %+v", report.Changes)
+	}
+	arm := byResource["agentPools/pool"]
+	if arm.Actor != "ops@contoso.com" || !strings.Contains(arm.Summary, "properties.count: ? -> 5") {
+		t.Errorf("Unexpected ARM change %+v", arm)
+	}
+}
+
+// TestRecentChangesVerbosity tests the summary and raw verbosity profiles
+func TestRecentChangesVerbosity(t *testing.T) {
+	api := &fakeARM{body: fmt.Sprintf(testActivityLog, ago(4*time.Hour))}
+	output, err := HandleRecentChanges(testParams(), api, newTestExecutor(), config.NewConfig().ForVerbosity(config.VerbositySummary))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report ChangesReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Changes) != summaryChanges || report.Truncated != 1 {
+		t.Errorf("Expected the %d newest changes, got %d with %d truncated", summaryChanges, len(report.Changes), report.Truncated)
+	}
+	if !strings.HasPrefix(report.Headline, "6 changes to cluster aks in the last 24 hours (2 rollout, 2 node, 1 helm, 1 arm)") {
+		t.Errorf("Unexpected headline %q", report.Headline)
+	}
+
+	params := testParams()
+	params["limit"] = 2.0
+	output, err = HandleRecentChanges(params, api, newTestExecutor(), config.NewConfig().ForVerbosity(config.VerbosityRaw))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report = ChangesReport{}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Changes) != 2 || report.Headline != "" {
+		t.Errorf("Expected an explicit limit to apply with raw verbosity, got %d changes", len(report.Changes))
+	}
+}
+
+// TestRecentChangesWindowAndWarnings tests the hours window and that failing sources become warnings
+func TestRecentChangesWindowAndWarnings(t *testing.T) {
+	params := testParams()
+	params["hours"] = float64(1)
+	params["limit"] = float64(2)
+	executor := newTestExecutor()
+	executor.responses["get secrets"] = "forbidden"
+	report := runRecentChanges(t, params, executor, &fakeARM{err: fmt.Errorf("status 403")})
+
+	if report.Counts[CategoryHelm] != 0 || report.Counts[CategoryRollout] != 1 || report.Counts[CategoryNode] != 2 {
+		t.Errorf("Expected only changes of the last hour, got counts %v", report.Counts)
+	}
+	if len(report.Changes) != 2 || report.Truncated != 1 {
+		t.Errorf("Expected the newest 2 of 3 changes, got %d (truncated %d)", len(report.Changes), report.Truncated)
+	}
+	warnings := strings.Join(report.Warnings, "; ")
+	if !strings.Contains(warnings, "Helm releases") || !strings.Contains(warnings, "Activity Log") {
+		t.Errorf("Expected warnings for the Helm and Activity Log sources, got %q", warnings)
+	}
+}
+
+// TestRecentChangesNamespace tests namespace validation and that node events ignore the namespace filter
+func TestRecentChangesNamespace(t *testing.T) {
+	params := testParams()
+	params["namespace"] = "shop; rm"
+	if _, err := HandleRecentChanges(params, &fakeARM{body: `{"value":[]}`}, &fakeExecutor{}, config.NewConfig()); err == nil {
+		t.Error("Expected an invalid namespace to be rejected")
+	}
+	params["hours"] = float64(1000)
+	params["namespace"] = "shop"
+	if _, err := HandleRecentChanges(params, &fakeARM{body: `{"value":[]}`}, &fakeExecutor{}, config.NewConfig()); err == nil {
+		t.Error("Expected hours above the maximum to be rejected")
+	}
+
+	delete(params, "hours")
+	executor := newTestExecutor()
+	runRecentChanges(t, params, executor, &fakeARM{body: `{"value":[]}`})
+	for _, cmd := range executor.commands {
+		if strings.HasPrefix(cmd, "get events") && !strings.Contains(cmd, "--all-namespaces") {
+			t.Errorf("Expected node events from all namespaces, got %q", cmd)
+		}
+		if strings.HasPrefix(cmd, "get replicasets") && !strings.Contains(cmd, "--namespace shop") {
+			t.Errorf("Expected workloads from the shop namespace, got %q", cmd)
+		}
+	}
+}
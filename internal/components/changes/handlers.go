@@ -0,0 +1,574 @@
+// Package changes summarizes what changed in and around an AKS cluster recently: workload rollouts and
+// image changes, node additions and removals, Helm release upgrades and ARM configuration writes.
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/monitor"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// defaultHours and maxHours bound the window of the summary
+	defaultHours = 24
+	maxHours     = 168
+	// defaultReportedChanges bounds the changes returned when limit is not given
+	defaultReportedChanges = 100
+	// maxDiffs bounds the setting changes described per ARM operation
+	maxDiffs = 5
+	// summaryChanges bounds the changes returned with summary verbosity
+	summaryChanges = 5
+)
+
+// Change categories
+const (
+	CategoryRollout = "rollout"
+	CategoryNode    = "node"
+	CategoryHelm    = "helm"
+	CategoryARM     = "arm"
+)
+
+// helmSecretColumns reads only the metadata of Helm release secrets, never the release payload
+const helmSecretColumns = `custom-columns=NAMESPACE:.metadata.namespace,RELEASE:.metadata.labels.name,REVISION:.metadata.labels.version,STATUS:.metadata.labels.status,CREATED:.metadata.creationTimestamp`
+
+// nodeRemovalReasons are the node event reasons recorded when a node leaves the cluster
+var nodeRemovalReasons = map[string]string{
+	"RemovingNode": "removed from the cluster",
+	"DeletingNode": "deleted because its VM no longer exists",
+	"ScaleDown":    "scaled down by the cluster autoscaler",
+}
+
+// ImageChange is a container whose image changed in a rollout
+type ImageChange struct {
+	Container string `json:"container"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// Change is one change affecting the cluster
+type Change struct {
+	Time      time.Time     `json:"time"`
+	Category  string        `json:"category"`
+	Namespace string        `json:"namespace,omitempty"`
+	Resource  string        `json:"resource"`
+	Summary   string        `json:"summary"`
+	Actor     string        `json:"actor,omitempty"`
+	Images    []ImageChange `json:"imageChanges,omitempty"`
+}
+
+// ChangesReport is the result returned by the aks_recent_changes tool
+type ChangesReport struct {
+	// Headline describes the changes in one sentence (summary verbosity only)
+	Headline    string         `json:"headline,omitempty"`
+	ClusterName string         `json:"clusterName"`
+	Since       time.Time      `json:"since"`
+	Counts      map[string]int `json:"counts"`
+	Changes     []Change       `json:"changes"`
+	Truncated   int            `json:"truncated,omitempty"`
+	Warnings    []string       `json:"warnings,omitempty"`
+	Note        string         `json:"note"`
+}
+
+type metadata struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	Labels            map[string]string `json:"labels"`
+	Annotations       map[string]string `json:"annotations"`
+	OwnerReferences   []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"ownerReferences"`
+}
+
+type container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type podTemplate struct {
+	Spec struct {
+		InitContainers []container `json:"initContainers"`
+		Containers     []container `json:"containers"`
+	} `json:"spec"`
+}
+
+// ReplicaSet is the subset of an apps/v1 ReplicaSet used to find Deployment rollouts
+type ReplicaSet struct {
+	Metadata metadata `json:"metadata"`
+	Spec     struct {
+		Template podTemplate `json:"template"`
+	} `json:"spec"`
+}
+
+// ControllerRevision is the subset of an apps/v1 ControllerRevision used to find StatefulSet and DaemonSet rollouts
+type ControllerRevision struct {
+	Metadata metadata `json:"metadata"`
+	Revision int64    `json:"revision"`
+	Data     struct {
+		Spec struct {
+			Template podTemplate `json:"template"`
+		} `json:"spec"`
+	} `json:"data"`
+}
+
+// Node is the subset of a node used to find nodes that joined the cluster
+type Node struct {
+	Metadata metadata `json:"metadata"`
+}
+
+// Event is the subset of a Kubernetes event used to find nodes that left the cluster
+type Event struct {
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"involvedObject"`
+	Reason        string    `json:"reason"`
+	Message       string    `json:"message"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+	EventTime     time.Time `json:"eventTime"`
+	Source        struct {
+		Component string `json:"component"`
+	} `json:"source"`
+}
+
+// HelmRevision is one revision of a Helm release, read from the metadata of its release secret
+type HelmRevision struct {
+	Namespace string
+	Release   string
+	Revision  int
+	Status    string
+	Created   time.Time
+}
+
+// Inventory is everything the summary reads from the cluster and the Activity Log
+type Inventory struct {
+	ReplicaSets         []ReplicaSet
+	ControllerRevisions []ControllerRevision
+	Nodes               []Node
+	NodeEvents          []Event
+	HelmRevisions       []HelmRevision
+	ARMChanges          []monitor.ConfigChange
+}
+
+// GetRecentChangesHandler returns a handler for the aks_recent_changes command
+func GetRecentChangesHandler(api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleRecentChanges(params, api, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleRecentChanges collects the changes of the last hours from the cluster and the Activity Log and returns
+// them newest first. Each source is best effort, so one denied or failing query does not hide the others.
+// The summary verbosity returns a headline with the newest changes and raw verbosity drops the default limit.
+func HandleRecentChanges(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	hours := defaultHours
+	if raw, ok := params["hours"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n > maxHours {
+			return "", fmt.Errorf("invalid hours: expected a number between 1 and %d", maxHours)
+		}
+		hours = int(n)
+	}
+	limit := defaultReportedChanges
+	if cfg.Verbosity == config.VerbosityRaw {
+		limit = 0
+	}
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = int(n)
+	}
+	// Node events are recorded in the default namespace, so they are listed from all allowed namespaces
+	// even when the workload changes are limited to one namespace
+	allowedFlags := common.NamespaceFlags(cfg.AllowNamespaces)
+	flags := allowedFlags
+	if namespace, _ := params["namespace"].(string); namespace != "" {
+		if !common.NamespacePattern.MatchString(namespace) {
+			return "", fmt.Errorf("invalid namespace parameter: %s", namespace)
+		}
+		if !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(namespace) {
+			return "", fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+		}
+		flags = []string{"--namespace " + namespace}
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-time.Duration(hours) * time.Hour)
+	var inv Inventory
+	var warnings []string
+
+	runKubectl := func(description, command string, decode func(output string) error) {
+		output, err := kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+		if err == nil {
+			err = decode(output)
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to list %s: %v", description, err))
+		}
+	}
+	for _, flag := range flags {
+		runKubectl("replica sets", "get replicasets "+flag+" -o json", func(output string) error {
+			return common.DecodeList(output, &inv.ReplicaSets)
+		})
+		runKubectl("controller revisions", "get controllerrevisions "+flag+" -o json", func(output string) error {
+			return common.DecodeList(output, &inv.ControllerRevisions)
+		})
+		runKubectl("Helm releases", "get secrets "+flag+" -l owner=helm --no-headers -o '"+helmSecretColumns+"'", func(output string) error {
+			revisions, err := ParseHelmRevisions(output)
+			inv.HelmRevisions = append(inv.HelmRevisions, revisions...)
+			return err
+		})
+	}
+	for _, flag := range allowedFlags {
+		runKubectl("node events", "get events "+flag+" --field-selector involvedObject.kind=Node -o json", func(output string) error {
+			return common.DecodeList(output, &inv.NodeEvents)
+		})
+	}
+	runKubectl("nodes", "get nodes -o json", func(output string) error {
+		return common.DecodeList(output, &inv.Nodes)
+	})
+
+	armChanges, err := monitor.ListConfigChanges(api, subID, rg, clusterName, since, now)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	inv.ARMChanges = armChanges
+
+	report := SummarizeChanges(inv, since)
+	report.ClusterName = clusterName
+	report.Warnings = warnings
+	if cfg.Verbosity == config.VerbositySummary {
+		report.Headline = changesHeadline(report, hours)
+		limit = min(limit, summaryChanges)
+	}
+	if limit > 0 && len(report.Changes) > limit {
+		report.Truncated = len(report.Changes) - limit
+		report.Changes = report.Changes[:limit]
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change summary to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// changesHeadline describes the number and kinds of changes in a report in one sentence
+func changesHeadline(report ChangesReport, hours int) string {
+	if len(report.Changes) == 0 {
+		return fmt.Sprintf("No changes to cluster %s in the last %d hours.", report.ClusterName, hours)
+	}
+	var kinds []string
+	for _, category := range []string{CategoryRollout, CategoryNode, CategoryHelm, CategoryARM} {
+		if n := report.Counts[category]; n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, category))
+		}
+	}
+	newest := report.Changes[0]
+	return fmt.Sprintf("%d changes to cluster %s in the last %d hours (%s). The newest, at %s, was %s %s: %s.",
+		len(report.Changes), report.ClusterName, hours, strings.Join(kinds, ", "),
+		newest.Time.Format(time.RFC3339), newest.Category, newest.Resource, newest.Summary)
+}
+
+// ParseHelmRevisions reads Helm release revisions from kubectl get secrets custom-columns output
+func ParseHelmRevisions(output string) ([]HelmRevision, error) {
+	var revisions []HelmRevision
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 5 {
+			return revisions, fmt.Errorf("unexpected Helm release secret line %q", line)
+		}
+		revision, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, HelmRevision{
+			Namespace: fields[0],
+			Release:   fields[1],
+			Revision:  revision,
+			Status:    fields[3],
+			Created:   created,
+		})
+	}
+	return revisions, nil
+}
+
+// SummarizeChanges returns the changes of an inventory made since the given time, newest first
+func SummarizeChanges(inv Inventory, since time.Time) ChangesReport {
+	report := ChangesReport{
+		Since:   since,
+		Counts:  map[string]int{CategoryRollout: 0, CategoryNode: 0, CategoryHelm: 0, CategoryARM: 0},
+		Changes: []Change{},
+		Note: "Rollouts are found from ReplicaSets and ControllerRevisions created in the window, so a rollback to a " +
+			"retained revision is not listed. Node removals come from node events, which are kept for about an hour; " +
+			"node pool scaling is also listed under arm.",
+	}
+
+	report.Changes = append(report.Changes, deploymentRollouts(inv.ReplicaSets, since)...)
+	report.Changes = append(report.Changes, controllerRollouts(inv.ControllerRevisions, since)...)
+	report.Changes = append(report.Changes, nodeChanges(inv.Nodes, inv.NodeEvents, since)...)
+	report.Changes = append(report.Changes, helmChanges(inv.HelmRevisions, since)...)
+	report.Changes = append(report.Changes, armChanges(inv.ARMChanges, since)...)
+
+	for _, change := range report.Changes {
+		report.Counts[change.Category]++
+		if len(change.Images) > 0 {
+			report.Counts["imageChanges"]++
+		}
+	}
+	sort.SliceStable(report.Changes, func(i, j int) bool { return report.Changes[i].Time.After(report.Changes[j].Time) })
+	return report
+}
+
+// workloadRevision is one revision of a workload's pod template
+type workloadRevision struct {
+	namespace string
+	kind      string
+	name      string
+	revision  int64
+	created   time.Time
+	object    string
+	template  podTemplate
+}
+
+// deploymentRollouts returns the rollouts of Deployments whose ReplicaSet was created in the window
+func deploymentRollouts(replicaSets []ReplicaSet, since time.Time) []Change {
+	var revisions []workloadRevision
+	for _, rs := range replicaSets {
+		owner := ownerName(rs.Metadata, "Deployment")
+		if owner == "" {
+			continue
+		}
+		revision, _ := strconv.ParseInt(rs.Metadata.Annotations["deployment.kubernetes.io/revision"], 10, 64)
+		revisions = append(revisions, workloadRevision{
+			namespace: rs.Metadata.Namespace,
+			kind:      "Deployment",
+			name:      owner,
+			revision:  revision,
+			created:   rs.Metadata.CreationTimestamp,
+			object:    "ReplicaSet " + rs.Metadata.Name,
+			template:  rs.Spec.Template,
+		})
+	}
+	return rollouts(revisions, since)
+}
+
+// controllerRollouts returns the rollouts of StatefulSets and DaemonSets whose revision was created in the window
+func controllerRollouts(controllerRevisions []ControllerRevision, since time.Time) []Change {
+	var revisions []workloadRevision
+	for _, cr := range controllerRevisions {
+		for _, kind := range []string{"StatefulSet", "DaemonSet"} {
+			if owner := ownerName(cr.Metadata, kind); owner != "" {
+				revisions = append(revisions, workloadRevision{
+					namespace: cr.Metadata.Namespace,
+					kind:      kind,
+					name:      owner,
+					revision:  cr.Revision,
+					created:   cr.Metadata.CreationTimestamp,
+					object:    "ControllerRevision " + cr.Metadata.Name,
+					template:  cr.Data.Spec.Template,
+				})
+			}
+		}
+	}
+	return rollouts(revisions, since)
+}
+
+// rollouts compares each revision created in the window with the workload's previous revision
+func rollouts(revisions []workloadRevision, since time.Time) []Change {
+	sort.SliceStable(revisions, func(i, j int) bool {
+		a, b := revisions[i], revisions[j]
+		if a.namespace+"/"+a.kind+"/"+a.name != b.namespace+"/"+b.kind+"/"+b.name {
+			return a.namespace+"/"+a.kind+"/"+a.name < b.namespace+"/"+b.kind+"/"+b.name
+		}
+		return a.revision < b.revision
+	})
+
+	var changes []Change
+	for i, rev := range revisions {
+		if rev.created.Before(since) {
+			continue
+		}
+		change := Change{
+			Time:      rev.created,
+			Category:  CategoryRollout,
+			Namespace: rev.namespace,
+			Resource:  rev.kind + "/" + rev.name,
+		}
+		if i > 0 && revisions[i-1].namespace == rev.namespace && revisions[i-1].kind == rev.kind && revisions[i-1].name == rev.name {
+			change.Images = imageChanges(revisions[i-1].template, rev.template)
+			change.Summary = fmt.Sprintf("rolled out revision %d (%s)", rev.revision, rev.object)
+			if len(change.Images) > 0 {
+				var images []string
+				for _, image := range change.Images {
+					images = append(images, fmt.Sprintf("%s %s -> %s", image.Container, image.From, image.To))
+				}
+				change.Summary += "; image " + strings.Join(images, ", ")
+			}
+		} else if rev.revision <= 1 {
+			change.Summary = fmt.Sprintf("created (%s)", rev.object)
+		} else {
+			change.Summary = fmt.Sprintf("rolled out revision %d (%s); the previous revision is no longer retained", rev.revision, rev.object)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// imageChanges returns the containers whose image differs between two pod templates
+func imageChanges(before, after podTemplate) []ImageChange {
+	previous := map[string]string{}
+	for _, containers := range [][]container{before.Spec.InitContainers, before.Spec.Containers} {
+		for _, c := range containers {
+			previous[c.Name] = c.Image
+		}
+	}
+	var changes []ImageChange
+	for _, containers := range [][]container{after.Spec.InitContainers, after.Spec.Containers} {
+		for _, c := range containers {
+			if image, ok := previous[c.Name]; ok && image != c.Image {
+				changes = append(changes, ImageChange{Container: c.Name, From: image, To: c.Image})
+			}
+		}
+	}
+	return changes
+}
+
+func ownerName(meta metadata, kind string) string {
+	for _, owner := range meta.OwnerReferences {
+		if owner.Kind == kind {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// nodeChanges returns the nodes that joined in the window and the removals recorded in node events
+func nodeChanges(nodes []Node, events []Event, since time.Time) []Change {
+	var changes []Change
+	for _, node := range nodes {
+		if node.Metadata.CreationTimestamp.Before(since) {
+			continue
+		}
+		summary := "joined the cluster"
+		if pool := node.Metadata.Labels["kubernetes.azure.com/agentpool"]; pool != "" {
+			summary += " in node pool " + pool
+		}
+		if size := node.Metadata.Labels["node.kubernetes.io/instance-type"]; size != "" {
+			summary += " (" + size + ")"
+		}
+		changes = append(changes, Change{
+			Time:     node.Metadata.CreationTimestamp,
+			Category: CategoryNode,
+			Resource: "Node/" + node.Metadata.Name,
+			Summary:  summary,
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, event := range events {
+		summary := nodeRemovalReasons[event.Reason]
+		if summary == "" || seen[event.InvolvedObject.Name+"/"+event.Reason] {
+			continue
+		}
+		at := event.LastTimestamp
+		if at.IsZero() {
+			at = event.EventTime
+		}
+		if at.Before(since) {
+			continue
+		}
+		seen[event.InvolvedObject.Name+"/"+event.Reason] = true
+		if event.Source.Component != "" {
+			summary += " (" + event.Source.Component + ")"
+		}
+		changes = append(changes, Change{
+			Time:     at,
+			Category: CategoryNode,
+			Resource: "Node/" + event.InvolvedObject.Name,
+			Summary:  summary,
+		})
+	}
+	return changes
+}
+
+// helmChanges returns the Helm release installs and upgrades made in the window
+func helmChanges(revisions []HelmRevision, since time.Time) []Change {
+	var changes []Change
+	for _, rev := range revisions {
+		if rev.Created.Before(since) {
+			continue
+		}
+		action := fmt.Sprintf("upgraded to revision %d", rev.Revision)
+		if rev.Revision == 1 {
+			action = "installed"
+		}
+		changes = append(changes, Change{
+			Time:      rev.Created,
+			Category:  CategoryHelm,
+			Namespace: rev.Namespace,
+			Resource:  "HelmRelease/" + rev.Release,
+			Summary:   fmt.Sprintf("%s (status %s)", action, rev.Status),
+		})
+	}
+	return changes
+}
+
+// armChanges returns the cluster, node pool and diagnostic setting writes from the Activity Log
+func armChanges(configChanges []monitor.ConfigChange, since time.Time) []Change {
+	var changes []Change
+	for _, cc := range configChanges {
+		at, err := time.Parse(time.RFC3339Nano, cc.Timestamp)
+		if err != nil || at.Before(since) {
+			continue
+		}
+		summary := fmt.Sprintf("%s (%s)", cc.Operation, cc.Status)
+		var diffs []string
+		for i, diff := range cc.Diffs {
+			if i == maxDiffs {
+				diffs = append(diffs, fmt.Sprintf("%d more", len(cc.Diffs)-maxDiffs))
+				break
+			}
+			before := diff.Before
+			if before == "" {
+				before = "?"
+			}
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", diff.Path, before, diff.After))
+		}
+		if len(diffs) > 0 {
+			summary += "; " + strings.Join(diffs, ", ")
+		}
+		changes = append(changes, Change{
+			Time:     at,
+			Category: CategoryARM,
+			Resource: cc.Resource,
+			Summary:  summary,
+			Actor:    cc.Caller,
+		})
+	}
+	return changes
+}
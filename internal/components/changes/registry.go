@@ -0,0 +1,51 @@
+package changes
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterRecentChangesTool registers the aks_recent_changes tool
+func RegisterRecentChangesTool() mcp.Tool {
+	description := fmt.Sprintf(`Summarize what changed in and around an AKS cluster in the last N hours, newest first. Use it as the first step of an incident.
+
+Changes reported:
+- rollout: Deployment, StatefulSet and DaemonSet rollouts in the allowed namespaces, from ReplicaSets and
+  ControllerRevisions created in the window, with container image changes against the previous revision
+- node: nodes that joined the cluster, and nodes removed or scaled down according to node events
+- helm: Helm release installs and upgrades, read from the metadata of Helm release secrets (never their contents)
+- arm: cluster, node pool and diagnostic setting writes from the Activity Log, with who made them and the
+  settings that changed
+
+Each source is best effort; sources that cannot be read are listed in warnings. Uses the current kubeconfig
+context for the cluster. Returns at most limit changes (default %d) from the last hours (default %d, at most %d).
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", hours=6`, defaultReportedChanges, defaultHours, maxHours)
+
+	return mcp.NewTool(
+		"aks_recent_changes",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description(fmt.Sprintf("How many hours back to look (default: %d, at most %d)", defaultHours, maxHours)),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Only report workload and Helm changes in this namespace (default: all allowed namespaces)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of changes to return (default: %d)", defaultReportedChanges)),
+		),
+	)
+}
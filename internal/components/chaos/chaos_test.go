@@ -0,0 +1,233 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const (
+	testClusterID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	testNodeRG    = "MC_rg_aks_eastus"
+)
+
+// fakeARM returns canned responses keyed by "METHOD path-prefix" and records calls
+type fakeARM struct {
+	responses map[string]string
+	calls     []string
+}
+
+func (f *fakeARM) CallARM(_ context.Context, method, path string) ([]byte, error) {
+	f.calls = append(f.calls, method+" "+path)
+	best := ""
+	for key := range f.responses {
+		if strings.HasPrefix(method+" "+path, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return nil, fmt.Errorf("API error (404): not found")
+	}
+	return []byte(f.responses[best]), nil
+}
+
+func experimentJSON(name, targetID, fault string) string {
+	return fmt.Sprintf(`{
+		"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/%s",
+		"name": "%s",
+		"location": "eastus",
+		"properties": {
+			"provisioningState": "Succeeded",
+			"selectors": [{"id": "s1", "targets": [{"id": "%s/providers/Microsoft.Chaos/targets/Microsoft-Target"}]}],
+			"steps": [{"branches": [{"actions": [{"name": "%s", "duration": "PT10M"}]}]}]
+		}
+	}`, name, name, targetID, fault)
+}
+
+func newFakeARM() *fakeARM {
+	podExp := experimentJSON("pod-failure", testClusterID, "urn:csci:microsoft:azureKubernetesServiceChaosMesh:podChaos/2.1")
+	nodeExp := experimentJSON("node-shutdown",
+		"/subscriptions/sub/resourceGroups/"+testNodeRG+"/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-vmss",
+		"urn:csci:microsoft:virtualMachineScaleSet:shutdown/2.0")
+	otherExp := experimentJSON("vm-other", "/subscriptions/sub/resourceGroups/other/providers/Microsoft.Compute/virtualMachines/vm1",
+		"urn:csci:microsoft:virtualMachine:shutdown/1.0")
+
+	return &fakeARM{responses: map[string]string{
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks": fmt.Sprintf(
+			`{"id": "%s", "properties": {"nodeResourceGroup": "%s"}}`, testClusterID, testNodeRG),
+		"GET /subscriptions/sub/providers/Microsoft.Chaos/experiments?":                                fmt.Sprintf(`{"value": [%s, %s, %s]}`, podExp, nodeExp, otherExp),
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/pod-failure?":  podExp,
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/vm-other?":     otherExp,
+		"POST /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/pod-failure/": `{}`,
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/pod-failure/exe": `{"value": [
+			{"name": "old", "properties": {"status": "Success", "startedAt": "2024-01-01T00:00:00Z"}},
+			{"name": "new", "properties": {"status": "Failed", "startedAt": "2024-02-01T00:00:00Z"}}
+		]}`,
+		"POST /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Chaos/experiments/pod-failure/executions/new/getExecutionDetails": `{
+			"name": "new",
+			"properties": {
+				"status": "Failed",
+				"failureReason": "target failed",
+				"runInformation": {"steps": [{"stepName": "Step 1", "branches": [{"actions": [{
+					"actionName": "urn:csci:microsoft:azureKubernetesServiceChaosMesh:podChaos/2.1",
+					"targets": [
+						{"target": "` + testClusterID + `/providers/Microsoft.Chaos/targets/Microsoft-AzureKubernetesServiceChaosMesh", "status": "Failed", "error": {"message": "chaos mesh not installed"}}
+					]
+				}]}]}]}
+			}
+		}`,
+	}}
+}
+
+func baseParams(operation string) map[string]interface{} {
+	return map[string]interface{}{
+		"operation":       operation,
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+	}
+}
+
+func adminConfig() *config.ConfigData {
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "admin"
+	return cfg
+}
+
+func TestRegisterChaosExperimentsTool(t *testing.T) {
+	tool := RegisterChaosExperimentsTool()
+	if tool.Name != "az_chaos_experiments" {
+		t.Errorf("Expected tool name az_chaos_experiments, got %s", tool.Name)
+	}
+	for _, param := range []string{"operation", "subscription_id", "resource_group", "cluster_name"} {
+		found := false
+		for _, required := range tool.InputSchema.Required {
+			if required == param {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s to be required", param)
+		}
+	}
+}
+
+func TestTargetsCluster(t *testing.T) {
+	exp := Experiment{Targets: []string{"/subscriptions/sub/resourcegroups/mc_rg_aks_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/vmss"}}
+	if !TargetsCluster(exp, testClusterID, testNodeRG) {
+		t.Error("Expected node resource group target to match case-insensitively")
+	}
+	exp = Experiment{Targets: []string{strings.ToUpper(testClusterID)}}
+	if !TargetsCluster(exp, testClusterID, testNodeRG) {
+		t.Error("Expected cluster target to match")
+	}
+	exp = Experiment{Targets: []string{testClusterID + "/providers/Microsoft.Chaos/targets/Microsoft-AzureKubernetesServiceChaosMesh"}}
+	if !TargetsCluster(exp, testClusterID, testNodeRG) {
+		t.Error("Expected cluster child target to match")
+	}
+	exp = Experiment{Targets: []string{"/subscriptions/sub/resourceGroups/other/providers/Microsoft.Compute/virtualMachines/vm1"}}
+	if TargetsCluster(exp, testClusterID, testNodeRG) {
+		t.Error("Expected unrelated target not to match")
+	}
+	for _, sibling := range []string{testClusterID + "10", testClusterID + "-prod/providers/Microsoft.Chaos/targets/Microsoft-Target"} {
+		if TargetsCluster(Experiment{Targets: []string{sibling}}, testClusterID, testNodeRG) {
+			t.Errorf("Expected sibling cluster target %s not to match", sibling)
+		}
+	}
+}
+
+func TestHandleChaosExperiments_List(t *testing.T) {
+	api := newFakeARM()
+	result, err := HandleChaosExperiments(baseParams(OpList), api, adminConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"pod-failure"`) || !strings.Contains(result, `"node-shutdown"`) {
+		t.Errorf("Expected cluster experiments in result: %s", result)
+	}
+	if strings.Contains(result, `"vm-other"`) {
+		t.Errorf("Expected unrelated experiment to be filtered out: %s", result)
+	}
+	if !strings.Contains(result, `"kind": "pod"`) || !strings.Contains(result, `"kind": "node"`) {
+		t.Errorf("Expected pod and node fault kinds: %s", result)
+	}
+}
+
+func TestHandleChaosExperiments_Start(t *testing.T) {
+	api := newFakeARM()
+	params := baseParams(OpStart)
+	params["experiment_name"] = "pod-failure"
+	if _, err := HandleChaosExperiments(params, api, adminConfig()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := api.calls[len(api.calls)-1]
+	if !strings.HasPrefix(last, "POST ") || !strings.Contains(last, "/experiments/pod-failure/start?") {
+		t.Errorf("Expected start call, got %s", last)
+	}
+
+	// Experiments that do not target the cluster cannot be started
+	params["experiment_name"] = "vm-other"
+	if _, err := HandleChaosExperiments(params, newFakeARM(), adminConfig()); err == nil {
+		t.Error("Expected error starting an experiment that does not target the cluster")
+	}
+
+	// Start requires admin access
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readwrite"
+	params["experiment_name"] = "pod-failure"
+	if _, err := HandleChaosExperiments(params, newFakeARM(), cfg); err == nil {
+		t.Error("Expected error starting an experiment without admin access")
+	}
+
+	// Names are validated before building ARM paths
+	params["experiment_name"] = "../other"
+	if _, err := HandleChaosExperiments(params, newFakeARM(), adminConfig()); err == nil {
+		t.Error("Expected error for invalid experiment name")
+	}
+}
+
+func TestHandleChaosExperiments_Stop(t *testing.T) {
+	api := newFakeARM()
+	params := baseParams(OpStop)
+	params["experiment_name"] = "pod-failure"
+	if _, err := HandleChaosExperiments(params, api, adminConfig()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := api.calls[len(api.calls)-1]
+	if !strings.HasPrefix(last, "POST ") || !strings.Contains(last, "/experiments/pod-failure/cancel?") {
+		t.Errorf("Expected cancel call, got %s", last)
+	}
+
+	// Experiments that do not target the cluster cannot be stopped
+	api = newFakeARM()
+	params["experiment_name"] = "vm-other"
+	if _, err := HandleChaosExperiments(params, api, adminConfig()); err == nil {
+		t.Error("Expected error stopping an experiment that does not target the cluster")
+	}
+	for _, call := range api.calls {
+		if strings.Contains(call, "/cancel?") {
+			t.Errorf("Expected no cancel call for an out-of-scope experiment, got %s", call)
+		}
+	}
+}
+
+func TestHandleChaosExperiments_Results(t *testing.T) {
+	params := baseParams(OpResults)
+	params["experiment_name"] = "pod-failure"
+	result, err := HandleChaosExperiments(params, newFakeARM(), adminConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"executionId": "new"`) {
+		t.Errorf("Expected latest execution to be summarized: %s", result)
+	}
+	if !strings.Contains(result, "chaos mesh not installed") || !strings.Contains(result, `"Failed": 1`) {
+		t.Errorf("Expected failed target result: %s", result)
+	}
+	if !strings.Contains(result, `"target": "`+testClusterID+`"`) {
+		t.Errorf("Expected target ID without Chaos target extension: %s", result)
+	}
+}
@@ -0,0 +1,300 @@
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// chaosAPIVersion is the Microsoft.Chaos API version used for experiments and executions
+const chaosAPIVersion = "2024-01-01"
+
+// faultURNPrefix is the common prefix of Chaos Studio fault (action) names
+const faultURNPrefix = "urn:csci:microsoft:"
+
+// Fault kinds reported for experiment actions
+const (
+	FaultKindPod   = "pod"
+	FaultKindNode  = "node"
+	FaultKindOther = "other"
+)
+
+// Experiment is a summarized Chaos Studio experiment
+type Experiment struct {
+	Name              string   `json:"name"`
+	ID                string   `json:"id"`
+	Location          string   `json:"location,omitempty"`
+	ProvisioningState string   `json:"provisioningState,omitempty"`
+	Faults            []Fault  `json:"faults"`
+	Targets           []string `json:"targets"`
+}
+
+// Fault is a single fault action of an experiment
+type Fault struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Execution is a single run of an experiment
+type Execution struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	StartedAt string `json:"startedAt,omitempty"`
+	StoppedAt string `json:"stoppedAt,omitempty"`
+}
+
+// ActionResult is the outcome of one fault action against one target
+type ActionResult struct {
+	Step   string `json:"step"`
+	Action string `json:"action"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ExecutionSummary summarizes the results of an experiment execution
+type ExecutionSummary struct {
+	Experiment    string         `json:"experiment"`
+	ExecutionID   string         `json:"executionId"`
+	Status        string         `json:"status"`
+	StartedAt     string         `json:"startedAt,omitempty"`
+	StoppedAt     string         `json:"stoppedAt,omitempty"`
+	FailureReason string         `json:"failureReason,omitempty"`
+	TargetResults map[string]int `json:"targetResultsByStatus"`
+	Actions       []ActionResult `json:"actions"`
+}
+
+// experimentResource is the ARM representation of a Chaos Studio experiment
+type experimentResource struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Properties struct {
+		ProvisioningState string `json:"provisioningState"`
+		Selectors         []struct {
+			ID      string `json:"id"`
+			Targets []struct {
+				ID string `json:"id"`
+			} `json:"targets"`
+		} `json:"selectors"`
+		Steps []struct {
+			Branches []struct {
+				Actions []struct {
+					Name     string `json:"name"`
+					Duration string `json:"duration"`
+				} `json:"actions"`
+			} `json:"branches"`
+		} `json:"steps"`
+	} `json:"properties"`
+}
+
+// experimentListResponse is a page of the experiment list API
+type experimentListResponse struct {
+	Value    []experimentResource `json:"value"`
+	NextLink string               `json:"nextLink"`
+}
+
+// executionResource is the ARM representation of an experiment execution
+type executionResource struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Status        string `json:"status"`
+		StartedAt     string `json:"startedAt"`
+		StoppedAt     string `json:"stoppedAt"`
+		FailureReason string `json:"failureReason"`
+	} `json:"properties"`
+}
+
+// executionListResponse is a page of the execution list API
+type executionListResponse struct {
+	Value []executionResource `json:"value"`
+}
+
+// executionDetailsResponse is the response of the getExecutionDetails action
+type executionDetailsResponse struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Status         string `json:"status"`
+		StartedAt      string `json:"startedAt"`
+		StoppedAt      string `json:"stoppedAt"`
+		FailureReason  string `json:"failureReason"`
+		RunInformation struct {
+			Steps []struct {
+				StepName string `json:"stepName"`
+				Branches []struct {
+					Actions []struct {
+						ActionName string `json:"actionName"`
+						Status     string `json:"status"`
+						Targets    []struct {
+							Target string `json:"target"`
+							Status string `json:"status"`
+							Error  *struct {
+								Message string `json:"message"`
+							} `json:"error"`
+						} `json:"targets"`
+					} `json:"actions"`
+				} `json:"branches"`
+			} `json:"steps"`
+		} `json:"runInformation"`
+	} `json:"properties"`
+}
+
+// ExperimentPath returns the ARM path of an experiment
+func ExperimentPath(subscriptionID, resourceGroup, name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Chaos/experiments/%s",
+		subscriptionID, resourceGroup, name)
+}
+
+// ParseExperiment converts an experiment resource into its summary
+func ParseExperiment(data []byte) (*Experiment, error) {
+	var resource experimentResource
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, fmt.Errorf("failed to parse experiment: %w", err)
+	}
+	exp := summarizeExperiment(resource)
+	return &exp, nil
+}
+
+// ParseExperimentList parses one page of the experiment list API and returns the next page link
+func ParseExperimentList(data []byte) ([]Experiment, string, error) {
+	var page experimentListResponse
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, "", fmt.Errorf("failed to parse experiment list: %w", err)
+	}
+	experiments := make([]Experiment, 0, len(page.Value))
+	for _, resource := range page.Value {
+		experiments = append(experiments, summarizeExperiment(resource))
+	}
+	return experiments, page.NextLink, nil
+}
+
+// ParseExecutions parses the execution list API, newest execution first
+func ParseExecutions(data []byte) ([]Execution, error) {
+	var list executionListResponse
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse experiment executions: %w", err)
+	}
+	executions := make([]Execution, 0, len(list.Value))
+	for _, e := range list.Value {
+		executions = append(executions, Execution{
+			ID:        e.Name,
+			Status:    e.Properties.Status,
+			StartedAt: e.Properties.StartedAt,
+			StoppedAt: e.Properties.StoppedAt,
+		})
+	}
+	// RFC 3339 timestamps sort lexically
+	sort.SliceStable(executions, func(i, j int) bool {
+		return executions[i].StartedAt > executions[j].StartedAt
+	})
+	return executions, nil
+}
+
+// ParseExecutionDetails summarizes per-target action results of an execution
+func ParseExecutionDetails(experimentName string, data []byte) (*ExecutionSummary, error) {
+	var details executionDetailsResponse
+	if err := json.Unmarshal(data, &details); err != nil {
+		return nil, fmt.Errorf("failed to parse execution details: %w", err)
+	}
+
+	summary := &ExecutionSummary{
+		Experiment:    experimentName,
+		ExecutionID:   details.Name,
+		Status:        details.Properties.Status,
+		StartedAt:     details.Properties.StartedAt,
+		StoppedAt:     details.Properties.StoppedAt,
+		FailureReason: details.Properties.FailureReason,
+		TargetResults: make(map[string]int),
+		Actions:       []ActionResult{},
+	}
+	for _, step := range details.Properties.RunInformation.Steps {
+		for _, branch := range step.Branches {
+			for _, action := range branch.Actions {
+				for _, target := range action.Targets {
+					result := ActionResult{
+						Step:   step.StepName,
+						Action: strings.TrimPrefix(action.ActionName, faultURNPrefix),
+						Target: targetResourceID(target.Target),
+						Status: target.Status,
+					}
+					if target.Error != nil {
+						result.Error = target.Error.Message
+					}
+					summary.TargetResults[target.Status]++
+					summary.Actions = append(summary.Actions, result)
+				}
+			}
+		}
+	}
+	return summary, nil
+}
+
+// TargetsCluster reports whether the experiment targets the cluster itself (pod faults)
+// or VM scale sets in the cluster's node resource group (node faults).
+func TargetsCluster(exp Experiment, clusterID, nodeResourceGroup string) bool {
+	clusterID = strings.TrimSuffix(strings.ToLower(clusterID), "/")
+	nodeRGSegment := "/resourcegroups/" + strings.ToLower(nodeResourceGroup) + "/"
+	for _, target := range exp.Targets {
+		target = strings.TrimSuffix(strings.ToLower(target), "/")
+		if clusterID != "" && (target == clusterID || strings.HasPrefix(target, clusterID+"/")) {
+			return true
+		}
+		if nodeResourceGroup != "" && strings.Contains(target, nodeRGSegment) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeExperiment flattens the selectors and steps of an experiment
+func summarizeExperiment(resource experimentResource) Experiment {
+	exp := Experiment{
+		Name:              resource.Name,
+		ID:                resource.ID,
+		Location:          resource.Location,
+		ProvisioningState: resource.Properties.ProvisioningState,
+		Faults:            []Fault{},
+		Targets:           []string{},
+	}
+	for _, selector := range resource.Properties.Selectors {
+		for _, target := range selector.Targets {
+			exp.Targets = append(exp.Targets, targetResourceID(target.ID))
+		}
+	}
+	for _, step := range resource.Properties.Steps {
+		for _, branch := range step.Branches {
+			for _, action := range branch.Actions {
+				exp.Faults = append(exp.Faults, Fault{
+					Name:     strings.TrimPrefix(action.Name, faultURNPrefix),
+					Kind:     faultKind(action.Name),
+					Duration: action.Duration,
+				})
+			}
+		}
+	}
+	return exp
+}
+
+// targetResourceID strips the Microsoft.Chaos target extension from a target ID,
+// leaving the ID of the resource the fault is applied to
+func targetResourceID(targetID string) string {
+	if idx := strings.Index(strings.ToLower(targetID), "/providers/microsoft.chaos/targets/"); idx > 0 {
+		return targetID[:idx]
+	}
+	return targetID
+}
+
+// faultKind classifies a fault by the target type in its URN
+func faultKind(actionName string) string {
+	name := strings.ToLower(actionName)
+	switch {
+	case strings.Contains(name, "azurekubernetesservicechaosmesh"):
+		return FaultKindPod
+	case strings.Contains(name, "virtualmachinescaleset"), strings.Contains(name, "virtualmachine:"):
+		return FaultKindNode
+	default:
+		return FaultKindOther
+	}
+}
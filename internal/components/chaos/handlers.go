@@ -0,0 +1,247 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// clusterAPIVersion is the Microsoft.ContainerService API version used to read the cluster
+const clusterAPIVersion = "2024-05-01"
+
+// maxListPages bounds nextLink paging when listing experiments
+const maxListPages = 20
+
+// clusterScope identifies the resources an in-scope experiment may target
+type clusterScope struct {
+	ID                string
+	NodeResourceGroup string
+}
+
+// ExperimentListResult is the result of the list operation
+type ExperimentListResult struct {
+	ClusterName       string       `json:"clusterName"`
+	NodeResourceGroup string       `json:"nodeResourceGroup,omitempty"`
+	Experiments       []Experiment `json:"experiments"`
+}
+
+// ExperimentDetails is the result of the show operation
+type ExperimentDetails struct {
+	Experiment       *Experiment `json:"experiment"`
+	TargetsCluster   bool        `json:"targetsCluster"`
+	RecentExecutions []Execution `json:"recentExecutions"`
+}
+
+// ExperimentActionResult is the result of the start and stop operations
+type ExperimentActionResult struct {
+	Experiment string `json:"experiment"`
+	Operation  string `json:"operation"`
+	Accepted   bool   `json:"accepted"`
+	Message    string `json:"message"`
+}
+
+// GetChaosExperimentsHandler returns a handler for the az_chaos_experiments tool
+func GetChaosExperimentsHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleChaosExperiments(params, azClient, cfg)
+	})
+}
+
+// HandleChaosExperiments dispatches a Chaos Studio experiment operation
+func HandleChaosExperiments(params map[string]interface{}, api common.ARMCaller, cfg *config.ConfigData) (string, error) {
+	operation, ok := params["operation"].(string)
+	if !ok || operation == "" {
+		return "", fmt.Errorf("missing or invalid operation parameter")
+	}
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	// Starting and stopping experiments injects faults, so the tool is restricted to admin access
+	if cfg.AccessLevel != "admin" && (operation == OpStart || operation == OpStop) {
+		return "", fmt.Errorf("operation '%s' requires admin access level", operation)
+	}
+
+	experimentName, _ := params["experiment_name"].(string)
+	if operation != OpList && experimentName == "" {
+		return "", fmt.Errorf("missing or invalid experiment_name parameter")
+	}
+	experimentRG, _ := params["experiment_resource_group"].(string)
+	if experimentRG == "" {
+		experimentRG = rg
+	}
+	executionID, _ := params["execution_id"].(string)
+	for label, value := range map[string]string{
+		"experiment_name":           experimentName,
+		"experiment_resource_group": experimentRG,
+		"execution_id":              executionID,
+	} {
+		if strings.ContainsAny(value, "/?#&%") {
+			return "", fmt.Errorf("invalid %s '%s'", label, value)
+		}
+	}
+	experimentPath := ExperimentPath(subID, experimentRG, experimentName)
+
+	ctx := context.Background()
+	var result interface{}
+	switch operation {
+	case OpList:
+		scope, err := getClusterScope(ctx, api, subID, rg, clusterName)
+		if err != nil {
+			return "", err
+		}
+		experiments, err := listClusterExperiments(ctx, api, subID, scope)
+		if err != nil {
+			return "", err
+		}
+		result = ExperimentListResult{ClusterName: clusterName, NodeResourceGroup: scope.NodeResourceGroup, Experiments: experiments}
+	case OpShow:
+		scope, err := getClusterScope(ctx, api, subID, rg, clusterName)
+		if err != nil {
+			return "", err
+		}
+		exp, err := getExperiment(ctx, api, experimentPath)
+		if err != nil {
+			return "", err
+		}
+		executions, err := listExecutions(ctx, api, experimentPath)
+		if err != nil {
+			return "", err
+		}
+		if len(executions) > 5 {
+			executions = executions[:5]
+		}
+		result = ExperimentDetails{Experiment: exp, TargetsCluster: TargetsCluster(*exp, scope.ID, scope.NodeResourceGroup), RecentExecutions: executions}
+	case OpStart:
+		if err := requireClusterTarget(ctx, api, subID, rg, clusterName, experimentName, experimentPath); err != nil {
+			return "", err
+		}
+		if _, err := api.CallARM(ctx, http.MethodPost, fmt.Sprintf("%s/start?api-version=%s", experimentPath, chaosAPIVersion)); err != nil {
+			return "", fmt.Errorf("failed to start experiment '%s': %w", experimentName, err)
+		}
+		result = ExperimentActionResult{Experiment: experimentName, Operation: OpStart, Accepted: true,
+			Message: "Experiment start accepted. Use operation=\"results\" to follow the execution."}
+	case OpStop:
+		if err := requireClusterTarget(ctx, api, subID, rg, clusterName, experimentName, experimentPath); err != nil {
+			return "", err
+		}
+		if _, err := api.CallARM(ctx, http.MethodPost, fmt.Sprintf("%s/cancel?api-version=%s", experimentPath, chaosAPIVersion)); err != nil {
+			return "", fmt.Errorf("failed to stop experiment '%s': %w", experimentName, err)
+		}
+		result = ExperimentActionResult{Experiment: experimentName, Operation: OpStop, Accepted: true,
+			Message: "Experiment cancellation accepted. Faults are rolled back as the execution stops."}
+	case OpResults:
+		if executionID == "" {
+			executions, err := listExecutions(ctx, api, experimentPath)
+			if err != nil {
+				return "", err
+			}
+			if len(executions) == 0 {
+				return "", fmt.Errorf("experiment '%s' has no executions", experimentName)
+			}
+			executionID = executions[0].ID
+		}
+		body, err := api.CallARM(ctx, http.MethodPost, fmt.Sprintf("%s/executions/%s/getExecutionDetails?api-version=%s", experimentPath, executionID, chaosAPIVersion))
+		if err != nil {
+			return "", fmt.Errorf("failed to get execution details: %w", err)
+		}
+		summary, err := ParseExecutionDetails(experimentName, body)
+		if err != nil {
+			return "", err
+		}
+		result = summary
+	default:
+		return "", fmt.Errorf("unsupported operation: %s", operation)
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chaos experiment result to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// requireClusterTarget returns an error unless the experiment targets the cluster or its node resource group,
+// so start and stop can only act on experiments within the caller's cluster scope
+func requireClusterTarget(ctx context.Context, api common.ARMCaller, subID, rg, clusterName, experimentName, experimentPath string) error {
+	scope, err := getClusterScope(ctx, api, subID, rg, clusterName)
+	if err != nil {
+		return err
+	}
+	exp, err := getExperiment(ctx, api, experimentPath)
+	if err != nil {
+		return err
+	}
+	if !TargetsCluster(*exp, scope.ID, scope.NodeResourceGroup) {
+		return fmt.Errorf("experiment '%s' does not target cluster '%s' or its node resource group", experimentName, clusterName)
+	}
+	return nil
+}
+
+// getClusterScope reads the cluster resource ID and node resource group
+func getClusterScope(ctx context.Context, api common.ARMCaller, subID, rg, clusterName string) (clusterScope, error) {
+	path := common.ClusterResourceID(subID, rg, clusterName) + "?api-version=" + clusterAPIVersion
+	body, err := api.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		return clusterScope{}, fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	var cluster struct {
+		ID         string `json:"id"`
+		Properties struct {
+			NodeResourceGroup string `json:"nodeResourceGroup"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return clusterScope{}, fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+	return clusterScope{ID: cluster.ID, NodeResourceGroup: cluster.Properties.NodeResourceGroup}, nil
+}
+
+// listClusterExperiments lists experiments in the subscription and keeps those targeting the cluster
+func listClusterExperiments(ctx context.Context, api common.ARMCaller, subID string, scope clusterScope) ([]Experiment, error) {
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Chaos/experiments?api-version=%s", subID, chaosAPIVersion)
+	matched := []Experiment{}
+	for page := 0; next != "" && page < maxListPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chaos experiments: %w", err)
+		}
+		experiments, nextLink, err := ParseExperimentList(body)
+		if err != nil {
+			return nil, err
+		}
+		for _, exp := range experiments {
+			if TargetsCluster(exp, scope.ID, scope.NodeResourceGroup) {
+				matched = append(matched, exp)
+			}
+		}
+		next = nextLink
+	}
+	return matched, nil
+}
+
+// getExperiment reads an experiment
+func getExperiment(ctx context.Context, api common.ARMCaller, experimentPath string) (*Experiment, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, fmt.Sprintf("%s?api-version=%s", experimentPath, chaosAPIVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+	return ParseExperiment(body)
+}
+
+// listExecutions lists the executions of an experiment, newest first
+func listExecutions(ctx context.Context, api common.ARMCaller, experimentPath string) ([]Execution, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, fmt.Sprintf("%s/executions?api-version=%s", experimentPath, chaosAPIVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment executions: %w", err)
+	}
+	return ParseExecutions(body)
+}
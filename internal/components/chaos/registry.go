@@ -0,0 +1,65 @@
+package chaos
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Chaos experiment operations
+const (
+	OpList    = "list"
+	OpShow    = "show"
+	OpStart   = "start"
+	OpStop    = "stop"
+	OpResults = "results"
+)
+
+// RegisterChaosExperimentsTool registers the az_chaos_experiments tool
+func RegisterChaosExperimentsTool() mcp.Tool {
+	description := `Orchestrate Azure Chaos Studio resilience experiments that target the AKS cluster.
+
+Experiments are in scope when they target the cluster itself (Chaos Mesh pod, network and stress faults)
+or VM scale sets in the cluster node resource group (node faults such as shutdown).
+
+Operations:
+- list: List experiments in the subscription that target the cluster
+- show: Show an experiment's faults, targets and recent executions
+- start: Start an experiment (only experiments targeting the cluster can be started)
+- stop: Cancel the running execution of an experiment
+- results: Summarize per-target results of an execution (defaults to the latest execution)
+
+Examples:
+- List experiments: operation="list", subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>"
+- Start a pod fault experiment: operation="start", ..., experiment_name="pod-failure"
+- Latest results: operation="results", ..., experiment_name="pod-failure"`
+
+	return mcp.NewTool(
+		"az_chaos_experiments",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform"),
+			mcp.Enum(OpList, OpShow, OpStart, OpStop, OpResults),
+			mcp.Required(),
+		),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("experiment_name",
+			mcp.Description("Name of the Chaos Studio experiment (required for show, start, stop and results)"),
+		),
+		mcp.WithString("experiment_resource_group",
+			mcp.Description("Resource group of the experiment (defaults to the cluster resource group)"),
+		),
+		mcp.WithString("execution_id",
+			mcp.Description("Execution ID for the results operation (defaults to the latest execution)"),
+		),
+	)
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"context"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+)
+
+// ARMCaller sends requests to the Azure Resource Manager API. *azureclient.AzureClient implements it.
+type ARMCaller interface {
+	CallARM(ctx context.Context, method, path string) ([]byte, error)
+}
+
+// ARMBodyCaller sends requests with a JSON payload to the Azure Resource Manager API. *azureclient.AzureClient
+// implements it.
+type ARMBodyCaller interface {
+	CallARMWithBody(ctx context.Context, method, path string, payload interface{}) ([]byte, error)
+}
+
+// ARMClient sends requests with and without a payload to the Azure Resource Manager API.
+// *azureclient.AzureClient implements it.
+type ARMClient interface {
+	ARMCaller
+	ARMBodyCaller
+}
+
+var _ ARMClient = (*azureclient.AzureClient)(nil)
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+)
+
+// SKUAutomatic is the SKU name of AKS Automatic clusters. Standard clusters have the SKU name Base.
+const SKUAutomatic = "Automatic"
+
+// IsAutomaticSKU reports whether a cluster SKU name is AKS Automatic
+func IsAutomaticSKU(sku string) bool {
+	return strings.EqualFold(strings.TrimSpace(sku), SKUAutomatic)
+}
+
+// ClusterSKU returns the SKU name of a cluster with az aks show. run takes the command without the leading az.
+// The subscription is optional and defaults to the az CLI's current subscription.
+func ClusterSKU(run func(args string) (string, error), subscription, resourceGroup, name string) (string, error) {
+	args := fmt.Sprintf("aks show --resource-group %s --name %s --query sku.name --output tsv", azcli.QuoteArg(resourceGroup), azcli.QuoteArg(name))
+	if subscription != "" {
+		args += " --subscription " + azcli.QuoteArg(subscription)
+	}
+	output, err := run(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the SKU of cluster %s: %w", name, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ManagingCluster returns the resource group and name of the cluster whose node resource group is resourceGroup,
+// from the group's managedBy property, or empty strings when no cluster manages the group
+func ManagingCluster(run func(args string) (string, error), subscription, resourceGroup string) (string, string, error) {
+	args := fmt.Sprintf("group show --name %s --query managedBy --output tsv", azcli.QuoteArg(resourceGroup))
+	if subscription != "" {
+		args += " --subscription " + azcli.QuoteArg(subscription)
+	}
+	output, err := run(args)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read resource group %s: %w", resourceGroup, err)
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSpace(output), "/"), "/")
+	// subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.ContainerService/managedClusters/<name>
+	if len(parts) != 8 || !strings.EqualFold(parts[6], "managedClusters") {
+		return "", "", nil
+	}
+	return parts[3], parts[7], nil
+}
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestManagingCluster(t *testing.T) {
+	run := func(output string) func(string) (string, error) {
+		return func(string) (string, error) { return output, nil }
+	}
+	rg, name, err := ManagingCluster(run("/subscriptions/sub/resourcegroups/rg/providers/Microsoft.ContainerService/managedClusters/aks\n"), "", "MC_rg_aks")
+	if err != nil || rg != "rg" || name != "aks" {
+		t.Errorf("Expected cluster rg/aks, got %s/%s (%v)", rg, name, err)
+	}
+	if _, name, err := ManagingCluster(run("\n"), "sub", "apps"); err != nil || name != "" {
+		t.Errorf("Expected no cluster for an unmanaged group, got %q (%v)", name, err)
+	}
+	if !IsAutomaticSKU(" automatic\n") || IsAutomaticSKU("Base") {
+		t.Error("Unexpected IsAutomaticSKU result")
+	}
+}
@@ -3,12 +3,25 @@ package common
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/Azure/aks-mcp/internal/azureclient"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
 )
 
+var (
+	// NamespacePattern matches Kubernetes namespace names
+	NamespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// NamePattern matches the names of Kubernetes objects such as pods and containers, which may contain dots
+	NamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// LabelSelectorPattern matches equality-based label selectors such as app=web,tier!=cache, including
+	// Hubble's source prefixed labels such as k8s:app=web
+	LabelSelectorPattern = regexp.MustCompile(`^[A-Za-z0-9._/=!,:-]+$`)
+)
+
 // ExtractAKSParameters extracts and validates the common AKS parameters from the params map
 func ExtractAKSParameters(params map[string]interface{}) (subscriptionID, resourceGroup, clusterName string, err error) {
 	subID, ok := params["subscription_id"].(string)
@@ -29,8 +42,42 @@ func ExtractAKSParameters(params map[string]interface{}) (subscriptionID, resour
 	return subID, rg, clusterNameParam, nil
 }
 
+// ClusterResourceID constructs the Azure resource ID for an AKS cluster
+func ClusterResourceID(subscriptionID, resourceGroup, clusterName string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s",
+		subscriptionID, resourceGroup, clusterName)
+}
+
 // GetClusterDetails gets the details of an AKS cluster
 func GetClusterDetails(ctx context.Context, client *azureclient.AzureClient, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error) {
 	// Get the cluster from Azure client (which now handles caching internally)
 	return client.GetAKSCluster(ctx, subscriptionID, resourceGroup, clusterName)
 }
+
+// NamespaceFlags returns kubectl flags covering every namespace the server may read.
+// --all-namespaces is rejected when the server is restricted with --allow-namespaces,
+// so each allowed namespace gets its own --namespace flag instead.
+func NamespaceFlags(allowNamespaces string) []string {
+	if allowNamespaces == "" {
+		return []string{"--all-namespaces"}
+	}
+	var flags []string
+	for _, ns := range strings.Split(allowNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			flags = append(flags, "--namespace "+ns)
+		}
+	}
+	return flags
+}
+
+// DecodeList appends the items of kubectl get -o json list output to items
+func DecodeList[T any](output string, items *[]T) error {
+	var list struct {
+		Items []T `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return fmt.Errorf("failed to parse kubectl output: %v", err)
+	}
+	*items = append(*items, list.Items...)
+	return nil
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -97,3 +98,65 @@ func TestExtractAKSParameters(t *testing.T) {
 		})
 	}
 }
+
+// TestClusterResourceID tests the AKS cluster resource ID format
+func TestClusterResourceID(t *testing.T) {
+	want := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	if got := ClusterResourceID("sub", "rg", "aks"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+// TestNamespaceFlags tests the kubectl namespace flags for restricted and unrestricted servers
+func TestNamespaceFlags(t *testing.T) {
+	if got := NamespaceFlags(""); !reflect.DeepEqual(got, []string{"--all-namespaces"}) {
+		t.Errorf("Expected --all-namespaces without restrictions, got %v", got)
+	}
+	if got := NamespaceFlags("default, apps,"); !reflect.DeepEqual(got, []string{"--namespace default", "--namespace apps"}) {
+		t.Errorf("Expected a flag per allowed namespace, got %v", got)
+	}
+}
+
+// TestDecodeList tests that list items are appended and invalid output is rejected
+func TestDecodeList(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	items := []item{{Name: "a"}}
+	if err := DecodeList(`{"items": [{"name": "b"}, {"name": "c"}]}`, &items); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(items, []item{{Name: "a"}, {Name: "b"}, {Name: "c"}}) {
+		t.Errorf("Unexpected items %v", items)
+	}
+	if err := DecodeList("error: forbidden", &items); err == nil {
+		t.Error("Expected invalid output to be rejected")
+	}
+}
+
+// TestNamespacePattern tests the namespace name pattern
+func TestNamespacePattern(t *testing.T) {
+	for value, want := range map[string]bool{"default": true, "team-a": true, "-a": false, "Prod": false, "a;b": false} {
+		if NamespacePattern.MatchString(value) != want {
+			t.Errorf("NamespacePattern(%q): expected %v", value, want)
+		}
+	}
+}
+
+// TestNamePattern tests the object name pattern
+func TestNamePattern(t *testing.T) {
+	for value, want := range map[string]bool{"web-0": true, "app.v2": true, "-web": false, "Web": false, "a/b": false} {
+		if NamePattern.MatchString(value) != want {
+			t.Errorf("NamePattern(%q): expected %v", value, want)
+		}
+	}
+}
+
+// TestLabelSelectorPattern tests the label selector pattern
+func TestLabelSelectorPattern(t *testing.T) {
+	for value, want := range map[string]bool{"app=web,tier!=cache": true, "k8s:app=web": true, "app in (a)": false, "app=$(id)": false} {
+		if LabelSelectorPattern.MatchString(value) != want {
+			t.Errorf("LabelSelectorPattern(%q): expected %v", value, want)
+		}
+	}
+}
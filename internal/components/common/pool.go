@@ -0,0 +1,23 @@
+package common
+
+import "sync"
+
+// RunPool calls fn for every index from 0 to n-1 on at most workers goroutines and waits for all calls
+func RunPool(n, workers int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunPool(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	done := make([]bool, 20)
+	RunPool(len(done), 3, func(i int) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		active--
+		done[i] = true
+		mu.Unlock()
+	})
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("Expected job %d to run", i)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("Expected at most 3 parallel jobs, got %d", peak)
+	}
+}
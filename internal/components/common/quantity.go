@@ -0,0 +1,21 @@
+package common
+
+import "k8s.io/apimachinery/pkg/api/resource"
+
+// CPUMillis parses a CPU quantity such as 250m or 2 into millicores, treating invalid values as zero
+func CPUMillis(value string) int64 {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return q.MilliValue()
+}
+
+// MemoryBytes parses a memory quantity such as 512Mi into bytes, treating invalid values as zero
+func MemoryBytes(value string) int64 {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
@@ -0,0 +1,19 @@
+package common
+
+import "testing"
+
+func TestCPUMillis(t *testing.T) {
+	for value, want := range map[string]int64{"250m": 250, "2": 2000, "0.5": 500, "": 0, "lots": 0} {
+		if got := CPUMillis(value); got != want {
+			t.Errorf("CPUMillis(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	for value, want := range map[string]int64{"512Mi": 512 << 20, "1G": 1000000000, "128974848": 128974848, "": 0, "lots": 0} {
+		if got := MemoryBytes(value); got != want {
+			t.Errorf("MemoryBytes(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+)
+
+// ResourceGraphQuerier runs Azure Resource Graph queries
+type ResourceGraphQuerier interface {
+	QueryResourceGraph(ctx context.Context, query string, subscriptions []string) ([]map[string]interface{}, error)
+}
+
+// clusterLookupPattern limits cluster names, FQDNs and resource group names to the characters Azure allows,
+// so they can be embedded in a Resource Graph query
+var clusterLookupPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._()\-]*$`)
+
+// maxListedCandidates bounds the clusters listed when a name is ambiguous
+const maxListedCandidates = 10
+
+// ClusterSelectionParam chooses one of the clusters matching an ambiguous cluster name, by the index or
+// resource ID of a candidate
+const ClusterSelectionParam = "cluster_selection"
+
+// ClusterCandidate is one of the clusters matching an ambiguous cluster name or FQDN
+type ClusterCandidate struct {
+	// Index is the 1-based position of the candidate, accepted by cluster_selection
+	Index             int    `json:"index"`
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	SubscriptionID    string `json:"subscriptionId"`
+	ResourceGroup     string `json:"resourceGroup"`
+	Location          string `json:"location,omitempty"`
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	FQDN              string `json:"fqdn,omitempty"`
+	PowerState        string `json:"powerState,omitempty"`
+}
+
+// AmbiguousClusterError is returned when a cluster name or FQDN matches several clusters and no valid
+// cluster_selection chooses one of them
+type AmbiguousClusterError struct {
+	Name       string
+	Candidates []ClusterCandidate
+	// Selection is the cluster_selection that matched none of the candidates, if one was given
+	Selection string
+}
+
+func (e *AmbiguousClusterError) Error() string {
+	var candidates []string
+	for i, c := range e.Candidates {
+		if i == maxListedCandidates {
+			candidates = append(candidates, fmt.Sprintf("and %d more", len(e.Candidates)-maxListedCandidates))
+			break
+		}
+		candidates = append(candidates, fmt.Sprintf("%d. %s (subscription %s, resource group %s)", c.Index, c.Name, c.SubscriptionID, c.ResourceGroup))
+	}
+	message := fmt.Sprintf("%d AKS clusters match %q: %s. Pass %s with the number of one of them, or subscription_id and resource_group, to choose one",
+		len(e.Candidates), e.Name, strings.Join(candidates, "; "), ClusterSelectionParam)
+	if e.Selection != "" {
+		message = fmt.Sprintf("%s %q matches none of the clusters. %s", ClusterSelectionParam, e.Selection, message)
+	}
+	return message
+}
+
+// Listed returns the candidates worth presenting to a user
+func (e *AmbiguousClusterError) Listed() []ClusterCandidate {
+	if len(e.Candidates) > maxListedCandidates {
+		return e.Candidates[:maxListedCandidates]
+	}
+	return e.Candidates
+}
+
+// Select returns the candidate chosen by a cluster_selection value: its 1-based index or its resource ID
+func (e *AmbiguousClusterError) Select(selection string) (ClusterCandidate, bool) {
+	selection = strings.TrimSpace(selection)
+	if index, err := strconv.Atoi(selection); err == nil {
+		if index >= 1 && index <= len(e.Candidates) {
+			return e.Candidates[index-1], true
+		}
+		return ClusterCandidate{}, false
+	}
+	for _, c := range e.Candidates {
+		if strings.EqualFold(c.ID, selection) {
+			return c, true
+		}
+	}
+	return ClusterCandidate{}, false
+}
+
+// NeedsClusterResolution reports whether the parameters name a cluster but lack its subscription or resource group
+func NeedsClusterResolution(params map[string]interface{}) bool {
+	name, _ := params["cluster_name"].(string)
+	if strings.TrimSpace(name) == "" {
+		return false
+	}
+	subID, _ := params["subscription_id"].(string)
+	rg, _ := params["resource_group"].(string)
+	return subID == "" || rg == "" || strings.HasPrefix(name, "/subscriptions/")
+}
+
+// ResolveClusterParameters fills in subscription_id and resource_group when only the cluster is known.
+// cluster_name may be a cluster name, the cluster's API server FQDN or its full resource ID. Names and
+// FQDNs are looked up with Resource Graph across the subscriptions the credential can read, narrowed
+// by subscription_id or resource_group when one of them is given. When several clusters match,
+// cluster_selection chooses one of them, and an *AmbiguousClusterError lists them otherwise.
+// cluster_selection is always removed, and the other parameters are left unchanged when nothing needs resolving.
+func ResolveClusterParameters(ctx context.Context, params map[string]interface{}, querier ResourceGraphQuerier) error {
+	selection := ""
+	if raw, ok := params[ClusterSelectionParam]; ok {
+		if raw != nil {
+			selection = strings.TrimSpace(fmt.Sprint(raw))
+		}
+		delete(params, ClusterSelectionParam)
+	}
+	if !NeedsClusterResolution(params) {
+		return nil
+	}
+	name := strings.TrimSpace(params["cluster_name"].(string))
+	subID, _ := params["subscription_id"].(string)
+	rg, _ := params["resource_group"].(string)
+
+	if strings.HasPrefix(name, "/subscriptions/") {
+		sub, group, cluster, err := azureclient.ParseAKSResourceID(name)
+		if err != nil {
+			return err
+		}
+		params["subscription_id"], params["resource_group"], params["cluster_name"] = sub, group, cluster
+		return nil
+	}
+
+	if !clusterLookupPattern.MatchString(name) || (rg != "" && !clusterLookupPattern.MatchString(rg)) {
+		return fmt.Errorf("invalid cluster_name or resource_group: %q", name)
+	}
+
+	query := "resources | where type =~ 'microsoft.containerservice/managedclusters'"
+	if strings.Contains(name, ".") {
+		query += fmt.Sprintf(" | where tostring(properties.fqdn) =~ '%[1]s' or tostring(properties.privateFQDN) =~ '%[1]s' or tostring(properties.azurePortalFQDN) =~ '%[1]s'", name)
+	} else {
+		query += fmt.Sprintf(" | where name =~ '%s'", name)
+	}
+	if rg != "" {
+		query += fmt.Sprintf(" | where resourceGroup =~ '%s'", rg)
+	}
+	query += " | project id, name, resourceGroup, subscriptionId, location, kubernetesVersion = tostring(properties.currentKubernetesVersion)," +
+		" fqdn = tostring(properties.fqdn), powerState = tostring(properties.powerState.code)" +
+		" | order by subscriptionId asc, resourceGroup asc, name asc"
+
+	var subscriptions []string
+	if subID != "" {
+		subscriptions = []string{subID}
+	}
+	rows, err := querier.QueryResourceGraph(ctx, query, subscriptions)
+	if err != nil {
+		return fmt.Errorf("failed to look up cluster %q: %w. Pass subscription_id and resource_group explicitly", name, err)
+	}
+
+	switch len(rows) {
+	case 0:
+		return fmt.Errorf("no AKS cluster matching %q was found in the subscriptions this server can read. Check the name, or pass subscription_id and resource_group explicitly", name)
+	case 1:
+		row := rows[0]
+		params["subscription_id"], _ = row["subscriptionId"].(string)
+		params["resource_group"], _ = row["resourceGroup"].(string)
+		params["cluster_name"], _ = row["name"].(string)
+		return nil
+	default:
+		ambiguous := &AmbiguousClusterError{Name: name, Candidates: clusterCandidates(rows)}
+		if selection == "" {
+			return ambiguous
+		}
+		chosen, ok := ambiguous.Select(selection)
+		if !ok {
+			ambiguous.Selection = selection
+			return ambiguous
+		}
+		params["subscription_id"], params["resource_group"], params["cluster_name"] = chosen.SubscriptionID, chosen.ResourceGroup, chosen.Name
+		return nil
+	}
+}
+
+// clusterCandidates converts Resource Graph rows into candidates, sorted so their indexes are stable
+// between a lookup and the follow-up call that selects one
+func clusterCandidates(rows []map[string]interface{}) []ClusterCandidate {
+	text := func(row map[string]interface{}, column string) string {
+		value, _ := row[column].(string)
+		return value
+	}
+	candidates := make([]ClusterCandidate, 0, len(rows))
+	for _, row := range rows {
+		candidates = append(candidates, ClusterCandidate{
+			ID:                text(row, "id"),
+			Name:              text(row, "name"),
+			SubscriptionID:    text(row, "subscriptionId"),
+			ResourceGroup:     text(row, "resourceGroup"),
+			Location:          text(row, "location"),
+			KubernetesVersion: text(row, "kubernetesVersion"),
+			FQDN:              text(row, "fqdn"),
+			PowerState:        text(row, "powerState"),
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if !strings.EqualFold(a.SubscriptionID, b.SubscriptionID) {
+			return strings.ToLower(a.SubscriptionID) < strings.ToLower(b.SubscriptionID)
+		}
+		if !strings.EqualFold(a.ResourceGroup, b.ResourceGroup) {
+			return strings.ToLower(a.ResourceGroup) < strings.ToLower(b.ResourceGroup)
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	for i := range candidates {
+		candidates[i].Index = i + 1
+	}
+	return candidates
+}
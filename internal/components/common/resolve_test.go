@@ -0,0 +1,124 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeResourceGraph struct {
+	rows          []map[string]interface{}
+	err           error
+	query         string
+	subscriptions []string
+}
+
+func (f *fakeResourceGraph) QueryResourceGraph(_ context.Context, query string, subscriptions []string) ([]map[string]interface{}, error) {
+	f.query, f.subscriptions = query, subscriptions
+	return f.rows, f.err
+}
+
+func TestResolveClusterParameters(t *testing.T) {
+	row := map[string]interface{}{"name": "aks-prod", "resourceGroup": "rg-prod", "subscriptionId": "sub-1"}
+
+	t.Run("name", func(t *testing.T) {
+		graph := &fakeResourceGraph{rows: []map[string]interface{}{row}}
+		params := map[string]interface{}{"cluster_name": "AKS-PROD"}
+		if err := ResolveClusterParameters(context.Background(), params, graph); err != nil {
+			t.Fatalf("ResolveClusterParameters failed: %v", err)
+		}
+		if params["subscription_id"] != "sub-1" || params["resource_group"] != "rg-prod" || params["cluster_name"] != "aks-prod" {
+			t.Errorf("Unexpected parameters %v", params)
+		}
+		if !strings.Contains(graph.query, "where name =~ 'AKS-PROD'") || graph.subscriptions != nil {
+			t.Errorf("Unexpected query %q across %v", graph.query, graph.subscriptions)
+		}
+	})
+
+	t.Run("fqdn narrowed by subscription", func(t *testing.T) {
+		graph := &fakeResourceGraph{rows: []map[string]interface{}{row}}
+		params := map[string]interface{}{"cluster_name": "aks-prod-dns-abc123.hcp.eastus.azmk8s.io", "subscription_id": "sub-1"}
+		if err := ResolveClusterParameters(context.Background(), params, graph); err != nil {
+			t.Fatalf("ResolveClusterParameters failed: %v", err)
+		}
+		if !strings.Contains(graph.query, "properties.fqdn") || len(graph.subscriptions) != 1 || params["cluster_name"] != "aks-prod" {
+			t.Errorf("Unexpected FQDN lookup %q %v -> %v", graph.query, graph.subscriptions, params)
+		}
+	})
+
+	t.Run("resource id", func(t *testing.T) {
+		params := map[string]interface{}{"cluster_name": "/subscriptions/sub-2/resourceGroups/rg-2/providers/Microsoft.ContainerService/managedClusters/aks-2"}
+		if err := ResolveClusterParameters(context.Background(), params, &fakeResourceGraph{err: errors.New("not called")}); err != nil {
+			t.Fatalf("ResolveClusterParameters failed: %v", err)
+		}
+		if params["subscription_id"] != "sub-2" || params["resource_group"] != "rg-2" || params["cluster_name"] != "aks-2" {
+			t.Errorf("Unexpected parameters %v", params)
+		}
+	})
+
+	t.Run("complete parameters are untouched", func(t *testing.T) {
+		graph := &fakeResourceGraph{}
+		params := map[string]interface{}{"cluster_name": "aks", "subscription_id": "sub", "resource_group": "rg"}
+		if err := ResolveClusterParameters(context.Background(), params, graph); err != nil || graph.query != "" {
+			t.Errorf("Expected no lookup, got %q (%v)", graph.query, err)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		graph *fakeResourceGraph
+		want  string
+	}{
+		"not found":   {&fakeResourceGraph{}, "no AKS cluster matching"},
+		"ambiguous":   {&fakeResourceGraph{rows: []map[string]interface{}{row, {"name": "aks-prod", "resourceGroup": "rg-dr", "subscriptionId": "sub-2"}}}, "2 AKS clusters match"},
+		"query error": {&fakeResourceGraph{err: errors.New("forbidden")}, "Pass subscription_id and resource_group explicitly"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ResolveClusterParameters(context.Background(), map[string]interface{}{"cluster_name": "aks-prod"}, tc.graph)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	t.Run("selection among ambiguous matches", func(t *testing.T) {
+		dr := map[string]interface{}{"id": "/subscriptions/sub-0/resourceGroups/rg-dr/providers/Microsoft.ContainerService/managedClusters/aks-prod",
+			"name": "aks-prod", "resourceGroup": "rg-dr", "subscriptionId": "sub-0", "location": "westus", "kubernetesVersion": "1.30.3"}
+		graph := &fakeResourceGraph{rows: []map[string]interface{}{row, dr}}
+
+		err := ResolveClusterParameters(context.Background(), map[string]interface{}{"cluster_name": "aks-prod"}, graph)
+		var ambiguous *AmbiguousClusterError
+		if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+			t.Fatalf("Expected the candidates, got %v", err)
+		}
+		if first := ambiguous.Candidates[0]; first.Index != 1 || first.SubscriptionID != "sub-0" || first.Location != "westus" || first.KubernetesVersion != "1.30.3" {
+			t.Errorf("Expected candidates sorted by subscription with their metadata, got %+v", ambiguous.Candidates)
+		}
+		if !strings.Contains(graph.query, "properties.currentKubernetesVersion") {
+			t.Errorf("Expected the query to project distinguishing metadata, got %q", graph.query)
+		}
+
+		for _, selection := range []interface{}{float64(2), "2", "/SUBSCRIPTIONS/sub-0/resourceGroups/rg-dr/providers/Microsoft.ContainerService/managedClusters/aks-prod"} {
+			params := map[string]interface{}{"cluster_name": "aks-prod", ClusterSelectionParam: selection}
+			if err := ResolveClusterParameters(context.Background(), params, graph); err != nil {
+				t.Fatalf("Selection %v failed: %v", selection, err)
+			}
+			want := "rg-prod"
+			if s, ok := selection.(string); ok && strings.HasPrefix(s, "/") {
+				want = "rg-dr"
+			}
+			if params["resource_group"] != want || params[ClusterSelectionParam] != nil {
+				t.Errorf("Selection %v: unexpected parameters %v", selection, params)
+			}
+		}
+
+		err = ResolveClusterParameters(context.Background(), map[string]interface{}{"cluster_name": "aks-prod", ClusterSelectionParam: "3"}, graph)
+		if !errors.As(err, &ambiguous) || ambiguous.Selection != "3" || !strings.Contains(err.Error(), "matches none") {
+			t.Errorf("Expected an out of range selection to list the candidates again, got %v", err)
+		}
+	})
+
+	if err := ResolveClusterParameters(context.Background(), map[string]interface{}{"cluster_name": "aks' | project secrets"}, &fakeResourceGraph{}); err == nil {
+		t.Error("Expected names that could change the query to be rejected")
+	}
+}
@@ -0,0 +1,59 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/google/shlex"
+)
+
+// CheckAutomaticNodeResourceGroup rejects write operations on VMs and scale sets in the node resource group of an
+// AKS Automatic cluster. Automatic clusters lock their node resource group down, so only AKS can change its
+// resources and the az command would fail with a deny assignment. When the resource group or the cluster can't
+// be read the operation goes ahead, so the az command reports the underlying problem.
+func CheckAutomaticNodeResourceGroup(operation, args string, run func(args string) (string, error)) error {
+	if GetOperationAccessLevel(operation) == "readonly" {
+		return nil
+	}
+	flags := commandFlags(args)
+	resourceGroup := flags["--resource-group"]
+	if resourceGroup == "" {
+		resourceGroup = flags["-g"]
+	}
+	if resourceGroup == "" {
+		return nil
+	}
+	clusterGroup, clusterName, err := common.ManagingCluster(run, flags["--subscription"], resourceGroup)
+	if err != nil || clusterName == "" {
+		return nil
+	}
+	sku, err := common.ClusterSKU(run, flags["--subscription"], clusterGroup, clusterName)
+	if err != nil || !common.IsAutomaticSKU(sku) {
+		return nil
+	}
+	return fmt.Errorf("resource group %s is the node resource group of AKS Automatic cluster %s, which is locked down so only AKS can "+
+		"change its VMs; %s is not allowed. To replace a faulty node, cordon and drain it and delete its Node object with kubectl, "+
+		"and node auto provisioning creates a new one", resourceGroup, clusterName, operation)
+}
+
+// commandFlags returns the flags of an az command with their values. Bare flags map to "".
+func commandFlags(args string) map[string]string {
+	parts, err := shlex.Split(args)
+	if err != nil {
+		parts = strings.Fields(args)
+	}
+	flags := make(map[string]string)
+	for i := 0; i < len(parts); i++ {
+		if !strings.HasPrefix(parts[i], "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(parts[i], "=")
+		if !hasValue && i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "-") {
+			value = parts[i+1]
+			i++
+		}
+		flags[name] = value
+	}
+	return flags
+}
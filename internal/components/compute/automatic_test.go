@@ -0,0 +1,42 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckAutomaticNodeResourceGroup(t *testing.T) {
+	var calls []string
+	run := func(args string) (string, error) {
+		calls = append(calls, args)
+		switch {
+		case strings.HasPrefix(args, "group show --name 'MC_rg_auto'"):
+			return "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/auto\n", nil
+		case strings.HasPrefix(args, "group show --name 'MC_rg_base'"):
+			return "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/base\n", nil
+		case strings.HasPrefix(args, "group show --name 'apps'"):
+			return "\n", nil
+		case strings.HasPrefix(args, "aks show --resource-group 'rg' --name 'auto'"):
+			return "Automatic\n", nil
+		case strings.HasPrefix(args, "aks show --resource-group 'rg' --name 'base'"):
+			return "Base\n", nil
+		}
+		return "", fmt.Errorf("unexpected command: %s", args)
+	}
+
+	err := CheckAutomaticNodeResourceGroup("restart", "--name aks-np-vmss --resource-group MC_rg_auto", run)
+	if err == nil || !strings.Contains(err.Error(), "AKS Automatic cluster auto") {
+		t.Errorf("Expected restart in the locked down node resource group to be rejected, got %v", err)
+	}
+	for _, args := range []string{"--name aks-np-vmss -g MC_rg_base", "--name vm1 --resource-group apps", "--name vm1"} {
+		if err := CheckAutomaticNodeResourceGroup("run-command", args, run); err != nil {
+			t.Errorf("%s: unexpected error %v", args, err)
+		}
+	}
+
+	calls = nil
+	if err := CheckAutomaticNodeResourceGroup("show", "--name aks-np-vmss --resource-group MC_rg_auto", run); err != nil || len(calls) != 0 {
+		t.Errorf("Expected read operations to go ahead without lookups, got %v and %v", err, calls)
+	}
+}
@@ -32,6 +32,10 @@ const (
 	OpVMSSGetInstanceView ComputeOperationType = "get-instance-view"
 	OpVMSSRunCommand      ComputeOperationType = "run-command"
 
+	// VMSS instance operations - admin only, scoped to a single drained instance
+	OpVMSSDeleteInstance     ComputeOperationType = "delete-instance"
+	OpVMSSDeallocateInstance ComputeOperationType = "deallocate-instance"
+
 	// Resource types
 	ResourceTypeVM   ResourceType = "vm"
 	ResourceTypeVMSS ResourceType = "vmss"
@@ -62,22 +66,42 @@ Available operation values:`
 		desc += "- restart: Restart VM/VMSS instances\n"
 		desc += "- run-command: Execute commands remotely on VM/VMSS instances\n"
 		desc += "- reimage: Reimage VMSS instances (VM not supported for reimage)\n"
+		desc += "Write operations are rejected in the node resource group of AKS Automatic clusters, which only AKS can change.\n"
+	}
+
+	// Removing a single wedged instance so the autoscaler replaces it
+	if accessLevel == "admin" {
+		desc += "- delete-instance: Delete one VMSS instance (admin only)\n"
+		desc += "- deallocate-instance: Deallocate one VMSS instance (admin only)\n"
+		desc += "These take exactly one instance ID and never act on the whole scale set. The instance's node must be cordoned " +
+			"and drained first (aks_node_drain), which is checked with kubectl; an instance whose node never joined or was " +
+			"already removed from the cluster passes the check. Every attempt is recorded in the audit log.\n"
 	}
 
-	// Note: All destructive operations (create, delete, deallocate, update, resize, scale)
-	// have been removed for AKS environment safety
+	// Note: Destructive operations on whole resources (create, delete, deallocate, update, resize, scale)
+	// are not offered for AKS environment safety
+
+	desc += "\nThe vmss operations on instances (show, get-instance-view, restart, reimage, run-command) accept node instead of " +
+		"--name and the instance ID: a node name, its provider ID or <vmss>_<instance-id>. The scale set, instance ID and " +
+		"resource group are filled in from the node; a node name without Kubernetes access also needs --resource-group.\n"
 
 	// Examples
 	desc += "\nEXAMPLES:\n"
 	desc += `List VMSS: operation="list", resource_type="vmss", args="--resource-group myRG"` + "\n"
 	desc += `Show VMSS: operation="show", resource_type="vmss", args="--name myVMSS --resource-group myRG"` + "\n"
 	desc += `List VMs: operation="list", resource_type="vm", args="--resource-group myRG"` + "\n"
+	desc += `Show the instance behind a node: operation="show", resource_type="vmss", node="aks-nodepool1-12345678-vmss000003"` + "\n"
+	desc += `Query with parameters: operation="list", resource_type="vmss", parameters={"resource_group": "myRG", "query": "[].{name:name, capacity:sku.capacity}"}` + "\n"
 
 	if accessLevel == "readwrite" || accessLevel == "admin" {
 		desc += `Restart VMSS: operation="restart", resource_type="vmss", args="--name myVMSS --resource-group myRG"` + "\n"
 		desc += `Reimage VMSS: operation="reimage", resource_type="vmss", args="--name myVMSS --resource-group myRG"` + "\n"
 		desc += `Run command on VM: operation="run-command", resource_type="vm", args="--name myVM --resource-group myRG --command-id RunShellScript --scripts 'echo hello'"` + "\n"
 		desc += `Run command on VMSS: operation="run-command", resource_type="vmss", args="--name myVMSS --resource-group myRG --command-id RunShellScript --scripts 'hostname' --instance-id 0"` + "\n"
+		desc += `Reimage a node: operation="reimage", resource_type="vmss", node="azure:///subscriptions/<sub>/resourceGroups/MC_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/3"` + "\n"
+	}
+	if accessLevel == "admin" {
+		desc += `Delete a drained node's instance: operation="delete-instance", resource_type="vmss", node="aks-nodepool1-12345678-vmss000003"` + "\n"
 	}
 
 	return desc
@@ -91,36 +115,93 @@ func RegisterAzComputeOperations(cfg *config.ConfigData) mcp.Tool {
 		mcp.WithDescription(description),
 		mcp.WithString("operation",
 			mcp.Required(),
-			mcp.Description("Operation to perform. Common operations: list, show, start, stop, restart, deallocate, run-command, scale, etc."),
+			mcp.Description("Operation to perform; see the tool description for the operations of each resource type"),
+			mcp.Enum(AllowedOperations(cfg.AccessLevel)...),
 		),
 		mcp.WithString("resource_type",
 			mcp.Required(),
 			mcp.Description("Resource type: 'vm' (single virtual machine) or 'vmss' (virtual machine scale set)"),
+			mcp.Enum(ResourceTypes...),
 		),
 		mcp.WithString("args",
-			mcp.Required(),
-			mcp.Description("Azure CLI arguments: '--resource-group myRG' (required for most operations), '--name myVM' (for specific resources), '--new-capacity 3' (for scaling)"),
+			mcp.Description("Azure CLI arguments: '--resource-group myRG' (required for most operations), '--name myVM' (for specific resources), '--new-capacity 3' (for scaling). Either args, parameters or node is required."),
+		),
+		mcp.WithString("node",
+			mcp.Description("Node whose scale set instance the vmss operation acts on: the node name (kubectl get nodes), its provider ID or <vmss>_<instance-id>. Fills in --name, the instance ID and --resource-group."),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("Arguments as an object of flag names to values, converted to escaped CLI flags, e.g. {\"name\": \"myVMSS\", \"resource_group\": \"myRG\", \"instance_ids\": [\"0\", \"1\"]}. true adds a bare flag, arrays become space separated values. Keys are validated against the flags allowed for the operation."),
 		),
 	)
 }
 
-// GetOperationAccessLevel returns the required access level for an operation
-func GetOperationAccessLevel(operation string) string {
-	readOnlyOps := []string{
+// operationParameters lists the flags accepted in the structured parameters object per resource type and operation.
+// Global flags such as --subscription and --query are accepted for every operation.
+var operationParameters = map[string]map[string][]string{
+	string(ResourceTypeVM): {
+		string(OpVMShow):            {"name", "resource-group", "ids", "show-details"},
+		string(OpVMList):            {"resource-group", "show-details"},
+		string(OpVMStart):           {"name", "resource-group", "ids", "no-wait"},
+		string(OpVMStop):            {"name", "resource-group", "ids", "skip-shutdown", "no-wait"},
+		string(OpVMRestart):         {"name", "resource-group", "ids", "force", "no-wait"},
+		string(OpVMGetInstanceView): {"name", "resource-group", "ids"},
+		string(OpVMRunCommand):      {"name", "resource-group", "ids", "command-id", "scripts", "parameters"},
+	},
+	string(ResourceTypeVMSS): {
+		string(OpVMSSShow):            {"name", "resource-group", "instance-id"},
+		string(OpVMSSList):            {"resource-group"},
+		string(OpVMSSGetInstanceView): {"name", "resource-group", "instance-id"},
+		string(OpVMSSRestart):         {"name", "resource-group", "instance-ids", "no-wait"},
+		string(OpVMSSReimage):         {"name", "resource-group", "instance-ids", "no-wait"},
+		string(OpVMSSRunCommand):      {"name", "resource-group", "instance-id", "command-id", "scripts", "parameters"},
+		// Removal takes exactly one instance ID, checked by ValidateSingleInstance
+		string(OpVMSSDeleteInstance):     {"name", "resource-group", "instance-ids", "no-wait"},
+		string(OpVMSSDeallocateInstance): {"name", "resource-group", "instance-ids", "no-wait"},
+	},
+}
+
+// GetOperationParameters returns the flags accepted in the parameters object of an operation
+func GetOperationParameters(operation, resourceType string) []string {
+	return operationParameters[resourceType][operation]
+}
+
+// Operations by the access level they require. The tool's operation enum and access checks are built from them.
+var (
+	readOnlyOps = []string{
 		string(OpVMShow), string(OpVMList), string(OpVMGetInstanceView),
 		string(OpVMSSShow), string(OpVMSSList), string(OpVMSSGetInstanceView),
 	}
 
-	readWriteOps := []string{
+	readWriteOps = []string{
 		// VM operations - safe operations only
 		string(OpVMStart), string(OpVMStop), string(OpVMRestart), string(OpVMRunCommand),
 		// VMSS operations - only safe operations for AKS-managed VMSS
 		string(OpVMSSRestart), string(OpVMSSReimage), string(OpVMSSRunCommand),
 	}
 
-	// No admin operations - all unsafe operations removed
-	adminOps := []string{}
+	// Removing a single instance is the only destructive operation
+	adminOps = []string{string(OpVMSSDeleteInstance), string(OpVMSSDeallocateInstance)}
+)
+
+// ResourceTypes lists the compute resource types
+var ResourceTypes = []string{string(ResourceTypeVM), string(ResourceTypeVMSS)}
 
+// AllowedOperations returns the operations the access level may perform, on either resource type
+func AllowedOperations(accessLevel string) []string {
+	ops := slices.Clone(readOnlyOps)
+	if accessLevel == "readwrite" || accessLevel == "admin" {
+		ops = append(ops, readWriteOps...)
+	}
+	if accessLevel == "admin" {
+		ops = append(ops, adminOps...)
+	}
+	// VM and VMSS operations share names
+	slices.Sort(ops)
+	return slices.Compact(ops)
+}
+
+// GetOperationAccessLevel returns the required access level for an operation
+func GetOperationAccessLevel(operation string) string {
 	if slices.Contains(readOnlyOps, operation) {
 		return "readonly"
 	}
@@ -185,7 +266,10 @@ func MapOperationToCommand(operation string, resourceType string) (string, error
 			string(OpVMSSRestart):    "az vmss restart",
 			string(OpVMSSReimage):    "az vmss reimage",
 			string(OpVMSSRunCommand): "az vmss run-command invoke",
-			// Removed unsafe operations: create, delete, start, stop, deallocate, scale, update
+			// Admin operations on a single instance, never the whole scale set
+			string(OpVMSSDeleteInstance):     "az vmss delete-instances",
+			string(OpVMSSDeallocateInstance): "az vmss deallocate",
+			// Removed unsafe operations: create, delete, start, stop, scale, update
 		},
 	}
 
@@ -0,0 +1,281 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+const (
+	// cseExtensionName is the name AKS gives the Custom Script Extension that bootstraps Linux nodes
+	cseExtensionName = "vmssCSE"
+	// provisionLogPath is the node bootstrap log written by the Custom Script Extension
+	provisionLogPath      = "/var/log/azure/cluster-provision.log"
+	defaultProvisionLines = 100
+	maxProvisionLines     = 1000
+	// maxExtensionMessage bounds the extension status message kept, which embeds the script's stdout and stderr
+	maxExtensionMessage = 4000
+	// maxProvisionErrors bounds the provision log lines reported as errors
+	maxProvisionErrors = 20
+)
+
+// cseExitStatusPattern extracts the exit code from a failed Custom Script Extension status message
+var cseExitStatusPattern = regexp.MustCompile(`exit status=(\d+)`)
+
+// cseExitCodes describes the AKS node bootstrap (CSE) exit codes most often seen when nodes fail to join
+var cseExitCodes = map[int]string{
+	4:  "a systemd unit failed to start during bootstrap",
+	5:  "cloud-init did not finish in time",
+	6:  "timed out waiting for a file written by cloud-init",
+	9:  "package installation timed out; check access to the package mirrors",
+	30: "timed out waiting for the node's Kubernetes components to run",
+	31: "Kubernetes binaries could not be downloaded; check egress to the required AKS endpoints",
+	33: "images could not be downloaded; check egress to mcr.microsoft.com",
+	34: "kubelet failed to start",
+	41: "CNI plugins could not be downloaded; check egress to the required AKS endpoints",
+	50: "no outbound connectivity: the node could not reach mcr.microsoft.com; check the firewall, NSG, route table and proxy settings",
+	51: "the node could not connect to the API server; check NSGs, firewalls and authorized IP ranges between the node subnet and the API server",
+	52: "the API server FQDN could not be resolved; check custom DNS servers and the private DNS zone link to the VNet",
+	53: "the API server FQDN could not be resolved through Azure DNS; check the VNet DNS settings",
+}
+
+// provisionErrorPatterns are lowercase fragments of provision log lines that point at a bootstrap failure
+var provisionErrorPatterns = []string{"error", "failed", "fail to", "timed out", "timeout", "exit code", "could not"}
+
+// BootstrapReader reads cluster details and scale set instance views and runs commands on instances.
+// *azureclient.AzureClient implements it.
+type BootstrapReader interface {
+	GetAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error)
+	GetVMSSInstanceView(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (*armcompute.VirtualMachineScaleSetVMInstanceView, error)
+	RunVMSSShellScript(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, script string) (string, error)
+}
+
+// ExtensionStatus is the provisioning status of a VM extension on the node
+type ExtensionStatus struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Status  string `json:"status"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// BootstrapDiagnosis is the result of the diagnose_aks_node_bootstrap tool
+type BootstrapDiagnosis struct {
+	NodeName          string `json:"nodeName"`
+	NodeResourceGroup string `json:"nodeResourceGroup"`
+	VMSS              string `json:"vmss"`
+	InstanceID        string `json:"instanceId"`
+	ProviderID        string `json:"providerId"`
+	// ProvisioningState is the instance's provisioning state, such as succeeded or failed
+	ProvisioningState  string            `json:"provisioningState,omitempty"`
+	ProvisioningErrors []string          `json:"provisioningErrors,omitempty"`
+	Extensions         []ExtensionStatus `json:"extensions"`
+	// ExitCode is the exit code of the failed bootstrap script (0 when it did not fail)
+	ExitCode           int      `json:"exitCode,omitempty"`
+	Cause              string   `json:"cause"`
+	ProvisionLog       string   `json:"provisionLog,omitempty"`
+	ProvisionLogErrors []string `json:"provisionLogErrors,omitempty"`
+	Notes              []string `json:"notes,omitempty"`
+}
+
+// GetNodeBootstrapDiagnosticsHandler returns a handler for the diagnose_aks_node_bootstrap command
+func GetNodeBootstrapDiagnosticsHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNodeBootstrapDiagnostics(params, client, newClusterNodeResolver(client, cfg), cfg)
+	})
+}
+
+// HandleNodeBootstrapDiagnostics reports why the scale set instance behind a node failed to bootstrap, from the
+// instance's provisioning state and extension statuses and, when include_provision_log is set and the access
+// level allows run-command, an excerpt of the node's cluster-provision.log.
+func HandleNodeBootstrapDiagnostics(params map[string]interface{}, reader BootstrapReader, nodes NodeResolver, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	nodeName, _ := params["node_name"].(string)
+	if nodeName == "" {
+		return "", fmt.Errorf("missing or invalid node_name parameter")
+	}
+	includeLog, _ := params["include_provision_log"].(bool)
+	if includeLog && cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+		return "", fmt.Errorf("include_provision_log runs a command on the node and requires readwrite or admin access level")
+	}
+	lines := defaultProvisionLines
+	if value, ok := params["tail_lines"].(float64); ok && value > 0 {
+		lines = min(int(value), maxProvisionLines)
+	}
+
+	ctx := context.Background()
+	cluster, err := reader.GetAKSCluster(ctx, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+	if cluster.Properties == nil || cluster.Properties.NodeResourceGroup == nil {
+		return "", fmt.Errorf("node resource group not found for AKS cluster")
+	}
+	node, err := nodes.Resolve(ctx, subID, *cluster.Properties.NodeResourceGroup, nodeName)
+	if err != nil {
+		return "", err
+	}
+	nodeRG, vmssName, instanceID := node.ResourceGroup, node.VMSS, node.InstanceID
+
+	view, err := reader.GetVMSSInstanceView(ctx, subID, nodeRG, vmssName, instanceID)
+	if err != nil {
+		return "", err
+	}
+	result := BootstrapDiagnosis{NodeName: node.NodeName, NodeResourceGroup: nodeRG, VMSS: vmssName, InstanceID: instanceID, ProviderID: node.ProviderID}
+	result.ProvisioningState, result.ProvisioningErrors = provisioningStatus(view.Statuses)
+	for _, ext := range view.Extensions {
+		if ext != nil {
+			result.Extensions = append(result.Extensions, extensionStatus(ext))
+		}
+	}
+
+	if includeLog {
+		script := fmt.Sprintf("tail -n %d %s", lines, provisionLogPath)
+		output, err := reader.RunVMSSShellScript(ctx, subID, nodeRG, vmssName, instanceID, script)
+		if err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("could not read %s: %v", provisionLogPath, err))
+		} else {
+			result.ProvisionLog = ParseRunCommandStdout(output)
+			result.ProvisionLogErrors = FindProvisionErrors(strings.Split(result.ProvisionLog, "\n"))
+		}
+	}
+	result.ExitCode, result.Cause = SummarizeBootstrapFailure(result)
+	if !includeLog && result.ExitCode != 0 {
+		result.Notes = append(result.Notes, fmt.Sprintf("call again with include_provision_log=true (readwrite access) to read %s from the node", provisionLogPath))
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bootstrap diagnosis to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// SummarizeBootstrapFailure returns the exit code of the failed bootstrap script and the most likely cause of
+// the failure, preferring the Custom Script Extension exit code over instance provisioning errors
+func SummarizeBootstrapFailure(d BootstrapDiagnosis) (int, string) {
+	for _, ext := range d.Extensions {
+		if ext.Name != cseExtensionName || ext.Level != string(armcompute.StatusLevelTypesError) {
+			continue
+		}
+		match := cseExitStatusPattern.FindStringSubmatch(ext.Message)
+		if match == nil {
+			return 0, "the node bootstrap script (vmssCSE) failed: " + firstLine(ext.Message)
+		}
+		code, _ := strconv.Atoi(match[1])
+		if cause, ok := cseExitCodes[code]; ok {
+			return code, fmt.Sprintf("the node bootstrap script (vmssCSE) exited with status %d: %s", code, cause)
+		}
+		return code, fmt.Sprintf("the node bootstrap script (vmssCSE) exited with status %d; see the provision log for the failing step", code)
+	}
+	if len(d.ProvisioningErrors) > 0 {
+		return 0, "instance provisioning failed: " + d.ProvisioningErrors[0]
+	}
+	for _, ext := range d.Extensions {
+		if ext.Level == string(armcompute.StatusLevelTypesError) {
+			return 0, fmt.Sprintf("extension %s failed: %s", ext.Name, firstLine(ext.Message))
+		}
+	}
+	for _, ext := range d.Extensions {
+		if ext.Name == cseExtensionName {
+			return 0, "the node bootstrap script (vmssCSE) succeeded; if the node is not Ready, check kubelet with get_aks_node_serial_log or the node's events"
+		}
+	}
+	return 0, "no bootstrap status was reported for the instance; the VM agent may not be running, check get_aks_node_serial_log"
+}
+
+// ParseRunCommandStdout returns the [stdout] section of a RunShellScript output message
+func ParseRunCommandStdout(message string) string {
+	stdout := message
+	if i := strings.Index(stdout, "[stdout]"); i >= 0 {
+		stdout = stdout[i+len("[stdout]"):]
+	}
+	if i := strings.Index(stdout, "[stderr]"); i >= 0 {
+		stdout = stdout[:i]
+	}
+	return strings.Trim(stdout, "\n")
+}
+
+// FindProvisionErrors returns the provision log lines that look like errors, keeping the last ones
+func FindProvisionErrors(lines []string) []string {
+	var errors []string
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		for _, pattern := range provisionErrorPatterns {
+			if strings.Contains(lower, pattern) {
+				errors = append(errors, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return errors[max(len(errors)-maxProvisionErrors, 0):]
+}
+
+// provisioningStatus returns the provisioning state of an instance and the messages of its error statuses
+func provisioningStatus(statuses []*armcompute.InstanceViewStatus) (string, []string) {
+	var state string
+	var errors []string
+	for _, status := range statuses {
+		if status == nil || status.Code == nil {
+			continue
+		}
+		if strings.HasPrefix(*status.Code, "ProvisioningState/") {
+			state = strings.TrimPrefix(*status.Code, "ProvisioningState/")
+		}
+		if status.Level != nil && *status.Level == armcompute.StatusLevelTypesError && status.Message != nil {
+			errors = append(errors, *status.Message)
+		}
+	}
+	return state, errors
+}
+
+// extensionStatus summarizes the instance view of an extension, keeping the end of long status messages
+// where the script's error output is
+func extensionStatus(ext *armcompute.VirtualMachineExtensionInstanceView) ExtensionStatus {
+	status := ExtensionStatus{Name: deref(ext.Name), Type: deref(ext.Type)}
+	for _, s := range ext.Statuses {
+		if s == nil {
+			continue
+		}
+		status.Status = deref(s.DisplayStatus)
+		if status.Status == "" {
+			status.Status = deref(s.Code)
+		}
+		if s.Level != nil {
+			status.Level = string(*s.Level)
+		}
+		message := deref(s.Message)
+		if len(message) > maxExtensionMessage {
+			message = "..." + message[len(message)-maxExtensionMessage:]
+		}
+		status.Message = message
+	}
+	return status
+}
+
+// firstLine returns the first line of a message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+// deref returns the value of a string pointer, or an empty string for nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
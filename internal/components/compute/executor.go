@@ -1,24 +1,60 @@
 package compute
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/command"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
 	"github.com/Azure/aks-mcp/internal/security"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
 )
 
 // ComputeOperationsExecutor handles execution of compute operations
-type ComputeOperationsExecutor struct{}
+type ComputeOperationsExecutor struct {
+	// auditLog records instance removals, including denied attempts; nil only writes them nowhere
+	auditLog *audit.Logger
+}
 
-// NewComputeOperationsExecutor creates a new ComputeOperationsExecutor
-func NewComputeOperationsExecutor() *ComputeOperationsExecutor {
-	return &ComputeOperationsExecutor{}
+// NewComputeOperationsExecutor creates a new ComputeOperationsExecutor recording instance removals in auditLog
+func NewComputeOperationsExecutor(auditLog *audit.Logger) *ComputeOperationsExecutor {
+	return &ComputeOperationsExecutor{auditLog: auditLog}
 }
 
-// Execute handles the compute operations
+// Execute handles the compute operations. Deleting or deallocating an instance is recorded in the audit log,
+// including attempts that are denied.
 func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	if !IsInstanceRemoval(operation) {
+		return e.execute(params, cfg, nil)
+	}
+
+	record := audit.Record{Tool: "az_compute_operations", Action: operation, Client: cfg.ClientName()}
+	record.Target, _ = params["node"].(string)
+	output, err := e.execute(params, cfg, &record)
+	switch {
+	case err == nil:
+		record.Outcome = audit.OutcomeSucceeded
+	case record.Outcome == "":
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+	default:
+		record.Error = err.Error()
+	}
+	if e.auditLog != nil {
+		e.auditLog.Log(record)
+	}
+	return output, err
+}
+
+// execute runs a compute operation. For instance removals record is filled in with the target and command, and
+// its outcome is set to failed when the command fails.
+func (e *ComputeOperationsExecutor) execute(params map[string]interface{}, cfg *config.ConfigData, record *audit.Record) (string, error) {
 	// Parse operation parameter
 	operation, ok := params["operation"].(string)
 	if !ok {
@@ -31,12 +67,6 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 		return "", fmt.Errorf("missing or invalid 'resource_type' parameter. Must be 'vm' (Virtual Machine) or 'vmss' (Virtual Machine Scale Set). Example: resource_type=\"vm\"")
 	}
 
-	// Parse args parameter
-	args, ok := params["args"].(string)
-	if !ok {
-		args = ""
-	}
-
 	// Validate access for this operation
 	if err := ValidateOperationAccess(operation, cfg); err != nil {
 		// Enhance access error with suggestions
@@ -52,11 +82,43 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 		return "", fmt.Errorf("%v. Valid operations for %s with %s access: %s", err, resourceType, cfg.AccessLevel, validOps)
 	}
 
+	// Combine the raw args with flags built from the structured parameters
+	args, err := azcli.ResolveArgs(params, GetOperationParameters(operation, resourceType))
+	if err != nil {
+		return "", err
+	}
+
+	// A node given by name or provider ID fills in its scale set, instance ID and resource group
+	if node, _ := params["node"].(string); node != "" {
+		var kubectlExecutor tools.CommandExecutor
+		if cfg.KubernetesAccessEnabled() {
+			kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+		}
+		args, err = AddNodeInstanceArgs(operation, resourceType, args, node, NewNodeResolver(nil, kubectlExecutor, cfg))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Removals act on one instance whose node is cordoned and drained, so the autoscaler replaces it safely
+	var removal *InstanceRemoval
+	if IsInstanceRemoval(operation) {
+		instance, err := ValidateSingleInstance(operation, args)
+		if err != nil {
+			return "", err
+		}
+		removal = &instance
+		record.Target = instance.ResourceGroup + "/" + instance.VMSS + "/" + instance.InstanceID
+	}
+
 	// Build full command
 	fullCommand := baseCommand
 	if args != "" {
 		fullCommand += " " + args
 	}
+	if record != nil {
+		record.Command = fullCommand
+	}
 
 	// Validate the command against security settings
 	validator := security.NewValidator(cfg.SecurityConfig)
@@ -65,6 +127,35 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 		return "", err
 	}
 
+	// AKS Automatic clusters lock down their node resource group
+	runAz := func(args string) (string, error) {
+		if err := validator.ValidateCommand("az "+args, security.CommandTypeAz); err != nil {
+			return "", err
+		}
+		return azcli.RunWithCache(command.NewShellProcess("az", cfg.Timeout), args, cfg)
+	}
+	if err := CheckAutomaticNodeResourceGroup(operation, args, runAz); err != nil {
+		return "", err
+	}
+	if removal != nil {
+		var kubectlRun func(args string) (string, error)
+		if cfg.KubernetesAccessEnabled() {
+			kubectlRun = NewNodeResolver(nil, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg).Kubectl
+		}
+		nodeName, subscription, err := CheckNodeDrained(kubectlRun, *removal)
+		if err != nil {
+			return "", err
+		}
+		if nodeName != "" {
+			record.Target += " (node " + nodeName + ")"
+		}
+		// Pin the command to the subscription of the checked scale set rather than the az default
+		if removal.Subscription == "" {
+			fullCommand += " --subscription " + subscription
+			record.Command = fullCommand
+		}
+	}
+
 	// Extract binary name and arguments from command
 	cmdParts := strings.Fields(fullCommand)
 	if len(cmdParts) == 0 {
@@ -74,11 +165,8 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 	// Use the first part as the binary name
 	binaryName := cmdParts[0]
 
-	// The rest of the command becomes the arguments
-	cmdArgs := ""
-	if len(cmdParts) > 1 {
-		cmdArgs = strings.Join(cmdParts[1:], " ")
-	}
+	// The rest of the command becomes the arguments, keeping whitespace inside quoted values
+	cmdArgs := strings.TrimSpace(strings.TrimPrefix(fullCommand, binaryName))
 
 	// If the command is not an az command, return an error
 	if binaryName != "az" {
@@ -87,8 +175,11 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 
 	// Execute the command
 	process := command.NewShellProcess(binaryName, cfg.Timeout)
-	result, err := process.Run(cmdArgs)
+	result, err := azcli.RunWithCache(process, cmdArgs, cfg)
 	if err != nil {
+		if record != nil {
+			record.Outcome = audit.OutcomeFailed
+		}
 		// Provide helpful error messages for common issues
 		errorMsg := fmt.Sprintf("Azure CLI command failed: %v", err)
 
@@ -104,6 +195,8 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 			errorMsg += "\nTip: Verify the VMSS name is correct and the instances are ready for reimaging"
 		case "run-command":
 			errorMsg += "\nTip: Ensure the resource is running and the command syntax is correct. Use --command-id RunShellScript for shell commands"
+		case string(OpVMSSDeleteInstance), string(OpVMSSDeallocateInstance):
+			errorMsg += "\nTip: Verify the instance ID with operation=\"get-instance-view\"; an instance that is already being deleted can't be removed again"
 		}
 
 		return "", fmt.Errorf("%s\nExecuted command: %s", errorMsg, fullCommand)
@@ -112,6 +205,64 @@ func (e *ComputeOperationsExecutor) Execute(params map[string]interface{}, cfg *
 	return strings.TrimSpace(result), nil
 }
 
+// AddNodeInstanceArgs adds the --name, instance ID and --resource-group flags of the scale set instance behind a
+// node to the args of a VMSS operation on instances. The flags already in args must match the node's instance.
+func AddNodeInstanceArgs(operation, resourceType, args, node string, nodes NodeResolver) (string, error) {
+	allowed := GetOperationParameters(operation, resourceType)
+	instanceFlag := ""
+	switch {
+	case resourceType != string(ResourceTypeVMSS):
+	case slices.Contains(allowed, "instance-id"):
+		instanceFlag = "--instance-id"
+	case slices.Contains(allowed, "instance-ids"):
+		instanceFlag = "--instance-ids"
+	}
+	if instanceFlag == "" {
+		return "", fmt.Errorf("node is only supported by the vmss operations on instances: show, get-instance-view, restart, reimage, run-command, delete-instance and deallocate-instance")
+	}
+
+	flags := commandFlags(args)
+	resourceGroup := flags["--resource-group"]
+	if resourceGroup == "" {
+		resourceGroup = flags["-g"]
+	}
+	instance, err := nodes.Resolve(context.Background(), flags["--subscription"], resourceGroup, node)
+	if err != nil {
+		return "", err
+	}
+	if resourceGroup != "" && !strings.EqualFold(resourceGroup, instance.ResourceGroup) {
+		return "", fmt.Errorf("node %s is in resource group %s, not %s", instance.NodeName, instance.ResourceGroup, resourceGroup)
+	}
+	if name := firstNonEmpty(flags["--name"], flags["-n"]); name != "" && !strings.EqualFold(name, instance.VMSS) {
+		return "", fmt.Errorf("node %s is in scale set %s, not %s", instance.NodeName, instance.VMSS, name)
+	}
+	if value, ok := flags[instanceFlag]; ok && value != instance.InstanceID {
+		return "", fmt.Errorf("node %s is instance %s, which conflicts with %s %s", instance.NodeName, instance.InstanceID, instanceFlag, value)
+	}
+
+	var added []string
+	if flags["--name"] == "" && flags["-n"] == "" {
+		added = append(added, "--name", azcli.QuoteArg(instance.VMSS))
+	}
+	if _, ok := flags[instanceFlag]; !ok {
+		added = append(added, instanceFlag, azcli.QuoteArg(instance.InstanceID))
+	}
+	if resourceGroup == "" {
+		added = append(added, "--resource-group", azcli.QuoteArg(instance.ResourceGroup))
+	}
+	return strings.TrimSpace(args + " " + strings.Join(added, " ")), nil
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 // getSuggestedOperations returns a helpful list of valid operations for the given resource type and access level
 func getSuggestedOperations(resourceType, accessLevel string) string {
 	var operations []string
@@ -131,7 +282,10 @@ func getSuggestedOperations(resourceType, accessLevel string) string {
 		}
 	}
 
-	// No admin operations - all unsafe operations removed for AKS safety
+	// Admin operations remove a single instance
+	if accessLevel == "admin" && resourceType == "vmss" {
+		operations = append(operations, string(OpVMSSDeleteInstance), string(OpVMSSDeallocateInstance))
+	}
 
 	return strings.Join(operations, ", ")
 }
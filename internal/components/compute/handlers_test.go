@@ -1,10 +1,17 @@
 package compute
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
 )
 
 // TestExtractAKSParameters tests the parameter extraction function
@@ -107,3 +114,180 @@ func TestGetAKSVMSSInfoHandler(t *testing.T) {
 	// and would make actual Azure API calls. The handler creation test above is sufficient
 	// to verify the basic functionality works.
 }
+
+type fakeSerialLogReader struct {
+	bootDiagnostics bool
+	log             string
+	enabled         []string
+	read            []string
+}
+
+func (f *fakeSerialLogReader) GetAKSCluster(_ context.Context, _, _, _ string) (*armcontainerservice.ManagedCluster, error) {
+	return &armcontainerservice.ManagedCluster{Properties: &armcontainerservice.ManagedClusterProperties{NodeResourceGroup: to.Ptr("MC_rg")}}, nil
+}
+
+func (f *fakeSerialLogReader) GetVMSS(_ context.Context, _, rg, name string) (*armcompute.VirtualMachineScaleSet, error) {
+	if rg != "MC_rg" || name != "aks-nodepool1-12345678-vmss" {
+		return nil, fmt.Errorf("unexpected scale set %s/%s", rg, name)
+	}
+	return &armcompute.VirtualMachineScaleSet{Properties: &armcompute.VirtualMachineScaleSetProperties{
+		VirtualMachineProfile: &armcompute.VirtualMachineScaleSetVMProfile{
+			DiagnosticsProfile: &armcompute.DiagnosticsProfile{BootDiagnostics: &armcompute.BootDiagnostics{Enabled: to.Ptr(f.bootDiagnostics)}},
+		},
+	}}, nil
+}
+
+func (f *fakeSerialLogReader) EnableVMSSBootDiagnostics(_ context.Context, _, _, vmss, instanceID string) error {
+	f.enabled = append(f.enabled, vmss+"/"+instanceID)
+	f.bootDiagnostics = true
+	return nil
+}
+
+func (f *fakeSerialLogReader) GetVMSSSerialLog(_ context.Context, _, _, vmss, instanceID string) (string, error) {
+	f.read = append(f.read, vmss+"/"+instanceID)
+	return f.log, nil
+}
+
+// TestHandleNodeSerialLog tests instance resolution, the boot diagnostics gate, tailing and boot problem detection
+func TestHandleNodeSerialLog(t *testing.T) {
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("[  %d.000000] boot line %d", i, i))
+	}
+	lines = append(lines, "[FAILED] Failed to start kubelet.service - Kubelet.", "[  301.000000] EXT4-fs error (device sda1): ext4_lookup:1785")
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "cluster",
+		"node_name": "aks-nodepool1-12345678-vmss00000a", "tail_lines": float64(50),
+	}
+
+	reader := &fakeSerialLogReader{log: strings.Join(lines, "\r\n") + "\r\n"}
+	if _, err := HandleNodeSerialLog(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readwrite"}); err == nil || !strings.Contains(err.Error(), "enable_boot_diagnostics") {
+		t.Errorf("Expected an error suggesting enable_boot_diagnostics, got %v", err)
+	}
+	params["enable_boot_diagnostics"] = true
+	if _, err := HandleNodeSerialLog(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readonly"}); err == nil || len(reader.enabled) != 0 {
+		t.Errorf("Expected readonly access not to enable boot diagnostics, got %v", err)
+	}
+
+	output, err := HandleNodeSerialLog(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readwrite"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result SerialLogResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(reader.enabled) != 1 || reader.enabled[0] != "aks-nodepool1-12345678-vmss/10" || !result.BootDiagnosticsEnabled {
+		t.Errorf("Expected boot diagnostics enabled on instance 10, got %v", reader.enabled)
+	}
+	if result.TotalLines != 302 || result.ReturnedLines != 50 || !strings.HasSuffix(result.Log, "ext4_lookup:1785") {
+		t.Errorf("Expected the last 50 of 302 lines, got %d of %d", result.ReturnedLines, result.TotalLines)
+	}
+	if len(result.BootProblems) != 2 || !strings.Contains(result.BootProblems[0], "kubelet.service") {
+		t.Errorf("Expected the kubelet and EXT4 lines as boot problems, got %v", result.BootProblems)
+	}
+}
+
+// TestParseVMSSNodeName tests deriving the scale set and instance ID from node names
+func TestParseVMSSNodeName(t *testing.T) {
+	vmss, instance, err := ParseVMSSNodeName("aks-userpool-87654321-vmss0000zz")
+	if err != nil || vmss != "aks-userpool-87654321-vmss" || instance != "1295" {
+		t.Errorf("Unexpected result %s %s %v", vmss, instance, err)
+	}
+	for _, name := range []string{"aksnpwin000000", "aks-nodepool1-12345678-vmss", "node-1"} {
+		if _, _, err := ParseVMSSNodeName(name); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+type fakeBootstrapReader struct {
+	view    *armcompute.VirtualMachineScaleSetVMInstanceView
+	scripts []string
+}
+
+func (f *fakeBootstrapReader) GetAKSCluster(_ context.Context, _, _, _ string) (*armcontainerservice.ManagedCluster, error) {
+	return &armcontainerservice.ManagedCluster{Properties: &armcontainerservice.ManagedClusterProperties{NodeResourceGroup: to.Ptr("MC_rg")}}, nil
+}
+
+func (f *fakeBootstrapReader) GetVMSSInstanceView(_ context.Context, _, rg, vmss, instanceID string) (*armcompute.VirtualMachineScaleSetVMInstanceView, error) {
+	if rg != "MC_rg" || vmss != "aks-nodepool1-12345678-vmss" || instanceID != "3" {
+		return nil, fmt.Errorf("unexpected instance %s/%s/%s", rg, vmss, instanceID)
+	}
+	return f.view, nil
+}
+
+func (f *fakeBootstrapReader) RunVMSSShellScript(_ context.Context, _, _, _, _, script string) (string, error) {
+	f.scripts = append(f.scripts, script)
+	return "Enable succeeded: \n[stdout]\n+ retrycmd_if_failure 50 1 5 nc -vz mcr.microsoft.com 443\nnc: connect to mcr.microsoft.com port 443 (tcp) timed out\nExit code: 50\n\n[stderr]\n", nil
+}
+
+// TestHandleNodeBootstrapDiagnostics tests the CSE exit code summary and the gated provision log excerpt
+func TestHandleNodeBootstrapDiagnostics(t *testing.T) {
+	errorLevel := armcompute.StatusLevelTypesError
+	reader := &fakeBootstrapReader{view: &armcompute.VirtualMachineScaleSetVMInstanceView{
+		Statuses: []*armcompute.InstanceViewStatus{
+			{Code: to.Ptr("ProvisioningState/failed/VMExtensionProvisioningError"), Level: &errorLevel,
+				Message: to.Ptr("VM has reported a failure when processing extension 'vmssCSE'.")},
+		},
+		Extensions: []*armcompute.VirtualMachineExtensionInstanceView{
+			{Name: to.Ptr("vmssCSE"), Type: to.Ptr("Microsoft.Azure.Extensions.CustomScript"), Statuses: []*armcompute.InstanceViewStatus{
+				{Code: to.Ptr("ProvisioningState/failed/50"), DisplayStatus: to.Ptr("Provisioning failed"), Level: &errorLevel,
+					Message: to.Ptr("Enable failed: failed to execute command: command terminated with exit status=50\n[stdout]\n...")},
+			}},
+		},
+	}}
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "cluster",
+		"node_name": "aks-nodepool1-12345678-vmss000003",
+	}
+
+	output, err := HandleNodeBootstrapDiagnostics(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readonly"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result BootstrapDiagnosis
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if result.ExitCode != 50 || !strings.Contains(result.Cause, "outbound connectivity") || result.ProvisioningState != "failed/VMExtensionProvisioningError" {
+		t.Errorf("Unexpected diagnosis %+v", result)
+	}
+	if len(result.Extensions) != 1 || result.Extensions[0].Level != "Error" || len(reader.scripts) != 0 {
+		t.Errorf("Expected the extension status without running commands, got %+v %v", result.Extensions, reader.scripts)
+	}
+
+	params["include_provision_log"] = true
+	if _, err := HandleNodeBootstrapDiagnostics(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readonly"}); err == nil {
+		t.Error("Expected readonly access to be refused reading the provision log")
+	}
+	output, err = HandleNodeBootstrapDiagnostics(params, reader, NodeResolver{}, &config.ConfigData{AccessLevel: "readwrite"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result = BootstrapDiagnosis{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(reader.scripts) != 1 || reader.scripts[0] != "tail -n 100 /var/log/azure/cluster-provision.log" {
+		t.Errorf("Unexpected scripts %v", reader.scripts)
+	}
+	if strings.Contains(result.ProvisionLog, "[stdout]") || len(result.ProvisionLogErrors) != 2 {
+		t.Errorf("Expected the stdout excerpt with two error lines, got %q %v", result.ProvisionLog, result.ProvisionLogErrors)
+	}
+}
+
+// TestSummarizeBootstrapFailure tests the cause reported without a CSE exit code
+func TestSummarizeBootstrapFailure(t *testing.T) {
+	succeeded := BootstrapDiagnosis{Extensions: []ExtensionStatus{{Name: "vmssCSE", Status: "Provisioning succeeded", Level: "Info"}}}
+	if code, cause := SummarizeBootstrapFailure(succeeded); code != 0 || !strings.Contains(cause, "succeeded") {
+		t.Errorf("Unexpected summary %d %s", code, cause)
+	}
+	unknown := BootstrapDiagnosis{Extensions: []ExtensionStatus{{Name: "vmssCSE", Level: "Error", Message: "Enable failed: command terminated with exit status=199"}}}
+	if code, cause := SummarizeBootstrapFailure(unknown); code != 199 || !strings.Contains(cause, "provision log") {
+		t.Errorf("Unexpected summary %d %s", code, cause)
+	}
+	if _, cause := SummarizeBootstrapFailure(BootstrapDiagnosis{}); !strings.Contains(cause, "VM agent") {
+		t.Errorf("Unexpected summary %s", cause)
+	}
+}
@@ -0,0 +1,206 @@
+package compute
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// maxListedPods bounds the pods named when a node is not drained
+const maxListedPods = 5
+
+// instanceIDPattern matches a single scale set instance ID
+var instanceIDPattern = regexp.MustCompile(`^\d+$`)
+
+// instanceRemovalFlags are the flags a delete-instance or deallocate-instance command may carry. Flags that select
+// resources another way, such as --ids, are rejected so the command can only name one instance.
+var instanceRemovalFlags = map[string]bool{
+	"--name": true, "-n": true, "--resource-group": true, "-g": true, "--instance-ids": true,
+	"--no-wait": true, "--subscription": true, "--output": true, "-o": true, "--only-show-errors": true,
+}
+
+// IsInstanceRemoval reports whether an operation deletes or deallocates a scale set instance
+func IsInstanceRemoval(operation string) bool {
+	return operation == string(OpVMSSDeleteInstance) || operation == string(OpVMSSDeallocateInstance)
+}
+
+// InstanceRemoval is the scale set instance a delete-instance or deallocate-instance command acts on. Subscription
+// is empty when the command has no --subscription.
+type InstanceRemoval struct {
+	Subscription  string
+	ResourceGroup string
+	VMSS          string
+	InstanceID    string
+}
+
+// ValidateSingleInstance checks that the args of an instance removal name one scale set, its resource group and
+// exactly one instance ID, and returns them. Without --instance-ids az vmss deallocate acts on every instance.
+func ValidateSingleInstance(operation, args string) (InstanceRemoval, error) {
+	var removal InstanceRemoval
+	parts, err := shlex.Split(args)
+	if err != nil {
+		return removal, fmt.Errorf("invalid arguments: %v", err)
+	}
+	var instanceIDs []string
+	for i := 0; i < len(parts); i++ {
+		name, value, hasValue := strings.Cut(parts[i], "=")
+		if !strings.HasPrefix(name, "-") {
+			return removal, fmt.Errorf("unexpected argument %s: %s takes flags only", parts[i], operation)
+		}
+		if !instanceRemovalFlags[name] {
+			return removal, fmt.Errorf("flag %s is not allowed with %s, which acts on a single instance given by --name, --resource-group and --instance-ids", name, operation)
+		}
+		// Collect every value, since az accepts a space separated list after --instance-ids
+		var values []string
+		if hasValue {
+			values = append(values, value)
+		}
+		for i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "-") {
+			values = append(values, parts[i+1])
+			i++
+		}
+		switch name {
+		case "--name", "-n":
+			removal.VMSS = strings.Join(values, " ")
+		case "--resource-group", "-g":
+			removal.ResourceGroup = strings.Join(values, " ")
+		case "--subscription":
+			removal.Subscription = strings.Join(values, " ")
+		case "--instance-ids":
+			instanceIDs = append(instanceIDs, values...)
+		}
+	}
+	if removal.VMSS == "" || removal.ResourceGroup == "" {
+		return removal, fmt.Errorf("%s requires --name and --resource-group of the scale set, or node", operation)
+	}
+	if len(instanceIDs) != 1 || !instanceIDPattern.MatchString(instanceIDs[0]) {
+		return removal, fmt.Errorf("%s acts on exactly one instance and never the whole scale set: give one numeric --instance-ids value, or node", operation)
+	}
+	removal.InstanceID = instanceIDs[0]
+	return removal, nil
+}
+
+// nodeState is the part of kubectl get node -o json the drain check reads
+type nodeState struct {
+	Spec struct {
+		Unschedulable bool `json:"unschedulable"`
+	} `json:"spec"`
+}
+
+// podsOnNode is the part of kubectl get pods -o json the drain check reads
+type podsOnNode struct {
+	Items []struct {
+		Metadata struct {
+			Name              string            `json:"name"`
+			Namespace         string            `json:"namespace"`
+			Annotations       map[string]string `json:"annotations"`
+			DeletionTimestamp string            `json:"deletionTimestamp"`
+			OwnerReferences   []struct {
+				Kind string `json:"kind"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// CheckNodeDrained checks that the node of a scale set instance is cordoned and runs no pods other than DaemonSet
+// and static pods, so removing the instance does not take workloads down with it, and returns the node name and
+// the subscription of the scale set. Pods that finished or are being deleted do not count.
+//
+// The scale set must belong to the cluster kubectl reaches: at least one node's provider ID must name its
+// resource group and scale set, and its subscription when the removal gives one. Otherwise the kubeconfig may be
+// for another cluster, and the check fails rather than passing an instance it cannot see. An instance of the scale
+// set whose node is not in the cluster, because it never joined or its Node object was deleted, passes with an
+// empty node name. kubectl runs kubectl with the command without the leading kubectl.
+func CheckNodeDrained(kubectl func(args string) (string, error), removal InstanceRemoval) (string, string, error) {
+	if kubectl == nil {
+		return "", "", fmt.Errorf("removing an instance requires Kubernetes access to check that its node is cordoned and drained")
+	}
+	// The node is found by provider ID, which also covers Windows nodes whose names don't encode their instance
+	output, err := kubectl("get nodes --no-headers -o " + nodeColumns)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodeName, subscription := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		match := scaleSetProviderIDPattern.FindStringSubmatch(fields[1])
+		if match == nil || !strings.EqualFold(match[2], removal.ResourceGroup) || !strings.EqualFold(match[3], removal.VMSS) {
+			continue
+		}
+		if removal.Subscription != "" && !strings.EqualFold(match[1], removal.Subscription) {
+			continue
+		}
+		subscription = match[1]
+		if match[4] == removal.InstanceID {
+			nodeName = fields[0]
+		}
+	}
+	if subscription == "" {
+		return "", "", fmt.Errorf("no node of the cluster in the current kubeconfig context belongs to scale set %s in resource group %s%s: "+
+			"the kubeconfig may be for another cluster, so the instance cannot be checked", removal.VMSS, removal.ResourceGroup, subscriptionSuffix(removal.Subscription))
+	}
+	if nodeName == "" {
+		return "", subscription, nil
+	}
+	instanceID := removal.InstanceID
+
+	output, err = kubectl("get node " + nodeName + " -o json")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read node %s: %v", nodeName, err)
+	}
+	var node nodeState
+	if err := json.Unmarshal([]byte(output), &node); err != nil {
+		return "", "", fmt.Errorf("failed to parse node %s: %v", nodeName, err)
+	}
+	if !node.Spec.Unschedulable {
+		return "", "", fmt.Errorf("node %s of instance %s is not cordoned: cordon and drain it first (aks_node_drain)", nodeName, instanceID)
+	}
+
+	output, err = kubectl("get pods --all-namespaces --field-selector spec.nodeName=" + nodeName + " -o json")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list the pods on node %s: %v", nodeName, err)
+	}
+	var pods podsOnNode
+	if err := json.Unmarshal([]byte(output), &pods); err != nil {
+		return "", "", fmt.Errorf("failed to parse the pods on node %s: %v", nodeName, err)
+	}
+	var remaining []string
+	for _, pod := range pods.Items {
+		if pod.Metadata.DeletionTimestamp != "" || pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		if _, mirror := pod.Metadata.Annotations["kubernetes.io/config.mirror"]; mirror {
+			continue
+		}
+		daemon := false
+		for _, owner := range pod.Metadata.OwnerReferences {
+			daemon = daemon || owner.Kind == "DaemonSet"
+		}
+		if !daemon {
+			remaining = append(remaining, pod.Metadata.Namespace+"/"+pod.Metadata.Name)
+		}
+	}
+	if len(remaining) > 0 {
+		listed := remaining[:min(len(remaining), maxListedPods)]
+		return "", "", fmt.Errorf("node %s of instance %s is not drained: %d pods still run on it (%s): drain it first (aks_node_drain)",
+			nodeName, instanceID, len(remaining), strings.Join(listed, ", "))
+	}
+	return nodeName, subscription, nil
+}
+
+// subscriptionSuffix names a subscription in errors
+func subscriptionSuffix(subscription string) string {
+	if subscription == "" {
+		return ""
+	}
+	return " of subscription " + subscription
+}
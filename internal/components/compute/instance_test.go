@@ -0,0 +1,117 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+func TestValidateSingleInstance(t *testing.T) {
+	removal, err := ValidateSingleInstance("delete-instance", "--name aks-nodepool1-12345678-vmss --resource-group MC_rg --instance-ids 3 --no-wait --subscription sub")
+	want := InstanceRemoval{Subscription: "sub", ResourceGroup: "MC_rg", VMSS: "aks-nodepool1-12345678-vmss", InstanceID: "3"}
+	if err != nil || removal != want {
+		t.Errorf("Unexpected result %+v (%v)", removal, err)
+	}
+	if removal, err := ValidateSingleInstance("deallocate-instance", "-n vmss -g rg --instance-ids=7"); err != nil || removal.InstanceID != "7" || removal.Subscription != "" {
+		t.Errorf("Expected short flags and --instance-ids=7 to be accepted, got %+v (%v)", removal, err)
+	}
+
+	for _, args := range []string{
+		"--name vmss --resource-group rg",
+		"--name vmss --resource-group rg --instance-ids 1 2",
+		"--name vmss --resource-group rg --instance-ids 1 --instance-ids 2",
+		"--name vmss --resource-group rg --instance-ids '*'",
+		"--ids /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss --instance-ids 1",
+		"--resource-group rg --instance-ids 1",
+		"extra --name vmss --resource-group rg --instance-ids 1",
+	} {
+		if _, err := ValidateSingleInstance("deallocate-instance", args); err == nil {
+			t.Errorf("%s: expected an error", args)
+		}
+	}
+}
+
+func TestCheckNodeDrained(t *testing.T) {
+	nodes := "aks-nodepool1-12345678-vmss000003   azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/3\n"
+	kubectl := func(node, pods string) func(string) (string, error) {
+		return func(args string) (string, error) {
+			switch {
+			case strings.HasPrefix(args, "get nodes"):
+				return nodes, nil
+			case strings.HasPrefix(args, "get node aks-nodepool1-12345678-vmss000003"):
+				return node, nil
+			case strings.HasPrefix(args, "get pods --all-namespaces --field-selector spec.nodeName=aks-nodepool1-12345678-vmss000003"):
+				return pods, nil
+			}
+			return "", fmt.Errorf("unexpected command %s", args)
+		}
+	}
+	drained := `{"items": [
+		{"metadata": {"name": "kube-proxy-x", "namespace": "kube-system", "ownerReferences": [{"kind": "DaemonSet"}]}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "web-0", "namespace": "default", "deletionTimestamp": "2025-01-01T00:00:00Z"}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "job-1", "namespace": "default"}, "status": {"phase": "Succeeded"}}]}`
+
+	removal := InstanceRemoval{ResourceGroup: "MC_rg", VMSS: "aks-nodepool1-12345678-vmss", InstanceID: "3"}
+	name, subscription, err := CheckNodeDrained(kubectl(`{"spec": {"unschedulable": true}}`, drained), removal)
+	if err != nil || name != "aks-nodepool1-12345678-vmss000003" || subscription != "sub" {
+		t.Errorf("Expected a drained node to pass, got %s %s (%v)", name, subscription, err)
+	}
+	if _, _, err := CheckNodeDrained(kubectl(`{"spec": {}}`, drained), removal); err == nil || !strings.Contains(err.Error(), "not cordoned") {
+		t.Errorf("Expected an uncordoned node to be rejected, got %v", err)
+	}
+	running := `{"items": [{"metadata": {"name": "api-1", "namespace": "prod", "ownerReferences": [{"kind": "ReplicaSet"}]}, "status": {"phase": "Running"}}]}`
+	if _, _, err := CheckNodeDrained(kubectl(`{"spec": {"unschedulable": true}}`, running), removal); err == nil || !strings.Contains(err.Error(), "prod/api-1") {
+		t.Errorf("Expected a node with workloads to be rejected, got %v", err)
+	}
+
+	// An instance of the cluster's scale set that is not a node has nothing to drain
+	missing := removal
+	missing.InstanceID = "4"
+	if name, subscription, err := CheckNodeDrained(kubectl("", ""), missing); err != nil || name != "" || subscription != "sub" {
+		t.Errorf("Expected an instance without a node to pass, got %s %s (%v)", name, subscription, err)
+	}
+	// A scale set the cluster has no nodes in may belong to another cluster, so it fails closed
+	for _, other := range []InstanceRemoval{
+		{ResourceGroup: "MC_other", VMSS: "aks-nodepool1-12345678-vmss", InstanceID: "3"},
+		{ResourceGroup: "MC_rg", VMSS: "aks-userpool-12345678-vmss", InstanceID: "3"},
+		{Subscription: "other-sub", ResourceGroup: "MC_rg", VMSS: "aks-nodepool1-12345678-vmss", InstanceID: "3"},
+	} {
+		if _, _, err := CheckNodeDrained(kubectl("", ""), other); err == nil || !strings.Contains(err.Error(), "another cluster") {
+			t.Errorf("Expected %+v to be refused, got %v", other, err)
+		}
+	}
+	removal.Subscription = "SUB"
+	if _, _, err := CheckNodeDrained(kubectl(`{"spec": {"unschedulable": true}}`, drained), removal); err != nil {
+		t.Errorf("Expected a matching subscription to pass, got %v", err)
+	}
+	if _, _, err := CheckNodeDrained(nil, removal); err == nil {
+		t.Error("Expected the check to require Kubernetes access")
+	}
+}
+
+func TestExecuteAuditsInstanceRemoval(t *testing.T) {
+	auditLog := audit.NewLogger(store.NewMemoryStore())
+	executor := NewComputeOperationsExecutor(auditLog)
+
+	params := map[string]interface{}{"operation": "delete-instance", "resource_type": "vmss", "args": "--name vmss --resource-group rg --instance-ids 3"}
+	if _, err := executor.Execute(params, &config.ConfigData{AccessLevel: "readwrite"}); err == nil {
+		t.Fatal("Expected delete-instance to require admin access")
+	}
+	params["args"] = "--name vmss --resource-group rg --instance-ids 3 4"
+	if _, err := executor.Execute(params, &config.ConfigData{AccessLevel: "admin"}); err == nil {
+		t.Fatal("Expected more than one instance to be rejected")
+	}
+
+	records, err := auditLog.Records()
+	if err != nil {
+		t.Fatalf("Records failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Outcome != audit.OutcomeDenied || records[1].Outcome != audit.OutcomeDenied ||
+		records[1].Action != "delete-instance" || !strings.Contains(records[1].Error, "exactly one instance") {
+		t.Errorf("Expected both attempts to be audited as denied, got %+v", records)
+	}
+}
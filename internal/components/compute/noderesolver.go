@@ -0,0 +1,184 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// nodeColumns lists the name and provider ID of each node
+const nodeColumns = `custom-columns=NAME:.metadata.name,PROVIDER:.spec.providerID`
+
+var (
+	// scaleSetProviderIDPattern matches the provider ID or resource ID of a scale set instance
+	scaleSetProviderIDPattern = regexp.MustCompile(`(?i)^(?:azure://)?/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft\.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/(\d+)$`)
+	// standaloneProviderIDPattern matches the provider ID of a standalone VM, as used by node auto provisioning
+	standaloneProviderIDPattern = regexp.MustCompile(`(?i)^(?:azure://)?/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/virtualMachines/([^/]+)$`)
+	// instanceNamePattern matches a scale set instance given as <vmss>_<id>, the VM name shown in the portal, or <vmss>/<id>
+	instanceNamePattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)[_/](\d+)$`)
+)
+
+// VMSSInstanceGetter reads a scale set instance. *azureclient.AzureClient implements it.
+type VMSSInstanceGetter interface {
+	GetVMSSInstance(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error)
+}
+
+// NodeInstance is a node with the scale set instance behind it
+type NodeInstance struct {
+	NodeName      string `json:"nodeName"`
+	ResourceGroup string `json:"resourceGroup"`
+	VMSS          string `json:"vmss"`
+	InstanceID    string `json:"instanceId"`
+	ProviderID    string `json:"providerId"`
+}
+
+// NodeResolver maps between node names, scale set instance IDs and provider IDs. Kubectl runs kubectl with the
+// command without the leading kubectl and Instances reads instances with the Azure SDK; both are optional. Without
+// kubectl, node names are decoded as Linux scale set node names and, without either, the node name of an instance
+// is derived the same way.
+type NodeResolver struct {
+	Kubectl   func(args string) (string, error)
+	Instances VMSSInstanceGetter
+}
+
+// NewNodeResolver returns a resolver reading instances with instances and nodes with kubectlExecutor, either of
+// which may be nil
+func NewNodeResolver(instances VMSSInstanceGetter, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) NodeResolver {
+	resolver := NodeResolver{Instances: instances}
+	if kubectlExecutor != nil {
+		resolver.Kubectl = func(args string) (string, error) {
+			return kubectlExecutor.Execute(map[string]interface{}{"command": args}, cfg)
+		}
+	}
+	return resolver
+}
+
+// newClusterNodeResolver returns a resolver reading instances with the Azure client and, when Kubernetes access is
+// enabled, nodes with kubectl
+func newClusterNodeResolver(client *azureclient.AzureClient, cfg *config.ConfigData) NodeResolver {
+	var instances VMSSInstanceGetter
+	if client != nil {
+		instances = client
+	}
+	var kubectlExecutor tools.CommandExecutor
+	if cfg.KubernetesAccessEnabled() {
+		kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+	}
+	return NewNodeResolver(instances, kubectlExecutor, cfg)
+}
+
+// Resolve returns the node and scale set instance for an identifier, which is a node name, a provider ID such as
+// azure:///subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.Compute/virtualMachineScaleSets/<vmss>/virtualMachines/3,
+// the instance's resource ID, or <vmss>_<id>. Scale sets are looked up in the node resource group unless the
+// identifier names another one.
+func (r NodeResolver) Resolve(ctx context.Context, subscriptionID, nodeResourceGroup, identifier string) (*NodeInstance, error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return nil, fmt.Errorf("missing node identifier")
+	}
+	if match := standaloneProviderIDPattern.FindStringSubmatch(identifier); match != nil {
+		return nil, fmt.Errorf("%s is the standalone virtual machine %s, not a scale set instance; use resource_type=vm with its name", identifier, match[1])
+	}
+
+	if match := scaleSetProviderIDPattern.FindStringSubmatch(identifier); match != nil {
+		instance := &NodeInstance{ResourceGroup: match[2], VMSS: match[3], InstanceID: match[4]}
+		instance.ProviderID = ScaleSetProviderID(match[1], instance.ResourceGroup, instance.VMSS, instance.InstanceID)
+		instance.NodeName = r.nodeName(ctx, match[1], instance)
+		return instance, nil
+	}
+	if match := instanceNamePattern.FindStringSubmatch(identifier); match != nil {
+		if nodeResourceGroup == "" {
+			return nil, fmt.Errorf("the resource group of scale set %s is needed to resolve instance %s", match[1], match[2])
+		}
+		instance := &NodeInstance{ResourceGroup: nodeResourceGroup, VMSS: match[1], InstanceID: match[2]}
+		instance.ProviderID = ScaleSetProviderID(subscriptionID, instance.ResourceGroup, instance.VMSS, instance.InstanceID)
+		instance.NodeName = r.nodeName(ctx, subscriptionID, instance)
+		return instance, nil
+	}
+
+	// A node name: its provider ID comes from the cluster, which also covers Windows nodes whose names don't
+	// encode their scale set. Nodes that never joined aren't listed, so Linux names are decoded as a fallback.
+	if providerID := r.providerIDs()[identifier]; providerID != "" {
+		match := scaleSetProviderIDPattern.FindStringSubmatch(providerID)
+		if match == nil {
+			return nil, fmt.Errorf("node %s is not a scale set instance (provider ID %s)", identifier, providerID)
+		}
+		return &NodeInstance{NodeName: identifier, ResourceGroup: match[2], VMSS: match[3], InstanceID: match[4], ProviderID: providerID}, nil
+	}
+	vmssName, instanceID, err := ParseVMSSNodeName(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve node %s: %v", identifier, err)
+	}
+	if nodeResourceGroup == "" {
+		return nil, fmt.Errorf("the node resource group is needed to resolve node %s", identifier)
+	}
+	return &NodeInstance{
+		NodeName:      identifier,
+		ResourceGroup: nodeResourceGroup,
+		VMSS:          vmssName,
+		InstanceID:    instanceID,
+		ProviderID:    ScaleSetProviderID(subscriptionID, nodeResourceGroup, vmssName, instanceID),
+	}, nil
+}
+
+// nodeName returns the node of a scale set instance from the cluster's provider IDs, then the instance's computer
+// name, which AKS uses as the node name, then the Linux node name derived from the scale set and instance ID
+func (r NodeResolver) nodeName(ctx context.Context, subscriptionID string, instance *NodeInstance) string {
+	for name, providerID := range r.providerIDs() {
+		if strings.EqualFold(providerID, instance.ProviderID) {
+			return name
+		}
+	}
+	if r.Instances != nil {
+		vm, err := r.Instances.GetVMSSInstance(ctx, subscriptionID, instance.ResourceGroup, instance.VMSS, instance.InstanceID)
+		if err == nil && vm.Properties != nil && vm.Properties.OSProfile != nil && vm.Properties.OSProfile.ComputerName != nil {
+			return *vm.Properties.OSProfile.ComputerName
+		}
+	}
+	return FormatVMSSNodeName(instance.VMSS, instance.InstanceID)
+}
+
+// providerIDs returns the provider ID of each node by name, or nil without kubectl or when the nodes can't be listed
+func (r NodeResolver) providerIDs() map[string]string {
+	if r.Kubectl == nil {
+		return nil
+	}
+	output, err := r.Kubectl("get nodes --no-headers -o " + nodeColumns)
+	if err != nil {
+		return nil
+	}
+	providerIDs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] != "<none>" {
+			providerIDs[fields[0]] = fields[1]
+		}
+	}
+	return providerIDs
+}
+
+// ScaleSetProviderID returns the Kubernetes provider ID of a scale set instance
+func ScaleSetProviderID(subscriptionID, resourceGroup, vmssName, instanceID string) string {
+	return fmt.Sprintf("azure:///subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/%s",
+		subscriptionID, resourceGroup, vmssName, instanceID)
+}
+
+// FormatVMSSNodeName returns the name of a Linux scale set node, the scale set name followed by the instance ID in
+// six base 36 digits. It is the inverse of ParseVMSSNodeName.
+func FormatVMSSNodeName(vmssName, instanceID string) string {
+	instance, err := strconv.ParseInt(instanceID, 10, 64)
+	if err != nil {
+		return vmssName + "_" + instanceID
+	}
+	suffix := strconv.FormatInt(instance, 36)
+	return vmssName + strings.Repeat("0", max(6-len(suffix), 0)) + suffix
+}
@@ -0,0 +1,122 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+)
+
+const (
+	linuxProviderID   = "azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/10"
+	windowsProviderID = "azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-win-87654321-vmss/virtualMachines/2"
+)
+
+// fakeNodes lists two nodes, one of them Windows, and records the kubectl commands run
+type fakeNodes struct {
+	commands []string
+}
+
+func (f *fakeNodes) run(args string) (string, error) {
+	f.commands = append(f.commands, args)
+	if !strings.HasPrefix(args, "get nodes") {
+		return "", fmt.Errorf("unexpected command %s", args)
+	}
+	return "aks-nodepool1-12345678-vmss00000a   " + linuxProviderID + "\naksnpwin000002   " + windowsProviderID + "\n", nil
+}
+
+// fakeInstances returns the computer name of a scale set instance
+type fakeInstances struct {
+	computerName string
+}
+
+func (f fakeInstances) GetVMSSInstance(_ context.Context, _, _, _, _ string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	return &armcompute.VirtualMachineScaleSetVM{Properties: &armcompute.VirtualMachineScaleSetVMProperties{
+		OSProfile: &armcompute.OSProfile{ComputerName: to.Ptr(f.computerName)},
+	}}, nil
+}
+
+// TestNodeResolverResolve tests resolving node names, provider IDs and instance names in both directions
+func TestNodeResolverResolve(t *testing.T) {
+	ctx := context.Background()
+	nodes := &fakeNodes{}
+	withKubectl := NodeResolver{Kubectl: nodes.run}
+
+	windows, err := withKubectl.Resolve(ctx, "sub", "MC_rg", "aksnpwin000002")
+	if err != nil || windows.VMSS != "aks-win-87654321-vmss" || windows.InstanceID != "2" || windows.ResourceGroup != "mc_rg" || windows.ProviderID != windowsProviderID {
+		t.Errorf("Expected the Windows node to resolve through its provider ID, got %+v (%v)", windows, err)
+	}
+	byProviderID, err := withKubectl.Resolve(ctx, "sub", "MC_rg", strings.ToUpper(windowsProviderID[:9])+windowsProviderID[9:])
+	if err != nil || byProviderID.NodeName != "aksnpwin000002" {
+		t.Errorf("Expected the node name of the provider ID, got %+v (%v)", byProviderID, err)
+	}
+	byInstance, err := withKubectl.Resolve(ctx, "sub", "mc_rg", "aks-nodepool1-12345678-vmss_10")
+	if err != nil || byInstance.NodeName != "aks-nodepool1-12345678-vmss00000a" {
+		t.Errorf("Expected the node name of instance 10, got %+v (%v)", byInstance, err)
+	}
+
+	// Without kubectl, a node that never joined is decoded from its name and an instance named from its computer name
+	offline, err := NodeResolver{}.Resolve(ctx, "sub", "MC_rg", "aks-nodepool1-12345678-vmss0000zz")
+	if err != nil || offline.VMSS != "aks-nodepool1-12345678-vmss" || offline.InstanceID != "1295" || offline.ResourceGroup != "MC_rg" ||
+		offline.ProviderID != ScaleSetProviderID("sub", "MC_rg", "aks-nodepool1-12345678-vmss", "1295") {
+		t.Errorf("Unexpected offline resolution %+v (%v)", offline, err)
+	}
+	withSDK := NodeResolver{Instances: fakeInstances{computerName: "akswin000004"}}
+	if instance, err := withSDK.Resolve(ctx, "sub", "MC_rg", "aks-win-87654321-vmss/4"); err != nil || instance.NodeName != "akswin000004" {
+		t.Errorf("Expected the instance's computer name, got %+v (%v)", instance, err)
+	}
+	if instance, err := (NodeResolver{}).Resolve(ctx, "sub", "MC_rg", "aks-nodepool1-12345678-vmss_1295"); err != nil || instance.NodeName != "aks-nodepool1-12345678-vmss0000zz" {
+		t.Errorf("Expected the derived Linux node name, got %+v (%v)", instance, err)
+	}
+
+	for _, identifier := range []string{
+		"aksnpwin000002",
+		"azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachines/aks-default-abcde",
+		"",
+	} {
+		if _, err := (NodeResolver{}).Resolve(ctx, "sub", "MC_rg", identifier); err == nil {
+			t.Errorf("Expected an error resolving %q", identifier)
+		}
+	}
+}
+
+// TestFormatVMSSNodeName tests that node names round trip through ParseVMSSNodeName
+func TestFormatVMSSNodeName(t *testing.T) {
+	name := FormatVMSSNodeName("aks-nodepool1-12345678-vmss", "1295")
+	if name != "aks-nodepool1-12345678-vmss0000zz" {
+		t.Errorf("Unexpected node name %s", name)
+	}
+	if _, instance, err := ParseVMSSNodeName(name); err != nil || instance != "1295" {
+		t.Errorf("Expected the name to parse back to instance 1295, got %s (%v)", instance, err)
+	}
+}
+
+// TestAddNodeInstanceArgs tests filling in the scale set flags of a node for VMSS operations
+func TestAddNodeInstanceArgs(t *testing.T) {
+	nodes := &fakeNodes{}
+	resolver := NodeResolver{Kubectl: nodes.run}
+
+	args, err := AddNodeInstanceArgs("run-command", "vmss", "--command-id RunShellScript --scripts 'hostname'", "aksnpwin000002", resolver)
+	if err != nil || args != "--command-id RunShellScript --scripts 'hostname' --name 'aks-win-87654321-vmss' --instance-id '2' --resource-group 'mc_rg'" {
+		t.Errorf("Unexpected run-command args %q (%v)", args, err)
+	}
+	args, err = AddNodeInstanceArgs("reimage", "vmss", "--resource-group MC_RG", linuxProviderID, resolver)
+	if err != nil || args != "--resource-group MC_RG --name 'aks-nodepool1-12345678-vmss' --instance-ids '10'" {
+		t.Errorf("Unexpected reimage args %q (%v)", args, err)
+	}
+
+	for _, tc := range []struct{ operation, resourceType, args string }{
+		{"list", "vmss", ""},
+		{"run-command", "vm", ""},
+		{"restart", "vmss", "--resource-group other-rg"},
+		{"show", "vmss", "--name other-vmss"},
+		{"show", "vmss", "--instance-id 3"},
+	} {
+		if _, err := AddNodeInstanceArgs(tc.operation, tc.resourceType, tc.args, "aksnpwin000002", resolver); err == nil {
+			t.Errorf("Expected an error for %s %s %q", tc.resourceType, tc.operation, tc.args)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Operating systems of node scripts
+const (
+	ScriptOSLinux   = "linux"
+	ScriptOSWindows = "windows"
+)
+
+// runNodeScriptTool is the name audit records of script runs are issued under
+const runNodeScriptTool = "run_node_script"
+
+var (
+	scriptNamePattern        = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_\-]*$`)
+	scriptPlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-]+)\s*\}\}`)
+	// defaultParameterPattern is what the values of parameters without a pattern or values of their own may hold
+	defaultParameterPattern = `[A-Za-z0-9._:/@=,+\-]*`
+)
+
+// NodeScriptParameter is a parameter of a node script. A value must be one of Values when they are set, otherwise
+// it must fully match Pattern, which defaults to letters, digits and . _ : / @ = , + -.
+type NodeScriptParameter struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Values      []string `json:"values,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// NodeScript is a run-command script loaded from a YAML file of the node script library. In the script,
+// {{parameter}} is replaced by the parameter's value, quoted for the shell of the script's OS.
+type NodeScript struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// OS is linux (RunShellScript) or windows (RunPowerShellScript)
+	OS string `json:"os"`
+	// AccessLevel is the access level the server needs to run the script; it defaults to readwrite
+	AccessLevel string                `json:"accessLevel"`
+	Parameters  []NodeScriptParameter `json:"parameters,omitempty"`
+	Script      string                `json:"script"`
+}
+
+// NodeScriptList is the result of the list_node_scripts tool
+type NodeScriptList struct {
+	Scripts []NodeScript `json:"scripts"`
+	// Hidden is the number of scripts that need a higher access level than the server's
+	Hidden int `json:"hidden,omitempty"`
+}
+
+// NodeScriptResult is the result of the run_node_script tool
+type NodeScriptResult struct {
+	Script     string            `json:"script"`
+	NodeName   string            `json:"nodeName"`
+	VMSS       string            `json:"vmss"`
+	InstanceID string            `json:"instanceId"`
+	OS         string            `json:"os"`
+	Arguments  map[string]string `json:"arguments,omitempty"`
+	Output     string            `json:"output"`
+	Stderr     string            `json:"stderr,omitempty"`
+}
+
+// NodeScriptRunner reads cluster details and scale set instances and runs scripts on instances.
+// *azureclient.AzureClient implements it.
+type NodeScriptRunner interface {
+	GetAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error)
+	GetVMSSInstance(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error)
+	RunVMSSShellScript(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, script string) (string, error)
+	RunVMSSPowerShellScript(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID, script string) (string, error)
+}
+
+// ParseNodeScript parses a node script from YAML. The name defaults to the file name without its extension.
+func ParseNodeScript(path string, content []byte) (NodeScript, error) {
+	var script NodeScript
+	if err := yaml.UnmarshalStrict(content, &script); err != nil {
+		return script, fmt.Errorf("%s: invalid node script: %v", path, err)
+	}
+	if script.Name == "" {
+		script.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if !scriptNamePattern.MatchString(script.Name) {
+		return script, fmt.Errorf("%s: invalid script name %q: use letters, digits, _ and -", path, script.Name)
+	}
+	script.OS = strings.ToLower(script.OS)
+	if script.OS == "" {
+		script.OS = ScriptOSLinux
+	}
+	if script.OS != ScriptOSLinux && script.OS != ScriptOSWindows {
+		return script, fmt.Errorf("%s: invalid os %q: expected %s or %s", path, script.OS, ScriptOSLinux, ScriptOSWindows)
+	}
+	if script.AccessLevel == "" {
+		script.AccessLevel = "readwrite"
+	}
+	if !slices.Contains(apikey.AccessLevels, script.AccessLevel) {
+		return script, fmt.Errorf("%s: invalid accessLevel %q: expected one of %s", path, script.AccessLevel, strings.Join(apikey.AccessLevels, ", "))
+	}
+	script.Script = strings.TrimSpace(script.Script)
+	if script.Script == "" {
+		return script, fmt.Errorf("%s: the script is empty", path)
+	}
+
+	declared := make(map[string]bool, len(script.Parameters))
+	for i := range script.Parameters {
+		param := &script.Parameters[i]
+		if !scriptNamePattern.MatchString(param.Name) || declared[param.Name] {
+			return script, fmt.Errorf("%s: invalid or duplicate parameter name %q", path, param.Name)
+		}
+		declared[param.Name] = true
+		pattern := param.Pattern
+		if pattern == "" {
+			pattern = defaultParameterPattern
+		}
+		compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return script, fmt.Errorf("%s: invalid pattern of parameter %s: %v", path, param.Name, err)
+		}
+		param.pattern = compiled
+		if param.Default != "" {
+			if err := param.validate(param.Default); err != nil {
+				return script, fmt.Errorf("%s: default of parameter %s: %v", path, param.Name, err)
+			}
+		}
+	}
+	for _, match := range scriptPlaceholderPattern.FindAllStringSubmatch(script.Script, -1) {
+		if !declared[match[1]] {
+			return script, fmt.Errorf("%s: the script uses {{%s}}, which is not a declared parameter", path, match[1])
+		}
+	}
+	return script, nil
+}
+
+// LoadNodeScripts loads the *.yaml and *.yml node scripts of a directory in file name order. A directory
+// mounted from a ConfigMap works as well, with one key per script. Invalid scripts and duplicate names are
+// logged and skipped, so one bad file does not hide the others.
+func LoadNodeScripts(dir string) ([]NodeScript, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read node scripts directory: %w", err)
+	}
+	var paths []string
+	for _, glob := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, glob))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	names := make(map[string]bool)
+	var scripts []NodeScript
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Warning: skipping node script %s: %v", path, err)
+			continue
+		}
+		script, err := ParseNodeScript(path, content)
+		if err != nil {
+			log.Printf("Warning: skipping node script %v", err)
+			continue
+		}
+		if names[script.Name] {
+			log.Printf("Warning: skipping node script %s: script name %q is already taken", path, script.Name)
+			continue
+		}
+		names[script.Name] = true
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// Render validates the arguments and replaces the {{parameter}} placeholders of the script with their values,
+// quoted as single-quoted strings of the script's shell. Missing arguments take their default.
+func (s NodeScript) Render(arguments map[string]string) (string, map[string]string, error) {
+	values := make(map[string]string, len(s.Parameters))
+	declared := make(map[string]bool, len(s.Parameters))
+	for _, param := range s.Parameters {
+		declared[param.Name] = true
+		value, ok := arguments[param.Name]
+		if !ok || value == "" {
+			value = param.Default
+		}
+		if value == "" {
+			if param.Required {
+				return "", nil, fmt.Errorf("script %s requires argument %q", s.Name, param.Name)
+			}
+		} else if err := param.validate(value); err != nil {
+			return "", nil, fmt.Errorf("invalid argument %s: %v", param.Name, err)
+		}
+		values[param.Name] = value
+	}
+	for name := range arguments {
+		if !declared[name] {
+			return "", nil, fmt.Errorf("script %s has no parameter %q", s.Name, name)
+		}
+	}
+
+	rendered := scriptPlaceholderPattern.ReplaceAllStringFunc(s.Script, func(match string) string {
+		return quoteScriptValue(s.OS, values[scriptPlaceholderPattern.FindStringSubmatch(match)[1]])
+	})
+	return rendered, values, nil
+}
+
+// validate checks a parameter value against the allowed values or the pattern
+func (p NodeScriptParameter) validate(value string) error {
+	if len(p.Values) > 0 {
+		for _, allowed := range p.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(p.Values, ", "))
+	}
+	if !p.pattern.MatchString(value) {
+		return fmt.Errorf("%q does not match %s", value, p.pattern.String())
+	}
+	return nil
+}
+
+// quoteScriptValue quotes a value as a single-quoted string of bash or PowerShell
+func quoteScriptValue(scriptOS, value string) string {
+	if scriptOS == ScriptOSWindows {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// GetListNodeScriptsHandler returns a handler for the list_node_scripts tool
+func GetListNodeScriptsHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleListNodeScripts(params, cfg)
+	})
+}
+
+// HandleListNodeScripts lists the node scripts the caller's access level may run, optionally for one OS
+func HandleListNodeScripts(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	scriptOS, _ := params["os"].(string)
+	if scriptOS != "" && scriptOS != ScriptOSLinux && scriptOS != ScriptOSWindows {
+		return "", fmt.Errorf("invalid os '%s': expected %s or %s", scriptOS, ScriptOSLinux, ScriptOSWindows)
+	}
+	scripts, err := LoadNodeScripts(cfg.NodeScriptsDir)
+	if err != nil {
+		return "", err
+	}
+
+	list := NodeScriptList{Scripts: []NodeScript{}}
+	for _, script := range scripts {
+		if scriptOS != "" && script.OS != scriptOS {
+			continue
+		}
+		if !apikey.LevelIncludes(cfg.AccessLevel, script.AccessLevel) {
+			list.Hidden++
+			continue
+		}
+		list.Scripts = append(list.Scripts, script)
+	}
+
+	resultJSON, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal node scripts to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// GetRunNodeScriptHandler returns a handler for the run_node_script tool
+func GetRunNodeScriptHandler(client *azureclient.AzureClient, auditLog *audit.Logger, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleRunNodeScript(params, client, newClusterNodeResolver(client, cfg), auditLog, cfg)
+	})
+}
+
+// HandleRunNodeScript runs a script of the library on the scale set instance behind a node with run-command.
+// Runs, including denied ones, are recorded in the audit log.
+func HandleRunNodeScript(params map[string]interface{}, runner NodeScriptRunner, nodes NodeResolver, auditLog *audit.Logger, cfg *config.ConfigData) (string, error) {
+	name, _ := params["script"].(string)
+	nodeName, _ := params["node_name"].(string)
+	record := audit.Record{Tool: runNodeScriptTool, Action: name, Target: nodeName, Client: cfg.ClientName()}
+	output, err := runNodeScript(params, runner, nodes, cfg, &record)
+	switch {
+	case err == nil:
+		record.Outcome = audit.OutcomeSucceeded
+	case record.Outcome == "":
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+	default:
+		record.Error = err.Error()
+	}
+	if auditLog != nil {
+		auditLog.Log(record)
+	}
+	return output, err
+}
+
+// runNodeScript runs a node script. record is filled in with the rendered script, and its outcome is set to
+// failed when the script could not be run.
+func runNodeScript(params map[string]interface{}, runner NodeScriptRunner, nodes NodeResolver, cfg *config.ConfigData, record *audit.Record) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	if record.Target == "" {
+		return "", fmt.Errorf("missing or invalid node_name parameter")
+	}
+	if record.Action == "" {
+		return "", fmt.Errorf("missing or invalid script parameter")
+	}
+	arguments := map[string]string{}
+	if raw, ok := params["arguments"]; ok && raw != nil {
+		values, ok := raw.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("invalid arguments: expected an object of parameter values")
+		}
+		for key, value := range values {
+			if _, isObject := value.(map[string]interface{}); isObject {
+				return "", fmt.Errorf("invalid argument %s: expected a string, number or boolean", key)
+			}
+			if _, isList := value.([]interface{}); isList {
+				return "", fmt.Errorf("invalid argument %s: expected a string, number or boolean", key)
+			}
+			arguments[key] = fmt.Sprint(value)
+		}
+	}
+
+	scripts, err := LoadNodeScripts(cfg.NodeScriptsDir)
+	if err != nil {
+		return "", err
+	}
+	var script *NodeScript
+	var names []string
+	for i := range scripts {
+		names = append(names, scripts[i].Name)
+		if scripts[i].Name == record.Action {
+			script = &scripts[i]
+		}
+	}
+	if script == nil {
+		return "", fmt.Errorf("unknown node script %q: available scripts are %s", record.Action, strings.Join(names, ", "))
+	}
+	if !apikey.LevelIncludes(cfg.AccessLevel, script.AccessLevel) {
+		return "", fmt.Errorf("node script %s requires %s access level, current access level is %s", script.Name, script.AccessLevel, cfg.AccessLevel)
+	}
+	rendered, values, err := script.Render(arguments)
+	if err != nil {
+		return "", err
+	}
+	record.Command = rendered
+
+	ctx := context.Background()
+	cluster, err := runner.GetAKSCluster(ctx, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+	if cluster.Properties == nil || cluster.Properties.NodeResourceGroup == nil {
+		return "", fmt.Errorf("node resource group not found for AKS cluster")
+	}
+	node, err := nodes.Resolve(ctx, subID, *cluster.Properties.NodeResourceGroup, record.Target)
+	if err != nil {
+		return "", err
+	}
+	record.Target = fmt.Sprintf("%s/%s", node.VMSS, node.InstanceID)
+	instance, err := runner.GetVMSSInstance(ctx, subID, node.ResourceGroup, node.VMSS, node.InstanceID)
+	if err != nil {
+		return "", err
+	}
+	if nodeOS := instanceOS(instance); nodeOS != "" && nodeOS != script.OS {
+		return "", fmt.Errorf("node script %s is for %s nodes, but node %s runs %s", script.Name, script.OS, node.NodeName, nodeOS)
+	}
+
+	result := NodeScriptResult{Script: script.Name, NodeName: node.NodeName, VMSS: node.VMSS, InstanceID: node.InstanceID, OS: script.OS, Arguments: values}
+	var output string
+	if script.OS == ScriptOSWindows {
+		output, err = runner.RunVMSSPowerShellScript(ctx, subID, node.ResourceGroup, node.VMSS, node.InstanceID, rendered)
+	} else {
+		output, err = runner.RunVMSSShellScript(ctx, subID, node.ResourceGroup, node.VMSS, node.InstanceID, rendered)
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeFailed
+		return "", err
+	}
+	if script.OS == ScriptOSWindows {
+		result.Output = strings.TrimSpace(output)
+	} else {
+		result.Output = ParseRunCommandStdout(output)
+		if i := strings.Index(output, "[stderr]"); i >= 0 {
+			result.Stderr = strings.Trim(output[i+len("[stderr]"):], "\n")
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal node script result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// instanceOS returns the OS of a scale set instance from its OS profile, or "" when it is not known
+func instanceOS(instance *armcompute.VirtualMachineScaleSetVM) string {
+	if instance == nil || instance.Properties == nil || instance.Properties.OSProfile == nil {
+		return ""
+	}
+	switch {
+	case instance.Properties.OSProfile.WindowsConfiguration != nil:
+		return ScriptOSWindows
+	case instance.Properties.OSProfile.LinuxConfiguration != nil:
+		return ScriptOSLinux
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+const checkDNSScript = `description: Resolve a host name from the node
+accessLevel: readonly
+parameters:
+  - name: host
+    description: Host name to resolve
+    required: true
+  - name: record
+    description: Record type
+    default: A
+    values: [A, AAAA]
+script: |
+  dig +short -t {{record}} {{ host }}
+`
+
+func writeNodeScripts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+type fakeNodeScriptRunner struct {
+	windows bool
+	scripts []string
+}
+
+func (f *fakeNodeScriptRunner) GetAKSCluster(_ context.Context, _, _, _ string) (*armcontainerservice.ManagedCluster, error) {
+	return &armcontainerservice.ManagedCluster{Properties: &armcontainerservice.ManagedClusterProperties{NodeResourceGroup: to.Ptr("MC_rg")}}, nil
+}
+
+func (f *fakeNodeScriptRunner) GetVMSSInstance(_ context.Context, _, _, _, _ string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	profile := &armcompute.OSProfile{LinuxConfiguration: &armcompute.LinuxConfiguration{}}
+	if f.windows {
+		profile = &armcompute.OSProfile{WindowsConfiguration: &armcompute.WindowsConfiguration{}}
+	}
+	return &armcompute.VirtualMachineScaleSetVM{Properties: &armcompute.VirtualMachineScaleSetVMProperties{OSProfile: profile}}, nil
+}
+
+func (f *fakeNodeScriptRunner) RunVMSSShellScript(_ context.Context, _, rg, vmss, instanceID, script string) (string, error) {
+	if rg != "MC_rg" || vmss != "aks-nodepool1-12345678-vmss" || instanceID != "3" {
+		return "", fmt.Errorf("unexpected instance %s/%s/%s", rg, vmss, instanceID)
+	}
+	f.scripts = append(f.scripts, script)
+	return "Enable succeeded: \n[stdout]\n20.1.2.3\n\n[stderr]\nwarning\n", nil
+}
+
+func (f *fakeNodeScriptRunner) RunVMSSPowerShellScript(_ context.Context, _, _, _, _, script string) (string, error) {
+	f.scripts = append(f.scripts, script)
+	return "Windows output\n", nil
+}
+
+func TestParseNodeScript(t *testing.T) {
+	script, err := ParseNodeScript("scripts/check-dns.yaml", []byte(checkDNSScript))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if script.Name != "check-dns" || script.OS != ScriptOSLinux || script.AccessLevel != "readonly" || len(script.Parameters) != 2 {
+		t.Errorf("Unexpected script %+v", script)
+	}
+
+	for name, content := range map[string]string{
+		"unknown field":       "script: uptime\nshell: bash\n",
+		"empty script":        "description: nothing\n",
+		"bad os":              "os: macos\nscript: uptime\n",
+		"bad access level":    "accessLevel: root\nscript: uptime\n",
+		"undeclared argument": "script: ping {{host}}\n",
+		"duplicate parameter": "parameters: [{name: a}, {name: a}]\nscript: echo {{a}}\n",
+		"bad pattern":         "parameters: [{name: a, pattern: '('}]\nscript: echo {{a}}\n",
+		"bad default":         "parameters: [{name: a, default: x, values: [y]}]\nscript: echo {{a}}\n",
+	} {
+		if _, err := ParseNodeScript("bad.yaml", []byte(content)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestNodeScriptRender(t *testing.T) {
+	script, err := ParseNodeScript("check-dns.yaml", []byte(checkDNSScript))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rendered, values, err := script.Render(map[string]string{"host": "mcr.microsoft.com"})
+	if err != nil || rendered != "dig +short -t 'A' 'mcr.microsoft.com'" || values["record"] != "A" {
+		t.Errorf("Unexpected rendering %q %v (%v)", rendered, values, err)
+	}
+
+	for _, arguments := range []map[string]string{
+		{},
+		{"host": "$(reboot)"},
+		{"host": "a.b", "record": "MX"},
+		{"host": "a.b", "port": "53"},
+	} {
+		if _, _, err := script.Render(arguments); err == nil {
+			t.Errorf("Expected %v to be rejected", arguments)
+		}
+	}
+
+	// Values matching a custom pattern are quoted for the script's shell
+	quoted, err := ParseNodeScript("echo.yaml", []byte("os: windows\nparameters: [{name: msg, pattern: '.*'}]\nscript: Write-Output {{msg}}\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rendered, _, _ := quoted.Render(map[string]string{"msg": "it's"}); rendered != "Write-Output 'it''s'" {
+		t.Errorf("Unexpected PowerShell quoting %q", rendered)
+	}
+	quoted.OS = ScriptOSLinux
+	if rendered, _, _ := quoted.Render(map[string]string{"msg": "it's"}); rendered != `Write-Output 'it'\''s'` {
+		t.Errorf("Unexpected shell quoting %q", rendered)
+	}
+}
+
+func TestHandleListNodeScripts(t *testing.T) {
+	dir := writeNodeScripts(t, map[string]string{
+		"check-dns.yaml":  checkDNSScript,
+		"restart.yml":     "description: Restart kubelet\nscript: systemctl restart kubelet\n",
+		"win.yaml":        "os: windows\naccessLevel: readonly\nscript: Get-Service kubelet\n",
+		"broken.yaml":     "script: ping {{host}}\n",
+		"duplicate.yaml":  "name: check-dns\nscript: uptime\n",
+		"notascript.json": "{}",
+	})
+	cfg := &config.ConfigData{AccessLevel: "readonly", NodeScriptsDir: dir}
+
+	output, err := HandleListNodeScripts(map[string]interface{}{}, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var list NodeScriptList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		t.Fatalf("Failed to parse list: %v", err)
+	}
+	if len(list.Scripts) != 2 || list.Scripts[0].Name != "check-dns" || list.Scripts[1].Name != "win" || list.Hidden != 1 {
+		t.Errorf("Unexpected list %+v", list)
+	}
+
+	output, _ = HandleListNodeScripts(map[string]interface{}{"os": "linux"}, &config.ConfigData{AccessLevel: "admin", NodeScriptsDir: dir})
+	if !strings.Contains(output, "restart") || strings.Contains(output, "Get-Service") {
+		t.Errorf("Expected the Linux scripts, got %s", output)
+	}
+	if _, err := HandleListNodeScripts(map[string]interface{}{"os": "macos"}, cfg); err == nil {
+		t.Error("Expected an unknown os to be rejected")
+	}
+}
+
+func TestHandleRunNodeScript(t *testing.T) {
+	dir := writeNodeScripts(t, map[string]string{
+		"check-dns.yaml": checkDNSScript,
+		"restart.yaml":   "description: Restart kubelet\nscript: systemctl restart kubelet\n",
+	})
+	auditLog := audit.NewLogger(store.NewMemoryStore())
+	runner := &fakeNodeScriptRunner{}
+	cfg := &config.ConfigData{AccessLevel: "readonly", NodeScriptsDir: dir}
+	params := func(script string, arguments map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"subscription_id": "sub", "resource_group": "rg", "cluster_name": "cluster",
+			"node_name": "aks-nodepool1-12345678-vmss000003", "script": script, "arguments": arguments,
+		}
+	}
+
+	output, err := HandleRunNodeScript(params("check-dns", map[string]interface{}{"host": "mcr.microsoft.com"}), runner, NodeResolver{}, auditLog, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result NodeScriptResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if result.Output != "20.1.2.3" || result.Stderr != "warning" || result.InstanceID != "3" || result.Arguments["record"] != "A" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if len(runner.scripts) != 1 || runner.scripts[0] != "dig +short -t 'A' 'mcr.microsoft.com'" {
+		t.Errorf("Unexpected scripts %v", runner.scripts)
+	}
+
+	// Scripts above the access level, invalid arguments and the wrong OS are denied without running anything
+	if _, err := HandleRunNodeScript(params("restart", nil), runner, NodeResolver{}, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "readwrite") {
+		t.Errorf("Expected the access level to be enforced, got %v", err)
+	}
+	if _, err := HandleRunNodeScript(params("check-dns", map[string]interface{}{"host": "a;reboot"}), runner, NodeResolver{}, auditLog, cfg); err == nil {
+		t.Error("Expected an invalid argument to be rejected")
+	}
+	if _, err := HandleRunNodeScript(params("missing", nil), runner, NodeResolver{}, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "check-dns, restart") {
+		t.Errorf("Expected the available scripts in the error, got %v", err)
+	}
+	windows := &fakeNodeScriptRunner{windows: true}
+	if _, err := HandleRunNodeScript(params("check-dns", map[string]interface{}{"host": "a.b"}), windows, NodeResolver{}, auditLog, cfg); err == nil || !strings.Contains(err.Error(), "runs windows") {
+		t.Errorf("Expected an OS mismatch, got %v", err)
+	}
+	if len(runner.scripts) != 1 || len(windows.scripts) != 0 {
+		t.Errorf("Expected no further scripts to run, got %v %v", runner.scripts, windows.scripts)
+	}
+
+	records, err := auditLog.Records()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 5 || records[0].Outcome != audit.OutcomeSucceeded || records[0].Target != "aks-nodepool1-12345678-vmss/3" || records[0].Command != runner.scripts[0] {
+		t.Fatalf("Unexpected audit records %+v", records)
+	}
+	for _, record := range records[1:] {
+		if record.Tool != "run_node_script" || record.Outcome != audit.OutcomeDenied {
+			t.Errorf("Expected a denied record, got %+v", record)
+		}
+	}
+}
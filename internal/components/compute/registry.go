@@ -1,6 +1,8 @@
 package compute
 
 import (
+	"fmt"
+
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -28,3 +30,132 @@ func RegisterAKSVMSSInfoTool() mcp.Tool {
 		),
 	)
 }
+
+// RegisterNodeSerialLogTool registers the get_aks_node_serial_log tool
+func RegisterNodeSerialLogTool() mcp.Tool {
+	return mcp.NewTool(
+		"get_aks_node_serial_log",
+		mcp.WithDescription(`Get the tail of a node's serial console log from boot diagnostics, for nodes stuck at boot or NotReady without a working kubelet.
+
+The node's scale set and instance are resolved from its name (e.g. aks-nodepool1-12345678-vmss00000a) through its
+provider ID, which also covers Windows nodes; a provider ID or <vmss>_<instance-id> is accepted too. Returns the last
+lines of the log and the lines matching known boot failures (kernel panic, emergency mode, failed units, disk and
+file system errors, failed cloud-init). If boot diagnostics is off on the scale set, enable_boot_diagnostics=true
+turns it on with managed storage (readwrite access); the log then only covers output since it was enabled.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", node_name="aks-nodepool1-12345678-vmss000003"`),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("node_name",
+			mcp.Description("Name of the node as listed by kubectl get nodes, its provider ID or <vmss>_<instance-id>"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("tail_lines",
+			mcp.Description(fmt.Sprintf("Number of lines to return from the end of the log (default: %d, at most %d)", defaultSerialLogLines, maxSerialLogLines)),
+		),
+		mcp.WithBoolean("enable_boot_diagnostics",
+			mcp.Description("Enable boot diagnostics on the node's scale set if it is off (requires readwrite access)"),
+		),
+	)
+}
+
+// RegisterNodeBootstrapDiagnosticsTool registers the diagnose_aks_node_bootstrap tool
+func RegisterNodeBootstrapDiagnosticsTool() mcp.Tool {
+	return mcp.NewTool(
+		"diagnose_aks_node_bootstrap",
+		mcp.WithDescription(`Diagnose why a Linux node failed to bootstrap and join the cluster.
+
+Reads the provisioning state and the extension statuses of the node's scale set instance, including the error
+message and exit status of the AKS Custom Script Extension (vmssCSE), and maps known exit codes to their cause
+(for example 50: no outbound connectivity, 51: API server unreachable, 52: API server name not resolvable).
+With include_provision_log=true (readwrite access) the tail of /var/log/azure/cluster-provision.log is read
+from the node with run-command and its error lines are reported.
+
+Nodes that never joined are not listed by kubectl; use the scale set instance's computer name, which has the
+same form, its provider ID or <vmss>_<instance-id>. Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", node_name="aks-nodepool1-12345678-vmss000003"`),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("node_name",
+			mcp.Description("Name of the Linux node, the computer name of its scale set instance, its provider ID or <vmss>_<instance-id>"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("include_provision_log",
+			mcp.Description("Read the tail of cluster-provision.log from the node with run-command (requires readwrite access)"),
+		),
+		mcp.WithNumber("tail_lines",
+			mcp.Description(fmt.Sprintf("Number of provision log lines to return (default: %d, at most %d)", defaultProvisionLines, maxProvisionLines)),
+		),
+	)
+}
+
+// RegisterListNodeScriptsTool registers the list_node_scripts tool
+func RegisterListNodeScriptsTool() mcp.Tool {
+	return mcp.NewTool(
+		"list_node_scripts",
+		mcp.WithDescription(`List the scripts of the node script library that run_node_script can run on this server's access level, with
+their description, OS, required access level, parameters and script body. Scripts needing a higher access level
+are counted as hidden. The library is read from the configured node scripts directory on every call.`),
+		mcp.WithString("os",
+			mcp.Description("Only scripts for nodes of this OS"),
+			mcp.Enum(ScriptOSLinux, ScriptOSWindows),
+		),
+	)
+}
+
+// RegisterRunNodeScriptTool registers the run_node_script tool
+func RegisterRunNodeScriptTool() mcp.Tool {
+	return mcp.NewTool(
+		"run_node_script",
+		mcp.WithDescription(`Run a script of the node script library on a node with VMSS run-command (RunShellScript on Linux nodes,
+RunPowerShellScript on Windows nodes) and return its output.
+
+Only scripts of the library can be run; call list_node_scripts for their names and parameters. Arguments are
+validated against each parameter's allowed values or pattern and substituted into the script as quoted strings;
+missing arguments take their default. The script's OS must match the node's, and the server's access level must
+include the script's. Every run is recorded in the audit log. Example: subscription_id="<sub>", resource_group="<rg>",
+cluster_name="<cluster>", node_name="aks-nodepool1-12345678-vmss000003", script="check-dns", arguments={"host": "mcr.microsoft.com"}`),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("node_name",
+			mcp.Description("Name of the node, the computer name of its scale set instance, its provider ID or <vmss>_<instance-id>"),
+			mcp.Required(),
+		),
+		mcp.WithString("script",
+			mcp.Description("Name of the script, as listed by list_node_scripts"),
+			mcp.Required(),
+		),
+		mcp.WithObject("arguments",
+			mcp.Description("Script arguments as an object of parameter names to values, e.g. {\"host\": \"mcr.microsoft.com\"}"),
+		),
+	)
+}
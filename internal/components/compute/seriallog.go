@@ -0,0 +1,190 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+const (
+	defaultSerialLogLines = 200
+	maxSerialLogLines     = 2000
+	// maxBootProblems bounds the log lines reported as likely boot problems
+	maxBootProblems = 20
+)
+
+// bootProblemPatterns are lowercase fragments of serial console lines that point at why a node did not boot
+var bootProblemPatterns = []string{
+	"kernel panic",
+	"emergency mode",
+	"failed to start",
+	"dependency failed",
+	"timed out waiting for device",
+	"soft lockup",
+	"out of memory",
+	"i/o error",
+	"ext4-fs error",
+	"xfs (",
+	"no space left on device",
+	"read-only file system",
+	"cloud-init",
+	"provisioning failed",
+}
+
+// SerialLogReader reads cluster and scale set details and boot diagnostics. *azureclient.AzureClient implements it.
+type SerialLogReader interface {
+	GetAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error)
+	GetVMSS(ctx context.Context, subscriptionID, resourceGroup, vmssName string) (*armcompute.VirtualMachineScaleSet, error)
+	EnableVMSSBootDiagnostics(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) error
+	GetVMSSSerialLog(ctx context.Context, subscriptionID, resourceGroup, vmssName, instanceID string) (string, error)
+}
+
+// SerialLogResult is the tail of a node's serial console log
+type SerialLogResult struct {
+	NodeName          string `json:"nodeName"`
+	NodeResourceGroup string `json:"nodeResourceGroup"`
+	VMSS              string `json:"vmss"`
+	InstanceID        string `json:"instanceId"`
+	ProviderID        string `json:"providerId"`
+	// BootDiagnosticsEnabled is true when this call enabled boot diagnostics on the scale set
+	BootDiagnosticsEnabled bool     `json:"bootDiagnosticsEnabled,omitempty"`
+	TotalLines             int      `json:"totalLines"`
+	ReturnedLines          int      `json:"returnedLines"`
+	BootProblems           []string `json:"bootProblems,omitempty"`
+	Log                    string   `json:"log"`
+	Note                   string   `json:"note,omitempty"`
+}
+
+// GetNodeSerialLogHandler returns a handler for the get_aks_node_serial_log command
+func GetNodeSerialLogHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNodeSerialLog(params, client, newClusterNodeResolver(client, cfg), cfg)
+	})
+}
+
+// HandleNodeSerialLog returns the tail of the serial console log of the scale set instance behind a node.
+// Boot diagnostics is enabled on the scale set first when enable_boot_diagnostics is set and the access
+// level allows writes.
+func HandleNodeSerialLog(params map[string]interface{}, reader SerialLogReader, nodes NodeResolver, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	nodeName, _ := params["node_name"].(string)
+	if nodeName == "" {
+		return "", fmt.Errorf("missing or invalid node_name parameter")
+	}
+	lines := defaultSerialLogLines
+	if value, ok := params["tail_lines"].(float64); ok && value > 0 {
+		lines = min(int(value), maxSerialLogLines)
+	}
+	enable, _ := params["enable_boot_diagnostics"].(bool)
+
+	ctx := context.Background()
+	cluster, err := reader.GetAKSCluster(ctx, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+	if cluster.Properties == nil || cluster.Properties.NodeResourceGroup == nil {
+		return "", fmt.Errorf("node resource group not found for AKS cluster")
+	}
+	node, err := nodes.Resolve(ctx, subID, *cluster.Properties.NodeResourceGroup, nodeName)
+	if err != nil {
+		return "", err
+	}
+	nodeRG, vmssName, instanceID := node.ResourceGroup, node.VMSS, node.InstanceID
+
+	vmss, err := reader.GetVMSS(ctx, subID, nodeRG, vmssName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get scale set %s of node %s: %v", vmssName, node.NodeName, err)
+	}
+
+	result := SerialLogResult{NodeName: node.NodeName, NodeResourceGroup: nodeRG, VMSS: vmssName, InstanceID: instanceID, ProviderID: node.ProviderID}
+	if !bootDiagnosticsEnabled(vmss) {
+		if !enable {
+			return "", fmt.Errorf("boot diagnostics is not enabled on scale set %s; call again with enable_boot_diagnostics=true (requires readwrite access) to enable it with managed storage", vmssName)
+		}
+		if cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+			return "", fmt.Errorf("enabling boot diagnostics on scale set %s requires readwrite or admin access level", vmssName)
+		}
+		if err := reader.EnableVMSSBootDiagnostics(ctx, subID, nodeRG, vmssName, instanceID); err != nil {
+			return "", err
+		}
+		result.BootDiagnosticsEnabled = true
+		result.Note = "Boot diagnostics was just enabled, so the log only covers output since then. " +
+			"Restart the instance to capture a full boot, then call again."
+	}
+
+	serialLog, err := reader.GetVMSSSerialLog(ctx, subID, nodeRG, vmssName, instanceID)
+	if err != nil {
+		return "", err
+	}
+	all := strings.Split(strings.TrimRight(strings.ReplaceAll(serialLog, "\r\n", "\n"), "\n"), "\n")
+	if serialLog == "" {
+		all = nil
+	}
+	tail := all[max(len(all)-lines, 0):]
+	result.TotalLines = len(all)
+	result.ReturnedLines = len(tail)
+	result.Log = strings.Join(tail, "\n")
+	result.BootProblems = FindBootProblems(tail)
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal serial log to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// ParseVMSSNodeName splits the name of a Linux scale set node, such as aks-nodepool1-12345678-vmss00000a,
+// into the scale set name and the instance ID, which the last six characters encode in base 36
+func ParseVMSSNodeName(nodeName string) (string, string, error) {
+	const suffixLength = 6
+	if len(nodeName) <= suffixLength || !strings.Contains(nodeName, "-vmss") {
+		return "", "", fmt.Errorf("node name %q is not a scale set node name such as aks-nodepool1-12345678-vmss000000", nodeName)
+	}
+	vmssName := nodeName[:len(nodeName)-suffixLength]
+	instance, err := strconv.ParseInt(nodeName[len(nodeName)-suffixLength:], 36, 64)
+	if err != nil || !strings.HasSuffix(vmssName, "-vmss") {
+		return "", "", fmt.Errorf("node name %q is not a scale set node name such as aks-nodepool1-12345678-vmss000000", nodeName)
+	}
+	return vmssName, strconv.FormatInt(instance, 10), nil
+}
+
+// FindBootProblems returns the log lines that match known boot failure patterns, keeping the last ones
+func FindBootProblems(lines []string) []string {
+	var problems []string
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		for _, pattern := range bootProblemPatterns {
+			if !strings.Contains(lower, pattern) {
+				continue
+			}
+			// cloud-init logs every stage, so only its failures are problems
+			if pattern == "cloud-init" && !strings.Contains(lower, "fail") && !strings.Contains(lower, "error") {
+				continue
+			}
+			problems = append(problems, strings.TrimSpace(line))
+			break
+		}
+	}
+	return problems[max(len(problems)-maxBootProblems, 0):]
+}
+
+// bootDiagnosticsEnabled reports whether the scale set model has boot diagnostics enabled
+func bootDiagnosticsEnabled(vmss *armcompute.VirtualMachineScaleSet) bool {
+	if vmss == nil || vmss.Properties == nil || vmss.Properties.VirtualMachineProfile == nil {
+		return false
+	}
+	profile := vmss.Properties.VirtualMachineProfile.DiagnosticsProfile
+	return profile != nil && profile.BootDiagnostics != nil && profile.BootDiagnostics.Enabled != nil && *profile.BootDiagnostics.Enabled
+}
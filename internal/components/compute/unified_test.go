@@ -60,6 +60,12 @@ func TestValidateOperationAccess(t *testing.T) {
 		{"reimage", "readwrite", true},
 		{"reimage", "admin", true},
 
+		// Admin operations
+		{"delete-instance", "readwrite", false},
+		{"delete-instance", "admin", true},
+		{"deallocate-instance", "readwrite", false},
+		{"deallocate-instance", "admin", true},
+
 		// Unknown operations
 		{"invalid-op", "admin", false},
 	}
@@ -96,6 +102,9 @@ func TestMapOperationToCommand(t *testing.T) {
 		{"restart", "vmss", "az vmss restart", true},
 		{"reimage", "vmss", "az vmss reimage", true},
 		{"run-command", "vmss", "az vmss run-command invoke", true},
+		{"delete-instance", "vmss", "az vmss delete-instances", true},
+		{"deallocate-instance", "vmss", "az vmss deallocate", true},
+		{"delete-instance", "vm", "", false},
 		// Scale operation removed - not safe for AKS-managed VMSS
 
 		// Invalid resource types
@@ -142,6 +151,10 @@ func TestGetOperationAccessLevel(t *testing.T) {
 		{"reimage", "readwrite"},
 		{"run-command", "readwrite"},
 
+		// Admin operations
+		{"delete-instance", "admin"},
+		{"deallocate-instance", "admin"},
+
 		// Unknown operations
 		{"invalid-op", "unknown"},
 	}
@@ -155,3 +168,17 @@ func TestGetOperationAccessLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOperationParameters(t *testing.T) {
+	for _, resourceType := range []string{"vm", "vmss"} {
+		for _, op := range []string{"show", "list", "get-instance-view", "restart", "run-command"} {
+			if len(GetOperationParameters(op, resourceType)) == 0 {
+				t.Errorf("Expected a parameters allowlist for %s %s", resourceType, op)
+			}
+		}
+	}
+
+	if GetOperationParameters("reimage", "vm") != nil {
+		t.Error("Expected no parameters for unsupported vm reimage")
+	}
+}
@@ -0,0 +1,241 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeARM struct {
+	cluster string
+	tables  map[string]string
+	queries []string
+}
+
+func (f *fakeARM) CallARM(_ context.Context, _, _ string) ([]byte, error) {
+	return []byte(f.cluster), nil
+}
+
+func (f *fakeARM) CallARMWithBody(_ context.Context, _, path string, payload interface{}) ([]byte, error) {
+	query := payload.(map[string]string)["query"]
+	f.queries = append(f.queries, path+" "+query)
+	for table, body := range f.tables {
+		if strings.Contains(query, "\n"+table+"\n") {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query %s", query)
+}
+
+type fakePrices map[string]float64
+
+func (f fakePrices) HourlyPrice(_ context.Context, key PriceKey, _ string) (float64, error) {
+	if price, ok := f[key.VMSize]; ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no retail price found for %s in %s", key.VMSize, key.Region)
+}
+
+type fakeExecutor struct {
+	responses map[string]string
+	commands  []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return `{"items":[]}`, nil
+}
+
+const insightsCluster = `{"location":"eastus","properties":{"addonProfiles":{"omsAgent":{"enabled":true,
+  "config":{"logAnalyticsWorkspaceResourceID":"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/ws"}}}}}`
+
+var insightsTables = map[string]string{
+	"KubeNodeInventory": `{"tables":[{"columns":[{"name":"Node"},{"name":"Pool"},{"name":"VMSize"},{"name":"Priority"},{"name":"OS"},{"name":"Hours"},{"name":"CPUCores"},{"name":"MemoryGiB"}],
+  "rows":[["aks-pool-0","pool","Standard_D4s_v5","","linux",10,4,12],["aks-spot-0","spot","Standard_D4s_v5","spot","linux",10,4,12]]}]}`,
+	"Perf": `{"tables":[{"columns":[{"name":"Namespace"},{"name":"ControllerKind"},{"name":"ControllerName"},{"name":"Node"},{"name":"CPUCoreHours"},{"name":"MemoryGiBHours"}],
+  "rows":[["shop","ReplicaSet","web-5d8f7c9b4","aks-pool-0",20,0],["shop","StatefulSet","db","aks-pool-0",0,60],["batch","Job","nightly","aks-unknown",1,1]]}]}`,
+}
+
+func testParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+}
+
+func runBreakdown(t *testing.T, params map[string]interface{}, api *fakeARM, executor *fakeExecutor, cfg *config.ConfigData) CostReport {
+	t.Helper()
+	prices := fakePrices{"Standard_D4s_v5": 0.2}
+	output, err := HandleCostBreakdown(params, api, prices, executor, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report CostReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestCostBreakdownContainerInsights tests that node cost is split by requests and the rest reported as idle
+func TestCostBreakdownContainerInsights(t *testing.T) {
+	api := &fakeARM{cluster: insightsCluster, tables: insightsTables}
+	params := testParams()
+	params["group_by"] = GroupByWorkload
+	report := runBreakdown(t, params, api, &fakeExecutor{}, config.NewConfig())
+
+	if report.Source != SourceContainerInsights || report.Location != "eastus" || report.Currency != "USD" {
+		t.Errorf("Unexpected report header %+v", report)
+	}
+	// Two nodes at 0.2 an hour for 10 hours; a core costs 7.5 GiB, so a 4 core 12 GiB node splits 0.2 over 42 units
+	if report.TotalCost != 4 {
+		t.Errorf("Expected a total cost of 4, got %v", report.TotalCost)
+	}
+	byName := map[string]CostRow{}
+	for _, row := range report.Breakdown {
+		byName[row.Name] = row
+	}
+	web := byName["Deployment/web"]
+	if want := roundTo(20*0.2/42*7.5, 2); web.CPUCost != want || web.Namespace != "shop" {
+		t.Errorf("Expected a CPU cost of %v for the web Deployment, got %+v", want, web)
+	}
+	if want := roundTo(60*0.2/42, 2); byName["StatefulSet/db"].MemoryCost != want {
+		t.Errorf("Expected a memory cost of %v for the db StatefulSet, got %+v", want, byName["StatefulSet/db"])
+	}
+	idle := byName[IdleRow]
+	if math.Abs(idle.TotalCost+report.AllocatedCost-report.TotalCost) > 0.011 || report.IdleCost != idle.TotalCost {
+		t.Errorf("Expected idle to cover the unrequested cost, got idle %+v and report %+v", idle, report)
+	}
+	if report.Breakdown[0].Name != IdleRow {
+		t.Errorf("Expected the breakdown sorted by cost, got %+v", report.Breakdown)
+	}
+	if len(report.Pools) != 2 || report.Pools[0].Cost != 2 {
+		t.Errorf("Unexpected pools %+v", report.Pools)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "aks-unknown") {
+		t.Errorf("Expected a warning for requests on an unknown node, got %v", report.Warnings)
+	}
+	if len(api.queries) != 2 || !strings.Contains(api.queries[0], "workspaces/ws/api/query") {
+		t.Errorf("Expected two workspace queries, got %v", api.queries)
+	}
+}
+
+func testNode(name, pool, size string, created time.Time) string {
+	return fmt.Sprintf(`{"metadata":{"name":%q,"creationTimestamp":%q,"labels":{"kubernetes.azure.com/agentpool":%q,
+  "node.kubernetes.io/instance-type":%q,"kubernetes.io/os":"linux"}},"status":{"allocatable":{"cpu":"3860m","memory":"12Gi"}}}`,
+		name, created.Format(time.RFC3339), pool, size)
+}
+
+func testPod(namespace, name, owner, hash, node, cpu, memory string, started time.Time) string {
+	return fmt.Sprintf(`{"metadata":{"name":%q,"namespace":%q,"labels":{"pod-template-hash":%q},
+  "ownerReferences":[{"kind":"ReplicaSet","name":%q}]},
+  "spec":{"nodeName":%q,"containers":[{"resources":{"requests":{"cpu":%q,"memory":%q}}}]},
+  "status":{"phase":"Running","startTime":%q}}`, name, namespace, hash, owner, node, cpu, memory, started.Format(time.RFC3339))
+}
+
+// TestCostBreakdownSnapshot tests the kubectl fallback, unpriced nodes and the namespace restriction
+func TestCostBreakdownSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+	executor := &fakeExecutor{responses: map[string]string{
+		"get nodes": `{"items":[` + testNode("aks-pool-0", "pool", "Standard_D4s_v5", now.Add(-48*time.Hour)) + `,` +
+			testNode("aks-gpu-0", "gpu", "Standard_NC6s_v3", now.Add(-48*time.Hour)) + `]}`,
+		"get pods": `{"items":[` + testPod("shop", "web-abc12", "web-7f9c8d", "7f9c8d", "aks-pool-0", "1", "2Gi", now.Add(-6*time.Hour)) + `,` +
+			testPod("ops", "agent-xyz", "agent-5c6b7d8e9f", "5c6b7d8e9f", "aks-gpu-0", "500m", "1Gi", now.Add(-72*time.Hour)) + `]}`,
+	}}
+	api := &fakeARM{cluster: `{"location":"eastus","properties":{}}`}
+	params := testParams()
+	report := runBreakdown(t, params, api, executor, config.NewConfig())
+
+	if report.Source != SourceSnapshot || len(api.queries) != 0 {
+		t.Errorf("Expected the snapshot source without workspace queries, got %s and %v", report.Source, api.queries)
+	}
+	byName := map[string]CostRow{}
+	for _, row := range report.Breakdown {
+		byName[row.Name] = row
+	}
+	if shop := byName["shop"]; shop.CPUCoreHours < 5.9 || shop.CPUCoreHours > 6.1 || shop.TotalCost == 0 {
+		t.Errorf("Expected about 6 core hours charged to shop, got %+v", shop)
+	}
+	if ops := byName["ops"]; ops.TotalCost != 0 || ops.CPUCoreHours < 11.9 {
+		t.Errorf("Expected the ops pod on the unpriced node to run all window without cost, got %+v", ops)
+	}
+	warnings := strings.Join(report.Warnings, "; ")
+	if !strings.Contains(warnings, "Standard_NC6s_v3") || !strings.Contains(warnings, "aks-gpu-0") {
+		t.Errorf("Expected warnings for the unpriced node, got %q", warnings)
+	}
+	if !strings.Contains(report.Note, "running now") {
+		t.Errorf("Expected the note to explain the snapshot, got %q", report.Note)
+	}
+
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "shop"
+	executor.commands = nil
+	report = runBreakdown(t, params, api, executor, cfg)
+	if report.IdleCost != 0 || !strings.Contains(report.Note, "--allow-namespaces") {
+		t.Errorf("Expected idle cost to be omitted with --allow-namespaces, got %+v", report)
+	}
+	for _, row := range report.Breakdown {
+		if row.Name != "shop" {
+			t.Errorf("Expected only the shop namespace, got %+v", row)
+		}
+	}
+	for _, cmd := range executor.commands {
+		if strings.HasPrefix(cmd, "get pods") && !strings.Contains(cmd, "--namespace shop") {
+			t.Errorf("Expected pods to be listed from the allowed namespace, got %q", cmd)
+		}
+	}
+}
+
+// TestCostBreakdownParameters tests parameter validation
+func TestCostBreakdownParameters(t *testing.T) {
+	for name, override := range map[string]map[string]interface{}{
+		"hours":    {"hours": float64(1000)},
+		"group_by": {"group_by": "pod"},
+		"currency": {"currency": "dollars"},
+		"limit":    {"limit": float64(0)},
+	} {
+		params := testParams()
+		for key, value := range override {
+			params[key] = value
+		}
+		if _, err := HandleCostBreakdown(params, &fakeARM{cluster: insightsCluster}, fakePrices{}, &fakeExecutor{}, config.NewConfig()); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+// TestSelectHourlyPrice tests that the meter matching the OS and priority is picked
+func TestSelectHourlyPrice(t *testing.T) {
+	items := []RetailPriceItem{
+		{RetailPrice: 0.05, UnitOfMeasure: "1 Hour", SkuName: "D4s v5 Low Priority", ProductName: "Virtual Machines Dsv5 Series"},
+		{RetailPrice: 0.04, UnitOfMeasure: "1 Hour", SkuName: "D4s v5 Spot", ProductName: "Virtual Machines Dsv5 Series"},
+		{RetailPrice: 0.38, UnitOfMeasure: "1 Hour", SkuName: "D4s v5", ProductName: "Virtual Machines Dsv5 Series Windows"},
+		{RetailPrice: 0.21, UnitOfMeasure: "1 Hour", SkuName: "D4s v5", ProductName: "Virtual Machines Dsv5 Series"},
+		{RetailPrice: 0.19, UnitOfMeasure: "1 Hour", SkuName: "D4s v5", ProductName: "Virtual Machines Dsv5 Series", IsPrimaryMeterRegion: true},
+	}
+	tests := []struct {
+		key  PriceKey
+		want float64
+	}{
+		{PriceKey{VMSize: "Standard_D4s_v5"}, 0.19},
+		{PriceKey{VMSize: "Standard_D4s_v5", Spot: true}, 0.04},
+		{PriceKey{VMSize: "Standard_D4s_v5", Windows: true}, 0.38},
+	}
+	for _, tt := range tests {
+		if got, ok := SelectHourlyPrice(items, tt.key); !ok || got != tt.want {
+			t.Errorf("%+v: expected %v, got %v (%t)", tt.key, tt.want, got, ok)
+		}
+	}
+	if _, ok := SelectHourlyPrice(items, PriceKey{Spot: true, Windows: true}); ok {
+		t.Error("Expected no price for Windows Spot")
+	}
+}
@@ -0,0 +1,695 @@
+// Package cost estimates what the namespaces and workloads of an AKS cluster cost, without OpenCost:
+// the retail price of each node's VM size is split between CPU and memory and charged to the pods by
+// what they requested on that node over a window. Whatever is not requested is reported as idle.
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// defaultHours and maxHours bound the window; Log Analytics keeps 30 days by default
+	defaultHours = 24
+	maxHours     = 720
+	// defaultRows bounds the breakdown rows when limit is not given
+	defaultRows     = 50
+	defaultCurrency = "USD"
+	// cpuToMemoryCostRatio is how many GiB of memory cost as much as one CPU core when a node's price is split,
+	// the ratio of the default OpenCost on-demand prices
+	cpuToMemoryCostRatio = 7.5
+
+	clusterAPIVersion           = "2024-05-01"
+	logAnalyticsQueryAPIVersion = "2020-08-01"
+)
+
+// Breakdown groupings
+const (
+	GroupByNamespace = "namespace"
+	GroupByWorkload  = "workload"
+)
+
+// Sources of the requested resources
+const (
+	SourceContainerInsights = "containerInsights"
+	SourceSnapshot          = "snapshot"
+)
+
+// IdleRow names the breakdown row holding node cost that no pod requested
+const IdleRow = "(idle)"
+
+// currencyPattern matches ISO 4217 currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// replicaSetSuffix matches the pod template hash a Deployment appends to its ReplicaSet names
+var replicaSetSuffix = regexp.MustCompile(`^(.+)-[a-z0-9]{6,10}$`)
+
+// defaultPrices is shared by all calls so retail prices are fetched once per VM size
+var defaultPrices = NewRetailPrices()
+
+// NodeUsage is a node's size and how long it ran in the window. CPU is in cores and memory in GiB.
+type NodeUsage struct {
+	Name      string
+	Pool      string
+	VMSize    string
+	Spot      bool
+	Windows   bool
+	Hours     float64
+	CPUCores  float64
+	MemoryGiB float64
+}
+
+// WorkloadUsage is what the pods of a workload requested on one node over the window
+type WorkloadUsage struct {
+	Namespace      string
+	Workload       string
+	Node           string
+	CPUCoreHours   float64
+	MemoryGiBHours float64
+}
+
+// CostRow is the estimated cost of a namespace or workload
+type CostRow struct {
+	Name           string  `json:"name"`
+	Namespace      string  `json:"namespace,omitempty"`
+	CPUCoreHours   float64 `json:"cpuCoreHours"`
+	MemoryGiBHours float64 `json:"memoryGiBHours"`
+	CPUCost        float64 `json:"cpuCost"`
+	MemoryCost     float64 `json:"memoryCost"`
+	TotalCost      float64 `json:"totalCost"`
+	SharePercent   float64 `json:"sharePercent"`
+}
+
+// PoolCost is the cost of the nodes of one node pool
+type PoolCost struct {
+	Pool        string  `json:"pool"`
+	VMSize      string  `json:"vmSize"`
+	Spot        bool    `json:"spot,omitempty"`
+	Windows     bool    `json:"windows,omitempty"`
+	Nodes       int     `json:"nodes"`
+	NodeHours   float64 `json:"nodeHours"`
+	HourlyPrice float64 `json:"hourlyPrice"`
+	Cost        float64 `json:"cost"`
+}
+
+// CostReport is the result returned by the aks_cost_breakdown tool
+type CostReport struct {
+	ClusterName   string     `json:"clusterName"`
+	Location      string     `json:"location"`
+	Currency      string     `json:"currency"`
+	Source        string     `json:"source"`
+	StartTime     string     `json:"startTime"`
+	EndTime       string     `json:"endTime"`
+	GroupBy       string     `json:"groupBy"`
+	TotalCost     float64    `json:"totalCost"`
+	AllocatedCost float64    `json:"allocatedCost"`
+	IdleCost      float64    `json:"idleCost"`
+	Breakdown     []CostRow  `json:"breakdown"`
+	Truncated     int        `json:"truncated,omitempty"`
+	Pools         []PoolCost `json:"pools"`
+	Warnings      []string   `json:"warnings,omitempty"`
+	Note          string     `json:"note"`
+}
+
+// GetCostBreakdownHandler returns a handler for the aks_cost_breakdown command
+func GetCostBreakdownHandler(api common.ARMClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleCostBreakdown(params, api, defaultPrices, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleCostBreakdown estimates the cost per namespace or workload over the window. Requests come from the
+// Container Insights workspace of the cluster when it has one, otherwise from the pods running now.
+func HandleCostBreakdown(params map[string]interface{}, api common.ARMClient, prices PriceSource, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	hours := defaultHours
+	if raw, ok := params["hours"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n > maxHours {
+			return "", fmt.Errorf("invalid hours: expected a number between 1 and %d", maxHours)
+		}
+		hours = int(n)
+	}
+	groupBy, _ := params["group_by"].(string)
+	if groupBy == "" {
+		groupBy = GroupByNamespace
+	}
+	if groupBy != GroupByNamespace && groupBy != GroupByWorkload {
+		return "", fmt.Errorf("invalid group_by %q: expected %s or %s", groupBy, GroupByNamespace, GroupByWorkload)
+	}
+	currency, _ := params["currency"].(string)
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	currency = strings.ToUpper(currency)
+	if !currencyPattern.MatchString(currency) {
+		return "", fmt.Errorf("invalid currency %q: expected an ISO 4217 code such as USD or EUR", currency)
+	}
+	limit := defaultRows
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = int(n)
+	}
+
+	ctx := context.Background()
+	end := time.Now().UTC().Truncate(time.Minute)
+	start := end.Add(-time.Duration(hours) * time.Hour)
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+
+	location, workspaceID, err := getCluster(ctx, api, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	var warnings []string
+	var nodes []NodeUsage
+	var usage []WorkloadUsage
+	source := SourceContainerInsights
+	if workspaceID == "" {
+		source = SourceSnapshot
+	} else {
+		nodes, usage, err = ReadContainerInsights(ctx, api, workspaceID, clusterID, start, end)
+		if err == nil && (len(nodes) == 0 || len(usage) == 0) {
+			err = fmt.Errorf("the workspace has no node or pod data for the cluster in the window")
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Container Insights could not be used (%v); falling back to the pods running now", err))
+			source = SourceSnapshot
+		}
+	}
+	if source == SourceSnapshot {
+		kubectlRun := func(command string) (string, error) {
+			return kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+		}
+		nodes, usage, err = ReadSnapshot(kubectlRun, cfg.AllowNamespaces, start, end)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Only the allowed namespaces are shown; idle cost would include the others, so it is left out
+	restricted := cfg.AllowNamespaces != ""
+	if restricted {
+		security := k8s.ConvertConfig(cfg).SecurityConfig
+		allowed := usage[:0]
+		for _, u := range usage {
+			if security.IsNamespaceAllowed(u.Namespace) {
+				allowed = append(allowed, u)
+			}
+		}
+		usage = allowed
+	}
+
+	hourly := make(map[PriceKey]float64)
+	for _, node := range nodes {
+		key := PriceKey{Region: location, VMSize: node.VMSize, Spot: node.Spot, Windows: node.Windows}
+		if _, ok := hourly[key]; ok || node.VMSize == "" {
+			continue
+		}
+		price, err := prices.HourlyPrice(ctx, key, currency)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Nodes of size %s are not priced: %v", node.VMSize, err))
+			price = 0
+		}
+		hourly[key] = price
+	}
+
+	report := BuildCostReport(nodes, usage, location, hourly, groupBy, !restricted)
+	report.ClusterName = clusterName
+	report.Location = location
+	report.Currency = currency
+	report.Source = source
+	report.StartTime = start.Format(time.RFC3339)
+	report.EndTime = end.Format(time.RFC3339)
+	report.Warnings = append(warnings, report.Warnings...)
+	if len(report.Breakdown) > limit {
+		report.Truncated = len(report.Breakdown) - limit
+		report.Breakdown = report.Breakdown[:limit]
+	}
+
+	note := "Estimates from pay-as-you-go retail VM prices, without reservations, savings plans, discounts, disks, networking or the AKS tier fee. " +
+		"Each node's price is split between CPU and memory and charged to pods by what they requested on it."
+	if source == SourceSnapshot {
+		note += " Requests are those of the pods running now, assumed constant since they started; enable Container Insights for requests over the whole window."
+	}
+	if restricted {
+		note += " Only the namespaces allowed by --allow-namespaces are shown, so idle cost is omitted."
+	}
+	report.Note = note
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cost report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// getCluster returns the cluster's region and the workspace of its Container Insights add-on, if enabled
+func getCluster(ctx context.Context, api common.ARMClient, clusterID string) (string, string, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+clusterAPIVersion)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	var cluster struct {
+		Location   string `json:"location"`
+		Properties struct {
+			AddonProfiles map[string]struct {
+				Enabled bool              `json:"enabled"`
+				Config  map[string]string `json:"config"`
+			} `json:"addonProfiles"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+	for name, addon := range cluster.Properties.AddonProfiles {
+		if !strings.EqualFold(name, "omsagent") || !addon.Enabled {
+			continue
+		}
+		for key, value := range addon.Config {
+			if strings.EqualFold(key, "logAnalyticsWorkspaceResourceID") {
+				return cluster.Location, value, nil
+			}
+		}
+	}
+	return cluster.Location, "", nil
+}
+
+// ReadContainerInsights reads node hours and the hourly requests of pods per node from the Container Insights
+// KubeNodeInventory, KubePodInventory and Perf tables
+func ReadContainerInsights(ctx context.Context, api common.ARMClient, workspaceID, clusterID string, start, end time.Time) ([]NodeUsage, []WorkloadUsage, error) {
+	window := fmt.Sprintf("let startTime = datetime(%s); let endTime = datetime(%s);", start.Format(time.RFC3339), end.Format(time.RFC3339))
+
+	rows, err := queryWorkspace(ctx, api, workspaceID, start, end, window+`
+let allocatable = Perf
+| where TimeGenerated between (startTime .. endTime) and ObjectName == 'K8SNode' and InstanceName startswith '`+clusterID+`'
+| where CounterName in ('cpuAllocatableNanoCores', 'memoryAllocatableBytes')
+| summarize CPUCores = maxif(CounterValue, CounterName == 'cpuAllocatableNanoCores') / 1e9,
+    MemoryGiB = maxif(CounterValue, CounterName == 'memoryAllocatableBytes') / 1073741824.0 by Node = Computer;
+KubeNodeInventory
+| where TimeGenerated between (startTime .. endTime) and ClusterId =~ '`+clusterID+`'
+| extend NodeLabels = parse_json(Labels)[0]
+| summarize Hours = dcount(bin(TimeGenerated, 1h)),
+    VMSize = take_any(tostring(NodeLabels['node.kubernetes.io/instance-type'])),
+    Pool = take_any(tostring(NodeLabels['kubernetes.azure.com/agentpool'])),
+    Priority = take_any(tostring(NodeLabels['kubernetes.azure.com/scalesetpriority'])),
+    OS = take_any(tostring(NodeLabels['kubernetes.io/os'])) by Node = Computer
+| join kind=leftouter allocatable on Node
+| project Node, Pool, VMSize, Priority, OS, Hours, CPUCores, MemoryGiB`)
+	if err != nil {
+		return nil, nil, err
+	}
+	var nodes []NodeUsage
+	for _, row := range rows {
+		nodes = append(nodes, NodeUsage{
+			Name:      rowString(row, "Node"),
+			Pool:      rowString(row, "Pool"),
+			VMSize:    rowString(row, "VMSize"),
+			Spot:      strings.EqualFold(rowString(row, "Priority"), "spot"),
+			Windows:   strings.EqualFold(rowString(row, "OS"), "windows"),
+			Hours:     rowNumber(row, "Hours"),
+			CPUCores:  rowNumber(row, "CPUCores"),
+			MemoryGiB: rowNumber(row, "MemoryGiB"),
+		})
+	}
+
+	// Container request counters are averaged per hour and summed, so each pod is charged for the hours it ran
+	rows, err = queryWorkspace(ctx, api, workspaceID, start, end, window+`
+let pods = KubePodInventory
+| where TimeGenerated between (startTime .. endTime) and ClusterId =~ '`+clusterID+`'
+| summarize arg_max(TimeGenerated, Namespace, ControllerKind, ControllerName, Computer) by PodUid;
+Perf
+| where TimeGenerated between (startTime .. endTime) and ObjectName == 'K8SContainer' and InstanceName startswith '`+clusterID+`'
+| where CounterName in ('cpuRequestNanoCores', 'memoryRequestBytes')
+| extend Parts = split(InstanceName, '/')
+| extend PodUid = tostring(Parts[array_length(Parts) - 2]), Container = tostring(Parts[array_length(Parts) - 1])
+| summarize Value = avg(CounterValue) by PodUid, Container, CounterName, Hour = bin(TimeGenerated, 1h)
+| join kind=inner pods on PodUid
+| summarize CPUCoreHours = sumif(Value, CounterName == 'cpuRequestNanoCores') / 1e9,
+    MemoryGiBHours = sumif(Value, CounterName == 'memoryRequestBytes') / 1073741824.0
+    by Namespace, ControllerKind, ControllerName, Node = Computer`)
+	if err != nil {
+		return nil, nil, err
+	}
+	var usage []WorkloadUsage
+	for _, row := range rows {
+		usage = append(usage, WorkloadUsage{
+			Namespace:      rowString(row, "Namespace"),
+			Workload:       workloadName(rowString(row, "ControllerKind"), rowString(row, "ControllerName"), ""),
+			Node:           rowString(row, "Node"),
+			CPUCoreHours:   rowNumber(row, "CPUCoreHours"),
+			MemoryGiBHours: rowNumber(row, "MemoryGiBHours"),
+		})
+	}
+	return nodes, usage, nil
+}
+
+// queryWorkspace runs a Log Analytics query through ARM and returns the rows of the first table
+func queryWorkspace(ctx context.Context, api common.ARMClient, workspaceID string, start, end time.Time, query string) ([]map[string]interface{}, error) {
+	body, err := api.CallARMWithBody(ctx, http.MethodPost, workspaceID+"/api/query?api-version="+logAnalyticsQueryAPIVersion, map[string]string{
+		"query":    query,
+		"timespan": start.Format(time.RFC3339) + "/" + end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Log Analytics workspace: %w", err)
+	}
+	var result struct {
+		Tables []struct {
+			Columns []struct {
+				Name string `json:"name"`
+			} `json:"columns"`
+			Rows [][]interface{} `json:"rows"`
+		} `json:"tables"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse Log Analytics query results: %w", err)
+	}
+	if len(result.Tables) == 0 {
+		return nil, nil
+	}
+	table := result.Tables[0]
+	rows := make([]map[string]interface{}, 0, len(table.Rows))
+	for _, values := range table.Rows {
+		row := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			if i < len(table.Columns) {
+				row[table.Columns[i].Name] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ReadSnapshot reads the nodes and the requests of the pods running now, and charges each pod for the hours
+// of the window since it started
+func ReadSnapshot(kubectlRun func(string) (string, error), allowNamespaces string, start, end time.Time) ([]NodeUsage, []WorkloadUsage, error) {
+	output, err := kubectlRun("get nodes -o json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodes, err := ParseNodes(output, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var usage []WorkloadUsage
+	for _, flag := range common.NamespaceFlags(allowNamespaces) {
+		output, err := kubectlRun("get pods " + flag + " --field-selector status.phase=Running -o json")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list pods: %v", err)
+		}
+		parsed, err := ParsePods(output, start, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		usage = append(usage, parsed...)
+	}
+	return nodes, usage, nil
+}
+
+// ParseNodes reads the size, pool, priority, OS and allocatable resources of nodes in kubectl get nodes -o json
+// output, with the hours of the window each node existed
+func ParseNodes(output string, start, end time.Time) ([]NodeUsage, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string            `json:"name"`
+				CreationTimestamp time.Time         `json:"creationTimestamp"`
+				Labels            map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+	nodes := make([]NodeUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		labels := item.Metadata.Labels
+		nodes = append(nodes, NodeUsage{
+			Name:      item.Metadata.Name,
+			Pool:      labels["kubernetes.azure.com/agentpool"],
+			VMSize:    labels["node.kubernetes.io/instance-type"],
+			Spot:      labels["kubernetes.azure.com/scalesetpriority"] == "spot",
+			Windows:   labels["kubernetes.io/os"] == "windows",
+			Hours:     hoursSince(item.Metadata.CreationTimestamp, start, end),
+			CPUCores:  float64(common.CPUMillis(item.Status.Allocatable["cpu"])) / 1000,
+			MemoryGiB: float64(common.MemoryBytes(item.Status.Allocatable["memory"])) / (1 << 30),
+		})
+	}
+	return nodes, nil
+}
+
+// ParsePods reads the requests of running pods in kubectl get pods -o json output, multiplied by the hours of
+// the window since each pod started. A pod requests the larger of its containers' sum and its largest init container.
+func ParsePods(output string, start, end time.Time) ([]WorkloadUsage, error) {
+	type container struct {
+		Resources struct {
+			Requests map[string]string `json:"requests"`
+		} `json:"resources"`
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				Labels          map[string]string `json:"labels"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName       string      `json:"nodeName"`
+				Containers     []container `json:"containers"`
+				InitContainers []container `json:"initContainers"`
+			} `json:"spec"`
+			Status struct {
+				Phase     string    `json:"phase"`
+				StartTime time.Time `json:"startTime"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %v", err)
+	}
+
+	var usage []WorkloadUsage
+	for _, item := range list.Items {
+		if item.Status.Phase != "Running" || item.Spec.NodeName == "" {
+			continue
+		}
+		var cpu, memory, initCPU, initMemory int64
+		for _, c := range item.Spec.Containers {
+			cpu += common.CPUMillis(c.Resources.Requests["cpu"])
+			memory += common.MemoryBytes(c.Resources.Requests["memory"])
+		}
+		for _, c := range item.Spec.InitContainers {
+			initCPU = max(initCPU, common.CPUMillis(c.Resources.Requests["cpu"]))
+			initMemory = max(initMemory, common.MemoryBytes(c.Resources.Requests["memory"]))
+		}
+		kind, owner := "", ""
+		if refs := item.Metadata.OwnerReferences; len(refs) > 0 {
+			kind, owner = refs[0].Kind, refs[0].Name
+		}
+		hours := hoursSince(item.Status.StartTime, start, end)
+		usage = append(usage, WorkloadUsage{
+			Namespace:      item.Metadata.Namespace,
+			Workload:       workloadName(kind, owner, item.Metadata.Labels["pod-template-hash"]),
+			Node:           item.Spec.NodeName,
+			CPUCoreHours:   float64(max(cpu, initCPU)) / 1000 * hours,
+			MemoryGiBHours: float64(max(memory, initMemory)) / (1 << 30) * hours,
+		})
+	}
+	return usage, nil
+}
+
+// BuildCostReport prices the node hours of each pool and charges each node's cost to the workloads by what they
+// requested on it. Requests on nodes that are unknown or unpriced are not charged and listed in warnings.
+func BuildCostReport(nodes []NodeUsage, usage []WorkloadUsage, location string, hourly map[PriceKey]float64, groupBy string, includeIdle bool) CostReport {
+	report := CostReport{GroupBy: groupBy, Breakdown: []CostRow{}, Pools: []PoolCost{}}
+
+	type rates struct{ cpu, memory, cost float64 }
+	byNode := make(map[string]*rates, len(nodes))
+	pools := make(map[string]*PoolCost)
+	var poolOrder []string
+	for _, node := range nodes {
+		key := PriceKey{Region: location, VMSize: node.VMSize, Spot: node.Spot, Windows: node.Windows}
+		price := hourly[key]
+		cost := price * node.Hours
+		report.TotalCost += cost
+
+		poolKey := fmt.Sprintf("%s/%s/%t/%t", node.Pool, node.VMSize, node.Spot, node.Windows)
+		pool, ok := pools[poolKey]
+		if !ok {
+			pool = &PoolCost{Pool: node.Pool, VMSize: node.VMSize, Spot: node.Spot, Windows: node.Windows, HourlyPrice: price}
+			pools[poolKey] = pool
+			poolOrder = append(poolOrder, poolKey)
+		}
+		pool.Nodes++
+		pool.NodeHours += node.Hours
+		pool.Cost += cost
+
+		// The price of a node is split so that one core costs as much as cpuToMemoryCostRatio GiB of memory
+		weight := node.CPUCores*cpuToMemoryCostRatio + node.MemoryGiB
+		if price == 0 || weight == 0 {
+			continue
+		}
+		memoryRate := price / weight
+		byNode[node.Name] = &rates{cpu: memoryRate * cpuToMemoryCostRatio, memory: memoryRate, cost: cost}
+	}
+
+	// Requests can briefly exceed what a node is charged for at hourly granularity, so charges are capped per node
+	charged := make(map[string]float64)
+	for _, u := range usage {
+		if r, ok := byNode[u.Node]; ok {
+			charged[u.Node] += u.CPUCoreHours*r.cpu + u.MemoryGiBHours*r.memory
+		}
+	}
+
+	rows := make(map[string]*CostRow)
+	uncharged := make(map[string]bool)
+	for _, u := range usage {
+		name, namespace := u.Namespace, ""
+		if groupBy == GroupByWorkload {
+			name, namespace = u.Workload, u.Namespace
+		}
+		rowKey := namespace + "/" + name
+		row, ok := rows[rowKey]
+		if !ok {
+			row = &CostRow{Name: name, Namespace: namespace}
+			rows[rowKey] = row
+		}
+		row.CPUCoreHours += u.CPUCoreHours
+		row.MemoryGiBHours += u.MemoryGiBHours
+
+		r, ok := byNode[u.Node]
+		if !ok {
+			uncharged[u.Node] = true
+			continue
+		}
+		scale := 1.0
+		if total := charged[u.Node]; total > r.cost {
+			scale = r.cost / total
+		}
+		row.CPUCost += u.CPUCoreHours * r.cpu * scale
+		row.MemoryCost += u.MemoryGiBHours * r.memory * scale
+	}
+
+	for _, row := range rows {
+		row.TotalCost = row.CPUCost + row.MemoryCost
+		report.AllocatedCost += row.TotalCost
+		report.Breakdown = append(report.Breakdown, *row)
+	}
+	report.IdleCost = math.Max(report.TotalCost-report.AllocatedCost, 0)
+	if includeIdle && report.IdleCost > 0 {
+		report.Breakdown = append(report.Breakdown, CostRow{Name: IdleRow, TotalCost: report.IdleCost})
+	}
+	if !includeIdle {
+		report.IdleCost = 0
+	}
+
+	for i := range report.Breakdown {
+		row := &report.Breakdown[i]
+		if report.TotalCost > 0 {
+			row.SharePercent = roundTo(row.TotalCost*100/report.TotalCost, 1)
+		}
+		row.CPUCoreHours = roundTo(row.CPUCoreHours, 2)
+		row.MemoryGiBHours = roundTo(row.MemoryGiBHours, 2)
+		row.CPUCost = roundTo(row.CPUCost, 2)
+		row.MemoryCost = roundTo(row.MemoryCost, 2)
+		row.TotalCost = roundTo(row.TotalCost, 2)
+	}
+	sort.SliceStable(report.Breakdown, func(i, j int) bool {
+		a, b := report.Breakdown[i], report.Breakdown[j]
+		if a.TotalCost != b.TotalCost {
+			return a.TotalCost > b.TotalCost
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	for _, key := range poolOrder {
+		pool := pools[key]
+		pool.NodeHours = roundTo(pool.NodeHours, 1)
+		pool.Cost = roundTo(pool.Cost, 2)
+		report.Pools = append(report.Pools, *pool)
+	}
+	sort.SliceStable(report.Pools, func(i, j int) bool { return report.Pools[i].Cost > report.Pools[j].Cost })
+
+	for node := range uncharged {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Requests on node %s are not charged because the node is unknown or unpriced", node))
+	}
+	sort.Strings(report.Warnings)
+
+	report.TotalCost = roundTo(report.TotalCost, 2)
+	report.AllocatedCost = roundTo(report.AllocatedCost, 2)
+	report.IdleCost = roundTo(report.IdleCost, 2)
+	return report
+}
+
+// workloadName names the controller owning a pod, resolving ReplicaSets to their Deployment. podTemplateHash
+// is the pod's pod-template-hash label when known; otherwise the ReplicaSet suffix is recognized by its shape.
+func workloadName(kind, name, podTemplateHash string) string {
+	switch {
+	case kind == "" || name == "":
+		return "(standalone pods)"
+	case kind == "ReplicaSet" && podTemplateHash != "" && strings.HasSuffix(name, "-"+podTemplateHash):
+		return "Deployment/" + strings.TrimSuffix(name, "-"+podTemplateHash)
+	case kind == "ReplicaSet" && podTemplateHash == "":
+		if m := replicaSetSuffix.FindStringSubmatch(name); m != nil {
+			return "Deployment/" + m[1]
+		}
+	}
+	return kind + "/" + name
+}
+
+// hoursSince returns the hours of the window after t
+func hoursSince(t, start, end time.Time) float64 {
+	if t.After(start) {
+		start = t
+	}
+	if !start.Before(end) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
+
+func rowString(row map[string]interface{}, key string) string {
+	value, _ := row[key].(string)
+	return value
+}
+
+func rowNumber(row map[string]interface{}, key string) float64 {
+	value, _ := row[key].(float64)
+	return value
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
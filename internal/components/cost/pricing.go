@@ -0,0 +1,147 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retailPricesURL is the public, unauthenticated Azure Retail Prices API
+const retailPricesURL = "https://prices.azure.com/api/retail/prices"
+
+// retailPricesTimeout bounds each Retail Prices API request
+const retailPricesTimeout = 20 * time.Second
+
+// maxRetailPricePages bounds NextPageLink paging for a single VM size
+const maxRetailPricePages = 3
+
+// armNamePattern matches region and VM size names, which are interpolated into the price filter
+var armNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// PriceKey identifies the hourly price of a node: a VM size in a region, with its OS and priority
+type PriceKey struct {
+	Region  string
+	VMSize  string
+	Spot    bool
+	Windows bool
+}
+
+// PriceSource returns pay-as-you-go hourly VM prices
+type PriceSource interface {
+	HourlyPrice(ctx context.Context, key PriceKey, currency string) (float64, error)
+}
+
+// RetailPrices reads hourly VM prices from the Azure Retail Prices API and caches them for the life of the server
+type RetailPrices struct {
+	client *http.Client
+	mu     sync.Mutex
+	cache  map[string]float64
+}
+
+// NewRetailPrices creates a price source backed by the Azure Retail Prices API
+func NewRetailPrices() *RetailPrices {
+	return &RetailPrices{
+		client: &http.Client{Timeout: retailPricesTimeout},
+		cache:  make(map[string]float64),
+	}
+}
+
+// RetailPriceItem is the subset of a Retail Prices API item used to pick a VM price
+type RetailPriceItem struct {
+	RetailPrice          float64 `json:"retailPrice"`
+	UnitOfMeasure        string  `json:"unitOfMeasure"`
+	SkuName              string  `json:"skuName"`
+	ProductName          string  `json:"productName"`
+	IsPrimaryMeterRegion bool    `json:"isPrimaryMeterRegion"`
+}
+
+// HourlyPrice returns the pay-as-you-go price of one hour of the VM size, for Linux or Windows and regular or Spot priority
+func (p *RetailPrices) HourlyPrice(ctx context.Context, key PriceKey, currency string) (float64, error) {
+	if !armNamePattern.MatchString(key.Region) || !armNamePattern.MatchString(key.VMSize) {
+		return 0, fmt.Errorf("invalid region %q or VM size %q", key.Region, key.VMSize)
+	}
+	cacheKey := fmt.Sprintf("%s/%s/%t/%t/%s", strings.ToLower(key.Region), strings.ToLower(key.VMSize), key.Spot, key.Windows, currency)
+	p.mu.Lock()
+	price, ok := p.cache[cacheKey]
+	p.mu.Unlock()
+	if ok {
+		return price, nil
+	}
+
+	query := url.Values{}
+	query.Set("currencyCode", currency)
+	query.Set("$filter", fmt.Sprintf("serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and armRegionName eq '%s' and armSkuName eq '%s'",
+		strings.ToLower(key.Region), key.VMSize))
+	next := retailPricesURL + "?" + query.Encode()
+
+	var items []RetailPriceItem
+	for page := 0; next != "" && page < maxRetailPricePages; page++ {
+		body, err := p.get(ctx, next)
+		if err != nil {
+			return 0, err
+		}
+		var result struct {
+			Items        []RetailPriceItem `json:"Items"`
+			NextPageLink string            `json:"NextPageLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return 0, fmt.Errorf("failed to parse retail prices: %v", err)
+		}
+		items = append(items, result.Items...)
+		next = result.NextPageLink
+	}
+
+	price, ok = SelectHourlyPrice(items, key)
+	if !ok {
+		return 0, fmt.Errorf("no retail price found for %s in %s", key.VMSize, key.Region)
+	}
+	p.mu.Lock()
+	p.cache[cacheKey] = price
+	p.mu.Unlock()
+	return price, nil
+}
+
+func (p *RetailPrices) get(ctx context.Context, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("retail prices request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retail prices: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("retail prices request returned HTTP %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// SelectHourlyPrice picks the hourly meter of the VM size matching the OS and priority, preferring the
+// primary meter region. Low Priority meters (the predecessor of Spot) are never used.
+func SelectHourlyPrice(items []RetailPriceItem, key PriceKey) (float64, bool) {
+	price, found, primary := 0.0, false, false
+	for _, item := range items {
+		if item.UnitOfMeasure != "1 Hour" || item.RetailPrice <= 0 || strings.Contains(item.SkuName, "Low Priority") {
+			continue
+		}
+		if strings.Contains(item.SkuName, "Spot") != key.Spot || strings.Contains(item.ProductName, "Windows") != key.Windows {
+			continue
+		}
+		if !found || (item.IsPrimaryMeterRegion && !primary) {
+			price, found, primary = item.RetailPrice, true, item.IsPrimaryMeterRegion
+		}
+	}
+	return price, found
+}
@@ -0,0 +1,56 @@
+package cost
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterCostBreakdownTool registers the aks_cost_breakdown tool
+func RegisterCostBreakdownTool() mcp.Tool {
+	description := fmt.Sprintf(`Estimate what each namespace or workload of an AKS cluster costs over the last N hours, without OpenCost.
+
+Node cost is the pay-as-you-go hourly retail price of the node's VM size (Linux or Windows, regular or Spot) in the
+cluster's region, from the Azure Retail Prices API, times the hours the node ran. Each node's price is split between
+CPU and memory, with one core costing as much as %.1f GiB, and charged to pods by what they requested on that node.
+Cost no pod requested is reported as the %s row.
+
+Requests over the window come from Container Insights (KubeNodeInventory, KubePodInventory and Perf) when the
+monitoring add-on is enabled; otherwise the pods running now are read with kubectl, assumed constant since they
+started. Estimates exclude reservations, savings plans, discounts, disks, networking and the AKS tier fee.
+With --allow-namespaces only the allowed namespaces are shown and idle cost is omitted.
+
+Returns the breakdown sorted by cost, the cost of each node pool, and warnings for nodes that could not be priced.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", hours=168, group_by="workload"`, cpuToMemoryCostRatio, IdleRow)
+
+	return mcp.NewTool(
+		"aks_cost_breakdown",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description(fmt.Sprintf("How many hours back to estimate (default: %d, at most %d)", defaultHours, maxHours)),
+		),
+		mcp.WithString("group_by",
+			mcp.Description("Break costs down per namespace or per workload (default: namespace)"),
+			mcp.Enum(GroupByNamespace, GroupByWorkload),
+		),
+		mcp.WithString("currency",
+			mcp.Description(fmt.Sprintf("ISO 4217 currency of the retail prices (default: %s)", defaultCurrency)),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum rows in the breakdown (default: %d)", defaultRows)),
+		),
+	)
+}
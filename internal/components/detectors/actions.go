@@ -0,0 +1,482 @@
+package detectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Output formats of run_detector and run_detectors_by_category
+const (
+	FormatFull       = "full"
+	FormatActionable = "actionable"
+)
+
+// ActionableFinding is a detector insight reduced to its status, a plain-text explanation and the
+// recommended actions, with the commands or tool calls that remediate it when the finding is recognized
+type ActionableFinding struct {
+	Status             string        `json:"status"`
+	Title              string        `json:"title"`
+	Details            []string      `json:"details,omitempty"`
+	RecommendedActions []string      `json:"recommendedActions,omitempty"`
+	Links              []string      `json:"links,omitempty"`
+	Remediations       []Remediation `json:"remediations,omitempty"`
+}
+
+// Remediation is a command or an aks-mcp tool call that addresses a finding
+type Remediation struct {
+	Description string                 `json:"description"`
+	Command     string                 `json:"command,omitempty"`
+	Tool        string                 `json:"tool,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	// AccessLevel is the access level the command or tool call needs: readonly, readwrite or admin
+	AccessLevel string `json:"accessLevel"`
+}
+
+// ActionableResult is the actionable form of one detector run
+type ActionableResult struct {
+	Detector string              `json:"detector"`
+	Name     string              `json:"name,omitempty"`
+	Status   string              `json:"status"`
+	Findings []ActionableFinding `json:"findings"`
+}
+
+// clusterRef identifies the cluster a detector ran against, to fill in remediation commands
+type clusterRef struct {
+	subscriptionID string
+	resourceGroup  string
+	clusterName    string
+	location       string
+}
+
+var (
+	htmlLinkPattern     = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	bareLinkPattern     = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+	htmlCodePattern     = regexp.MustCompile(`(?is)<(?:code|pre)[^>]*>(.*?)</(?:code|pre)>`)
+	fencedCodePattern   = regexp.MustCompile("(?s)```[a-zA-Z]*\\n?(.*?)```")
+	inlineCodePattern   = regexp.MustCompile("`([^`\n]+)`")
+	lineBreakPattern    = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>|</h[1-6]>`)
+	listItemPattern     = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlTagPattern      = regexp.MustCompile(`<[^>]+>`)
+	listMarkerPattern   = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+	// commandPlaceholders are placeholders detector content uses in commands, with the cluster value they stand for
+	commandPlaceholders = []struct {
+		pattern *regexp.Regexp
+		value   func(c clusterRef) string
+	}{
+		{regexp.MustCompile(`(?i)<(?:resource[-_ ]?group(?:[-_ ]?name)?|rg)>|\$\{?(?:RESOURCE_GROUP|RG)\}?|\bmyResourceGroup\b`), func(c clusterRef) string { return c.resourceGroup }},
+		{regexp.MustCompile(`(?i)<(?:aks[-_ ]?)?cluster[-_ ]?name>|<aks[-_ ]?name>|\$\{?(?:CLUSTER_NAME|AKS_NAME)\}?|\bmyAKSCluster\b`), func(c clusterRef) string { return c.clusterName }},
+		{regexp.MustCompile(`(?i)<subscription[-_ ]?id>|\$\{?SUBSCRIPTION_ID\}?`), func(c clusterRef) string { return c.subscriptionID }},
+	}
+)
+
+// Data names whose values are the recommended actions of an insight, and names whose values are its explanation
+var (
+	actionDataNames      = []string{"recommend", "action", "solution", "mitigat", "next step", "how to fix", "resolution"}
+	descriptionDataNames = []string{"description", "detail", "summary", "reason", "why", "customer ready content"}
+)
+
+// BuildActionableResult reduces a detector run to its critical, warning and info insights
+func BuildActionableResult(result DetectorRunResponse, cluster clusterRef) ActionableResult {
+	actionable := ActionableResult{
+		Detector: result.Name,
+		Name:     result.Properties.Metadata.Name,
+		Status:   statusName(result.Properties.Status.StatusID),
+		Findings: ExtractActionableFindings(result),
+	}
+	if cluster.location == "" {
+		cluster.location = result.Location
+	}
+	for i := range actionable.Findings {
+		actionable.Findings[i].Remediations = remediationsFor(actionable.Findings[i], cluster)
+	}
+	return actionable
+}
+
+// ExtractActionableFindings groups the rows of the insight tables of a detector run into findings. An insight
+// is one Status and Message pair with any number of Data.Name and Data.Value rows; values named like a
+// recommendation become the recommended actions and the others the details. Success insights are dropped.
+func ExtractActionableFindings(result DetectorRunResponse) []ActionableFinding {
+	var findings []ActionableFinding
+	index := map[string]int{}
+	for _, dataset := range result.Properties.Dataset {
+		columns := map[string]int{}
+		for i, column := range dataset.Table.Columns {
+			columns[strings.ToLower(column.ColumnName)] = i
+		}
+		statusColumn, hasStatus := columns["status"]
+		messageColumn, hasMessage := columns["message"]
+		if !hasStatus || !hasMessage {
+			continue
+		}
+		for _, row := range dataset.Table.Rows {
+			status := strings.ToLower(cell(row, statusColumn))
+			if status != StatusCritical && status != StatusWarning && status != StatusInfo {
+				continue
+			}
+			title, links := plainText(cell(row, messageColumn))
+			key := status + "\x00" + title
+			i, ok := index[key]
+			if !ok {
+				i = len(findings)
+				index[key] = i
+				findings = append(findings, ActionableFinding{Status: status, Title: title})
+			}
+			finding := &findings[i]
+			finding.Links = appendUnique(finding.Links, links...)
+
+			name, _ := plainText(cell(row, columnIndex(columns, "data.name", "name")))
+			value := cell(row, columnIndex(columns, "data.value", "value"))
+			if value != "" {
+				text, links := plainText(value)
+				finding.Links = appendUnique(finding.Links, links...)
+				finding.RecommendedActions, finding.Details = classifyData(name, text, finding.RecommendedActions, finding.Details)
+				finding.RecommendedActions = appendUnique(finding.RecommendedActions, codeCommands(value)...)
+			}
+			finding.RecommendedActions = appendUnique(finding.RecommendedActions, solutionTitles(cell(row, columnIndex(columns, "solutions")))...)
+		}
+	}
+
+	status := statusName(result.Properties.Status.StatusID)
+	if len(findings) == 0 && (status == StatusCritical || status == StatusWarning) {
+		title := "detector reported " + status
+		if result.Properties.Status.Message != nil && *result.Properties.Status.Message != "" {
+			title, _ = plainText(*result.Properties.Status.Message)
+		}
+		findings = append(findings, ActionableFinding{Status: status, Title: title})
+	}
+	return findings
+}
+
+// classifyData files the text of a Data.Name and Data.Value pair as recommended actions or details
+func classifyData(name, text string, actions, details []string) ([]string, []string) {
+	lowerName := strings.ToLower(name)
+	for _, actionName := range actionDataNames {
+		if strings.Contains(lowerName, actionName) {
+			for _, line := range strings.Split(text, "\n") {
+				actions = appendUnique(actions, listMarkerPattern.ReplaceAllString(line, ""))
+			}
+			return actions, details
+		}
+	}
+	described := slices.ContainsFunc(descriptionDataNames, func(n string) bool { return strings.Contains(lowerName, n) })
+	if name != "" && !described && !strings.Contains(text, "\n") && len(text) < 200 {
+		return actions, appendUnique(details, name+": "+text)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		details = appendUnique(details, line)
+	}
+	return actions, details
+}
+
+// solutionTitles returns the titles of the solutions attached to an insight as a JSON list
+func solutionTitles(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var solutions []struct {
+		Title string `json:"Title"`
+	}
+	if err := json.Unmarshal([]byte(value), &solutions); err != nil {
+		return nil
+	}
+	var titles []string
+	for _, solution := range solutions {
+		if title, _ := plainText(solution.Title); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	return titles
+}
+
+// codeCommands returns the az and kubectl commands in the code spans and blocks of HTML or markdown
+func codeCommands(value string) []string {
+	var commands []string
+	for _, pattern := range []*regexp.Regexp{htmlCodePattern, fencedCodePattern, inlineCodePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(value, -1) {
+			for _, line := range strings.Split(html.UnescapeString(htmlTagPattern.ReplaceAllString(match[1], "")), "\n") {
+				line = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "$ ")), " ")
+				if strings.HasPrefix(line, "az ") || strings.HasPrefix(line, "kubectl ") {
+					commands = appendUnique(commands, line)
+				}
+			}
+		}
+	}
+	return commands
+}
+
+// plainText converts the HTML or markdown of detector content to plain text lines and returns the
+// links it contained
+func plainText(value string) (string, []string) {
+	var links []string
+	value = htmlLinkPattern.ReplaceAllStringFunc(value, func(match string) string {
+		parts := htmlLinkPattern.FindStringSubmatch(match)
+		links = append(links, html.UnescapeString(parts[1]))
+		return parts[2]
+	})
+	value = markdownLinkPattern.ReplaceAllStringFunc(value, func(match string) string {
+		parts := markdownLinkPattern.FindStringSubmatch(match)
+		links = append(links, parts[2])
+		return parts[1]
+	})
+	links = appendUnique(links, bareLinkPattern.FindAllString(value, -1)...)
+
+	value = lineBreakPattern.ReplaceAllString(value, "\n")
+	value = listItemPattern.ReplaceAllString(value, "\n- ")
+	value = html.UnescapeString(htmlTagPattern.ReplaceAllString(value, ""))
+	value = strings.NewReplacer("**", "", "__", "", "```", "", "`", "").Replace(value)
+
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.Join(strings.Fields(strings.TrimLeft(line, "# ")), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), links
+}
+
+// remediation rules match the text of a finding and return the commands and tool calls that address it
+var remediationRules = []struct {
+	pattern *regexp.Regexp
+	build   func(c clusterRef) []Remediation
+}{
+	{
+		regexp.MustCompile(`kubernetes version.*(out of support|not supported|unsupported|end of life|deprecated)|unsupported (kubernetes )?version|upgrade (your|the) cluster`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{
+				azCommand(c, "List the Kubernetes versions the cluster can be upgraded to, then upgrade with az aks upgrade --kubernetes-version",
+					"az aks get-upgrades --resource-group %s --name %s --subscription %s --output table", readonlyAccess),
+				clusterTool(c, "Check the cluster for APIs and features removed in the target version", "aks_deprecated_features"),
+			}
+		},
+	},
+	{
+		regexp.MustCompile(`node image.*(outdated|old|behind|not (the )?latest|available|upgrade)|(outdated|older) node image`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{azCommand(c, "Upgrade the node images of all node pools to the latest version",
+				"az aks upgrade --resource-group %s --name %s --subscription %s --node-image-only --yes", readwriteAccess)}
+		},
+	},
+	{
+		regexp.MustCompile(`provisioning state.*failed|(is|in) (a )?failed state|failed provisioning`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{
+				azCommand(c, "Show the provisioning state and last error of the cluster",
+					"az aks show --resource-group %s --name %s --subscription %s --query \"{state: provisioningState, power: powerState.code}\"", readonlyAccess),
+				azCommand(c, "Reconcile the cluster to its current configuration once the cause is fixed",
+					"az aks update --resource-group %s --name %s --subscription %s", readwriteAccess),
+			}
+		},
+	},
+	{
+		regexp.MustCompile(`cluster (is|was) stopped|power ?state.*stopped`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{azCommand(c, "Start the cluster", "az aks start --resource-group %s --name %s --subscription %s", readwriteAccess)}
+		},
+	},
+	{
+		regexp.MustCompile(`snat|port exhaustion|outbound (ports?|connections?) .*exhaust`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{azCommand(c, "Show the outbound type and load balancer outbound settings; add outbound IPs or ports with az aks update --load-balancer-managed-outbound-ip-count or use a NAT gateway",
+				"az aks show --resource-group %s --name %s --subscription %s --query \"{outboundType: networkProfile.outboundType, loadBalancer: networkProfile.loadBalancerProfile}\"", readonlyAccess)}
+		},
+	},
+	{
+		regexp.MustCompile(`certificate.*(expir|rotat)`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{
+				clusterTool(c, "Report the expiry of the cluster certificates", "check_certificate_expiry"),
+				azCommand(c, "Rotate the cluster certificates (restarts nodes and briefly interrupts the API server)",
+					"az aks rotate-certs --resource-group %s --name %s --subscription %s --yes", readwriteAccess),
+			}
+		},
+	},
+	{
+		regexp.MustCompile(`\bdns\b|name resolution|coredns`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{clusterTool(c, "Validate the VNet DNS servers and CoreDNS forwarding", "aks_dns_validation")}
+		},
+	},
+	{
+		regexp.MustCompile(`egress|outbound connectivity|firewall|required (fqdn|endpoint|url)s?`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{clusterTool(c, "Find the firewall rules and denies affecting the cluster egress", "aks_egress_firewall_analysis")}
+		},
+	},
+	{
+		regexp.MustCompile(`notready|not ready|node.*(unhealthy|unreachable)`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{{Description: "List the nodes and their status", Command: "kubectl get nodes --output wide", AccessLevel: readonlyAccess}}
+		},
+	},
+	{
+		regexp.MustCompile(`(memory|cpu|disk|pid) pressure|oom|out of memory|resource (exhaustion|pressure)`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{{Description: "Compare node usage with the requests of the pods", Tool: "aks_resource_usage", AccessLevel: readonlyAccess}}
+		},
+	},
+	{
+		regexp.MustCompile(`upgrade.*(fail|stuck|blocked)|pod ?disruption ?budget|\bpdb\b`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{
+				clusterTool(c, "Report the upgrade progress and the nodes it is stuck on", "aks_upgrade_progress"),
+				{Description: "List the pod disruption budgets that can block node drains", Command: "kubectl get poddisruptionbudgets --all-namespaces", AccessLevel: readonlyAccess},
+			}
+		},
+	},
+	{
+		regexp.MustCompile(`throttl|too many requests|\b429\b|api ?server.*(load|latency|overload)`),
+		func(c clusterRef) []Remediation {
+			remediation := clusterTool(c, "Analyze the API server load and the clients sending the most requests", "az_monitoring")
+			remediation.Arguments["operation"] = "apiserver_load"
+			return []Remediation{remediation}
+		},
+	},
+	{
+		regexp.MustCompile(`quota|exceed.*(limit|cores|vcpu)`),
+		func(c clusterRef) []Remediation {
+			if c.location == "" {
+				return nil
+			}
+			return []Remediation{{Description: "Show the compute quota usage of the cluster region",
+				Command: fmt.Sprintf("az vm list-usage --location %s --subscription %s --output table", c.location, c.subscriptionID), AccessLevel: readonlyAccess}}
+		},
+	},
+	{
+		regexp.MustCompile(`permission|authorization ?failed|role assignment|forbidden|managed identity`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{clusterTool(c, "Check the role assignments of the cluster identities", "check_identity_permissions")}
+		},
+	},
+	{
+		regexp.MustCompile(`deprecat|api.*removed`),
+		func(c clusterRef) []Remediation {
+			return []Remediation{clusterTool(c, "Check the cluster for deprecated APIs and features", "aks_deprecated_features")}
+		},
+	},
+}
+
+// Access levels of remediations
+const (
+	readonlyAccess  = "readonly"
+	readwriteAccess = "readwrite"
+	adminAccess     = "admin"
+)
+
+// remediationsFor returns the commands in the recommended actions, with the cluster filled in, followed by
+// the remediations of the rules the finding matches
+func remediationsFor(finding ActionableFinding, cluster clusterRef) []Remediation {
+	var remediations []Remediation
+	seen := map[string]bool{}
+	add := func(r Remediation) {
+		key := r.Command + "\x00" + r.Tool + "\x00" + fmt.Sprint(r.Arguments["operation"])
+		if !seen[key] {
+			seen[key] = true
+			remediations = append(remediations, r)
+		}
+	}
+
+	for _, action := range finding.RecommendedActions {
+		if !strings.HasPrefix(action, "az ") && !strings.HasPrefix(action, "kubectl ") {
+			continue
+		}
+		command := action
+		for _, placeholder := range commandPlaceholders {
+			if value := placeholder.value(cluster); value != "" {
+				command = placeholder.pattern.ReplaceAllLiteralString(command, value)
+			}
+		}
+		add(Remediation{Description: "Command recommended by the detector", Command: command, AccessLevel: commandAccessLevel(command)})
+	}
+
+	text := strings.ToLower(strings.Join(append(append([]string{finding.Title}, finding.Details...), finding.RecommendedActions...), "\n"))
+	for _, rule := range remediationRules {
+		if rule.pattern.MatchString(text) {
+			for _, r := range rule.build(cluster) {
+				add(r)
+			}
+		}
+	}
+	return remediations
+}
+
+// commandAccessLevel classifies an az or kubectl command by the access level it needs
+func commandAccessLevel(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) < 2 {
+		return readwriteAccess
+	}
+	if fields[0] == "kubectl" {
+		switch fields[1] {
+		case "get", "describe", "logs", "top", "explain", "api-resources", "api-versions", "version", "events":
+			return readonlyAccess
+		case "cordon", "uncordon", "drain", "exec", "port-forward":
+			return adminAccess
+		}
+		return readwriteAccess
+	}
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "-") {
+			break
+		}
+		if field == "show" || field == "list" || strings.HasPrefix(field, "list-") || strings.HasPrefix(field, "get-") {
+			return readonlyAccess
+		}
+	}
+	return readwriteAccess
+}
+
+// azCommand builds a remediation from an az command format taking the resource group, cluster and subscription
+func azCommand(c clusterRef, description, format, accessLevel string) Remediation {
+	return Remediation{Description: description, Command: fmt.Sprintf(format, c.resourceGroup, c.clusterName, c.subscriptionID), AccessLevel: accessLevel}
+}
+
+// clusterTool builds a read-only remediation calling an aks-mcp tool with the cluster parameters
+func clusterTool(c clusterRef, description, tool string) Remediation {
+	return Remediation{
+		Description: description,
+		Tool:        tool,
+		Arguments: map[string]interface{}{
+			"subscription_id": c.subscriptionID,
+			"resource_group":  c.resourceGroup,
+			"cluster_name":    c.clusterName,
+		},
+		AccessLevel: readonlyAccess,
+	}
+}
+
+// cell returns a table cell as a string, or "" when the column is missing
+func cell(row []interface{}, column int) string {
+	if column < 0 || column >= len(row) || row[column] == nil {
+		return ""
+	}
+	if s, ok := row[column].(string); ok {
+		return s
+	}
+	return fmt.Sprint(row[column])
+}
+
+// columnIndex returns the index of the first of the named columns in the table, or -1
+func columnIndex(columns map[string]int, names ...string) int {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendUnique appends the non-empty values that are not in list yet
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,151 @@
+package detectors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+)
+
+const (
+	// detectorCatalogRefresh is the age after which a catalog is still served but refreshed in the background
+	detectorCatalogRefresh = 15 * time.Minute
+	// detectorCatalogTTL is how long a catalog is kept; older catalogs are fetched again before they are used
+	detectorCatalogTTL = 24 * time.Hour
+	// detectorCatalogFetchTimeout bounds a background fetch of a catalog
+	detectorCatalogFetchTimeout = 2 * time.Minute
+	// maxDetectorSuggestions is how many similar detectors an unknown detector name error lists
+	maxDetectorSuggestions = 5
+)
+
+// catalogEntry is the cached detector metadata of one cluster
+type catalogEntry struct {
+	detectors *DetectorListResponse
+	fetched   time.Time
+}
+
+// refreshing holds the catalogs being fetched in the background, keyed by cache and cluster, so a catalog is
+// not fetched twice at once
+var refreshing sync.Map
+
+// catalogKey is the cache key of a cluster's catalog. Resource group and cluster names are case-insensitive.
+func catalogKey(subscriptionID, resourceGroup, clusterName string) string {
+	return strings.ToLower(fmt.Sprintf("detectors:catalog:%s:%s:%s", subscriptionID, resourceGroup, clusterName))
+}
+
+// cachedCatalog returns the cached catalog of a cluster without fetching it
+func (c *DetectorClient) cachedCatalog(subscriptionID, resourceGroup, clusterName string) (*catalogEntry, bool) {
+	cached, found := c.cache.Get(catalogKey(subscriptionID, resourceGroup, clusterName))
+	if !found {
+		return nil, false
+	}
+	entry, ok := cached.(*catalogEntry)
+	return entry, ok
+}
+
+// WarmCatalog fetches the catalog of a cluster and caches it, replacing any cached catalog
+func (c *DetectorClient) WarmCatalog(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*DetectorListResponse, error) {
+	detectors, err := c.fetch(ctx, subscriptionID, resourceGroup, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.SetWithExpiration(catalogKey(subscriptionID, resourceGroup, clusterName), &catalogEntry{detectors: detectors, fetched: time.Now()}, detectorCatalogTTL)
+	return detectors, nil
+}
+
+// refreshCatalog fetches the catalog of a cluster in the background, unless it is already being fetched
+func (c *DetectorClient) refreshCatalog(subscriptionID, resourceGroup, clusterName string) {
+	key := fmt.Sprintf("%p:%s", c.cache, catalogKey(subscriptionID, resourceGroup, clusterName))
+	if _, busy := refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer refreshing.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), detectorCatalogFetchTimeout)
+		defer cancel()
+		if _, err := c.WarmCatalog(ctx, subscriptionID, resourceGroup, clusterName); err != nil {
+			log.Printf("Failed to refresh the detector catalog of cluster %s: %v", clusterName, err)
+		}
+	}()
+}
+
+// ValidateDetectorName checks a detector name against the cached catalog of a cluster, listing similar
+// detectors when it is unknown. Without a cached catalog the name is not checked, and the catalog is
+// fetched in the background for the next call.
+func (c *DetectorClient) ValidateDetectorName(subscriptionID, resourceGroup, clusterName, detectorName string) error {
+	entry, ok := c.cachedCatalog(subscriptionID, resourceGroup, clusterName)
+	if !ok {
+		c.refreshCatalog(subscriptionID, resourceGroup, clusterName)
+		return nil
+	}
+	if time.Since(entry.fetched) > detectorCatalogRefresh {
+		c.refreshCatalog(subscriptionID, resourceGroup, clusterName)
+	}
+
+	var similar []string
+	needle := strings.ToLower(detectorName)
+	for _, detector := range entry.detectors.Value {
+		id := detector.Properties.Metadata.ID
+		if id == "" {
+			id = detector.Name
+		}
+		if strings.EqualFold(id, detectorName) || strings.EqualFold(detector.Name, detectorName) {
+			return nil
+		}
+		if strings.Contains(strings.ToLower(id), needle) || strings.Contains(strings.ToLower(detector.Properties.Metadata.Name), needle) {
+			similar = append(similar, id)
+		}
+	}
+	if len(similar) == 0 {
+		return fmt.Errorf("detector '%s' not found for cluster %s, use list_detectors to see the available detectors", detectorName, clusterName)
+	}
+	sort.Strings(similar)
+	if len(similar) > maxDetectorSuggestions {
+		similar = similar[:maxDetectorSuggestions]
+	}
+	return fmt.Errorf("detector '%s' not found for cluster %s, did you mean: %s", detectorName, clusterName, strings.Join(similar, ", "))
+}
+
+// KeepCatalogsWarm fetches the detector catalogs of the given clusters and refreshes them whenever they are
+// due, until the context is cancelled. Resource IDs that are not AKS clusters are logged and skipped.
+func KeepCatalogsWarm(ctx context.Context, azClient *azureclient.AzureClient, clusterIDs []string) {
+	client := NewDetectorClient(azClient)
+	type cluster struct{ subscriptionID, resourceGroup, name string }
+	var clusters []cluster
+	for _, clusterID := range clusterIDs {
+		subscriptionID, resourceGroup, name, err := azureclient.ParseAKSResourceID(clusterID)
+		if err != nil {
+			log.Printf("Not prewarming detectors: %v", err)
+			continue
+		}
+		clusters = append(clusters, cluster{subscriptionID, resourceGroup, name})
+	}
+	if len(clusters) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(detectorCatalogRefresh)
+	defer ticker.Stop()
+	for {
+		for _, cl := range clusters {
+			fetchCtx, cancel := context.WithTimeout(ctx, detectorCatalogFetchTimeout)
+			detectors, err := client.WarmCatalog(fetchCtx, cl.subscriptionID, cl.resourceGroup, cl.name)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to prewarm the detector catalog of cluster %s: %v", cl.name, err)
+				continue
+			}
+			log.Printf("Prewarmed the detector catalog of cluster %s (%d detectors)", cl.name, len(detectors.Value))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
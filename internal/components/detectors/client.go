@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Azure/aks-mcp/internal/azureclient"
 )
@@ -14,33 +15,39 @@ import (
 type DetectorClient struct {
 	azClient *azureclient.AzureClient
 	cache    *azureclient.AzureCache
+	// fetch lists the detectors of a cluster from the API
+	fetch func(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*DetectorListResponse, error)
 }
 
 // NewDetectorClient creates a new detector client
 func NewDetectorClient(azClient *azureclient.AzureClient) *DetectorClient {
-	return &DetectorClient{
+	c := &DetectorClient{
 		azClient: azClient,
 		cache:    azClient.GetCache(),
 	}
+	c.fetch = c.fetchDetectors
+	return c
 }
 
-// ListDetectors lists all detectors for a cluster with caching
+// ListDetectors lists all detectors for a cluster from its catalog, which is fetched on first use and
+// refreshed in the background once it is older than detectorCatalogRefresh
 func (c *DetectorClient) ListDetectors(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*DetectorListResponse, error) {
-	// Create cache key
-	cacheKey := fmt.Sprintf("detectors:list:%s:%s:%s", subscriptionID, resourceGroup, clusterName)
-
-	// Check cache first
-	if cached, found := c.cache.Get(cacheKey); found {
-		if detectors, ok := cached.(*DetectorListResponse); ok {
-			return detectors, nil
+	if entry, ok := c.cachedCatalog(subscriptionID, resourceGroup, clusterName); ok {
+		if time.Since(entry.fetched) > detectorCatalogRefresh {
+			c.refreshCatalog(subscriptionID, resourceGroup, clusterName)
 		}
+		return entry.detectors, nil
 	}
+	return c.WarmCatalog(ctx, subscriptionID, resourceGroup, clusterName)
+}
 
+// fetchDetectors lists the detectors of a cluster from the detector API
+func (c *DetectorClient) fetchDetectors(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*DetectorListResponse, error) {
 	// Build API URL
-	apiURL := fmt.Sprintf("https://management.azure.com/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s/detectors?api-version=2024-08-01",
+	apiURL := c.azClient.Cloud().ResourceManagerURL(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s/detectors?api-version=2024-08-01",
 		url.PathEscape(subscriptionID),
 		url.PathEscape(resourceGroup),
-		url.PathEscape(clusterName))
+		url.PathEscape(clusterName)))
 
 	// Make API call
 	resp, err := c.azClient.MakeDetectorAPICall(ctx, apiURL, subscriptionID)
@@ -60,22 +67,19 @@ func (c *DetectorClient) ListDetectors(ctx context.Context, subscriptionID, reso
 		return nil, fmt.Errorf("failed to parse detector list response: %v", err)
 	}
 
-	// Cache the result
-	c.cache.Set(cacheKey, &detectorList)
-
 	return &detectorList, nil
 }
 
 // RunDetector executes a specific detector
 func (c *DetectorClient) RunDetector(ctx context.Context, subscriptionID, resourceGroup, clusterName, detectorName, startTime, endTime string) (*DetectorRunResponse, error) {
 	// Build API URL with query parameters
-	apiURL := fmt.Sprintf("https://management.azure.com/subscriptions/%s/resourcegroups/%s/providers/microsoft.containerservice/managedclusters/%s/detectors/%s?startTime=%s&endTime=%s&api-version=2024-08-01",
+	apiURL := c.azClient.Cloud().ResourceManagerURL(fmt.Sprintf("/subscriptions/%s/resourcegroups/%s/providers/microsoft.containerservice/managedclusters/%s/detectors/%s?startTime=%s&endTime=%s&api-version=2024-08-01",
 		url.PathEscape(subscriptionID),
 		url.PathEscape(resourceGroup),
 		url.PathEscape(clusterName),
 		url.PathEscape(detectorName),
 		url.QueryEscape(startTime),
-		url.QueryEscape(endTime))
+		url.QueryEscape(endTime)))
 
 	// Make API call
 	resp, err := c.azClient.MakeDetectorAPICall(ctx, apiURL, subscriptionID)
@@ -1,8 +1,16 @@
 package detectors
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
 )
 
 func TestValidateTimeParameters(t *testing.T) {
@@ -108,3 +116,261 @@ func TestValidateCategory(t *testing.T) {
 		})
 	}
 }
+
+type fakeMultiClusterRunner struct {
+	mu      sync.Mutex
+	results map[string][]DetectorRunResponse
+	members []string
+	ran     []string
+}
+
+func (f *fakeMultiClusterRunner) RunDetectorsByCategory(_ context.Context, _, _, clusterName, _, _, _ string) ([]DetectorRunResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ran = append(f.ran, clusterName)
+	results, ok := f.results[clusterName]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", clusterName)
+	}
+	return results, nil
+}
+
+func (f *fakeMultiClusterRunner) ListFleetMembers(_ context.Context, _ string) ([]string, error) {
+	return f.members, nil
+}
+
+func clusterID(name string) string {
+	return "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/" + name
+}
+
+func insightRun(detector string, statusID int, rows ...[]interface{}) DetectorRunResponse {
+	run := DetectorRunResponse{Name: detector}
+	run.Properties.Metadata.Name = detector
+	run.Properties.Status.StatusID = statusID
+	run.Properties.Dataset = []DetectorDataset{{Table: DetectorTable{
+		Columns: []DetectorColumn{{ColumnName: "Status"}, {ColumnName: "Message"}},
+		Rows:    rows,
+	}}}
+	return run
+}
+
+func TestHandleRunDetectorsMultiCluster(t *testing.T) {
+	runner := &fakeMultiClusterRunner{
+		results: map[string][]DetectorRunResponse{
+			"healthy": {insightRun("node-health", 3, []interface{}{"Success", "All nodes are ready"})},
+			"warn": {
+				insightRun("node-health", 1, []interface{}{"Warning", "2 nodes are NotReady"}, []interface{}{"Info", "Node image is current"}),
+				insightRun("disk-pressure", 0),
+			},
+		},
+		members: []string{clusterID("warn"), clusterID("missing")},
+	}
+	now := time.Now()
+	params := map[string]interface{}{
+		"cluster_resource_ids": clusterID("healthy") + ", " + strings.ToLower(clusterID("warn")),
+		"fleet_resource_id":    "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/fleets/fleet1",
+		"category":             "Node Health",
+		"start_time":           now.Add(-time.Hour).Format(time.RFC3339),
+		"end_time":             now.Format(time.RFC3339),
+	}
+
+	output, err := HandleRunDetectorsMultiCluster(params, runner)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result MultiClusterResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(runner.ran) != 3 || len(result.Clusters) != 3 {
+		t.Fatalf("Expected the duplicate fleet member to be skipped, ran %v", runner.ran)
+	}
+	if result.Clusters[0].Status != StatusError || result.Clusters[0].ClusterName != "missing" {
+		t.Errorf("Expected the failed cluster first, got %+v", result.Clusters[0])
+	}
+	warn := result.Clusters[1]
+	if warn.Status != StatusCritical || warn.DetectorsRun != 2 || len(warn.Findings) != 2 {
+		t.Errorf("Unexpected summary %+v", warn)
+	}
+	if warn.Findings[0].Message != "2 nodes are NotReady" || warn.Findings[1].Detector != "disk-pressure" {
+		t.Errorf("Unexpected findings %+v", warn.Findings)
+	}
+	if result.Clusters[2].Status != StatusSuccess || len(result.Clusters[2].Findings) != 0 {
+		t.Errorf("Unexpected healthy summary %+v", result.Clusters[2])
+	}
+	if result.StatusCounts[StatusError] != 1 || result.StatusCounts[StatusCritical] != 1 || result.StatusCounts[StatusSuccess] != 1 {
+		t.Errorf("Unexpected status counts %v", result.StatusCounts)
+	}
+
+	delete(params, "cluster_resource_ids")
+	params["fleet_resource_id"] = clusterID("warn")
+	if _, err := HandleRunDetectorsMultiCluster(params, runner); err == nil {
+		t.Error("Expected an error for a cluster ID passed as the fleet")
+	}
+}
+
+func TestBuildActionableResult(t *testing.T) {
+	columns := []DetectorColumn{{ColumnName: "Status"}, {ColumnName: "Message"}, {ColumnName: "Data.Name"}, {ColumnName: "Data.Value"}, {ColumnName: "Expanded"}, {ColumnName: "Solutions"}}
+	message := "Kubernetes version 1.27 is out of support"
+	run := DetectorRunResponse{
+		Name:     "aks-version-support",
+		Location: "eastus",
+		Properties: DetectorRunProperties{
+			Metadata: DetectorMetadata{Name: "Kubernetes Version Support"},
+			Status:   DetectorStatus{StatusID: 0},
+			Dataset: []DetectorDataset{{Table: DetectorTable{Columns: columns, Rows: [][]interface{}{
+				{"Critical", message, "Description", "<p>Clusters on <b>unsupported</b> versions do not receive security patches.</p>", true, ""},
+				{"Critical", message, "Recommended Action",
+					"<ul><li>Upgrade with <code>az aks upgrade -g &lt;resource-group&gt; -n &lt;cluster-name&gt; --kubernetes-version 1.29.4</code></li>" +
+						"<li>See <a href=\"https://aka.ms/aks/supported-versions\">supported versions</a></li></ul>", true, ""},
+				{"Critical", message, "Current Version", "1.27.9", true, `[{"Title": "Plan the upgrade path"}]`},
+				{"Warning", "SNAT port exhaustion detected on the outbound load balancer", "", "", false, ""},
+				{"Success", "Node images are current", "", "", false, ""},
+			}}}},
+		},
+	}
+	result := BuildActionableResult(run, clusterRef{subscriptionID: "sub", resourceGroup: "rg", clusterName: "aks"})
+
+	if result.Status != StatusCritical || result.Name != "Kubernetes Version Support" || len(result.Findings) != 2 {
+		t.Fatalf("Expected the critical and warning insights, got %+v", result)
+	}
+	finding := result.Findings[0]
+	if finding.Title != message || strings.Join(finding.Details, "|") != "Clusters on unsupported versions do not receive security patches.|Current Version: 1.27.9" {
+		t.Errorf("Unexpected details %q", finding.Details)
+	}
+	wantActions := "Upgrade with az aks upgrade -g <resource-group> -n <cluster-name> --kubernetes-version 1.29.4|See supported versions|" +
+		"az aks upgrade -g <resource-group> -n <cluster-name> --kubernetes-version 1.29.4|Plan the upgrade path"
+	if strings.Join(finding.RecommendedActions, "|") != wantActions {
+		t.Errorf("Unexpected recommended actions %q", finding.RecommendedActions)
+	}
+	if len(finding.Links) != 1 || finding.Links[0] != "https://aka.ms/aks/supported-versions" {
+		t.Errorf("Unexpected links %v", finding.Links)
+	}
+	if len(finding.Remediations) != 3 {
+		t.Fatalf("Expected the detector command and the version rule remediations, got %+v", finding.Remediations)
+	}
+	if r := finding.Remediations[0]; r.Command != "az aks upgrade -g rg -n aks --kubernetes-version 1.29.4" || r.AccessLevel != readwriteAccess {
+		t.Errorf("Expected the detector command with the cluster filled in, got %+v", r)
+	}
+	if r := finding.Remediations[1]; r.Command != "az aks get-upgrades --resource-group rg --name aks --subscription sub --output table" || r.AccessLevel != readonlyAccess {
+		t.Errorf("Unexpected upgrade remediation %+v", r)
+	}
+	if r := finding.Remediations[2]; r.Tool != "aks_deprecated_features" || r.Arguments["cluster_name"] != "aks" {
+		t.Errorf("Unexpected tool remediation %+v", r)
+	}
+
+	snat := result.Findings[1]
+	if snat.Status != StatusWarning || len(snat.Remediations) != 1 || !strings.Contains(snat.Remediations[0].Command, "loadBalancerProfile") {
+		t.Errorf("Unexpected SNAT finding %+v", snat)
+	}
+}
+
+func TestBuildActionableResultWithoutInsights(t *testing.T) {
+	statusMessage := "Found **3** failed operations"
+	run := DetectorRunResponse{Name: "failed-operations", Properties: DetectorRunProperties{Status: DetectorStatus{StatusID: 1, Message: &statusMessage}}}
+	result := BuildActionableResult(run, clusterRef{})
+	if len(result.Findings) != 1 || result.Findings[0].Title != "Found 3 failed operations" || result.Findings[0].Status != StatusWarning {
+		t.Errorf("Expected the status message as the finding, got %+v", result.Findings)
+	}
+}
+
+func TestCommandAccessLevel(t *testing.T) {
+	tests := map[string]string{
+		"az aks show -g rg -n aks":                    readonlyAccess,
+		"az aks nodepool list -g rg --cluster-name a": readonlyAccess,
+		"az aks get-upgrades -g rg -n aks":            readonlyAccess,
+		"az aks update -g rg -n aks --enable-ahub":    readwriteAccess,
+		"kubectl describe node aks-1":                 readonlyAccess,
+		"kubectl delete pod web-0":                    readwriteAccess,
+		"kubectl drain aks-1 --ignore-daemonsets":     adminAccess,
+	}
+	for command, want := range tests {
+		if got := commandAccessLevel(command); got != want {
+			t.Errorf("commandAccessLevel(%q) = %s, want %s", command, got, want)
+		}
+	}
+}
+
+func TestHandleRunDetectorInvalidFormat(t *testing.T) {
+	now := time.Now()
+	params := map[string]interface{}{
+		"cluster_resource_id": clusterID("aks"),
+		"detector_name":       "node-health",
+		"start_time":          now.Add(-time.Hour).Format(time.RFC3339),
+		"end_time":            now.Format(time.RFC3339),
+		"format":              "markdown",
+	}
+	if _, err := HandleRunDetector(params, nil); err == nil || !strings.Contains(err.Error(), "invalid format 'markdown'") {
+		t.Errorf("Expected an invalid format error, got %v", err)
+	}
+}
+
+// fakeCatalogFetcher lists the same detectors for every cluster and counts the fetches
+type fakeCatalogFetcher struct {
+	fetches atomic.Int32
+}
+
+func (f *fakeCatalogFetcher) fetch(_ context.Context, _, _, _ string) (*DetectorListResponse, error) {
+	f.fetches.Add(1)
+	detector := func(id, name string) Detector {
+		return Detector{Name: id, Properties: DetectorProperties{Metadata: DetectorMetadata{ID: id, Name: name}}}
+	}
+	return &DetectorListResponse{Value: []Detector{
+		detector("node-health", "Node Health"),
+		detector("node-drain-failures", "Node Drain Failures"),
+		detector("api-server-availability", "API Server Availability"),
+	}}, nil
+}
+
+func TestDetectorCatalog(t *testing.T) {
+	fetcher := &fakeCatalogFetcher{}
+	client := &DetectorClient{cache: azureclient.NewAzureCache(time.Minute), fetch: fetcher.fetch}
+	ctx := context.Background()
+
+	// Without a cached catalog the name is not checked, and the catalog is fetched in the background
+	if err := client.ValidateDetectorName("sub", "rg", "aks", "anything"); err != nil {
+		t.Fatalf("Expected no check without a catalog, got %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := client.cachedCatalog("sub", "RG", "AKS"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the catalog to be fetched in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	list, err := client.ListDetectors(ctx, "sub", "rg", "aks")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(list.Value) != 3 || fetcher.fetches.Load() != 1 {
+		t.Errorf("Expected the cached catalog of 3 detectors from 1 fetch, got %d detectors from %d fetches", len(list.Value), fetcher.fetches.Load())
+	}
+
+	if err := client.ValidateDetectorName("sub", "rg", "aks", "NODE-HEALTH"); err != nil {
+		t.Errorf("Expected a known detector to pass, got %v", err)
+	}
+	err = client.ValidateDetectorName("sub", "rg", "aks", "node")
+	if err == nil || !strings.Contains(err.Error(), "did you mean: node-drain-failures, node-health") {
+		t.Errorf("Expected similar detectors to be suggested, got %v", err)
+	}
+	err = client.ValidateDetectorName("sub", "rg", "aks", "gpu")
+	if err == nil || !strings.Contains(err.Error(), "use list_detectors") {
+		t.Errorf("Expected an unknown detector error, got %v", err)
+	}
+
+	// A stale catalog is still served and is refreshed in the background
+	client.cache.SetWithExpiration(catalogKey("sub", "rg", "aks"), &catalogEntry{detectors: list, fetched: time.Now().Add(-2 * detectorCatalogRefresh)}, time.Hour)
+	if _, err := client.ListDetectors(ctx, "sub", "rg", "aks"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for fetcher.fetches.Load() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the stale catalog to be refreshed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -102,12 +103,22 @@ func HandleRunDetector(params map[string]interface{}, client *DetectorClient) (s
 		return "", fmt.Errorf("invalid time parameters: %v", err)
 	}
 
+	format, err := parseFormat(params)
+	if err != nil {
+		return "", err
+	}
+
 	// Parse resource ID
 	subscriptionID, resourceGroup, clusterName, err := azureclient.ParseAKSResourceID(clusterResourceID)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse cluster resource ID: %v", err)
 	}
 
+	// Check the name against the cached catalog, so a mistyped name fails without a detector run
+	if err := client.ValidateDetectorName(subscriptionID, resourceGroup, clusterName, detectorName); err != nil {
+		return "", err
+	}
+
 	// Run detector
 	ctx := context.Background()
 	result, err := client.RunDetector(ctx, subscriptionID, resourceGroup, clusterName, detectorName, startTime, endTime)
@@ -115,8 +126,13 @@ func HandleRunDetector(params map[string]interface{}, client *DetectorClient) (s
 		return "", fmt.Errorf("failed to run detector: %v", err)
 	}
 
+	var output interface{} = result
+	if format == FormatActionable {
+		output = BuildActionableResult(*result, clusterRef{subscriptionID: subscriptionID, resourceGroup: resourceGroup, clusterName: clusterName})
+	}
+
 	// Return as JSON
-	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	resultJSON, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal detector result to JSON: %v", err)
 	}
@@ -160,6 +176,11 @@ func HandleRunDetectorsByCategory(params map[string]interface{}, client *Detecto
 		return "", fmt.Errorf("invalid category: %v", err)
 	}
 
+	format, err := parseFormat(params)
+	if err != nil {
+		return "", err
+	}
+
 	// Parse resource ID
 	subscriptionID, resourceGroup, clusterName, err := azureclient.ParseAKSResourceID(clusterResourceID)
 	if err != nil {
@@ -179,6 +200,19 @@ func HandleRunDetectorsByCategory(params map[string]interface{}, client *Detecto
 		"detectors_count": len(results),
 		"results":         results,
 	}
+	if format == FormatActionable {
+		// Only detectors with findings are listed; the others are counted
+		cluster := clusterRef{subscriptionID: subscriptionID, resourceGroup: resourceGroup, clusterName: clusterName}
+		actionable := []ActionableResult{}
+		for _, result := range results {
+			if r := BuildActionableResult(result, cluster); len(r.Findings) > 0 {
+				actionable = append(actionable, r)
+			}
+		}
+		sort.SliceStable(actionable, func(i, j int) bool { return statusRank(actionable[i].Status) < statusRank(actionable[j].Status) })
+		response["results"] = actionable
+		response["detectors_without_findings"] = len(results) - len(actionable)
+	}
 
 	// Return as JSON
 	resultJSON, err := json.MarshalIndent(response, "", "  ")
@@ -231,24 +265,37 @@ func validateTimeParameters(startTime, endTime string) error {
 	return nil
 }
 
+// parseFormat returns the output format parameter, full by default
+func parseFormat(params map[string]interface{}) (string, error) {
+	format, _ := params["format"].(string)
+	switch format {
+	case "":
+		return FormatFull, nil
+	case FormatFull, FormatActionable:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format '%s', must be %s or %s", format, FormatFull, FormatActionable)
+}
+
+// Categories lists the detector categories, offered as the values of the category parameters
+var Categories = []string{
+	"Best Practices",
+	"Cluster and Control Plane Availability and Performance",
+	"Connectivity Issues",
+	"Create, Upgrade, Delete and Scale",
+	"Deprecations",
+	"Identity and Security",
+	"Node Health",
+	"Storage",
+}
+
 // validateCategory validates the category parameter
 func validateCategory(category string) error {
-	validCategories := []string{
-		"Best Practices",
-		"Cluster and Control Plane Availability and Performance",
-		"Connectivity Issues",
-		"Create, Upgrade, Delete and Scale",
-		"Deprecations",
-		"Identity and Security",
-		"Node Health",
-		"Storage",
-	}
-
-	for _, valid := range validCategories {
+	for _, valid := range Categories {
 		if strings.EqualFold(category, valid) {
 			return nil
 		}
 	}
 
-	return fmt.Errorf("invalid category '%s', must be one of: %v", category, validCategories)
+	return fmt.Errorf("invalid category '%s', must be one of: %v", category, Categories)
 }
@@ -0,0 +1,314 @@
+package detectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const (
+	// fleetAPIVersion is the Microsoft.ContainerService fleets API version used to list members
+	fleetAPIVersion = "2024-04-01"
+	// maxParallelClusters bounds the clusters whose detectors run at the same time
+	maxParallelClusters = 5
+	// maxClusters bounds the clusters one call runs detectors against
+	maxClusters = 50
+	// maxFleetMemberPages bounds nextLink paging when listing fleet members
+	maxFleetMemberPages = 10
+)
+
+// Detector statuses, worst first. The detector API reports them as statusId 0 to 4.
+const (
+	StatusCritical = "critical"
+	StatusWarning  = "warning"
+	StatusInfo     = "info"
+	StatusSuccess  = "success"
+	StatusNone     = "none"
+	// StatusError marks a cluster whose detectors could not be run
+	StatusError = "error"
+)
+
+var statusNames = []string{StatusCritical, StatusWarning, StatusInfo, StatusSuccess, StatusNone}
+
+// MultiClusterRunner runs detector categories and lists fleet members. *DetectorClient implements it.
+type MultiClusterRunner interface {
+	RunDetectorsByCategory(ctx context.Context, subscriptionID, resourceGroup, clusterName, category, startTime, endTime string) ([]DetectorRunResponse, error)
+	ListFleetMembers(ctx context.Context, fleetResourceID string) ([]string, error)
+}
+
+// DetectorFinding is a warning or critical insight reported by a detector
+type DetectorFinding struct {
+	Detector string `json:"detector"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+// ClusterDetectorSummary is the outcome of a detector category on one cluster
+type ClusterDetectorSummary struct {
+	ClusterResourceID string            `json:"clusterResourceId"`
+	ClusterName       string            `json:"clusterName"`
+	Status            string            `json:"status"`
+	DetectorsRun      int               `json:"detectorsRun"`
+	Findings          []DetectorFinding `json:"findings,omitempty"`
+	Error             string            `json:"error,omitempty"`
+}
+
+// MultiClusterResult is the result of the run_detectors_multi_cluster tool
+type MultiClusterResult struct {
+	Category  string `json:"category"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	Fleet     string `json:"fleet,omitempty"`
+	// StatusCounts counts the clusters by their worst detector status
+	StatusCounts map[string]int           `json:"statusCounts"`
+	Clusters     []ClusterDetectorSummary `json:"clusters"`
+}
+
+// GetRunDetectorsMultiClusterHandler returns handler for run_detectors_multi_cluster tool
+func GetRunDetectorsMultiClusterHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleRunDetectorsMultiCluster(params, NewDetectorClient(azClient))
+	})
+}
+
+// HandleRunDetectorsMultiCluster runs a detector category against several clusters in parallel and
+// aggregates the findings per cluster
+func HandleRunDetectorsMultiCluster(params map[string]interface{}, runner MultiClusterRunner) (string, error) {
+	category, ok := params["category"].(string)
+	if !ok || category == "" {
+		return "", fmt.Errorf("missing or invalid category parameter")
+	}
+	startTime, ok := params["start_time"].(string)
+	if !ok || startTime == "" {
+		return "", fmt.Errorf("missing or invalid start_time parameter")
+	}
+	endTime, ok := params["end_time"].(string)
+	if !ok || endTime == "" {
+		return "", fmt.Errorf("missing or invalid end_time parameter")
+	}
+	if err := validateTimeParameters(startTime, endTime); err != nil {
+		return "", fmt.Errorf("invalid time parameters: %v", err)
+	}
+	if err := validateCategory(category); err != nil {
+		return "", fmt.Errorf("invalid category: %v", err)
+	}
+
+	idsValue, _ := params["cluster_resource_ids"].(string)
+	fleetID, _ := params["fleet_resource_id"].(string)
+	if idsValue == "" && fleetID == "" {
+		return "", fmt.Errorf("either cluster_resource_ids or fleet_resource_id is required")
+	}
+
+	ctx := context.Background()
+	clusterIDs := splitResourceIDs(idsValue)
+	if fleetID != "" {
+		if !isFleetResourceID(fleetID) {
+			return "", fmt.Errorf("invalid fleet resource ID format: %s", fleetID)
+		}
+		members, err := runner.ListFleetMembers(ctx, fleetID)
+		if err != nil {
+			return "", fmt.Errorf("failed to list fleet members: %v", err)
+		}
+		clusterIDs = append(clusterIDs, members...)
+	}
+	clusterIDs = dedupeResourceIDs(clusterIDs)
+	if len(clusterIDs) == 0 {
+		return "", fmt.Errorf("no member clusters found")
+	}
+	if len(clusterIDs) > maxClusters {
+		return "", fmt.Errorf("%d clusters requested, at most %d are supported in one call", len(clusterIDs), maxClusters)
+	}
+
+	summaries := make([]ClusterDetectorSummary, len(clusterIDs))
+	common.RunPool(len(clusterIDs), maxParallelClusters, func(i int) {
+		summaries[i] = runClusterCategory(ctx, runner, clusterIDs[i], category, startTime, endTime)
+	})
+
+	result := MultiClusterResult{
+		Category:     category,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Fleet:        fleetID,
+		StatusCounts: map[string]int{},
+		Clusters:     summaries,
+	}
+	for _, summary := range summaries {
+		result.StatusCounts[summary.Status]++
+	}
+	sort.SliceStable(result.Clusters, func(i, j int) bool {
+		a, b := statusRank(result.Clusters[i].Status), statusRank(result.Clusters[j].Status)
+		if a != b {
+			return a < b
+		}
+		return result.Clusters[i].ClusterName < result.Clusters[j].ClusterName
+	})
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal detector results to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// runClusterCategory runs the category on one cluster and summarizes its findings
+func runClusterCategory(ctx context.Context, runner MultiClusterRunner, clusterID, category, startTime, endTime string) ClusterDetectorSummary {
+	summary := ClusterDetectorSummary{ClusterResourceID: clusterID, Status: StatusError}
+	subscriptionID, resourceGroup, clusterName, err := azureclient.ParseAKSResourceID(clusterID)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	summary.ClusterName = clusterName
+
+	results, err := runner.RunDetectorsByCategory(ctx, subscriptionID, resourceGroup, clusterName, category, startTime, endTime)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	summary.DetectorsRun = len(results)
+	summary.Status = StatusNone
+	for _, result := range results {
+		status := statusName(result.Properties.Status.StatusID)
+		if statusRank(status) < statusRank(summary.Status) {
+			summary.Status = status
+		}
+		summary.Findings = append(summary.Findings, ExtractFindings(result)...)
+	}
+	return summary
+}
+
+// ExtractFindings returns the warning and critical insights of a detector run. Detectors that report a
+// warning or critical status without an insight table are reported with their status message.
+func ExtractFindings(result DetectorRunResponse) []DetectorFinding {
+	detector := result.Properties.Metadata.Name
+	if detector == "" {
+		detector = result.Name
+	}
+	var findings []DetectorFinding
+	for _, dataset := range result.Properties.Dataset {
+		statusColumn, messageColumn := -1, -1
+		for i, column := range dataset.Table.Columns {
+			switch {
+			case strings.EqualFold(column.ColumnName, "Status"):
+				statusColumn = i
+			case strings.EqualFold(column.ColumnName, "Message"):
+				messageColumn = i
+			}
+		}
+		if statusColumn < 0 || messageColumn < 0 {
+			continue
+		}
+		for _, row := range dataset.Table.Rows {
+			if len(row) <= statusColumn || len(row) <= messageColumn {
+				continue
+			}
+			status := strings.ToLower(fmt.Sprint(row[statusColumn]))
+			if status != StatusCritical && status != StatusWarning {
+				continue
+			}
+			findings = append(findings, DetectorFinding{Detector: detector, Status: status, Message: fmt.Sprint(row[messageColumn])})
+		}
+	}
+
+	status := statusName(result.Properties.Status.StatusID)
+	if len(findings) == 0 && (status == StatusCritical || status == StatusWarning) {
+		message := "detector reported " + status
+		if result.Properties.Status.Message != nil && *result.Properties.Status.Message != "" {
+			message = *result.Properties.Status.Message
+		}
+		findings = append(findings, DetectorFinding{Detector: detector, Status: status, Message: message})
+	}
+	return findings
+}
+
+// ListFleetMembers returns the cluster resource IDs of the members of a fleet
+func (c *DetectorClient) ListFleetMembers(ctx context.Context, fleetResourceID string) ([]string, error) {
+	path := fmt.Sprintf("%s/members?api-version=%s", strings.TrimSuffix(fleetResourceID, "/"), fleetAPIVersion)
+	var clusterIDs []string
+	for page := 0; path != "" && page < maxFleetMemberPages; page++ {
+		body, err := c.azClient.CallARM(ctx, http.MethodGet, path)
+		if err != nil {
+			return nil, err
+		}
+		var members struct {
+			Value []struct {
+				Properties struct {
+					ClusterResourceID string `json:"clusterResourceId"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &members); err != nil {
+			return nil, fmt.Errorf("failed to parse fleet members: %v", err)
+		}
+		for _, member := range members.Value {
+			if member.Properties.ClusterResourceID != "" {
+				clusterIDs = append(clusterIDs, member.Properties.ClusterResourceID)
+			}
+		}
+		path = members.NextLink
+	}
+	return clusterIDs, nil
+}
+
+// statusName maps a detector statusId to its name
+func statusName(statusID int) string {
+	if statusID < 0 || statusID >= len(statusNames) {
+		return StatusNone
+	}
+	return statusNames[statusID]
+}
+
+// statusRank orders statuses worst first, with errors ahead of every detector status
+func statusRank(status string) int {
+	if status == StatusError {
+		return -1
+	}
+	for i, name := range statusNames {
+		if name == status {
+			return i
+		}
+	}
+	return len(statusNames)
+}
+
+// isFleetResourceID reports whether a resource ID names a Microsoft.ContainerService fleet
+func isFleetResourceID(resourceID string) bool {
+	parts := strings.Split(strings.Trim(resourceID, "/"), "/")
+	return len(parts) == 8 && strings.EqualFold(parts[0], "subscriptions") && strings.EqualFold(parts[2], "resourceGroups") &&
+		strings.EqualFold(parts[4], "providers") && strings.EqualFold(parts[5], "Microsoft.ContainerService") && strings.EqualFold(parts[6], "fleets")
+}
+
+// splitResourceIDs splits a comma-separated list of resource IDs
+func splitResourceIDs(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// dedupeResourceIDs removes resource IDs that repeat, ignoring case
+func dedupeResourceIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var unique []string
+	for _, id := range ids {
+		key := strings.ToLower(strings.TrimSuffix(id, "/"))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -39,6 +39,11 @@ func RegisterRunDetectorTool() mcp.Tool {
 			mcp.Description("End time in UTC ISO format (within last 30 days, max 24h from start). Example: 2025-07-11T14:55:13Z"),
 			mcp.Required(),
 		),
+		mcp.WithString("format",
+			mcp.Description("Output format: full (default) returns the raw detector datasets; actionable returns each critical, warning and info finding "+
+				"with its status, plain-text details and recommended actions, and the az or kubectl command or aks-mcp tool call that remediates it when recognized"),
+			mcp.Enum(FormatFull, FormatActionable),
+		),
 	)
 }
 
@@ -52,7 +57,41 @@ func RegisterRunDetectorsByCategoryTool() mcp.Tool {
 			mcp.Required(),
 		),
 		mcp.WithString("category",
-			mcp.Description("Detector category to run (Best Practices, Cluster and Control Plane Availability and Performance, Connectivity Issues, Create/Upgrade/Delete and Scale, Deprecations, Identity and Security, Node Health, Storage)"),
+			mcp.Description("Detector category to run"),
+			mcp.Enum(Categories...),
+			mcp.Required(),
+		),
+		mcp.WithString("start_time",
+			mcp.Description("Start time in UTC ISO format (within last 30 days). Example: 2025-07-11T10:55:13Z"),
+			mcp.Required(),
+		),
+		mcp.WithString("end_time",
+			mcp.Description("End time in UTC ISO format (within last 30 days, max 24h from start). Example: 2025-07-11T14:55:13Z"),
+			mcp.Required(),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format: full (default) returns the raw detector datasets; actionable returns each critical, warning and info finding "+
+				"with its status, plain-text details and recommended actions, and the az or kubectl command or aks-mcp tool call that remediates it when recognized"),
+			mcp.Enum(FormatFull, FormatActionable),
+		),
+	)
+}
+
+// RegisterRunDetectorsMultiClusterTool registers the run_detectors_multi_cluster MCP tool
+func RegisterRunDetectorsMultiClusterTool() mcp.Tool {
+	return mcp.NewTool(
+		"run_detectors_multi_cluster",
+		mcp.WithDescription("Run all detectors in a category against several AKS clusters, or every member cluster of a fleet, in parallel. "+
+			"Returns each cluster's worst detector status with its warning and critical findings, worst clusters first."),
+		mcp.WithString("cluster_resource_ids",
+			mcp.Description("Comma-separated list of AKS cluster resource IDs (required unless fleet_resource_id is set)"),
+		),
+		mcp.WithString("fleet_resource_id",
+			mcp.Description("Fleet resource ID whose member clusters are checked, for example /subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.ContainerService/fleets/<fleet>"),
+		),
+		mcp.WithString("category",
+			mcp.Description("Detector category to run"),
+			mcp.Enum(Categories...),
 			mcp.Required(),
 		),
 		mcp.WithString("start_time",
@@ -0,0 +1,358 @@
+package estate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// clusterAPIVersion is the Microsoft.ContainerService API version used to read a cluster
+const clusterAPIVersion = "2024-05-01"
+
+// Deprecation states, most urgent first
+const (
+	DeprecationRetired    = "retired"
+	DeprecationRetiring   = "retiring"
+	DeprecationDeprecated = "deprecated"
+)
+
+// deprecationRank orders deprecation states, most urgent first
+var deprecationRank = map[string]int{
+	DeprecationRetired:    0,
+	DeprecationRetiring:   1,
+	DeprecationDeprecated: 2,
+}
+
+// deprecation is a retired or deprecated AKS feature. RetirementDate is empty when AKS announced no date,
+// in which case Status is used. Migration commands use {rg}, {cluster} and {pool} placeholders.
+type deprecation struct {
+	Feature        string
+	RetirementDate string
+	Status         string
+	Details        string
+	Migration      []string
+}
+
+// Deprecated features the tool detects
+var (
+	deprecationHTTPApplicationRouting = deprecation{
+		Feature:        "HTTP application routing add-on",
+		RetirementDate: "2025-03-03",
+		Details: "The add-on is not supported for production and was replaced by the application routing add-on (managed NGINX). " +
+			"Ingresses must move to the webapprouting.kubernetes.azure.com ingress class and DNS records off the add-on's DNS zone.",
+		Migration: []string{
+			"az aks approuting enable --resource-group {rg} --name {cluster}",
+			"kubectl get ingress -A -o wide  # set spec.ingressClassName: webapprouting.kubernetes.azure.com on each ingress",
+			"az aks disable-addons --addons http_application_routing --resource-group {rg} --name {cluster}",
+		},
+	}
+	deprecationBasicLoadBalancer = deprecation{
+		Feature:        "Basic SKU load balancer",
+		RetirementDate: "2025-09-30",
+		Details: "Basic Load Balancer is retired. Migrating to the Standard SKU recreates the load balancer, " +
+			"so outbound and service IPs change and the cluster has downtime during the update.",
+		Migration: []string{
+			"az aks update --resource-group {rg} --name {cluster} --load-balancer-sku standard",
+		},
+	}
+	deprecationKubenet = deprecation{
+		Feature:        "kubenet networking",
+		RetirementDate: "2028-03-31",
+		Details:        "kubenet is retiring in favor of Azure CNI Overlay, which keeps pod IPs off the VNet without route tables.",
+		Migration: []string{
+			"az aks update --resource-group {rg} --name {cluster} --network-plugin azure --network-plugin-mode overlay",
+		},
+	}
+	deprecationOpenServiceMesh = deprecation{
+		Feature:        "Open Service Mesh add-on",
+		RetirementDate: "2027-09-30",
+		Details:        "The Open Service Mesh project is archived; the Istio-based service mesh add-on replaces it.",
+		Migration: []string{
+			"az aks mesh enable --resource-group {rg} --name {cluster}",
+			"az aks disable-addons --addons open-service-mesh --resource-group {rg} --name {cluster}",
+		},
+	}
+	deprecationKubeDashboard = deprecation{
+		Feature: "Kubernetes dashboard add-on",
+		Status:  DeprecationRetired,
+		Details: "The dashboard add-on is no longer supported; use the Kubernetes resource view in the Azure portal.",
+		Migration: []string{
+			"az aks disable-addons --addons kube-dashboard --resource-group {rg} --name {cluster}",
+		},
+	}
+	deprecationPodIdentity = deprecation{
+		Feature: "Microsoft Entra pod-managed identity",
+		Status:  DeprecationDeprecated,
+		Details: "Pod-managed identity (aad-pod-identity) is deprecated and its open source project archived; " +
+			"workload identity replaces it. Federate each identity with its service account before disabling it.",
+		Migration: []string{
+			"az aks update --resource-group {rg} --name {cluster} --enable-oidc-issuer --enable-workload-identity",
+			"az identity federated-credential create --name <name> --identity-name <identity> --resource-group <identity-rg> --issuer <oidc-issuer-url> --subject system:serviceaccount:<namespace>:<service-account>",
+			"az aks update --resource-group {rg} --name {cluster} --disable-pod-identity",
+		},
+	}
+	deprecationLegacyAAD = deprecation{
+		Feature: "Legacy Microsoft Entra ID integration",
+		Status:  DeprecationRetired,
+		Details: "Legacy integration with your own server and client app registrations is retired; AKS-managed Entra ID integration replaces it.",
+		Migration: []string{
+			"az aks update --resource-group {rg} --name {cluster} --enable-aad --aad-admin-group-object-ids <group-id>",
+		},
+	}
+	deprecationDockershim = deprecation{
+		Feature: "Docker container runtime (dockershim)",
+		Status:  DeprecationRetired,
+		Details: "Kubernetes 1.24 removed dockershim, and nodes before it may still run Docker, notably Windows pools before 1.23. " +
+			"Workloads that mount /var/run/docker.sock or build images with the node's Docker daemon break on containerd.",
+		Migration: []string{
+			"az aks nodepool upgrade --resource-group {rg} --cluster-name {cluster} --name {pool} --kubernetes-version <version>",
+		},
+	}
+	deprecationWindows2019 = deprecation{
+		Feature:        "Windows Server 2019 node pools",
+		RetirementDate: "2026-03-01",
+		Details:        "Windows Server 2019 is retiring and not supported from Kubernetes 1.33; move workloads to a Windows Server 2022 pool.",
+		Migration: []string{
+			"az aks nodepool add --resource-group {rg} --cluster-name {cluster} --name <new-pool> --os-type Windows --os-sku Windows2022",
+			"kubectl drain -l agentpool={pool} --ignore-daemonsets --delete-emptydir-data",
+			"az aks nodepool delete --resource-group {rg} --cluster-name {cluster} --name {pool}",
+		},
+	}
+	deprecationUbuntu1804 = deprecation{
+		Feature: "Ubuntu 18.04 node image",
+		Status:  DeprecationRetired,
+		Details: "Ubuntu 18.04 is out of support; node pools on Kubernetes 1.25 or later use Ubuntu 22.04.",
+		Migration: []string{
+			"az aks nodepool upgrade --resource-group {rg} --cluster-name {cluster} --name {pool} --kubernetes-version <version>",
+		},
+	}
+	deprecationAzureLinux2 = deprecation{
+		Feature:        "Azure Linux 2.0 (CBL-Mariner) node image",
+		RetirementDate: "2025-11-30",
+		Details:        "Azure Linux 2.0 reaches end of support; node pools on Kubernetes 1.32 or later use Azure Linux 3.0.",
+		Migration: []string{
+			"az aks nodepool upgrade --resource-group {rg} --cluster-name {cluster} --name {pool} --kubernetes-version <1.32 or later>",
+		},
+	}
+)
+
+// DeprecatedFeature is a retired or deprecated feature a cluster or node pool uses
+type DeprecatedFeature struct {
+	Feature             string   `json:"feature"`
+	NodePool            string   `json:"nodePool,omitempty"`
+	Status              string   `json:"status"`
+	RetirementDate      string   `json:"retirementDate,omitempty"`
+	DaysUntilRetirement *int     `json:"daysUntilRetirement,omitempty"`
+	Details             string   `json:"details"`
+	Migration           []string `json:"migration"`
+}
+
+// DeprecationReport is the result returned by the aks_deprecated_features tool
+type DeprecationReport struct {
+	ClusterName       string              `json:"clusterName"`
+	KubernetesVersion string              `json:"kubernetesVersion"`
+	SupportState      string              `json:"supportState"`
+	Features          []DeprecatedFeature `json:"features"`
+	Summary           map[string]int      `json:"summary"`
+	Warnings          []string            `json:"warnings,omitempty"`
+	Note              string              `json:"note"`
+}
+
+// GetDeprecatedFeaturesHandler returns a handler for the aks_deprecated_features command
+func GetDeprecatedFeaturesHandler(azClient *azureclient.AzureClient, _ *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleDeprecatedFeatures(params, azClient)
+	})
+}
+
+// HandleDeprecatedFeatures lists the retired and deprecated features a cluster uses with migration commands
+func HandleDeprecatedFeatures(params map[string]interface{}, reader Reader) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	path := common.ClusterResourceID(subID, rg, clusterName) + "?api-version=" + clusterAPIVersion
+	body, err := reader.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+	var cluster map[string]interface{}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %v", err)
+	}
+
+	var warnings []string
+	supported, err := listSupportedVersions(ctx, reader, subID, strings.ToLower(rowString(cluster, "location")))
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to list supported Kubernetes versions: %v; the version support state is unknown", err))
+	}
+
+	report := BuildDeprecationReport(cluster, rg, supported, time.Now())
+	report.Warnings = append(report.Warnings, warnings...)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal deprecated features to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// BuildDeprecationReport finds the retired and deprecated features in the ARM JSON of a cluster. Without
+// supported versions the Kubernetes version is not checked.
+func BuildDeprecationReport(cluster map[string]interface{}, resourceGroup string, supported []VersionSupport, now time.Time) DeprecationReport {
+	props, _ := cluster["properties"].(map[string]interface{})
+	clusterName := rowString(cluster, "name")
+	report := DeprecationReport{
+		ClusterName:       clusterName,
+		KubernetesVersion: rowString(props, "currentKubernetesVersion"),
+		SupportState:      SupportUnknown,
+		Features:          []DeprecatedFeature{},
+		Summary:           map[string]int{},
+		Note: "Retirement dates are from AKS and Azure retirement announcements and may move; " +
+			"check the AKS release notes before planning. Migration commands contain <placeholders> to fill in.",
+	}
+	if report.KubernetesVersion == "" {
+		report.KubernetesVersion = rowString(props, "kubernetesVersion")
+	}
+
+	add := func(d deprecation, pool string) {
+		feature := DeprecatedFeature{
+			Feature:        d.Feature,
+			NodePool:       pool,
+			Status:         d.Status,
+			RetirementDate: d.RetirementDate,
+			Details:        d.Details,
+		}
+		if retirement, err := time.Parse("2006-01-02", d.RetirementDate); err == nil {
+			days := int(retirement.Sub(now).Hours() / 24)
+			if retirement.After(now) {
+				feature.Status = DeprecationRetiring
+				feature.DaysUntilRetirement = &days
+			} else {
+				feature.Status = DeprecationRetired
+			}
+		}
+		replacer := strings.NewReplacer("{rg}", resourceGroup, "{cluster}", clusterName, "{pool}", pool)
+		for _, command := range d.Migration {
+			feature.Migration = append(feature.Migration, replacer.Replace(command))
+		}
+		report.Features = append(report.Features, feature)
+	}
+
+	// Kubernetes version
+	if len(supported) > 0 {
+		report.SupportState = supportState(report.KubernetesVersion, rowString(props, "supportPlan"), supported)
+		if report.SupportState == SupportOutOfSupport || report.SupportState == SupportPlatformSupport {
+			add(deprecation{
+				Feature: fmt.Sprintf("Kubernetes %s", report.KubernetesVersion),
+				Status:  DeprecationRetired,
+				Details: fmt.Sprintf("Kubernetes %s is %s in the cluster's region. Upgrade one minor version at a time and "+
+					"check for removed APIs before each step.", report.KubernetesVersion, supportDescription(report.SupportState)),
+				Migration: []string{
+					"az aks get-upgrades --resource-group {rg} --name {cluster} --output table",
+					"az aks upgrade --resource-group {rg} --name {cluster} --kubernetes-version <version>",
+				},
+			}, "")
+		}
+	}
+
+	// Cluster add-ons and features
+	addons, _ := props["addonProfiles"].(map[string]interface{})
+	if addonEnabled(addons, "httpApplicationRouting") {
+		add(deprecationHTTPApplicationRouting, "")
+	}
+	if addonEnabled(addons, "openServiceMesh") {
+		add(deprecationOpenServiceMesh, "")
+	}
+	if addonEnabled(addons, "kubeDashboard") {
+		add(deprecationKubeDashboard, "")
+	}
+	network, _ := props["networkProfile"].(map[string]interface{})
+	if strings.EqualFold(rowString(network, "loadBalancerSku"), "basic") {
+		add(deprecationBasicLoadBalancer, "")
+	}
+	if strings.EqualFold(rowString(network, "networkPlugin"), "kubenet") {
+		add(deprecationKubenet, "")
+	}
+	if podIdentity, ok := props["podIdentityProfile"].(map[string]interface{}); ok && podIdentity["enabled"] == true {
+		add(deprecationPodIdentity, "")
+	}
+	if aad, ok := props["aadProfile"].(map[string]interface{}); ok && aad["managed"] != true && rowString(aad, "serverAppID") != "" {
+		add(deprecationLegacyAAD, "")
+	}
+
+	// Node pools
+	profiles, _ := props["agentPoolProfiles"].([]interface{})
+	for _, raw := range profiles {
+		pool, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := rowString(pool, "name")
+		version := rowString(pool, "currentOrchestratorVersion")
+		if version == "" {
+			version = rowString(pool, "orchestratorVersion")
+		}
+		osSKU := rowString(pool, "osSKU")
+		image := rowString(pool, "nodeImageVersion")
+		if version != "" && compareMinor(version, "1.24") < 0 {
+			add(deprecationDockershim, name)
+		}
+		if strings.EqualFold(rowString(pool, "osType"), "Windows") &&
+			(strings.EqualFold(osSKU, "Windows2019") || (osSKU == "" && version != "" && compareMinor(version, "1.25") < 0)) {
+			add(deprecationWindows2019, name)
+		}
+		if strings.Contains(image, "Ubuntu-1804") {
+			add(deprecationUbuntu1804, name)
+		}
+		if strings.EqualFold(osSKU, "CBLMariner") || strings.EqualFold(osSKU, "Mariner") ||
+			strings.Contains(image, "AzureLinux-V2") || strings.Contains(image, "Mariner-V2") {
+			add(deprecationAzureLinux2, name)
+		}
+	}
+
+	for _, feature := range report.Features {
+		report.Summary[feature.Status]++
+	}
+	sort.SliceStable(report.Features, func(i, j int) bool {
+		a, b := report.Features[i], report.Features[j]
+		if deprecationRank[a.Status] != deprecationRank[b.Status] {
+			return deprecationRank[a.Status] < deprecationRank[b.Status]
+		}
+		return a.RetirementDate < b.RetirementDate
+	})
+	return report
+}
+
+// addonEnabled reports whether an add-on profile is enabled, matching its name case-insensitively
+// since older clusters use different casing
+func addonEnabled(addons map[string]interface{}, name string) bool {
+	for key, value := range addons {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		if profile, ok := value.(map[string]interface{}); ok && profile["enabled"] == true {
+			return true
+		}
+	}
+	return false
+}
+
+// supportDescription describes a support state in a sentence
+func supportDescription(state string) string {
+	if state == SupportPlatformSupport {
+		return "only under platform support"
+	}
+	return "out of support"
+}
@@ -0,0 +1,308 @@
+package estate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/notes"
+)
+
+type fakeReader struct {
+	clusters  string
+	health    string
+	versions  map[string]string
+	cluster   string
+	healthErr error
+	queries   []string
+	paths     []string
+}
+
+func (f *fakeReader) QueryResourceGraph(_ context.Context, query string, subscriptions []string) ([]map[string]interface{}, error) {
+	f.queries = append(f.queries, fmt.Sprintf("%v %s", subscriptions, query))
+	body := f.clusters
+	if strings.HasPrefix(query, "healthresources") {
+		if f.healthErr != nil {
+			return nil, f.healthErr
+		}
+		body = f.health
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (f *fakeReader) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	f.paths = append(f.paths, path)
+	if f.cluster != "" && strings.Contains(path, "/managedClusters/") {
+		return []byte(f.cluster), nil
+	}
+	for location, body := range f.versions {
+		if strings.Contains(path, "/locations/"+location+"/") {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected path %s", path)
+}
+
+const testVersions = `{"values":[
+  {"version":"1.27","capabilities":{"supportPlan":["AKSLongTermSupport"]}},
+  {"version":"1.29","capabilities":{"supportPlan":["KubernetesOfficial","AKSLongTermSupport"]}},
+  {"version":"1.30","capabilities":{"supportPlan":["KubernetesOfficial"]}},
+  {"version":"1.31","isPreview":true,"capabilities":{"supportPlan":["KubernetesOfficial"]}}
+]}`
+
+func testCluster(name, location, version, plan, provisioning string) string {
+	return fmt.Sprintf(`{"id":"/subscriptions/sub1/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/%[1]s",
+  "name":%[1]q,"subscriptionId":"sub1","resourceGroup":"rg","location":%[2]q,"skuTier":"Standard",
+  "kubernetesVersion":%[3]q,"supportPlan":%[4]q,"provisioningState":%[5]q,"powerState":"Running",
+  "agentPools":[{"name":"system","mode":"System","vmSize":"Standard_D4s_v5","count":3,"currentOrchestratorVersion":%[3]q}]}`,
+		name, location, version, plan, provisioning)
+}
+
+func newTestReader() *fakeReader {
+	return &fakeReader{
+		clusters: `[` + strings.Join([]string{
+			testCluster("current", "eastus", "1.30.3", "KubernetesOfficial", "Succeeded"),
+			testCluster("old", "eastus", "1.26.6", "KubernetesOfficial", "Succeeded"),
+			testCluster("platform", "eastus", "1.28.9", "KubernetesOfficial", "Succeeded"),
+			testCluster("lts", "eastus", "1.27.9", "AKSLongTermSupport", "Succeeded"),
+			testCluster("failed", "westeurope", "1.29.4", "KubernetesOfficial", "Failed"),
+		}, ",") + `]`,
+		health: `[{"targetResourceId":"/subscriptions/sub1/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/current","availabilityState":"Available"},
+  {"targetResourceId":"/subscriptions/sub1/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/old","availabilityState":"Degraded","summary":"API server latency is high"}]`,
+		versions: map[string]string{"eastus": testVersions, "westeurope": testVersions},
+	}
+}
+
+func runOverview(t *testing.T, params map[string]interface{}, reader *fakeReader) EstateReport {
+	t.Helper()
+	output, err := HandleEstateOverview(params, reader, nil, config.VerbosityStandard)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report EstateReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestEstateOverview tests support states, health roll-up, summary counts and ordering
+func TestEstateOverview(t *testing.T) {
+	reader := newTestReader()
+	report := runOverview(t, map[string]interface{}{}, reader)
+
+	byName := map[string]ClusterOverview{}
+	for _, cluster := range report.Clusters {
+		byName[cluster.Name] = cluster
+	}
+	tests := []struct {
+		name    string
+		support string
+		health  string
+	}{
+		{"current", SupportSupported, HealthHealthy},
+		{"old", SupportOutOfSupport, HealthDegraded},
+		{"platform", SupportPlatformSupport, HealthUnknown},
+		{"lts", SupportLongTermSupport, HealthUnknown},
+		{"failed", SupportSupported, HealthUnhealthy},
+	}
+	for _, tt := range tests {
+		cluster := byName[tt.name]
+		if cluster.SupportState != tt.support || cluster.Health != tt.health {
+			t.Errorf("%s: expected %s and %s, got %s and %s", tt.name, tt.support, tt.health, cluster.SupportState, cluster.Health)
+		}
+	}
+	if report.Clusters[0].Name != "old" || report.Clusters[1].Name != "platform" {
+		t.Errorf("Expected the least supported clusters first, got %s, %s", report.Clusters[0].Name, report.Clusters[1].Name)
+	}
+	if issues := strings.Join(byName["old"].Issues, "; "); !strings.Contains(issues, "out of support") || !strings.Contains(issues, "API server latency") {
+		t.Errorf("Unexpected issues %q", issues)
+	}
+	if report.Summary.Clusters != 5 || report.Summary.Nodes != 15 || report.Summary.Subscriptions != 1 ||
+		report.Summary.BySupportState[SupportSupported] != 2 || report.Summary.ByVersion["1.30"] != 1 {
+		t.Errorf("Unexpected summary %+v", report.Summary)
+	}
+	if len(reader.paths) != 2 {
+		t.Errorf("Expected supported versions to be read once per region, got %v", reader.paths)
+	}
+}
+
+// TestEstateOverviewFilters tests the filters, subscription validation and unreadable sources
+func TestEstateOverviewFilters(t *testing.T) {
+	report := runOverview(t, map[string]interface{}{"filter": FilterOutOfSupport}, newTestReader())
+	if len(report.Clusters) != 2 || report.Summary.Clusters != 2 {
+		t.Errorf("Expected the out of support and platform support clusters, got %+v", report.Clusters)
+	}
+	report = runOverview(t, map[string]interface{}{"filter": FilterUnhealthy}, newTestReader())
+	if len(report.Clusters) != 2 {
+		t.Errorf("Expected the degraded and failed clusters, got %+v", report.Clusters)
+	}
+
+	if _, err := HandleEstateOverview(map[string]interface{}{"subscriptions": "sub1' or 1==1"}, newTestReader(), nil, config.VerbosityStandard); err == nil {
+		t.Error("Expected an invalid subscription ID to be rejected")
+	}
+	if _, err := HandleEstateOverview(map[string]interface{}{"filter": "everything"}, newTestReader(), nil, config.VerbosityStandard); err == nil {
+		t.Error("Expected an invalid filter to be rejected")
+	}
+
+	reader := newTestReader()
+	reader.healthErr = fmt.Errorf("forbidden")
+	delete(reader.versions, "westeurope")
+	sub := "00000000-0000-0000-0000-000000000001"
+	report = runOverview(t, map[string]interface{}{"subscriptions": " " + sub + ", "}, reader)
+	if len(report.Warnings) != 2 {
+		t.Errorf("Expected warnings for Resource Health and the westeurope versions, got %v", report.Warnings)
+	}
+	for _, cluster := range report.Clusters {
+		if cluster.Name == "failed" && cluster.SupportState != SupportUnknown {
+			t.Errorf("Expected unknown support without the region's versions, got %s", cluster.SupportState)
+		}
+	}
+	if !strings.HasPrefix(reader.queries[0], "["+sub+"]") {
+		t.Errorf("Expected the query to be limited to the given subscription, got %q", reader.queries[0])
+	}
+}
+
+// TestEstateOverviewNotes tests that operator notes are shown with their clusters
+func TestEstateOverviewNotes(t *testing.T) {
+	book := notes.NewBook(nil)
+	if _, err := book.Add(notes.ClusterID("sub1", "RG", "current"), "canary for the shop team", "platform"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output, err := HandleEstateOverview(map[string]interface{}{}, newTestReader(), book, config.VerbosityStandard)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report EstateReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	for _, cluster := range report.Clusters {
+		wantNotes := 0
+		if cluster.Name == "current" {
+			wantNotes = 1
+		}
+		if len(cluster.Notes) != wantNotes {
+			t.Errorf("Expected %d notes on %s, got %v", wantNotes, cluster.Name, cluster.Notes)
+		}
+		if wantNotes == 1 && !strings.HasPrefix(cluster.Notes[0], "canary for the shop team (platform, ") {
+			t.Errorf("Unexpected note %q", cluster.Notes[0])
+		}
+	}
+}
+
+// TestEstateOverviewVerbosity tests the summary and raw verbosity profiles
+func TestEstateOverviewVerbosity(t *testing.T) {
+	output, err := HandleEstateOverview(map[string]interface{}{}, newTestReader(), nil, config.VerbositySummary)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report EstateReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Clusters) != 3 || report.Clusters[0].Name != "old" {
+		t.Errorf("Expected only the clusters with issues, got %+v", report.Clusters)
+	}
+	if !strings.HasPrefix(report.Headline, "5 AKS clusters with 15 nodes in 1 subscriptions: 1 out of support") ||
+		!strings.Contains(report.Headline, "3 clusters need attention, starting with old") {
+		t.Errorf("Unexpected headline %q", report.Headline)
+	}
+	if report.Summary.Clusters != 5 || report.Resources != nil {
+		t.Errorf("Expected the counts of every cluster and no raw rows, got %+v", report.Summary)
+	}
+
+	output, err = HandleEstateOverview(map[string]interface{}{}, newTestReader(), nil, config.VerbosityRaw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report = EstateReport{}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Resources) != 5 || report.Headline != "" || len(report.Clusters) != 5 {
+		t.Errorf("Expected every cluster with its Resource Graph row, got %d rows", len(report.Resources))
+	}
+}
+
+// TestDeprecatedFeatures tests detection, statuses, ordering and migration commands
+func TestDeprecatedFeatures(t *testing.T) {
+	reader := &fakeReader{
+		versions: map[string]string{"eastus": testVersions},
+		cluster: `{"name":"legacy","location":"eastus","properties":{
+  "currentKubernetesVersion":"1.28.9","supportPlan":"KubernetesOfficial",
+  "addonProfiles":{"httpapplicationrouting":{"enabled":true},"openServiceMesh":{"enabled":false}},
+  "networkProfile":{"networkPlugin":"kubenet","loadBalancerSku":"basic"},
+  "agentPoolProfiles":[
+    {"name":"system","osType":"Linux","osSKU":"Ubuntu","currentOrchestratorVersion":"1.28.9","nodeImageVersion":"AKSUbuntu-2204gen2containerd-202405.03.0"},
+    {"name":"win","osType":"Windows","osSKU":"Windows2019","currentOrchestratorVersion":"1.28.9"},
+    {"name":"mariner","osType":"Linux","osSKU":"AzureLinux","currentOrchestratorVersion":"1.28.9","nodeImageVersion":"AKSAzureLinux-V2gen2-202405.03.0"}]}}`,
+	}
+	output, err := HandleDeprecatedFeatures(map[string]interface{}{"subscription_id": "sub1", "resource_group": "rg", "cluster_name": "legacy"}, reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report DeprecationReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.SupportState != SupportPlatformSupport {
+		t.Errorf("Expected platform support for 1.28, got %s", report.SupportState)
+	}
+
+	features := map[string]DeprecatedFeature{}
+	for _, feature := range report.Features {
+		features[feature.Feature+"/"+feature.NodePool] = feature
+	}
+	for _, want := range []string{"Kubernetes 1.28.9/", "HTTP application routing add-on/", "Basic SKU load balancer/",
+		"kubenet networking/", "Windows Server 2019 node pools/win", "Azure Linux 2.0 (CBL-Mariner) node image/mariner"} {
+		if _, ok := features[want]; !ok {
+			t.Errorf("Expected %s to be reported, got %+v", want, report.Features)
+		}
+	}
+	if len(report.Features) != 6 {
+		t.Errorf("Expected 6 features, got %d", len(report.Features))
+	}
+	routing := features["HTTP application routing add-on/"]
+	if routing.Migration[0] != "az aks approuting enable --resource-group rg --name legacy" {
+		t.Errorf("Expected migration commands filled in, got %v", routing.Migration)
+	}
+	win := features["Windows Server 2019 node pools/win"]
+	if !strings.Contains(strings.Join(win.Migration, "\n"), "--cluster-name legacy --name win") {
+		t.Errorf("Expected the node pool in migration commands, got %v", win.Migration)
+	}
+	for i := 1; i < len(report.Features); i++ {
+		if deprecationRank[report.Features[i-1].Status] > deprecationRank[report.Features[i].Status] {
+			t.Errorf("Expected features ordered by status, got %+v", report.Features)
+		}
+	}
+}
+
+// TestDeprecationStatus tests that retirement dates decide between retiring and retired
+func TestDeprecationStatus(t *testing.T) {
+	cluster := map[string]interface{}{"name": "c", "properties": map[string]interface{}{
+		"networkProfile": map[string]interface{}{"networkPlugin": "kubenet"},
+	}}
+
+	report := BuildDeprecationReport(cluster, "rg", nil, time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(report.Features) != 1 || report.Features[0].Status != DeprecationRetiring ||
+		report.Features[0].DaysUntilRetirement == nil || *report.Features[0].DaysUntilRetirement != 30 {
+		t.Errorf("Expected kubenet retiring in 30 days, got %+v", report.Features)
+	}
+	if report.SupportState != SupportUnknown {
+		t.Errorf("Expected unknown support without versions, got %s", report.SupportState)
+	}
+
+	report = BuildDeprecationReport(cluster, "rg", nil, time.Date(2028, 4, 1, 0, 0, 0, 0, time.UTC))
+	if report.Features[0].Status != DeprecationRetired || report.Features[0].DaysUntilRetirement != nil || report.Summary[DeprecationRetired] != 1 {
+		t.Errorf("Expected kubenet retired, got %+v", report)
+	}
+}
@@ -0,0 +1,559 @@
+// Package estate gives an overview of every AKS cluster across the subscriptions a credential can read:
+// Kubernetes version and its support state, node counts, SKU tier and rolled-up health.
+package estate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/notes"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// kubernetesVersionsAPIVersion is the Microsoft.ContainerService API version used to list supported versions
+const kubernetesVersionsAPIVersion = "2024-02-01"
+
+// Support states of a cluster's Kubernetes version
+const (
+	SupportSupported       = "supported"
+	SupportPreview         = "preview"
+	SupportLongTermSupport = "longTermSupport"
+	SupportPlatformSupport = "platformSupport"
+	SupportOutOfSupport    = "outOfSupport"
+	SupportUnknown         = "unknown"
+)
+
+// Rolled-up health states
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+	HealthStopped   = "stopped"
+	HealthUnknown   = "unknown"
+)
+
+// Filters selecting the clusters returned
+const (
+	FilterAll          = "all"
+	FilterOutOfSupport = "out_of_support"
+	FilterUnhealthy    = "unhealthy"
+)
+
+// Support plans reported by the kubernetesVersions API and set on clusters
+const (
+	planKubernetesOfficial = "KubernetesOfficial"
+	planLongTermSupport    = "AKSLongTermSupport"
+)
+
+// supportRank orders support states, least supported first
+var supportRank = map[string]int{
+	SupportOutOfSupport:    0,
+	SupportPlatformSupport: 1,
+	SupportUnknown:         2,
+	SupportPreview:         3,
+	SupportLongTermSupport: 4,
+	SupportSupported:       5,
+}
+
+// healthRank orders health states, least healthy first
+var healthRank = map[string]int{
+	HealthUnhealthy: 0,
+	HealthDegraded:  1,
+	HealthUnknown:   2,
+	HealthStopped:   3,
+	HealthHealthy:   4,
+}
+
+// subscriptionPattern matches subscription IDs
+var subscriptionPattern = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// clusterQuery lists every AKS cluster with the properties the overview reports
+const clusterQuery = `resources
+| where type =~ 'microsoft.containerservice/managedclusters'
+| project id, name, subscriptionId, resourceGroup, location,
+  skuTier = tostring(sku.tier),
+  kubernetesVersion = tostring(properties.currentKubernetesVersion),
+  requestedVersion = tostring(properties.kubernetesVersion),
+  supportPlan = tostring(properties.supportPlan),
+  provisioningState = tostring(properties.provisioningState),
+  powerState = tostring(properties.powerState.code),
+  agentPools = properties.agentPoolProfiles`
+
+// healthQuery lists the Resource Health availability of every AKS cluster
+const healthQuery = `healthresources
+| where type =~ 'microsoft.resourcehealth/availabilitystatuses'
+| where tostring(properties.targetResourceType) =~ 'microsoft.containerservice/managedclusters'
+| project targetResourceId = tolower(tostring(properties.targetResourceId)),
+  availabilityState = tostring(properties.availabilityState),
+  summary = tostring(properties.summary)`
+
+// Reader queries Azure Resource Graph and the ARM API. *azureclient.AzureClient implements it.
+type Reader interface {
+	QueryResourceGraph(ctx context.Context, query string, subscriptions []string) ([]map[string]interface{}, error)
+	CallARM(ctx context.Context, method, path string) ([]byte, error)
+}
+
+// NodePool is the size and version of one node pool
+type NodePool struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode,omitempty"`
+	VMSize   string `json:"vmSize,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Count    int    `json:"count"`
+	MinCount int    `json:"minCount,omitempty"`
+	MaxCount int    `json:"maxCount,omitempty"`
+}
+
+// ClusterOverview is the state of one cluster
+type ClusterOverview struct {
+	Name              string     `json:"name"`
+	SubscriptionID    string     `json:"subscriptionId"`
+	ResourceGroup     string     `json:"resourceGroup"`
+	Location          string     `json:"location"`
+	KubernetesVersion string     `json:"kubernetesVersion"`
+	SupportState      string     `json:"supportState"`
+	SupportPlan       string     `json:"supportPlan,omitempty"`
+	SKUTier           string     `json:"skuTier,omitempty"`
+	ProvisioningState string     `json:"provisioningState,omitempty"`
+	PowerState        string     `json:"powerState,omitempty"`
+	Health            string     `json:"health"`
+	HealthSummary     string     `json:"healthSummary,omitempty"`
+	Nodes             int        `json:"nodes"`
+	NodePools         []NodePool `json:"nodePools"`
+	Issues            []string   `json:"issues,omitempty"`
+	// Notes are the operator notes recorded for the cluster with aks_cluster_notes
+	Notes []string `json:"notes,omitempty"`
+}
+
+// EstateSummary counts the clusters by support state, health, tier and version
+type EstateSummary struct {
+	Clusters       int            `json:"clusters"`
+	Subscriptions  int            `json:"subscriptions"`
+	Nodes          int            `json:"nodes"`
+	BySupportState map[string]int `json:"bySupportState"`
+	ByHealth       map[string]int `json:"byHealth"`
+	BySKUTier      map[string]int `json:"bySkuTier"`
+	ByVersion      map[string]int `json:"byMinorVersion"`
+}
+
+// EstateReport is the result returned by the aks_estate_overview tool
+type EstateReport struct {
+	// Headline describes the estate in one sentence (summary verbosity only)
+	Headline string            `json:"headline,omitempty"`
+	Summary  EstateSummary     `json:"summary"`
+	Clusters []ClusterOverview `json:"clusters"`
+	Warnings []string          `json:"warnings,omitempty"`
+	Note     string            `json:"note"`
+	// Resources are the Resource Graph rows the report was built from (raw verbosity only)
+	Resources []map[string]interface{} `json:"resources,omitempty"`
+}
+
+// VersionSupport is a Kubernetes minor version offered in a region with its support plans
+type VersionSupport struct {
+	Version      string `json:"version"`
+	IsPreview    bool   `json:"isPreview"`
+	Capabilities struct {
+		SupportPlan []string `json:"supportPlan"`
+	} `json:"capabilities"`
+}
+
+// GetEstateOverviewHandler returns a handler for the aks_estate_overview command
+func GetEstateOverviewHandler(azClient *azureclient.AzureClient, book *notes.Book, _ *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		return HandleEstateOverview(params, azClient, book, cfg.Verbosity)
+	})
+}
+
+// HandleEstateOverview lists the AKS clusters of the selected subscriptions with their support state and health.
+// Operator notes from book (nil for none) are attached to their clusters. The summary verbosity keeps only
+// the clusters with issues and raw verbosity adds the Resource Graph rows.
+func HandleEstateOverview(params map[string]interface{}, reader Reader, book *notes.Book, verbosity string) (string, error) {
+	var subscriptions []string
+	if value, _ := params["subscriptions"].(string); value != "" {
+		for _, sub := range strings.Split(value, ",") {
+			sub = strings.TrimSpace(sub)
+			if sub == "" {
+				continue
+			}
+			if !subscriptionPattern.MatchString(sub) {
+				return "", fmt.Errorf("invalid subscription ID: %s", sub)
+			}
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+	filter, _ := params["filter"].(string)
+	switch filter {
+	case "":
+		filter = FilterAll
+	case FilterAll, FilterOutOfSupport, FilterUnhealthy:
+	default:
+		return "", fmt.Errorf("invalid filter %q: expected %s, %s or %s", filter, FilterAll, FilterOutOfSupport, FilterUnhealthy)
+	}
+
+	ctx := context.Background()
+	rows, err := reader.QueryResourceGraph(ctx, clusterQuery, subscriptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to list AKS clusters: %v", err)
+	}
+
+	var warnings []string
+	health := map[string]map[string]interface{}{}
+	if healthRows, err := reader.QueryResourceGraph(ctx, healthQuery, subscriptions); err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to read Resource Health: %v", err))
+	} else {
+		for _, row := range healthRows {
+			health[rowString(row, "targetResourceId")] = row
+		}
+	}
+
+	// Supported versions differ by region, so they are read once per region
+	versions := map[string][]VersionSupport{}
+	for _, row := range rows {
+		location := strings.ToLower(rowString(row, "location"))
+		if _, ok := versions[location]; ok || location == "" {
+			continue
+		}
+		supported, err := listSupportedVersions(ctx, reader, rowString(row, "subscriptionId"), location)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to list supported Kubernetes versions in %s: %v", location, err))
+		}
+		versions[location] = supported
+	}
+
+	report := BuildEstateReport(rows, health, versions, filter)
+	if book != nil {
+		if byCluster, err := book.ByCluster(); err != nil {
+			warnings = append(warnings, err.Error())
+		} else {
+			AttachNotes(&report, byCluster)
+		}
+	}
+	report.Warnings = warnings
+	switch verbosity {
+	case config.VerbositySummary:
+		SummarizeEstateReport(&report)
+	case config.VerbosityRaw:
+		report.Resources = rows
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal estate overview to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// AttachNotes adds the operator notes of each cluster, keyed by notes.ClusterID, to the report
+func AttachNotes(report *EstateReport, byCluster map[string][]notes.Note) {
+	for i := range report.Clusters {
+		cluster := &report.Clusters[i]
+		for _, note := range byCluster[notes.ClusterID(cluster.SubscriptionID, cluster.ResourceGroup, cluster.Name)] {
+			cluster.Notes = append(cluster.Notes, note.String())
+		}
+	}
+}
+
+// listSupportedVersions lists the Kubernetes minor versions AKS offers in a region
+func listSupportedVersions(ctx context.Context, reader Reader, subID, location string) ([]VersionSupport, error) {
+	path := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.ContainerService/locations/%s/kubernetesVersions?api-version=%s",
+		subID, location, kubernetesVersionsAPIVersion)
+	body, err := reader.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Values []VersionSupport `json:"values"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse supported versions: %v", err)
+	}
+	return result.Values, nil
+}
+
+// BuildEstateReport builds the overview from Resource Graph rows, Resource Health keyed by lowercase
+// cluster ID and the supported versions of each region. A region without versions reports unknown support.
+func BuildEstateReport(rows []map[string]interface{}, health map[string]map[string]interface{}, versions map[string][]VersionSupport, filter string) EstateReport {
+	report := EstateReport{
+		Summary: EstateSummary{
+			BySupportState: map[string]int{},
+			ByHealth:       map[string]int{},
+			BySKUTier:      map[string]int{},
+			ByVersion:      map[string]int{},
+		},
+		Clusters: []ClusterOverview{},
+		Note: "Support states follow the AKS version policy: versions AKS no longer lists in the region are out of support, " +
+			"the minor version below the oldest community-supported one gets platform support only, and versions listed only " +
+			"for AKSLongTermSupport need the Premium tier with the long-term support plan. Health is the Resource Health " +
+			"availability of the cluster, lowered by a failed provisioning state.",
+	}
+
+	subscriptions := map[string]bool{}
+	for _, row := range rows {
+		cluster := ClusterOverview{
+			Name:              rowString(row, "name"),
+			SubscriptionID:    rowString(row, "subscriptionId"),
+			ResourceGroup:     rowString(row, "resourceGroup"),
+			Location:          strings.ToLower(rowString(row, "location")),
+			KubernetesVersion: rowString(row, "kubernetesVersion"),
+			SupportPlan:       rowString(row, "supportPlan"),
+			SKUTier:           rowString(row, "skuTier"),
+			ProvisioningState: rowString(row, "provisioningState"),
+			PowerState:        rowString(row, "powerState"),
+			NodePools:         []NodePool{},
+		}
+		if cluster.KubernetesVersion == "" {
+			cluster.KubernetesVersion = rowString(row, "requestedVersion")
+		}
+		for _, pool := range rowPools(row) {
+			cluster.Nodes += pool.Count
+			cluster.NodePools = append(cluster.NodePools, pool)
+		}
+
+		cluster.SupportState = SupportUnknown
+		if supported := versions[cluster.Location]; len(supported) > 0 {
+			cluster.SupportState = supportState(cluster.KubernetesVersion, cluster.SupportPlan, supported)
+		}
+		switch cluster.SupportState {
+		case SupportOutOfSupport:
+			cluster.Issues = append(cluster.Issues, fmt.Sprintf("Kubernetes %s is out of support; upgrade to a supported version", cluster.KubernetesVersion))
+		case SupportPlatformSupport:
+			cluster.Issues = append(cluster.Issues, fmt.Sprintf("Kubernetes %s only has platform support; Kubernetes issues are not covered until the cluster is upgraded", cluster.KubernetesVersion))
+		}
+		for _, pool := range cluster.NodePools {
+			if pool.Version != "" && minorVersion(pool.Version) != minorVersion(cluster.KubernetesVersion) {
+				cluster.Issues = append(cluster.Issues, fmt.Sprintf("node pool %s runs Kubernetes %s", pool.Name, pool.Version))
+			}
+		}
+
+		cluster.Health, cluster.HealthSummary = rollUpHealth(cluster, health[strings.ToLower(rowString(row, "id"))])
+		if cluster.Health == HealthUnhealthy || cluster.Health == HealthDegraded {
+			issue := "cluster is " + cluster.Health
+			if cluster.HealthSummary != "" {
+				issue += ": " + cluster.HealthSummary
+			}
+			cluster.Issues = append(cluster.Issues, issue)
+		}
+
+		switch filter {
+		case FilterOutOfSupport:
+			if cluster.SupportState != SupportOutOfSupport && cluster.SupportState != SupportPlatformSupport {
+				continue
+			}
+		case FilterUnhealthy:
+			if cluster.Health != HealthUnhealthy && cluster.Health != HealthDegraded {
+				continue
+			}
+		}
+
+		subscriptions[cluster.SubscriptionID] = true
+		report.Summary.Clusters++
+		report.Summary.Nodes += cluster.Nodes
+		report.Summary.BySupportState[cluster.SupportState]++
+		report.Summary.ByHealth[cluster.Health]++
+		if cluster.SKUTier != "" {
+			report.Summary.BySKUTier[cluster.SKUTier]++
+		}
+		if minor := minorVersion(cluster.KubernetesVersion); minor != "" {
+			report.Summary.ByVersion[minor]++
+		}
+		report.Clusters = append(report.Clusters, cluster)
+	}
+	report.Summary.Subscriptions = len(subscriptions)
+
+	sort.SliceStable(report.Clusters, func(i, j int) bool {
+		a, b := report.Clusters[i], report.Clusters[j]
+		if supportRank[a.SupportState] != supportRank[b.SupportState] {
+			return supportRank[a.SupportState] < supportRank[b.SupportState]
+		}
+		if healthRank[a.Health] != healthRank[b.Health] {
+			return healthRank[a.Health] < healthRank[b.Health]
+		}
+		if a.SubscriptionID != b.SubscriptionID {
+			return a.SubscriptionID < b.SubscriptionID
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// SummarizeEstateReport reduces a report to a headline and the clusters that have issues
+func SummarizeEstateReport(report *EstateReport) {
+	var attention []ClusterOverview
+	for _, cluster := range report.Clusters {
+		if len(cluster.Issues) > 0 {
+			attention = append(attention, cluster)
+		}
+	}
+
+	headline := fmt.Sprintf("%d AKS clusters with %d nodes in %d subscriptions", report.Summary.Clusters, report.Summary.Nodes, report.Summary.Subscriptions)
+	var problems []string
+	if n := report.Summary.BySupportState[SupportOutOfSupport]; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d out of support", n))
+	}
+	if n := report.Summary.BySupportState[SupportPlatformSupport]; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d on platform support only", n))
+	}
+	if n := report.Summary.ByHealth[HealthUnhealthy]; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d unhealthy", n))
+	}
+	if n := report.Summary.ByHealth[HealthDegraded]; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d degraded", n))
+	}
+	if len(problems) > 0 {
+		headline += ": " + strings.Join(problems, ", ")
+	}
+	if len(attention) == 0 {
+		headline += ". No cluster needs attention."
+	} else {
+		headline += fmt.Sprintf(". %d clusters need attention, starting with %s: %s.", len(attention), attention[0].Name, attention[0].Issues[0])
+	}
+
+	report.Headline = headline
+	report.Clusters = append([]ClusterOverview{}, attention...)
+	report.Note = "Only clusters with issues are listed; call again with verbosity standard for every cluster."
+}
+
+// supportState returns the support state of a Kubernetes version given the minor versions offered in the region
+func supportState(version, clusterPlan string, supported []VersionSupport) string {
+	minor := minorVersion(version)
+	if minor == "" {
+		return SupportUnknown
+	}
+	oldestOfficial := ""
+	for _, v := range supported {
+		if !v.IsPreview && contains(v.Capabilities.SupportPlan, planKubernetesOfficial) &&
+			(oldestOfficial == "" || compareMinor(v.Version, oldestOfficial) < 0) {
+			oldestOfficial = v.Version
+		}
+		if minorVersion(v.Version) != minor {
+			continue
+		}
+		switch {
+		case v.IsPreview:
+			return SupportPreview
+		case contains(v.Capabilities.SupportPlan, planKubernetesOfficial):
+			if clusterPlan == planLongTermSupport {
+				return SupportLongTermSupport
+			}
+			return SupportSupported
+		case contains(v.Capabilities.SupportPlan, planLongTermSupport) && clusterPlan == planLongTermSupport:
+			return SupportLongTermSupport
+		}
+	}
+	// AKS gives the minor version before the oldest supported one platform support only
+	if oldestOfficial != "" && compareMinor(minor, oldestOfficial) < 0 && nextMinor(minor) == minorVersion(oldestOfficial) {
+		return SupportPlatformSupport
+	}
+	return SupportOutOfSupport
+}
+
+// rollUpHealth combines the Resource Health availability with the provisioning and power state
+func rollUpHealth(cluster ClusterOverview, status map[string]interface{}) (string, string) {
+	if strings.EqualFold(cluster.PowerState, "Stopped") {
+		return HealthStopped, "cluster is stopped"
+	}
+	summary := rowString(status, "summary")
+	if strings.EqualFold(cluster.ProvisioningState, "Failed") {
+		if summary == "" {
+			summary = "the last operation on the cluster failed"
+		}
+		return HealthUnhealthy, summary
+	}
+	switch strings.ToLower(rowString(status, "availabilityState")) {
+	case "available":
+		return HealthHealthy, ""
+	case "degraded":
+		return HealthDegraded, summary
+	case "unavailable":
+		return HealthUnhealthy, summary
+	default:
+		return HealthUnknown, summary
+	}
+}
+
+// rowPools reads the agent pool profiles of a cluster row
+func rowPools(row map[string]interface{}) []NodePool {
+	profiles, _ := row["agentPools"].([]interface{})
+	var pools []NodePool
+	for _, raw := range profiles {
+		profile, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		version := rowString(profile, "currentOrchestratorVersion")
+		if version == "" {
+			version = rowString(profile, "orchestratorVersion")
+		}
+		pools = append(pools, NodePool{
+			Name:     rowString(profile, "name"),
+			Mode:     rowString(profile, "mode"),
+			VMSize:   rowString(profile, "vmSize"),
+			Version:  version,
+			Count:    rowInt(profile, "count"),
+			MinCount: rowInt(profile, "minCount"),
+			MaxCount: rowInt(profile, "maxCount"),
+		})
+	}
+	return pools
+}
+
+func rowString(row map[string]interface{}, key string) string {
+	value, _ := row[key].(string)
+	return value
+}
+
+func rowInt(row map[string]interface{}, key string) int {
+	value, _ := row[key].(float64)
+	return int(value)
+}
+
+// minorVersion returns the major.minor part of a Kubernetes version
+func minorVersion(version string) string {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// compareMinor compares the minor versions of two Kubernetes versions
+func compareMinor(a, b string) int {
+	am, an := splitMinor(a)
+	bm, bn := splitMinor(b)
+	if am != bm {
+		return am - bm
+	}
+	return an - bn
+}
+
+func splitMinor(version string) (int, int) {
+	parts := strings.Split(minorVersion(version), ".")
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+	return major, minor
+}
+
+func nextMinor(version string) string {
+	major, minor := splitMinor(version)
+	return fmt.Sprintf("%d.%d", major, minor+1)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package estate
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterEstateOverviewTool registers the aks_estate_overview tool
+func RegisterEstateOverviewTool() mcp.Tool {
+	description := `List every AKS cluster across the subscriptions this server can read, with a rolled-up summary. Answers questions such as "which of my clusters are out of support?"
+
+Reported for each cluster:
+- Kubernetes version and support state: supported, preview, longTermSupport, platformSupport (the minor version
+  before the oldest supported one) or outOfSupport, from the versions AKS offers in the cluster's region
+- Support plan and SKU tier (Free, Standard, Premium)
+- Node pools with their VM size, version and node count, and the total node count
+- Health: the Resource Health availability of the cluster, unhealthy when the last operation failed, or stopped
+
+Clusters are listed least supported and least healthy first. Uses Azure Resource Graph, so it covers every
+subscription the credential can read unless subscriptions is given.
+
+Examples:
+- All clusters: (no arguments)
+- Out of support only: filter="out_of_support"
+- Two subscriptions: subscriptions="<sub1>,<sub2>"`
+
+	return mcp.NewTool(
+		"aks_estate_overview",
+		mcp.WithDescription(description),
+		mcp.WithString("subscriptions",
+			mcp.Description("Comma-separated subscription IDs to include (default: all subscriptions the credential can read)"),
+		),
+		mcp.WithString("filter",
+			mcp.Description("Which clusters to return: all (default), out_of_support (including platform support only) or unhealthy"),
+			mcp.Enum(FilterAll, FilterOutOfSupport, FilterUnhealthy),
+		),
+	)
+}
+
+// RegisterDeprecatedFeaturesTool registers the aks_deprecated_features tool
+func RegisterDeprecatedFeaturesTool() mcp.Tool {
+	description := `Find retired and deprecated AKS features a cluster still uses, with their retirement dates and the commands to migrate off them.
+
+Detected from the cluster configuration:
+- Kubernetes version out of support or only under platform support in the cluster's region
+- HTTP application routing, Open Service Mesh and Kubernetes dashboard add-ons
+- Basic SKU load balancer, kubenet networking, pod-managed identity and legacy Entra ID integration
+- Node pools before Kubernetes 1.24 that may use the Docker runtime (dockershim), Windows Server 2019,
+  Ubuntu 18.04 and Azure Linux 2.0 (CBL-Mariner) node images
+
+Each feature is retired (past its retirement date or no longer supported), retiring (with the days left) or
+deprecated (no date announced), listed most urgent first. Migration commands are filled in with the cluster's
+resource group, name and node pool.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>"`
+
+	return mcp.NewTool(
+		"aks_deprecated_features",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+	)
+}
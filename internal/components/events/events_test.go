@@ -0,0 +1,155 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const testEvents = `{
+  "type": "Warning", "reason": "BackOff", "message": "Back-off restarting failed container",
+  "count": 4, "lastTimestamp": "2025-03-01T10:00:00Z",
+  "involvedObject": {"kind": "Pod", "name": "web-0", "namespace": "apps"},
+  "metadata": {"namespace": "apps"}, "source": {"component": "kubelet"}
+}
+{
+  "type": "Warning", "reason": "FailedScheduling", "message": "0/3 nodes are available",
+  "eventTime": "2025-03-01T10:00:05.000000Z",
+  "involvedObject": {"kind": "Pod", "name": "web-1", "namespace": "apps"},
+  "metadata": {"namespace": "apps"}, "reportingComponent": "default-scheduler"
+}
+`
+
+// fakeStreamer replays canned output, optionally holding the stream open until ctx is done
+type fakeStreamer struct {
+	output   string
+	holdOpen bool
+	args     []string
+}
+
+func (f *fakeStreamer) stream(ctx context.Context, args []string) (io.Reader, func() error, error) {
+	f.args = args
+	if !f.holdOpen {
+		return strings.NewReader(f.output), func() error { return nil }, nil
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte(f.output))
+		<-ctx.Done()
+		_ = writer.Close()
+	}()
+	return reader, func() error { return nil }, nil
+}
+
+// recorder collects progress notifications
+type recorder struct {
+	progress []float64
+	messages []string
+}
+
+func (r *recorder) notify(_ context.Context, progress float64, message string) error {
+	r.progress = append(r.progress, progress)
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestRegisterWatchEventsTool(t *testing.T) {
+	tool := RegisterWatchEventsTool()
+	if tool.Name != "aks_watch_events" {
+		t.Errorf("Expected tool name 'aks_watch_events', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 0 {
+		t.Errorf("Expected no required parameters, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestHandleWatchEventsStreamsEvents(t *testing.T) {
+	streamer := &fakeStreamer{output: testEvents}
+	rec := &recorder{}
+
+	result, err := HandleWatchEvents(context.Background(), map[string]interface{}{
+		"namespace":            "apps",
+		"involved_object_kind": "Pod",
+	}, streamer.stream, rec.notify, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantArgs := "get events --watch-only --output json --field-selector type=Warning,involvedObject.kind=Pod --namespace apps"
+	if got := strings.Join(streamer.args, " "); got != wantArgs {
+		t.Errorf("Expected kubectl %s, got %s", wantArgs, got)
+	}
+
+	if len(rec.progress) != 2 || rec.progress[0] != 1 || rec.progress[1] != 2 {
+		t.Errorf("Expected increasing progress for each event, got %v", rec.progress)
+	}
+	if rec.messages[0] != "Warning BackOff apps/pod/web-0: Back-off restarting failed container (x4)" {
+		t.Errorf("Unexpected notification message: %q", rec.messages[0])
+	}
+
+	var report WatchReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.Total != 2 || report.StopReason != "watch closed by the API server" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Events[1].Time != "2025-03-01T10:00:05.000000Z" || report.Events[1].Source != "default-scheduler" {
+		t.Errorf("Expected eventTime and reportingComponent fallbacks, got %+v", report.Events[1])
+	}
+}
+
+func TestHandleWatchEventsStopsWhenCancelled(t *testing.T) {
+	streamer := &fakeStreamer{output: testEvents, holdOpen: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	notify := func(context.Context, float64, string) error {
+		// Cancel the call once the first event has been streamed
+		cancel()
+		return nil
+	}
+
+	result, err := HandleWatchEvents(ctx, map[string]interface{}{"event_type": "all"}, streamer.stream, notify, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"stopReason": "cancelled"`) {
+		t.Errorf("Expected the watch to report cancellation, got %s", result)
+	}
+	if got := strings.Join(streamer.args, " "); got != "get events --watch-only --output json --all-namespaces" {
+		t.Errorf("Expected an unfiltered watch across all namespaces, got %s", got)
+	}
+}
+
+func TestHandleWatchEventsValidation(t *testing.T) {
+	restricted := config.NewConfig()
+	restricted.AllowNamespaces = "apps"
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		cfg     *config.ConfigData
+		wantErr string
+	}{
+		{"all namespaces when restricted", map[string]interface{}{}, restricted, "namespace is required"},
+		{"denied namespace", map[string]interface{}{"namespace": "kube-system"}, restricted, "denied"},
+		{"selector injection", map[string]interface{}{"involved_object_name": "web,type!=Warning"}, config.NewConfig(), "invalid involved_object_name"},
+		{"duration too long", map[string]interface{}{"duration_seconds": "3600"}, config.NewConfig(), "invalid duration_seconds"},
+		{"bad event type", map[string]interface{}{"event_type": "normal"}, config.NewConfig(), "invalid event_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamer := &fakeStreamer{}
+			_, err := HandleWatchEvents(context.Background(), tt.params, streamer.stream, (&recorder{}).notify, tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if streamer.args != nil {
+				t.Error("Expected no watch to be started")
+			}
+		})
+	}
+}
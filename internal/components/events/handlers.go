@@ -0,0 +1,263 @@
+// Package events provides a bounded-duration watch on Kubernetes events that streams
+// new events to the client as progress notifications.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// Watch limits
+const (
+	defaultWatchSeconds = 60
+	maxWatchSeconds     = 600
+	// maxReportedEvents caps the events returned in the final result; all of them are still streamed
+	maxReportedEvents = 200
+)
+
+// namePattern matches Kubernetes names and kinds used in field selectors
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9.]*[A-Za-z0-9])?$`)
+
+// Event is a Kubernetes event seen during the watch
+type Event struct {
+	Time      string `json:"time,omitempty"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Message   string `json:"message"`
+	Count     int    `json:"count,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+// WatchReport is the result returned when the watch ends
+type WatchReport struct {
+	Namespace  string  `json:"namespace,omitempty"`
+	Selector   string  `json:"fieldSelector,omitempty"`
+	Seconds    float64 `json:"seconds"`
+	StopReason string  `json:"stopReason"`
+	Total      int     `json:"totalEvents"`
+	Truncated  bool    `json:"truncated,omitempty"`
+	Events     []Event `json:"events"`
+}
+
+// eventStreamer starts kubectl with the given arguments and returns its stdout.
+// The process must stop when ctx is done; wait blocks until it has exited.
+type eventStreamer func(ctx context.Context, args []string) (stdout io.Reader, wait func() error, err error)
+
+// notifier reports a streamed event to the client
+type notifier func(ctx context.Context, progress float64, message string) error
+
+// watchOptions holds the parsed tool parameters
+type watchOptions struct {
+	namespace  string
+	kind       string
+	name       string
+	reason     string
+	includeAll bool
+	duration   time.Duration
+}
+
+// GetWatchEventsHandler returns a handler for the aks_watch_events command
+func GetWatchEventsHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		notify := func(ctx context.Context, progress float64, message string) error {
+			return tools.NotifyProgress(ctx, progress, 0, message)
+		}
+		return HandleWatchEvents(ctx, params, k8s.StartKubectl, notify, cfg)
+	})
+}
+
+// HandleWatchEvents watches events until the duration elapses or ctx is cancelled,
+// notifying the client of each new event and returning every event seen
+func HandleWatchEvents(ctx context.Context, params map[string]interface{}, stream eventStreamer, notify notifier, cfg *config.ConfigData) (string, error) {
+	opts, err := parseWatchOptions(params, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	selector := buildFieldSelector(opts)
+	args := []string{"get", "events", "--watch-only", "--output", "json"}
+	if selector != "" {
+		args = append(args, "--field-selector", selector)
+	}
+	if opts.namespace != "" {
+		args = append(args, "--namespace", opts.namespace)
+	} else {
+		args = append(args, "--all-namespaces")
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	start := time.Now()
+	stdout, wait, err := stream(watchCtx, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to start event watch: %v", err)
+	}
+
+	report := WatchReport{Namespace: opts.namespace, Selector: selector, Events: []Event{}}
+	decoder := json.NewDecoder(stdout)
+	for {
+		var raw rawEvent
+		if err := decoder.Decode(&raw); err != nil {
+			if !errors.Is(err, io.EOF) && watchCtx.Err() == nil {
+				cancel()
+				_ = wait()
+				return "", fmt.Errorf("failed to read events: %v", err)
+			}
+			break
+		}
+		event := raw.toEvent()
+		report.Total++
+		if len(report.Events) < maxReportedEvents {
+			report.Events = append(report.Events, event)
+		} else {
+			report.Truncated = true
+		}
+		// Notification failures must not end the watch; the events are still in the result
+		_ = notify(ctx, float64(report.Total), formatEvent(event))
+	}
+	waitErr := wait()
+	report.Seconds = time.Since(start).Round(time.Millisecond).Seconds()
+
+	switch {
+	case ctx.Err() != nil:
+		report.StopReason = "cancelled"
+	case errors.Is(watchCtx.Err(), context.DeadlineExceeded):
+		report.StopReason = "duration elapsed"
+	case waitErr != nil:
+		return "", fmt.Errorf("event watch failed: %v", waitErr)
+	default:
+		report.StopReason = "watch closed by the API server"
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal watch report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// rawEvent holds the fields of a core/v1 Event used by the watch
+type rawEvent struct {
+	Type           string `json:"type"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Count          int    `json:"count"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	EventTime      string `json:"eventTime"`
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+	Metadata struct {
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Source struct {
+		Component string `json:"component"`
+	} `json:"source"`
+	ReportingComponent string `json:"reportingComponent"`
+}
+
+func (r rawEvent) toEvent() Event {
+	event := Event{
+		Time:      r.LastTimestamp,
+		Type:      r.Type,
+		Reason:    r.Reason,
+		Namespace: r.Metadata.Namespace,
+		Object:    strings.ToLower(r.InvolvedObject.Kind) + "/" + r.InvolvedObject.Name,
+		Message:   strings.TrimSpace(r.Message),
+		Count:     r.Count,
+		Source:    r.Source.Component,
+	}
+	if event.Time == "" {
+		event.Time = r.EventTime
+	}
+	if event.Source == "" {
+		event.Source = r.ReportingComponent
+	}
+	return event
+}
+
+// formatEvent renders an event as a single line for a progress notification
+func formatEvent(e Event) string {
+	line := fmt.Sprintf("%s %s %s/%s: %s", e.Type, e.Reason, e.Namespace, e.Object, e.Message)
+	if e.Count > 1 {
+		line += fmt.Sprintf(" (x%d)", e.Count)
+	}
+	return line
+}
+
+// buildFieldSelector selects the event type and involved object server-side
+func buildFieldSelector(opts watchOptions) string {
+	var selectors []string
+	if !opts.includeAll {
+		selectors = append(selectors, "type=Warning")
+	}
+	if opts.kind != "" {
+		selectors = append(selectors, "involvedObject.kind="+opts.kind)
+	}
+	if opts.name != "" {
+		selectors = append(selectors, "involvedObject.name="+opts.name)
+	}
+	if opts.reason != "" {
+		selectors = append(selectors, "reason="+opts.reason)
+	}
+	return strings.Join(selectors, ",")
+}
+
+// parseWatchOptions validates and parses the tool parameters
+func parseWatchOptions(params map[string]interface{}, cfg *config.ConfigData) (watchOptions, error) {
+	opts := watchOptions{duration: defaultWatchSeconds * time.Second}
+
+	opts.namespace, _ = params["namespace"].(string)
+	opts.kind, _ = params["involved_object_kind"].(string)
+	opts.name, _ = params["involved_object_name"].(string)
+	opts.reason, _ = params["reason"].(string)
+	opts.includeAll = params["event_type"] == "all"
+
+	for _, field := range []struct{ label, value string }{
+		{"namespace", opts.namespace},
+		{"involved_object_kind", opts.kind},
+		{"involved_object_name", opts.name},
+		{"reason", opts.reason},
+	} {
+		if field.value != "" && !namePattern.MatchString(field.value) {
+			return opts, fmt.Errorf("invalid %s parameter: %s", field.label, field.value)
+		}
+	}
+	if eventType, _ := params["event_type"].(string); eventType != "" && eventType != "warning" && eventType != "all" {
+		return opts, fmt.Errorf("invalid event_type parameter '%s': must be warning or all", eventType)
+	}
+
+	// A watch across all namespaces would bypass --allow-namespaces
+	securityConfig := k8s.ConvertConfig(cfg).SecurityConfig
+	if opts.namespace == "" && cfg.AllowNamespaces != "" {
+		return opts, fmt.Errorf("namespace is required when the server is restricted with --allow-namespaces")
+	}
+	if opts.namespace != "" && !securityConfig.IsNamespaceAllowed(opts.namespace) {
+		return opts, fmt.Errorf("access to namespace '%s' is denied by security configuration", opts.namespace)
+	}
+
+	if value, ok := params["duration_seconds"].(string); ok && value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 || seconds > maxWatchSeconds {
+			return opts, fmt.Errorf("invalid duration_seconds parameter: %s (must be between 1 and %d)", value, maxWatchSeconds)
+		}
+		opts.duration = time.Duration(seconds) * time.Second
+	}
+	return opts, nil
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterWatchEventsTool registers the aks_watch_events tool
+func RegisterWatchEventsTool() mcp.Tool {
+	description := fmt.Sprintf(`Watch Kubernetes events for a bounded duration and stream each new event to the client
+as a progress notification (send a progressToken with the call to receive them).
+
+Only events created or updated after the watch starts are reported, Warning events by default.
+The watch stops after duration_seconds (default %d, at most %d) or when the call is cancelled,
+then returns every event seen. Uses the current kubeconfig context. When the server is restricted
+with --allow-namespaces, a namespace is required.`, defaultWatchSeconds, maxWatchSeconds)
+
+	return mcp.NewTool(
+		"aks_watch_events",
+		mcp.WithDescription(description),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace to watch (default: all namespaces)"),
+		),
+		mcp.WithString("involved_object_kind",
+			mcp.Description("Only report events about objects of this kind, e.g. Pod, Node or Deployment"),
+		),
+		mcp.WithString("involved_object_name",
+			mcp.Description("Only report events about the object with this name"),
+		),
+		mcp.WithString("reason",
+			mcp.Description("Only report events with this reason, e.g. BackOff, FailedScheduling or OOMKilling"),
+		),
+		mcp.WithString("event_type",
+			mcp.Description("Event types to report: warning (default) or all"),
+			mcp.Enum("warning", "all"),
+		),
+		mcp.WithString("duration_seconds",
+			mcp.Description(fmt.Sprintf("How long to watch (default: %d)", defaultWatchSeconds)),
+		),
+	)
+}
@@ -0,0 +1,213 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+const (
+	primaryID   = "/subscriptions/sub/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/aks-east"
+	secondaryID = "/subscriptions/sub/resourcegroups/rg-dr/providers/microsoft.containerservice/managedclusters/aks-west"
+	registryID  = "/subscriptions/sub/resourcegroups/acr/providers/microsoft.containerregistry/registries/images"
+	vaultID     = "/subscriptions/sub/resourcegroups/backup/providers/microsoft.dataprotection/backupvaults/vault"
+)
+
+// fakeReader returns clusters by name and Resource Graph rows for the first query fragment that matches
+type fakeReader struct {
+	clusters map[string]*armcontainerservice.ManagedCluster
+	rows     map[string][]map[string]interface{}
+	queries  []string
+}
+
+func (f *fakeReader) GetAKSCluster(_ context.Context, _, _, clusterName string) (*armcontainerservice.ManagedCluster, error) {
+	cluster, ok := f.clusters[clusterName]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", clusterName)
+	}
+	return cluster, nil
+}
+
+func (f *fakeReader) QueryResourceGraph(_ context.Context, query string, _ []string) ([]map[string]interface{}, error) {
+	f.queries = append(f.queries, query)
+	for fragment, rows := range f.rows {
+		if strings.Contains(query, fragment) {
+			return rows, nil
+		}
+	}
+	return nil, nil
+}
+
+func testCluster(id, location, nodeRG string, maxCount int32) *armcontainerservice.ManagedCluster {
+	return &armcontainerservice.ManagedCluster{
+		ID:       to.Ptr(id),
+		Location: to.Ptr(location),
+		Properties: &armcontainerservice.ManagedClusterProperties{
+			CurrentKubernetesVersion: to.Ptr("1.30.4"),
+			NodeResourceGroup:        to.Ptr(nodeRG),
+			NetworkProfile:           &armcontainerservice.NetworkProfile{NetworkPlugin: to.Ptr(armcontainerservice.NetworkPluginAzure)},
+			IdentityProfile:          map[string]*armcontainerservice.UserAssignedIdentity{"kubeletidentity": {ObjectID: to.Ptr("kubelet-object-id")}},
+			AgentPoolProfiles: []*armcontainerservice.ManagedClusterAgentPoolProfile{{
+				Name:              to.Ptr("system"),
+				Mode:              to.Ptr(armcontainerservice.AgentPoolModeSystem),
+				VMSize:            to.Ptr("Standard_D4s_v5"),
+				EnableAutoScaling: to.Ptr(true),
+				MaxCount:          to.Ptr(maxCount),
+			}},
+		},
+	}
+}
+
+func readyReader() *fakeReader {
+	return &fakeReader{
+		clusters: map[string]*armcontainerservice.ManagedCluster{
+			"aks-east": testCluster(primaryID, "East US", "MC_rg_aks-east_eastus", 10),
+			"aks-west": testCluster(secondaryID, "westus", "MC_rg-dr_aks-west_westus", 10),
+		},
+		rows: map[string][]map[string]interface{}{
+			"authorizationresources":                      {{"scope": registryID}},
+			"registries/replications":                     {{"registryId": registryID, "location": "westus"}},
+			"microsoft.containerregistry/registries' and": {{"id": registryID, "name": "images", "location": "eastus", "sku": "Premium"}},
+			"backupinstances":                             {{"name": "aks-east-backup", "datasource": primaryID, "vaultId": vaultID, "protection": "ProtectionConfigured"}},
+			"backupvaults' and":                           {{"id": vaultID, "name": "vault", "redundancy": "GeoRedundant", "crossRegionRestore": "Enabled"}},
+			"publicipaddresses": {
+				{"id": "/ip-east", "resourceGroup": "mc_rg_aks-east_eastus", "ip": "20.0.0.1"},
+				{"id": "/ip-west", "resourceGroup": "mc_rg-dr_aks-west_westus", "ip": "20.0.0.2"},
+			},
+			"trafficmanagerprofiles": {{"name": "app", "type": "microsoft.network/trafficmanagerprofiles", "properties": map[string]interface{}{
+				"endpoints": []interface{}{
+					map[string]interface{}{"properties": map[string]interface{}{"targetResourceId": "/IP-EAST"}},
+					map[string]interface{}{"properties": map[string]interface{}{"target": "20.0.0.2"}},
+				},
+			}}},
+		},
+	}
+}
+
+func checkReadiness(t *testing.T, params map[string]interface{}, reader Reader) ReadinessReport {
+	t.Helper()
+	output, err := HandleCheckFailoverReadiness(params, reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report ReadinessReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+func areaStatus(report ReadinessReport, area string) string {
+	for _, item := range report.Scorecard {
+		if item.Area == area {
+			return item.Status
+		}
+	}
+	return ""
+}
+
+func TestHandleCheckFailoverReadiness(t *testing.T) {
+	params := map[string]interface{}{
+		"subscription_id":          "sub",
+		"resource_group":           "rg",
+		"cluster_name":             "aks-east",
+		"secondary_resource_group": "rg-dr",
+		"secondary_cluster_name":   "aks-west",
+	}
+
+	t.Run("paired regions with full coverage are ready", func(t *testing.T) {
+		report := checkReadiness(t, params, readyReader())
+		if report.Readiness != "ready" || report.Score != report.MaxScore || report.PrimaryRegion != "eastus" {
+			t.Errorf("Expected a ready report with full score, got %+v", report)
+		}
+	})
+
+	t.Run("gaps lower the score", func(t *testing.T) {
+		reader := readyReader()
+		reader.clusters["aks-west"] = testCluster(secondaryID, "westus2", "MC_rg-dr_aks-west_westus", 3)
+		reader.rows["registries/replications"] = nil
+		reader.rows["backupvaults' and"] = []map[string]interface{}{{"id": vaultID, "name": "vault", "redundancy": "LocallyRedundant"}}
+		reader.rows["trafficmanagerprofiles"] = nil
+		report := checkReadiness(t, params, reader)
+
+		want := map[string]string{
+			AreaSecondaryCluster: StatusWarn,
+			AreaConfigParity:     StatusWarn,
+			AreaRegistry:         StatusFail,
+			AreaBackup:           StatusWarn,
+			AreaTrafficRouting:   StatusFail,
+		}
+		for area, status := range want {
+			if got := areaStatus(report, area); got != status {
+				t.Errorf("Expected %s to be %s, got %s", area, status, got)
+			}
+		}
+		if report.Readiness != "not_ready" || report.Score != 30 {
+			t.Errorf("Expected not_ready with 30 points, got %s with %d", report.Readiness, report.Score)
+		}
+	})
+
+	t.Run("without a secondary candidates are listed", func(t *testing.T) {
+		reader := readyReader()
+		reader.rows["managedclusters' and location !~ 'eastus'"] = []map[string]interface{}{{"name": "aks-west", "resourceGroup": "rg-dr", "location": "westus"}}
+		report := checkReadiness(t, map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks-east"}, reader)
+
+		if areaStatus(report, AreaSecondaryCluster) != StatusFail || len(report.Candidates) != 1 || !strings.Contains(report.Candidates[0], "aks-west") {
+			t.Errorf("Expected a failed secondary area with one candidate, got %+v", report)
+		}
+		if areaStatus(report, AreaTrafficRouting) != StatusFail {
+			t.Errorf("Expected routing to the primary only to fail, got %+v", report.Scorecard)
+		}
+	})
+
+	t.Run("secondary name requires its resource group", func(t *testing.T) {
+		_, err := HandleCheckFailoverReadiness(map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks-east", "secondary_cluster_name": "aks-west"}, readyReader())
+		if err == nil {
+			t.Error("Expected an error without secondary_resource_group")
+		}
+	})
+}
+
+func TestCheckSecondaryRegion(t *testing.T) {
+	tests := []struct {
+		primary, secondary, status string
+	}{
+		{"eastus", "westus", StatusPass},
+		{"eastus", "westus2", StatusWarn},
+		{"eastus", "eastus", StatusFail},
+		{"eastus", "", StatusFail},
+		{"qatarcentral", "uaenorth", StatusPass},
+	}
+	for _, tt := range tests {
+		if got := CheckSecondaryRegion(tt.primary, tt.secondary); got.Status != tt.status {
+			t.Errorf("CheckSecondaryRegion(%q, %q) = %s, want %s", tt.primary, tt.secondary, got.Status, tt.status)
+		}
+	}
+}
+
+func TestCheckConfigParity(t *testing.T) {
+	primary := testCluster(primaryID, "eastus", "mc", 10)
+	secondary := testCluster(secondaryID, "westus", "mc", 10)
+	secondary.Properties.AgentPoolProfiles[0].Name = to.Ptr("sys")
+
+	result := CheckConfigParity(primary, secondary)
+	if result.Status != StatusFail || !strings.Contains(strings.Join(result.Details, "\n"), "pool system: primary System Standard_D4s_v5") {
+		t.Errorf("Expected a missing node pool to fail parity, got %+v", result)
+	}
+}
+
+func TestScore(t *testing.T) {
+	items := []ScorecardItem{item("a", StatusPass, ""), item("b", StatusPass, ""), item("c", StatusPass, ""), item("d", StatusPass, ""), item("e", StatusWarn, "")}
+	if score, readiness := Score(items); score != 90 || readiness != "ready" {
+		t.Errorf("Expected 90 and ready, got %d and %s", score, readiness)
+	}
+	items[4] = item("e", StatusFail, "")
+	if score, readiness := Score(items); score != 80 || readiness != "partial" {
+		t.Errorf("Expected a failed area to cap readiness at partial, got %d and %s", score, readiness)
+	}
+}
@@ -0,0 +1,728 @@
+// Package failover scores how ready an AKS cluster and its secondary region cluster are for a
+// regional failover: region pairing, configuration parity, registry geo-replication, backups
+// and global traffic routing.
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+// acrPullRoleID is the role definition ID of the built-in AcrPull role
+const acrPullRoleID = "7f951dda-4ed3-4680-a7ca-43fe172d538d"
+
+// maxCandidates bounds the secondary cluster candidates listed when none is given
+const maxCandidates = 10
+
+// Scorecard statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Scorecard areas, each worth checkWeight points
+const (
+	AreaSecondaryCluster = "secondary_cluster"
+	AreaConfigParity     = "config_parity"
+	AreaRegistry         = "registry_geo_replication"
+	AreaBackup           = "backup"
+	AreaTrafficRouting   = "traffic_routing"
+	checkWeight          = 20
+)
+
+// regionPairs lists the Azure region pairs used to check that the secondary is in the paired region
+var regionPairs = map[string]string{
+	"eastus": "westus", "westus": "eastus",
+	"eastus2": "centralus", "centralus": "eastus2",
+	"westus2": "westcentralus", "westcentralus": "westus2",
+	"westus3":        "eastus",
+	"northcentralus": "southcentralus", "southcentralus": "northcentralus",
+	"canadacentral": "canadaeast", "canadaeast": "canadacentral",
+	"brazilsouth": "southcentralus",
+	"northeurope": "westeurope", "westeurope": "northeurope",
+	"uksouth": "ukwest", "ukwest": "uksouth",
+	"francecentral": "francesouth", "francesouth": "francecentral",
+	"germanywestcentral": "germanynorth", "germanynorth": "germanywestcentral",
+	"switzerlandnorth": "switzerlandwest", "switzerlandwest": "switzerlandnorth",
+	"norwayeast": "norwaywest", "norwaywest": "norwayeast",
+	"swedencentral": "swedensouth", "swedensouth": "swedencentral",
+	"japaneast": "japanwest", "japanwest": "japaneast",
+	"koreacentral": "koreasouth", "koreasouth": "koreacentral",
+	"southeastasia": "eastasia", "eastasia": "southeastasia",
+	"australiaeast": "australiasoutheast", "australiasoutheast": "australiaeast",
+	"centralindia": "southindia", "southindia": "centralindia",
+	"southafricanorth": "southafricawest", "southafricawest": "southafricanorth",
+	"uaenorth": "uaecentral", "uaecentral": "uaenorth",
+}
+
+// kqlValuePattern matches the IDs and names embedded in Resource Graph queries
+var kqlValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./()\-]+$`)
+
+// Reader reads clusters and queries Azure Resource Graph. *azureclient.AzureClient implements it.
+type Reader interface {
+	GetAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*armcontainerservice.ManagedCluster, error)
+	QueryResourceGraph(ctx context.Context, query string, subscriptions []string) ([]map[string]interface{}, error)
+}
+
+// ScorecardItem is the result of one readiness area
+type ScorecardItem struct {
+	Area           string   `json:"area"`
+	Status         string   `json:"status"`
+	Score          int      `json:"score"`
+	Summary        string   `json:"summary"`
+	Details        []string `json:"details,omitempty"`
+	Recommendation string   `json:"recommendation,omitempty"`
+}
+
+// ReadinessReport is the result returned by the check_failover_readiness tool
+type ReadinessReport struct {
+	PrimaryCluster   string          `json:"primaryCluster"`
+	PrimaryRegion    string          `json:"primaryRegion"`
+	SecondaryCluster string          `json:"secondaryCluster,omitempty"`
+	SecondaryRegion  string          `json:"secondaryRegion,omitempty"`
+	Score            int             `json:"score"`
+	MaxScore         int             `json:"maxScore"`
+	Readiness        string          `json:"readiness"`
+	Scorecard        []ScorecardItem `json:"scorecard"`
+	Candidates       []string        `json:"secondaryCandidates,omitempty"`
+	Note             string          `json:"note"`
+}
+
+// GetCheckFailoverReadinessHandler returns a handler for the check_failover_readiness command
+func GetCheckFailoverReadinessHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleCheckFailoverReadiness(params, azClient)
+	})
+}
+
+// HandleCheckFailoverReadiness scores the failover readiness of a primary cluster and its secondary
+func HandleCheckFailoverReadiness(params map[string]interface{}, reader Reader) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	secondarySub, _ := params["secondary_subscription_id"].(string)
+	if secondarySub == "" {
+		secondarySub = subID
+	}
+	secondaryRG, _ := params["secondary_resource_group"].(string)
+	secondaryName, _ := params["secondary_cluster_name"].(string)
+	if (secondaryRG == "") != (secondaryName == "") {
+		return "", fmt.Errorf("secondary_resource_group and secondary_cluster_name must be given together")
+	}
+
+	ctx := context.Background()
+	primary, err := reader.GetAKSCluster(ctx, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %v", clusterName, err)
+	}
+	report := ReadinessReport{
+		PrimaryCluster: clusterName,
+		PrimaryRegion:  normalizeRegion(deref(primary.Location)),
+		MaxScore:       5 * checkWeight,
+		Note: "Checks read the deployed resources through Azure Resource Graph, so configuration parity compares the running " +
+			"clusters rather than IaC templates, and routing through DNS names that do not resolve to the clusters' public IPs " +
+			"(for example behind Application Gateway) or Velero backups are not recognized.",
+	}
+	subscriptions := uniqueStrings(subID, secondarySub)
+
+	var secondary *armcontainerservice.ManagedCluster
+	if secondaryName != "" {
+		secondary, err = reader.GetAKSCluster(ctx, secondarySub, secondaryRG, secondaryName)
+		if err != nil {
+			return "", fmt.Errorf("failed to get secondary cluster %s: %v", secondaryName, err)
+		}
+		report.SecondaryCluster = secondaryName
+		report.SecondaryRegion = normalizeRegion(deref(secondary.Location))
+	} else {
+		report.Candidates = findCandidates(ctx, reader, subID, report.PrimaryRegion)
+	}
+
+	report.Scorecard = []ScorecardItem{
+		CheckSecondaryRegion(report.PrimaryRegion, report.SecondaryRegion),
+		CheckConfigParity(primary, secondary),
+		checkRegistries(ctx, reader, primary, report.SecondaryRegion, subscriptions),
+		checkBackup(ctx, reader, primary, secondary, subscriptions),
+		checkTrafficRouting(ctx, reader, primary, secondary, subscriptions),
+	}
+	report.Score, report.Readiness = Score(report.Scorecard)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal readiness report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// Score sums the scorecard and rates it: ready needs 80% with no failed area, partial needs 50%
+func Score(items []ScorecardItem) (int, string) {
+	total, failed := 0, false
+	for _, item := range items {
+		total += item.Score
+		if item.Status == StatusFail {
+			failed = true
+		}
+	}
+	maxScore := len(items) * checkWeight
+	switch {
+	case !failed && total*100 >= maxScore*80:
+		return total, "ready"
+	case total*100 >= maxScore*50:
+		return total, "partial"
+	default:
+		return total, "not_ready"
+	}
+}
+
+func item(area, status, summary string) ScorecardItem {
+	score := 0
+	switch status {
+	case StatusPass:
+		score = checkWeight
+	case StatusWarn:
+		score = checkWeight / 2
+	}
+	return ScorecardItem{Area: area, Status: status, Score: score, Summary: summary}
+}
+
+// CheckSecondaryRegion checks that a secondary cluster exists in another region, ideally the paired one
+func CheckSecondaryRegion(primaryRegion, secondaryRegion string) ScorecardItem {
+	switch {
+	case secondaryRegion == "":
+		result := item(AreaSecondaryCluster, StatusFail, "no secondary cluster was given, so there is nothing to fail over to")
+		result.Recommendation = "pass secondary_resource_group and secondary_cluster_name; candidates in other regions are listed in secondaryCandidates"
+		return result
+	case secondaryRegion == primaryRegion:
+		result := item(AreaSecondaryCluster, StatusFail, "the secondary cluster is in the same region "+primaryRegion+", so a regional outage takes down both")
+		result.Recommendation = "deploy the secondary cluster in another region, ideally " + regionPairs[primaryRegion]
+		return result
+	}
+	pair, paired := regionPairs[primaryRegion]
+	switch {
+	case pair == secondaryRegion:
+		return item(AreaSecondaryCluster, StatusPass, fmt.Sprintf("the secondary cluster is in %s, the Azure region pair of %s", secondaryRegion, primaryRegion))
+	case paired:
+		result := item(AreaSecondaryCluster, StatusWarn, fmt.Sprintf("the secondary cluster is in %s, not %s, the region pair of %s", secondaryRegion, pair, primaryRegion))
+		result.Details = []string{"paired regions get sequential platform updates and prioritized recovery; other regions work but without those guarantees"}
+		return result
+	default:
+		return item(AreaSecondaryCluster, StatusPass, fmt.Sprintf("the secondary cluster is in %s; %s has no Azure region pair", secondaryRegion, primaryRegion))
+	}
+}
+
+// CheckConfigParity compares the settings of the two clusters that a failed-over workload depends on
+func CheckConfigParity(primary, secondary *armcontainerservice.ManagedCluster) ScorecardItem {
+	if secondary == nil {
+		return item(AreaConfigParity, StatusFail, "no secondary cluster to compare with")
+	}
+	p, s := clusterSettings(primary), clusterSettings(secondary)
+	var diffs []string
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	for key := range s {
+		if _, ok := p[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if p[key] != s[key] {
+			diffs = append(diffs, fmt.Sprintf("%s: primary %s, secondary %s", key, orNone(p[key]), orNone(s[key])))
+		}
+	}
+
+	primaryNodes, secondaryNodes := maxNodes(primary), maxNodes(secondary)
+	if secondaryNodes < primaryNodes {
+		diffs = append(diffs, fmt.Sprintf("capacity: the secondary can scale to %d nodes, the primary to %d, so it cannot absorb the full load unless that is the plan", secondaryNodes, primaryNodes))
+	}
+
+	if len(diffs) == 0 {
+		return item(AreaConfigParity, StatusPass, "the clusters match on version, networking, identity, add-ons, node pools and capacity")
+	}
+	status := StatusWarn
+	for _, diff := range diffs {
+		// Workloads may not even schedule or reach their dependencies when these differ
+		for _, critical := range []string{"networkPlugin", "oidcIssuer", "privateCluster", "pool "} {
+			if strings.HasPrefix(diff, critical) && strings.Contains(diff, "none") {
+				status = StatusFail
+			}
+		}
+	}
+	result := item(AreaConfigParity, status, fmt.Sprintf("%d differences between the clusters", len(diffs)))
+	result.Details = diffs
+	result.Recommendation = "deploy both clusters from the same IaC template with only the region and names parameterized"
+	return result
+}
+
+// clusterSettings flattens the settings compared between clusters
+func clusterSettings(cluster *armcontainerservice.ManagedCluster) map[string]string {
+	settings := make(map[string]string)
+	if cluster.SKU != nil && cluster.SKU.Tier != nil {
+		settings["skuTier"] = string(*cluster.SKU.Tier)
+	}
+	props := cluster.Properties
+	if props == nil {
+		return settings
+	}
+	settings["kubernetesVersion"] = deref(props.CurrentKubernetesVersion)
+	if np := props.NetworkProfile; np != nil {
+		settings["networkPlugin"] = enumString(np.NetworkPlugin)
+		settings["networkMode"] = enumString(np.NetworkMode)
+		settings["networkPolicy"] = enumString(np.NetworkPolicy)
+		settings["outboundType"] = enumString(np.OutboundType)
+	}
+	if ap := props.APIServerAccessProfile; ap != nil && ap.EnablePrivateCluster != nil && *ap.EnablePrivateCluster {
+		settings["privateCluster"] = "enabled"
+	}
+	if props.OidcIssuerProfile != nil && props.OidcIssuerProfile.Enabled != nil && *props.OidcIssuerProfile.Enabled {
+		settings["oidcIssuer"] = "enabled"
+	}
+	if sp := props.SecurityProfile; sp != nil && sp.Defender != nil && sp.Defender.SecurityMonitoring != nil && sp.Defender.SecurityMonitoring.Enabled != nil && *sp.Defender.SecurityMonitoring.Enabled {
+		settings["defender"] = "enabled"
+	}
+	for name, addon := range props.AddonProfiles {
+		if addon != nil && addon.Enabled != nil && *addon.Enabled {
+			settings["addon "+name] = "enabled"
+		}
+	}
+	for _, pool := range props.AgentPoolProfiles {
+		if pool == nil || pool.Name == nil {
+			continue
+		}
+		zones := make([]string, 0, len(pool.AvailabilityZones))
+		for _, zone := range pool.AvailabilityZones {
+			zones = append(zones, deref(zone))
+		}
+		sort.Strings(zones)
+		settings["pool "+*pool.Name] = fmt.Sprintf("%s %s %s zones[%s]", enumString(pool.Mode), deref(pool.VMSize), enumString(pool.OSSKU), strings.Join(zones, ","))
+	}
+	for key, value := range settings {
+		if value == "" {
+			delete(settings, key)
+		}
+	}
+	return settings
+}
+
+// maxNodes is how many nodes the cluster can scale to
+func maxNodes(cluster *armcontainerservice.ManagedCluster) int {
+	total := 0
+	if cluster.Properties == nil {
+		return total
+	}
+	for _, pool := range cluster.Properties.AgentPoolProfiles {
+		switch {
+		case pool == nil:
+		case pool.EnableAutoScaling != nil && *pool.EnableAutoScaling && pool.MaxCount != nil:
+			total += int(*pool.MaxCount)
+		case pool.Count != nil:
+			total += int(*pool.Count)
+		}
+	}
+	return total
+}
+
+// checkRegistries checks that each registry the kubelet pulls from is replicated to the secondary region
+func checkRegistries(ctx context.Context, reader Reader, primary *armcontainerservice.ManagedCluster, secondaryRegion string, subscriptions []string) ScorecardItem {
+	kubelet := ""
+	if primary.Properties != nil {
+		if identity, ok := primary.Properties.IdentityProfile["kubeletidentity"]; ok && identity != nil {
+			kubelet = deref(identity.ObjectID)
+		}
+	}
+	if !kqlValuePattern.MatchString(kubelet) {
+		return item(AreaRegistry, StatusWarn, "the cluster has no kubelet identity, so attached registries cannot be found")
+	}
+
+	rows, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`authorizationresources
+| where type =~ 'microsoft.authorization/roleassignments'
+| where tostring(properties.principalId) =~ '%s' and tostring(properties.roleDefinitionId) endswith '/%s'
+| project scope = tolower(tostring(properties.scope))`, kubelet, acrPullRoleID), nil)
+	if err != nil {
+		return item(AreaRegistry, StatusWarn, fmt.Sprintf("failed to read the kubelet identity's AcrPull role assignments: %v", err))
+	}
+	var registryIDs []string
+	for _, row := range rows {
+		scope := rowString(row, "scope")
+		if strings.Contains(scope, "/providers/microsoft.containerregistry/registries/") && kqlValuePattern.MatchString(scope) {
+			registryIDs = append(registryIDs, scope)
+		}
+	}
+	if len(registryIDs) == 0 {
+		result := item(AreaRegistry, StatusWarn, "no registry is attached to the cluster through an AcrPull role assignment")
+		result.Details = []string{"images pulled from other registries must also be reachable from the secondary region"}
+		return result
+	}
+	return EvaluateRegistries(ctx, reader, registryIDs, secondaryRegion, subscriptions)
+}
+
+// EvaluateRegistries checks the SKU and replications of the given registries against the secondary region
+func EvaluateRegistries(ctx context.Context, reader Reader, registryIDs []string, secondaryRegion string, subscriptions []string) ScorecardItem {
+	idList := kqlList(registryIDs)
+	registries, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`resources
+| where type =~ 'microsoft.containerregistry/registries' and tolower(id) in (%s)
+| project id = tolower(id), name, location = tolower(location), sku = tostring(sku.name)`, idList), subscriptions)
+	if err != nil {
+		return item(AreaRegistry, StatusWarn, fmt.Sprintf("failed to read the attached registries: %v", err))
+	}
+	replications, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`resources
+| where type =~ 'microsoft.containerregistry/registries/replications'
+| extend registryId = tolower(substring(id, 0, indexof(tolower(id), '/replications/')))
+| where registryId in (%s)
+| project registryId, location = tolower(location)`, idList), subscriptions)
+	if err != nil {
+		return item(AreaRegistry, StatusWarn, fmt.Sprintf("failed to read registry replications: %v", err))
+	}
+	regions := make(map[string][]string)
+	for _, row := range replications {
+		id := rowString(row, "registryId")
+		regions[id] = append(regions[id], normalizeRegion(rowString(row, "location")))
+	}
+
+	var details []string
+	replicated := 0
+	for _, row := range registries {
+		name, id := rowString(row, "name"), rowString(row, "id")
+		home := normalizeRegion(rowString(row, "location"))
+		switch {
+		case secondaryRegion != "" && (home == secondaryRegion || contains(regions[id], secondaryRegion)):
+			replicated++
+			details = append(details, fmt.Sprintf("%s is available in %s", name, secondaryRegion))
+		case rowString(row, "sku") != "Premium":
+			details = append(details, fmt.Sprintf("%s is %s in %s; geo-replication needs the Premium SKU", name, rowString(row, "sku"), home))
+		case secondaryRegion == "":
+			details = append(details, fmt.Sprintf("%s (Premium, %s) is replicated to %s", name, home, orNone(strings.Join(regions[id], ", "))))
+		default:
+			details = append(details, fmt.Sprintf("%s (Premium, %s) has no replica in %s: az acr replication create --registry %s --location %s", name, home, secondaryRegion, name, secondaryRegion))
+		}
+	}
+	if len(registries) < len(registryIDs) {
+		details = append(details, fmt.Sprintf("%d attached registries could not be read in the given subscriptions", len(registryIDs)-len(registries)))
+	}
+
+	switch {
+	case secondaryRegion != "" && replicated == len(registryIDs):
+		result := item(AreaRegistry, StatusPass, fmt.Sprintf("all %d attached registries serve images in %s", replicated, secondaryRegion))
+		result.Details = details
+		return result
+	case replicated > 0:
+		result := item(AreaRegistry, StatusWarn, fmt.Sprintf("%d of %d attached registries serve images in %s", replicated, len(registryIDs), secondaryRegion))
+		result.Details = details
+		return result
+	}
+	result := item(AreaRegistry, StatusFail, "no attached registry is replicated to the secondary region, so image pulls fail there during a regional outage")
+	result.Details = details
+	result.Recommendation = "upgrade the registries to Premium and add a replication in the secondary region"
+	return result
+}
+
+// checkBackup checks for Azure Backup for AKS instances protecting the clusters in geo-redundant vaults
+func checkBackup(ctx context.Context, reader Reader, primary, secondary *armcontainerservice.ManagedCluster, subscriptions []string) ScorecardItem {
+	clusterIDs := []string{strings.ToLower(deref(primary.ID))}
+	if secondary != nil {
+		clusterIDs = append(clusterIDs, strings.ToLower(deref(secondary.ID)))
+	}
+	for _, id := range clusterIDs {
+		if !kqlValuePattern.MatchString(id) {
+			return item(AreaBackup, StatusWarn, "the cluster resource ID is unknown, so backups cannot be found")
+		}
+	}
+
+	instances, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`recoveryservicesresources
+| where type =~ 'microsoft.dataprotection/backupvaults/backupinstances'
+| extend datasource = tolower(tostring(properties.dataSourceInfo.resourceID))
+| where datasource in (%s)
+| project name, datasource, vaultId = tolower(substring(id, 0, indexof(tolower(id), '/backupinstances/'))), protection = tostring(properties.currentProtectionState)`, kqlList(clusterIDs)), subscriptions)
+	if err != nil {
+		return item(AreaBackup, StatusWarn, fmt.Sprintf("failed to read backup instances: %v", err))
+	}
+	var vaultIDs []string
+	for _, row := range instances {
+		if id := rowString(row, "vaultId"); kqlValuePattern.MatchString(id) && !contains(vaultIDs, id) {
+			vaultIDs = append(vaultIDs, id)
+		}
+	}
+	vaults := make(map[string]map[string]interface{})
+	if len(vaultIDs) > 0 {
+		rows, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`resources
+| where type =~ 'microsoft.dataprotection/backupvaults' and tolower(id) in (%s)
+| project id = tolower(id), name, redundancy = tostring(properties.storageSettings[0].type), crossRegionRestore = tostring(properties.featureSettings.crossRegionRestoreSettings.state)`, kqlList(vaultIDs)), subscriptions)
+		if err != nil {
+			return item(AreaBackup, StatusWarn, fmt.Sprintf("failed to read backup vaults: %v", err))
+		}
+		for _, row := range rows {
+			vaults[rowString(row, "id")] = row
+		}
+	}
+	return EvaluateBackup(clusterIDs[0], instances, vaults)
+}
+
+// EvaluateBackup rates the backup instances of the primary cluster and the redundancy of their vaults
+func EvaluateBackup(primaryID string, instances []map[string]interface{}, vaults map[string]map[string]interface{}) ScorecardItem {
+	var details []string
+	protected, restorable := false, false
+	for _, row := range instances {
+		if rowString(row, "datasource") != primaryID {
+			continue
+		}
+		vault := vaults[rowString(row, "vaultId")]
+		state := rowString(row, "protection")
+		details = append(details, fmt.Sprintf("backup instance %s in vault %s is %s (storage %s, cross region restore %s)",
+			rowString(row, "name"), orNone(rowString(vault, "name")), orNone(state), orNone(rowString(vault, "redundancy")), orNone(rowString(vault, "crossRegionRestore"))))
+		if state == "ProtectionConfigured" {
+			protected = true
+			if rowString(vault, "redundancy") == "GeoRedundant" && rowString(vault, "crossRegionRestore") == "Enabled" {
+				restorable = true
+			}
+		}
+	}
+
+	switch {
+	case restorable:
+		result := item(AreaBackup, StatusPass, "the primary cluster is backed up to a geo-redundant vault with cross region restore")
+		result.Details = details
+		return result
+	case protected:
+		result := item(AreaBackup, StatusWarn, "the primary cluster is backed up, but the vault cannot restore into the secondary region")
+		result.Details = details
+		result.Recommendation = "use a backup vault with geo-redundant storage and cross region restore enabled"
+		return result
+	}
+	result := item(AreaBackup, StatusFail, "no Azure Backup for AKS instance protects the primary cluster")
+	result.Details = details
+	result.Recommendation = "configure Azure Backup for AKS with a geo-redundant vault, or confirm that another backup tool such as Velero stores backups outside the region"
+	return result
+}
+
+// checkTrafficRouting looks for Traffic Manager and Front Door profiles that route to both clusters
+func checkTrafficRouting(ctx context.Context, reader Reader, primary, secondary *armcontainerservice.ManagedCluster, subscriptions []string) ScorecardItem {
+	nodeGroups := map[string]string{}
+	for role, cluster := range map[string]*armcontainerservice.ManagedCluster{"primary": primary, "secondary": secondary} {
+		if cluster != nil && cluster.Properties != nil {
+			if rg := strings.ToLower(deref(cluster.Properties.NodeResourceGroup)); kqlValuePattern.MatchString(rg) {
+				nodeGroups[rg] = role
+			}
+		}
+	}
+	if len(nodeGroups) == 0 {
+		return item(AreaTrafficRouting, StatusWarn, "the node resource groups are unknown, so the clusters' public IPs cannot be found")
+	}
+	groups := make([]string, 0, len(nodeGroups))
+	for rg := range nodeGroups {
+		groups = append(groups, rg)
+	}
+	sort.Strings(groups)
+
+	ips, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`resources
+| where type =~ 'microsoft.network/publicipaddresses' and tolower(resourceGroup) in (%s)
+| project id = tolower(id), resourceGroup = tolower(resourceGroup), ip = tostring(properties.ipAddress), fqdn = tolower(tostring(properties.dnsSettings.fqdn))`, kqlList(groups)), subscriptions)
+	if err != nil {
+		return item(AreaTrafficRouting, StatusWarn, fmt.Sprintf("failed to read the clusters' public IPs: %v", err))
+	}
+	targets := make(map[string]string)
+	for _, row := range ips {
+		role := nodeGroups[rowString(row, "resourceGroup")]
+		for _, key := range []string{"id", "ip", "fqdn"} {
+			if value := rowString(row, key); value != "" {
+				targets[strings.ToLower(value)] = role
+			}
+		}
+	}
+
+	profiles, err := reader.QueryResourceGraph(ctx, `resources
+| where type in~ ('microsoft.network/trafficmanagerprofiles', 'microsoft.network/frontdoors', 'microsoft.cdn/profiles/origingroups/origins')
+| project name, type = tolower(type), properties`, subscriptions)
+	if err != nil {
+		return item(AreaTrafficRouting, StatusWarn, fmt.Sprintf("failed to read Traffic Manager and Front Door profiles: %v", err))
+	}
+	return EvaluateRouting(profiles, targets, secondary != nil)
+}
+
+// EvaluateRouting matches the endpoints of routing profiles against the clusters' public IP IDs, addresses and DNS names
+func EvaluateRouting(profiles []map[string]interface{}, targets map[string]string, hasSecondary bool) ScorecardItem {
+	var details []string
+	both := false
+	// Front Door origins are separate resources, so the clusters they reach are collected across origins
+	originRoles := make(map[string]bool)
+	for _, row := range profiles {
+		name, kind := rowString(row, "name"), rowString(row, "type")
+		roles := make(map[string]bool)
+		for _, endpoint := range routingEndpoints(kind, row["properties"]) {
+			if role, ok := targets[strings.ToLower(endpoint)]; ok {
+				roles[role] = true
+			}
+		}
+		if len(roles) == 0 {
+			continue
+		}
+		if kind == "microsoft.cdn/profiles/origingroups/origins" {
+			for role := range roles {
+				originRoles[role] = true
+			}
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s %s routes to the %s cluster", kindName(kind), name, joinRoles(roles)))
+		both = both || (roles["primary"] && roles["secondary"])
+	}
+	if len(originRoles) > 0 {
+		details = append(details, fmt.Sprintf("Azure Front Door origins route to the %s cluster", joinRoles(originRoles)))
+		both = both || (originRoles["primary"] && originRoles["secondary"])
+	}
+
+	switch {
+	case both:
+		result := item(AreaTrafficRouting, StatusPass, "global routing sends traffic to both clusters")
+		result.Details = details
+		return result
+	case len(details) > 0:
+		result := item(AreaTrafficRouting, StatusFail, "global routing only reaches one cluster, so traffic has nowhere to fail over to")
+		result.Details = details
+		result.Recommendation = "add the secondary cluster's ingress as a (priority or weighted) endpoint of the same profile"
+		if !hasSecondary {
+			result.Recommendation = "add a secondary cluster and its ingress as an endpoint of the same profile"
+		}
+		return result
+	}
+	result := item(AreaTrafficRouting, StatusFail, "no Traffic Manager or Front Door endpoint points at the clusters' public IPs")
+	result.Recommendation = "put Azure Front Door or Traffic Manager in front of both clusters' ingress, or confirm that DNS failover is handled elsewhere"
+	return result
+}
+
+// routingEndpoints returns the targets of a Traffic Manager profile, classic Front Door or Front Door origin
+func routingEndpoints(kind string, properties interface{}) []string {
+	props, _ := properties.(map[string]interface{})
+	var endpoints []string
+	switch kind {
+	case "microsoft.network/trafficmanagerprofiles":
+		list, _ := props["endpoints"].([]interface{})
+		for _, raw := range list {
+			endpoint, _ := raw.(map[string]interface{})
+			ep, _ := endpoint["properties"].(map[string]interface{})
+			endpoints = append(endpoints, rowString(ep, "targetResourceId"), rowString(ep, "target"))
+		}
+	case "microsoft.network/frontdoors":
+		pools, _ := props["backendPools"].([]interface{})
+		for _, raw := range pools {
+			pool, _ := raw.(map[string]interface{})
+			poolProps, _ := pool["properties"].(map[string]interface{})
+			backends, _ := poolProps["backends"].([]interface{})
+			for _, rawBackend := range backends {
+				backend, _ := rawBackend.(map[string]interface{})
+				endpoints = append(endpoints, rowString(backend, "address"))
+			}
+		}
+	case "microsoft.cdn/profiles/origingroups/origins":
+		endpoints = append(endpoints, rowString(props, "hostName"))
+		if origin, ok := props["azureOrigin"].(map[string]interface{}); ok {
+			endpoints = append(endpoints, rowString(origin, "id"))
+		}
+	}
+	return endpoints
+}
+
+// findCandidates lists AKS clusters in other regions of the subscription that could be the secondary
+func findCandidates(ctx context.Context, reader Reader, subID, primaryRegion string) []string {
+	if !kqlValuePattern.MatchString(primaryRegion) {
+		return nil
+	}
+	rows, err := reader.QueryResourceGraph(ctx, fmt.Sprintf(`resources
+| where type =~ 'microsoft.containerservice/managedclusters' and location !~ '%s'
+| project name, resourceGroup, location
+| order by name asc
+| take %d`, primaryRegion, maxCandidates), []string{subID})
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, row := range rows {
+		candidates = append(candidates, fmt.Sprintf("%s (resource group %s, %s)", rowString(row, "name"), rowString(row, "resourceGroup"), rowString(row, "location")))
+	}
+	return candidates
+}
+
+func kindName(kind string) string {
+	if kind == "microsoft.network/trafficmanagerprofiles" {
+		return "Traffic Manager profile"
+	}
+	return "Front Door"
+}
+
+func joinRoles(roles map[string]bool) string {
+	if roles["primary"] && roles["secondary"] {
+		return "primary and secondary"
+	}
+	if roles["primary"] {
+		return "primary"
+	}
+	return "secondary"
+}
+
+func kqlList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + value + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func rowString(row map[string]interface{}, key string) string {
+	value, _ := row[key].(string)
+	return value
+}
+
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(region, " ", ""))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func enumString[T ~string](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values ...string) []string {
+	var unique []string
+	for _, value := range values {
+		if value != "" && !contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,48 @@
+package failover
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterCheckFailoverReadinessTool registers the check_failover_readiness tool
+func RegisterCheckFailoverReadinessTool() mcp.Tool {
+	description := `Score how ready an AKS cluster is to fail over to a cluster in another region.
+
+Each area scores 20 points (pass 20, warn 10, fail 0):
+- secondary_cluster: the secondary cluster exists in another region, ideally the Azure region pair
+- config_parity: both clusters match on version, networking, identity, add-ons, node pools and capacity
+- registry_geo_replication: registries attached through AcrPull are replicated to the secondary region
+- backup: Azure Backup for AKS protects the cluster in a geo-redundant vault with cross region restore
+- traffic_routing: a Traffic Manager or Front Door profile routes to both clusters' public IPs
+
+Readiness is ready (80 or more and no failed area), partial (50 or more) or not_ready.
+Without a secondary cluster, candidate clusters in other regions are listed.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="aks-eastus", secondary_resource_group="<rg-dr>", secondary_cluster_name="aks-westus"`
+
+	return mcp.NewTool(
+		"check_failover_readiness",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID of the primary cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the primary AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the primary AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("secondary_subscription_id",
+			mcp.Description("Azure Subscription ID of the secondary cluster (defaults to subscription_id)"),
+		),
+		mcp.WithString("secondary_resource_group",
+			mcp.Description("Azure Resource Group containing the secondary AKS cluster"),
+		),
+		mcp.WithString("secondary_cluster_name",
+			mcp.Description("Name of the secondary AKS cluster in the failover region"),
+		),
+	)
+}
@@ -1,6 +1,7 @@
 package fleet
 
 import (
+	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/utils"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -35,11 +36,13 @@ Examples:
 		mcp.WithDescription(description),
 		mcp.WithString("operation",
 			mcp.Required(),
-			mcp.Description("The operation to perform. Valid values: list, show, create, update, delete, start, stop, get-credentials"),
+			mcp.Description("The operation to perform; the valid operations depend on the resource"),
+			mcp.Enum(azcli.FleetOperationNames()...),
 		),
 		mcp.WithString("resource",
 			mcp.Required(),
-			mcp.Description("The resource type to operate on. Valid values: fleet, member, updaterun, updatestrategy, clusterresourceplacement"),
+			mcp.Description("The resource type to operate on"),
+			mcp.Enum(azcli.FleetResources()...),
 		),
 		mcp.WithString("args",
 			mcp.Required(),
@@ -123,3 +126,63 @@ func GetAdminFleetCommands() []FleetCommand {
 		// Admin users get all readwrite commands by default
 	}
 }
+
+// RegisterFleetUpgradeTool registers the az_fleet_upgrade tool
+func RegisterFleetUpgradeTool() mcp.Tool {
+	description := `Plan and run a staged Kubernetes upgrade across the member clusters of an Azure Kubernetes Fleet Manager fleet.
+
+Each update group becomes a stage, in stage_order and then alphabetical order. Stages run one after another with
+after_stage_wait_seconds of soak time in between; the clusters of a stage upgrade in parallel. Members without
+an update group are listed as unassigned and are not upgraded.
+
+Operations:
+- plan: Group member clusters into stages and run preflight checks per cluster (provisioning and power state,
+  node pool state, and a version step of at most one minor version)
+- execute: Create and start a fleet update run for the plan when every staged cluster passes preflight (requires readwrite)
+- status: Report the progression of an update run per stage and member cluster
+- pause: Stop an update run; clusters already upgrading finish (requires readwrite)
+- resume: Start a stopped update run again (requires readwrite)
+
+Examples:
+- Preview: operation="plan", subscription_id="<sub>", resource_group="<rg>", fleet_name="<fleet>", kubernetes_version="1.30", stage_order="canary,staging,production"
+- Run: operation="execute", ..., kubernetes_version="1.30", stage_order="canary,staging,production"
+- Progress: operation="status", ..., update_run_name="<run>"`
+
+	return mcp.NewTool(
+		"az_fleet_upgrade",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform"),
+			mcp.Enum(UpgradeOpPlan, UpgradeOpExecute, UpgradeOpStatus, UpgradeOpPause, UpgradeOpResume),
+			mcp.Required(),
+		),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID of the fleet"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the fleet"),
+			mcp.Required(),
+		),
+		mcp.WithString("fleet_name",
+			mcp.Description("Name of the fleet"),
+			mcp.Required(),
+		),
+		mcp.WithString("kubernetes_version",
+			mcp.Description("Target Kubernetes version, such as 1.30 or 1.30.2 (required for plan and execute unless upgrade_type is NodeImageOnly)"),
+		),
+		mcp.WithString("upgrade_type",
+			mcp.Description("What to upgrade (default: Full)"),
+			mcp.Enum(UpgradeTypeFull, UpgradeTypeControlPlaneOnly, UpgradeTypeNodeImageOnly),
+		),
+		mcp.WithString("stage_order",
+			mcp.Description("Comma-separated update groups in the order they are upgraded; other groups follow alphabetically"),
+		),
+		mcp.WithNumber("after_stage_wait_seconds",
+			mcp.Description("Soak time between stages in seconds (default: 3600)"),
+		),
+		mcp.WithString("update_run_name",
+			mcp.Description("Name of the update run (required for status, pause and resume; generated for execute when empty)"),
+		),
+	)
+}
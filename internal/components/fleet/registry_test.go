@@ -1,8 +1,11 @@
 package fleet
 
 import (
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
 )
 
 func TestGetReadOnlyFleetCommands_ContainsBasicCommands(t *testing.T) {
@@ -101,11 +104,16 @@ func TestRegisterFleet_StructuredParameters(t *testing.T) {
 		t.Error("Expected description to contain examples")
 	}
 
-	// Test that the tool has the required parameters
-	// Note: We can't directly test the parameters without access to the tool's internal structure
-	// but we can verify that the tool was created successfully
-	if tool.Name == "" {
-		t.Error("Expected tool to have a name")
+	// Test that the operation and resource parameters enumerate the values the executor accepts
+	resources, _ := tool.InputSchema.Properties["resource"].(map[string]any)["enum"].([]string)
+	if !slices.Equal(resources, azcli.FleetResources()) || !slices.Contains(resources, "clusterresourceplacement") {
+		t.Errorf("Unexpected resource enum %v", resources)
+	}
+	operations, _ := tool.InputSchema.Properties["operation"].(map[string]any)["enum"].([]string)
+	for _, op := range []string{"get", "get-credentials", "start", "stop"} {
+		if !slices.Contains(operations, op) {
+			t.Errorf("Expected operation enum to contain %s, got %v", op, operations)
+		}
 	}
 }
 
@@ -0,0 +1,542 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const (
+	// fleetAPIVersion is the Microsoft.ContainerService fleets API version used for members and update runs
+	fleetAPIVersion = "2024-04-01"
+	// clusterAPIVersion is the Microsoft.ContainerService API version used to read member clusters
+	clusterAPIVersion = "2024-05-01"
+	// defaultAfterStageWait is the soak time between stages when none is given
+	defaultAfterStageWait = 3600
+	// maxMemberPages bounds nextLink paging when listing fleet members
+	maxMemberPages = 10
+)
+
+// Fleet upgrade operations
+const (
+	UpgradeOpPlan    = "plan"
+	UpgradeOpExecute = "execute"
+	UpgradeOpStatus  = "status"
+	UpgradeOpPause   = "pause"
+	UpgradeOpResume  = "resume"
+)
+
+// Upgrade types of an update run
+const (
+	UpgradeTypeFull             = "Full"
+	UpgradeTypeControlPlaneOnly = "ControlPlaneOnly"
+	UpgradeTypeNodeImageOnly    = "NodeImageOnly"
+)
+
+// Preflight check statuses
+const (
+	PreflightPass = "pass"
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+)
+
+// PreflightCheck is one readiness check of a member cluster
+type PreflightCheck struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// PlanCluster is a member cluster in an upgrade plan
+type PlanCluster struct {
+	Member            string           `json:"member"`
+	ClusterResourceID string           `json:"clusterResourceId"`
+	Group             string           `json:"group,omitempty"`
+	CurrentVersion    string           `json:"currentVersion,omitempty"`
+	Ready             bool             `json:"ready"`
+	Preflight         []PreflightCheck `json:"preflight,omitempty"`
+}
+
+// PlanStage is a stage of an upgrade plan. Stages run in order; the groups of a stage run in parallel.
+type PlanStage struct {
+	Name                  string        `json:"name"`
+	Groups                []string      `json:"groups"`
+	AfterStageWaitSeconds int           `json:"afterStageWaitSeconds"`
+	Clusters              []PlanCluster `json:"clusters"`
+}
+
+// UpgradePlan is a staged upgrade of the member clusters of a fleet
+type UpgradePlan struct {
+	Fleet             string      `json:"fleet"`
+	UpgradeType       string      `json:"upgradeType"`
+	KubernetesVersion string      `json:"kubernetesVersion,omitempty"`
+	Stages            []PlanStage `json:"stages"`
+	// Unassigned members have no update group, so a staged update run does not update them
+	Unassigned []PlanCluster `json:"unassigned,omitempty"`
+	Ready      bool          `json:"ready"`
+	Blockers   []string      `json:"blockers,omitempty"`
+}
+
+// UpgradeRunResult is the result of the execute, status, pause and resume operations
+type UpgradeRunResult struct {
+	UpdateRun string       `json:"updateRun"`
+	Action    string       `json:"action,omitempty"`
+	State     string       `json:"state,omitempty"`
+	Progress  string       `json:"progress,omitempty"`
+	Stages    []StageState `json:"stages,omitempty"`
+	Plan      *UpgradePlan `json:"plan,omitempty"`
+	Message   string       `json:"message,omitempty"`
+}
+
+// StageState is the progression of an update run stage
+type StageState struct {
+	Name    string        `json:"name"`
+	State   string        `json:"state"`
+	Members []MemberState `json:"members"`
+}
+
+// MemberState is the progression of a member cluster in an update run
+type MemberState struct {
+	Name    string `json:"name"`
+	Group   string `json:"group"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+}
+
+// fleetMember is the part of a fleet member resource the tool reads
+type fleetMember struct {
+	Name       string `json:"name"`
+	Properties struct {
+		ClusterResourceID string `json:"clusterResourceId"`
+		Group             string `json:"group"`
+	} `json:"properties"`
+}
+
+// memberCluster is the part of a managed cluster resource the preflight checks read
+type memberCluster struct {
+	Properties struct {
+		ProvisioningState        string `json:"provisioningState"`
+		CurrentKubernetesVersion string `json:"currentKubernetesVersion"`
+		KubernetesVersion        string `json:"kubernetesVersion"`
+		PowerState               struct {
+			Code string `json:"code"`
+		} `json:"powerState"`
+		AgentPoolProfiles []struct {
+			Name              string `json:"name"`
+			ProvisioningState string `json:"provisioningState"`
+		} `json:"agentPoolProfiles"`
+	} `json:"properties"`
+}
+
+// updateRunStatus is the part of an update run resource the status operation reads
+type updateRunStatus struct {
+	Properties struct {
+		Status struct {
+			Status runState `json:"status"`
+			Stages []struct {
+				Name   string   `json:"name"`
+				Status runState `json:"status"`
+				Groups []struct {
+					Name    string `json:"name"`
+					Members []struct {
+						Name   string   `json:"name"`
+						Status runState `json:"status"`
+					} `json:"members"`
+				} `json:"groups"`
+			} `json:"stages"`
+		} `json:"status"`
+	} `json:"properties"`
+}
+
+type runState struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// GetFleetUpgradeHandler returns a handler for the az_fleet_upgrade tool
+func GetFleetUpgradeHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleFleetUpgrade(params, client, cfg)
+	})
+}
+
+// HandleFleetUpgrade dispatches a staged fleet upgrade operation
+func HandleFleetUpgrade(params map[string]interface{}, client common.ARMClient, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	switch operation {
+	case UpgradeOpPlan, UpgradeOpStatus:
+	case UpgradeOpExecute, UpgradeOpPause, UpgradeOpResume:
+		if cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+			return "", fmt.Errorf("operation '%s' requires readwrite or admin access level", operation)
+		}
+	default:
+		return "", fmt.Errorf("missing or invalid operation parameter")
+	}
+
+	values := map[string]string{}
+	for _, name := range []string{"subscription_id", "resource_group", "fleet_name", "kubernetes_version", "upgrade_type", "stage_order", "update_run_name"} {
+		values[name], _ = params[name].(string)
+		if strings.ContainsAny(values[name], "/?#&%") {
+			return "", fmt.Errorf("invalid %s '%s'", name, values[name])
+		}
+	}
+	for _, name := range []string{"subscription_id", "resource_group", "fleet_name"} {
+		if values[name] == "" {
+			return "", fmt.Errorf("missing or invalid %s parameter", name)
+		}
+	}
+	fleetPath := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/fleets/%s",
+		values["subscription_id"], values["resource_group"], values["fleet_name"])
+	runName := values["update_run_name"]
+	ctx := context.Background()
+
+	var result interface{}
+	switch operation {
+	case UpgradeOpPlan, UpgradeOpExecute:
+		upgradeType := values["upgrade_type"]
+		if upgradeType == "" {
+			upgradeType = UpgradeTypeFull
+		}
+		if upgradeType != UpgradeTypeFull && upgradeType != UpgradeTypeControlPlaneOnly && upgradeType != UpgradeTypeNodeImageOnly {
+			return "", fmt.Errorf("invalid upgrade_type '%s': must be %s, %s or %s", upgradeType, UpgradeTypeFull, UpgradeTypeControlPlaneOnly, UpgradeTypeNodeImageOnly)
+		}
+		version := values["kubernetes_version"]
+		if upgradeType != UpgradeTypeNodeImageOnly && version == "" {
+			return "", fmt.Errorf("kubernetes_version is required for %s upgrades", upgradeType)
+		}
+		wait := defaultAfterStageWait
+		if value, ok := params["after_stage_wait_seconds"].(float64); ok && value >= 0 {
+			wait = int(value)
+		}
+
+		members, err := listMembers(ctx, client, fleetPath)
+		if err != nil {
+			return "", err
+		}
+		clusters := make(map[string]*memberCluster, len(members))
+		for _, member := range members {
+			cluster, err := getMemberCluster(ctx, client, member.Properties.ClusterResourceID)
+			if err != nil {
+				clusters[member.Name] = nil
+				continue
+			}
+			clusters[member.Name] = cluster
+		}
+		plan := buildUpgradePlan(values["fleet_name"], members, clusters, upgradeType, version, splitList(values["stage_order"]), wait)
+		if operation == UpgradeOpPlan {
+			result = plan
+			break
+		}
+
+		if !plan.Ready {
+			return "", fmt.Errorf("the upgrade plan is not ready: %s", strings.Join(plan.Blockers, "; "))
+		}
+		if runName == "" {
+			runName = fmt.Sprintf("upgrade-%s-%s", strings.ReplaceAll(strings.ToLower(upgradeType), "only", ""), time.Now().UTC().Format("20060102-150405"))
+		}
+		runPath := fleetPath + "/updateRuns/" + runName
+		if _, err := client.CallARMWithBody(ctx, http.MethodPut, runPath+"?api-version="+fleetAPIVersion, UpdateRunBody(plan)); err != nil {
+			return "", fmt.Errorf("failed to create update run %s: %v", runName, err)
+		}
+		if _, err := client.CallARMWithBody(ctx, http.MethodPost, runPath+"/start?api-version="+fleetAPIVersion, nil); err != nil {
+			return "", fmt.Errorf("update run %s was created but failed to start: %v", runName, err)
+		}
+		result = UpgradeRunResult{
+			UpdateRun: runName,
+			Action:    "started",
+			Plan:      &plan,
+			Message:   fmt.Sprintf("Track progression with operation=%q and update_run_name=%q; pause with operation=%q.", UpgradeOpStatus, runName, UpgradeOpPause),
+		}
+	case UpgradeOpStatus, UpgradeOpPause, UpgradeOpResume:
+		if runName == "" {
+			return "", fmt.Errorf("operation '%s' requires the update_run_name parameter", operation)
+		}
+		runPath := fleetPath + "/updateRuns/" + runName
+		action := ""
+		switch operation {
+		case UpgradeOpPause:
+			action = "stop"
+		case UpgradeOpResume:
+			action = "start"
+		}
+		if action != "" {
+			if _, err := client.CallARMWithBody(ctx, http.MethodPost, runPath+"/"+action+"?api-version="+fleetAPIVersion, nil); err != nil {
+				return "", fmt.Errorf("failed to %s update run %s: %v", operation, runName, err)
+			}
+		}
+		data, err := client.CallARM(ctx, http.MethodGet, runPath+"?api-version="+fleetAPIVersion)
+		if err != nil {
+			return "", fmt.Errorf("failed to get update run %s: %v", runName, err)
+		}
+		run, err := SummarizeUpdateRun(runName, data)
+		if err != nil {
+			return "", err
+		}
+		switch operation {
+		case UpgradeOpPause:
+			run.Action = "paused"
+			run.Message = "Clusters already being upgraded finish their upgrade; no further clusters start until the run is resumed."
+		case UpgradeOpResume:
+			run.Action = "resumed"
+		}
+		result = run
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fleet upgrade to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// buildUpgradePlan groups member clusters into one stage per update group, in stageOrder and then
+// alphabetical order, and runs the preflight checks. A nil cluster means it could not be read.
+func buildUpgradePlan(fleet string, members []fleetMember, clusters map[string]*memberCluster, upgradeType, version string, stageOrder []string, afterStageWait int) UpgradePlan {
+	plan := UpgradePlan{Fleet: fleet, UpgradeType: upgradeType, KubernetesVersion: version, Ready: true}
+
+	byGroup := map[string][]PlanCluster{}
+	for _, member := range members {
+		planCluster := PlanCluster{Member: member.Name, ClusterResourceID: member.Properties.ClusterResourceID, Group: member.Properties.Group}
+		cluster := clusters[member.Name]
+		if cluster != nil {
+			planCluster.CurrentVersion = cluster.Properties.CurrentKubernetesVersion
+			if planCluster.CurrentVersion == "" {
+				planCluster.CurrentVersion = cluster.Properties.KubernetesVersion
+			}
+		}
+		planCluster.Preflight = preflightChecks(cluster, upgradeType, version)
+		planCluster.Ready = true
+		for _, check := range planCluster.Preflight {
+			if check.Status == PreflightFail {
+				planCluster.Ready = false
+			}
+		}
+		if planCluster.Group == "" {
+			plan.Unassigned = append(plan.Unassigned, planCluster)
+			continue
+		}
+		byGroup[planCluster.Group] = append(byGroup[planCluster.Group], planCluster)
+		if !planCluster.Ready {
+			plan.Ready = false
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("member %s failed preflight checks", member.Name))
+		}
+	}
+
+	var groups []string
+	seen := map[string]bool{}
+	for _, group := range stageOrder {
+		if _, ok := byGroup[group]; !ok {
+			plan.Ready = false
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("update group %s in stage_order has no members", group))
+			continue
+		}
+		if !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	var rest []string
+	for group := range byGroup {
+		if !seen[group] {
+			rest = append(rest, group)
+		}
+	}
+	sort.Strings(rest)
+	groups = append(groups, rest...)
+
+	for i, group := range groups {
+		stage := PlanStage{Name: fmt.Sprintf("stage%d", i+1), Groups: []string{group}, Clusters: byGroup[group]}
+		// No soak time is needed after the last stage
+		if i < len(groups)-1 {
+			stage.AfterStageWaitSeconds = afterStageWait
+		}
+		sort.Slice(stage.Clusters, func(a, b int) bool { return stage.Clusters[a].Member < stage.Clusters[b].Member })
+		plan.Stages = append(plan.Stages, stage)
+	}
+	if len(plan.Stages) == 0 {
+		plan.Ready = false
+		plan.Blockers = append(plan.Blockers, "no member has an update group; assign groups with az fleet member update --update-group")
+	}
+	return plan
+}
+
+// preflightChecks checks that a member cluster can take the upgrade
+func preflightChecks(cluster *memberCluster, upgradeType, version string) []PreflightCheck {
+	if cluster == nil {
+		return []PreflightCheck{{Check: "cluster", Status: PreflightFail, Detail: "the member cluster could not be read"}}
+	}
+	props := cluster.Properties
+	checks := []PreflightCheck{{Check: "provisioning", Status: PreflightPass, Detail: "the last cluster operation succeeded"}}
+	if !strings.EqualFold(props.ProvisioningState, "Succeeded") {
+		checks[0] = PreflightCheck{Check: "provisioning", Status: PreflightFail,
+			Detail: fmt.Sprintf("the cluster provisioning state is %s; wait for the running operation or reconcile the cluster", props.ProvisioningState)}
+	}
+
+	power := PreflightCheck{Check: "power", Status: PreflightPass, Detail: "the cluster is running"}
+	if strings.EqualFold(props.PowerState.Code, "Stopped") {
+		power = PreflightCheck{Check: "power", Status: PreflightFail, Detail: "the cluster is stopped; start it before upgrading"}
+	}
+	checks = append(checks, power)
+
+	var failedPools []string
+	for _, pool := range props.AgentPoolProfiles {
+		if !strings.EqualFold(pool.ProvisioningState, "Succeeded") {
+			failedPools = append(failedPools, fmt.Sprintf("%s (%s)", pool.Name, pool.ProvisioningState))
+		}
+	}
+	if len(failedPools) > 0 {
+		checks = append(checks, PreflightCheck{Check: "nodepools", Status: PreflightFail, Detail: "node pools are not in a succeeded state: " + strings.Join(failedPools, ", ")})
+	} else {
+		checks = append(checks, PreflightCheck{Check: "nodepools", Status: PreflightPass, Detail: "all node pools are in a succeeded state"})
+	}
+
+	if upgradeType == UpgradeTypeNodeImageOnly {
+		return checks
+	}
+	current := props.CurrentKubernetesVersion
+	if current == "" {
+		current = props.KubernetesVersion
+	}
+	checks = append(checks, versionCheck(current, version))
+	return checks
+}
+
+// versionCheck checks that the target version is an upgrade of at most one minor version
+func versionCheck(current, target string) PreflightCheck {
+	currentMinor, okCurrent := minorVersion(current)
+	targetMinor, okTarget := minorVersion(target)
+	switch {
+	case !okCurrent || !okTarget:
+		return PreflightCheck{Check: "version", Status: PreflightWarn, Detail: fmt.Sprintf("cannot compare version %s with %s", current, target)}
+	case current == target || (targetMinor == currentMinor && strings.Count(target, ".") == 1):
+		return PreflightCheck{Check: "version", Status: PreflightPass, Detail: fmt.Sprintf("the cluster already runs %s", current)}
+	case targetMinor < currentMinor:
+		return PreflightCheck{Check: "version", Status: PreflightFail, Detail: fmt.Sprintf("%s is older than the current version %s; downgrades are not supported", target, current)}
+	case targetMinor-currentMinor > 1:
+		return PreflightCheck{Check: "version", Status: PreflightFail,
+			Detail: fmt.Sprintf("upgrading from %s to %s skips minor versions; upgrade to 1.%d first", current, target, currentMinor+1)}
+	}
+	return PreflightCheck{Check: "version", Status: PreflightPass, Detail: fmt.Sprintf("%s can be upgraded to %s", current, target)}
+}
+
+// UpdateRunBody builds the update run resource of a plan
+func UpdateRunBody(plan UpgradePlan) map[string]interface{} {
+	stages := make([]map[string]interface{}, 0, len(plan.Stages))
+	for _, stage := range plan.Stages {
+		groups := make([]map[string]interface{}, 0, len(stage.Groups))
+		for _, group := range stage.Groups {
+			groups = append(groups, map[string]interface{}{"name": group})
+		}
+		stages = append(stages, map[string]interface{}{
+			"name":                    stage.Name,
+			"groups":                  groups,
+			"afterStageWaitInSeconds": stage.AfterStageWaitSeconds,
+		})
+	}
+	upgrade := map[string]interface{}{"type": plan.UpgradeType}
+	if plan.UpgradeType != UpgradeTypeNodeImageOnly {
+		upgrade["kubernetesVersion"] = plan.KubernetesVersion
+	}
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"strategy": map[string]interface{}{"stages": stages},
+			"managedClusterUpdate": map[string]interface{}{
+				"upgrade":            upgrade,
+				"nodeImageSelection": map[string]interface{}{"type": "Latest"},
+			},
+		},
+	}
+}
+
+// SummarizeUpdateRun reports the state of an update run and of each stage and member
+func SummarizeUpdateRun(name string, data []byte) (UpgradeRunResult, error) {
+	var run updateRunStatus
+	if err := json.Unmarshal(data, &run); err != nil {
+		return UpgradeRunResult{}, fmt.Errorf("failed to parse update run %s: %v", name, err)
+	}
+	result := UpgradeRunResult{UpdateRun: name, State: run.Properties.Status.Status.State, Message: run.Properties.Status.Status.Message}
+	total, completed := 0, 0
+	for _, stage := range run.Properties.Status.Stages {
+		stageState := StageState{Name: stage.Name, State: stage.Status.State}
+		for _, group := range stage.Groups {
+			for _, member := range group.Members {
+				total++
+				if member.Status.State == "Completed" || member.Status.State == "Skipped" {
+					completed++
+				}
+				stageState.Members = append(stageState.Members, MemberState{
+					Name: member.Name, Group: group.Name, State: member.Status.State, Message: member.Status.Message,
+				})
+			}
+		}
+		result.Stages = append(result.Stages, stageState)
+	}
+	result.Progress = fmt.Sprintf("%d of %d members completed", completed, total)
+	return result, nil
+}
+
+// listMembers lists the members of a fleet
+func listMembers(ctx context.Context, client common.ARMClient, fleetPath string) ([]fleetMember, error) {
+	path := fleetPath + "/members?api-version=" + fleetAPIVersion
+	var members []fleetMember
+	for page := 0; path != "" && page < maxMemberPages; page++ {
+		data, err := client.CallARM(ctx, http.MethodGet, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fleet members: %v", err)
+		}
+		var list struct {
+			Value    []fleetMember `json:"value"`
+			NextLink string        `json:"nextLink"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse fleet members: %v", err)
+		}
+		members = append(members, list.Value...)
+		path = list.NextLink
+	}
+	return members, nil
+}
+
+// getMemberCluster reads a member cluster
+func getMemberCluster(ctx context.Context, client common.ARMClient, clusterID string) (*memberCluster, error) {
+	data, err := client.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+clusterAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	var cluster memberCluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// minorVersion returns the minor version of a Kubernetes version such as 1.30 or 1.30.2
+func minorVersion(version string) (int, bool) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	return minor, err == nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
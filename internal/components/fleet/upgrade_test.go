@@ -0,0 +1,179 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeARM struct {
+	responses map[string]string
+	calls     []string
+	bodies    []interface{}
+}
+
+func (f *fakeARM) CallARM(ctx context.Context, method, path string) ([]byte, error) {
+	return f.CallARMWithBody(ctx, method, path, nil)
+}
+
+func (f *fakeARM) CallARMWithBody(_ context.Context, method, path string, payload interface{}) ([]byte, error) {
+	f.calls = append(f.calls, method+" "+path)
+	f.bodies = append(f.bodies, payload)
+	for fragment, body := range f.responses {
+		if strings.Contains(path, fragment) {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected request %s %s", method, path)
+}
+
+const testMembers = `{"value": [
+	{"name": "prod-east", "properties": {"clusterResourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/prod-east", "group": "production"}},
+	{"name": "canary", "properties": {"clusterResourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/canary", "group": "canary"}},
+	{"name": "dev", "properties": {"clusterResourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/dev"}}
+]}`
+
+func testCluster(version, state string) string {
+	return fmt.Sprintf(`{"properties": {"provisioningState": %q, "currentKubernetesVersion": %q, "powerState": {"code": "Running"},
+		"agentPoolProfiles": [{"name": "system", "provisioningState": "Succeeded"}]}}`, state, version)
+}
+
+func upgradeParams(operation string) map[string]interface{} {
+	return map[string]interface{}{
+		"operation":          operation,
+		"subscription_id":    "sub",
+		"resource_group":     "rg",
+		"fleet_name":         "fleet1",
+		"kubernetes_version": "1.30",
+		"stage_order":        "canary",
+	}
+}
+
+func TestFleetUpgradePlanAndExecute(t *testing.T) {
+	client := &fakeARM{responses: map[string]string{
+		"/members?":                     testMembers,
+		"managedClusters/prod-east?":    testCluster("1.29.4", "Succeeded"),
+		"managedClusters/canary?":       testCluster("1.29.4", "Succeeded"),
+		"managedClusters/dev?":          testCluster("1.28.9", "Failed"),
+		"/updateRuns/rollout-130/start": "",
+		"/updateRuns/rollout-130?":      `{}`,
+	}}
+
+	output, err := HandleFleetUpgrade(upgradeParams(UpgradeOpPlan), client, &config.ConfigData{AccessLevel: "readonly"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var plan UpgradePlan
+	if err := json.Unmarshal([]byte(output), &plan); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if !plan.Ready || len(plan.Stages) != 2 || plan.Stages[0].Groups[0] != "canary" || plan.Stages[1].Groups[0] != "production" {
+		t.Fatalf("Unexpected plan %s", output)
+	}
+	if plan.Stages[0].AfterStageWaitSeconds != defaultAfterStageWait || plan.Stages[1].AfterStageWaitSeconds != 0 {
+		t.Errorf("Expected soak time only between stages, got %+v", plan.Stages)
+	}
+	// The failed member has no update group, so it is reported but does not block the run
+	if len(plan.Unassigned) != 1 || plan.Unassigned[0].Ready {
+		t.Errorf("Expected the unassigned member to fail preflight, got %+v", plan.Unassigned)
+	}
+
+	if _, err := HandleFleetUpgrade(upgradeParams(UpgradeOpExecute), client, &config.ConfigData{AccessLevel: "readonly"}); err == nil {
+		t.Error("Expected execute to require readwrite")
+	}
+
+	params := upgradeParams(UpgradeOpExecute)
+	params["update_run_name"] = "rollout-130"
+	client.calls = nil
+	output, err = HandleFleetUpgrade(params, client, &config.ConfigData{AccessLevel: "readwrite"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, `"action": "started"`) {
+		t.Errorf("Expected a started run, got %s", output)
+	}
+	last := client.calls[len(client.calls)-2:]
+	if !strings.HasPrefix(last[0], "PUT ") || !strings.HasPrefix(last[1], "POST ") || !strings.Contains(last[1], "/start") {
+		t.Errorf("Expected the run to be created then started, got %v", last)
+	}
+	body, _ := json.Marshal(client.bodies[len(client.bodies)-2])
+	if !strings.Contains(string(body), `"kubernetesVersion":"1.30"`) || !strings.Contains(string(body), `"name":"stage1"`) {
+		t.Errorf("Unexpected update run body %s", body)
+	}
+}
+
+func TestFleetUpgradeBlockers(t *testing.T) {
+	client := &fakeARM{responses: map[string]string{
+		"/members?":                  testMembers,
+		"managedClusters/prod-east?": testCluster("1.28.9", "Succeeded"),
+		"managedClusters/canary?":    testCluster("1.29.4", "Updating"),
+		"managedClusters/dev?":       testCluster("1.29.4", "Succeeded"),
+	}}
+	output, err := HandleFleetUpgrade(upgradeParams(UpgradeOpPlan), client, &config.ConfigData{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var plan UpgradePlan
+	if err := json.Unmarshal([]byte(output), &plan); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if plan.Ready || len(plan.Blockers) != 2 {
+		t.Errorf("Expected the updating canary and the minor version skip to block, got %v", plan.Blockers)
+	}
+
+	if _, err := HandleFleetUpgrade(upgradeParams(UpgradeOpExecute), client, &config.ConfigData{AccessLevel: "admin"}); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("Expected execute to refuse a plan that is not ready, got %v", err)
+	}
+}
+
+func TestFleetUpgradeStatusAndPause(t *testing.T) {
+	run := `{"properties": {"status": {"status": {"state": "Stopped"}, "stages": [
+		{"name": "stage1", "status": {"state": "Completed"}, "groups": [{"name": "canary", "members": [{"name": "canary", "status": {"state": "Completed"}}]}]},
+		{"name": "stage2", "status": {"state": "Stopped"}, "groups": [{"name": "production", "members": [{"name": "prod-east", "status": {"state": "NotStarted"}}]}]}
+	]}}}`
+	client := &fakeARM{responses: map[string]string{"/updateRuns/rollout/stop": "", "/updateRuns/rollout?": run}}
+	params := upgradeParams(UpgradeOpPause)
+	params["update_run_name"] = "rollout"
+
+	output, err := HandleFleetUpgrade(params, client, &config.ConfigData{AccessLevel: "readwrite"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result UpgradeRunResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if result.Action != "paused" || result.State != "Stopped" || result.Progress != "1 of 2 members completed" || len(result.Stages) != 2 {
+		t.Errorf("Unexpected status %s", output)
+	}
+	if !strings.Contains(client.calls[0], "POST ") || !strings.Contains(client.calls[0], "/stop") {
+		t.Errorf("Expected the run to be stopped, got %v", client.calls)
+	}
+
+	delete(params, "update_run_name")
+	params["operation"] = UpgradeOpStatus
+	if _, err := HandleFleetUpgrade(params, client, &config.ConfigData{}); err == nil {
+		t.Error("Expected an error without update_run_name")
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	for _, tc := range []struct {
+		current, target, status string
+	}{
+		{"1.29.4", "1.30", PreflightPass},
+		{"1.29.4", "1.30.2", PreflightPass},
+		{"1.30.2", "1.30", PreflightPass},
+		{"1.28.9", "1.30", PreflightFail},
+		{"1.30.2", "1.29", PreflightFail},
+		{"", "1.30", PreflightWarn},
+	} {
+		if check := versionCheck(tc.current, tc.target); check.Status != tc.status {
+			t.Errorf("versionCheck(%q, %q) = %+v, want %s", tc.current, tc.target, check, tc.status)
+		}
+	}
+}
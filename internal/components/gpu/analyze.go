@@ -0,0 +1,479 @@
+package gpu
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GPU resource names advertised by device plugins
+const (
+	resourceNvidiaGPU = "nvidia.com/gpu"
+	resourceAMDGPU    = "amd.com/gpu"
+	migResourcePrefix = "nvidia.com/mig-"
+)
+
+// amdSizePattern matches the VM sizes with AMD GPUs (NVv4 and NGads V620), which need the AMD device plugin
+var amdSizePattern = regexp.MustCompile(`(?i)^Standard_(NV\d+as_v4|NG\d+ads_V620_v1)$`)
+
+// NodePool is the subset of an AKS agent pool used by the diagnosis
+type NodePool struct {
+	Name               string   `json:"name"`
+	VMSize             string   `json:"vmSize"`
+	Count              int      `json:"count"`
+	EnableAutoScaling  bool     `json:"enableAutoScaling"`
+	MinCount           *int     `json:"minCount"`
+	MaxCount           *int     `json:"maxCount"`
+	NodeTaints         []string `json:"nodeTaints"`
+	GPUInstanceProfile string   `json:"gpuInstanceProfile"`
+	GPUProfile         *struct {
+		Driver string `json:"driver"`
+	} `json:"gpuProfile"`
+}
+
+type taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+type toleration struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	Effect   string `json:"effect"`
+}
+
+type metadata struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// Node is the subset of a Kubernetes node used by the diagnosis
+type Node struct {
+	Metadata metadata `json:"metadata"`
+	Spec     struct {
+		ProviderID    string  `json:"providerID"`
+		Unschedulable bool    `json:"unschedulable"`
+		Taints        []taint `json:"taints"`
+	} `json:"spec"`
+	Status struct {
+		Capacity    map[string]string `json:"capacity"`
+		Allocatable map[string]string `json:"allocatable"`
+		Conditions  []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+type podSpec struct {
+	NodeSelector map[string]string `json:"nodeSelector"`
+	Tolerations  []toleration      `json:"tolerations"`
+	Containers   []struct {
+		Image     string `json:"image"`
+		Resources struct {
+			Limits   map[string]string `json:"limits"`
+			Requests map[string]string `json:"requests"`
+		} `json:"resources"`
+	} `json:"containers"`
+}
+
+// DaemonSet is the subset of a DaemonSet used to find and check the device plugin
+type DaemonSet struct {
+	Metadata metadata `json:"metadata"`
+	Spec     struct {
+		Template struct {
+			Spec podSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		NumberReady            int `json:"numberReady"`
+		NumberUnavailable      int `json:"numberUnavailable"`
+	} `json:"status"`
+}
+
+// Pod is the subset of a pending pod used to explain why it does not schedule
+type Pod struct {
+	Metadata metadata `json:"metadata"`
+	Spec     podSpec  `json:"spec"`
+	Status   struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// DriverCheck is the nvidia-smi result of one node, run through VMSS run-command
+type DriverCheck struct {
+	Node    string `json:"node"`
+	Healthy bool   `json:"healthy"`
+	Output  string `json:"output"`
+}
+
+// Inventory is everything the diagnosis reads from Azure and the cluster
+type Inventory struct {
+	Pools []NodePool
+	// SKUGPUs is the GPU count of each VM size in the cluster region (absent when the size was not found)
+	SKUGPUs    map[string]int
+	Nodes      []Node
+	DaemonSets []DaemonSet
+	Pending    []Pod
+	Drivers    map[string]DriverCheck
+}
+
+// NodeGPUs is the GPU state of one node
+type NodeGPUs struct {
+	Name        string       `json:"name"`
+	Ready       bool         `json:"ready"`
+	Capacity    int          `json:"capacity"`
+	Allocatable int          `json:"allocatable"`
+	Driver      *DriverCheck `json:"driver,omitempty"`
+}
+
+// PoolReport is the GPU state of one node pool
+type PoolReport struct {
+	Name        string     `json:"name"`
+	VMSize      string     `json:"vmSize"`
+	SKUGPUs     int        `json:"skuGpus"`
+	GPUResource string     `json:"gpuResource"`
+	Nodes       []NodeGPUs `json:"nodes"`
+	Issues      []string   `json:"issues,omitempty"`
+}
+
+// DevicePluginReport is the state of the GPU device plugin DaemonSet
+type DevicePluginReport struct {
+	Found        bool     `json:"found"`
+	Name         string   `json:"name,omitempty"`
+	Namespace    string   `json:"namespace,omitempty"`
+	Desired      int      `json:"desired"`
+	Ready        int      `json:"ready"`
+	MissingNodes []string `json:"missingNodes,omitempty"`
+	Issues       []string `json:"issues,omitempty"`
+}
+
+// PendingPodReport explains why a pod requesting GPUs does not schedule
+type PendingPodReport struct {
+	Namespace        string   `json:"namespace"`
+	Name             string   `json:"name"`
+	GPUs             int      `json:"gpus"`
+	SchedulerMessage string   `json:"schedulerMessage,omitempty"`
+	Reasons          []string `json:"reasons"`
+}
+
+// GPUReport is the result returned by the diagnose_gpu_workloads tool
+type GPUReport struct {
+	GPUPools     []PoolReport       `json:"gpuPools"`
+	DevicePlugin DevicePluginReport `json:"devicePlugin"`
+	PendingPods  []PendingPodReport `json:"pendingGpuPods"`
+	Summary      []string           `json:"summary"`
+	Note         string             `json:"note,omitempty"`
+}
+
+// AnalyzeGPU checks the GPU pools, the device plugin and the pending GPU pods of an inventory
+func AnalyzeGPU(inv Inventory) GPUReport {
+	report := GPUReport{GPUPools: []PoolReport{}, PendingPods: []PendingPodReport{}, Summary: []string{}}
+
+	nodesByPool := make(map[string][]Node)
+	for _, node := range inv.Nodes {
+		pool := node.Metadata.Labels["kubernetes.azure.com/agentpool"]
+		if pool == "" {
+			pool = node.Metadata.Labels["agentpool"]
+		}
+		nodesByPool[pool] = append(nodesByPool[pool], node)
+	}
+
+	var gpuNodes []Node
+	for _, pool := range inv.Pools {
+		skuGPUs := inv.SKUGPUs[pool.VMSize]
+		advertised := false
+		for _, node := range nodesByPool[pool.Name] {
+			advertised = advertised || gpuQuantity(node.Status.Capacity, resourceNvidiaGPU) > 0 || gpuQuantity(node.Status.Capacity, resourceAMDGPU) > 0
+		}
+		if skuGPUs == 0 && !advertised {
+			continue
+		}
+		gpuNodes = append(gpuNodes, nodesByPool[pool.Name]...)
+		report.GPUPools = append(report.GPUPools, analyzePool(pool, skuGPUs, nodesByPool[pool.Name], inv.Drivers))
+	}
+
+	report.DevicePlugin = analyzeDevicePlugin(inv.DaemonSets, gpuNodes)
+	for _, pod := range inv.Pending {
+		if gpus := podGPUs(pod.Spec); gpus > 0 {
+			report.PendingPods = append(report.PendingPods, explainPending(pod, gpus, gpuNodes, len(report.GPUPools) > 0))
+		}
+	}
+	sort.Slice(report.PendingPods, func(i, j int) bool {
+		a, b := report.PendingPods[i], report.PendingPods[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	switch {
+	case len(report.GPUPools) == 0:
+		report.Summary = append(report.Summary, "the cluster has no GPU node pool: add one with az aks nodepool add --node-vm-size set to a GPU size such as Standard_NC4as_T4_v3")
+	default:
+		for _, pool := range report.GPUPools {
+			for _, issue := range pool.Issues {
+				report.Summary = append(report.Summary, fmt.Sprintf("pool %s: %s", pool.Name, issue))
+			}
+		}
+		for _, issue := range report.DevicePlugin.Issues {
+			report.Summary = append(report.Summary, "device plugin: "+issue)
+		}
+	}
+	if len(report.PendingPods) > 0 {
+		report.Summary = append(report.Summary, fmt.Sprintf("%d pods requesting GPUs are pending; see pendingGpuPods for why each does not schedule", len(report.PendingPods)))
+	}
+	if len(report.Summary) == 0 {
+		report.Summary = append(report.Summary, "GPU pools, device plugin and GPU pods look healthy")
+	}
+	return report
+}
+
+func analyzePool(pool NodePool, skuGPUs int, nodes []Node, drivers map[string]DriverCheck) PoolReport {
+	result := PoolReport{Name: pool.Name, VMSize: pool.VMSize, SKUGPUs: skuGPUs, GPUResource: resourceNvidiaGPU, Nodes: []NodeGPUs{}}
+	amd := amdSizePattern.MatchString(pool.VMSize)
+	if amd {
+		result.GPUResource = resourceAMDGPU
+		result.Issues = append(result.Issues, pool.VMSize+" has AMD GPUs, which are advertised as amd.com/gpu by the AMD device plugin, not nvidia.com/gpu")
+	}
+	if skuGPUs == 0 {
+		result.Issues = append(result.Issues, fmt.Sprintf("the VM size %s reports no GPUs in the cluster region, yet its nodes advertise GPUs; verify the size", pool.VMSize))
+	}
+	if pool.GPUProfile != nil && strings.EqualFold(pool.GPUProfile.Driver, "None") {
+		result.Issues = append(result.Issues, "the pool skips the AKS GPU driver install (gpu driver None), so the NVIDIA GPU Operator or another installer must provide the driver")
+	}
+	if pool.GPUInstanceProfile != "" {
+		result.Issues = append(result.Issues, fmt.Sprintf("the pool uses the MIG profile %s, so GPUs are advertised as %s* or nvidia.com/gpu depending on the device plugin MIG strategy", pool.GPUInstanceProfile, migResourcePrefix))
+	}
+	if len(nodes) == 0 {
+		if pool.EnableAutoScaling {
+			result.Issues = append(result.Issues, "the pool has no nodes; pending GPU pods rely on the cluster autoscaler to scale it up from zero")
+		} else {
+			result.Issues = append(result.Issues, "the pool has no nodes: scale it up with az aks nodepool scale")
+		}
+	}
+
+	var unadvertised []string
+	for _, node := range nodes {
+		state := NodeGPUs{
+			Name:        node.Metadata.Name,
+			Ready:       nodeReady(node),
+			Capacity:    poolGPUQuantity(node.Status.Capacity, result.GPUResource),
+			Allocatable: poolGPUQuantity(node.Status.Allocatable, result.GPUResource),
+		}
+		if check, ok := drivers[node.Metadata.Name]; ok {
+			state.Driver = &check
+			if !check.Healthy {
+				result.Issues = append(result.Issues, fmt.Sprintf("nvidia-smi fails on %s, so the NVIDIA driver is not installed or not loaded: %s", node.Metadata.Name, check.Output))
+			}
+		}
+		switch {
+		case !state.Ready:
+			result.Issues = append(result.Issues, fmt.Sprintf("node %s is not Ready", node.Metadata.Name))
+		case state.Allocatable == 0:
+			unadvertised = append(unadvertised, node.Metadata.Name)
+		case skuGPUs > 0 && state.Allocatable < skuGPUs && pool.GPUInstanceProfile == "":
+			result.Issues = append(result.Issues, fmt.Sprintf("node %s advertises %d of the %d GPUs of %s; an unhealthy GPU may have been removed by the device plugin", node.Metadata.Name, state.Allocatable, skuGPUs, pool.VMSize))
+		}
+		result.Nodes = append(result.Nodes, state)
+	}
+	if len(unadvertised) > 0 {
+		result.Issues = append(result.Issues, fmt.Sprintf("%s allocatable is 0 on %s: the device plugin is not running there or cannot find the driver", result.GPUResource, strings.Join(unadvertised, ", ")))
+	}
+	return result
+}
+
+// isDevicePlugin reports whether a DaemonSet runs a GPU device plugin
+func isDevicePlugin(ds DaemonSet) bool {
+	name := strings.ToLower(ds.Metadata.Name)
+	if strings.Contains(name, "device-plugin") && (strings.Contains(name, "nvidia") || strings.Contains(name, "gpu") || strings.Contains(name, "amd")) {
+		return true
+	}
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		image := strings.ToLower(container.Image)
+		if strings.Contains(image, "k8s-device-plugin") {
+			return true
+		}
+	}
+	return false
+}
+
+func analyzeDevicePlugin(daemonSets []DaemonSet, gpuNodes []Node) DevicePluginReport {
+	var plugin *DaemonSet
+	for i := range daemonSets {
+		if isDevicePlugin(daemonSets[i]) {
+			plugin = &daemonSets[i]
+			break
+		}
+	}
+	if plugin == nil {
+		result := DevicePluginReport{}
+		if len(gpuNodes) > 0 {
+			result.Issues = []string{"no GPU device plugin DaemonSet was found in the allowed namespaces, so nodes do not advertise GPUs: " +
+				"install the NVIDIA device plugin (for example the nvidia-device-plugin-daemonset manifest) or the NVIDIA GPU Operator"}
+		}
+		return result
+	}
+
+	result := DevicePluginReport{
+		Found:     true,
+		Name:      plugin.Metadata.Name,
+		Namespace: plugin.Metadata.Namespace,
+		Desired:   plugin.Status.DesiredNumberScheduled,
+		Ready:     plugin.Status.NumberReady,
+	}
+	if result.Ready < result.Desired {
+		result.Issues = append(result.Issues, fmt.Sprintf("%d of %d device plugin pods are not ready: check them with kubectl describe pods -n %s", result.Desired-result.Ready, result.Desired, result.Namespace))
+	}
+	spec := plugin.Spec.Template.Spec
+	for _, node := range gpuNodes {
+		if reason := blockingReason(spec, node); reason != "" {
+			result.MissingNodes = append(result.MissingNodes, node.Metadata.Name)
+			result.Issues = append(result.Issues, fmt.Sprintf("the DaemonSet does not run on %s: %s", node.Metadata.Name, reason))
+		}
+	}
+	return result
+}
+
+func explainPending(pod Pod, gpus int, gpuNodes []Node, hasGPUPools bool) PendingPodReport {
+	result := PendingPodReport{Namespace: pod.Metadata.Namespace, Name: pod.Metadata.Name, GPUs: gpus, Reasons: []string{}}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == "PodScheduled" && cond.Status == "False" {
+			result.SchedulerMessage = cond.Message
+		}
+	}
+	if !hasGPUPools {
+		result.Reasons = append(result.Reasons, "no node pool in the cluster has GPUs")
+		return result
+	}
+
+	var eligible []Node
+	maxAllocatable := 0
+	for _, node := range gpuNodes {
+		allocatable := gpuQuantity(node.Status.Allocatable, resourceNvidiaGPU)
+		if allocatable > maxAllocatable {
+			maxAllocatable = allocatable
+		}
+		if reason := blockingReason(pod.Spec, node); reason != "" {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("cannot run on %s: %s", node.Metadata.Name, reason))
+			continue
+		}
+		eligible = append(eligible, node)
+	}
+
+	switch {
+	case len(gpuNodes) == 0:
+		result.Reasons = append(result.Reasons, "the GPU pools have no nodes; the pod waits for the cluster autoscaler or a manual scale up")
+	case maxAllocatable > 0 && gpus > maxAllocatable:
+		result.Reasons = append(result.Reasons, fmt.Sprintf("the pod requests %d GPUs, but the largest GPU node offers %d; use a larger VM size or request fewer GPUs", gpus, maxAllocatable))
+	case len(eligible) == 0:
+		result.Reasons = append(result.Reasons, "no GPU node accepts the pod: add tolerations for the GPU pool taints or fix the node selector")
+	default:
+		offered := 0
+		for _, node := range eligible {
+			offered += gpuQuantity(node.Status.Allocatable, resourceNvidiaGPU)
+		}
+		switch {
+		case offered == 0:
+			result.Reasons = append(result.Reasons, "the GPU nodes the pod may run on advertise no nvidia.com/gpu: fix the device plugin or driver first")
+		case strings.Contains(result.SchedulerMessage, "Insufficient "+resourceNvidiaGPU):
+			result.Reasons = append(result.Reasons, fmt.Sprintf("all %d GPUs on the eligible nodes are in use: scale the GPU pool or enable the cluster autoscaler", offered))
+		default:
+			result.Reasons = append(result.Reasons, "GPU capacity is available on eligible nodes; the scheduler message names the remaining constraint (CPU, memory, affinity or volume)")
+		}
+	}
+	return result
+}
+
+// blockingReason explains why a pod spec cannot run on a node, or returns "" when it can
+func blockingReason(spec podSpec, node Node) string {
+	for key, value := range spec.NodeSelector {
+		if node.Metadata.Labels[key] != value {
+			return fmt.Sprintf("the node selector %s=%s does not match", key, value)
+		}
+	}
+	for _, t := range node.Spec.Taints {
+		if t.Effect != "NoSchedule" && t.Effect != "NoExecute" {
+			continue
+		}
+		if !tolerates(spec.Tolerations, t) {
+			return fmt.Sprintf("no toleration for the taint %s=%s:%s", t.Key, t.Value, t.Effect)
+		}
+	}
+	if node.Spec.Unschedulable {
+		return "the node is cordoned"
+	}
+	return ""
+}
+
+func tolerates(tolerations []toleration, t taint) bool {
+	for _, tol := range tolerations {
+		if tol.Effect != "" && tol.Effect != t.Effect {
+			continue
+		}
+		if tol.Key == "" && tol.Operator == "Exists" {
+			return true
+		}
+		if tol.Key == t.Key && (tol.Operator == "Exists" || tol.Value == t.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// podGPUs is the number of NVIDIA GPUs, including MIG slices, a pod's containers request
+func podGPUs(spec podSpec) int {
+	total := 0
+	for _, container := range spec.Containers {
+		limits := container.Resources.Limits
+		if len(limits) == 0 {
+			limits = container.Resources.Requests
+		}
+		for name, value := range limits {
+			if name == resourceNvidiaGPU || strings.HasPrefix(name, migResourcePrefix) {
+				n, _ := strconv.Atoi(value)
+				total += n
+			}
+		}
+	}
+	return total
+}
+
+// poolGPUQuantity counts a pool's GPU resource, adding MIG slices for NVIDIA pools
+func poolGPUQuantity(resources map[string]string, resource string) int {
+	total := gpuQuantity(resources, resource)
+	if resource == resourceNvidiaGPU {
+		for name, value := range resources {
+			if strings.HasPrefix(name, migResourcePrefix) {
+				n, _ := strconv.Atoi(value)
+				total += n
+			}
+		}
+	}
+	return total
+}
+
+func gpuQuantity(resources map[string]string, resource string) int {
+	n, _ := strconv.Atoi(resources[resource])
+	return n
+}
+
+func nodeReady(node Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeExecutor struct {
+	responses map[string]string
+	commands  []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return `{"items":[]}`, nil
+}
+
+const testProviderID = "azure:///subscriptions/sub/resourceGroups/mc_rg_aks_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-gpupool-123-vmss/virtualMachines/0"
+
+func testNode(name, pool, allocatable string) string {
+	return fmt.Sprintf(`{
+  "metadata": {"name": %q, "labels": {"kubernetes.azure.com/agentpool": %q}},
+  "spec": {"providerID": %q, "taints": [{"key": "sku", "value": "gpu", "effect": "NoSchedule"}]},
+  "status": {
+    "capacity": {"nvidia.com/gpu": %q},
+    "allocatable": {"nvidia.com/gpu": %q},
+    "conditions": [{"type": "Ready", "status": "True"}]
+  }
+}`, name, pool, testProviderID, allocatable, allocatable)
+}
+
+const testDevicePlugin = `{
+  "metadata": {"name": "nvidia-device-plugin-daemonset", "namespace": "kube-system"},
+  "spec": {"template": {"spec": {
+    "tolerations": [{"key": "sku", "operator": "Equal", "value": "gpu", "effect": "NoSchedule"}],
+    "containers": [{"image": "nvcr.io/nvidia/k8s-device-plugin:v0.14.1"}]
+  }}},
+  "status": {"desiredNumberScheduled": 1, "numberReady": 1}
+}`
+
+func testPendingPod(tolerations string, gpus int) string {
+	return fmt.Sprintf(`{
+  "metadata": {"name": "trainer", "namespace": "ml"},
+  "spec": {
+    "tolerations": %s,
+    "containers": [{"image": "pytorch", "resources": {"limits": {"nvidia.com/gpu": "%d"}}}]
+  },
+  "status": {"conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/2 nodes are available: 1 Insufficient nvidia.com/gpu."}]}
+}`, tolerations, gpus)
+}
+
+func newTestExecutor(allocatable, pendingPod string) *fakeExecutor {
+	return &fakeExecutor{responses: map[string]string{
+		"az aks show":          "eastus\n",
+		"az aks nodepool list": `[{"name":"system","vmSize":"Standard_D4s_v5","count":1},{"name":"gpupool","vmSize":"Standard_NC6s_v3","count":1,"nodeTaints":["sku=gpu:NoSchedule"]}]`,
+		"az vm list-skus --location eastus --size Standard_NC6s_v3": `[{"name":"Standard_NC6s_v3","capabilities":[{"name":"GPUs","value":"1"}]}]`,
+		"az vm list-skus --location eastus --size Standard_D4s_v5":  `[{"name":"Standard_D4s_v5","capabilities":[{"name":"vCPUs","value":"4"}]}]`,
+		"az vmss run-command invoke":                                `{"value":[{"message":"Enable succeeded: \n[stdout]\nTesla V100-PCIE-16GB, 535.104.05\n\n[stderr]\n"}]}`,
+		"get nodes":                                                 `{"items":[` + testNode("aks-gpupool-123-vmss000000", "gpupool", allocatable) + `]}`,
+		"get daemonsets":                                            `{"items":[` + testDevicePlugin + `]}`,
+		"get pods --all-namespaces":                                 `{"items":[` + pendingPod + `]}`,
+	}}
+}
+
+func runDiagnose(t *testing.T, params map[string]interface{}, executor *fakeExecutor, cfg *config.ConfigData) GPUReport {
+	t.Helper()
+	output, err := HandleDiagnoseGPU(params, executor, executor, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report GPUReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+func testParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+}
+
+// TestDiagnoseGPUHealthy tests that a GPU pool with a running device plugin and no pending pods is healthy
+func TestDiagnoseGPUHealthy(t *testing.T) {
+	report := runDiagnose(t, testParams(), newTestExecutor("1", `{"metadata":{"name":"web"},"spec":{"containers":[{}]}}`), config.NewConfig())
+
+	if len(report.GPUPools) != 1 || report.GPUPools[0].Name != "gpupool" || report.GPUPools[0].SKUGPUs != 1 {
+		t.Fatalf("Expected only gpupool with 1 GPU, got %+v", report.GPUPools)
+	}
+	if len(report.GPUPools[0].Issues) != 0 || len(report.DevicePlugin.Issues) != 0 || !report.DevicePlugin.Found {
+		t.Errorf("Expected no issues, got pool %v and plugin %+v", report.GPUPools[0].Issues, report.DevicePlugin)
+	}
+	if len(report.PendingPods) != 0 {
+		t.Errorf("Expected no pending GPU pods, got %+v", report.PendingPods)
+	}
+	if report.Note == "" {
+		t.Error("Expected a note that drivers were not checked")
+	}
+}
+
+// TestDiagnoseGPUPendingPods tests the explanations for pending pods that request GPUs
+func TestDiagnoseGPUPendingPods(t *testing.T) {
+	tolerated := `[{"key":"sku","operator":"Exists"}]`
+	tests := []struct {
+		name        string
+		allocatable string
+		pod         string
+		want        string
+	}{
+		{"missing toleration", "1", testPendingPod(`[]`, 1), "no toleration for the taint sku=gpu:NoSchedule"},
+		{"too many GPUs", "1", testPendingPod(tolerated, 4), "the largest GPU node offers 1"},
+		{"all in use", "1", testPendingPod(tolerated, 1), "GPUs on the eligible nodes are in use"},
+		{"nothing advertised", "0", testPendingPod(tolerated, 1), "advertise no nvidia.com/gpu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := runDiagnose(t, testParams(), newTestExecutor(tt.allocatable, tt.pod), config.NewConfig())
+			if len(report.PendingPods) != 1 {
+				t.Fatalf("Expected one pending GPU pod, got %+v", report.PendingPods)
+			}
+			if reasons := strings.Join(report.PendingPods[0].Reasons, "; "); !strings.Contains(reasons, tt.want) {
+				t.Errorf("Expected reasons to contain %q, got %q", tt.want, reasons)
+			}
+		})
+	}
+}
+
+// TestDiagnoseGPUDevicePlugin tests a missing device plugin and one that cannot run on the GPU nodes
+func TestDiagnoseGPUDevicePlugin(t *testing.T) {
+	executor := newTestExecutor("0", `{}`)
+	executor.responses["get daemonsets"] = `{"items":[]}`
+	report := runDiagnose(t, testParams(), executor, config.NewConfig())
+	if report.DevicePlugin.Found || len(report.DevicePlugin.Issues) != 1 {
+		t.Errorf("Expected a missing device plugin issue, got %+v", report.DevicePlugin)
+	}
+
+	executor = newTestExecutor("0", `{}`)
+	executor.responses["get daemonsets"] = `{"items":[` + strings.Replace(testDevicePlugin, `"tolerations": [{"key": "sku", "operator": "Equal", "value": "gpu", "effect": "NoSchedule"}],`, "", 1) + `]}`
+	report = runDiagnose(t, testParams(), executor, config.NewConfig())
+	if len(report.DevicePlugin.MissingNodes) != 1 || report.DevicePlugin.MissingNodes[0] != "aks-gpupool-123-vmss000000" {
+		t.Errorf("Expected the device plugin to miss the GPU node, got %+v", report.DevicePlugin)
+	}
+}
+
+// TestDiagnoseGPUDrivers tests that check_drivers runs nvidia-smi with VMSS run-command and needs write access
+func TestDiagnoseGPUDrivers(t *testing.T) {
+	params := testParams()
+	params["check_drivers"] = true
+
+	if _, err := HandleDiagnoseGPU(params, newTestExecutor("1", `{}`), newTestExecutor("1", `{}`), config.NewConfig()); err == nil {
+		t.Error("Expected check_drivers to be rejected with readonly access")
+	}
+
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readwrite"
+	executor := newTestExecutor("1", `{}`)
+	report := runDiagnose(t, params, executor, cfg)
+	driver := report.GPUPools[0].Nodes[0].Driver
+	if driver == nil || !driver.Healthy || driver.Output != "Tesla V100-PCIE-16GB, 535.104.05" {
+		t.Errorf("Expected a healthy driver check, got %+v", driver)
+	}
+	var invoked string
+	for _, cmd := range executor.commands {
+		if strings.HasPrefix(cmd, "az vmss run-command invoke") {
+			invoked = cmd
+		}
+	}
+	if !strings.Contains(invoked, "--resource-group mc_rg_aks_eastus --name aks-gpupool-123-vmss --instance-id 0") {
+		t.Errorf("Expected run-command on the node's scale set instance, got %q", invoked)
+	}
+}
+
+// TestParseDriverCheck tests reading nvidia-smi failures from run-command output
+func TestParseDriverCheck(t *testing.T) {
+	check := ParseDriverCheck("node", `{"value":[{"message":"Enable succeeded: \n[stdout]\n\n[stderr]\n/bin/sh: 1: nvidia-smi: not found\n"}]}`)
+	if check.Healthy || check.Output != "/bin/sh: 1: nvidia-smi: not found" {
+		t.Errorf("Expected a failed driver check, got %+v", check)
+	}
+	if check := ParseDriverCheck("node", "not json"); check.Healthy {
+		t.Errorf("Expected unexpected output to be unhealthy, got %+v", check)
+	}
+}
+
+// TestDiagnoseGPUInvalidNodePool tests node pool validation
+func TestDiagnoseGPUInvalidNodePool(t *testing.T) {
+	params := testParams()
+	params["node_pool"] = "gpu; rm -rf /"
+	if _, err := HandleDiagnoseGPU(params, &fakeExecutor{}, &fakeExecutor{}, config.NewConfig()); err == nil {
+		t.Error("Expected an invalid node pool to be rejected")
+	}
+	params["node_pool"] = "missing"
+	if _, err := HandleDiagnoseGPU(params, newTestExecutor("1", `{}`), newTestExecutor("1", `{}`), config.NewConfig()); err == nil {
+		t.Error("Expected an unknown node pool to be rejected")
+	}
+}
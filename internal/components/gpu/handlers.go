@@ -0,0 +1,225 @@
+// Package gpu diagnoses GPU node pools: VM size capability, the device plugin DaemonSet, driver state,
+// allocatable GPUs per node and why pods requesting GPUs do not schedule.
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// driverCheckScript reports each GPU's name and driver version, and fails when the driver is missing
+const driverCheckScript = "nvidia-smi --query-gpu=name,driver_version --format=csv,noheader"
+
+var (
+	// vmssProviderIDPattern extracts the scale set and instance of a VMSS node from its provider ID
+	vmssProviderIDPattern = regexp.MustCompile(`(?i)/resourceGroups/([^/]+)/providers/Microsoft\.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/(\d+)$`)
+	// poolNamePattern matches AKS agent pool names
+	poolNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]{0,11}$`)
+)
+
+// GetDiagnoseGPUHandler returns a handler for the diagnose_gpu_workloads command
+func GetDiagnoseGPUHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleDiagnoseGPU(params, azcli.NewExecutor(), k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleDiagnoseGPU reads the node pools, VM size capabilities, nodes, device plugin and pending pods,
+// optionally runs nvidia-smi on one node per GPU pool, and explains why GPU workloads do not schedule
+func HandleDiagnoseGPU(params map[string]interface{}, azExecutor, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	poolFilter, _ := params["node_pool"].(string)
+	if poolFilter != "" && !poolNamePattern.MatchString(poolFilter) {
+		return "", fmt.Errorf("invalid node_pool parameter: %s", poolFilter)
+	}
+	checkDrivers := params["check_drivers"] == true || params["check_drivers"] == "true"
+	if checkDrivers && cfg.AccessLevel == "readonly" {
+		return "", fmt.Errorf("check_drivers runs nvidia-smi on nodes with VMSS run-command and requires readwrite or admin access")
+	}
+	clusterArgs := fmt.Sprintf("--resource-group %s --subscription %s", rg, subID)
+
+	var inv Inventory
+	location, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az aks show --name %s %s --query location -o tsv", clusterName, clusterArgs),
+	}, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %v", clusterName, err)
+	}
+	location = strings.TrimSpace(location)
+
+	output, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az aks nodepool list --cluster-name %s %s -o json", clusterName, clusterArgs),
+	}, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to list node pools: %v", err)
+	}
+	if err := json.Unmarshal([]byte(output), &inv.Pools); err != nil {
+		return "", fmt.Errorf("failed to parse node pools: %v", err)
+	}
+	if poolFilter != "" {
+		var pools []NodePool
+		for _, pool := range inv.Pools {
+			if pool.Name == poolFilter {
+				pools = append(pools, pool)
+			}
+		}
+		if len(pools) == 0 {
+			return "", fmt.Errorf("node pool %s not found in cluster %s", poolFilter, clusterName)
+		}
+		inv.Pools = pools
+	}
+
+	inv.SKUGPUs = make(map[string]int)
+	for _, pool := range inv.Pools {
+		if _, seen := inv.SKUGPUs[pool.VMSize]; seen {
+			continue
+		}
+		gpus, err := skuGPUs(azExecutor, pool.VMSize, location, subID, cfg)
+		if err != nil {
+			return "", err
+		}
+		inv.SKUGPUs[pool.VMSize] = gpus
+	}
+
+	if output, err = kubectlExecutor.Execute(map[string]interface{}{"command": "get nodes -o json"}, cfg); err != nil {
+		return "", fmt.Errorf("failed to list nodes: %v", err)
+	}
+	if err := common.DecodeList(output, &inv.Nodes); err != nil {
+		return "", err
+	}
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		if output, err = kubectlExecutor.Execute(map[string]interface{}{"command": "get daemonsets " + flag + " -o json"}, cfg); err != nil {
+			return "", fmt.Errorf("failed to list daemonsets: %v", err)
+		}
+		if err := common.DecodeList(output, &inv.DaemonSets); err != nil {
+			return "", err
+		}
+		if output, err = kubectlExecutor.Execute(map[string]interface{}{"command": "get pods " + flag + " --field-selector status.phase=Pending -o json"}, cfg); err != nil {
+			return "", fmt.Errorf("failed to list pending pods: %v", err)
+		}
+		if err := common.DecodeList(output, &inv.Pending); err != nil {
+			return "", err
+		}
+	}
+
+	report := AnalyzeGPU(inv)
+	if checkDrivers {
+		inv.Drivers = checkPoolDrivers(azExecutor, report, inv.Nodes, subID, cfg)
+		report = AnalyzeGPU(inv)
+	} else if len(report.GPUPools) > 0 {
+		report.Note = "Driver installation was not checked; pass check_drivers=true to run nvidia-smi on one node per GPU pool with VMSS run-command."
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal GPU report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// skuGPUs returns the GPU count of a VM size in a region, or 0 when the size has no GPUs or is not offered there
+func skuGPUs(azExecutor tools.CommandExecutor, vmSize, location, subID string, cfg *config.ConfigData) (int, error) {
+	output, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az vm list-skus --location %s --size %s --resource-type virtualMachines --subscription %s -o json", location, vmSize, subID),
+	}, cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the capabilities of %s: %v", vmSize, err)
+	}
+	var skus []struct {
+		Name         string `json:"name"`
+		Capabilities []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal([]byte(output), &skus); err != nil {
+		return 0, fmt.Errorf("failed to parse the capabilities of %s: %v", vmSize, err)
+	}
+	for _, sku := range skus {
+		if !strings.EqualFold(sku.Name, vmSize) {
+			continue
+		}
+		for _, capability := range sku.Capabilities {
+			if capability.Name == "GPUs" {
+				n, _ := strconv.Atoi(capability.Value)
+				return n, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// checkPoolDrivers runs nvidia-smi on one node of each NVIDIA GPU pool, preferring a node that advertises no GPUs
+func checkPoolDrivers(azExecutor tools.CommandExecutor, report GPUReport, nodes []Node, subID string, cfg *config.ConfigData) map[string]DriverCheck {
+	providerIDs := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		providerIDs[node.Metadata.Name] = node.Spec.ProviderID
+	}
+	checks := make(map[string]DriverCheck)
+	for _, pool := range report.GPUPools {
+		if pool.GPUResource != resourceNvidiaGPU || len(pool.Nodes) == 0 {
+			continue
+		}
+		candidates := append([]NodeGPUs(nil), pool.Nodes...)
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Allocatable < candidates[j].Allocatable })
+		node := candidates[0].Name
+		match := vmssProviderIDPattern.FindStringSubmatch(providerIDs[node])
+		if match == nil {
+			checks[node] = DriverCheck{Node: node, Output: "the node is not a VMSS instance, so run-command was not used"}
+			continue
+		}
+		output, err := azExecutor.Execute(map[string]interface{}{
+			"command": fmt.Sprintf("az vmss run-command invoke --resource-group %s --name %s --instance-id %s --subscription %s --command-id RunShellScript --scripts \"%s\" -o json",
+				match[1], match[2], match[3], subID, driverCheckScript),
+		}, cfg)
+		if err != nil {
+			checks[node] = DriverCheck{Node: node, Output: fmt.Sprintf("run-command failed: %v", err)}
+			continue
+		}
+		checks[node] = ParseDriverCheck(node, output)
+	}
+	return checks
+}
+
+// ParseDriverCheck reads the stdout and stderr of nvidia-smi from run-command invoke output
+func ParseDriverCheck(node, output string) DriverCheck {
+	var result struct {
+		Value []struct {
+			Message string `json:"message"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil || len(result.Value) == 0 {
+		return DriverCheck{Node: node, Output: "unexpected run-command output"}
+	}
+	message := result.Value[0].Message
+	stdout, stderr := message, ""
+	if i := strings.Index(message, "[stderr]"); i >= 0 {
+		stdout, stderr = message[:i], message[i+len("[stderr]"):]
+	}
+	if i := strings.Index(stdout, "[stdout]"); i >= 0 {
+		stdout = stdout[i+len("[stdout]"):]
+	}
+	stdout = strings.TrimSpace(stdout)
+	stderr = strings.TrimSpace(stderr)
+	if stdout != "" && stderr == "" {
+		return DriverCheck{Node: node, Healthy: true, Output: stdout}
+	}
+	if stderr == "" {
+		stderr = "nvidia-smi printed nothing"
+	}
+	return DriverCheck{Node: node, Output: stderr}
+}
@@ -0,0 +1,48 @@
+package gpu
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterDiagnoseGPUTool registers the diagnose_gpu_workloads tool
+func RegisterDiagnoseGPUTool() mcp.Tool {
+	description := `Diagnose the GPU node pools of an AKS cluster and explain why GPU workloads do not schedule.
+
+Checks:
+- VM size capability: the GPU count of each pool's VM size in the cluster region (az vm list-skus),
+  AMD sizes that advertise amd.com/gpu, MIG profiles and pools that skip the AKS driver install
+- Device plugin: the NVIDIA (or AMD) device plugin DaemonSet, its ready pods, and GPU nodes it cannot
+  run on because of taints, node selectors or cordoning
+- Allocatable nvidia.com/gpu per node compared with the VM size, and nodes that advertise no GPUs
+- Pending pods requesting GPUs, with why each does not schedule (no GPU pool, missing tolerations,
+  requests larger than a node, all GPUs in use, no advertised GPUs)
+- Driver installation (check_drivers=true): runs nvidia-smi on one node per NVIDIA pool with VMSS
+  run-command; requires readwrite or admin access
+
+Uses the current kubeconfig context for the cluster.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", node_pool="gpupool"`
+
+	return mcp.NewTool(
+		"diagnose_gpu_workloads",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("node_pool",
+			mcp.Description("Only diagnose this node pool (default: all node pools)"),
+		),
+		mcp.WithBoolean("check_drivers",
+			mcp.Description("Run nvidia-smi on one node per NVIDIA GPU pool with VMSS run-command (requires readwrite or admin access, default: false)"),
+		),
+	)
+}
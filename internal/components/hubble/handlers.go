@@ -0,0 +1,434 @@
+// Package hubble queries Hubble, the observability layer of Cilium, for dropped flows and aggregates them
+// by drop reason and by source and destination, so a model does not have to compose hubble observe filters
+// and read raw flow dumps.
+package hubble
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const (
+	// defaultWindow and maxWindow bound the time window of a query
+	defaultWindow = 15 * time.Minute
+	maxWindow     = 24 * time.Hour
+	// defaultFlowLimit and maxFlowLimit bound the flows read from Hubble Relay
+	defaultFlowLimit = 2000
+	maxFlowLimit     = 20000
+	// maxReportedFlows bounds the source and destination pairs returned
+	maxReportedFlows = 25
+	// maxPairNodes bounds the nodes listed per source and destination pair
+	maxPairNodes = 5
+)
+
+// dropReasonHints explain the most common Cilium drop reasons
+var dropReasonHints = map[string]string{
+	"POLICY_DENIED": "No network policy allows this traffic while a policy selects the source (egress) or destination (ingress) " +
+		"endpoint; check the NetworkPolicies and CiliumNetworkPolicies of both namespaces",
+	"POLICY_DENY":                               "An explicit deny rule of a CiliumNetworkPolicy or CiliumClusterwideNetworkPolicy matches this traffic",
+	"AUTH_REQUIRED":                             "A policy requires mutual authentication that the endpoints did not complete",
+	"NO_SERVICE":                                "The destination is a service without ready backends; check the service's endpoints and pod readiness",
+	"STALE_OR_UNROUTABLE_IP":                    "The destination IP belongs to no known endpoint, usually a pod that was deleted or a stale DNS or conntrack entry",
+	"UNKNOWN_CONNECTION_TRACKING_STATE":         "Packet of a connection Cilium has no conntrack entry for, often after an agent restart or an asymmetric route",
+	"CT_MAP_INSERTION_FAILED":                   "The connection tracking table is full; consider raising bpf-ct-global-tcp-max and bpf-ct-global-any-max",
+	"INVALID_SOURCE_IP":                         "The source IP does not belong to the sending endpoint, for example spoofed traffic or a secondary IP Cilium does not manage",
+	"UNSUPPORTED_L3_PROTOCOL":                   "Cilium does not forward this layer 3 protocol",
+	"NO_TUNNEL_OR_ENCAPSULATION_ENDPOINT":       "No tunnel endpoint is known for the destination node; check the Cilium agents and the node's pod CIDR",
+	"DENIED_BY_LB_SOURCE_RANGE_CHECK":           "The source is outside loadBalancerSourceRanges of the destination service",
+	"TTL_EXCEEDED":                              "The packet's TTL expired, which points to a routing loop",
+	"UNENCRYPTED_TRAFFIC":                       "Unencrypted traffic was dropped because encryption is enforced between nodes",
+	"INVALID_PACKET_DROPPED":                    "Malformed packet",
+	"FIB_LOOKUP_FAILED":                         "The kernel had no route to the destination",
+	"HOST_NOT_READY":                            "The node's Cilium datapath was not ready yet",
+	"MISSED_TAIL_CALL":                          "A datapath program was missing, usually while the Cilium agent reloads; persistent drops point to an agent problem",
+	"SERVICE_BACKEND_NOT_FOUND":                 "The service backend selected for the connection no longer exists",
+	"NAT_NOT_NEEDED":                            "Packet did not need NAT; usually harmless",
+	"IS_A_CLUSTERIP":                            "Traffic to a ClusterIP from outside the cluster is not routable",
+	"FIRST_LOGICAL_DATAGRAM_FRAGMENT_NOT_FOUND": "A later IP fragment arrived without its first fragment",
+}
+
+// DropReason counts the flows dropped for one reason
+type DropReason struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// DroppedFlow aggregates the dropped flows between one source and destination
+type DroppedFlow struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Protocol    string    `json:"protocol,omitempty"`
+	Port        int       `json:"port,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+	Reason      string    `json:"reason"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Nodes       []string  `json:"nodes,omitempty"`
+	DeniedBy    []string  `json:"deniedBy,omitempty"`
+}
+
+// DroppedFlowsReport is the result returned by the cilium_dropped_flows tool
+type DroppedFlowsReport struct {
+	Window  string   `json:"window"`
+	Filters []string `json:"filters,omitempty"`
+	// FlowsRead is the number of dropped flows read; LimitReached means older flows of the window were not read
+	FlowsRead    int           `json:"flowsRead"`
+	LimitReached bool          `json:"limitReached,omitempty"`
+	Reasons      []DropReason  `json:"reasons"`
+	Flows        []DroppedFlow `json:"flows"`
+	Truncated    int           `json:"truncated,omitempty"`
+	Note         string        `json:"note,omitempty"`
+}
+
+// Endpoint is the subset of a Hubble flow endpoint used for aggregation
+type Endpoint struct {
+	Namespace string   `json:"namespace"`
+	PodName   string   `json:"pod_name"`
+	Labels    []string `json:"labels"`
+	Workloads []struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	} `json:"workloads"`
+}
+
+type port struct {
+	DestinationPort int `json:"destination_port"`
+}
+
+type policy struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+}
+
+// Flow is the subset of a Hubble flow used for aggregation
+type Flow struct {
+	Time    time.Time `json:"time"`
+	Verdict string    `json:"verdict"`
+	IP      struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	} `json:"IP"`
+	L4 struct {
+		TCP    *port     `json:"TCP"`
+		UDP    *port     `json:"UDP"`
+		SCTP   *port     `json:"SCTP"`
+		ICMPv4 *struct{} `json:"ICMPv4"`
+		ICMPv6 *struct{} `json:"ICMPv6"`
+	} `json:"l4"`
+	Source           Endpoint `json:"source"`
+	Destination      Endpoint `json:"destination"`
+	NodeName         string   `json:"node_name"`
+	TrafficDirection string   `json:"traffic_direction"`
+	IsReply          bool     `json:"is_reply"`
+	DropReason       int      `json:"drop_reason"`
+	DropReasonDesc   string   `json:"drop_reason_desc"`
+	EgressDeniedBy   []policy `json:"egress_denied_by"`
+	IngressDeniedBy  []policy `json:"ingress_denied_by"`
+}
+
+// HubbleRunner runs a hubble CLI command given without the leading "hubble" and returns its output
+type HubbleRunner func(args string) (string, error)
+
+// NewHubbleRunner returns a HubbleRunner that runs the hubble CLI with the timeout of cfg
+func NewHubbleRunner(cfg *config.ConfigData) HubbleRunner {
+	return func(args string) (string, error) {
+		return command.NewShellProcess("hubble", cfg.Timeout).Run(args)
+	}
+}
+
+// GetDroppedFlowsHandler returns a handler for the cilium_dropped_flows command
+func GetDroppedFlowsHandler() tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		return HandleDroppedFlows(params, NewHubbleRunner(cfg), cfg)
+	})
+}
+
+// HandleDroppedFlows queries Hubble Relay for the dropped flows matching the parameters and returns them
+// aggregated by drop reason and by source and destination
+func HandleDroppedFlows(params map[string]interface{}, run HubbleRunner, cfg *config.ConfigData) (string, error) {
+	window := defaultWindow
+	if raw, _ := params["since"].(string); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid since parameter '%s': expected a duration such as 15m or 2h", raw)
+		}
+		if d > maxWindow {
+			return "", fmt.Errorf("since parameter %s exceeds the maximum window of %s", raw, maxWindow)
+		}
+		window = d
+	}
+	limit := defaultFlowLimit
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = min(int(n), maxFlowLimit)
+	}
+	filters, err := buildFilters(params, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	args := fmt.Sprintf("observe --verdict DROPPED --since %s --last %d --output jsonpb", window, limit)
+	if len(filters) > 0 {
+		args += " " + strings.Join(filters, " ")
+	}
+	output, err := run(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to query Hubble: %v. The hubble CLI must be installed and reach Hubble Relay, "+
+			"for example through cilium hubble port-forward or the HUBBLE_SERVER environment variable", err)
+	}
+	flows, err := ParseFlows(output)
+	if err != nil {
+		return "", err
+	}
+
+	report := AggregateDrops(allowedFlows(flows, cfg))
+	report.Window = window.String()
+	report.Filters = filters
+	report.FlowsRead = len(flows)
+	report.LimitReached = len(flows) >= limit
+	if report.LimitReached {
+		report.Note = fmt.Sprintf("Only the newest %d dropped flows of the window were read; narrow the filters or the window, or raise limit, to cover all of it.", limit)
+	}
+	if len(report.Flows) > maxReportedFlows {
+		report.Truncated = len(report.Flows) - maxReportedFlows
+		report.Flows = report.Flows[:maxReportedFlows]
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal dropped flows report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// buildFilters validates the source and destination selectors and returns them as hubble observe flags
+func buildFilters(params map[string]interface{}, cfg *config.ConfigData) ([]string, error) {
+	var filters []string
+	restricted := cfg.AllowNamespaces != ""
+	for _, side := range []struct{ param, flag string }{{"source", "from"}, {"destination", "to"}} {
+		namespace, _ := params[side.param+"_namespace"].(string)
+		pod, _ := params[side.param+"_pod"].(string)
+		labels, _ := params[side.param+"_labels"].(string)
+		if namespace != "" {
+			if !common.NamespacePattern.MatchString(namespace) {
+				return nil, fmt.Errorf("invalid %s_namespace parameter: %s", side.param, namespace)
+			}
+			if restricted && !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(namespace) {
+				return nil, fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+			}
+		}
+		switch {
+		case pod == "":
+		case namespace == "":
+			return nil, fmt.Errorf("%s_pod requires %s_namespace", side.param, side.param)
+		case !common.NamePattern.MatchString(pod):
+			return nil, fmt.Errorf("invalid %s_pod parameter: %s", side.param, pod)
+		default:
+			filters = append(filters, fmt.Sprintf("--%s-pod %s/%s", side.flag, namespace, pod))
+		}
+		if namespace != "" && pod == "" {
+			filters = append(filters, fmt.Sprintf("--%s-namespace %s", side.flag, namespace))
+		}
+		if labels != "" {
+			if !common.LabelSelectorPattern.MatchString(labels) {
+				return nil, fmt.Errorf("invalid %s_labels parameter: %s", side.param, labels)
+			}
+			filters = append(filters, fmt.Sprintf("--%s-label %s", side.flag, labels))
+		}
+	}
+	if raw, ok := params["port"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n > 65535 || n != float64(int(n)) {
+			return nil, fmt.Errorf("invalid port: expected a number between 1 and 65535")
+		}
+		filters = append(filters, fmt.Sprintf("--to-port %d", int(n)))
+	}
+	return filters, nil
+}
+
+// ParseFlows decodes hubble observe --output jsonpb output, one GetFlowsResponse per line.
+// Lines that are not flows, such as lost event notices, are skipped.
+func ParseFlows(output string) ([]Flow, error) {
+	var flows []Flow
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var response struct {
+			Flow *Flow `json:"flow"`
+		}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			return nil, fmt.Errorf("failed to parse hubble output: %v", err)
+		}
+		if response.Flow != nil {
+			flows = append(flows, *response.Flow)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hubble output: %v", err)
+	}
+	return flows, nil
+}
+
+// allowedFlows keeps the flows with an endpoint in the allowed namespaces when the server is restricted to namespaces
+func allowedFlows(flows []Flow, cfg *config.ConfigData) []Flow {
+	if cfg.AllowNamespaces == "" {
+		return flows
+	}
+	secConfig := k8s.ConvertConfig(cfg).SecurityConfig
+	var allowed []Flow
+	for _, flow := range flows {
+		if (flow.Source.Namespace != "" && secConfig.IsNamespaceAllowed(flow.Source.Namespace)) ||
+			(flow.Destination.Namespace != "" && secConfig.IsNamespaceAllowed(flow.Destination.Namespace)) {
+			allowed = append(allowed, flow)
+		}
+	}
+	return allowed
+}
+
+// AggregateDrops counts dropped flows by reason and groups them by source, destination, port and reason,
+// most frequent first
+func AggregateDrops(flows []Flow) DroppedFlowsReport {
+	report := DroppedFlowsReport{Reasons: []DropReason{}, Flows: []DroppedFlow{}}
+	reasons := make(map[string]int)
+	pairs := make(map[string]*DroppedFlow)
+	for _, flow := range flows {
+		if flow.Verdict != "" && flow.Verdict != "DROPPED" {
+			continue
+		}
+		reason := flow.DropReasonDesc
+		if reason == "" {
+			reason = fmt.Sprintf("drop reason %d", flow.DropReason)
+		}
+		reasons[reason]++
+
+		protocol, port := FlowPort(flow)
+		entry := DroppedFlow{
+			Source:      describeEndpoint(flow.Source, flow.IP.Source),
+			Destination: describeEndpoint(flow.Destination, flow.IP.Destination),
+			Protocol:    protocol,
+			Port:        port,
+			Direction:   flow.TrafficDirection,
+			Reason:      reason,
+		}
+		key := fmt.Sprintf("%s|%s|%s|%d|%s|%s", entry.Source, entry.Destination, entry.Protocol, entry.Port, entry.Direction, entry.Reason)
+		pair, ok := pairs[key]
+		if !ok {
+			entry.FirstSeen, entry.LastSeen = flow.Time, flow.Time
+			pair = &entry
+			pairs[key] = pair
+		}
+		pair.Count++
+		if flow.Time.Before(pair.FirstSeen) {
+			pair.FirstSeen = flow.Time
+		}
+		if flow.Time.After(pair.LastSeen) {
+			pair.LastSeen = flow.Time
+		}
+		if flow.NodeName != "" && len(pair.Nodes) < maxPairNodes && !slices.Contains(pair.Nodes, flow.NodeName) {
+			pair.Nodes = append(pair.Nodes, flow.NodeName)
+		}
+		for _, p := range append(flow.EgressDeniedBy, flow.IngressDeniedBy...) {
+			if name := describePolicy(p); !slices.Contains(pair.DeniedBy, name) {
+				pair.DeniedBy = append(pair.DeniedBy, name)
+			}
+		}
+	}
+
+	for reason, count := range reasons {
+		report.Reasons = append(report.Reasons, DropReason{Reason: reason, Count: count, Hint: dropReasonHints[reason]})
+	}
+	sort.Slice(report.Reasons, func(i, j int) bool {
+		a, b := report.Reasons[i], report.Reasons[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Reason < b.Reason
+	})
+	for _, pair := range pairs {
+		sort.Strings(pair.Nodes)
+		report.Flows = append(report.Flows, *pair)
+	}
+	sort.Slice(report.Flows, func(i, j int) bool {
+		a, b := report.Flows[i], report.Flows[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		return a.Source+a.Destination < b.Source+b.Destination
+	})
+	return report
+}
+
+// describeEndpoint names an endpoint by namespace and workload (or pod) name, by its reserved identity
+// such as world or host, or by IP
+func describeEndpoint(ep Endpoint, ip string) string {
+	switch {
+	case ep.Namespace != "" && len(ep.Workloads) > 0 && ep.Workloads[0].Name != "":
+		return ep.Namespace + "/" + ep.Workloads[0].Name
+	case ep.Namespace != "" && ep.PodName != "":
+		return ep.Namespace + "/" + ep.PodName
+	}
+	for _, label := range ep.Labels {
+		if identity, ok := strings.CutPrefix(label, "reserved:"); ok {
+			if ip != "" {
+				return identity + " (" + ip + ")"
+			}
+			return identity
+		}
+	}
+	if ip != "" {
+		return ip
+	}
+	return "unknown"
+}
+
+// FlowPort returns the layer 4 protocol and destination port of a flow
+func FlowPort(flow Flow) (string, int) {
+	switch {
+	case flow.L4.TCP != nil:
+		return "TCP", flow.L4.TCP.DestinationPort
+	case flow.L4.UDP != nil:
+		return "UDP", flow.L4.UDP.DestinationPort
+	case flow.L4.SCTP != nil:
+		return "SCTP", flow.L4.SCTP.DestinationPort
+	case flow.L4.ICMPv4 != nil:
+		return "ICMPv4", 0
+	case flow.L4.ICMPv6 != nil:
+		return "ICMPv6", 0
+	}
+	return "", 0
+}
+
+func describePolicy(p policy) string {
+	name := p.Name
+	if p.Namespace != "" {
+		name = p.Namespace + "/" + name
+	}
+	if p.Kind != "" {
+		name = p.Kind + " " + name
+	}
+	return name
+}
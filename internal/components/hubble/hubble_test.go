@@ -0,0 +1,138 @@
+package hubble
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// fakeHubble returns canned hubble output and records the arguments it was run with
+type fakeHubble struct {
+	output string
+	err    error
+	args   []string
+}
+
+func (f *fakeHubble) run(args string) (string, error) {
+	f.args = append(f.args, args)
+	return f.output, f.err
+}
+
+const droppedFlows = `{"flow":{"time":"2025-06-10T12:00:01Z","verdict":"DROPPED","IP":{"source":"10.244.1.5","destination":"10.244.2.7"},"l4":{"TCP":{"source_port":51234,"destination_port":5432}},"source":{"namespace":"shop","pod_name":"web-7d9f-abcde","labels":["k8s:app=web"],"workloads":[{"name":"web","kind":"Deployment"}]},"destination":{"namespace":"data","pod_name":"db-0","labels":["k8s:app=db"]},"node_name":"aks-nodepool1-1","traffic_direction":"INGRESS","drop_reason":133,"drop_reason_desc":"POLICY_DENIED","ingress_denied_by":[{"name":"db-ingress","namespace":"data","kind":"CiliumNetworkPolicy"}]},"node_name":"aks-nodepool1-1","time":"2025-06-10T12:00:01Z"}
+{"flow":{"time":"2025-06-10T12:03:00Z","verdict":"DROPPED","IP":{"source":"10.244.1.9","destination":"10.244.2.7"},"l4":{"TCP":{"source_port":40000,"destination_port":5432}},"source":{"namespace":"shop","pod_name":"web-7d9f-fghij","workloads":[{"name":"web","kind":"Deployment"}]},"destination":{"namespace":"data","pod_name":"db-0"},"node_name":"aks-nodepool1-2","traffic_direction":"INGRESS","drop_reason":133,"drop_reason_desc":"POLICY_DENIED"},"node_name":"aks-nodepool1-2","time":"2025-06-10T12:03:00Z"}
+{"lost_events":{"source":"HUBBLE_RING_BUFFER","num_events_lost":3}}
+
+{"flow":{"time":"2025-06-10T12:02:00Z","verdict":"DROPPED","IP":{"source":"10.244.1.5","destination":"20.1.2.3"},"l4":{"UDP":{"source_port":5353,"destination_port":53}},"source":{"namespace":"shop","pod_name":"worker-1"},"destination":{"labels":["reserved:world"]},"node_name":"aks-nodepool1-1","traffic_direction":"EGRESS","drop_reason_desc":"STALE_OR_UNROUTABLE_IP"},"node_name":"aks-nodepool1-1","time":"2025-06-10T12:02:00Z"}
+`
+
+func runDroppedFlows(t *testing.T, params map[string]interface{}, fake *fakeHubble, cfg *config.ConfigData) DroppedFlowsReport {
+	t.Helper()
+	result, err := HandleDroppedFlows(params, fake.run, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report DroppedFlowsReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+func TestRegisterDroppedFlowsTool(t *testing.T) {
+	tool := RegisterDroppedFlowsTool()
+	if tool.Name != "cilium_dropped_flows" {
+		t.Errorf("Expected tool name 'cilium_dropped_flows', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 0 {
+		t.Errorf("Expected no required parameters, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestDroppedFlowsAggregation(t *testing.T) {
+	fake := &fakeHubble{output: droppedFlows}
+	params := map[string]interface{}{"since": "30m", "source_namespace": "shop", "destination_labels": "app=db", "port": float64(5432)}
+	report := runDroppedFlows(t, params, fake, config.NewConfig())
+
+	want := "observe --verdict DROPPED --since 30m0s --last 2000 --output jsonpb --from-namespace shop --to-label app=db --to-port 5432"
+	if len(fake.args) != 1 || fake.args[0] != want {
+		t.Errorf("Expected hubble arguments %q, got %v", want, fake.args)
+	}
+	if report.Window != "30m0s" || report.FlowsRead != 3 || report.LimitReached {
+		t.Errorf("Unexpected report header %+v", report)
+	}
+	if len(report.Reasons) != 2 || report.Reasons[0].Reason != "POLICY_DENIED" || report.Reasons[0].Count != 2 || report.Reasons[0].Hint == "" {
+		t.Fatalf("Unexpected reasons %+v", report.Reasons)
+	}
+
+	if len(report.Flows) != 2 {
+		t.Fatalf("Expected 2 source and destination pairs, got %+v", report.Flows)
+	}
+	db := report.Flows[0]
+	if db.Source != "shop/web" || db.Destination != "data/db-0" || db.Protocol != "TCP" || db.Port != 5432 || db.Count != 2 {
+		t.Errorf("Expected the web replicas grouped under their deployment, got %+v", db)
+	}
+	if db.FirstSeen.Format("15:04:05") != "12:00:01" || db.LastSeen.Format("15:04:05") != "12:03:00" {
+		t.Errorf("Unexpected first and last seen %s and %s", db.FirstSeen, db.LastSeen)
+	}
+	if strings.Join(db.Nodes, ",") != "aks-nodepool1-1,aks-nodepool1-2" || strings.Join(db.DeniedBy, ",") != "CiliumNetworkPolicy data/db-ingress" {
+		t.Errorf("Unexpected nodes %v and policies %v", db.Nodes, db.DeniedBy)
+	}
+	if dns := report.Flows[1]; dns.Destination != "world (20.1.2.3)" || dns.Protocol != "UDP" || dns.Direction != "EGRESS" {
+		t.Errorf("Unexpected world flow %+v", dns)
+	}
+}
+
+func TestDroppedFlowsAllowedNamespaces(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "shop"
+	fake := &fakeHubble{output: droppedFlows}
+	report := runDroppedFlows(t, map[string]interface{}{"source_namespace": "shop", "source_pod": "worker", "limit": float64(3)}, fake, cfg)
+
+	if !strings.HasSuffix(fake.args[0], "--last 3 --output jsonpb --from-pod shop/worker") {
+		t.Errorf("Unexpected hubble arguments %q", fake.args[0])
+	}
+	if !report.LimitReached || report.Note == "" {
+		t.Errorf("Expected the flow limit to be reported, got %+v", report)
+	}
+
+	cfg.AllowNamespaces = "data"
+	report = runDroppedFlows(t, map[string]interface{}{}, &fakeHubble{output: droppedFlows}, cfg)
+	if len(report.Flows) != 1 || report.Flows[0].Destination != "data/db-0" {
+		t.Errorf("Expected only flows touching allowed namespaces, got %+v", report.Flows)
+	}
+}
+
+func TestDroppedFlowsInvalidParameters(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "shop"
+	tests := []struct {
+		params map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"since": "yesterday"}, "invalid since parameter"},
+		{map[string]interface{}{"since": "48h"}, "exceeds the maximum window"},
+		{map[string]interface{}{"source_pod": "web"}, "source_pod requires source_namespace"},
+		{map[string]interface{}{"destination_namespace": "data"}, "access to namespace 'data' is denied"},
+		{map[string]interface{}{"source_labels": "app in (web)"}, "invalid source_labels parameter"},
+		{map[string]interface{}{"port": float64(70000)}, "invalid port"},
+		{map[string]interface{}{"limit": float64(0)}, "invalid limit"},
+	}
+	for _, tt := range tests {
+		fake := &fakeHubble{}
+		_, err := HandleDroppedFlows(tt.params, fake.run, cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %v, got %v", tt.want, tt.params, err)
+		}
+		if len(fake.args) != 0 {
+			t.Errorf("Expected hubble not to run for %v", tt.params)
+		}
+	}
+
+	_, err := HandleDroppedFlows(map[string]interface{}{}, (&fakeHubble{err: errors.New("connection refused")}).run, config.NewConfig())
+	if err == nil || !strings.Contains(err.Error(), "Hubble Relay") {
+		t.Errorf("Expected a Hubble Relay hint, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package hubble
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterDroppedFlowsTool registers the cilium_dropped_flows tool
+func RegisterDroppedFlowsTool() mcp.Tool {
+	description := fmt.Sprintf(`Query Hubble for the flows Cilium dropped over a time window and aggregate them by drop reason and by
+source and destination, instead of running raw hubble observe commands.
+
+Source and destination are selected by namespace, pod name prefix or label selector; without any filter all
+dropped flows of the window are read. Returned:
+- Drop reasons with their counts, most frequent first, and what each usually means (policy denied, no
+  service backend, stale or unroutable IP, ...)
+- The source and destination pairs that were dropped, with protocol, destination port, traffic direction,
+  reason, count, first and last time seen, the nodes that dropped them and the policies that denied them when
+  Hubble reports them. Pods of the same workload are grouped under the workload name.
+
+Reads at most limit flows (default %d), the newest first. Requires the hubble CLI and access to Hubble Relay,
+for example through cilium hubble port-forward or the HUBBLE_SERVER environment variable.`, defaultFlowLimit)
+
+	return mcp.NewTool(
+		"cilium_dropped_flows",
+		mcp.WithDescription(description),
+		mcp.WithString("since",
+			mcp.Description(fmt.Sprintf("Time window to query, as a duration such as 15m or 2h (default: %s, at most %s)", defaultWindow, maxWindow)),
+		),
+		mcp.WithString("source_namespace",
+			mcp.Description("Only flows from pods in this namespace"),
+		),
+		mcp.WithString("source_pod",
+			mcp.Description("Only flows from pods whose name starts with this prefix (requires source_namespace)"),
+		),
+		mcp.WithString("source_labels",
+			mcp.Description("Only flows from endpoints matching this equality-based label selector, e.g. app=web"),
+		),
+		mcp.WithString("destination_namespace",
+			mcp.Description("Only flows to pods in this namespace"),
+		),
+		mcp.WithString("destination_pod",
+			mcp.Description("Only flows to pods whose name starts with this prefix (requires destination_namespace)"),
+		),
+		mcp.WithString("destination_labels",
+			mcp.Description("Only flows to endpoints matching this equality-based label selector, e.g. app=db"),
+		),
+		mcp.WithNumber("port",
+			mcp.Description("Only flows to this destination port"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of dropped flows to read (default: %d, at most %d)", defaultFlowLimit, maxFlowLimit)),
+		),
+	)
+}
@@ -0,0 +1,665 @@
+package identity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	clientIDAnnotation = "azure.workload.identity/client-id"
+	useLabel           = "azure.workload.identity/use"
+	// tokenVolumeName is the projected token volume added by the workload identity webhook
+	tokenVolumeName = "azure-identity-token"
+	// defaultTokenFile is where the webhook mounts the projected token
+	defaultTokenFile = "/var/run/secrets/azure/tokens/azure-identity-token"
+	// tokenExchangeTimeout bounds the live token request to Microsoft Entra ID
+	tokenExchangeTimeout = 15 * time.Second
+)
+
+// Check statuses of a workload identity diagnosis
+const (
+	CheckPass    = "pass"
+	CheckFail    = "fail"
+	CheckWarn    = "warn"
+	CheckSkipped = "skipped"
+)
+
+// Links of the workload identity chain, in the order a token flows through them
+const (
+	LinkClusterOIDC         = "cluster_oidc_issuer"
+	LinkServiceAccount      = "service_account_annotation"
+	LinkPodLabel            = "pod_label"
+	LinkWebhookInjection    = "webhook_injection"
+	LinkProjectedToken      = "projected_token"
+	LinkManagedIdentity     = "managed_identity"
+	LinkFederatedCredential = "federated_credential"
+	LinkTokenClaims         = "token_claims"
+	LinkTokenExchange       = "token_exchange"
+)
+
+var (
+	guidPattern      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	tokenPathPattern = regexp.MustCompile(`^/[A-Za-z0-9._/-]+$`)
+	aadstsPattern    = regexp.MustCompile(`AADSTS\d+`)
+)
+
+// injectedEnvVars are set on each container by the workload identity webhook
+var injectedEnvVars = []string{"AZURE_CLIENT_ID", "AZURE_TENANT_ID", "AZURE_FEDERATED_TOKEN_FILE", "AZURE_AUTHORITY_HOST"}
+
+// aadstsLinks maps Microsoft Entra ID token exchange errors to the link they point at
+var aadstsLinks = map[string]struct{ link, hint string }{
+	"AADSTS70021":  {LinkFederatedCredential, "no federated credential matches the token's issuer, subject and audience"},
+	"AADSTS700211": {LinkFederatedCredential, "no federated credential matches the token's issuer; compare the credential issuer with the cluster OIDC issuer URL, including the trailing slash"},
+	"AADSTS700213": {LinkFederatedCredential, "no federated credential matches the token's subject; check the namespace and service account name in the credential subject"},
+	"AADSTS700212": {LinkFederatedCredential, "no federated credential matches the token's audience; the credential audience must be " + workloadIdentityAudience},
+	"AADSTS700016": {LinkServiceAccount, "the client ID is not known in the tenant; check the client-id annotation and the tenant"},
+	"AADSTS700024": {LinkProjectedToken, "the projected token is expired or not yet valid; the kubelet should refresh it, so check node clock skew"},
+	"AADSTS90002":  {LinkServiceAccount, "the tenant was not found; check AZURE_TENANT_ID"},
+	"AADSTS50166":  {LinkClusterOIDC, "Microsoft Entra ID could not fetch the issuer's signing keys; check that the OIDC issuer URL is reachable"},
+}
+
+// WorkloadIdentityCheck is the result of checking one link of the workload identity chain
+type WorkloadIdentityCheck struct {
+	Link        string `json:"link"`
+	Status      string `json:"status"`
+	Detail      string `json:"detail"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// WorkloadIdentityDiagnosis is the result returned by the diagnose_workload_identity tool
+type WorkloadIdentityDiagnosis struct {
+	Namespace      string                  `json:"namespace"`
+	ServiceAccount string                  `json:"serviceAccount"`
+	Pod            string                  `json:"pod,omitempty"`
+	ClientID       string                  `json:"clientId,omitempty"`
+	IssuerURL      string                  `json:"issuerUrl,omitempty"`
+	Checks         []WorkloadIdentityCheck `json:"checks"`
+	// BrokenLink is the first link that failed, where the chain needs fixing
+	BrokenLink string `json:"brokenLink,omitempty"`
+	Healthy    bool   `json:"healthy"`
+}
+
+// TokenExchanger exchanges a projected service account token for a Microsoft Entra ID token.
+// It returns the error description from Entra ID when the exchange is rejected.
+type TokenExchanger func(authorityHost, tenantID, clientID, assertion, scope string) error
+
+// GetDiagnoseWorkloadIdentityHandler returns a handler for the diagnose_workload_identity command
+func GetDiagnoseWorkloadIdentityHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleDiagnoseWorkloadIdentity(params, azcli.NewExecutor(), k8s.WrapK8sExecutor(kubectl.NewExecutor()), ExchangeFederatedToken, cfg)
+	})
+}
+
+// wiTarget holds what the diagnosis learns about the workload as it walks the chain
+type wiTarget struct {
+	subID, rg, cluster  string
+	namespace, sa, pod  string
+	clientID, tenantID  string
+	issuerURL           string
+	tokenContainer      string
+	tokenFile           string
+	identityName        string
+	identityRG          string
+	podServiceAccount   string
+	podEnvClientID      string
+	podEnvAuthorityHost string
+}
+
+// HandleDiagnoseWorkloadIdentity checks each link of a workload's federated identity setup and reports the first broken one
+func HandleDiagnoseWorkloadIdentity(params map[string]interface{}, azExecutor, kubectlExecutor tools.CommandExecutor, exchange TokenExchanger, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	t := &wiTarget{subID: subID, rg: rg, cluster: clusterName}
+	t.namespace, _ = params["namespace"].(string)
+	t.pod, _ = params["pod"].(string)
+	t.sa, _ = params["service_account"].(string)
+	if !dnsLabelPattern.MatchString(t.namespace) {
+		return "", fmt.Errorf("missing or invalid namespace parameter")
+	}
+	if t.pod == "" && t.sa == "" {
+		return "", fmt.Errorf("either pod or service_account is required")
+	}
+	if t.pod != "" && !dnsLabelPattern.MatchString(t.pod) {
+		return "", fmt.Errorf("invalid pod parameter: %s", t.pod)
+	}
+	if t.sa != "" && !dnsLabelPattern.MatchString(t.sa) {
+		return "", fmt.Errorf("invalid service_account parameter: %s", t.sa)
+	}
+	if !cfg.SecurityConfig.IsNamespaceAllowed(t.namespace) {
+		return "", fmt.Errorf("access to namespace '%s' is denied by security configuration", t.namespace)
+	}
+	testExchange, _ := params["test_token_exchange"].(string)
+	if testExchange == "true" && cfg.AccessLevel != "admin" {
+		return "", fmt.Errorf("test_token_exchange reads the pod's token with kubectl exec and requires the admin access level")
+	}
+
+	var checks []WorkloadIdentityCheck
+	checks = append(checks, checkClusterOIDC(t, azExecutor, cfg))
+	if t.pod != "" {
+		checks = append(checks, checkPod(t, kubectlExecutor, cfg)...)
+	}
+	checks = append(checks, checkServiceAccount(t, kubectlExecutor, cfg))
+	identityCheck := checkManagedIdentity(t, azExecutor, cfg)
+	checks = append(checks, identityCheck)
+	if identityCheck.Status == CheckPass {
+		checks = append(checks, checkFederatedCredential(t, azExecutor, cfg))
+	} else {
+		checks = append(checks, WorkloadIdentityCheck{Link: LinkFederatedCredential, Status: CheckSkipped, Detail: "the managed identity could not be resolved"})
+	}
+	checks = append(checks, checkTokenExchange(t, testExchange == "true", kubectlExecutor, exchange, cfg)...)
+
+	diagnosis := WorkloadIdentityDiagnosis{
+		Namespace:      t.namespace,
+		ServiceAccount: t.sa,
+		Pod:            t.pod,
+		ClientID:       t.clientID,
+		IssuerURL:      t.issuerURL,
+		Checks:         checks,
+	}
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			diagnosis.BrokenLink = check.Link
+			break
+		}
+	}
+	diagnosis.Healthy = diagnosis.BrokenLink == ""
+
+	resultJSON, err := json.MarshalIndent(diagnosis, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal workload identity diagnosis to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// subject returns the federated credential subject of the workload's service account
+func (t *wiTarget) subject() string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", t.namespace, t.sa)
+}
+
+// checkClusterOIDC checks that the cluster publishes an OIDC issuer and runs the workload identity webhook
+func checkClusterOIDC(t *wiTarget, executor tools.CommandExecutor, cfg *config.ConfigData) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkClusterOIDC}
+	state, err := getClusterOIDCState(executor, WorkloadIdentityRequest{SubscriptionID: t.subID, ClusterResourceGroup: t.rg, ClusterName: t.cluster}, cfg)
+	if err != nil {
+		check.Status, check.Detail = CheckFail, err.Error()
+		return check
+	}
+	t.issuerURL = state.IssuerURL
+	fix := fmt.Sprintf("az aks update --resource-group %s --name %s --subscription %s --enable-oidc-issuer --enable-workload-identity", t.rg, t.cluster, t.subID)
+	switch {
+	case !state.OIDCIssuerEnabled || state.IssuerURL == "":
+		check.Status, check.Detail, check.Remediation = CheckFail, "the OIDC issuer is not enabled on the cluster", fix
+	case !state.WorkloadIdentityEnabled:
+		check.Status, check.Detail, check.Remediation = CheckFail, "workload identity is not enabled, so the webhook does not inject tokens into pods", fix
+	default:
+		check.Status, check.Detail = CheckPass, "OIDC issuer "+state.IssuerURL+" and workload identity are enabled"
+	}
+	return check
+}
+
+// checkPod checks the pod label, the webhook injected environment and the projected token volume
+func checkPod(t *wiTarget, executor tools.CommandExecutor, cfg *config.ConfigData) []WorkloadIdentityCheck {
+	output, err := executor.Execute(map[string]interface{}{"command": fmt.Sprintf("get pod %s --namespace %s -o json", t.pod, t.namespace)}, cfg)
+	if err != nil {
+		return []WorkloadIdentityCheck{{Link: LinkPodLabel, Status: CheckFail, Detail: fmt.Sprintf("failed to get pod %s: %v: %s", t.pod, err, strings.TrimSpace(output))}}
+	}
+	pod, err := parseWIPod(output)
+	if err != nil {
+		return []WorkloadIdentityCheck{{Link: LinkPodLabel, Status: CheckFail, Detail: err.Error()}}
+	}
+	return evaluateWIPod(t, pod)
+}
+
+// wiPod is the subset of a pod needed to check workload identity injection
+type wiPod struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		ServiceAccountName string        `json:"serviceAccountName"`
+		Containers         []wiContainer `json:"containers"`
+		Volumes            []struct {
+			Name      string `json:"name"`
+			Projected *struct {
+				Sources []struct {
+					ServiceAccountToken *struct {
+						Audience string `json:"audience"`
+						Path     string `json:"path"`
+					} `json:"serviceAccountToken"`
+				} `json:"sources"`
+			} `json:"projected"`
+		} `json:"volumes"`
+	} `json:"spec"`
+}
+
+type wiContainer struct {
+	Name string `json:"name"`
+	Env  []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env"`
+	VolumeMounts []struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+	} `json:"volumeMounts"`
+}
+
+func parseWIPod(output string) (wiPod, error) {
+	var pod wiPod
+	if err := json.Unmarshal([]byte(output), &pod); err != nil {
+		return pod, fmt.Errorf("failed to parse pod: %v", err)
+	}
+	return pod, nil
+}
+
+func (c wiContainer) env(name string) string {
+	for _, env := range c.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// evaluateWIPod checks a pod against what the workload identity webhook should have done to it
+func evaluateWIPod(t *wiTarget, pod wiPod) []WorkloadIdentityCheck {
+	t.podServiceAccount = pod.Spec.ServiceAccountName
+	if t.podServiceAccount == "" {
+		t.podServiceAccount = "default"
+	}
+	if t.sa == "" {
+		t.sa = t.podServiceAccount
+	}
+
+	var checks []WorkloadIdentityCheck
+	label := WorkloadIdentityCheck{Link: LinkPodLabel, Status: CheckPass, Detail: fmt.Sprintf("pod is labelled %s=true and uses service account %s", useLabel, t.podServiceAccount)}
+	if pod.Metadata.Labels[useLabel] != "true" {
+		label.Status = CheckFail
+		label.Detail = fmt.Sprintf("pod is missing the label %s=true, so the webhook skipped it", useLabel)
+		label.Remediation = fmt.Sprintf("add the label %s: \"true\" to the pod template and recreate the pod", useLabel)
+	} else if t.podServiceAccount != t.sa {
+		label.Status = CheckFail
+		label.Detail = fmt.Sprintf("pod uses service account %s, not %s", t.podServiceAccount, t.sa)
+		label.Remediation = "set serviceAccountName in the pod template to the annotated service account"
+	}
+	checks = append(checks, label)
+
+	injection := WorkloadIdentityCheck{Link: LinkWebhookInjection}
+	var injected, missing []string
+	for _, container := range pod.Spec.Containers {
+		var absent []string
+		for _, name := range injectedEnvVars {
+			if container.env(name) == "" {
+				absent = append(absent, name)
+			}
+		}
+		if len(absent) > 0 {
+			missing = append(missing, fmt.Sprintf("%s (missing %s)", container.Name, strings.Join(absent, ", ")))
+			continue
+		}
+		injected = append(injected, container.Name)
+		if t.tokenContainer == "" {
+			t.tokenContainer = container.Name
+			t.tokenFile = container.env("AZURE_FEDERATED_TOKEN_FILE")
+			t.podEnvClientID = container.env("AZURE_CLIENT_ID")
+			t.tenantID = container.env("AZURE_TENANT_ID")
+			t.podEnvAuthorityHost = container.env("AZURE_AUTHORITY_HOST")
+		}
+	}
+	switch {
+	case len(injected) == 0:
+		injection.Status = CheckFail
+		injection.Detail = "no container has the environment injected by the workload identity webhook: " + strings.Join(missing, "; ")
+		injection.Remediation = "check that the webhook pods in kube-system are running, then recreate the pod; the webhook only mutates pods at creation"
+	case len(missing) > 0:
+		injection.Status = CheckWarn
+		injection.Detail = fmt.Sprintf("injected into %s but not into %s; containers listed in the azure.workload.identity/skip-containers annotation are skipped on purpose", strings.Join(injected, ", "), strings.Join(missing, "; "))
+	default:
+		injection.Status = CheckPass
+		injection.Detail = "webhook environment injected into " + strings.Join(injected, ", ")
+	}
+	checks = append(checks, injection)
+
+	token := WorkloadIdentityCheck{Link: LinkProjectedToken}
+	audience, found := "", false
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name != tokenVolumeName || volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken != nil {
+				found, audience = true, source.ServiceAccountToken.Audience
+			}
+		}
+	}
+	mounted := t.tokenContainer == ""
+	for _, container := range pod.Spec.Containers {
+		if container.Name != t.tokenContainer {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == tokenVolumeName {
+				mounted = true
+			}
+		}
+	}
+	switch {
+	case !found:
+		token.Status, token.Detail = CheckFail, fmt.Sprintf("pod has no projected service account token volume %s", tokenVolumeName)
+		token.Remediation = "recreate the pod so the webhook can add the token volume"
+	case audience != workloadIdentityAudience:
+		token.Status, token.Detail = CheckFail, fmt.Sprintf("projected token audience is %q, expected %s", audience, workloadIdentityAudience)
+	case !mounted:
+		token.Status, token.Detail = CheckFail, fmt.Sprintf("container %s does not mount the %s volume", t.tokenContainer, tokenVolumeName)
+	default:
+		token.Status, token.Detail = CheckPass, fmt.Sprintf("projected token with audience %s is mounted", audience)
+	}
+	checks = append(checks, token)
+	return checks
+}
+
+// checkServiceAccount checks that the service account names the managed identity to use
+func checkServiceAccount(t *wiTarget, executor tools.CommandExecutor, cfg *config.ConfigData) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkServiceAccount}
+	if t.sa == "" {
+		check.Status, check.Detail = CheckSkipped, "the pod's service account is unknown"
+		return check
+	}
+	output, err := executor.Execute(map[string]interface{}{"command": fmt.Sprintf("get serviceaccount %s --namespace %s -o json", t.sa, t.namespace)}, cfg)
+	if err != nil {
+		check.Status, check.Detail = CheckFail, fmt.Sprintf("failed to get service account %s: %v: %s", t.sa, err, strings.TrimSpace(output))
+		return check
+	}
+	var sa struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(output), &sa); err != nil {
+		check.Status, check.Detail = CheckFail, fmt.Sprintf("failed to parse service account: %v", err)
+		return check
+	}
+
+	annotated := sa.Metadata.Annotations[clientIDAnnotation]
+	switch {
+	case annotated == "":
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("service account %s has no %s annotation", t.sa, clientIDAnnotation)
+		check.Remediation = fmt.Sprintf("kubectl annotate serviceaccount %s --namespace %s %s=<client ID of the managed identity>", t.sa, t.namespace, clientIDAnnotation)
+	case !guidPattern.MatchString(annotated):
+		check.Status, check.Detail = CheckFail, fmt.Sprintf("the %s annotation %q is not a client ID", clientIDAnnotation, annotated)
+	case t.podEnvClientID != "" && t.podEnvClientID != annotated:
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("the pod was injected with client ID %s but the service account now names %s", t.podEnvClientID, annotated)
+		check.Remediation = "restart the pod so the webhook injects the current client ID"
+	default:
+		check.Status, check.Detail = CheckPass, fmt.Sprintf("service account %s is annotated with client ID %s", t.sa, annotated)
+	}
+	if guidPattern.MatchString(annotated) {
+		t.clientID = annotated
+	}
+	return check
+}
+
+// checkManagedIdentity finds the managed identity with the annotated client ID in the subscription
+func checkManagedIdentity(t *wiTarget, executor tools.CommandExecutor, cfg *config.ConfigData) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkManagedIdentity}
+	if t.clientID == "" {
+		check.Status, check.Detail = CheckSkipped, "the client ID is unknown"
+		return check
+	}
+	cmd := fmt.Sprintf("az identity list --subscription %s --query \"[?clientId=='%s']\" --output json", t.subID, t.clientID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		check.Status, check.Detail = CheckWarn, fmt.Sprintf("failed to list managed identities: %v", err)
+		return check
+	}
+	var identities []struct {
+		Name          string `json:"name"`
+		ResourceGroup string `json:"resourceGroup"`
+	}
+	if err := json.Unmarshal([]byte(output), &identities); err != nil {
+		check.Status, check.Detail = CheckWarn, fmt.Sprintf("failed to parse managed identities: %v", err)
+		return check
+	}
+	if len(identities) == 0 {
+		// The identity may live in another subscription, or be an app registration
+		check.Status = CheckWarn
+		check.Detail = fmt.Sprintf("no user-assigned managed identity with client ID %s in subscription %s; if it lives elsewhere or is an app registration, check its federated credentials there", t.clientID, t.subID)
+		return check
+	}
+	t.identityName, t.identityRG = identities[0].Name, identities[0].ResourceGroup
+	check.Status, check.Detail = CheckPass, fmt.Sprintf("client ID belongs to managed identity %s in resource group %s", t.identityName, t.identityRG)
+	return check
+}
+
+// FederatedCredential is a federated identity credential of a managed identity
+type FederatedCredential struct {
+	Name      string   `json:"name"`
+	Issuer    string   `json:"issuer"`
+	Subject   string   `json:"subject"`
+	Audiences []string `json:"audiences"`
+}
+
+// checkFederatedCredential checks that a federated credential trusts the cluster issuer for the service account subject
+func checkFederatedCredential(t *wiTarget, executor tools.CommandExecutor, cfg *config.ConfigData) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkFederatedCredential}
+	cmd := fmt.Sprintf("az identity federated-credential list --identity-name %s --resource-group %s --subscription %s --output json", t.identityName, t.identityRG, t.subID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		check.Status, check.Detail = CheckFail, fmt.Sprintf("failed to list federated credentials: %v", err)
+		return check
+	}
+	var credentials []FederatedCredential
+	if err := json.Unmarshal([]byte(output), &credentials); err != nil {
+		check.Status, check.Detail = CheckFail, fmt.Sprintf("failed to parse federated credentials: %v", err)
+		return check
+	}
+	return MatchFederatedCredential(credentials, t.issuerURL, t.subject(), fmt.Sprintf(
+		"az identity federated-credential create --name %s-%s-%s --identity-name %s --resource-group %s --subscription %s --issuer %s --subject %s --audience %s",
+		t.cluster, t.namespace, t.sa, t.identityName, t.identityRG, t.subID, t.issuerURL, t.subject(), workloadIdentityAudience))
+}
+
+// MatchFederatedCredential looks for a credential matching the issuer, subject and audience exactly,
+// as Microsoft Entra ID does, and otherwise explains the closest near miss
+func MatchFederatedCredential(credentials []FederatedCredential, issuer, subject, createCommand string) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkFederatedCredential, Status: CheckFail, Remediation: createCommand}
+	var nearMisses []string
+	for _, cred := range credentials {
+		issuerMatch := cred.Issuer == issuer
+		subjectMatch := cred.Subject == subject
+		audienceMatch := slices.Contains(cred.Audiences, workloadIdentityAudience)
+		if issuerMatch && subjectMatch && audienceMatch {
+			check.Status, check.Remediation = CheckPass, ""
+			check.Detail = fmt.Sprintf("federated credential %s trusts %s for %s", cred.Name, issuer, subject)
+			return check
+		}
+		switch {
+		case subjectMatch && !issuerMatch && strings.TrimSuffix(cred.Issuer, "/") == strings.TrimSuffix(issuer, "/"):
+			nearMisses = append(nearMisses, fmt.Sprintf("%s has issuer %s, which differs from the cluster issuer %s only by the trailing slash; the match is exact", cred.Name, cred.Issuer, issuer))
+		case subjectMatch && !issuerMatch:
+			nearMisses = append(nearMisses, fmt.Sprintf("%s has the right subject but issuer %s, not the cluster issuer %s (another cluster, or a recreated cluster?)", cred.Name, cred.Issuer, issuer))
+		case issuerMatch && !subjectMatch:
+			nearMisses = append(nearMisses, fmt.Sprintf("%s trusts the cluster issuer for subject %s, not %s", cred.Name, cred.Subject, subject))
+		case issuerMatch && subjectMatch:
+			nearMisses = append(nearMisses, fmt.Sprintf("%s has audiences %s, not %s", cred.Name, strings.Join(cred.Audiences, ", "), workloadIdentityAudience))
+		}
+	}
+	switch {
+	case len(nearMisses) > 0:
+		check.Detail = "no federated credential matches exactly: " + strings.Join(nearMisses, "; ")
+	case len(credentials) == 0:
+		check.Detail = "the managed identity has no federated credentials"
+	default:
+		check.Detail = fmt.Sprintf("none of the %d federated credentials trusts issuer %s for subject %s", len(credentials), issuer, subject)
+	}
+	return check
+}
+
+// checkTokenExchange reads the pod's projected token, checks its claims and exchanges it with Microsoft Entra ID.
+// The token is never included in the result.
+func checkTokenExchange(t *wiTarget, enabled bool, executor tools.CommandExecutor, exchange TokenExchanger, cfg *config.ConfigData) []WorkloadIdentityCheck {
+	skipped := func(detail string) []WorkloadIdentityCheck {
+		return []WorkloadIdentityCheck{
+			{Link: LinkTokenClaims, Status: CheckSkipped, Detail: detail},
+			{Link: LinkTokenExchange, Status: CheckSkipped, Detail: detail},
+		}
+	}
+	switch {
+	case !enabled:
+		return skipped("set test_token_exchange to \"true\" (admin access level) to test the live token exchange with the pod's token")
+	case t.pod == "":
+		return skipped("the live token exchange needs a pod")
+	case t.tokenContainer == "" || t.clientID == "" || t.tenantID == "":
+		return skipped("the pod has no injected client ID, tenant ID and token file to exchange")
+	}
+
+	tokenFile := t.tokenFile
+	if tokenFile == "" {
+		tokenFile = defaultTokenFile
+	}
+	if !tokenPathPattern.MatchString(tokenFile) {
+		return skipped(fmt.Sprintf("unexpected token file path %q", tokenFile))
+	}
+	cmd := fmt.Sprintf("exec %s --namespace %s --container %s -- cat %s", t.pod, t.namespace, t.tokenContainer, tokenFile)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		// Do not echo the output, which may contain the token
+		return []WorkloadIdentityCheck{
+			{Link: LinkTokenClaims, Status: CheckFail, Detail: fmt.Sprintf("failed to read %s in container %s: %v", tokenFile, t.tokenContainer, err)},
+			{Link: LinkTokenExchange, Status: CheckSkipped, Detail: "the pod's token could not be read"},
+		}
+	}
+	token := strings.TrimSpace(output)
+
+	claimsCheck := CheckTokenClaims(token, t.issuerURL, t.subject(), time.Now())
+	checks := []WorkloadIdentityCheck{claimsCheck}
+
+	env := cfg.CloudEnvironment()
+	exchangeCheck := WorkloadIdentityCheck{Link: LinkTokenExchange}
+	if err := exchange(env.ActiveDirectoryAuthorityHost, t.tenantID, t.clientID, token, env.ResourceManagerScope()); err != nil {
+		exchangeCheck.Status = CheckFail
+		exchangeCheck.Detail = "Microsoft Entra ID rejected the token exchange: " + err.Error()
+		if code := aadstsPattern.FindString(err.Error()); code != "" {
+			if mapped, ok := aadstsLinks[code]; ok {
+				exchangeCheck.Detail += fmt.Sprintf(" (points at %s: %s)", mapped.link, mapped.hint)
+			}
+		}
+	} else {
+		exchangeCheck.Status = CheckPass
+		exchangeCheck.Detail = fmt.Sprintf("the pod's token was exchanged for an access token for client ID %s", t.clientID)
+	}
+	if host := t.podEnvAuthorityHost; host != "" && strings.TrimSuffix(host, "/") != strings.TrimSuffix(env.ActiveDirectoryAuthorityHost, "/") {
+		exchangeCheck.Detail += fmt.Sprintf("; note the pod uses authority host %s while the test used %s", host, env.ActiveDirectoryAuthorityHost)
+	}
+	return append(checks, exchangeCheck)
+}
+
+// CheckTokenClaims decodes a projected service account token without verifying its signature and
+// compares its issuer, subject, audience and expiry with what the federated credential expects
+func CheckTokenClaims(token, issuer, subject string, now time.Time) WorkloadIdentityCheck {
+	check := WorkloadIdentityCheck{Link: LinkTokenClaims}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		check.Status, check.Detail = CheckFail, "the token file does not contain a JWT"
+		return check
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		check.Status, check.Detail = CheckFail, "the token payload is not valid base64url"
+		return check
+	}
+	var claims struct {
+		Issuer   string          `json:"iss"`
+		Subject  string          `json:"sub"`
+		Audience json.RawMessage `json:"aud"`
+		Expiry   int64           `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		check.Status, check.Detail = CheckFail, "the token payload is not valid JSON"
+		return check
+	}
+	// aud may be a string or a list of strings
+	var audiences []string
+	if err := json.Unmarshal(claims.Audience, &audiences); err != nil {
+		var single string
+		if json.Unmarshal(claims.Audience, &single) == nil {
+			audiences = []string{single}
+		}
+	}
+
+	var problems []string
+	if issuer != "" && claims.Issuer != issuer {
+		problems = append(problems, fmt.Sprintf("issuer is %s, not the cluster issuer %s", claims.Issuer, issuer))
+	}
+	if claims.Subject != subject {
+		problems = append(problems, fmt.Sprintf("subject is %s, not %s", claims.Subject, subject))
+	}
+	if !slices.Contains(audiences, workloadIdentityAudience) {
+		problems = append(problems, fmt.Sprintf("audience is %s, not %s", strings.Join(audiences, ", "), workloadIdentityAudience))
+	}
+	if expiry := time.Unix(claims.Expiry, 0); claims.Expiry != 0 && !expiry.After(now) {
+		problems = append(problems, fmt.Sprintf("token expired at %s; the kubelet is not refreshing it", expiry.UTC().Format(time.RFC3339)))
+	}
+	if len(problems) > 0 {
+		check.Status, check.Detail = CheckFail, strings.Join(problems, "; ")
+		return check
+	}
+	check.Status = CheckPass
+	check.Detail = fmt.Sprintf("token issued by %s for %s, expires %s", claims.Issuer, claims.Subject, time.Unix(claims.Expiry, 0).UTC().Format(time.RFC3339))
+	return check
+}
+
+// ExchangeFederatedToken requests an access token with the client credentials flow, using the
+// projected service account token as the client assertion, as the Azure Identity SDKs do in the pod
+func ExchangeFederatedToken(authorityHost, tenantID, clientID, assertion, scope string) error {
+	if !guidPattern.MatchString(tenantID) && !dnsLabelPattern.MatchString(tenantID) {
+		return fmt.Errorf("invalid tenant ID %q", tenantID)
+	}
+	endpoint := strings.TrimSuffix(authorityHost, "/") + "/" + tenantID + "/oauth2/v2.0/token"
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_id":             {clientID},
+		"scope":                 {scope},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {assertion},
+	}
+	client := &http.Client{Timeout: tokenExchangeTimeout}
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return fmt.Errorf("token request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var failure struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(body, &failure) == nil && failure.ErrorDescription != "" {
+		// The first line holds the AADSTS code and message; the rest are trace and correlation IDs
+		description, _, _ := strings.Cut(failure.ErrorDescription, "\r\n")
+		return fmt.Errorf("%s: %s", failure.Error, description)
+	}
+	return fmt.Errorf("token request returned HTTP %d", resp.StatusCode)
+}
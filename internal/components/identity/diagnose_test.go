@@ -0,0 +1,232 @@
+package identity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const (
+	testClientID = "11111111-2222-3333-4444-555555555555"
+	testTenantID = "66666666-7777-8888-9999-000000000000"
+	testIssuer   = "https://eastus.oic.prod-aks.azure.com/tenant/cluster/"
+)
+
+func testToken(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func testWIPod(label string) string {
+	return fmt.Sprintf(`{
+  "metadata": {"labels": {"azure.workload.identity/use": %q}},
+  "spec": {
+    "serviceAccountName": "web",
+    "containers": [
+      {"name": "app", "env": [
+        {"name": "AZURE_CLIENT_ID", "value": %q},
+        {"name": "AZURE_TENANT_ID", "value": %q},
+        {"name": "AZURE_FEDERATED_TOKEN_FILE", "value": "/var/run/secrets/azure/tokens/azure-identity-token"},
+        {"name": "AZURE_AUTHORITY_HOST", "value": "https://login.microsoftonline.com/"}
+      ], "volumeMounts": [{"name": "azure-identity-token", "mountPath": "/var/run/secrets/azure/tokens"}]},
+      {"name": "sidecar"}
+    ],
+    "volumes": [{"name": "azure-identity-token", "projected": {"sources": [{"serviceAccountToken": {"audience": "api://AzureADTokenExchange", "path": "azure-identity-token"}}]}}]
+  }
+}`, label, testClientID, testTenantID)
+}
+
+func newWIExecutor(t *testing.T, credentialIssuer string) *fakeAzExecutor {
+	return &fakeAzExecutor{responses: map[string]string{
+		"az aks show":                                 `{"oidcIssuerProfile":{"enabled":true,"issuerUrl":"` + testIssuer + `"},"securityProfile":{"workloadIdentity":{"enabled":true}}}`,
+		"get pod web-0":                               testWIPod("true"),
+		"get serviceaccount web":                      `{"metadata":{"annotations":{"azure.workload.identity/client-id":"` + testClientID + `"}}}`,
+		"az identity list":                            `[{"name":"web-identity","resourceGroup":"id-rg"}]`,
+		"az identity federated-credential list":       `[{"name":"aks-apps-web","issuer":"` + credentialIssuer + `","subject":"system:serviceaccount:apps:web","audiences":["api://AzureADTokenExchange"]}]`,
+		"exec web-0 --namespace apps --container app": testToken(t, map[string]interface{}{"iss": testIssuer, "sub": "system:serviceaccount:apps:web", "aud": []string{"api://AzureADTokenExchange"}, "exp": time.Now().Add(time.Hour).Unix()}),
+	}}
+}
+
+func diagnose(t *testing.T, params map[string]interface{}, executor *fakeAzExecutor, exchange TokenExchanger, cfg *config.ConfigData) WorkloadIdentityDiagnosis {
+	t.Helper()
+	output, err := HandleDiagnoseWorkloadIdentity(params, executor, executor, exchange, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var diagnosis WorkloadIdentityDiagnosis
+	if err := json.Unmarshal([]byte(output), &diagnosis); err != nil {
+		t.Fatalf("Failed to parse diagnosis: %v", err)
+	}
+	return diagnosis
+}
+
+func checkStatus(diagnosis WorkloadIdentityDiagnosis, link string) string {
+	for _, check := range diagnosis.Checks {
+		if check.Link == link {
+			return check.Status
+		}
+	}
+	return ""
+}
+
+func TestHandleDiagnoseWorkloadIdentity(t *testing.T) {
+	params := map[string]interface{}{
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+		"namespace":       "apps",
+		"pod":             "web-0",
+	}
+	noExchange := func(string, string, string, string, string) error {
+		t.Fatal("Expected no token exchange")
+		return nil
+	}
+
+	t.Run("healthy chain without live exchange", func(t *testing.T) {
+		executor := newWIExecutor(t, testIssuer)
+		diagnosis := diagnose(t, params, executor, noExchange, config.NewConfig())
+
+		if !diagnosis.Healthy || diagnosis.ServiceAccount != "web" || diagnosis.ClientID != testClientID {
+			t.Errorf("Expected a healthy diagnosis using the pod's service account, got %+v", diagnosis)
+		}
+		if checkStatus(diagnosis, LinkWebhookInjection) != CheckWarn {
+			t.Errorf("Expected a warning for the sidecar without injected environment, got %+v", diagnosis.Checks)
+		}
+		if checkStatus(diagnosis, LinkTokenExchange) != CheckSkipped {
+			t.Errorf("Expected the live exchange to be skipped, got %+v", diagnosis.Checks)
+		}
+		for _, cmd := range executor.commands {
+			if strings.HasPrefix(cmd, "exec ") {
+				t.Errorf("Expected no exec without test_token_exchange, got %s", cmd)
+			}
+		}
+	})
+
+	t.Run("issuer trailing slash mismatch is the broken link", func(t *testing.T) {
+		diagnosis := diagnose(t, params, newWIExecutor(t, strings.TrimSuffix(testIssuer, "/")), noExchange, config.NewConfig())
+
+		if diagnosis.BrokenLink != LinkFederatedCredential {
+			t.Fatalf("Expected the federated credential to be the broken link, got %+v", diagnosis)
+		}
+		for _, check := range diagnosis.Checks {
+			if check.Link == LinkFederatedCredential && (!strings.Contains(check.Detail, "trailing slash") || !strings.Contains(check.Remediation, "--issuer "+testIssuer)) {
+				t.Errorf("Expected the trailing slash to be explained with a fix, got %+v", check)
+			}
+		}
+	})
+
+	t.Run("missing pod label is reported first", func(t *testing.T) {
+		executor := newWIExecutor(t, testIssuer)
+		executor.responses["get pod web-0"] = testWIPod("false")
+		diagnosis := diagnose(t, params, executor, noExchange, config.NewConfig())
+
+		if diagnosis.BrokenLink != LinkPodLabel {
+			t.Errorf("Expected the pod label to be the broken link, got %+v", diagnosis)
+		}
+	})
+
+	t.Run("live exchange maps AADSTS errors", func(t *testing.T) {
+		exchangeParams := map[string]interface{}{"test_token_exchange": "true"}
+		for k, v := range params {
+			exchangeParams[k] = v
+		}
+		cfg := config.NewConfig()
+		cfg.AccessLevel = "admin"
+
+		var gotTenant, gotClient, gotScope string
+		exchange := func(_, tenantID, clientID, assertion, scope string) error {
+			gotTenant, gotClient, gotScope = tenantID, clientID, scope
+			return fmt.Errorf("invalid_request: AADSTS700213: No matching federated identity record found for presented assertion subject")
+		}
+		output, err := HandleDiagnoseWorkloadIdentity(exchangeParams, newWIExecutor(t, testIssuer), newWIExecutor(t, testIssuer), exchange, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(output, "eyJhbGciOiJSUzI1NiJ9") {
+			t.Fatal("Expected the token not to be included in the result")
+		}
+		if gotTenant != testTenantID || gotClient != testClientID || gotScope != "https://management.core.windows.net/.default" {
+			t.Errorf("Unexpected exchange arguments: %s %s %s", gotTenant, gotClient, gotScope)
+		}
+
+		var diagnosis WorkloadIdentityDiagnosis
+		if err := json.Unmarshal([]byte(output), &diagnosis); err != nil {
+			t.Fatal(err)
+		}
+		if diagnosis.BrokenLink != LinkTokenExchange || checkStatus(diagnosis, LinkTokenClaims) != CheckPass {
+			t.Errorf("Expected valid claims and a failed exchange, got %+v", diagnosis)
+		}
+		if !strings.Contains(output, "service account name in the credential subject") {
+			t.Errorf("Expected the AADSTS code to be explained, got %s", output)
+		}
+	})
+
+	t.Run("live exchange requires admin", func(t *testing.T) {
+		cfg := config.NewConfig()
+		cfg.AccessLevel = "readwrite"
+		_, err := HandleDiagnoseWorkloadIdentity(map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks", "namespace": "apps", "pod": "web-0", "test_token_exchange": "true"}, &fakeAzExecutor{}, &fakeAzExecutor{}, noExchange, cfg)
+		if err == nil || !strings.Contains(err.Error(), "admin") {
+			t.Errorf("Expected an admin access error, got %v", err)
+		}
+	})
+
+	t.Run("pod or service account is required", func(t *testing.T) {
+		_, err := HandleDiagnoseWorkloadIdentity(map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks", "namespace": "apps"}, &fakeAzExecutor{}, &fakeAzExecutor{}, noExchange, config.NewConfig())
+		if err == nil {
+			t.Error("Expected an error without pod or service_account")
+		}
+	})
+}
+
+func TestCheckTokenClaims(t *testing.T) {
+	now := time.Now()
+	subject := "system:serviceaccount:apps:web"
+
+	valid := testToken(t, map[string]interface{}{"iss": testIssuer, "sub": subject, "aud": "api://AzureADTokenExchange", "exp": now.Add(time.Hour).Unix()})
+	if check := CheckTokenClaims(valid, testIssuer, subject, now); check.Status != CheckPass {
+		t.Errorf("Expected a single string audience to pass, got %+v", check)
+	}
+
+	wrong := testToken(t, map[string]interface{}{"iss": "https://other/", "sub": "system:serviceaccount:apps:default", "aud": []string{"https://kubernetes.default.svc"}, "exp": now.Add(-time.Minute).Unix()})
+	check := CheckTokenClaims(wrong, testIssuer, subject, now)
+	for _, want := range []string{"issuer is https://other/", "subject is system:serviceaccount:apps:default", "audience is https://kubernetes.default.svc", "token expired"} {
+		if check.Status != CheckFail || !strings.Contains(check.Detail, want) {
+			t.Errorf("Expected failure mentioning %q, got %+v", want, check)
+		}
+	}
+
+	if check := CheckTokenClaims("not-a-jwt", testIssuer, subject, now); check.Status != CheckFail {
+		t.Errorf("Expected a malformed token to fail, got %+v", check)
+	}
+}
+
+func TestMatchFederatedCredential(t *testing.T) {
+	subject := "system:serviceaccount:apps:web"
+	credentials := []FederatedCredential{
+		{Name: "other-cluster", Issuer: "https://old-issuer/", Subject: subject, Audiences: []string{workloadIdentityAudience}},
+		{Name: "other-sa", Issuer: testIssuer, Subject: "system:serviceaccount:apps:api", Audiences: []string{workloadIdentityAudience}},
+	}
+
+	check := MatchFederatedCredential(credentials, testIssuer, subject, "create")
+	if check.Status != CheckFail || !strings.Contains(check.Detail, "other-cluster has the right subject") || !strings.Contains(check.Detail, "other-sa trusts the cluster issuer for subject system:serviceaccount:apps:api") {
+		t.Errorf("Expected both near misses to be explained, got %+v", check)
+	}
+
+	credentials = append(credentials, FederatedCredential{Name: "match", Issuer: testIssuer, Subject: subject, Audiences: []string{workloadIdentityAudience}})
+	if check := MatchFederatedCredential(credentials, testIssuer, subject, "create"); check.Status != CheckPass || check.Remediation != "" {
+		t.Errorf("Expected an exact match to pass, got %+v", check)
+	}
+
+	if check := MatchFederatedCredential(nil, testIssuer, subject, "create"); !strings.Contains(check.Detail, "no federated credentials") {
+		t.Errorf("Expected no credentials to be reported, got %+v", check)
+	}
+}
@@ -0,0 +1,400 @@
+// Package identity provides tools for verifying the role assignments of AKS cluster identities.
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/directory"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// PermissionReport is the result returned by the check_identity_permissions tool
+type PermissionReport struct {
+	ClusterName   string                  `json:"clusterName"`
+	ResourceGroup string                  `json:"resourceGroup"`
+	Identities    []ClusterIdentity       `json:"identities"`
+	Checks        []PermissionCheckResult `json:"checks"`
+	MissingCount  int                     `json:"missingCount"`
+	FixCommands   []string                `json:"fixCommands,omitempty"`
+	Notes         []string                `json:"notes,omitempty"`
+}
+
+// GetCheckIdentityPermissionsHandler returns a handler for the check_identity_permissions command
+func GetCheckIdentityPermissionsHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleCheckIdentityPermissions(params, client, azcli.NewExecutor(), cfg)
+	})
+}
+
+// HandleCheckIdentityPermissions verifies the role assignments required by the cluster configuration
+func HandleCheckIdentityPermissions(params map[string]interface{}, client *azureclient.AzureClient, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	acrValue, _ := params["acr_resource_ids"].(string)
+	kvValue, _ := params["key_vault_resource_ids"].(string)
+
+	cluster, err := common.GetClusterDetails(context.Background(), client, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %v", err)
+	}
+
+	identities := GetClusterIdentities(cluster)
+	requirements := BuildPermissionRequirements(cluster, identities, parseResourceIDList(acrValue), parseResourceIDList(kvValue))
+
+	report := PermissionReport{
+		ClusterName:   clusterName,
+		ResourceGroup: rg,
+	}
+	for _, identity := range identities {
+		report.Identities = append(report.Identities, identity)
+	}
+	sort.Slice(report.Identities, func(i, j int) bool { return report.Identities[i].Kind < report.Identities[j].Kind })
+	if cfg.GraphLookupEnabled() {
+		if err := NameIdentities(context.Background(), client, report.Identities); err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not resolve identity names: %v", err))
+		}
+	}
+
+	if _, ok := identities[IdentityKindCluster]; !ok {
+		report.Notes = append(report.Notes, "cluster does not use a managed identity; service principal permissions are not verified")
+	}
+	if _, ok := identities[IdentityKindKubelet]; !ok && acrValue != "" {
+		report.Notes = append(report.Notes, "no kubelet identity found; AcrPull checks were skipped")
+	}
+	if _, ok := identities[IdentityKindKeyVault]; !ok && kvValue != "" {
+		report.Notes = append(report.Notes, "Key Vault Secrets Provider addon is not enabled; Key Vault checks were skipped")
+	}
+
+	assignments := make(map[string][]RoleAssignment)
+	for _, req := range requirements {
+		if _, done := assignments[req.PrincipalID]; done {
+			continue
+		}
+		list, err := listRoleAssignments(executor, subID, req.PrincipalID, cfg)
+		if err != nil {
+			return "", fmt.Errorf("failed to list role assignments for %s identity: %v", req.IdentityKind, err)
+		}
+		assignments[req.PrincipalID] = list
+	}
+
+	report.Checks = EvaluateRequirements(requirements, assignments)
+
+	vaults := make(map[string]KeyVaultAuthorization)
+	for _, req := range requirements {
+		if req.Role != RoleKeyVaultSecretsUser {
+			continue
+		}
+		key := strings.ToLower(req.Scope)
+		if _, done := vaults[key]; done {
+			continue
+		}
+		vault, err := getKeyVaultAuthorization(executor, req.Scope, cfg)
+		if err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not read authorization model of key vault %s, assuming RBAC: %v", req.Scope, err))
+			continue
+		}
+		vaults[key] = vault
+	}
+	ApplyKeyVaultAuthorization(report.Checks, vaults)
+
+	for _, check := range report.Checks {
+		if !check.Satisfied {
+			report.MissingCount++
+			report.FixCommands = append(report.FixCommands, check.FixCommand)
+		}
+	}
+	if len(requirements) == 0 {
+		report.Notes = append(report.Notes, "cluster configuration does not require additional role assignments for the checked scenarios")
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal permission report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// NameIdentities sets the display names of identities from their service principals
+func NameIdentities(ctx context.Context, graph directory.GraphCaller, identities []ClusterIdentity) error {
+	var ids []string
+	for _, identity := range identities {
+		ids = append(ids, identity.PrincipalID)
+	}
+	objects, err := directory.Resolve(ctx, graph, ids)
+	if err != nil {
+		return err
+	}
+	for i := range identities {
+		identities[i].DisplayName = objects[strings.ToLower(identities[i].PrincipalID)].DisplayName
+	}
+	return nil
+}
+
+// listRoleAssignments lists all role assignments, including inherited ones, held by a principal
+func listRoleAssignments(executor tools.CommandExecutor, subscriptionID, principalID string, cfg *config.ConfigData) ([]RoleAssignment, error) {
+	cmd := fmt.Sprintf("az role assignment list --assignee %s --all --include-inherited --subscription %s --output json", principalID, subscriptionID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []RoleAssignment
+	if err := json.Unmarshal([]byte(output), &assignments); err != nil {
+		return nil, fmt.Errorf("failed to parse role assignments: %v", err)
+	}
+	return assignments, nil
+}
+
+// GetSetupWorkloadIdentityHandler returns a handler for the setup_workload_identity command
+func GetSetupWorkloadIdentityHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleSetupWorkloadIdentity(params, azcli.NewExecutor(), cfg)
+	})
+}
+
+// WorkloadIdentityResult is the result returned by the setup_workload_identity tool
+type WorkloadIdentityResult struct {
+	DryRun             bool                    `json:"dryRun"`
+	Request            WorkloadIdentityRequest `json:"request"`
+	Cluster            ClusterOIDCState        `json:"cluster"`
+	ClientID           string                  `json:"clientId,omitempty"`
+	Steps              []WorkloadIdentityStep  `json:"steps"`
+	ServiceAccountYAML string                  `json:"serviceAccountYaml"`
+}
+
+// HandleSetupWorkloadIdentity plans, and unless dry_run is set, executes the workload identity setup
+func HandleSetupWorkloadIdentity(params map[string]interface{}, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	req := WorkloadIdentityRequest{
+		SubscriptionID:        subID,
+		ClusterResourceGroup:  rg,
+		ClusterName:           clusterName,
+		IdentityResourceGroup: rg,
+	}
+	for key, target := range map[string]*string{
+		"namespace":       &req.Namespace,
+		"service_account": &req.ServiceAccount,
+		"identity_name":   &req.IdentityName,
+	} {
+		value, ok := params[key].(string)
+		if !ok || value == "" {
+			return "", fmt.Errorf("missing or invalid %s parameter", key)
+		}
+		*target = value
+	}
+	if value, ok := params["identity_resource_group"].(string); ok && value != "" {
+		req.IdentityResourceGroup = value
+	}
+	if value, ok := params["location"].(string); ok && value != "" {
+		req.Location = value
+	}
+	req.FederatedCredential = fmt.Sprintf("%s-%s-%s", clusterName, req.Namespace, req.ServiceAccount)
+	if value, ok := params["federated_credential_name"].(string); ok && value != "" {
+		req.FederatedCredential = value
+	}
+	if len(req.FederatedCredential) > 120 {
+		req.FederatedCredential = req.FederatedCredential[:120]
+	}
+
+	dryRun := true
+	if value, ok := params["dry_run"].(string); ok && value != "" {
+		if value != "true" && value != "false" {
+			return "", fmt.Errorf("invalid dry_run value %q, must be \"true\" or \"false\"", value)
+		}
+		dryRun = value == "true"
+	}
+
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+	if !cfg.SecurityConfig.IsNamespaceAllowed(req.Namespace) {
+		return "", fmt.Errorf("access to namespace '%s' is denied by security configuration", req.Namespace)
+	}
+	if !dryRun && cfg.AccessLevel == "readonly" {
+		return "", fmt.Errorf("setting up workload identity requires readwrite or admin access level, use dry_run to preview the changes")
+	}
+
+	state, err := getClusterOIDCState(executor, req, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	clientID, identityExists, err := showIdentity(executor, req, cfg)
+	if err != nil {
+		return "", err
+	}
+	credentialExists := false
+	if identityExists {
+		credentialExists, err = federatedCredentialExists(executor, req, cfg)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	result := WorkloadIdentityResult{
+		DryRun:   dryRun,
+		Request:  req,
+		Cluster:  state,
+		ClientID: clientID,
+		Steps:    BuildWorkloadIdentityPlan(req, state, identityExists, credentialExists),
+	}
+
+	if !dryRun {
+		if err := executeWorkloadIdentityPlan(executor, &result, cfg); err != nil {
+			return "", err
+		}
+	}
+	result.ServiceAccountYAML = BuildServiceAccountYAML(req.Namespace, req.ServiceAccount, result.ClientID)
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal workload identity result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// executeWorkloadIdentityPlan runs the plan steps in order, refreshing the issuer URL and client ID as they become known
+func executeWorkloadIdentityPlan(executor tools.CommandExecutor, result *WorkloadIdentityResult, cfg *config.ConfigData) error {
+	for i := range result.Steps {
+		step := &result.Steps[i]
+		if step.Skipped || step.Command == "" {
+			continue
+		}
+
+		if strings.HasPrefix(step.Command, "az identity federated-credential create") && !result.Cluster.OIDCIssuerEnabled {
+			// The issuer URL only becomes available after the cluster update, so rebuild the step
+			state, err := getClusterOIDCState(executor, result.Request, cfg)
+			if err != nil {
+				return err
+			}
+			result.Cluster = state
+			rebuilt := BuildWorkloadIdentityPlan(result.Request, state, true, false)
+			step.Command = rebuilt[len(rebuilt)-1].Command
+		}
+
+		output, err := executor.Execute(map[string]interface{}{"command": step.Command}, cfg)
+		if err != nil {
+			return fmt.Errorf("step '%s' failed: %v: %s", step.Description, err, output)
+		}
+		step.Executed = true
+
+		var parsed map[string]interface{}
+		if json.Unmarshal([]byte(output), &parsed) == nil {
+			if clientID, ok := parsed["clientId"].(string); ok && clientID != "" {
+				result.ClientID = clientID
+			}
+		}
+	}
+	return nil
+}
+
+// getClusterOIDCState reads the OIDC issuer and workload identity settings of the cluster
+func getClusterOIDCState(executor tools.CommandExecutor, req WorkloadIdentityRequest, cfg *config.ConfigData) (ClusterOIDCState, error) {
+	cmd := fmt.Sprintf("az aks show --resource-group %s --name %s --subscription %s --output json", req.ClusterResourceGroup, req.ClusterName, req.SubscriptionID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		return ClusterOIDCState{}, fmt.Errorf("failed to get cluster details: %v", err)
+	}
+	return ParseClusterOIDCState(output)
+}
+
+// ParseClusterOIDCState extracts workload identity related settings from az aks show output
+func ParseClusterOIDCState(output string) (ClusterOIDCState, error) {
+	var cluster struct {
+		Location          string `json:"location"`
+		OIDCIssuerProfile *struct {
+			Enabled   bool   `json:"enabled"`
+			IssuerURL string `json:"issuerUrl"`
+		} `json:"oidcIssuerProfile"`
+		SecurityProfile *struct {
+			WorkloadIdentity *struct {
+				Enabled bool `json:"enabled"`
+			} `json:"workloadIdentity"`
+		} `json:"securityProfile"`
+	}
+	if err := json.Unmarshal([]byte(output), &cluster); err != nil {
+		return ClusterOIDCState{}, fmt.Errorf("failed to parse cluster details: %v", err)
+	}
+
+	state := ClusterOIDCState{Location: cluster.Location}
+	if cluster.OIDCIssuerProfile != nil {
+		state.OIDCIssuerEnabled = cluster.OIDCIssuerProfile.Enabled
+		state.IssuerURL = cluster.OIDCIssuerProfile.IssuerURL
+	}
+	if cluster.SecurityProfile != nil && cluster.SecurityProfile.WorkloadIdentity != nil {
+		state.WorkloadIdentityEnabled = cluster.SecurityProfile.WorkloadIdentity.Enabled
+	}
+	return state, nil
+}
+
+// showIdentity returns the client ID of the managed identity and whether it exists
+func showIdentity(executor tools.CommandExecutor, req WorkloadIdentityRequest, cfg *config.ConfigData) (string, bool, error) {
+	cmd := fmt.Sprintf("az identity show --name %s --resource-group %s --subscription %s --output json", req.IdentityName, req.IdentityResourceGroup, req.SubscriptionID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		if isNotFoundOutput(output) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get managed identity: %v", err)
+	}
+
+	var identity struct {
+		ClientID string `json:"clientId"`
+	}
+	if err := json.Unmarshal([]byte(output), &identity); err != nil {
+		return "", false, fmt.Errorf("failed to parse managed identity: %v", err)
+	}
+	return identity.ClientID, true, nil
+}
+
+// federatedCredentialExists reports whether the federated credential is already configured
+func federatedCredentialExists(executor tools.CommandExecutor, req WorkloadIdentityRequest, cfg *config.ConfigData) (bool, error) {
+	cmd := fmt.Sprintf("az identity federated-credential show --name %s --identity-name %s --resource-group %s --subscription %s --output json",
+		req.FederatedCredential, req.IdentityName, req.IdentityResourceGroup, req.SubscriptionID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		if isNotFoundOutput(output) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get federated credential: %v", err)
+	}
+	return true, nil
+}
+
+// isNotFoundOutput reports whether az CLI error output indicates a missing resource
+func isNotFoundOutput(output string) bool {
+	lower := strings.ToLower(output)
+	return strings.Contains(lower, "resourcenotfound") || strings.Contains(lower, "not found") || strings.Contains(lower, "notfound")
+}
+
+// getKeyVaultAuthorization reads whether a key vault uses RBAC or access policies for data plane access
+func getKeyVaultAuthorization(executor tools.CommandExecutor, vaultID string, cfg *config.ConfigData) (KeyVaultAuthorization, error) {
+	cmd := fmt.Sprintf("az keyvault show --ids %s --output json", vaultID)
+	output, err := executor.Execute(map[string]interface{}{"command": cmd}, cfg)
+	if err != nil {
+		return KeyVaultAuthorization{}, err
+	}
+
+	var vault struct {
+		Properties KeyVaultAuthorization `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(output), &vault); err != nil {
+		return KeyVaultAuthorization{}, fmt.Errorf("failed to parse key vault: %v", err)
+	}
+	return vault.Properties, nil
+}
@@ -0,0 +1,255 @@
+package identity
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+func strPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
+
+const (
+	testSubnetID = "/subscriptions/sub/resourceGroups/net-rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/nodes"
+	testVNetID   = "/subscriptions/sub/resourceGroups/net-rg/providers/Microsoft.Network/virtualNetworks/vnet"
+	testDNSZone  = "/subscriptions/sub/resourceGroups/dns-rg/providers/Microsoft.Network/privateDnsZones/privatelink.eastus.azmk8s.io"
+	testACRID    = "/subscriptions/sub/resourceGroups/acr-rg/providers/Microsoft.ContainerRegistry/registries/myacr"
+)
+
+func newTestCluster() *armcontainerservice.ManagedCluster {
+	return &armcontainerservice.ManagedCluster{
+		Identity: &armcontainerservice.ManagedClusterIdentity{
+			PrincipalID: strPtr("cluster-principal"),
+		},
+		Properties: &armcontainerservice.ManagedClusterProperties{
+			AgentPoolProfiles: []*armcontainerservice.ManagedClusterAgentPoolProfile{
+				{Name: strPtr("system"), VnetSubnetID: strPtr(testSubnetID)},
+				{Name: strPtr("user"), VnetSubnetID: strPtr(testSubnetID)},
+			},
+			APIServerAccessProfile: &armcontainerservice.ManagedClusterAPIServerAccessProfile{
+				PrivateDNSZone: strPtr(testDNSZone),
+			},
+			IdentityProfile: map[string]*armcontainerservice.UserAssignedIdentity{
+				"kubeletidentity": {ObjectID: strPtr("kubelet-principal"), ClientID: strPtr("kubelet-client")},
+			},
+			AddonProfiles: map[string]*armcontainerservice.ManagedClusterAddonProfile{
+				IdentityKindKeyVault: {
+					Enabled:  boolPtr(true),
+					Identity: &armcontainerservice.ManagedClusterAddonProfileIdentity{ObjectID: strPtr("kv-principal")},
+				},
+			},
+		},
+	}
+}
+
+func TestRegisterCheckIdentityPermissionsTool(t *testing.T) {
+	tool := RegisterCheckIdentityPermissionsTool()
+
+	if tool.Name != "check_identity_permissions" {
+		t.Errorf("Expected tool name 'check_identity_permissions', got '%s'", tool.Name)
+	}
+	if tool.Description == "" {
+		t.Error("Expected tool description to be set")
+	}
+	for _, param := range []string{"subscription_id", "resource_group", "cluster_name"} {
+		found := false
+		for _, required := range tool.InputSchema.Required {
+			if required == param {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected '%s' to be a required parameter", param)
+		}
+	}
+}
+
+func TestGetClusterIdentities(t *testing.T) {
+	identities := GetClusterIdentities(newTestCluster())
+
+	expected := map[string]string{
+		IdentityKindCluster:  "cluster-principal",
+		IdentityKindKubelet:  "kubelet-principal",
+		IdentityKindKeyVault: "kv-principal",
+	}
+	for kind, principal := range expected {
+		identity, ok := identities[kind]
+		if !ok {
+			t.Errorf("Expected %s identity to be found", kind)
+			continue
+		}
+		if identity.PrincipalID != principal {
+			t.Errorf("Expected %s principal '%s', got '%s'", kind, principal, identity.PrincipalID)
+		}
+	}
+
+	if got := GetClusterIdentities(nil); len(got) != 0 {
+		t.Errorf("Expected no identities for nil cluster, got %d", len(got))
+	}
+}
+
+func TestBuildPermissionRequirements(t *testing.T) {
+	cluster := newTestCluster()
+	identities := GetClusterIdentities(cluster)
+	requirements := BuildPermissionRequirements(cluster, identities, []string{testACRID}, nil)
+
+	// Duplicate subnets are collapsed, so we expect subnet + DNS zone + ACR
+	if len(requirements) != 3 {
+		t.Fatalf("Expected 3 requirements, got %d: %+v", len(requirements), requirements)
+	}
+
+	roles := map[string]string{}
+	for _, req := range requirements {
+		roles[req.Role] = req.PrincipalID
+	}
+	if roles[RoleNetworkContributor] != "cluster-principal" {
+		t.Errorf("Expected Network Contributor requirement for cluster identity")
+	}
+	if roles[RolePrivateDNSZoneContributor] != "cluster-principal" {
+		t.Errorf("Expected Private DNS Zone Contributor requirement for cluster identity")
+	}
+	if roles[RoleAcrPull] != "kubelet-principal" {
+		t.Errorf("Expected AcrPull requirement for kubelet identity")
+	}
+}
+
+func TestBuildPermissionRequirements_SystemPrivateDNSZone(t *testing.T) {
+	cluster := newTestCluster()
+	cluster.Properties.APIServerAccessProfile.PrivateDNSZone = strPtr("system")
+	cluster.Properties.AgentPoolProfiles = nil
+
+	requirements := BuildPermissionRequirements(cluster, GetClusterIdentities(cluster), nil, nil)
+	if len(requirements) != 0 {
+		t.Errorf("Expected no requirements for system-managed DNS zone, got %+v", requirements)
+	}
+}
+
+func TestEvaluateRequirements(t *testing.T) {
+	requirements := []PermissionRequirement{
+		{IdentityKind: IdentityKindCluster, PrincipalID: "cluster-principal", Role: RoleNetworkContributor, Scope: testSubnetID},
+		{IdentityKind: IdentityKindKubelet, PrincipalID: "kubelet-principal", Role: RoleAcrPull, Scope: testACRID},
+	}
+	assignments := map[string][]RoleAssignment{
+		"cluster-principal": {
+			{PrincipalID: "cluster-principal", RoleDefinitionName: "Network Contributor", Scope: testVNetID},
+		},
+		"kubelet-principal": {
+			{PrincipalID: "kubelet-principal", RoleDefinitionName: "Reader", Scope: testACRID},
+		},
+	}
+
+	results := EvaluateRequirements(requirements, assignments)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	if !results[0].Satisfied {
+		t.Error("Expected VNet-scoped Network Contributor to satisfy subnet requirement")
+	}
+	if results[0].FixCommand != "" {
+		t.Error("Expected no fix command for satisfied requirement")
+	}
+
+	if results[1].Satisfied {
+		t.Error("Expected Reader role not to satisfy AcrPull requirement")
+	}
+	if !strings.Contains(results[1].FixCommand, "--role \"AcrPull\"") || !strings.Contains(results[1].FixCommand, testACRID) {
+		t.Errorf("Unexpected fix command: %s", results[1].FixCommand)
+	}
+}
+
+func TestApplyKeyVaultAuthorization(t *testing.T) {
+	rbacVault := "/subscriptions/sub/resourceGroups/kv-rg/providers/Microsoft.KeyVault/vaults/rbac-kv"
+	policyVault := "/subscriptions/sub/resourceGroups/kv-rg/providers/Microsoft.KeyVault/vaults/policy-kv"
+	missingVault := "/subscriptions/sub/resourceGroups/kv-rg/providers/Microsoft.KeyVault/vaults/missing-kv"
+
+	requirements := []PermissionRequirement{
+		{IdentityKind: IdentityKindKeyVault, PrincipalID: "kv-principal", Role: RoleKeyVaultSecretsUser, Scope: rbacVault},
+		{IdentityKind: IdentityKindKeyVault, PrincipalID: "kv-principal", Role: RoleKeyVaultSecretsUser, Scope: policyVault},
+		{IdentityKind: IdentityKindKeyVault, PrincipalID: "kv-principal", Role: RoleKeyVaultSecretsUser, Scope: missingVault},
+	}
+	results := EvaluateRequirements(requirements, map[string][]RoleAssignment{})
+
+	granted := KeyVaultAccessPolicy{ObjectID: "KV-PRINCIPAL"}
+	granted.Permissions.Secrets = []string{"Get", "List"}
+	other := KeyVaultAccessPolicy{ObjectID: "other-principal"}
+	other.Permissions.Secrets = []string{"get"}
+
+	ApplyKeyVaultAuthorization(results, map[string]KeyVaultAuthorization{
+		strings.ToLower(rbacVault):    {EnableRbacAuthorization: true},
+		strings.ToLower(policyVault):  {AccessPolicies: []KeyVaultAccessPolicy{other, granted}},
+		strings.ToLower(missingVault): {AccessPolicies: []KeyVaultAccessPolicy{other}},
+	})
+
+	if results[0].Satisfied || results[0].AuthorizationModel != AuthorizationModelRBAC {
+		t.Errorf("Expected RBAC vault check to stay unsatisfied, got %+v", results[0])
+	}
+	if !strings.Contains(results[0].FixCommand, "az role assignment create") {
+		t.Errorf("Expected role assignment fix command for RBAC vault, got %s", results[0].FixCommand)
+	}
+
+	if !results[1].Satisfied || results[1].AuthorizationModel != AuthorizationModelAccessPolicy {
+		t.Errorf("Expected access policy to satisfy check, got %+v", results[1])
+	}
+	if results[1].FixCommand != "" {
+		t.Errorf("Expected no fix command for satisfied access policy check, got %s", results[1].FixCommand)
+	}
+
+	if results[2].Satisfied {
+		t.Error("Expected check to fail when no access policy grants the principal")
+	}
+	if results[2].FixCommand != "az keyvault set-policy --name missing-kv --object-id kv-principal --secret-permissions get list" {
+		t.Errorf("Unexpected access policy fix command: %s", results[2].FixCommand)
+	}
+}
+
+func TestScopeCovers(t *testing.T) {
+	tests := []struct {
+		assigned string
+		target   string
+		expected bool
+	}{
+		{"/subscriptions/sub", testSubnetID, true},
+		{testVNetID, testSubnetID, true},
+		{strings.ToUpper(testVNetID), testSubnetID, true},
+		{testSubnetID, testSubnetID, true},
+		{testVNetID + "2", testSubnetID, false},
+		{testSubnetID, testVNetID, false},
+	}
+
+	for _, tt := range tests {
+		if got := scopeCovers(tt.assigned, tt.target); got != tt.expected {
+			t.Errorf("scopeCovers(%q, %q) = %v, expected %v", tt.assigned, tt.target, got, tt.expected)
+		}
+	}
+}
+
+func TestParseResourceIDList(t *testing.T) {
+	ids := parseResourceIDList(" a, ,b ,")
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("Unexpected parsed IDs: %v", ids)
+	}
+}
+
+// fakeGraph names the kubelet identity's service principal only
+type fakeGraph struct{}
+
+func (fakeGraph) CallGraph(_ context.Context, _, _ string, _ interface{}) ([]byte, error) {
+	return []byte(`{"value": [{"@odata.type": "#microsoft.graph.servicePrincipal", "id": "KUBELET-PRINCIPAL", "displayName": "aks-agentpool"}]}`), nil
+}
+
+func TestNameIdentities(t *testing.T) {
+	identities := []ClusterIdentity{
+		{Kind: IdentityKindCluster, PrincipalID: "cluster-principal"},
+		{Kind: IdentityKindKubelet, PrincipalID: "kubelet-principal"},
+	}
+	if err := NameIdentities(context.Background(), fakeGraph{}, identities); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if identities[0].DisplayName != "" || identities[1].DisplayName != "aks-agentpool" {
+		t.Errorf("Unexpected identities %+v", identities)
+	}
+}
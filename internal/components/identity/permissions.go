@@ -0,0 +1,330 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+)
+
+// Identity kinds checked by the verifier
+const (
+	IdentityKindCluster  = "cluster"
+	IdentityKindKubelet  = "kubelet"
+	IdentityKindKeyVault = "azureKeyvaultSecretsProvider"
+)
+
+// Built-in role names used by the checks
+const (
+	RoleNetworkContributor        = "Network Contributor"
+	RolePrivateDNSZoneContributor = "Private DNS Zone Contributor"
+	RoleAcrPull                   = "AcrPull"
+	RoleKeyVaultSecretsUser       = "Key Vault Secrets User"
+)
+
+// Authorization models reported for Key Vault checks
+const (
+	AuthorizationModelRBAC         = "rbac"
+	AuthorizationModelAccessPolicy = "accessPolicy"
+)
+
+// satisfyingRoles lists, for each required role, the built-in roles that also grant the required permissions
+var satisfyingRoles = map[string][]string{
+	RoleNetworkContributor:        {RoleNetworkContributor, "Contributor", "Owner"},
+	RolePrivateDNSZoneContributor: {RolePrivateDNSZoneContributor, "Contributor", "Owner"},
+	RoleAcrPull:                   {RoleAcrPull, "AcrPush", "Contributor", "Owner"},
+	RoleKeyVaultSecretsUser:       {RoleKeyVaultSecretsUser, "Key Vault Secrets Officer", "Key Vault Administrator"},
+}
+
+// ClusterIdentity describes an identity used by the cluster
+type ClusterIdentity struct {
+	Kind        string `json:"kind"`
+	PrincipalID string `json:"principalId"`
+	ClientID    string `json:"clientId,omitempty"`
+	ResourceID  string `json:"resourceId,omitempty"`
+	// DisplayName is the name of the identity's service principal (set when Graph lookups are enabled)
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// PermissionRequirement describes a role an identity needs on a scope
+type PermissionRequirement struct {
+	IdentityKind string `json:"identityKind"`
+	PrincipalID  string `json:"principalId"`
+	Role         string `json:"role"`
+	Scope        string `json:"scope"`
+	Reason       string `json:"reason"`
+}
+
+// RoleAssignment is the subset of az role assignment list output used by the checks
+type RoleAssignment struct {
+	PrincipalID        string `json:"principalId"`
+	RoleDefinitionName string `json:"roleDefinitionName"`
+	Scope              string `json:"scope"`
+}
+
+// PermissionCheckResult is the result of evaluating a single requirement
+type PermissionCheckResult struct {
+	PermissionRequirement
+	Satisfied    bool   `json:"satisfied"`
+	GrantedBy    string `json:"grantedBy,omitempty"`
+	GrantedScope string `json:"grantedScope,omitempty"`
+	FixCommand   string `json:"fixCommand,omitempty"`
+
+	AuthorizationModel string `json:"authorizationModel,omitempty"`
+}
+
+// KeyVaultAccessPolicy is the subset of a Key Vault access policy entry used by the checks
+type KeyVaultAccessPolicy struct {
+	ObjectID    string `json:"objectId"`
+	Permissions struct {
+		Secrets []string `json:"secrets"`
+	} `json:"permissions"`
+}
+
+// KeyVaultAuthorization describes how a key vault authorizes data plane access
+type KeyVaultAuthorization struct {
+	EnableRbacAuthorization bool                   `json:"enableRbacAuthorization"`
+	AccessPolicies          []KeyVaultAccessPolicy `json:"accessPolicies"`
+}
+
+// GetClusterIdentities extracts the control plane, kubelet and addon identities from a cluster
+func GetClusterIdentities(cluster *armcontainerservice.ManagedCluster) map[string]ClusterIdentity {
+	identities := make(map[string]ClusterIdentity)
+	if cluster == nil {
+		return identities
+	}
+
+	if cluster.Identity != nil {
+		if cluster.Identity.PrincipalID != nil && *cluster.Identity.PrincipalID != "" {
+			identities[IdentityKindCluster] = ClusterIdentity{
+				Kind:        IdentityKindCluster,
+				PrincipalID: *cluster.Identity.PrincipalID,
+			}
+		} else {
+			for resourceID, uai := range cluster.Identity.UserAssignedIdentities {
+				if uai == nil || uai.PrincipalID == nil {
+					continue
+				}
+				identity := ClusterIdentity{
+					Kind:        IdentityKindCluster,
+					PrincipalID: *uai.PrincipalID,
+					ResourceID:  resourceID,
+				}
+				if uai.ClientID != nil {
+					identity.ClientID = *uai.ClientID
+				}
+				identities[IdentityKindCluster] = identity
+				break
+			}
+		}
+	}
+
+	if cluster.Properties == nil {
+		return identities
+	}
+
+	if kubelet, ok := cluster.Properties.IdentityProfile["kubeletidentity"]; ok && kubelet != nil && kubelet.ObjectID != nil {
+		identities[IdentityKindKubelet] = toClusterIdentity(IdentityKindKubelet, kubelet.ObjectID, kubelet.ClientID, kubelet.ResourceID)
+	}
+
+	if addon, ok := cluster.Properties.AddonProfiles[IdentityKindKeyVault]; ok && addon != nil &&
+		addon.Enabled != nil && *addon.Enabled && addon.Identity != nil && addon.Identity.ObjectID != nil {
+		identities[IdentityKindKeyVault] = toClusterIdentity(IdentityKindKeyVault, addon.Identity.ObjectID, addon.Identity.ClientID, addon.Identity.ResourceID)
+	}
+
+	return identities
+}
+
+// toClusterIdentity converts SDK user-assigned identity fields into a ClusterIdentity
+func toClusterIdentity(kind string, objectID, clientID, resourceID *string) ClusterIdentity {
+	identity := ClusterIdentity{Kind: kind, PrincipalID: *objectID}
+	if clientID != nil {
+		identity.ClientID = *clientID
+	}
+	if resourceID != nil {
+		identity.ResourceID = *resourceID
+	}
+	return identity
+}
+
+// BuildPermissionRequirements derives the role assignments the cluster configuration depends on
+func BuildPermissionRequirements(cluster *armcontainerservice.ManagedCluster, identities map[string]ClusterIdentity, acrIDs, keyVaultIDs []string) []PermissionRequirement {
+	var requirements []PermissionRequirement
+	if cluster == nil || cluster.Properties == nil {
+		return requirements
+	}
+
+	if clusterIdentity, ok := identities[IdentityKindCluster]; ok {
+		seen := make(map[string]bool)
+		for _, pool := range cluster.Properties.AgentPoolProfiles {
+			if pool == nil || pool.VnetSubnetID == nil || *pool.VnetSubnetID == "" {
+				continue
+			}
+			subnetID := *pool.VnetSubnetID
+			if seen[strings.ToLower(subnetID)] {
+				continue
+			}
+			seen[strings.ToLower(subnetID)] = true
+			requirements = append(requirements, PermissionRequirement{
+				IdentityKind: IdentityKindCluster,
+				PrincipalID:  clusterIdentity.PrincipalID,
+				Role:         RoleNetworkContributor,
+				Scope:        subnetID,
+				Reason:       "custom VNet subnet used by node pools requires the cluster identity to manage network resources",
+			})
+		}
+
+		if profile := cluster.Properties.APIServerAccessProfile; profile != nil && profile.PrivateDNSZone != nil &&
+			strings.HasPrefix(strings.ToLower(*profile.PrivateDNSZone), "/subscriptions/") {
+			requirements = append(requirements, PermissionRequirement{
+				IdentityKind: IdentityKindCluster,
+				PrincipalID:  clusterIdentity.PrincipalID,
+				Role:         RolePrivateDNSZoneContributor,
+				Scope:        *profile.PrivateDNSZone,
+				Reason:       "custom private DNS zone requires the cluster identity to manage API server DNS records",
+			})
+		}
+	}
+
+	if kubeletIdentity, ok := identities[IdentityKindKubelet]; ok {
+		for _, acrID := range acrIDs {
+			requirements = append(requirements, PermissionRequirement{
+				IdentityKind: IdentityKindKubelet,
+				PrincipalID:  kubeletIdentity.PrincipalID,
+				Role:         RoleAcrPull,
+				Scope:        acrID,
+				Reason:       "attached container registry requires the kubelet identity to pull images",
+			})
+		}
+	}
+
+	if kvIdentity, ok := identities[IdentityKindKeyVault]; ok {
+		for _, kvID := range keyVaultIDs {
+			requirements = append(requirements, PermissionRequirement{
+				IdentityKind: IdentityKindKeyVault,
+				PrincipalID:  kvIdentity.PrincipalID,
+				Role:         RoleKeyVaultSecretsUser,
+				Scope:        kvID,
+				Reason:       "Key Vault Secrets Provider addon requires its identity to read secrets from the key vault",
+			})
+		}
+	}
+
+	return requirements
+}
+
+// EvaluateRequirements checks each requirement against the role assignments of its principal
+func EvaluateRequirements(requirements []PermissionRequirement, assignments map[string][]RoleAssignment) []PermissionCheckResult {
+	results := make([]PermissionCheckResult, 0, len(requirements))
+	for _, req := range requirements {
+		result := PermissionCheckResult{PermissionRequirement: req}
+		for _, assignment := range assignments[req.PrincipalID] {
+			if roleSatisfies(req.Role, assignment.RoleDefinitionName) && scopeCovers(assignment.Scope, req.Scope) {
+				result.Satisfied = true
+				result.GrantedBy = assignment.RoleDefinitionName
+				result.GrantedScope = assignment.Scope
+				break
+			}
+		}
+		if !result.Satisfied {
+			result.FixCommand = BuildFixCommand(req)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ApplyKeyVaultAuthorization re-evaluates Key Vault checks for vaults that use access policies instead of RBAC.
+// Vaults are keyed by lower-case resource ID; checks for vaults that are missing from the map are left unchanged.
+func ApplyKeyVaultAuthorization(results []PermissionCheckResult, vaults map[string]KeyVaultAuthorization) {
+	for i := range results {
+		result := &results[i]
+		if result.Role != RoleKeyVaultSecretsUser {
+			continue
+		}
+		vault, ok := vaults[strings.ToLower(result.Scope)]
+		if !ok {
+			continue
+		}
+		if vault.EnableRbacAuthorization {
+			result.AuthorizationModel = AuthorizationModelRBAC
+			continue
+		}
+
+		result.AuthorizationModel = AuthorizationModelAccessPolicy
+		result.Satisfied = false
+		result.GrantedBy = ""
+		result.GrantedScope = ""
+		result.FixCommand = ""
+		for _, policy := range vault.AccessPolicies {
+			if strings.EqualFold(policy.ObjectID, result.PrincipalID) && grantsSecretGet(policy.Permissions.Secrets) {
+				result.Satisfied = true
+				result.GrantedBy = "access policy"
+				result.GrantedScope = result.Scope
+				break
+			}
+		}
+		if !result.Satisfied {
+			result.FixCommand = BuildAccessPolicyFixCommand(result.PermissionRequirement)
+		}
+	}
+}
+
+// BuildAccessPolicyFixCommand returns the az command that grants secret read access through a vault access policy
+func BuildAccessPolicyFixCommand(req PermissionRequirement) string {
+	return fmt.Sprintf("az keyvault set-policy --name %s --object-id %s --secret-permissions get list",
+		resourceName(req.Scope), req.PrincipalID)
+}
+
+// grantsSecretGet reports whether a list of secret permissions allows reading secret values
+func grantsSecretGet(permissions []string) bool {
+	for _, permission := range permissions {
+		if strings.EqualFold(permission, "get") || strings.EqualFold(permission, "all") {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceName returns the last segment of an Azure resource ID
+func resourceName(resourceID string) string {
+	trimmed := strings.TrimSuffix(resourceID, "/")
+	return trimmed[strings.LastIndex(trimmed, "/")+1:]
+}
+
+// BuildFixCommand returns the az command that grants the required role
+func BuildFixCommand(req PermissionRequirement) string {
+	return fmt.Sprintf("az role assignment create --assignee-object-id %s --assignee-principal-type ServicePrincipal --role \"%s\" --scope %s",
+		req.PrincipalID, req.Role, req.Scope)
+}
+
+// roleSatisfies reports whether an assigned role grants the permissions of the required role
+func roleSatisfies(required, assigned string) bool {
+	for _, role := range satisfyingRoles[required] {
+		if strings.EqualFold(role, assigned) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeCovers reports whether an assignment at assignedScope applies to targetScope
+func scopeCovers(assignedScope, targetScope string) bool {
+	assigned := strings.TrimSuffix(strings.ToLower(assignedScope), "/")
+	target := strings.TrimSuffix(strings.ToLower(targetScope), "/")
+	if assigned == "" || assigned == "/" {
+		return true
+	}
+	return target == assigned || strings.HasPrefix(target, assigned+"/")
+}
+
+// parseResourceIDList splits a comma-separated list of resource IDs
+func parseResourceIDList(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(id); trimmed != "" {
+			ids = append(ids, trimmed)
+		}
+	}
+	return ids
+}
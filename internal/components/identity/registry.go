@@ -0,0 +1,141 @@
+package identity
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterCheckIdentityPermissionsTool registers the check_identity_permissions tool
+func RegisterCheckIdentityPermissionsTool() mcp.Tool {
+	description := `Verify that the AKS cluster (control plane) identity, kubelet identity and addon identities hold the role assignments required by the cluster configuration.
+
+Checks performed:
+- Network Contributor for the cluster identity on custom VNet subnets used by node pools
+- Private DNS Zone Contributor for the cluster identity on a custom private DNS zone
+- AcrPull for the kubelet identity on attached container registries (acr_resource_ids)
+- Key Vault Secrets User for the Key Vault CSI addon identity on key vaults (key_vault_resource_ids)
+
+Each missing role is reported with the exact az role assignment create command to fix it.`
+
+	return mcp.NewTool(
+		"check_identity_permissions",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("acr_resource_ids",
+			mcp.Description("Optional comma-separated list of Azure Container Registry resource IDs the kubelet identity should be able to pull from"),
+		),
+		mcp.WithString("key_vault_resource_ids",
+			mcp.Description("Optional comma-separated list of Key Vault resource IDs the Key Vault Secrets Provider addon should be able to read secrets from"),
+		),
+	)
+}
+
+// RegisterSetupWorkloadIdentityTool registers the setup_workload_identity tool
+func RegisterSetupWorkloadIdentityTool() mcp.Tool {
+	description := `Set up Azure AD workload identity for an application running on the AKS cluster.
+
+Steps performed:
+- Enables the OIDC issuer and workload identity on the cluster if needed
+- Creates the user-assigned managed identity, or links an existing one
+- Adds a federated credential for the service account subject and the cluster OIDC issuer
+- Returns the service account YAML annotated with the identity client ID
+
+Runs as a dry-run preview by default; set dry_run to "false" to apply the changes.`
+
+	return mcp.NewTool(
+		"setup_workload_identity",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Kubernetes namespace of the application service account"),
+			mcp.Required(),
+		),
+		mcp.WithString("service_account",
+			mcp.Description("Name of the Kubernetes service account used by the application"),
+			mcp.Required(),
+		),
+		mcp.WithString("identity_name",
+			mcp.Description("Name of the user-assigned managed identity to create or link"),
+			mcp.Required(),
+		),
+		mcp.WithString("identity_resource_group",
+			mcp.Description("Resource group of the managed identity (defaults to the cluster resource group)"),
+		),
+		mcp.WithString("location",
+			mcp.Description("Location for a new managed identity (defaults to the cluster location)"),
+		),
+		mcp.WithString("federated_credential_name",
+			mcp.Description("Name of the federated credential (defaults to <cluster>-<namespace>-<service_account>)"),
+		),
+		mcp.WithString("dry_run",
+			mcp.Description("Preview the changes without applying them (default: true)"),
+			mcp.Enum("true", "false"),
+		),
+	)
+}
+
+// RegisterDiagnoseWorkloadIdentityTool registers the diagnose_workload_identity tool
+func RegisterDiagnoseWorkloadIdentityTool() mcp.Tool {
+	description := `Diagnose a workload's Azure AD workload identity setup end to end and pinpoint the broken link.
+
+Links checked, in order:
+- Cluster OIDC issuer and workload identity webhook enabled
+- Pod label azure.workload.identity/use, webhook injected AZURE_* environment and projected token volume (when pod is given)
+- Service account azure.workload.identity/client-id annotation, and that the running pod has the same client ID
+- Managed identity with that client ID, and a federated credential matching the cluster issuer, the service account subject and the api://AzureADTokenExchange audience exactly
+- With test_token_exchange (admin access level only): reads the pod's projected token with kubectl exec, checks its claims and exchanges it with Microsoft Entra ID, mapping AADSTS errors to the link they point at. The token is never returned.
+
+The result lists each check as pass, fail, warn or skipped, with brokenLink set to the first failure.`
+
+	return mcp.NewTool(
+		"diagnose_workload_identity",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Kubernetes namespace of the workload"),
+			mcp.Required(),
+		),
+		mcp.WithString("pod",
+			mcp.Description("Pod of the workload; its service account is used when service_account is not set"),
+		),
+		mcp.WithString("service_account",
+			mcp.Description("Service account of the workload (required when pod is not set)"),
+		),
+		mcp.WithString("test_token_exchange",
+			mcp.Description("Exchange the pod's projected token with Microsoft Entra ID to test the federation live; requires pod and the admin access level (default: false)"),
+			mcp.Enum("true", "false"),
+		),
+	)
+}
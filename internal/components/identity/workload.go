@@ -0,0 +1,132 @@
+package identity
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// workloadIdentityAudience is the token audience used by Azure AD workload identity federation
+const workloadIdentityAudience = "api://AzureADTokenExchange"
+
+// dnsLabelPattern matches Kubernetes namespace and service account names (RFC 1123 labels/subdomains)
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// azureNamePattern matches managed identity, resource group and federated credential names
+var azureNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._()-]{0,126}[A-Za-z0-9_)]?$`)
+
+// WorkloadIdentityRequest describes the workload identity to set up
+type WorkloadIdentityRequest struct {
+	SubscriptionID        string `json:"subscriptionId"`
+	ClusterResourceGroup  string `json:"clusterResourceGroup"`
+	ClusterName           string `json:"clusterName"`
+	Namespace             string `json:"namespace"`
+	ServiceAccount        string `json:"serviceAccount"`
+	IdentityName          string `json:"identityName"`
+	IdentityResourceGroup string `json:"identityResourceGroup"`
+	FederatedCredential   string `json:"federatedCredentialName"`
+	Location              string `json:"location,omitempty"`
+}
+
+// ClusterOIDCState is the subset of cluster configuration needed for workload identity
+type ClusterOIDCState struct {
+	Location                string `json:"location"`
+	OIDCIssuerEnabled       bool   `json:"oidcIssuerEnabled"`
+	IssuerURL               string `json:"issuerUrl"`
+	WorkloadIdentityEnabled bool   `json:"workloadIdentityEnabled"`
+}
+
+// WorkloadIdentityStep is a single step of the setup plan
+type WorkloadIdentityStep struct {
+	Description string `json:"description"`
+	Command     string `json:"command,omitempty"`
+	Skipped     bool   `json:"skipped,omitempty"`
+	Executed    bool   `json:"executed,omitempty"`
+}
+
+// Validate checks that all names are present and safe to use in commands and manifests
+func (r *WorkloadIdentityRequest) Validate() error {
+	if !dnsLabelPattern.MatchString(r.Namespace) {
+		return fmt.Errorf("invalid namespace '%s': must be a lowercase RFC 1123 name", r.Namespace)
+	}
+	if !dnsLabelPattern.MatchString(r.ServiceAccount) {
+		return fmt.Errorf("invalid service_account '%s': must be a lowercase RFC 1123 name", r.ServiceAccount)
+	}
+	for label, value := range map[string]string{
+		"identity_name":             r.IdentityName,
+		"identity_resource_group":   r.IdentityResourceGroup,
+		"federated_credential_name": r.FederatedCredential,
+	} {
+		if !azureNamePattern.MatchString(value) {
+			return fmt.Errorf("invalid %s '%s'", label, value)
+		}
+	}
+	return nil
+}
+
+// Subject returns the federated credential subject for the service account
+func (r *WorkloadIdentityRequest) Subject() string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", r.Namespace, r.ServiceAccount)
+}
+
+// BuildWorkloadIdentityPlan returns the ordered steps needed to set up workload identity.
+// identityExists and credentialExists skip creation of resources that are already present.
+func BuildWorkloadIdentityPlan(req WorkloadIdentityRequest, state ClusterOIDCState, identityExists, credentialExists bool) []WorkloadIdentityStep {
+	var steps []WorkloadIdentityStep
+
+	if !state.OIDCIssuerEnabled || !state.WorkloadIdentityEnabled {
+		steps = append(steps, WorkloadIdentityStep{
+			Description: "Enable the OIDC issuer and workload identity on the cluster",
+			Command: fmt.Sprintf("az aks update --resource-group %s --name %s --subscription %s --enable-oidc-issuer --enable-workload-identity",
+				req.ClusterResourceGroup, req.ClusterName, req.SubscriptionID),
+		})
+	}
+
+	location := req.Location
+	if location == "" {
+		location = state.Location
+	}
+	identityStep := WorkloadIdentityStep{
+		Description: fmt.Sprintf("Create user-assigned managed identity '%s'", req.IdentityName),
+		Command: fmt.Sprintf("az identity create --name %s --resource-group %s --subscription %s --location %s",
+			req.IdentityName, req.IdentityResourceGroup, req.SubscriptionID, location),
+	}
+	if identityExists {
+		identityStep.Description = fmt.Sprintf("Link existing user-assigned managed identity '%s'", req.IdentityName)
+		identityStep.Skipped = true
+	}
+	steps = append(steps, identityStep)
+
+	issuer := state.IssuerURL
+	if issuer == "" {
+		issuer = "<cluster OIDC issuer URL>"
+	}
+	credentialStep := WorkloadIdentityStep{
+		Description: fmt.Sprintf("Add federated credential '%s' for subject %s", req.FederatedCredential, req.Subject()),
+		Command: fmt.Sprintf("az identity federated-credential create --name %s --identity-name %s --resource-group %s --subscription %s --issuer %s --subject %s --audience %s",
+			req.FederatedCredential, req.IdentityName, req.IdentityResourceGroup, req.SubscriptionID, issuer, req.Subject(), workloadIdentityAudience),
+	}
+	if credentialExists {
+		credentialStep.Description = fmt.Sprintf("Federated credential '%s' already exists", req.FederatedCredential)
+		credentialStep.Skipped = true
+	}
+	steps = append(steps, credentialStep)
+
+	return steps
+}
+
+// BuildServiceAccountYAML renders the service account manifest annotated for workload identity
+func BuildServiceAccountYAML(namespace, serviceAccount, clientID string) string {
+	if clientID == "" {
+		clientID = "<client ID of the managed identity>"
+	}
+	return fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %s
+  namespace: %s
+  annotations:
+    azure.workload.identity/client-id: "%s"
+# Pods using this service account must also carry the label:
+#   azure.workload.identity/use: "true"
+`, serviceAccount, namespace, clientID)
+}
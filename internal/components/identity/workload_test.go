@@ -0,0 +1,223 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// fakeAzExecutor returns canned output for commands matching a prefix and records every command run
+type fakeAzExecutor struct {
+	responses map[string]string
+	failures  map[string]string
+	commands  []string
+}
+
+func (f *fakeAzExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix, output := range f.failures {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, fmt.Errorf("exit status 3")
+		}
+	}
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return "{}", nil
+}
+
+func newWorkloadIdentityRequest() WorkloadIdentityRequest {
+	return WorkloadIdentityRequest{
+		SubscriptionID:        "sub",
+		ClusterResourceGroup:  "rg",
+		ClusterName:           "aks",
+		Namespace:             "apps",
+		ServiceAccount:        "web",
+		IdentityName:          "web-identity",
+		IdentityResourceGroup: "rg",
+		FederatedCredential:   "aks-apps-web",
+	}
+}
+
+func TestRegisterSetupWorkloadIdentityTool(t *testing.T) {
+	tool := RegisterSetupWorkloadIdentityTool()
+
+	if tool.Name != "setup_workload_identity" {
+		t.Errorf("Expected tool name 'setup_workload_identity', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 6 {
+		t.Errorf("Expected 6 required parameters, got %v", tool.InputSchema.Required)
+	}
+	if _, ok := tool.InputSchema.Properties["dry_run"]; !ok {
+		t.Error("Expected dry_run parameter")
+	}
+}
+
+func TestWorkloadIdentityRequestValidate(t *testing.T) {
+	req := newWorkloadIdentityRequest()
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Expected valid request, got %v", err)
+	}
+	if req.Subject() != "system:serviceaccount:apps:web" {
+		t.Errorf("Unexpected subject %s", req.Subject())
+	}
+
+	invalid := req
+	invalid.Namespace = "Apps;rm"
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected invalid namespace to be rejected")
+	}
+
+	invalid = req
+	invalid.IdentityName = "id --debug"
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected invalid identity name to be rejected")
+	}
+}
+
+func TestBuildWorkloadIdentityPlan(t *testing.T) {
+	req := newWorkloadIdentityRequest()
+
+	steps := BuildWorkloadIdentityPlan(req, ClusterOIDCState{Location: "eastus"}, false, false)
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 steps when OIDC is disabled, got %d", len(steps))
+	}
+	if !strings.Contains(steps[0].Command, "--enable-oidc-issuer --enable-workload-identity") {
+		t.Errorf("Expected first step to enable OIDC, got %s", steps[0].Command)
+	}
+	if !strings.Contains(steps[1].Command, "--location eastus") {
+		t.Errorf("Expected identity to default to cluster location, got %s", steps[1].Command)
+	}
+
+	state := ClusterOIDCState{OIDCIssuerEnabled: true, WorkloadIdentityEnabled: true, IssuerURL: "https://issuer/"}
+	steps = BuildWorkloadIdentityPlan(req, state, true, false)
+	if len(steps) != 2 {
+		t.Fatalf("Expected 2 steps when OIDC is enabled, got %d", len(steps))
+	}
+	if !steps[0].Skipped {
+		t.Error("Expected identity creation to be skipped for an existing identity")
+	}
+	credential := steps[1].Command
+	for _, want := range []string{"--issuer https://issuer/", "--subject system:serviceaccount:apps:web", "--audience api://AzureADTokenExchange"} {
+		if !strings.Contains(credential, want) {
+			t.Errorf("Expected federated credential command to contain %q, got %s", want, credential)
+		}
+	}
+}
+
+func TestBuildServiceAccountYAML(t *testing.T) {
+	yaml := BuildServiceAccountYAML("apps", "web", "client-id")
+	for _, want := range []string{"name: web", "namespace: apps", `azure.workload.identity/client-id: "client-id"`} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", want, yaml)
+		}
+	}
+}
+
+func TestHandleSetupWorkloadIdentity(t *testing.T) {
+	params := map[string]interface{}{
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+		"namespace":       "apps",
+		"service_account": "web",
+		"identity_name":   "web-identity",
+	}
+	cluster := `{"location":"eastus","oidcIssuerProfile":{"enabled":true,"issuerUrl":"https://issuer/"},"securityProfile":{"workloadIdentity":{"enabled":true}}}`
+
+	t.Run("dry run does not make changes", func(t *testing.T) {
+		executor := &fakeAzExecutor{
+			responses: map[string]string{"az aks show": cluster},
+			failures:  map[string]string{"az identity show": "ERROR: (ResourceNotFound) not found"},
+		}
+		cfg := config.NewConfig()
+
+		output, err := HandleSetupWorkloadIdentity(params, executor, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, cmd := range executor.commands {
+			if strings.Contains(cmd, " create ") {
+				t.Errorf("Expected no create commands in dry run, got %s", cmd)
+			}
+		}
+
+		var result WorkloadIdentityResult
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		if !result.DryRun || len(result.Steps) != 2 {
+			t.Errorf("Expected dry run with 2 steps, got %+v", result)
+		}
+	})
+
+	t.Run("apply requires write access", func(t *testing.T) {
+		applyParams := map[string]interface{}{"dry_run": "false"}
+		for k, v := range params {
+			applyParams[k] = v
+		}
+		cfg := config.NewConfig()
+		cfg.AccessLevel = "readonly"
+
+		if _, err := HandleSetupWorkloadIdentity(applyParams, &fakeAzExecutor{}, cfg); err == nil {
+			t.Error("Expected error applying changes with readonly access")
+		}
+	})
+
+	t.Run("invalid dry_run is rejected", func(t *testing.T) {
+		invalidParams := map[string]interface{}{"dry_run": "no"}
+		for k, v := range params {
+			invalidParams[k] = v
+		}
+		executor := &fakeAzExecutor{}
+		if _, err := HandleSetupWorkloadIdentity(invalidParams, executor, config.NewConfig()); err == nil {
+			t.Error("Expected error for a dry_run value other than true or false")
+		}
+		if len(executor.commands) != 0 {
+			t.Errorf("Expected no commands, got %v", executor.commands)
+		}
+	})
+
+	t.Run("apply creates identity and credential", func(t *testing.T) {
+		applyParams := map[string]interface{}{"dry_run": "false"}
+		for k, v := range params {
+			applyParams[k] = v
+		}
+		executor := &fakeAzExecutor{
+			responses: map[string]string{
+				"az aks show":        cluster,
+				"az identity create": `{"clientId":"new-client"}`,
+			},
+			failures: map[string]string{"az identity show": "ERROR: (ResourceNotFound) not found"},
+		}
+		cfg := config.NewConfig()
+		cfg.AccessLevel = "readwrite"
+
+		output, err := HandleSetupWorkloadIdentity(applyParams, executor, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result WorkloadIdentityResult
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		if result.ClientID != "new-client" {
+			t.Errorf("Expected client ID from identity create, got %s", result.ClientID)
+		}
+		for _, step := range result.Steps {
+			if !step.Executed {
+				t.Errorf("Expected step %q to be executed", step.Description)
+			}
+		}
+		if !strings.Contains(result.ServiceAccountYAML, "new-client") {
+			t.Error("Expected service account YAML to carry the new client ID")
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/aks-mcp/internal/spool"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
 	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/environment"
@@ -30,6 +32,8 @@ var KubernetesFlags = genericclioptions.NewConfigFlags(false)
 type GadgetManager interface {
 	// RunGadget runs a gadget with the given parameters for a specified duration
 	RunGadget(ctx context.Context, image string, params map[string]string, duration time.Duration) (string, error)
+	// StreamGadget runs a gadget for a specified duration and passes each event to handle as JSON
+	StreamGadget(ctx context.Context, image string, params map[string]string, duration time.Duration, handle func(data []byte)) error
 	// StartGadget starts a gadget with the given parameters
 	StartGadget(ctx context.Context, image string, params map[string]string, tags []string) (string, error)
 	// StopGadget stops a running gadget by its ID
@@ -70,44 +74,65 @@ type manager struct {
 
 // RunGadget runs a gadget with the specified image and parameters for a given duration
 func (g *manager) RunGadget(ctx context.Context, image string, params map[string]string, duration time.Duration) (string, error) {
-	var results strings.Builder
+	// Results are spooled to disk beyond the spool threshold, so a busy gadget does not hold them all in memory
+	results := spool.New(spool.DefaultThreshold)
+	defer func() { _ = results.Close() }()
+	err := g.StreamGadget(ctx, image, params, duration, func(data []byte) {
+		_, _ = results.Write(append(data, '\n'))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return truncateResults(results, false)
+}
+
+// StreamGadget runs a gadget with the specified image and parameters for a given duration and passes the JSON
+// of each event to handle as it arrives, without keeping or truncating the results
+func (g *manager) StreamGadget(ctx context.Context, image string, params map[string]string, duration time.Duration, handle func(data []byte)) error {
 	gadgetCtx := gadgetcontext.New(
 		ctx,
 		image,
-		gadgetcontext.WithDataOperators(
-			g.outputDataOperator(func(data []byte) {
-				results.Write(data)
-				results.WriteByte('\n')
-			}),
-		),
+		gadgetcontext.WithDataOperators(g.outputDataOperator(handle)),
 		gadgetcontext.WithTimeout(duration),
 	)
 
 	rt, err := getRuntime()
 	if err != nil {
-		return "", fmt.Errorf("getting runtime: %w", err)
+		return fmt.Errorf("getting runtime: %w", err)
 	}
 
 	if err := rt.RunGadget(gadgetCtx, rt.ParamDescs().ToParams(), params); err != nil {
-		return "", fmt.Errorf("running gadget: %w", err)
+		return fmt.Errorf("running gadget: %w", err)
 	}
-
-	return truncateResults(results.String(), false), nil
+	return nil
 }
 
-func truncateResults(results string, latest bool) string {
-	if len(results) <= maxResultLen {
-		return fmt.Sprintf("\n<results>%s</results>\n", results)
+// truncateResults returns the spooled results, or the first or latest maxResultLen bytes of them,
+// reading only the part that is returned
+func truncateResults(results *spool.Writer, latest bool) (string, error) {
+	size := results.Len()
+	if size <= maxResultLen {
+		text, err := results.String()
+		if err != nil {
+			return "", fmt.Errorf("reading results: %w", err)
+		}
+		return fmt.Sprintf("\n<results>%s</results>\n", text), nil
 	}
 
 	var truncated string
+	var err error
 	if latest {
-		truncated = results[len(results)-maxResultLen:]
+		truncated, err = results.Slice(size-maxResultLen, maxResultLen)
 	} else {
-		truncated = results[:maxResultLen] + "…"
+		truncated, err = results.Slice(0, maxResultLen)
+		truncated += "…"
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading results: %w", err)
 	}
 
-	return fmt.Sprintf("\n<isTruncated>true</isTruncated>\n<results>%s</results>\n", truncated)
+	return fmt.Sprintf("\n<isTruncated>true</isTruncated>\n<results>%s</results>\n", truncated), nil
 }
 
 func (g *manager) outputDataOperator(cb func(data []byte)) operators.DataOperator {
@@ -198,14 +223,14 @@ func (g *manager) GetResults(ctx context.Context, id string) (string, error) {
 	to, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	var results strings.Builder
+	results := spool.New(spool.DefaultThreshold)
+	defer func() { _ = results.Close() }()
 	gadgetCtx := gadgetcontext.New(
 		to,
 		id,
 		gadgetcontext.WithDataOperators(
 			g.outputDataOperator(func(data []byte) {
-				results.Write(data)
-				results.WriteByte('\n')
+				_, _ = results.Write(append(data, '\n'))
 			}),
 		),
 		gadgetcontext.WithID(id),
@@ -222,7 +247,7 @@ func (g *manager) GetResults(ctx context.Context, id string) (string, error) {
 		return "", fmt.Errorf("attaching to gadget: %w", err)
 	}
 
-	return truncateResults(results.String(), true), nil
+	return truncateResults(results, true)
 }
 
 // ListGadgets lists all running gadgets and returns their instances
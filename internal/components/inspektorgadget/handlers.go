@@ -250,10 +250,14 @@ func handleLifecycleAction(mgr GadgetManager, deployed bool, action string, acti
 		fmt.Fprintf(os.Stderr, "Failed to get latest version: %v\n", err)
 	}
 
-	hc, err := newHelmClient(cfg.Verbose)
+	var hc HelmClient
+	hc, err = newHelmClient(cfg.Verbose)
 	if err != nil {
 		return "", fmt.Errorf("creating helm client: %w", err)
 	}
+	if cfg.Review != nil {
+		hc = reviewHelmClient{HelmClient: hc, plan: cfg.Review}
+	}
 
 	switch action {
 	case isDeployedAction:
@@ -415,3 +419,33 @@ func isGadgetAccessAllowed(gadget *GadgetInstance, cfg *config.ConfigData) bool
 
 	return true
 }
+
+// SampleTCPConnects runs the observe_tcp gadget for duration in the namespaces the security policy allows and
+// passes the JSON of each TCP connect event to handle. It returns ErrNotDeployed when Inspektor Gadget is not
+// deployed.
+func SampleTCPConnects(ctx context.Context, mgr GadgetManager, duration time.Duration, cfg *config.ConfigData, handle func(data []byte)) error {
+	deployed, _, err := mgr.IsDeployed(ctx)
+	if err != nil {
+		return fmt.Errorf("checking Inspektor Gadget deployment: %w", err)
+	}
+	if !deployed {
+		return ErrNotDeployed
+	}
+
+	gadget, _ := getGadgetByName(observeTCP)
+	filterParams := map[string]interface{}{observeTCP + ".event_type": "connect"}
+	gadgetParams, err := prepareCommonParams(filterParams, cfg)
+	if err != nil {
+		return fmt.Errorf("preparing common parameters: %w", err)
+	}
+	gadget.ParamsFunc(filterParams, gadgetParams)
+
+	ver, err := mgr.GetVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get inspektor gadget version: %v\n", err)
+	}
+	if err := mgr.StreamGadget(ctx, gadget.getImage(ver), gadgetParams, duration, handle); err != nil {
+		return fmt.Errorf("running gadget: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,6 +35,18 @@ func (m *mockGadgetManager) RunGadget(ctx context.Context, image string, params
 	return m.runResult, nil
 }
 
+func (m *mockGadgetManager) StreamGadget(ctx context.Context, image string, params map[string]string, duration time.Duration, handle func(data []byte)) error {
+	if m.runError != nil {
+		return m.runError
+	}
+	for _, line := range strings.Split(strings.TrimSpace(m.runResult), "\n") {
+		if line != "" {
+			handle([]byte(line))
+		}
+	}
+	return nil
+}
+
 func (m *mockGadgetManager) StartGadget(ctx context.Context, image string, params map[string]string, tags []string) (string, error) {
 	if m.startError != nil {
 		return "", m.startError
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/Azure/aks-mcp/internal/review"
+
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
@@ -146,3 +149,41 @@ func (c *helmClient) debugLog(format string, args ...any) {
 		fmt.Fprintf(os.Stderr, format, args...)
 	}
 }
+
+// reviewHelmClient defers installs, upgrades and uninstalls to a review plan as the equivalent helm commands
+type reviewHelmClient struct {
+	HelmClient
+	plan *review.Plan
+}
+
+func (c reviewHelmClient) InstallChart(chartUrl, releaseName, namespace string) (string, error) {
+	return "", c.deferCommand(fmt.Sprintf("helm install %s %s --namespace %s --create-namespace --wait --timeout 5m", releaseName, helmChartRef(chartUrl), namespace))
+}
+
+func (c reviewHelmClient) UninstallChart(releaseName, namespace string) (string, error) {
+	return "", c.deferCommand(fmt.Sprintf("helm uninstall %s --namespace %s --no-hooks --timeout 5m", releaseName, namespace))
+}
+
+func (c reviewHelmClient) UpgradeChart(chartUrl, releaseName, namespace string) (string, error) {
+	return "", c.deferCommand(fmt.Sprintf("helm upgrade %s %s --namespace %s --wait --timeout 5m", releaseName, helmChartRef(chartUrl), namespace))
+}
+
+func (c reviewHelmClient) deferCommand(command string) error {
+	return c.plan.Defer(review.Change{
+		Kind:    review.KindHelm,
+		Command: command,
+		Artifacts: []review.Artifact{{
+			Format:      review.FormatScript,
+			Description: "Run from a pipeline with access to the cluster",
+			Content:     review.Script(command),
+		}},
+	})
+}
+
+// helmChartRef turns a chart URL with a version suffix into helm CLI arguments
+func helmChartRef(chartUrl string) string {
+	if i := strings.LastIndex(chartUrl, ":"); i > strings.Index(chartUrl, "://")+2 {
+		return chartUrl[:i] + " --version " + chartUrl[i+1:]
+	}
+	return chartUrl
+}
@@ -0,0 +1,659 @@
+// Package jobs analyzes failing Kubernetes Jobs and CronJobs: failed runs, pod backoff
+// histories and missed CronJob schedules, ranked by how urgently they need attention.
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// defaultReportedJobs bounds the unhealthy jobs returned when limit is not given
+	defaultReportedJobs = 20
+	// maxAttempts and maxEvents bound the pod attempts and events listed per job
+	maxAttempts = 5
+	maxEvents   = 5
+	// recentRuns is how many of a CronJob's newest jobs make up its run history
+	recentRuns = 5
+	// scheduleGrace is how late a scheduled run may start before it counts as missed
+	scheduleGrace = 2 * time.Minute
+	// maxMissedRuns matches the CronJob controller, which stops scheduling after 100 missed start times
+	maxMissedRuns = 100
+)
+
+// Scores rank unhealthy jobs, most urgent first
+const (
+	scoreFailed         = 100
+	scoreMissedSchedule = 80
+	scoreFailingPods    = 60
+	scoreFlaky          = 30
+)
+
+// podWaitingFailures are container waiting reasons that keep a job's pod from ever running
+var podWaitingFailures = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// JobRun is the state of one run of a Job
+type JobRun struct {
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Active         int        `json:"active,omitempty"`
+	Succeeded      int        `json:"succeeded,omitempty"`
+	Failed         int        `json:"failed,omitempty"`
+	BackoffLimit   int        `json:"backoffLimit"`
+}
+
+// PodAttempt is one pod a Job created, with why it failed
+type PodAttempt struct {
+	Pod     string    `json:"pod"`
+	Created time.Time `json:"created"`
+	Phase   string    `json:"phase"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// UnhealthyJob is a Job or CronJob that needs attention
+type UnhealthyJob struct {
+	Kind            string       `json:"kind"`
+	Namespace       string       `json:"namespace"`
+	Name            string       `json:"name"`
+	Score           int          `json:"score"`
+	Problem         string       `json:"problem"`
+	Causes          []string     `json:"causes"`
+	LastRun         *JobRun      `json:"lastRun,omitempty"`
+	RunHistory      string       `json:"runHistory,omitempty"`
+	MissedSchedules int          `json:"missedSchedules,omitempty"`
+	Attempts        []PodAttempt `json:"backoffHistory,omitempty"`
+	Events          []string     `json:"events,omitempty"`
+	// lastActivity orders jobs of the same score, most recent first
+	lastActivity time.Time
+}
+
+// JobsReport is the result returned by the aks_job_failures tool
+type JobsReport struct {
+	JobsChecked     int            `json:"jobsChecked"`
+	CronJobsChecked int            `json:"cronJobsChecked"`
+	Unhealthy       []UnhealthyJob `json:"unhealthy"`
+	Truncated       int            `json:"truncated,omitempty"`
+	Note            string         `json:"note"`
+}
+
+type condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+type metadata struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	Labels            map[string]string `json:"labels"`
+	OwnerReferences   []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"ownerReferences"`
+}
+
+// Job is the subset of a batch/v1 Job used by the analysis
+type Job struct {
+	Metadata metadata `json:"metadata"`
+	Spec     struct {
+		BackoffLimit          *int   `json:"backoffLimit"`
+		ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds"`
+	} `json:"spec"`
+	Status struct {
+		Active         int         `json:"active"`
+		Succeeded      int         `json:"succeeded"`
+		Failed         int         `json:"failed"`
+		StartTime      *time.Time  `json:"startTime"`
+		CompletionTime *time.Time  `json:"completionTime"`
+		Conditions     []condition `json:"conditions"`
+	} `json:"status"`
+}
+
+// CronJob is the subset of a batch/v1 CronJob used by the analysis
+type CronJob struct {
+	Metadata metadata `json:"metadata"`
+	Spec     struct {
+		Schedule                string  `json:"schedule"`
+		TimeZone                *string `json:"timeZone"`
+		Suspend                 bool    `json:"suspend"`
+		ConcurrencyPolicy       string  `json:"concurrencyPolicy"`
+		StartingDeadlineSeconds *int64  `json:"startingDeadlineSeconds"`
+	} `json:"spec"`
+	Status struct {
+		Active []struct {
+			Name string `json:"name"`
+		} `json:"active"`
+		LastScheduleTime   *time.Time `json:"lastScheduleTime"`
+		LastSuccessfulTime *time.Time `json:"lastSuccessfulTime"`
+	} `json:"status"`
+}
+
+type containerStatus struct {
+	Name  string `json:"name"`
+	State struct {
+		Waiting *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"waiting"`
+		Terminated *struct {
+			ExitCode int    `json:"exitCode"`
+			Reason   string `json:"reason"`
+		} `json:"terminated"`
+	} `json:"state"`
+	LastState struct {
+		Terminated *struct {
+			ExitCode int    `json:"exitCode"`
+			Reason   string `json:"reason"`
+		} `json:"terminated"`
+	} `json:"lastState"`
+}
+
+// Pod is the subset of a pod used to explain job failures
+type Pod struct {
+	Metadata metadata `json:"metadata"`
+	Status   struct {
+		Phase                 string            `json:"phase"`
+		Reason                string            `json:"reason"`
+		Message               string            `json:"message"`
+		Conditions            []condition       `json:"conditions"`
+		InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+		ContainerStatuses     []containerStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// Event is the subset of a Kubernetes event used for correlation
+type Event struct {
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+	Reason        string    `json:"reason"`
+	Message       string    `json:"message"`
+	Count         int       `json:"count"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+	EventTime     time.Time `json:"eventTime"`
+	Source        struct {
+		Component string `json:"component"`
+	} `json:"source"`
+}
+
+// Inventory is everything the analysis reads from the cluster
+type Inventory struct {
+	Jobs     []Job
+	CronJobs []CronJob
+	Pods     []Pod
+	Events   []Event
+}
+
+// GetJobFailuresHandler returns a handler for the aks_job_failures command
+func GetJobFailuresHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleJobFailures(params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleJobFailures lists Jobs, CronJobs, their pods and warning events and returns the ranked unhealthy jobs
+func HandleJobFailures(params map[string]interface{}, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	limit := defaultReportedJobs
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = int(n)
+	}
+	flags := common.NamespaceFlags(cfg.AllowNamespaces)
+	if namespace, _ := params["namespace"].(string); namespace != "" {
+		if !common.NamespacePattern.MatchString(namespace) {
+			return "", fmt.Errorf("invalid namespace parameter: %s", namespace)
+		}
+		if !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(namespace) {
+			return "", fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+		}
+		flags = []string{"--namespace " + namespace}
+	}
+
+	var inv Inventory
+	for _, flag := range flags {
+		for _, list := range []struct {
+			command string
+			decode  func(output string) error
+		}{
+			{"get jobs " + flag + " -o json", func(output string) error { return common.DecodeList(output, &inv.Jobs) }},
+			{"get cronjobs " + flag + " -o json", func(output string) error { return common.DecodeList(output, &inv.CronJobs) }},
+			{"get pods " + flag + " -l job-name -o json", func(output string) error { return common.DecodeList(output, &inv.Pods) }},
+			{"get events " + flag + " --field-selector type=Warning -o json", func(output string) error { return common.DecodeList(output, &inv.Events) }},
+		} {
+			output, err := executor.Execute(map[string]interface{}{"command": list.command}, cfg)
+			if err != nil {
+				return "", fmt.Errorf("failed to run kubectl %s: %v", list.command, err)
+			}
+			if err := list.decode(output); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	report := AnalyzeJobs(inv, time.Now())
+	if len(report.Unhealthy) > limit {
+		report.Truncated = len(report.Unhealthy) - limit
+		report.Unhealthy = report.Unhealthy[:limit]
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// AnalyzeJobs ranks the failing Jobs and CronJobs of an inventory, most urgent first.
+// Jobs created by a CronJob are reported as part of the CronJob.
+func AnalyzeJobs(inv Inventory, now time.Time) JobsReport {
+	report := JobsReport{
+		JobsChecked:     len(inv.Jobs),
+		CronJobsChecked: len(inv.CronJobs),
+		Unhealthy:       []UnhealthyJob{},
+		Note: "Events come from the job and cronjob controllers in kube-controller-manager and are kept for about an hour. " +
+			"For older missed schedules, query the kube-controller-manager category with az_monitoring control_plane_logs, " +
+			"which requires the category to be enabled in the cluster's diagnostic settings.",
+	}
+
+	podsByJob := make(map[string][]Pod)
+	for _, pod := range inv.Pods {
+		if job := pod.Metadata.Labels["job-name"]; job != "" {
+			key := pod.Metadata.Namespace + "/" + job
+			podsByJob[key] = append(podsByJob[key], pod)
+		}
+	}
+	events := groupEvents(inv.Events, podsByJob)
+
+	jobsByCronJob := make(map[string][]Job)
+	for _, job := range inv.Jobs {
+		if owner := cronJobOwner(job); owner != "" {
+			key := job.Metadata.Namespace + "/" + owner
+			jobsByCronJob[key] = append(jobsByCronJob[key], job)
+			continue
+		}
+		if finding, ok := analyzeJob(job, podsByJob, events); ok {
+			report.Unhealthy = append(report.Unhealthy, finding)
+		}
+	}
+	for _, cronJob := range inv.CronJobs {
+		key := cronJob.Metadata.Namespace + "/" + cronJob.Metadata.Name
+		if finding, ok := analyzeCronJob(cronJob, jobsByCronJob[key], podsByJob, events, now); ok {
+			report.Unhealthy = append(report.Unhealthy, finding)
+		}
+	}
+
+	sort.SliceStable(report.Unhealthy, func(i, j int) bool {
+		a, b := report.Unhealthy[i], report.Unhealthy[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.lastActivity.After(b.lastActivity)
+	})
+	return report
+}
+
+func cronJobOwner(job Job) string {
+	for _, owner := range job.Metadata.OwnerReferences {
+		if owner.Kind == "CronJob" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// groupEvents indexes warning events by the Job or CronJob they concern; pod events count for the pod's job
+func groupEvents(events []Event, podsByJob map[string][]Pod) map[string][]Event {
+	podJob := make(map[string]string)
+	for key, pods := range podsByJob {
+		for _, pod := range pods {
+			podJob[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = key
+		}
+	}
+	grouped := make(map[string][]Event)
+	for _, event := range events {
+		object := event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name
+		switch event.InvolvedObject.Kind {
+		case "Job":
+			grouped["Job/"+object] = append(grouped["Job/"+object], event)
+		case "CronJob":
+			grouped["CronJob/"+object] = append(grouped["CronJob/"+object], event)
+		case "Pod":
+			if job, ok := podJob[object]; ok {
+				grouped["Job/"+job] = append(grouped["Job/"+job], event)
+			}
+		}
+	}
+	return grouped
+}
+
+// formatEvents returns the newest events, oldest first, as readable lines
+func formatEvents(events []Event) []string {
+	eventTime := func(e Event) time.Time {
+		if !e.LastTimestamp.IsZero() {
+			return e.LastTimestamp
+		}
+		return e.EventTime
+	}
+	sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).Before(eventTime(events[j])) })
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	var lines []string
+	for _, e := range events {
+		line := fmt.Sprintf("%s %s/%s %s: %s", eventTime(e).UTC().Format(time.RFC3339), e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Message)
+		if e.Source.Component != "" {
+			line += " (" + e.Source.Component + ")"
+		}
+		if e.Count > 1 {
+			line += fmt.Sprintf(" x%d", e.Count)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// jobStatus returns Failed, Complete, Suspended or Running for a job, with the failure condition if any
+func jobStatus(job Job) (string, *condition) {
+	for i, c := range job.Status.Conditions {
+		if c.Status != "True" {
+			continue
+		}
+		switch c.Type {
+		case "Failed":
+			return "Failed", &job.Status.Conditions[i]
+		case "Complete":
+			return "Complete", nil
+		case "Suspended":
+			return "Suspended", nil
+		}
+	}
+	return "Running", nil
+}
+
+func jobRun(job Job) *JobRun {
+	status, _ := jobStatus(job)
+	backoffLimit := 6
+	if job.Spec.BackoffLimit != nil {
+		backoffLimit = *job.Spec.BackoffLimit
+	}
+	return &JobRun{
+		Name:           job.Metadata.Name,
+		Status:         status,
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+		Active:         job.Status.Active,
+		Succeeded:      job.Status.Succeeded,
+		Failed:         job.Status.Failed,
+		BackoffLimit:   backoffLimit,
+	}
+}
+
+// analyzeJob reports a standalone job that failed or whose pods keep failing
+func analyzeJob(job Job, podsByJob map[string][]Pod, events map[string][]Event) (UnhealthyJob, bool) {
+	key := job.Metadata.Namespace + "/" + job.Metadata.Name
+	finding := UnhealthyJob{
+		Kind:         "Job",
+		Namespace:    job.Metadata.Namespace,
+		Name:         job.Metadata.Name,
+		LastRun:      jobRun(job),
+		lastActivity: job.Metadata.CreationTimestamp,
+	}
+	if !describeRun(&finding, job, podsByJob[key]) {
+		return finding, false
+	}
+	finding.Events = formatEvents(events["Job/"+key])
+	return finding, true
+}
+
+// describeRun fills in the problem, causes and backoff history of a failed or failing run.
+// It reports false when the run is healthy.
+func describeRun(finding *UnhealthyJob, job Job, pods []Pod) bool {
+	status, failed := jobStatus(job)
+	attempts, waiting := podAttempts(pods)
+	finding.Attempts = attempts
+
+	switch {
+	case status == "Failed":
+		finding.Score = scoreFailed
+		finding.Problem = fmt.Sprintf("job %s failed: %s", job.Metadata.Name, failed.Reason)
+		if !failed.LastTransitionTime.IsZero() {
+			finding.lastActivity = failed.LastTransitionTime
+		}
+		finding.Causes = append(finding.Causes, failureCause(job, failed))
+	case status == "Running" && (len(waiting) > 0 || job.Status.Failed > 0):
+		finding.Score = scoreFailingPods
+		finding.Problem = fmt.Sprintf("job %s is running but its pods are failing (%d failed so far, backoff limit %d)", job.Metadata.Name, job.Status.Failed, finding.LastRun.BackoffLimit)
+		finding.Causes = append(finding.Causes, waiting...)
+	default:
+		return false
+	}
+	if reason := commonReason(attempts); reason != "" {
+		finding.Causes = append(finding.Causes, reason)
+	}
+	return true
+}
+
+// failureCause explains the Failed condition of a job
+func failureCause(job Job, failed *condition) string {
+	switch failed.Reason {
+	case "BackoffLimitExceeded":
+		return fmt.Sprintf("every retry failed: %d pods failed, reaching the backoff limit; see the backoff history for why", job.Status.Failed)
+	case "DeadlineExceeded":
+		if job.Spec.ActiveDeadlineSeconds != nil {
+			return fmt.Sprintf("the job ran longer than activeDeadlineSeconds (%ds) and its pods were terminated", *job.Spec.ActiveDeadlineSeconds)
+		}
+		return "the job exceeded its active deadline"
+	case "PodFailurePolicy":
+		return "a pod failure matched a FailJob rule of the pod failure policy: " + failed.Message
+	}
+	if failed.Message != "" {
+		return failed.Reason + ": " + failed.Message
+	}
+	return failed.Reason
+}
+
+// podAttempts returns the newest failed pods of a job, oldest first, and why running pods are stuck
+func podAttempts(pods []Pod) ([]PodAttempt, []string) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[i].Metadata.CreationTimestamp.Before(pods[j].Metadata.CreationTimestamp)
+	})
+	var attempts []PodAttempt
+	var waiting []string
+	for _, pod := range pods {
+		reason := PodFailureReason(pod)
+		if pod.Status.Phase == "Failed" {
+			attempts = append(attempts, PodAttempt{Pod: pod.Metadata.Name, Created: pod.Metadata.CreationTimestamp, Phase: pod.Status.Phase, Reason: reason})
+		} else if reason != "" && pod.Status.Phase != "Succeeded" {
+			waiting = append(waiting, fmt.Sprintf("pod %s is %s: %s", pod.Metadata.Name, pod.Status.Phase, reason))
+		}
+	}
+	if len(attempts) > maxAttempts {
+		attempts = attempts[len(attempts)-maxAttempts:]
+	}
+	return attempts, waiting
+}
+
+// commonReason summarizes the most frequent failure reason across attempts
+func commonReason(attempts []PodAttempt) string {
+	if len(attempts) < 2 {
+		return ""
+	}
+	counts := make(map[string]int)
+	best := ""
+	for _, attempt := range attempts {
+		if attempt.Reason == "" {
+			continue
+		}
+		counts[attempt.Reason]++
+		if counts[attempt.Reason] > counts[best] {
+			best = attempt.Reason
+		}
+	}
+	if best == "" || counts[best] < 2 {
+		return ""
+	}
+	return fmt.Sprintf("%d of the last %d attempts failed the same way: %s", counts[best], len(attempts), best)
+}
+
+// PodFailureReason explains why a job's pod failed or cannot run, or returns "" when it is healthy
+func PodFailureReason(pod Pod) string {
+	if pod.Status.Reason != "" {
+		// Evicted, DeadlineExceeded and similar pod level failures
+		if pod.Status.Message != "" {
+			return pod.Status.Reason + ": " + pod.Status.Message
+		}
+		return pod.Status.Reason
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == "PodScheduled" && c.Status == "False" {
+			return fmt.Sprintf("cannot be scheduled (%s): %s", c.Reason, c.Message)
+		}
+	}
+	statuses := append(append([]containerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if w := cs.State.Waiting; w != nil && podWaitingFailures[w.Reason] {
+			reason := fmt.Sprintf("container %s is waiting: %s", cs.Name, w.Reason)
+			if w.Message != "" {
+				reason += ": " + w.Message
+			}
+			if t := cs.LastState.Terminated; t != nil && w.Reason == "CrashLoopBackOff" {
+				reason += fmt.Sprintf(" (last exit code %d, %s)", t.ExitCode, t.Reason)
+			}
+			return reason
+		}
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			if t.Reason == "OOMKilled" {
+				return fmt.Sprintf("container %s was OOMKilled: raise its memory limit", cs.Name)
+			}
+			return fmt.Sprintf("container %s exited with code %d (%s)", cs.Name, t.ExitCode, t.Reason)
+		}
+	}
+	return ""
+}
+
+// analyzeCronJob reports a CronJob whose last run failed, that missed schedules or that fails intermittently
+func analyzeCronJob(cronJob CronJob, jobs []Job, podsByJob map[string][]Pod, events map[string][]Event, now time.Time) (UnhealthyJob, bool) {
+	ns, name := cronJob.Metadata.Namespace, cronJob.Metadata.Name
+	finding := UnhealthyJob{Kind: "CronJob", Namespace: ns, Name: name, lastActivity: cronJob.Metadata.CreationTimestamp}
+	if cronJob.Status.LastScheduleTime != nil {
+		finding.lastActivity = *cronJob.Status.LastScheduleTime
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Metadata.CreationTimestamp.Before(jobs[j].Metadata.CreationTimestamp)
+	})
+	if len(jobs) > recentRuns {
+		jobs = jobs[len(jobs)-recentRuns:]
+	}
+	failedRuns := 0
+	for _, job := range jobs {
+		if status, _ := jobStatus(job); status == "Failed" {
+			failedRuns++
+		}
+	}
+	if len(jobs) > 0 {
+		finding.RunHistory = fmt.Sprintf("%d of the last %d retained runs failed", failedRuns, len(jobs))
+	}
+
+	unhealthy := false
+	if len(jobs) > 0 {
+		last := jobs[len(jobs)-1]
+		lastKey := ns + "/" + last.Metadata.Name
+		finding.LastRun = jobRun(last)
+		if describeRun(&finding, last, podsByJob[lastKey]) {
+			unhealthy = true
+			finding.Events = formatEvents(events["Job/"+lastKey])
+		}
+	}
+
+	if missed, causes := missedSchedules(cronJob, now); missed > 0 {
+		finding.MissedSchedules = missed
+		if !unhealthy {
+			finding.Score = scoreMissedSchedule + min(missed, 20)
+			finding.Problem = fmt.Sprintf("cronjob %s missed %d scheduled runs", name, missed)
+		}
+		finding.Causes = append(finding.Causes, causes...)
+		unhealthy = true
+	}
+
+	if !unhealthy && failedRuns > 0 {
+		finding.Score = scoreFlaky
+		finding.Problem = fmt.Sprintf("cronjob %s fails intermittently: %s", name, finding.RunHistory)
+		unhealthy = true
+	}
+	if !unhealthy {
+		return finding, false
+	}
+	if t := cronJob.Status.LastSuccessfulTime; t != nil {
+		finding.Causes = append(finding.Causes, "the last successful run finished at "+t.UTC().Format(time.RFC3339))
+	} else if len(jobs) > 0 {
+		finding.Causes = append(finding.Causes, "no run has succeeded yet")
+	}
+	finding.Events = append(formatEvents(events["CronJob/"+ns+"/"+name]), finding.Events...)
+	return finding, true
+}
+
+// missedSchedules counts the scheduled runs since the last one that did not start, and explains why
+func missedSchedules(cronJob CronJob, now time.Time) (int, []string) {
+	if cronJob.Spec.Suspend {
+		return 0, nil
+	}
+	schedule, err := ParseSchedule(cronJob.Spec.Schedule)
+	if err != nil {
+		return 0, nil
+	}
+	// Without a time zone the controller uses the kube-controller-manager's, which is UTC on AKS
+	location := time.UTC
+	if cronJob.Spec.TimeZone != nil && *cronJob.Spec.TimeZone != "" {
+		if loc, err := time.LoadLocation(*cronJob.Spec.TimeZone); err == nil {
+			location = loc
+		}
+	}
+	last := cronJob.Metadata.CreationTimestamp
+	if cronJob.Status.LastScheduleTime != nil {
+		last = *cronJob.Status.LastScheduleTime
+	}
+	missed, first := schedule.MissedRuns(last.In(location), now.Add(-scheduleGrace).In(location), maxMissedRuns)
+	if missed == 0 {
+		return 0, nil
+	}
+
+	causes := []string{fmt.Sprintf("no run started since %s; the first missed run was due at %s", last.UTC().Format(time.RFC3339), first.UTC().Format(time.RFC3339))}
+	switch {
+	case len(cronJob.Status.Active) > 0 && cronJob.Spec.ConcurrencyPolicy == "Forbid":
+		causes = append(causes, fmt.Sprintf("concurrencyPolicy is Forbid and job %s is still active, so runs are skipped until it finishes", cronJob.Status.Active[0].Name))
+	case missed >= maxMissedRuns && cronJob.Spec.StartingDeadlineSeconds == nil:
+		causes = append(causes, "more than 100 start times were missed, so the controller stopped scheduling (TooManyMissedTimes); set startingDeadlineSeconds so it only counts recent misses")
+	case cronJob.Spec.StartingDeadlineSeconds != nil:
+		causes = append(causes, fmt.Sprintf("runs that cannot start within startingDeadlineSeconds (%ds) are skipped; check the controller events and whether the deadline is too short", *cronJob.Spec.StartingDeadlineSeconds))
+	default:
+		causes = append(causes, "the cronjob controller did not create the jobs; check its events for FailedCreate (quota, admission webhooks or policy)")
+	}
+	return missed, causes
+}
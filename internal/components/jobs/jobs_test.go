@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// fakeKubectl returns canned output for commands matching a prefix and records every command run
+type fakeKubectl struct {
+	responses map[string]string
+	commands  []string
+}
+
+func (f *fakeKubectl) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return `{"items":[]}`, nil
+}
+
+var testNow = time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+func ts(d time.Duration) string {
+	return testNow.Add(d).Format(time.RFC3339)
+}
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2025, 6, 10, 12, 7, 30, 0, time.UTC) // a Tuesday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 6, 10, 12, 15, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * mon-fri", time.Date(2025, 6, 11, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 9 1 jan,jul *", time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)},
+		// Day of month and day of week both restricted: either matches
+		{"0 0 13 * 3", time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.spec, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: expected next run %s, got %s", tt.spec, tt.want, got)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+
+	hourly, _ := ParseSchedule("@hourly")
+	count, first := hourly.MissedRuns(testNow.Add(-5*time.Hour), testNow, 100)
+	if count != 5 || !first.Equal(testNow.Add(-4*time.Hour)) {
+		t.Errorf("Expected 5 missed runs starting 4 hours ago, got %d from %s", count, first)
+	}
+	if count, _ := hourly.MissedRuns(testNow.Add(-500*time.Hour), testNow, 100); count != 100 {
+		t.Errorf("Expected missed runs to stop at the limit, got %d", count)
+	}
+}
+
+func testInventory(t *testing.T) Inventory {
+	t.Helper()
+	jobs := `{"items":[
+  {"metadata":{"name":"migrate","namespace":"apps","creationTimestamp":"` + ts(-3*time.Hour) + `"},
+   "spec":{"backoffLimit":2},
+   "status":{"failed":3,"startTime":"` + ts(-3*time.Hour) + `","conditions":[{"type":"Failed","status":"True","reason":"BackoffLimitExceeded","lastTransitionTime":"` + ts(-2*time.Hour) + `"}]}},
+  {"metadata":{"name":"seed","namespace":"apps","creationTimestamp":"` + ts(-time.Hour) + `"},
+   "status":{"succeeded":1,"conditions":[{"type":"Complete","status":"True"}]}},
+  {"metadata":{"name":"report-1","namespace":"apps","creationTimestamp":"` + ts(-48*time.Hour) + `","ownerReferences":[{"kind":"CronJob","name":"report"}]},
+   "status":{"conditions":[{"type":"Failed","status":"True","reason":"DeadlineExceeded"}]}},
+  {"metadata":{"name":"report-2","namespace":"apps","creationTimestamp":"` + ts(-24*time.Hour) + `","ownerReferences":[{"kind":"CronJob","name":"report"}]},
+   "status":{"succeeded":1,"conditions":[{"type":"Complete","status":"True"}]}},
+  {"metadata":{"name":"sync-1","namespace":"apps","creationTimestamp":"` + ts(-5*time.Hour) + `","ownerReferences":[{"kind":"CronJob","name":"sync"}]},
+   "status":{"active":1}}
+]}`
+	cronJobs := `{"items":[
+  {"metadata":{"name":"report","namespace":"apps","creationTimestamp":"` + ts(-72*time.Hour) + `"},
+   "spec":{"schedule":"0 12 * * *"},
+   "status":{"lastScheduleTime":"` + ts(-24*time.Hour) + `"}},
+  {"metadata":{"name":"sync","namespace":"apps","creationTimestamp":"` + ts(-72*time.Hour) + `"},
+   "spec":{"schedule":"@hourly","concurrencyPolicy":"Forbid"},
+   "status":{"active":[{"name":"sync-1"}],"lastScheduleTime":"` + ts(-5*time.Hour) + `"}},
+  {"metadata":{"name":"paused","namespace":"apps","creationTimestamp":"` + ts(-72*time.Hour) + `"},
+   "spec":{"schedule":"@hourly","suspend":true}}
+]}`
+	pods := `{"items":[
+  {"metadata":{"name":"migrate-a","namespace":"apps","creationTimestamp":"` + ts(-170*time.Minute) + `","labels":{"job-name":"migrate"}},
+   "status":{"phase":"Failed","containerStatuses":[{"name":"migrate","state":{"terminated":{"exitCode":137,"reason":"OOMKilled"}}}]}},
+  {"metadata":{"name":"migrate-b","namespace":"apps","creationTimestamp":"` + ts(-160*time.Minute) + `","labels":{"job-name":"migrate"}},
+   "status":{"phase":"Failed","containerStatuses":[{"name":"migrate","state":{"terminated":{"exitCode":137,"reason":"OOMKilled"}}}]}},
+  {"metadata":{"name":"migrate-c","namespace":"apps","creationTimestamp":"` + ts(-150*time.Minute) + `","labels":{"job-name":"migrate"}},
+   "status":{"phase":"Failed","reason":"Evicted","message":"The node was low on resource: memory."}}
+]}`
+	events := `{"items":[
+  {"involvedObject":{"kind":"Pod","name":"migrate-c","namespace":"apps"},"reason":"Evicted","message":"low on memory","count":1,"lastTimestamp":"` + ts(-150*time.Minute) + `","source":{"component":"kubelet"}},
+  {"involvedObject":{"kind":"Job","name":"migrate","namespace":"apps"},"reason":"BackoffLimitExceeded","message":"Job has reached the specified backoff limit","lastTimestamp":"` + ts(-2*time.Hour) + `","source":{"component":"job-controller"}}
+]}`
+
+	var inv Inventory
+	for output, target := range map[string]func(string) error{
+		jobs:     func(o string) error { return common.DecodeList(o, &inv.Jobs) },
+		cronJobs: func(o string) error { return common.DecodeList(o, &inv.CronJobs) },
+		pods:     func(o string) error { return common.DecodeList(o, &inv.Pods) },
+		events:   func(o string) error { return common.DecodeList(o, &inv.Events) },
+	} {
+		if err := target(output); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return inv
+}
+
+func TestAnalyzeJobs(t *testing.T) {
+	report := AnalyzeJobs(testInventory(t), testNow)
+
+	if report.JobsChecked != 5 || report.CronJobsChecked != 3 {
+		t.Errorf("Unexpected counts: %+v", report)
+	}
+	var order []string
+	for _, job := range report.Unhealthy {
+		order = append(order, job.Kind+"/"+job.Name)
+	}
+	if strings.Join(order, ",") != "Job/migrate,CronJob/sync,CronJob/report" {
+		t.Fatalf("Expected failed job, missed schedules then flaky cronjob, got %v", order)
+	}
+
+	migrate := report.Unhealthy[0]
+	if len(migrate.Attempts) != 3 || migrate.Attempts[0].Pod != "migrate-a" || !strings.Contains(migrate.Attempts[0].Reason, "OOMKilled") {
+		t.Errorf("Expected the backoff history oldest first, got %+v", migrate.Attempts)
+	}
+	causes := strings.Join(migrate.Causes, "\n")
+	if !strings.Contains(causes, "3 pods failed, reaching the backoff limit") || !strings.Contains(causes, "2 of the last 3 attempts failed the same way") {
+		t.Errorf("Unexpected causes: %v", migrate.Causes)
+	}
+	if len(migrate.Events) != 2 || !strings.Contains(migrate.Events[1], "(job-controller)") {
+		t.Errorf("Expected pod and job events, got %v", migrate.Events)
+	}
+
+	sync := report.Unhealthy[1]
+	if sync.MissedSchedules != 4 || !strings.Contains(strings.Join(sync.Causes, "\n"), "concurrencyPolicy is Forbid and job sync-1 is still active") {
+		t.Errorf("Expected missed schedules explained by Forbid, got %+v", sync)
+	}
+
+	report2 := report.Unhealthy[2]
+	if report2.RunHistory != "1 of the last 2 retained runs failed" || report2.LastRun.Name != "report-2" {
+		t.Errorf("Expected an intermittently failing cronjob, got %+v", report2)
+	}
+}
+
+func TestPodFailureReason(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{`{"phase":"Pending","conditions":[{"type":"PodScheduled","status":"False","reason":"Unschedulable","message":"0/3 nodes are available"}]}`, "cannot be scheduled (Unschedulable)"},
+		{`{"phase":"Pending","containerStatuses":[{"name":"app","state":{"waiting":{"reason":"ImagePullBackOff","message":"not found"}}}]}`, "container app is waiting: ImagePullBackOff: not found"},
+		{`{"phase":"Running","containerStatuses":[{"name":"app","state":{"waiting":{"reason":"CrashLoopBackOff"}},"lastState":{"terminated":{"exitCode":2,"reason":"Error"}}}]}`, "last exit code 2"},
+		{`{"phase":"Failed","initContainerStatuses":[{"name":"init","state":{"terminated":{"exitCode":1,"reason":"Error"}}}]}`, "container init exited with code 1"},
+		{`{"phase":"Running","containerStatuses":[{"name":"app","state":{"running":{}}}]}`, ""},
+	}
+	for _, tt := range tests {
+		var pod Pod
+		if err := json.Unmarshal([]byte(`{"status":`+tt.status+`}`), &pod); err != nil {
+			t.Fatal(err)
+		}
+		got := PodFailureReason(pod)
+		if (tt.want == "" && got != "") || !strings.Contains(got, tt.want) {
+			t.Errorf("Expected reason containing %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestHandleJobFailures(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "apps,batch"
+
+	executor := &fakeKubectl{responses: map[string]string{
+		"get jobs --namespace apps": `{"items":[{"metadata":{"name":"migrate","namespace":"apps"},"status":{"conditions":[{"type":"Failed","status":"True","reason":"DeadlineExceeded"}]}},
+  {"metadata":{"name":"other","namespace":"apps"},"status":{"conditions":[{"type":"Failed","status":"True","reason":"BackoffLimitExceeded"}]}}]}`,
+	}}
+	output, err := HandleJobFailures(map[string]interface{}{"limit": float64(1)}, executor, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(executor.commands) != 8 || executor.commands[6] != "get pods --namespace batch -l job-name -o json" {
+		t.Errorf("Expected four lists per allowed namespace, got %v", executor.commands)
+	}
+	var report JobsReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.JobsChecked != 2 || len(report.Unhealthy) != 1 || report.Truncated != 1 {
+		t.Errorf("Expected the report to be limited to one job, got %+v", report)
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"namespace": "kube-system"},
+		{"namespace": "Bad_Name"},
+		{"limit": "ten"},
+	} {
+		if _, err := HandleJobFailures(params, &fakeKubectl{}, cfg); err == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterJobFailuresTool registers the aks_job_failures tool
+func RegisterJobFailuresTool() mcp.Tool {
+	description := fmt.Sprintf(`Analyze failing Jobs and CronJobs across the allowed namespaces and rank the unhealthy ones with their causes.
+
+Reported for each unhealthy job:
+- Last run status, with the Failed condition explained (backoff limit, active deadline, pod failure policy)
+- Backoff history: the newest failed pods with why each failed (exit code, OOMKilled, eviction, image pull, scheduling)
+- For CronJobs: run history of the retained jobs, missed schedules since the last run (computed from the schedule
+  and time zone) and why runs did not start (concurrencyPolicy Forbid, startingDeadlineSeconds, more than 100 missed times)
+- Warning events from the job and cronjob controllers of kube-controller-manager, and from the job's pods
+
+Failed jobs rank first, then missed schedules, jobs running with failing pods and intermittently failing CronJobs.
+Jobs created by a CronJob are reported under the CronJob. Uses the current kubeconfig context.
+Returns at most limit jobs (default %d).`, defaultReportedJobs)
+
+	return mcp.NewTool(
+		"aks_job_failures",
+		mcp.WithDescription(description),
+		mcp.WithString("namespace",
+			mcp.Description("Only analyze jobs in this namespace (default: all allowed namespaces)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of unhealthy jobs to return (default: %d)", defaultReportedJobs)),
+		),
+	)
+}
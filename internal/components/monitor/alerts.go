@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// alertsAPIVersion is the Alerts Management API version used to list alerts
+const alertsAPIVersion = "2019-05-05-preview"
+
+// supportedAlertTimeRanges are the time ranges accepted by the Alerts Management API
+var supportedAlertTimeRanges = []string{"1h", "1d", "7d", "30d"}
+
+// FiredAlert is a summarized Azure Monitor alert
+type FiredAlert struct {
+	Name             string `json:"name"`
+	Severity         string `json:"severity"`
+	MonitorCondition string `json:"monitorCondition"`
+	AlertState       string `json:"alertState"`
+	SignalType       string `json:"signalType,omitempty"`
+	MonitorService   string `json:"monitorService,omitempty"`
+	TargetResource   string `json:"targetResource"`
+	StartDateTime    string `json:"startDateTime,omitempty"`
+	ResolvedDateTime string `json:"resolvedDateTime,omitempty"`
+	AlertRule        string `json:"alertRule,omitempty"`
+}
+
+// FiredAlertsReport is the result of the fired_alerts operation
+type FiredAlertsReport struct {
+	ClusterName       string         `json:"clusterName"`
+	NodeResourceGroup string         `json:"nodeResourceGroup,omitempty"`
+	TimeRange         string         `json:"timeRange"`
+	FiredCount        int            `json:"firedCount"`
+	ResolvedCount     int            `json:"resolvedCount"`
+	BySeverity        map[string]int `json:"firedBySeverity"`
+	Alerts            []FiredAlert   `json:"alerts"`
+}
+
+// alertListResponse is the Alerts Management API list response
+type alertListResponse struct {
+	Value []struct {
+		Name       string `json:"name"`
+		Properties struct {
+			Essentials struct {
+				Severity                         string `json:"severity"`
+				SignalType                       string `json:"signalType"`
+				AlertState                       string `json:"alertState"`
+				MonitorCondition                 string `json:"monitorCondition"`
+				MonitorService                   string `json:"monitorService"`
+				TargetResource                   string `json:"targetResource"`
+				AlertRule                        string `json:"alertRule"`
+				StartDateTime                    string `json:"startDateTime"`
+				MonitorConditionResolvedDateTime string `json:"monitorConditionResolvedDateTime"`
+			} `json:"essentials"`
+		} `json:"properties"`
+	} `json:"value"`
+	NextLink string `json:"nextLink"`
+}
+
+// HandleFiredAlertsQuery lists fired and recently resolved alerts targeting the cluster and its node resource group
+func HandleFiredAlertsQuery(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	timeRange := "1d"
+	if value, ok := params["time_range"].(string); ok && value != "" {
+		if !slices.Contains(supportedAlertTimeRanges, value) {
+			return "", fmt.Errorf("invalid time_range parameter, must be one of: %s", strings.Join(supportedAlertTimeRanges, ", "))
+		}
+		timeRange = value
+	}
+	includeResolved := true
+	if value, ok := params["include_resolved"].(string); ok && value == "false" {
+		includeResolved = false
+	}
+
+	ctx := context.Background()
+	cluster, err := common.GetClusterDetails(ctx, azClient, subID, rg, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	if cluster.ID != nil {
+		clusterID = *cluster.ID
+	}
+	nodeRG := ""
+	if cluster.Properties != nil && cluster.Properties.NodeResourceGroup != nil {
+		nodeRG = *cluster.Properties.NodeResourceGroup
+	}
+
+	var responses [][]byte
+	for _, targetRG := range []string{rg, nodeRG} {
+		if targetRG == "" {
+			continue
+		}
+		body, err := listAlerts(ctx, azClient, subID, targetRG, timeRange)
+		if err != nil {
+			return "", fmt.Errorf("failed to list alerts for resource group %s: %w", targetRG, err)
+		}
+		responses = append(responses, body...)
+	}
+
+	alerts, err := ParseAlerts(responses)
+	if err != nil {
+		return "", err
+	}
+	report := BuildFiredAlertsReport(alerts, clusterID, nodeRG, includeResolved)
+	report.ClusterName = clusterName
+	report.NodeResourceGroup = nodeRG
+	report.TimeRange = timeRange
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal alerts report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// listAlerts retrieves every page of alerts targeting a resource group
+func listAlerts(ctx context.Context, azClient *azureclient.AzureClient, subscriptionID, resourceGroup, timeRange string) ([][]byte, error) {
+	apiPath := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.AlertsManagement/alerts?api-version=%s&targetResourceGroup=%s&timeRange=%s",
+		url.PathEscape(subscriptionID), alertsAPIVersion, url.QueryEscape(resourceGroup), url.QueryEscape(timeRange))
+
+	var pages [][]byte
+	for apiPath != "" {
+		body, err := azClient.CallARM(ctx, http.MethodGet, apiPath)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, body)
+
+		var page alertListResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse alerts response: %w", err)
+		}
+		apiPath = page.NextLink
+	}
+	return pages, nil
+}
+
+// ParseAlerts flattens Alerts Management API list responses into alert summaries
+func ParseAlerts(pages [][]byte) ([]FiredAlert, error) {
+	var alerts []FiredAlert
+	for _, body := range pages {
+		var page alertListResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse alerts response: %w", err)
+		}
+		for _, item := range page.Value {
+			e := item.Properties.Essentials
+			alerts = append(alerts, FiredAlert{
+				Name:             item.Name,
+				Severity:         e.Severity,
+				MonitorCondition: e.MonitorCondition,
+				AlertState:       e.AlertState,
+				SignalType:       e.SignalType,
+				MonitorService:   e.MonitorService,
+				TargetResource:   e.TargetResource,
+				StartDateTime:    e.StartDateTime,
+				ResolvedDateTime: e.MonitorConditionResolvedDateTime,
+				AlertRule:        e.AlertRule,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+// BuildFiredAlertsReport keeps alerts targeting the cluster (or any resource in the node resource group),
+// sorts fired alerts first by severity and counts them
+func BuildFiredAlertsReport(alerts []FiredAlert, clusterID, nodeResourceGroup string, includeResolved bool) FiredAlertsReport {
+	report := FiredAlertsReport{BySeverity: map[string]int{}, Alerts: []FiredAlert{}}
+	nodeRGPrefix := ""
+	if nodeResourceGroup != "" {
+		nodeRGPrefix = strings.ToLower(fmt.Sprintf("/resourceGroups/%s/", nodeResourceGroup))
+	}
+	seen := make(map[string]bool)
+
+	clusterID = strings.TrimSuffix(strings.ToLower(clusterID), "/")
+	for _, alert := range alerts {
+		target := strings.TrimSuffix(strings.ToLower(alert.TargetResource), "/")
+		relevant := target == clusterID || strings.HasPrefix(target, clusterID+"/") ||
+			(nodeRGPrefix != "" && strings.Contains(target, nodeRGPrefix))
+		if !relevant || seen[alert.Name+alert.StartDateTime] {
+			continue
+		}
+		seen[alert.Name+alert.StartDateTime] = true
+
+		switch alert.MonitorCondition {
+		case "Fired":
+			report.FiredCount++
+			report.BySeverity[alert.Severity]++
+		case "Resolved":
+			if !includeResolved {
+				continue
+			}
+			report.ResolvedCount++
+		}
+		report.Alerts = append(report.Alerts, alert)
+	}
+
+	sort.SliceStable(report.Alerts, func(i, j int) bool {
+		a, b := report.Alerts[i], report.Alerts[j]
+		if (a.MonitorCondition == "Fired") != (b.MonitorCondition == "Fired") {
+			return a.MonitorCondition == "Fired"
+		}
+		if a.Severity != b.Severity {
+			return a.Severity < b.Severity
+		}
+		return a.StartDateTime > b.StartDateTime
+	})
+	return report
+}
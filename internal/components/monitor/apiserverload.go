@@ -0,0 +1,590 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// API versions used by the API server load analysis
+const (
+	diagnosticSettingsAPIVersion = "2021-05-01-preview"
+	workspaceAPIVersion          = "2022-10-01"
+)
+
+// Audit logs are large, so the analysis window is kept short
+const (
+	defaultLoadWindow  = time.Hour
+	maxLoadWindow      = 7 * 24 * time.Hour
+	defaultLoadClients = 10
+	maxLoadClients     = 50
+)
+
+// apiServerInflightMetric is the platform metric of requests the API server is serving, split by requestKind
+const apiServerInflightMetric = "apiserver_current_inflight_requests"
+
+// Default kube-apiserver inflight limits (--max-requests-inflight and --max-mutating-requests-inflight)
+var defaultInflightLimits = map[string]float64{
+	"readOnly": 400,
+	"mutating": 200,
+}
+
+// Upstream API call latency SLOs: p99 of single-object calls within 1s and of list calls within 30s
+const (
+	objectLatencySLOMs = 1000
+	listLatencySLOMs   = 30000
+)
+
+// Thresholds for calling out throttling and clients that dominate the load
+const (
+	throttledPercentThreshold  = 1.0
+	dominantClientSharePercent = 25.0
+)
+
+// longRunningURIFragments mark requests that stay open by design, so their duration is not latency
+var longRunningURIFragments = []string{"watch=true", "/exec", "/attach", "/portforward", "/proxy", "/log?", "follow=true"}
+
+// InflightSeries summarizes the inflight requests of one request kind over the window
+type InflightSeries struct {
+	RequestKind string  `json:"requestKind"`
+	Average     float64 `json:"average"`
+	Maximum     float64 `json:"maximum"`
+	PeakTime    string  `json:"peakTime,omitempty"`
+}
+
+// VerbLatency is the request count, latency percentiles and error counts of one verb
+type VerbLatency struct {
+	Verb         string  `json:"verb"`
+	Requests     int     `json:"requests"`
+	P50Ms        float64 `json:"p50Ms"`
+	P95Ms        float64 `json:"p95Ms"`
+	P99Ms        float64 `json:"p99Ms"`
+	Throttled    int     `json:"throttled"`
+	ServerErrors int     `json:"serverErrors"`
+}
+
+// ClientLoad is the load one user agent and user put on the API server
+type ClientLoad struct {
+	UserAgent     string  `json:"userAgent"`
+	Username      string  `json:"username"`
+	Requests      int     `json:"requests"`
+	SharePercent  float64 `json:"sharePercent"`
+	Lists         int     `json:"lists"`
+	Watches       int     `json:"watches"`
+	Mutations     int     `json:"mutations"`
+	Throttled     int     `json:"throttled"`
+	ThrottledRate float64 `json:"throttledPercent"`
+}
+
+// RejectionBucket counts priority and fairness rejections in one time bucket
+type RejectionBucket struct {
+	Time       string `json:"time"`
+	Rejections int    `json:"rejections"`
+	Clients    int    `json:"clients"`
+}
+
+// AuditAnalysis is the part of the report computed from kube-audit logs
+type AuditAnalysis struct {
+	Category         string            `json:"category,omitempty"`
+	Workspace        string            `json:"workspace,omitempty"`
+	TotalRequests    int               `json:"totalRequests"`
+	Throttled        int               `json:"throttled"`
+	ThrottledPercent float64           `json:"throttledPercent"`
+	Verbs            []VerbLatency     `json:"verbs"`
+	TopClients       []ClientLoad      `json:"topClients"`
+	Rejections       []RejectionBucket `json:"rejections"`
+	Error            string            `json:"error,omitempty"`
+}
+
+// APIServerLoadReport is the result of the apiserver_load operation
+type APIServerLoadReport struct {
+	ClusterName    string           `json:"clusterName"`
+	StartTime      string           `json:"startTime"`
+	EndTime        string           `json:"endTime"`
+	Inflight       []InflightSeries `json:"inflight"`
+	InflightError  string           `json:"inflightError,omitempty"`
+	Audit          AuditAnalysis    `json:"audit"`
+	Findings       []string         `json:"findings"`
+	MetricInterval string           `json:"metricInterval"`
+}
+
+// auditDestination is the workspace and table mode a log category, such as kube-audit, is sent to
+type auditDestination struct {
+	Category          string
+	WorkspaceID       string
+	ResourceSpecific  bool
+	WorkspaceCustomer string
+}
+
+// HandleAPIServerLoadQuery analyzes API server latency, inflight requests and throttling over a window.
+// Inflight requests come from platform metrics; latency, 429 rejections and the clients causing load
+// come from kube-audit logs in the Log Analytics workspace of the cluster's diagnostic settings.
+func HandleAPIServerLoadQuery(params map[string]interface{}, api common.ARMCaller, azExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := parseLoadWindow(params, time.Now().UTC(), defaultLoadWindow)
+	if err != nil {
+		return "", err
+	}
+	top := defaultLoadClients
+	if raw, ok := params["top"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		if top, err = strconv.Atoi(value); err != nil || top <= 0 || top > maxLoadClients {
+			return "", fmt.Errorf("invalid top parameter: %s (expected 1 to %d)", value, maxLoadClients)
+		}
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	interval := loadMetricInterval(end.Sub(start))
+	report := APIServerLoadReport{
+		ClusterName:    clusterName,
+		StartTime:      start.Format(time.RFC3339),
+		EndTime:        end.Format(time.RFC3339),
+		MetricInterval: interval,
+		Inflight:       []InflightSeries{},
+		Audit:          AuditAnalysis{Verbs: []VerbLatency{}, TopClients: []ClientLoad{}, Rejections: []RejectionBucket{}},
+	}
+
+	// Metrics and audit logs are independent sources, so a failure of one still reports the other
+	if report.Inflight, err = listInflightRequests(ctx, api, clusterID, start, end, interval); err != nil {
+		report.Inflight = []InflightSeries{}
+		report.InflightError = err.Error()
+	}
+	if err := analyzeAuditLogs(ctx, &report.Audit, api, azExecutor, clusterID, start, end, top, cfg); err != nil {
+		report.Audit.Error = err.Error()
+	}
+	report.Findings = BuildLoadFindings(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal API server load report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// parseLoadWindow resolves the analysis window from start_time and end_time, defaulting to defaultWindow
+// before now
+func parseLoadWindow(params map[string]interface{}, now time.Time, defaultWindow time.Duration) (time.Time, time.Time, error) {
+	var err error
+	end := now
+	if value, ok := params["end_time"].(string); ok && value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	start := end.Add(-defaultWindow)
+	if value, ok := params["start_time"].(string); ok && value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_time must be before end_time")
+	}
+	if end.Sub(start) > maxLoadWindow {
+		return time.Time{}, time.Time{}, fmt.Errorf("the window must not exceed 7 days")
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// loadMetricInterval picks a metric granularity and audit bucket size for the window
+func loadMetricInterval(window time.Duration) string {
+	switch {
+	case window <= 6*time.Hour:
+		return "PT5M"
+	case window <= 24*time.Hour:
+		return "PT15M"
+	default:
+		return "PT1H"
+	}
+}
+
+// listInflightRequests reads the average and peak inflight requests of each request kind
+func listInflightRequests(ctx context.Context, api common.ARMCaller, clusterID string, start, end time.Time, interval string) ([]InflightSeries, error) {
+	path := fmt.Sprintf("%s/providers/Microsoft.Insights/metrics?api-version=%s&metricnames=%s&aggregation=Average,Maximum&interval=%s&timespan=%s&$filter=%s",
+		clusterID, metricsAPIVersion, apiServerInflightMetric, interval,
+		url.QueryEscape(start.Format(time.RFC3339)+"/"+end.Format(time.RFC3339)), url.QueryEscape("requestKind eq '*'"))
+	body, err := api.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", apiServerInflightMetric, err)
+	}
+	return ParseInflightSeries(body)
+}
+
+// ParseInflightSeries summarizes each requestKind time series of the inflight requests metric
+func ParseInflightSeries(body []byte) ([]InflightSeries, error) {
+	var result struct {
+		Value []struct {
+			Timeseries []struct {
+				Metadatavalues []struct {
+					Name struct {
+						Value string `json:"value"`
+					} `json:"name"`
+					Value string `json:"value"`
+				} `json:"metadatavalues"`
+				Data []struct {
+					TimeStamp string   `json:"timeStamp"`
+					Average   *float64 `json:"average"`
+					Maximum   *float64 `json:"maximum"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics response: %w", err)
+	}
+	if len(result.Value) == 0 {
+		return nil, fmt.Errorf("no %s samples returned for the window", apiServerInflightMetric)
+	}
+
+	series := []InflightSeries{}
+	for _, ts := range result.Value[0].Timeseries {
+		s := InflightSeries{RequestKind: "all"}
+		for _, md := range ts.Metadatavalues {
+			if strings.EqualFold(md.Name.Value, "requestKind") {
+				s.RequestKind = md.Value
+			}
+		}
+		var sum float64
+		samples := 0
+		for _, point := range ts.Data {
+			if point.Average != nil {
+				sum += *point.Average
+				samples++
+			}
+			if point.Maximum != nil && *point.Maximum > s.Maximum {
+				s.Maximum, s.PeakTime = *point.Maximum, point.TimeStamp
+			}
+		}
+		if samples == 0 {
+			continue
+		}
+		s.Average = roundTo(sum/float64(samples), 1)
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].RequestKind < series[j].RequestKind })
+	return series, nil
+}
+
+// analyzeAuditLogs finds the workspace receiving kube-audit logs and runs the latency, client and rejection queries
+func analyzeAuditLogs(ctx context.Context, audit *AuditAnalysis, api common.ARMCaller, azExecutor tools.CommandExecutor, clusterID string, start, end time.Time, top int, cfg *config.ConfigData) error {
+	dest, err := findAuditDestination(ctx, api, clusterID)
+	if err != nil {
+		return err
+	}
+	audit.Category, audit.Workspace = dest.Category, dest.WorkspaceID
+
+	if dest.WorkspaceCustomer, err = workspaceCustomerID(ctx, api, dest.WorkspaceID); err != nil {
+		return err
+	}
+
+	base := auditBaseQuery(dest, clusterID)
+	run := func(query string) ([]map[string]interface{}, error) {
+		return queryAuditLogs(azExecutor, dest, query, start, end, cfg)
+	}
+
+	rows, err := run(base + " | where " + shortRunningFilter() +
+		" | summarize Requests = count(), P50Ms = percentile(LatencyMs, 50), P95Ms = percentile(LatencyMs, 95), P99Ms = percentile(LatencyMs, 99)," +
+		" Throttled = countif(" + rejectedFilter + "), ServerErrors = countif(Code >= 500) by Verb | order by Requests desc")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		audit.Verbs = append(audit.Verbs, VerbLatency{
+			Verb:         rowString(row, "Verb"),
+			Requests:     int(rowNumber(row, "Requests")),
+			P50Ms:        rowNumber(row, "P50Ms"),
+			P95Ms:        rowNumber(row, "P95Ms"),
+			P99Ms:        rowNumber(row, "P99Ms"),
+			Throttled:    int(rowNumber(row, "Throttled")),
+			ServerErrors: int(rowNumber(row, "ServerErrors")),
+		})
+	}
+
+	rows, err = run(base + " | summarize Requests = count(), Lists = countif(Verb == 'list'), Watches = countif(Verb == 'watch')," +
+		" Mutations = countif(Verb in ('create', 'update', 'patch', 'delete', 'deletecollection')), Throttled = countif(" + rejectedFilter + ")" +
+		" by UserAgent, Username | as Clients | extend Total = toscalar(Clients | summarize sum(Requests)) | top " + strconv.Itoa(top) + " by Requests desc")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		audit.TopClients = append(audit.TopClients, ClientLoad{
+			UserAgent: rowString(row, "UserAgent"),
+			Username:  rowString(row, "Username"),
+			Requests:  int(rowNumber(row, "Requests")),
+			Lists:     int(rowNumber(row, "Lists")),
+			Watches:   int(rowNumber(row, "Watches")),
+			Mutations: int(rowNumber(row, "Mutations")),
+			Throttled: int(rowNumber(row, "Throttled")),
+		})
+		audit.TotalRequests = int(rowNumber(row, "Total"))
+	}
+
+	rows, err = run(base + " | where " + rejectedFilter + " | summarize Rejections = count(), Clients = dcount(UserAgent)" +
+		" by bin(TimeGenerated, " + kqlBin(loadMetricInterval(end.Sub(start))) + ") | order by TimeGenerated asc")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		audit.Rejections = append(audit.Rejections, RejectionBucket{
+			Time:       rowString(row, "TimeGenerated"),
+			Rejections: int(rowNumber(row, "Rejections")),
+			Clients:    int(rowNumber(row, "Clients")),
+		})
+	}
+
+	SummarizeAudit(audit)
+	return nil
+}
+
+// workspaceCustomerID returns the customer ID the Azure CLI queries a Log Analytics workspace by
+func workspaceCustomerID(ctx context.Context, api common.ARMCaller, workspaceID string) (string, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, workspaceID+"?api-version="+workspaceAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Log Analytics workspace %s: %w", workspaceID, err)
+	}
+	var workspace struct {
+		Properties struct {
+			CustomerID string `json:"customerId"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &workspace); err != nil || workspace.Properties.CustomerID == "" {
+		return "", fmt.Errorf("failed to read the customer ID of Log Analytics workspace %s", workspaceID)
+	}
+	return workspace.Properties.CustomerID, nil
+}
+
+// queryAuditLogs runs a KQL query over the audit destination's workspace for the window and returns its rows
+func queryAuditLogs(azExecutor tools.CommandExecutor, dest auditDestination, query string, start, end time.Time, cfg *config.ConfigData) ([]map[string]interface{}, error) {
+	output, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az monitor log-analytics query --workspace %s --analytics-query \"%s\" --timespan %s --output json",
+			dest.WorkspaceCustomer, query, start.Format(time.RFC3339)+"/"+end.Format(time.RFC3339)),
+	}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s logs: %w", dest.Category, err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse %s query results: %w", dest.Category, err)
+	}
+	return rows, nil
+}
+
+// rejectedFilter matches requests rejected by API Priority and Fairness. Evictions blocked by a
+// PodDisruptionBudget also return 429 and are excluded.
+const rejectedFilter = "Code == 429 and RequestUri !endswith '/eviction'"
+
+// shortRunningFilter excludes watches and streaming requests, whose duration is not latency
+func shortRunningFilter() string {
+	conditions := []string{"Verb != 'watch'"}
+	for _, fragment := range longRunningURIFragments {
+		conditions = append(conditions, fmt.Sprintf("RequestUri !contains '%s'", fragment))
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// auditBaseQuery selects completed audit events of the cluster with normalized columns for both table modes
+func auditBaseQuery(dest auditDestination, clusterID string) string {
+	if dest.ResourceSpecific {
+		table := "AKSAudit"
+		if dest.Category == "kube-audit-admin" {
+			table = "AKSAuditAdmin"
+		}
+		return fmt.Sprintf("%s | where _ResourceId == '%s' and Stage == 'ResponseComplete'"+
+			" | project TimeGenerated, Verb, RequestUri, UserAgent, Username = tostring(User.username), Code = toint(ResponseStatus.code),"+
+			" LatencyMs = datetime_diff('millisecond', StageReceivedTime, RequestReceivedTime)", table, strings.ToLower(clusterID))
+	}
+	return fmt.Sprintf("AzureDiagnostics | where Category == '%s' and ResourceId == '%s' | extend Event = parse_json(log_s) | where tostring(Event.stage) == 'ResponseComplete'"+
+		" | project TimeGenerated, Verb = tostring(Event.verb), RequestUri = tostring(Event.requestURI), UserAgent = tostring(Event.userAgent),"+
+		" Username = tostring(Event.user.username), Code = toint(Event.responseStatus.code),"+
+		" LatencyMs = datetime_diff('millisecond', todatetime(Event.stageTimestamp), todatetime(Event.requestReceivedTimestamp))",
+		dest.Category, strings.ToUpper(clusterID))
+}
+
+// findAuditDestination returns the workspace of the first diagnostic setting sending kube-audit logs,
+// falling back to kube-audit-admin, which omits get and list requests
+func findAuditDestination(ctx context.Context, api common.ARMCaller, clusterID string) (auditDestination, error) {
+	dest, found, err := findLogDestination(ctx, api, clusterID, []string{"kube-audit", "kube-audit-admin"}, "audit")
+	if err != nil {
+		return auditDestination{}, err
+	}
+	if !found {
+		return auditDestination{}, fmt.Errorf("no diagnostic setting sends kube-audit or kube-audit-admin logs to a Log Analytics workspace; " +
+			"enable kube-audit in the cluster's diagnostic settings")
+	}
+	return dest, nil
+}
+
+// findLogDestination returns the workspace of the first diagnostic setting sending one of the log categories,
+// in order of preference. The allLogs category group and categoryGroup, when set, include every category.
+func findLogDestination(ctx context.Context, api common.ARMCaller, clusterID string, categories []string, categoryGroup string) (auditDestination, bool, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, fmt.Sprintf("%s/providers/Microsoft.Insights/diagnosticSettings?api-version=%s", clusterID, diagnosticSettingsAPIVersion))
+	if err != nil {
+		return auditDestination{}, false, fmt.Errorf("failed to get diagnostic settings: %w", err)
+	}
+	var result struct {
+		Value []struct {
+			Properties struct {
+				WorkspaceID                 string `json:"workspaceId"`
+				LogAnalyticsDestinationType string `json:"logAnalyticsDestinationType"`
+				Logs                        []struct {
+					Category      string `json:"category"`
+					CategoryGroup string `json:"categoryGroup"`
+					Enabled       bool   `json:"enabled"`
+				} `json:"logs"`
+			} `json:"properties"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return auditDestination{}, false, fmt.Errorf("failed to parse diagnostic settings: %w", err)
+	}
+	for _, category := range categories {
+		for _, setting := range result.Value {
+			if setting.Properties.WorkspaceID == "" {
+				continue
+			}
+			for _, log := range setting.Properties.Logs {
+				group := strings.ToLower(log.CategoryGroup)
+				if log.Enabled && (log.Category == category || group == "alllogs" || (categoryGroup != "" && group == categoryGroup)) {
+					return auditDestination{
+						Category:         category,
+						WorkspaceID:      setting.Properties.WorkspaceID,
+						ResourceSpecific: strings.EqualFold(setting.Properties.LogAnalyticsDestinationType, "Dedicated"),
+					}, true, nil
+				}
+			}
+		}
+	}
+	return auditDestination{}, false, nil
+}
+
+// SummarizeAudit totals the 429 rejections and computes each client's share of the load.
+// Rejections are counted from the time buckets, since the verb table leaves out watches.
+func SummarizeAudit(audit *AuditAnalysis) {
+	audit.Throttled = 0
+	for _, bucket := range audit.Rejections {
+		audit.Throttled += bucket.Rejections
+	}
+	if audit.TotalRequests > 0 {
+		audit.ThrottledPercent = roundTo(100*float64(audit.Throttled)/float64(audit.TotalRequests), 2)
+	}
+	for i := range audit.TopClients {
+		client := &audit.TopClients[i]
+		if audit.TotalRequests > 0 {
+			client.SharePercent = roundTo(100*float64(client.Requests)/float64(audit.TotalRequests), 1)
+		}
+		if client.Requests > 0 {
+			client.ThrottledRate = roundTo(100*float64(client.Throttled)/float64(client.Requests), 1)
+		}
+	}
+}
+
+// BuildLoadFindings calls out throttling, latency SLO breaches, dominant clients and inflight peaks
+func BuildLoadFindings(report APIServerLoadReport) []string {
+	findings := []string{}
+	audit := report.Audit
+
+	if audit.Throttled > 0 {
+		finding := fmt.Sprintf("%d requests (%.2f%%) were rejected with 429 Too Many Requests by API Priority and Fairness", audit.Throttled, audit.ThrottledPercent)
+		var rejected []string
+		for _, client := range audit.TopClients {
+			if client.Throttled > 0 {
+				rejected = append(rejected, fmt.Sprintf("%s (%d)", clientName(client), client.Throttled))
+			}
+		}
+		if len(rejected) > 0 {
+			finding += "; rejected clients: " + strings.Join(rejected, ", ")
+		}
+		if audit.ThrottledPercent >= throttledPercentThreshold {
+			finding += ". Reduce the request rate of these clients or give them a FlowSchema with a dedicated priority level"
+		}
+		findings = append(findings, finding)
+	}
+
+	for _, verb := range audit.Verbs {
+		slo := float64(objectLatencySLOMs)
+		if verb.Verb == "list" {
+			slo = listLatencySLOMs
+		}
+		if verb.P99Ms > slo {
+			findings = append(findings, fmt.Sprintf("p99 latency of %s requests is %.0fms, above the %.0fms API call latency SLO", verb.Verb, verb.P99Ms, slo))
+		}
+		if verb.ServerErrors > 0 {
+			findings = append(findings, fmt.Sprintf("%d %s requests failed with a 5xx response", verb.ServerErrors, verb.Verb))
+		}
+	}
+
+	for _, client := range audit.TopClients {
+		if client.SharePercent < dominantClientSharePercent {
+			continue
+		}
+		finding := fmt.Sprintf("%s sent %.1f%% of all requests (%d lists, %d watches, %d mutations)",
+			clientName(client), client.SharePercent, client.Lists, client.Watches, client.Mutations)
+		if client.Lists > client.Requests/2 {
+			finding += "; frequent lists suggest a controller polling instead of using an informer cache"
+		}
+		findings = append(findings, finding)
+	}
+
+	for _, series := range report.Inflight {
+		if limit, ok := defaultInflightLimits[series.RequestKind]; ok && series.Maximum >= 0.8*limit {
+			findings = append(findings, fmt.Sprintf("%s inflight requests peaked at %.0f at %s, close to the default kube-apiserver limit of %.0f",
+				series.RequestKind, series.Maximum, series.PeakTime, limit))
+		}
+	}
+
+	if len(findings) == 0 && audit.Error == "" {
+		findings = append(findings, "no throttling, latency SLO breaches or dominant clients were found in the window")
+	}
+	return findings
+}
+
+// clientName identifies a client by user agent and user
+func clientName(client ClientLoad) string {
+	agent := client.UserAgent
+	if i := strings.Index(agent, " "); i > 0 {
+		agent = agent[:i]
+	}
+	if client.Username == "" {
+		return agent
+	}
+	return fmt.Sprintf("%s as %s", agent, client.Username)
+}
+
+// kqlBin converts a metric interval to a KQL timespan literal
+func kqlBin(interval string) string {
+	return strings.ToLower(strings.TrimPrefix(interval, "PT"))
+}
+
+// rowString reads a string column from a log query result row
+func rowString(row map[string]interface{}, column string) string {
+	if value, ok := row[column].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// rowNumber reads a numeric column from a log query result row. The Azure CLI returns numbers as strings.
+func rowNumber(row map[string]interface{}, column string) float64 {
+	switch value := row[column].(type) {
+	case float64:
+		return value
+	case string:
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	}
+	return 0
+}
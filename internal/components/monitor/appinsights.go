@@ -0,0 +1,372 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// appInsightsAPIVersion is the API version used to list Application Insights components
+const appInsightsAPIVersion = "2020-02-02"
+
+// appInsightsEnvVars are the environment variables Application Insights SDKs and the OpenTelemetry distro read
+var appInsightsEnvVars = []string{
+	"APPLICATIONINSIGHTS_CONNECTION_STRING",
+	"APPLICATIONINSIGHTS__CONNECTIONSTRING",
+	"APPINSIGHTS_CONNECTIONSTRING",
+	"APPINSIGHTS_INSTRUMENTATIONKEY",
+	"APPLICATIONINSIGHTS_INSTRUMENTATIONKEY",
+}
+
+var (
+	instrumentationKeyPattern = regexp.MustCompile(`(?i)instrumentationkey=([0-9a-f-]{36})`)
+	guidPattern               = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// AppInsightsCandidate is an Application Insights resource that may hold the cluster's telemetry
+type AppInsightsCandidate struct {
+	Name           string   `json:"name"`
+	ResourceGroup  string   `json:"resourceGroup"`
+	SubscriptionID string   `json:"subscriptionId"`
+	ID             string   `json:"id"`
+	Workloads      []string `json:"workloads,omitempty"`
+	Evidence       []string `json:"evidence"`
+}
+
+// AppInsightsDiscovery lists the Application Insights resources linked to the cluster
+type AppInsightsDiscovery struct {
+	ClusterName string                 `json:"clusterName"`
+	Candidates  []AppInsightsCandidate `json:"candidates"`
+	// UnresolvedReferences are workload settings whose value could not be read, such as secret references
+	UnresolvedReferences []string `json:"unresolvedReferences,omitempty"`
+	Warnings             []string `json:"warnings,omitempty"`
+	Message              string   `json:"message"`
+}
+
+// workloadReference is an Application Insights setting found on a workload
+type workloadReference struct {
+	workload string
+	source   string
+	// exactly one of key, id and name is set
+	key, id, name string
+}
+
+// HandleAppInsightsWithDiscovery runs an app_insights query, discovering the Application Insights resource
+// from the cluster's workloads, tags and resource group when app_insights_name is not given. The query runs
+// only when discovery finds a single resource; otherwise the candidates are returned for the caller to choose.
+func HandleAppInsightsWithDiscovery(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor,
+	query func(params map[string]interface{}) (string, error), cfg *config.ConfigData) (string, error) {
+	if name, _ := params["app_insights_name"].(string); name != "" {
+		return query(params)
+	}
+	if cluster, _ := params["cluster_name"].(string); cluster == "" {
+		return "", fmt.Errorf("missing or invalid app_insights_name parameter (or pass cluster_name to discover it)")
+	}
+
+	discovery, err := DiscoverAppInsights(params, api, kubectlExecutor, cfg)
+	if err != nil {
+		return "", err
+	}
+	chosen := discovery.chosen()
+	kql, _ := params["query"].(string)
+	if chosen == nil || kql == "" {
+		return marshalDiscovery(discovery)
+	}
+
+	queryParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		queryParams[k] = v
+	}
+	queryParams["subscription_id"] = chosen.SubscriptionID
+	queryParams["resource_group"] = chosen.ResourceGroup
+	queryParams["app_insights_name"] = chosen.Name
+	result, err := query(queryParams)
+	if err != nil {
+		return "", err
+	}
+
+	output := map[string]interface{}{"appInsights": chosen, "result": json.RawMessage(result)}
+	if !json.Valid([]byte(result)) {
+		output["result"] = result
+	}
+	resultJSON, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Application Insights result to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// chosen returns the candidate to query: the only one linked from a workload, or the only one found
+func (d AppInsightsDiscovery) chosen() *AppInsightsCandidate {
+	var linked []int
+	for i, candidate := range d.Candidates {
+		if len(candidate.Workloads) > 0 {
+			linked = append(linked, i)
+		}
+	}
+	switch {
+	case len(linked) == 1:
+		return &d.Candidates[linked[0]]
+	case len(linked) == 0 && len(d.Candidates) == 1:
+		return &d.Candidates[0]
+	default:
+		return nil
+	}
+}
+
+// DiscoverAppInsights finds Application Insights resources linked to the cluster through workload
+// environment variables and annotations, tags naming the cluster, or the cluster's resource group
+func DiscoverAppInsights(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (AppInsightsDiscovery, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return AppInsightsDiscovery{}, err
+	}
+	discovery := AppInsightsDiscovery{ClusterName: clusterName, Candidates: []AppInsightsCandidate{}}
+
+	var refs []workloadReference
+	if kubectlExecutor == nil {
+		discovery.Warnings = append(discovery.Warnings, "workload settings were not scanned because the k8s component is disabled or the server runs in session credential mode")
+	} else {
+		for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+			cmd := fmt.Sprintf("get deployments,statefulsets,daemonsets %s -o json", flag)
+			output, err := kubectlExecutor.Execute(map[string]interface{}{"command": cmd}, cfg)
+			if err != nil {
+				discovery.Warnings = append(discovery.Warnings, fmt.Sprintf("failed to list workloads (%s): %v", flag, err))
+				continue
+			}
+			found, unresolved, err := ParseWorkloadAppInsightsReferences(output)
+			if err != nil {
+				discovery.Warnings = append(discovery.Warnings, err.Error())
+				continue
+			}
+			refs = append(refs, found...)
+			discovery.UnresolvedReferences = append(discovery.UnresolvedReferences, unresolved...)
+		}
+	}
+
+	components, err := listAppInsightsComponents(context.Background(), api, subID)
+	if err != nil {
+		return AppInsightsDiscovery{}, err
+	}
+	clusterSuffix := strings.ToLower("/managedClusters/" + clusterName)
+	for _, component := range components {
+		candidate := AppInsightsCandidate{
+			Name:           component.Name,
+			ResourceGroup:  resourceGroupFromID(component.ID),
+			SubscriptionID: subID,
+			ID:             component.ID,
+		}
+		for _, ref := range refs {
+			if (ref.key != "" && strings.EqualFold(ref.key, component.instrumentationKey())) ||
+				(ref.id != "" && strings.EqualFold(ref.id, component.ID)) ||
+				(ref.name != "" && strings.EqualFold(ref.name, component.Name)) {
+				candidate.Workloads = appendUnique(candidate.Workloads, ref.workload)
+				candidate.Evidence = appendUnique(candidate.Evidence, fmt.Sprintf("%s %s", ref.workload, ref.source))
+			}
+		}
+		for key, value := range component.Tags {
+			lower := strings.ToLower(value)
+			if strings.EqualFold(value, clusterName) || strings.HasSuffix(lower, clusterSuffix) {
+				candidate.Evidence = append(candidate.Evidence, fmt.Sprintf("tag %s=%s names the cluster", key, value))
+			}
+		}
+		if strings.EqualFold(candidate.ResourceGroup, rg) {
+			candidate.Evidence = append(candidate.Evidence, "in the cluster's resource group")
+		}
+		if len(candidate.Evidence) > 0 {
+			sort.Strings(candidate.Evidence)
+			discovery.Candidates = append(discovery.Candidates, candidate)
+		}
+	}
+	sort.SliceStable(discovery.Candidates, func(i, j int) bool {
+		return len(discovery.Candidates[i].Workloads) > len(discovery.Candidates[j].Workloads)
+	})
+
+	switch chosen := discovery.chosen(); {
+	case len(discovery.Candidates) == 0:
+		discovery.Message = "No Application Insights resource linked to the cluster was found. Pass app_insights_name and resource_group explicitly"
+	case chosen != nil:
+		discovery.Message = fmt.Sprintf("Discovered %s in resource group %s. Pass a query to run it against this resource", chosen.Name, chosen.ResourceGroup)
+	default:
+		discovery.Message = "Several Application Insights resources are linked to the cluster. Pass app_insights_name and resource_group to choose one"
+	}
+	return discovery, nil
+}
+
+// ParseWorkloadAppInsightsReferences finds Application Insights settings in the pod templates of a kubectl workload list.
+// Settings read from secrets or config maps are returned as unresolved references; their values are never read.
+func ParseWorkloadAppInsightsReferences(output string) ([]workloadReference, []string, error) {
+	var list struct {
+		Items []struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name        string            `json:"name"`
+				Namespace   string            `json:"namespace"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Metadata struct {
+						Annotations map[string]string `json:"annotations"`
+					} `json:"metadata"`
+					Spec struct {
+						Containers []struct {
+							Name string `json:"name"`
+							Env  []struct {
+								Name      string          `json:"name"`
+								Value     string          `json:"value"`
+								ValueFrom json.RawMessage `json:"valueFrom"`
+							} `json:"env"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse workloads: %w", err)
+	}
+
+	var refs []workloadReference
+	var unresolved []string
+	for _, item := range list.Items {
+		workload := fmt.Sprintf("%s %s/%s", strings.ToLower(item.Kind), item.Metadata.Namespace, item.Metadata.Name)
+		for _, annotations := range []map[string]string{item.Metadata.Annotations, item.Spec.Template.Metadata.Annotations} {
+			for key, value := range annotations {
+				lowerKey := strings.ToLower(key)
+				if !strings.Contains(lowerKey, "applicationinsights") && !strings.Contains(lowerKey, "appinsights") {
+					continue
+				}
+				if ref, ok := appInsightsReference(value); ok {
+					ref.workload, ref.source = workload, "annotation "+key
+					refs = append(refs, ref)
+				}
+			}
+		}
+		for _, container := range item.Spec.Template.Spec.Containers {
+			for _, env := range container.Env {
+				if !isAppInsightsEnvVar(env.Name) {
+					continue
+				}
+				source := fmt.Sprintf("container %s env %s", container.Name, env.Name)
+				if env.Value == "" && len(env.ValueFrom) > 0 {
+					unresolved = append(unresolved, fmt.Sprintf("%s %s is read from a secret or config map", workload, source))
+					continue
+				}
+				if ref, ok := appInsightsReference(env.Value); ok {
+					ref.workload, ref.source = workload, source
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+	return refs, unresolved, nil
+}
+
+// appInsightsReference reads a connection string, instrumentation key, resource ID or resource name
+func appInsightsReference(value string) (workloadReference, bool) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return workloadReference{}, false
+	case instrumentationKeyPattern.MatchString(value):
+		return workloadReference{key: instrumentationKeyPattern.FindStringSubmatch(value)[1]}, true
+	case guidPattern.MatchString(value):
+		return workloadReference{key: value}, true
+	case strings.Contains(strings.ToLower(value), "/providers/microsoft.insights/components/"):
+		return workloadReference{id: value}, true
+	case !strings.ContainsAny(value, " ;=/"):
+		return workloadReference{name: value}, true
+	default:
+		return workloadReference{}, false
+	}
+}
+
+func isAppInsightsEnvVar(name string) bool {
+	for _, known := range appInsightsEnvVars {
+		if strings.EqualFold(name, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// appInsightsComponent is an Application Insights resource returned by ARM
+type appInsightsComponent struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		InstrumentationKey string `json:"InstrumentationKey"`
+		ConnectionString   string `json:"ConnectionString"`
+	} `json:"properties"`
+}
+
+// instrumentationKey returns the component's instrumentation key, falling back to its connection string
+func (c appInsightsComponent) instrumentationKey() string {
+	if c.Properties.InstrumentationKey != "" {
+		return c.Properties.InstrumentationKey
+	}
+	if match := instrumentationKeyPattern.FindStringSubmatch(c.Properties.ConnectionString); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// listAppInsightsComponents lists the Application Insights resources in a subscription
+func listAppInsightsComponents(ctx context.Context, api common.ARMCaller, subID string) ([]appInsightsComponent, error) {
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Insights/components?api-version=%s", subID, appInsightsAPIVersion)
+	var components []appInsightsComponent
+	for page := 0; next != "" && page < maxActivityLogPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Application Insights resources: %w", err)
+		}
+		var result struct {
+			Value    []appInsightsComponent `json:"value"`
+			NextLink string                 `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse Application Insights resources: %w", err)
+		}
+		components = append(components, result.Value...)
+		next = result.NextLink
+	}
+	return components, nil
+}
+
+// resourceGroupFromID returns the resource group segment of a resource ID
+func resourceGroupFromID(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func marshalDiscovery(discovery AppInsightsDiscovery) (string, error) {
+	resultJSON, err := json.MarshalIndent(discovery, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Application Insights discovery to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,490 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// resourceGroupAPIVersion is used for the resource group read that returns the request quota headers
+const resourceGroupAPIVersion = "2021-04-01"
+
+// The subscription Activity Log is large, so the window is shorter than for config_history
+const (
+	defaultThrottlingWindow  = 6 * time.Hour
+	defaultThrottlingCallers = 10
+	maxThrottlingCallers     = 50
+)
+
+// throttlingCategories are the control plane components that call ARM on behalf of the cluster
+var throttlingCategories = []string{"cloud-controller-manager", "cluster-autoscaler"}
+
+// quotaLimits are the subscription limits of the request quota headers ARM returns. The legacy counters are
+// hourly; the global counters are token buckets of the throttling model that refills per second.
+var quotaLimits = map[string]int{
+	"x-ms-ratelimit-remaining-subscription-reads":          12000,
+	"x-ms-ratelimit-remaining-subscription-writes":         1200,
+	"x-ms-ratelimit-remaining-subscription-deletes":        15000,
+	"x-ms-ratelimit-remaining-subscription-global-reads":   250,
+	"x-ms-ratelimit-remaining-subscription-global-writes":  200,
+	"x-ms-ratelimit-remaining-subscription-global-deletes": 200,
+}
+
+// quotaConsumedThreshold is the share of a request quota past which throttling is imminent
+const quotaConsumedThreshold = 80.0
+
+// ARMHeaderCaller is an common.ARMCaller that can also return response headers. *azureclient.AzureClient implements it.
+type ARMHeaderCaller interface {
+	common.ARMCaller
+	CallARMWithHeaders(ctx context.Context, method, path string) ([]byte, http.Header, error)
+}
+
+// QuotaCounter is the remaining requests of one ARM request quota
+type QuotaCounter struct {
+	Header          string  `json:"header"`
+	Remaining       int     `json:"remaining"`
+	Limit           int     `json:"limit,omitempty"`
+	ConsumedPercent float64 `json:"consumedPercent,omitempty"`
+}
+
+// RequestQuota is the request quota ARM reported for a read in the subscription
+type RequestQuota struct {
+	Counters []QuotaCounter `json:"counters"`
+	// Note explains whose quota the counters describe
+	Note  string `json:"note"`
+	Error string `json:"error,omitempty"`
+}
+
+// ThrottledCaller is a caller and operation whose requests ARM rejected with 429
+type ThrottledCaller struct {
+	Caller    string `json:"caller"`
+	Operation string `json:"operation"`
+	// ClusterIdentity names the cluster identity the caller is, such as control plane or kubelet
+	ClusterIdentity string `json:"clusterIdentity,omitempty"`
+	Count           int    `json:"count"`
+	FirstSeen       string `json:"firstSeen"`
+	LastSeen        string `json:"lastSeen"`
+}
+
+// ActivityLogThrottling is the throttled write operations of the subscription Activity Log
+type ActivityLogThrottling struct {
+	EventsRead int               `json:"eventsRead"`
+	Throttled  int               `json:"throttled"`
+	Callers    []ThrottledCaller `json:"callers"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ComponentThrottling is a group of throttling log lines of a control plane component for one resource type
+type ComponentThrottling struct {
+	Component    string `json:"component"`
+	ResourceType string `json:"resourceType,omitempty"`
+	Count        int    `json:"count"`
+	LastSeen     string `json:"lastSeen,omitempty"`
+	// MaxRetryAfterSeconds is the longest Retry-After ARM asked the component to wait
+	MaxRetryAfterSeconds int    `json:"maxRetryAfterSeconds,omitempty"`
+	Sample               string `json:"sample,omitempty"`
+}
+
+// ControllerThrottling is the throttling the cloud-controller-manager and cluster-autoscaler logged
+type ControllerThrottling struct {
+	Workspaces []string              `json:"workspaces,omitempty"`
+	Components []ComponentThrottling `json:"components"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// ARMThrottlingReport is the result of the arm_throttling operation
+type ARMThrottlingReport struct {
+	ClusterName    string                `json:"clusterName"`
+	SubscriptionID string                `json:"subscriptionId"`
+	StartTime      string                `json:"startTime"`
+	EndTime        string                `json:"endTime"`
+	Quota          RequestQuota          `json:"quota"`
+	ActivityLog    ActivityLogThrottling `json:"activityLog"`
+	Controllers    ControllerThrottling  `json:"controllers"`
+	Findings       []string              `json:"findings"`
+	Warnings       []string              `json:"warnings,omitempty"`
+}
+
+// throttlingEvent is the subset of an Activity Log event used to detect throttled requests
+type throttlingEvent struct {
+	Caller         string `json:"caller"`
+	EventTimestamp string `json:"eventTimestamp"`
+	OperationName  struct {
+		Value string `json:"value"`
+	} `json:"operationName"`
+	SubStatus struct {
+		Value          string `json:"value"`
+		LocalizedValue string `json:"localizedValue"`
+	} `json:"subStatus"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// HandleARMThrottlingQuery detects ARM request throttling in the cluster's subscription: the 429s of the
+// subscription Activity Log grouped by caller and operation, the throttling the cloud-controller-manager and
+// cluster-autoscaler logged, and the remaining request quota ARM reports
+func HandleARMThrottlingQuery(params map[string]interface{}, api ARMHeaderCaller, azExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := parseLoadWindow(params, time.Now().UTC(), defaultThrottlingWindow)
+	if err != nil {
+		return "", err
+	}
+	if start.Before(time.Now().UTC().Add(-activityLogRetention)) {
+		return "", fmt.Errorf("start_time is outside the 90 day Activity Log retention")
+	}
+	top := defaultThrottlingCallers
+	if raw, ok := params["top"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		if top, err = strconv.Atoi(value); err != nil || top <= 0 || top > maxThrottlingCallers {
+			return "", fmt.Errorf("invalid top parameter: %s (expected 1 to %d)", value, maxThrottlingCallers)
+		}
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	report := ARMThrottlingReport{
+		ClusterName:    clusterName,
+		SubscriptionID: subID,
+		StartTime:      start.Format(time.RFC3339),
+		EndTime:        end.Format(time.RFC3339),
+		Quota:          RequestQuota{Counters: []QuotaCounter{}},
+		ActivityLog:    ActivityLogThrottling{Callers: []ThrottledCaller{}},
+		Controllers:    ControllerThrottling{Components: []ComponentThrottling{}},
+	}
+
+	// The three sources are independent, so a failure of one still reports the others
+	readRequestQuota(ctx, &report.Quota, api, subID, rg)
+	identities, err := clusterIdentities(ctx, api, clusterID)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("throttled callers were not matched to cluster identities: %v", err))
+	}
+	if err := readThrottledActivity(ctx, &report.ActivityLog, api, subID, start, end, identities, top); err != nil {
+		report.ActivityLog.Error = err.Error()
+	}
+	if err := readControllerThrottling(ctx, &report.Controllers, api, azExecutor, clusterID, start, end, top, cfg); err != nil {
+		report.Controllers.Error = err.Error()
+	}
+	report.Findings = BuildThrottlingFindings(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ARM throttling report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// readRequestQuota reads the cluster's resource group and records the request quota headers of the response
+func readRequestQuota(ctx context.Context, quota *RequestQuota, api ARMHeaderCaller, subID, rg string) {
+	quota.Note = "ARM counts requests per subscription and principal, so these counters are the quota left to the identity " +
+		"this server uses, not to the cluster's identities"
+	path := fmt.Sprintf("/subscriptions/%s/resourcegroups/%s?api-version=%s", url.PathEscape(subID), url.PathEscape(rg), resourceGroupAPIVersion)
+	_, header, err := api.CallARMWithHeaders(ctx, http.MethodGet, path)
+	if err != nil {
+		quota.Error = fmt.Sprintf("failed to read the request quota: %v", err)
+		return
+	}
+	quota.Counters = ParseQuotaHeaders(header)
+	if len(quota.Counters) == 0 {
+		quota.Error = "ARM returned no request quota headers"
+	}
+}
+
+// ParseQuotaHeaders reads the x-ms-ratelimit-remaining-subscription-* headers, with the share of each known
+// limit consumed
+func ParseQuotaHeaders(header http.Header) []QuotaCounter {
+	counters := []QuotaCounter{}
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-ms-ratelimit-remaining-subscription-") || len(values) == 0 {
+			continue
+		}
+		remaining, err := strconv.Atoi(strings.TrimSpace(values[0]))
+		if err != nil {
+			continue
+		}
+		counter := QuotaCounter{Header: name, Remaining: remaining, Limit: quotaLimits[name]}
+		if counter.Limit > 0 && remaining <= counter.Limit {
+			counter.ConsumedPercent = roundTo(100*float64(counter.Limit-remaining)/float64(counter.Limit), 1)
+		}
+		counters = append(counters, counter)
+	}
+	sort.Slice(counters, func(i, j int) bool { return counters[i].Header < counters[j].Header })
+	return counters
+}
+
+// clusterIdentities maps the client and object IDs of the cluster's identities to what they are used for
+func clusterIdentities(ctx context.Context, api common.ARMCaller, clusterID string) (map[string]string, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+sloClusterAPIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+	type identity struct {
+		ClientID    string `json:"clientId"`
+		ObjectID    string `json:"objectId"`
+		PrincipalID string `json:"principalId"`
+	}
+	var cluster struct {
+		Identity struct {
+			PrincipalID            string              `json:"principalId"`
+			UserAssignedIdentities map[string]identity `json:"userAssignedIdentities"`
+		} `json:"identity"`
+		Properties struct {
+			ServicePrincipalProfile struct {
+				ClientID string `json:"clientId"`
+			} `json:"servicePrincipalProfile"`
+			IdentityProfile map[string]identity `json:"identityProfile"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster: %w", err)
+	}
+
+	identities := map[string]string{}
+	add := func(name string, ids ...string) {
+		for _, id := range ids {
+			// A service principal cluster reports the literal client ID "msi" when it uses managed identities
+			if id != "" && id != "msi" {
+				identities[strings.ToLower(id)] = name
+			}
+		}
+	}
+	add("control plane", cluster.Identity.PrincipalID, cluster.Properties.ServicePrincipalProfile.ClientID)
+	for _, uai := range cluster.Identity.UserAssignedIdentities {
+		add("control plane", uai.ClientID, uai.PrincipalID)
+	}
+	for name, profile := range cluster.Properties.IdentityProfile {
+		if name == "kubeletidentity" {
+			name = "kubelet"
+		}
+		add(name, profile.ClientID, profile.ObjectID)
+	}
+	return identities, nil
+}
+
+// readThrottledActivity reads the subscription Activity Log of the window and groups the throttled requests
+func readThrottledActivity(ctx context.Context, activity *ActivityLogThrottling, api common.ARMCaller, subID string, start, end time.Time, identities map[string]string, top int) error {
+	filter := fmt.Sprintf("eventTimestamp ge '%s' and eventTimestamp le '%s'", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Insights/eventtypes/management/values?api-version=%s&$filter=%s&$select=%s",
+		url.PathEscape(subID), activityLogAPIVersion, url.QueryEscape(filter), url.QueryEscape("caller,eventTimestamp,operationName,subStatus,properties"))
+
+	var events []throttlingEvent
+	for page := 0; next != "" && page < maxActivityLogPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return fmt.Errorf("failed to list Activity Log events: %w", err)
+		}
+		var result struct {
+			Value    []throttlingEvent `json:"value"`
+			NextLink string            `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("failed to parse Activity Log events: %w", err)
+		}
+		events = append(events, result.Value...)
+		next = result.NextLink
+	}
+	activity.EventsRead = len(events)
+	activity.Throttled, activity.Callers = GroupThrottledCallers(events, identities, top)
+	if next != "" {
+		return fmt.Errorf("only the newest %d pages of Activity Log events were read; narrow the window for complete counts", maxActivityLogPages)
+	}
+	return nil
+}
+
+// isThrottled reports whether an Activity Log event records a request ARM or a resource provider rejected with 429
+func (e throttlingEvent) isThrottled() bool {
+	if strings.EqualFold(e.SubStatus.Value, "TooManyRequests") || strings.Contains(e.SubStatus.LocalizedValue, "429") {
+		return true
+	}
+	for _, key := range []string{"statusCode", "statusMessage"} {
+		value, _ := e.Properties[key].(string)
+		lower := strings.ToLower(value)
+		if strings.Contains(lower, "toomanyrequests") || strings.Contains(lower, "throttl") || lower == "429" {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupThrottledCallers counts the throttled events and groups them by caller and operation, most throttled first
+func GroupThrottledCallers(events []throttlingEvent, identities map[string]string, top int) (int, []ThrottledCaller) {
+	groups := map[string]*ThrottledCaller{}
+	total := 0
+	for _, event := range events {
+		if !event.isThrottled() {
+			continue
+		}
+		total++
+		caller := event.Caller
+		if caller == "" {
+			caller = "unknown"
+		}
+		key := strings.ToLower(caller) + "|" + strings.ToLower(event.OperationName.Value)
+		group, ok := groups[key]
+		if !ok {
+			group = &ThrottledCaller{Caller: caller, Operation: event.OperationName.Value, ClusterIdentity: identities[strings.ToLower(caller)],
+				FirstSeen: event.EventTimestamp, LastSeen: event.EventTimestamp}
+			groups[key] = group
+		}
+		group.Count++
+		// RFC3339 timestamps in UTC compare as strings
+		if event.EventTimestamp < group.FirstSeen {
+			group.FirstSeen = event.EventTimestamp
+		}
+		if event.EventTimestamp > group.LastSeen {
+			group.LastSeen = event.EventTimestamp
+		}
+	}
+
+	callers := []ThrottledCaller{}
+	for _, group := range groups {
+		callers = append(callers, *group)
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		if callers[i].Count != callers[j].Count {
+			return callers[i].Count > callers[j].Count
+		}
+		return callers[i].Caller+callers[i].Operation < callers[j].Caller+callers[j].Operation
+	})
+	if len(callers) > top {
+		callers = callers[:top]
+	}
+	return total, callers
+}
+
+// readControllerThrottling queries the workspaces receiving the cloud-controller-manager and cluster-autoscaler
+// logs for throttling responses from ARM
+func readControllerThrottling(ctx context.Context, controllers *ControllerThrottling, api common.ARMCaller, azExecutor tools.CommandExecutor, clusterID string, start, end time.Time, top int, cfg *config.ConfigData) error {
+	var missing, failed []string
+	for _, category := range throttlingCategories {
+		dest, found, err := findLogDestination(ctx, api, clusterID, []string{category}, "")
+		if err != nil {
+			return err
+		}
+		if !found {
+			missing = append(missing, category)
+			continue
+		}
+		if dest.WorkspaceCustomer, err = workspaceCustomerID(ctx, api, dest.WorkspaceID); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		rows, err := queryAuditLogs(azExecutor, dest, ControllerThrottlingQuery(dest, clusterID, top), start, end, cfg)
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		controllers.Workspaces = append(controllers.Workspaces, dest.WorkspaceID)
+		for _, row := range rows {
+			controllers.Components = append(controllers.Components, ComponentThrottling{
+				Component:            category,
+				ResourceType:         rowString(row, "ResourceType"),
+				Count:                int(rowNumber(row, "Count")),
+				LastSeen:             rowString(row, "LastSeen"),
+				MaxRetryAfterSeconds: int(rowNumber(row, "MaxRetryAfter")),
+				Sample:               rowString(row, "Sample"),
+			})
+		}
+	}
+	sort.SliceStable(controllers.Components, func(i, j int) bool { return controllers.Components[i].Count > controllers.Components[j].Count })
+
+	if len(missing) > 0 {
+		failed = append(failed, fmt.Sprintf("no diagnostic setting sends %s logs to a Log Analytics workspace", strings.Join(missing, " or ")))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// ControllerThrottlingQuery groups the log lines of a control plane component that record ARM throttling by the
+// resource type of the request. The query avoids double quotes and backslashes, as it is passed on a command line.
+func ControllerThrottlingQuery(dest auditDestination, clusterID string, top int) string {
+	base := fmt.Sprintf("AzureDiagnostics | where Category == '%s' and ResourceId == '%s' | project TimeGenerated, Message = log_s",
+		dest.Category, strings.ToUpper(clusterID))
+	if dest.ResourceSpecific {
+		base = fmt.Sprintf("AKSControlPlane | where _ResourceId == '%s' and Category == '%s' | project TimeGenerated, Message",
+			strings.ToLower(clusterID), dest.Category)
+	}
+	return base +
+		" | where Message has_any ('429', 'TooManyRequests') or Message contains 'throttl'" +
+		" | extend ResourceType = extract('providers/(Microsoft[.][A-Za-z]+/[A-Za-z]+)', 1, Message)," +
+		" RetryAfter = toint(extract('[Rr]etry-?[Aa]fter[^0-9]{0,5}([0-9]+)', 1, Message))" +
+		" | summarize Count = count(), LastSeen = max(TimeGenerated), MaxRetryAfter = max(RetryAfter), Sample = take_any(Message) by ResourceType" +
+		fmt.Sprintf(" | extend Sample = substring(Sample, 0, 300) | order by Count desc | take %d", top)
+}
+
+// BuildThrottlingFindings summarizes the throttling, who caused it and how close the quota is to running out
+func BuildThrottlingFindings(report ARMThrottlingReport) []string {
+	findings := []string{}
+	for _, counter := range report.Quota.Counters {
+		if counter.ConsumedPercent >= quotaConsumedThreshold {
+			findings = append(findings, fmt.Sprintf("%s is %.1f%% consumed (%d of %d left) for this server's identity; further requests will be throttled soon",
+				counter.Header, counter.ConsumedPercent, counter.Remaining, counter.Limit))
+		}
+	}
+
+	if activity := report.ActivityLog; activity.Throttled > 0 {
+		finding := fmt.Sprintf("%d requests in the subscription were rejected with 429 (TooManyRequests)", activity.Throttled)
+		if len(activity.Callers) > 0 {
+			noisiest := activity.Callers[0]
+			finding += fmt.Sprintf("; the most throttled caller is %s on %s (%d)", noisiest.Caller, noisiest.Operation, noisiest.Count)
+		}
+		findings = append(findings, finding)
+		for _, caller := range activity.Callers {
+			if caller.ClusterIdentity != "" {
+				findings = append(findings, fmt.Sprintf("The cluster's %s identity %s was throttled %d times on %s; the cluster's own operations are being delayed",
+					caller.ClusterIdentity, caller.Caller, caller.Count, caller.Operation))
+			}
+		}
+	}
+
+	perComponent := map[string]int{}
+	for _, group := range report.Controllers.Components {
+		perComponent[group.Component] += group.Count
+	}
+	for _, component := range throttlingCategories {
+		count := perComponent[component]
+		if count == 0 {
+			continue
+		}
+		var top ComponentThrottling
+		for _, group := range report.Controllers.Components {
+			if group.Component == component {
+				top = group
+				break
+			}
+		}
+		finding := fmt.Sprintf("%s logged %d throttled ARM requests", component, count)
+		if top.ResourceType != "" {
+			finding += fmt.Sprintf(", mostly on %s", top.ResourceType)
+		}
+		if top.MaxRetryAfterSeconds > 0 {
+			finding += fmt.Sprintf(" (Retry-After up to %ds)", top.MaxRetryAfterSeconds)
+		}
+		switch component {
+		case "cloud-controller-manager":
+			finding += "; load balancer, route and node updates are delayed until the quota recovers"
+		case "cluster-autoscaler":
+			finding += "; scale ups and scale downs are delayed, so pending pods may wait longer for nodes"
+		}
+		findings = append(findings, finding)
+	}
+
+	if len(findings) == 0 && report.ActivityLog.Error == "" && report.Controllers.Error == "" {
+		findings = append(findings, "No ARM throttling was found in the window")
+	}
+	return findings
+}
@@ -0,0 +1,424 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// activityLogAPIVersion is the Microsoft.Insights API version used to list Activity Log events
+const activityLogAPIVersion = "2015-04-01"
+
+// activityLogRetention is how far back the Activity Log keeps events
+const activityLogRetention = 90 * 24 * time.Hour
+
+// defaultConfigHistoryWindow is the window used when start_time is not provided
+const defaultConfigHistoryWindow = 7 * 24 * time.Hour
+
+// maxActivityLogPages bounds nextLink paging when listing Activity Log events
+const maxActivityLogPages = 20
+
+// Kinds of cluster resources whose changes are reported
+const (
+	ChangeKindCluster            = "cluster"
+	ChangeKindNodePool           = "nodePool"
+	ChangeKindDiagnosticSettings = "diagnosticSettings"
+)
+
+// trackedAgentPoolFields are the node pool properties reported for cluster and agent pool writes
+var trackedAgentPoolFields = []string{"count", "minCount", "maxCount", "enableAutoScaling", "vmSize", "orchestratorVersion", "mode"}
+
+// trackedClusterPrefixes are the cluster properties reported besides node pool profiles
+var trackedClusterPrefixes = []string{
+	"properties.apiServerAccessProfile",
+	"properties.kubernetesVersion",
+	"properties.autoScalerProfile",
+	"properties.autoUpgradeProfile",
+}
+
+// PropertyDiff is a change to one setting. Before is empty when the previous value is unknown.
+type PropertyDiff struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// ConfigChange is one write or delete operation on the cluster or one of its child resources
+type ConfigChange struct {
+	Timestamp string `json:"timestamp"`
+	// EndTimestamp is the time of the last status event of a long-running operation
+	EndTimestamp  string `json:"endTimestamp,omitempty"`
+	Caller        string `json:"caller,omitempty"`
+	Operation     string `json:"operation"`
+	Kind          string `json:"kind"`
+	Resource      string `json:"resource"`
+	Status        string `json:"status"`
+	CorrelationID string `json:"correlationId"`
+	// PayloadAvailable is false when the Activity Log did not record the request body
+	PayloadAvailable bool `json:"payloadAvailable"`
+	// BeforeUnknown is true for the first payload of a resource in the window
+	BeforeUnknown bool           `json:"beforeUnknown,omitempty"`
+	Diffs         []PropertyDiff `json:"diffs,omitempty"`
+}
+
+// ConfigHistoryReport is the result of the config_history operation
+type ConfigHistoryReport struct {
+	ClusterName string         `json:"clusterName"`
+	StartTime   string         `json:"startTime"`
+	EndTime     string         `json:"endTime"`
+	Changes     []ConfigChange `json:"changes"`
+}
+
+// activityLogEvent is the subset of an Activity Log event used to reconstruct changes
+type activityLogEvent struct {
+	Caller         string `json:"caller"`
+	CorrelationID  string `json:"correlationId"`
+	EventTimestamp string `json:"eventTimestamp"`
+	ResourceID     string `json:"resourceId"`
+	OperationName  struct {
+		Value string `json:"value"`
+	} `json:"operationName"`
+	Status struct {
+		Value string `json:"value"`
+	} `json:"status"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// HandleConfigHistoryQuery reconstructs when and by whom cluster settings changed from Activity Log write operations
+func HandleConfigHistoryQuery(params map[string]interface{}, api common.ARMCaller, _ *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	end := now
+	if value, ok := params["end_time"].(string); ok && value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			return "", fmt.Errorf("invalid end_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	start := end.Add(-defaultConfigHistoryWindow)
+	if value, ok := params["start_time"].(string); ok && value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			return "", fmt.Errorf("invalid start_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return "", fmt.Errorf("start_time must be before end_time")
+	}
+	if start.Before(now.Add(-activityLogRetention)) {
+		return "", fmt.Errorf("start_time is outside the 90 day Activity Log retention")
+	}
+
+	changes, err := ListConfigChanges(api, subID, rg, clusterName, start, end)
+	if err != nil {
+		return "", err
+	}
+	report := ConfigHistoryReport{
+		ClusterName: clusterName,
+		StartTime:   start.Format(time.RFC3339),
+		EndTime:     end.Format(time.RFC3339),
+		Changes:     changes,
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config history to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// ListConfigChanges lists the write and delete operations on a cluster and its child resources between start and end
+// from the Activity Log of the cluster's resource group, oldest first
+func ListConfigChanges(api common.ARMCaller, subID, rg, clusterName string, start, end time.Time) ([]ConfigChange, error) {
+	filter := fmt.Sprintf("eventTimestamp ge '%s' and eventTimestamp le '%s' and resourceGroupName eq '%s'",
+		start.Format(time.RFC3339), end.Format(time.RFC3339), rg)
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Insights/eventtypes/management/values?api-version=%s&$filter=%s",
+		url.PathEscape(subID), activityLogAPIVersion, url.QueryEscape(filter))
+
+	var events []activityLogEvent
+	for page := 0; next != "" && page < maxActivityLogPages; page++ {
+		body, err := api.CallARM(context.Background(), http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Activity Log events: %w", err)
+		}
+		var result struct {
+			Value    []activityLogEvent `json:"value"`
+			NextLink string             `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse Activity Log events: %w", err)
+		}
+		events = append(events, result.Value...)
+		next = result.NextLink
+	}
+
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	return BuildConfigChanges(events, clusterID), nil
+}
+
+// BuildConfigChanges groups Activity Log events into operations on the cluster and its child resources,
+// oldest first, and diffs each request payload against the previous one for the same resource
+func BuildConfigChanges(events []activityLogEvent, clusterID string) []ConfigChange {
+	clusterID = strings.TrimSuffix(strings.ToLower(clusterID), "/")
+
+	type operation struct {
+		change  ConfigChange
+		payload string
+		last    string
+	}
+	ops := map[string]*operation{}
+	for _, event := range events {
+		resourceID := strings.TrimSuffix(strings.ToLower(event.ResourceID), "/")
+		if resourceID != clusterID && !strings.HasPrefix(resourceID, clusterID+"/") {
+			continue
+		}
+		opName := event.OperationName.Value
+		lowerOp := strings.ToLower(opName)
+		if !strings.HasSuffix(lowerOp, "/write") && !strings.HasSuffix(lowerOp, "/delete") {
+			continue
+		}
+		kind, resource := classifyResource(resourceID, clusterID)
+		if kind == "" {
+			continue
+		}
+
+		key := event.CorrelationID + "|" + resourceID + "|" + lowerOp
+		op := ops[key]
+		if op == nil {
+			op = &operation{change: ConfigChange{
+				Timestamp:     event.EventTimestamp,
+				Operation:     opName,
+				Kind:          kind,
+				Resource:      resource,
+				CorrelationID: event.CorrelationID,
+			}}
+			ops[key] = op
+		}
+		if eventTime(event.EventTimestamp).Before(eventTime(op.change.Timestamp)) {
+			op.change.Timestamp = event.EventTimestamp
+		}
+		if op.change.Caller == "" {
+			op.change.Caller = event.Caller
+		}
+		if !eventTime(event.EventTimestamp).Before(eventTime(op.last)) && event.Status.Value != "" {
+			op.last = event.EventTimestamp
+			op.change.Status = event.Status.Value
+		}
+		if op.payload == "" {
+			op.payload = eventPayload(event)
+		}
+	}
+
+	sorted := make([]*operation, 0, len(ops))
+	for _, op := range ops {
+		sorted = append(sorted, op)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return eventTime(sorted[i].change.Timestamp).Before(eventTime(sorted[j].change.Timestamp))
+	})
+
+	previous := map[string]map[string]string{}
+	changes := []ConfigChange{}
+	for _, op := range sorted {
+		change := op.change
+		if op.last != "" && eventTime(op.last).After(eventTime(change.Timestamp)) {
+			change.EndTimestamp = op.last
+		}
+		resourceKey := change.Kind + "/" + change.Resource
+		succeeded := strings.EqualFold(change.Status, "Succeeded")
+
+		if strings.HasSuffix(strings.ToLower(change.Operation), "/delete") {
+			if succeeded {
+				// The resource no longer exists, so a later create starts from known empty settings
+				previous[resourceKey] = map[string]string{}
+			}
+			changes = append(changes, change)
+			continue
+		}
+
+		settings, ok := trackedSettings(op.payload, change.Kind)
+		change.PayloadAvailable = ok
+		if ok {
+			before, known := previous[resourceKey]
+			change.BeforeUnknown = !known
+			change.Diffs = diffSettings(before, settings)
+			if succeeded {
+				previous[resourceKey] = settings
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// eventTime parses an Activity Log timestamp; fractional seconds vary in length so strings do not sort
+func eventTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, value)
+	return t
+}
+
+// classifyResource returns the change kind and the resource name relative to the cluster
+func classifyResource(resourceID, clusterID string) (string, string) {
+	if resourceID == clusterID {
+		return ChangeKindCluster, "cluster"
+	}
+	relative := strings.TrimPrefix(resourceID, clusterID+"/")
+	parts := strings.Split(relative, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "agentpools":
+		return ChangeKindNodePool, "agentPools/" + parts[1]
+	case len(parts) == 4 && parts[0] == "providers" && parts[1] == "microsoft.insights" && parts[2] == "diagnosticsettings":
+		return ChangeKindDiagnosticSettings, "diagnosticSettings/" + parts[3]
+	default:
+		return "", ""
+	}
+}
+
+// eventPayload returns the request body recorded on an event, falling back to the response body
+func eventPayload(event activityLogEvent) string {
+	for _, key := range []string{"requestbody", "responseBody"} {
+		for name, value := range event.Properties {
+			if s, ok := value.(string); ok && strings.EqualFold(name, key) && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// trackedSettings flattens a request payload and keeps the settings reported for the change kind
+func trackedSettings(payload, kind string) (map[string]string, bool) {
+	if payload == "" {
+		return nil, false
+	}
+	var body interface{}
+	if err := json.Unmarshal([]byte(payload), &body); err != nil {
+		return nil, false
+	}
+	flat := map[string]string{}
+	flattenJSON(body, "", flat)
+
+	settings := map[string]string{}
+	for path, value := range flat {
+		if isTrackedSetting(path, kind) {
+			settings[path] = value
+		}
+	}
+	return settings, true
+}
+
+// isTrackedSetting reports whether a flattened payload path is reported for the change kind
+func isTrackedSetting(path, kind string) bool {
+	switch kind {
+	case ChangeKindDiagnosticSettings:
+		return strings.HasPrefix(path, "properties.")
+	case ChangeKindNodePool:
+		for _, field := range trackedAgentPoolFields {
+			if path == "properties."+field {
+				return true
+			}
+		}
+	case ChangeKindCluster:
+		for _, prefix := range trackedClusterPrefixes {
+			if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+				return true
+			}
+		}
+		if strings.HasPrefix(path, "properties.agentPoolProfiles[") {
+			for _, field := range trackedAgentPoolFields {
+				if strings.HasSuffix(path, "]."+field) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// flattenJSON flattens a decoded JSON value into dotted paths. Arrays of objects with a name
+// (node pool profiles, log categories) are keyed by name so reordering is not reported as a change.
+func flattenJSON(value interface{}, prefix string, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenJSON(child, path, out)
+		}
+	case []interface{}:
+		if named, ok := namedArray(v); ok {
+			for name, child := range named {
+				flattenJSON(child, fmt.Sprintf("%s[%s]", prefix, name), out)
+			}
+			return
+		}
+		data, _ := json.Marshal(v)
+		out[prefix] = string(data)
+	case string:
+		out[prefix] = v
+	case nil:
+		out[prefix] = "null"
+	default:
+		data, _ := json.Marshal(v)
+		out[prefix] = string(data)
+	}
+}
+
+// namedArray returns the array elements keyed by their name or category field, if every element has one
+func namedArray(items []interface{}) (map[string]interface{}, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+	named := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, _ := obj["name"].(string)
+		if name == "" {
+			name, _ = obj["category"].(string)
+		}
+		if name == "" {
+			name, _ = obj["categoryGroup"].(string)
+		}
+		if name == "" {
+			return nil, false
+		}
+		named[name] = obj
+	}
+	return named, true
+}
+
+// diffSettings returns the settings that differ between before and after, ordered by path.
+// A nil before reports every setting in after.
+func diffSettings(before, after map[string]string) []PropertyDiff {
+	paths := map[string]bool{}
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+	var diffs []PropertyDiff
+	for path := range paths {
+		b, a := before[path], after[path]
+		if b != a {
+			diffs = append(diffs, PropertyDiff{Path: path, Before: b, After: a})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
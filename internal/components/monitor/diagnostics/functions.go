@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FunctionParameters is the parameter list of every library function; functions are scoped to one cluster
+const FunctionParameters = "clusterId:string"
+
+// KQLFunction is a saved function of the curated library deployed to the cluster's Log Analytics workspace.
+// Queries use single quotes only, since control_plane_logs passes them to the Azure CLI in double quotes,
+// and leave the time range to the query timespan.
+type KQLFunction struct {
+	// Name is the function alias, invoked as Name('<cluster resource ID>')
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Category is the log category the function reads; the diagnostic setting sending it names the workspace
+	Category string `json:"category"`
+	// AzureDiagnosticsQuery and ResourceSpecificQuery are the bodies for the two destination table modes
+	AzureDiagnosticsQuery string `json:"-"`
+	ResourceSpecificQuery string `json:"-"`
+}
+
+// auditProjection summarizes audit events by user, verb and resource
+const auditProjection = "summarize Count = count(), LastSeen = max(TimeGenerated) by Username, Verb, Resource, Namespace | order by Count desc"
+
+// auditEventColumns extracts the audit columns of AzureDiagnostics rows, whose log_s holds the event JSON
+const auditEventColumns = "extend Event = parse_json(log_s) | extend Username = tostring(Event.user.username), Verb = tostring(Event.verb), " +
+	"Resource = tostring(Event.objectRef.resource), Namespace = tostring(Event.objectRef.namespace), Code = toint(Event.responseStatus.code), " +
+	"Stage = tostring(Event.stage), UserAgent = tostring(Event.userAgent)"
+
+// auditTableColumns extracts the same columns from the AKSAudit table
+const auditTableColumns = "extend Username = tostring(User.username), Resource = tostring(ObjectRef.resource), " +
+	"Namespace = tostring(ObjectRef.namespace), Code = toint(ResponseStatus.code)"
+
+// auditFunction builds a library function over kube-audit events matching a filter on the extracted columns
+func auditFunction(name, description, filter, projection string) KQLFunction {
+	return KQLFunction{
+		Name:                  name,
+		Description:           description,
+		Category:              "kube-audit",
+		AzureDiagnosticsQuery: "AzureDiagnostics | where ResourceId =~ clusterId and Category == 'kube-audit' | " + auditEventColumns + " | where " + filter + " | " + projection,
+		ResourceSpecificQuery: "AKSAudit | where _ResourceId =~ clusterId | " + auditTableColumns + " | where " + filter + " | " + projection,
+	}
+}
+
+// functionLibrary is the curated set of saved functions
+var functionLibrary = []KQLFunction{
+	{
+		Name:                  "AKSControlPlaneErrors",
+		Description:           "Error lines of each control plane component with their count, last occurrence and a sample",
+		Category:              "kube-apiserver",
+		AzureDiagnosticsQuery: "AzureDiagnostics | where ResourceId =~ clusterId and Category !in ('kube-audit', 'kube-audit-admin') and log_s startswith 'E' | summarize Count = count(), LastSeen = max(TimeGenerated), Sample = take_any(log_s) by Category | order by Count desc",
+		ResourceSpecificQuery: "AKSControlPlane | where _ResourceId =~ clusterId and Level == 'ERROR' | summarize Count = count(), LastSeen = max(TimeGenerated), Sample = take_any(Message) by Category | order by Count desc",
+	},
+	{
+		Name:                  "AKSControlPlaneErrorTrend",
+		Description:           "Hourly error counts of each control plane component",
+		Category:              "kube-apiserver",
+		AzureDiagnosticsQuery: "AzureDiagnostics | where ResourceId =~ clusterId and Category !in ('kube-audit', 'kube-audit-admin') and log_s startswith 'E' | summarize Errors = count() by Category, Hour = bin(TimeGenerated, 1h) | order by Hour desc, Errors desc",
+		ResourceSpecificQuery: "AKSControlPlane | where _ResourceId =~ clusterId and Level == 'ERROR' | summarize Errors = count() by Category, Hour = bin(TimeGenerated, 1h) | order by Hour desc, Errors desc",
+	},
+	auditFunction("AKSAuditForbidden", "Requests the API server answered with 403 Forbidden, by user, verb and resource",
+		"Code == 403", auditProjection),
+	auditFunction("AKSAuditMutations", "Completed create, update, patch and delete requests, by user, verb and resource",
+		"Verb in ('create', 'update', 'patch', 'delete') and Stage == 'ResponseComplete'", auditProjection),
+	auditFunction("AKSAuditSecretAccess", "Reads of secrets (get, list and watch), by user and namespace",
+		"Resource == 'secrets' and Verb in ('get', 'list', 'watch') and Stage == 'ResponseComplete'", auditProjection),
+	auditFunction("AKSAuditThrottled", "Requests rejected with 429 Too Many Requests, by user and user agent",
+		"Code == 429", "summarize Count = count(), LastSeen = max(TimeGenerated) by Username, UserAgent | order by Count desc"),
+	{
+		Name:                  "AKSAutoscalerScaleEvents",
+		Description:           "Cluster autoscaler scale-up, scale-down and not-triggered decisions",
+		Category:              "cluster-autoscaler",
+		AzureDiagnosticsQuery: "AzureDiagnostics | where ResourceId =~ clusterId and Category == 'cluster-autoscaler' and log_s has_any ('Scale-up', 'Scale-down', 'scale down', 'NotTriggerScaleUp') | project TimeGenerated, log_s | order by TimeGenerated desc",
+		ResourceSpecificQuery: "AKSControlPlane | where _ResourceId =~ clusterId and Category == 'cluster-autoscaler' and Message has_any ('Scale-up', 'Scale-down', 'scale down', 'NotTriggerScaleUp') | project TimeGenerated, Message | order by TimeGenerated desc",
+	},
+}
+
+// FunctionLibrary returns the curated functions sorted by name
+func FunctionLibrary() []KQLFunction {
+	functions := append([]KQLFunction(nil), functionLibrary...)
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+	return functions
+}
+
+// FindKQLFunction returns the library function with the given name
+func FindKQLFunction(name string) (KQLFunction, bool) {
+	for _, fn := range functionLibrary {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+	return KQLFunction{}, false
+}
+
+// functionNames lists the names of the library functions
+func functionNames() string {
+	names := make([]string, 0, len(functionLibrary))
+	for _, fn := range FunctionLibrary() {
+		names = append(names, fn.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Query returns the function body for the destination table mode of the workspace
+func (f KQLFunction) Query(isResourceSpecific bool) string {
+	if isResourceSpecific {
+		return f.ResourceSpecificQuery
+	}
+	return f.AzureDiagnosticsQuery
+}
+
+// BuildFunctionQuery builds the query invoking a deployed library function for a cluster. Only library
+// functions are accepted, so the name cannot carry arbitrary KQL.
+func BuildFunctionQuery(name, clusterResourceID string, maxRecords int) (string, error) {
+	if _, ok := FindKQLFunction(name); !ok {
+		return "", fmt.Errorf("unknown KQL function %s. Library functions: %s", name, functionNames())
+	}
+	if !azureResourceIDPattern.MatchString(clusterResourceID) {
+		return "", fmt.Errorf("invalid clusterResourceID format: %s", clusterResourceID)
+	}
+	if maxRecords < MinMaxRecords || maxRecords > MaxMaxRecords {
+		return "", fmt.Errorf("maxRecords must be between %d and %d, got %d", MinMaxRecords, MaxMaxRecords, maxRecords)
+	}
+	return fmt.Sprintf("%s('%s') | limit %d", name, clusterResourceID, maxRecords), nil
+}
@@ -0,0 +1,57 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFunctionLibrary(t *testing.T) {
+	for _, fn := range FunctionLibrary() {
+		for mode, query := range map[string]string{"AzureDiagnostics": fn.AzureDiagnosticsQuery, "resource-specific": fn.ResourceSpecificQuery} {
+			if query == "" || !strings.Contains(query, "=~ clusterId") {
+				t.Errorf("Expected the %s query of %s to be scoped to the clusterId parameter, got %q", mode, fn.Name, query)
+			}
+			if strings.Contains(query, `"`) {
+				t.Errorf("Expected the %s query of %s to use single quotes only", mode, fn.Name)
+			}
+		}
+		if _, ok := resourceSpecificTableMapping[fn.Category]; !ok {
+			t.Errorf("Expected %s to read a known log category, got %s", fn.Name, fn.Category)
+		}
+	}
+}
+
+func TestBuildFunctionQuery(t *testing.T) {
+	clusterID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	query, err := BuildFunctionQuery("AKSAuditForbidden", clusterID, 50)
+	if err != nil || query != "AKSAuditForbidden('"+clusterID+"') | limit 50" {
+		t.Errorf("Unexpected function query %q (%v)", query, err)
+	}
+	if _, err := BuildFunctionQuery("AzureDiagnostics | take 1", clusterID, 50); err == nil {
+		t.Error("Expected names outside the library to be rejected")
+	}
+	if _, err := BuildFunctionQuery("AKSAuditForbidden", "/subscriptions/sub/x'", 50); err == nil {
+		t.Error("Expected an invalid cluster ID to be rejected")
+	}
+}
+
+func TestHandleControlPlaneLogsFunctionValidation(t *testing.T) {
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks",
+		"function": "AKSAuditForbidden", "log_category": "guard", "start_time": "2024-01-01T00:00:00Z",
+	}
+	if _, err := HandleControlPlaneLogs(params, nil, nil); err == nil || !strings.Contains(err.Error(), "reads the kube-audit log category") {
+		t.Errorf("Expected a mismatched log category to be rejected, got %v", err)
+	}
+	params["log_category"] = "kube-audit"
+	params["profile"] = "klog"
+	if _, err := HandleControlPlaneLogs(params, nil, nil); err == nil || !strings.Contains(err.Error(), "profile can't be combined") {
+		t.Errorf("Expected a profile to be rejected with a function, got %v", err)
+	}
+	delete(params, "profile")
+	params["log_category"] = "guard"
+	params["function"] = "Unknown"
+	if _, err := HandleControlPlaneLogs(params, nil, nil); err == nil || !strings.Contains(err.Error(), "unknown KQL function") {
+		t.Errorf("Expected an unknown function to be rejected, got %v", err)
+	}
+}
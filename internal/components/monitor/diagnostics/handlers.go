@@ -5,20 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/azureclient"
 	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/directory"
+	"github.com/Azure/aks-mcp/internal/logprofile"
 	"github.com/Azure/aks-mcp/internal/tools"
 )
 
-// buildClusterResourceID constructs the Azure resource ID for an AKS cluster
-func buildClusterResourceID(subscriptionID, resourceGroup, clusterName string) string {
-	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s",
-		subscriptionID, resourceGroup, clusterName)
-}
-
 // HandleControlPlaneDiagnosticSettings checks diagnostic settings for AKS cluster
 func HandleControlPlaneDiagnosticSettings(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
 	// Extract and validate parameters using common helper
@@ -28,7 +26,7 @@ func HandleControlPlaneDiagnosticSettings(params map[string]interface{}, azClien
 	}
 
 	// Build cluster resource ID using utility function
-	clusterResourceID := buildClusterResourceID(subscriptionID, resourceGroup, clusterName)
+	clusterResourceID := common.ClusterResourceID(subscriptionID, resourceGroup, clusterName)
 
 	// Azure client is required
 	if azClient == nil {
@@ -59,18 +57,62 @@ func HandleControlPlaneLogs(params map[string]interface{}, azClient *azureclient
 		return "", err
 	}
 
+	// A library function reads one log category, which locates the workspace it was deployed to
+	functionName, _ := params["function"].(string)
+	var function KQLFunction
+	if functionName != "" {
+		var ok bool
+		if function, ok = FindKQLFunction(functionName); !ok {
+			return "", fmt.Errorf("unknown KQL function %s. Library functions: %s", functionName, functionNames())
+		}
+		if category, _ := params["log_category"].(string); category != "" && category != function.Category {
+			return "", fmt.Errorf("function %s reads the %s log category, not %s", functionName, function.Category, category)
+		}
+		if GetAuditOptions(params) != (AuditOptions{}) {
+			return "", fmt.Errorf("namespace and group_by can't be combined with function %s", functionName)
+		}
+		if profileName, _ := params["profile"].(string); profileName != "" {
+			return "", fmt.Errorf("profile can't be combined with function %s", functionName)
+		}
+		withCategory := make(map[string]interface{}, len(params)+1)
+		for key, value := range params {
+			withCategory[key] = value
+		}
+		withCategory["log_category"] = function.Category
+		params = withCategory
+	}
+
 	// Extract remaining parameters
 	logCategory, _ := params["log_category"].(string)
 	startTime, _ := params["start_time"].(string)
 	endTime, _ := params["end_time"].(string)
 	maxRecords := GetMaxRecords(params)
 	logLevel, _ := params["log_level"].(string)
+	audit := GetAuditOptions(params)
 
 	// Validate parameters
 	if err := ValidateControlPlaneLogsParams(params); err != nil {
 		return "", err
 	}
 
+	// A projection profile selects the columns and parses log lines into fields
+	var profile *logprofile.Profile
+	if profileName, _ := params["profile"].(string); profileName != "" {
+		if audit != (AuditOptions{}) {
+			return "", fmt.Errorf("profile can't be combined with namespace and group_by")
+		}
+		var profiles *logprofile.Set
+		if cfg != nil {
+			profiles = cfg.LogProfiles
+		}
+		if profile, err = profiles.Get(strings.TrimSpace(profileName)); err != nil {
+			return "", err
+		}
+		if !profile.AppliesTo(logCategory) {
+			return "", fmt.Errorf("log profile '%s' does not apply to the %s category (categories: %s)", profile.Name, logCategory, strings.Join(profile.Categories, ", "))
+		}
+	}
+
 	// Find the diagnostic setting that has the requested log category enabled
 	// This handles cases where multiple diagnostic settings exist for the same cluster
 	workspaceResourceID, isResourceSpecific, err := FindDiagnosticSettingForCategory(subscriptionID, resourceGroup, clusterName, logCategory, azClient, cfg)
@@ -85,39 +127,84 @@ func HandleControlPlaneLogs(params map[string]interface{}, azClient *azureclient
 	}
 
 	// Build cluster resource ID for scoping using utility function
-	clusterResourceID := buildClusterResourceID(subscriptionID, resourceGroup, clusterName)
+	clusterResourceID := common.ClusterResourceID(subscriptionID, resourceGroup, clusterName)
 
 	// Build safe KQL query scoped to this specific AKS cluster with appropriate table mode
-	kqlQuery, err := BuildSafeKQLQuery(logCategory, logLevel, maxRecords, clusterResourceID, isResourceSpecific)
+	var kqlQuery string
+	if functionName != "" {
+		kqlQuery, err = BuildFunctionQuery(functionName, clusterResourceID, maxRecords)
+	} else if audit != (AuditOptions{}) {
+		kqlQuery, err = BuildAuditKQLQuery(logCategory, maxRecords, clusterResourceID, isResourceSpecific, audit)
+	} else if profile != nil {
+		kqlQuery, err = BuildProfileKQLQuery(logCategory, logLevel, maxRecords, clusterResourceID, isResourceSpecific, profile)
+	} else {
+		kqlQuery, err = BuildSafeKQLQuery(logCategory, logLevel, maxRecords, clusterResourceID, isResourceSpecific)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to build KQL query for cluster %s: %w", clusterName, err)
 	}
 
-	// Calculate timespan for the query
-	timespan, err := CalculateTimespan(startTime, endTime)
+	// The window was validated above; a missing end time means now
+	now := time.Now()
+	start, err := time.Parse(time.RFC3339, startTime)
 	if err != nil {
 		return "", fmt.Errorf("failed to calculate timespan: %w", err)
 	}
+	end := now
+	if endTime != "" {
+		if end, err = time.Parse(time.RFC3339, endTime); err != nil {
+			return "", fmt.Errorf("failed to calculate timespan: %w", err)
+		}
+	}
 
-	// Execute log query with properly quoted KQL
+	// Execute log queries with properly quoted KQL
 	executor := azcli.NewExecutor()
+	run := func(kql, timespan string) (string, error) {
+		cmd := fmt.Sprintf("az monitor log-analytics query --workspace %s --analytics-query \"%s\" --timespan %s --output json",
+			workspaceGUID, kql, timespan)
 
-	// Build command string with proper quoting for the KQL query
-	cmd := fmt.Sprintf("az monitor log-analytics query --workspace %s --analytics-query \"%s\" --timespan %s --output json",
-		workspaceGUID, kqlQuery, timespan)
-
-	// Log the query command for debugging
-	log.Printf("Executing KQL query command: %s", cmd)
+		// Log the query command for debugging
+		log.Printf("Executing KQL query command: %s", cmd)
 
-	cmdParams := map[string]interface{}{
-		"command": cmd,
+		return executor.Execute(map[string]interface{}{"command": cmd}, cfg)
 	}
 
-	result, err := executor.Execute(cmdParams, cfg)
+	query := logQuery{
+		category:           logCategory,
+		clusterResourceID:  clusterResourceID,
+		isResourceSpecific: isResourceSpecific,
+		kql:                kqlQuery,
+		start:              start,
+		end:                end,
+		widenOnEmpty:       widenOnEmpty(params),
+	}
+	result, ingestion, err := runWithIngestionCheck(query, run, now)
+	if err != nil && functionName != "" {
+		return "", fmt.Errorf("failed to run KQL function %s in cluster %s (deploy the library to the workspace with the deploy_kql_functions operation): %w",
+			functionName, clusterName, err)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to query control plane logs for category %s in cluster %s: %w", logCategory, clusterName, err)
 	}
 
+	if profile != nil {
+		if result, err = profile.Apply(result, MessageColumn(logCategory, isResourceSpecific)); err != nil {
+			return "", err
+		}
+	}
+
+	// Guard logs identify users and groups by object ID; name them when Graph lookups are enabled
+	if logCategory == "guard" && cfg.GraphLookupEnabled() && azClient != nil {
+		if result, err = directory.Annotate(context.Background(), azClient, result); err != nil {
+			return "", err
+		}
+	}
+
+	// Recent windows may miss records that are not ingested yet
+	if ingestion != nil {
+		return withIngestionStatus(result, *ingestion)
+	}
+
 	// Return raw JSON result from Azure CLI
 	return result, nil
 }
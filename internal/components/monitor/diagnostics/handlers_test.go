@@ -3,8 +3,10 @@ package diagnostics
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/logprofile"
 	"github.com/Azure/aks-mcp/internal/security"
 )
 
@@ -158,3 +160,33 @@ func TestHandleControlPlaneLogs_ParameterValidation(t *testing.T) {
 		t.Errorf("Expected validation error, got: %v", err)
 	}
 }
+
+func TestHandleControlPlaneLogs_ProfileValidation(t *testing.T) {
+	profiles, err := logprofile.Parse([]byte(`{"profiles": [{"name": "scheduling", "categories": ["kube-scheduler"], "klog": true}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg := &config.ConfigData{LogProfiles: profiles}
+	start := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name          string
+		params        map[string]interface{}
+		expectedError string
+	}{
+		{"unknown profile", map[string]interface{}{"log_category": "kube-apiserver", "profile": "other"}, "Available profiles: klog, scheduling"},
+		{"category outside the profile", map[string]interface{}{"log_category": "kube-apiserver", "profile": "scheduling"}, "does not apply to the kube-apiserver category"},
+		{"audit options", map[string]interface{}{"log_category": "kube-audit", "namespace": "payments", "profile": "klog"}, "can't be combined with namespace and group_by"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks", "start_time": start}
+			for key, value := range tt.params {
+				params[key] = value
+			}
+			if _, err := HandleControlPlaneLogs(params, nil, cfg); err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
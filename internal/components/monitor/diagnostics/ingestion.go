@@ -0,0 +1,187 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// recentWindow is how close to now a query window must end for ingestion lag to affect it
+	recentWindow = 15 * time.Minute
+	// ingestionLagThreshold is how old the newest record may be before results carry a lag warning
+	ingestionLagThreshold = 5 * time.Minute
+	// ingestionProbeSpan is how far back the newest record of the category is looked for
+	ingestionProbeSpan = 24 * time.Hour
+	// widenMargin is how far before the newest record an empty window is widened to start
+	widenMargin = 15 * time.Minute
+)
+
+// IngestionStatus reports how far Log Analytics ingestion of the queried log category lags behind
+type IngestionStatus struct {
+	// LatestTimeGenerated is the TimeGenerated of the newest record of the category for the cluster
+	LatestTimeGenerated string `json:"latestTimeGenerated,omitempty"`
+	// LagSeconds is the time between the newest record and the query
+	LagSeconds float64 `json:"lagSeconds,omitempty"`
+	Warning    string  `json:"warning,omitempty"`
+	// WidenedStartTime is set when an empty window was queried again from this start time
+	WidenedStartTime string `json:"widenedStartTime,omitempty"`
+	// WidenedRows is the number of rows the widened window returned
+	WidenedRows int `json:"widenedRows,omitempty"`
+}
+
+// logQuery is a control plane log query and what the ingestion check needs to know about it
+type logQuery struct {
+	category           string
+	clusterResourceID  string
+	isResourceSpecific bool
+	kql                string
+	start, end         time.Time
+	widenOnEmpty       bool
+}
+
+// logRunner runs a KQL query over a timespan in the cluster's workspace
+type logRunner func(kql, timespan string) (string, error)
+
+// BuildLatestRecordQuery builds a query for the TimeGenerated of the newest record of a log category for a cluster
+func BuildLatestRecordQuery(category, clusterResourceID string, isResourceSpecific bool) (string, error) {
+	tableMode := AzureDiagnosticsMode
+	if isResourceSpecific {
+		tableMode = ResourceSpecificMode
+	}
+	builder, err := NewKQLQueryBuilder(category, "", DefaultKQLMaxRecords, clusterResourceID, tableMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create KQL query builder: %w", err)
+	}
+	if err := builder.determineTableStrategy(); err != nil {
+		return "", err
+	}
+	query, err := builder.buildBaseQuery()
+	if err != nil {
+		return "", err
+	}
+	return query + " | summarize LatestTimeGenerated = max(TimeGenerated)", nil
+}
+
+// runWithIngestionCheck runs a log query and, when its window reaches the last minutes, compares the newest
+// ingested record with now. Empty windows are queried again from just before the newest record when
+// widenOnEmpty is set. The status is nil when the window is older or the check found nothing to report.
+func runWithIngestionCheck(q logQuery, run logRunner, now time.Time) (string, *IngestionStatus, error) {
+	result, err := run(q.kql, formatTimespan(q.start, q.end))
+	if err != nil {
+		return "", nil, err
+	}
+	if now.Sub(q.end) > recentWindow {
+		return result, nil, nil
+	}
+
+	probe, err := BuildLatestRecordQuery(q.category, q.clusterResourceID, q.isResourceSpecific)
+	if err != nil {
+		return result, nil, nil
+	}
+	// The lag check is best effort; the query result is returned even if it fails
+	probeResult, err := run(probe, formatTimespan(now.Add(-ingestionProbeSpan), now))
+	if err != nil {
+		log.Printf("Failed to check Log Analytics ingestion lag for %s: %v", q.category, err)
+		return result, nil, nil
+	}
+	latest, found := parseLatestRecord(probeResult)
+	status := assessIngestion(q.category, latest, found, now)
+
+	if found && q.widenOnEmpty && countRows(result) == 0 && latest.Before(q.start) {
+		start := latest.Add(-widenMargin)
+		if q.end.Sub(start) > MaxQueryRangeDuration {
+			start = q.end.Add(-MaxQueryRangeDuration)
+		}
+		widened, err := run(q.kql, formatTimespan(start, q.end))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to query the widened window from %s: %w", start.Format(time.RFC3339), err)
+		}
+		result = widened
+		status.WidenedStartTime = start.Format(time.RFC3339)
+		status.WidenedRows = countRows(widened)
+	}
+
+	if status.Warning == "" && status.WidenedStartTime == "" {
+		return result, nil, nil
+	}
+	return result, &status, nil
+}
+
+// assessIngestion builds the ingestion status of a category from its newest record
+func assessIngestion(category string, latest time.Time, found bool, now time.Time) IngestionStatus {
+	if !found {
+		return IngestionStatus{Warning: fmt.Sprintf(
+			"no %s records were ingested for this cluster in the last %s; check that the category is enabled in the cluster's diagnostic settings",
+			category, ingestionProbeSpan)}
+	}
+	lag := now.Sub(latest)
+	status := IngestionStatus{
+		LatestTimeGenerated: latest.UTC().Format(time.RFC3339),
+		LagSeconds:          lag.Round(time.Second).Seconds(),
+	}
+	if lag > ingestionLagThreshold {
+		status.Warning = fmt.Sprintf(
+			"the newest %s record is from %s (%s ago). Log Analytics ingestion usually lags several minutes, so later events may not be queryable yet; query again later rather than reading missing rows as no activity",
+			category, status.LatestTimeGenerated, lag.Round(time.Second))
+	}
+	return status
+}
+
+// parseLatestRecord reads the newest TimeGenerated from the result of a latest record query. max() over
+// no rows yields an empty value.
+func parseLatestRecord(result string) (time.Time, bool) {
+	var rows []struct {
+		LatestTimeGenerated string `json:"LatestTimeGenerated"`
+	}
+	if err := json.Unmarshal([]byte(result), &rows); err != nil || len(rows) == 0 || rows[0].LatestTimeGenerated == "" {
+		return time.Time{}, false
+	}
+	latest, err := time.Parse(time.RFC3339Nano, rows[0].LatestTimeGenerated)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return latest, true
+}
+
+// countRows returns the number of rows in a query result, or -1 when it is not a JSON array
+func countRows(result string) int {
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(result), &rows); err != nil {
+		return -1
+	}
+	return len(rows)
+}
+
+// withIngestionStatus adds the ingestion status to a result. Rows are moved to result; an object, such as
+// a result annotated with directory objects, gets an ingestion field.
+func withIngestionStatus(result string, status IngestionStatus) (string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(result), &object); err != nil {
+		raw := json.RawMessage(result)
+		if !json.Valid(raw) {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal result: %v", err)
+			}
+			raw = data
+		}
+		object = map[string]json.RawMessage{"result": raw}
+	}
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ingestion status: %v", err)
+	}
+	object["ingestion"] = statusJSON
+	data, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result with ingestion status: %v", err)
+	}
+	return string(data), nil
+}
+
+// formatTimespan formats a window in the Azure CLI timespan format
+func formatTimespan(start, end time.Time) string {
+	return fmt.Sprintf("%s/%s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
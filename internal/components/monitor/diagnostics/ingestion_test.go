@@ -0,0 +1,141 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClusterResourceID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+
+type fakeLogRunner struct {
+	latest string
+	rows   map[string]string
+	calls  []string
+}
+
+// run answers latest record queries with latest and other queries with the rows of their timespan start
+func (f *fakeLogRunner) run(kql, timespan string) (string, error) {
+	f.calls = append(f.calls, timespan)
+	if strings.Contains(kql, "summarize LatestTimeGenerated") {
+		return fmt.Sprintf(`[{"LatestTimeGenerated": %q}]`, f.latest), nil
+	}
+	if rows, ok := f.rows[strings.Split(timespan, "/")[0]]; ok {
+		return rows, nil
+	}
+	return "[]", nil
+}
+
+func TestBuildLatestRecordQuery(t *testing.T) {
+	query, err := BuildLatestRecordQuery("kube-apiserver", testClusterResourceID, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "AKSControlPlane | where _ResourceId == '" + strings.ToLower(testClusterResourceID) +
+		"' | where Category == 'kube-apiserver' | summarize LatestTimeGenerated = max(TimeGenerated)"
+	if query != want {
+		t.Errorf("Unexpected query:\n%s\nwant:\n%s", query, want)
+	}
+	if _, err := BuildLatestRecordQuery("kube-audit", "not-a-resource-id", false); err == nil {
+		t.Error("Expected an invalid cluster resource ID to be rejected")
+	}
+}
+
+func TestRunWithIngestionCheck(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	query := logQuery{
+		category:          "kube-apiserver",
+		clusterResourceID: testClusterResourceID,
+		kql:               "AzureDiagnostics | limit 10",
+		start:             now.Add(-10 * time.Minute),
+		end:               now,
+	}
+
+	t.Run("current ingestion", func(t *testing.T) {
+		runner := &fakeLogRunner{latest: "2024-05-01T11:58:30.123Z", rows: map[string]string{"2024-05-01T11:50:00Z": `[{"log_s": "ok"}]`}}
+		result, status, err := runWithIngestionCheck(query, runner.run, now)
+		if err != nil || status != nil || result != `[{"log_s": "ok"}]` || len(runner.calls) != 2 {
+			t.Errorf("Expected the rows without a status, got %q %+v %v after %v", result, status, err, runner.calls)
+		}
+	})
+
+	t.Run("lagging ingestion", func(t *testing.T) {
+		runner := &fakeLogRunner{latest: "2024-05-01T11:48:00Z"}
+		result, status, err := runWithIngestionCheck(query, runner.run, now)
+		if err != nil || status == nil || result != "[]" {
+			t.Fatalf("Expected a lag status, got %q %+v %v", result, status, err)
+		}
+		if status.LagSeconds != 720 || !strings.Contains(status.Warning, "2024-05-01T11:48:00Z (12m0s ago)") || status.WidenedStartTime != "" {
+			t.Errorf("Unexpected status %+v", status)
+		}
+	})
+
+	t.Run("widen empty window", func(t *testing.T) {
+		widened := query
+		widened.widenOnEmpty = true
+		runner := &fakeLogRunner{latest: "2024-05-01T11:40:00Z", rows: map[string]string{"2024-05-01T11:25:00Z": `[{"log_s": "a"}, {"log_s": "b"}]`}}
+		result, status, err := runWithIngestionCheck(widened, runner.run, now)
+		if err != nil || status == nil || countRows(result) != 2 {
+			t.Fatalf("Expected the widened rows, got %q %+v %v", result, status, err)
+		}
+		if status.WidenedStartTime != "2024-05-01T11:25:00Z" || status.WidenedRows != 2 || runner.calls[2] != "2024-05-01T11:25:00Z/2024-05-01T12:00:00Z" {
+			t.Errorf("Unexpected widening %+v after %v", status, runner.calls)
+		}
+	})
+
+	t.Run("nothing ingested", func(t *testing.T) {
+		runner := &fakeLogRunner{latest: ""}
+		_, status, err := runWithIngestionCheck(query, runner.run, now)
+		if err != nil || status == nil || !strings.Contains(status.Warning, "no kube-apiserver records were ingested") {
+			t.Errorf("Expected a missing ingestion warning, got %+v %v", status, err)
+		}
+	})
+
+	t.Run("older window", func(t *testing.T) {
+		older := query
+		older.start, older.end = now.Add(-3*time.Hour), now.Add(-2*time.Hour)
+		runner := &fakeLogRunner{latest: "2024-05-01T10:00:00Z"}
+		_, status, err := runWithIngestionCheck(older, runner.run, now)
+		if err != nil || status != nil || len(runner.calls) != 1 {
+			t.Errorf("Expected no ingestion check for a window ending hours ago, got %+v %v after %v", status, err, runner.calls)
+		}
+	})
+}
+
+func TestWithIngestionStatus(t *testing.T) {
+	status := IngestionStatus{Warning: "lagging"}
+
+	wrapped, err := withIngestionStatus(`[{"log_s": "a"}]`, status)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var rows struct {
+		Result    []map[string]string `json:"result"`
+		Ingestion IngestionStatus     `json:"ingestion"`
+	}
+	if err := json.Unmarshal([]byte(wrapped), &rows); err != nil || len(rows.Result) != 1 || rows.Ingestion.Warning != "lagging" {
+		t.Errorf("Expected rows under result with the status, got %s (%v)", wrapped, err)
+	}
+
+	annotated, err := withIngestionStatus(`{"result": [], "directoryObjects": {}}`, status)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(annotated), &object); err != nil || len(object) != 3 || object["ingestion"] == nil {
+		t.Errorf("Expected the status to be added to the annotated object, got %s (%v)", annotated, err)
+	}
+}
+
+func TestWidenOnEmpty(t *testing.T) {
+	for value, want := range map[interface{}]bool{true: true, "true": true, "false": false, "maybe": false, 1.0: false} {
+		if got := widenOnEmpty(map[string]interface{}{"widen_on_empty": value}); got != want {
+			t.Errorf("widenOnEmpty(%v) = %t, want %t", value, got, want)
+		}
+	}
+	if widenOnEmpty(map[string]interface{}{}) {
+		t.Error("Expected widening to be off by default")
+	}
+}
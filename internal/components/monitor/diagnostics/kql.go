@@ -3,8 +3,11 @@ package diagnostics
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/Azure/aks-mcp/internal/logprofile"
 )
 
 // LogLevelMapping defines the mapping between log levels and their representations
@@ -26,6 +29,17 @@ var auditCategories = map[string]bool{
 	"kube-audit-admin": true,
 }
 
+// fleetCategories defines the log categories of Azure Kubernetes Fleet Manager member agents
+var fleetCategories = map[string]bool{
+	"fleet-member-agent":                  true,
+	"fleet-member-net-controller-manager": true,
+	"fleet-mcs-controller-manager":        true,
+}
+
+// fleetControllerExtract is a KQL regex that pulls the reconciling controller out of
+// fleet agent log lines such as: "Reconciling object" controller="serviceexport"
+const fleetControllerExtract = `'controller=.?([A-Za-z0-9_-]+)'`
+
 // ResourceSpecificTableMapping defines the mapping from log categories to resource-specific table names
 var resourceSpecificTableMapping = map[string]string{
 	"kube-audit":               "AKSAudit",
@@ -39,6 +53,10 @@ var resourceSpecificTableMapping = map[string]string{
 	"csi-azuredisk-controller": "AKSControlPlane",
 	"csi-azurefile-controller": "AKSControlPlane",
 	"csi-snapshot-controller":  "AKSControlPlane",
+	// Fleet member agents run on the member cluster and log to the control plane table
+	"fleet-member-agent":                  "AKSControlPlane",
+	"fleet-member-net-controller-manager": "AKSControlPlane",
+	"fleet-mcs-controller-manager":        "AKSControlPlane",
 }
 
 // KQLQueryBuilder builds KQL queries for AKS control plane logs
@@ -50,6 +68,40 @@ type KQLQueryBuilder struct {
 	tableMode           TableMode // Specifies the mode of the table being queried (e.g., AzureDiagnosticsMode or ResourceSpecificMode).
 	selectedTable       string    // The name of the table selected for the query.
 	processedResourceID string    // The processed resource ID used in the query.
+	audit               AuditOptions
+	profile             *logprofile.Profile // The projection profile replacing the default projection, if any.
+}
+
+// AuditGroupByNamespace counts audit events by namespace, user and verb instead of listing them
+const AuditGroupByNamespace = "namespace"
+
+// namespacePattern matches Kubernetes namespace names. Only names matching it are put into queries.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// AuditOptions scopes kube-audit and kube-audit-admin queries to a namespace and selects how events are returned
+type AuditOptions struct {
+	// Namespace keeps the requests to objects in this namespace
+	Namespace string
+	// GroupBy is empty to list events, or AuditGroupByNamespace to aggregate them
+	GroupBy string
+}
+
+// ValidateAuditOptions checks that audit options are only used with audit categories and that the namespace
+// is a valid Kubernetes namespace name, which keeps it safe to quote in KQL
+func ValidateAuditOptions(category string, opts AuditOptions) error {
+	if opts == (AuditOptions{}) {
+		return nil
+	}
+	if !auditCategories[category] {
+		return fmt.Errorf("namespace and group_by are only supported for the kube-audit and kube-audit-admin categories, not %s", category)
+	}
+	if opts.Namespace != "" && !namespacePattern.MatchString(opts.Namespace) {
+		return fmt.Errorf("invalid namespace '%s': expected a Kubernetes namespace name", opts.Namespace)
+	}
+	if opts.GroupBy != "" && opts.GroupBy != AuditGroupByNamespace {
+		return fmt.Errorf("invalid group_by '%s'. Valid values: %s", opts.GroupBy, AuditGroupByNamespace)
+	}
+	return nil
 }
 
 // TableMode represents the type of table being used
@@ -134,6 +186,40 @@ func NewKQLQueryBuilder(category, logLevel string, maxRecords int, clusterResour
 	}, nil
 }
 
+// SetAuditOptions scopes an audit query to a namespace or aggregates it by namespace
+func (q *KQLQueryBuilder) SetAuditOptions(opts AuditOptions) error {
+	if err := ValidateAuditOptions(q.category, opts); err != nil {
+		return err
+	}
+	q.audit = opts
+	return nil
+}
+
+// SetProfile replaces the default projection with the columns of a projection profile. Profiles that parse
+// log lines need the table of the category to have a message column, which is then projected too.
+func (q *KQLQueryBuilder) SetProfile(profile *logprofile.Profile) error {
+	if !profile.AppliesTo(q.category) {
+		return fmt.Errorf("log profile '%s' does not apply to the %s category (categories: %s)", profile.Name, q.category, strings.Join(profile.Categories, ", "))
+	}
+	if profile.Parses() && MessageColumn(q.category, q.tableMode == ResourceSpecificMode) == "" {
+		return fmt.Errorf("log profile '%s' parses log lines, but the %s table of the %s category has structured fields instead", profile.Name, resourceSpecificTableMapping[q.category], q.category)
+	}
+	q.profile = profile
+	return nil
+}
+
+// MessageColumn returns the column holding the log line of a category's logs, or "" for the structured
+// audit tables
+func MessageColumn(category string, isResourceSpecific bool) string {
+	if !isResourceSpecific {
+		return "log_s"
+	}
+	if resourceSpecificTableMapping[category] == "AKSControlPlane" {
+		return "Message"
+	}
+	return ""
+}
+
 // determineTableStrategy decides which table to use and processes the resource ID accordingly
 func (q *KQLQueryBuilder) determineTableStrategy() error {
 	if q.tableMode == ResourceSpecificMode {
@@ -207,6 +293,31 @@ func (q *KQLQueryBuilder) addLogLevelFilter(baseQuery string) string {
 	}
 }
 
+// addNamespaceFilter keeps the audit events of requests to objects in the namespace of the audit options
+func (q *KQLQueryBuilder) addNamespaceFilter(query string) string {
+	if q.audit.Namespace == "" || !q.isAuditCategory() {
+		return query
+	}
+	if q.tableMode == ResourceSpecificMode {
+		return query + fmt.Sprintf(" | where tostring(ObjectRef.namespace) == '%s'", q.audit.Namespace)
+	}
+	// The has term filter uses the table index before each remaining event is parsed
+	return query + fmt.Sprintf(" | where log_s has '%s' | where tostring(parse_json(log_s).objectRef.namespace) == '%s'",
+		q.audit.Namespace, q.audit.Namespace)
+}
+
+// addNamespaceAggregation counts audit events by namespace, user and verb, with the busiest users and verbs of
+// each namespace first. Cluster-scoped requests have an empty namespace.
+func (q *KQLQueryBuilder) addNamespaceAggregation(query string) string {
+	columns := auditEventColumns
+	if q.tableMode == ResourceSpecificMode {
+		columns = auditTableColumns
+	}
+	return query + " | " + columns +
+		" | summarize Count = count(), LastSeen = max(TimeGenerated) by Namespace, Username, Verb" +
+		fmt.Sprintf(" | order by Namespace asc, Count desc | limit %d", q.maxRecords)
+}
+
 // addOrderingAndLimit adds the ordering and limit clauses
 func (q *KQLQueryBuilder) addOrderingAndLimit(query string) string {
 	query += " | order by TimeGenerated desc"
@@ -214,8 +325,19 @@ func (q *KQLQueryBuilder) addOrderingAndLimit(query string) string {
 	return query
 }
 
+// isFleetCategory checks if the current category is a fleet member agent category
+func (q *KQLQueryBuilder) isFleetCategory() bool {
+	return fleetCategories[q.category]
+}
+
 // addProjection adds the appropriate field projection based on table type
 func (q *KQLQueryBuilder) addProjection(query string) string {
+	if q.profile != nil && len(q.profile.Columns) > 0 {
+		return q.addProfileProjection(query)
+	}
+	if q.isFleetCategory() {
+		return q.addFleetProjection(query)
+	}
 	switch q.tableMode {
 	case ResourceSpecificMode:
 		return q.addResourceSpecificProjection(query)
@@ -241,6 +363,26 @@ func (q *KQLQueryBuilder) addResourceSpecificProjection(query string) string {
 	}
 }
 
+// addProfileProjection projects the columns of the profile, and the message column when the profile parses it
+func (q *KQLQueryBuilder) addProfileProjection(query string) string {
+	columns := slices.Clone(q.profile.Columns)
+	if message := MessageColumn(q.category, q.tableMode == ResourceSpecificMode); q.profile.Parses() && !slices.Contains(columns, message) {
+		columns = append(columns, message)
+	}
+	return query + " | project " + strings.Join(columns, ", ")
+}
+
+// addFleetProjection adds projection for fleet member agent logs, surfacing the controller
+// that logged each line so member join, work apply and service export issues can be told apart
+func (q *KQLQueryBuilder) addFleetProjection(query string) string {
+	if q.tableMode == ResourceSpecificMode {
+		return query + fmt.Sprintf(" | extend Controller = extract(%s, 1, Message)", fleetControllerExtract) +
+			" | project TimeGenerated, Category, Level, Controller, Message, PodName"
+	}
+	return query + fmt.Sprintf(" | extend Controller = extract(%s, 1, log_s)", fleetControllerExtract) +
+		" | project TimeGenerated, Category, Level, Controller, log_s"
+}
+
 // Build constructs the complete KQL query
 func (q *KQLQueryBuilder) Build() (string, error) {
 	// Step 1: Determine table strategy
@@ -254,8 +396,12 @@ func (q *KQLQueryBuilder) Build() (string, error) {
 		return "", err
 	}
 
-	// Step 3: Add log level filtering
+	// Step 3: Add log level and audit namespace filtering
 	query = q.addLogLevelFilter(query)
+	query = q.addNamespaceFilter(query)
+	if q.audit.GroupBy == AuditGroupByNamespace && q.isAuditCategory() {
+		return q.addNamespaceAggregation(query), nil
+	}
 
 	// Step 4: Add ordering and limit
 	query = q.addOrderingAndLimit(query)
@@ -288,6 +434,53 @@ func BuildSafeKQLQuery(category, logLevel string, maxRecords int, clusterResourc
 	return query, nil
 }
 
+// BuildAuditKQLQuery builds a kube-audit or kube-audit-admin query scoped to a namespace or aggregated by
+// namespace, scoped to the cluster like BuildSafeKQLQuery
+func BuildAuditKQLQuery(category string, maxRecords int, clusterResourceID string, isResourceSpecific bool, opts AuditOptions) (string, error) {
+	tableMode := AzureDiagnosticsMode
+	if isResourceSpecific {
+		tableMode = ResourceSpecificMode
+	}
+
+	builder, err := NewKQLQueryBuilder(category, "", maxRecords, clusterResourceID, tableMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create KQL query builder: %w", err)
+	}
+	if err := builder.SetAuditOptions(opts); err != nil {
+		return "", err
+	}
+
+	query, err := builder.Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build KQL query: %w", err)
+	}
+
+	return query, nil
+}
+
+// BuildProfileKQLQuery builds a query like BuildSafeKQLQuery that projects the columns of a projection profile
+func BuildProfileKQLQuery(category, logLevel string, maxRecords int, clusterResourceID string, isResourceSpecific bool, profile *logprofile.Profile) (string, error) {
+	tableMode := AzureDiagnosticsMode
+	if isResourceSpecific {
+		tableMode = ResourceSpecificMode
+	}
+
+	builder, err := NewKQLQueryBuilder(category, logLevel, maxRecords, clusterResourceID, tableMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create KQL query builder: %w", err)
+	}
+	if err := builder.SetProfile(profile); err != nil {
+		return "", err
+	}
+
+	query, err := builder.Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build KQL query: %w", err)
+	}
+
+	return query, nil
+}
+
 // CalculateTimespan converts start/end times to Azure CLI timespan format
 func CalculateTimespan(startTime, endTime string) (string, error) {
 	start, err := time.Parse(time.RFC3339, startTime)
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/Azure/aks-mcp/internal/logprofile"
 )
 
 func TestBuildSafeKQLQuery(t *testing.T) {
@@ -503,6 +505,9 @@ func TestAKSControlPlaneCategoryFiltering(t *testing.T) {
 		"csi-azuredisk-controller",
 		"csi-azurefile-controller",
 		"csi-snapshot-controller",
+		"fleet-member-agent",
+		"fleet-member-net-controller-manager",
+		"fleet-mcs-controller-manager",
 	}
 
 	for _, category := range controlPlaneCategories {
@@ -566,6 +571,50 @@ func TestAKSControlPlaneCategoryFiltering(t *testing.T) {
 	}
 }
 
+// TestFleetCategoryProjection tests that fleet member agent logs project the reconciling controller
+func TestFleetCategoryProjection(t *testing.T) {
+	testResourceID := "/subscriptions/test/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/cluster"
+
+	for _, category := range []string{"fleet-member-agent", "fleet-member-net-controller-manager", "fleet-mcs-controller-manager"} {
+		t.Run(category+"_resource_specific", func(t *testing.T) {
+			query, err := BuildSafeKQLQuery(category, "error", 50, testResourceID, true)
+			if err != nil {
+				t.Fatalf("BuildSafeKQLQuery failed for category %s: %v", category, err)
+			}
+			for _, expected := range []string{
+				"where Category == '" + category + "'",
+				"where Level == 'ERROR'",
+				"extend Controller = extract(" + fleetControllerExtract + ", 1, Message)",
+				"project TimeGenerated, Category, Level, Controller, Message, PodName",
+			} {
+				if !strings.Contains(query, expected) {
+					t.Errorf("Expected query to contain %q, got: %s", expected, query)
+				}
+			}
+		})
+
+		t.Run(category+"_azure_diagnostics", func(t *testing.T) {
+			query, err := BuildSafeKQLQuery(category, "warning", 50, testResourceID, false)
+			if err != nil {
+				t.Fatalf("BuildSafeKQLQuery failed for category %s: %v", category, err)
+			}
+			for _, expected := range []string{
+				"where Category == '" + category + "'",
+				"where log_s startswith 'W'",
+				"extend Controller = extract(" + fleetControllerExtract + ", 1, log_s)",
+				"project TimeGenerated, Category, Level, Controller, log_s",
+			} {
+				if !strings.Contains(query, expected) {
+					t.Errorf("Expected query to contain %q, got: %s", expected, query)
+				}
+			}
+			if strings.ContainsAny(query, `"$`) {
+				t.Errorf("Query must not contain characters that break the quoted az CLI argument: %s", query)
+			}
+		})
+	}
+}
+
 // TestCategoryFilteringPreventsCrossContamination tests that the fix prevents
 // different categories from returning each other's logs (the original bug)
 func TestCategoryFilteringPreventsCrossContamination(t *testing.T) {
@@ -933,6 +982,12 @@ func TestBuildSafeKQLQueryResourceSpecificMode(t *testing.T) {
 			expectedTable:      "AKSControlPlane",
 			isResourceSpecific: true,
 		},
+		{
+			name:               "fleet-member-agent maps to AKSControlPlane table",
+			category:           "fleet-member-agent",
+			expectedTable:      "AKSControlPlane",
+			isResourceSpecific: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1494,3 +1549,158 @@ func TestBuildSafeKQLQueryErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildAuditKQLQuery(t *testing.T) {
+	clusterID := "/subscriptions/test/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/cluster"
+	tests := []struct {
+		name               string
+		category           string
+		isResourceSpecific bool
+		opts               AuditOptions
+		expectedContains   []string
+		notExpected        []string
+	}{
+		{
+			name:               "resource-specific namespace filter",
+			category:           "kube-audit",
+			isResourceSpecific: true,
+			opts:               AuditOptions{Namespace: "payments"},
+			expectedContains: []string{
+				"AKSAudit",
+				"| where tostring(ObjectRef.namespace) == 'payments' | order by TimeGenerated desc | limit 50",
+				"project TimeGenerated, Level, AuditId, Stage, RequestUri, Verb, User",
+			},
+			notExpected: []string{"summarize"},
+		},
+		{
+			name:     "azure diagnostics namespace filter",
+			category: "kube-audit-admin",
+			opts:     AuditOptions{Namespace: "payments"},
+			expectedContains: []string{
+				"where Category == 'kube-audit-admin'",
+				"| where log_s has 'payments' | where tostring(parse_json(log_s).objectRef.namespace) == 'payments'",
+				"project TimeGenerated, Level, log_s",
+			},
+		},
+		{
+			name:               "resource-specific aggregation in one namespace",
+			category:           "kube-audit",
+			isResourceSpecific: true,
+			opts:               AuditOptions{Namespace: "payments", GroupBy: AuditGroupByNamespace},
+			expectedContains: []string{
+				"where tostring(ObjectRef.namespace) == 'payments' | extend Username = tostring(User.username)",
+				"| summarize Count = count(), LastSeen = max(TimeGenerated) by Namespace, Username, Verb | order by Namespace asc, Count desc | limit 50",
+			},
+			notExpected: []string{"project TimeGenerated", "order by TimeGenerated desc"},
+		},
+		{
+			name:     "azure diagnostics aggregation across namespaces",
+			category: "kube-audit",
+			opts:     AuditOptions{GroupBy: AuditGroupByNamespace},
+			expectedContains: []string{
+				"| extend Event = parse_json(log_s)",
+				"Namespace = tostring(Event.objectRef.namespace)",
+				"by Namespace, Username, Verb",
+			},
+			notExpected: []string{"log_s has", "project TimeGenerated"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := BuildAuditKQLQuery(tt.category, 50, clusterID, tt.isResourceSpecific, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.expectedContains {
+				if !strings.Contains(query, want) {
+					t.Errorf("Expected query to contain %q, got: %s", want, query)
+				}
+			}
+			for _, unwanted := range tt.notExpected {
+				if strings.Contains(query, unwanted) {
+					t.Errorf("Expected query not to contain %q, got: %s", unwanted, query)
+				}
+			}
+			if strings.Contains(query, `"`) {
+				t.Errorf("Expected only single quotes, got: %s", query)
+			}
+		})
+	}
+}
+
+func TestBuildProfileKQLQuery(t *testing.T) {
+	clusterID := "/subscriptions/test/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/cluster"
+	klog, err := (*logprofile.Set)(nil).Get("klog")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	set, err := logprofile.Parse([]byte(`{"profiles": [
+		{"name": "pods", "extract": [{"field": "Pod", "pattern": "pod=([^ ]+)"}]},
+		{"name": "audit-users", "categories": ["kube-audit"], "columns": ["TimeGenerated", "User", "Verb"]}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	pods, _ := set.Get("pods")
+	auditUsers, _ := set.Get("audit-users")
+
+	tests := []struct {
+		name               string
+		category           string
+		isResourceSpecific bool
+		profile            *logprofile.Profile
+		expectedSuffix     string
+		expectedError      string
+	}{
+		{"klog projects the message it parses", "kube-scheduler", true, klog, "| limit 50 | project TimeGenerated, Message", ""},
+		{"klog in azure diagnostics", "kube-apiserver", false, klog, "| limit 50 | project TimeGenerated, log_s", ""},
+		{"parse rules keep the default projection", "fleet-member-agent", true, pods, "| project TimeGenerated, Category, Level, Controller, Message, PodName", ""},
+		{"columns of a structured table", "kube-audit", true, auditUsers, "| limit 50 | project TimeGenerated, User, Verb", ""},
+		{"category outside the profile", "guard", true, klog, "", "does not apply to the guard category"},
+		{"parse rules without a message column", "kube-audit", true, pods, "", "AKSAudit table of the kube-audit category has structured fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := BuildProfileKQLQuery(tt.category, "", 50, clusterID, tt.isResourceSpecific, tt.profile)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.HasSuffix(query, tt.expectedSuffix) {
+				t.Errorf("Expected query to end with %q, got: %s", tt.expectedSuffix, query)
+			}
+		})
+	}
+}
+
+func TestValidateAuditOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		opts     AuditOptions
+		wantErr  bool
+	}{
+		{name: "no options on any category", category: "kube-apiserver", opts: AuditOptions{}},
+		{name: "namespace", category: "kube-audit", opts: AuditOptions{Namespace: "kube-system"}},
+		{name: "grouping", category: "kube-audit-admin", opts: AuditOptions{GroupBy: AuditGroupByNamespace}},
+		{name: "non-audit category", category: "kube-apiserver", opts: AuditOptions{Namespace: "default"}, wantErr: true},
+		{name: "quote injection", category: "kube-audit", opts: AuditOptions{Namespace: "x' or 1==1 //"}, wantErr: true},
+		{name: "uppercase namespace", category: "kube-audit", opts: AuditOptions{Namespace: "Default"}, wantErr: true},
+		{name: "too long namespace", category: "kube-audit", opts: AuditOptions{Namespace: strings.Repeat("a", 64)}, wantErr: true},
+		{name: "unknown grouping", category: "kube-audit", opts: AuditOptions{GroupBy: "user"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuditOptions(tt.category, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAuditOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
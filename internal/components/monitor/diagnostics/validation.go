@@ -69,6 +69,11 @@ func ValidateControlPlaneLogsParams(params map[string]interface{}) error {
 		return err
 	}
 
+	// Validate the audit namespace scope and grouping
+	if err := ValidateAuditOptions(logCategory, GetAuditOptions(params)); err != nil {
+		return err
+	}
+
 	// Validate log level if provided
 	if logLevel, ok := params["log_level"].(string); ok && logLevel != "" {
 		validLevels := []string{"error", "warning", "info"}
@@ -144,3 +149,23 @@ func GetMaxRecords(params map[string]interface{}) int {
 	}
 	return DefaultMaxRecords
 }
+
+// GetAuditOptions extracts the namespace and group_by parameters of audit queries
+func GetAuditOptions(params map[string]interface{}) AuditOptions {
+	namespace, _ := params["namespace"].(string)
+	groupBy, _ := params["group_by"].(string)
+	return AuditOptions{Namespace: strings.TrimSpace(namespace), GroupBy: strings.TrimSpace(groupBy)}
+}
+
+// widenOnEmpty reports whether an empty recent window should be queried again from before the newest
+// ingested record (widen_on_empty, default false)
+func widenOnEmpty(params map[string]interface{}) bool {
+	switch value := params["widen_on_empty"].(type) {
+	case bool:
+		return value
+	case string:
+		widen, _ := strconv.ParseBool(value)
+		return widen
+	}
+	return false
+}
@@ -140,6 +140,32 @@ func TestValidateControlPlaneLogsParams(t *testing.T) {
 			},
 			wantError: false,
 		},
+		{
+			name: "audit namespace aggregation",
+			params: map[string]interface{}{
+				"subscription_id": "12345678-1234-1234-1234-123456789012",
+				"resource_group":  "test-rg",
+				"cluster_name":    "test-cluster",
+				"log_category":    "kube-audit",
+				"start_time":      time.Now().Add(-5 * time.Hour).Format(time.RFC3339),
+				"namespace":       "payments",
+				"group_by":        "namespace",
+			},
+			wantError: false,
+		},
+		{
+			name: "namespace on a non-audit category",
+			params: map[string]interface{}{
+				"subscription_id": "12345678-1234-1234-1234-123456789012",
+				"resource_group":  "test-rg",
+				"cluster_name":    "test-cluster",
+				"log_category":    "kube-apiserver",
+				"start_time":      time.Now().Add(-5 * time.Hour).Format(time.RFC3339),
+				"namespace":       "payments",
+			},
+			wantError: true,
+			errorMsg:  "only supported for the kube-audit and kube-audit-admin categories",
+		},
 	}
 
 	for _, tt := range tests {
@@ -8,13 +8,14 @@ import (
 
 	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/config"
 )
 
 // ExtractWorkspaceGUIDFromDiagnosticSettings extracts workspace GUID from diagnostic settings
 func ExtractWorkspaceGUIDFromDiagnosticSettings(subscriptionID, resourceGroup, clusterName string, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
 	// Build cluster resource ID
-	clusterResourceID := buildClusterResourceID(subscriptionID, resourceGroup, clusterName)
+	clusterResourceID := common.ClusterResourceID(subscriptionID, resourceGroup, clusterName)
 
 	// Azure client is required
 	if azClient == nil {
@@ -89,7 +90,7 @@ func getWorkspaceGUID(workspaceResourceID string, cfg *config.ConfigData) (strin
 // Returns the workspace ID and whether it uses resource-specific tables
 func FindDiagnosticSettingForCategory(subscriptionID, resourceGroup, clusterName, logCategory string, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, bool, error) {
 	// Build cluster resource ID
-	clusterResourceID := buildClusterResourceID(subscriptionID, resourceGroup, clusterName)
+	clusterResourceID := common.ClusterResourceID(subscriptionID, resourceGroup, clusterName)
 
 	// Azure client is required
 	if azClient == nil {
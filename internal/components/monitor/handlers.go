@@ -8,9 +8,12 @@ import (
 
 	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/components/monitor/diagnostics"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
 	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
 )
 
 // mergeMonitoringParams merges top-level parameters with nested "parameters" JSON string
@@ -67,8 +70,7 @@ func HandleResourceHealthQuery(params map[string]interface{}, cfg *config.Config
 	}
 
 	// Build resource ID
-	resourceID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s",
-		subscriptionID, resourceGroup, clusterName)
+	resourceID := common.ClusterResourceID(subscriptionID, resourceGroup, clusterName)
 
 	// Build Azure CLI command
 	executor := azcli.NewExecutor()
@@ -285,13 +287,29 @@ func GetAzMonitoringHandler(azClient *azureclient.AzureClient, cfg *config.Confi
 		case string(OpMetrics):
 			return handleMetricsOperation(params, cfg)
 		case string(OpResourceHealth):
-			return handleResourceHealthOperation(params, cfg)
+			return handleResourceHealthOperation(params, azClient, cfg)
 		case string(OpAppInsights):
-			return handleAppInsightsOperation(params, cfg)
+			return handleAppInsightsOperation(params, azClient, cfg)
 		case string(OpDiagnostics):
 			return handleDiagnosticsOperation(params, azClient, cfg)
 		case string(OpControlPlaneLogs):
 			return handleLogsOperation(params, azClient, cfg)
+		case string(OpFiredAlerts):
+			return handleFiredAlertsOperation(params, azClient, cfg)
+		case string(OpSafeguards):
+			return handleSafeguardsOperation(params, cfg)
+		case string(OpConfigHistory):
+			return handleConfigHistoryOperation(params, azClient, cfg)
+		case string(OpAPIServerSLO):
+			return handleAPIServerSLOOperation(params, azClient, cfg)
+		case string(OpAPIServerLoad):
+			return handleAPIServerLoadOperation(params, azClient, cfg)
+		case string(OpDeployKQL):
+			return handleDeployKQLOperation(params, azClient, cfg)
+		case string(OpPodSecurity):
+			return handlePodSecurityOperation(params, azClient, cfg)
+		case string(OpARMThrottling):
+			return handleARMThrottlingOperation(params, azClient, cfg)
 		default:
 			return "", fmt.Errorf("operation '%s' not implemented", operation)
 		}
@@ -344,26 +362,40 @@ func handleMetricsOperation(params map[string]interface{}, cfg *config.ConfigDat
 	return executor.Execute(cmdParams, cfg)
 }
 
-func handleResourceHealthOperation(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+func handleResourceHealthOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
 	// Merge parameters from top-level and nested JSON
 	mergedParams, err := mergeMonitoringParams(params)
 	if err != nil {
 		return "", fmt.Errorf("failed to merge parameters: %w", err)
 	}
 
+	mode, err := ValidateResourceHealthMode(mergedParams)
+	if err != nil {
+		return "", err
+	}
+	if mode == ResourceHealthModeServiceHealth {
+		return HandleServiceHealthQuery(mergedParams, azClient, cfg)
+	}
+
 	// Use existing resource health handler
 	return GetResourceHealthHandler(cfg).Handle(mergedParams, cfg)
 }
 
-func handleAppInsightsOperation(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+func handleAppInsightsOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
 	// Merge parameters from top-level and nested JSON
 	mergedParams, err := mergeMonitoringParams(params)
 	if err != nil {
 		return "", fmt.Errorf("failed to merge parameters: %w", err)
 	}
 
-	// Use existing app insights handler
-	return GetAppInsightsHandler(cfg).Handle(mergedParams, cfg)
+	// Use existing app insights handler, discovering the resource from the cluster when it is not named
+	var kubectlExecutor tools.CommandExecutor
+	if cfg.KubernetesAccessEnabled() {
+		kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+	}
+	return HandleAppInsightsWithDiscovery(mergedParams, azClient, kubectlExecutor, func(queryParams map[string]interface{}) (string, error) {
+		return GetAppInsightsHandler(cfg).Handle(queryParams, cfg)
+	}, cfg)
 }
 
 func handleDiagnosticsOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
@@ -387,3 +419,91 @@ func handleLogsOperation(params map[string]interface{}, azClient *azureclient.Az
 	// Use existing control plane logs handler
 	return diagnostics.GetControlPlaneLogsHandler(azClient, cfg).Handle(mergedParams, cfg)
 }
+
+func handleFiredAlertsOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleFiredAlertsQuery(mergedParams, azClient, cfg)
+}
+
+func handleSafeguardsOperation(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	var kubectlExecutor tools.CommandExecutor
+	if cfg.KubernetesAccessEnabled() {
+		kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+	}
+	return HandleSafeguardsQuery(mergedParams, azcli.NewExecutor(), kubectlExecutor, cfg)
+}
+
+func handleConfigHistoryOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleConfigHistoryQuery(mergedParams, azClient, cfg)
+}
+
+func handleAPIServerSLOOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleAPIServerSLOQuery(mergedParams, azClient, cfg)
+}
+
+func handleAPIServerLoadOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleAPIServerLoadQuery(mergedParams, azClient, azcli.NewExecutor(), cfg)
+}
+
+func handlePodSecurityOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	var kubectlExecutor tools.CommandExecutor
+	if cfg.KubernetesAccessEnabled() {
+		kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+	}
+	return HandlePodSecurityQuery(mergedParams, azClient, azcli.NewExecutor(), kubectlExecutor, cfg)
+}
+
+func handleARMThrottlingOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleARMThrottlingQuery(mergedParams, azClient, azcli.NewExecutor(), cfg)
+}
+
+func handleDeployKQLOperation(params map[string]interface{}, azClient *azureclient.AzureClient, cfg *config.ConfigData) (string, error) {
+	// Merge parameters from top-level and nested JSON
+	mergedParams, err := mergeMonitoringParams(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge parameters: %w", err)
+	}
+
+	return HandleDeployKQLFunctions(mergedParams, azClient, cfg)
+}
@@ -1,7 +1,15 @@
 package monitor
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
 )
 
 func TestHandleAppInsightsQuery_ValidParameters(t *testing.T) {
@@ -132,3 +140,1078 @@ func TestValidateAppInsightsParams_TimeValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFiredAlertsReport(t *testing.T) {
+	clusterID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	page := []byte(`{"value":[
+		{"name":"cpu-high","properties":{"essentials":{"severity":"Sev2","monitorCondition":"Fired","alertState":"New","targetResource":"` + clusterID + `","startDateTime":"2026-01-01T10:00:00Z"}}},
+		{"name":"disk-full","properties":{"essentials":{"severity":"Sev1","monitorCondition":"Fired","alertState":"New","targetResource":"/subscriptions/sub/resourceGroups/MC_rg_aks/providers/Microsoft.Compute/virtualMachineScaleSets/vmss","startDateTime":"2026-01-01T09:00:00Z"}}},
+		{"name":"old","properties":{"essentials":{"severity":"Sev0","monitorCondition":"Resolved","alertState":"Closed","targetResource":"` + clusterID + `","startDateTime":"2026-01-01T08:00:00Z"}}},
+		{"name":"other","properties":{"essentials":{"severity":"Sev0","monitorCondition":"Fired","targetResource":"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/sa"}}},
+		{"name":"sibling","properties":{"essentials":{"severity":"Sev0","monitorCondition":"Fired","targetResource":"` + clusterID + `10"}}}
+	]}`)
+
+	alerts, err := ParseAlerts([][]byte{page, page})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := BuildFiredAlertsReport(alerts, clusterID, "MC_rg_aks", true)
+	if report.FiredCount != 2 || report.ResolvedCount != 1 {
+		t.Errorf("Expected 2 fired and 1 resolved alert, got %d and %d", report.FiredCount, report.ResolvedCount)
+	}
+	if len(report.Alerts) != 3 || report.Alerts[0].Name != "disk-full" || report.Alerts[2].Name != "old" {
+		t.Errorf("Expected fired alerts first ordered by severity, got %+v", report.Alerts)
+	}
+	if report.BySeverity["Sev1"] != 1 || report.BySeverity["Sev2"] != 1 {
+		t.Errorf("Unexpected severity counts: %v", report.BySeverity)
+	}
+
+	report = BuildFiredAlertsReport(alerts, clusterID, "MC_rg_aks", false)
+	if report.ResolvedCount != 0 || len(report.Alerts) != 2 {
+		t.Errorf("Expected resolved alerts to be excluded, got %+v", report.Alerts)
+	}
+}
+
+func TestHandleFiredAlertsQuery_InvalidTimeRange(t *testing.T) {
+	params := map[string]interface{}{
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+		"time_range":      "2h",
+	}
+	if _, err := HandleFiredAlertsQuery(params, nil, config.NewConfig()); err == nil {
+		t.Error("Expected error for unsupported time_range")
+	}
+}
+
+// fakeExecutor returns canned output for commands containing a key
+type fakeExecutor struct {
+	outputs map[string]string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	for key, output := range f.outputs {
+		if strings.Contains(cmd, key) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected command: %s", cmd)
+}
+
+func TestHandleSafeguardsQuery(t *testing.T) {
+	az := &fakeExecutor{outputs: map[string]string{
+		"az aks show": `{
+			"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks",
+			"safeguardsProfile": {"level": "Enforcement", "version": "v2.0.0", "excludedNamespaces": ["dev"]},
+			"addonProfiles": {"azurepolicy": {"enabled": true}}
+		}`,
+		"az policy assignment list": `[
+			{"name": "aks-deployment-safeguards-policy-assignment", "displayName": "AKS Deployment Safeguards", "enforcementMode": "Default", "parameters": {"effect": {"value": "Deny"}}}
+		]`,
+	}}
+	kubectl := &fakeExecutor{outputs: map[string]string{
+		"get constraints": `{"items": [
+			{"kind": "K8sAzureV2ContainerNoPrivilege", "metadata": {"name": "azurepolicy-no-privileged"}, "spec": {},
+			 "status": {"totalViolations": 2, "violations": [{"kind": "Pod", "name": "p1", "namespace": "default", "message": "privileged container"}]}},
+			{"kind": "K8sAzureV1ContainerLimits", "metadata": {"name": "azurepolicy-limits"}, "spec": {"enforcementAction": "warn"}, "status": {"totalViolations": 0}}
+		]}`,
+		"get events": `{"items": [
+			{"involvedObject": {"kind": "ReplicaSet", "name": "web-123", "namespace": "default"}, "count": 4,
+			 "lastTimestamp": "2024-05-01T10:00:00Z",
+			 "message": "Error creating: admission webhook \"validation.gatekeeper.sh\" denied the request: [azurepolicy-no-privileged] privileged container"},
+			{"involvedObject": {"kind": "ReplicaSet", "name": "db-1", "namespace": "default"}, "message": "Error creating: pods \"db-1\" is forbidden: exceeded quota"}
+		]}`,
+	}}
+
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleSafeguardsQuery(params, az, kubectl, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"level": "Enforcement"`,
+		`"azurePolicyAddonEnabled": true`,
+		`"deploymentSafeguards": true`,
+		`"effect": "Deny"`,
+		`"name": "azurepolicy-no-privileged"`,
+		`"name": "azurepolicy-limits"`,
+		`"object": "ReplicaSet/web-123"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %s in result: %s", want, result)
+		}
+	}
+	if strings.Contains(result, "exceeded quota") {
+		t.Error("Expected non-admission failures to be excluded from denials")
+	}
+}
+
+func TestParseSafeguardsProfile_Off(t *testing.T) {
+	_, profile, addonEnabled, err := ParseSafeguardsProfile(`{"id": "x", "safeguardsProfile": {"level": "Off"}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if profile.Level != "Off" || addonEnabled {
+		t.Errorf("Expected safeguards Off without add-on, got %+v addon=%v", profile, addonEnabled)
+	}
+
+	// A missing profile (older API or CLI versions) is reported as Unknown rather than Off
+	_, profile, _, err = ParseSafeguardsProfile(`{"id": "x"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if profile.Level != "Unknown" {
+		t.Errorf("Expected Unknown level without safeguardsProfile, got %+v", profile)
+	}
+}
+
+// recordingExecutor records every command and returns the same output for all of them
+type recordingExecutor struct {
+	output   string
+	commands []string
+}
+
+func (r *recordingExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	r.commands = append(r.commands, cmd)
+	return r.output, nil
+}
+
+func TestHandleSafeguardsQuery_AllowedNamespaces(t *testing.T) {
+	az := &fakeExecutor{outputs: map[string]string{"az aks show": `{}`}}
+	kubectl := &recordingExecutor{output: `{"items": []}`}
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "apps, web"
+
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	if _, err := HandleSafeguardsQuery(params, az, kubectl, cfg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var eventCmds []string
+	for _, cmd := range kubectl.commands {
+		if strings.HasPrefix(cmd, "get events") {
+			eventCmds = append(eventCmds, cmd)
+		}
+		if strings.Contains(cmd, "--all-namespaces") {
+			t.Errorf("Expected no --all-namespaces query with allowed namespaces, got %s", cmd)
+		}
+	}
+	if len(eventCmds) != 2 || !strings.Contains(eventCmds[0], "--namespace apps") || !strings.Contains(eventCmds[1], "--namespace web") {
+		t.Errorf("Expected one events query per allowed namespace, got %v", eventCmds)
+	}
+}
+
+func TestHandleSafeguardsQuery_KubernetesDisabled(t *testing.T) {
+	az := &fakeExecutor{outputs: map[string]string{"az aks show": `{}`}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleSafeguardsQuery(params, az, nil, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "Kubernetes access is disabled") {
+		t.Errorf("Expected a warning when Kubernetes access is disabled: %s", result)
+	}
+}
+
+type fakeActivityLog struct {
+	body string
+	path string
+}
+
+func (f *fakeActivityLog) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	f.path = path
+	return []byte(f.body), nil
+}
+
+func activityEvent(correlationID, timestamp, resource, operation, status, caller, requestBody string) map[string]interface{} {
+	event := map[string]interface{}{
+		"caller":         caller,
+		"correlationId":  correlationID,
+		"eventTimestamp": timestamp,
+		"resourceId":     "/subscriptions/sub/resourcegroups/rg/providers/Microsoft.ContainerService/managedClusters/aks" + resource,
+		"operationName":  map[string]string{"value": operation},
+		"status":         map[string]string{"value": status},
+		"properties":     map[string]string{},
+	}
+	if requestBody != "" {
+		event["properties"] = map[string]string{"requestbody": requestBody}
+	}
+	return event
+}
+
+func TestHandleConfigHistoryQuery(t *testing.T) {
+	clusterWrite := "Microsoft.ContainerService/managedClusters/write"
+	firstBody := `{"properties":{"apiServerAccessProfile":{"authorizedIPRanges":["1.1.1.1/32"]},"agentPoolProfiles":[{"name":"system","count":3,"vmSize":"Standard_D4s_v5"}],"dnsPrefix":"aks"}}`
+	secondBody := `{"properties":{"apiServerAccessProfile":{"authorizedIPRanges":["1.1.1.1/32","2.2.2.2/32"]},"agentPoolProfiles":[{"name":"system","count":5,"vmSize":"Standard_D4s_v5"}],"dnsPrefix":"aks"}}`
+	events := []map[string]interface{}{
+		activityEvent("c2", "2024-05-02T10:00:00.5Z", "", clusterWrite, "Started", "bob@contoso.com", secondBody),
+		activityEvent("c2", "2024-05-02T10:05:00Z", "", clusterWrite, "Succeeded", "bob@contoso.com", ""),
+		activityEvent("c1", "2024-05-01T09:00:00Z", "", clusterWrite, "Started", "alice@contoso.com", firstBody),
+		activityEvent("c1", "2024-05-01T09:04:00Z", "", clusterWrite, "Succeeded", "", ""),
+		activityEvent("c3", "2024-05-03T08:00:00Z", "/providers/microsoft.insights/diagnosticSettings/diag", "microsoft.insights/diagnosticSettings/delete", "Succeeded", "carol@contoso.com", ""),
+		activityEvent("c4", "2024-05-03T09:00:00Z", "/agentPools/user", "Microsoft.ContainerService/managedClusters/agentPools/write", "Failed", "dave@contoso.com", ""),
+		activityEvent("c5", "2024-05-03T09:30:00Z", "2", clusterWrite, "Succeeded", "erin@contoso.com", firstBody),
+		activityEvent("c6", "2024-05-03T09:45:00Z", "", "Microsoft.ContainerService/managedClusters/listClusterUserCredential/action", "Succeeded", "frank@contoso.com", ""),
+	}
+	body, _ := json.Marshal(map[string]interface{}{"value": events})
+	api := &fakeActivityLog{body: string(body)}
+
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks",
+		"start_time": time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339),
+	}
+	result, err := HandleConfigHistoryQuery(params, api, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleConfigHistoryQuery failed: %v", err)
+	}
+	if !strings.Contains(api.path, "/providers/Microsoft.Insights/eventtypes/management/values?") || !strings.Contains(api.path, "resourceGroupName+eq+%27rg%27") {
+		t.Errorf("Unexpected Activity Log path %s", api.path)
+	}
+
+	var report ConfigHistoryReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Changes) != 4 {
+		t.Fatalf("Expected actions and sibling clusters to be ignored, got %+v", report.Changes)
+	}
+
+	first := report.Changes[0]
+	if first.Caller != "alice@contoso.com" || first.Status != "Succeeded" || !first.BeforeUnknown || !first.PayloadAvailable {
+		t.Errorf("Unexpected first change: %+v", first)
+	}
+	for _, diff := range first.Diffs {
+		if strings.Contains(diff.Path, "dnsPrefix") {
+			t.Errorf("Expected untracked settings to be skipped, got %+v", diff)
+		}
+	}
+
+	second := report.Changes[1]
+	if second.BeforeUnknown || second.Caller != "bob@contoso.com" || second.EndTimestamp != "2024-05-02T10:05:00Z" {
+		t.Errorf("Expected the second change to diff against the first, got %+v", second)
+	}
+	want := map[string][2]string{
+		"properties.agentPoolProfiles[system].count":           {"3", "5"},
+		"properties.apiServerAccessProfile.authorizedIPRanges": {`["1.1.1.1/32"]`, `["1.1.1.1/32","2.2.2.2/32"]`},
+	}
+	if len(second.Diffs) != len(want) {
+		t.Fatalf("Unexpected diffs: %+v", second.Diffs)
+	}
+	for _, diff := range second.Diffs {
+		if w, ok := want[diff.Path]; !ok || diff.Before != w[0] || diff.After != w[1] {
+			t.Errorf("Unexpected diff %+v", diff)
+		}
+	}
+
+	if report.Changes[2].Kind != ChangeKindDiagnosticSettings || report.Changes[2].Resource != "diagnosticSettings/diag" {
+		t.Errorf("Unexpected diagnostic settings change: %+v", report.Changes[2])
+	}
+	if pool := report.Changes[3]; pool.Kind != ChangeKindNodePool || pool.Status != "Failed" || pool.PayloadAvailable || pool.EndTimestamp != "" {
+		t.Errorf("Unexpected node pool change: %+v", pool)
+	}
+}
+
+func TestHandleConfigHistoryQuery_InvalidWindow(t *testing.T) {
+	base := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	for name, extra := range map[string]map[string]interface{}{
+		"bad format":        {"start_time": "yesterday"},
+		"outside retention": {"start_time": time.Now().Add(-100 * 24 * time.Hour).UTC().Format(time.RFC3339)},
+		"reversed":          {"start_time": "2024-05-02T00:00:00Z", "end_time": "2024-05-01T00:00:00Z"},
+	} {
+		params := map[string]interface{}{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+		if _, err := HandleConfigHistoryQuery(params, &fakeActivityLog{body: `{"value":[]}`}, config.NewConfig()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+type fakeSLOARM struct {
+	responses map[string]string
+	paths     []string
+}
+
+func (f *fakeSLOARM) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	f.paths = append(f.paths, path)
+	for fragment, body := range f.responses {
+		if strings.Contains(path, fragment) {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected path %s", path)
+}
+
+func TestBuildSLOReport(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+	transitions := []healthTransition{
+		{Time: start.Add(-time.Hour), State: StateAvailable},
+		{Time: start.Add(24 * time.Hour), State: StateUnavailable, Summary: "API server unreachable", ReasonType: "Unplanned"},
+		{Time: start.Add(24*time.Hour + 10*time.Minute), State: StateAvailable},
+		{Time: start.Add(48 * time.Hour), State: StateDegraded},
+		{Time: start.Add(48*time.Hour + 30*time.Minute), State: StateAvailable},
+		{Time: start.Add(72 * time.Hour), State: StateUnavailable},
+		{Time: start.Add(72*time.Hour + 5*time.Minute), State: StateUnavailable},
+		{Time: start.Add(72*time.Hour + 15*time.Minute), State: StateAvailable},
+		{Time: end.Add(time.Hour), State: StateUnknown},
+	}
+
+	report := BuildSLOReport(transitions, start, end, 99.95, false)
+	if len(report.Incidents) != 3 {
+		t.Fatalf("Expected 3 incidents, got %+v", report.Incidents)
+	}
+	if report.DowntimeMinutes != 25 || report.DegradedMinutes != 30 {
+		t.Errorf("Expected 25 downtime and 30 degraded minutes, got %+v", report)
+	}
+	if report.Incidents[1].CountedAsDowntime || !report.Incidents[2].CountedAsDowntime || report.Incidents[2].DurationMinutes != 15 {
+		t.Errorf("Unexpected incidents: %+v", report.Incidents)
+	}
+	if report.Incidents[0].Summary != "API server unreachable" {
+		t.Errorf("Expected the incident summary, got %+v", report.Incidents[0])
+	}
+	if report.AvailabilityPercent != 99.942 || report.TargetPercent != 99.95 {
+		t.Errorf("Unexpected availability: %+v", report)
+	}
+	if report.Met || report.ErrorBudgetMinutes != 21.6 || report.BudgetRemaining != -3.4 {
+		t.Errorf("Expected the 99.95%% target to be missed, got %+v", report)
+	}
+	if !strings.HasPrefix(report.Summary, "99.94% over 30 days with 3 incidents (target 99.95%: missed") {
+		t.Errorf("Unexpected summary %q", report.Summary)
+	}
+
+	degraded := BuildSLOReport(transitions, start, end, 99.5, true)
+	if degraded.DowntimeMinutes != 55 || !degraded.Met {
+		t.Errorf("Expected degraded time to count as downtime, got %+v", degraded)
+	}
+
+	// A window that starts inside an incident is clipped to the window
+	clipped := BuildSLOReport(transitions, start.Add(24*time.Hour+5*time.Minute), start.Add(25*time.Hour), 99.9, false)
+	if len(clipped.Incidents) != 1 || clipped.Incidents[0].DurationMinutes != 5 {
+		t.Errorf("Expected a clipped 5 minute incident, got %+v", clipped.Incidents)
+	}
+}
+
+func TestHandleAPIServerSLOQuery(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	outage := now.Add(-48 * time.Hour)
+	health := fmt.Sprintf(`{"value":[
+		{"properties":{"availabilityState":"Available","occuredTime":%q}},
+		{"properties":{"availabilityState":"Unavailable","summary":"Control plane restart","reasonType":"Unplanned","occuredTime":%q}}
+	],"nextLink":"https://management.azure.com/page2"}`,
+		outage.Add(10*time.Minute).Format(time.RFC3339), outage.Format(time.RFC3339))
+	page2 := fmt.Sprintf(`{"value":[{"properties":{"availabilityState":"Available","occurredTime":%q}}]}`,
+		now.Add(-40*24*time.Hour).Format(time.RFC3339))
+	metrics := fmt.Sprintf(`{"value":[{"timeseries":[{"data":[
+		{"timeStamp":%q,"maximum":12},
+		{"timeStamp":%q},
+		{"timeStamp":%q},
+		{"timeStamp":%q,"maximum":10},
+		{"timeStamp":%q}
+	]}]}]}`,
+		outage.Add(-15*time.Minute).Format(time.RFC3339), outage.Format(time.RFC3339),
+		outage.Add(15*time.Minute).Format(time.RFC3339), outage.Add(30*time.Minute).Format(time.RFC3339),
+		outage.Add(24*time.Hour).Format(time.RFC3339))
+	api := &fakeSLOARM{responses: map[string]string{
+		"availabilityStatuses":       health,
+		"page2":                      page2,
+		"Microsoft.Insights/metrics": metrics,
+		"managedClusters/aks?":       `{"sku":{"tier":"Standard"},"properties":{"agentPoolProfiles":[{"availabilityZones":["1","2","3"]}]}}`,
+	}}
+
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleAPIServerSLOQuery(params, api, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAPIServerSLOQuery failed: %v", err)
+	}
+
+	var report SLOReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.TargetPercent != 99.95 || !strings.Contains(report.TargetSource, "availability zones") {
+		t.Errorf("Expected the zonal Standard tier SLA, got %v (%s)", report.TargetPercent, report.TargetSource)
+	}
+	if len(report.Incidents) != 1 || report.Incidents[0].DurationMinutes != 10 || !report.Incidents[0].TelemetryGap {
+		t.Fatalf("Expected one 10 minute incident with a metrics gap, got %+v", report.Incidents)
+	}
+	if report.Telemetry.Interval != "PT15M" || report.Telemetry.MissingIntervals != 3 || report.Telemetry.UnexplainedGapMinutes != 35 {
+		t.Errorf("Unexpected telemetry: %+v", report.Telemetry)
+	}
+	if !report.Met || report.WindowDays != 30 {
+		t.Errorf("Expected a met 30 day report, got %+v", report)
+	}
+
+	// Metrics failures are reported without failing the report
+	delete(api.responses, "Microsoft.Insights/metrics")
+	params["slo_target"] = "99.99"
+	result, err = HandleAPIServerSLOQuery(params, api, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAPIServerSLOQuery failed without metrics: %v", err)
+	}
+	report = SLOReport{}
+	_ = json.Unmarshal([]byte(result), &report)
+	if report.Telemetry.Error == "" || report.TargetPercent != 99.99 || report.Met {
+		t.Errorf("Expected a metrics error and the custom target to be missed, got %+v", report)
+	}
+}
+
+func TestHandleAPIServerSLOQuery_InvalidParameters(t *testing.T) {
+	base := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	for name, extra := range map[string]map[string]interface{}{
+		"window too long":     {"window_days": "45"},
+		"bad window":          {"window_days": "a month"},
+		"reversed":            {"start_time": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), "end_time": time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)},
+		"target out of range": {"slo_target": "100"},
+	} {
+		params := map[string]interface{}{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+		if _, err := HandleAPIServerSLOQuery(params, &fakeSLOARM{responses: map[string]string{}}, config.NewConfig()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestUptimeSLATarget(t *testing.T) {
+	for _, tt := range []struct {
+		tier  string
+		zonal bool
+		want  float64
+	}{
+		{"Standard", true, 99.95}, {"Standard", false, 99.9}, {"Premium", true, 99.95}, {"Free", true, 99.5}, {"", false, 99.5},
+	} {
+		if got, _ := UptimeSLATarget(tt.tier, tt.zonal); got != tt.want {
+			t.Errorf("UptimeSLATarget(%q, %v) = %v, want %v", tt.tier, tt.zonal, got, tt.want)
+		}
+	}
+}
+
+const testInstrumentationKey = "11111111-2222-3333-4444-555555555555"
+
+func appInsightsComponents() string {
+	return `{"value": [
+		{"id": "/subscriptions/sub/resourceGroups/rg-apps/providers/Microsoft.Insights/components/web-ai", "name": "web-ai",
+		 "properties": {"ConnectionString": "InstrumentationKey=` + testInstrumentationKey + `;IngestionEndpoint=https://eastus-0.in.applicationinsights.azure.com/"}},
+		{"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Insights/components/cluster-ai", "name": "cluster-ai",
+		 "properties": {"InstrumentationKey": "99999999-2222-3333-4444-555555555555"}},
+		{"id": "/subscriptions/sub/resourceGroups/rg-other/providers/Microsoft.Insights/components/tagged-ai", "name": "tagged-ai",
+		 "tags": {"aks-cluster": "aks"}, "properties": {}},
+		{"id": "/subscriptions/sub/resourceGroups/rg-other/providers/Microsoft.Insights/components/unrelated", "name": "unrelated", "properties": {}}
+	]}`
+}
+
+func TestParseWorkloadAppInsightsReferences(t *testing.T) {
+	output := `{"items": [
+		{"kind": "Deployment", "metadata": {"name": "web", "namespace": "shop"}, "spec": {"template": {"metadata": {}, "spec": {"containers": [
+			{"name": "web", "env": [
+				{"name": "APPLICATIONINSIGHTS_CONNECTION_STRING", "value": "InstrumentationKey=` + testInstrumentationKey + `;IngestionEndpoint=https://x/"},
+				{"name": "OTHER", "value": "x"}
+			]},
+			{"name": "sidecar", "env": [{"name": "ApplicationInsights__ConnectionString", "valueFrom": {"secretKeyRef": {"name": "ai", "key": "cs"}}}]}
+		]}}}},
+		{"kind": "StatefulSet", "metadata": {"name": "db", "namespace": "shop", "annotations": {"example.com/appinsights-resource": "db-ai"}}, "spec": {"template": {"metadata": {}, "spec": {"containers": []}}}}
+	]}`
+	refs, unresolved, err := ParseWorkloadAppInsightsReferences(output)
+	if err != nil {
+		t.Fatalf("ParseWorkloadAppInsightsReferences failed: %v", err)
+	}
+	if len(refs) != 2 || refs[0].key != testInstrumentationKey || refs[0].workload != "deployment shop/web" || refs[1].name != "db-ai" {
+		t.Errorf("Unexpected references %+v", refs)
+	}
+	if len(unresolved) != 1 || !strings.Contains(unresolved[0], "sidecar env ApplicationInsights__ConnectionString is read from a secret") {
+		t.Errorf("Unexpected unresolved references %v", unresolved)
+	}
+}
+
+func TestHandleAppInsightsWithDiscovery(t *testing.T) {
+	kubectl := &fakeExecutor{outputs: map[string]string{
+		"get deployments,statefulsets,daemonsets": `{"items": [{"kind": "Deployment", "metadata": {"name": "web", "namespace": "shop"}, "spec": {"template": {"metadata": {}, "spec": {"containers": [
+			{"name": "web", "env": [{"name": "APPINSIGHTS_INSTRUMENTATIONKEY", "value": "` + testInstrumentationKey + `"}]}
+		]}}}}]}`,
+	}}
+	api := &fakeActivityLog{body: appInsightsComponents()}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks", "query": "requests | take 1"}
+
+	var queried map[string]interface{}
+	query := func(p map[string]interface{}) (string, error) {
+		queried = p
+		return `{"tables": []}`, nil
+	}
+	result, err := HandleAppInsightsWithDiscovery(params, api, kubectl, query, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAppInsightsWithDiscovery failed: %v", err)
+	}
+	if queried["app_insights_name"] != "web-ai" || queried["resource_group"] != "rg-apps" {
+		t.Errorf("Expected the workload-linked resource to be queried, got %v", queried)
+	}
+	if !strings.Contains(result, `"appInsights"`) || !strings.Contains(result, `"tables": []`) {
+		t.Errorf("Expected the chosen resource and the raw result, got %s", result)
+	}
+
+	// Without workload links several candidates remain, so they are listed instead of queried
+	queried = nil
+	result, err = HandleAppInsightsWithDiscovery(params, api, nil, query, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAppInsightsWithDiscovery failed: %v", err)
+	}
+	var discovery AppInsightsDiscovery
+	if err := json.Unmarshal([]byte(result), &discovery); err != nil {
+		t.Fatalf("Expected a discovery report: %v", err)
+	}
+	if queried != nil || len(discovery.Candidates) != 2 || len(discovery.Warnings) != 1 {
+		t.Errorf("Unexpected discovery %+v", discovery)
+	}
+	for _, candidate := range discovery.Candidates {
+		if candidate.Name == "tagged-ai" && candidate.Evidence[0] != "tag aks-cluster=aks names the cluster" {
+			t.Errorf("Unexpected tag evidence %v", candidate.Evidence)
+		}
+	}
+
+	// An explicit name skips discovery
+	params["app_insights_name"] = "given"
+	if _, err := HandleAppInsightsWithDiscovery(params, &fakeActivityLog{}, nil, query, config.NewConfig()); err != nil || queried["app_insights_name"] != "given" {
+		t.Errorf("Expected the named resource to be queried directly, got %v (%v)", queried, err)
+	}
+}
+
+func TestHandleAPIServerLoadQuery(t *testing.T) {
+	api := &fakeSLOARM{responses: map[string]string{
+		"Microsoft.Insights/metrics": `{"value":[{"timeseries":[
+			{"metadatavalues":[{"name":{"value":"requestKind"},"value":"readOnly"}],"data":[
+				{"timeStamp":"2024-05-01T10:00:00Z","average":120,"maximum":180},
+				{"timeStamp":"2024-05-01T10:05:00Z","average":200,"maximum":350}
+			]},
+			{"metadatavalues":[{"name":{"value":"requestKind"},"value":"mutating"}],"data":[
+				{"timeStamp":"2024-05-01T10:00:00Z","average":10,"maximum":20}
+			]}
+		]}]}`,
+		"diagnosticSettings": `{"value":[
+			{"properties":{"workspaceId":"/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws1","logs":[{"category":"kube-apiserver","enabled":true}]}},
+			{"properties":{"workspaceId":"/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws2","logAnalyticsDestinationType":"Dedicated","logs":[{"category":"kube-audit","enabled":true}]}}
+		]}`,
+		"workspaces/ws2?": `{"properties":{"customerId":"00000000-1111-2222-3333-444444444444"}}`,
+	}}
+	az := &fakeExecutor{outputs: map[string]string{
+		"by Verb": `[
+			{"Verb":"list","Requests":"4000","P50Ms":"40","P95Ms":"900","P99Ms":"2500","Throttled":"300","ServerErrors":"0"},
+			{"Verb":"get","Requests":"3000","P50Ms":"5","P95Ms":"200","P99Ms":"1500","Throttled":"0","ServerErrors":"2"}
+		]`,
+		"by UserAgent": `[
+			{"UserAgent":"my-operator/v1.2 (linux/amd64)","Username":"system:serviceaccount:ops:my-operator","Requests":"5000","Lists":"3800","Watches":"10","Mutations":"100","Throttled":"300","Total":"10000"},
+			{"UserAgent":"kubectl/v1.30.0","Username":"alice@contoso.com","Requests":"500","Lists":"100","Watches":"0","Mutations":"20","Throttled":"0","Total":"10000"}
+		]`,
+		"bin(TimeGenerated, 5m)": `[{"TimeGenerated":"2024-05-01T10:05:00Z","Rejections":"320","Clients":"1"}]`,
+	}}
+
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks",
+		"start_time": "2024-05-01T10:00:00Z", "end_time": "2024-05-01T11:00:00Z",
+	}
+	result, err := HandleAPIServerLoadQuery(params, api, az, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAPIServerLoadQuery failed: %v", err)
+	}
+	var report APIServerLoadReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if len(report.Inflight) != 2 || report.Inflight[1].RequestKind != "readOnly" || report.Inflight[1].Maximum != 350 || report.Inflight[1].Average != 160 {
+		t.Errorf("Unexpected inflight series: %+v", report.Inflight)
+	}
+	if report.Audit.Category != "kube-audit" || !strings.HasSuffix(report.Audit.Workspace, "ws2") {
+		t.Errorf("Expected the kube-audit workspace, got %s in %s", report.Audit.Category, report.Audit.Workspace)
+	}
+	if report.Audit.TotalRequests != 10000 || report.Audit.Throttled != 320 || report.Audit.ThrottledPercent != 3.2 {
+		t.Errorf("Unexpected totals: %+v", report.Audit)
+	}
+	if report.Audit.TopClients[0].SharePercent != 50 || report.Audit.TopClients[0].ThrottledRate != 6 {
+		t.Errorf("Unexpected top client: %+v", report.Audit.TopClients[0])
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{
+		"320 requests (3.20%) were rejected",
+		"my-operator/v1.2 as system:serviceaccount:ops:my-operator (300)",
+		"p99 latency of get requests is 1500ms",
+		"2 get requests failed",
+		"sent 50.0% of all requests",
+		"polling instead of using an informer",
+		"readOnly inflight requests peaked at 350",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected findings to contain %q, got:\n%s", want, findings)
+		}
+	}
+	if strings.Contains(findings, "list requests is") {
+		t.Errorf("Expected list latency within the 30s SLO, got:\n%s", findings)
+	}
+}
+
+func TestHandleAPIServerLoadQuery_WithoutAuditLogs(t *testing.T) {
+	api := &fakeSLOARM{responses: map[string]string{
+		"diagnosticSettings": `{"value":[{"properties":{"workspaceId":"/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws1","logs":[{"category":"kube-apiserver","enabled":true},{"category":"kube-audit","enabled":false}]}}]}`,
+	}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleAPIServerLoadQuery(params, api, &fakeExecutor{}, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleAPIServerLoadQuery failed: %v", err)
+	}
+	var report APIServerLoadReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.InflightError == "" || !strings.Contains(report.Audit.Error, "enable kube-audit") {
+		t.Errorf("Expected metrics and audit errors, got %+v", report)
+	}
+}
+
+func TestAuditBaseQuery(t *testing.T) {
+	clusterID := "/subscriptions/sub/resourceGroups/RG/providers/Microsoft.ContainerService/managedClusters/aks"
+	query := auditBaseQuery(auditDestination{Category: "kube-audit-admin", ResourceSpecific: true}, clusterID)
+	if !strings.HasPrefix(query, "AKSAuditAdmin | where _ResourceId == '"+strings.ToLower(clusterID)+"'") {
+		t.Errorf("Unexpected resource-specific query: %s", query)
+	}
+	query = auditBaseQuery(auditDestination{Category: "kube-audit"}, clusterID)
+	if !strings.Contains(query, "Category == 'kube-audit' and ResourceId == '"+strings.ToUpper(clusterID)+"'") || strings.Contains(query, `"`) {
+		t.Errorf("Unexpected AzureDiagnostics query: %s", query)
+	}
+	if filter := shortRunningFilter(); !strings.Contains(filter, "Verb != 'watch'") || !strings.Contains(filter, "RequestUri !contains '/exec'") {
+		t.Errorf("Unexpected short-running filter: %s", filter)
+	}
+}
+
+func TestHandleAPIServerLoadQuery_InvalidParameters(t *testing.T) {
+	base := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	for name, extra := range map[string]map[string]interface{}{
+		"window too long": {"start_time": "2024-05-01T00:00:00Z", "end_time": "2024-05-09T00:00:00Z"},
+		"reversed":        {"start_time": "2024-05-02T00:00:00Z", "end_time": "2024-05-01T00:00:00Z"},
+		"bad top":         {"top": "500"},
+	} {
+		params := map[string]interface{}{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+		if _, err := HandleAPIServerLoadQuery(params, &fakeSLOARM{responses: map[string]string{}}, &fakeExecutor{}, config.NewConfig()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHandleServiceHealthQuery(t *testing.T) {
+	events := `{"value":[
+  {"name":"AKS1-XYZ","properties":{"eventType":"ServiceIssue","title":"AKS API server latency","status":"Resolved","level":"Warning",
+    "summary":"<p>Customers in <b>East US</b> may see   latency.</p>",
+    "impactStartTime":"2024-05-02T10:00:00Z","impactMitigationTime":"2024-05-02T12:00:00Z",
+    "impact":[{"impactedService":"Azure Kubernetes Service (AKS)","impactedRegions":[{"impactedRegion":"East US"}]}]}},
+  {"name":"PM-123","properties":{"eventType":"PlannedMaintenance","title":"Load balancer maintenance","status":"Active",
+    "impactStartTime":"2024-05-03T00:00:00Z",
+    "impact":[{"impactedService":"Load Balancer","impactedRegions":[{"impactedRegion":"Global"}]}]}},
+  {"name":"OTHER-REGION","properties":{"eventType":"ServiceIssue","title":"VM outage","status":"Resolved",
+    "impactStartTime":"2024-05-02T10:00:00Z","impactMitigationTime":"2024-05-02T11:00:00Z",
+    "impact":[{"impactedService":"Virtual Machines","impactedRegions":[{"impactedRegion":"West Europe"}]}]}},
+  {"name":"OTHER-SERVICE","properties":{"eventType":"ServiceIssue","title":"Cosmos DB outage","status":"Resolved",
+    "impactStartTime":"2024-05-02T10:00:00Z","impactMitigationTime":"2024-05-02T11:00:00Z",
+    "impact":[{"impactedService":"Azure Cosmos DB","impactedRegions":[{"impactedRegion":"East US"}]}]}},
+  {"name":"TOO-OLD","properties":{"eventType":"ServiceIssue","title":"Old AKS issue","status":"Resolved",
+    "impactStartTime":"2024-04-01T10:00:00Z","impactMitigationTime":"2024-04-01T11:00:00Z",
+    "impact":[{"impactedService":"Azure Kubernetes Service (AKS)","impactedRegions":[{"impactedRegion":"East US"}]}]}}
+]}`
+	health := `{"value":[
+  {"properties":{"availabilityState":"Degraded","summary":"API server latency","reasonType":"Unplanned","occurredTime":"2024-05-02T10:30:00Z"}},
+  {"properties":{"availabilityState":"Available","occurredTime":"2024-05-02T12:00:00Z"}},
+  {"properties":{"availabilityState":"Unavailable","summary":"Cluster stopped","reasonType":"UserInitiated","occurredTime":"2024-05-02T20:00:00Z"}},
+  {"properties":{"availabilityState":"Unavailable","summary":"Node pool unhealthy","reasonType":"Unplanned","occurredTime":"2024-05-02T22:00:00Z"}}
+]}`
+	api := &fakeSLOARM{responses: map[string]string{
+		"managedClusters/aks?":            `{"location":"eastus"}`,
+		"Microsoft.ResourceHealth/events": events,
+		"availabilityStatuses":            health,
+	}}
+
+	params := map[string]interface{}{
+		"operation":       "resource_health",
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+		"parameters":      `{"mode":"service_health","start_time":"2024-05-02T00:00:00Z","end_time":"2024-05-03T12:00:00Z"}`,
+	}
+	merged, err := mergeMonitoringParams(params)
+	if err != nil {
+		t.Fatalf("mergeMonitoringParams failed: %v", err)
+	}
+	result, err := HandleServiceHealthQuery(merged, api, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleServiceHealthQuery failed: %v", err)
+	}
+
+	var report ServiceHealthReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.PlatformEvents) != 2 {
+		t.Fatalf("Expected the regional AKS issue and the global maintenance, got %+v", report.PlatformEvents)
+	}
+	maintenance, issue := report.PlatformEvents[0], report.PlatformEvents[1]
+	if maintenance.TrackingID != "PM-123" || maintenance.Scope != "global" || maintenance.Categories[0] != ServiceCategoryNetworking {
+		t.Errorf("Unexpected planned maintenance %+v", maintenance)
+	}
+	if issue.TrackingID != "AKS1-XYZ" || issue.Scope != "region" || issue.Summary != "Customers in East US may see latency." {
+		t.Errorf("Unexpected service issue %+v", issue)
+	}
+
+	want := []string{ClassificationPlatform, ClassificationUserInitiated, ClassificationClusterSpecific}
+	if len(report.ClusterEvents) != len(want) {
+		t.Fatalf("Expected %d cluster events, got %+v", len(want), report.ClusterEvents)
+	}
+	for i, classification := range want {
+		if report.ClusterEvents[i].Classification != classification {
+			t.Errorf("Event %d: expected %s, got %+v", i, classification, report.ClusterEvents[i])
+		}
+	}
+	if related := report.ClusterEvents[0].RelatedEvents; len(related) != 1 || related[0] != "AKS1-XYZ" {
+		t.Errorf("Expected the degradation to be related to AKS1-XYZ, got %v", related)
+	}
+	if report.Counts["ServiceIssue"] != 1 || report.Counts["PlannedMaintenance"] != 1 || len(report.Warnings) != 0 {
+		t.Errorf("Unexpected counts %v or warnings %v", report.Counts, report.Warnings)
+	}
+	if !strings.Contains(report.Summary, "2 Service Health event(s)") {
+		t.Errorf("Unexpected summary %q", report.Summary)
+	}
+}
+
+func TestHandleServiceHealthQueryWarningsAndMode(t *testing.T) {
+	if _, err := ValidateResourceHealthMode(map[string]interface{}{"mode": "region"}); err == nil {
+		t.Error("Expected an invalid mode to be rejected")
+	}
+	if mode, err := ValidateResourceHealthMode(map[string]interface{}{}); err != nil || mode != ResourceHealthModeCluster {
+		t.Errorf("Expected the cluster mode by default, got %q (%v)", mode, err)
+	}
+
+	api := &fakeSLOARM{responses: map[string]string{"managedClusters/aks?": `{"location":"westeurope"}`}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleServiceHealthQuery(params, api, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleServiceHealthQuery failed: %v", err)
+	}
+	var report ServiceHealthReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Warnings) != 2 || len(report.PlatformEvents) != 0 {
+		t.Errorf("Expected warnings for both unreadable sources, got %+v", report)
+	}
+
+	params["start_time"] = "2024-05-02T00:00:00Z"
+	params["end_time"] = "2024-05-01T00:00:00Z"
+	if _, err := HandleServiceHealthQuery(params, api, config.NewConfig()); err == nil {
+		t.Error("Expected a start_time after end_time to be rejected")
+	}
+}
+
+// fakeARMWriter records the bodies written with CallARMWithBody
+type fakeARMWriter struct {
+	fakeSLOARM
+	writes map[string]interface{}
+}
+
+func (f *fakeARMWriter) CallARMWithBody(_ context.Context, _, path string, payload interface{}) ([]byte, error) {
+	f.writes[path] = payload
+	return []byte(`{}`), nil
+}
+
+func TestHandleDeployKQLFunctions(t *testing.T) {
+	api := &fakeARMWriter{writes: map[string]interface{}{}, fakeSLOARM: fakeSLOARM{responses: map[string]string{
+		"diagnosticSettings": `{"value": [
+			{"properties": {"workspaceId": "/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws1",
+			 "logAnalyticsDestinationType": "Dedicated", "logs": [{"category": "kube-apiserver", "enabled": true}, {"category": "kube-audit", "enabled": true}]}}]}`,
+	}}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	cfg := config.NewConfig()
+	if _, err := HandleDeployKQLFunctions(params, api, cfg); err == nil || !strings.Contains(err.Error(), "readwrite") {
+		t.Fatalf("Expected readonly access to be rejected, got %v", err)
+	}
+
+	cfg.AccessLevel = "readwrite"
+	result, err := HandleDeployKQLFunctions(params, api, cfg)
+	if err != nil {
+		t.Fatalf("HandleDeployKQLFunctions failed: %v", err)
+	}
+	var deployment KQLFunctionDeployment
+	if err := json.Unmarshal([]byte(result), &deployment); err != nil {
+		t.Fatalf("Failed to parse deployment: %v", err)
+	}
+	if len(deployment.Deployed) != 6 || len(deployment.Skipped) != 1 || deployment.Skipped[0].Name != "AKSAutoscalerScaleEvents" {
+		t.Fatalf("Expected every function but the autoscaler one deployed, got %+v", deployment)
+	}
+	if deployment.Deployed[0].TableMode != "resource-specific" {
+		t.Errorf("Expected the resource-specific table mode of the diagnostic setting, got %+v", deployment.Deployed[0])
+	}
+	path := "/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws1/savedSearches/aksmcp-aksauditforbidden?api-version=" + savedSearchesAPIVersion
+	body, ok := api.writes[path].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected AKSAuditForbidden to be saved at %s, got %v", path, api.writes)
+	}
+	properties := body["properties"].(map[string]interface{})
+	if properties["functionAlias"] != "AKSAuditForbidden" || properties["functionParameters"] != "clusterId:string" ||
+		!strings.HasPrefix(properties["query"].(string), "AKSAudit | where _ResourceId =~ clusterId") {
+		t.Errorf("Unexpected saved function %v", properties)
+	}
+
+	params["functions"] = "AKSAuditForbidden, Unknown"
+	if _, err := HandleDeployKQLFunctions(params, api, cfg); err == nil {
+		t.Error("Expected an unknown function name to be rejected")
+	}
+}
+
+func podSecurityKubectl() *fakeExecutor {
+	return &fakeExecutor{outputs: map[string]string{
+		"get namespaces": `{"items": [
+			{"metadata": {"name": "default"}},
+			{"metadata": {"name": "kube-system"}},
+			{"metadata": {"name": "shop", "labels": {"pod-security.kubernetes.io/enforce": "baseline", "pod-security.kubernetes.io/audit": "restricted", "pod-security.kubernetes.io/warn": "restricted"}}},
+			{"metadata": {"name": "web", "labels": {"pod-security.kubernetes.io/enforce": "baseline", "pod-security.kubernetes.io/enforce-version": "v1.30", "pod-security.kubernetes.io/audit": "restricted"}}}
+		]}`,
+		"get constraints": `{"items": [
+			{"kind": "K8sAzureV2NoPrivilege", "metadata": {"name": "azurepolicy-k8sazurev2noprivilege-abc"}, "spec": {"enforcementAction": "dryrun"}, "status": {"totalViolations": 3}},
+			{"kind": "K8sAzureV1ContainerLimits", "metadata": {"name": "azurepolicy-limits"}, "spec": {}, "status": {"totalViolations": 5}}
+		]}`,
+		"get events": `{"items": [
+			{"involvedObject": {"kind": "ReplicaSet", "name": "cart-1", "namespace": "shop"}, "count": 2, "lastTimestamp": "2024-05-01T10:00:00Z",
+			 "message": "Error creating: admission webhook \"validation.gatekeeper.sh\" denied the request: [azurepolicy-k8sazurev2noprivilege-abc] Privileged container is not allowed: app"},
+			{"involvedObject": {"kind": "ReplicaSet", "name": "api-1", "namespace": "web"}, "count": 4, "lastTimestamp": "2024-05-01T11:00:00Z",
+			 "message": "Error creating: pods \"api-1-x\" is forbidden: violates PodSecurity \"baseline:v1.30\": host namespaces (hostNetwork=true)"},
+			{"involvedObject": {"kind": "ReplicaSet", "name": "limits-1", "namespace": "shop"},
+			 "message": "Error creating: admission webhook \"validation.gatekeeper.sh\" denied the request: [azurepolicy-limits] memory limit missing"}
+		]}`,
+	}}
+}
+
+func TestHandlePodSecurityQuery(t *testing.T) {
+	api := &fakeSLOARM{responses: map[string]string{
+		"diagnosticSettings": `{"value":[{"properties":{"workspaceId":"/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws2","logAnalyticsDestinationType":"Dedicated","logs":[{"category":"kube-audit","enabled":true}]}}]}`,
+		"workspaces/ws2?":    `{"properties":{"customerId":"00000000-1111-2222-3333-444444444444"}}`,
+	}}
+	az := &fakeExecutor{outputs: map[string]string{
+		"violates PodSecurity": `[
+			{"Namespace":"shop","Resource":"deployments","Name":"cart","Mode":"audit","Detail":"allowPrivilegeEscalation != false","Policy":"baseline:latest","Username":"ci","Count":"3","LastSeen":"2024-05-01T10:00:00Z"},
+			{"Namespace":"web","Resource":"pods","Name":"","Mode":"enforce","Detail":"pods \"api-1-x\" is forbidden: violates PodSecurity","Username":"system:serviceaccount:kube-system:replicaset-controller","Count":"2"},
+			{"Namespace":"shop","Resource":"pods","Name":"debug","Mode":"policy","Detail":"admission webhook \"validation.gatekeeper.sh\" denied the request: [azurepolicy-k8sazurev2noprivilege-abc] privileged","Count":"1"},
+			{"Namespace":"shop","Resource":"pods","Name":"greedy","Mode":"policy","Detail":"admission webhook \"validation.gatekeeper.sh\" denied the request: [azurepolicy-limits] memory","Count":"1"}
+		]`,
+	}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandlePodSecurityQuery(params, api, az, podSecurityKubectl(), config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandlePodSecurityQuery failed: %v", err)
+	}
+	var report PodSecurityReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if len(report.PolicyConstraints) != 1 || report.PolicyConstraints[0].Kind != "K8sAzureV2NoPrivilege" {
+		t.Errorf("Expected only the pod security constraint, got %+v", report.PolicyConstraints)
+	}
+	if len(report.Violations) != 2 || report.Violations[0].Object != "deployments/cart" || len(report.PolicyDenials) != 1 {
+		t.Errorf("Expected the audit log rows without the events, got %+v and %+v", report.Violations, report.PolicyDenials)
+	}
+	namespaces := map[string]NamespacePodSecurity{}
+	for _, ns := range report.Namespaces {
+		namespaces[ns.Name] = ns
+	}
+	if ns := namespaces["shop"]; ns.AuditViolations != 3 || ns.PolicyDenials != 1 || !strings.Contains(ns.Recommendation, "fix the 3 restricted violations") {
+		t.Errorf("Unexpected shop namespace %+v", ns)
+	}
+	if ns := namespaces["web"]; ns.EnforceDenials != 2 || ns.EnforceVersion != "v1.30" || !strings.Contains(ns.Recommendation, "raise enforce to restricted") {
+		t.Errorf("Unexpected web namespace %+v", ns)
+	}
+	if ns := namespaces["default"]; ns.Labeled || ns.Enforce != "privileged" || !strings.Contains(ns.Recommendation, "audit=baseline and warn=baseline") {
+		t.Errorf("Unexpected default namespace %+v", ns)
+	}
+	if ns := namespaces["kube-system"]; !ns.System || ns.Recommendation != "" {
+		t.Errorf("Expected no recommendation for kube-system, got %+v", ns)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{
+		"Pod Security Admission rejected 2 requests in namespace web",
+		"1 namespaces have no Pod Security Admission labels and run as privileged: default",
+		"azurepolicy-k8sazurev2noprivilege-abc (K8sAzureV2NoPrivilege) reports 3 existing violations with enforcement dryrun",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected findings to contain %q, got:\n%s", want, findings)
+		}
+	}
+}
+
+func TestHandlePodSecurityQuery_WithoutAuditLogs(t *testing.T) {
+	api := &fakeSLOARM{responses: map[string]string{"diagnosticSettings": `{"value":[]}`}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandlePodSecurityQuery(params, api, &fakeExecutor{}, podSecurityKubectl(), config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandlePodSecurityQuery failed: %v", err)
+	}
+	var report PodSecurityReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "enable kube-audit") {
+		t.Errorf("Expected a missing audit logs warning, got %v", report.Warnings)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Count != 4 || len(report.PolicyDenials) != 1 || report.PolicyDenials[0].Object != "ReplicaSet/cart-1" {
+		t.Errorf("Expected the denials from events, got %+v and %+v", report.Violations, report.PolicyDenials)
+	}
+	for _, ns := range report.Namespaces {
+		if ns.Name == "web" && !strings.Contains(ns.Recommendation, "kubectl label --dry-run=server --overwrite ns web pod-security.kubernetes.io/enforce=restricted") {
+			t.Errorf("Expected a server dry-run recommendation without audit logs, got %q", ns.Recommendation)
+		}
+	}
+}
+
+func TestPodSecurityAuditQuery(t *testing.T) {
+	clusterID := "/subscriptions/sub/resourceGroups/RG/providers/Microsoft.ContainerService/managedClusters/aks"
+	query := PodSecurityAuditQuery(auditDestination{Category: "kube-audit", ResourceSpecific: true}, clusterID)
+	if !strings.HasPrefix(query, "AKSAudit | where _ResourceId == '"+strings.ToLower(clusterID)+"'") ||
+		!strings.Contains(query, "Annotations['pod-security.kubernetes.io/audit-violations']") {
+		t.Errorf("Unexpected resource-specific query: %s", query)
+	}
+	query = PodSecurityAuditQuery(auditDestination{Category: "kube-audit-admin"}, clusterID)
+	if !strings.Contains(query, "Category == 'kube-audit-admin'") || !strings.Contains(query, "Event.annotations[") || strings.Contains(query, `"`) {
+		t.Errorf("Unexpected AzureDiagnostics query: %s", query)
+	}
+}
+
+// fakeQuotaARM is a fakeSLOARM that also returns request quota headers
+type fakeQuotaARM struct {
+	fakeSLOARM
+	header http.Header
+}
+
+func (f *fakeQuotaARM) CallARMWithHeaders(ctx context.Context, method, path string) ([]byte, http.Header, error) {
+	body, err := f.CallARM(ctx, method, path)
+	return body, f.header, err
+}
+
+func throttledEvent(caller, operation, timestamp string) string {
+	return fmt.Sprintf(`{"caller":%q,"eventTimestamp":%q,"operationName":{"value":%q},"subStatus":{"value":"TooManyRequests","localizedValue":"Too Many Requests (HTTP Status Code: 429)"},"properties":{}}`,
+		caller, timestamp, operation)
+}
+
+func TestHandleARMThrottlingQuery(t *testing.T) {
+	vmssWrite := "Microsoft.Compute/virtualMachineScaleSets/write"
+	api := &fakeQuotaARM{
+		fakeSLOARM: fakeSLOARM{responses: map[string]string{
+			"resourcegroups/rg?": `{}`,
+			"managedClusters/aks?": `{"identity":{"principalId":"aaaa-control"},"properties":{"servicePrincipalProfile":{"clientId":"msi"},
+				"identityProfile":{"kubeletidentity":{"clientId":"bbbb-kubelet","objectId":"cccc-kubelet"}}}}`,
+			"eventtypes/management": `{"value":[` + strings.Join([]string{
+				throttledEvent("aaaa-control", vmssWrite, "2024-05-01T10:05:00Z"),
+				throttledEvent("aaaa-control", vmssWrite, "2024-05-01T10:01:00Z"),
+				throttledEvent("ci@contoso.com", "Microsoft.Network/loadBalancers/write", "2024-05-01T10:03:00Z"),
+				`{"caller":"ops@contoso.com","eventTimestamp":"2024-05-01T10:04:00Z","operationName":{"value":"` + vmssWrite + `"},"subStatus":{"value":"Created"},"properties":{"statusCode":"Created"}}`,
+			}, ",") + `]}`,
+			"diagnosticSettings": `{"value":[{"properties":{"workspaceId":"/subscriptions/sub/resourceGroups/logs/providers/Microsoft.OperationalInsights/workspaces/ws1",
+				"logAnalyticsDestinationType":"Dedicated","logs":[{"category":"cloud-controller-manager","enabled":true},{"category":"cluster-autoscaler","enabled":false}]}}]}`,
+			"workspaces/ws1?": `{"properties":{"customerId":"00000000-1111-2222-3333-444444444444"}}`,
+		}},
+		header: http.Header{
+			"X-Ms-Ratelimit-Remaining-Subscription-Reads":        []string{"1500"},
+			"X-Ms-Ratelimit-Remaining-Subscription-Global-Reads": []string{"249"},
+			"X-Ms-Request-Id": []string{"id"},
+		},
+	}
+	az := &fakeExecutor{outputs: map[string]string{
+		"AKSControlPlane": `[{"ResourceType":"Microsoft.Network/loadBalancers","Count":"42","LastSeen":"2024-05-01T10:06:00Z","MaxRetryAfter":"30","Sample":"HTTPStatusCode: 429"}]`,
+	}}
+
+	params := map[string]interface{}{
+		"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks",
+		"start_time": time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+	}
+	result, err := HandleARMThrottlingQuery(params, api, az, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleARMThrottlingQuery failed: %v", err)
+	}
+	var report ARMThrottlingReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if len(report.Quota.Counters) != 2 || report.Quota.Counters[1].Header != "x-ms-ratelimit-remaining-subscription-reads" || report.Quota.Counters[1].ConsumedPercent != 87.5 {
+		t.Errorf("Unexpected quota counters: %+v", report.Quota.Counters)
+	}
+	if report.ActivityLog.EventsRead != 4 || report.ActivityLog.Throttled != 3 || len(report.ActivityLog.Callers) != 2 {
+		t.Fatalf("Unexpected Activity Log summary: %+v", report.ActivityLog)
+	}
+	noisiest := report.ActivityLog.Callers[0]
+	if noisiest.Caller != "aaaa-control" || noisiest.Count != 2 || noisiest.ClusterIdentity != "control plane" ||
+		noisiest.FirstSeen != "2024-05-01T10:01:00Z" || noisiest.LastSeen != "2024-05-01T10:05:00Z" {
+		t.Errorf("Unexpected noisiest caller: %+v", noisiest)
+	}
+	if len(report.Controllers.Components) != 1 || report.Controllers.Components[0].Component != "cloud-controller-manager" {
+		t.Errorf("Unexpected controller throttling: %+v", report.Controllers.Components)
+	}
+	if !strings.Contains(report.Controllers.Error, "no diagnostic setting sends cluster-autoscaler logs") {
+		t.Errorf("Expected the missing autoscaler logs to be reported, got %q", report.Controllers.Error)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{
+		"x-ms-ratelimit-remaining-subscription-reads is 87.5% consumed (1500 of 12000 left)",
+		"3 requests in the subscription were rejected with 429",
+		"most throttled caller is aaaa-control on " + vmssWrite + " (2)",
+		"cluster's control plane identity aaaa-control",
+		"cloud-controller-manager logged 42 throttled ARM requests, mostly on Microsoft.Network/loadBalancers (Retry-After up to 30s)",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected findings to contain %q, got:\n%s", want, findings)
+		}
+	}
+}
+
+func TestHandleARMThrottlingQuery_InvalidParameters(t *testing.T) {
+	base := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	for name, extra := range map[string]map[string]interface{}{
+		"window too long":   {"start_time": "2024-05-01T00:00:00Z", "end_time": "2024-05-09T00:00:00Z"},
+		"outside retention": {"start_time": "2020-05-01T00:00:00Z", "end_time": "2020-05-02T00:00:00Z"},
+		"bad top":           {"top": "0"},
+	} {
+		params := map[string]interface{}{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+		if _, err := HandleARMThrottlingQuery(params, &fakeQuotaARM{}, &fakeExecutor{}, config.NewConfig()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestControllerThrottlingQuery(t *testing.T) {
+	clusterID := "/subscriptions/sub/resourceGroups/RG/providers/Microsoft.ContainerService/managedClusters/aks"
+	query := ControllerThrottlingQuery(auditDestination{Category: "cluster-autoscaler"}, clusterID, 5)
+	if !strings.HasPrefix(query, "AzureDiagnostics | where Category == 'cluster-autoscaler' and ResourceId == '"+strings.ToUpper(clusterID)+"'") ||
+		!strings.HasSuffix(query, "take 5") {
+		t.Errorf("Unexpected AzureDiagnostics query: %s", query)
+	}
+	if strings.ContainsAny(query, "\"\\") {
+		t.Errorf("Expected no double quotes or backslashes in the query: %s", query)
+	}
+}
@@ -8,7 +8,9 @@ import (
 // supportedMonitoringOperations defines all supported monitoring operations
 var supportedMonitoringOperations = []string{
 	string(OpMetrics), string(OpResourceHealth), string(OpAppInsights),
-	string(OpDiagnostics), string(OpControlPlaneLogs),
+	string(OpDiagnostics), string(OpControlPlaneLogs), string(OpFiredAlerts), string(OpSafeguards),
+	string(OpConfigHistory), string(OpAPIServerSLO), string(OpAPIServerLoad), string(OpDeployKQL),
+	string(OpPodSecurity), string(OpARMThrottling),
 }
 
 // ValidateMonitoringOperation checks if the monitoring operation is supported
@@ -21,10 +23,12 @@ func GetSupportedMonitoringOperations() []string {
 	return supportedMonitoringOperations
 }
 
+// MetricsQueryTypes are the query types of the metrics operation
+var MetricsQueryTypes = []string{"list", "list-definitions", "list-namespaces"}
+
 // ValidateMetricsQueryType checks if the metrics query type is supported
 func ValidateMetricsQueryType(queryType string) bool {
-	supportedTypes := []string{"list", "list-definitions", "list-namespaces"}
-	return slices.Contains(supportedTypes, queryType)
+	return slices.Contains(MetricsQueryTypes, queryType)
 }
 
 // MapMetricsQueryTypeToCommand maps a metrics query type to its corresponding az command
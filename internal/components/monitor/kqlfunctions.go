@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/monitor/diagnostics"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// savedSearchesAPIVersion is the Log Analytics API version used to create saved functions
+const savedSearchesAPIVersion = "2020-08-01"
+
+// kqlFunctionCategory groups the deployed functions in the workspace's saved queries
+const kqlFunctionCategory = "AKS MCP"
+
+// ARMWriter reads and writes ARM resources. *azureclient.AzureClient implements it.
+type ARMWriter interface {
+	common.ARMCaller
+	CallARMWithBody(ctx context.Context, method, path string, payload interface{}) ([]byte, error)
+}
+
+// DeployedFunction is a library function saved to a workspace
+type DeployedFunction struct {
+	Name        string `json:"name"`
+	Workspace   string `json:"workspace"`
+	TableMode   string `json:"tableMode"`
+	Description string `json:"description"`
+}
+
+// SkippedFunction is a library function that was not deployed, and why
+type SkippedFunction struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// KQLFunctionDeployment is the result of the deploy_kql_functions operation
+type KQLFunctionDeployment struct {
+	ClusterName string             `json:"clusterName"`
+	Deployed    []DeployedFunction `json:"deployed"`
+	Skipped     []SkippedFunction  `json:"skipped,omitempty"`
+	Usage       string             `json:"usage"`
+}
+
+// diagnosticDestination is a workspace receiving cluster logs and its destination table mode
+type diagnosticDestination struct {
+	WorkspaceID      string
+	ResourceSpecific bool
+	categories       map[string]bool
+	categoryGroups   map[string]bool
+}
+
+// sends reports whether the destination receives a log category. The allLogs and audit category groups
+// include the audit categories, and allLogs includes every other category.
+func (d diagnosticDestination) sends(category string) bool {
+	if d.categories[category] || d.categoryGroups["alllogs"] {
+		return true
+	}
+	return d.categoryGroups["audit"] && strings.HasPrefix(category, "kube-audit")
+}
+
+// HandleDeployKQLFunctions saves the curated KQL functions to the Log Analytics workspaces receiving the log
+// categories they read, in the table mode of each workspace's diagnostic setting, so control_plane_logs can
+// invoke them by name
+func HandleDeployKQLFunctions(params map[string]interface{}, api ARMWriter, cfg *config.ConfigData) (string, error) {
+	if cfg.AccessLevel == "readonly" {
+		return "", fmt.Errorf("deploy_kql_functions saves functions to the Log Analytics workspace and requires readwrite or admin access")
+	}
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	functions := diagnostics.FunctionLibrary()
+	if names, _ := params["functions"].(string); names != "" {
+		var selected []diagnostics.KQLFunction
+		for _, name := range strings.Split(names, ",") {
+			fn, ok := diagnostics.FindKQLFunction(strings.TrimSpace(name))
+			if !ok {
+				return "", fmt.Errorf("unknown KQL function %s", strings.TrimSpace(name))
+			}
+			selected = append(selected, fn)
+		}
+		functions = selected
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	destinations, err := readDiagnosticDestinations(ctx, api, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	result := KQLFunctionDeployment{ClusterName: clusterName, Deployed: []DeployedFunction{},
+		Usage: fmt.Sprintf(`invoke a function with operation="control_plane_logs" and parameters {"function": "<name>", "start_time": "<start-time>"}, `+
+			"or in the workspace as <name>('%s')", clusterID)}
+	for _, fn := range functions {
+		var dest *diagnosticDestination
+		for i := range destinations {
+			if destinations[i].sends(fn.Category) {
+				dest = &destinations[i]
+				break
+			}
+		}
+		if dest == nil {
+			result.Skipped = append(result.Skipped, SkippedFunction{Name: fn.Name,
+				Reason: fmt.Sprintf("no diagnostic setting sends the %s log category to a Log Analytics workspace", fn.Category)})
+			continue
+		}
+
+		tableMode := "AzureDiagnostics"
+		if dest.ResourceSpecific {
+			tableMode = "resource-specific"
+		}
+		body := map[string]interface{}{
+			"properties": map[string]interface{}{
+				"category":           kqlFunctionCategory,
+				"displayName":        fn.Name,
+				"query":              fn.Query(dest.ResourceSpecific),
+				"functionAlias":      fn.Name,
+				"functionParameters": diagnostics.FunctionParameters,
+				"version":            2,
+				"tags":               []map[string]string{{"name": "description", "value": fn.Description}},
+			},
+		}
+		path := fmt.Sprintf("%s/savedSearches/aksmcp-%s?api-version=%s", dest.WorkspaceID, strings.ToLower(fn.Name), savedSearchesAPIVersion)
+		if _, err := api.CallARMWithBody(ctx, http.MethodPut, path, body); err != nil {
+			result.Skipped = append(result.Skipped, SkippedFunction{Name: fn.Name, Reason: fmt.Sprintf("failed to save the function: %v", err)})
+			continue
+		}
+		result.Deployed = append(result.Deployed, DeployedFunction{Name: fn.Name, Workspace: dest.WorkspaceID, TableMode: tableMode, Description: fn.Description})
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal KQL function deployment to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// readDiagnosticDestinations reads the Log Analytics workspaces the cluster's diagnostic settings send logs to
+func readDiagnosticDestinations(ctx context.Context, api common.ARMCaller, clusterID string) ([]diagnosticDestination, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, fmt.Sprintf("%s/providers/Microsoft.Insights/diagnosticSettings?api-version=%s", clusterID, diagnosticSettingsAPIVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diagnostic settings: %w", err)
+	}
+	var result struct {
+		Value []struct {
+			Properties struct {
+				WorkspaceID                 string `json:"workspaceId"`
+				LogAnalyticsDestinationType string `json:"logAnalyticsDestinationType"`
+				Logs                        []struct {
+					Category      string `json:"category"`
+					CategoryGroup string `json:"categoryGroup"`
+					Enabled       bool   `json:"enabled"`
+				} `json:"logs"`
+			} `json:"properties"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse diagnostic settings: %w", err)
+	}
+	var destinations []diagnosticDestination
+	for _, setting := range result.Value {
+		if setting.Properties.WorkspaceID == "" {
+			continue
+		}
+		dest := diagnosticDestination{
+			WorkspaceID:      setting.Properties.WorkspaceID,
+			ResourceSpecific: strings.EqualFold(setting.Properties.LogAnalyticsDestinationType, "Dedicated"),
+			categories:       map[string]bool{},
+			categoryGroups:   map[string]bool{},
+		}
+		for _, log := range setting.Properties.Logs {
+			if !log.Enabled {
+				continue
+			}
+			if log.Category != "" {
+				dest.categories[log.Category] = true
+			}
+			if log.CategoryGroup != "" {
+				dest.categoryGroups[strings.ToLower(log.CategoryGroup)] = true
+			}
+		}
+		destinations = append(destinations, dest)
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("no diagnostic setting of the cluster sends logs to a Log Analytics workspace; " +
+			"create one with the control plane log categories before deploying the KQL functions")
+	}
+	return destinations, nil
+}
@@ -0,0 +1,427 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// defaultPodSecurityWindow is how far back audit logs are searched for Pod Security violations
+const defaultPodSecurityWindow = 24 * time.Hour
+
+// maxPodSecurityRows bounds the violation and denial groups read from the audit logs
+const maxPodSecurityRows = 200
+
+// Pod Security Standards levels, from least to most restrictive
+const (
+	psaPrivileged = "privileged"
+	psaBaseline   = "baseline"
+	psaRestricted = "restricted"
+)
+
+// Pod Security Admission namespace labels
+const (
+	psaLabelPrefix       = "pod-security.kubernetes.io/"
+	psaAuditAnnotation   = psaLabelPrefix + "audit-violations"
+	psaEnforceAnnotation = psaLabelPrefix + "enforce-policy"
+)
+
+// psaLevels ranks the Pod Security Standards levels
+var psaLevels = map[string]int{psaPrivileged: 0, psaBaseline: 1, psaRestricted: 2}
+
+// systemNamespaces run platform components that need privileges, so no tightening is recommended for them
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease", "gatekeeper-system", "calico-system", "tigera-operator", "app-routing-system"}
+
+// podSecurityConstraintFragments are lowercase fragments of the kinds of Gatekeeper constraints (Azure Policy
+// pod security baseline and restricted initiatives) that check pod security settings
+var podSecurityConstraintFragments = []string{
+	"privilege", "hostnamespace", "hostfilesystem", "hostnetwork", "hostport", "capabilit", "readonlyrootfilesystem",
+	"allowedusers", "seccomp", "apparmor", "selinux", "procmount", "volumetypes", "sysctl", "flexvolume",
+}
+
+// NamespacePodSecurity is the Pod Security Admission configuration of a namespace and what was seen violating it
+type NamespacePodSecurity struct {
+	Name string `json:"name"`
+	// Enforce, Audit and Warn are the levels of the namespace labels; an unlabeled mode is privileged
+	Enforce        string `json:"enforce"`
+	EnforceVersion string `json:"enforceVersion,omitempty"`
+	Audit          string `json:"audit"`
+	AuditVersion   string `json:"auditVersion,omitempty"`
+	Warn           string `json:"warn"`
+	WarnVersion    string `json:"warnVersion,omitempty"`
+	Labeled        bool   `json:"labeled"`
+	System         bool   `json:"system,omitempty"`
+	// AuditViolations counts the requests the audit level flagged in the window
+	AuditViolations int `json:"auditViolations"`
+	// EnforceDenials counts the requests and pod creations the enforce level rejected
+	EnforceDenials int `json:"enforceDenials"`
+	// PolicyDenials counts the requests Azure Policy pod security constraints rejected
+	PolicyDenials  int    `json:"policyDenials"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+// PodSecurityViolation is a group of requests Pod Security Admission flagged or rejected for one object
+type PodSecurityViolation struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	// Mode is audit for violations recorded by the audit level, enforce for rejected requests
+	Mode     string `json:"mode"`
+	Policy   string `json:"policy,omitempty"`
+	Message  string `json:"message"`
+	Username string `json:"username,omitempty"`
+	Count    int    `json:"count"`
+	LastSeen string `json:"lastSeen,omitempty"`
+}
+
+// PodSecurityReport is the result of the pod_security operation
+type PodSecurityReport struct {
+	ClusterName    string                 `json:"clusterName"`
+	StartTime      string                 `json:"startTime"`
+	EndTime        string                 `json:"endTime"`
+	AuditCategory  string                 `json:"auditCategory,omitempty"`
+	AuditWorkspace string                 `json:"auditWorkspace,omitempty"`
+	Namespaces     []NamespacePodSecurity `json:"namespaces"`
+	Violations     []PodSecurityViolation `json:"violations"`
+	// PolicyConstraints are the Gatekeeper constraints that check pod security settings
+	PolicyConstraints []SafeguardPolicy `json:"policyConstraints"`
+	PolicyDenials     []AdmissionDenial `json:"policyDenials"`
+	Findings          []string          `json:"findings"`
+	Warnings          []string          `json:"warnings,omitempty"`
+}
+
+// HandlePodSecurityQuery reports the Pod Security Admission labels of each namespace, the audit violations and
+// enforce denials of the window from kube-audit logs and FailedCreate events, and the Azure Policy pod security
+// constraints and their denials, with a recommendation on how far each namespace can be tightened
+func HandlePodSecurityQuery(params map[string]interface{}, api common.ARMCaller, azExecutor, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := parseLoadWindow(params, time.Now().UTC(), defaultPodSecurityWindow)
+	if err != nil {
+		return "", err
+	}
+
+	report := PodSecurityReport{
+		ClusterName:       clusterName,
+		StartTime:         start.Format(time.RFC3339),
+		EndTime:           end.Format(time.RFC3339),
+		Namespaces:        []NamespacePodSecurity{},
+		Violations:        []PodSecurityViolation{},
+		PolicyConstraints: []SafeguardPolicy{},
+		PolicyDenials:     []AdmissionDenial{},
+	}
+
+	// Namespaces, constraints and events need cluster access; a nil executor means the server may not use its kubeconfig
+	var psaEvents, policyEvents []AdmissionDenial
+	if kubectlExecutor == nil {
+		report.Warnings = append(report.Warnings, "Kubernetes access is disabled for this server; namespace labels, Gatekeeper constraints and events were not checked")
+	} else {
+		if err := readNamespacePodSecurity(&report, kubectlExecutor, cfg); err != nil {
+			return "", err
+		}
+		psaEvents, policyEvents = collectPodSecurityPolicyState(&report, kubectlExecutor, cfg)
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	auditRead := true
+	if err := readPodSecurityAuditLogs(ctx, &report, api, azExecutor, clusterID, start, end, cfg); err != nil {
+		auditRead = false
+		report.Warnings = append(report.Warnings, fmt.Sprintf("audit violations and denials of direct requests were not read: %v", err))
+	}
+	// Pod creations of controllers are in the audit logs too, so the events are only used without them
+	if !auditRead {
+		for _, event := range psaEvents {
+			report.Violations = append(report.Violations, PodSecurityViolation{Namespace: event.Namespace, Object: event.Object, Mode: "enforce",
+				Message: event.Message, Count: event.Count, LastSeen: event.LastSeen})
+		}
+		report.PolicyDenials = append(report.PolicyDenials, policyEvents...)
+	}
+	sort.SliceStable(report.Violations, func(i, j int) bool { return report.Violations[i].Count > report.Violations[j].Count })
+
+	SummarizePodSecurity(&report, auditRead)
+	report.Findings = BuildPodSecurityFindings(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pod security report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// readNamespacePodSecurity reads the Pod Security Admission labels of all namespaces, or of the allowed ones
+func readNamespacePodSecurity(report *PodSecurityReport, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) error {
+	command := "get namespaces -o json"
+	if cfg.AllowNamespaces != "" {
+		var names []string
+		for _, ns := range strings.Split(cfg.AllowNamespaces, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				names = append(names, ns)
+			}
+		}
+		command = "get namespaces " + strings.Join(names, " ") + " -o json"
+	}
+	output, err := kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %v", err)
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return fmt.Errorf("failed to parse namespaces: %v", err)
+	}
+	for _, item := range list.Items {
+		report.Namespaces = append(report.Namespaces, ParseNamespacePodSecurity(item.Metadata.Name, item.Metadata.Labels))
+	}
+	return nil
+}
+
+// ParseNamespacePodSecurity reads the Pod Security Admission levels from namespace labels
+func ParseNamespacePodSecurity(name string, labels map[string]string) NamespacePodSecurity {
+	ns := NamespacePodSecurity{Name: name, System: slices.Contains(systemNamespaces, name)}
+	level := func(mode string) string {
+		value := strings.ToLower(labels[psaLabelPrefix+mode])
+		if value != "" {
+			ns.Labeled = true
+		}
+		if _, ok := psaLevels[value]; !ok {
+			return psaPrivileged
+		}
+		return value
+	}
+	ns.Enforce, ns.EnforceVersion = level("enforce"), labels[psaLabelPrefix+"enforce-version"]
+	ns.Audit, ns.AuditVersion = level("audit"), labels[psaLabelPrefix+"audit-version"]
+	ns.Warn, ns.WarnVersion = level("warn"), labels[psaLabelPrefix+"warn-version"]
+	return ns
+}
+
+// collectPodSecurityPolicyState adds the pod security constraints of Azure Policy to the report and returns the
+// pod creations that Pod Security Admission and those constraints rejected, from FailedCreate events
+func collectPodSecurityPolicyState(report *PodSecurityReport, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) ([]AdmissionDenial, []AdmissionDenial) {
+	if output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get constraints -o json"}, cfg); err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list Gatekeeper constraints (is the Azure Policy add-on installed?): %v", err))
+	} else if policies, err := ParseConstraints(output); err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+	} else {
+		for _, policy := range policies {
+			if IsPodSecurityConstraint(policy.Kind) {
+				report.PolicyConstraints = append(report.PolicyConstraints, policy)
+			}
+		}
+	}
+
+	var psaDenials, policyDenials []AdmissionDenial
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		output, err := kubectlExecutor.Execute(map[string]interface{}{"command": fmt.Sprintf("get events %s --field-selector reason=FailedCreate -o json", flag)}, cfg)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list FailedCreate events (%s): %v", flag, err))
+			continue
+		}
+		if denials, err := ParseAdmissionDenials(output); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			for _, denial := range denials {
+				if report.isPodSecurityDenial(denial.Message) {
+					policyDenials = append(policyDenials, denial)
+				}
+			}
+		}
+		if denials, err := parseFailedCreateEvents(output, func(message string) bool {
+			return strings.Contains(message, "violates PodSecurity")
+		}); err == nil {
+			psaDenials = append(psaDenials, denials...)
+		}
+	}
+	return psaDenials, policyDenials
+}
+
+// IsPodSecurityConstraint reports whether a Gatekeeper constraint kind checks pod security settings
+func IsPodSecurityConstraint(kind string) bool {
+	kind = strings.ToLower(kind)
+	for _, fragment := range podSecurityConstraintFragments {
+		if strings.Contains(kind, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPodSecurityDenial reports whether a Gatekeeper denial message names one of the pod security constraints.
+// Gatekeeper prefixes each violation with the constraint name in brackets.
+func (r *PodSecurityReport) isPodSecurityDenial(message string) bool {
+	for _, constraint := range r.PolicyConstraints {
+		if strings.Contains(message, "["+constraint.Name+"]") {
+			return true
+		}
+	}
+	return false
+}
+
+// readPodSecurityAuditLogs reads the requests Pod Security Admission flagged or rejected and the Gatekeeper
+// denials of pod security constraints from the kube-audit logs of the window
+func readPodSecurityAuditLogs(ctx context.Context, report *PodSecurityReport, api common.ARMCaller, azExecutor tools.CommandExecutor, clusterID string, start, end time.Time, cfg *config.ConfigData) error {
+	dest, err := findAuditDestination(ctx, api, clusterID)
+	if err != nil {
+		return err
+	}
+	report.AuditCategory, report.AuditWorkspace = dest.Category, dest.WorkspaceID
+	if dest.WorkspaceCustomer, err = workspaceCustomerID(ctx, api, dest.WorkspaceID); err != nil {
+		return err
+	}
+	rows, err := queryAuditLogs(azExecutor, dest, PodSecurityAuditQuery(dest, clusterID), start, end, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		object := rowString(row, "Resource") + "/" + rowString(row, "Name")
+		count := int(rowNumber(row, "Count"))
+		switch mode := rowString(row, "Mode"); mode {
+		case "policy":
+			if !report.isPodSecurityDenial(rowString(row, "Detail")) && len(report.PolicyConstraints) > 0 {
+				continue
+			}
+			report.PolicyDenials = append(report.PolicyDenials, AdmissionDenial{Namespace: rowString(row, "Namespace"), Object: object,
+				Message: rowString(row, "Detail"), Count: count, LastSeen: rowString(row, "LastSeen")})
+		default:
+			report.Violations = append(report.Violations, PodSecurityViolation{
+				Namespace: rowString(row, "Namespace"),
+				Object:    object,
+				Mode:      mode,
+				Policy:    rowString(row, "Policy"),
+				Message:   rowString(row, "Detail"),
+				Username:  rowString(row, "Username"),
+				Count:     count,
+				LastSeen:  rowString(row, "LastSeen"),
+			})
+		}
+	}
+	return nil
+}
+
+// PodSecurityAuditQuery selects the create and update requests of the window that the Pod Security Admission
+// audit level annotated, that its enforce level rejected or that Gatekeeper denied, grouped by object
+func PodSecurityAuditQuery(dest auditDestination, clusterID string) string {
+	var base string
+	if dest.ResourceSpecific {
+		table := "AKSAudit"
+		if dest.Category == "kube-audit-admin" {
+			table = "AKSAuditAdmin"
+		}
+		base = fmt.Sprintf("%s | where _ResourceId == '%s' and Stage == 'ResponseComplete' and Verb in ('create', 'update', 'patch')"+
+			" | project TimeGenerated, Username = tostring(User.username), Namespace = tostring(ObjectRef.namespace), Resource = tostring(ObjectRef.resource),"+
+			" Name = tostring(ObjectRef.name), Code = toint(ResponseStatus.code), Message = tostring(ResponseStatus.message),"+
+			" AuditViolations = tostring(Annotations['%s']), Policy = tostring(Annotations['%s'])",
+			table, strings.ToLower(clusterID), psaAuditAnnotation, psaEnforceAnnotation)
+	} else {
+		base = fmt.Sprintf("AzureDiagnostics | where Category == '%s' and ResourceId == '%s' | extend Event = parse_json(log_s)"+
+			" | where tostring(Event.stage) == 'ResponseComplete' and tostring(Event.verb) in ('create', 'update', 'patch')"+
+			" | project TimeGenerated, Username = tostring(Event.user.username), Namespace = tostring(Event.objectRef.namespace), Resource = tostring(Event.objectRef.resource),"+
+			" Name = tostring(Event.objectRef.name), Code = toint(Event.responseStatus.code), Message = tostring(Event.responseStatus.message),"+
+			" AuditViolations = tostring(Event.annotations['%s']), Policy = tostring(Event.annotations['%s'])",
+			dest.Category, strings.ToUpper(clusterID), psaAuditAnnotation, psaEnforceAnnotation)
+	}
+	return base + " | where isnotempty(AuditViolations) or (Code == 403 and (Message has 'violates PodSecurity' or Message has 'validation.gatekeeper.sh'))" +
+		" | extend Mode = case(isnotempty(AuditViolations), 'audit', Message has 'violates PodSecurity', 'enforce', 'policy'), Detail = iff(isnotempty(AuditViolations), AuditViolations, Message)" +
+		" | summarize Count = count(), LastSeen = max(TimeGenerated), Detail = take_any(Detail), Policy = take_any(Policy), Username = take_any(Username) by Namespace, Resource, Name, Mode" +
+		fmt.Sprintf(" | top %d by Count desc", maxPodSecurityRows)
+}
+
+// SummarizePodSecurity counts the violations and denials of each namespace and recommends the next step to
+// tighten its enforcement. Without audit logs an unviolated audit level cannot be told from an unread one.
+func SummarizePodSecurity(report *PodSecurityReport, auditRead bool) {
+	index := map[string]*NamespacePodSecurity{}
+	for i := range report.Namespaces {
+		index[report.Namespaces[i].Name] = &report.Namespaces[i]
+	}
+	for _, v := range report.Violations {
+		if ns := index[v.Namespace]; ns != nil {
+			if v.Mode == "audit" {
+				ns.AuditViolations += v.Count
+			} else {
+				ns.EnforceDenials += v.Count
+			}
+		}
+	}
+	for _, d := range report.PolicyDenials {
+		if ns := index[d.Namespace]; ns != nil {
+			ns.PolicyDenials += d.Count
+		}
+	}
+
+	for i := range report.Namespaces {
+		ns := &report.Namespaces[i]
+		if ns.System || ns.Enforce == psaRestricted {
+			continue
+		}
+		target := ns.Audit
+		if psaLevels[target] <= psaLevels[ns.Enforce] {
+			target = nextPodSecurityLevel(ns.Enforce)
+			ns.Recommendation = fmt.Sprintf("label the namespace with audit=%s and warn=%s to find violating workloads without blocking them", target, target)
+			continue
+		}
+		switch {
+		case ns.AuditViolations > 0:
+			ns.Recommendation = fmt.Sprintf("fix the %d %s violations recorded by the audit level before raising enforce to %s", ns.AuditViolations, target, target)
+		case auditRead:
+			ns.Recommendation = fmt.Sprintf("no %s audit violations were recorded in the window; raise enforce to %s", target, target)
+		default:
+			ns.Recommendation = fmt.Sprintf("run kubectl label --dry-run=server --overwrite ns %s %senforce=%s to list the pods that would violate %s before raising enforce",
+				ns.Name, psaLabelPrefix, target, target)
+		}
+	}
+}
+
+// BuildPodSecurityFindings reports rejected pods, unlabeled namespaces and Azure Policy pod security
+// constraints that only audit violations
+func BuildPodSecurityFindings(report PodSecurityReport) []string {
+	findings := []string{}
+	var unlabeled []string
+	for _, ns := range report.Namespaces {
+		if !ns.Labeled && !ns.System {
+			unlabeled = append(unlabeled, ns.Name)
+		}
+		if ns.EnforceDenials > 0 {
+			findings = append(findings, fmt.Sprintf("Pod Security Admission rejected %d requests in namespace %s (enforce=%s); the affected workloads are not running as intended",
+				ns.EnforceDenials, ns.Name, ns.Enforce))
+		}
+		if ns.PolicyDenials > 0 {
+			findings = append(findings, fmt.Sprintf("Azure Policy pod security constraints denied %d requests in namespace %s", ns.PolicyDenials, ns.Name))
+		}
+	}
+	if len(unlabeled) > 0 {
+		sort.Strings(unlabeled)
+		findings = append(findings, fmt.Sprintf("%d namespaces have no Pod Security Admission labels and run as privileged: %s",
+			len(unlabeled), strings.Join(unlabeled, ", ")))
+	}
+	for _, policy := range report.PolicyConstraints {
+		if policy.EnforcementAction != enforcementDeny && policy.TotalViolations > 0 {
+			findings = append(findings, fmt.Sprintf("Azure Policy constraint %s (%s) reports %d existing violations with enforcement %s; switching it to deny would block them on their next update",
+				policy.Name, policy.Kind, policy.TotalViolations, policy.EnforcementAction))
+		}
+	}
+	return findings
+}
+
+// nextPodSecurityLevel returns the next more restrictive Pod Security Standards level
+func nextPodSecurityLevel(level string) string {
+	if level == psaPrivileged {
+		return psaBaseline
+	}
+	return psaRestricted
+}
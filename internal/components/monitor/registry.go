@@ -13,6 +13,14 @@ const (
 	OpAppInsights      MonitoringOperationType = "app_insights"
 	OpDiagnostics      MonitoringOperationType = "diagnostics"
 	OpControlPlaneLogs MonitoringOperationType = "control_plane_logs"
+	OpFiredAlerts      MonitoringOperationType = "fired_alerts"
+	OpSafeguards       MonitoringOperationType = "safeguards"
+	OpConfigHistory    MonitoringOperationType = "config_history"
+	OpAPIServerSLO     MonitoringOperationType = "apiserver_slo"
+	OpAPIServerLoad    MonitoringOperationType = "apiserver_load"
+	OpDeployKQL        MonitoringOperationType = "deploy_kql_functions"
+	OpPodSecurity      MonitoringOperationType = "pod_security"
+	OpARMThrottling    MonitoringOperationType = "arm_throttling"
 )
 
 // RegisterAzMonitoring registers the monitoring tool
@@ -35,11 +43,18 @@ Supported Operations:
    Use for: Cluster availability issues, platform problems, service health events
    Required parameters: subscription_id, resource_group, cluster_name, start_time
    Optional: end_time, status (Available, Unavailable, Degraded, Unknown)
+   Set mode to service_health to report Azure Service Health issues, advisories and planned maintenance affecting
+   AKS, compute and networking in the cluster's region during the window (start_time defaults to 24 hours ago).
+   The cluster's own Resource Health transitions are classified as platform (during such an event), userInitiated
+   or clusterSpecific, to tell regional platform problems from issues of the cluster itself.
 
 3. Application Insights - Execute KQL queries against Application Insights telemetry
    Use for: Application performance monitoring, custom telemetry analysis, trace correlation
    Required parameters: subscription_id, resource_group, app_insights_name, query
    Optional: start_time + end_time OR timespan (not both)
+   Discovery: pass cluster_name instead of app_insights_name to find the resource from workload connection
+   strings, instrumentation keys and annotations, tags naming the cluster and the cluster's resource group.
+   The query runs when a single resource is found; otherwise the candidates are returned. Omit query to list them.
 
 4. Diagnostics - Check AKS cluster diagnostic settings configuration
    Use for: Verify logging is enabled, check log retention, validate diagnostic configuration
@@ -54,7 +69,7 @@ Supported Operations:
    - kube-scheduler
    - cluster-autoscaler
    - cloud-controller-manager
-   - guard (for authentication/authorization issues)
+   - guard (for authentication/authorization issues; user and group object IDs are named in directoryObjects when directory lookups are enabled)
    - csi-azuredisk-controller
    - csi-azurefile-controller
    - csi-snapshot-controller
@@ -62,6 +77,69 @@ Supported Operations:
    - fleet-member-net-controller-manager
    - fleet-mcs-controller-manager
    PLEASE NOTE: you need to check if the category is enabled in your cluster's diagnostic settings by using the diagnostics tool.
+   Ingestion lags several minutes. For windows ending within the last 15 minutes the newest record is checked, and
+   when ingestion lags the rows are returned in result with an ingestion warning: missing recent rows do not mean
+   no activity. Optional: widen_on_empty ("true" queries an empty recent window again from before the newest record).
+   For kube-audit and kube-audit-admin, namespace keeps the requests to objects in that namespace, and group_by="namespace"
+   counts the events by namespace, user and verb instead of listing them (cluster-scoped requests have an empty namespace).
+   Optional: profile names a projection profile that selects the columns and parses each log line into fields:
+   "klog" (builtin) returns the klog severity, time, thread, source file and message, and the server may define more
+   with --log-profiles-file. Profiles can't be combined with function, namespace or group_by.
+
+6. Fired Alerts - List Azure Monitor alerts targeting the cluster and its node resource group
+   Use for: Including alerting state in health assessments, finding active metric/log alerts
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: time_range (1h, 1d, 7d, 30d; default 1d), include_resolved (default "true")
+
+7. Safeguards - Report deployment safeguards (Azure Policy for AKS) status
+   Use for: Explaining why a deployment was rejected or warned by policy
+   Reports: safeguards level (Off, Warning, Enforcement), policy assignments on the cluster,
+   enforced (deny) and warn Gatekeeper policies with audit violations, and recent admission webhook denials
+   Required parameters: subscription_id, resource_group, cluster_name
+
+8. Config History - Reconstruct when and by whom cluster settings changed from Activity Log write operations
+   Use for: Finding who changed authorized IP ranges, node pool counts or diagnostic settings, and what changed
+   Reports: each write or delete on the cluster, its node pools and diagnostic settings with caller and status,
+   and before/after property diffs where the Activity Log recorded the request body
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: start_time (default 7 days before end_time, within the 90 day retention), end_time (default now)
+
+9. API Server SLO - Report API server availability over a window, e.g. for monthly reviews
+   Use for: Checking uptime against the SLA, listing the incidents that consumed the error budget
+   Reports: availability percent, target (from slo_target or the pricing tier's uptime SLA), error budget,
+   Unavailable and Degraded periods from Resource Health, and whether apiserver request metrics were missing during each
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: window_days (default 30) or start_time, end_time (default now), slo_target, count_degraded (default "false").
+   Resource Health keeps 30 days of history, so the window must start within the last 30 days.
+
+10. API Server Load - Analyze API server latency, inflight requests and throttling over a window
+   Use for: Finding why kubectl or controllers are slow or get 429 Too Many Requests, and which clients cause the load
+   Reports: average and peak inflight requests by request kind (platform metrics), p50/p95/p99 latency by verb,
+   429 rejections by API Priority and Fairness over time, and the top user agents and users by request count
+   with their lists, watches, mutations and rejections (kube-audit logs in the diagnostic settings workspace)
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: start_time (default 1 hour before end_time), end_time (default now), top (default 10, at most 50).
+
+11. Deploy KQL Functions - Save a curated library of KQL functions to the cluster's Log Analytics workspace
+   Use for: Reusing the same diagnostic queries across a team, in the portal and through control_plane_logs
+   Functions: AKSControlPlaneErrors, AKSControlPlaneErrorTrend (control plane error summaries), AKSAuditForbidden,
+   AKSAuditMutations, AKSAuditSecretAccess, AKSAuditThrottled (audit helpers), AKSAutoscalerScaleEvents.
+   Each takes the cluster resource ID and is saved to the workspace receiving the log category it reads,
+   in that diagnostic setting's table mode. Requires readwrite or admin access.
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: functions (comma-separated names, default all)
+   Invoke a deployed function with control_plane_logs and the function parameter instead of log_category.
+   The window must not exceed 7 days. kube-audit (or kube-audit-admin, without reads) must be sent to Log Analytics.
+
+12. Pod Security - Summarize Pod Security Admission and Azure Policy pod security enforcement per namespace
+   Use for: Tightening pod security enforcement without breaking workloads
+   Reports: each namespace's enforce, audit and warn levels (unlabeled modes are privileged), the requests the
+   audit level flagged and the enforce level rejected (kube-audit logs, or FailedCreate events without them),
+   Azure Policy pod security constraints with their violations and denials, and per namespace the next safe step
+   (add audit and warn labels, fix recorded violations, or raise enforce when none were recorded)
+   Required parameters: subscription_id, resource_group, cluster_name
+   Optional: start_time (default 24 hours before end_time), end_time (default now). The window must not exceed 7 days.
+   Warn violations are only returned to the client that made the request and are not in the audit logs.
 
 Use This Tool When You Need To:
 - Monitor cluster or other azure resource performance and usage (use metrics)
@@ -74,6 +152,13 @@ Use This Tool When You Need To:
 - Check storage-related problems (use control_plane_logs with csi-azuredisk-controller, csi-azurefile-controller)
 - Analyze cluster scaling behavior (use control_plane_logs with cluster-autoscaler)
 - Review security audit events (use control_plane_logs with kube-audit, kube-audit-admin)
+- Check which alerts are currently firing for the cluster (use fired_alerts)
+- Understand why a deployment was denied by policy (use safeguards)
+- Find out who changed a cluster setting and when (use config_history)
+- Produce an uptime report against the SLA (use apiserver_slo)
+- Find misbehaving operators overloading the API server or being throttled (use apiserver_load)
+- Find namespaces whose pod security enforcement can be raised and the workloads blocking it (use pod_security)
+- Find who is exhausting the subscription's ARM request quota and whether the cloud provider or autoscaler is throttled (use arm_throttling)
 
 Examples:
 
@@ -83,10 +168,12 @@ metrics:
 
 resource_health:
 - Check recent cluster health: operation="resource_health", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"start_time\":\"<start-time>\"}"
+- Check for regional outages and planned maintenance: operation="resource_health", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"mode\":\"service_health\", \"start_time\":\"<start-time>\"}"
 
 app_insights:
 - Query request telemetry: operation="app_insights", subscription_id="<subscription-id>", resource_group="<resource-group>", parameters="{\"app_insights_name\":\"myapp-insights\", \"query\":\"requests | where timestamp > ago(1h) | summarize count() by bin(timestamp, 5m)\"}"
 - Analyze exceptions: operation="app_insights", subscription_id="<subscription-id>", resource_group="<resource-group>", parameters="{\"app_insights_name\":\"myapp-insights\", \"query\":\"exceptions | where timestamp > ago(24h) | summarize count() by type, bin(timestamp, 1h)\"}"
+- Discover and query the cluster's Application Insights: operation="app_insights", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"query\":\"requests | where success == false | summarize count() by name\", \"timespan\":\"PT1H\"}"
 - Performance with timespan: operation="app_insights", subscription_id="<subscription-id>", resource_group="<resource-group>", parameters="{\"app_insights_name\":\"myapp-insights\", \"query\":\"performanceCounters | where category == 'Processor' | summarize avg(value) by bin(timestamp, 5m)\", \"timespan\":\"PT1H\"}"
 
 diagnostics:
@@ -96,29 +183,58 @@ control_plane_logs:
 - Query API server logs: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"log_category\":\"kube-apiserver\", \"start_time\":\"<start-time>\", \"end_time\":\"<end-time>\", \"max_records\":\"50\"}"
 - Debug authentication issues: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"log_category\":\"guard\", \"start_time\":\"<start-time>\", \"end_time\":\"<end-time>\", \"max_records\":\"100\"}"
 - Analyze audit events: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"log_category\":\"kube-audit\", \"log_level\":\"error\", \"start_time\":\"<start-time>\", \"end_time\":\"<end-time>\", \"max_records\":\"50\"}"
+- Who did what in a namespace: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"log_category\":\"kube-audit\", \"namespace\":\"payments\", \"group_by\":\"namespace\", \"start_time\":\"<start-time>\"}"
+- Parse klog lines into fields: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"log_category\":\"kube-controller-manager\", \"profile\":\"klog\", \"log_level\":\"error\", \"start_time\":\"<start-time>\"}"
+- Run a deployed library function: operation="control_plane_logs", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"function\":\"AKSAuditForbidden\", \"start_time\":\"<start-time>\", \"max_records\":\"50\"}"
+
+fired_alerts:
+- List alerts from the last day: operation="fired_alerts", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"time_range\":\"1d\"}"
+
+safeguards:
+- Explain a rejected deployment: operation="safeguards", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{}"
+
+config_history:
+- Review changes in the last day: operation="config_history", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"start_time\":\"<start-time>\"}"
+
+apiserver_slo:
+- Monthly uptime report: operation="apiserver_slo", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"window_days\":\"30\"}"
+
+apiserver_load:
+- Top clients and throttling in the last hour: operation="apiserver_load", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{\"top\":\"10\"}"
+
+deploy_kql_functions:
+- Deploy the function library: operation="deploy_kql_functions", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{}"
+
+pod_security:
+- Pod security violations in the last day: operation="pod_security", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{}"
+
+arm_throttling:
+- ARM 429s and remaining request quota in the last 6 hours: operation="arm_throttling", subscription_id="<subscription-id>", resource_group="<resource-group>", cluster_name="<cluster-name>", parameters="{}"
 `
 
 	return mcp.NewTool("az_monitoring",
 		mcp.WithDescription(description),
 		mcp.WithString("operation",
 			mcp.Required(),
-			mcp.Description("The monitoring operation to perform: 'metrics' (CPU/memory/network), 'resource_health' (cluster availability), 'app_insights' (telemetry analysis), 'diagnostics' (logging config), 'control_plane_logs' (Kubernetes logs like kube-apiserver, kube-audit, guard, etc.)"),
+			mcp.Description("The monitoring operation to perform: 'metrics' (CPU/memory/network), 'resource_health' (cluster availability), 'app_insights' (telemetry analysis), 'diagnostics' (logging config), 'control_plane_logs' (Kubernetes logs like kube-apiserver, kube-audit, guard, etc.), 'fired_alerts' (Azure Monitor alerts), 'safeguards' (deployment safeguards and policy denials), 'config_history' (who changed cluster settings and when), 'apiserver_slo' (API server availability report), 'apiserver_load' (API server latency and throttling), 'deploy_kql_functions' (save the KQL function library to the workspace), 'pod_security' (Pod Security Admission labels and violations), 'arm_throttling' (ARM 429s, noisy callers and request quota)"),
+			mcp.Enum(GetSupportedMonitoringOperations()...),
 		),
 		mcp.WithString("query_type",
 			mcp.Description("For metrics operations only: 'list' (get metric values), 'list-definitions' (available metrics), 'list-namespaces' (metric categories)"),
+			mcp.Enum(MetricsQueryTypes...),
 		),
 		mcp.WithString("parameters",
 			mcp.Required(),
-			mcp.Description("JSON string with operation parameters. metrics: resource (required), metrics (required for 'list' query_type), aggregation/start-time/end-time/interval/filter (optional). resource_health: start_time, end_time, status. app_insights: app_insights_name, query, start_time/end_time OR timespan (optional). diagnostics: none required. control_plane_logs: log_category (kube-apiserver/kube-audit/guard/etc), start_time, end_time, max_records, log_level"),
+			mcp.Description("JSON string with operation parameters. metrics: resource (required), metrics (required for 'list' query_type), aggregation/start-time/end-time/interval/filter (optional). resource_health: start_time, end_time, status, mode (cluster or service_health). app_insights: app_insights_name (or cluster_name to discover it), query, start_time/end_time OR timespan (optional). diagnostics: none required. control_plane_logs: log_category (kube-apiserver/kube-audit/guard/etc) or function (a deployed library function), start_time, end_time, max_records, log_level. fired_alerts: time_range, include_resolved (optional). safeguards: none required. config_history: start_time, end_time (optional). apiserver_slo: window_days or start_time, end_time, slo_target, count_degraded (optional). deploy_kql_functions: functions (optional). pod_security: start_time, end_time (optional). arm_throttling: start_time, end_time, top (optional)"),
 		),
 		mcp.WithString("subscription_id",
-			mcp.Description("Azure subscription ID (required for resource_health, app_insights, diagnostics, control_plane_logs)"),
+			mcp.Description("Azure subscription ID (required for resource_health, app_insights, diagnostics, control_plane_logs, fired_alerts, safeguards, config_history, apiserver_slo, deploy_kql_functions, pod_security, arm_throttling)"),
 		),
 		mcp.WithString("resource_group",
-			mcp.Description("Resource group name (required for resource_health, app_insights, diagnostics, control_plane_logs)"),
+			mcp.Description("Resource group name (required for resource_health, app_insights, diagnostics, control_plane_logs, fired_alerts, safeguards, config_history, apiserver_slo, deploy_kql_functions, pod_security, arm_throttling)"),
 		),
 		mcp.WithString("cluster_name",
-			mcp.Description("AKS cluster name (required for resource_health, diagnostics, control_plane_logs)"),
+			mcp.Description("AKS cluster name (used by app_insights to discover the Application Insights resource; required for resource_health, diagnostics, control_plane_logs, fired_alerts, safeguards, config_history, apiserver_slo, deploy_kql_functions, pod_security, arm_throttling)"),
 		),
 	)
 }
@@ -22,7 +22,7 @@ func TestGetSupportedMonitoringOperations_ContainsExpectedOps(t *testing.T) {
 	operations := GetSupportedMonitoringOperations()
 
 	expectedOps := []string{
-		"metrics", "resource_health", "app_insights", "diagnostics", "control_plane_logs",
+		"metrics", "resource_health", "app_insights", "diagnostics", "control_plane_logs", "fired_alerts", "safeguards", "config_history", "apiserver_slo", "apiserver_load", "deploy_kql_functions", "pod_security", "arm_throttling",
 	}
 
 	for _, expectedOp := range expectedOps {
@@ -41,7 +41,7 @@ func TestGetSupportedMonitoringOperations_ContainsExpectedOps(t *testing.T) {
 
 func TestValidateMonitoringOperation_ChecksValidOperations(t *testing.T) {
 	// Test that validation works for supported operations
-	validOps := []string{"metrics", "resource_health", "app_insights", "diagnostics", "control_plane_logs"}
+	validOps := []string{"metrics", "resource_health", "app_insights", "diagnostics", "control_plane_logs", "fired_alerts", "safeguards", "config_history", "apiserver_slo", "apiserver_load", "deploy_kql_functions", "pod_security", "arm_throttling"}
 	for _, op := range validOps {
 		if !ValidateMonitoringOperation(op) {
 			t.Errorf("Expected operation '%s' to be valid", op)
@@ -0,0 +1,359 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// maxSafeguardsDenials bounds the number of admission denials returned
+const maxSafeguardsDenials = 20
+
+// maxConstraintViolations bounds the audit violations returned per policy
+const maxConstraintViolations = 5
+
+// Gatekeeper enforcement actions
+const (
+	enforcementDeny   = "deny"
+	enforcementWarn   = "warn"
+	enforcementDryRun = "dryrun"
+)
+
+// SafeguardsProfile is the deployment safeguards configuration of the cluster
+type SafeguardsProfile struct {
+	Level              string   `json:"level"`
+	Version            string   `json:"version,omitempty"`
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+}
+
+// PolicyAssignment is an Azure Policy assignment scoped to the cluster
+type PolicyAssignment struct {
+	Name            string `json:"name"`
+	DisplayName     string `json:"displayName,omitempty"`
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+	Effect          string `json:"effect,omitempty"`
+	Safeguards      bool   `json:"deploymentSafeguards"`
+}
+
+// ConstraintViolation is a Gatekeeper audit violation
+type ConstraintViolation struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Message   string `json:"message"`
+}
+
+// SafeguardPolicy is a Gatekeeper constraint enforced in the cluster
+type SafeguardPolicy struct {
+	Kind              string                `json:"kind"`
+	Name              string                `json:"name"`
+	EnforcementAction string                `json:"enforcementAction"`
+	TotalViolations   int                   `json:"totalViolations"`
+	Violations        []ConstraintViolation `json:"violations,omitempty"`
+}
+
+// AdmissionDenial is a workload creation rejected by an admission webhook
+type AdmissionDenial struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Message   string `json:"message"`
+	Count     int    `json:"count"`
+	LastSeen  string `json:"lastSeen,omitempty"`
+}
+
+// SafeguardsReport is the result of the safeguards operation
+type SafeguardsReport struct {
+	ClusterName             string             `json:"clusterName"`
+	Safeguards              SafeguardsProfile  `json:"safeguards"`
+	AzurePolicyAddonEnabled bool               `json:"azurePolicyAddonEnabled"`
+	PolicyAssignments       []PolicyAssignment `json:"policyAssignments"`
+	EnforcedPolicies        []SafeguardPolicy  `json:"enforcedPolicies"`
+	WarnPolicies            []SafeguardPolicy  `json:"warnPolicies"`
+	AuditOnlyPolicies       []SafeguardPolicy  `json:"auditOnlyPolicies,omitempty"`
+	RecentDenials           []AdmissionDenial  `json:"recentDenials"`
+	Warnings                []string           `json:"warnings,omitempty"`
+	Hint                    string             `json:"hint,omitempty"`
+}
+
+// HandleSafeguardsQuery reports the deployment safeguards level, the enforced and warn policies,
+// and recent admission denials for the cluster
+func HandleSafeguardsQuery(params map[string]interface{}, azExecutor, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	showCmd := fmt.Sprintf("az aks show --resource-group %s --name %s --subscription %s --output json", rg, clusterName, subID)
+	showOutput, err := azExecutor.Execute(map[string]interface{}{"command": showCmd}, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	clusterID, profile, addonEnabled, err := ParseSafeguardsProfile(showOutput)
+	if err != nil {
+		return "", err
+	}
+
+	report := SafeguardsReport{
+		ClusterName:             clusterName,
+		Safeguards:              profile,
+		AzurePolicyAddonEnabled: addonEnabled,
+		PolicyAssignments:       []PolicyAssignment{},
+		EnforcedPolicies:        []SafeguardPolicy{},
+		WarnPolicies:            []SafeguardPolicy{},
+		RecentDenials:           []AdmissionDenial{},
+	}
+
+	// Policy assignments, constraints and events are best-effort: missing data becomes a warning
+	if clusterID != "" {
+		assignmentsCmd := fmt.Sprintf("az policy assignment list --scope %s --subscription %s --output json", clusterID, subID)
+		if output, err := azExecutor.Execute(map[string]interface{}{"command": assignmentsCmd}, cfg); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list policy assignments: %v", err))
+		} else if assignments, err := ParsePolicyAssignments(output); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			report.PolicyAssignments = assignments
+		}
+	}
+
+	// Gatekeeper constraints and admission events need cluster access; a nil executor means
+	// the server may not use its kubeconfig (Kubernetes component disabled or session credential mode)
+	if kubectlExecutor == nil {
+		report.Warnings = append(report.Warnings, "Kubernetes access is disabled for this server; Gatekeeper constraints and recent denials were not checked")
+	} else {
+		collectClusterPolicyState(&report, kubectlExecutor, cfg)
+	}
+
+	report.Hint = "Events only capture denials of controller-created objects. For denials of direct kubectl or CI requests, " +
+		"query control_plane_logs with log_category kube-audit for responses with code 403 from validation.gatekeeper.sh."
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal safeguards report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// collectClusterPolicyState adds the Gatekeeper constraints and recent admission denials to the report
+func collectClusterPolicyState(report *SafeguardsReport, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) {
+	if output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get constraints -o json"}, cfg); err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list Gatekeeper constraints (is the Azure Policy add-on installed?): %v", err))
+	} else if policies, err := ParseConstraints(output); err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+	} else {
+		for _, policy := range policies {
+			switch policy.EnforcementAction {
+			case enforcementDeny:
+				report.EnforcedPolicies = append(report.EnforcedPolicies, policy)
+			case enforcementWarn:
+				report.WarnPolicies = append(report.WarnPolicies, policy)
+			default:
+				report.AuditOnlyPolicies = append(report.AuditOnlyPolicies, policy)
+			}
+		}
+	}
+
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		eventsCmd := fmt.Sprintf("get events %s --field-selector reason=FailedCreate -o json", flag)
+		if output, err := kubectlExecutor.Execute(map[string]interface{}{"command": eventsCmd}, cfg); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list FailedCreate events (%s): %v", flag, err))
+		} else if denials, err := ParseAdmissionDenials(output); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else {
+			report.RecentDenials = append(report.RecentDenials, denials...)
+		}
+	}
+	sort.SliceStable(report.RecentDenials, func(i, j int) bool {
+		return report.RecentDenials[i].LastSeen > report.RecentDenials[j].LastSeen
+	})
+}
+
+// ParseSafeguardsProfile extracts the cluster ID, safeguards profile and Azure Policy add-on state from az aks show output
+func ParseSafeguardsProfile(output string) (string, SafeguardsProfile, bool, error) {
+	var cluster struct {
+		ID                string `json:"id"`
+		SafeguardsProfile *struct {
+			Level              string   `json:"level"`
+			Version            string   `json:"version"`
+			ExcludedNamespaces []string `json:"excludedNamespaces"`
+		} `json:"safeguardsProfile"`
+		AddonProfiles map[string]struct {
+			Enabled bool `json:"enabled"`
+		} `json:"addonProfiles"`
+	}
+	if err := json.Unmarshal([]byte(output), &cluster); err != nil {
+		return "", SafeguardsProfile{}, false, fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+
+	// Older API and CLI versions omit safeguardsProfile entirely, so its absence does not mean safeguards are off
+	profile := SafeguardsProfile{Level: "Unknown"}
+	if cluster.SafeguardsProfile != nil {
+		profile.Level = "Off"
+		if cluster.SafeguardsProfile.Level != "" {
+			profile.Level = cluster.SafeguardsProfile.Level
+		}
+		profile.Version = cluster.SafeguardsProfile.Version
+		profile.ExcludedNamespaces = cluster.SafeguardsProfile.ExcludedNamespaces
+	}
+
+	addonEnabled := false
+	for name, addon := range cluster.AddonProfiles {
+		if strings.EqualFold(name, "azurepolicy") && addon.Enabled {
+			addonEnabled = true
+		}
+	}
+	return cluster.ID, profile, addonEnabled, nil
+}
+
+// ParsePolicyAssignments parses az policy assignment list output
+func ParsePolicyAssignments(output string) ([]PolicyAssignment, error) {
+	var raw []struct {
+		Name            string `json:"name"`
+		DisplayName     string `json:"displayName"`
+		EnforcementMode string `json:"enforcementMode"`
+		Parameters      map[string]struct {
+			Value interface{} `json:"value"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(output), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse policy assignments: %w", err)
+	}
+
+	assignments := make([]PolicyAssignment, 0, len(raw))
+	for _, a := range raw {
+		assignment := PolicyAssignment{
+			Name:            a.Name,
+			DisplayName:     a.DisplayName,
+			EnforcementMode: a.EnforcementMode,
+			Safeguards:      strings.Contains(strings.ToLower(a.Name+" "+a.DisplayName), "safeguards"),
+		}
+		if effect, ok := a.Parameters["effect"]; ok {
+			if value, ok := effect.Value.(string); ok {
+				assignment.Effect = value
+			}
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+// ParseConstraints parses kubectl get constraints output into policies with their audit violations
+func ParseConstraints(output string) ([]SafeguardPolicy, error) {
+	var list struct {
+		Items []struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				EnforcementAction string `json:"enforcementAction"`
+			} `json:"spec"`
+			Status struct {
+				TotalViolations int `json:"totalViolations"`
+				Violations      []struct {
+					Kind      string `json:"kind"`
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+					Message   string `json:"message"`
+				} `json:"violations"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse constraints: %w", err)
+	}
+
+	policies := make([]SafeguardPolicy, 0, len(list.Items))
+	for _, item := range list.Items {
+		action := strings.ToLower(item.Spec.EnforcementAction)
+		if action == "" {
+			// Gatekeeper defaults to deny when no enforcement action is set
+			action = enforcementDeny
+		}
+		policy := SafeguardPolicy{
+			Kind:              item.Kind,
+			Name:              item.Metadata.Name,
+			EnforcementAction: action,
+			TotalViolations:   item.Status.TotalViolations,
+		}
+		for i, v := range item.Status.Violations {
+			if i >= maxConstraintViolations {
+				break
+			}
+			policy.Violations = append(policy.Violations, ConstraintViolation{
+				Kind: v.Kind, Name: v.Name, Namespace: v.Namespace, Message: v.Message,
+			})
+		}
+		policies = append(policies, policy)
+	}
+	// Policies with the most violations first
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].TotalViolations > policies[j].TotalViolations
+	})
+	return policies, nil
+}
+
+// ParseAdmissionDenials extracts admission webhook denials from FailedCreate events, most recent first
+func ParseAdmissionDenials(output string) ([]AdmissionDenial, error) {
+	return parseFailedCreateEvents(output, func(message string) bool {
+		message = strings.ToLower(message)
+		return strings.Contains(message, "admission webhook") && strings.Contains(message, "denied")
+	})
+}
+
+// parseFailedCreateEvents returns the FailedCreate events whose message matches, most recent first
+func parseFailedCreateEvents(output string, match func(message string) bool) ([]AdmissionDenial, error) {
+	var list struct {
+		Items []struct {
+			InvolvedObject struct {
+				Kind      string `json:"kind"`
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"involvedObject"`
+			Message        string `json:"message"`
+			Count          int    `json:"count"`
+			LastTimestamp  string `json:"lastTimestamp"`
+			EventTime      string `json:"eventTime"`
+			FirstTimestamp string `json:"firstTimestamp"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+
+	denials := []AdmissionDenial{}
+	for _, event := range list.Items {
+		if !match(event.Message) {
+			continue
+		}
+		lastSeen := event.LastTimestamp
+		if lastSeen == "" {
+			lastSeen = event.EventTime
+		}
+		if lastSeen == "" {
+			lastSeen = event.FirstTimestamp
+		}
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		denials = append(denials, AdmissionDenial{
+			Namespace: event.InvolvedObject.Namespace,
+			Object:    fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+			Message:   event.Message,
+			Count:     count,
+			LastSeen:  lastSeen,
+		})
+	}
+	sort.SliceStable(denials, func(i, j int) bool {
+		return denials[i].LastSeen > denials[j].LastSeen
+	})
+	if len(denials) > maxSafeguardsDenials {
+		denials = denials[:maxSafeguardsDenials]
+	}
+	return denials, nil
+}
@@ -0,0 +1,408 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// serviceHealthAPIVersion is the Microsoft.ResourceHealth API version used to list Service Health events
+const serviceHealthAPIVersion = "2022-10-01"
+
+// defaultServiceHealthWindow is the window used when start_time is not provided
+const defaultServiceHealthWindow = 24 * time.Hour
+
+// Resource health modes. The cluster mode lists the cluster's own Resource Health events.
+const (
+	ResourceHealthModeCluster       = "cluster"
+	ResourceHealthModeServiceHealth = "service_health"
+)
+
+// Service categories a Service Health event is reported for
+const (
+	ServiceCategoryAKS        = "aks"
+	ServiceCategoryCompute    = "compute"
+	ServiceCategoryNetworking = "networking"
+)
+
+// Classifications of the cluster's own health transitions
+const (
+	ClassificationPlatform        = "platform"
+	ClassificationUserInitiated   = "userInitiated"
+	ClassificationClusterSpecific = "clusterSpecific"
+)
+
+// serviceCategories maps lower-case fragments of Service Health service names to the category they belong to
+var serviceCategories = []struct {
+	fragment string
+	category string
+}{
+	{"kubernetes", ServiceCategoryAKS},
+	{"virtual machine", ServiceCategoryCompute},
+	{"scale set", ServiceCategoryCompute},
+	{"managed disk", ServiceCategoryCompute},
+	{"virtual network", ServiceCategoryNetworking},
+	{"load balancer", ServiceCategoryNetworking},
+	{"dns", ServiceCategoryNetworking},
+	{"application gateway", ServiceCategoryNetworking},
+	{"firewall", ServiceCategoryNetworking},
+	{"nat gateway", ServiceCategoryNetworking},
+	{"private link", ServiceCategoryNetworking},
+	{"expressroute", ServiceCategoryNetworking},
+	{"network infrastructure", ServiceCategoryNetworking},
+}
+
+// globalRegions are the impacted region names used for events that are not tied to a region
+var globalRegions = map[string]bool{"global": true, "nonregional": true}
+
+// maxEventSummaryLength bounds the summary kept from an event's HTML description
+const maxEventSummaryLength = 500
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ServiceHealthEvent is a Service Health issue, advisory or planned maintenance affecting the cluster's region
+type ServiceHealthEvent struct {
+	TrackingID  string   `json:"trackingId"`
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Status      string   `json:"status"`
+	Level       string   `json:"level,omitempty"`
+	ImpactStart string   `json:"impactStart,omitempty"`
+	ImpactEnd   string   `json:"impactEnd,omitempty"`
+	Services    []string `json:"services"`
+	Categories  []string `json:"categories"`
+	// Scope is "region" when the cluster's region is impacted and "global" for non-regional events
+	Scope   string `json:"scope"`
+	Summary string `json:"summary,omitempty"`
+
+	start, end time.Time
+}
+
+// ClusterHealthEvent is a transition of the cluster's own Resource Health state within the window
+type ClusterHealthEvent struct {
+	Time           string `json:"time"`
+	State          string `json:"state"`
+	Summary        string `json:"summary,omitempty"`
+	ReasonType     string `json:"reasonType,omitempty"`
+	Classification string `json:"classification"`
+	// RelatedEvents are the tracking IDs of platform events active at the time of the transition
+	RelatedEvents []string `json:"relatedEvents,omitempty"`
+}
+
+// ServiceHealthReport is the result of the resource_health operation in service_health mode
+type ServiceHealthReport struct {
+	ClusterName    string               `json:"clusterName"`
+	Location       string               `json:"location"`
+	StartTime      string               `json:"startTime"`
+	EndTime        string               `json:"endTime"`
+	PlatformEvents []ServiceHealthEvent `json:"platformEvents"`
+	ClusterEvents  []ClusterHealthEvent `json:"clusterEvents"`
+	Counts         map[string]int       `json:"counts"`
+	Warnings       []string             `json:"warnings,omitempty"`
+	Summary        string               `json:"summary"`
+}
+
+// HandleServiceHealthQuery reports Service Health events affecting AKS, compute and networking in the
+// cluster's region during the window, and separates the cluster's own Resource Health transitions into
+// those coinciding with a platform event and those specific to the cluster
+func HandleServiceHealthQuery(params map[string]interface{}, api common.ARMCaller, _ *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := parseServiceHealthWindow(params, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	location, err := getClusterLocation(ctx, api, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	report := ServiceHealthReport{
+		ClusterName:    clusterName,
+		Location:       location,
+		StartTime:      start.Format(time.RFC3339),
+		EndTime:        end.Format(time.RFC3339),
+		PlatformEvents: []ServiceHealthEvent{},
+		ClusterEvents:  []ClusterHealthEvent{},
+		Counts:         map[string]int{},
+	}
+
+	events, err := listServiceHealthEvents(ctx, api, subID, location, start, end)
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+	}
+	report.PlatformEvents = append(report.PlatformEvents, events...)
+
+	transitions, err := listHealthTransitions(ctx, api, clusterID)
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+	}
+	report.ClusterEvents = ClassifyClusterHealth(transitions, report.PlatformEvents, start, end)
+
+	for _, event := range report.PlatformEvents {
+		report.Counts[event.Type]++
+	}
+	for _, event := range report.ClusterEvents {
+		report.Counts[event.Classification]++
+	}
+	report.Summary = summarizeServiceHealth(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal service health report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// ValidateResourceHealthMode checks the mode parameter of the resource_health operation
+func ValidateResourceHealthMode(params map[string]interface{}) (string, error) {
+	mode, _ := params["mode"].(string)
+	switch mode {
+	case "", ResourceHealthModeCluster:
+		return ResourceHealthModeCluster, nil
+	case ResourceHealthModeServiceHealth:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid mode parameter: %s (expected %s or %s)", mode, ResourceHealthModeCluster, ResourceHealthModeServiceHealth)
+	}
+}
+
+// parseServiceHealthWindow resolves the window from start_time and end_time, defaulting to the last 24 hours
+func parseServiceHealthWindow(params map[string]interface{}, now time.Time) (time.Time, time.Time, error) {
+	var err error
+	end := now
+	if value, ok := params["end_time"].(string); ok && value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	start := end.Add(-defaultServiceHealthWindow)
+	if value, ok := params["start_time"].(string); ok && value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_time must be before end_time")
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// getClusterLocation returns the region the cluster runs in
+func getClusterLocation(ctx context.Context, api common.ARMCaller, clusterID string) (string, error) {
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+sloClusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	var cluster struct {
+		Location string `json:"location"`
+	}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+	if cluster.Location == "" {
+		return "", fmt.Errorf("cluster details do not include a location")
+	}
+	return cluster.Location, nil
+}
+
+// listServiceHealthEvents reads the subscription's Service Health events and keeps those impacting
+// AKS, compute or networking in the cluster's region (or globally) during the window
+func listServiceHealthEvents(ctx context.Context, api common.ARMCaller, subID, location string, start, end time.Time) ([]ServiceHealthEvent, error) {
+	query := url.Values{}
+	query.Set("api-version", serviceHealthAPIVersion)
+	// queryStartTime matches lastUpdateTime, so events that started earlier but were updated in the window are included
+	query.Set("queryStartTime", start.Format("1/2/2006"))
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.ResourceHealth/events?%s", subID, query.Encode())
+
+	var events []ServiceHealthEvent
+	for page := 0; next != "" && page < maxActivityLogPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Service Health events: %w", err)
+		}
+		var result struct {
+			Value []struct {
+				Name       string `json:"name"`
+				Properties struct {
+					EventType            string `json:"eventType"`
+					Title                string `json:"title"`
+					Summary              string `json:"summary"`
+					Status               string `json:"status"`
+					Level                string `json:"level"`
+					ImpactStartTime      string `json:"impactStartTime"`
+					ImpactMitigationTime string `json:"impactMitigationTime"`
+					Impact               []struct {
+						ImpactedService string `json:"impactedService"`
+						ImpactedRegions []struct {
+							ImpactedRegion string `json:"impactedRegion"`
+						} `json:"impactedRegions"`
+					} `json:"impact"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse Service Health events: %w", err)
+		}
+
+		for _, item := range result.Value {
+			props := item.Properties
+			event := ServiceHealthEvent{
+				TrackingID: item.Name,
+				Type:       props.EventType,
+				Title:      props.Title,
+				Status:     props.Status,
+				Level:      props.Level,
+				Services:   []string{},
+				Categories: []string{},
+				Summary:    plainSummary(props.Summary),
+			}
+			event.start, _ = time.Parse(time.RFC3339Nano, props.ImpactStartTime)
+			event.end, _ = time.Parse(time.RFC3339Nano, props.ImpactMitigationTime)
+			if !event.start.IsZero() {
+				event.ImpactStart = event.start.UTC().Format(time.RFC3339)
+			}
+			if !event.end.IsZero() {
+				event.ImpactEnd = event.end.UTC().Format(time.RFC3339)
+			}
+			if !eventOverlaps(event, start, end) {
+				continue
+			}
+
+			for _, impact := range props.Impact {
+				category := serviceCategory(impact.ImpactedService)
+				if category == "" {
+					continue
+				}
+				scope := ""
+				for _, region := range impact.ImpactedRegions {
+					name := normalizeRegion(region.ImpactedRegion)
+					if name == normalizeRegion(location) {
+						scope = "region"
+						break
+					}
+					if globalRegions[name] {
+						scope = "global"
+					}
+				}
+				if scope == "" {
+					continue
+				}
+				if event.Scope != "region" {
+					event.Scope = scope
+				}
+				event.Services = appendUnique(event.Services, impact.ImpactedService)
+				event.Categories = appendUnique(event.Categories, category)
+			}
+			if len(event.Services) > 0 {
+				events = append(events, event)
+			}
+		}
+		next = result.NextLink
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].start.After(events[j].start) })
+	return events, nil
+}
+
+// ClassifyClusterHealth returns the cluster's Resource Health transitions within the window and marks
+// those that happened while a platform event was impacting the region
+func ClassifyClusterHealth(transitions []healthTransition, events []ServiceHealthEvent, start, end time.Time) []ClusterHealthEvent {
+	sorted := append([]healthTransition(nil), transitions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	result := []ClusterHealthEvent{}
+	for _, t := range sorted {
+		if t.Time.Before(start) || t.Time.After(end) || t.State == StateAvailable {
+			continue
+		}
+		event := ClusterHealthEvent{
+			Time:       t.Time.Format(time.RFC3339),
+			State:      t.State,
+			Summary:    t.Summary,
+			ReasonType: t.ReasonType,
+		}
+		for _, platform := range events {
+			if eventOverlaps(platform, t.Time, t.Time) {
+				event.RelatedEvents = append(event.RelatedEvents, platform.TrackingID)
+			}
+		}
+		switch {
+		case len(event.RelatedEvents) > 0:
+			event.Classification = ClassificationPlatform
+		case strings.EqualFold(t.ReasonType, "UserInitiated"):
+			event.Classification = ClassificationUserInitiated
+		default:
+			event.Classification = ClassificationClusterSpecific
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// eventOverlaps reports whether the event's impact overlaps the given period. Events without an
+// impact start are kept, and events without a mitigation time are treated as still ongoing.
+func eventOverlaps(event ServiceHealthEvent, start, end time.Time) bool {
+	if event.start.IsZero() {
+		return true
+	}
+	if event.start.After(end) {
+		return false
+	}
+	return event.end.IsZero() || !event.end.Before(start)
+}
+
+// serviceCategory returns the category of a Service Health service name, or "" when it is not relevant to AKS
+func serviceCategory(service string) string {
+	name := strings.ToLower(service)
+	for _, candidate := range serviceCategories {
+		if strings.Contains(name, candidate.fragment) {
+			return candidate.category
+		}
+	}
+	return ""
+}
+
+// normalizeRegion turns display names such as "East US" into region names such as "eastus"
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(region, " ", ""), "-", ""))
+}
+
+// plainSummary strips the HTML of an event description and bounds its length
+func plainSummary(summary string) string {
+	text := strings.Join(strings.Fields(htmlTagPattern.ReplaceAllString(summary, " ")), " ")
+	if runes := []rune(text); len(runes) > maxEventSummaryLength {
+		text = string(runes[:maxEventSummaryLength]) + "..."
+	}
+	return text
+}
+
+// summarizeServiceHealth describes the report in one sentence for the caller
+func summarizeServiceHealth(report ServiceHealthReport) string {
+	platform := report.Counts[ClassificationPlatform]
+	other := len(report.ClusterEvents) - platform
+	if len(report.PlatformEvents) == 0 {
+		if len(report.ClusterEvents) == 0 {
+			return fmt.Sprintf("No Service Health events for AKS, compute or networking in %s and no cluster health issues in the window.", report.Location)
+		}
+		return fmt.Sprintf("No Service Health events for AKS, compute or networking in %s; the cluster's %d health issue(s) are specific to the cluster.",
+			report.Location, len(report.ClusterEvents))
+	}
+	return fmt.Sprintf("%d Service Health event(s) affected AKS, compute or networking in %s; %d cluster health issue(s) coincide with them and %d are specific to the cluster.",
+		len(report.PlatformEvents), report.Location, platform, other)
+}
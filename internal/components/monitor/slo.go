@@ -0,0 +1,495 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// API versions used by the API server SLO report
+const (
+	resourceHealthAPIVersion = "2020-05-01"
+	metricsAPIVersion        = "2018-01-01"
+	sloClusterAPIVersion     = "2024-05-01"
+)
+
+// Resource Health keeps 30 days of availability history, which bounds the report window
+const (
+	defaultSLOWindowDays = 30
+	maxSLOWindow         = 30 * 24 * time.Hour
+)
+
+// apiServerRequestMetric is the platform metric whose missing samples indicate the API server stopped reporting
+const apiServerRequestMetric = "apiserver_current_inflight_requests"
+
+// Resource Health availability states
+const (
+	StateAvailable   = "Available"
+	StateUnavailable = "Unavailable"
+	StateDegraded    = "Degraded"
+	StateUnknown     = "Unknown"
+)
+
+// SLOIncident is a period in which the cluster was not available
+type SLOIncident struct {
+	Start           string  `json:"start"`
+	End             string  `json:"end"`
+	DurationMinutes float64 `json:"durationMinutes"`
+	State           string  `json:"state"`
+	Summary         string  `json:"summary,omitempty"`
+	ReasonType      string  `json:"reasonType,omitempty"`
+	// CountedAsDowntime is false for Degraded periods unless count_degraded is set
+	CountedAsDowntime bool `json:"countedAsDowntime"`
+	// TelemetryGap is true when API server request metrics are missing during the incident
+	TelemetryGap bool `json:"telemetryGap"`
+}
+
+// SLOTelemetry summarizes the API server request metric samples used to corroborate incidents
+type SLOTelemetry struct {
+	Metric           string  `json:"metric"`
+	Interval         string  `json:"interval"`
+	MissingIntervals int     `json:"missingIntervals"`
+	MissingMinutes   float64 `json:"missingMinutes"`
+	// UnexplainedGapMinutes are missing samples outside any incident; they are not counted as downtime
+	UnexplainedGapMinutes float64 `json:"unexplainedGapMinutes"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+// SLOReport is the result of the apiserver_slo operation
+type SLOReport struct {
+	ClusterName         string        `json:"clusterName"`
+	StartTime           string        `json:"startTime"`
+	EndTime             string        `json:"endTime"`
+	WindowDays          float64       `json:"windowDays"`
+	AvailabilityPercent float64       `json:"availabilityPercent"`
+	TargetPercent       float64       `json:"targetPercent"`
+	TargetSource        string        `json:"targetSource"`
+	Met                 bool          `json:"met"`
+	DowntimeMinutes     float64       `json:"downtimeMinutes"`
+	DegradedMinutes     float64       `json:"degradedMinutes"`
+	UnknownMinutes      float64       `json:"unknownMinutes"`
+	ErrorBudgetMinutes  float64       `json:"errorBudgetMinutes"`
+	BudgetRemaining     float64       `json:"errorBudgetRemainingMinutes"`
+	Incidents           []SLOIncident `json:"incidents"`
+	Telemetry           SLOTelemetry  `json:"telemetry"`
+	Summary             string        `json:"summary"`
+}
+
+// healthTransition is a change of the cluster's Resource Health availability state
+type healthTransition struct {
+	Time       time.Time
+	State      string
+	Summary    string
+	ReasonType string
+}
+
+// availabilityPeriod is a span of time in a single availability state
+type availabilityPeriod struct {
+	Start, End time.Time
+	healthTransition
+}
+
+// HandleAPIServerSLOQuery reports API server availability over a window from Resource Health
+// transitions, corroborated by gaps in API server request metrics
+func HandleAPIServerSLOQuery(params map[string]interface{}, api common.ARMCaller, _ *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := parseSLOWindow(params, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	countDegraded := params["count_degraded"] == "true" || params["count_degraded"] == true
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+
+	target, targetSource, err := resolveSLOTarget(ctx, params, api, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	transitions, err := listHealthTransitions(ctx, api, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	report := BuildSLOReport(transitions, start, end, target, countDegraded)
+	report.ClusterName = clusterName
+	report.TargetSource = targetSource
+
+	interval := sloMetricInterval(end.Sub(start))
+	report.Telemetry = SLOTelemetry{Metric: apiServerRequestMetric, Interval: interval}
+	gaps, err := listMetricGaps(ctx, api, clusterID, start, end, interval)
+	if err != nil {
+		// Availability comes from Resource Health; metrics only corroborate it
+		report.Telemetry.Error = err.Error()
+	} else {
+		annotateTelemetryGaps(&report, gaps, start, end)
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SLO report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// parseSLOWindow resolves the report window from window_days or start_time and end_time
+func parseSLOWindow(params map[string]interface{}, now time.Time) (time.Time, time.Time, error) {
+	var err error
+	end := now
+	if value, ok := params["end_time"].(string); ok && value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	days := defaultSLOWindowDays
+	if raw, ok := params["window_days"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid window_days parameter: %s", value)
+		}
+	}
+	start := end.Add(-time.Duration(days) * 24 * time.Hour)
+	if value, ok := params["start_time"].(string); ok && value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_time format, expected RFC3339 (ISO 8601): %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_time must be before end_time")
+	}
+	if start.Before(now.Add(-maxSLOWindow)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the window must start within the last 30 days of Resource Health history")
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// resolveSLOTarget returns the slo_target parameter, or the uptime SLA of the cluster's pricing tier
+func resolveSLOTarget(ctx context.Context, params map[string]interface{}, api common.ARMCaller, clusterID string) (float64, string, error) {
+	if raw, ok := params["slo_target"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		target, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || target <= 0 || target >= 100 {
+			return 0, "", fmt.Errorf("invalid slo_target parameter: %s (expected a percentage such as 99.9)", value)
+		}
+		return target, "slo_target parameter", nil
+	}
+
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+sloClusterAPIVersion)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	var cluster struct {
+		Sku struct {
+			Tier string `json:"tier"`
+		} `json:"sku"`
+		Properties struct {
+			AgentPoolProfiles []struct {
+				AvailabilityZones []string `json:"availabilityZones"`
+			} `json:"agentPoolProfiles"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return 0, "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+	zonal := false
+	for _, pool := range cluster.Properties.AgentPoolProfiles {
+		zonal = zonal || len(pool.AvailabilityZones) > 0
+	}
+	target, source := UptimeSLATarget(cluster.Sku.Tier, zonal)
+	return target, source, nil
+}
+
+// UptimeSLATarget returns the API server uptime target of an AKS pricing tier.
+// The Free tier has no financially backed SLA, so its service level objective is used.
+func UptimeSLATarget(tier string, zonal bool) (float64, string) {
+	switch strings.ToLower(tier) {
+	case "standard", "premium":
+		if zonal {
+			return 99.95, tier + " tier uptime SLA (availability zones)"
+		}
+		return 99.9, tier + " tier uptime SLA (no availability zones)"
+	default:
+		return 99.5, "Free tier service level objective (no financially backed SLA)"
+	}
+}
+
+// listHealthTransitions reads the Resource Health availability history of the cluster
+func listHealthTransitions(ctx context.Context, api common.ARMCaller, clusterID string) ([]healthTransition, error) {
+	next := fmt.Sprintf("%s/providers/Microsoft.ResourceHealth/availabilityStatuses?api-version=%s", clusterID, resourceHealthAPIVersion)
+	var transitions []healthTransition
+	for page := 0; next != "" && page < maxActivityLogPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Resource Health availability statuses: %w", err)
+		}
+		var result struct {
+			Value []struct {
+				Properties struct {
+					AvailabilityState string `json:"availabilityState"`
+					Summary           string `json:"summary"`
+					ReasonType        string `json:"reasonType"`
+					// Older API versions spell the field occuredTime
+					OccuredTime  string `json:"occuredTime"`
+					OccurredTime string `json:"occurredTime"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse Resource Health availability statuses: %w", err)
+		}
+		for _, status := range result.Value {
+			props := status.Properties
+			occurred := props.OccurredTime
+			if occurred == "" {
+				occurred = props.OccuredTime
+			}
+			t, err := time.Parse(time.RFC3339Nano, occurred)
+			if err != nil {
+				continue
+			}
+			transitions = append(transitions, healthTransition{
+				Time:       t.UTC(),
+				State:      props.AvailabilityState,
+				Summary:    props.Summary,
+				ReasonType: props.ReasonType,
+			})
+		}
+		next = result.NextLink
+	}
+	return transitions, nil
+}
+
+// BuildAvailabilityPeriods turns availability transitions into consecutive periods covering the window.
+// The cluster is assumed Available before its first transition, since Resource Health records changes only.
+func BuildAvailabilityPeriods(transitions []healthTransition, start, end time.Time) []availabilityPeriod {
+	sorted := append([]healthTransition(nil), transitions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	current := healthTransition{Time: start, State: StateAvailable}
+	var inWindow []healthTransition
+	for _, t := range sorted {
+		if !t.Time.After(start) {
+			current = t
+			current.Time = start
+			continue
+		}
+		if t.Time.Before(end) {
+			inWindow = append(inWindow, t)
+		}
+	}
+
+	var periods []availabilityPeriod
+	add := func(from, to time.Time, state healthTransition) {
+		if !from.Before(to) {
+			return
+		}
+		if n := len(periods); n > 0 && periods[n-1].State == state.State {
+			periods[n-1].End = to
+			if periods[n-1].Summary == "" {
+				periods[n-1].Summary, periods[n-1].ReasonType = state.Summary, state.ReasonType
+			}
+			return
+		}
+		periods = append(periods, availabilityPeriod{Start: from, End: to, healthTransition: state})
+	}
+	for _, t := range inWindow {
+		add(current.Time, t.Time, current)
+		current = t
+	}
+	add(current.Time, end, current)
+	return periods
+}
+
+// BuildSLOReport computes availability, incidents and the error budget for the window
+func BuildSLOReport(transitions []healthTransition, start, end time.Time, target float64, countDegraded bool) SLOReport {
+	window := end.Sub(start)
+	report := SLOReport{
+		StartTime:     start.Format(time.RFC3339),
+		EndTime:       end.Format(time.RFC3339),
+		WindowDays:    roundTo(window.Hours()/24, 2),
+		TargetPercent: target,
+		Incidents:     []SLOIncident{},
+	}
+
+	var downtime time.Duration
+	for _, period := range BuildAvailabilityPeriods(transitions, start, end) {
+		duration := period.End.Sub(period.Start)
+		switch period.State {
+		case StateAvailable:
+			continue
+		case StateUnknown:
+			report.UnknownMinutes += duration.Minutes()
+			continue
+		case StateDegraded:
+			report.DegradedMinutes += duration.Minutes()
+		}
+		counted := period.State != StateDegraded || countDegraded
+		if counted {
+			downtime += duration
+		}
+		report.Incidents = append(report.Incidents, SLOIncident{
+			Start:             period.Start.Format(time.RFC3339),
+			End:               period.End.Format(time.RFC3339),
+			DurationMinutes:   roundTo(duration.Minutes(), 1),
+			State:             period.State,
+			Summary:           period.Summary,
+			ReasonType:        period.ReasonType,
+			CountedAsDowntime: counted,
+		})
+	}
+
+	// Round availability down so the report never overstates it
+	availability := 100 * (1 - downtime.Seconds()/window.Seconds())
+	report.AvailabilityPercent = math.Floor(availability*1000) / 1000
+	report.Met = availability >= target
+	report.DowntimeMinutes = roundTo(downtime.Minutes(), 1)
+	report.DegradedMinutes = roundTo(report.DegradedMinutes, 1)
+	report.UnknownMinutes = roundTo(report.UnknownMinutes, 1)
+	report.ErrorBudgetMinutes = roundTo(window.Minutes()*(100-target)/100, 1)
+	report.BudgetRemaining = roundTo(report.ErrorBudgetMinutes-downtime.Minutes(), 1)
+
+	status := "met"
+	if !report.Met {
+		status = "missed"
+	}
+	plural := "s"
+	if len(report.Incidents) == 1 {
+		plural = ""
+	}
+	report.Summary = fmt.Sprintf("%.2f%% over %s days with %d incident%s (target %s%%: %s, %.1f of %.1f error budget minutes remaining)",
+		math.Floor(availability*100)/100, strconv.FormatFloat(report.WindowDays, 'f', -1, 64), len(report.Incidents), plural,
+		strconv.FormatFloat(target, 'f', -1, 64), status, report.BudgetRemaining, report.ErrorBudgetMinutes)
+	return report
+}
+
+// sloMetricInterval picks a metric granularity that keeps the sample count manageable
+func sloMetricInterval(window time.Duration) string {
+	if window <= 7*24*time.Hour {
+		return "PT5M"
+	}
+	return "PT15M"
+}
+
+// metricGap is a span of metric intervals without samples
+type metricGap struct {
+	Start, End time.Time
+}
+
+// listMetricGaps returns the intervals in which the API server request metric has no samples
+func listMetricGaps(ctx context.Context, api common.ARMCaller, clusterID string, start, end time.Time, interval string) ([]metricGap, error) {
+	path := fmt.Sprintf("%s/providers/Microsoft.Insights/metrics?api-version=%s&metricnames=%s&aggregation=Maximum&interval=%s&timespan=%s",
+		clusterID, metricsAPIVersion, apiServerRequestMetric, interval,
+		url.QueryEscape(start.Format(time.RFC3339)+"/"+end.Format(time.RFC3339)))
+	body, err := api.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", apiServerRequestMetric, err)
+	}
+	return ParseMetricGaps(body, sloIntervalDuration(interval))
+}
+
+// ParseMetricGaps finds metric data points without a value and merges adjacent ones into gaps
+func ParseMetricGaps(body []byte, step time.Duration) ([]metricGap, error) {
+	var result struct {
+		Value []struct {
+			Timeseries []struct {
+				Data []struct {
+					TimeStamp string   `json:"timeStamp"`
+					Maximum   *float64 `json:"maximum"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics response: %w", err)
+	}
+	if len(result.Value) == 0 || len(result.Value[0].Timeseries) == 0 {
+		return nil, fmt.Errorf("no %s samples returned for the window", apiServerRequestMetric)
+	}
+
+	var gaps []metricGap
+	for _, point := range result.Value[0].Timeseries[0].Data {
+		if point.Maximum != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, point.TimeStamp)
+		if err != nil {
+			continue
+		}
+		if n := len(gaps); n > 0 && gaps[n-1].End.Equal(t) {
+			gaps[n-1].End = t.Add(step)
+			continue
+		}
+		gaps = append(gaps, metricGap{Start: t, End: t.Add(step)})
+	}
+	return gaps, nil
+}
+
+// annotateTelemetryGaps marks incidents with missing metrics and totals gaps outside incidents
+func annotateTelemetryGaps(report *SLOReport, gaps []metricGap, start, end time.Time) {
+	step := sloIntervalDuration(report.Telemetry.Interval)
+	var missing, unexplained time.Duration
+	for _, gap := range gaps {
+		gapStart, gapEnd := maxTime(gap.Start, start), minTime(gap.End, end)
+		if !gapStart.Before(gapEnd) {
+			continue
+		}
+		report.Telemetry.MissingIntervals += int(gap.End.Sub(gap.Start) / step)
+		missing += gapEnd.Sub(gapStart)
+
+		covered := time.Duration(0)
+		for i := range report.Incidents {
+			incidentStart, _ := time.Parse(time.RFC3339, report.Incidents[i].Start)
+			incidentEnd, _ := time.Parse(time.RFC3339, report.Incidents[i].End)
+			overlapStart, overlapEnd := maxTime(gapStart, incidentStart), minTime(gapEnd, incidentEnd)
+			if overlapStart.Before(overlapEnd) {
+				report.Incidents[i].TelemetryGap = true
+				covered += overlapEnd.Sub(overlapStart)
+			}
+		}
+		unexplained += gapEnd.Sub(gapStart) - covered
+	}
+	report.Telemetry.MissingMinutes = roundTo(missing.Minutes(), 1)
+	report.Telemetry.UnexplainedGapMinutes = roundTo(unexplained.Minutes(), 1)
+}
+
+// sloIntervalDuration converts a metric interval to a duration
+func sloIntervalDuration(interval string) time.Duration {
+	if interval == "PT15M" {
+		return 15 * time.Minute
+	}
+	return 5 * time.Minute
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,767 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/compute"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// API versions used by the DNS validation
+const (
+	dnsClusterAPIVersion = "2024-05-01"
+	dnsNetworkAPIVersion = "2024-05-01"
+)
+
+const (
+	// azureDNSAddress is the virtual IP of Azure-provided DNS, used by VNets without custom DNS servers
+	azureDNSAddress = "168.63.129.16"
+	// clusterDomain is the cluster domain CoreDNS serves on AKS
+	clusterDomain = "cluster.local"
+	// maxDNSTestNames bounds the user supplied names resolved from the node
+	maxDNSTestNames = 10
+	// resolvedConfPath lists the upstream servers of systemd-resolved, which AKS nodes use behind the
+	// 127.0.0.53 stub in /etc/resolv.conf
+	resolvedConfPath = "/run/systemd/resolve/resolv.conf"
+)
+
+// defaultDNSTestNames are the names resolved in every resolution test besides the API server FQDN
+var defaultDNSTestNames = []string{"kubernetes.default.svc." + clusterDomain, "mcr.microsoft.com"}
+
+// dnsNamePattern matches the host names accepted in the names parameter
+var dnsNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_-]{0,62})(\.[A-Za-z0-9_]([A-Za-z0-9_-]{0,62}))*\.?$`)
+
+// VNetDNS is the DNS server setting of a VNet the node pools use
+type VNetDNS struct {
+	ID string `json:"id"`
+	// DNSServers are the custom DNS servers; empty means Azure-provided DNS
+	DNSServers []string `json:"dnsServers"`
+	AzureDNS   bool     `json:"azureDns"`
+}
+
+// StubDomain is a server block of the coredns-custom ConfigMap that forwards zones to their own servers
+type StubDomain struct {
+	Key             string           `json:"key"`
+	Zones           []string         `json:"zones"`
+	Forwarders      []string         `json:"forwarders"`
+	ForwarderChecks []ForwarderCheck `json:"forwarderChecks,omitempty"`
+}
+
+// ForwarderCheck is the result of asking a stub domain forwarder for the SOA record of its zone from a node
+type ForwarderCheck struct {
+	Zone      string `json:"zone"`
+	Forwarder string `json:"forwarder"`
+	// Status is answered, unreachable or failed
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// CoreDNSConfig is the forwarding configuration of CoreDNS
+type CoreDNSConfig struct {
+	// Upstreams are the targets of the forward plugin of the default server block; /etc/resolv.conf means
+	// the DNS servers of the node
+	Upstreams []string `json:"upstreams"`
+	// OverrideForwarders are forward plugin targets added to the default server block by .override keys
+	OverrideForwarders []string     `json:"overrideForwarders,omitempty"`
+	StubDomains        []StubDomain `json:"stubDomains"`
+	// IgnoredKeys are coredns-custom keys that AKS does not import because they end in neither .server nor .override
+	IgnoredKeys []string `json:"ignoredKeys,omitempty"`
+}
+
+// NodeDNS is the resolver configuration read from the node the resolution tests ran on
+type NodeDNS struct {
+	Name        string   `json:"name"`
+	VMSS        string   `json:"vmss"`
+	InstanceID  string   `json:"instanceId"`
+	Nameservers []string `json:"nameservers,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// NameResolution compares how a node and CoreDNS resolve a name
+type NameResolution struct {
+	Name string `json:"name"`
+	// Node are the addresses the node's resolver returned; empty for cluster names, which only CoreDNS serves
+	Node         []string `json:"node,omitempty"`
+	NodeError    string   `json:"nodeError,omitempty"`
+	CoreDNS      []string `json:"coreDns,omitempty"`
+	CoreDNSError string   `json:"coreDnsError,omitempty"`
+	Mismatch     string   `json:"mismatch,omitempty"`
+}
+
+// DNSReport is the result of the aks_dns_validation tool
+type DNSReport struct {
+	ClusterName    string           `json:"clusterName"`
+	DNSServiceIP   string           `json:"dnsServiceIP"`
+	PrivateCluster bool             `json:"privateCluster"`
+	PrivateDNSZone string           `json:"privateDnsZone,omitempty"`
+	APIServerFQDN  string           `json:"apiServerFqdn,omitempty"`
+	VNets          []VNetDNS        `json:"vnets"`
+	CoreDNS        CoreDNSConfig    `json:"coreDns"`
+	Node           *NodeDNS         `json:"node,omitempty"`
+	Resolution     []NameResolution `json:"resolution,omitempty"`
+	Findings       []string         `json:"findings"`
+	Notes          []string         `json:"notes,omitempty"`
+}
+
+type dnsCluster struct {
+	Properties struct {
+		FQDN                   string `json:"fqdn"`
+		PrivateFQDN            string `json:"privateFQDN"`
+		NodeResourceGroup      string `json:"nodeResourceGroup"`
+		APIServerAccessProfile *struct {
+			EnablePrivateCluster bool   `json:"enablePrivateCluster"`
+			PrivateDNSZone       string `json:"privateDNSZone"`
+		} `json:"apiServerAccessProfile"`
+		NetworkProfile struct {
+			DNSServiceIP string `json:"dnsServiceIP"`
+		} `json:"networkProfile"`
+		AgentPoolProfiles []struct {
+			VnetSubnetID string `json:"vnetSubnetID"`
+		} `json:"agentPoolProfiles"`
+	} `json:"properties"`
+}
+
+type dnsVNet struct {
+	ID         string `json:"id"`
+	Properties struct {
+		DHCPOptions struct {
+			DNSServers []string `json:"dnsServers"`
+		} `json:"dhcpOptions"`
+	} `json:"properties"`
+}
+
+type dnsNode struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// CorefileBlock is a server block of a Corefile and the targets of its forward plugin
+type CorefileBlock struct {
+	Zones   []string
+	Forward []string
+}
+
+// GetDNSValidationHandler returns a handler for the aks_dns_validation command
+func GetDNSValidationHandler(api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		var azExecutor tools.CommandExecutor
+		if !cfg.NoAzCli {
+			azExecutor = azcli.NewExecutor()
+		}
+		return HandleDNSValidation(params, api, azExecutor, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleDNSValidation validates the custom DNS configuration of a cluster: the DNS servers of the node VNets,
+// the CoreDNS upstreams and stub domains of the coredns and coredns-custom ConfigMaps and, with test_resolution,
+// the resolver of one node, the reachability of the stub domain forwarders and how the node and CoreDNS resolve
+// representative names. Resolution tests use run-command and need readwrite access and the Azure CLI.
+func HandleDNSValidation(params map[string]interface{}, api common.ARMCaller, azExecutor, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	if raw, _ := params["names"].(string); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !dnsNamePattern.MatchString(name) {
+				return "", fmt.Errorf("invalid name in names parameter: %q", name)
+			}
+			names = append(names, strings.TrimSuffix(name, "."))
+		}
+		if len(names) > maxDNSTestNames {
+			return "", fmt.Errorf("too many names: %d (maximum: %d)", len(names), maxDNSTestNames)
+		}
+	}
+	nodeName, _ := params["node_name"].(string)
+	testResolution, _ := params["test_resolution"].(bool)
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+dnsClusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+	var cluster dnsCluster
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+
+	report := DNSReport{
+		ClusterName:  clusterName,
+		DNSServiceIP: cluster.Properties.NetworkProfile.DNSServiceIP,
+		VNets:        []VNetDNS{},
+		CoreDNS:      CoreDNSConfig{Upstreams: []string{}, StubDomains: []StubDomain{}},
+	}
+	report.APIServerFQDN = cluster.Properties.FQDN
+	if profile := cluster.Properties.APIServerAccessProfile; profile != nil && profile.EnablePrivateCluster {
+		report.PrivateCluster = true
+		report.PrivateDNSZone = profile.PrivateDNSZone
+		if cluster.Properties.PrivateFQDN != "" && !strings.EqualFold(profile.PrivateDNSZone, "none") {
+			report.APIServerFQDN = cluster.Properties.PrivateFQDN
+		}
+	}
+
+	if err := readVNetDNS(ctx, api, subID, cluster, &report); err != nil {
+		return "", err
+	}
+	if err := readCoreDNSConfig(kubectlExecutor, cfg, &report); err != nil {
+		return "", err
+	}
+
+	var resolution []NameResolution
+	switch {
+	case !testResolution:
+		report.Notes = append(report.Notes, "set test_resolution=true (readwrite access) to read the resolver of a node, probe the stub domain "+
+			"forwarders and compare how the node and CoreDNS resolve names")
+	case cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin":
+		report.Notes = append(report.Notes, "test_resolution uses run-command on a node and requires readwrite or admin access; only the configuration was checked")
+	case azExecutor == nil:
+		report.Notes = append(report.Notes, "test_resolution uses run-command through the Azure CLI, which this server runs without; only the configuration was checked")
+	case report.DNSServiceIP == "":
+		report.Notes = append(report.Notes, "the cluster reports no DNS service IP, so names could not be resolved through CoreDNS")
+	default:
+		node, err := selectDNSTestNode(kubectlExecutor, cfg, subID, cluster.Properties.NodeResourceGroup, nodeName)
+		if err != nil {
+			return "", err
+		}
+		testNames := append(append([]string{}, defaultDNSTestNames...), names...)
+		if report.APIServerFQDN != "" {
+			testNames = append(testNames, report.APIServerFQDN)
+		}
+		report.Node = node
+		resolution = testDNSResolution(azExecutor, cfg, subID, cluster.Properties.NodeResourceGroup, &report, testNames)
+	}
+	report.Resolution = resolution
+	report.Findings = BuildDNSFindings(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal DNS report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// readVNetDNS reads the DNS servers of the VNets of the node pool subnets, or of the VNets in the node resource
+// group when the cluster uses a managed VNet
+func readVNetDNS(ctx context.Context, api common.ARMCaller, subID string, cluster dnsCluster, report *DNSReport) error {
+	var vnetIDs []string
+	for _, pool := range cluster.Properties.AgentPoolProfiles {
+		if i := strings.Index(strings.ToLower(pool.VnetSubnetID), "/subnets/"); i > 0 && !containsFold(vnetIDs, pool.VnetSubnetID[:i]) {
+			vnetIDs = append(vnetIDs, pool.VnetSubnetID[:i])
+		}
+	}
+
+	var vnets []dnsVNet
+	if len(vnetIDs) == 0 {
+		path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks?api-version=%s",
+			subID, cluster.Properties.NodeResourceGroup, dnsNetworkAPIVersion)
+		body, err := api.CallARM(ctx, http.MethodGet, path)
+		if err != nil {
+			return fmt.Errorf("failed to list the VNets of node resource group %s: %w", cluster.Properties.NodeResourceGroup, err)
+		}
+		var list struct {
+			Value []dnsVNet `json:"value"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return fmt.Errorf("failed to parse VNets: %w", err)
+		}
+		vnets = list.Value
+	}
+	for _, id := range vnetIDs {
+		body, err := api.CallARM(ctx, http.MethodGet, id+"?api-version="+dnsNetworkAPIVersion)
+		if err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not read VNet %s: %v", resourceName(id), err))
+			continue
+		}
+		var vnet dnsVNet
+		if err := json.Unmarshal(body, &vnet); err != nil {
+			return fmt.Errorf("failed to parse VNet %s: %w", resourceName(id), err)
+		}
+		vnet.ID = id
+		vnets = append(vnets, vnet)
+	}
+
+	for _, vnet := range vnets {
+		servers := vnet.Properties.DHCPOptions.DNSServers
+		if servers == nil {
+			servers = []string{}
+		}
+		report.VNets = append(report.VNets, VNetDNS{ID: vnet.ID, DNSServers: servers, AzureDNS: len(servers) == 0 || containsFold(servers, azureDNSAddress)})
+	}
+	return nil
+}
+
+// readCoreDNSConfig reads the upstreams of the default CoreDNS server block and the stub domains and overrides
+// of the coredns-custom ConfigMap
+func readCoreDNSConfig(kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData, report *DNSReport) error {
+	var configMap struct {
+		Data map[string]string `json:"data"`
+	}
+	output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get configmap coredns -n kube-system -o json"}, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get the coredns ConfigMap: %v", err)
+	}
+	if err := json.Unmarshal([]byte(output), &configMap); err != nil {
+		return fmt.Errorf("failed to parse the coredns ConfigMap: %v", err)
+	}
+	for _, block := range ParseCorefile(configMap.Data["Corefile"]) {
+		if isRootZone(block.Zones) {
+			report.CoreDNS.Upstreams = append(report.CoreDNS.Upstreams, block.Forward...)
+		}
+	}
+
+	configMap.Data = nil
+	output, err = kubectlExecutor.Execute(map[string]interface{}{"command": "get configmap coredns-custom -n kube-system -o json"}, cfg)
+	if err != nil {
+		if !strings.Contains(err.Error(), "NotFound") && !strings.Contains(err.Error(), "not found") {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not read the coredns-custom ConfigMap: %v", err))
+		}
+		return nil
+	}
+	if err := json.Unmarshal([]byte(output), &configMap); err != nil {
+		return fmt.Errorf("failed to parse the coredns-custom ConfigMap: %v", err)
+	}
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case strings.HasSuffix(key, ".server"):
+			for _, block := range ParseCorefile(configMap.Data[key]) {
+				forwarders := block.Forward
+				if forwarders == nil {
+					forwarders = []string{}
+				}
+				report.CoreDNS.StubDomains = append(report.CoreDNS.StubDomains, StubDomain{Key: key, Zones: block.Zones, Forwarders: forwarders})
+			}
+		case strings.HasSuffix(key, ".override"):
+			// Overrides are plugin lines imported into the default server block
+			report.CoreDNS.OverrideForwarders = append(report.CoreDNS.OverrideForwarders, ParseCorefile("override {\n" + configMap.Data[key] + "\n}")[0].Forward...)
+		default:
+			report.CoreDNS.IgnoredKeys = append(report.CoreDNS.IgnoredKeys, key)
+		}
+	}
+	return nil
+}
+
+// ParseCorefile returns the server blocks of a Corefile with the targets of their forward plugin. Comments and
+// plugins other than forward are skipped.
+func ParseCorefile(corefile string) []CorefileBlock {
+	var blocks []CorefileBlock
+	depth := 0
+	for _, line := range strings.Split(corefile, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		opens := strings.Count(line, "{")
+		switch {
+		case depth == 0 && opens > 0:
+			var zones []string
+			for _, field := range fields {
+				if field == "{" {
+					break
+				}
+				zones = append(zones, strings.TrimSuffix(field, "{"))
+			}
+			blocks = append(blocks, CorefileBlock{Zones: zones})
+		case depth == 1 && fields[0] == "forward" && len(blocks) > 0:
+			block := &blocks[len(blocks)-1]
+			for _, field := range fields[min(2, len(fields)):] {
+				if field == "{" {
+					break
+				}
+				block.Forward = append(block.Forward, field)
+			}
+		}
+		depth += opens - strings.Count(line, "}")
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	return blocks
+}
+
+// selectDNSTestNode returns the given node, which may also be a provider ID or scale set instance, or the first
+// ready Linux scale set node
+func selectDNSTestNode(kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData, subID, nodeRG, nodeName string) (*NodeDNS, error) {
+	if nodeName == "" {
+		output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get nodes -o json"}, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %v", err)
+		}
+		var nodes []dnsNode
+		if err := decodeItems(output, &nodes); err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if node.Metadata.Labels["kubernetes.io/os"] == "windows" || !strings.Contains(node.Metadata.Name, "-vmss") {
+				continue
+			}
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == "Ready" && condition.Status == "True" && nodeName == "" {
+					nodeName = node.Metadata.Name
+				}
+			}
+		}
+		if nodeName == "" {
+			return nil, fmt.Errorf("no ready Linux scale set node found to run the resolution tests on")
+		}
+	}
+	instance, err := compute.NewNodeResolver(nil, kubectlExecutor, cfg).Resolve(context.Background(), subID, nodeRG, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeDNS{Name: instance.NodeName, VMSS: instance.VMSS, InstanceID: instance.InstanceID}, nil
+}
+
+// testDNSResolution runs one script on the node that reads its upstream DNS servers, resolves each name with
+// the node's resolver and through CoreDNS, and asks each stub domain forwarder for the SOA record of its zone
+func testDNSResolution(azExecutor tools.CommandExecutor, cfg *config.ConfigData, subID, nodeRG string, report *DNSReport, names []string) []NameResolution {
+	scripts := []string{"echo ==resolvconf==", "cat " + resolvedConfPath}
+	for _, name := range names {
+		if !strings.HasSuffix(name, "."+clusterDomain) {
+			scripts = append(scripts, "echo ==node:"+name+"==", "getent ahostsv4 "+name)
+		}
+		scripts = append(scripts, "echo ==coredns:"+name+"==", fmt.Sprintf("nslookup -timeout=3 %s %s", name, report.DNSServiceIP))
+	}
+	for _, stub := range report.CoreDNS.StubDomains {
+		for _, zone := range stub.Zones {
+			zone = trimZone(zone)
+			for _, forwarder := range stub.Forwarders {
+				if ip := forwarderIP(forwarder); ip != "" && zone != "." {
+					scripts = append(scripts, "echo ==forwarder:"+zone+"@"+ip+"==", fmt.Sprintf("nslookup -timeout=3 -type=SOA %s %s", zone, ip))
+				}
+			}
+		}
+	}
+
+	node := report.Node
+	output, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az vmss run-command invoke --name %s --resource-group %s --subscription %s --instance-id %s --command-id RunShellScript --scripts \"%s\" -o json",
+			node.VMSS, nodeRG, subID, node.InstanceID, strings.Join(scripts, "\" \"")),
+	}, cfg)
+	if err != nil {
+		node.Error = fmt.Sprintf("run-command failed on %s instance %s: %v", node.VMSS, node.InstanceID, err)
+		return nil
+	}
+	sections := ParseDNSTestOutput(output)
+	node.Nameservers = parseNameservers(sections["resolvconf"])
+
+	resolution := make([]NameResolution, 0, len(names))
+	for _, name := range names {
+		result := NameResolution{Name: name}
+		if !strings.HasSuffix(name, "."+clusterDomain) {
+			result.Node, result.NodeError = parseGetent(sections["node:"+name])
+		}
+		result.CoreDNS, result.CoreDNSError = parseNslookup(sections["coredns:"+name])
+		result.Mismatch = CompareResolution(result)
+		resolution = append(resolution, result)
+	}
+	for i := range report.CoreDNS.StubDomains {
+		stub := &report.CoreDNS.StubDomains[i]
+		for _, zone := range stub.Zones {
+			zone = trimZone(zone)
+			for _, forwarder := range stub.Forwarders {
+				ip := forwarderIP(forwarder)
+				if ip == "" || zone == "." {
+					continue
+				}
+				check := ForwarderCheck{Zone: zone, Forwarder: ip}
+				check.Status, check.Detail = parseSOACheck(sections["forwarder:"+zone+"@"+ip])
+				stub.ForwarderChecks = append(stub.ForwarderChecks, check)
+			}
+		}
+	}
+	return resolution
+}
+
+// ParseDNSTestOutput splits the stdout of the resolution test script into its ==name== sections
+func ParseDNSTestOutput(output string) map[string]string {
+	message := output
+	var result struct {
+		Value []struct {
+			Message string `json:"message"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err == nil && len(result.Value) > 0 {
+		message = result.Value[0].Message
+	}
+	sections := map[string]string{}
+	section := ""
+	for _, line := range strings.Split(compute.ParseRunCommandStdout(message), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) > 4 && strings.HasPrefix(trimmed, "==") && strings.HasSuffix(trimmed, "==") {
+			section = strings.Trim(trimmed, "=")
+			sections[section] = ""
+			continue
+		}
+		if section != "" {
+			sections[section] += line + "\n"
+		}
+	}
+	return sections
+}
+
+// parseNameservers returns the nameserver addresses of resolv.conf content
+func parseNameservers(content string) []string {
+	var servers []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// parseGetent returns the addresses of getent ahostsv4 output
+func parseGetent(content string) ([]string, string) {
+	var addresses []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && net.ParseIP(fields[0]) != nil && !containsFold(addresses, fields[0]) {
+			addresses = append(addresses, fields[0])
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, "the node's resolver returned no address"
+	}
+	return addresses, ""
+}
+
+// parseNslookup returns the addresses of the answer section of nslookup output, or the error it reported
+func parseNslookup(content string) ([]string, string) {
+	var addresses []string
+	answer := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "** ") || strings.Contains(line, "timed out") || strings.Contains(line, "no servers could be reached"):
+			return nil, strings.Trim(line, "*; ")
+		case strings.HasPrefix(line, "Name:"):
+			answer = true
+		case answer && strings.HasPrefix(line, "Address:"):
+			address := strings.TrimSpace(strings.TrimPrefix(line, "Address:"))
+			if i := strings.Index(address, "#"); i >= 0 {
+				address = address[:i]
+			}
+			if !containsFold(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		if strings.TrimSpace(content) == "" {
+			return nil, "no output; nslookup may be missing on the node"
+		}
+		return nil, "no address in the answer"
+	}
+	return addresses, ""
+}
+
+// parseSOACheck classifies the nslookup output of a SOA query sent to a stub domain forwarder
+func parseSOACheck(content string) (string, string) {
+	switch {
+	case strings.Contains(content, "origin ="):
+		return "answered", ""
+	case strings.Contains(content, "timed out") || strings.Contains(content, "no servers could be reached"):
+		return "unreachable", "the forwarder did not answer from the node"
+	case strings.TrimSpace(content) == "":
+		return "failed", "no output; nslookup may be missing on the node"
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "** ") {
+			return "failed", strings.Trim(line, "* ")
+		}
+	}
+	return "failed", "the forwarder returned no SOA record for the zone"
+}
+
+// CompareResolution describes how the node and CoreDNS disagree about a name, or returns an empty string.
+// Cluster names are only checked through CoreDNS.
+func CompareResolution(r NameResolution) string {
+	if strings.HasSuffix(r.Name, "."+clusterDomain) {
+		if len(r.CoreDNS) == 0 {
+			return fmt.Sprintf("CoreDNS could not resolve %s (%s), so in-cluster service discovery is failing", r.Name, r.CoreDNSError)
+		}
+		return ""
+	}
+	switch {
+	case len(r.Node) == 0 && len(r.CoreDNS) == 0:
+		return fmt.Sprintf("neither the node nor CoreDNS resolves %s", r.Name)
+	case len(r.CoreDNS) == 0:
+		return fmt.Sprintf("the node resolves %s but CoreDNS does not (%s); pods cannot reach it by name", r.Name, r.CoreDNSError)
+	case len(r.Node) == 0:
+		return fmt.Sprintf("CoreDNS resolves %s but the node does not; kubelet and image pulls use the node's DNS servers", r.Name)
+	}
+	for _, address := range r.Node {
+		if containsFold(r.CoreDNS, address) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("the node resolves %s to %s but CoreDNS to %s; the node and CoreDNS use DNS servers with different records",
+		r.Name, strings.Join(r.Node, ", "), strings.Join(r.CoreDNS, ", "))
+}
+
+// BuildDNSFindings reports inconsistent VNet DNS servers, custom DNS that cannot resolve the private API
+// server, CoreDNS configuration AKS ignores or rejects, unreachable stub domain forwarders, nodes that have not
+// picked up the VNet DNS servers and names the node and CoreDNS resolve differently
+func BuildDNSFindings(report DNSReport) []string {
+	findings := []string{}
+
+	serverSets := map[string]bool{}
+	var customServers []string
+	for _, vnet := range report.VNets {
+		serverSets[strings.Join(vnet.DNSServers, ",")] = true
+		for _, server := range vnet.DNSServers {
+			if !containsFold(customServers, server) {
+				customServers = append(customServers, server)
+			}
+		}
+	}
+	if len(serverSets) > 1 {
+		findings = append(findings, "the node pool VNets use different DNS servers, so nodes of different pools can resolve names differently")
+	}
+	for _, vnet := range report.VNets {
+		if !vnet.AzureDNS && report.PrivateCluster && report.APIServerFQDN != "" && !strings.EqualFold(report.PrivateDNSZone, "none") {
+			findings = append(findings, fmt.Sprintf("VNet %s uses custom DNS servers (%s) and the cluster is private: they must forward the API server zone "+
+				"(%s) to %s from a VNet linked to the private DNS zone, or nodes cannot resolve %s",
+				resourceName(vnet.ID), strings.Join(vnet.DNSServers, ", "), apiServerZone(report.APIServerFQDN), azureDNSAddress, report.APIServerFQDN))
+		}
+	}
+
+	for _, key := range report.CoreDNS.IgnoredKeys {
+		findings = append(findings, fmt.Sprintf("coredns-custom key %s ends in neither .server nor .override, so AKS does not load it", key))
+	}
+	if len(report.CoreDNS.OverrideForwarders) > 0 && len(report.CoreDNS.Upstreams) > 0 {
+		findings = append(findings, fmt.Sprintf("a coredns-custom .override adds forward %s to the default server block, which already forwards to %s; "+
+			"CoreDNS allows one forward plugin per server block, so check the coredns pod logs for a configuration error and use a .server block instead",
+			strings.Join(report.CoreDNS.OverrideForwarders, " "), strings.Join(report.CoreDNS.Upstreams, " ")))
+	}
+	for _, stub := range report.CoreDNS.StubDomains {
+		if isRootZone(stub.Zones) {
+			findings = append(findings, fmt.Sprintf("coredns-custom key %s defines a server block for the root zone, which the default Corefile already serves; "+
+				"CoreDNS rejects duplicate zones", stub.Key))
+		}
+		if len(stub.Forwarders) == 0 {
+			continue
+		}
+		for _, forwarder := range stub.Forwarders {
+			if forwarderIP(forwarder) == "" && forwarder != "/etc/resolv.conf" {
+				findings = append(findings, fmt.Sprintf("coredns-custom key %s forwards to %s, which is not an IP address", stub.Key, forwarder))
+			}
+		}
+		for _, check := range stub.ForwarderChecks {
+			switch check.Status {
+			case "unreachable":
+				findings = append(findings, fmt.Sprintf("stub domain %s forwards to %s, which did not answer from node %s; check the routes, NSGs and "+
+					"firewalls between the cluster and that DNS server (conditional forwarding to on-premises needs VPN or ExpressRoute connectivity)",
+					check.Zone, check.Forwarder, report.Node.Name))
+			case "failed":
+				findings = append(findings, fmt.Sprintf("stub domain %s forwards to %s, which did not answer for the zone (%s); check that the server "+
+					"is authoritative for or forwards %s", check.Zone, check.Forwarder, check.Detail, check.Zone))
+			}
+		}
+	}
+
+	if node := report.Node; node != nil {
+		if node.Error != "" {
+			findings = append(findings, node.Error)
+		} else if len(report.VNets) > 0 && len(node.Nameservers) > 0 {
+			expected := customServers
+			if len(expected) == 0 {
+				expected = []string{azureDNSAddress}
+			}
+			for _, server := range node.Nameservers {
+				if !containsFold(expected, server) {
+					findings = append(findings, fmt.Sprintf("node %s uses DNS servers %s but the VNets configure %s; nodes pick up VNet DNS changes "+
+						"only after a restart or reimage", node.Name, strings.Join(node.Nameservers, ", "), strings.Join(expected, ", ")))
+					break
+				}
+			}
+		}
+	}
+	for _, r := range report.Resolution {
+		if r.Mismatch != "" {
+			findings = append(findings, r.Mismatch)
+		}
+	}
+	return findings
+}
+
+// isRootZone reports whether the zones of a server block include the root zone
+func isRootZone(zones []string) bool {
+	for _, zone := range zones {
+		if trimZone(zone) == "." {
+			return true
+		}
+	}
+	return false
+}
+
+// trimZone strips the scheme and port of a server block zone, such as dns://contoso.com:53
+func trimZone(zone string) string {
+	if i := strings.Index(zone, "://"); i >= 0 {
+		zone = zone[i+3:]
+	}
+	if i := strings.LastIndex(zone, ":"); i >= 0 {
+		zone = zone[:i]
+	}
+	if zone != "." {
+		zone = strings.TrimSuffix(zone, ".")
+	}
+	return zone
+}
+
+// forwarderIP returns the IP address of a forward plugin target, such as dns://10.0.0.4:53, or an empty string
+func forwarderIP(target string) string {
+	if i := strings.Index(target, "://"); i >= 0 {
+		target = target[i+3:]
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		target = host
+	}
+	if net.ParseIP(target) == nil {
+		return ""
+	}
+	return target
+}
+
+// apiServerZone returns the DNS zone of the API server FQDN, such as privatelink.eastus.azmk8s.io
+func apiServerZone(fqdn string) string {
+	if _, zone, ok := strings.Cut(fqdn, "."); ok {
+		return zone
+	}
+	return fqdn
+}
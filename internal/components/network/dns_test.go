@@ -0,0 +1,220 @@
+package network
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const testVNetID = "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet"
+
+const testCorefile = `.:53 {
+    errors
+    ready
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+      pods insecure
+      fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf # upstream
+    cache 30
+    import custom/*.override
+}
+import custom/*.server
+`
+
+const testDNSTestOutput = `[stdout]
+==resolvconf==
+nameserver 10.0.0.4
+==coredns:kubernetes.default.svc.cluster.local==
+Server:		10.0.0.10
+Address:	10.0.0.10#53
+
+Name:	kubernetes.default.svc.cluster.local
+Address: 10.0.0.1
+==node:mcr.microsoft.com==
+13.107.246.64   STREAM mcr.microsoft.com
+13.107.246.64   DGRAM
+==coredns:mcr.microsoft.com==
+Non-authoritative answer:
+mcr.microsoft.com	canonical name = mcr-microsoft-com.a-0016.a-msedge.net.
+Name:	mcr-microsoft-com.a-0016.a-msedge.net
+Address: 13.107.246.64
+==node:app.corp.contoso.com==
+10.50.0.20      STREAM app.corp.contoso.com
+==coredns:app.corp.contoso.com==
+** server can't find app.corp.contoso.com: NXDOMAIN
+==node:aks-1234.abc.privatelink.eastus.azmk8s.io==
+10.240.0.100    STREAM aks-1234.abc.privatelink.eastus.azmk8s.io
+==coredns:aks-1234.abc.privatelink.eastus.azmk8s.io==
+Name:	aks-1234.abc.privatelink.eastus.azmk8s.io
+Address: 10.240.0.100
+==forwarder:corp.contoso.com@10.50.0.53==
+;; connection timed out; no servers could be reached
+==forwarder:corp.contoso.com@10.50.0.54==
+corp.contoso.com
+	origin = dc1.corp.contoso.com
+[stderr]
+`
+
+func newDNSARM() *fakeARM {
+	return &fakeARM{bodies: map[string]string{
+		testClusterID: `{"properties": {"fqdn": "aks-1234.hcp.eastus.azmk8s.io", "privateFQDN": "aks-1234.abc.privatelink.eastus.azmk8s.io",
+			"nodeResourceGroup": "mc_rg_aks_eastus", "apiServerAccessProfile": {"enablePrivateCluster": true, "privateDNSZone": "system"},
+			"networkProfile": {"dnsServiceIP": "10.0.0.10"}, "agentPoolProfiles": [{"vnetSubnetID": "` + testVNetID + `/subnets/nodes"}]}}`,
+		testVNetID: `{"properties": {"dhcpOptions": {"dnsServers": ["10.0.0.5"]}}}`,
+	}}
+}
+
+func newDNSKubectl() *fakeAzExecutor {
+	corefile, _ := json.Marshal(testCorefile)
+	return &fakeAzExecutor{responses: map[string]string{
+		"get configmap coredns -n": `{"data": {"Corefile": ` + string(corefile) + `}}`,
+		"get configmap coredns-custom": `{"data": {
+			"corp.server": "corp.contoso.com:53 {\n  errors\n  cache 30\n  forward . 10.50.0.53 10.50.0.54\n}\n",
+			"upstream.override": "forward . 1.1.1.1\n",
+			"hosts.conf": "hosts { 10.1.1.1 legacy.local }"}}`,
+		"get nodes": `{"items": [
+			{"metadata": {"name": "akswin000000", "labels": {"kubernetes.io/os": "windows"}}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}},
+			{"metadata": {"name": "aks-system-12345678-vmss000000", "labels": {"kubernetes.io/os": "linux"}}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}},
+			{"metadata": {"name": "aks-system-12345678-vmss00000a", "labels": {"kubernetes.io/os": "linux"}}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}]}`,
+	}}
+}
+
+func runDNSValidation(t *testing.T, params map[string]interface{}, az *fakeAzExecutor, cfg *config.ConfigData) DNSReport {
+	t.Helper()
+	var azExecutor tools.CommandExecutor
+	if az != nil {
+		azExecutor = az
+	}
+	output, err := HandleDNSValidation(params, newDNSARM(), azExecutor, newDNSKubectl(), cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report DNSReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestDNSValidationConfiguration tests the checks that only read the VNet and CoreDNS configuration
+func TestDNSValidationConfiguration(t *testing.T) {
+	params := testEgressParams()
+	params["test_resolution"] = true
+	report := runDNSValidation(t, params, nil, config.NewConfig())
+
+	if report.APIServerFQDN != "aks-1234.abc.privatelink.eastus.azmk8s.io" || report.DNSServiceIP != "10.0.0.10" || !report.PrivateCluster {
+		t.Errorf("Unexpected cluster details %+v", report)
+	}
+	if len(report.VNets) != 1 || report.VNets[0].AzureDNS || report.VNets[0].DNSServers[0] != "10.0.0.5" {
+		t.Errorf("Unexpected VNets %+v", report.VNets)
+	}
+	coreDNS := report.CoreDNS
+	if len(coreDNS.Upstreams) != 1 || coreDNS.Upstreams[0] != "/etc/resolv.conf" || len(coreDNS.OverrideForwarders) != 1 {
+		t.Errorf("Unexpected CoreDNS upstreams %+v", coreDNS)
+	}
+	if len(coreDNS.StubDomains) != 1 || coreDNS.StubDomains[0].Zones[0] != "corp.contoso.com:53" || len(coreDNS.StubDomains[0].Forwarders) != 2 {
+		t.Errorf("Unexpected stub domains %+v", coreDNS.StubDomains)
+	}
+	if len(coreDNS.IgnoredKeys) != 1 || coreDNS.IgnoredKeys[0] != "hosts.conf" {
+		t.Errorf("Unexpected ignored keys %v", coreDNS.IgnoredKeys)
+	}
+	if report.Node != nil || len(report.Notes) != 1 || !strings.Contains(report.Notes[0], "requires readwrite") {
+		t.Errorf("Expected the resolution tests to be skipped at readonly access, got %+v %v", report.Node, report.Notes)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{"forward the API server zone (abc.privatelink.eastus.azmk8s.io) to 168.63.129.16", "hosts.conf ends in neither",
+		"adds forward 1.1.1.1 to the default server block"} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected a finding containing %q, got:\n%s", want, findings)
+		}
+	}
+}
+
+// TestDNSValidationResolution tests the node resolver, forwarder and resolution checks of test_resolution
+func TestDNSValidationResolution(t *testing.T) {
+	params := testEgressParams()
+	params["test_resolution"] = true
+	params["names"] = "app.corp.contoso.com."
+	output, _ := json.Marshal(map[string]interface{}{"value": []map[string]string{{"message": "Enable succeeded: \n" + testDNSTestOutput}}})
+	az := &fakeAzExecutor{responses: map[string]string{"az vmss run-command invoke": string(output)}}
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readwrite"
+	report := runDNSValidation(t, params, az, cfg)
+
+	if report.Node == nil || report.Node.Name != "aks-system-12345678-vmss00000a" || report.Node.InstanceID != "10" ||
+		len(report.Node.Nameservers) != 1 || report.Node.Nameservers[0] != "10.0.0.4" {
+		t.Fatalf("Unexpected node %+v", report.Node)
+	}
+	if len(report.Resolution) != 4 {
+		t.Fatalf("Expected 4 resolved names, got %+v", report.Resolution)
+	}
+	for _, r := range report.Resolution {
+		switch r.Name {
+		case "kubernetes.default.svc.cluster.local", "mcr.microsoft.com", "aks-1234.abc.privatelink.eastus.azmk8s.io":
+			if r.Mismatch != "" || len(r.CoreDNS) != 1 {
+				t.Errorf("Expected %s to resolve consistently, got %+v", r.Name, r)
+			}
+		case "app.corp.contoso.com":
+			if r.CoreDNSError != "server can't find app.corp.contoso.com: NXDOMAIN" || !strings.Contains(r.Mismatch, "CoreDNS does not") {
+				t.Errorf("Expected a CoreDNS mismatch for %s, got %+v", r.Name, r)
+			}
+		}
+	}
+	checks := report.CoreDNS.StubDomains[0].ForwarderChecks
+	if len(checks) != 2 || checks[0].Status != "unreachable" || checks[1].Status != "answered" {
+		t.Errorf("Unexpected forwarder checks %+v", checks)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{"node aks-system-12345678-vmss00000a uses DNS servers 10.0.0.4 but the VNets configure 10.0.0.5",
+		"stub domain corp.contoso.com forwards to 10.50.0.53, which did not answer", "the node resolves app.corp.contoso.com but CoreDNS does not"} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected a finding containing %q, got:\n%s", want, findings)
+		}
+	}
+}
+
+func TestDNSValidationInvalidNames(t *testing.T) {
+	params := testEgressParams()
+	params["names"] = "ok.example.com,bad name;rm"
+	if _, err := HandleDNSValidation(params, newDNSARM(), nil, newDNSKubectl(), config.NewConfig()); err == nil {
+		t.Error("Expected an invalid name to be rejected")
+	}
+}
+
+func TestParseCorefile(t *testing.T) {
+	blocks := ParseCorefile(testCorefile + "contoso.com:53 {\n  forward . dns://10.0.0.4:53 10.0.0.5 {\n    max_fails 2\n  }\n}\n")
+	if len(blocks) != 2 {
+		t.Fatalf("Expected 2 server blocks, got %+v", blocks)
+	}
+	if blocks[0].Zones[0] != ".:53" || len(blocks[0].Forward) != 1 || blocks[0].Forward[0] != "/etc/resolv.conf" {
+		t.Errorf("Unexpected default block %+v", blocks[0])
+	}
+	if len(blocks[1].Forward) != 2 || forwarderIP(blocks[1].Forward[0]) != "10.0.0.4" || trimZone(blocks[1].Zones[0]) != "contoso.com" {
+		t.Errorf("Unexpected stub domain block %+v", blocks[1])
+	}
+}
+
+func TestCompareResolution(t *testing.T) {
+	tests := []struct {
+		result NameResolution
+		want   string
+	}{
+		{NameResolution{Name: "kubernetes.default.svc.cluster.local", CoreDNSError: "timed out"}, "in-cluster service discovery is failing"},
+		{NameResolution{Name: "a.example.com", Node: []string{"10.0.0.1"}, CoreDNS: []string{"10.0.0.1", "10.0.0.2"}}, ""},
+		{NameResolution{Name: "a.example.com", Node: []string{"10.0.0.1"}, CoreDNS: []string{"20.0.0.1"}}, "different records"},
+		{NameResolution{Name: "a.example.com", CoreDNS: []string{"20.0.0.1"}}, "the node does not"},
+		{NameResolution{Name: "a.example.com"}, "neither the node nor CoreDNS"},
+	}
+	for _, tt := range tests {
+		got := CompareResolution(tt.result)
+		if (tt.want == "" && got != "") || !strings.Contains(got, tt.want) {
+			t.Errorf("CompareResolution(%+v) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
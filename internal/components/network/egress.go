@@ -0,0 +1,1070 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// API versions used by the egress firewall analysis
+const (
+	egressClusterAPIVersion     = "2024-05-01"
+	egressNetworkAPIVersion     = "2024-05-01"
+	egressDiagnosticsAPIVersion = "2021-05-01-preview"
+	egressWorkspaceAPIVersion   = "2022-10-01"
+)
+
+// Deny log window and size limits
+const (
+	defaultDenyHours = 24
+	maxDenyHours     = 168
+	maxDenyRows      = 50
+)
+
+// aksFQDNTag is the Azure Firewall FQDN tag covering the FQDNs AKS requires
+const aksFQDNTag = "AzureKubernetesService"
+
+// Kinds of the source a denied flow is mapped to
+const (
+	SourcePod     = "pod"
+	SourceNode    = "node"
+	SourceUnknown = "unknown"
+)
+
+// RequiredDestination is an outbound destination AKS needs, and whether the firewall allows it
+type RequiredDestination struct {
+	Destination string `json:"destination"`
+	Protocol    string `json:"protocol"`
+	Port        int    `json:"port"`
+	Purpose     string `json:"purpose"`
+	Allowed     bool   `json:"allowed"`
+	// AllowedBy and DeniedBy name the matching rules as collection/rule
+	AllowedBy []string `json:"allowedBy,omitempty"`
+	DeniedBy  []string `json:"deniedBy,omitempty"`
+	// tags are service tags that also cover the destination
+	tags []string
+}
+
+// FirewallRule is an application or network rule of an Azure Firewall or its policy
+type FirewallRule struct {
+	Type         string   `json:"type"`
+	Collection   string   `json:"collection"`
+	Name         string   `json:"name"`
+	Action       string   `json:"action"`
+	Priority     int      `json:"priority,omitempty"`
+	Sources      []string `json:"sources"`
+	Destinations []string `json:"destinations"`
+	// Ports are destination ports of network rules and protocol:port pairs of application rules
+	Ports     []string `json:"ports"`
+	Protocols []string `json:"protocols,omitempty"`
+	// Required lists the AKS-required destinations the rule matches
+	Required []string `json:"required,omitempty"`
+	fqdnTags []string
+}
+
+// FlowSource is the pod or node a denied flow came from
+type FlowSource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Node      string `json:"node,omitempty"`
+}
+
+// DeniedFlow is a destination the firewall denied to one source IP
+type DeniedFlow struct {
+	SourceIP    string     `json:"sourceIp"`
+	Destination string     `json:"destination"`
+	Port        int        `json:"port"`
+	Protocol    string     `json:"protocol"`
+	RuleType    string     `json:"ruleType"`
+	Count       int        `json:"count"`
+	LastSeen    string     `json:"lastSeen"`
+	Required    bool       `json:"required"`
+	Source      FlowSource `json:"source"`
+}
+
+// EgressReport is the result of the aks_egress_firewall_analysis tool
+type EgressReport struct {
+	ClusterName    string                `json:"clusterName"`
+	OutboundType   string                `json:"outboundType"`
+	SubnetPrefixes []string              `json:"subnetPrefixes"`
+	NextHop        string                `json:"nextHop,omitempty"`
+	Firewall       string                `json:"firewall,omitempty"`
+	FirewallPolicy string                `json:"firewallPolicy,omitempty"`
+	Required       []RequiredDestination `json:"required"`
+	Rules          []FirewallRule        `json:"rules"`
+	Denies         []DeniedFlow          `json:"denies"`
+	DeniesError    string                `json:"deniesError,omitempty"`
+	Findings       []string              `json:"findings"`
+	Notes          []string              `json:"notes,omitempty"`
+}
+
+// egressCluster is the part of the managed cluster the analysis needs
+type egressCluster struct {
+	Location   string `json:"location"`
+	Properties struct {
+		FQDN           string `json:"fqdn"`
+		NetworkProfile struct {
+			NetworkPlugin     string `json:"networkPlugin"`
+			NetworkPluginMode string `json:"networkPluginMode"`
+			OutboundType      string `json:"outboundType"`
+		} `json:"networkProfile"`
+		APIServerAccessProfile struct {
+			EnablePrivateCluster bool `json:"enablePrivateCluster"`
+		} `json:"apiServerAccessProfile"`
+		AgentPoolProfiles []struct {
+			VnetSubnetID string `json:"vnetSubnetID"`
+			PodSubnetID  string `json:"podSubnetID"`
+		} `json:"agentPoolProfiles"`
+	} `json:"properties"`
+}
+
+// GetEgressFirewallAnalysisHandler returns a handler for the aks_egress_firewall_analysis command
+func GetEgressFirewallAnalysisHandler(api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		var kubectlExecutor tools.CommandExecutor
+		if cfg.KubernetesAccessEnabled() {
+			kubectlExecutor = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+		}
+		return HandleEgressFirewallAnalysis(params, api, azcli.NewExecutor(), kubectlExecutor, cfg)
+	})
+}
+
+// HandleEgressFirewallAnalysis checks the Azure Firewall that cluster egress is routed through: which of its rules
+// allow the destinations AKS requires, which requests from the cluster subnets it recently denied, and which pods
+// or nodes sent them. A nil kubectl executor skips the mapping of source IPs to pods.
+func HandleEgressFirewallAnalysis(params map[string]interface{}, api common.ARMCaller, azExecutor, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	hours := defaultDenyHours
+	if raw, ok := params["hours"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		if hours, err = strconv.Atoi(value); err != nil || hours <= 0 || hours > maxDenyHours {
+			return "", fmt.Errorf("invalid hours parameter: %s (expected 1 to %d)", value, maxDenyHours)
+		}
+	}
+	firewallID, _ := params["firewall_id"].(string)
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+egressClusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+	var cluster egressCluster
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+
+	report := EgressReport{
+		ClusterName:    clusterName,
+		OutboundType:   cluster.Properties.NetworkProfile.OutboundType,
+		SubnetPrefixes: []string{},
+		Required:       requiredDestinations(cluster),
+		Rules:          []FirewallRule{},
+		Denies:         []DeniedFlow{},
+	}
+
+	nextHops, err := readSubnetRoutes(ctx, api, cluster, &report)
+	if err != nil {
+		return "", err
+	}
+	if len(report.SubnetPrefixes) == 0 {
+		report.Findings = append(report.Findings, "the cluster uses the managed virtual network, so egress cannot be routed through a firewall; "+
+			"routing egress through Azure Firewall requires node pools in your own subnets with a route table")
+		return marshalEgressReport(report)
+	}
+	if len(nextHops) > 1 {
+		report.Notes = append(report.Notes, fmt.Sprintf("node subnets route egress to different next hops (%s); only %s is analyzed",
+			strings.Join(nextHops, ", "), nextHops[0]))
+	}
+	if len(nextHops) > 0 {
+		report.NextHop = nextHops[0]
+	}
+
+	firewall, err := findFirewall(ctx, api, subID, firewallID, report.NextHop)
+	if err != nil {
+		return "", err
+	}
+	if firewall == nil {
+		switch {
+		case report.NextHop == "":
+			report.Findings = append(report.Findings, fmt.Sprintf("no 0.0.0.0/0 route to a virtual appliance is attached to the node subnets (outbound type %s), "+
+				"so egress does not go through a firewall; pass firewall_id to check a firewall anyway", report.OutboundType))
+		default:
+			report.Findings = append(report.Findings, fmt.Sprintf("egress is routed to %s, which is not an Azure Firewall in subscription %s; "+
+				"the rules and logs of network virtual appliances cannot be read, so check that the appliance allows the required destinations. "+
+				"Pass firewall_id if the firewall is in another subscription", report.NextHop, subID))
+		}
+		return marshalEgressReport(report)
+	}
+	report.Firewall = firewall.ID
+
+	rules, policyID, err := readFirewallRules(ctx, api, firewall)
+	if err != nil {
+		return "", err
+	}
+	report.FirewallPolicy = policyID
+	prefixes := parsePrefixes(report.SubnetPrefixes)
+	report.Rules = MatchRequiredRules(report.Required, rules, prefixes)
+
+	// Denies come from the firewall logs, so a missing workspace still reports the rule analysis
+	if err := queryDenies(ctx, &report, api, azExecutor, firewall.ID, hours, cfg); err != nil {
+		report.DeniesError = err.Error()
+	}
+	if len(report.Denies) > 0 && kubectlExecutor != nil {
+		if err := mapDenySources(report.Denies, kubectlExecutor, cfg); err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not map source IPs to pods: %v", err))
+		}
+	} else if len(report.Denies) > 0 {
+		report.Notes = append(report.Notes, "Kubernetes access is not available, so source IPs were not mapped to pods")
+	}
+	if plugin := cluster.Properties.NetworkProfile; plugin.NetworkPlugin == "kubenet" || plugin.NetworkPluginMode == "overlay" {
+		report.Notes = append(report.Notes, "pod traffic leaves the node with the node IP (SNAT) with kubenet and Azure CNI overlay, "+
+			"so denies map to nodes rather than pods")
+	}
+	report.Findings = BuildEgressFindings(report, hours)
+	return marshalEgressReport(report)
+}
+
+// requiredDestinations returns the outbound destinations AKS requires for the cluster. The API server of a
+// private cluster is reached inside the virtual network, so it is only required for public clusters.
+func requiredDestinations(cluster egressCluster) []RequiredDestination {
+	regionTag := "AzureCloud." + strings.ToLower(strings.ReplaceAll(cluster.Location, " ", ""))
+	var required []RequiredDestination
+	if cluster.Properties.FQDN != "" && !cluster.Properties.APIServerAccessProfile.EnablePrivateCluster {
+		required = append(required, RequiredDestination{Destination: cluster.Properties.FQDN, Protocol: "TCP", Port: 443,
+			Purpose: "API server", tags: []string{regionTag}})
+	}
+	https := []struct{ fqdn, purpose string }{
+		{"mcr.microsoft.com", "Microsoft Container Registry images"},
+		{"*.data.mcr.microsoft.com", "Microsoft Container Registry image layers"},
+		{"mcr-0001.mcr-msedge.net", "Microsoft Container Registry CDN"},
+		{"management.azure.com", "Azure Resource Manager for Kubernetes operations"},
+		{"login.microsoftonline.com", "Microsoft Entra ID authentication"},
+		{"packages.microsoft.com", "Microsoft packages and cached apt-get operations"},
+		{"acs-mirror.azureedge.net", "binaries such as kubenet and Azure CNI"},
+		{"packages.aks.azure.com", "binaries such as kubenet and Azure CNI"},
+	}
+	for _, d := range https {
+		required = append(required, RequiredDestination{Destination: d.fqdn, Protocol: "TCP", Port: 443, Purpose: d.purpose})
+	}
+	if !cluster.Properties.APIServerAccessProfile.EnablePrivateCluster {
+		required = append(required,
+			RequiredDestination{Destination: regionTag, Protocol: "UDP", Port: 1194, Purpose: "tunnel between nodes and the control plane", tags: []string{regionTag}},
+			RequiredDestination{Destination: regionTag, Protocol: "TCP", Port: 9000, Purpose: "tunnel between nodes and the control plane", tags: []string{regionTag}})
+	}
+	required = append(required, RequiredDestination{Destination: "ntp.ubuntu.com", Protocol: "UDP", Port: 123, Purpose: "time synchronization of Linux nodes"})
+	return required
+}
+
+// readSubnetRoutes reads the address prefixes of the node and pod subnets and the next hops of their default routes
+func readSubnetRoutes(ctx context.Context, api common.ARMCaller, cluster egressCluster, report *EgressReport) ([]string, error) {
+	seen := map[string]bool{}
+	var subnetIDs []string
+	for _, pool := range cluster.Properties.AgentPoolProfiles {
+		for _, id := range []string{pool.VnetSubnetID, pool.PodSubnetID} {
+			if id != "" && !seen[strings.ToLower(id)] {
+				seen[strings.ToLower(id)] = true
+				subnetIDs = append(subnetIDs, id)
+			}
+		}
+	}
+
+	var nextHops []string
+	routeTables := map[string]bool{}
+	for _, id := range subnetIDs {
+		body, err := api.CallARM(ctx, http.MethodGet, id+"?api-version="+egressNetworkAPIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subnet %s: %w", id, err)
+		}
+		var subnet struct {
+			Properties struct {
+				AddressPrefix   string   `json:"addressPrefix"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+				RouteTable      *struct {
+					ID string `json:"id"`
+				} `json:"routeTable"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(body, &subnet); err != nil {
+			return nil, fmt.Errorf("failed to parse subnet %s: %w", id, err)
+		}
+		if subnet.Properties.AddressPrefix != "" {
+			report.SubnetPrefixes = append(report.SubnetPrefixes, subnet.Properties.AddressPrefix)
+		}
+		report.SubnetPrefixes = append(report.SubnetPrefixes, subnet.Properties.AddressPrefixes...)
+		if subnet.Properties.RouteTable == nil || routeTables[strings.ToLower(subnet.Properties.RouteTable.ID)] {
+			continue
+		}
+		routeTables[strings.ToLower(subnet.Properties.RouteTable.ID)] = true
+
+		body, err = api.CallARM(ctx, http.MethodGet, subnet.Properties.RouteTable.ID+"?api-version="+egressNetworkAPIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get route table %s: %w", subnet.Properties.RouteTable.ID, err)
+		}
+		var table struct {
+			Properties struct {
+				Routes []struct {
+					Properties struct {
+						AddressPrefix    string `json:"addressPrefix"`
+						NextHopType      string `json:"nextHopType"`
+						NextHopIPAddress string `json:"nextHopIpAddress"`
+					} `json:"properties"`
+				} `json:"routes"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(body, &table); err != nil {
+			return nil, fmt.Errorf("failed to parse route table %s: %w", subnet.Properties.RouteTable.ID, err)
+		}
+		for _, route := range table.Properties.Routes {
+			hop := route.Properties
+			if hop.AddressPrefix == "0.0.0.0/0" && hop.NextHopType == "VirtualAppliance" && hop.NextHopIPAddress != "" && !containsFold(nextHops, hop.NextHopIPAddress) {
+				nextHops = append(nextHops, hop.NextHopIPAddress)
+			}
+		}
+	}
+	return nextHops, nil
+}
+
+// azureFirewall is the part of an Azure Firewall the analysis needs
+type azureFirewall struct {
+	ID         string `json:"id"`
+	Properties struct {
+		IPConfigurations []struct {
+			Properties struct {
+				PrivateIPAddress string `json:"privateIPAddress"`
+			} `json:"properties"`
+		} `json:"ipConfigurations"`
+		HubIPAddresses *struct {
+			PrivateIPAddress string `json:"privateIPAddress"`
+		} `json:"hubIPAddresses"`
+		FirewallPolicy *struct {
+			ID string `json:"id"`
+		} `json:"firewallPolicy"`
+		ApplicationRuleCollections []classicRuleCollection `json:"applicationRuleCollections"`
+		NetworkRuleCollections     []classicRuleCollection `json:"networkRuleCollections"`
+	} `json:"properties"`
+}
+
+// privateIPs returns the private IP addresses traffic is routed to the firewall with
+func (f azureFirewall) privateIPs() []string {
+	var ips []string
+	for _, ipConfig := range f.Properties.IPConfigurations {
+		ips = append(ips, ipConfig.Properties.PrivateIPAddress)
+	}
+	if f.Properties.HubIPAddresses != nil {
+		ips = append(ips, f.Properties.HubIPAddresses.PrivateIPAddress)
+	}
+	return ips
+}
+
+// findFirewall returns the given firewall, or the Azure Firewall of the subscription that owns the next hop.
+// It returns nil when the next hop is not an Azure Firewall.
+func findFirewall(ctx context.Context, api common.ARMCaller, subID, firewallID, nextHop string) (*azureFirewall, error) {
+	if firewallID != "" {
+		body, err := api.CallARM(ctx, http.MethodGet, firewallID+"?api-version="+egressNetworkAPIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get firewall %s: %w", firewallID, err)
+		}
+		var firewall azureFirewall
+		if err := json.Unmarshal(body, &firewall); err != nil {
+			return nil, fmt.Errorf("failed to parse firewall %s: %w", firewallID, err)
+		}
+		return &firewall, nil
+	}
+	if nextHop == "" {
+		return nil, nil
+	}
+
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Network/azureFirewalls?api-version=%s", subID, egressNetworkAPIVersion)
+	for next != "" {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Azure Firewalls: %w", err)
+		}
+		var page struct {
+			Value    []azureFirewall `json:"value"`
+			NextLink string          `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse Azure Firewalls: %w", err)
+		}
+		for i := range page.Value {
+			if containsFold(page.Value[i].privateIPs(), nextHop) {
+				return &page.Value[i], nil
+			}
+		}
+		next = page.NextLink
+	}
+	return nil, nil
+}
+
+// classicRuleCollection is a rule collection defined on the firewall itself rather than in a policy
+type classicRuleCollection struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Priority int `json:"priority"`
+		Action   struct {
+			Type string `json:"type"`
+		} `json:"action"`
+		Rules []firewallRuleJSON `json:"rules"`
+	} `json:"properties"`
+}
+
+// firewallRuleJSON holds the fields of classic and policy application and network rules
+type firewallRuleJSON struct {
+	Name     string `json:"name"`
+	RuleType string `json:"ruleType"`
+	// Protocols are protocol objects for application rules and names for classic network rules
+	Protocols            json.RawMessage `json:"protocols"`
+	IPProtocols          []string        `json:"ipProtocols"`
+	SourceAddresses      []string        `json:"sourceAddresses"`
+	SourceIPGroups       []string        `json:"sourceIpGroups"`
+	TargetFqdns          []string        `json:"targetFqdns"`
+	FqdnTags             []string        `json:"fqdnTags"`
+	DestinationAddresses []string        `json:"destinationAddresses"`
+	DestinationFqdns     []string        `json:"destinationFqdns"`
+	DestinationIPGroups  []string        `json:"destinationIpGroups"`
+	DestinationPorts     []string        `json:"destinationPorts"`
+}
+
+// toRule normalizes a classic or policy rule
+func (r firewallRuleJSON) toRule(ruleType, collection, action string, priority int) FirewallRule {
+	rule := FirewallRule{Type: ruleType, Collection: collection, Name: r.Name, Action: action, Priority: priority, fqdnTags: r.FqdnTags}
+	rule.Sources = append(rule.Sources, r.SourceAddresses...)
+	for _, group := range r.SourceIPGroups {
+		rule.Sources = append(rule.Sources, "ipGroup:"+resourceName(group))
+	}
+	if ruleType == "application" {
+		var protocols []struct {
+			ProtocolType string `json:"protocolType"`
+			Port         int    `json:"port"`
+		}
+		_ = json.Unmarshal(r.Protocols, &protocols)
+		for _, p := range protocols {
+			rule.Ports = append(rule.Ports, fmt.Sprintf("%s:%d", p.ProtocolType, p.Port))
+		}
+		rule.Destinations = append(rule.Destinations, r.TargetFqdns...)
+		for _, tag := range r.FqdnTags {
+			rule.Destinations = append(rule.Destinations, "fqdnTag:"+tag)
+		}
+		return rule
+	}
+	rule.Protocols = r.IPProtocols
+	if len(rule.Protocols) == 0 {
+		_ = json.Unmarshal(r.Protocols, &rule.Protocols)
+	}
+	rule.Ports = r.DestinationPorts
+	rule.Destinations = append(append(rule.Destinations, r.DestinationAddresses...), r.DestinationFqdns...)
+	for _, group := range r.DestinationIPGroups {
+		rule.Destinations = append(rule.Destinations, "ipGroup:"+resourceName(group))
+	}
+	return rule
+}
+
+// readFirewallRules returns the application and network rules of the firewall's policy, including its parent
+// policy, or of the firewall itself when it has no policy
+func readFirewallRules(ctx context.Context, api common.ARMCaller, firewall *azureFirewall) ([]FirewallRule, string, error) {
+	var rules []FirewallRule
+	if firewall.Properties.FirewallPolicy == nil {
+		for _, collection := range firewall.Properties.ApplicationRuleCollections {
+			for _, r := range collection.Properties.Rules {
+				rules = append(rules, r.toRule("application", collection.Name, collection.Properties.Action.Type, collection.Properties.Priority))
+			}
+		}
+		for _, collection := range firewall.Properties.NetworkRuleCollections {
+			for _, r := range collection.Properties.Rules {
+				rules = append(rules, r.toRule("network", collection.Name, collection.Properties.Action.Type, collection.Properties.Priority))
+			}
+		}
+		return rules, "", nil
+	}
+
+	policyID := firewall.Properties.FirewallPolicy.ID
+	for id := policyID; id != ""; {
+		body, err := api.CallARM(ctx, http.MethodGet, id+"?api-version="+egressNetworkAPIVersion)
+		if err != nil {
+			return nil, policyID, fmt.Errorf("failed to get firewall policy %s: %w", id, err)
+		}
+		var policy struct {
+			Properties struct {
+				BasePolicy *struct {
+					ID string `json:"id"`
+				} `json:"basePolicy"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(body, &policy); err != nil {
+			return nil, policyID, fmt.Errorf("failed to parse firewall policy %s: %w", id, err)
+		}
+		groupRules, err := readRuleCollectionGroups(ctx, api, id)
+		if err != nil {
+			return nil, policyID, err
+		}
+		rules = append(rules, groupRules...)
+		id = ""
+		if policy.Properties.BasePolicy != nil && !strings.EqualFold(policy.Properties.BasePolicy.ID, policyID) {
+			id = policy.Properties.BasePolicy.ID
+		}
+	}
+	return rules, policyID, nil
+}
+
+// readRuleCollectionGroups returns the filter rules of the rule collection groups of a firewall policy
+func readRuleCollectionGroups(ctx context.Context, api common.ARMCaller, policyID string) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	next := policyID + "/ruleCollectionGroups?api-version=" + egressNetworkAPIVersion
+	for next != "" {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rule collection groups of %s: %w", policyID, err)
+		}
+		var page struct {
+			Value []struct {
+				Properties struct {
+					RuleCollections []struct {
+						RuleCollectionType string `json:"ruleCollectionType"`
+						Name               string `json:"name"`
+						Priority           int    `json:"priority"`
+						Action             struct {
+							Type string `json:"type"`
+						} `json:"action"`
+						Rules []firewallRuleJSON `json:"rules"`
+					} `json:"ruleCollections"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse rule collection groups of %s: %w", policyID, err)
+		}
+		for _, group := range page.Value {
+			for _, collection := range group.Properties.RuleCollections {
+				// DNAT collections translate inbound traffic and do not filter egress
+				if collection.RuleCollectionType != "FirewallPolicyFilterRuleCollection" {
+					continue
+				}
+				for _, r := range collection.Rules {
+					switch r.RuleType {
+					case "ApplicationRule":
+						rules = append(rules, r.toRule("application", collection.Name, collection.Action.Type, collection.Priority))
+					case "NetworkRule":
+						rules = append(rules, r.toRule("network", collection.Name, collection.Action.Type, collection.Priority))
+					}
+				}
+			}
+		}
+		next = page.NextLink
+	}
+	return rules, nil
+}
+
+// MatchRequiredRules marks the required destinations allowed or denied by rules that apply to the cluster subnets,
+// and returns those rules
+func MatchRequiredRules(required []RequiredDestination, rules []FirewallRule, prefixes []*net.IPNet) []FirewallRule {
+	matched := []FirewallRule{}
+	for _, rule := range rules {
+		if !coversSources(rule.Sources, prefixes) {
+			continue
+		}
+		for i := range required {
+			if !ruleMatches(rule, required[i]) {
+				continue
+			}
+			name := rule.Collection + "/" + rule.Name
+			if strings.EqualFold(rule.Action, "Deny") {
+				required[i].DeniedBy = append(required[i].DeniedBy, name)
+			} else {
+				required[i].Allowed = true
+				required[i].AllowedBy = append(required[i].AllowedBy, name)
+			}
+			rule.Required = append(rule.Required, fmt.Sprintf("%s:%d", required[i].Destination, required[i].Port))
+		}
+		if len(rule.Required) > 0 {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// ruleMatches reports whether a rule matches the protocol, port and destination of a required destination
+func ruleMatches(rule FirewallRule, required RequiredDestination) bool {
+	if rule.Type == "application" {
+		if required.Protocol != "TCP" || !containsFold(rule.Ports, fmt.Sprintf("Https:%d", required.Port)) {
+			return false
+		}
+		if containsFold(rule.fqdnTags, aksFQDNTag) {
+			return true
+		}
+		for _, destination := range rule.Destinations {
+			if fqdnMatches(destination, required.Destination) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !containsFold(rule.Protocols, required.Protocol) && !containsFold(rule.Protocols, "Any") {
+		return false
+	}
+	if !portMatches(rule.Ports, required.Port) {
+		return false
+	}
+	for _, destination := range rule.Destinations {
+		if destination == "*" || fqdnMatches(destination, required.Destination) {
+			return true
+		}
+		for _, tag := range required.tags {
+			// The global AzureCloud tag includes every regional AzureCloud tag
+			if strings.EqualFold(destination, tag) || strings.EqualFold(destination, "AzureCloud") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryDenies finds the workspace receiving the firewall rule logs and reads the denied requests from the cluster subnets
+func queryDenies(ctx context.Context, report *EgressReport, api common.ARMCaller, azExecutor tools.CommandExecutor, firewallID string, hours int, cfg *config.ConfigData) error {
+	body, err := api.CallARM(ctx, http.MethodGet, fmt.Sprintf("%s/providers/Microsoft.Insights/diagnosticSettings?api-version=%s", firewallID, egressDiagnosticsAPIVersion))
+	if err != nil {
+		return fmt.Errorf("failed to get firewall diagnostic settings: %w", err)
+	}
+	workspaceID, dedicated, err := findFirewallLogWorkspace(body)
+	if err != nil {
+		return err
+	}
+	body, err = api.CallARM(ctx, http.MethodGet, workspaceID+"?api-version="+egressWorkspaceAPIVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get Log Analytics workspace %s: %w", workspaceID, err)
+	}
+	var workspace struct {
+		Properties struct {
+			CustomerID string `json:"customerId"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &workspace); err != nil || workspace.Properties.CustomerID == "" {
+		return fmt.Errorf("failed to read the customer ID of Log Analytics workspace %s", workspaceID)
+	}
+
+	end := time.Now().UTC()
+	start := end.Add(-time.Duration(hours) * time.Hour)
+	output, err := azExecutor.Execute(map[string]interface{}{
+		"command": fmt.Sprintf("az monitor log-analytics query --workspace %s --analytics-query \"%s\" --timespan %s --output json",
+			workspace.Properties.CustomerID, DenyQuery(firewallID, report.SubnetPrefixes, dedicated), start.Format(time.RFC3339)+"/"+end.Format(time.RFC3339)),
+	}, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to query firewall logs: %w", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		return fmt.Errorf("failed to parse firewall log query results: %w", err)
+	}
+	for _, row := range rows {
+		flow := DeniedFlow{
+			SourceIP:    rowText(row, "SourceIp"),
+			Destination: rowText(row, "Destination"),
+			Protocol:    strings.ToUpper(rowText(row, "Protocol")),
+			RuleType:    rowText(row, "RuleType"),
+			LastSeen:    rowText(row, "LastSeen"),
+			Source:      FlowSource{Kind: SourceUnknown},
+		}
+		flow.Port, _ = strconv.Atoi(rowText(row, "DestinationPort"))
+		count, _ := strconv.ParseFloat(rowText(row, "Count"), 64)
+		flow.Count = int(count)
+		flow.Required = isRequired(flow, report.Required)
+		report.Denies = append(report.Denies, flow)
+	}
+	return nil
+}
+
+// findFirewallLogWorkspace returns the workspace of the first diagnostic setting sending firewall rule logs, and
+// whether it writes resource-specific tables
+func findFirewallLogWorkspace(body []byte) (string, bool, error) {
+	var result struct {
+		Value []struct {
+			Properties struct {
+				WorkspaceID                 string `json:"workspaceId"`
+				LogAnalyticsDestinationType string `json:"logAnalyticsDestinationType"`
+				Logs                        []struct {
+					Category      string `json:"category"`
+					CategoryGroup string `json:"categoryGroup"`
+					Enabled       bool   `json:"enabled"`
+				} `json:"logs"`
+			} `json:"properties"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", false, fmt.Errorf("failed to parse firewall diagnostic settings: %w", err)
+	}
+	categories := []string{"AZFWApplicationRule", "AZFWNetworkRule", "AzureFirewallApplicationRule", "AzureFirewallNetworkRule"}
+	for _, setting := range result.Value {
+		if setting.Properties.WorkspaceID == "" {
+			continue
+		}
+		for _, log := range setting.Properties.Logs {
+			if log.Enabled && (containsFold(categories, log.Category) || strings.EqualFold(log.CategoryGroup, "allLogs")) {
+				return setting.Properties.WorkspaceID, strings.EqualFold(setting.Properties.LogAnalyticsDestinationType, "Dedicated"), nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("no diagnostic setting sends the firewall's application or network rule logs to a Log Analytics workspace; " +
+		"enable them to find denied requests")
+}
+
+// DenyQuery returns the query summarizing the denied requests from the given source prefixes, for the
+// resource-specific tables or the legacy AzureDiagnostics table
+func DenyQuery(firewallID string, prefixes []string, dedicated bool) string {
+	ranges := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		ranges[i] = "'" + prefix + "'"
+	}
+	sourceFilter := fmt.Sprintf("ipv4_is_in_any_range(SourceIp, dynamic([%s]))", strings.Join(ranges, ", "))
+	summary := fmt.Sprintf(" | summarize Count = count(), LastSeen = max(TimeGenerated) by SourceIp, Destination, DestinationPort = tostring(DestinationPort), Protocol, RuleType"+
+		" | top %d by Count desc", maxDenyRows)
+	if dedicated {
+		return fmt.Sprintf("union (AZFWApplicationRule | extend Destination = Fqdn, RuleType = 'application'),"+
+			" (AZFWNetworkRule | extend Destination = DestinationIp, RuleType = 'network')"+
+			" | where _ResourceId == '%s' and Action == 'Deny' and %s", strings.ToLower(firewallID), sourceFilter) + summary
+	}
+	return fmt.Sprintf("AzureDiagnostics | where Category in ('AzureFirewallApplicationRule', 'AzureFirewallNetworkRule') and ResourceId == '%s'"+
+		" and msg_s has 'Action: Deny'"+
+		" | parse msg_s with Protocol ' request from ' SourceIp ':' SourcePort ' to ' Destination ':' DestinationPort '. Action: ' *"+
+		" | extend RuleType = iff(Category == 'AzureFirewallApplicationRule', 'application', 'network') | where %s", strings.ToUpper(firewallID), sourceFilter) + summary
+}
+
+// mapDenySources maps the source IPs of denied flows to pods, Cilium endpoints and nodes
+func mapDenySources(denies []DeniedFlow, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) error {
+	run := func(command string) (string, error) {
+		return kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+	sources := map[string]FlowSource{}
+
+	output, err := run("get nodes -o json")
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %v", err)
+	}
+	var nodes struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Addresses []struct {
+					Type    string `json:"type"`
+					Address string `json:"address"`
+				} `json:"addresses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &nodes); err != nil {
+		return fmt.Errorf("failed to parse nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == "InternalIP" {
+				sources[address.Address] = FlowSource{Kind: SourceNode, Name: node.Metadata.Name, Node: node.Metadata.Name}
+			}
+		}
+	}
+
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		output, err := run("get pods " + flag + " -o json")
+		if err != nil {
+			return fmt.Errorf("failed to list pods: %v", err)
+		}
+		var pods struct {
+			Items []struct {
+				Metadata struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+				Spec struct {
+					NodeName    string `json:"nodeName"`
+					HostNetwork bool   `json:"hostNetwork"`
+				} `json:"spec"`
+				Status struct {
+					PodIPs []struct {
+						IP string `json:"ip"`
+					} `json:"podIPs"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(output), &pods); err != nil {
+			return fmt.Errorf("failed to parse pods: %v", err)
+		}
+		for _, pod := range pods.Items {
+			// Host network pods share the node IP, which already maps to the node
+			if pod.Spec.HostNetwork {
+				continue
+			}
+			for _, ip := range pod.Status.PodIPs {
+				sources[ip.IP] = FlowSource{Kind: SourcePod, Namespace: pod.Metadata.Namespace, Name: pod.Metadata.Name, Node: pod.Spec.NodeName}
+			}
+		}
+
+		// Cilium endpoints keep the IPAM allocation of pods the pod list missed, such as pods being deleted.
+		// Clusters without Cilium have no endpoints, so failures are ignored.
+		output, err = run("get ciliumendpoints " + flag + " -o json")
+		if err != nil {
+			continue
+		}
+		var endpoints struct {
+			Items []struct {
+				Metadata struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+				Status struct {
+					Networking struct {
+						NodeIP     string `json:"node"`
+						Addressing []struct {
+							IPv4 string `json:"ipv4"`
+						} `json:"addressing"`
+					} `json:"networking"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if json.Unmarshal([]byte(output), &endpoints) != nil {
+			continue
+		}
+		for _, endpoint := range endpoints.Items {
+			node := sources[endpoint.Status.Networking.NodeIP].Node
+			for _, address := range endpoint.Status.Networking.Addressing {
+				if _, known := sources[address.IPv4]; !known && address.IPv4 != "" {
+					sources[address.IPv4] = FlowSource{Kind: SourcePod, Namespace: endpoint.Metadata.Namespace, Name: endpoint.Metadata.Name, Node: node}
+				}
+			}
+		}
+	}
+
+	for i := range denies {
+		if source, ok := sources[denies[i].SourceIP]; ok {
+			denies[i].Source = source
+		}
+	}
+	return nil
+}
+
+// BuildEgressFindings reports required destinations without an allow rule and summarizes the denied requests
+func BuildEgressFindings(report EgressReport, hours int) []string {
+	findings := []string{}
+	for _, required := range report.Required {
+		if len(required.DeniedBy) > 0 {
+			findings = append(findings, fmt.Sprintf("required destination %s (%s %d, %s) matches deny rule %s; check that it is not processed before the allow rules",
+				required.Destination, required.Protocol, required.Port, required.Purpose, strings.Join(required.DeniedBy, ", ")))
+		}
+		if !required.Allowed {
+			findings = append(findings, fmt.Sprintf("no allow rule for the cluster subnets matches required destination %s (%s %d, %s)",
+				required.Destination, required.Protocol, required.Port, required.Purpose))
+		}
+	}
+
+	if report.DeniesError != "" {
+		return findings
+	}
+	if len(report.Denies) == 0 {
+		return append(findings, fmt.Sprintf("the firewall denied no requests from the cluster subnets in the last %d hours", hours))
+	}
+	type destinationDenies struct {
+		requests int
+		sources  map[string]bool
+		required bool
+	}
+	byDestination := map[string]*destinationDenies{}
+	for _, deny := range report.Denies {
+		key := fmt.Sprintf("%s:%d", deny.Destination, deny.Port)
+		if byDestination[key] == nil {
+			byDestination[key] = &destinationDenies{sources: map[string]bool{}}
+		}
+		d := byDestination[key]
+		d.requests += deny.Count
+		d.required = d.required || deny.Required
+		d.sources[describeSource(deny)] = true
+	}
+	keys := make([]string, 0, len(byDestination))
+	for key := range byDestination {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := byDestination[keys[i]], byDestination[keys[j]]
+		if a.required != b.required {
+			return a.required
+		}
+		if a.requests != b.requests {
+			return a.requests > b.requests
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		d := byDestination[key]
+		sources := make([]string, 0, len(d.sources))
+		for source := range d.sources {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		if len(sources) > 3 {
+			sources = append(sources[:3], fmt.Sprintf("%d more", len(d.sources)-3))
+		}
+		kind := "denied"
+		if d.required {
+			kind = "denied required destination"
+		}
+		findings = append(findings, fmt.Sprintf("%s %s: %d requests in the last %d hours from %s", kind, key, d.requests, hours, strings.Join(sources, ", ")))
+	}
+	return findings
+}
+
+// describeSource names the pod or node a denied flow came from, or its IP
+func describeSource(deny DeniedFlow) string {
+	switch deny.Source.Kind {
+	case SourcePod:
+		return "pod " + deny.Source.Namespace + "/" + deny.Source.Name
+	case SourceNode:
+		return "node " + deny.Source.Name
+	}
+	return deny.SourceIP
+}
+
+// isRequired reports whether a denied flow went to a destination AKS requires
+func isRequired(flow DeniedFlow, required []RequiredDestination) bool {
+	for _, r := range required {
+		if r.Port != flow.Port {
+			continue
+		}
+		// Network rule logs show the IP of service tag destinations, so the tunnel ports identify them
+		if fqdnMatches(r.Destination, flow.Destination) || r.Port != 443 && len(r.tags) > 0 && net.ParseIP(flow.Destination) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// coversSources reports whether rule sources include the cluster subnets. IP groups are not expanded,
+// so they are assumed to include them.
+func coversSources(sources []string, prefixes []*net.IPNet) bool {
+	for _, source := range sources {
+		if source == "*" || strings.HasPrefix(source, "ipGroup:") {
+			return true
+		}
+		network := parsePrefix(source)
+		if network == nil {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if network.Contains(prefix.IP) || prefix.Contains(network.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fqdnMatches reports whether a rule FQDN, which may start with a wildcard, matches a destination.
+// A wildcard destination matches rules covering any of its names.
+func fqdnMatches(pattern, destination string) bool {
+	pattern, destination = strings.ToLower(pattern), strings.ToLower(destination)
+	if pattern == "*" || pattern == destination {
+		return true
+	}
+	if strings.HasPrefix(destination, "*.") {
+		destination = "any" + destination[1:]
+	}
+	return strings.HasPrefix(pattern, "*.") && strings.HasSuffix(destination, pattern[1:])
+}
+
+// portMatches reports whether destination ports, which may be * or ranges, include a port
+func portMatches(ports []string, port int) bool {
+	for _, p := range ports {
+		if p == "*" {
+			return true
+		}
+		low, high, isRange := strings.Cut(p, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(low))
+		if err != nil {
+			continue
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(high)); err != nil {
+				continue
+			}
+		}
+		if port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefixes parses CIDR prefixes, skipping invalid ones
+func parsePrefixes(prefixes []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, prefix := range prefixes {
+		if network := parsePrefix(prefix); network != nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// parsePrefix parses a CIDR prefix or a single IP address
+func parsePrefix(value string) *net.IPNet {
+	if !strings.Contains(value, "/") {
+		value += "/32"
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// resourceName returns the last segment of a resource ID
+func resourceName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+// containsFold reports whether values contain value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// rowText reads a column of a log query result row as text. The Azure CLI returns numbers as strings.
+func rowText(row map[string]interface{}, column string) string {
+	switch value := row[column].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+func marshalEgressReport(report EgressReport) (string, error) {
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal egress report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,218 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// fakeARM returns canned bodies by request path, ignoring the query string
+type fakeARM struct {
+	bodies map[string]string
+}
+
+func (f *fakeARM) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	path, _, _ = strings.Cut(path, "?")
+	if body, ok := f.bodies[path]; ok {
+		return []byte(body), nil
+	}
+	return nil, fmt.Errorf("unexpected path: %s", path)
+}
+
+const (
+	testClusterID  = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	testSubnetID   = "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet/subnets/nodes"
+	testRouteTable = "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/routeTables/egress"
+	testFirewallID = "/subscriptions/sub/resourceGroups/hub/providers/Microsoft.Network/azureFirewalls/fw"
+	testPolicyID   = "/subscriptions/sub/resourceGroups/hub/providers/Microsoft.Network/firewallPolicies/aks"
+	testWorkspace  = "/subscriptions/sub/resourceGroups/hub/providers/Microsoft.OperationalInsights/workspaces/logs"
+)
+
+const testRuleCollectionGroups = `{"value": [{"properties": {"ruleCollections": [
+	{"ruleCollectionType": "FirewallPolicyFilterRuleCollection", "name": "aks-fqdns", "priority": 200, "action": {"type": "Allow"}, "rules": [
+		{"ruleType": "ApplicationRule", "name": "aks-tag", "protocols": [{"protocolType": "Https", "port": 443}],
+		 "fqdnTags": ["AzureKubernetesService"], "sourceAddresses": ["10.240.0.0/16"]}
+	]},
+	{"ruleCollectionType": "FirewallPolicyFilterRuleCollection", "name": "aks-network", "priority": 100, "action": {"type": "Allow"}, "rules": [
+		{"ruleType": "NetworkRule", "name": "tunnel-udp", "ipProtocols": ["UDP"], "sourceAddresses": ["*"],
+		 "destinationAddresses": ["AzureCloud.eastus"], "destinationPorts": ["1194"]},
+		{"ruleType": "NetworkRule", "name": "tunnel-tcp", "ipProtocols": ["TCP"], "sourceAddresses": ["10.240.0.0/16"],
+		 "destinationAddresses": ["AzureCloud"], "destinationPorts": ["9000-9001"]},
+		{"ruleType": "NetworkRule", "name": "other-spoke", "ipProtocols": ["UDP"], "sourceAddresses": ["192.168.0.0/24"],
+		 "destinationFqdns": ["ntp.ubuntu.com"], "destinationPorts": ["123"]}
+	]},
+	{"ruleCollectionType": "FirewallPolicyNatRuleCollection", "name": "inbound", "priority": 300, "action": {"type": "Dnat"}, "rules": []}
+]}}]}`
+
+func newEgressARM() *fakeARM {
+	return &fakeARM{bodies: map[string]string{
+		testClusterID: `{"location": "East US", "properties": {"fqdn": "aks-1234.hcp.eastus.azmk8s.io",
+			"networkProfile": {"networkPlugin": "azure", "outboundType": "userDefinedRouting"},
+			"agentPoolProfiles": [{"vnetSubnetID": "` + testSubnetID + `"}, {"vnetSubnetID": "` + testSubnetID + `"}]}}`,
+		testSubnetID:   `{"properties": {"addressPrefix": "10.240.0.0/16", "routeTable": {"id": "` + testRouteTable + `"}}}`,
+		testRouteTable: `{"properties": {"routes": [{"properties": {"addressPrefix": "0.0.0.0/0", "nextHopType": "VirtualAppliance", "nextHopIpAddress": "10.0.1.4"}}]}}`,
+		"/subscriptions/sub/providers/Microsoft.Network/azureFirewalls": `{"value": [
+			{"id": "/subscriptions/sub/resourceGroups/hub/providers/Microsoft.Network/azureFirewalls/other", "properties": {"ipConfigurations": [{"properties": {"privateIPAddress": "10.9.1.4"}}]}},
+			{"id": "` + testFirewallID + `", "properties": {"ipConfigurations": [{"properties": {"privateIPAddress": "10.0.1.4"}}], "firewallPolicy": {"id": "` + testPolicyID + `"}}}]}`,
+		testPolicyID:                           `{"properties": {}}`,
+		testPolicyID + "/ruleCollectionGroups": testRuleCollectionGroups,
+		testFirewallID + "/providers/Microsoft.Insights/diagnosticSettings": `{"value": [{"properties": {"workspaceId": "` + testWorkspace + `",
+			"logAnalyticsDestinationType": "Dedicated", "logs": [{"category": "AZFWApplicationRule", "enabled": true}]}}]}`,
+		testWorkspace: `{"properties": {"customerId": "ws-customer"}}`,
+	}}
+}
+
+const testDenyRows = `[
+	{"SourceIp": "10.240.0.50", "Destination": "api.github.com", "DestinationPort": "443", "Protocol": "HTTPS", "RuleType": "application", "Count": "12", "LastSeen": "2025-01-02T03:04:05Z"},
+	{"SourceIp": "10.240.0.4", "Destination": "20.42.1.1", "DestinationPort": "1194", "Protocol": "UDP", "RuleType": "network", "Count": "3", "LastSeen": "2025-01-02T03:00:00Z"},
+	{"SourceIp": "10.240.0.77", "Destination": "mcr.microsoft.com", "DestinationPort": "443", "Protocol": "HTTPS", "RuleType": "application", "Count": "1", "LastSeen": "2025-01-02T02:00:00Z"}
+]`
+
+func newEgressKubectl() *fakeAzExecutor {
+	return &fakeAzExecutor{responses: map[string]string{
+		"get nodes": `{"items": [{"metadata": {"name": "aks-system-0"}, "status": {"addresses": [{"type": "InternalIP", "address": "10.240.0.4"}]}}]}`,
+		"get pods": `{"items": [
+			{"metadata": {"name": "web-1", "namespace": "shop"}, "spec": {"nodeName": "aks-system-0"}, "status": {"podIPs": [{"ip": "10.240.0.50"}]}},
+			{"metadata": {"name": "kube-proxy-x", "namespace": "kube-system"}, "spec": {"nodeName": "aks-system-0", "hostNetwork": true}, "status": {"podIPs": [{"ip": "10.240.0.4"}]}}]}`,
+		"get ciliumendpoints": `{"items": [{"metadata": {"name": "worker-9", "namespace": "jobs"},
+			"status": {"networking": {"node": "10.240.0.4", "addressing": [{"ipv4": "10.240.0.77"}]}}}]}`,
+	}}
+}
+
+func runEgressAnalysis(t *testing.T, params map[string]interface{}, api *fakeARM, az, kubectl *fakeAzExecutor) EgressReport {
+	t.Helper()
+	var kubectlExecutor tools.CommandExecutor
+	if kubectl != nil {
+		kubectlExecutor = kubectl
+	}
+	output, err := HandleEgressFirewallAnalysis(params, api, az, kubectlExecutor, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report EgressReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+func testEgressParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+}
+
+// TestEgressFirewallAnalysis tests rule matching, the deny query and the mapping of denies to pods and nodes
+func TestEgressFirewallAnalysis(t *testing.T) {
+	az := &fakeAzExecutor{responses: map[string]string{"az monitor log-analytics query": testDenyRows}}
+	report := runEgressAnalysis(t, testEgressParams(), newEgressARM(), az, newEgressKubectl())
+
+	if report.Firewall != testFirewallID || report.NextHop != "10.0.1.4" || report.FirewallPolicy != testPolicyID {
+		t.Fatalf("Expected the firewall owning the next hop, got %+v", report)
+	}
+	if len(report.SubnetPrefixes) != 1 || report.SubnetPrefixes[0] != "10.240.0.0/16" {
+		t.Errorf("Expected the node subnet prefix once, got %v", report.SubnetPrefixes)
+	}
+	for _, required := range report.Required {
+		wantAllowed := required.Destination != "ntp.ubuntu.com"
+		if required.Allowed != wantAllowed {
+			t.Errorf("Expected %s allowed=%v, got %+v", required.Destination, wantAllowed, required)
+		}
+	}
+	if len(report.Rules) != 3 {
+		t.Errorf("Expected the 3 rules matching required destinations from the cluster subnets, got %+v", report.Rules)
+	}
+
+	if len(report.Denies) != 3 {
+		t.Fatalf("Expected 3 denied flows, got %+v", report.Denies)
+	}
+	pod, node, endpoint := report.Denies[0], report.Denies[1], report.Denies[2]
+	if pod.Source.Kind != SourcePod || pod.Source.Namespace != "shop" || pod.Source.Name != "web-1" || pod.Count != 12 || pod.Required {
+		t.Errorf("Expected the first deny to map to pod shop/web-1, got %+v", pod)
+	}
+	if node.Source.Kind != SourceNode || node.Source.Name != "aks-system-0" || !node.Required {
+		t.Errorf("Expected the tunnel deny to map to the node and be required, got %+v", node)
+	}
+	if endpoint.Source.Name != "worker-9" || endpoint.Source.Node != "aks-system-0" || !endpoint.Required {
+		t.Errorf("Expected the Cilium endpoint to identify the pod, got %+v", endpoint)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	if !strings.Contains(findings, "no allow rule for the cluster subnets matches required destination ntp.ubuntu.com") {
+		t.Errorf("Expected a finding for the missing NTP rule, got %s", findings)
+	}
+	if !strings.HasPrefix(report.Findings[1], "denied required destination") ||
+		!strings.Contains(findings, "denied api.github.com:443: 12 requests in the last 24 hours from pod shop/web-1") {
+		t.Errorf("Expected required destinations first in the deny findings, got %s", findings)
+	}
+}
+
+// TestEgressFirewallAnalysisWithoutFirewall tests clusters whose egress does not reach an Azure Firewall
+func TestEgressFirewallAnalysisWithoutFirewall(t *testing.T) {
+	api := newEgressARM()
+	api.bodies[testRouteTable] = `{"properties": {"routes": [{"properties": {"addressPrefix": "0.0.0.0/0", "nextHopType": "VirtualAppliance", "nextHopIpAddress": "10.0.2.4"}}]}}`
+	report := runEgressAnalysis(t, testEgressParams(), api, &fakeAzExecutor{}, nil)
+	if report.Firewall != "" || len(report.Findings) != 1 || !strings.Contains(report.Findings[0], "not an Azure Firewall") {
+		t.Errorf("Expected the next hop to be reported as a network virtual appliance, got %+v", report)
+	}
+
+	api.bodies[testClusterID] = `{"location": "eastus", "properties": {"networkProfile": {"outboundType": "loadBalancer"}, "agentPoolProfiles": [{}]}}`
+	report = runEgressAnalysis(t, testEgressParams(), api, &fakeAzExecutor{}, nil)
+	if len(report.Findings) != 1 || !strings.Contains(report.Findings[0], "managed virtual network") {
+		t.Errorf("Expected a managed virtual network finding, got %v", report.Findings)
+	}
+
+	params := testEgressParams()
+	params["hours"] = 500.0
+	if _, err := HandleEgressFirewallAnalysis(params, api, &fakeAzExecutor{}, nil, config.NewConfig()); err == nil {
+		t.Error("Expected hours above the maximum to be rejected")
+	}
+}
+
+// TestEgressDeniesWithoutLogs tests that missing firewall logs leave the rule analysis intact
+func TestEgressDeniesWithoutLogs(t *testing.T) {
+	api := newEgressARM()
+	api.bodies[testFirewallID+"/providers/Microsoft.Insights/diagnosticSettings"] = `{"value": []}`
+	report := runEgressAnalysis(t, testEgressParams(), api, &fakeAzExecutor{}, nil)
+	if !strings.Contains(report.DeniesError, "no diagnostic setting") || len(report.Rules) != 3 {
+		t.Errorf("Expected the rules without denies, got %+v", report)
+	}
+}
+
+func TestDenyQuery(t *testing.T) {
+	dedicated := DenyQuery(testFirewallID, []string{"10.240.0.0/16", "10.241.0.0/16"}, true)
+	if !strings.Contains(dedicated, "AZFWApplicationRule") || !strings.Contains(dedicated, "_ResourceId == '"+strings.ToLower(testFirewallID)+"'") ||
+		!strings.Contains(dedicated, "dynamic(['10.240.0.0/16', '10.241.0.0/16'])") {
+		t.Errorf("Unexpected resource-specific query %s", dedicated)
+	}
+	legacy := DenyQuery(testFirewallID, []string{"10.240.0.0/16"}, false)
+	if !strings.Contains(legacy, "AzureDiagnostics") || !strings.Contains(legacy, "ResourceId == '"+strings.ToUpper(testFirewallID)+"'") ||
+		strings.Contains(legacy, `"`) {
+		t.Errorf("Unexpected AzureDiagnostics query %s", legacy)
+	}
+}
+
+func TestFQDNAndPortMatching(t *testing.T) {
+	tests := []struct {
+		pattern, destination string
+		want                 bool
+	}{
+		{"*", "mcr.microsoft.com", true},
+		{"MCR.microsoft.com", "mcr.microsoft.com", true},
+		{"*.microsoft.com", "mcr.microsoft.com", true},
+		{"*.mcr.microsoft.com", "*.data.mcr.microsoft.com", true},
+		{"*.data.mcr.microsoft.com", "mcr.microsoft.com", false},
+		{"microsoft.com", "mcr.microsoft.com", false},
+	}
+	for _, tt := range tests {
+		if got := fqdnMatches(tt.pattern, tt.destination); got != tt.want {
+			t.Errorf("fqdnMatches(%q, %q) = %v, want %v", tt.pattern, tt.destination, got, tt.want)
+		}
+	}
+	if !portMatches([]string{"80", "1000-2000"}, 1194) || portMatches([]string{"80", "x-y"}, 1194) || !portMatches([]string{"*"}, 1) {
+		t.Error("Unexpected port matching")
+	}
+}
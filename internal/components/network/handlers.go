@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/azureclient"
 	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/components/network/resourcehelpers"
@@ -490,3 +491,97 @@ func handlePrivateEndpointResource(client *azureclient.AzureClient, subID, rg, c
 	}
 	return handler.Handle(params, nil)
 }
+
+// =============================================================================
+// Network Plugin Migration Handlers
+// =============================================================================
+
+// GetNetworkMigrationAdvisorHandler returns a handler for the aks_network_migration_advisor command
+func GetNetworkMigrationAdvisorHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNetworkMigrationAdvisor(params, azcli.NewExecutor(), cfg)
+	})
+}
+
+// HandleNetworkMigrationAdvisor reports readiness for migrating the cluster network plugin
+func HandleNetworkMigrationAdvisor(params map[string]interface{}, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	target := MigrationTargetOverlay
+	if value, ok := params["target"].(string); ok && value != "" {
+		if value != MigrationTargetOverlay && value != MigrationTargetPodSubnet {
+			return "", fmt.Errorf("invalid target parameter, must be one of: %s, %s", MigrationTargetOverlay, MigrationTargetPodSubnet)
+		}
+		target = value
+	}
+	podCIDR, _ := params["pod_cidr"].(string)
+
+	run := func(command string) (string, error) {
+		return executor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+
+	output, err := run(fmt.Sprintf("az aks show --subscription %s --resource-group %s --name %s --output json", subID, rg, clusterName))
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster details: %w", err)
+	}
+	state, err := ParseClusterNetworkState(output)
+	if err != nil {
+		return "", err
+	}
+
+	var notes []string
+	vnetPrefixes, err := getClusterVNetPrefixes(run, subID, state)
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("could not read the cluster VNet address space, VNet overlap was not checked: %v", err))
+	}
+
+	report := AnalyzeNetworkMigration(state, target, podCIDR, vnetPrefixes)
+	report.ClusterName = clusterName
+	report.Notes = append(report.Notes, notes...)
+	if report.Ready && target == MigrationTargetOverlay {
+		if cfg.AccessLevel == "readwrite" || cfg.AccessLevel == "admin" {
+			report.Command = BuildOverlayMigrationCommand(subID, rg, clusterName, report.PodCIDR)
+		} else {
+			report.Notes = append(report.Notes, "the migration command is only generated for readwrite or admin access levels")
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal migration report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// getClusterVNetPrefixes returns the address prefixes of the custom VNet, or of the managed VNet in the node resource group
+func getClusterVNetPrefixes(run func(string) (string, error), subscriptionID string, state ClusterNetworkState) ([]string, error) {
+	vnetID := state.CustomVNetID()
+	if vnetID == "" {
+		if state.NodeResourceGroup == "" {
+			return nil, fmt.Errorf("cluster has no node resource group")
+		}
+		output, err := run(fmt.Sprintf("az resource list --subscription %s --resource-group %s --resource-type Microsoft.Network/virtualNetworks --query [].id --output json",
+			subscriptionID, state.NodeResourceGroup))
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		if err := json.Unmarshal([]byte(output), &ids); err != nil || len(ids) == 0 {
+			return nil, fmt.Errorf("no virtual network found in node resource group %s", state.NodeResourceGroup)
+		}
+		vnetID = ids[0]
+	}
+
+	output, err := run(fmt.Sprintf("az resource show --ids %s --query properties.addressSpace.addressPrefixes --output json", vnetID))
+	if err != nil {
+		return nil, err
+	}
+	var prefixes []string
+	if err := json.Unmarshal([]byte(output), &prefixes); err != nil {
+		return nil, fmt.Errorf("failed to parse VNet address prefixes: %w", err)
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,897 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// Ingress controllers detected by the ingress health report
+const (
+	ControllerIngressNginx = "ingress-nginx"
+	ControllerAGIC         = "agic"
+	ControllerALB          = "alb"
+	ControllerTraefik      = "traefik"
+	ControllerEnvoy        = "envoy"
+)
+
+// API versions used by the ingress health report
+const (
+	ingressClusterAPIVersion = "2024-05-01"
+	ingressMetricsAPIVersion = "2018-01-01"
+)
+
+// Log and metric bounds of the ingress health report
+const (
+	defaultIngressLogLines = 2000
+	maxIngressLogLines     = 20000
+	maxLogPods             = 3
+	maxReloadErrorSamples  = 5
+	maxErrorBackends       = 5
+	probeMetricWindow      = 30 * time.Minute
+	// minRequestsForRate is the number of sampled requests below which a 502/504 rate is not reported as a finding
+	minRequestsForRate = 20
+	// upstreamErrorRateThreshold is the 502/504 percentage of sampled requests reported as a finding
+	upstreamErrorRateThreshold = 1.0
+)
+
+// Azure annotations of LoadBalancer services
+const (
+	internalLBAnnotation       = "service.beta.kubernetes.io/azure-load-balancer-internal"
+	probeRequestPathAnnotation = "service.beta.kubernetes.io/azure-load-balancer-health-probe-request-path"
+)
+
+// ingressControllerKind describes how a kind of ingress controller is recognized and what its logs contain
+type ingressControllerKind struct {
+	name string
+	// image matches the container images of the controller pods
+	image *regexp.Regexp
+	// class matches the spec.controller of the IngressClasses the controller serves
+	class *regexp.Regexp
+	// reloadError matches log lines reporting that configuration could not be applied
+	reloadError *regexp.Regexp
+	// dataPlaneInCluster is set when the controller pods proxy the traffic, so their access logs carry
+	// response statuses; AGIC and ALB program Azure-managed data planes instead
+	dataPlaneInCluster bool
+}
+
+// ingressControllerKinds lists the supported controllers. Envoy comes last because Contour pods run both
+// the contour and envoy images.
+var ingressControllerKinds = []ingressControllerKind{
+	{
+		name:               ControllerIngressNginx,
+		image:              regexp.MustCompile(`(?i)ingress-nginx/controller|nginx-ingress-controller`),
+		class:              regexp.MustCompile(`(?i)nginx`),
+		reloadError:        regexp.MustCompile(`(?i)error reloading nginx|unexpected failure reloading the backend|\[emerg\]`),
+		dataPlaneInCluster: true,
+	},
+	{
+		name:        ControllerAGIC,
+		image:       regexp.MustCompile(`(?i)application-gateway.*kubernetes-ingress`),
+		class:       regexp.MustCompile(`(?i)application-gateway`),
+		reloadError: regexp.MustCompile(`(?i)(error|fail)\w*.*(app gwy|application gateway|appgw)|(app gwy|application gateway|appgw).*(error|fail)`),
+	},
+	{
+		name:        ControllerALB,
+		image:       regexp.MustCompile(`(?i)alb-controller`),
+		class:       regexp.MustCompile(`(?i)alb\.networking\.azure\.io`),
+		reloadError: regexp.MustCompile(`(?i)"level":"error"|level=error`),
+	},
+	{
+		name:               ControllerTraefik,
+		image:              regexp.MustCompile(`(?i)(^|/)traefik(:|@|$)`),
+		class:              regexp.MustCompile(`(?i)traefik`),
+		reloadError:        regexp.MustCompile(`(?i)level=error|"level":"error"| ERR `),
+		dataPlaneInCluster: true,
+	},
+	{
+		name:               ControllerEnvoy,
+		image:              regexp.MustCompile(`(?i)envoyproxy/envoy`),
+		class:              regexp.MustCompile(`(?i)contour|envoy`),
+		reloadError:        regexp.MustCompile(`(?i)config for \S+ rejected|error adding/updating listener`),
+		dataPlaneInCluster: true,
+	},
+}
+
+var (
+	// accessLogPattern matches the request and status of access log lines in the ingress-nginx, Traefik (common
+	// log format) and Envoy default formats; the optional group is the upstream name of ingress-nginx
+	accessLogPattern = regexp.MustCompile(`"[A-Z]+ [^"]* HTTP/[0-9.]+" (\d{3}) (?:.*? \[([^\]]*)\] \[[^\]]*\] )?`)
+)
+
+// ControllerPod is the health of one ingress controller pod
+type ControllerPod struct {
+	Name     string `json:"name"`
+	Node     string `json:"node,omitempty"`
+	Phase    string `json:"phase"`
+	Ready    bool   `json:"ready"`
+	Restarts int    `json:"restarts"`
+	// Reason is the current waiting reason, or the reason of the last termination
+	Reason string `json:"reason,omitempty"`
+}
+
+// BackendErrors counts the requests and 502/504 responses of one ingress-nginx upstream
+type BackendErrors struct {
+	Backend  string `json:"backend"`
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+}
+
+// RequestStats counts the response statuses in the sampled access log lines of a controller
+type RequestStats struct {
+	Sampled   int `json:"sampled"`
+	Status5xx int `json:"status5xx"`
+	Status502 int `json:"status502"`
+	Status503 int `json:"status503"`
+	Status504 int `json:"status504"`
+	// UpstreamErrorRate is the percentage of sampled requests answered with 502 or 504
+	UpstreamErrorRate float64         `json:"upstreamErrorRate"`
+	Backends          []BackendErrors `json:"backends,omitempty"`
+	backends          map[string]*BackendErrors
+}
+
+// ProbeSample is the latest health probe availability of one backend port of a load balancer frontend
+type ProbeSample struct {
+	BackendPort  string  `json:"backendPort"`
+	Availability float64 `json:"availability"`
+}
+
+// LoadBalancerHealth is a LoadBalancer service exposing the controller and the status of its Azure health probes
+type LoadBalancerHealth struct {
+	Service               string        `json:"service"`
+	Address               string        `json:"address,omitempty"`
+	Internal              bool          `json:"internal"`
+	ExternalTrafficPolicy string        `json:"externalTrafficPolicy,omitempty"`
+	ProbeRequestPath      string        `json:"probeRequestPath,omitempty"`
+	Probes                []ProbeSample `json:"probes,omitempty"`
+	ProbeError            string        `json:"probeError,omitempty"`
+	// httpProbeWithoutPath is set when a port uses an HTTP app protocol and no probe request path is annotated
+	httpProbeWithoutPath bool
+}
+
+// BackendPoolHealth is the latest healthy and unhealthy host count of an Application Gateway backend
+type BackendPoolHealth struct {
+	BackendSettingsPool string  `json:"backendSettingsPool"`
+	Healthy             float64 `json:"healthy"`
+	Unhealthy           float64 `json:"unhealthy"`
+}
+
+// AppGatewayHealth is the backend health of the Application Gateway programmed by AGIC
+type AppGatewayHealth struct {
+	ID       string              `json:"id"`
+	Backends []BackendPoolHealth `json:"backends"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// IngressControllerHealth is the health of the ingress controller pods of one kind in one namespace
+type IngressControllerHealth struct {
+	Controller         string               `json:"controller"`
+	Namespace          string               `json:"namespace"`
+	Image              string               `json:"image"`
+	Pods               []ControllerPod      `json:"pods"`
+	IngressClasses     []string             `json:"ingressClasses,omitempty"`
+	Ingresses          int                  `json:"ingresses"`
+	LogsRead           int                  `json:"logsRead"`
+	ReloadErrors       int                  `json:"reloadErrors"`
+	ReloadErrorSamples []string             `json:"reloadErrorSamples,omitempty"`
+	Requests           *RequestStats        `json:"requests,omitempty"`
+	LoadBalancers      []LoadBalancerHealth `json:"loadBalancers,omitempty"`
+	AppGateway         *AppGatewayHealth    `json:"appGateway,omitempty"`
+	labels             []map[string]string
+}
+
+// IngressReport is the result of the aks_ingress_health tool
+type IngressReport struct {
+	ClusterName string                    `json:"clusterName"`
+	LogLines    int                       `json:"logLines"`
+	Controllers []IngressControllerHealth `json:"controllers"`
+	Findings    []string                  `json:"findings"`
+	Notes       []string                  `json:"notes,omitempty"`
+}
+
+// ingressCluster is the part of the managed cluster the report needs
+type ingressCluster struct {
+	Properties struct {
+		NodeResourceGroup string `json:"nodeResourceGroup"`
+		AddonProfiles     map[string]struct {
+			Enabled bool              `json:"enabled"`
+			Config  map[string]string `json:"config"`
+		} `json:"addonProfiles"`
+	} `json:"properties"`
+}
+
+type ingressPod struct {
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Image string `json:"image"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			Ready        bool `json:"ready"`
+			RestartCount int  `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+			} `json:"state"`
+			LastState struct {
+				Terminated *struct {
+					Reason string `json:"reason"`
+				} `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type ingressService struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Type                  string            `json:"type"`
+		Selector              map[string]string `json:"selector"`
+		ExternalTrafficPolicy string            `json:"externalTrafficPolicy"`
+		Ports                 []struct {
+			AppProtocol string `json:"appProtocol"`
+		} `json:"ports"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				IP string `json:"ip"`
+			} `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+type ingressClass struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Controller string `json:"controller"`
+	} `json:"spec"`
+}
+
+type ingressResource struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		IngressClassName string `json:"ingressClassName"`
+	} `json:"spec"`
+}
+
+// GetIngressHealthHandler returns a handler for the aks_ingress_health command
+func GetIngressHealthHandler(api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleIngressHealth(params, api, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleIngressHealth detects the ingress controllers of a cluster and reports the health of their pods, the
+// configuration reload errors and 502/504 rates in their logs, and the Azure health probe status of the load
+// balancers (or the Application Gateway) in front of them
+func HandleIngressHealth(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	filter, _ := params["controller"].(string)
+	if filter != "" && findControllerKind(filter) == nil {
+		return "", fmt.Errorf("invalid controller parameter: %s", filter)
+	}
+	logLines := defaultIngressLogLines
+	if raw, ok := params["log_lines"]; ok && raw != nil && raw != "" {
+		value := fmt.Sprint(raw)
+		if logLines, err = strconv.Atoi(value); err != nil || logLines <= 0 || logLines > maxIngressLogLines {
+			return "", fmt.Errorf("invalid log_lines parameter: %s (expected 1 to %d)", value, maxIngressLogLines)
+		}
+	}
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+ingressClusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+	var cluster ingressCluster
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+
+	var pods []ingressPod
+	var services []ingressService
+	var ingresses []ingressResource
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		for _, list := range []struct {
+			resource string
+			decode   func(string) error
+		}{
+			{"pods", func(output string) error { return decodeItems(output, &pods) }},
+			{"services", func(output string) error { return decodeItems(output, &services) }},
+			{"ingresses", func(output string) error { return decodeItems(output, &ingresses) }},
+		} {
+			output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get " + list.resource + " " + flag + " -o json"}, cfg)
+			if err != nil {
+				return "", fmt.Errorf("failed to list %s: %v", list.resource, err)
+			}
+			if err := list.decode(output); err != nil {
+				return "", err
+			}
+		}
+	}
+	var classes []ingressClass
+	report := IngressReport{ClusterName: clusterName, LogLines: logLines, Controllers: []IngressControllerHealth{}}
+	if output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get ingressclasses -o json"}, cfg); err != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("could not list ingress classes: %v", err))
+	} else if err := decodeItems(output, &classes); err != nil {
+		return "", err
+	}
+
+	report.Controllers = detectIngressControllers(pods, filter)
+	unserved := assignIngresses(report.Controllers, classes, ingresses)
+	if cfg.AllowNamespaces != "" {
+		report.Notes = append(report.Notes, "only the allowed namespaces were searched, so controllers in other namespaces are not reported")
+		unserved = nil
+	} else if filter != "" {
+		unserved = nil
+	}
+
+	for i := range report.Controllers {
+		controller := &report.Controllers[i]
+		readControllerLogs(controller, kubectlExecutor, logLines, cfg, &report)
+		controller.LoadBalancers = matchLoadBalancers(controller, services)
+		for j := range controller.LoadBalancers {
+			readProbeStatus(ctx, api, subID, cluster.Properties.NodeResourceGroup, &controller.LoadBalancers[j])
+		}
+		if controller.Controller == ControllerAGIC {
+			controller.AppGateway = readAppGatewayHealth(ctx, api, cluster, &report)
+		}
+		if controller.Controller == ControllerALB {
+			report.Notes = append(report.Notes, "Application Gateway for Containers proxies the traffic of the ALB controller, so "+
+				"its 502/504 rates and backend health are in its own metrics and access logs")
+		}
+	}
+
+	report.Findings = BuildIngressFindings(report, unserved)
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ingress report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// findControllerKind returns the controller kind with the given name
+func findControllerKind(name string) *ingressControllerKind {
+	for i := range ingressControllerKinds {
+		if ingressControllerKinds[i].name == name {
+			return &ingressControllerKinds[i]
+		}
+	}
+	return nil
+}
+
+// detectIngressControllers groups the pods running an ingress controller image by controller and namespace.
+// A non-empty filter keeps only that controller.
+func detectIngressControllers(pods []ingressPod, filter string) []IngressControllerHealth {
+	var controllers []IngressControllerHealth
+	index := map[string]int{}
+	for _, pod := range pods {
+		kind, image := podControllerKind(pod)
+		if kind == "" || (filter != "" && kind != filter) {
+			continue
+		}
+		key := kind + "/" + pod.Metadata.Namespace
+		i, ok := index[key]
+		if !ok {
+			i = len(controllers)
+			index[key] = i
+			controllers = append(controllers, IngressControllerHealth{Controller: kind, Namespace: pod.Metadata.Namespace, Image: image})
+		}
+		controllers[i].Pods = append(controllers[i].Pods, podHealth(pod))
+		controllers[i].labels = append(controllers[i].labels, pod.Metadata.Labels)
+	}
+	for i := range controllers {
+		sort.Slice(controllers[i].Pods, func(a, b int) bool { return controllers[i].Pods[a].Name < controllers[i].Pods[b].Name })
+	}
+	sort.SliceStable(controllers, func(i, j int) bool {
+		if controllers[i].Controller != controllers[j].Controller {
+			return controllers[i].Controller < controllers[j].Controller
+		}
+		return controllers[i].Namespace < controllers[j].Namespace
+	})
+	return controllers
+}
+
+// podControllerKind returns the controller kind and image of the first container running a controller image
+func podControllerKind(pod ingressPod) (string, string) {
+	for _, kind := range ingressControllerKinds {
+		for _, container := range pod.Spec.Containers {
+			if kind.image.MatchString(container.Image) {
+				return kind.name, container.Image
+			}
+		}
+	}
+	return "", ""
+}
+
+// podHealth summarizes the readiness, restarts and failure reason of a pod
+func podHealth(pod ingressPod) ControllerPod {
+	health := ControllerPod{Name: pod.Metadata.Name, Node: pod.Spec.NodeName, Phase: pod.Status.Phase,
+		Ready: len(pod.Status.ContainerStatuses) > 0}
+	for _, status := range pod.Status.ContainerStatuses {
+		health.Ready = health.Ready && status.Ready
+		health.Restarts += status.RestartCount
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+			health.Reason = status.State.Waiting.Reason
+		case health.Reason == "" && status.LastState.Terminated != nil:
+			health.Reason = status.LastState.Terminated.Reason
+		}
+	}
+	return health
+}
+
+// assignIngresses records the IngressClasses and Ingress count of each controller. It returns the number of
+// ingresses per class that no detected controller serves.
+func assignIngresses(controllers []IngressControllerHealth, classes []ingressClass, ingresses []ingressResource) map[string]int {
+	classKinds := map[string]string{}
+	defaultClass := ""
+	for _, class := range classes {
+		kind := classControllerKind(class.Spec.Controller)
+		classKinds[class.Metadata.Name] = kind
+		if class.Metadata.Annotations["ingressclass.kubernetes.io/is-default-class"] == "true" {
+			defaultClass = class.Metadata.Name
+		}
+		for i := range controllers {
+			if controllers[i].Controller == kind {
+				controllers[i].IngressClasses = append(controllers[i].IngressClasses, class.Metadata.Name)
+			}
+		}
+	}
+
+	unserved := map[string]int{}
+	for _, ingress := range ingresses {
+		class := ingress.Spec.IngressClassName
+		if class == "" {
+			class = ingress.Metadata.Annotations["kubernetes.io/ingress.class"]
+		}
+		if class == "" {
+			class = defaultClass
+		}
+		if class == "" {
+			unserved["(no class)"]++
+			continue
+		}
+		kind, ok := classKinds[class]
+		if !ok {
+			// Classes set with the legacy annotation may have no IngressClass
+			kind = classControllerKind(class)
+		}
+		served := false
+		for i := range controllers {
+			if controllers[i].Controller == kind {
+				// Ingresses of a kind are attributed to its first controller; several installs of the same
+				// controller are told apart by their class names, which the pods do not expose
+				if !served {
+					controllers[i].Ingresses++
+				}
+				served = true
+			}
+		}
+		if !served {
+			unserved[class]++
+		}
+	}
+	return unserved
+}
+
+// classControllerKind returns the controller kind serving an IngressClass controller name
+func classControllerKind(controller string) string {
+	for _, kind := range ingressControllerKinds {
+		if kind.class.MatchString(controller) {
+			return kind.name
+		}
+	}
+	return ""
+}
+
+// readControllerLogs reads the recent logs of up to maxLogPods controller pods and counts their reload
+// errors and response statuses
+func readControllerLogs(controller *IngressControllerHealth, kubectlExecutor tools.CommandExecutor, logLines int, cfg *config.ConfigData, report *IngressReport) {
+	kind := findControllerKind(controller.Controller)
+	if kind.dataPlaneInCluster {
+		controller.Requests = &RequestStats{}
+	}
+	for i, pod := range controller.Pods {
+		if i == maxLogPods {
+			report.Notes = append(report.Notes, fmt.Sprintf("only the logs of %d of the %d %s pods in %s were read",
+				maxLogPods, len(controller.Pods), controller.Controller, controller.Namespace))
+			break
+		}
+		output, err := kubectlExecutor.Execute(map[string]interface{}{
+			"command": fmt.Sprintf("logs %s --namespace %s --all-containers --tail %d", pod.Name, controller.Namespace, logLines),
+		}, cfg)
+		if err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("could not read the logs of %s/%s: %v", controller.Namespace, pod.Name, err))
+			continue
+		}
+		controller.LogsRead++
+		scanControllerLogs(*kind, output, controller)
+	}
+	if controller.Requests != nil {
+		controller.Requests.finish()
+	}
+}
+
+// scanControllerLogs counts the configuration reload errors and access log response statuses of controller logs
+func scanControllerLogs(kind ingressControllerKind, logs string, controller *IngressControllerHealth) {
+	for _, line := range strings.Split(logs, "\n") {
+		if match := accessLogPattern.FindStringSubmatch(line); match != nil {
+			if controller.Requests != nil {
+				controller.Requests.add(match[1], match[2])
+			}
+			continue
+		}
+		if kind.reloadError.MatchString(line) {
+			controller.ReloadErrors++
+			if len(controller.ReloadErrorSamples) < maxReloadErrorSamples {
+				controller.ReloadErrorSamples = append(controller.ReloadErrorSamples, truncateLine(strings.TrimSpace(line), 300))
+			}
+		}
+	}
+}
+
+// add counts one access log response
+func (s *RequestStats) add(status, backend string) {
+	code, _ := strconv.Atoi(status)
+	s.Sampled++
+	if code >= 500 {
+		s.Status5xx++
+	}
+	switch code {
+	case http.StatusBadGateway:
+		s.Status502++
+	case http.StatusServiceUnavailable:
+		s.Status503++
+	case http.StatusGatewayTimeout:
+		s.Status504++
+	}
+	if backend == "" || backend == "-" {
+		return
+	}
+	if s.backends == nil {
+		s.backends = map[string]*BackendErrors{}
+	}
+	b := s.backends[backend]
+	if b == nil {
+		b = &BackendErrors{Backend: backend}
+		s.backends[backend] = b
+	}
+	b.Requests++
+	if code == http.StatusBadGateway || code == http.StatusGatewayTimeout {
+		b.Errors++
+	}
+}
+
+// finish computes the 502/504 rate and keeps the backends with the most errors
+func (s *RequestStats) finish() {
+	if s.Sampled > 0 {
+		s.UpstreamErrorRate = math.Round(float64(s.Status502+s.Status504)*1000/float64(s.Sampled)) / 10
+	}
+	s.Backends = nil
+	for _, b := range s.backends {
+		if b.Errors > 0 {
+			s.Backends = append(s.Backends, *b)
+		}
+	}
+	sort.Slice(s.Backends, func(i, j int) bool {
+		if s.Backends[i].Errors != s.Backends[j].Errors {
+			return s.Backends[i].Errors > s.Backends[j].Errors
+		}
+		return s.Backends[i].Backend < s.Backends[j].Backend
+	})
+	if len(s.Backends) > maxErrorBackends {
+		s.Backends = s.Backends[:maxErrorBackends]
+	}
+}
+
+// matchLoadBalancers returns the LoadBalancer services in the controller namespace selecting its pods
+func matchLoadBalancers(controller *IngressControllerHealth, services []ingressService) []LoadBalancerHealth {
+	var balancers []LoadBalancerHealth
+	for _, svc := range services {
+		if svc.Spec.Type != "LoadBalancer" || svc.Metadata.Namespace != controller.Namespace || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selected := false
+		for _, labels := range controller.labels {
+			if selectorMatches(svc.Spec.Selector, labels) {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			continue
+		}
+		lb := LoadBalancerHealth{
+			Service:               svc.Metadata.Namespace + "/" + svc.Metadata.Name,
+			Internal:              svc.Metadata.Annotations[internalLBAnnotation] == "true",
+			ExternalTrafficPolicy: svc.Spec.ExternalTrafficPolicy,
+			ProbeRequestPath:      svc.Metadata.Annotations[probeRequestPathAnnotation],
+		}
+		if len(svc.Status.LoadBalancer.Ingress) > 0 {
+			lb.Address = svc.Status.LoadBalancer.Ingress[0].IP
+		}
+		for _, port := range svc.Spec.Ports {
+			if lb.ProbeRequestPath == "" && (strings.EqualFold(port.AppProtocol, "http") || strings.EqualFold(port.AppProtocol, "https")) {
+				lb.httpProbeWithoutPath = true
+			}
+		}
+		balancers = append(balancers, lb)
+	}
+	return balancers
+}
+
+// selectorMatches reports whether every selector label is set on the pod
+func selectorMatches(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// readProbeStatus reads the latest health probe availability (DipAvailability) of the load balancer frontend
+// of a service. AKS names its load balancers kubernetes and kubernetes-internal in the node resource group.
+func readProbeStatus(ctx context.Context, api common.ARMCaller, subID, nodeResourceGroup string, lb *LoadBalancerHealth) {
+	if lb.Address == "" || nodeResourceGroup == "" {
+		return
+	}
+	name := "kubernetes"
+	if lb.Internal {
+		name = "kubernetes-internal"
+	}
+	lbID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/loadBalancers/%s", subID, nodeResourceGroup, name)
+	end := time.Now().UTC()
+	path := fmt.Sprintf("%s/providers/Microsoft.Insights/metrics?api-version=%s&metricnames=DipAvailability&aggregation=Average&interval=PT5M&timespan=%s&$filter=%s",
+		lbID, ingressMetricsAPIVersion, url.QueryEscape(end.Add(-probeMetricWindow).Format(time.RFC3339)+"/"+end.Format(time.RFC3339)),
+		url.QueryEscape(fmt.Sprintf("FrontendIPAddress eq '%s' and BackendPort eq '*'", lb.Address)))
+	body, err := api.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		lb.ProbeError = fmt.Sprintf("failed to read the health probe status of load balancer %s: %v", name, err)
+		return
+	}
+	latest, err := ParseLatestMetrics(body, "BackendPort")
+	if err != nil {
+		lb.ProbeError = err.Error()
+		return
+	}
+	for port, availability := range latest["DipAvailability"] {
+		lb.Probes = append(lb.Probes, ProbeSample{BackendPort: port, Availability: availability})
+	}
+	sort.Slice(lb.Probes, func(i, j int) bool { return lb.Probes[i].BackendPort < lb.Probes[j].BackendPort })
+	if len(lb.Probes) == 0 {
+		lb.ProbeError = fmt.Sprintf("load balancer %s reported no health probe samples for %s in the last %d minutes",
+			name, lb.Address, int(probeMetricWindow.Minutes()))
+	}
+}
+
+// readAppGatewayHealth reads the healthy and unhealthy host counts of the Application Gateway of the AGIC add-on
+func readAppGatewayHealth(ctx context.Context, api common.ARMCaller, cluster ingressCluster, report *IngressReport) *AppGatewayHealth {
+	addon, ok := cluster.Properties.AddonProfiles["ingressApplicationGateway"]
+	gatewayID := addon.Config["effectiveApplicationGatewayId"]
+	if !ok || !addon.Enabled || gatewayID == "" {
+		report.Notes = append(report.Notes, "AGIC is not installed with the ingressApplicationGateway add-on, so the Application Gateway "+
+			"it programs is unknown and its backend health was not read")
+		return nil
+	}
+	health := &AppGatewayHealth{ID: gatewayID, Backends: []BackendPoolHealth{}}
+	end := time.Now().UTC()
+	path := fmt.Sprintf("%s/providers/Microsoft.Insights/metrics?api-version=%s&metricnames=HealthyHostCount,UnhealthyHostCount&aggregation=Average&interval=PT5M&timespan=%s&$filter=%s",
+		gatewayID, ingressMetricsAPIVersion, url.QueryEscape(end.Add(-probeMetricWindow).Format(time.RFC3339)+"/"+end.Format(time.RFC3339)),
+		url.QueryEscape("BackendSettingsPool eq '*'"))
+	body, err := api.CallARM(ctx, http.MethodGet, path)
+	if err != nil {
+		health.Error = fmt.Sprintf("failed to read the backend health of %s: %v", resourceName(gatewayID), err)
+		return health
+	}
+	latest, err := ParseLatestMetrics(body, "BackendSettingsPool")
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	pools := map[string]bool{}
+	for _, metric := range []string{"HealthyHostCount", "UnhealthyHostCount"} {
+		for pool := range latest[metric] {
+			pools[pool] = true
+		}
+	}
+	for pool := range pools {
+		health.Backends = append(health.Backends, BackendPoolHealth{BackendSettingsPool: pool,
+			Healthy: latest["HealthyHostCount"][pool], Unhealthy: latest["UnhealthyHostCount"][pool]})
+	}
+	sort.Slice(health.Backends, func(i, j int) bool {
+		return health.Backends[i].BackendSettingsPool < health.Backends[j].BackendSettingsPool
+	})
+	return health
+}
+
+// ParseLatestMetrics returns the latest average of each metric time series, by metric name and the value of
+// the given dimension
+func ParseLatestMetrics(body []byte, dimension string) (map[string]map[string]float64, error) {
+	var result struct {
+		Value []struct {
+			Name struct {
+				Value string `json:"value"`
+			} `json:"name"`
+			Timeseries []struct {
+				Metadatavalues []struct {
+					Name struct {
+						Value string `json:"value"`
+					} `json:"name"`
+					Value string `json:"value"`
+				} `json:"metadatavalues"`
+				Data []struct {
+					Average *float64 `json:"average"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics response: %w", err)
+	}
+	latest := map[string]map[string]float64{}
+	for _, metric := range result.Value {
+		for _, ts := range metric.Timeseries {
+			key := ""
+			for _, md := range ts.Metadatavalues {
+				if strings.EqualFold(md.Name.Value, dimension) {
+					key = md.Value
+				}
+			}
+			for i := len(ts.Data) - 1; i >= 0; i-- {
+				if ts.Data[i].Average != nil {
+					if latest[metric.Name.Value] == nil {
+						latest[metric.Name.Value] = map[string]float64{}
+					}
+					latest[metric.Name.Value][key] = math.Round(*ts.Data[i].Average*10) / 10
+					break
+				}
+			}
+		}
+	}
+	return latest, nil
+}
+
+// BuildIngressFindings reports unhealthy controller pods, reload errors, high 502/504 rates, failing health
+// probes and ingresses that no detected controller serves
+func BuildIngressFindings(report IngressReport, unserved map[string]int) []string {
+	findings := []string{}
+	if len(report.Controllers) == 0 {
+		findings = append(findings, "no ingress controller pods (ingress-nginx, AGIC, ALB, Traefik or Envoy) were found")
+	}
+	for _, c := range report.Controllers {
+		name := fmt.Sprintf("%s in %s", c.Controller, c.Namespace)
+		ready := 0
+		for _, pod := range c.Pods {
+			if pod.Ready {
+				ready++
+			}
+			switch {
+			case !pod.Ready && pod.Reason != "":
+				findings = append(findings, fmt.Sprintf("%s pod %s is not ready (%s, %d restarts)", name, pod.Name, pod.Reason, pod.Restarts))
+			case !pod.Ready:
+				findings = append(findings, fmt.Sprintf("%s pod %s is not ready (phase %s)", name, pod.Name, pod.Phase))
+			case pod.Restarts > 0 && pod.Reason == "OOMKilled":
+				findings = append(findings, fmt.Sprintf("%s pod %s restarted %d times and was last OOMKilled; raise its memory limit", name, pod.Name, pod.Restarts))
+			}
+		}
+		if ready == 0 {
+			findings = append(findings, fmt.Sprintf("%s has no ready pods, so its %d ingresses receive no traffic", name, c.Ingresses))
+		}
+		if c.ReloadErrors > 0 {
+			findings = append(findings, fmt.Sprintf("%s logged %d configuration reload errors in the last %d lines; the latest configuration "+
+				"was not applied, check the ingress resources and annotations named in reloadErrorSamples", name, c.ReloadErrors, report.LogLines))
+		}
+		if r := c.Requests; r != nil && r.Sampled >= minRequestsForRate && r.UpstreamErrorRate >= upstreamErrorRateThreshold {
+			finding := fmt.Sprintf("%s answered %.1f%% of %d sampled requests with 502 or 504 (%d 502, %d 504): backends are failing or timing out",
+				name, r.UpstreamErrorRate, r.Sampled, r.Status502, r.Status504)
+			if len(r.Backends) > 0 {
+				finding += fmt.Sprintf("; most errors come from %s (%d of %d requests)", r.Backends[0].Backend, r.Backends[0].Errors, r.Backends[0].Requests)
+			}
+			findings = append(findings, finding)
+		}
+		for _, lb := range c.LoadBalancers {
+			findings = append(findings, loadBalancerFindings(c, lb)...)
+		}
+		if c.AppGateway != nil {
+			for _, pool := range c.AppGateway.Backends {
+				if pool.Unhealthy > 0 {
+					findings = append(findings, fmt.Sprintf("Application Gateway %s reports %.0f unhealthy and %.0f healthy hosts for %s; "+
+						"the gateway returns 502 for requests to a backend without healthy hosts", resourceName(c.AppGateway.ID), pool.Unhealthy, pool.Healthy, pool.BackendSettingsPool))
+				}
+			}
+		}
+	}
+
+	classes := make([]string, 0, len(unserved))
+	for class := range unserved {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		findings = append(findings, fmt.Sprintf("%d ingresses use class %s, but no controller serving it was found", unserved[class], class))
+	}
+	return findings
+}
+
+// loadBalancerFindings reports a pending load balancer address, failing health probes and the HTTP probe path
+// that ingress-nginx answers with 404
+func loadBalancerFindings(c IngressControllerHealth, lb LoadBalancerHealth) []string {
+	if lb.Address == "" {
+		return []string{fmt.Sprintf("service %s has no load balancer address yet; check the service events for Azure errors", lb.Service)}
+	}
+	var findings []string
+	for _, probe := range lb.Probes {
+		switch {
+		case probe.Availability == 0:
+			findings = append(findings, fmt.Sprintf("all health probes of %s (%s) port %s fail, so the load balancer sends it no traffic",
+				lb.Service, lb.Address, probe.BackendPort))
+		case probe.Availability < 100 && lb.ExternalTrafficPolicy != "Local":
+			findings = append(findings, fmt.Sprintf("health probes of %s (%s) port %s succeed on %.1f%% of nodes; with externalTrafficPolicy Cluster every node should pass",
+				lb.Service, lb.Address, probe.BackendPort, probe.Availability))
+		}
+	}
+	if c.Controller == ControllerIngressNginx && lb.httpProbeWithoutPath {
+		findings = append(findings, fmt.Sprintf("service %s uses an HTTP app protocol without the %s annotation; the load balancer probes / and "+
+			"ingress-nginx answers 404 there, so set the annotation to /healthz", lb.Service, probeRequestPathAnnotation))
+	}
+	return findings
+}
+
+// decodeItems appends the items of kubectl get -o json list output to items
+func decodeItems[T any](output string, items *[]T) error {
+	var list struct {
+		Items []T `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return fmt.Errorf("failed to parse kubectl output: %v", err)
+	}
+	*items = append(*items, list.Items...)
+	return nil
+}
+
+// truncateLine shortens a log line to at most n bytes
+func truncateLine(line string, n int) string {
+	if len(line) <= n {
+		return line
+	}
+	return line[:n] + "..."
+}
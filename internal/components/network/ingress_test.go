@@ -0,0 +1,157 @@
+package network
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const (
+	testNodeLB     = "/subscriptions/sub/resourceGroups/mc_rg_aks_eastus/providers/Microsoft.Network/loadBalancers/kubernetes"
+	testAppGateway = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationGateways/appgw"
+)
+
+const testNginxAccessLog = `10.244.0.1 - - [02/Jan/2025:03:04:05 +0000] "GET /cart HTTP/1.1" 502 150 "-" "curl/8.0" 76 0.001 [shop-cart-80] [] 10.244.1.5:80 0 0.001 502 abc
+10.244.0.1 - - [02/Jan/2025:03:04:06 +0000] "GET /cart HTTP/1.1" 504 150 "-" "curl/8.0" 76 60.0 [shop-cart-80] [] 10.244.1.5:80 0 60.0 504 def`
+
+func newIngressARM() *fakeARM {
+	return &fakeARM{bodies: map[string]string{
+		testClusterID: `{"properties": {"nodeResourceGroup": "mc_rg_aks_eastus", "addonProfiles": {
+			"ingressApplicationGateway": {"enabled": true, "config": {"effectiveApplicationGatewayId": "` + testAppGateway + `"}}}}}`,
+		testNodeLB + "/providers/Microsoft.Insights/metrics": `{"value": [{"name": {"value": "DipAvailability"}, "timeseries": [
+			{"metadatavalues": [{"name": {"value": "backendport"}, "value": "80"}], "data": [{"average": 100}, {"average": 50}, {}]},
+			{"metadatavalues": [{"name": {"value": "backendport"}, "value": "443"}], "data": [{"average": 100}]}]}]}`,
+		testAppGateway + "/providers/Microsoft.Insights/metrics": `{"value": [
+			{"name": {"value": "HealthyHostCount"}, "timeseries": [{"metadatavalues": [{"name": {"value": "BackendSettingsPool"}, "value": "pool-shop~settings"}], "data": [{"average": 1}]}]},
+			{"name": {"value": "UnhealthyHostCount"}, "timeseries": [{"metadatavalues": [{"name": {"value": "BackendSettingsPool"}, "value": "pool-shop~settings"}], "data": [{"average": 2}]}]}]}`,
+	}}
+}
+
+func newIngressKubectl(accessLog string) *fakeAzExecutor {
+	return &fakeAzExecutor{responses: map[string]string{
+		"get pods": `{"items": [
+			{"metadata": {"name": "ingress-nginx-controller-1", "namespace": "ingress-nginx", "labels": {"app.kubernetes.io/name": "ingress-nginx"}},
+			 "spec": {"nodeName": "aks-system-0", "containers": [{"image": "registry.k8s.io/ingress-nginx/controller:v1.10.0"}]},
+			 "status": {"phase": "Running", "containerStatuses": [{"ready": true, "restartCount": 0, "state": {}}]}},
+			{"metadata": {"name": "ingress-nginx-controller-2", "namespace": "ingress-nginx", "labels": {"app.kubernetes.io/name": "ingress-nginx"}},
+			 "spec": {"containers": [{"image": "registry.k8s.io/ingress-nginx/controller:v1.10.0"}]},
+			 "status": {"phase": "Running", "containerStatuses": [{"ready": false, "restartCount": 4, "state": {"waiting": {"reason": "CrashLoopBackOff"}}}]}},
+			{"metadata": {"name": "ingress-appgw-deployment-1", "namespace": "kube-system"},
+			 "spec": {"containers": [{"image": "mcr.microsoft.com/azure-application-gateway/kubernetes-ingress:1.7.2"}]},
+			 "status": {"phase": "Running", "containerStatuses": [{"ready": true, "restartCount": 1, "state": {}, "lastState": {"terminated": {"reason": "OOMKilled"}}}]}},
+			{"metadata": {"name": "web-1", "namespace": "shop"}, "spec": {"containers": [{"image": "nginx:1.25"}]}, "status": {"phase": "Running"}}]}`,
+		"get services": `{"items": [
+			{"metadata": {"name": "ingress-nginx-controller", "namespace": "ingress-nginx"},
+			 "spec": {"type": "LoadBalancer", "selector": {"app.kubernetes.io/name": "ingress-nginx"}, "externalTrafficPolicy": "Cluster",
+			          "ports": [{"port": 80, "appProtocol": "http"}, {"port": 443, "appProtocol": "https"}]},
+			 "status": {"loadBalancer": {"ingress": [{"ip": "20.1.2.3"}]}}},
+			{"metadata": {"name": "pending", "namespace": "shop"}, "spec": {"type": "LoadBalancer", "selector": {"app": "web"}}}]}`,
+		"get ingresses": `{"items": [
+			{"metadata": {"name": "cart"}, "spec": {"ingressClassName": "nginx"}},
+			{"metadata": {"name": "legacy", "annotations": {"kubernetes.io/ingress.class": "azure/application-gateway"}}, "spec": {}},
+			{"metadata": {"name": "orphan"}, "spec": {"ingressClassName": "istio"}}]}`,
+		"get ingressclasses": `{"items": [
+			{"metadata": {"name": "nginx"}, "spec": {"controller": "k8s.io/ingress-nginx"}},
+			{"metadata": {"name": "istio"}, "spec": {"controller": "istio.io/ingress-controller"}}]}`,
+		"logs ingress-nginx-controller-1": accessLog + "\nE0102 03:04:07.000000 7 controller.go:210] Unexpected failure reloading the backend:\n",
+		"logs ingress-nginx-controller-2": "",
+		"logs ingress-appgw-deployment-1": "I0102 BackendPool ready\n",
+	}}
+}
+
+func runIngressHealth(t *testing.T, params map[string]interface{}, api *fakeARM, kubectl *fakeAzExecutor) IngressReport {
+	t.Helper()
+	output, err := HandleIngressHealth(params, api, kubectl, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report IngressReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestIngressHealth tests controller detection, log scanning, health probes and the Application Gateway backends
+func TestIngressHealth(t *testing.T) {
+	report := runIngressHealth(t, testEgressParams(), newIngressARM(), newIngressKubectl(testNginxAccessLog))
+	if len(report.Controllers) != 2 {
+		t.Fatalf("Expected AGIC and ingress-nginx, got %+v", report.Controllers)
+	}
+	agic, nginx := report.Controllers[0], report.Controllers[1]
+	if agic.Controller != ControllerAGIC || agic.Ingresses != 1 || agic.Requests != nil {
+		t.Errorf("Expected AGIC serving the legacy ingress without request stats, got %+v", agic)
+	}
+	if nginx.Controller != ControllerIngressNginx || len(nginx.Pods) != 2 || nginx.Ingresses != 1 || nginx.LogsRead != 2 {
+		t.Fatalf("Unexpected ingress-nginx health %+v", nginx)
+	}
+	if nginx.ReloadErrors != 1 || nginx.Requests.Sampled != 2 || nginx.Requests.Status502 != 1 || nginx.Requests.UpstreamErrorRate != 100 ||
+		len(nginx.Requests.Backends) != 1 || nginx.Requests.Backends[0].Backend != "shop-cart-80" {
+		t.Errorf("Unexpected log scan %+v %+v", nginx, nginx.Requests)
+	}
+	if len(nginx.LoadBalancers) != 1 || len(nginx.LoadBalancers[0].Probes) != 2 || nginx.LoadBalancers[0].Probes[1].Availability != 50 {
+		t.Fatalf("Expected the latest probe availability per port, got %+v", nginx.LoadBalancers)
+	}
+	if agic.AppGateway == nil || len(agic.AppGateway.Backends) != 1 || agic.AppGateway.Backends[0].Unhealthy != 2 {
+		t.Errorf("Expected the Application Gateway backend health, got %+v", agic.AppGateway)
+	}
+
+	findings := strings.Join(report.Findings, "\n")
+	for _, want := range []string{
+		"pod ingress-appgw-deployment-1 restarted 1 times and was last OOMKilled",
+		"pod ingress-nginx-controller-2 is not ready (CrashLoopBackOff, 4 restarts)",
+		"logged 1 configuration reload errors",
+		"port 80 succeed on 50.0% of nodes",
+		"set the annotation to /healthz",
+		"reports 2 unhealthy and 1 healthy hosts for pool-shop~settings",
+		"1 ingresses use class istio, but no controller serving it was found",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Expected finding %q, got:\n%s", want, findings)
+		}
+	}
+	if strings.Contains(findings, "502 or 504") {
+		t.Errorf("Expected no rate finding below %d sampled requests, got:\n%s", minRequestsForRate, findings)
+	}
+}
+
+// TestIngressHealthFilter tests the controller filter and parameter validation
+func TestIngressHealthFilter(t *testing.T) {
+	params := testEgressParams()
+	params["controller"] = ControllerTraefik
+	report := runIngressHealth(t, params, newIngressARM(), newIngressKubectl(""))
+	if len(report.Controllers) != 0 || len(report.Findings) != 1 || !strings.Contains(report.Findings[0], "no ingress controller pods") {
+		t.Errorf("Expected no Traefik controller and no unserved ingress findings, got %+v", report)
+	}
+
+	params["controller"] = "haproxy"
+	if _, err := HandleIngressHealth(params, newIngressARM(), newIngressKubectl(""), config.NewConfig()); err == nil {
+		t.Error("Expected an unknown controller to be rejected")
+	}
+	params = testEgressParams()
+	params["log_lines"] = 50000.0
+	if _, err := HandleIngressHealth(params, newIngressARM(), newIngressKubectl(""), config.NewConfig()); err == nil {
+		t.Error("Expected log_lines above the maximum to be rejected")
+	}
+}
+
+func TestAccessLogPattern(t *testing.T) {
+	tests := []struct {
+		line, status, backend string
+	}{
+		{`10.0.0.1 - - [02/Jan/2025:03:04:05 +0000] "GET / HTTP/1.1" 200 5 "-" "curl" 70 0.002 [default-web-80] [] 10.244.0.9:80 5 0.002 200 id`, "200", "default-web-80"},
+		{`10.0.0.1 - - [02/Jan/2025:03:04:05 +0000] "GET / HTTP/2.0" 504 11 "-" "-" 5 "web-router@kubernetes" "http://10.0.0.1:80" 3ms`, "504", ""},
+		{`[2025-01-02T03:04:05.000Z] "POST /api HTTP/1.1" 503 UH 0 91 0 - "-" "curl" "id" "shop.example.com" "-"`, "503", ""},
+	}
+	for _, tt := range tests {
+		match := accessLogPattern.FindStringSubmatch(tt.line)
+		if match == nil || match[1] != tt.status || match[2] != tt.backend {
+			t.Errorf("accessLogPattern(%q) = %q, want status %s and backend %q", tt.line, match, tt.status, tt.backend)
+		}
+	}
+	if accessLogPattern.MatchString("level=error msg=\"Cannot create service\"") {
+		t.Error("Expected non-access log lines not to match")
+	}
+}
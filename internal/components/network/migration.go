@@ -0,0 +1,349 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Migration targets supported by the network plugin migration advisor
+const (
+	MigrationTargetOverlay   = "overlay"
+	MigrationTargetPodSubnet = "podsubnet"
+)
+
+// Migration check statuses
+const (
+	CheckPass    = "pass"
+	CheckWarning = "warning"
+	CheckBlocker = "blocker"
+)
+
+// defaultOverlayPodCIDR is the pod CIDR AKS assigns to overlay clusters when none is given
+const defaultOverlayPodCIDR = "10.244.0.0/16"
+
+// overlayNodePrefixLength is the size of the pod CIDR block allocated to each node in overlay mode
+const overlayNodePrefixLength = 24
+
+// minOverlayMigrationVersion is the minimum Kubernetes version supporting in-place overlay migration
+const minOverlayMigrationVersion = "1.22"
+
+// NodePoolNetwork is the networking configuration of a node pool
+type NodePoolNetwork struct {
+	Name         string `json:"name"`
+	OSType       string `json:"osType"`
+	Count        int    `json:"count"`
+	MaxCount     int    `json:"maxCount,omitempty"`
+	MaxPods      int    `json:"maxPods"`
+	VnetSubnetID string `json:"vnetSubnetId,omitempty"`
+	PodSubnetID  string `json:"podSubnetId,omitempty"`
+}
+
+// ClusterNetworkState is the cluster networking configuration relevant to plugin migration
+type ClusterNetworkState struct {
+	KubernetesVersion string            `json:"kubernetesVersion"`
+	NetworkPlugin     string            `json:"networkPlugin"`
+	NetworkPluginMode string            `json:"networkPluginMode,omitempty"`
+	NetworkPolicy     string            `json:"networkPolicy,omitempty"`
+	NetworkDataplane  string            `json:"networkDataplane,omitempty"`
+	PodCIDR           string            `json:"podCidr,omitempty"`
+	ServiceCIDR       string            `json:"serviceCidr,omitempty"`
+	NodeResourceGroup string            `json:"nodeResourceGroup"`
+	NodePools         []NodePoolNetwork `json:"nodePools"`
+}
+
+// MigrationCheck is a single readiness check
+type MigrationCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// MigrationReport is the result returned by the network plugin migration advisor
+type MigrationReport struct {
+	ClusterName string              `json:"clusterName"`
+	Current     ClusterNetworkState `json:"current"`
+	Target      string              `json:"target"`
+	PodCIDR     string              `json:"podCidr,omitempty"`
+	Ready       bool                `json:"ready"`
+	Checks      []MigrationCheck    `json:"checks"`
+	Downtime    string              `json:"downtime"`
+	Command     string              `json:"command,omitempty"`
+	Notes       []string            `json:"notes,omitempty"`
+}
+
+// ParseClusterNetworkState extracts the networking configuration from az aks show output
+func ParseClusterNetworkState(output string) (ClusterNetworkState, error) {
+	var cluster struct {
+		KubernetesVersion string `json:"kubernetesVersion"`
+		NodeResourceGroup string `json:"nodeResourceGroup"`
+		NetworkProfile    struct {
+			NetworkPlugin     string `json:"networkPlugin"`
+			NetworkPluginMode string `json:"networkPluginMode"`
+			NetworkPolicy     string `json:"networkPolicy"`
+			NetworkDataplane  string `json:"networkDataplane"`
+			PodCidr           string `json:"podCidr"`
+			ServiceCidr       string `json:"serviceCidr"`
+		} `json:"networkProfile"`
+		AgentPoolProfiles []struct {
+			Name         string `json:"name"`
+			OSType       string `json:"osType"`
+			Count        int    `json:"count"`
+			MaxCount     int    `json:"maxCount"`
+			MaxPods      int    `json:"maxPods"`
+			VnetSubnetID string `json:"vnetSubnetId"`
+			PodSubnetID  string `json:"podSubnetId"`
+		} `json:"agentPoolProfiles"`
+	}
+	if err := json.Unmarshal([]byte(output), &cluster); err != nil {
+		return ClusterNetworkState{}, fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+
+	state := ClusterNetworkState{
+		KubernetesVersion: cluster.KubernetesVersion,
+		NetworkPlugin:     strings.ToLower(cluster.NetworkProfile.NetworkPlugin),
+		NetworkPluginMode: strings.ToLower(cluster.NetworkProfile.NetworkPluginMode),
+		NetworkPolicy:     strings.ToLower(cluster.NetworkProfile.NetworkPolicy),
+		NetworkDataplane:  strings.ToLower(cluster.NetworkProfile.NetworkDataplane),
+		PodCIDR:           cluster.NetworkProfile.PodCidr,
+		ServiceCIDR:       cluster.NetworkProfile.ServiceCidr,
+		NodeResourceGroup: cluster.NodeResourceGroup,
+		NodePools:         []NodePoolNetwork{},
+	}
+	for _, pool := range cluster.AgentPoolProfiles {
+		state.NodePools = append(state.NodePools, NodePoolNetwork(pool))
+	}
+	return state, nil
+}
+
+// CustomVNetID returns the VNet resource ID of the first node pool using a custom subnet
+func (s ClusterNetworkState) CustomVNetID() string {
+	for _, pool := range s.NodePools {
+		if idx := strings.Index(strings.ToLower(pool.VnetSubnetID), "/subnets/"); idx > 0 {
+			return pool.VnetSubnetID[:idx]
+		}
+	}
+	return ""
+}
+
+// currentModeLabel describes the current network plugin configuration
+func (s ClusterNetworkState) currentModeLabel() string {
+	switch {
+	case s.NetworkPlugin == "kubenet":
+		return "kubenet"
+	case s.NetworkPlugin == "azure" && s.NetworkPluginMode == "overlay":
+		return "Azure CNI overlay"
+	case s.NetworkPlugin == "azure" && s.hasPodSubnet():
+		return "Azure CNI with dynamic pod IP allocation (pod subnet)"
+	case s.NetworkPlugin == "azure":
+		return "Azure CNI (node subnet)"
+	case s.NetworkPlugin == "none":
+		return "bring your own CNI"
+	default:
+		return s.NetworkPlugin
+	}
+}
+
+// hasPodSubnet reports whether any node pool uses a dedicated pod subnet
+func (s ClusterNetworkState) hasPodSubnet() bool {
+	for _, pool := range s.NodePools {
+		if pool.PodSubnetID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeNetworkMigration evaluates readiness for migrating the cluster to the target network mode.
+// vnetPrefixes are the address prefixes of the cluster VNet, used to detect pod CIDR overlaps.
+func AnalyzeNetworkMigration(state ClusterNetworkState, target, podCIDR string, vnetPrefixes []string) MigrationReport {
+	report := MigrationReport{Current: state, Target: target}
+	add := func(name, status, detail string) {
+		report.Checks = append(report.Checks, MigrationCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	if target == MigrationTargetPodSubnet {
+		analyzePodSubnetMigration(state, &report, add)
+		report.Ready = !hasBlocker(report.Checks)
+		return report
+	}
+
+	// Current plugin
+	switch {
+	case state.NetworkPlugin == "azure" && state.NetworkPluginMode == "overlay":
+		add("current-plugin", CheckBlocker, "cluster already uses Azure CNI overlay")
+	case state.NetworkPlugin == "azure" && state.hasPodSubnet():
+		add("current-plugin", CheckBlocker, "clusters using dynamic pod IP allocation (pod subnet) cannot be migrated to overlay in place")
+	case state.NetworkPlugin == "kubenet" || state.NetworkPlugin == "azure":
+		add("current-plugin", CheckPass, fmt.Sprintf("%s clusters support in-place migration to Azure CNI overlay", state.currentModeLabel()))
+	default:
+		add("current-plugin", CheckBlocker, fmt.Sprintf("network plugin '%s' cannot be migrated to Azure CNI overlay", state.NetworkPlugin))
+	}
+
+	// Kubernetes version
+	if compareVersions(state.KubernetesVersion, minOverlayMigrationVersion) < 0 {
+		add("kubernetes-version", CheckBlocker, fmt.Sprintf("Kubernetes %s is older than %s; upgrade the cluster first", state.KubernetesVersion, minOverlayMigrationVersion))
+	} else {
+		add("kubernetes-version", CheckPass, fmt.Sprintf("Kubernetes %s supports overlay migration", state.KubernetesVersion))
+	}
+
+	// Pod CIDR selection and address space
+	if podCIDR == "" {
+		podCIDR = state.PodCIDR
+	}
+	if podCIDR == "" {
+		podCIDR = defaultOverlayPodCIDR
+	}
+	report.PodCIDR = podCIDR
+	checkPodCIDR(state, podCIDR, vnetPrefixes, add)
+
+	// Network policy compatibility
+	switch state.NetworkPolicy {
+	case "", "none":
+		add("network-policy", CheckPass, "no network policy engine is configured")
+	case "calico":
+		add("network-policy", CheckPass, "Calico network policy is supported with Azure CNI overlay")
+	case "azure":
+		if hasWindowsPools(state) {
+			add("network-policy", CheckBlocker, "Azure Network Policy Manager is not supported on Windows node pools with Azure CNI overlay")
+		} else {
+			add("network-policy", CheckWarning, "Azure Network Policy Manager is supported on Linux nodes; consider Cilium network policy for overlay clusters")
+		}
+	case "cilium":
+		add("network-policy", CheckPass, "Cilium network policy is supported with Azure CNI overlay")
+	default:
+		add("network-policy", CheckWarning, fmt.Sprintf("verify that network policy '%s' is supported with Azure CNI overlay", state.NetworkPolicy))
+	}
+
+	// Max pods per node
+	for _, pool := range state.NodePools {
+		if pool.MaxPods > 250 {
+			add("max-pods", CheckBlocker, fmt.Sprintf("node pool '%s' has maxPods %d; overlay supports at most 250 pods per node", pool.Name, pool.MaxPods))
+		}
+	}
+
+	// Route table usage for kubenet
+	if state.NetworkPlugin == "kubenet" {
+		add("route-table", CheckWarning, "kubenet pod routes in the node subnet route table are no longer used after migration; custom routes that reference pod CIDRs must be reviewed")
+	}
+	if hasWindowsPools(state) {
+		add("windows-node-pools", CheckWarning, "Windows node pools are reimaged during migration; ensure Windows workloads tolerate the restart")
+	}
+
+	report.Downtime = "All node pools are reimaged simultaneously during the migration, so every pod is restarted and workloads are briefly unavailable. The migration cannot be rolled back; schedule a maintenance window."
+	report.Ready = !hasBlocker(report.Checks)
+	return report
+}
+
+// analyzePodSubnetMigration reports the options for moving to dynamic pod IP allocation,
+// which cannot be enabled on existing node pools
+func analyzePodSubnetMigration(state ClusterNetworkState, report *MigrationReport, add func(name, status, detail string)) {
+	switch {
+	case state.NetworkPlugin == "azure" && state.hasPodSubnet():
+		add("current-plugin", CheckBlocker, "cluster already uses dynamic pod IP allocation")
+	case state.NetworkPlugin == "azure" && state.NetworkPluginMode != "overlay":
+		add("current-plugin", CheckWarning, "dynamic pod IP allocation cannot be enabled in place; add new node pools with --pod-subnet-id and migrate workloads to them")
+	default:
+		add("current-plugin", CheckBlocker, fmt.Sprintf("%s clusters cannot be migrated to dynamic pod IP allocation in place; create a new cluster with --network-plugin azure --pod-subnet-id", state.currentModeLabel()))
+	}
+	report.Downtime = "Workloads move to new node pools (or a new cluster), so downtime depends on how workloads are drained and rescheduled."
+}
+
+// checkPodCIDR validates the overlay pod CIDR size and overlaps
+func checkPodCIDR(state ClusterNetworkState, podCIDR string, vnetPrefixes []string, add func(name, status, detail string)) {
+	_, podNet, err := net.ParseCIDR(podCIDR)
+	if err != nil {
+		add("pod-cidr", CheckBlocker, fmt.Sprintf("invalid pod CIDR '%s'", podCIDR))
+		return
+	}
+
+	for _, prefix := range vnetPrefixes {
+		if cidrsOverlap(podCIDR, prefix) {
+			add("pod-cidr-overlap", CheckBlocker, fmt.Sprintf("pod CIDR %s overlaps the cluster VNet address space %s", podCIDR, prefix))
+			return
+		}
+	}
+	if state.ServiceCIDR != "" && cidrsOverlap(podCIDR, state.ServiceCIDR) {
+		add("pod-cidr-overlap", CheckBlocker, fmt.Sprintf("pod CIDR %s overlaps the service CIDR %s", podCIDR, state.ServiceCIDR))
+		return
+	}
+	add("pod-cidr-overlap", CheckPass, fmt.Sprintf("pod CIDR %s does not overlap the VNet or service CIDR; also verify peered and on-premises networks", podCIDR))
+
+	ones, _ := podNet.Mask.Size()
+	capacity := 1
+	if ones < overlayNodePrefixLength {
+		capacity = 1 << (overlayNodePrefixLength - ones)
+	}
+	maxNodes := 0
+	for _, pool := range state.NodePools {
+		count := pool.Count
+		if pool.MaxCount > count {
+			count = pool.MaxCount
+		}
+		maxNodes += count
+	}
+	if maxNodes > capacity {
+		add("pod-cidr-size", CheckBlocker, fmt.Sprintf("pod CIDR %s provides %d /24 node blocks but node pools can scale to %d nodes", podCIDR, capacity, maxNodes))
+	} else {
+		add("pod-cidr-size", CheckPass, fmt.Sprintf("pod CIDR %s provides %d /24 node blocks for up to %d nodes", podCIDR, capacity, maxNodes))
+	}
+}
+
+// BuildOverlayMigrationCommand returns the az command that migrates the cluster to Azure CNI overlay
+func BuildOverlayMigrationCommand(subscriptionID, resourceGroup, clusterName, podCIDR string) string {
+	return fmt.Sprintf("az aks update --subscription %s --resource-group %s --name %s --network-plugin azure --network-plugin-mode overlay --pod-cidr %s",
+		subscriptionID, resourceGroup, clusterName, podCIDR)
+}
+
+// hasBlocker reports whether any check is a blocker
+func hasBlocker(checks []MigrationCheck) bool {
+	for _, check := range checks {
+		if check.Status == CheckBlocker {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWindowsPools reports whether the cluster has Windows node pools
+func hasWindowsPools(state ClusterNetworkState) bool {
+	for _, pool := range state.NodePools {
+		if strings.EqualFold(pool.OSType, "Windows") {
+			return true
+		}
+	}
+	return false
+}
+
+// cidrsOverlap reports whether two CIDR ranges overlap; invalid ranges never overlap
+func cidrsOverlap(a, b string) bool {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var va, vb int
+		if i < len(pa) {
+			va, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			vb, _ = strconv.Atoi(pb[i])
+		}
+		if va != vb {
+			if va < vb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
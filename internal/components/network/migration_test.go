@@ -0,0 +1,151 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const testKubenetCluster = `{
+	"kubernetesVersion": "1.29.4",
+	"nodeResourceGroup": "MC_rg_aks",
+	"networkProfile": {"networkPlugin": "kubenet", "networkPolicy": "calico", "podCidr": "10.244.0.0/16", "serviceCidr": "10.0.0.0/16"},
+	"agentPoolProfiles": [
+		{"name": "system", "osType": "Linux", "count": 3, "maxPods": 110, "vnetSubnetId": "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet/subnets/nodes"},
+		{"name": "user", "osType": "Linux", "count": 2, "maxCount": 10, "maxPods": 110}
+	]
+}`
+
+// fakeAzExecutor returns canned output by command prefix
+type fakeAzExecutor struct {
+	responses map[string]string
+}
+
+func (f *fakeAzExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected command: %s", cmd)
+}
+
+func findCheck(report MigrationReport, name string) *MigrationCheck {
+	for i := range report.Checks {
+		if report.Checks[i].Name == name {
+			return &report.Checks[i]
+		}
+	}
+	return nil
+}
+
+func TestRegisterNetworkMigrationAdvisor(t *testing.T) {
+	tool := RegisterNetworkMigrationAdvisor()
+	if tool.Name != "aks_network_migration_advisor" {
+		t.Errorf("Expected tool name 'aks_network_migration_advisor', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 3 {
+		t.Errorf("Expected 3 required parameters, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestParseClusterNetworkState(t *testing.T) {
+	state, err := ParseClusterNetworkState(testKubenetCluster)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.NetworkPlugin != "kubenet" || len(state.NodePools) != 2 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+	if state.CustomVNetID() != "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet" {
+		t.Errorf("Unexpected VNet ID: %s", state.CustomVNetID())
+	}
+}
+
+func TestAnalyzeNetworkMigration(t *testing.T) {
+	state, _ := ParseClusterNetworkState(testKubenetCluster)
+
+	t.Run("kubenet cluster is ready", func(t *testing.T) {
+		report := AnalyzeNetworkMigration(state, MigrationTargetOverlay, "", []string{"10.224.0.0/12"})
+		if !report.Ready {
+			t.Errorf("Expected cluster to be ready, got checks %+v", report.Checks)
+		}
+		if report.PodCIDR != "10.244.0.0/16" {
+			t.Errorf("Expected existing pod CIDR to be reused, got %s", report.PodCIDR)
+		}
+		if report.Downtime == "" {
+			t.Error("Expected downtime expectations")
+		}
+	})
+
+	t.Run("overlapping pod CIDR blocks migration", func(t *testing.T) {
+		report := AnalyzeNetworkMigration(state, MigrationTargetOverlay, "10.0.0.0/8", nil)
+		if report.Ready {
+			t.Error("Expected overlap with the service CIDR to block migration")
+		}
+		if check := findCheck(report, "pod-cidr-overlap"); check == nil || check.Status != CheckBlocker {
+			t.Errorf("Expected pod-cidr-overlap blocker, got %+v", check)
+		}
+	})
+
+	t.Run("pod CIDR too small", func(t *testing.T) {
+		report := AnalyzeNetworkMigration(state, MigrationTargetOverlay, "192.168.0.0/22", nil)
+		if check := findCheck(report, "pod-cidr-size"); check == nil || check.Status != CheckBlocker {
+			t.Errorf("Expected pod-cidr-size blocker for 4 node blocks and 13 nodes, got %+v", check)
+		}
+	})
+
+	t.Run("overlay cluster cannot migrate again", func(t *testing.T) {
+		overlay := state
+		overlay.NetworkPlugin, overlay.NetworkPluginMode = "azure", "overlay"
+		report := AnalyzeNetworkMigration(overlay, MigrationTargetOverlay, "", nil)
+		if report.Ready {
+			t.Error("Expected overlay cluster not to be ready for overlay migration")
+		}
+	})
+
+	t.Run("kubenet cannot move to pod subnet in place", func(t *testing.T) {
+		report := AnalyzeNetworkMigration(state, MigrationTargetPodSubnet, "", nil)
+		if report.Ready {
+			t.Error("Expected kubenet to pod subnet migration to be blocked")
+		}
+	})
+}
+
+func TestHandleNetworkMigrationAdvisor(t *testing.T) {
+	executor := &fakeAzExecutor{responses: map[string]string{
+		"az aks show":      testKubenetCluster,
+		"az resource show": `["10.224.0.0/12"]`,
+	}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+
+	for _, tc := range []struct {
+		accessLevel string
+		wantCommand bool
+	}{
+		{"readonly", false},
+		{"readwrite", true},
+	} {
+		cfg := config.NewConfig()
+		cfg.AccessLevel = tc.accessLevel
+
+		output, err := HandleNetworkMigrationAdvisor(params, executor, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report MigrationReport
+		if err := json.Unmarshal([]byte(output), &report); err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		if (report.Command != "") != tc.wantCommand {
+			t.Errorf("access level %s: expected command=%v, got %q", tc.accessLevel, tc.wantCommand, report.Command)
+		}
+		if tc.wantCommand && !strings.Contains(report.Command, "--network-plugin-mode overlay --pod-cidr 10.244.0.0/16") {
+			t.Errorf("Unexpected migration command: %s", report.Command)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// API versions used by the orphaned load balancer resource check
+const (
+	orphanClusterAPIVersion = "2024-05-01"
+	orphanNetworkAPIVersion = "2024-05-01"
+)
+
+// Kinds of orphaned load balancer resources
+const (
+	OrphanLBRule     = "lb-rule"
+	OrphanLBProbe    = "lb-probe"
+	OrphanLBFrontend = "lb-frontend"
+	OrphanPublicIP   = "public-ip"
+)
+
+// Confidence that a resource is orphaned. Only high confidence orphans get cleanup commands.
+const (
+	ConfidenceHigh = "high"
+	ConfidenceLow  = "low"
+)
+
+// Tags the cloud provider and AKS set on the public IPs of the node resource group
+const (
+	serviceTag       = "k8s-azure-service"
+	legacyServiceTag = "service"
+	aksManagedTag    = "aks-managed-type"
+)
+
+var (
+	// serviceResourcePattern matches the names the cloud provider gives the frontends, rules and probes of a
+	// service: "a" and the first 31 hex digits of the service UID, followed by a suffix such as -TCP-80
+	serviceResourcePattern = regexp.MustCompile(`^(a[0-9a-f]{31})`)
+	// servicePublicIPPattern matches the default name of the public IP the cloud provider creates for a service
+	servicePublicIPPattern = regexp.MustCompile(`^kubernetes-(a[0-9a-f]{31})`)
+)
+
+// OrphanedResource is a load balancer rule, probe or frontend, or a public IP, that no LoadBalancer service owns
+type OrphanedResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// LoadBalancer is the load balancer of rules, probes and frontends
+	LoadBalancer string `json:"loadBalancer,omitempty"`
+	ID           string `json:"id"`
+	// IPAddress is the address of a public IP
+	IPAddress string `json:"ipAddress,omitempty"`
+	// Service is the namespace/name a public IP is tagged with
+	Service    string `json:"service,omitempty"`
+	Reason     string `json:"reason"`
+	Confidence string `json:"confidence"`
+}
+
+// OrphanReport is the result of the aks_orphaned_lb_resources tool
+type OrphanReport struct {
+	ClusterName       string             `json:"clusterName"`
+	NodeResourceGroup string             `json:"nodeResourceGroup"`
+	LoadBalancers     []string           `json:"loadBalancers"`
+	PublicIPs         int                `json:"publicIps"`
+	Services          int                `json:"services"`
+	Orphans           []OrphanedResource `json:"orphans"`
+	// Cleanup lists the az commands deleting the high confidence orphans, in an order Azure accepts
+	Cleanup  []string `json:"cleanup,omitempty"`
+	Findings []string `json:"findings"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// orphanCluster is the part of the managed cluster the check needs
+type orphanCluster struct {
+	Properties struct {
+		NodeResourceGroup string `json:"nodeResourceGroup"`
+	} `json:"properties"`
+}
+
+type armReference struct {
+	ID string `json:"id"`
+}
+
+type orphanLoadBalancer struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	Properties struct {
+		FrontendIPConfigurations []struct {
+			Name       string `json:"name"`
+			ID         string `json:"id"`
+			Properties struct {
+				PublicIPAddress *armReference `json:"publicIPAddress"`
+			} `json:"properties"`
+		} `json:"frontendIPConfigurations"`
+		LoadBalancingRules []struct {
+			Name       string `json:"name"`
+			ID         string `json:"id"`
+			Properties struct {
+				FrontendIPConfiguration *armReference `json:"frontendIPConfiguration"`
+			} `json:"properties"`
+		} `json:"loadBalancingRules"`
+		Probes []struct {
+			Name       string `json:"name"`
+			ID         string `json:"id"`
+			Properties struct {
+				LoadBalancingRules []armReference `json:"loadBalancingRules"`
+			} `json:"properties"`
+		} `json:"probes"`
+		OutboundRules []struct {
+			Properties struct {
+				FrontendIPConfigurations []armReference `json:"frontendIPConfigurations"`
+			} `json:"properties"`
+		} `json:"outboundRules"`
+	} `json:"properties"`
+}
+
+type orphanPublicIP struct {
+	Name       string            `json:"name"`
+	ID         string            `json:"id"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		IPAddress       string        `json:"ipAddress"`
+		IPConfiguration *armReference `json:"ipConfiguration"`
+	} `json:"properties"`
+}
+
+type orphanService struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		UID       string `json:"uid"`
+	} `json:"metadata"`
+	Spec struct {
+		Type string `json:"type"`
+	} `json:"spec"`
+}
+
+// liveServices are the LoadBalancer services of the cluster, by the name prefix of their Azure resources and by
+// namespace/name
+type liveServices struct {
+	prefixes map[string]bool
+	names    map[string]bool
+}
+
+// GetOrphanedLBResourcesHandler returns a handler for the aks_orphaned_lb_resources command
+func GetOrphanedLBResourcesHandler(api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleOrphanedLBResources(params, api, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleOrphanedLBResources cross-references the LoadBalancer services of a cluster with the load balancers and
+// public IPs of its node resource group, and reports the rules, probes, frontends and public IPs no service owns.
+// Cleanup commands are only generated for readwrite or admin access levels.
+func HandleOrphanedLBResources(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	// Services outside the allowed namespaces would look deleted, and their resources orphaned
+	if cfg.AllowNamespaces != "" {
+		return "", fmt.Errorf("orphaned load balancer resources can only be detected with access to all namespaces")
+	}
+
+	output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get services --all-namespaces -o json"}, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %v", err)
+	}
+	var services []orphanService
+	if err := decodeItems(output, &services); err != nil {
+		return "", err
+	}
+	live := newLiveServices(services)
+
+	ctx := context.Background()
+	clusterID := common.ClusterResourceID(subID, rg, clusterName)
+	body, err := api.CallARM(ctx, http.MethodGet, clusterID+"?api-version="+orphanClusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+	var cluster orphanCluster
+	if err := json.Unmarshal(body, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster details: %w", err)
+	}
+	nodeRG := cluster.Properties.NodeResourceGroup
+	if nodeRG == "" {
+		return "", fmt.Errorf("cluster %s has no node resource group", clusterName)
+	}
+
+	networkPath := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/", subID, nodeRG)
+	loadBalancers, err := listNetworkResources[orphanLoadBalancer](ctx, api, networkPath+"loadBalancers")
+	if err != nil {
+		return "", err
+	}
+	publicIPs, err := listNetworkResources[orphanPublicIP](ctx, api, networkPath+"publicIPAddresses")
+	if err != nil {
+		return "", err
+	}
+
+	report := OrphanReport{
+		ClusterName:       clusterName,
+		NodeResourceGroup: nodeRG,
+		LoadBalancers:     []string{},
+		PublicIPs:         len(publicIPs),
+		Services:          len(live.names),
+		Orphans:           []OrphanedResource{},
+	}
+	orphanedPublicIPs := map[string]bool{}
+	outboundPublicIPs := map[string]bool{}
+	for _, lb := range loadBalancers {
+		report.LoadBalancers = append(report.LoadBalancers, lb.Name)
+		report.Orphans = append(report.Orphans, findOrphanedLBResources(lb, live, orphanedPublicIPs, outboundPublicIPs)...)
+	}
+	for _, pip := range publicIPs {
+		if orphan := checkPublicIP(pip, live, orphanedPublicIPs, outboundPublicIPs); orphan != nil {
+			report.Orphans = append(report.Orphans, *orphan)
+		}
+	}
+
+	if cfg.AccessLevel == "readwrite" || cfg.AccessLevel == "admin" {
+		report.Cleanup = BuildOrphanCleanupCommands(subID, nodeRG, report.Orphans)
+		if len(report.Cleanup) > 0 {
+			report.Notes = append(report.Notes, "the node resource group is managed by AKS; review each orphan before running the cleanup commands, "+
+				"which delete the rules and probes before the frontends and public IPs that they reference")
+		}
+	} else if len(report.Orphans) > 0 {
+		report.Notes = append(report.Notes, "cleanup commands are only generated for readwrite or admin access levels")
+	}
+	report.Findings = buildOrphanFindings(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal orphan report to JSON: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// newLiveServices indexes the LoadBalancer services of the cluster
+func newLiveServices(services []orphanService) liveServices {
+	live := liveServices{prefixes: map[string]bool{}, names: map[string]bool{}}
+	for _, svc := range services {
+		if svc.Spec.Type != "LoadBalancer" {
+			continue
+		}
+		live.names[svc.Metadata.Namespace+"/"+svc.Metadata.Name] = true
+		if prefix := ServiceResourcePrefix(svc.Metadata.UID); prefix != "" {
+			live.prefixes[prefix] = true
+		}
+	}
+	return live
+}
+
+// ServiceResourcePrefix returns the prefix the cloud provider names the load balancer resources of a service
+// with: "a" followed by the service UID without dashes, truncated to 32 characters
+func ServiceResourcePrefix(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	prefix := "a" + strings.ToLower(strings.ReplaceAll(uid, "-", ""))
+	if len(prefix) > 32 {
+		prefix = prefix[:32]
+	}
+	return prefix
+}
+
+// listNetworkResources lists the resources at a collection path, following nextLink
+func listNetworkResources[T any](ctx context.Context, api common.ARMCaller, collection string) ([]T, error) {
+	var resources []T
+	next := collection + "?api-version=" + orphanNetworkAPIVersion
+	for next != "" {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", collection, err)
+		}
+		var page struct {
+			Value    []T    `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", collection, err)
+		}
+		resources = append(resources, page.Value...)
+		next = page.NextLink
+	}
+	return resources, nil
+}
+
+// findOrphanedLBResources returns the rules, probes and frontends of a load balancer named after a service that
+// no longer exists. It records the public IPs of the orphaned frontends in orphanedPublicIPs and those of the
+// outbound frontends in outboundPublicIPs, keyed by lowercase resource ID.
+func findOrphanedLBResources(lb orphanLoadBalancer, live liveServices, orphanedPublicIPs, outboundPublicIPs map[string]bool) []OrphanedResource {
+	orphaned := func(name string) bool {
+		match := serviceResourcePattern.FindStringSubmatch(strings.ToLower(name))
+		return match != nil && !live.prefixes[match[1]]
+	}
+
+	outbound := map[string]bool{}
+	for _, rule := range lb.Properties.OutboundRules {
+		for _, ref := range rule.Properties.FrontendIPConfigurations {
+			outbound[strings.ToLower(ref.ID)] = true
+		}
+	}
+
+	var rules, probes, frontends []OrphanedResource
+	orphanedFrontends := map[string]bool{}
+	for _, frontend := range lb.Properties.FrontendIPConfigurations {
+		id := strings.ToLower(frontend.ID)
+		pip := frontend.Properties.PublicIPAddress
+		if outbound[id] {
+			if pip != nil {
+				outboundPublicIPs[strings.ToLower(pip.ID)] = true
+			}
+			continue
+		}
+		if !orphaned(frontend.Name) {
+			continue
+		}
+		orphanedFrontends[id] = true
+		if pip != nil {
+			orphanedPublicIPs[strings.ToLower(pip.ID)] = true
+		}
+		frontends = append(frontends, OrphanedResource{
+			Kind: OrphanLBFrontend, Name: frontend.Name, LoadBalancer: lb.Name, ID: frontend.ID,
+			Reason: "named after a service UID that matches no LoadBalancer service", Confidence: ConfidenceHigh,
+		})
+	}
+
+	orphanedRules := map[string]bool{}
+	for _, rule := range lb.Properties.LoadBalancingRules {
+		reason := ""
+		switch {
+		case rule.Properties.FrontendIPConfiguration != nil && orphanedFrontends[strings.ToLower(rule.Properties.FrontendIPConfiguration.ID)]:
+			reason = "uses an orphaned frontend"
+		case orphaned(rule.Name):
+			reason = "named after a service UID that matches no LoadBalancer service"
+		default:
+			continue
+		}
+		orphanedRules[strings.ToLower(rule.ID)] = true
+		rules = append(rules, OrphanedResource{
+			Kind: OrphanLBRule, Name: rule.Name, LoadBalancer: lb.Name, ID: rule.ID, Reason: reason, Confidence: ConfidenceHigh,
+		})
+	}
+
+	for _, probe := range lb.Properties.Probes {
+		// A probe still used by a rule of a live service stays
+		used := false
+		for _, ref := range probe.Properties.LoadBalancingRules {
+			if !orphanedRules[strings.ToLower(ref.ID)] {
+				used = true
+			}
+		}
+		switch {
+		case used:
+			continue
+		case len(probe.Properties.LoadBalancingRules) > 0:
+			probes = append(probes, OrphanedResource{
+				Kind: OrphanLBProbe, Name: probe.Name, LoadBalancer: lb.Name, ID: probe.ID,
+				Reason: "only used by orphaned rules", Confidence: ConfidenceHigh,
+			})
+		case orphaned(probe.Name):
+			probes = append(probes, OrphanedResource{
+				Kind: OrphanLBProbe, Name: probe.Name, LoadBalancer: lb.Name, ID: probe.ID,
+				Reason: "named after a service UID that matches no LoadBalancer service", Confidence: ConfidenceHigh,
+			})
+		}
+	}
+
+	return append(append(rules, probes...), frontends...)
+}
+
+// checkPublicIP returns the public IP as an orphan when the service it is tagged with, or named after, is gone,
+// or when it only serves an orphaned frontend. Untagged public IPs that are not associated with anything are
+// returned with low confidence, since they may have been created for a service that has not claimed them yet.
+func checkPublicIP(pip orphanPublicIP, live liveServices, orphanedPublicIPs, outboundPublicIPs map[string]bool) *OrphanedResource {
+	id := strings.ToLower(pip.ID)
+	if pip.Tags[aksManagedTag] != "" || outboundPublicIPs[id] {
+		return nil
+	}
+	orphan := &OrphanedResource{Kind: OrphanPublicIP, Name: pip.Name, ID: pip.ID, IPAddress: pip.Properties.IPAddress, Confidence: ConfidenceHigh}
+
+	tag := pip.Tags[serviceTag]
+	if tag == "" {
+		tag = pip.Tags[legacyServiceTag]
+	}
+	if tag != "" {
+		// Public IPs shared by several services list them all
+		for _, name := range strings.Split(tag, ",") {
+			if live.names[strings.TrimSpace(name)] {
+				return nil
+			}
+		}
+		orphan.Service = tag
+		orphan.Reason = "tagged with a service that is deleted or no longer of type LoadBalancer"
+		return orphan
+	}
+	if match := servicePublicIPPattern.FindStringSubmatch(strings.ToLower(pip.Name)); match != nil {
+		if live.prefixes[match[1]] {
+			return nil
+		}
+		orphan.Reason = "named after a service UID that matches no LoadBalancer service"
+		return orphan
+	}
+	if orphanedPublicIPs[id] {
+		orphan.Reason = "only used by an orphaned frontend"
+		return orphan
+	}
+	if pip.Properties.IPConfiguration == nil {
+		orphan.Reason = "not tagged with a service and not associated with any resource"
+		orphan.Confidence = ConfidenceLow
+		return orphan
+	}
+	return nil
+}
+
+// BuildOrphanCleanupCommands returns the az commands deleting the high confidence orphans. Rules go first, then
+// the probes and frontends they referenced, then the public IPs the frontends used.
+func BuildOrphanCleanupCommands(subscriptionID, nodeResourceGroup string, orphans []OrphanedResource) []string {
+	order := map[string]int{OrphanLBRule: 0, OrphanLBProbe: 1, OrphanLBFrontend: 2, OrphanPublicIP: 3}
+	sorted := make([]OrphanedResource, 0, len(orphans))
+	for _, orphan := range orphans {
+		if orphan.Confidence == ConfidenceHigh {
+			sorted = append(sorted, orphan)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return order[sorted[i].Kind] < order[sorted[j].Kind] })
+
+	var commands []string
+	for _, orphan := range sorted {
+		scope := fmt.Sprintf("--subscription %s --resource-group %s", subscriptionID, nodeResourceGroup)
+		switch orphan.Kind {
+		case OrphanLBRule:
+			commands = append(commands, fmt.Sprintf("az network lb rule delete %s --lb-name %s --name %s", scope, orphan.LoadBalancer, orphan.Name))
+		case OrphanLBProbe:
+			commands = append(commands, fmt.Sprintf("az network lb probe delete %s --lb-name %s --name %s", scope, orphan.LoadBalancer, orphan.Name))
+		case OrphanLBFrontend:
+			commands = append(commands, fmt.Sprintf("az network lb frontend-ip delete %s --lb-name %s --name %s", scope, orphan.LoadBalancer, orphan.Name))
+		case OrphanPublicIP:
+			commands = append(commands, fmt.Sprintf("az network public-ip delete %s --name %s", scope, orphan.Name))
+		}
+	}
+	return commands
+}
+
+// buildOrphanFindings summarizes the orphans by kind
+func buildOrphanFindings(report OrphanReport) []string {
+	findings := []string{}
+	counts := map[string]int{}
+	low := 0
+	for _, orphan := range report.Orphans {
+		if orphan.Confidence == ConfidenceLow {
+			low++
+			continue
+		}
+		counts[orphan.Kind]++
+	}
+	for _, kind := range []struct{ kind, label string }{
+		{OrphanLBRule, "load balancing rules"},
+		{OrphanLBProbe, "health probes"},
+		{OrphanLBFrontend, "frontend IP configurations"},
+		{OrphanPublicIP, "public IPs"},
+	} {
+		if counts[kind.kind] > 0 {
+			findings = append(findings, fmt.Sprintf("%d %s in %s belong to no LoadBalancer service", counts[kind.kind], kind.label, report.NodeResourceGroup))
+		}
+	}
+	if low > 0 {
+		findings = append(findings, fmt.Sprintf("%d untagged public IPs in %s are not associated with any resource; check whether a service "+
+			"refers to them by name before deleting them", low, report.NodeResourceGroup))
+	}
+	return findings
+}
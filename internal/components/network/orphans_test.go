@@ -0,0 +1,158 @@
+package network
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const (
+	testNodeRG       = "/subscriptions/sub/resourceGroups/MC_rg_aks_eastus/providers/Microsoft.Network"
+	testLB           = testNodeRG + "/loadBalancers/kubernetes"
+	testLiveUID      = "11111111-2222-3333-4444-555555555555"
+	testLivePrefix   = "a1111111122223333444455555555555"
+	testDeletedUID   = "99999999-8888-7777-6666-555555555555"
+	testOutboundPIP  = testNodeRG + "/publicIPAddresses/outbound-ip"
+	testDeletedPIP   = testNodeRG + "/publicIPAddresses/kubernetes-" + testDeletedFront
+	testTaggedPIP    = testNodeRG + "/publicIPAddresses/shop-ip"
+	testLivePIP      = testNodeRG + "/publicIPAddresses/kubernetes-" + testLivePrefix
+	testSpareIP      = testNodeRG + "/publicIPAddresses/spare"
+	testAttachedIP   = testNodeRG + "/publicIPAddresses/attached"
+	testDeletedFront = "a9999999988887777666655555555555"
+)
+
+func newOrphanARM() *fakeARM {
+	deleted := testDeletedFront
+	live := testLivePrefix
+	return &fakeARM{bodies: map[string]string{
+		testClusterID: `{"properties": {"nodeResourceGroup": "MC_rg_aks_eastus"}}`,
+		testNodeRG + "/loadBalancers": `{"value": [{"name": "kubernetes", "id": "` + testLB + `", "properties": {
+			"frontendIPConfigurations": [
+				{"name": "aksOutbound", "id": "` + testLB + `/frontendIPConfigurations/aksOutbound", "properties": {"publicIPAddress": {"id": "` + testOutboundPIP + `"}}},
+				{"name": "` + live + `", "id": "` + testLB + `/frontendIPConfigurations/` + live + `", "properties": {"publicIPAddress": {"id": "` + testLivePIP + `"}}},
+				{"name": "` + deleted + `", "id": "` + testLB + `/frontendIPConfigurations/` + deleted + `", "properties": {"publicIPAddress": {"id": "` + testAttachedIP + `"}}}],
+			"loadBalancingRules": [
+				{"name": "` + live + `-TCP-80", "id": "` + testLB + `/loadBalancingRules/` + live + `-TCP-80", "properties": {"frontendIPConfiguration": {"id": "` + testLB + `/frontendIPConfigurations/` + live + `"}}},
+				{"name": "` + deleted + `-TCP-443", "id": "` + testLB + `/loadBalancingRules/` + deleted + `-TCP-443", "properties": {"frontendIPConfiguration": {"id": "` + testLB + `/frontendIPConfigurations/` + deleted + `"}}}],
+			"probes": [
+				{"name": "` + live + `-TCP-80", "id": "` + testLB + `/probes/` + live + `-TCP-80", "properties": {"loadBalancingRules": [{"id": "` + testLB + `/loadBalancingRules/` + live + `-TCP-80"}]}},
+				{"name": "` + deleted + `-TCP-443", "id": "` + testLB + `/probes/` + deleted + `-TCP-443", "properties": {"loadBalancingRules": [{"id": "` + testLB + `/loadBalancingRules/` + deleted + `-TCP-443"}]}}],
+			"outboundRules": [{"properties": {"frontendIPConfigurations": [{"id": "` + testLB + `/frontendIPConfigurations/aksOutbound"}]}}]}}]}`,
+		testNodeRG + "/publicIPAddresses": `{"value": [
+			{"name": "outbound-ip", "id": "` + testOutboundPIP + `", "properties": {"ipConfiguration": {"id": "x"}}}],
+			"nextLink": "` + testNodeRG + `/publicIPAddresses/page2"}`,
+		testNodeRG + "/publicIPAddresses/page2": `{"value": [
+			{"name": "kubernetes-` + live + `", "id": "` + testLivePIP + `", "properties": {"ipConfiguration": {"id": "x"}}},
+			{"name": "kubernetes-` + deleted + `", "id": "` + testDeletedPIP + `", "properties": {"ipAddress": "20.1.1.1"}},
+			{"name": "shop-ip", "id": "` + testTaggedPIP + `", "tags": {"k8s-azure-service": "shop/old-frontend"}, "properties": {"ipAddress": "20.1.1.2"}},
+			{"name": "attached", "id": "` + testAttachedIP + `", "properties": {"ipConfiguration": {"id": "y"}}},
+			{"name": "spare", "id": "` + testSpareIP + `", "properties": {}}]}`,
+	}}
+}
+
+func newOrphanKubectl() *fakeAzExecutor {
+	return &fakeAzExecutor{responses: map[string]string{
+		"get services --all-namespaces": `{"items": [
+			{"metadata": {"name": "web", "namespace": "shop", "uid": "` + testLiveUID + `"}, "spec": {"type": "LoadBalancer"}},
+			{"metadata": {"name": "old-frontend", "namespace": "shop", "uid": "` + testDeletedUID + `"}, "spec": {"type": "ClusterIP"}}]}`,
+	}}
+}
+
+func runOrphanCheck(t *testing.T, cfg *config.ConfigData) OrphanReport {
+	t.Helper()
+	output, err := HandleOrphanedLBResources(testEgressParams(), newOrphanARM(), newOrphanKubectl(), cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report OrphanReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+// TestServiceResourcePrefix tests the name prefix the cloud provider derives from a service UID
+func TestServiceResourcePrefix(t *testing.T) {
+	if got := ServiceResourcePrefix(testLiveUID); got != testLivePrefix {
+		t.Errorf("Expected %s, got %s", testLivePrefix, got)
+	}
+	if got := ServiceResourcePrefix(""); got != "" {
+		t.Errorf("Expected no prefix without a UID, got %s", got)
+	}
+}
+
+// TestHandleOrphanedLBResources tests matching load balancer resources and public IPs to the services
+func TestHandleOrphanedLBResources(t *testing.T) {
+	report := runOrphanCheck(t, &config.ConfigData{AccessLevel: "readwrite"})
+	if report.NodeResourceGroup != "MC_rg_aks_eastus" || report.PublicIPs != 6 || report.Services != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	got := map[string]OrphanedResource{}
+	for _, orphan := range report.Orphans {
+		got[orphan.Kind+":"+orphan.Name] = orphan
+	}
+	deleted := testDeletedFront
+	for _, want := range []struct{ key, confidence string }{
+		{OrphanLBRule + ":" + deleted + "-TCP-443", ConfidenceHigh},
+		{OrphanLBProbe + ":" + deleted + "-TCP-443", ConfidenceHigh},
+		{OrphanLBFrontend + ":" + deleted, ConfidenceHigh},
+		{OrphanPublicIP + ":kubernetes-" + deleted, ConfidenceHigh},
+		{OrphanPublicIP + ":shop-ip", ConfidenceHigh},
+		{OrphanPublicIP + ":attached", ConfidenceHigh},
+		{OrphanPublicIP + ":spare", ConfidenceLow},
+	} {
+		orphan, ok := got[want.key]
+		if !ok {
+			t.Errorf("Expected orphan %s, got %v", want.key, report.Orphans)
+			continue
+		}
+		if orphan.Confidence != want.confidence {
+			t.Errorf("Expected %s confidence for %s, got %s", want.confidence, want.key, orphan.Confidence)
+		}
+	}
+	if len(report.Orphans) != 7 {
+		t.Errorf("Expected 7 orphans, got %v", report.Orphans)
+	}
+	if got[OrphanPublicIP+":shop-ip"].Service != "shop/old-frontend" {
+		t.Errorf("Expected the tagged service, got %+v", got[OrphanPublicIP+":shop-ip"])
+	}
+
+	// Rules, probes and frontends are deleted before the public IPs, and low confidence orphans are left alone
+	if len(report.Cleanup) != 6 {
+		t.Fatalf("Expected 6 cleanup commands, got %v", report.Cleanup)
+	}
+	for i, prefix := range []string{"az network lb rule delete", "az network lb probe delete", "az network lb frontend-ip delete", "az network public-ip delete"} {
+		if !strings.HasPrefix(report.Cleanup[i], prefix) {
+			t.Errorf("Expected command %d to start with %s, got %s", i, prefix, report.Cleanup[i])
+		}
+	}
+	if !strings.Contains(report.Cleanup[0], "--subscription sub --resource-group MC_rg_aks_eastus --lb-name kubernetes") {
+		t.Errorf("Unexpected rule command %s", report.Cleanup[0])
+	}
+	if strings.Contains(strings.Join(report.Cleanup, "\n"), "spare") {
+		t.Errorf("Expected no command for the low confidence orphan, got %v", report.Cleanup)
+	}
+	if len(report.Findings) != 5 {
+		t.Errorf("Expected a finding per kind and one for the low confidence orphan, got %v", report.Findings)
+	}
+}
+
+// TestHandleOrphanedLBResourcesAccess tests that read-only users get no cleanup commands and that a namespace
+// allow list is refused
+func TestHandleOrphanedLBResourcesAccess(t *testing.T) {
+	report := runOrphanCheck(t, &config.ConfigData{AccessLevel: "readonly"})
+	if len(report.Cleanup) != 0 || len(report.Orphans) == 0 {
+		t.Errorf("Expected orphans without cleanup commands, got %+v", report)
+	}
+	if len(report.Notes) != 1 || !strings.Contains(report.Notes[0], "readwrite or admin") {
+		t.Errorf("Expected a note about the access level, got %v", report.Notes)
+	}
+
+	cfg := &config.ConfigData{AccessLevel: "admin", AllowNamespaces: "shop"}
+	if _, err := HandleOrphanedLBResources(testEgressParams(), newOrphanARM(), newOrphanKubectl(), cfg); err == nil {
+		t.Error("Expected an error with a namespace allow list")
+	}
+}
@@ -42,6 +42,7 @@ Examples:
 		mcp.WithString("resource_type",
 			mcp.Required(),
 			mcp.Description("The type of network resource to query"),
+			mcp.Enum(GetSupportedNetworkResourceTypes()...),
 		),
 		mcp.WithString("subscription_id",
 			mcp.Description("Azure Subscription ID"),
@@ -61,6 +62,198 @@ Examples:
 	)
 }
 
+// RegisterNetworkMigrationAdvisor registers the network plugin migration advisor tool
+func RegisterNetworkMigrationAdvisor() mcp.Tool {
+	description := `Analyze a kubenet or Azure CNI (node subnet) cluster and report readiness to migrate to Azure CNI overlay or dynamic pod IP allocation (pod subnet).
+
+Checks performed:
+- Current network plugin and Kubernetes version support for in-place migration
+- Pod CIDR size and overlap with the cluster VNet and service CIDR
+- Network policy engine and Windows node pool compatibility
+- Max pods per node limits
+
+Reports the expected downtime and, for readwrite or admin access levels, the az aks update command to run.`
+
+	return mcp.NewTool("aks_network_migration_advisor",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("target",
+			mcp.Description("Migration target: 'overlay' (Azure CNI overlay, default) or 'podsubnet' (dynamic pod IP allocation)"),
+			mcp.Enum(MigrationTargetOverlay, MigrationTargetPodSubnet),
+		),
+		mcp.WithString("pod_cidr",
+			mcp.Description("Pod CIDR to use for overlay (defaults to the current kubenet pod CIDR or 10.244.0.0/16)"),
+		),
+	)
+}
+
+// RegisterEgressFirewallAnalysis registers the egress firewall analysis tool
+func RegisterEgressFirewallAnalysis() mcp.Tool {
+	description := `Analyze the Azure Firewall that AKS egress is routed through.
+
+Follows the 0.0.0.0/0 route of the node and pod subnets to the Azure Firewall that owns the next hop, then:
+- Lists the application and network rules (of the firewall or its policy and parent policy) that match the
+  destinations AKS requires, and reports required destinations that no allow rule covers
+- Queries the firewall's application and network rule logs for requests denied from the cluster subnets
+- Maps the source IP of each deny to a pod, Cilium endpoint or node (requires Kubernetes access; with kubenet
+  and Azure CNI overlay pod traffic leaves with the node IP, so denies map to nodes)
+
+Network virtual appliances other than Azure Firewall are detected, but their rules and logs cannot be read.
+Denies require a diagnostic setting that sends the firewall rule logs to a Log Analytics workspace.`
+
+	return mcp.NewTool("aks_egress_firewall_analysis",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("firewall_id",
+			mcp.Description("Resource ID of the Azure Firewall to analyze (default: the firewall owning the next hop of the node subnet routes)"),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description("How many hours of firewall logs to search for denied requests (default: 24, maximum: 168)"),
+		),
+	)
+}
+
+// RegisterIngressHealth registers the ingress controller health tool
+func RegisterIngressHealth() mcp.Tool {
+	description := `Troubleshoot the ingress controllers of an AKS cluster and return a consolidated ingress health report.
+
+Detects ingress-nginx (including the application routing add-on), AGIC, the ALB controller (Application Gateway
+for Containers), Traefik and Envoy-based controllers from the images of running pods, then checks:
+- Controller pod readiness, restarts and crash or OOM reasons, and the ingresses each controller serves
+- Configuration reload errors in the recent controller logs
+- 502/504 rates from the access logs of the controllers that proxy traffic, with the ingress-nginx upstreams
+  producing the most errors
+- The Azure health probe status of the LoadBalancer services in front of the controller, and the probe
+  request path ingress-nginx needs on AKS
+- The healthy and unhealthy backend hosts of the Application Gateway of the AGIC add-on
+
+Uses the current kubeconfig context for the cluster. Ingresses whose class no detected controller serves are
+reported as findings.`
+
+	return mcp.NewTool("aks_ingress_health",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("controller",
+			mcp.Description("Only check this ingress controller (default: all detected controllers)"),
+			mcp.Enum(ControllerIngressNginx, ControllerAGIC, ControllerALB, ControllerTraefik, ControllerEnvoy),
+		),
+		mcp.WithNumber("log_lines",
+			mcp.Description("Number of recent log lines to read from each controller pod (default: 2000, maximum: 20000)"),
+		),
+	)
+}
+
+// RegisterDNSValidation registers the cluster DNS validation tool
+func RegisterDNSValidation() mcp.Tool {
+	description := `Validate the custom DNS configuration of an AKS cluster and highlight mismatches.
+
+Checks:
+- The DNS servers of the VNets the node pools use (Azure-provided DNS or custom servers), and whether custom
+  servers can resolve the API server of a private cluster
+- The CoreDNS upstreams, and the stub domains (conditional forwarding, for example to on-premises DNS) and
+  overrides of the coredns-custom ConfigMap, including keys AKS ignores and blocks CoreDNS rejects
+
+With test_resolution (readwrite access, runs a script on one node with run-command):
+- Reads the DNS servers the node actually uses and compares them with the VNet setting
+- Asks each stub domain forwarder for its zone's SOA record from the node
+- Resolves kubernetes.default.svc.cluster.local, mcr.microsoft.com, the API server FQDN and the given names
+  with the node's resolver and through CoreDNS, and reports names they resolve differently
+
+Uses the current kubeconfig context for the cluster.`
+
+	return mcp.NewTool("aks_dns_validation",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("test_resolution",
+			mcp.Description("Run the resolution tests on a node with run-command (requires readwrite access; default: false)"),
+		),
+		mcp.WithString("names",
+			mcp.Description("Optional comma-separated list of additional names to resolve, such as on-premises hosts behind a stub domain (maximum: 10)"),
+		),
+		mcp.WithString("node_name",
+			mcp.Description("Node to run the resolution tests on (default: the first ready Linux node)"),
+		),
+	)
+}
+
+// RegisterOrphanedLBResources registers the orphaned load balancer resources tool
+func RegisterOrphanedLBResources() mcp.Tool {
+	description := `Find load balancer rules and public IPs in the node resource group that no Kubernetes service owns.
+
+Cross-references the Services of type LoadBalancer with the load balancers and public IPs of the node resource
+group, and flags as orphaned:
+- Frontend IP configurations, load balancing rules and health probes named after the UID of a service that no
+  longer exists (the cloud provider names them "a" followed by the service UID), and probes only used by
+  orphaned rules
+- Public IPs tagged with a service (k8s-azure-service) that is deleted or no longer of type LoadBalancer, named
+  after a deleted service, or only used by an orphaned frontend
+- Untagged public IPs that are not associated with anything, with low confidence
+
+The outbound frontend and public IPs of the cluster are never flagged. With readwrite or admin access the report
+includes the az commands that delete the high confidence orphans, in the order Azure accepts; nothing is deleted
+by this tool. Uses the current kubeconfig context for the cluster and needs access to all namespaces.`
+
+	return mcp.NewTool("aks_orphaned_lb_resources",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+	)
+}
+
 // ValidateNetworkResourceType checks if the resource type is supported
 func ValidateNetworkResourceType(resourceType string) bool {
 	supportedTypes := []string{
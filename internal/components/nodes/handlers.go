@@ -0,0 +1,291 @@
+// Package nodes provides tools for cordoning and draining AKS nodes with safety checks
+// and for comparing node and namespace resource use with pod reservations.
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// defaultDrainTimeoutSeconds is the per-node drain timeout used when timeout_seconds is not provided
+const defaultDrainTimeoutSeconds = 300
+
+// NodeProgress records what happened to a single node
+type NodeProgress struct {
+	Node       string  `json:"node"`
+	Cordoned   bool    `json:"cordoned"`
+	Drained    bool    `json:"drained"`
+	Uncordoned bool    `json:"uncordoned"`
+	RolledBack bool    `json:"rolledBack,omitempty"`
+	Seconds    float64 `json:"seconds"`
+	Error      string  `json:"error,omitempty"`
+	Output     string  `json:"output,omitempty"`
+}
+
+// DrainReport is the result returned by the aks_node_drain tool
+type DrainReport struct {
+	Operation         string           `json:"operation"`
+	Nodes             []string         `json:"nodes"`
+	Succeeded         bool             `json:"succeeded"`
+	Aborted           bool             `json:"aborted,omitempty"`
+	AbortReason       string           `json:"abortReason,omitempty"`
+	BlockingBudgets   []BlockingBudget `json:"blockingPodDisruptionBudgets,omitempty"`
+	RemainingCapacity int              `json:"remainingSchedulableNodes"`
+	Progress          []NodeProgress   `json:"progress"`
+}
+
+// drainOptions holds the parsed tool parameters
+type drainOptions struct {
+	operation          string
+	gracePeriodSeconds int
+	timeoutSeconds     int
+	deleteEmptyDirData bool
+	force              bool
+	rollbackOnFailure  bool
+}
+
+// GetNodeDrainHandler returns a handler for the aks_node_drain command
+func GetNodeDrainHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNodeDrain(params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleNodeDrain cordons, drains or uncordons the target nodes after running safety checks
+func HandleNodeDrain(params map[string]interface{}, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	opts, err := parseDrainOptions(params)
+	if err != nil {
+		return "", err
+	}
+	nodeNames, _ := params["node_names"].(string)
+	nodePool, _ := params["nodepool_name"].(string)
+
+	// Draining evicts pods in every namespace and its safety checks must see all of them,
+	// so it cannot be scoped to the namespaces a restricted deployment may touch
+	if opts.operation == "drain" && cfg.AllowNamespaces != "" {
+		return "", fmt.Errorf("drain is not available when --allow-namespaces is set: draining evicts pods from every namespace on the node; use cordon and move workloads in the allowed namespaces instead")
+	}
+
+	kubectlRun := func(command string) (string, error) {
+		return executor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+
+	nodesOutput, err := kubectlRun("get nodes -o json")
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %v", err)
+	}
+	allNodes, err := ParseNodes(nodesOutput)
+	if err != nil {
+		return "", err
+	}
+	targets, err := SelectTargetNodes(allNodes, parseNameList(nodeNames), nodePool)
+	if err != nil {
+		return "", err
+	}
+
+	report := DrainReport{
+		Operation:         opts.operation,
+		RemainingCapacity: RemainingSchedulableNodes(allNodes, targets),
+		Progress:          []NodeProgress{},
+	}
+	for _, node := range targets {
+		report.Nodes = append(report.Nodes, node.Name)
+	}
+
+	if opts.operation == "drain" {
+		aborted, err := checkDrainSafety(kubectlRun, &report, targets, opts)
+		if err != nil {
+			return "", err
+		}
+		if aborted {
+			return marshalReport(report)
+		}
+	}
+
+	switch opts.operation {
+	case "uncordon":
+		report.Succeeded = true
+		for _, node := range targets {
+			progress := runStep(node.Name, func(p *NodeProgress) error {
+				output, err := kubectlRun("uncordon " + node.Name)
+				p.Output = output
+				p.Uncordoned = err == nil
+				return err
+			})
+			report.Succeeded = report.Succeeded && progress.Error == ""
+			report.Progress = append(report.Progress, progress)
+		}
+	default:
+		report.Succeeded = cordonAndDrain(kubectlRun, &report, targets, opts)
+	}
+
+	return marshalReport(report)
+}
+
+// checkDrainSafety evaluates PodDisruptionBudgets and remaining capacity, recording the
+// abort reason on the report and returning true when the drain must not proceed
+func checkDrainSafety(kubectlRun func(string) (string, error), report *DrainReport, targets []NodeInfo, opts drainOptions) (bool, error) {
+	if report.RemainingCapacity == 0 && !opts.force {
+		report.Aborted = true
+		report.AbortReason = "draining these nodes would leave no schedulable nodes in the cluster; set force to proceed"
+		return true, nil
+	}
+
+	pdbOutput, err := kubectlRun("get poddisruptionbudgets --all-namespaces -o json")
+	if err != nil {
+		return false, fmt.Errorf("failed to list pod disruption budgets: %v", err)
+	}
+	budgets, err := ParseDisruptionBudgets(pdbOutput)
+	if err != nil || len(budgets) == 0 {
+		return false, err
+	}
+
+	podsOutput, err := kubectlRun("get pods --all-namespaces -o json")
+	if err != nil {
+		return false, fmt.Errorf("failed to list pods: %v", err)
+	}
+	pods, err := ParsePods(podsOutput)
+	if err != nil {
+		return false, err
+	}
+
+	report.BlockingBudgets = FindBlockingBudgets(budgets, pods, targets)
+	if len(report.BlockingBudgets) > 0 && !opts.force {
+		report.Aborted = true
+		report.AbortReason = "pod disruption budgets currently allow no disruptions for pods on the target nodes; scale the workloads or set force to proceed"
+		return true, nil
+	}
+	return false, nil
+}
+
+// cordonAndDrain cordons every target node, then drains them one at a time when requested.
+// On failure the nodes cordoned by this call are uncordoned if rollback is enabled.
+func cordonAndDrain(kubectlRun func(string) (string, error), report *DrainReport, targets []NodeInfo, opts drainOptions) bool {
+	var cordonedByUs []int
+	failed := false
+
+	for _, node := range targets {
+		progress := runStep(node.Name, func(p *NodeProgress) error {
+			if node.Unschedulable {
+				p.Cordoned = true
+				return nil
+			}
+			output, err := kubectlRun("cordon " + node.Name)
+			p.Output = output
+			p.Cordoned = err == nil
+			return err
+		})
+		report.Progress = append(report.Progress, progress)
+		if progress.Error != "" {
+			failed = true
+			break
+		}
+		if !node.Unschedulable {
+			cordonedByUs = append(cordonedByUs, len(report.Progress)-1)
+		}
+	}
+
+	if !failed && opts.operation == "drain" {
+		for i, node := range targets {
+			start := time.Now()
+			output, err := kubectlRun(buildDrainCommand(node.Name, opts))
+			progress := &report.Progress[i]
+			progress.Seconds += time.Since(start).Seconds()
+			progress.Output = output
+			if err != nil {
+				progress.Error = err.Error()
+				failed = true
+				break
+			}
+			progress.Drained = true
+		}
+	}
+
+	if failed && opts.rollbackOnFailure {
+		for _, idx := range cordonedByUs {
+			progress := &report.Progress[idx]
+			if _, err := kubectlRun("uncordon " + progress.Node); err == nil {
+				progress.Uncordoned = true
+				progress.RolledBack = true
+			}
+		}
+	}
+	return !failed
+}
+
+// buildDrainCommand builds the kubectl drain command for a node
+func buildDrainCommand(node string, opts drainOptions) string {
+	cmd := fmt.Sprintf("drain %s --ignore-daemonsets --timeout=%ds", node, opts.timeoutSeconds)
+	if opts.gracePeriodSeconds >= 0 {
+		cmd += fmt.Sprintf(" --grace-period=%d", opts.gracePeriodSeconds)
+	}
+	if opts.deleteEmptyDirData {
+		cmd += " --delete-emptydir-data"
+	}
+	return cmd
+}
+
+// runStep runs fn for a node and records its duration and error
+func runStep(node string, fn func(*NodeProgress) error) NodeProgress {
+	progress := NodeProgress{Node: node}
+	start := time.Now()
+	if err := fn(&progress); err != nil {
+		progress.Error = err.Error()
+	}
+	progress.Seconds = time.Since(start).Seconds()
+	return progress
+}
+
+// parseDrainOptions validates and parses the tool parameters
+func parseDrainOptions(params map[string]interface{}) (drainOptions, error) {
+	opts := drainOptions{
+		gracePeriodSeconds: -1,
+		timeoutSeconds:     defaultDrainTimeoutSeconds,
+		rollbackOnFailure:  true,
+	}
+
+	operation, ok := params["operation"].(string)
+	if !ok || operation == "" {
+		return opts, fmt.Errorf("missing or invalid operation parameter")
+	}
+	switch operation {
+	case "cordon", "drain", "uncordon":
+		opts.operation = operation
+	default:
+		return opts, fmt.Errorf("invalid operation '%s': must be cordon, drain or uncordon", operation)
+	}
+
+	var err error
+	if value, ok := params["grace_period_seconds"].(string); ok && value != "" {
+		if opts.gracePeriodSeconds, err = strconv.Atoi(value); err != nil || opts.gracePeriodSeconds < 0 {
+			return opts, fmt.Errorf("invalid grace_period_seconds parameter: %s", value)
+		}
+	}
+	if value, ok := params["timeout_seconds"].(string); ok && value != "" {
+		if opts.timeoutSeconds, err = strconv.Atoi(value); err != nil || opts.timeoutSeconds <= 0 {
+			return opts, fmt.Errorf("invalid timeout_seconds parameter: %s", value)
+		}
+	}
+	opts.deleteEmptyDirData = params["delete_emptydir_data"] == "true"
+	opts.force = params["force"] == "true"
+	if params["rollback_on_failure"] == "false" {
+		opts.rollbackOnFailure = false
+	}
+	return opts, nil
+}
+
+// marshalReport renders the drain report as indented JSON
+func marshalReport(report DrainReport) (string, error) {
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal drain report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
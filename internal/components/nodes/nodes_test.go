@@ -0,0 +1,431 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const testNodesJSON = `{"items":[
+	{"metadata":{"name":"aks-user-0","labels":{"agentpool":"user"}},"spec":{},"status":{"conditions":[{"type":"Ready","status":"True"}]}},
+	{"metadata":{"name":"aks-user-1","labels":{"agentpool":"user"}},"spec":{},"status":{"conditions":[{"type":"Ready","status":"True"}]}},
+	{"metadata":{"name":"aks-system-0","labels":{"agentpool":"system"}},"spec":{},"status":{"conditions":[{"type":"Ready","status":"True"}]}}
+]}`
+
+const testPodsJSON = `{"items":[
+	{"metadata":{"name":"web-1","namespace":"apps","labels":{"app":"web"}},"spec":{"nodeName":"aks-user-0"},"status":{"phase":"Running"}},
+	{"metadata":{"name":"agent","namespace":"kube-system","labels":{"app":"web"},"ownerReferences":[{"kind":"DaemonSet"}]},"spec":{"nodeName":"aks-user-0"},"status":{"phase":"Running"}},
+	{"metadata":{"name":"done","namespace":"apps","labels":{"app":"web"}},"spec":{"nodeName":"aks-user-1"},"status":{"phase":"Succeeded"}}
+]}`
+
+// fakeKubectl returns canned output by command prefix and records the commands run
+type fakeKubectl struct {
+	responses map[string]string
+	failures  map[string]bool
+	commands  []string
+}
+
+func (f *fakeKubectl) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for prefix := range f.failures {
+		if strings.HasPrefix(cmd, prefix) {
+			return "error: cannot evict pod", fmt.Errorf("exit status 1")
+		}
+	}
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(cmd, prefix) {
+			return output, nil
+		}
+	}
+	return "", nil
+}
+
+func (f *fakeKubectl) ran(prefix string) bool {
+	for _, cmd := range f.commands {
+		if strings.HasPrefix(cmd, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegisterNodeDrainTool(t *testing.T) {
+	tool := RegisterNodeDrainTool()
+
+	if tool.Name != "aks_node_drain" {
+		t.Errorf("Expected tool name 'aks_node_drain', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 1 || tool.InputSchema.Required[0] != "operation" {
+		t.Errorf("Expected operation to be the only required parameter, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestSelectTargetNodes(t *testing.T) {
+	nodes, err := ParseNodes(testNodesJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	targets, err := SelectTargetNodes(nodes, nil, "user")
+	if err != nil || len(targets) != 2 {
+		t.Fatalf("Expected 2 nodes in the user pool, got %v (%v)", targets, err)
+	}
+	if remaining := RemainingSchedulableNodes(nodes, targets); remaining != 1 {
+		t.Errorf("Expected 1 remaining schedulable node, got %d", remaining)
+	}
+
+	if _, err := SelectTargetNodes(nodes, []string{"missing"}, ""); err == nil {
+		t.Error("Expected error for unknown node")
+	}
+	if _, err := SelectTargetNodes(nodes, nil, ""); err == nil {
+		t.Error("Expected error when no target is given")
+	}
+}
+
+func TestFindBlockingBudgets(t *testing.T) {
+	pods, err := ParsePods(testPodsJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("Expected completed pods to be skipped, got %d pods", len(pods))
+	}
+
+	budgets := []DisruptionBudget{
+		{Namespace: "apps", Name: "web", MatchLabels: map[string]string{"app": "web"}, DisruptionsAllowed: 0},
+		{Namespace: "apps", Name: "healthy", MatchLabels: map[string]string{"app": "web"}, DisruptionsAllowed: 1},
+		{Namespace: "kube-system", Name: "agent", MatchLabels: map[string]string{"app": "web"}, DisruptionsAllowed: 0},
+	}
+	blocking := FindBlockingBudgets(budgets, pods, []NodeInfo{{Name: "aks-user-0"}})
+	if len(blocking) != 1 || blocking[0].Name != "web" || blocking[0].Pods[0] != "apps/web-1" {
+		t.Errorf("Expected only the apps/web budget to block, got %+v", blocking)
+	}
+}
+
+func TestBuildDrainCommand(t *testing.T) {
+	cmd := buildDrainCommand("aks-user-0", drainOptions{gracePeriodSeconds: 30, timeoutSeconds: 120, deleteEmptyDirData: true})
+	expected := "drain aks-user-0 --ignore-daemonsets --timeout=120s --grace-period=30 --delete-emptydir-data"
+	if cmd != expected {
+		t.Errorf("Expected %q, got %q", expected, cmd)
+	}
+	if cmd := buildDrainCommand("n", drainOptions{gracePeriodSeconds: -1, timeoutSeconds: 300}); strings.Contains(cmd, "--grace-period") {
+		t.Errorf("Expected default grace period to be omitted, got %q", cmd)
+	}
+}
+
+func TestHandleNodeDrain(t *testing.T) {
+	cfg := config.NewConfig()
+
+	t.Run("blocking budget aborts drain", func(t *testing.T) {
+		executor := &fakeKubectl{responses: map[string]string{
+			"get nodes":                testNodesJSON,
+			"get pods":                 testPodsJSON,
+			"get poddisruptionbudgets": `{"items":[{"metadata":{"name":"web","namespace":"apps"},"spec":{"selector":{"matchLabels":{"app":"web"}}},"status":{"disruptionsAllowed":0}}]}`,
+		}}
+		output, err := HandleNodeDrain(map[string]interface{}{"operation": "drain", "node_names": "aks-user-0"}, executor, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report DrainReport
+		if err := json.Unmarshal([]byte(output), &report); err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		if !report.Aborted || len(report.BlockingBudgets) != 1 {
+			t.Errorf("Expected drain to be aborted by the budget, got %+v", report)
+		}
+		if executor.ran("cordon") || executor.ran("drain") {
+			t.Error("Expected no cordon or drain after abort")
+		}
+	})
+
+	t.Run("failed drain rolls back cordon", func(t *testing.T) {
+		executor := &fakeKubectl{
+			responses: map[string]string{
+				"get nodes":                testNodesJSON,
+				"get poddisruptionbudgets": `{"items":[]}`,
+			},
+			failures: map[string]bool{"drain aks-user-1": true},
+		}
+		output, err := HandleNodeDrain(map[string]interface{}{"operation": "drain", "nodepool_name": "user"}, executor, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report DrainReport
+		if err := json.Unmarshal([]byte(output), &report); err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		if report.Succeeded {
+			t.Error("Expected drain to fail")
+		}
+		if !report.Progress[0].Drained || report.Progress[1].Error == "" {
+			t.Errorf("Expected first node drained and second failed, got %+v", report.Progress)
+		}
+		for _, progress := range report.Progress {
+			if !progress.RolledBack {
+				t.Errorf("Expected node %s to be uncordoned by rollback", progress.Node)
+			}
+		}
+	})
+
+	t.Run("drain refused with namespace restriction", func(t *testing.T) {
+		restricted := config.NewConfig()
+		restricted.AllowNamespaces = "apps"
+		executor := &fakeKubectl{responses: map[string]string{"get nodes": testNodesJSON}}
+		_, err := HandleNodeDrain(map[string]interface{}{"operation": "drain", "node_names": "aks-user-0"}, executor, restricted)
+		if err == nil || !strings.Contains(err.Error(), "--allow-namespaces") {
+			t.Errorf("Expected drain to be refused with --allow-namespaces, got %v", err)
+		}
+		if len(executor.commands) != 0 {
+			t.Errorf("Expected no kubectl commands, got %v", executor.commands)
+		}
+		if _, err := HandleNodeDrain(map[string]interface{}{"operation": "cordon", "node_names": "aks-user-0"}, executor, restricted); err != nil {
+			t.Errorf("Expected cordon to stay available, got %v", err)
+		}
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		if _, err := HandleNodeDrain(map[string]interface{}{"operation": "delete"}, &fakeKubectl{}, cfg); err == nil {
+			t.Error("Expected error for invalid operation")
+		}
+	})
+}
+
+const testUsageNodesJSON = `{"items":[
+	{"metadata":{"name":"aks-user-0"},"status":{"allocatable":{"cpu":"1900m","memory":"5Gi"}}},
+	{"metadata":{"name":"aks-user-1"},"status":{"allocatable":{"cpu":"1900m","memory":"5Gi"}}}
+]}`
+
+const testUsagePodsJSON = `{"items":[
+	{"metadata":{"name":"api-1","namespace":"apps"},"spec":{"nodeName":"aks-user-0","containers":[
+		{"resources":{"requests":{"cpu":"1","memory":"2Gi"},"limits":{"cpu":"2","memory":"2Gi"}}},
+		{"resources":{"requests":{"cpu":"500m","memory":"512Mi"}}}]},"status":{"phase":"Running"}},
+	{"metadata":{"name":"migrate","namespace":"apps"},"spec":{"nodeName":"aks-user-1","initContainers":[
+		{"resources":{"requests":{"cpu":"300m"}}}],"containers":[
+		{"resources":{"requests":{"cpu":"100m","memory":"128Mi"}}}]},"status":{"phase":"Running"}},
+	{"metadata":{"name":"done","namespace":"apps"},"spec":{"nodeName":"aks-user-1","containers":[
+		{"resources":{"requests":{"cpu":"4"}}}]},"status":{"phase":"Succeeded"}},
+	{"metadata":{"name":"dns","namespace":"kube-system"},"spec":{"nodeName":"aks-user-1","containers":[
+		{"resources":{"requests":{"cpu":"100m","memory":"70Mi"}}}]},"status":{"phase":"Running"}}
+]}`
+
+func TestParsePodReservations(t *testing.T) {
+	pods, err := ParsePodReservations(testUsagePodsJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pods) != 3 {
+		t.Fatalf("Expected finished pods to be skipped, got %d pods", len(pods))
+	}
+	if pods[0].CPURequests != 1500 || pods[0].CPULimits != 2000 || pods[0].MemoryRequests != 2560<<20 {
+		t.Errorf("Expected container requests to be summed, got %+v", pods[0])
+	}
+	if pods[1].CPURequests != 300 || pods[1].MemoryRequests != 128<<20 {
+		t.Errorf("Expected the init container request to win when larger, got %+v", pods[1])
+	}
+}
+
+func TestHandleResourceUsage(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{
+		"get pods":  testUsagePodsJSON,
+		"top pods":  "apps api-1 80m 300Mi\napps migrate 20m 60Mi\nkube-system dns 5m 20Mi\n",
+		"get nodes": testUsageNodesJSON,
+		"top nodes": "aks-user-0 200m 10% 1200Mi 23%\naks-user-1 150m 7% 800Mi 15%\n",
+	}}
+
+	out, err := HandleResourceUsage(map[string]interface{}{}, kubectl, &config.ConfigData{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Nodes (percent of allocatable):",
+		"aks-user-0  1     10%",
+		"Namespaces (usage is percent of requests):",
+		"apps         2     100m",
+		"Node aks-user-0 has 78% of 1900m allocatable CPU requested but uses 200m",
+		"Namespace apps requests 1800m CPU but uses 100m (5%)",
+		"Namespace apps requests 2688Mi memory but uses 360Mi (13%)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Namespace kube-system") {
+		t.Errorf("Expected small namespaces to be left out of the findings, got:\n%s", out)
+	}
+	if strings.Index(out, "aks-user-0") > strings.Index(out, "aks-user-1") {
+		t.Errorf("Expected nodes sorted by requested CPU, got:\n%s", out)
+	}
+}
+
+func TestHandleResourceUsageAllowedNamespaces(t *testing.T) {
+	kubectl := &fakeKubectl{responses: map[string]string{
+		"get pods --namespace apps": testUsagePodsJSON,
+		"top pods --namespace apps": "api-1 80m 300Mi\nmigrate 20m 60Mi\n",
+	}}
+
+	out, err := HandleResourceUsage(map[string]interface{}{"sort_by": "memory"}, kubectl, &config.ConfigData{AllowNamespaces: "apps"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if kubectl.ran("get nodes") || kubectl.ran("top nodes") || strings.Contains(out, "Nodes (") {
+		t.Errorf("Expected node commitment to be skipped with --allow-namespaces, got:\n%s", out)
+	}
+	if !strings.Contains(out, "--allow-namespaces") || !strings.Contains(out, "360Mi") {
+		t.Errorf("Expected namespace usage and a note, got:\n%s", out)
+	}
+
+	if _, err := HandleResourceUsage(map[string]interface{}{"sort_by": "disk"}, kubectl, &config.ConfigData{}); err == nil {
+		t.Error("Expected an error for an unknown sort_by")
+	}
+}
+
+const testNoisyNodeJSON = `{"metadata":{"name":"aks-user-0","labels":{"agentpool":"user","node.kubernetes.io/instance-type":"Standard_D2s_v5"}},
+	"status":{"allocatable":{"cpu":"2","memory":"5Gi"}}}`
+
+const testNoisyPodsJSON = `{"items":[
+	{"metadata":{"name":"cruncher","namespace":"batch"},"spec":{"nodeName":"aks-user-0","containers":[{"resources":{}}]},"status":{"phase":"Running"}},
+	{"metadata":{"name":"api","namespace":"apps"},"spec":{"nodeName":"aks-user-0","containers":[
+		{"resources":{"requests":{"cpu":"100m"},"limits":{"cpu":"200m"}}}]},"status":{"phase":"Running"}},
+	{"metadata":{"name":"postgres","namespace":"db"},"spec":{"nodeName":"aks-user-0","containers":[
+		{"resources":{"requests":{"cpu":"500m"}}}]},"status":{"phase":"Running"}},
+	{"metadata":{"name":"dns","namespace":"kube-system"},"spec":{"nodeName":"aks-user-0","containers":[
+		{"resources":{"requests":{"cpu":"100m"}}}]},"status":{"phase":"Running"}}
+]}`
+
+const testCAdvisorBefore = `# HELP container_cpu_usage_seconds_total Cumulative cpu time consumed in seconds.
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="work",cpu="total",namespace="batch",pod="cruncher"} 100 1700000000000
+container_cpu_usage_seconds_total{container="",namespace="batch",pod="cruncher"} 100
+container_cpu_usage_seconds_total{container="app",namespace="apps",pod="api"} 10
+container_cpu_cfs_periods_total{container="app",namespace="apps",pod="api"} 1000
+container_cpu_cfs_throttled_periods_total{container="app",namespace="apps",pod="api"} 100
+container_cpu_usage_seconds_total{container="postgres",namespace="db",pod="postgres"} 20
+container_fs_reads_total{container="postgres",device="/dev/sda",namespace="db",pod="postgres"} 1000
+container_fs_writes_total{container="postgres",device="/dev/sda",namespace="db",pod="postgres"} 500
+container_cpu_usage_seconds_total{container="coredns",namespace="kube-system",pod="dns"} 5
+container_network_receive_bytes_total{container="POD",interface="eth0",namespace="batch",pod="cruncher"} 1e+06
+container_network_receive_bytes_total{container="",interface="eth0",namespace="batch",pod="cruncher"} 1e+06
+`
+
+const testCAdvisorAfter = `container_cpu_usage_seconds_total{container="work",cpu="total",namespace="batch",pod="cruncher"} 112 1700000010000
+container_cpu_usage_seconds_total{container="",namespace="batch",pod="cruncher"} 112
+container_cpu_usage_seconds_total{container="app",namespace="apps",pod="api"} 11.5
+container_cpu_cfs_periods_total{container="app",namespace="apps",pod="api"} 1100
+container_cpu_cfs_throttled_periods_total{container="app",namespace="apps",pod="api"} 150
+container_cpu_usage_seconds_total{container="postgres",namespace="db",pod="postgres"} 22
+container_fs_reads_total{container="postgres",device="/dev/sda",namespace="db",pod="postgres"} 7000
+container_fs_writes_total{container="postgres",device="/dev/sda",namespace="db",pod="postgres"} 2500
+container_cpu_usage_seconds_total{container="coredns",namespace="kube-system",pod="dns"} 5.5
+container_network_receive_bytes_total{container="POD",interface="eth0",namespace="batch",pod="cruncher"} 1.35e+07
+container_network_receive_bytes_total{container="",interface="eth0",namespace="batch",pod="cruncher"} 1.35e+07
+`
+
+func TestHandleNoisyNeighbors(t *testing.T) {
+	const scrape = "get --raw /api/v1/nodes/aks-user-0/proxy/metrics/cadvisor"
+	kubectl := &fakeKubectl{responses: map[string]string{
+		"get node aks-user-0": testNoisyNodeJSON,
+		"get pods":            testNoisyPodsJSON,
+		scrape:                testCAdvisorBefore,
+	}}
+	var waited time.Duration
+	wait := func(_ context.Context, d time.Duration) error {
+		waited = d
+		kubectl.responses[scrape] = testCAdvisorAfter
+		return nil
+	}
+
+	out, err := HandleNoisyNeighbors(context.Background(), map[string]interface{}{"node_name": "aks-user-0", "sample_seconds": float64(10)}, kubectl, wait, &config.ConfigData{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if waited != 10*time.Second {
+		t.Errorf("Expected a 10s sample, waited %v", waited)
+	}
+	var report NoisyNeighborReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.NodePool != "user" || report.CPUAllocatableMillis != 2000 || report.CPUUsageMillis != 1600 || report.CPUUsagePercent != 80 {
+		t.Errorf("Unexpected node summary %+v", report)
+	}
+	if strings.Join(report.Pressure, ",") != "cpu,disk" {
+		t.Errorf("Expected CPU and disk pressure, got %v", report.Pressure)
+	}
+	if report.DiskIOPS != 800 || report.NetworkMbps != 10 {
+		t.Errorf("Expected 800 IOPS and 10 Mbps, got %v and %v", report.DiskIOPS, report.NetworkMbps)
+	}
+
+	roles := map[string]string{}
+	for _, pod := range report.Pods {
+		roles[pod.Namespace+"/"+pod.Name] = pod.Role
+	}
+	want := map[string]string{"batch/cruncher": RoleOffender, "db/postgres": RoleOffender, "apps/api": RoleVictim, "kube-system/dns": ""}
+	for name, role := range want {
+		if roles[name] != role {
+			t.Errorf("Expected %s to be %q, got %q", name, role, roles[name])
+		}
+	}
+	if report.Pods[0].Name != "cruncher" || report.Pods[len(report.Pods)-1].Name != "dns" {
+		t.Errorf("Expected offenders first and uninvolved pods last, got %+v", report.Pods)
+	}
+	for _, want := range []string{
+		"Raise the CPU request of batch/cruncher to at least 1200m so the scheduler reserves what it uses, and set a CPU limit",
+		"Move db/postgres to a dedicated node pool",
+		"Raise the CPU limit of apps/api above 200m",
+		"apps/api was throttled in 50% of its CPU periods while the node is CPU bound",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Move batch/cruncher") {
+		t.Errorf("Expected a CPU-only offender to get request changes rather than a pool move, got:\n%s", out)
+	}
+}
+
+func TestHandleNoisyNeighborsErrors(t *testing.T) {
+	noWait := func(context.Context, time.Duration) error { return nil }
+	for name, tc := range map[string]struct {
+		params map[string]interface{}
+		cfg    *config.ConfigData
+	}{
+		"missing node":       {map[string]interface{}{}, &config.ConfigData{}},
+		"invalid node":       {map[string]interface{}{"node_name": "aks; rm"}, &config.ConfigData{}},
+		"short sample":       {map[string]interface{}{"node_name": "aks-user-0", "sample_seconds": float64(1)}, &config.ConfigData{}},
+		"negative limit":     {map[string]interface{}{"node_name": "aks-user-0", "disk_iops_limit": float64(-5)}, &config.ConfigData{}},
+		"allowed namespaces": {map[string]interface{}{"node_name": "aks-user-0"}, &config.ConfigData{AllowNamespaces: "apps"}},
+	} {
+		kubectl := &fakeKubectl{}
+		if _, err := HandleNoisyNeighbors(context.Background(), tc.params, kubectl, noWait, tc.cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if len(kubectl.commands) != 0 {
+			t.Errorf("%s: expected no kubectl commands, got %v", name, kubectl.commands)
+		}
+	}
+}
+
+func TestAnalyzeNoisyNeighborsDiskLimit(t *testing.T) {
+	pods := []PodReservation{{Namespace: "db", Name: "postgres"}}
+	first := ParseCAdvisorCounters(`container_fs_writes_total{container="pg",namespace="db",pod="postgres"} 0`)
+	second := ParseCAdvisorCounters(`container_fs_writes_total{container="pg",namespace="db",pod="postgres"} 3000`)
+
+	// 300 IOPS is below the default busy level but 94% of a 320 IOPS limit
+	report := NoisyNeighborReport{Node: "aks-user-0", DiskIOPSLimit: 320}
+	AnalyzeNoisyNeighbors(&report, pods, first, second, 10)
+	if len(report.Pressure) != 1 || report.Pressure[0] != PressureDisk || report.Pods[0].Role != RoleOffender {
+		t.Errorf("Expected disk pressure against the given limit, got %+v", report)
+	}
+
+	report = NoisyNeighborReport{Node: "aks-user-0"}
+	AnalyzeNoisyNeighbors(&report, pods, first, second, 10)
+	if len(report.Pressure) != 0 || report.Pods[0].Role != "" || !strings.Contains(report.Findings[0], "No pod") {
+		t.Errorf("Expected no pressure without a limit, got %+v", report)
+	}
+}
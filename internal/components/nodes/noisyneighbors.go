@@ -0,0 +1,483 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// Sampling window between the two cAdvisor scrapes rates are computed from
+	defaultSampleSeconds = 15
+	minSampleSeconds     = 5
+	maxSampleSeconds     = 120
+	// cpuPressurePercent is the share of allocatable CPU in use above which the node counts as CPU bound
+	cpuPressurePercent = 80
+	// cpuBurstFactor is how many times its request a pod must use to count as bursting onto its neighbors
+	cpuBurstFactor = 2
+	// cpuOffenderSharePercent is the share of the node's pod CPU use a bursting pod must have to be flagged
+	cpuOffenderSharePercent = 20
+	// throttledVictimPercent is the share of CFS periods throttled above which a pod counts as degraded
+	throttledVictimPercent = 25
+	// saturationPercent is the share of a given disk or network limit above which the node counts as saturated
+	saturationPercent = 80
+	// defaultBusyIOPS and defaultBusyMbps stand in for the limits when they are not given
+	defaultBusyIOPS = 500
+	defaultBusyMbps = 1000
+	// ioOffenderSharePercent is the share of the node's disk or network use a pod must have to be flagged
+	ioOffenderSharePercent = 50
+)
+
+// Roles of a pod in a noisy neighbor report
+const (
+	RoleOffender = "offender"
+	RoleVictim   = "victim"
+)
+
+// Resources a node can be under pressure on
+const (
+	PressureCPU     = "cpu"
+	PressureDisk    = "disk"
+	PressureNetwork = "network"
+)
+
+// PodPressure is what a pod on the node used during the sample and how it affects its neighbors
+type PodPressure struct {
+	Namespace        string   `json:"namespace"`
+	Name             string   `json:"name"`
+	Role             string   `json:"role,omitempty"`
+	CPURequestMillis int64    `json:"cpuRequestMillis"`
+	CPULimitMillis   int64    `json:"cpuLimitMillis,omitempty"`
+	CPUUsageMillis   int64    `json:"cpuUsageMillis"`
+	CPUThrottledPct  float64  `json:"cpuThrottledPercent"`
+	DiskIOPS         float64  `json:"diskIops"`
+	NetworkMbps      float64  `json:"networkMbps"`
+	CPUSharePercent  float64  `json:"cpuSharePercent"`
+	DiskSharePercent float64  `json:"diskSharePercent"`
+	NetworkSharePct  float64  `json:"networkSharePercent"`
+	Signals          []string `json:"signals,omitempty"`
+	Suggestions      []string `json:"suggestions,omitempty"`
+}
+
+// NoisyNeighborReport is the result of the aks_noisy_neighbors tool
+type NoisyNeighborReport struct {
+	Node                 string        `json:"node"`
+	NodePool             string        `json:"nodePool,omitempty"`
+	InstanceType         string        `json:"instanceType,omitempty"`
+	SampleSeconds        float64       `json:"sampleSeconds"`
+	CPUAllocatableMillis int64         `json:"cpuAllocatableMillis"`
+	CPUUsageMillis       int64         `json:"cpuUsageMillis"`
+	CPUUsagePercent      float64       `json:"cpuUsagePercent"`
+	DiskIOPS             float64       `json:"diskIops"`
+	DiskIOPSLimit        float64       `json:"diskIopsLimit,omitempty"`
+	NetworkMbps          float64       `json:"networkMbps"`
+	NetworkMbpsLimit     float64       `json:"networkMbpsLimit,omitempty"`
+	Pressure             []string      `json:"pressure"`
+	Pods                 []PodPressure `json:"pods"`
+	Findings             []string      `json:"findings"`
+	Notes                []string      `json:"notes,omitempty"`
+}
+
+// podCounters are the cumulative cAdvisor counters of a pod at one scrape
+type podCounters struct {
+	cpuSeconds      float64
+	throttled       float64
+	periods         float64
+	fsOps           float64
+	networkBytes    float64
+	networkSeen     map[string]float64
+	hasCPU, hasDisk bool
+}
+
+// GetNoisyNeighborsHandler returns a handler for the aks_noisy_neighbors command
+func GetNoisyNeighborsHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleNoisyNeighbors(ctx, params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), sleepContext, cfg)
+	})
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// HandleNoisyNeighbors scrapes the node's cAdvisor metrics twice, sample_seconds apart, and combines the CPU
+// throttling, disk operations and network traffic of each pod with its requests to find pods degrading their
+// neighbors. wait is called between the scrapes.
+func HandleNoisyNeighbors(ctx context.Context, params map[string]interface{}, executor tools.CommandExecutor, wait func(context.Context, time.Duration) error, cfg *config.ConfigData) (string, error) {
+	nodeName, _ := params["node_name"].(string)
+	if nodeName == "" || !validNodeName(nodeName) {
+		return "", fmt.Errorf("invalid node_name %q", nodeName)
+	}
+	if cfg.AllowNamespaces != "" {
+		return "", fmt.Errorf("noisy neighbor detection compares the pods of every namespace on the node, so it is not available when the server is restricted with --allow-namespaces")
+	}
+	sampleSeconds := defaultSampleSeconds
+	if value, ok := params["sample_seconds"].(float64); ok {
+		if value < minSampleSeconds || value > maxSampleSeconds {
+			return "", fmt.Errorf("sample_seconds must be between %d and %d", minSampleSeconds, maxSampleSeconds)
+		}
+		sampleSeconds = int(value)
+	}
+	report := NoisyNeighborReport{Node: nodeName}
+	for _, limit := range []struct {
+		name  string
+		value *float64
+	}{{"disk_iops_limit", &report.DiskIOPSLimit}, {"network_mbps_limit", &report.NetworkMbpsLimit}} {
+		if value, ok := params[limit.name].(float64); ok {
+			if value <= 0 {
+				return "", fmt.Errorf("%s must be positive", limit.name)
+			}
+			*limit.value = value
+		}
+	}
+
+	kubectlRun := func(command string) (string, error) {
+		return executor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+	nodeOutput, err := kubectlRun("get node " + nodeName + " -o json")
+	if err != nil {
+		return "", fmt.Errorf("failed to get node %s: %v", nodeName, err)
+	}
+	var node struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(nodeOutput), &node); err != nil {
+		return "", fmt.Errorf("failed to parse node %s: %v", nodeName, err)
+	}
+	report.NodePool = node.Metadata.Labels["agentpool"]
+	report.InstanceType = node.Metadata.Labels["node.kubernetes.io/instance-type"]
+	report.CPUAllocatableMillis = common.CPUMillis(node.Status.Allocatable["cpu"])
+
+	podsOutput, err := kubectlRun("get pods --all-namespaces --field-selector spec.nodeName=" + nodeName + " -o json")
+	if err != nil {
+		return "", fmt.Errorf("failed to list the pods on node %s: %v", nodeName, err)
+	}
+	pods, err := ParsePodReservations(podsOutput)
+	if err != nil {
+		return "", err
+	}
+
+	scrape := "get --raw /api/v1/nodes/" + nodeName + "/proxy/metrics/cadvisor"
+	first, err := kubectlRun(scrape)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cAdvisor metrics of node %s, check that the credential may get nodes/proxy: %v", nodeName, err)
+	}
+	start := time.Now()
+	if err := wait(ctx, time.Duration(sampleSeconds)*time.Second); err != nil {
+		return "", err
+	}
+	second, err := kubectlRun(scrape)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cAdvisor metrics of node %s: %v", nodeName, err)
+	}
+	// The configured window is used when the scrapes ran faster than it, as they do with a fake wait
+	elapsed := math.Max(time.Since(start).Seconds(), float64(sampleSeconds))
+	report.SampleSeconds = math.Round(elapsed*10) / 10
+
+	AnalyzeNoisyNeighbors(&report, pods, ParseCAdvisorCounters(first), ParseCAdvisorCounters(second), elapsed)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal noisy neighbor report: %v", err)
+	}
+	return string(data), nil
+}
+
+// validNodeName reports whether a node name is a valid DNS subdomain, so it can be placed in commands and paths
+func validNodeName(name string) bool {
+	if len(name) > 253 {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case (r == '-' || r == '.') && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParseCAdvisorCounters sums the CPU, CFS throttling, filesystem operation and network counters of the
+// Prometheus text exposition of the kubelet's cAdvisor endpoint per namespace/pod
+func ParseCAdvisorCounters(output string) map[string]*podCounters {
+	counters := make(map[string]*podCounters)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, value, ok := parseSample(line)
+		if !ok || labels["namespace"] == "" || labels["pod"] == "" {
+			continue
+		}
+		key := labels["namespace"] + "/" + labels["pod"]
+		c := counters[key]
+		if c == nil {
+			c = &podCounters{networkSeen: make(map[string]float64)}
+			counters[key] = c
+		}
+		// Container series are summed; pod-level series (no container, or the POD sandbox) would double count them
+		container := labels["container"]
+		inContainer := container != "" && container != "POD"
+		switch name {
+		case "container_cpu_usage_seconds_total":
+			if inContainer {
+				c.cpuSeconds += value
+				c.hasCPU = true
+			}
+		case "container_cpu_cfs_throttled_periods_total":
+			if inContainer {
+				c.throttled += value
+			}
+		case "container_cpu_cfs_periods_total":
+			if inContainer {
+				c.periods += value
+			}
+		case "container_fs_reads_total", "container_fs_writes_total":
+			if inContainer {
+				c.fsOps += value
+				c.hasDisk = true
+			}
+		case "container_network_receive_bytes_total", "container_network_transmit_bytes_total":
+			// Network is only reported for the pod sandbox, sometimes under several container labels
+			series := name + "|" + labels["interface"]
+			if value > c.networkSeen[series] {
+				c.networkBytes += value - c.networkSeen[series]
+				c.networkSeen[series] = value
+			}
+		}
+	}
+	return counters
+}
+
+// parseSample splits a Prometheus text sample into its metric name, labels and value
+func parseSample(line string) (string, map[string]string, float64, bool) {
+	labels := map[string]string{}
+	name, rest := line, ""
+	if open := strings.IndexByte(line, '{'); open >= 0 {
+		closing := strings.LastIndexByte(line, '}')
+		if closing < open {
+			return "", nil, 0, false
+		}
+		name, rest = line[:open], strings.TrimSpace(line[closing+1:])
+		body := line[open+1 : closing]
+		for body != "" {
+			eq := strings.IndexByte(body, '=')
+			if eq < 0 || eq+1 >= len(body) || body[eq+1] != '"' {
+				return "", nil, 0, false
+			}
+			key := strings.TrimSpace(body[:eq])
+			var value strings.Builder
+			i := eq + 2
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				value.WriteByte(body[i])
+			}
+			labels[key] = value.String()
+			body = strings.TrimPrefix(strings.TrimSpace(body[min(i+1, len(body)):]), ",")
+		}
+	} else if space := strings.IndexByte(line, ' '); space >= 0 {
+		name, rest = line[:space], strings.TrimSpace(line[space+1:])
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, false
+	}
+	return name, labels, value, true
+}
+
+// AnalyzeNoisyNeighbors fills the report with the rates of each pod between the two scrapes and flags the
+// pods that degrade their neighbors and the pods that are degraded
+func AnalyzeNoisyNeighbors(report *NoisyNeighborReport, pods []PodReservation, first, second map[string]*podCounters, seconds float64) {
+	rate := func(before, after float64) float64 {
+		// Counters restart with their container
+		if after < before || seconds <= 0 {
+			return 0
+		}
+		return (after - before) / seconds
+	}
+
+	report.Pressure = []string{}
+	report.Findings = []string{}
+	var cpuTotal int64
+	var diskTotal, networkTotal float64
+	noMetrics := 0
+	for _, pod := range pods {
+		key := pod.Namespace + "/" + pod.Name
+		before, after := first[key], second[key]
+		row := PodPressure{Namespace: pod.Namespace, Name: pod.Name, CPURequestMillis: pod.CPURequests, CPULimitMillis: pod.CPULimits}
+		if before == nil || after == nil {
+			noMetrics++
+		} else {
+			row.CPUUsageMillis = int64(math.Round(rate(before.cpuSeconds, after.cpuSeconds) * 1000))
+			if periods := after.periods - before.periods; periods > 0 && after.throttled >= before.throttled {
+				row.CPUThrottledPct = round1(100 * (after.throttled - before.throttled) / periods)
+			}
+			row.DiskIOPS = round1(rate(before.fsOps, after.fsOps))
+			row.NetworkMbps = round1(rate(before.networkBytes, after.networkBytes) * 8 / 1e6)
+		}
+		cpuTotal += row.CPUUsageMillis
+		diskTotal += row.DiskIOPS
+		networkTotal += row.NetworkMbps
+		report.Pods = append(report.Pods, row)
+	}
+	report.CPUUsageMillis = cpuTotal
+	report.DiskIOPS = round1(diskTotal)
+	report.NetworkMbps = round1(networkTotal)
+	if report.CPUAllocatableMillis > 0 {
+		report.CPUUsagePercent = round1(100 * float64(cpuTotal) / float64(report.CPUAllocatableMillis))
+	}
+	if noMetrics > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d pods had no cAdvisor metrics in both scrapes, for example because they started during the sample; their use counts as zero", noMetrics))
+	}
+
+	cpuPressure := report.CPUUsagePercent >= cpuPressurePercent
+	diskBusy, diskLimit := busy(report.DiskIOPS, report.DiskIOPSLimit, defaultBusyIOPS)
+	networkBusy, networkLimit := busy(report.NetworkMbps, report.NetworkMbpsLimit, defaultBusyMbps)
+	if cpuPressure {
+		report.Pressure = append(report.Pressure, PressureCPU)
+		report.Findings = append(report.Findings, fmt.Sprintf("Node %s uses %.0f%% of its allocatable CPU: pods without enough CPU requested compete for the rest", report.Node, report.CPUUsagePercent))
+	}
+	if diskBusy {
+		report.Pressure = append(report.Pressure, PressureDisk)
+		report.Findings = append(report.Findings, fmt.Sprintf("Pods on node %s perform %.0f disk operations per second, %s", report.Node, report.DiskIOPS, limitDescription(report.DiskIOPS, report.DiskIOPSLimit, diskLimit, "IOPS")))
+	}
+	if networkBusy {
+		report.Pressure = append(report.Pressure, PressureNetwork)
+		report.Findings = append(report.Findings, fmt.Sprintf("Pods on node %s move %.0f Mbps over the network, %s", report.Node, report.NetworkMbps, limitDescription(report.NetworkMbps, report.NetworkMbpsLimit, networkLimit, "Mbps")))
+	}
+
+	for i := range report.Pods {
+		pod := &report.Pods[i]
+		pod.CPUSharePercent = share(float64(pod.CPUUsageMillis), float64(cpuTotal))
+		pod.DiskSharePercent = share(pod.DiskIOPS, diskTotal)
+		pod.NetworkSharePct = share(pod.NetworkMbps, networkTotal)
+		name := pod.Namespace + "/" + pod.Name
+		// Requests and limits cannot bound disk and network use, so those workloads need nodes of their own
+		unbounded := false
+
+		if cpuPressure && pod.CPUSharePercent >= cpuOffenderSharePercent && (pod.CPURequestMillis == 0 || pod.CPUUsageMillis >= cpuBurstFactor*pod.CPURequestMillis) {
+			pod.Signals = append(pod.Signals, fmt.Sprintf("uses %dm CPU (%.0f%% of the node's pod CPU) against a request of %dm", pod.CPUUsageMillis, pod.CPUSharePercent, pod.CPURequestMillis))
+			suggestion := fmt.Sprintf("Raise the CPU request of %s to at least %dm so the scheduler reserves what it uses", name, pod.CPUUsageMillis)
+			if pod.CPULimitMillis == 0 {
+				suggestion += ", and set a CPU limit to cap its bursts"
+			}
+			pod.Suggestions = append(pod.Suggestions, suggestion)
+		}
+		if diskBusy && pod.DiskSharePercent >= ioOffenderSharePercent {
+			pod.Signals = append(pod.Signals, fmt.Sprintf("performs %.0f disk operations per second (%.0f%% of the node's)", pod.DiskIOPS, pod.DiskSharePercent))
+			unbounded = true
+		}
+		if networkBusy && pod.NetworkSharePct >= ioOffenderSharePercent {
+			pod.Signals = append(pod.Signals, fmt.Sprintf("moves %.0f Mbps over the network (%.0f%% of the node's)", pod.NetworkMbps, pod.NetworkSharePct))
+			unbounded = true
+		}
+		if len(pod.Signals) > 0 {
+			pod.Role = RoleOffender
+			if unbounded || len(pod.Signals) > 1 {
+				pod.Suggestions = append(pod.Suggestions, fmt.Sprintf("Move %s to a dedicated node pool: add a pool with --node-taints dedicated=%s:NoSchedule --labels dedicated=%s, then give the workload a matching toleration and nodeSelector",
+					name, pod.Namespace, pod.Namespace))
+			}
+			report.Findings = append(report.Findings, fmt.Sprintf("%s degrades its neighbors: it %s", name, strings.Join(pod.Signals, ", ")))
+			continue
+		}
+
+		if pod.CPUThrottledPct >= throttledVictimPercent {
+			pod.Role = RoleVictim
+			pod.Signals = append(pod.Signals, fmt.Sprintf("was throttled in %.0f%% of its CPU periods", pod.CPUThrottledPct))
+			if pod.CPULimitMillis > 0 {
+				pod.Suggestions = append(pod.Suggestions, fmt.Sprintf("Raise the CPU limit of %s above %dm, or remove it and rely on the request", name, pod.CPULimitMillis))
+			}
+			finding := fmt.Sprintf("%s was throttled in %.0f%% of its CPU periods", name, pod.CPUThrottledPct)
+			if cpuPressure {
+				finding += " while the node is CPU bound"
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+
+	sort.SliceStable(report.Pods, func(i, j int) bool {
+		a, b := report.Pods[i], report.Pods[j]
+		if roleRank(a.Role) != roleRank(b.Role) {
+			return roleRank(a.Role) < roleRank(b.Role)
+		}
+		if a.CPUUsageMillis != b.CPUUsageMillis {
+			return a.CPUUsageMillis > b.CPUUsageMillis
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	if len(report.Findings) == 0 {
+		report.Findings = append(report.Findings, fmt.Sprintf("No pod on node %s degraded its neighbors during the sample", report.Node))
+	}
+}
+
+// busy reports whether use reaches saturationPercent of the given limit, or the default level when no limit is given
+func busy(use, limit, fallback float64) (bool, float64) {
+	if limit > 0 {
+		return use*100 >= limit*saturationPercent, limit
+	}
+	return use >= fallback, fallback
+}
+
+// limitDescription describes use against the given limit, or against the default busy level
+func limitDescription(use, given, limit float64, unit string) string {
+	if given > 0 {
+		return fmt.Sprintf("%.0f%% of the %.0f %s limit", 100*use/limit, limit, unit)
+	}
+	return fmt.Sprintf("above the %.0f %s treated as busy when no limit is given", limit, unit)
+}
+
+// roleRank orders offenders before victims before the other pods
+func roleRank(role string) int {
+	switch role {
+	case RoleOffender:
+		return 0
+	case RoleVictim:
+		return 1
+	}
+	return 2
+}
+
+// share returns part as a percentage of total, rounded to one decimal
+func share(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round1(100 * part / total)
+}
+
+// round1 rounds to one decimal
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
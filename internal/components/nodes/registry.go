@@ -0,0 +1,120 @@
+package nodes
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterNodeDrainTool registers the aks_node_drain tool
+func RegisterNodeDrainTool() mcp.Tool {
+	description := `Cordon, drain or uncordon AKS nodes with safety checks.
+
+Target either a list of nodes (node_names) or every node in a node pool (nodepool_name).
+
+Safety checks performed before draining:
+- PodDisruptionBudgets that currently allow no disruptions and cover pods on the target nodes
+- At least one schedulable node remains outside the target set
+
+Nodes are drained one at a time using PDB-aware eviction. If a drain fails and rollback_on_failure is true (default),
+every node cordoned by this call is uncordoned again. Uses the current kubeconfig context.
+
+Requires admin access because kubectl cordon, uncordon and drain are admin operations.
+Drain is refused when the server is restricted with --allow-namespaces, since it evicts pods from every namespace.`
+
+	return mcp.NewTool(
+		"aks_node_drain",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform: cordon, drain or uncordon"),
+			mcp.Enum("cordon", "drain", "uncordon"),
+			mcp.Required(),
+		),
+		mcp.WithString("node_names",
+			mcp.Description("Comma-separated list of node names to target"),
+		),
+		mcp.WithString("nodepool_name",
+			mcp.Description("Name of the node pool whose nodes should be targeted (matched by the agentpool label)"),
+		),
+		mcp.WithString("grace_period_seconds",
+			mcp.Description("Grace period for pod termination in seconds (default: the pod's own terminationGracePeriodSeconds)"),
+		),
+		mcp.WithString("timeout_seconds",
+			mcp.Description("Maximum time to wait for each node to drain (default: 300)"),
+		),
+		mcp.WithString("delete_emptydir_data",
+			mcp.Description("Allow evicting pods that use emptyDir volumes, deleting their local data (default: false)"),
+			mcp.Enum("true", "false"),
+		),
+		mcp.WithString("force",
+			mcp.Description("Proceed even if PodDisruptionBudgets currently allow no disruptions (default: false)"),
+			mcp.Enum("true", "false"),
+		),
+		mcp.WithString("rollback_on_failure",
+			mcp.Description("Uncordon the nodes cordoned by this call if draining fails (default: true)"),
+			mcp.Enum("true", "false"),
+		),
+	)
+}
+
+// RegisterResourceUsageTool registers the aks_resource_usage tool
+func RegisterResourceUsageTool() mcp.Tool {
+	description := `Compare CPU and memory use with what pods reserve, per node and per namespace.
+
+Merges kubectl top nodes and kubectl top pods with the requests and limits of running pods and the allocatable
+resources of each node, and returns compact tables sorted by requested CPU or memory:
+- Nodes: use, requests and limits as a percentage of allocatable
+- Namespaces: use and requests, and use as a percentage of requests
+
+Findings list nodes with at least 70% of allocatable requested but less than 30% of the requests in use, and
+namespaces using less than 30% of what they request. Requires metrics-server and uses the current kubeconfig context.
+With --allow-namespaces only the allowed namespaces are read and the node table is omitted.`
+
+	return mcp.NewTool(
+		"aks_resource_usage",
+		mcp.WithDescription(description),
+		mcp.WithString("sort_by",
+			mcp.Description("Resource to sort rows by, largest request first (default: cpu)"),
+			mcp.Enum("cpu", "memory"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum rows per table (default: 20)"),
+		),
+	)
+}
+
+// RegisterNoisyNeighborsTool registers the aks_noisy_neighbors tool
+func RegisterNoisyNeighborsTool() mcp.Tool {
+	description := `Find pods on a node that degrade their neighbors, and the pods they degrade.
+
+Reads the node's cAdvisor metrics twice, sample_seconds apart, and combines per pod:
+- CPU use against its request and limit, and the share of CFS periods it was throttled in
+- Disk read and write operations per second
+- Network receive and transmit bandwidth
+
+Heuristics:
+- Offender: on a node using at least 80% of its allocatable CPU, a pod with at least 20% of the CPU use that has no
+  CPU request or uses twice its request; or, on a node near its disk or network limit, a pod with half of that use
+- Victim: a pod throttled in at least 25% of its CPU periods
+
+Suggests request and limit changes, and moving disk or network heavy workloads to a dedicated node pool. Pass the
+disk and network limits of the VM size to judge saturation against them; without them 500 IOPS and 1000 Mbps count
+as busy. Requires get access to nodes/proxy and uses the current kubeconfig context. Not available with
+--allow-namespaces, since it reads the pods of every namespace on the node.`
+
+	return mcp.NewTool(
+		"aks_noisy_neighbors",
+		mcp.WithDescription(description),
+		mcp.WithString("node_name",
+			mcp.Description("Name of the node to analyze"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("sample_seconds",
+			mcp.Description("Seconds between the two metric scrapes rates are computed from, 5 to 120 (default: 15)"),
+		),
+		mcp.WithNumber("disk_iops_limit",
+			mcp.Description("Uncached disk IOPS limit of the node's VM size"),
+		),
+		mcp.WithNumber("network_mbps_limit",
+			mcp.Description("Network bandwidth limit of the node's VM size in Mbps"),
+		),
+	)
+}
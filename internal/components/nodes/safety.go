@@ -0,0 +1,272 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NodeInfo is the subset of node state needed to plan a drain
+type NodeInfo struct {
+	Name          string `json:"name"`
+	NodePool      string `json:"nodePool,omitempty"`
+	Unschedulable bool   `json:"unschedulable"`
+	Ready         bool   `json:"ready"`
+}
+
+// PodInfo identifies a pod running on a node
+type PodInfo struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	NodeName  string            `json:"nodeName"`
+	Labels    map[string]string `json:"-"`
+	DaemonSet bool              `json:"-"`
+	// TerminationGracePeriodSeconds is how long an evicted pod may take to shut down
+	TerminationGracePeriodSeconds int64 `json:"-"`
+}
+
+// DisruptionBudget is the subset of a PodDisruptionBudget needed for safety checks
+type DisruptionBudget struct {
+	Namespace          string            `json:"namespace"`
+	Name               string            `json:"name"`
+	MatchLabels        map[string]string `json:"-"`
+	DisruptionsAllowed int               `json:"disruptionsAllowed"`
+}
+
+// BlockingBudget is a PodDisruptionBudget that would block eviction of pods on the target nodes
+type BlockingBudget struct {
+	Namespace          string   `json:"namespace"`
+	Name               string   `json:"name"`
+	DisruptionsAllowed int      `json:"disruptionsAllowed"`
+	Pods               []string `json:"pods"`
+}
+
+// ParseNodes parses kubectl get nodes JSON output
+func ParseNodes(output string) ([]NodeInfo, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Unschedulable bool `json:"unschedulable"`
+			} `json:"spec"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+
+	nodes := make([]NodeInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		node := NodeInfo{
+			Name:          item.Metadata.Name,
+			NodePool:      item.Metadata.Labels["agentpool"],
+			Unschedulable: item.Spec.Unschedulable,
+		}
+		for _, cond := range item.Status.Conditions {
+			if cond.Type == "Ready" {
+				node.Ready = cond.Status == "True"
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// ParsePods parses kubectl get pods JSON output
+func ParsePods(output string) ([]PodInfo, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				Labels          map[string]string `json:"labels"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName                      string `json:"nodeName"`
+				TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds"`
+			} `json:"spec"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %v", err)
+	}
+
+	pods := make([]PodInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		if item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		pod := PodInfo{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			NodeName:  item.Spec.NodeName,
+			Labels:    item.Metadata.Labels,
+			// The API server defaults the grace period to 30 seconds
+			TerminationGracePeriodSeconds: 30,
+		}
+		if item.Spec.TerminationGracePeriodSeconds != nil {
+			pod.TerminationGracePeriodSeconds = *item.Spec.TerminationGracePeriodSeconds
+		}
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Kind == "DaemonSet" {
+				pod.DaemonSet = true
+			}
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// ParseDisruptionBudgets parses kubectl get poddisruptionbudgets JSON output.
+// Only matchLabels selectors are evaluated; budgets using matchExpressions match any pod in their namespace.
+func ParseDisruptionBudgets(output string) ([]DisruptionBudget, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Selector *struct {
+					MatchLabels map[string]string `json:"matchLabels"`
+				} `json:"selector"`
+			} `json:"spec"`
+			Status struct {
+				DisruptionsAllowed int `json:"disruptionsAllowed"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod disruption budgets: %v", err)
+	}
+
+	budgets := make([]DisruptionBudget, 0, len(list.Items))
+	for _, item := range list.Items {
+		budget := DisruptionBudget{
+			Namespace:          item.Metadata.Namespace,
+			Name:               item.Metadata.Name,
+			DisruptionsAllowed: item.Status.DisruptionsAllowed,
+		}
+		if item.Spec.Selector != nil {
+			budget.MatchLabels = item.Spec.Selector.MatchLabels
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, nil
+}
+
+// SelectTargetNodes resolves the nodes to operate on from explicit names or a node pool
+func SelectTargetNodes(nodes []NodeInfo, names []string, nodePool string) ([]NodeInfo, error) {
+	byName := make(map[string]NodeInfo, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	var targets []NodeInfo
+	for _, name := range names {
+		node, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("node '%s' not found", name)
+		}
+		targets = append(targets, node)
+	}
+	if nodePool != "" {
+		for _, node := range nodes {
+			if node.NodePool == nodePool && !containsNode(targets, node.Name) {
+				targets = append(targets, node)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("no nodes found in node pool '%s'", nodePool)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("either node_names or nodepool_name must be provided")
+	}
+	return targets, nil
+}
+
+// RemainingSchedulableNodes counts Ready, schedulable nodes outside the target set
+func RemainingSchedulableNodes(nodes, targets []NodeInfo) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Ready && !node.Unschedulable && !containsNode(targets, node.Name) {
+			count++
+		}
+	}
+	return count
+}
+
+// FindBlockingBudgets returns the budgets allowing no disruptions that cover evictable pods on the target nodes
+func FindBlockingBudgets(budgets []DisruptionBudget, pods []PodInfo, targets []NodeInfo) []BlockingBudget {
+	var blocking []BlockingBudget
+	for _, budget := range budgets {
+		if budget.DisruptionsAllowed > 0 {
+			continue
+		}
+		var covered []string
+		for _, pod := range pods {
+			if pod.DaemonSet || pod.Namespace != budget.Namespace || !containsNode(targets, pod.NodeName) {
+				continue
+			}
+			if labelsMatch(budget.MatchLabels, pod.Labels) {
+				covered = append(covered, pod.Namespace+"/"+pod.Name)
+			}
+		}
+		if len(covered) > 0 {
+			blocking = append(blocking, BlockingBudget{
+				Namespace:          budget.Namespace,
+				Name:               budget.Name,
+				DisruptionsAllowed: budget.DisruptionsAllowed,
+				Pods:               covered,
+			})
+		}
+	}
+	return blocking
+}
+
+// labelsMatch reports whether every selector label is present on the pod
+func labelsMatch(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// containsNode reports whether a node with the given name is in the list
+func containsNode(nodes []NodeInfo, name string) bool {
+	for _, node := range nodes {
+		if node.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNameList splits a comma-separated list, dropping empty entries
+func parseNameList(value string) []string {
+	var names []string
+	for _, part := range strings.Split(value, ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,426 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// defaultUsageRows bounds the rows of each table when limit is not given
+	defaultUsageRows = 20
+	// highCommitmentPercent is the share of a node's allocatable resources requested before it counts as highly committed
+	highCommitmentPercent = 70
+	// lowUsagePercent is the share of requested resources in use below which a reservation counts as mostly idle
+	lowUsagePercent = 30
+	// minFlaggedCPUMillis and minFlaggedMemoryBytes keep small namespaces out of the over-provisioning findings
+	minFlaggedCPUMillis   = 500
+	minFlaggedMemoryBytes = 1 << 30
+)
+
+// ResourceUsage compares what is used with what is reserved for a node or namespace.
+// CPU is in millicores and memory in bytes. Allocatable is only set for nodes.
+type ResourceUsage struct {
+	Name              string `json:"name"`
+	Pods              int    `json:"pods"`
+	CPUUsage          int64  `json:"cpuUsageMillis"`
+	CPURequests       int64  `json:"cpuRequestsMillis"`
+	CPULimits         int64  `json:"cpuLimitsMillis"`
+	CPUAllocatable    int64  `json:"cpuAllocatableMillis,omitempty"`
+	MemoryUsage       int64  `json:"memoryUsageBytes"`
+	MemoryRequests    int64  `json:"memoryRequestsBytes"`
+	MemoryLimits      int64  `json:"memoryLimitsBytes"`
+	MemoryAllocatable int64  `json:"memoryAllocatableBytes,omitempty"`
+}
+
+// UsageReport is the utilization versus reservation of the cluster's nodes and namespaces
+type UsageReport struct {
+	Nodes      []ResourceUsage `json:"nodes,omitempty"`
+	Namespaces []ResourceUsage `json:"namespaces"`
+	Findings   []string        `json:"findings"`
+	Note       string          `json:"note,omitempty"`
+}
+
+// PodReservation is what a running pod reserves and where it runs
+type PodReservation struct {
+	Namespace      string
+	Name           string
+	Node           string
+	CPURequests    int64
+	CPULimits      int64
+	MemoryRequests int64
+	MemoryLimits   int64
+}
+
+// GetResourceUsageHandler returns a handler for the aks_resource_usage command
+func GetResourceUsageHandler(cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleResourceUsage(params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleResourceUsage merges kubectl top with pod requests and limits into per node and per namespace tables
+func HandleResourceUsage(params map[string]interface{}, executor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	sortBy, _ := params["sort_by"].(string)
+	if sortBy == "" {
+		sortBy = "cpu"
+	}
+	if sortBy != "cpu" && sortBy != "memory" {
+		return "", fmt.Errorf("invalid sort_by %q: expected cpu or memory", sortBy)
+	}
+	limit := defaultUsageRows
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = int(n)
+	}
+
+	kubectlRun := func(command string) (string, error) {
+		return executor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+
+	var pods []PodReservation
+	podUsage := make(map[string][2]int64)
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		output, err := kubectlRun("get pods " + flag + " -o json")
+		if err != nil {
+			return "", fmt.Errorf("failed to list pods: %v", err)
+		}
+		parsed, err := ParsePodReservations(output)
+		if err != nil {
+			return "", err
+		}
+		pods = append(pods, parsed...)
+
+		output, err = kubectlRun("top pods " + flag + " --no-headers")
+		if err != nil {
+			return "", fmt.Errorf("failed to read pod usage, check that metrics-server is running: %v", err)
+		}
+		namespace := strings.TrimPrefix(flag, "--namespace ")
+		if flag == "--all-namespaces" {
+			namespace = ""
+		}
+		if err := ParseTopPods(output, namespace, podUsage); err != nil {
+			return "", err
+		}
+	}
+
+	report := UsageReport{Namespaces: AggregateNamespaces(pods, podUsage)}
+
+	// Node commitment counts the pods of every namespace, so it is only shown when all of them are visible
+	if cfg.AllowNamespaces == "" {
+		nodesOutput, err := kubectlRun("get nodes -o json")
+		if err != nil {
+			return "", fmt.Errorf("failed to list nodes: %v", err)
+		}
+		topOutput, err := kubectlRun("top nodes --no-headers")
+		if err != nil {
+			return "", fmt.Errorf("failed to read node usage, check that metrics-server is running: %v", err)
+		}
+		report.Nodes, err = AggregateNodes(nodesOutput, topOutput, pods)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		report.Note = "Node commitment is omitted because the server is restricted with --allow-namespaces and cannot see every pod on a node."
+	}
+
+	report.Findings = UsageFindings(report)
+	sortUsage(report.Nodes, sortBy)
+	sortUsage(report.Namespaces, sortBy)
+	return FormatUsageReport(report, limit), nil
+}
+
+// ParsePodReservations reads the requests and limits of the running pods in kubectl get pods -o json output.
+// A pod reserves the larger of its containers' sum and its largest init container, as the scheduler does.
+func ParsePodReservations(output string) ([]PodReservation, error) {
+	type container struct {
+		Resources struct {
+			Requests map[string]string `json:"requests"`
+			Limits   map[string]string `json:"limits"`
+		} `json:"resources"`
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName       string      `json:"nodeName"`
+				Containers     []container `json:"containers"`
+				InitContainers []container `json:"initContainers"`
+			} `json:"spec"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %v", err)
+	}
+
+	var pods []PodReservation
+	for _, item := range list.Items {
+		if item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		pod := PodReservation{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, Node: item.Spec.NodeName}
+		var initCPURequests, initCPULimits, initMemoryRequests, initMemoryLimits int64
+		for _, c := range item.Spec.Containers {
+			pod.CPURequests += common.CPUMillis(c.Resources.Requests["cpu"])
+			pod.CPULimits += common.CPUMillis(c.Resources.Limits["cpu"])
+			pod.MemoryRequests += common.MemoryBytes(c.Resources.Requests["memory"])
+			pod.MemoryLimits += common.MemoryBytes(c.Resources.Limits["memory"])
+		}
+		for _, c := range item.Spec.InitContainers {
+			initCPURequests = max(initCPURequests, common.CPUMillis(c.Resources.Requests["cpu"]))
+			initCPULimits = max(initCPULimits, common.CPUMillis(c.Resources.Limits["cpu"]))
+			initMemoryRequests = max(initMemoryRequests, common.MemoryBytes(c.Resources.Requests["memory"]))
+			initMemoryLimits = max(initMemoryLimits, common.MemoryBytes(c.Resources.Limits["memory"]))
+		}
+		pod.CPURequests = max(pod.CPURequests, initCPURequests)
+		pod.CPULimits = max(pod.CPULimits, initCPULimits)
+		pod.MemoryRequests = max(pod.MemoryRequests, initMemoryRequests)
+		pod.MemoryLimits = max(pod.MemoryLimits, initMemoryLimits)
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// ParseTopPods adds the CPU and memory use in kubectl top pods --no-headers output to usage, keyed by namespace/name.
+// Output for a single namespace has no namespace column, so namespace names it; it is empty for --all-namespaces output.
+func ParseTopPods(output, namespace string, usage map[string][2]int64) error {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		ns := namespace
+		if ns == "" {
+			if len(fields) < 4 {
+				return fmt.Errorf("unexpected kubectl top pods output: %q", line)
+			}
+			ns, fields = fields[0], fields[1:]
+		}
+		if len(fields) < 3 {
+			return fmt.Errorf("unexpected kubectl top pods output: %q", line)
+		}
+		usage[ns+"/"+fields[0]] = [2]int64{common.CPUMillis(fields[1]), common.MemoryBytes(fields[2])}
+	}
+	return nil
+}
+
+// AggregateNamespaces sums pod use and reservations per namespace
+func AggregateNamespaces(pods []PodReservation, usage map[string][2]int64) []ResourceUsage {
+	byNamespace := make(map[string]*ResourceUsage)
+	for _, pod := range pods {
+		row, ok := byNamespace[pod.Namespace]
+		if !ok {
+			row = &ResourceUsage{Name: pod.Namespace}
+			byNamespace[pod.Namespace] = row
+		}
+		row.add(pod, usage[pod.Namespace+"/"+pod.Name])
+	}
+
+	rows := make([]ResourceUsage, 0, len(byNamespace))
+	for _, row := range byNamespace {
+		rows = append(rows, *row)
+	}
+	return rows
+}
+
+// AggregateNodes combines node allocatable resources and kubectl top nodes output with the reservations of the pods on each node
+func AggregateNodes(nodesOutput, topOutput string, pods []PodReservation) ([]ResourceUsage, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(nodesOutput), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+
+	byNode := make(map[string]*ResourceUsage, len(list.Items))
+	rows := make([]*ResourceUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		row := &ResourceUsage{
+			Name:              item.Metadata.Name,
+			CPUAllocatable:    common.CPUMillis(item.Status.Allocatable["cpu"]),
+			MemoryAllocatable: common.MemoryBytes(item.Status.Allocatable["memory"]),
+		}
+		byNode[row.Name] = row
+		rows = append(rows, row)
+	}
+
+	// kubectl top nodes reports NAME CPU(cores) CPU% MEMORY(bytes) MEMORY%
+	for _, line := range strings.Split(strings.TrimSpace(topOutput), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		if row, ok := byNode[fields[0]]; ok {
+			row.CPUUsage = common.CPUMillis(fields[1])
+			row.MemoryUsage = common.MemoryBytes(fields[3])
+		}
+	}
+
+	for _, pod := range pods {
+		if row, ok := byNode[pod.Node]; ok {
+			// Node use comes from kubectl top nodes, which includes system daemons
+			row.add(pod, [2]int64{})
+		}
+	}
+
+	result := make([]ResourceUsage, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	return result, nil
+}
+
+// add counts a pod's reservations and use in the row
+func (r *ResourceUsage) add(pod PodReservation, usage [2]int64) {
+	r.Pods++
+	r.CPURequests += pod.CPURequests
+	r.CPULimits += pod.CPULimits
+	r.MemoryRequests += pod.MemoryRequests
+	r.MemoryLimits += pod.MemoryLimits
+	r.CPUUsage += usage[0]
+	r.MemoryUsage += usage[1]
+}
+
+// UsageFindings lists highly committed nodes that are mostly idle and namespaces that use little of what they request
+func UsageFindings(report UsageReport) []string {
+	findings := []string{}
+	for _, node := range report.Nodes {
+		for _, res := range []struct {
+			name                      string
+			used, requested, capacity int64
+			format                    func(int64) string
+		}{
+			{"CPU", node.CPUUsage, node.CPURequests, node.CPUAllocatable, formatCPU},
+			{"memory", node.MemoryUsage, node.MemoryRequests, node.MemoryAllocatable, formatMemory},
+		} {
+			if res.capacity == 0 || res.requested*100 < res.capacity*highCommitmentPercent || res.used*100 >= res.requested*lowUsagePercent {
+				continue
+			}
+			findings = append(findings, fmt.Sprintf("Node %s has %s of %s allocatable %s requested but uses %s: new pods may not schedule while reserved capacity sits idle",
+				node.Name, percent(res.requested, res.capacity), res.format(res.capacity), res.name, res.format(res.used)))
+		}
+	}
+	for _, ns := range report.Namespaces {
+		for _, res := range []struct {
+			name            string
+			used, requested int64
+			floor           int64
+			format          func(int64) string
+		}{
+			{"CPU", ns.CPUUsage, ns.CPURequests, minFlaggedCPUMillis, formatCPU},
+			{"memory", ns.MemoryUsage, ns.MemoryRequests, minFlaggedMemoryBytes, formatMemory},
+		} {
+			if res.requested < res.floor || res.used*100 >= res.requested*lowUsagePercent {
+				continue
+			}
+			findings = append(findings, fmt.Sprintf("Namespace %s requests %s %s but uses %s (%s): lowering requests would release %s",
+				ns.Name, res.format(res.requested), res.name, res.format(res.used), percent(res.used, res.requested), res.format(res.requested-res.used)))
+		}
+	}
+	return findings
+}
+
+// FormatUsageReport renders the report as compact tables with at most limit rows each, followed by the findings
+func FormatUsageReport(report UsageReport, limit int) string {
+	var b strings.Builder
+	if report.Nodes != nil {
+		b.WriteString("Nodes (percent of allocatable):\n")
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tPODS\tCPU USED\tCPU REQ\tCPU LIM\tMEM USED\tMEM REQ\tMEM LIM")
+		for i, node := range report.Nodes {
+			if i == limit {
+				fmt.Fprintf(w, "... %d more\n", len(report.Nodes)-limit)
+				break
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", node.Name, node.Pods,
+				percent(node.CPUUsage, node.CPUAllocatable), percent(node.CPURequests, node.CPUAllocatable), percent(node.CPULimits, node.CPUAllocatable),
+				percent(node.MemoryUsage, node.MemoryAllocatable), percent(node.MemoryRequests, node.MemoryAllocatable), percent(node.MemoryLimits, node.MemoryAllocatable))
+		}
+		_ = w.Flush()
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Namespaces (usage is percent of requests):\n")
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tPODS\tCPU USED\tCPU REQ\tCPU USE%\tMEM USED\tMEM REQ\tMEM USE%")
+	for i, ns := range report.Namespaces {
+		if i == limit {
+			fmt.Fprintf(w, "... %d more\n", len(report.Namespaces)-limit)
+			break
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", ns.Name, ns.Pods,
+			formatCPU(ns.CPUUsage), formatCPU(ns.CPURequests), percent(ns.CPUUsage, ns.CPURequests),
+			formatMemory(ns.MemoryUsage), formatMemory(ns.MemoryRequests), percent(ns.MemoryUsage, ns.MemoryRequests))
+	}
+	_ = w.Flush()
+
+	if report.Note != "" {
+		b.WriteString("\n" + report.Note + "\n")
+	}
+	if len(report.Findings) > 0 {
+		b.WriteString("\nFindings:\n")
+		for _, finding := range report.Findings {
+			b.WriteString("- " + finding + "\n")
+		}
+	}
+	return b.String()
+}
+
+// sortUsage orders rows by requested CPU or memory, largest first
+func sortUsage(rows []ResourceUsage, sortBy string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if sortBy == "memory" {
+			if rows[i].MemoryRequests != rows[j].MemoryRequests {
+				return rows[i].MemoryRequests > rows[j].MemoryRequests
+			}
+		} else if rows[i].CPURequests != rows[j].CPURequests {
+			return rows[i].CPURequests > rows[j].CPURequests
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+// formatCPU renders millicores the way kubectl top does
+func formatCPU(millis int64) string {
+	return fmt.Sprintf("%dm", millis)
+}
+
+// formatMemory renders bytes in mebibytes, or gibibytes above 10Gi
+func formatMemory(bytes int64) string {
+	if bytes >= 10<<30 {
+		return fmt.Sprintf("%.1fGi", float64(bytes)/(1<<30))
+	}
+	return fmt.Sprintf("%dMi", bytes>>20)
+}
+
+// percent renders part as a whole percentage of total, or - when total is zero
+func percent(part, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", part*100/total)
+}
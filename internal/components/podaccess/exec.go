@@ -0,0 +1,111 @@
+// Package podaccess provides admin-only tools for one-shot commands in containers and
+// time-boxed port-forward sessions. Every attempt, including denied ones, is audited.
+package podaccess
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+	"github.com/google/shlex"
+)
+
+// GetPodExecHandler returns a handler for the aks_pod_exec command
+func GetPodExecHandler(auditLog *audit.Logger, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandlePodExec(params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), auditLog, cfg)
+	})
+}
+
+// HandlePodExec runs an allowlisted command once in a container and returns its output
+func HandlePodExec(params map[string]interface{}, executor tools.CommandExecutor, auditLog *audit.Logger, cfg *config.ConfigData) (string, error) {
+	namespace, _ := params["namespace"].(string)
+	pod, _ := params["pod"].(string)
+	container, _ := params["container"].(string)
+	command, _ := params["command"].(string)
+
+	record := audit.Record{Tool: "aks_pod_exec", Action: "exec", Namespace: namespace, Target: pod, Command: command, Client: cfg.ClientName()}
+	if container != "" {
+		record.Target += "/" + container
+	}
+
+	argv, err := validateExec(namespace, pod, container, command, cfg)
+	if err != nil {
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+		auditLog.Log(record)
+		return "", err
+	}
+
+	output, err := executor.Execute(map[string]interface{}{
+		"command": buildExecCommand(namespace, pod, container, argv),
+	}, cfg)
+	if err != nil {
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+		auditLog.Log(record)
+		return "", fmt.Errorf("command failed in %s/%s: %v\n%s", namespace, pod, err, strings.TrimSpace(output))
+	}
+	record.Outcome = audit.OutcomeSucceeded
+	auditLog.Log(record)
+	return output, nil
+}
+
+// validateExec checks the target against the namespace restrictions and the command against
+// the allowlist, returning the command split into arguments
+func validateExec(namespace, pod, container, command string, cfg *config.ConfigData) ([]string, error) {
+	if err := validateNamespace(namespace, cfg); err != nil {
+		return nil, err
+	}
+	if !common.NamePattern.MatchString(pod) {
+		return nil, fmt.Errorf("missing or invalid pod parameter")
+	}
+	if container != "" && !common.NamePattern.MatchString(container) {
+		return nil, fmt.Errorf("invalid container parameter: %s", container)
+	}
+
+	argv, err := shlex.Split(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command parameter: %v", err)
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("missing command parameter")
+	}
+	// Match on the binary name so /bin/ls and ls are treated alike
+	if binary := path.Base(argv[0]); !slices.Contains(cfg.ExecAllowedCommands, binary) {
+		return nil, fmt.Errorf("command '%s' is not allowed; allowed commands: %s (configure with --exec-allowed-commands)",
+			binary, strings.Join(cfg.ExecAllowedCommands, ", "))
+	}
+	return argv, nil
+}
+
+// validateNamespace checks that a namespace is named and allowed by --allow-namespaces
+func validateNamespace(namespace string, cfg *config.ConfigData) error {
+	if !common.NamespacePattern.MatchString(namespace) {
+		return fmt.Errorf("missing or invalid namespace parameter")
+	}
+	if !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(namespace) {
+		return fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+	}
+	return nil
+}
+
+// buildExecCommand builds the kubectl exec command, quoting each argument so it reaches
+// the container unchanged and without a shell interpreting it
+func buildExecCommand(namespace, pod, container string, argv []string) string {
+	cmd := fmt.Sprintf("exec %s --namespace %s", pod, namespace)
+	if container != "" {
+		cmd += " --container " + container
+	}
+	cmd += " --"
+	for _, arg := range argv {
+		cmd += " " + azcli.QuoteArg(arg)
+	}
+	return cmd
+}
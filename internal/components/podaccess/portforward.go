@@ -0,0 +1,346 @@
+package podaccess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// Port-forward session limits
+const (
+	defaultForwardSeconds = 300
+	maxForwardSeconds     = 1800
+	maxForwardSessions    = 5
+	// startupGrace is how long Start waits for kubectl to fail fast, e.g. when the pod does not exist
+	startupGrace = time.Second
+)
+
+// resourcePattern matches the resources kubectl port-forward accepts
+var resourcePattern = regexp.MustCompile(`^(pod|pods|po|service|services|svc|deployment|deployments|deploy)/([a-z0-9]([-a-z0-9.]*[a-z0-9])?)$`)
+
+// ForwardSession describes a port-forward session
+type ForwardSession struct {
+	ID           string    `json:"id"`
+	Namespace    string    `json:"namespace"`
+	Resource     string    `json:"resource"`
+	LocalAddress string    `json:"localAddress"`
+	RemotePort   int       `json:"remotePort"`
+	StartedAt    time.Time `json:"startedAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// forwardStarter starts kubectl with the given arguments. The process must stop when ctx is done;
+// wait blocks until it has exited.
+type forwardStarter func(ctx context.Context, args []string) (wait func() error, err error)
+
+// forwardEntry is an active session and the means to end it
+type forwardEntry struct {
+	session ForwardSession
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	// client is the API key the session was started with, recorded when it ends
+	client string
+}
+
+// PortForwardManager runs time-boxed kubectl port-forward sessions and tears them down
+// when they expire, are stopped or the server shuts down
+type PortForwardManager struct {
+	mu       sync.Mutex
+	seq      int
+	sessions map[string]*forwardEntry
+	auditLog *audit.Logger
+	start    forwardStarter
+	freePort func() (int, error)
+	grace    time.Duration
+}
+
+// NewPortForwardManager creates a manager that starts sessions with the kubectl binary
+func NewPortForwardManager(auditLog *audit.Logger) *PortForwardManager {
+	return &PortForwardManager{
+		sessions: make(map[string]*forwardEntry),
+		auditLog: auditLog,
+		start:    startKubectl,
+		freePort: freeLocalPort,
+		grace:    startupGrace,
+	}
+}
+
+// GetPortForwardHandler returns a handler for the aks_port_forward command
+func GetPortForwardHandler(m *PortForwardManager, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandlePortForward(params, m, cfg)
+	})
+}
+
+// HandlePortForward starts, stops or lists port-forward sessions
+func HandlePortForward(params map[string]interface{}, m *PortForwardManager, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	switch operation {
+	case "start":
+		if cfg.Review != nil {
+			return "", review.Unsupported("A port-forward")
+		}
+		namespace, _ := params["namespace"].(string)
+		resource, _ := params["resource"].(string)
+		localPort, err := parsePort(params, "local_port", false)
+		if err != nil {
+			return "", err
+		}
+		remotePort, err := parsePort(params, "remote_port", true)
+		if err != nil {
+			return "", err
+		}
+		seconds := defaultForwardSeconds
+		if value, ok := params["duration_seconds"].(string); ok && value != "" {
+			if seconds, err = strconv.Atoi(value); err != nil || seconds <= 0 || seconds > maxForwardSeconds {
+				return "", fmt.Errorf("invalid duration_seconds parameter: %s (must be between 1 and %d)", value, maxForwardSeconds)
+			}
+		}
+		session, err := m.Start(namespace, resource, localPort, remotePort, time.Duration(seconds)*time.Second, cfg)
+		if err != nil {
+			return "", err
+		}
+		return marshal(session)
+	case "stop":
+		id, _ := params["session_id"].(string)
+		session, err := m.Stop(id)
+		if err != nil {
+			return "", err
+		}
+		return marshal(session)
+	case "list":
+		return marshal(m.List())
+	default:
+		return "", fmt.Errorf("invalid operation '%s': must be start, stop or list", operation)
+	}
+}
+
+// Start validates the target and starts a port-forward bound to 127.0.0.1 that ends after duration.
+// A localPort of 0 picks a free port.
+func (m *PortForwardManager) Start(namespace, resource string, localPort, remotePort int, duration time.Duration, cfg *config.ConfigData) (ForwardSession, error) {
+	record := audit.Record{Tool: "aks_port_forward", Action: "start", Namespace: namespace, Target: resource, Client: cfg.ClientName()}
+	deny := func(err error) (ForwardSession, error) {
+		record.Outcome, record.Error = audit.OutcomeDenied, err.Error()
+		m.auditLog.Log(record)
+		return ForwardSession{}, err
+	}
+
+	if err := validateNamespace(namespace, cfg); err != nil {
+		return deny(err)
+	}
+	if !resourcePattern.MatchString(resource) {
+		return deny(fmt.Errorf("invalid resource parameter '%s': expected pod/<name>, service/<name> or deployment/<name>", resource))
+	}
+	m.mu.Lock()
+	if len(m.sessions) >= maxForwardSessions {
+		m.mu.Unlock()
+		return deny(fmt.Errorf("at most %d port-forward sessions may run at once; stop one first", maxForwardSessions))
+	}
+	m.mu.Unlock()
+
+	if localPort == 0 {
+		port, err := m.freePort()
+		if err != nil {
+			return ForwardSession{}, fmt.Errorf("failed to find a free local port: %v", err)
+		}
+		localPort = port
+	}
+	args := []string{
+		"port-forward", resource, fmt.Sprintf("%d:%d", localPort, remotePort),
+		"--namespace", namespace, "--address", "127.0.0.1",
+	}
+	record.Command = "kubectl " + strings.Join(args, " ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	wait, err := m.start(ctx, args)
+	if err != nil {
+		cancel()
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+		m.auditLog.Log(record)
+		return ForwardSession{}, fmt.Errorf("failed to start port-forward: %v", err)
+	}
+
+	now := time.Now().UTC()
+	m.mu.Lock()
+	m.seq++
+	entry := &forwardEntry{
+		session: ForwardSession{
+			ID:           fmt.Sprintf("pf-%d", m.seq),
+			Namespace:    namespace,
+			Resource:     resource,
+			LocalAddress: fmt.Sprintf("127.0.0.1:%d", localPort),
+			RemotePort:   remotePort,
+			StartedAt:    now,
+			ExpiresAt:    now.Add(duration),
+		},
+		cancel: cancel,
+		client: record.Client,
+	}
+	m.sessions[entry.session.ID] = entry
+	m.mu.Unlock()
+
+	exited := make(chan error, 1)
+	go func() {
+		err := wait()
+		exited <- err
+		m.finish(ctx, entry, err)
+	}()
+
+	// kubectl exits at once when the target cannot be reached
+	select {
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("kubectl port-forward exited")
+		}
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+		m.auditLog.Log(record)
+		return ForwardSession{}, fmt.Errorf("port-forward failed: %v", err)
+	case <-time.After(m.grace):
+	}
+	m.mu.Lock()
+	entry.started = true
+	m.mu.Unlock()
+
+	record.Outcome = audit.OutcomeSucceeded
+	m.auditLog.Log(record)
+	return entry.session, nil
+}
+
+// finish removes an ended session and audits why it ended
+func (m *PortForwardManager) finish(ctx context.Context, entry *forwardEntry, err error) {
+	entry.cancel()
+	m.mu.Lock()
+	delete(m.sessions, entry.session.ID)
+	started, stopped := entry.started, entry.stopped
+	m.mu.Unlock()
+	if !started {
+		// Start has already audited the failure
+		return
+	}
+
+	record := audit.Record{
+		Tool:      "aks_port_forward",
+		Action:    "end",
+		Namespace: entry.session.Namespace,
+		Target:    entry.session.Resource,
+		Command:   entry.session.ID,
+		Outcome:   audit.OutcomeSucceeded,
+		Client:    entry.client,
+	}
+	switch {
+	case stopped:
+		record.Action = "stop"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		record.Action = "expire"
+	case err != nil:
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+	}
+	m.auditLog.Log(record)
+}
+
+// Stop ends a session before it expires
+func (m *PortForwardManager) Stop(id string) (ForwardSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.sessions[id]
+	if !ok {
+		return ForwardSession{}, fmt.Errorf("port-forward session '%s' not found", id)
+	}
+	entry.stopped = true
+	entry.cancel()
+	return entry.session, nil
+}
+
+// List returns the active sessions ordered by start time
+func (m *PortForwardManager) List() []ForwardSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]ForwardSession, 0, len(m.sessions))
+	for _, entry := range m.sessions {
+		sessions = append(sessions, entry.session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.Before(sessions[j].StartedAt) })
+	return sessions
+}
+
+// Close stops every session, for use when the server shuts down
+func (m *PortForwardManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entry := range m.sessions {
+		entry.stopped = true
+		entry.cancel()
+	}
+}
+
+// parsePort parses an optional or required port parameter
+func parsePort(params map[string]interface{}, name string, required bool) (int, error) {
+	value, _ := params[name].(string)
+	if value == "" {
+		if required {
+			return 0, fmt.Errorf("missing %s parameter", name)
+		}
+		return 0, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid %s parameter: %s", name, value)
+	}
+	return port, nil
+}
+
+// startKubectl starts kubectl in the background, killing it when ctx is done
+func startKubectl(ctx context.Context, args []string) (func() error, error) {
+	// #nosec G204: arguments are validated and passed without a shell
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return func() error {
+		err := cmd.Wait()
+		if err != nil && ctx.Err() == nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%v: %s", err, msg)
+			}
+			return err
+		}
+		return nil
+	}, nil
+}
+
+// freeLocalPort asks the kernel for an unused loopback port
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// marshal renders a result as indented JSON
+func marshal(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
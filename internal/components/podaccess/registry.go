@@ -0,0 +1,79 @@
+package podaccess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterPodExecTool registers the aks_pod_exec tool
+func RegisterPodExecTool(cfg *config.ConfigData) mcp.Tool {
+	description := fmt.Sprintf(`Run a single command in a running container (kubectl exec) and return its output.
+
+The command runs once without a TTY or stdin and without a shell, so pipes, redirects and && are not
+interpreted. Only these binaries may be run: %s.
+Namespaces restricted with --allow-namespaces are enforced, and every attempt, allowed or denied,
+is written to the audit log. Requires admin access. Uses the current kubeconfig context.`,
+		strings.Join(cfg.ExecAllowedCommands, ", "))
+
+	return mcp.NewTool(
+		"aks_pod_exec",
+		mcp.WithDescription(description),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace of the pod"),
+			mcp.Required(),
+		),
+		mcp.WithString("pod",
+			mcp.Description("Name of the pod"),
+			mcp.Required(),
+		),
+		mcp.WithString("container",
+			mcp.Description("Container to run the command in (default: the pod's default container)"),
+		),
+		mcp.WithString("command",
+			mcp.Description("Command and arguments, e.g. \"nslookup kubernetes.default\" or \"ls -la /etc\""),
+			mcp.Required(),
+		),
+	)
+}
+
+// RegisterPortForwardTool registers the aks_port_forward tool
+func RegisterPortForwardTool() mcp.Tool {
+	description := fmt.Sprintf(`Start, stop or list time-boxed kubectl port-forward sessions to a pod, service or deployment.
+
+Sessions listen on 127.0.0.1 of the machine running aks-mcp and are torn down automatically after
+duration_seconds (default %d, at most %d), when stopped, or when the server exits. At most %d
+sessions may run at once. Namespaces restricted with --allow-namespaces are enforced, and session
+starts, stops and expiries are written to the audit log. Requires admin access.`,
+		defaultForwardSeconds, maxForwardSeconds, maxForwardSessions)
+
+	return mcp.NewTool(
+		"aks_port_forward",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform: start, stop or list"),
+			mcp.Enum("start", "stop", "list"),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace of the target (start)"),
+		),
+		mcp.WithString("resource",
+			mcp.Description("Target to forward to, e.g. pod/web-0, service/web or deployment/web (start)"),
+		),
+		mcp.WithString("remote_port",
+			mcp.Description("Port on the target to forward to (start)"),
+		),
+		mcp.WithString("local_port",
+			mcp.Description("Local port to listen on (start, default: a free port)"),
+		),
+		mcp.WithString("duration_seconds",
+			mcp.Description(fmt.Sprintf("How long the session stays open (start, default: %d)", defaultForwardSeconds)),
+		),
+		mcp.WithString("session_id",
+			mcp.Description("ID of the session to stop (stop)"),
+		),
+	)
+}
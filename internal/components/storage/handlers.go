@@ -0,0 +1,501 @@
+// Package storage provides a tool for the storage accounts AKS diagnostics, backups and log export write
+// artifacts to: access validation, artifact listing and download links.
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const (
+	// storageAPIVersion is the Microsoft.Storage API version used to read the account
+	storageAPIVersion = "2023-05-01"
+	// sasVersion is the signed version of generated SAS links, which decides the string-to-sign layout
+	sasVersion = "2022-11-02"
+
+	defaultMaxResults    = 100
+	maxMaxResults        = 1000
+	defaultExpiryMinutes = 60
+	maxExpiryMinutes     = 24 * 60
+	// clockSkew backdates SAS start times so links work on clients whose clocks run behind
+	clockSkew = 5 * time.Minute
+)
+
+// Features that write artifacts to storage
+const (
+	FeaturePeriscope = "periscope"
+	FeatureLogExport = "diagnostic-settings"
+	FeatureBackup    = "backup"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Client reads the storage account through ARM and calls its Blob service. *azureclient.AzureClient implements it.
+type Client interface {
+	CallARM(ctx context.Context, method, path string) ([]byte, error)
+	CallBlobService(ctx context.Context, method, url string, body []byte) ([]byte, error)
+}
+
+// AccountInfo summarizes the storage account settings that decide whether artifacts can be read
+type AccountInfo struct {
+	Name                  string `json:"name"`
+	Location              string `json:"location"`
+	Kind                  string `json:"kind,omitempty"`
+	SKU                   string `json:"sku,omitempty"`
+	BlobEndpoint          string `json:"blobEndpoint"`
+	PublicNetworkAccess   string `json:"publicNetworkAccess,omitempty"`
+	NetworkDefaultAction  string `json:"networkDefaultAction,omitempty"`
+	SharedKeyAccess       *bool  `json:"sharedKeyAccess,omitempty"`
+	HierarchicalNamespace bool   `json:"hierarchicalNamespace,omitempty"`
+}
+
+// AccessCheck is one access validation step
+type AccessCheck struct {
+	Check          string `json:"check"`
+	Status         string `json:"status"`
+	Detail         string `json:"detail"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+// ContainerInfo is a blob container of the account
+type ContainerInfo struct {
+	Name         string `json:"name"`
+	Feature      string `json:"feature,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// BlobInfo is a blob of a container
+type BlobInfo struct {
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"contentType,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// SASLink is a read-only download link for a blob
+type SASLink struct {
+	URL         string    `json:"url"`
+	Permissions string    `json:"permissions"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ArtifactsResult is the result of the az_storage_artifacts tool
+type ArtifactsResult struct {
+	Operation  string          `json:"operation"`
+	Account    AccountInfo     `json:"account"`
+	Container  string          `json:"container,omitempty"`
+	Feature    string          `json:"feature,omitempty"`
+	Checks     []AccessCheck   `json:"checks,omitempty"`
+	Containers []ContainerInfo `json:"containers,omitempty"`
+	Blobs      []BlobInfo      `json:"blobs,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"`
+	Link       *SASLink        `json:"link,omitempty"`
+}
+
+// UserDelegationKey is a key obtained with Entra ID credentials to sign SAS links
+type UserDelegationKey struct {
+	SignedOid     string `xml:"SignedOid"`
+	SignedTid     string `xml:"SignedTid"`
+	SignedStart   string `xml:"SignedStart"`
+	SignedExpiry  string `xml:"SignedExpiry"`
+	SignedService string `xml:"SignedService"`
+	SignedVersion string `xml:"SignedVersion"`
+	Value         string `xml:"Value"`
+}
+
+// storageAccount is the part of the Microsoft.Storage account resource the tool reads
+type storageAccount struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Kind     string `json:"kind"`
+	SKU      struct {
+		Name string `json:"name"`
+	} `json:"sku"`
+	Properties struct {
+		PrimaryEndpoints struct {
+			Blob string `json:"blob"`
+		} `json:"primaryEndpoints"`
+		PublicNetworkAccess  string `json:"publicNetworkAccess"`
+		AllowSharedKeyAccess *bool  `json:"allowSharedKeyAccess"`
+		IsHnsEnabled         bool   `json:"isHnsEnabled"`
+		NetworkACLs          struct {
+			DefaultAction string `json:"defaultAction"`
+		} `json:"networkAcls"`
+	} `json:"properties"`
+}
+
+// enumerationResults is a Blob service container or blob listing
+type enumerationResults struct {
+	Containers []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified string `xml:"Last-Modified"`
+		} `xml:"Properties"`
+	} `xml:"Containers>Container"`
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified  string `xml:"Last-Modified"`
+			ContentLength int64  `xml:"Content-Length"`
+			ContentType   string `xml:"Content-Type"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// GetStorageArtifactsHandler returns a handler for the az_storage_artifacts tool
+func GetStorageArtifactsHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleStorageArtifacts(params, client, time.Now())
+	})
+}
+
+// HandleStorageArtifacts dispatches a storage artifact operation
+func HandleStorageArtifacts(params map[string]interface{}, client Client, now time.Time) (string, error) {
+	operation, _ := params["operation"].(string)
+	if operation != OpValidate && operation != OpList && operation != OpSAS {
+		return "", fmt.Errorf("missing or invalid operation parameter: must be %s, %s or %s", OpValidate, OpList, OpSAS)
+	}
+	values := map[string]string{}
+	for _, name := range []string{"subscription_id", "resource_group", "account_name", "container", "prefix", "blob"} {
+		values[name], _ = params[name].(string)
+	}
+	for _, name := range []string{"subscription_id", "resource_group", "account_name"} {
+		if values[name] == "" {
+			return "", fmt.Errorf("missing or invalid %s parameter", name)
+		}
+	}
+	for _, name := range []string{"subscription_id", "resource_group", "account_name", "container"} {
+		if strings.ContainsAny(values[name], "/?#&%") {
+			return "", fmt.Errorf("invalid %s '%s'", name, values[name])
+		}
+	}
+	container, blob := values["container"], values["blob"]
+	if operation == OpSAS && (container == "" || blob == "") {
+		return "", fmt.Errorf("operation '%s' requires the container and blob parameters", OpSAS)
+	}
+
+	ctx := context.Background()
+	account, err := GetAccount(ctx, client, values["subscription_id"], values["resource_group"], values["account_name"])
+	if err != nil {
+		return "", err
+	}
+	result := ArtifactsResult{Operation: operation, Account: account, Container: container, Feature: ClassifyContainer(container)}
+
+	switch operation {
+	case OpValidate:
+		result.Checks = validateAccess(ctx, client, account, container, now)
+	case OpList:
+		limit := boundedNumber(params["max_results"], defaultMaxResults, maxMaxResults)
+		if container == "" {
+			result.Containers, result.Truncated, err = listContainers(ctx, client, account.BlobEndpoint, values["prefix"], limit)
+		} else {
+			result.Blobs, result.Truncated, err = listBlobs(ctx, client, account.BlobEndpoint, container, values["prefix"], limit)
+		}
+		if err != nil {
+			return "", describeStorageError(err, container)
+		}
+	case OpSAS:
+		expiry := now.Add(time.Duration(boundedNumber(params["expiry_minutes"], defaultExpiryMinutes, maxExpiryMinutes)) * time.Minute)
+		blobURL := account.BlobEndpoint + container + "/" + escapeBlobName(blob)
+		if _, err := client.CallBlobService(ctx, http.MethodHead, blobURL, nil); err != nil {
+			return "", describeStorageError(err, container)
+		}
+		key, err := getUserDelegationKey(ctx, client, account.BlobEndpoint, now.Add(-clockSkew), expiry)
+		if err != nil {
+			return "", describeStorageError(err, container)
+		}
+		query, err := BuildUserDelegationSAS(account.Name, container, blob, key, now.Add(-clockSkew), expiry)
+		if err != nil {
+			return "", err
+		}
+		result.Link = &SASLink{URL: blobURL + "?" + query, Permissions: "r", ExpiresAt: expiry.UTC().Truncate(time.Second)}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal storage artifacts to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// GetAccount reads the storage account and its Blob endpoint, which ends with a slash
+func GetAccount(ctx context.Context, client Client, subID, rg, name string) (AccountInfo, error) {
+	data, err := client.CallARM(ctx, http.MethodGet, fmt.Sprintf(
+		"/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Storage/storageAccounts/%s?api-version=%s", subID, rg, name, storageAPIVersion))
+	if err != nil {
+		return AccountInfo{}, fmt.Errorf("failed to get storage account %s: %v", name, err)
+	}
+	var account storageAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return AccountInfo{}, fmt.Errorf("failed to parse storage account %s: %v", name, err)
+	}
+	info := AccountInfo{
+		Name:                  account.Name,
+		Location:              account.Location,
+		Kind:                  account.Kind,
+		SKU:                   account.SKU.Name,
+		BlobEndpoint:          account.Properties.PrimaryEndpoints.Blob,
+		PublicNetworkAccess:   account.Properties.PublicNetworkAccess,
+		NetworkDefaultAction:  account.Properties.NetworkACLs.DefaultAction,
+		SharedKeyAccess:       account.Properties.AllowSharedKeyAccess,
+		HierarchicalNamespace: account.Properties.IsHnsEnabled,
+	}
+	if info.Name == "" {
+		info.Name = name
+	}
+	if info.BlobEndpoint == "" {
+		return info, fmt.Errorf("storage account %s has no Blob service endpoint", name)
+	}
+	if !strings.HasSuffix(info.BlobEndpoint, "/") {
+		info.BlobEndpoint += "/"
+	}
+	return info, nil
+}
+
+// validateAccess checks the network rules, Blob data read access and user delegation key access
+func validateAccess(ctx context.Context, client Client, account AccountInfo, container string, now time.Time) []AccessCheck {
+	network := AccessCheck{Check: "network", Status: StatusPass, Detail: "public network access is allowed from all networks"}
+	switch {
+	case strings.EqualFold(account.PublicNetworkAccess, "Disabled"):
+		network.Status, network.Detail = StatusWarn, "public network access is disabled, so only private endpoint traffic is accepted"
+		network.Recommendation = "run the server in a network with a private endpoint to the account"
+	case strings.EqualFold(account.NetworkDefaultAction, "Deny"):
+		network.Status, network.Detail = StatusWarn, "the storage firewall denies networks that are not on its allow list"
+		network.Recommendation = "add the server's network or outbound IP to the account firewall"
+	}
+	checks := []AccessCheck{network}
+
+	read := AccessCheck{Check: "read", Status: StatusPass}
+	var err error
+	if container == "" {
+		_, _, err = listContainers(ctx, client, account.BlobEndpoint, "", 1)
+		read.Detail = "containers of the account can be listed"
+	} else {
+		_, _, err = listBlobs(ctx, client, account.BlobEndpoint, container, "", 1)
+		read.Detail = fmt.Sprintf("blobs of container %s can be listed", container)
+	}
+	if err != nil {
+		read.Status, read.Detail, read.Recommendation = StatusFail, err.Error(), recommendation(err, account.Name, container)
+	}
+	checks = append(checks, read)
+
+	delegation := AccessCheck{Check: "user-delegation-key", Status: StatusPass, Detail: "SAS download links can be generated"}
+	if _, err := getUserDelegationKey(ctx, client, account.BlobEndpoint, now.Add(-clockSkew), now.Add(time.Hour)); err != nil {
+		delegation.Status, delegation.Detail, delegation.Recommendation = StatusFail, err.Error(), recommendation(err, account.Name, container)
+	}
+	return append(checks, delegation)
+}
+
+// listContainers lists the containers of the account with the feature that wrote them
+func listContainers(ctx context.Context, client Client, endpoint, prefix string, limit int) ([]ContainerInfo, bool, error) {
+	query := url.Values{"comp": {"list"}, "maxresults": {fmt.Sprint(limit)}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	results, err := enumerate(ctx, client, endpoint+"?"+query.Encode())
+	if err != nil {
+		return nil, false, err
+	}
+	containers := make([]ContainerInfo, 0, len(results.Containers))
+	for _, c := range results.Containers {
+		containers = append(containers, ContainerInfo{Name: c.Name, Feature: ClassifyContainer(c.Name), LastModified: c.Properties.LastModified})
+	}
+	return containers, results.NextMarker != "", nil
+}
+
+// listBlobs lists the blobs of a container
+func listBlobs(ctx context.Context, client Client, endpoint, container, prefix string, limit int) ([]BlobInfo, bool, error) {
+	query := url.Values{"restype": {"container"}, "comp": {"list"}, "maxresults": {fmt.Sprint(limit)}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	results, err := enumerate(ctx, client, endpoint+container+"?"+query.Encode())
+	if err != nil {
+		return nil, false, err
+	}
+	blobs := make([]BlobInfo, 0, len(results.Blobs))
+	for _, b := range results.Blobs {
+		blobs = append(blobs, BlobInfo{Name: b.Name, Size: b.Properties.ContentLength, ContentType: b.Properties.ContentType, LastModified: b.Properties.LastModified})
+	}
+	return blobs, results.NextMarker != "", nil
+}
+
+func enumerate(ctx context.Context, client Client, listURL string) (*enumerationResults, error) {
+	data, err := client.CallBlobService(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var results enumerationResults
+	if err := xml.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse storage listing: %v", err)
+	}
+	return &results, nil
+}
+
+// getUserDelegationKey requests a user delegation key valid from start to expiry
+func getUserDelegationKey(ctx context.Context, client Client, endpoint string, start, expiry time.Time) (UserDelegationKey, error) {
+	body := fmt.Sprintf("<?xml version=\"1.0\" encoding=\"utf-8\"?><KeyInfo><Start>%s</Start><Expiry>%s</Expiry></KeyInfo>",
+		sasTime(start), sasTime(expiry))
+	data, err := client.CallBlobService(ctx, http.MethodPost, endpoint+"?restype=service&comp=userdelegationkey", []byte(body))
+	if err != nil {
+		return UserDelegationKey{}, err
+	}
+	var key UserDelegationKey
+	if err := xml.Unmarshal(data, &key); err != nil {
+		return UserDelegationKey{}, fmt.Errorf("failed to parse user delegation key: %v", err)
+	}
+	return key, nil
+}
+
+// BuildUserDelegationSAS returns the query string of a read-only HTTPS user delegation SAS for a blob
+func BuildUserDelegationSAS(account, container, blob string, key UserDelegationKey, start, expiry time.Time) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(key.Value)
+	if err != nil {
+		return "", fmt.Errorf("invalid user delegation key: %v", err)
+	}
+	const permissions, protocol, resource = "r", "https", "b"
+	stringToSign := strings.Join([]string{
+		permissions,
+		sasTime(start),
+		sasTime(expiry),
+		"/blob/" + account + "/" + container + "/" + blob,
+		key.SignedOid,
+		key.SignedTid,
+		key.SignedStart,
+		key.SignedExpiry,
+		key.SignedService,
+		key.SignedVersion,
+		"", // signedAuthorizedUserObjectId
+		"", // signedUnauthorizedUserObjectId
+		"", // signedCorrelationId
+		"", // signedIP
+		protocol,
+		sasVersion,
+		resource,
+		"", // signedSnapshotTime
+		"", // signedEncryptionScope
+		"", // rscc
+		"", // rscd
+		"", // rsce
+		"", // rscl
+		"", // rsct
+	}, "\n")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+
+	query := url.Values{
+		"sp":    {permissions},
+		"st":    {sasTime(start)},
+		"se":    {sasTime(expiry)},
+		"skoid": {key.SignedOid},
+		"sktid": {key.SignedTid},
+		"skt":   {key.SignedStart},
+		"ske":   {key.SignedExpiry},
+		"sks":   {key.SignedService},
+		"skv":   {key.SignedVersion},
+		"spr":   {protocol},
+		"sv":    {sasVersion},
+		"sr":    {resource},
+		"sig":   {base64.StdEncoding.EncodeToString(mac.Sum(nil))},
+	}
+	return query.Encode(), nil
+}
+
+// ClassifyContainer returns the feature that writes artifacts to a container, if its name is recognized
+func ClassifyContainer(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == "":
+		return ""
+	case strings.HasPrefix(lower, "insights-logs-"), strings.HasPrefix(lower, "insights-metrics-"):
+		return FeatureLogExport
+	case strings.Contains(lower, "azmk8s-io"):
+		// az aks kollect names the container after the cluster FQDN with dots replaced by dashes
+		return FeaturePeriscope
+	case strings.Contains(lower, "backup"), strings.Contains(lower, "velero"):
+		return FeatureBackup
+	}
+	return ""
+}
+
+// describeStorageError adds the fix to a failed Blob service request
+func describeStorageError(err error, container string) error {
+	var storageErr *azureclient.StorageError
+	if !errors.As(err, &storageErr) {
+		return err
+	}
+	if fix := recommendation(err, "", container); fix != "" {
+		return fmt.Errorf("%v; %s", err, fix)
+	}
+	return err
+}
+
+// recommendation explains how to fix a failed Blob service request
+func recommendation(err error, account, container string) string {
+	var storageErr *azureclient.StorageError
+	if !errors.As(err, &storageErr) {
+		return ""
+	}
+	scope := "the storage account"
+	if account != "" {
+		scope = "storage account " + account
+	}
+	switch storageErr.Code {
+	case "AuthorizationPermissionMismatch":
+		return "assign the server's identity Storage Blob Data Reader on " + scope + " or the container"
+	case "AuthorizationFailure":
+		return "the request was blocked by the storage firewall or by disabled public network access; allow the server's network"
+	case "ContainerNotFound":
+		return fmt.Sprintf("container %s does not exist", container)
+	case "BlobNotFound":
+		return "the blob does not exist; list the container to find its name"
+	case "InvalidAuthenticationInfo":
+		return "the account does not accept Entra ID tokens from the server's tenant"
+	}
+	if storageErr.StatusCode == http.StatusNotFound && container != "" {
+		return fmt.Sprintf("container %s or the blob does not exist", container)
+	}
+	return ""
+}
+
+// escapeBlobName escapes each path segment of a blob name for use in a URL
+func escapeBlobName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sasTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// boundedNumber reads a positive number parameter, applying the default and maximum
+func boundedNumber(value interface{}, defaultValue, maxValue int) int {
+	number, ok := value.(float64)
+	if !ok || number <= 0 {
+		return defaultValue
+	}
+	return min(int(number), maxValue)
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Storage artifact operations
+const (
+	OpValidate = "validate"
+	OpList     = "list"
+	OpSAS      = "sas"
+)
+
+// RegisterStorageArtifactsTool registers the az_storage_artifacts tool
+func RegisterStorageArtifactsTool() mcp.Tool {
+	description := `Inspect the storage account that AKS diagnostics write artifacts to, such as Periscope node logs
+(az aks kollect), backups and diagnostic settings log export.
+
+Operations:
+- validate: Check that the server can reach the account and read the container: network rules,
+  Blob data read access and user delegation key access, with a fix for each failure
+- list: List the containers of the account, or the blobs of a container, with the feature that wrote them
+- sas: Generate a read-only user delegation SAS link to download a blob (expires after expiry_minutes)
+
+Data plane requests use the server's Azure credential, which needs a Blob data role such as Storage Blob Data Reader.
+
+Examples:
+- Validate access: operation="validate", subscription_id="<sub>", resource_group="<rg>", account_name="<account>", container="<container>"
+- List Periscope runs: operation="list", ..., container="<container>", prefix="2024-05-01"
+- Download link: operation="sas", ..., container="<container>", blob="<path/to/blob>"`
+
+	return mcp.NewTool(
+		"az_storage_artifacts",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform"),
+			mcp.Enum(OpValidate, OpList, OpSAS),
+			mcp.Required(),
+		),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID of the storage account"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the storage account"),
+			mcp.Required(),
+		),
+		mcp.WithString("account_name",
+			mcp.Description("Name of the storage account"),
+			mcp.Required(),
+		),
+		mcp.WithString("container",
+			mcp.Description("Blob container (required for sas; list and validate use the account when it is empty)"),
+		),
+		mcp.WithString("prefix",
+			mcp.Description("Only list containers, or blobs of the container, whose name starts with this prefix"),
+		),
+		mcp.WithString("blob",
+			mcp.Description("Name of the blob to generate a SAS link for (required for sas)"),
+		),
+		mcp.WithNumber("expiry_minutes",
+			mcp.Description("Minutes until the SAS link expires (default: 60, maximum: 1440)"),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description("Maximum number of containers or blobs to list (default: 100, maximum: 1000)"),
+		),
+	)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+)
+
+const testAccount = `{"name": "diagstore", "location": "eastus", "kind": "StorageV2", "sku": {"name": "Standard_LRS"},
+	"properties": {"primaryEndpoints": {"blob": "https://diagstore.blob.core.windows.net/"}, "networkAcls": {"defaultAction": "Deny"}}}`
+
+const testKey = `<?xml version="1.0" encoding="utf-8"?><UserDelegationKey><SignedOid>oid</SignedOid><SignedTid>tid</SignedTid>
+<SignedStart>2024-05-01T11:55:00Z</SignedStart><SignedExpiry>2024-05-01T13:00:00Z</SignedExpiry><SignedService>b</SignedService>
+<SignedVersion>2022-11-02</SignedVersion><Value>c2VjcmV0</Value></UserDelegationKey>`
+
+type fakeClient struct {
+	blob  map[string]string
+	err   map[string]error
+	calls []string
+}
+
+func (f *fakeClient) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	if strings.Contains(path, "/storageAccounts/diagstore") {
+		return []byte(testAccount), nil
+	}
+	return nil, fmt.Errorf("unexpected path %s", path)
+}
+
+func (f *fakeClient) CallBlobService(_ context.Context, method, rawURL string, _ []byte) ([]byte, error) {
+	f.calls = append(f.calls, method+" "+rawURL)
+	for fragment, err := range f.err {
+		if strings.Contains(rawURL, fragment) {
+			return nil, err
+		}
+	}
+	for fragment, body := range f.blob {
+		if strings.Contains(rawURL, fragment) {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected URL %s", rawURL)
+}
+
+func baseParams(operation string) map[string]interface{} {
+	return map[string]interface{}{
+		"operation":       operation,
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"account_name":    "diagstore",
+	}
+}
+
+func TestListContainersAndBlobs(t *testing.T) {
+	client := &fakeClient{blob: map[string]string{
+		"restype=container": `<EnumerationResults><Blobs><Blob><Name>2024-05-01/aks-nodepool1-vmss000000/kubelet.log</Name>
+			<Properties><Content-Length>2048</Content-Length><Content-Type>text/plain</Content-Type></Properties></Blob></Blobs><NextMarker>m</NextMarker></EnumerationResults>`,
+		"windows.net/?comp=list": `<EnumerationResults><Containers><Container><Name>insights-logs-kube-audit</Name></Container>
+			<Container><Name>mycluster-dns-1a2b3c-hcp-eastus-azmk8s-io</Name></Container><Container><Name>other</Name></Container></Containers><NextMarker/></EnumerationResults>`,
+	}}
+
+	output, err := HandleStorageArtifacts(baseParams(OpList), client, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result ArtifactsResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(result.Containers) != 3 || result.Containers[0].Feature != FeatureLogExport || result.Containers[1].Feature != FeaturePeriscope || result.Containers[2].Feature != "" {
+		t.Errorf("Unexpected containers %+v", result.Containers)
+	}
+	if result.Truncated {
+		t.Error("Expected a complete container listing")
+	}
+
+	params := baseParams(OpList)
+	params["container"] = "mycluster-dns-1a2b3c-hcp-eastus-azmk8s-io"
+	params["prefix"] = "2024-05-01"
+	output, err = HandleStorageArtifacts(params, client, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result = ArtifactsResult{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(result.Blobs) != 1 || result.Blobs[0].Size != 2048 || !result.Truncated || result.Feature != FeaturePeriscope {
+		t.Errorf("Unexpected blob listing %s", output)
+	}
+	if last := client.calls[len(client.calls)-1]; !strings.Contains(last, "prefix=2024-05-01") || !strings.Contains(last, "maxresults=100") {
+		t.Errorf("Expected the prefix and default limit in %s", last)
+	}
+}
+
+func TestValidateAccess(t *testing.T) {
+	client := &fakeClient{
+		blob: map[string]string{"comp=userdelegationkey": testKey},
+		err: map[string]error{"restype=container": &azureclient.StorageError{
+			StatusCode: http.StatusForbidden, Code: "AuthorizationPermissionMismatch", Message: "This request is not authorized"}},
+	}
+	params := baseParams(OpValidate)
+	params["container"] = "backups"
+	output, err := HandleStorageArtifacts(params, client, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result ArtifactsResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(result.Checks) != 3 || result.Feature != FeatureBackup {
+		t.Fatalf("Expected 3 checks, got %s", output)
+	}
+	if result.Checks[0].Status != StatusWarn || result.Checks[1].Status != StatusFail || result.Checks[2].Status != StatusPass {
+		t.Errorf("Unexpected check statuses %+v", result.Checks)
+	}
+	if !strings.Contains(result.Checks[1].Recommendation, "Storage Blob Data Reader") {
+		t.Errorf("Expected a role recommendation, got %q", result.Checks[1].Recommendation)
+	}
+}
+
+func TestGenerateSAS(t *testing.T) {
+	client := &fakeClient{blob: map[string]string{"comp=userdelegationkey": testKey, "/backups/": ""}}
+	params := baseParams(OpSAS)
+	params["container"] = "backups"
+	params["blob"] = "run 1/state.json"
+	params["expiry_minutes"] = 5000.0
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	output, err := HandleStorageArtifacts(params, client, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result ArtifactsResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if result.Link == nil || !result.Link.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("Expected a link capped at 24 hours, got %s", output)
+	}
+	link, err := url.Parse(result.Link.URL)
+	if err != nil {
+		t.Fatalf("Expected a valid URL: %v", err)
+	}
+	if link.Path != "/backups/run 1/state.json" || link.Query().Get("sp") != "r" || link.Query().Get("skoid") != "oid" || link.Query().Get("st") != "2024-05-01T11:55:00Z" {
+		t.Errorf("Unexpected link %s", result.Link.URL)
+	}
+	if sig, err := base64.StdEncoding.DecodeString(link.Query().Get("sig")); err != nil || len(sig) != 32 {
+		t.Errorf("Expected an HMAC-SHA256 signature, got %q", link.Query().Get("sig"))
+	}
+
+	params["blob"] = ""
+	if _, err := HandleStorageArtifacts(params, client, now); err == nil {
+		t.Error("Expected an error without blob")
+	}
+}
@@ -0,0 +1,570 @@
+// Package supportbundle provides a tool that collects the diagnostics of an AKS cluster into a tar.gz support
+// bundle with an index manifest, written locally or uploaded to blob storage.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/detectors"
+	"github.com/Azure/aks-mcp/internal/components/monitor/diagnostics"
+	"github.com/Azure/aks-mcp/internal/components/storage"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/aks-mcp/internal/version"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// manifestVersion is bumped when the layout of manifest.json changes
+	manifestVersion = "1"
+	manifestName    = "manifest.json"
+	// maxFileBytes bounds each file of the bundle; longer command output is truncated
+	maxFileBytes = 8 << 20
+	defaultHours = 24
+	// maxHours is the longest window detectors and control plane log queries accept
+	maxHours = 24
+	// controlPlaneMaxRecords bounds the error records collected per control plane component
+	controlPlaneMaxRecords = 200
+	bundleContentType      = "application/gzip"
+)
+
+var (
+	// clusterNamePattern matches AKS cluster names, which also name the bundle file
+	clusterNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	// containerPattern matches blob container names
+	containerPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9]|-[a-z0-9]){2,62}$`)
+)
+
+// detectorCategories are the detector categories run for the detectors artifact
+var detectorCategories = []string{
+	"Cluster and Control Plane Availability and Performance",
+	"Node Health",
+	"Create, Upgrade, Delete and Scale",
+}
+
+// controlPlaneCategories are the control plane log categories queried for errors
+var controlPlaneCategories = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "cluster-autoscaler"}
+
+// DetectorRunner runs the detectors of a category. *detectors.DetectorClient implements it.
+type DetectorRunner interface {
+	RunDetectorsByCategory(ctx context.Context, subscriptionID, resourceGroup, clusterName, category, startTime, endTime string) ([]detectors.DetectorRunResponse, error)
+}
+
+// BlobUploader reads storage accounts and uploads blobs. *azureclient.AzureClient implements it.
+type BlobUploader interface {
+	storage.Client
+	PutBlockBlob(ctx context.Context, url string, content []byte, contentType string) error
+}
+
+// Collectors are the sources of the bundle artifacts. Az runs full az commands and Kubectl runs kubectl commands
+// without the leading kubectl. Kubectl, Detectors, ControlPlaneLogs and Blob are optional: artifacts whose source
+// is missing are recorded in the manifest as not collected, and uploads fail.
+type Collectors struct {
+	Az        tools.CommandExecutor
+	Kubectl   tools.CommandExecutor
+	Detectors DetectorRunner
+	// ControlPlaneLogs runs a query with the parameters of the az_monitoring control_plane_logs operation
+	ControlPlaneLogs func(params map[string]interface{}) (string, error)
+	Blob             BlobUploader
+	// Dir is the directory local bundles are written to
+	Dir string
+	Now func() time.Time
+}
+
+// Entry is a file of the bundle, or an artifact that could not be collected
+type Entry struct {
+	Artifact string `json:"artifact"`
+	// Path is the file in the bundle, empty when nothing was collected
+	Path string `json:"path,omitempty"`
+	// Source is the command or API that produced the file
+	Source    string `json:"source"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Manifest is the manifest.json index at the root of the bundle
+type Manifest struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	ServerVersion string    `json:"serverVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Subscription  string    `json:"subscriptionId"`
+	ResourceGroup string    `json:"resourceGroup"`
+	ClusterName   string    `json:"clusterName"`
+	// StartTime and EndTime are the window of detector results and control plane errors
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Artifacts []string  `json:"artifacts"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// BundleResult is the result of the generate_support_bundle tool
+type BundleResult struct {
+	ClusterName string `json:"clusterName"`
+	Destination string `json:"destination"`
+	// Location is the local path or the blob URL of the bundle
+	Location  string   `json:"location"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256"`
+	Artifacts []string `json:"artifacts"`
+	Entries   []Entry  `json:"entries"`
+	// Errors counts the entries that could not be collected
+	Errors int `json:"errors"`
+}
+
+// clusterInfo is the part of az aks show the other artifacts need
+type clusterInfo struct {
+	ID                string `json:"id"`
+	NodeResourceGroup string `json:"nodeResourceGroup"`
+}
+
+// bundleFile is the content of a file of the bundle
+type bundleFile struct {
+	path    string
+	content []byte
+}
+
+// bundle accumulates the files and manifest entries of a support bundle
+type bundle struct {
+	files   []bundleFile
+	entries []Entry
+}
+
+// add records the output of a source as a file of the bundle, truncated to maxFileBytes, or the error that
+// prevented collecting it
+func (b *bundle) add(artifact, path, source, content string, err error) {
+	entry := Entry{Artifact: artifact, Source: source}
+	if err != nil {
+		entry.Error = err.Error()
+		b.entries = append(b.entries, entry)
+		return
+	}
+	data := []byte(content)
+	if len(data) > maxFileBytes {
+		data, entry.Truncated = data[:maxFileBytes], true
+	}
+	sum := sha256.Sum256(data)
+	entry.Path, entry.Size, entry.SHA256 = path, int64(len(data)), hex.EncodeToString(sum[:])
+	b.files = append(b.files, bundleFile{path: path, content: data})
+	b.entries = append(b.entries, entry)
+}
+
+// addJSON records a value marshaled as indented JSON
+func (b *bundle) addJSON(artifact, path, source string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	b.add(artifact, path, source, string(data), err)
+}
+
+// DefaultDir returns the directory local bundles are written to in the user cache directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the support bundle directory: %w", err)
+	}
+	return filepath.Join(dir, "aks-mcp", "support-bundles"), nil
+}
+
+// GetGenerateSupportBundleHandler returns a handler for the generate_support_bundle tool
+func GetGenerateSupportBundleHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		dir, err := DefaultDir()
+		if err != nil {
+			return "", err
+		}
+		collectors := Collectors{Az: azcli.NewExecutor(), Dir: dir, Now: time.Now}
+		if cfg.KubernetesAccessEnabled() {
+			collectors.Kubectl = k8s.WrapK8sExecutor(kubectl.NewExecutor())
+		}
+		if azClient != nil {
+			collectors.Detectors = detectors.NewDetectorClient(azClient)
+			collectors.ControlPlaneLogs = func(params map[string]interface{}) (string, error) {
+				return diagnostics.HandleControlPlaneLogs(params, azClient, cfg)
+			}
+			collectors.Blob = azClient
+		}
+		return HandleGenerateSupportBundle(params, collectors, cfg)
+	})
+}
+
+// HandleGenerateSupportBundle collects the selected artifacts of a cluster into a tar.gz bundle with a manifest,
+// and writes it to the bundle directory or uploads it to a blob container
+func HandleGenerateSupportBundle(params map[string]interface{}, c Collectors, cfg *config.ConfigData) (string, error) {
+	// Bundles copy cluster configuration and logs out of the cluster, so the tool is restricted to admin access
+	if cfg.AccessLevel != "admin" {
+		return "", fmt.Errorf("generate_support_bundle requires admin access level")
+	}
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	if !clusterNamePattern.MatchString(clusterName) {
+		return "", fmt.Errorf("invalid cluster_name parameter: %s", clusterName)
+	}
+	artifacts, err := parseArtifacts(params["artifacts"])
+	if err != nil {
+		return "", err
+	}
+	hours, err := parseHours(params["hours"])
+	if err != nil {
+		return "", err
+	}
+
+	destination, _ := params["destination"].(string)
+	if destination == "" {
+		destination = DestinationLocal
+	}
+	var account *storage.AccountInfo
+	var container string
+	ctx := context.Background()
+	switch destination {
+	case DestinationLocal:
+	case DestinationBlob:
+		if c.Blob == nil {
+			return "", fmt.Errorf("uploading support bundles requires an Azure client")
+		}
+		// The account is checked before collecting, so a wrong account doesn't waste a collection
+		if account, container, err = resolveContainer(ctx, c.Blob, params); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("invalid destination '%s', must be %s or %s", destination, DestinationLocal, DestinationBlob)
+	}
+
+	now := c.Now().UTC().Truncate(time.Second)
+	manifest := Manifest{
+		SchemaVersion: manifestVersion,
+		ServerVersion: version.GitVersion,
+		CreatedAt:     now,
+		Subscription:  subID,
+		ResourceGroup: rg,
+		ClusterName:   clusterName,
+		StartTime:     now.Add(-time.Duration(hours) * time.Hour),
+		EndTime:       now,
+		Artifacts:     artifacts,
+	}
+	b := &bundle{}
+	collect(b, c, manifest, cfg)
+	manifest.Entries = b.entries
+
+	archive, err := writeArchive(manifest, b.files)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(archive)
+	name := fmt.Sprintf("aks-support-%s-%s.tar.gz", clusterName, now.Format("20060102T150405Z"))
+	result := BundleResult{
+		ClusterName: clusterName,
+		Destination: destination,
+		Size:        int64(len(archive)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Artifacts:   artifacts,
+		Entries:     manifest.Entries,
+	}
+	for _, entry := range manifest.Entries {
+		if entry.Error != "" {
+			result.Errors++
+		}
+	}
+
+	if destination == DestinationBlob {
+		result.Location = account.BlobEndpoint + container + "/" + url.PathEscape(name)
+		if err := c.Blob.PutBlockBlob(ctx, result.Location, archive, bundleContentType); err != nil {
+			return "", fmt.Errorf("failed to upload the support bundle to %s: %v", result.Location, err)
+		}
+	} else {
+		if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+			return "", fmt.Errorf("failed to create the support bundle directory: %v", err)
+		}
+		result.Location = filepath.Join(c.Dir, name)
+		if err := os.WriteFile(result.Location, archive, 0o600); err != nil {
+			return "", fmt.Errorf("failed to write the support bundle: %v", err)
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal support bundle result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// collect adds the selected artifacts of the manifest to the bundle
+func collect(b *bundle, c Collectors, m Manifest, cfg *config.ConfigData) {
+	selected := make(map[string]bool, len(m.Artifacts))
+	for _, artifact := range m.Artifacts {
+		selected[artifact] = true
+	}
+	az := func(command string) (string, error) {
+		return c.Az.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+	clusterArgs := fmt.Sprintf("--resource-group %s --subscription %s", azcli.QuoteArg(m.ResourceGroup), azcli.QuoteArg(m.Subscription))
+
+	// The cluster show output also provides the resource ID and node resource group for other artifacts
+	var cluster clusterInfo
+	var clusterErr error
+	if selected[ArtifactCluster] || selected[ArtifactNodePools] || selected[ArtifactDiagnosticSettings] {
+		command := fmt.Sprintf("az aks show --name %s %s --output json", azcli.QuoteArg(m.ClusterName), clusterArgs)
+		output, err := az(command)
+		if err == nil {
+			if err = json.Unmarshal([]byte(output), &cluster); err != nil {
+				err = fmt.Errorf("failed to parse cluster: %v", err)
+			}
+		}
+		clusterErr = err
+		if selected[ArtifactCluster] {
+			b.add(ArtifactCluster, "azure/cluster.json", command, output, err)
+		}
+	}
+
+	if selected[ArtifactNodePools] {
+		command := fmt.Sprintf("az aks nodepool list --cluster-name %s %s --output json", azcli.QuoteArg(m.ClusterName), clusterArgs)
+		output, err := az(command)
+		b.add(ArtifactNodePools, "azure/nodepools.json", command, output, err)
+
+		command = "az vmss list --resource-group <node resource group> --output json"
+		if clusterErr != nil || cluster.NodeResourceGroup == "" {
+			b.add(ArtifactNodePools, "", command, "", fmt.Errorf("the node resource group is unknown: %v", describeMissing(clusterErr)))
+		} else {
+			command = fmt.Sprintf("az vmss list --resource-group %s --subscription %s --output json",
+				azcli.QuoteArg(cluster.NodeResourceGroup), azcli.QuoteArg(m.Subscription))
+			output, err = az(command)
+			b.add(ArtifactNodePools, "azure/vmss.json", command, output, err)
+		}
+	}
+
+	if selected[ArtifactDetectors] {
+		for _, category := range detectorCategories {
+			source := "AKS detectors: " + category
+			if c.Detectors == nil {
+				b.add(ArtifactDetectors, "", source, "", fmt.Errorf("detectors require an Azure client"))
+				continue
+			}
+			results, err := c.Detectors.RunDetectorsByCategory(context.Background(), m.Subscription, m.ResourceGroup, m.ClusterName, category,
+				m.StartTime.Format(time.RFC3339), m.EndTime.Format(time.RFC3339))
+			if err != nil {
+				b.add(ArtifactDetectors, "", source, "", err)
+				continue
+			}
+			findings := []detectors.DetectorFinding{}
+			for _, result := range results {
+				findings = append(findings, detectors.ExtractFindings(result)...)
+			}
+			b.addJSON(ArtifactDetectors, "detectors/"+slug(category)+".json", source, map[string]interface{}{
+				"category":     category,
+				"detectorsRun": len(results),
+				"findings":     findings,
+				"results":      results,
+			})
+		}
+	}
+
+	if selected[ArtifactDiagnosticSettings] {
+		command := "az monitor diagnostic-settings list --resource <cluster resource ID> --output json"
+		if clusterErr != nil || cluster.ID == "" {
+			b.add(ArtifactDiagnosticSettings, "", command, "", fmt.Errorf("the cluster resource ID is unknown: %v", describeMissing(clusterErr)))
+		} else {
+			command = fmt.Sprintf("az monitor diagnostic-settings list --resource %s --output json", azcli.QuoteArg(cluster.ID))
+			output, err := az(command)
+			b.add(ArtifactDiagnosticSettings, "azure/diagnostic-settings.json", command, output, err)
+		}
+	}
+
+	if selected[ArtifactControlPlaneErrors] {
+		for _, category := range controlPlaneCategories {
+			source := "control plane logs: " + category + " errors"
+			if c.ControlPlaneLogs == nil {
+				b.add(ArtifactControlPlaneErrors, "", source, "", fmt.Errorf("control plane logs require an Azure client"))
+				continue
+			}
+			output, err := c.ControlPlaneLogs(map[string]interface{}{
+				"subscription_id": m.Subscription,
+				"resource_group":  m.ResourceGroup,
+				"cluster_name":    m.ClusterName,
+				"log_category":    category,
+				"log_level":       "error",
+				"start_time":      m.StartTime.Format(time.RFC3339),
+				"max_records":     strconv.Itoa(controlPlaneMaxRecords),
+			})
+			b.add(ArtifactControlPlaneErrors, "control-plane/"+category+".json", source, output, err)
+		}
+	}
+
+	if selected[ArtifactKubernetes] {
+		for _, command := range kubernetesCommands(cfg) {
+			source := "kubectl " + command.args
+			if c.Kubectl == nil {
+				b.add(ArtifactKubernetes, "", source, "", fmt.Errorf("kubectl is not available: Kubernetes access is disabled"))
+				continue
+			}
+			output, err := c.Kubectl.Execute(map[string]interface{}{"command": command.args}, cfg)
+			b.add(ArtifactKubernetes, command.path, source, output, err)
+		}
+	}
+}
+
+// kubectlCommand is a kubectl command of the kubernetes artifact and the bundle file of its output
+type kubectlCommand struct {
+	path string
+	args string
+}
+
+// kubernetesCommands returns the kubectl commands of the kubernetes artifact. Warning events are listed in every
+// namespace the server may read.
+func kubernetesCommands(cfg *config.ConfigData) []kubectlCommand {
+	return []kubectlCommand{
+		{"kubernetes/cluster-info.txt", "cluster-info"},
+		{"kubernetes/version.json", "version --output json"},
+		{"kubernetes/nodes.txt", "get nodes --output wide"},
+		{"kubernetes/kube-system-pods.txt", "get pods --namespace kube-system --output wide"},
+		{"kubernetes/warning-events.txt", "get events " + strings.Join(common.NamespaceFlags(cfg.AllowNamespaces), " ") +
+			" --field-selector type=Warning --sort-by=.lastTimestamp"},
+		{"kubernetes/cluster-info-dump.txt", "cluster-info dump --namespaces kube-system"},
+	}
+}
+
+// writeArchive writes the manifest followed by the files into a gzip compressed tar archive
+func writeArchive(manifest Manifest, files []bundleFile) ([]byte, error) {
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal support bundle manifest: %v", err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range append([]bundleFile{{path: manifestName, content: manifestJSON}}, files...) {
+		header := &tar.Header{Name: file.path, Mode: 0o644, Size: int64(len(file.content)), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the support bundle: %v", file.path, err)
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the support bundle: %v", file.path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write the support bundle: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write the support bundle: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resolveContainer reads the storage account of the storage_account_id parameter and validates the container
+func resolveContainer(ctx context.Context, client BlobUploader, params map[string]interface{}) (*storage.AccountInfo, string, error) {
+	accountID, _ := params["storage_account_id"].(string)
+	container, _ := params["container"].(string)
+	if accountID == "" || container == "" {
+		return nil, "", fmt.Errorf("destination '%s' requires the storage_account_id and container parameters", DestinationBlob)
+	}
+	parsed, err := arm.ParseResourceID(accountID)
+	if err != nil || !strings.EqualFold(parsed.ResourceType.String(), "Microsoft.Storage/storageAccounts") {
+		return nil, "", fmt.Errorf("invalid storage_account_id '%s': expected a Microsoft.Storage/storageAccounts resource ID", accountID)
+	}
+	if !containerPattern.MatchString(container) {
+		return nil, "", fmt.Errorf("invalid container '%s'", container)
+	}
+	account, err := storage.GetAccount(ctx, client, parsed.SubscriptionID, parsed.ResourceGroupName, parsed.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	return &account, container, nil
+}
+
+// parseArtifacts returns the artifacts of a comma-separated list in collection order, or all of them when empty
+func parseArtifacts(value interface{}) ([]string, error) {
+	list, _ := value.(string)
+	if strings.TrimSpace(list) == "" {
+		return append([]string(nil), AllArtifacts...), nil
+	}
+	requested := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, artifact := range AllArtifacts {
+			known = known || artifact == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown artifact '%s', must be one of: %s", name, strings.Join(AllArtifacts, ", "))
+		}
+		requested[name] = true
+	}
+	var artifacts []string
+	for _, artifact := range AllArtifacts {
+		if requested[artifact] {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts, nil
+}
+
+// parseHours returns the hours parameter, which is a number or a numeric string between 1 and maxHours
+func parseHours(value interface{}) (int, error) {
+	hours := defaultHours
+	switch v := value.(type) {
+	case nil:
+	case float64:
+		hours = int(v)
+	case string:
+		if v == "" {
+			break
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours parameter: %s", v)
+		}
+		hours = n
+	default:
+		return 0, fmt.Errorf("invalid hours parameter: %v", v)
+	}
+	if hours < 1 || hours > maxHours {
+		return 0, fmt.Errorf("hours must be between 1 and %d", maxHours)
+	}
+	return hours, nil
+}
+
+// describeMissing explains why cluster details are missing
+func describeMissing(err error) string {
+	if err != nil {
+		return "az aks show failed: " + err.Error()
+	}
+	return "az aks show did not return it"
+}
+
+// slug turns a detector category into a file name
+func slug(category string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(category) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
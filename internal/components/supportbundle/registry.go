@@ -0,0 +1,88 @@
+package supportbundle
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Artifacts a bundle can collect
+const (
+	ArtifactCluster            = "cluster"
+	ArtifactNodePools          = "nodepools"
+	ArtifactDetectors          = "detectors"
+	ArtifactDiagnosticSettings = "diagnostic-settings"
+	ArtifactControlPlaneErrors = "control-plane-errors"
+	ArtifactKubernetes         = "kubernetes"
+)
+
+// Bundle destinations
+const (
+	DestinationLocal = "local"
+	DestinationBlob  = "blob"
+)
+
+// AllArtifacts lists every artifact in the order it is collected, which is the default selection
+var AllArtifacts = []string{
+	ArtifactCluster,
+	ArtifactNodePools,
+	ArtifactDetectors,
+	ArtifactDiagnosticSettings,
+	ArtifactControlPlaneErrors,
+	ArtifactKubernetes,
+}
+
+// RegisterGenerateSupportBundleTool registers the generate_support_bundle tool
+func RegisterGenerateSupportBundleTool() mcp.Tool {
+	description := `Collect diagnostics of an AKS cluster into a support bundle: a tar.gz archive with a manifest.json
+index listing each file, the command that produced it, its size and SHA-256, and any collection error.
+
+Artifacts (default: all):
+- cluster: az aks show output
+- nodepools: Node pools and the VM scale sets in the node resource group
+- detectors: AKS diagnostic detector runs and their warning and critical findings
+- diagnostic-settings: Diagnostic settings of the cluster
+- control-plane-errors: Recent error logs of kube-apiserver, kube-controller-manager, kube-scheduler and
+  cluster-autoscaler (requires diagnostic settings sending them to Log Analytics)
+- kubernetes: cluster-info, version, nodes, kube-system pods, warning events and a cluster-info dump of kube-system
+
+An artifact that can't be collected is recorded in the manifest and does not fail the bundle. Large command
+output is truncated. The bundle is written to the server's cache directory or uploaded to a blob container with
+the server's Azure credential, which then needs a role such as Storage Blob Data Contributor.
+
+Examples:
+- Full bundle: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>"
+- Azure side only: ..., artifacts="cluster,nodepools,diagnostic-settings"
+- Upload: ..., destination="blob", storage_account_id="/subscriptions/<sub>/resourceGroups/<rg>/providers/Microsoft.Storage/storageAccounts/<account>", container="support"`
+
+	return mcp.NewTool(
+		"generate_support_bundle",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("artifacts",
+			mcp.Description("Comma-separated artifacts to collect: cluster, nodepools, detectors, diagnostic-settings, control-plane-errors, kubernetes (default: all)"),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description("Hours of detector results and control plane errors to collect (default: 24, maximum: 24)"),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Where to store the bundle (default: local)"),
+			mcp.Enum(DestinationLocal, DestinationBlob),
+		),
+		mcp.WithString("storage_account_id",
+			mcp.Description("Resource ID of the storage account to upload to (required for destination=blob)"),
+		),
+		mcp.WithString("container",
+			mcp.Description("Blob container to upload to (required for destination=blob)"),
+		),
+	)
+}
@@ -0,0 +1,250 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/detectors"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const clusterJSON = `{"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/prod", "nodeResourceGroup": "MC_rg_prod"}`
+
+// fakeExecutor answers commands by their longest matching prefix and records the commands run
+type fakeExecutor struct {
+	outputs  map[string]string
+	commands []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	command, _ := params["command"].(string)
+	f.commands = append(f.commands, command)
+	match := ""
+	for prefix := range f.outputs {
+		if strings.HasPrefix(command, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("unexpected command %s", command)
+	}
+	return f.outputs[match], nil
+}
+
+// fakeDetectors reports a critical finding in every category
+type fakeDetectors struct{}
+
+func (fakeDetectors) RunDetectorsByCategory(_ context.Context, _, _, _, category, _, _ string) ([]detectors.DetectorRunResponse, error) {
+	result := detectors.DetectorRunResponse{Name: "node-health"}
+	result.Properties.Status.StatusID = 1
+	result.Properties.Dataset = []detectors.DetectorDataset{{Table: detectors.DetectorTable{
+		Columns: []detectors.DetectorColumn{{ColumnName: "Status"}, {ColumnName: "Message"}},
+		Rows:    [][]interface{}{{"Critical", category + " is unhealthy"}},
+	}}}
+	return []detectors.DetectorRunResponse{result}, nil
+}
+
+// fakeBlob serves a storage account and records uploads
+type fakeBlob struct {
+	uploads map[string][]byte
+}
+
+func (f *fakeBlob) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	if !strings.Contains(path, "/storageAccounts/diag?") {
+		return nil, fmt.Errorf("unexpected path %s", path)
+	}
+	return []byte(`{"name": "diag", "properties": {"primaryEndpoints": {"blob": "https://diag.blob.core.windows.net/"}}}`), nil
+}
+
+func (f *fakeBlob) CallBlobService(_ context.Context, _, _ string, _ []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *fakeBlob) PutBlockBlob(_ context.Context, url string, content []byte, _ string) error {
+	f.uploads[url] = content
+	return nil
+}
+
+func bundleParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "prod"}
+}
+
+func newCollectors(t *testing.T) (Collectors, *fakeExecutor) {
+	az := &fakeExecutor{outputs: map[string]string{
+		"az aks show":                         clusterJSON,
+		"az aks nodepool list":                `[{"name": "nodepool1"}]`,
+		"az vmss list":                        `[{"name": "aks-nodepool1-12345678-vmss"}]`,
+		"az monitor diagnostic-settings list": `[]`,
+	}}
+	kubectl := &fakeExecutor{outputs: map[string]string{
+		"cluster-info dump": strings.Repeat("x", maxFileBytes+10),
+		"cluster-info":      "Kubernetes control plane is running",
+		"version":           `{"serverVersion": {"gitVersion": "v1.30.3"}}`,
+		"get ":              "NAME   STATUS",
+	}}
+	return Collectors{
+		Az:        az,
+		Kubectl:   kubectl,
+		Detectors: fakeDetectors{},
+		ControlPlaneLogs: func(params map[string]interface{}) (string, error) {
+			if params["log_category"] == "cluster-autoscaler" {
+				return "", fmt.Errorf("log category cluster-autoscaler is not enabled")
+			}
+			if params["log_level"] != "error" {
+				t.Errorf("Expected error logs, got %v", params["log_level"])
+			}
+			return `{"tables": []}`, nil
+		},
+		Dir: t.TempDir(),
+		Now: func() time.Time { return time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC) },
+	}, az
+}
+
+// readArchive returns the files of a tar.gz archive in order
+func readArchive(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names, files
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		names = append(names, header.Name)
+		files[header.Name] = content
+	}
+}
+
+// TestHandleGenerateSupportBundleLocal tests collecting every artifact into a local bundle with its manifest
+func TestHandleGenerateSupportBundleLocal(t *testing.T) {
+	collectors, az := newCollectors(t)
+	output, err := HandleGenerateSupportBundle(bundleParams(), collectors, &config.ConfigData{AccessLevel: "admin"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result BundleResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if result.Destination != DestinationLocal || !strings.HasSuffix(result.Location, "aks-support-prod-20250610T120000Z.tar.gz") || result.Errors != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if len(result.Artifacts) != len(AllArtifacts) {
+		t.Errorf("Expected every artifact by default, got %v", result.Artifacts)
+	}
+	if !strings.Contains(strings.Join(az.commands, "\n"), "az vmss list --resource-group 'MC_rg_prod'") {
+		t.Errorf("Expected the scale sets of the node resource group, got %v", az.commands)
+	}
+
+	data, err := os.ReadFile(result.Location)
+	if err != nil || int64(len(data)) != result.Size {
+		t.Fatalf("Expected the bundle at %s, got %d bytes (%v)", result.Location, len(data), err)
+	}
+	names, files := readArchive(t, data)
+	if names[0] != manifestName {
+		t.Errorf("Expected the manifest first, got %v", names)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(files[manifestName], &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if manifest.ClusterName != "prod" || manifest.EndTime.Sub(manifest.StartTime) != 24*time.Hour ||
+		len(manifest.Entries) != len(result.Entries) {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+	for _, entry := range manifest.Entries {
+		switch {
+		case entry.Error != "":
+			if entry.Artifact != ArtifactControlPlaneErrors || entry.Path != "" {
+				t.Errorf("Unexpected failed entry %+v", entry)
+			}
+		case int64(len(files[entry.Path])) != entry.Size:
+			t.Errorf("Entry %s has size %d but the file has %d bytes", entry.Path, entry.Size, len(files[entry.Path]))
+		case entry.Path == "kubernetes/cluster-info-dump.txt" && (!entry.Truncated || entry.Size != maxFileBytes):
+			t.Errorf("Expected the cluster-info dump to be truncated, got %+v", entry)
+		}
+	}
+	if !strings.Contains(string(files["detectors/node-health.json"]), "Node Health is unhealthy") {
+		t.Errorf("Expected the detector findings, got %s", files["detectors/node-health.json"])
+	}
+}
+
+// TestHandleGenerateSupportBundleBlob tests uploading a bundle of selected artifacts to a blob container
+func TestHandleGenerateSupportBundleBlob(t *testing.T) {
+	collectors, _ := newCollectors(t)
+	blob := &fakeBlob{uploads: map[string][]byte{}}
+	collectors.Blob = blob
+	params := bundleParams()
+	params["artifacts"] = "diagnostic-settings, cluster"
+	params["destination"] = DestinationBlob
+	params["storage_account_id"] = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/diag"
+	params["container"] = "support"
+
+	output, err := HandleGenerateSupportBundle(params, collectors, &config.ConfigData{AccessLevel: "admin"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result BundleResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	want := "https://diag.blob.core.windows.net/support/aks-support-prod-20250610T120000Z.tar.gz"
+	if result.Location != want || blob.uploads[want] == nil || result.Errors != 0 {
+		t.Fatalf("Expected the bundle uploaded to %s, got %+v", want, result)
+	}
+	if strings.Join(result.Artifacts, ",") != "cluster,diagnostic-settings" || len(result.Entries) != 2 {
+		t.Errorf("Expected the selected artifacts in collection order, got %v and %d entries", result.Artifacts, len(result.Entries))
+	}
+	names, _ := readArchive(t, blob.uploads[want])
+	if strings.Join(names, ",") != "manifest.json,azure/cluster.json,azure/diagnostic-settings.json" {
+		t.Errorf("Unexpected bundle files %v", names)
+	}
+}
+
+// TestHandleGenerateSupportBundleValidation tests the access level and parameter checks
+func TestHandleGenerateSupportBundleValidation(t *testing.T) {
+	collectors, az := newCollectors(t)
+	admin := &config.ConfigData{AccessLevel: "admin"}
+	if _, err := HandleGenerateSupportBundle(bundleParams(), collectors, &config.ConfigData{AccessLevel: "readwrite"}); err == nil {
+		t.Error("Expected an error below admin access")
+	}
+	for name, change := range map[string]map[string]interface{}{
+		"unknown artifact": {"artifacts": "cluster,etcd"},
+		"hours too long":   {"hours": float64(48)},
+		"cluster name":     {"cluster_name": "../prod"},
+		"destination":      {"destination": "ftp"},
+		"blob without id":  {"destination": DestinationBlob, "container": "support"},
+		"not a storage id": {"destination": DestinationBlob, "container": "support", "storage_account_id": "/subscriptions/sub/resourceGroups/rg"},
+		"container name":   {"destination": DestinationBlob, "container": "Support_1", "storage_account_id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/diag"},
+	} {
+		params := bundleParams()
+		for key, value := range change {
+			params[key] = value
+		}
+		collectors.Blob = &fakeBlob{uploads: map[string][]byte{}}
+		if _, err := HandleGenerateSupportBundle(params, collectors, admin); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if len(az.commands) != 0 {
+		t.Errorf("Expected no collection for invalid parameters, got %v", az.commands)
+	}
+}
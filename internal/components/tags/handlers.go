@@ -0,0 +1,471 @@
+// Package tags views and applies tags on an AKS cluster and propagates them to the resources of its
+// node resource group.
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+const (
+	// clusterAPIVersion is the Microsoft.ContainerService API version used to read and tag the cluster
+	clusterAPIVersion = "2024-05-01"
+	// resourcesAPIVersion is the Microsoft.Resources API version used to read resource groups and update tags
+	resourcesAPIVersion = "2021-04-01"
+	// maxTags is the number of tags Azure allows on one resource
+	maxTags = 50
+	// maxResources bounds the node resource group resources one call reads
+	maxResources = 1000
+	// maxListPages bounds nextLink paging when listing node resource group resources
+	maxListPages = 20
+	// maxParallelUpdates bounds the resources whose tags are updated at the same time
+	maxParallelUpdates = 8
+)
+
+// invalidKeyCharacters cannot appear in Azure tag names
+const invalidKeyCharacters = `<>%&\?/`
+
+// resourceGroupType is the resource type reported for the node resource group itself
+const resourceGroupType = "Microsoft.Resources/resourceGroups"
+
+// ValueChange is a tag whose value changes
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ResourceChange is the tag change of one resource
+type ResourceChange struct {
+	ResourceID string                 `json:"resourceId"`
+	Type       string                 `json:"type"`
+	Added      map[string]string      `json:"added,omitempty"`
+	Changed    map[string]ValueChange `json:"changed,omitempty"`
+	Removed    []string               `json:"removed,omitempty"`
+	// Status is applied or failed (apply only)
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// result holds the tags after the change
+	result map[string]string
+}
+
+// ResourceTags is a node resource group resource and the cluster tags it lacks or holds with another value
+type ResourceTags struct {
+	ResourceID         string            `json:"resourceId"`
+	Type               string            `json:"type"`
+	Tags               map[string]string `json:"tags"`
+	MissingClusterTags []string          `json:"missingClusterTags,omitempty"`
+}
+
+// SkippedResource is a resource in scope whose tags are not changed, and why
+type SkippedResource struct {
+	ResourceID string `json:"resourceId"`
+	Reason     string `json:"reason"`
+}
+
+// TagReport is the result of the az_aks_tags tool
+type TagReport struct {
+	Operation             string            `json:"operation"`
+	ClusterName           string            `json:"clusterName"`
+	ClusterTags           map[string]string `json:"clusterTags"`
+	NodeResourceGroup     string            `json:"nodeResourceGroup"`
+	NodeResourceGroupTags map[string]string `json:"nodeResourceGroupTags"`
+	// Resources lists the node resource group resources (view only)
+	Resources []ResourceTags `json:"resources,omitempty"`
+	// OutOfSync counts node resource group resources lacking a cluster tag (view only)
+	OutOfSync int `json:"outOfSync"`
+	// Changes lists the resources whose tags change (preview and apply)
+	Changes   []ResourceChange  `json:"changes,omitempty"`
+	Unchanged int               `json:"unchanged"`
+	Skipped   []SkippedResource `json:"skipped,omitempty"`
+	Applied   int               `json:"applied,omitempty"`
+	Failed    int               `json:"failed,omitempty"`
+	Next      string            `json:"next,omitempty"`
+}
+
+// taggedResource is a resource and its current tags
+type taggedResource struct {
+	ID   string            `json:"id"`
+	Type string            `json:"type"`
+	Tags map[string]string `json:"tags"`
+}
+
+// clusterTags is the part of the managed cluster read by the tool
+type clusterTags struct {
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		NodeResourceGroup        string `json:"nodeResourceGroup"`
+		NodeResourceGroupProfile *struct {
+			RestrictionLevel string `json:"restrictionLevel"`
+		} `json:"nodeResourceGroupProfile"`
+	} `json:"properties"`
+}
+
+// GetTagsHandler returns a handler for the az_aks_tags tool
+func GetTagsHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleTags(params, azClient, cfg)
+	})
+}
+
+// HandleTags shows the tags of the cluster and its node resource group, previews the changes of a tag update
+// or applies them
+func HandleTags(params map[string]interface{}, api common.ARMClient, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	switch operation {
+	case OpView, OpPreview:
+	case OpApply:
+		if cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+			return "", fmt.Errorf("operation '%s' requires readwrite or admin access level", operation)
+		}
+	default:
+		return "", fmt.Errorf("missing or invalid operation parameter")
+	}
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	scope, _ := params["scope"].(string)
+	if scope == "" {
+		scope = ScopeAll
+	}
+	if scope != ScopeAll && scope != ScopeCluster && scope != ScopeNodeResourceGroup {
+		return "", fmt.Errorf("invalid scope '%s': must be %s, %s or %s", scope, ScopeAll, ScopeCluster, ScopeNodeResourceGroup)
+	}
+	tagsValue, _ := params["tags"].(string)
+	set, err := ParseTags(tagsValue)
+	if err != nil {
+		return "", err
+	}
+	removeValue, _ := params["remove"].(string)
+	var remove []string
+	for _, name := range strings.Split(removeValue, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			remove = append(remove, name)
+		}
+	}
+	for _, name := range remove {
+		if _, ok := lookupTag(set, name); ok {
+			return "", fmt.Errorf("tag %s is both set and removed", name)
+		}
+	}
+
+	ctx := context.Background()
+	clusterPath := common.ClusterResourceID(subID, rg, clusterName)
+	data, err := api.CallARM(ctx, http.MethodGet, clusterPath+"?api-version="+clusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %v", clusterName, err)
+	}
+	var cluster clusterTags
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster %s: %v", clusterName, err)
+	}
+	if cluster.Properties.NodeResourceGroup == "" {
+		return "", fmt.Errorf("cluster %s has no node resource group", clusterName)
+	}
+	nodeRGPath := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subID, cluster.Properties.NodeResourceGroup)
+	nodeRG, err := getResourceGroup(ctx, api, nodeRGPath)
+	if err != nil {
+		return "", err
+	}
+	resources, err := listResources(ctx, api, nodeRGPath)
+	if err != nil {
+		return "", err
+	}
+
+	report := TagReport{
+		Operation:             operation,
+		ClusterName:           clusterName,
+		ClusterTags:           nonNil(cluster.Tags),
+		NodeResourceGroup:     cluster.Properties.NodeResourceGroup,
+		NodeResourceGroupTags: nonNil(nodeRG.Tags),
+	}
+	if operation == OpView {
+		for _, resource := range append([]taggedResource{nodeRG}, resources...) {
+			missing := missingTags(resource.Tags, cluster.Tags)
+			if len(missing) > 0 {
+				report.OutOfSync++
+			}
+			report.Resources = append(report.Resources, ResourceTags{ResourceID: resource.ID, Type: resource.Type, Tags: nonNil(resource.Tags), MissingClusterTags: missing})
+		}
+		if report.OutOfSync > 0 {
+			report.Next = fmt.Sprintf("Propagate the cluster tags with operation=%q and scope=%q.", OpPreview, ScopeNodeResourceGroup)
+		}
+		return marshal(report)
+	}
+
+	// The cluster's tags after the update are propagated, so node resource group resources end up with every cluster tag
+	clusterChange := DiffTags(cluster.Tags, set, remove)
+	propagated := applyChange(cluster.Tags, clusterChange)
+	var clusterUpdate *ResourceChange
+	if scope != ScopeNodeResourceGroup {
+		clusterChange.ResourceID, clusterChange.Type = clusterPath, "Microsoft.ContainerService/managedClusters"
+		if !clusterChange.empty() {
+			if count := len(propagated); count > maxTags {
+				report.Skipped = append(report.Skipped, SkippedResource{ResourceID: clusterPath, Reason: fmt.Sprintf("would have %d tags, more than the %d allowed", count, maxTags)})
+			} else {
+				clusterUpdate = &clusterChange
+			}
+		} else {
+			report.Unchanged++
+		}
+	}
+
+	var changes []ResourceChange
+	if scope != ScopeCluster {
+		lockedDown := cluster.Properties.NodeResourceGroupProfile != nil && strings.EqualFold(cluster.Properties.NodeResourceGroupProfile.RestrictionLevel, "ReadOnly")
+		for _, resource := range append([]taggedResource{nodeRG}, resources...) {
+			change := DiffTags(resource.Tags, propagated, remove)
+			change.ResourceID, change.Type, change.result = resource.ID, resource.Type, applyChange(resource.Tags, change)
+			switch {
+			case change.empty():
+				report.Unchanged++
+			case lockedDown:
+				report.Skipped = append(report.Skipped, SkippedResource{ResourceID: resource.ID,
+					Reason: "the node resource group restriction level is ReadOnly, so its resources cannot be tagged directly; tag the cluster instead"})
+			case len(change.result) > maxTags:
+				report.Skipped = append(report.Skipped, SkippedResource{ResourceID: resource.ID,
+					Reason: fmt.Sprintf("would have %d tags, more than the %d allowed", len(change.result), maxTags)})
+			default:
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	if operation == OpPreview {
+		if clusterUpdate != nil {
+			report.Changes = append(report.Changes, *clusterUpdate)
+		}
+		report.Changes = append(report.Changes, changes...)
+		if len(report.Changes) > 0 {
+			report.Next = fmt.Sprintf("Show the changes to the user; apply them with operation=%q and the same tags, remove and scope.", OpApply)
+		}
+		return marshal(report)
+	}
+
+	// The cluster is tagged first, so a failure leaves the node resource group untouched
+	if clusterUpdate != nil {
+		body := map[string]interface{}{"tags": propagated}
+		if _, err := api.CallARMWithBody(ctx, http.MethodPatch, clusterPath+"?api-version="+clusterAPIVersion, body); err != nil {
+			return "", fmt.Errorf("failed to update the tags of cluster %s: %v", clusterName, err)
+		}
+		clusterUpdate.Status = "applied"
+		report.Changes = append(report.Changes, *clusterUpdate)
+		report.Applied++
+	}
+	common.RunPool(len(changes), maxParallelUpdates, func(i int) {
+		if err := updateResourceTags(ctx, api, changes[i]); err != nil {
+			changes[i].Status, changes[i].Error = "failed", err.Error()
+			return
+		}
+		changes[i].Status = "applied"
+	})
+	for _, change := range changes {
+		if change.Status == "applied" {
+			report.Applied++
+		} else {
+			report.Failed++
+		}
+	}
+	report.Changes = append(report.Changes, changes...)
+	return marshal(report)
+}
+
+// ParseTags parses tags given as comma-separated key=value pairs or as a JSON object and validates
+// the names and values against the Azure tag limits
+func ParseTags(value string) (map[string]string, error) {
+	tags := map[string]string{}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return tags, nil
+	}
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &tags); err != nil {
+			return nil, fmt.Errorf("invalid tags parameter: %v", err)
+		}
+	} else {
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid tag '%s': expected key=value", pair)
+			}
+			tags[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	for key, val := range tags {
+		if key == "" || len(key) > 512 || strings.ContainsAny(key, invalidKeyCharacters) {
+			return nil, fmt.Errorf("invalid tag name '%s': names are 1 to 512 characters and cannot contain %s", key, invalidKeyCharacters)
+		}
+		if len(val) > 256 {
+			return nil, fmt.Errorf("tag %s has a value longer than 256 characters", key)
+		}
+	}
+	return tags, nil
+}
+
+// DiffTags returns the change that sets and removes tags on a resource with the current tags.
+// Tag names are compared case-insensitively, like Azure does.
+func DiffTags(current, set map[string]string, remove []string) ResourceChange {
+	change := ResourceChange{}
+	for key, val := range set {
+		name, ok := lookupTag(current, key)
+		switch {
+		case !ok:
+			if change.Added == nil {
+				change.Added = map[string]string{}
+			}
+			change.Added[key] = val
+		case current[name] != val:
+			if change.Changed == nil {
+				change.Changed = map[string]ValueChange{}
+			}
+			change.Changed[name] = ValueChange{From: current[name], To: val}
+		}
+	}
+	for _, key := range remove {
+		if name, ok := lookupTag(current, key); ok {
+			change.Removed = append(change.Removed, name)
+		}
+	}
+	sort.Strings(change.Removed)
+	return change
+}
+
+// empty reports whether the change leaves the tags as they are
+func (c ResourceChange) empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// applyChange returns the tags after the change
+func applyChange(current map[string]string, change ResourceChange) map[string]string {
+	tags := make(map[string]string, len(current)+len(change.Added))
+	for key, val := range current {
+		tags[key] = val
+	}
+	for key, val := range change.Added {
+		tags[key] = val
+	}
+	for key, val := range change.Changed {
+		tags[key] = val.To
+	}
+	for _, key := range change.Removed {
+		delete(tags, key)
+	}
+	return tags
+}
+
+// updateResourceTags merges the added and changed tags into a resource with the Tags API. Tags are removed by
+// replacing the resource's tags with the full set after the change.
+func updateResourceTags(ctx context.Context, api common.ARMClient, change ResourceChange) error {
+	path := change.ResourceID + "/providers/Microsoft.Resources/tags/default?api-version=" + resourcesAPIVersion
+	body := map[string]interface{}{"operation": "Replace", "properties": map[string]interface{}{"tags": change.result}}
+	if len(change.Removed) == 0 {
+		merge := map[string]string{}
+		for key, val := range change.Added {
+			merge[key] = val
+		}
+		for key, val := range change.Changed {
+			merge[key] = val.To
+		}
+		body = map[string]interface{}{"operation": "Merge", "properties": map[string]interface{}{"tags": merge}}
+	}
+	if _, err := api.CallARMWithBody(ctx, http.MethodPatch, path, body); err != nil {
+		return fmt.Errorf("failed to update tags: %v", err)
+	}
+	return nil
+}
+
+// getResourceGroup reads a resource group and its tags
+func getResourceGroup(ctx context.Context, api common.ARMClient, path string) (taggedResource, error) {
+	data, err := api.CallARM(ctx, http.MethodGet, path+"?api-version="+resourcesAPIVersion)
+	if err != nil {
+		return taggedResource{}, fmt.Errorf("failed to get node resource group: %v", err)
+	}
+	var group taggedResource
+	if err := json.Unmarshal(data, &group); err != nil {
+		return taggedResource{}, fmt.Errorf("failed to parse node resource group: %v", err)
+	}
+	if group.ID == "" {
+		group.ID = path
+	}
+	group.Type = resourceGroupType
+	return group, nil
+}
+
+// listResources lists the resources of a resource group with their tags
+func listResources(ctx context.Context, api common.ARMClient, groupPath string) ([]taggedResource, error) {
+	path := groupPath + "/resources?api-version=" + resourcesAPIVersion
+	var resources []taggedResource
+	for page := 0; path != "" && page < maxListPages; page++ {
+		data, err := api.CallARM(ctx, http.MethodGet, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list node resource group resources: %v", err)
+		}
+		var list struct {
+			Value    []taggedResource `json:"value"`
+			NextLink string           `json:"nextLink"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse node resource group resources: %v", err)
+		}
+		resources = append(resources, list.Value...)
+		if len(resources) > maxResources {
+			return nil, fmt.Errorf("the node resource group has more than %d resources", maxResources)
+		}
+		path = list.NextLink
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].ID < resources[j].ID })
+	return resources, nil
+}
+
+// lookupTag returns the name under which tags holds key, compared case-insensitively
+func lookupTag(tags map[string]string, key string) (string, bool) {
+	if _, ok := tags[key]; ok {
+		return key, true
+	}
+	for name := range tags {
+		if strings.EqualFold(name, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// missingTags returns the names of the wanted tags that tags lacks or holds with another value
+func missingTags(tags, wanted map[string]string) []string {
+	var missing []string
+	for key, val := range wanted {
+		if name, ok := lookupTag(tags, key); !ok || tags[name] != val {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// nonNil returns tags, or an empty map for a resource without tags
+func nonNil(tags map[string]string) map[string]string {
+	if tags == nil {
+		return map[string]string{}
+	}
+	return tags
+}
+
+func marshal(report TagReport) (string, error) {
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tag report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,73 @@
+package tags
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tag operations
+const (
+	OpView    = "view"
+	OpPreview = "preview"
+	OpApply   = "apply"
+)
+
+// Tag scopes
+const (
+	ScopeCluster           = "cluster"
+	ScopeNodeResourceGroup = "node_resource_group"
+	ScopeAll               = "all"
+)
+
+// RegisterTagsTool registers the az_aks_tags tool
+func RegisterTagsTool() mcp.Tool {
+	description := `View and apply tags on an AKS cluster and propagate them to its node resource group.
+
+The cluster's tags, after the requested changes, are propagated to the node resource group and every resource in it;
+other tags on those resources are kept. Tags listed in remove are removed from every resource in scope.
+
+Operations:
+- view: Show the cluster, node resource group and node resource group resource tags, and which resources lack cluster tags
+- preview: Show the tag changes per resource without applying them
+- apply: Apply the previewed changes; node resource group resources are updated in parallel (requires readwrite)
+
+Resources are skipped when the node resource group is locked down (restriction level ReadOnly) or when a resource would
+exceed 50 tags. AKS may reset tags on node resource group resources it recreates; keeping the tags on the cluster lets
+them be propagated again.
+
+Examples:
+- Audit: operation="view", subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>"
+- Preview: operation="preview", ..., tags="env=prod,team=platform", remove="owner"
+- Propagate existing cluster tags: operation="apply", ..., scope="node_resource_group"`
+
+	return mcp.NewTool(
+		"az_aks_tags",
+		mcp.WithDescription(description),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform"),
+			mcp.Enum(OpView, OpPreview, OpApply),
+			mcp.Required(),
+		),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("tags",
+			mcp.Description(`Tags to set, as key=value pairs separated by commas or a JSON object such as {"env": "prod"}`),
+		),
+		mcp.WithString("remove",
+			mcp.Description("Comma-separated tag names to remove"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("Resources to change (default: all)"),
+			mcp.Enum(ScopeAll, ScopeCluster, ScopeNodeResourceGroup),
+		),
+	)
+}
@@ -0,0 +1,179 @@
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeARM struct {
+	mu        sync.Mutex
+	responses map[string]string
+	failing   string
+	calls     []string
+	bodies    map[string]interface{}
+}
+
+func (f *fakeARM) CallARM(ctx context.Context, method, path string) ([]byte, error) {
+	return f.CallARMWithBody(ctx, method, path, nil)
+}
+
+func (f *fakeARM) CallARMWithBody(_ context.Context, method, path string, payload interface{}) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, method+" "+path)
+	if method != "GET" {
+		if f.bodies == nil {
+			f.bodies = map[string]interface{}{}
+		}
+		f.bodies[strings.Split(path, "?")[0]] = payload
+		if f.failing != "" && strings.Contains(path, f.failing) {
+			return nil, fmt.Errorf("AuthorizationFailed")
+		}
+		return []byte(`{}`), nil
+	}
+	if body, ok := f.responses[strings.Split(path, "?")[0]]; ok {
+		return []byte(body), nil
+	}
+	return nil, fmt.Errorf("unexpected request %s %s", method, path)
+}
+
+const (
+	testClusterPath = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks"
+	testNodeRG      = "/subscriptions/sub/resourceGroups/MC_rg_aks_eastus"
+	testVMSS        = testNodeRG + "/providers/Microsoft.Compute/virtualMachineScaleSets/aks-system-vmss"
+	testLB          = testNodeRG + "/providers/Microsoft.Network/loadBalancers/kubernetes"
+)
+
+func newTagsARM(restrictionLevel string) *fakeARM {
+	return &fakeARM{responses: map[string]string{
+		testClusterPath: `{"tags": {"env": "dev", "owner": "alice"}, "properties": {"nodeResourceGroup": "MC_rg_aks_eastus",
+			"nodeResourceGroupProfile": {"restrictionLevel": "` + restrictionLevel + `"}}}`,
+		testNodeRG: `{"id": "` + testNodeRG + `", "tags": {"env": "dev", "owner": "alice"}}`,
+		testNodeRG + "/resources": `{"value": [
+			{"id": "` + testVMSS + `", "type": "Microsoft.Compute/virtualMachineScaleSets", "tags": {"Env": "dev", "aks-managed-poolName": "system"}},
+			{"id": "` + testLB + `", "type": "Microsoft.Network/loadBalancers"}]}`,
+	}}
+}
+
+func tagParams(operation string) map[string]interface{} {
+	return map[string]interface{}{
+		"operation":       operation,
+		"subscription_id": "sub",
+		"resource_group":  "rg",
+		"cluster_name":    "aks",
+	}
+}
+
+func runTags(t *testing.T, params map[string]interface{}, api *fakeARM, accessLevel string) TagReport {
+	t.Helper()
+	cfg := config.NewConfig()
+	cfg.AccessLevel = accessLevel
+	output, err := HandleTags(params, api, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report TagReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report
+}
+
+func TestTagsView(t *testing.T) {
+	report := runTags(t, tagParams(OpView), newTagsARM(""), "readonly")
+	if report.NodeResourceGroup != "MC_rg_aks_eastus" || len(report.Resources) != 3 || report.OutOfSync != 2 {
+		t.Fatalf("Unexpected view %+v", report)
+	}
+	if missing := strings.Join(report.Resources[1].MissingClusterTags, ","); missing != "owner" {
+		t.Errorf("Expected the scale set to lack only owner, since tag names are case-insensitive, got %q", missing)
+	}
+	if !strings.Contains(report.Next, OpPreview) {
+		t.Errorf("Expected a hint to propagate the tags, got %q", report.Next)
+	}
+}
+
+func TestTagsPreviewAndApply(t *testing.T) {
+	params := tagParams(OpPreview)
+	params["tags"] = "env=prod,team=platform"
+	params["remove"] = "owner"
+	api := newTagsARM("")
+	report := runTags(t, params, api, "readonly")
+	if len(report.Changes) != 4 || report.Unchanged != 0 {
+		t.Fatalf("Expected the cluster, node resource group and both resources to change, got %+v", report)
+	}
+	cluster, vmss := report.Changes[0], report.Changes[2]
+	if cluster.ResourceID != testClusterPath || cluster.Added["team"] != "platform" || cluster.Changed["env"].To != "prod" ||
+		len(cluster.Removed) != 1 || cluster.Removed[0] != "owner" {
+		t.Errorf("Unexpected cluster change %+v", cluster)
+	}
+	if vmss.ResourceID != testVMSS || vmss.Changed["Env"].From != "dev" || vmss.Added["team"] != "platform" || len(vmss.Removed) != 0 {
+		t.Errorf("Unexpected scale set change %+v", vmss)
+	}
+	for _, call := range api.calls {
+		if !strings.HasPrefix(call, "GET ") {
+			t.Errorf("Expected preview to only read, got %s", call)
+		}
+	}
+
+	if _, err := HandleTags(tagParams(OpApply), api, config.NewConfig()); err == nil {
+		t.Error("Expected apply to require readwrite access")
+	}
+	params["operation"] = OpApply
+	api.failing = "loadBalancers"
+	report = runTags(t, params, api, "readwrite")
+	if report.Applied != 3 || report.Failed != 1 {
+		t.Fatalf("Expected three applied and one failed change, got %+v", report)
+	}
+	clusterBody, _ := json.Marshal(api.bodies[testClusterPath])
+	if string(clusterBody) != `{"tags":{"env":"prod","team":"platform"}}` {
+		t.Errorf("Expected the cluster tags to be replaced, got %s", clusterBody)
+	}
+	vmssBody, _ := json.Marshal(api.bodies[testVMSS+"/providers/Microsoft.Resources/tags/default"])
+	if string(vmssBody) != `{"operation":"Merge","properties":{"tags":{"Env":"prod","team":"platform"}}}` {
+		t.Errorf("Expected the scale set tags to be merged, got %s", vmssBody)
+	}
+	rgBody, _ := json.Marshal(api.bodies[testNodeRG+"/providers/Microsoft.Resources/tags/default"])
+	if string(rgBody) != `{"operation":"Replace","properties":{"tags":{"env":"prod","team":"platform"}}}` {
+		t.Errorf("Expected the removal to replace the node resource group tags, got %s", rgBody)
+	}
+}
+
+func TestTagsLockedDownNodeResourceGroup(t *testing.T) {
+	params := tagParams(OpPreview)
+	params["scope"] = ScopeNodeResourceGroup
+	report := runTags(t, params, newTagsARM("ReadOnly"), "readonly")
+	if len(report.Changes) != 0 || len(report.Skipped) != 2 || report.Unchanged != 1 {
+		t.Fatalf("Expected the out of sync resources to be skipped, got %+v", report)
+	}
+	if !strings.Contains(report.Skipped[0].Reason, "ReadOnly") {
+		t.Errorf("Unexpected skip reason %q", report.Skipped[0].Reason)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"env=prod, team = platform", 2, false},
+		{`{"env": "prod", "cost center": "42"}`, 2, false},
+		{"env", 0, true},
+		{"a/b=c", 0, true},
+		{"=value", 0, true},
+		{`{"env": 1}`, 0, true},
+	}
+	for _, tt := range tests {
+		tags, err := ParseTags(tt.value)
+		if (err != nil) != tt.wantErr || (err == nil && len(tags) != tt.want) {
+			t.Errorf("ParseTags(%q) = %v, %v", tt.value, tags, err)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Package trafficmatrix samples the network flows of a cluster for a few minutes and aggregates them into a
+// namespace-to-namespace traffic matrix with the ports used and the flows that were denied, as a starting point
+// for writing NetworkPolicies and for judging the blast radius of a namespace. Flows are read from Hubble when
+// it is reachable, otherwise TCP connections are traced with Inspektor Gadget.
+package trafficmatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/hubble"
+	"github.com/Azure/aks-mcp/internal/components/inspektorgadget"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+)
+
+// Flow sources of the network_traffic_matrix tool
+const (
+	SourceAuto            = "auto"
+	SourceHubble          = "hubble"
+	SourceInspektorGadget = "inspektor_gadget"
+)
+
+const (
+	// defaultMinutes and maxMinutes bound the sampled window
+	defaultMinutes = 2
+	maxMinutes     = 15
+	// defaultHubbleFlowLimit and maxHubbleFlowLimit bound the flows read from Hubble Relay
+	defaultHubbleFlowLimit = 10000
+	maxHubbleFlowLimit     = 50000
+	// maxEdges bounds the namespace pairs returned, and maxEdgePorts the ports listed per pair
+	maxEdges     = 50
+	maxEdgePorts = 10
+)
+
+// Names of the matrix endpoints that are not namespaces
+const (
+	externalEndpoint = "external"
+	unknownEndpoint  = "unknown"
+)
+
+// Sources lists the flow sources the tool accepts
+var Sources = []string{SourceAuto, SourceHubble, SourceInspektorGadget}
+
+// policyDropReasons are the Cilium drop reasons of flows denied by a network policy
+var policyDropReasons = map[string]bool{"POLICY_DENIED": true, "POLICY_DENY": true, "AUTH_REQUIRED": true}
+
+// PortCount counts the flows between two namespaces to one destination port
+type PortCount struct {
+	Protocol string `json:"protocol,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Flows    int    `json:"flows"`
+	Denied   int    `json:"denied,omitempty"`
+	Failed   int    `json:"failed,omitempty"`
+}
+
+// Edge counts the flows from a source namespace to a destination namespace. Denied counts the flows a network
+// policy dropped (Hubble only); Failed counts the flows dropped for other reasons, or with Inspektor Gadget the
+// connections that failed, which includes connections a policy dropped.
+type Edge struct {
+	Source      string      `json:"source"`
+	Destination string      `json:"destination"`
+	Flows       int         `json:"flows"`
+	Denied      int         `json:"denied,omitempty"`
+	Failed      int         `json:"failed,omitempty"`
+	Ports       []PortCount `json:"ports"`
+	// MorePorts is the number of further destination ports left out of Ports
+	MorePorts int `json:"morePorts,omitempty"`
+}
+
+// TrafficMatrix is the result returned by the network_traffic_matrix tool
+type TrafficMatrix struct {
+	// FlowSource is hubble or inspektor_gadget
+	FlowSource string `json:"flowSource"`
+	Window     string `json:"window"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Sampled is the number of flows or connections read; LimitReached means older flows of the window were not read
+	Sampled      int      `json:"sampled"`
+	LimitReached bool     `json:"limitReached,omitempty"`
+	Namespaces   []string `json:"namespaces"`
+	Edges        []Edge   `json:"edges"`
+	Truncated    int      `json:"truncated,omitempty"`
+	Note         string   `json:"note,omitempty"`
+}
+
+// GadgetSampler traces the TCP connections of the cluster for a duration and passes each connect event to
+// handle as JSON
+type GadgetSampler func(ctx context.Context, duration time.Duration, handle func(data []byte)) error
+
+// GetTrafficMatrixHandler returns a handler for the network_traffic_matrix tool
+func GetTrafficMatrixHandler(mgr inspektorgadget.GadgetManager) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		sample := func(ctx context.Context, duration time.Duration, handle func(data []byte)) error {
+			return inspektorgadget.SampleTCPConnects(ctx, mgr, duration, cfg, handle)
+		}
+		return HandleTrafficMatrix(params, hubble.NewHubbleRunner(cfg), sample, cfg)
+	})
+}
+
+// HandleTrafficMatrix samples the flows of the window from the requested source, or from Hubble and then
+// Inspektor Gadget when the source is auto, and returns them as a namespace-to-namespace matrix
+func HandleTrafficMatrix(params map[string]interface{}, runHubble hubble.HubbleRunner, sampleGadget GadgetSampler, cfg *config.ConfigData) (string, error) {
+	window := defaultMinutes * time.Minute
+	if raw, ok := params["minutes"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return "", fmt.Errorf("invalid minutes: expected a whole number of minutes between 1 and %d", maxMinutes)
+		}
+		if n > maxMinutes {
+			return "", fmt.Errorf("minutes %d exceeds the maximum window of %d minutes", int(n), maxMinutes)
+		}
+		window = time.Duration(n) * time.Minute
+	}
+	limit := defaultHubbleFlowLimit
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 {
+			return "", fmt.Errorf("invalid limit: expected a positive number")
+		}
+		limit = min(int(n), maxHubbleFlowLimit)
+	}
+	namespace, _ := params["namespace"].(string)
+	if namespace != "" {
+		if !common.NamespacePattern.MatchString(namespace) {
+			return "", fmt.Errorf("invalid namespace parameter: %s", namespace)
+		}
+		if cfg.AllowNamespaces != "" && !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(namespace) {
+			return "", fmt.Errorf("access to namespace '%s' is denied by security configuration", namespace)
+		}
+	}
+	source, _ := params["source"].(string)
+	if source == "" {
+		source = SourceAuto
+	}
+
+	builder := newMatrixBuilder(namespace, cfg)
+	var matrix TrafficMatrix
+	var err error
+	switch source {
+	case SourceHubble:
+		matrix, err = sampleHubbleFlows(builder, runHubble, window, limit, namespace)
+	case SourceInspektorGadget:
+		matrix, err = sampleGadgetConnections(builder, sampleGadget, window, cfg)
+	case SourceAuto:
+		matrix, err = sampleHubbleFlows(builder, runHubble, window, limit, namespace)
+		if err != nil {
+			hubbleErr := err
+			builder = newMatrixBuilder(namespace, cfg)
+			matrix, err = sampleGadgetConnections(builder, sampleGadget, window, cfg)
+			if err != nil {
+				return "", fmt.Errorf("no flow source is available. Hubble: %v. Inspektor Gadget: %v", hubbleErr, err)
+			}
+			matrix.Note = joinNotes(fmt.Sprintf("Hubble was not available (%v), so TCP connections were traced with Inspektor Gadget.", hubbleErr), matrix.Note)
+		}
+	default:
+		return "", fmt.Errorf("invalid source '%s': expected one of %s", source, strings.Join(Sources, ", "))
+	}
+	if err != nil {
+		return "", err
+	}
+	matrix.Window = window.String()
+	matrix.Namespace = namespace
+
+	resultJSON, err := json.MarshalIndent(matrix, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal traffic matrix to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// sampleHubbleFlows reads the forwarded and dropped flows of the window from Hubble Relay
+func sampleHubbleFlows(builder *matrixBuilder, run hubble.HubbleRunner, window time.Duration, limit int, namespace string) (TrafficMatrix, error) {
+	args := fmt.Sprintf("observe --verdict FORWARDED --verdict DROPPED --since %s --last %d --output jsonpb", window, limit)
+	if namespace != "" {
+		args += " --namespace " + namespace
+	}
+	output, err := run(args)
+	if err != nil {
+		return TrafficMatrix{}, fmt.Errorf("failed to query Hubble: %v. The hubble CLI must be installed and reach Hubble Relay, "+
+			"for example through cilium hubble port-forward or the HUBBLE_SERVER environment variable", err)
+	}
+	flows, err := hubble.ParseFlows(output)
+	if err != nil {
+		return TrafficMatrix{}, err
+	}
+
+	for _, flow := range flows {
+		// Replies belong to the flow that opened the connection
+		if flow.IsReply {
+			continue
+		}
+		protocol, port := hubble.FlowPort(flow)
+		dropped := flow.Verdict == "DROPPED"
+		denied := dropped && policyDropReasons[flow.DropReasonDesc]
+		builder.add(hubbleEndpoint(flow.Source), hubbleEndpoint(flow.Destination), protocol, port, denied, dropped && !denied)
+	}
+
+	matrix := builder.build(SourceHubble)
+	matrix.Sampled = len(flows)
+	matrix.LimitReached = len(flows) >= limit
+	if matrix.LimitReached {
+		matrix.Note = fmt.Sprintf("Only the newest %d flows of the window were read; set namespace, shorten minutes or raise limit to cover all of it.", limit)
+	}
+	return matrix, nil
+}
+
+// sampleGadgetConnections traces the TCP connections opened during the window with Inspektor Gadget
+func sampleGadgetConnections(builder *matrixBuilder, sample GadgetSampler, window time.Duration, cfg *config.ConfigData) (TrafficMatrix, error) {
+	if window >= time.Duration(cfg.Timeout)*time.Second {
+		return TrafficMatrix{}, fmt.Errorf("tracing connections for %s takes longer than the timeout of this call (%ds): "+
+			"shorten minutes or raise timeout_seconds", window, cfg.Timeout)
+	}
+	var sampled atomic.Int64
+	err := sample(context.Background(), window, func(data []byte) {
+		var event gadgetEvent
+		if err := json.Unmarshal(data, &event); err != nil || (event.Type != "" && event.Type != "connect") {
+			return
+		}
+		sampled.Add(1)
+		source := event.K8s.Namespace
+		if source == "" {
+			source = event.Src.K8s.Namespace
+		}
+		builder.add(orUnknown(source), gadgetEndpoint(event.Dst), "TCP", event.Dst.Port, false, event.ErrorRaw != 0)
+	})
+	if err != nil {
+		return TrafficMatrix{}, err
+	}
+
+	matrix := builder.build(SourceInspektorGadget)
+	matrix.Sampled = int(sampled.Load())
+	matrix.Note = "Inspektor Gadget traces the TCP connections pods open: UDP traffic and connections opened by nodes " +
+		"or from outside the cluster are not seen, and failed counts connections that were refused or timed out, " +
+		"which includes connections a network policy dropped."
+	return matrix, nil
+}
+
+// gadgetEvent is the subset of an observe_tcp event used for the matrix
+type gadgetEvent struct {
+	K8s struct {
+		Namespace string `json:"namespace"`
+	} `json:"k8s"`
+	Src      gadgetAddress `json:"src"`
+	Dst      gadgetAddress `json:"dst"`
+	Type     string        `json:"type"`
+	ErrorRaw int           `json:"error_raw"`
+}
+
+// gadgetAddress is an endpoint of an observe_tcp event, with the pod or service Inspektor Gadget resolved it to
+type gadgetAddress struct {
+	Addr string `json:"addr"`
+	Port int    `json:"port"`
+	K8s  struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"k8s"`
+}
+
+// gadgetEndpoint names the namespace of a connection's destination, or external when it is not a pod or service
+func gadgetEndpoint(addr gadgetAddress) string {
+	if addr.K8s.Namespace != "" && addr.K8s.Kind != "raw" {
+		return addr.K8s.Namespace
+	}
+	return externalEndpoint
+}
+
+// hubbleEndpoint names the namespace of a flow endpoint, or its reserved identity such as host or
+// kube-apiserver, with world named external
+func hubbleEndpoint(ep hubble.Endpoint) string {
+	if ep.Namespace != "" {
+		return ep.Namespace
+	}
+	for _, label := range ep.Labels {
+		if identity, ok := strings.CutPrefix(label, "reserved:"); ok {
+			if identity == "world" {
+				return externalEndpoint
+			}
+			return identity
+		}
+	}
+	return unknownEndpoint
+}
+
+func orUnknown(name string) string {
+	if name == "" {
+		return unknownEndpoint
+	}
+	return name
+}
+
+// matrixBuilder counts flows by source and destination namespace and by destination port. Flows outside
+// namespace, when set, or with no endpoint in the namespaces the server may access are left out.
+type matrixBuilder struct {
+	namespace string
+	allowed   func(namespace string) bool
+
+	mu    sync.Mutex
+	edges map[[2]string]*Edge
+	ports map[[2]string]map[string]*PortCount
+}
+
+func newMatrixBuilder(namespace string, cfg *config.ConfigData) *matrixBuilder {
+	allowed := func(string) bool { return true }
+	if cfg.AllowNamespaces != "" {
+		allowed = k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed
+	}
+	return &matrixBuilder{
+		namespace: namespace,
+		allowed:   allowed,
+		edges:     map[[2]string]*Edge{},
+		ports:     map[[2]string]map[string]*PortCount{},
+	}
+}
+
+// add counts one flow from source to destination
+func (b *matrixBuilder) add(source, destination, protocol string, port int, denied, failed bool) {
+	if b.namespace != "" && source != b.namespace && destination != b.namespace {
+		return
+	}
+	if !b.allowed(source) && !b.allowed(destination) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := [2]string{source, destination}
+	edge, ok := b.edges[key]
+	if !ok {
+		edge = &Edge{Source: source, Destination: destination}
+		b.edges[key] = edge
+		b.ports[key] = map[string]*PortCount{}
+	}
+	portKey := fmt.Sprintf("%s/%d", protocol, port)
+	count, ok := b.ports[key][portKey]
+	if !ok {
+		count = &PortCount{Protocol: protocol, Port: port}
+		b.ports[key][portKey] = count
+	}
+	edge.Flows++
+	count.Flows++
+	if denied {
+		edge.Denied++
+		count.Denied++
+	}
+	if failed {
+		edge.Failed++
+		count.Failed++
+	}
+}
+
+// build returns the matrix of the flows counted so far, with the busiest namespace pairs and ports first
+func (b *matrixBuilder) build(flowSource string) TrafficMatrix {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	matrix := TrafficMatrix{FlowSource: flowSource, Namespaces: []string{}, Edges: []Edge{}}
+	namespaces := map[string]bool{}
+	for key, edge := range b.edges {
+		namespaces[edge.Source], namespaces[edge.Destination] = true, true
+		edge.Ports = make([]PortCount, 0, len(b.ports[key]))
+		for _, count := range b.ports[key] {
+			edge.Ports = append(edge.Ports, *count)
+		}
+		sort.Slice(edge.Ports, func(i, j int) bool {
+			a, b := edge.Ports[i], edge.Ports[j]
+			if a.Flows != b.Flows {
+				return a.Flows > b.Flows
+			}
+			if a.Port != b.Port {
+				return a.Port < b.Port
+			}
+			return a.Protocol < b.Protocol
+		})
+		if len(edge.Ports) > maxEdgePorts {
+			edge.MorePorts = len(edge.Ports) - maxEdgePorts
+			edge.Ports = edge.Ports[:maxEdgePorts]
+		}
+		matrix.Edges = append(matrix.Edges, *edge)
+	}
+	for namespace := range namespaces {
+		matrix.Namespaces = append(matrix.Namespaces, namespace)
+	}
+	sort.Strings(matrix.Namespaces)
+	sort.Slice(matrix.Edges, func(i, j int) bool {
+		a, b := matrix.Edges[i], matrix.Edges[j]
+		if a.Flows != b.Flows {
+			return a.Flows > b.Flows
+		}
+		return a.Source+"|"+a.Destination < b.Source+"|"+b.Destination
+	})
+	if len(matrix.Edges) > maxEdges {
+		matrix.Truncated = len(matrix.Edges) - maxEdges
+		matrix.Edges = matrix.Edges[:maxEdges]
+	}
+	return matrix
+}
+
+// joinNotes joins the non-empty notes of a result
+func joinNotes(notes ...string) string {
+	var parts []string
+	for _, note := range notes {
+		if note != "" {
+			parts = append(parts, note)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,45 @@
+package trafficmatrix
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterTrafficMatrixTool registers the network_traffic_matrix tool
+func RegisterTrafficMatrixTool() mcp.Tool {
+	description := fmt.Sprintf(`Sample the network flows of the cluster over a window of minutes and report a namespace-to-namespace
+traffic matrix: which namespaces talk to which, on which protocols and destination ports, and how many of those flows
+were denied. Use it to write NetworkPolicies that allow the traffic that is actually used, or to see what a namespace
+reaches and what reaches it (its blast radius).
+
+Flow sources:
+- hubble: reads the forwarded and dropped flows of the last minutes from Hubble Relay. Denied counts flows a network
+  policy dropped, failed counts flows dropped for other reasons. Requires the hubble CLI and access to Hubble Relay.
+- inspektor_gadget: traces the TCP connections pods open for the given minutes, so the call takes that long.
+  Failed counts connections that were refused or timed out, which includes connections a policy dropped; UDP and
+  connections opened by nodes or from outside the cluster are not seen. Requires Inspektor Gadget in the cluster.
+- auto (default): Hubble when it is reachable, otherwise Inspektor Gadget.
+
+Endpoints outside the cluster are named external; Hubble names other non-pod endpoints by their identity, such as
+host, remote-node or kube-apiserver. At most %d namespace pairs are returned, the busiest first, each with its %d
+busiest ports.`, maxEdges, maxEdgePorts)
+
+	return mcp.NewTool(
+		"network_traffic_matrix",
+		mcp.WithDescription(description),
+		mcp.WithNumber("minutes",
+			mcp.Description(fmt.Sprintf("Window to sample in minutes (default: %d, at most %d)", defaultMinutes, maxMinutes)),
+		),
+		mcp.WithString("source",
+			mcp.Description("Where to read flows from (default: auto)"),
+			mcp.Enum(Sources...),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Only flows from or to this namespace"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of flows to read from Hubble (default: %d, at most %d)", defaultHubbleFlowLimit, maxHubbleFlowLimit)),
+		),
+	)
+}
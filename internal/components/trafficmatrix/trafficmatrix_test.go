@@ -0,0 +1,159 @@
+package trafficmatrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+const hubbleFlows = `{"flow":{"time":"2025-06-10T12:00:01Z","verdict":"FORWARDED","l4":{"TCP":{"destination_port":5432}},"source":{"namespace":"shop","pod_name":"web-1"},"destination":{"namespace":"data","pod_name":"db-0"}}}
+{"flow":{"time":"2025-06-10T12:00:02Z","verdict":"FORWARDED","l4":{"TCP":{"destination_port":51234}},"source":{"namespace":"data","pod_name":"db-0"},"destination":{"namespace":"shop","pod_name":"web-1"},"is_reply":true}}
+{"flow":{"time":"2025-06-10T12:00:03Z","verdict":"DROPPED","l4":{"TCP":{"destination_port":5432}},"source":{"namespace":"shop","pod_name":"web-2"},"destination":{"namespace":"data","pod_name":"db-0"},"drop_reason_desc":"POLICY_DENIED"}}
+{"flow":{"time":"2025-06-10T12:00:04Z","verdict":"FORWARDED","l4":{"TCP":{"destination_port":6379}},"source":{"namespace":"shop","pod_name":"web-1"},"destination":{"namespace":"data","pod_name":"cache-0"}}}
+{"flow":{"time":"2025-06-10T12:00:05Z","verdict":"FORWARDED","l4":{"UDP":{"destination_port":53}},"source":{"namespace":"shop","pod_name":"web-1"},"destination":{"labels":["reserved:world"]}}}
+{"flow":{"time":"2025-06-10T12:00:06Z","verdict":"DROPPED","l4":{"TCP":{"destination_port":443}},"source":{"namespace":"batch","pod_name":"job-1"},"destination":{"labels":["reserved:kube-apiserver"]},"drop_reason_desc":"STALE_OR_UNROUTABLE_IP"}}
+`
+
+const gadgetEvents = `{"k8s":{"namespace":"shop","podName":"web-1"},"src":{"addr":"10.244.1.5","port":40000},"dst":{"addr":"10.0.12.3","port":5432,"k8s":{"kind":"svc","name":"db","namespace":"data"}},"type":"connect","error_raw":0}
+{"k8s":{"namespace":"shop","podName":"web-1"},"src":{"addr":"10.244.1.5","port":40001},"dst":{"addr":"10.0.12.3","port":5432,"k8s":{"kind":"svc","name":"db","namespace":"data"}},"type":"connect","error_raw":110}
+{"k8s":{"namespace":"shop","podName":"web-1"},"src":{"addr":"10.244.1.5","port":40002},"dst":{"addr":"20.1.2.3","port":443,"k8s":{"kind":"raw"}},"type":"connect","error_raw":0}
+not json
+`
+
+func runMatrix(t *testing.T, params map[string]interface{}, runHubble func(string) (string, error), sample GadgetSampler, cfg *config.ConfigData) TrafficMatrix {
+	t.Helper()
+	result, err := HandleTrafficMatrix(params, runHubble, sample, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var matrix TrafficMatrix
+	if err := json.Unmarshal([]byte(result), &matrix); err != nil {
+		t.Fatalf("Failed to parse matrix: %v", err)
+	}
+	return matrix
+}
+
+func gadgetSampler(events string, err error) GadgetSampler {
+	return func(_ context.Context, _ time.Duration, handle func(data []byte)) error {
+		for _, line := range strings.Split(strings.TrimSpace(events), "\n") {
+			handle([]byte(line))
+		}
+		return err
+	}
+}
+
+func TestRegisterTrafficMatrixTool(t *testing.T) {
+	tool := RegisterTrafficMatrixTool()
+	if tool.Name != "network_traffic_matrix" {
+		t.Errorf("Expected tool name 'network_traffic_matrix', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 0 {
+		t.Errorf("Expected no required parameters, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestHubbleMatrix(t *testing.T) {
+	var args []string
+	runHubble := func(a string) (string, error) {
+		args = append(args, a)
+		return hubbleFlows, nil
+	}
+	noGadget := gadgetSampler("", errors.New("unexpected"))
+	matrix := runMatrix(t, map[string]interface{}{"minutes": float64(5)}, runHubble, noGadget, config.NewConfig())
+
+	if len(args) != 1 || args[0] != "observe --verdict FORWARDED --verdict DROPPED --since 5m0s --last 10000 --output jsonpb" {
+		t.Errorf("Unexpected hubble arguments %v", args)
+	}
+	if matrix.FlowSource != SourceHubble || matrix.Window != "5m0s" || matrix.Sampled != 6 {
+		t.Errorf("Unexpected matrix header %+v", matrix)
+	}
+	if strings.Join(matrix.Namespaces, ",") != "batch,data,external,kube-apiserver,shop" {
+		t.Errorf("Unexpected namespaces %v", matrix.Namespaces)
+	}
+	if len(matrix.Edges) != 3 {
+		t.Fatalf("Expected 3 edges, got %+v", matrix.Edges)
+	}
+	edge := matrix.Edges[0]
+	if edge.Source != "shop" || edge.Destination != "data" || edge.Flows != 3 || edge.Denied != 1 || edge.Failed != 0 {
+		t.Errorf("Unexpected busiest edge %+v", edge)
+	}
+	if len(edge.Ports) != 2 || edge.Ports[0].Port != 5432 || edge.Ports[0].Flows != 2 || edge.Ports[0].Denied != 1 || edge.Ports[1].Port != 6379 {
+		t.Errorf("Unexpected ports %+v", edge.Ports)
+	}
+	if edge := matrix.Edges[1]; edge.Source != "batch" || edge.Destination != "kube-apiserver" || edge.Failed != 1 || edge.Denied != 0 {
+		t.Errorf("Unexpected dropped edge %+v", edge)
+	}
+
+	// A namespace filters the flows on both sides and narrows the query
+	args = nil
+	matrix = runMatrix(t, map[string]interface{}{"namespace": "batch", "source": SourceHubble}, runHubble, noGadget, config.NewConfig())
+	if !strings.HasSuffix(args[0], " --namespace batch") || len(matrix.Edges) != 1 || matrix.Edges[0].Source != "batch" {
+		t.Errorf("Unexpected namespace matrix %v %+v", args, matrix.Edges)
+	}
+}
+
+func TestGadgetMatrix(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Timeout = 600
+	noHubble := func(string) (string, error) { return "", errors.New("hubble: command not found") }
+
+	// auto falls back to Inspektor Gadget when Hubble is not reachable
+	matrix := runMatrix(t, map[string]interface{}{}, noHubble, gadgetSampler(gadgetEvents, nil), cfg)
+	if matrix.FlowSource != SourceInspektorGadget || matrix.Sampled != 3 || !strings.Contains(matrix.Note, "Hubble was not available") {
+		t.Errorf("Unexpected matrix header %+v", matrix)
+	}
+	if len(matrix.Edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %+v", matrix.Edges)
+	}
+	if edge := matrix.Edges[0]; edge.Source != "shop" || edge.Destination != "data" || edge.Flows != 2 || edge.Failed != 1 || edge.Ports[0].Protocol != "TCP" {
+		t.Errorf("Unexpected edge %+v", edge)
+	}
+	if edge := matrix.Edges[1]; edge.Destination != externalEndpoint || edge.Ports[0].Port != 443 {
+		t.Errorf("Unexpected external edge %+v", edge)
+	}
+
+	// Without either source the call fails with both reasons
+	_, err := HandleTrafficMatrix(map[string]interface{}{}, noHubble, gadgetSampler("", errors.New("inspektor gadget is not deployed")), cfg)
+	if err == nil || !strings.Contains(err.Error(), "command not found") || !strings.Contains(err.Error(), "not deployed") {
+		t.Errorf("Expected both source errors, got %v", err)
+	}
+
+	// Tracing must finish within the call's timeout
+	cfg.Timeout = 60
+	if _, err := HandleTrafficMatrix(map[string]interface{}{"source": SourceInspektorGadget}, noHubble, gadgetSampler(gadgetEvents, nil), cfg); err == nil {
+		t.Error("Expected a window longer than the timeout to be rejected")
+	}
+}
+
+func TestTrafficMatrixValidation(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "shop"
+	noHubble := func(string) (string, error) { return "", errors.New("unexpected") }
+	for _, params := range []map[string]interface{}{
+		{"minutes": float64(0)},
+		{"minutes": float64(16)},
+		{"minutes": 1.5},
+		{"limit": float64(0)},
+		{"namespace": "Bad_NS"},
+		{"namespace": "data"},
+		{"source": "tcpdump"},
+	} {
+		if _, err := HandleTrafficMatrix(params, noHubble, gadgetSampler("", nil), cfg); err == nil {
+			t.Errorf("Expected %v to be rejected", params)
+		}
+	}
+
+	// Flows with no endpoint in the allowed namespaces are left out
+	runHubble := func(string) (string, error) { return hubbleFlows, nil }
+	matrix := runMatrix(t, map[string]interface{}{}, runHubble, gadgetSampler("", nil), cfg)
+	for _, edge := range matrix.Edges {
+		if edge.Source != "shop" && edge.Destination != "shop" {
+			t.Errorf("Unexpected edge outside the allowed namespaces %+v", edge)
+		}
+	}
+}
@@ -0,0 +1,731 @@
+// Package triage runs the standard first steps of an AKS incident in parallel and turns their results into one
+// prioritized summary: resource health, fired alerts, critical detectors, node readiness, pending pods and
+// recent changes, with a severity, a score and the tool calls to make next.
+package triage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/changes"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/detectors"
+	"github.com/Azure/aks-mcp/internal/components/monitor"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// defaultHours and maxHours bound the window of health events, detector results and changes
+	defaultHours = 6
+	maxHours     = 24
+	// pendingGrace is how long a pod may be Pending before it counts as stuck
+	pendingGrace = 5 * time.Minute
+	// manyPendingPods is the number of stuck pods from which pending pods are critical
+	manyPendingPods = 10
+	// reportedChanges bounds the recent changes listed as findings
+	reportedChanges = 5
+	// criticalWeight and warningWeight are what a finding adds to the score
+	criticalWeight = 25
+	warningWeight  = 10
+	maxScore       = 100
+)
+
+// Severities of findings and of the triage. Info findings give context and do not add to the score.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	SeverityHealthy  = "healthy"
+)
+
+// Statuses of checks besides the severity of their worst finding
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+// Checks in the order their findings are listed at the same severity
+const (
+	CheckResourceHealth = "resource_health"
+	CheckFiredAlerts    = "fired_alerts"
+	CheckDetectors      = "detectors"
+	CheckNodeReadiness  = "node_readiness"
+	CheckPendingPods    = "pending_pods"
+	CheckRecentChanges  = "recent_changes"
+)
+
+// detectorCategories are the detector categories whose critical findings indicate an incident
+var detectorCategories = []string{
+	"Cluster and Control Plane Availability and Performance",
+	"Node Health",
+}
+
+// nodePressureConditions are the node conditions that are True when the node is under pressure
+var nodePressureConditions = []string{"MemoryPressure", "DiskPressure", "PIDPressure"}
+
+// DetectorRunner runs the detectors of a category. *detectors.DetectorClient implements it.
+type DetectorRunner interface {
+	RunDetectorsByCategory(ctx context.Context, subscriptionID, resourceGroup, clusterName, category, startTime, endTime string) ([]detectors.DetectorRunResponse, error)
+}
+
+// Sources are what the checks read. ResourceHealth, FiredAlerts and RecentChanges take the parameters of the
+// az_monitoring resource_health and fired_alerts operations and of aks_recent_changes. Checks whose source is
+// missing are skipped.
+type Sources struct {
+	ResourceHealth func(params map[string]interface{}) (string, error)
+	FiredAlerts    func(params map[string]interface{}) (string, error)
+	Detectors      DetectorRunner
+	RecentChanges  func(params map[string]interface{}) (string, error)
+	Kubectl        tools.CommandExecutor
+	Now            func() time.Time
+}
+
+// Finding is one problem, or one piece of context, found by a check
+type Finding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+// NextStep is a tool call suggested to dig into findings
+type NextStep struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Reason    string                 `json:"reason"`
+}
+
+// CheckResult is the outcome of one check. Status is the severity of its worst finding, ok, error or skipped.
+type CheckResult struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	Summary        string `json:"summary,omitempty"`
+	Error          string `json:"error,omitempty"`
+	DurationMillis int64  `json:"durationMillis"`
+}
+
+// TriageReport is the result of the aks_triage tool
+type TriageReport struct {
+	ClusterName string    `json:"clusterName"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	// Severity is critical, warning or healthy
+	Severity string `json:"severity"`
+	// Score grows with the number and severity of the findings, from 0 to 100
+	Score     int           `json:"score"`
+	Summary   string        `json:"summary"`
+	Findings  []Finding     `json:"findings"`
+	Checks    []CheckResult `json:"checks"`
+	NextSteps []NextStep    `json:"nextSteps"`
+}
+
+// outcome is what a check found
+type outcome struct {
+	summary   string
+	findings  []Finding
+	nextSteps []NextStep
+	skipped   bool
+}
+
+// triage is one run of the checks against a cluster
+type triage struct {
+	sources     Sources
+	cfg         *config.ConfigData
+	subID       string
+	rg          string
+	clusterName string
+	hours       int
+	start, end  time.Time
+}
+
+// GetTriageHandler returns a handler for the aks_triage tool
+func GetTriageHandler(azClient *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		kubectlExecutor := k8s.WrapK8sExecutor(kubectl.NewExecutor())
+		sources := Sources{Kubectl: kubectlExecutor, Now: time.Now}
+		if azClient != nil {
+			sources.ResourceHealth = func(params map[string]interface{}) (string, error) {
+				return monitor.HandleServiceHealthQuery(params, azClient, cfg)
+			}
+			sources.FiredAlerts = func(params map[string]interface{}) (string, error) {
+				return monitor.HandleFiredAlertsQuery(params, azClient, cfg)
+			}
+			sources.Detectors = detectors.NewDetectorClient(azClient)
+			sources.RecentChanges = func(params map[string]interface{}) (string, error) {
+				return changes.HandleRecentChanges(params, azClient, kubectlExecutor, cfg)
+			}
+		}
+		return HandleTriage(params, sources, cfg)
+	})
+}
+
+// HandleTriage runs the triage checks in parallel and returns their findings sorted by severity, with the
+// severity and score of the cluster and the tool calls to make next. A check that fails is reported in checks
+// and does not fail the triage.
+func HandleTriage(params map[string]interface{}, sources Sources, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	hours := defaultHours
+	if raw, ok := params["hours"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n > maxHours {
+			return "", fmt.Errorf("invalid hours: expected a number between 1 and %d", maxHours)
+		}
+		hours = int(n)
+	}
+	end := sources.Now().UTC().Truncate(time.Second)
+	t := &triage{
+		sources:     sources,
+		cfg:         cfg,
+		subID:       subID,
+		rg:          rg,
+		clusterName: clusterName,
+		hours:       hours,
+		start:       end.Add(-time.Duration(hours) * time.Hour),
+		end:         end,
+	}
+
+	checks := []struct {
+		name string
+		run  func() (outcome, error)
+	}{
+		{CheckResourceHealth, t.checkResourceHealth},
+		{CheckFiredAlerts, t.checkFiredAlerts},
+		{CheckDetectors, t.checkDetectors},
+		{CheckNodeReadiness, t.checkNodeReadiness},
+		{CheckPendingPods, t.checkPendingPods},
+		{CheckRecentChanges, t.checkRecentChanges},
+	}
+	results := make([]CheckResult, len(checks))
+	outcomes := make([]outcome, len(checks))
+	common.RunPool(len(checks), len(checks), func(i int) {
+		started := time.Now()
+		out, err := checks[i].run()
+		results[i] = CheckResult{Name: checks[i].name, Status: checkStatus(out), Summary: out.summary, DurationMillis: time.Since(started).Milliseconds()}
+		if err != nil {
+			results[i].Status, results[i].Error = StatusError, err.Error()
+		}
+		outcomes[i] = out
+	})
+
+	report := TriageReport{
+		ClusterName: clusterName,
+		StartTime:   t.start,
+		EndTime:     t.end,
+		Findings:    []Finding{},
+		Checks:      results,
+		NextSteps:   []NextStep{},
+	}
+	for _, out := range outcomes {
+		report.Findings = append(report.Findings, out.findings...)
+	}
+	// Checks are listed in triage order, which breaks ties between findings of the same severity
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityRank(report.Findings[i].Severity) < severityRank(report.Findings[j].Severity)
+	})
+	report.Severity, report.Score = score(report.Findings)
+	report.NextSteps = prioritizeNextSteps(outcomes)
+	report.Summary = summarize(report)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal triage report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// clusterParams returns the cluster parameters shared by the tools the checks call
+func (t *triage) clusterParams() map[string]interface{} {
+	return map[string]interface{}{
+		"subscription_id": t.subID,
+		"resource_group":  t.rg,
+		"cluster_name":    t.clusterName,
+	}
+}
+
+// clusterResourceID returns the resource ID of the cluster
+func (t *triage) clusterResourceID() string {
+	return common.ClusterResourceID(t.subID, t.rg, t.clusterName)
+}
+
+// monitoringStep suggests an az_monitoring operation with its parameters
+func (t *triage) monitoringStep(operation string, parameters map[string]interface{}, reason string) NextStep {
+	arguments := t.clusterParams()
+	arguments["operation"] = operation
+	if data, err := json.Marshal(parameters); err == nil {
+		arguments["parameters"] = string(data)
+	}
+	return NextStep{Tool: "az_monitoring", Arguments: arguments, Reason: reason}
+}
+
+// checkResourceHealth reports the cluster's Resource Health transitions and active Service Health events
+func (t *triage) checkResourceHealth() (outcome, error) {
+	if t.sources.ResourceHealth == nil {
+		return outcome{summary: "Resource Health requires an Azure client", skipped: true}, nil
+	}
+	output, err := t.sources.ResourceHealth(map[string]interface{}{
+		"subscription_id": t.subID,
+		"resource_group":  t.rg,
+		"cluster_name":    t.clusterName,
+		"mode":            monitor.ResourceHealthModeServiceHealth,
+		"start_time":      t.start.Format(time.RFC3339),
+	})
+	if err != nil {
+		return outcome{}, err
+	}
+	var report monitor.ServiceHealthReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return outcome{}, fmt.Errorf("failed to parse the service health report: %v", err)
+	}
+
+	out := outcome{summary: report.Summary}
+	latest := ""
+	for _, event := range report.ClusterEvents {
+		if event.Time > latest {
+			latest = event.Time
+		}
+	}
+	for _, event := range report.ClusterEvents {
+		if event.State == "Available" || event.State == "" {
+			continue
+		}
+		message := fmt.Sprintf("The cluster was %s at %s", event.State, event.Time)
+		severity := SeverityWarning
+		if event.Time == latest {
+			message = fmt.Sprintf("The cluster has been %s since %s", event.State, event.Time)
+			if event.State == "Unavailable" {
+				severity = SeverityCritical
+			}
+		}
+		if event.Summary != "" {
+			message += ": " + event.Summary
+		}
+		out.findings = append(out.findings, Finding{Severity: severity, Check: CheckResourceHealth, Message: message + fmt.Sprintf(" (%s)", event.Classification)})
+	}
+	for _, event := range report.PlatformEvents {
+		if !strings.EqualFold(event.Status, "Active") {
+			continue
+		}
+		severity := SeverityInfo
+		switch event.Type {
+		case "ServiceIssue":
+			severity = SeverityCritical
+		case "PlannedMaintenance":
+			severity = SeverityWarning
+		}
+		out.findings = append(out.findings, Finding{Severity: severity, Check: CheckResourceHealth,
+			Message: fmt.Sprintf("Active %s %s in %s affecting %s: %s", event.Type, event.TrackingID, report.Location, strings.Join(event.Services, ", "), event.Title)})
+	}
+	if len(out.findings) > 0 {
+		out.nextSteps = append(out.nextSteps, t.monitoringStep("resource_health",
+			map[string]interface{}{"mode": monitor.ResourceHealthModeServiceHealth, "start_time": t.start.Format(time.RFC3339)},
+			"Read the health transitions and Service Health events with their impact windows"))
+	}
+	return out, nil
+}
+
+// checkFiredAlerts reports the Azure Monitor alerts firing on the cluster and its node resource group
+func (t *triage) checkFiredAlerts() (outcome, error) {
+	if t.sources.FiredAlerts == nil {
+		return outcome{summary: "Fired alerts require an Azure client", skipped: true}, nil
+	}
+	timeRange := "1d"
+	if t.hours <= 1 {
+		timeRange = "1h"
+	}
+	params := t.clusterParams()
+	params["time_range"] = timeRange
+	params["include_resolved"] = "false"
+	output, err := t.sources.FiredAlerts(params)
+	if err != nil {
+		return outcome{}, err
+	}
+	var report monitor.FiredAlertsReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return outcome{}, fmt.Errorf("failed to parse the fired alerts report: %v", err)
+	}
+
+	out := outcome{summary: fmt.Sprintf("%d alerts firing in the last %s", report.FiredCount, timeRange)}
+	for _, alert := range report.Alerts {
+		if alert.MonitorCondition != "Fired" {
+			continue
+		}
+		severity := SeverityInfo
+		switch alert.Severity {
+		case "Sev0", "Sev1":
+			severity = SeverityCritical
+		case "Sev2":
+			severity = SeverityWarning
+		}
+		target := alert.TargetResource
+		if i := strings.LastIndex(target, "/"); i >= 0 {
+			target = target[i+1:]
+		}
+		out.findings = append(out.findings, Finding{Severity: severity, Check: CheckFiredAlerts,
+			Message: fmt.Sprintf("Alert %s (%s) firing on %s since %s", alert.Name, alert.Severity, target, alert.StartDateTime)})
+	}
+	if len(out.findings) > 0 {
+		out.nextSteps = append(out.nextSteps, t.monitoringStep("fired_alerts", map[string]interface{}{"time_range": timeRange},
+			"List the firing alerts with their rules and signal types"))
+	}
+	return out, nil
+}
+
+// checkDetectors reports the critical and warning findings of the availability and node health detectors
+func (t *triage) checkDetectors() (outcome, error) {
+	if t.sources.Detectors == nil {
+		return outcome{summary: "Detectors require an Azure client", skipped: true}, nil
+	}
+	var out outcome
+	var failures []string
+	run, suggested := 0, map[string]bool{}
+	for _, category := range detectorCategories {
+		results, err := t.sources.Detectors.RunDetectorsByCategory(context.Background(), t.subID, t.rg, t.clusterName, category,
+			t.start.Format(time.RFC3339), t.end.Format(time.RFC3339))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", category, err))
+			continue
+		}
+		run += len(results)
+		for _, result := range results {
+			findings := detectors.ExtractFindings(result)
+			for _, finding := range findings {
+				out.findings = append(out.findings, Finding{Severity: finding.Status, Check: CheckDetectors,
+					Message: fmt.Sprintf("%s: %s", finding.Detector, finding.Message)})
+			}
+			id := result.Properties.Metadata.ID
+			if id == "" {
+				id = result.Name
+			}
+			if len(findings) == 0 || suggested[id] {
+				continue
+			}
+			suggested[id] = true
+			out.nextSteps = append(out.nextSteps, NextStep{
+				Tool: "run_detector",
+				Arguments: map[string]interface{}{
+					"cluster_resource_id": t.clusterResourceID(),
+					"detector_name":       id,
+					"start_time":          t.start.Format(time.RFC3339),
+					"end_time":            t.end.Format(time.RFC3339),
+				},
+				Reason: fmt.Sprintf("Read the full output of the %s detector", findings[0].Detector),
+			})
+		}
+	}
+	if len(failures) == len(detectorCategories) {
+		return outcome{}, fmt.Errorf("failed to run detectors: %s", strings.Join(failures, "; "))
+	}
+	out.summary = fmt.Sprintf("%d detectors run, %d findings", run, len(out.findings))
+	if len(failures) > 0 {
+		out.summary += fmt.Sprintf(" (failed to run %s)", strings.Join(failures, "; "))
+	}
+	return out, nil
+}
+
+// nodeList is the part of kubectl get nodes -o json the node readiness check reads
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type               string `json:"type"`
+				Status             string `json:"status"`
+				Reason             string `json:"reason"`
+				Message            string `json:"message"`
+				LastTransitionTime string `json:"lastTransitionTime"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// checkNodeReadiness reports NotReady nodes and nodes under memory, disk or PID pressure
+func (t *triage) checkNodeReadiness() (outcome, error) {
+	if t.sources.Kubectl == nil {
+		return outcome{summary: "Node readiness requires Kubernetes access", skipped: true}, nil
+	}
+	output, err := t.sources.Kubectl.Execute(map[string]interface{}{"command": "get nodes -o json"}, t.cfg)
+	if err != nil {
+		return outcome{}, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	var nodes nodeList
+	if err := json.Unmarshal([]byte(output), &nodes); err != nil {
+		return outcome{}, fmt.Errorf("failed to parse kubectl output: %v", err)
+	}
+
+	var out outcome
+	ready := 0
+	for _, node := range nodes.Items {
+		isReady := false
+		for _, condition := range node.Status.Conditions {
+			switch {
+			case condition.Type == "Ready" && condition.Status == "True":
+				isReady = true
+			case condition.Type == "Ready":
+				out.findings = append(out.findings, Finding{Severity: SeverityCritical, Check: CheckNodeReadiness,
+					Message: fmt.Sprintf("Node %s has been NotReady since %s: %s", node.Metadata.Name, condition.LastTransitionTime, conditionReason(condition.Reason, condition.Message))})
+				out.nextSteps = append(out.nextSteps, NextStep{
+					Tool:      "kubectl_resources",
+					Arguments: map[string]interface{}{"operation": "describe", "resource": "node", "args": node.Metadata.Name},
+					Reason:    fmt.Sprintf("Read the conditions and events of the NotReady node %s", node.Metadata.Name),
+				})
+			case condition.Status == "True" && containsString(nodePressureConditions, condition.Type):
+				out.findings = append(out.findings, Finding{Severity: SeverityWarning, Check: CheckNodeReadiness,
+					Message: fmt.Sprintf("Node %s has %s since %s: %s", node.Metadata.Name, condition.Type, condition.LastTransitionTime, conditionReason(condition.Reason, condition.Message))})
+				out.nextSteps = append(out.nextSteps, NextStep{
+					Tool:      "aks_noisy_neighbors",
+					Arguments: map[string]interface{}{"node_name": node.Metadata.Name},
+					Reason:    fmt.Sprintf("Find the pods driving the %s of node %s", condition.Type, node.Metadata.Name),
+				})
+			}
+		}
+		if isReady {
+			ready++
+		}
+	}
+	out.summary = fmt.Sprintf("%d of %d nodes Ready", ready, len(nodes.Items))
+	return out, nil
+}
+
+// podList is the part of kubectl get pods -o json the pending pods check reads
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string    `json:"name"`
+			Namespace         string    `json:"namespace"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// checkPendingPods reports the pods Pending for longer than pendingGrace in the allowed namespaces
+func (t *triage) checkPendingPods() (outcome, error) {
+	if t.sources.Kubectl == nil {
+		return outcome{summary: "Pending pods require Kubernetes access", skipped: true}, nil
+	}
+	var pods podList
+	for _, flag := range common.NamespaceFlags(t.cfg.AllowNamespaces) {
+		output, err := t.sources.Kubectl.Execute(map[string]interface{}{"command": "get pods " + flag + " --field-selector status.phase=Pending -o json"}, t.cfg)
+		if err != nil {
+			return outcome{}, fmt.Errorf("failed to list pending pods: %v", err)
+		}
+		var list podList
+		if err := json.Unmarshal([]byte(output), &list); err != nil {
+			return outcome{}, fmt.Errorf("failed to parse kubectl output: %v", err)
+		}
+		pods.Items = append(pods.Items, list.Items...)
+	}
+
+	var out outcome
+	stuck, unschedulable := 0, 0
+	var first string
+	for _, pod := range pods.Items {
+		if t.end.Sub(pod.Metadata.CreationTimestamp) < pendingGrace {
+			continue
+		}
+		stuck++
+		reason := "waiting for its containers to start"
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == "PodScheduled" && condition.Status == "False" {
+				unschedulable++
+				reason = "not scheduled: " + conditionReason(condition.Reason, condition.Message)
+			}
+		}
+		if first == "" {
+			first = pod.Metadata.Namespace + "/" + pod.Metadata.Name
+			out.nextSteps = append(out.nextSteps, NextStep{
+				Tool:      "kubectl_resources",
+				Arguments: map[string]interface{}{"operation": "describe", "resource": "pod", "args": fmt.Sprintf("%s --namespace %s", pod.Metadata.Name, pod.Metadata.Namespace)},
+				Reason:    fmt.Sprintf("Read the events of the pending pod %s", first),
+			})
+		}
+		out.findings = append(out.findings, Finding{Severity: SeverityWarning, Check: CheckPendingPods,
+			Message: fmt.Sprintf("Pod %s/%s has been Pending since %s, %s", pod.Metadata.Namespace, pod.Metadata.Name, pod.Metadata.CreationTimestamp.Format(time.RFC3339), reason)})
+	}
+	if stuck >= manyPendingPods {
+		for i := range out.findings {
+			out.findings[i].Severity = SeverityCritical
+		}
+	}
+	out.summary = fmt.Sprintf("%d pods Pending for more than %s, %d of them not scheduled", stuck, pendingGrace, unschedulable)
+	return out, nil
+}
+
+// checkRecentChanges lists the newest changes of the window as context and reports failed ARM operations
+func (t *triage) checkRecentChanges() (outcome, error) {
+	if t.sources.RecentChanges == nil {
+		return outcome{summary: "Recent changes require an Azure client", skipped: true}, nil
+	}
+	params := t.clusterParams()
+	params["hours"] = float64(t.hours)
+	output, err := t.sources.RecentChanges(params)
+	if err != nil {
+		return outcome{}, err
+	}
+	var report changes.ChangesReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return outcome{}, fmt.Errorf("failed to parse the recent changes: %v", err)
+	}
+
+	out := outcome{summary: fmt.Sprintf("%d changes in the last %d hours", len(report.Changes)+report.Truncated, t.hours)}
+	if len(report.Warnings) > 0 {
+		out.summary += fmt.Sprintf(" (%d sources could not be read)", len(report.Warnings))
+	}
+	listed := 0
+	for _, change := range report.Changes {
+		severity := SeverityInfo
+		if change.Category == changes.CategoryARM && strings.Contains(change.Summary, "(Failed)") {
+			severity = SeverityWarning
+		} else if listed == reportedChanges {
+			continue
+		} else {
+			listed++
+		}
+		message := fmt.Sprintf("%s %s changed at %s: %s", change.Category, change.Resource, change.Time.Format(time.RFC3339), change.Summary)
+		if change.Namespace != "" {
+			message = fmt.Sprintf("%s %s/%s changed at %s: %s", change.Category, change.Namespace, change.Resource, change.Time.Format(time.RFC3339), change.Summary)
+		}
+		out.findings = append(out.findings, Finding{Severity: severity, Check: CheckRecentChanges, Message: message})
+	}
+	if len(report.Changes) > 0 {
+		params["hours"] = t.hours
+		out.nextSteps = append(out.nextSteps, NextStep{Tool: "aks_recent_changes", Arguments: params,
+			Reason: "Correlate the incident start with the rollouts, node, Helm and ARM changes of the window"})
+	}
+	return out, nil
+}
+
+// checkStatus is the severity of the worst finding of a check, ok without findings, or skipped
+func checkStatus(out outcome) string {
+	if out.skipped {
+		return StatusSkipped
+	}
+	status := StatusOK
+	for _, finding := range out.findings {
+		if finding.Severity != SeverityInfo && severityRank(finding.Severity) < severityRank(status) {
+			status = finding.Severity
+		}
+	}
+	return status
+}
+
+// severityRank orders severities from the most to the least severe
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 0
+	case SeverityWarning:
+		return 1
+	case SeverityInfo:
+		return 2
+	}
+	return 3
+}
+
+// score returns the severity of the worst finding and a score adding up the weights of the findings
+func score(findings []Finding) (string, int) {
+	severity, total := SeverityHealthy, 0
+	for _, finding := range findings {
+		switch finding.Severity {
+		case SeverityCritical:
+			total += criticalWeight
+		case SeverityWarning:
+			total += warningWeight
+		default:
+			continue
+		}
+		if severityRank(finding.Severity) < severityRank(severity) {
+			severity = finding.Severity
+		}
+	}
+	return severity, min(total, maxScore)
+}
+
+// prioritizeNextSteps orders the next steps of the checks by the worst finding of their check, keeping
+// triage order between checks as severe, and drops repeated suggestions
+func prioritizeNextSteps(outcomes []outcome) []NextStep {
+	order := make([]int, len(outcomes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return severityRank(checkStatus(outcomes[order[a]])) < severityRank(checkStatus(outcomes[order[b]]))
+	})
+	steps := []NextStep{}
+	seen := map[string]bool{}
+	for _, i := range order {
+		for _, step := range outcomes[i].nextSteps {
+			key, _ := json.Marshal(step)
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// summarize describes the severity and the most severe finding of a triage in one or two sentences
+func summarize(report TriageReport) string {
+	counts := map[string]int{}
+	var failed []string
+	for _, finding := range report.Findings {
+		counts[finding.Severity]++
+	}
+	for _, check := range report.Checks {
+		if check.Status == StatusError {
+			failed = append(failed, check.Name)
+		}
+	}
+	summary := fmt.Sprintf("Cluster %s is healthy: no critical or warning findings in the last %s.", report.ClusterName, report.EndTime.Sub(report.StartTime))
+	if report.Severity != SeverityHealthy {
+		summary = fmt.Sprintf("Cluster %s is %s (score %d): %d critical and %d warning findings. Start with: %s.",
+			report.ClusterName, report.Severity, report.Score, counts[SeverityCritical], counts[SeverityWarning], report.Findings[0].Message)
+	}
+	if len(failed) > 0 {
+		summary += fmt.Sprintf(" Incomplete: the %s checks failed.", strings.Join(failed, ", "))
+	}
+	return summary
+}
+
+// conditionReason describes a condition by its reason and message
+func conditionReason(reason, message string) string {
+	switch {
+	case reason == "":
+		return message
+	case message == "":
+		return reason
+	}
+	return reason + " (" + message + ")"
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package triage
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterTriageTool registers the aks_triage tool
+func RegisterTriageTool() mcp.Tool {
+	description := `Run the first 15 minutes of an AKS incident in one call: the standard triage checks run in parallel,
+their findings are scored and the result lists what to look at first and which tools to call next.
+
+Checks:
+- resource_health: the cluster's Resource Health transitions and active Azure Service Health events in its region
+- fired_alerts: Azure Monitor alerts firing on the cluster and its node resource group
+- detectors: critical and warning findings of the control plane availability and node health detectors
+- node_readiness: NotReady nodes and nodes under memory, disk or PID pressure
+- pending_pods: pods stuck Pending in the allowed namespaces, with why they are not scheduled
+- recent_changes: rollouts, node, Helm and ARM changes in the window, and failed ARM operations
+
+Severity is critical, warning or healthy, and score grows from 0 with the number and severity of the
+findings (at most 100). Findings are sorted critical first, and nextSteps suggests a tool call for each
+kind of finding. A check that fails is reported with its error and does not fail the triage. Uses the
+current kubeconfig context for the cluster.
+
+Example: subscription_id="<sub>", resource_group="<rg>", cluster_name="<cluster>", hours=2`
+
+	return mcp.NewTool(
+		"aks_triage",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description(fmt.Sprintf("Hours of health events, detector results and changes to check (default: %d, maximum: %d)", defaultHours, maxHours)),
+		),
+	)
+}
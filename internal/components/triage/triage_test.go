@@ -0,0 +1,192 @@
+package triage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/detectors"
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeExecutor answers commands by their longest matching prefix and records the commands run
+type fakeExecutor struct {
+	mu       sync.Mutex
+	outputs  map[string]string
+	commands []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	command, _ := params["command"].(string)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, command)
+	match := ""
+	for prefix := range f.outputs {
+		if strings.HasPrefix(command, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("unexpected command %s", command)
+	}
+	return f.outputs[match], nil
+}
+
+// fakeDetectors reports a critical finding in the node health category
+type fakeDetectors struct{}
+
+func (fakeDetectors) RunDetectorsByCategory(_ context.Context, _, _, _, category, _, _ string) ([]detectors.DetectorRunResponse, error) {
+	if category != "Node Health" {
+		return nil, nil
+	}
+	result := detectors.DetectorRunResponse{Name: "node-health"}
+	result.Properties.Metadata.ID = "node-health"
+	result.Properties.Status.StatusID = 1
+	result.Properties.Dataset = []detectors.DetectorDataset{{Table: detectors.DetectorTable{
+		Columns: []detectors.DetectorColumn{{ColumnName: "Status"}, {ColumnName: "Message"}},
+		Rows:    [][]interface{}{{"Critical", "Nodes are failing to pull images"}},
+	}}}
+	return []detectors.DetectorRunResponse{result}, nil
+}
+
+const nodesJSON = `{"items": [
+	{"metadata": {"name": "node-1"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}},
+	{"metadata": {"name": "node-2"}, "status": {"conditions": [
+		{"type": "MemoryPressure", "status": "True", "reason": "KubeletHasInsufficientMemory", "lastTransitionTime": "2025-01-01T11:00:00Z"},
+		{"type": "Ready", "status": "False", "reason": "KubeletNotReady", "lastTransitionTime": "2025-01-01T11:30:00Z"}]}}]}`
+
+const pendingJSON = `{"items": [
+	{"metadata": {"name": "web-0", "namespace": "default", "creationTimestamp": "2025-01-01T11:00:00Z"},
+	 "status": {"conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/2 nodes are available"}]}},
+	{"metadata": {"name": "web-1", "namespace": "default", "creationTimestamp": "2025-01-01T11:59:00Z"}, "status": {}}]}`
+
+func triageParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "prod", "hours": float64(2)}
+}
+
+func TestHandleTriage(t *testing.T) {
+	executor := &fakeExecutor{outputs: map[string]string{
+		"get nodes": nodesJSON,
+		"get pods":  pendingJSON,
+	}}
+	var alertParams map[string]interface{}
+	sources := Sources{
+		ResourceHealth: func(map[string]interface{}) (string, error) {
+			return `{"location": "eastus", "clusterEvents": [
+				{"time": "2025-01-01T11:00:00Z", "state": "Degraded", "summary": "Nodes are unhealthy", "classification": "Unplanned"},
+				{"time": "2025-01-01T11:30:00Z", "state": "Available", "classification": "Unplanned"}],
+				"platformEvents": [{"trackingId": "ABC-123", "type": "ServiceIssue", "title": "Networking degradation", "status": "Active", "services": ["Virtual Network"]}]}`, nil
+		},
+		FiredAlerts: func(params map[string]interface{}) (string, error) {
+			alertParams = params
+			return `{"firedCount": 1, "alerts": [{"name": "HighCPU", "severity": "Sev3", "monitorCondition": "Fired", "targetResource": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/prod"}]}`, nil
+		},
+		Detectors: fakeDetectors{},
+		RecentChanges: func(map[string]interface{}) (string, error) {
+			return "", fmt.Errorf("activity log unavailable")
+		},
+		Kubectl: executor,
+		Now:     func() time.Time { return now },
+	}
+
+	output, err := HandleTriage(triageParams(), sources, &config.ConfigData{})
+	if err != nil {
+		t.Fatalf("HandleTriage failed: %v", err)
+	}
+	var report TriageReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+
+	if report.Severity != SeverityCritical || report.Score != 100 {
+		t.Errorf("Expected a critical triage with the maximum score, got %s %d", report.Severity, report.Score)
+	}
+	if !report.StartTime.Equal(now.Add(-2*time.Hour)) || alertParams["time_range"] != "1d" {
+		t.Errorf("Expected a two hour window, got %s and %v", report.StartTime, alertParams)
+	}
+
+	// Critical findings come first, in triage order
+	var order []string
+	for _, finding := range report.Findings {
+		order = append(order, finding.Severity+":"+finding.Check)
+	}
+	expected := "critical:resource_health,critical:detectors,critical:node_readiness,warning:resource_health,warning:node_readiness,warning:pending_pods,info:fired_alerts"
+	if got := strings.Join(order, ","); got != expected {
+		t.Errorf("Unexpected findings order %s", got)
+	}
+	if !strings.Contains(report.Findings[0].Message, "ABC-123") || !strings.Contains(report.Summary, "ABC-123") {
+		t.Errorf("Expected the summary to start with the service issue, got %s", report.Summary)
+	}
+
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	if statuses[CheckRecentChanges] != StatusError || statuses[CheckFiredAlerts] != StatusOK || statuses[CheckPendingPods] != SeverityWarning {
+		t.Errorf("Unexpected check statuses %v", statuses)
+	}
+	if !strings.Contains(report.Summary, "recent_changes checks failed") {
+		t.Errorf("Expected the summary to name the failed check, got %s", report.Summary)
+	}
+
+	var tools []string
+	for _, step := range report.NextSteps {
+		tools = append(tools, step.Tool)
+	}
+	if got := strings.Join(tools, ","); got != "az_monitoring,run_detector,aks_noisy_neighbors,kubectl_resources,kubectl_resources,az_monitoring" {
+		t.Errorf("Unexpected next steps %s", got)
+	}
+	if report.NextSteps[1].Arguments["detector_name"] != "node-health" {
+		t.Errorf("Expected the detector to be suggested, got %v", report.NextSteps[1])
+	}
+}
+
+func TestHandleTriage_Healthy(t *testing.T) {
+	executor := &fakeExecutor{outputs: map[string]string{
+		"get nodes": `{"items": [{"metadata": {"name": "node-1"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}]}`,
+		"get pods":  `{"items": []}`,
+	}}
+	sources := Sources{Kubectl: executor, Now: func() time.Time { return now }}
+	cfg := &config.ConfigData{AllowNamespaces: "team-a,team-b"}
+
+	output, err := HandleTriage(triageParams(), sources, cfg)
+	if err != nil {
+		t.Fatalf("HandleTriage failed: %v", err)
+	}
+	var report TriageReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Severity != SeverityHealthy || report.Score != 0 || len(report.Findings) != 0 {
+		t.Errorf("Expected a healthy triage, got %+v", report)
+	}
+	for _, check := range report.Checks {
+		if check.Name == CheckDetectors && check.Status != StatusSkipped {
+			t.Errorf("Expected checks without a source to be skipped, got %+v", check)
+		}
+	}
+	pods := 0
+	for _, command := range executor.commands {
+		if strings.HasPrefix(command, "get pods --namespace") {
+			pods++
+		}
+	}
+	if pods != 2 {
+		t.Errorf("Expected pending pods to be read per allowed namespace, got %v", executor.commands)
+	}
+
+	for _, hours := range []interface{}{float64(0), float64(25), "2"} {
+		params := triageParams()
+		params["hours"] = hours
+		if _, err := HandleTriage(params, sources, cfg); err == nil {
+			t.Errorf("Expected hours %v to be rejected", hours)
+		}
+	}
+}
@@ -0,0 +1,499 @@
+// Package upgrade reports the live progress of AKS cluster and node pool upgrades: versions and
+// provisioning states from ARM, and surged, cordoned, drained and stuck nodes from the cluster.
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/nodes"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// clusterAPIVersion is the Microsoft.ContainerService API version used to read the cluster
+	clusterAPIVersion = "2024-05-01"
+	// Polling limits
+	maxDurationSeconds     = 3600
+	defaultIntervalSeconds = 30
+	minIntervalSeconds     = 10
+	// stuckAfterMinutes is how long a cordoned node may hold evictable pods before it is reported as stuck
+	stuckAfterMinutes = 15
+	// maxEventReasons caps the eviction failures reported per stuck node
+	maxEventReasons = 3
+)
+
+// unschedulableTaint is the taint the node controller adds to cordoned nodes, with the time it was added
+const unschedulableTaint = "node.kubernetes.io/unschedulable"
+
+// notifier reports a poll to the client
+type notifier func(ctx context.Context, progress, total float64, message string) error
+
+// PoolProgress is the upgrade state of a node pool
+type PoolProgress struct {
+	Name              string `json:"name"`
+	ProvisioningState string `json:"provisioningState"`
+	Upgrading         bool   `json:"upgrading"`
+	CurrentVersion    string `json:"currentVersion"`
+	TargetVersion     string `json:"targetVersion"`
+	NodeImageVersion  string `json:"nodeImageVersion,omitempty"`
+	MaxSurge          string `json:"maxSurge,omitempty"`
+	// Count is the node count of the pool; nodes beyond it are surge nodes
+	Count    int `json:"count"`
+	Nodes    int `json:"nodes"`
+	Upgraded int `json:"upgraded"`
+	Surged   int `json:"surged"`
+	Cordoned int `json:"cordoned"`
+	Drained  int `json:"drained"`
+}
+
+// StuckNode is a node holding up the upgrade, and why
+type StuckNode struct {
+	Name            string   `json:"name"`
+	Pool            string   `json:"pool"`
+	CordonedMinutes int      `json:"cordonedMinutes,omitempty"`
+	EvictablePods   int      `json:"evictablePods"`
+	Reasons         []string `json:"reasons"`
+}
+
+// UpgradeProgress is the result of the aks_upgrade_progress tool
+type UpgradeProgress struct {
+	ClusterName         string         `json:"clusterName"`
+	ProvisioningState   string         `json:"provisioningState"`
+	Upgrading           bool           `json:"upgrading"`
+	ControlPlaneVersion string         `json:"controlPlaneVersion"`
+	TargetVersion       string         `json:"targetVersion"`
+	Pools               []PoolProgress `json:"pools"`
+	StuckNodes          []StuckNode    `json:"stuckNodes"`
+	Polls               int            `json:"polls"`
+	Seconds             float64        `json:"seconds"`
+	StopReason          string         `json:"stopReason"`
+}
+
+// managedCluster is the part of the managed cluster read by the tool
+type managedCluster struct {
+	Properties struct {
+		ProvisioningState        string `json:"provisioningState"`
+		KubernetesVersion        string `json:"kubernetesVersion"`
+		CurrentKubernetesVersion string `json:"currentKubernetesVersion"`
+		AgentPoolProfiles        []struct {
+			Name                       string `json:"name"`
+			Count                      int    `json:"count"`
+			Mode                       string `json:"mode"`
+			VMSize                     string `json:"vmSize"`
+			EnableAutoScaling          bool   `json:"enableAutoScaling"`
+			MaxCount                   int    `json:"maxCount"`
+			ProvisioningState          string `json:"provisioningState"`
+			OrchestratorVersion        string `json:"orchestratorVersion"`
+			CurrentOrchestratorVersion string `json:"currentOrchestratorVersion"`
+			NodeImageVersion           string `json:"nodeImageVersion"`
+			UpgradeSettings            struct {
+				MaxSurge                  string `json:"maxSurge"`
+				DrainTimeoutInMinutes     int    `json:"drainTimeoutInMinutes"`
+				NodeSoakDurationInMinutes int    `json:"nodeSoakDurationInMinutes"`
+			} `json:"upgradeSettings"`
+		} `json:"agentPoolProfiles"`
+	} `json:"properties"`
+}
+
+// nodeState is the part of a node the progress is computed from
+type nodeState struct {
+	Name           string
+	Pool           string
+	KubeletVersion string
+	ImageVersion   string
+	Ready          bool
+	Unschedulable  bool
+	CordonedAt     time.Time
+}
+
+// pollOptions holds the parsed polling parameters
+type pollOptions struct {
+	duration time.Duration
+	interval time.Duration
+}
+
+// GetUpgradeProgressHandler returns a handler for the aks_upgrade_progress tool
+func GetUpgradeProgressHandler(client *azureclient.AzureClient) tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		api := client.ForExplain(cfg.Explain).ForTimeout(cfg.Timeout)
+		return HandleUpgradeProgress(ctx, params, api, k8s.WrapK8sExecutor(kubectl.NewExecutor()), tools.NotifyProgress, cfg)
+	})
+}
+
+// HandleUpgradeProgress reports the upgrade progress of a cluster once, or polls it until the upgrade finishes,
+// the duration elapses or ctx is cancelled, notifying the client of every poll
+func HandleUpgradeProgress(ctx context.Context, params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, notify notifier, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	opts := pollOptions{interval: defaultIntervalSeconds * time.Second}
+	if value, ok := params["duration_seconds"].(float64); ok {
+		if value < 0 || value > maxDurationSeconds {
+			return "", fmt.Errorf("duration_seconds must be between 0 and %d", maxDurationSeconds)
+		}
+		opts.duration = time.Duration(value) * time.Second
+	}
+	if value, ok := params["interval_seconds"].(float64); ok {
+		if value < minIntervalSeconds {
+			return "", fmt.Errorf("interval_seconds must be at least %d", minIntervalSeconds)
+		}
+		opts.interval = time.Duration(value) * time.Second
+	}
+	clusterPath := common.ClusterResourceID(subID, rg, clusterName)
+
+	kubectlRun := func(command string) (string, error) {
+		return kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+	progress, err := pollProgress(ctx, opts, func() (UpgradeProgress, error) {
+		return takeSnapshot(ctx, api, kubectlRun, clusterPath, clusterName, cfg)
+	}, notify)
+	if err != nil {
+		return "", err
+	}
+	resultJSON, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal upgrade progress to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// pollProgress takes snapshots every interval until the upgrade is no longer running, the duration elapses
+// or ctx is cancelled. The last snapshot is returned; a failed poll after the first one ends the polling.
+func pollProgress(ctx context.Context, opts pollOptions, snapshot func() (UpgradeProgress, error), notify notifier) (UpgradeProgress, error) {
+	start := time.Now()
+	var last UpgradeProgress
+	for polls := 1; ; polls++ {
+		progress, err := snapshot()
+		if err != nil {
+			if polls == 1 {
+				return UpgradeProgress{}, err
+			}
+			last.StopReason = fmt.Sprintf("poll failed: %v", err)
+			break
+		}
+		progress.Polls = polls
+		last = progress
+		// Notification failures must not end the polling; the last snapshot is still returned
+		_ = notify(ctx, float64(polls), 0, summarize(progress))
+
+		if !progress.Upgrading {
+			last.StopReason = "no upgrade is running"
+			if polls > 1 {
+				last.StopReason = "upgrade finished"
+			}
+			break
+		}
+		if opts.duration == 0 {
+			last.StopReason = "single snapshot"
+			break
+		}
+		if time.Since(start)+opts.interval > opts.duration {
+			last.StopReason = "duration elapsed"
+			break
+		}
+		select {
+		case <-ctx.Done():
+			last.StopReason = "cancelled"
+		case <-time.After(opts.interval):
+		}
+		if last.StopReason != "" {
+			break
+		}
+	}
+	last.Seconds = time.Since(start).Round(time.Millisecond).Seconds()
+	return last, nil
+}
+
+// takeSnapshot reads the cluster from ARM and the nodes, and for cordoned nodes the pods, budgets and
+// warning events, from the cluster
+func takeSnapshot(ctx context.Context, api common.ARMCaller, kubectlRun func(string) (string, error), clusterPath, clusterName string, cfg *config.ConfigData) (UpgradeProgress, error) {
+	data, err := api.CallARM(ctx, http.MethodGet, clusterPath+"?api-version="+clusterAPIVersion)
+	if err != nil {
+		return UpgradeProgress{}, fmt.Errorf("failed to get cluster %s: %v", clusterName, err)
+	}
+	var cluster managedCluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return UpgradeProgress{}, fmt.Errorf("failed to parse cluster %s: %v", clusterName, err)
+	}
+	output, err := kubectlRun("get nodes -o json")
+	if err != nil {
+		return UpgradeProgress{}, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodeStates, err := parseNodes(output)
+	if err != nil {
+		return UpgradeProgress{}, err
+	}
+
+	var pods []nodes.PodInfo
+	var budgets []nodes.DisruptionBudget
+	var events []warningEvent
+	for _, node := range nodeStates {
+		if !node.Unschedulable {
+			continue
+		}
+		// Pods, budgets and events are only needed to tell drained from stuck cordoned nodes
+		for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+			output, err := kubectlRun("get pods " + flag + " -o json")
+			if err != nil {
+				return UpgradeProgress{}, fmt.Errorf("failed to list pods: %v", err)
+			}
+			listed, err := nodes.ParsePods(output)
+			if err != nil {
+				return UpgradeProgress{}, err
+			}
+			pods = append(pods, listed...)
+			if output, err = kubectlRun("get poddisruptionbudgets " + flag + " -o json"); err != nil {
+				return UpgradeProgress{}, fmt.Errorf("failed to list pod disruption budgets: %v", err)
+			}
+			listedBudgets, err := nodes.ParseDisruptionBudgets(output)
+			if err != nil {
+				return UpgradeProgress{}, err
+			}
+			budgets = append(budgets, listedBudgets...)
+			// Events are best effort; the drain state does not depend on them
+			if output, err = kubectlRun("get events " + flag + " --field-selector type=Warning -o json"); err == nil {
+				events = append(events, parseWarningEvents(output)...)
+			}
+		}
+		break
+	}
+	return buildProgress(clusterName, cluster, nodeStates, pods, budgets, events, time.Now()), nil
+}
+
+// buildProgress computes the per-pool progress and the stuck nodes at now
+func buildProgress(clusterName string, cluster managedCluster, nodeStates []nodeState, pods []nodes.PodInfo, budgets []nodes.DisruptionBudget, events []warningEvent, now time.Time) UpgradeProgress {
+	props := cluster.Properties
+	progress := UpgradeProgress{
+		ClusterName:         clusterName,
+		ProvisioningState:   props.ProvisioningState,
+		Upgrading:           isUpgrading(props.ProvisioningState),
+		ControlPlaneVersion: props.CurrentKubernetesVersion,
+		TargetVersion:       props.KubernetesVersion,
+		Pools:               []PoolProgress{},
+		StuckNodes:          []StuckNode{},
+	}
+
+	evictable := map[string]int{}
+	podNodes := map[string]string{}
+	for _, pod := range pods {
+		podNodes[pod.Namespace+"/"+pod.Name] = pod.NodeName
+		if !pod.DaemonSet && pod.NodeName != "" {
+			evictable[pod.NodeName]++
+		}
+	}
+
+	upgradingPools := map[string]bool{}
+	for _, profile := range props.AgentPoolProfiles {
+		pool := PoolProgress{
+			Name:              profile.Name,
+			ProvisioningState: profile.ProvisioningState,
+			Upgrading:         isUpgrading(profile.ProvisioningState),
+			CurrentVersion:    profile.CurrentOrchestratorVersion,
+			TargetVersion:     profile.OrchestratorVersion,
+			NodeImageVersion:  profile.NodeImageVersion,
+			MaxSurge:          profile.UpgradeSettings.MaxSurge,
+			Count:             profile.Count,
+		}
+		for _, node := range nodeStates {
+			if node.Pool != profile.Name {
+				continue
+			}
+			pool.Nodes++
+			if onTarget(node, profile.OrchestratorVersion, profile.NodeImageVersion) {
+				pool.Upgraded++
+			}
+			if node.Unschedulable {
+				pool.Cordoned++
+				if evictable[node.Name] == 0 {
+					pool.Drained++
+				}
+			}
+		}
+		pool.Surged = max(0, pool.Nodes-pool.Count)
+		upgradingPools[pool.Name] = pool.Upgrading
+		progress.Upgrading = progress.Upgrading || pool.Upgrading
+		progress.Pools = append(progress.Pools, pool)
+	}
+
+	for _, node := range nodeStates {
+		if !upgradingPools[node.Pool] {
+			continue
+		}
+		stuck := StuckNode{Name: node.Name, Pool: node.Pool, EvictablePods: evictable[node.Name], Reasons: []string{}}
+		if !node.Ready {
+			stuck.Reasons = append(stuck.Reasons, "the node is not Ready; surge and reimaged nodes that do not become Ready hold up the upgrade")
+		}
+		if node.Unschedulable && stuck.EvictablePods > 0 {
+			if !node.CordonedAt.IsZero() {
+				stuck.CordonedMinutes = int(now.Sub(node.CordonedAt).Minutes())
+			}
+			for _, budget := range nodes.FindBlockingBudgets(budgets, pods, []nodes.NodeInfo{{Name: node.Name}}) {
+				stuck.Reasons = append(stuck.Reasons, fmt.Sprintf("PodDisruptionBudget %s/%s allows no disruptions and covers %s",
+					budget.Namespace, budget.Name, strings.Join(budget.Pods, ", ")))
+			}
+			seen := map[string]bool{}
+			for _, event := range events {
+				if len(seen) == maxEventReasons {
+					break
+				}
+				onNode := (event.Kind == "Node" && event.Name == node.Name) || (event.Kind == "Pod" && podNodes[event.Namespace+"/"+event.Name] == node.Name)
+				if onNode && isEvictionFailure(event) && !seen[event.Message] {
+					seen[event.Message] = true
+					stuck.Reasons = append(stuck.Reasons, fmt.Sprintf("%s: %s", event.Reason, event.Message))
+				}
+			}
+			if len(stuck.Reasons) == 0 && stuck.CordonedMinutes >= stuckAfterMinutes {
+				stuck.Reasons = append(stuck.Reasons, fmt.Sprintf("cordoned for %d minutes with %d evictable pods still running", stuck.CordonedMinutes, stuck.EvictablePods))
+			}
+		}
+		if len(stuck.Reasons) > 0 {
+			progress.StuckNodes = append(progress.StuckNodes, stuck)
+		}
+	}
+	sort.Slice(progress.StuckNodes, func(i, j int) bool { return progress.StuckNodes[i].Name < progress.StuckNodes[j].Name })
+	return progress
+}
+
+// summarize renders a snapshot as a one-line progress message
+func summarize(progress UpgradeProgress) string {
+	parts := []string{fmt.Sprintf("control plane %s (%s)", progress.ControlPlaneVersion, progress.ProvisioningState)}
+	for _, pool := range progress.Pools {
+		if !pool.Upgrading {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("pool %s: %d/%d upgraded, %d surged, %d cordoned, %d drained",
+			pool.Name, pool.Upgraded, pool.Nodes, pool.Surged, pool.Cordoned, pool.Drained))
+	}
+	if len(progress.StuckNodes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d stuck nodes", len(progress.StuckNodes)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// isUpgrading reports whether a provisioning state means an upgrade or update is running
+func isUpgrading(state string) bool {
+	return strings.EqualFold(state, "Upgrading") || strings.EqualFold(state, "Updating")
+}
+
+// onTarget reports whether a node runs the target Kubernetes version, which may be given as a minor version,
+// and the node image of its pool
+func onTarget(node nodeState, version, image string) bool {
+	kubelet := strings.TrimPrefix(node.KubeletVersion, "v")
+	if version != "" && kubelet != version && !strings.HasPrefix(kubelet, version+".") {
+		return false
+	}
+	return image == "" || node.ImageVersion == "" || node.ImageVersion == image
+}
+
+// parseNodes parses kubectl get nodes JSON output
+func parseNodes(output string) ([]nodeState, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Unschedulable bool `json:"unschedulable"`
+				Taints        []struct {
+					Key       string    `json:"key"`
+					TimeAdded time.Time `json:"timeAdded"`
+				} `json:"taints"`
+			} `json:"spec"`
+			Status struct {
+				NodeInfo struct {
+					KubeletVersion string `json:"kubeletVersion"`
+				} `json:"nodeInfo"`
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+	states := make([]nodeState, 0, len(list.Items))
+	for _, item := range list.Items {
+		labels := item.Metadata.Labels
+		state := nodeState{
+			Name:           item.Metadata.Name,
+			Pool:           labels["kubernetes.azure.com/agentpool"],
+			KubeletVersion: item.Status.NodeInfo.KubeletVersion,
+			ImageVersion:   labels["kubernetes.azure.com/node-image-version"],
+			Unschedulable:  item.Spec.Unschedulable,
+		}
+		if state.Pool == "" {
+			state.Pool = labels["agentpool"]
+		}
+		for _, taint := range item.Spec.Taints {
+			if taint.Key == unschedulableTaint {
+				state.CordonedAt = taint.TimeAdded
+			}
+		}
+		for _, cond := range item.Status.Conditions {
+			if cond.Type == "Ready" {
+				state.Ready = cond.Status == "True"
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// warningEvent is a Warning event and the object it is about
+type warningEvent struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+	Message   string
+}
+
+// parseWarningEvents parses kubectl get events JSON output; unparsable output yields no events
+func parseWarningEvents(output string) []warningEvent {
+	var list struct {
+		Items []struct {
+			Reason         string `json:"reason"`
+			Message        string `json:"message"`
+			InvolvedObject struct {
+				Kind      string `json:"kind"`
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"involvedObject"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil
+	}
+	events := make([]warningEvent, 0, len(list.Items))
+	for _, item := range list.Items {
+		events = append(events, warningEvent{
+			Kind:      item.InvolvedObject.Kind,
+			Namespace: item.InvolvedObject.Namespace,
+			Name:      item.InvolvedObject.Name,
+			Reason:    item.Reason,
+			Message:   item.Message,
+		})
+	}
+	return events
+}
+
+// isEvictionFailure reports whether an event reports a failed eviction or drain
+func isEvictionFailure(event warningEvent) bool {
+	text := strings.ToLower(event.Reason + " " + event.Message)
+	return strings.Contains(text, "evict") || strings.Contains(text, "drain") || strings.Contains(text, "disruption budget")
+}
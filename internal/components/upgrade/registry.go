@@ -0,0 +1,77 @@
+package upgrade
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterUpgradeProgressTool registers the aks_upgrade_progress tool
+func RegisterUpgradeProgressTool() mcp.Tool {
+	description := fmt.Sprintf(`Report the live progress of a running AKS cluster or node pool upgrade.
+
+Each poll reads the control plane version and provisioning state of the cluster and every node pool, and counts per pool
+the nodes already on the target version, the surge nodes, and the nodes cordoned and drained. Nodes cordoned for more than
+%d minutes that still run evictable pods are reported as stuck, with the reasons: PodDisruptionBudgets allowing no
+disruptions, failed evictions reported in events, or the node not becoming Ready.
+
+With duration_seconds the tool polls every interval_seconds until the upgrade finishes, the duration elapses or the call
+is cancelled, and sends each poll to the client as a progress notification (send a progressToken with the call to
+receive them). Without it the tool reports a single snapshot. Uses ARM and the current kubeconfig context.`, stuckAfterMinutes)
+
+	return mcp.NewTool(
+		"aks_upgrade_progress",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("duration_seconds",
+			mcp.Description(fmt.Sprintf("How long to keep polling, at most %d (default: 0, a single snapshot)", maxDurationSeconds)),
+		),
+		mcp.WithNumber("interval_seconds",
+			mcp.Description(fmt.Sprintf("Seconds between polls, at least %d (default: %d)", minIntervalSeconds, defaultIntervalSeconds)),
+		),
+	)
+}
+
+// RegisterUpgradeTuningTool registers the aks_upgrade_tuning tool
+func RegisterUpgradeTuningTool() mcp.Tool {
+	description := fmt.Sprintf(`Recommend max surge, drain timeout and node soak duration values per node pool, with the az commands to apply them.
+
+The recommendations are based on the pool sizes and current upgrade settings from ARM, the PodDisruptionBudgets and
+termination grace periods of the pods on each pool, and the duration of past cluster and node pool version upgrades in
+the Activity Log (up to %d days back). Pools surging fewer nodes than %d%% of their count are recommended %d%%, the
+drain timeout is raised to cover the longest pod grace period, and a soak is recommended after a failed upgrade.
+Minutes per batch from the latest successful upgrade estimate the upgrade duration with the current and recommended
+settings. Budgets allowing no disruptions are reported, since no surge setting speeds up a blocked drain.
+Uses ARM and the current kubeconfig context; read-only.`, maxHistoryDays, recommendedSurgePercent, recommendedSurgePercent)
+
+	return mcp.NewTool(
+		"aks_upgrade_tuning",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("history_days",
+			mcp.Description(fmt.Sprintf("Days of Activity Log upgrade history to analyze, at most %d (default: %d)", maxHistoryDays, defaultHistoryDays)),
+		),
+	)
+}
@@ -0,0 +1,450 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/components/monitor"
+	"github.com/Azure/aks-mcp/internal/components/nodes"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+const (
+	// Upgrade history window, limited by the Activity Log retention
+	defaultHistoryDays = 30
+	maxHistoryDays     = 90
+	// defaultDrainTimeoutMinutes is the drain timeout AKS applies when the pool does not set one
+	defaultDrainTimeoutMinutes = 30
+	// recommendedSurgePercent is the max surge AKS recommends for production node pools
+	recommendedSurgePercent = 33
+	// drainHeadroomMinutes is added to the longest pod grace period to cover evictions and rescheduling
+	drainHeadroomMinutes = 10
+	// recommendedSoakMinutes is the soak recommended after a failed upgrade
+	recommendedSoakMinutes = 5
+)
+
+// UpgradeSettings are the surge, drain and soak settings of a node pool
+type UpgradeSettings struct {
+	MaxSurge                string `json:"maxSurge"`
+	DrainTimeoutMinutes     int    `json:"drainTimeoutMinutes"`
+	NodeSoakDurationMinutes int    `json:"nodeSoakDurationMinutes"`
+}
+
+// UpgradeRun is a version upgrade of the cluster or a node pool found in the Activity Log
+type UpgradeRun struct {
+	Resource  string  `json:"resource"`
+	Started   string  `json:"started"`
+	Completed string  `json:"completed,omitempty"`
+	Status    string  `json:"status"`
+	Version   string  `json:"version"`
+	Minutes   float64 `json:"minutes,omitempty"`
+}
+
+// PoolTuning is the upgrade settings recommendation for a node pool
+type PoolTuning struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode,omitempty"`
+	VMSize   string `json:"vmSize,omitempty"`
+	Count    int    `json:"count"`
+	MaxCount int    `json:"maxCount,omitempty"`
+	// Current holds the effective settings, with AKS defaults filled in for unset values
+	Current               UpgradeSettings `json:"current"`
+	Recommended           UpgradeSettings `json:"recommended"`
+	SurgeNodes            int             `json:"surgeNodes"`
+	RecommendedSurgeNodes int             `json:"recommendedSurgeNodes"`
+	Upgrades              []UpgradeRun    `json:"upgrades,omitempty"`
+	// MinutesPerBatch is derived from the latest successful upgrade named in EstimateBasis
+	MinutesPerBatch             float64                `json:"minutesPerBatch,omitempty"`
+	EstimateBasis               string                 `json:"estimateBasis,omitempty"`
+	EstimatedMinutes            float64                `json:"estimatedMinutes,omitempty"`
+	RecommendedEstimatedMinutes float64                `json:"recommendedEstimatedMinutes,omitempty"`
+	LongestGracePeriodSeconds   int64                  `json:"longestGracePeriodSeconds,omitempty"`
+	ProtectedPods               int                    `json:"protectedPods,omitempty"`
+	BlockingBudgets             []nodes.BlockingBudget `json:"blockingBudgets,omitempty"`
+	Reasons                     []string               `json:"reasons"`
+	// Command applies the recommended settings; empty when the current settings are kept
+	Command string `json:"command,omitempty"`
+}
+
+// UpgradeTuningReport is the result of the aks_upgrade_tuning tool
+type UpgradeTuningReport struct {
+	ClusterName     string       `json:"clusterName"`
+	HistoryStart    string       `json:"historyStart"`
+	ClusterUpgrades []UpgradeRun `json:"clusterUpgrades,omitempty"`
+	Pools           []PoolTuning `json:"pools"`
+	Warnings        []string     `json:"warnings,omitempty"`
+}
+
+// GetUpgradeTuningHandler returns a handler for the aks_upgrade_tuning tool
+func GetUpgradeTuningHandler(client *azureclient.AzureClient) tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		api := client.ForExplain(cfg.Explain).ForTimeout(cfg.Timeout)
+		return HandleUpgradeTuning(ctx, params, api, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleUpgradeTuning recommends per-pool max surge, drain timeout and node soak duration values from the pool
+// sizes and current settings, the PodDisruptionBudgets and grace periods of the pods on each pool, and the duration
+// of past upgrades in the Activity Log. Budgets and history are best effort and reported as warnings when unavailable.
+func HandleUpgradeTuning(ctx context.Context, params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	days := defaultHistoryDays
+	if value, ok := params["history_days"].(float64); ok {
+		if value < 1 || value > maxHistoryDays {
+			return "", fmt.Errorf("history_days must be between 1 and %d", maxHistoryDays)
+		}
+		days = int(value)
+	}
+
+	clusterPath := common.ClusterResourceID(subID, rg, clusterName)
+	data, err := api.CallARM(ctx, http.MethodGet, clusterPath+"?api-version="+clusterAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %v", clusterName, err)
+	}
+	var cluster managedCluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return "", fmt.Errorf("failed to parse cluster %s: %v", clusterName, err)
+	}
+
+	var warnings []string
+	kubectlRun := func(command string) (string, error) {
+		return kubectlExecutor.Execute(map[string]interface{}{"command": command}, cfg)
+	}
+	nodeStates, pods, budgets, err := listWorkloads(kubectlRun, cfg)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("PodDisruptionBudgets and pod grace periods were not analyzed: %v", err))
+	}
+
+	now := time.Now().UTC()
+	start := now.AddDate(0, 0, -days)
+	changes, err := monitor.ListConfigChanges(api, subID, rg, clusterName, start, now)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("upgrade history was not analyzed: %v", err))
+	}
+
+	report := buildTuning(clusterName, rg, cluster, nodeStates, pods, budgets, upgradeRuns(changes))
+	report.HistoryStart = start.Format(time.RFC3339)
+	report.Warnings = append(warnings, report.Warnings...)
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal upgrade tuning to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// listWorkloads lists the nodes, pods and PodDisruptionBudgets the recommendations are based on
+func listWorkloads(kubectlRun func(string) (string, error), cfg *config.ConfigData) ([]nodeState, []nodes.PodInfo, []nodes.DisruptionBudget, error) {
+	output, err := kubectlRun("get nodes -o json")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodeStates, err := parseNodes(output)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var pods []nodes.PodInfo
+	var budgets []nodes.DisruptionBudget
+	for _, flag := range common.NamespaceFlags(cfg.AllowNamespaces) {
+		output, err := kubectlRun("get pods " + flag + " -o json")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to list pods: %v", err)
+		}
+		listed, err := nodes.ParsePods(output)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pods = append(pods, listed...)
+		if output, err = kubectlRun("get poddisruptionbudgets " + flag + " -o json"); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to list pod disruption budgets: %v", err)
+		}
+		listedBudgets, err := nodes.ParseDisruptionBudgets(output)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		budgets = append(budgets, listedBudgets...)
+	}
+	return nodeStates, pods, budgets, nil
+}
+
+// upgradeRuns picks the writes that changed the Kubernetes version of the cluster or a node pool. Writes whose
+// previous version is unknown (the first in the window) are skipped, since they may not have changed it.
+func upgradeRuns(changes []monitor.ConfigChange) []UpgradeRun {
+	var runs []UpgradeRun
+	for _, change := range changes {
+		if change.BeforeUnknown || !strings.HasSuffix(strings.ToLower(change.Operation), "/write") {
+			continue
+		}
+		version := ""
+		for _, diff := range change.Diffs {
+			if diff.Before != "" && (diff.Path == "properties.kubernetesVersion" || diff.Path == "properties.orchestratorVersion") {
+				version = diff.After
+			}
+		}
+		if version == "" {
+			continue
+		}
+		run := UpgradeRun{
+			Resource:  change.Resource,
+			Started:   change.Timestamp,
+			Completed: change.EndTimestamp,
+			Status:    change.Status,
+			Version:   version,
+		}
+		if change.EndTimestamp != "" {
+			started, _ := time.Parse(time.RFC3339Nano, change.Timestamp)
+			completed, _ := time.Parse(time.RFC3339Nano, change.EndTimestamp)
+			run.Minutes = roundMinutes(completed.Sub(started).Minutes())
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// buildTuning computes the recommendations for every node pool of the cluster
+func buildTuning(clusterName, rg string, cluster managedCluster, nodeStates []nodeState, pods []nodes.PodInfo, budgets []nodes.DisruptionBudget, runs []UpgradeRun) UpgradeTuningReport {
+	report := UpgradeTuningReport{ClusterName: clusterName}
+	for _, run := range runs {
+		if run.Resource == "cluster" {
+			report.ClusterUpgrades = append(report.ClusterUpgrades, run)
+		}
+	}
+
+	// A cluster upgrade replaces the nodes of every pool, so its duration is spread over all their batches
+	totalBatches := 0
+	for _, profile := range cluster.Properties.AgentPoolProfiles {
+		totalBatches += batches(profile.Count, surgeNodes(profile.UpgradeSettings.MaxSurge, profile.Count))
+	}
+
+	for _, profile := range cluster.Properties.AgentPoolProfiles {
+		settings := profile.UpgradeSettings
+		pool := PoolTuning{
+			Name:     profile.Name,
+			Mode:     profile.Mode,
+			VMSize:   profile.VMSize,
+			Count:    profile.Count,
+			MaxCount: profile.MaxCount,
+			Current: UpgradeSettings{
+				MaxSurge:                settings.MaxSurge,
+				DrainTimeoutMinutes:     settings.DrainTimeoutInMinutes,
+				NodeSoakDurationMinutes: settings.NodeSoakDurationInMinutes,
+			},
+			SurgeNodes: surgeNodes(settings.MaxSurge, profile.Count),
+			Reasons:    []string{},
+		}
+		if pool.Current.MaxSurge == "" {
+			pool.Current.MaxSurge = "1"
+			pool.Reasons = append(pool.Reasons, "maxSurge is not set, so AKS upgrades one node at a time")
+		}
+		if pool.Current.DrainTimeoutMinutes == 0 {
+			pool.Current.DrainTimeoutMinutes = defaultDrainTimeoutMinutes
+		}
+		pool.Recommended = pool.Current
+		pool.RecommendedSurgeNodes = pool.SurgeNodes
+
+		var poolNodes []nodes.NodeInfo
+		for _, node := range nodeStates {
+			if node.Pool == profile.Name {
+				poolNodes = append(poolNodes, nodes.NodeInfo{Name: node.Name, NodePool: node.Pool})
+			}
+		}
+		pool.BlockingBudgets = nodes.FindBlockingBudgets(budgets, pods, poolNodes)
+		pool.ProtectedPods = protectedPods(budgets, pods, poolNodes)
+		for _, pod := range pods {
+			if !pod.DaemonSet && nodeInPool(poolNodes, pod.NodeName) {
+				pool.LongestGracePeriodSeconds = max(pool.LongestGracePeriodSeconds, pod.TerminationGracePeriodSeconds)
+			}
+		}
+		for _, run := range runs {
+			if run.Resource == "agentPools/"+strings.ToLower(profile.Name) {
+				pool.Upgrades = append(pool.Upgrades, run)
+			}
+		}
+
+		recommendSurge(&pool)
+		recommendDrainTimeout(&pool)
+		recommendSoak(&pool)
+		estimateDuration(&pool, report.ClusterUpgrades, totalBatches)
+		pool.Command = tuningCommand(pool, rg, clusterName)
+		report.Pools = append(report.Pools, pool)
+	}
+	sort.SliceStable(report.Pools, func(i, j int) bool { return report.Pools[i].Name < report.Pools[j].Name })
+	return report
+}
+
+// recommendSurge raises the max surge to the recommended percentage when it surges fewer nodes
+func recommendSurge(pool *PoolTuning) {
+	if len(pool.BlockingBudgets) > 0 {
+		names := make([]string, 0, len(pool.BlockingBudgets))
+		for _, budget := range pool.BlockingBudgets {
+			names = append(names, budget.Namespace+"/"+budget.Name)
+		}
+		pool.Reasons = append(pool.Reasons, fmt.Sprintf("PodDisruptionBudgets %s allow no disruptions: drains on this pool wait for the drain timeout whatever the surge, so fix them before tuning", strings.Join(names, ", ")))
+	}
+	target := surgeNodes(fmt.Sprintf("%d%%", recommendedSurgePercent), pool.Count)
+	if pool.SurgeNodes >= target {
+		return
+	}
+	pool.Recommended.MaxSurge = fmt.Sprintf("%d%%", recommendedSurgePercent)
+	pool.RecommendedSurgeNodes = target
+	pool.Reasons = append(pool.Reasons, fmt.Sprintf(
+		"maxSurge %s upgrades %d of %d nodes per batch; %d%% is recommended for production pools and cuts the upgrade from %d to %d batches, but needs quota for %d extra %s nodes",
+		pool.Current.MaxSurge, pool.SurgeNodes, pool.Count, recommendedSurgePercent,
+		batches(pool.Count, pool.SurgeNodes), batches(pool.Count, target), target, pool.VMSize))
+}
+
+// recommendDrainTimeout raises the drain timeout when a pod on the pool may take longer to shut down
+func recommendDrainTimeout(pool *PoolTuning) {
+	needed := int(math.Ceil(float64(pool.LongestGracePeriodSeconds)/60)) + drainHeadroomMinutes
+	if pool.LongestGracePeriodSeconds == 0 || needed <= pool.Current.DrainTimeoutMinutes {
+		return
+	}
+	pool.Recommended.DrainTimeoutMinutes = needed
+	pool.Reasons = append(pool.Reasons, fmt.Sprintf(
+		"a pod on this pool has a termination grace period of %ds; a drain timeout of %d minutes leaves it time to shut down before the upgrade fails",
+		pool.LongestGracePeriodSeconds, needed))
+}
+
+// recommendSoak adds a soak after each node when the last upgrade of the pool failed, so the replaced
+// workloads settle before the next node is drained
+func recommendSoak(pool *PoolTuning) {
+	if len(pool.Upgrades) == 0 || pool.Current.NodeSoakDurationMinutes > 0 {
+		return
+	}
+	last := pool.Upgrades[len(pool.Upgrades)-1]
+	if !strings.EqualFold(last.Status, "Failed") {
+		return
+	}
+	pool.Recommended.NodeSoakDurationMinutes = recommendedSoakMinutes
+	reason := fmt.Sprintf("the last upgrade of this pool to %s failed; a %d minute soak lets workloads become ready on the upgraded nodes before the next node is drained", last.Version, recommendedSoakMinutes)
+	if pool.ProtectedPods > 0 {
+		reason += fmt.Sprintf(", which keeps the %d pods protected by PodDisruptionBudgets available", pool.ProtectedPods)
+	}
+	pool.Reasons = append(pool.Reasons, reason)
+}
+
+// estimateDuration derives the minutes per batch from the latest successful upgrade of the pool, or of the
+// cluster, and estimates the upgrade duration with the current and the recommended settings
+func estimateDuration(pool *PoolTuning, clusterRuns []UpgradeRun, totalBatches int) {
+	currentBatches := batches(pool.Count, pool.SurgeNodes)
+	if run, ok := latestSucceeded(pool.Upgrades); ok && currentBatches > 0 {
+		pool.MinutesPerBatch = roundMinutes(run.Minutes / float64(currentBatches))
+		pool.EstimateBasis = fmt.Sprintf("%s upgrade to %s at %s", run.Resource, run.Version, run.Started)
+	} else if run, ok := latestSucceeded(clusterRuns); ok && totalBatches > 0 {
+		pool.MinutesPerBatch = roundMinutes(run.Minutes / float64(totalBatches))
+		pool.EstimateBasis = fmt.Sprintf("cluster upgrade to %s at %s", run.Version, run.Started)
+	} else {
+		return
+	}
+	pool.EstimatedMinutes = roundMinutes(pool.MinutesPerBatch * float64(currentBatches))
+	perBatch := math.Max(0, pool.MinutesPerBatch-float64(pool.Current.NodeSoakDurationMinutes)) + float64(pool.Recommended.NodeSoakDurationMinutes)
+	pool.RecommendedEstimatedMinutes = roundMinutes(perBatch * float64(batches(pool.Count, pool.RecommendedSurgeNodes)))
+}
+
+// tuningCommand returns the az command applying the recommended settings that differ from the current ones
+func tuningCommand(pool PoolTuning, rg, clusterName string) string {
+	var flags []string
+	if pool.Recommended.MaxSurge != pool.Current.MaxSurge {
+		flags = append(flags, "--max-surge "+pool.Recommended.MaxSurge)
+	}
+	if pool.Recommended.DrainTimeoutMinutes != pool.Current.DrainTimeoutMinutes {
+		flags = append(flags, fmt.Sprintf("--drain-timeout %d", pool.Recommended.DrainTimeoutMinutes))
+	}
+	if pool.Recommended.NodeSoakDurationMinutes != pool.Current.NodeSoakDurationMinutes {
+		flags = append(flags, fmt.Sprintf("--node-soak-duration %d", pool.Recommended.NodeSoakDurationMinutes))
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("az aks nodepool update --resource-group %s --cluster-name %s --name %s %s", rg, clusterName, pool.Name, strings.Join(flags, " "))
+}
+
+// surgeNodes returns the number of nodes a max surge value adds per batch. Percentages are of the node
+// count, rounded up; an unset or invalid value surges one node.
+func surgeNodes(maxSurge string, count int) int {
+	if percent, ok := strings.CutSuffix(maxSurge, "%"); ok {
+		value, err := strconv.Atoi(percent)
+		if err != nil {
+			return 1
+		}
+		return max(1, int(math.Ceil(float64(count*value)/100)))
+	}
+	value, err := strconv.Atoi(maxSurge)
+	if err != nil || value < 1 {
+		return 1
+	}
+	return value
+}
+
+// batches returns the number of batches needed to upgrade count nodes with the given surge
+func batches(count, surge int) int {
+	if count <= 0 || surge <= 0 {
+		return 0
+	}
+	return (count + surge - 1) / surge
+}
+
+// protectedPods counts the evictable pods on the nodes that a PodDisruptionBudget covers
+func protectedPods(budgets []nodes.DisruptionBudget, pods []nodes.PodInfo, poolNodes []nodes.NodeInfo) int {
+	count := 0
+	for _, pod := range pods {
+		if pod.DaemonSet || !nodeInPool(poolNodes, pod.NodeName) {
+			continue
+		}
+		for _, budget := range budgets {
+			if budget.Namespace == pod.Namespace && selectorMatches(budget.MatchLabels, pod.Labels) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// selectorMatches reports whether every selector label is present on the pod
+func selectorMatches(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeInPool(poolNodes []nodes.NodeInfo, name string) bool {
+	for _, node := range poolNodes {
+		if node.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// latestSucceeded returns the most recent successful run with a known duration
+func latestSucceeded(runs []UpgradeRun) (UpgradeRun, bool) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if strings.EqualFold(runs[i].Status, "Succeeded") && runs[i].Minutes > 0 {
+			return runs[i], true
+		}
+	}
+	return UpgradeRun{}, false
+}
+
+func roundMinutes(minutes float64) float64 {
+	return math.Round(minutes*10) / 10
+}
@@ -0,0 +1,157 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// tuningARM serves the cluster and the Activity Log events of its node pool writes
+type tuningARM struct {
+	cluster string
+	events  []string
+}
+
+func (f *tuningARM) CallARM(_ context.Context, method, path string) ([]byte, error) {
+	switch {
+	case method == "GET" && strings.Contains(path, "managedClusters/aks?"):
+		return []byte(f.cluster), nil
+	case method == "GET" && strings.Contains(path, "Microsoft.Insights/eventtypes/management/values"):
+		return []byte(`{"value": [` + strings.Join(f.events, ",") + `]}`), nil
+	}
+	return nil, fmt.Errorf("unexpected request %s %s", method, path)
+}
+
+// poolWrite returns the start and end events of a node pool write setting the version
+func poolWrite(pool, correlationID, version, status string, start time.Time, minutes int) []string {
+	resourceID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks/agentPools/" + pool
+	event := func(timestamp time.Time, eventStatus, body string) string {
+		return fmt.Sprintf(`{"caller": "admin@contoso.com", "correlationId": %q, "eventTimestamp": %q, "resourceId": %q,
+			"operationName": {"value": "Microsoft.ContainerService/managedClusters/agentPools/write"}, "status": {"value": %q},
+			"properties": {"requestbody": %q}}`, correlationID, timestamp.Format(time.RFC3339), resourceID, eventStatus, body)
+	}
+	body := fmt.Sprintf(`{"properties": {"orchestratorVersion": %q}}`, version)
+	return []string{event(start, "Started", body), event(start.Add(time.Duration(minutes)*time.Minute), status, "")}
+}
+
+func tuningCluster() string {
+	return `{"properties": {"provisioningState": "Succeeded", "kubernetesVersion": "1.30.2", "agentPoolProfiles": [
+		{"name": "system", "count": 6, "mode": "System", "vmSize": "Standard_D4s_v5", "orchestratorVersion": "1.30.2"},
+		{"name": "user", "count": 10, "mode": "User", "vmSize": "Standard_D8s_v5", "orchestratorVersion": "1.29.4",
+		 "upgradeSettings": {"maxSurge": "50%"}}]}}`
+}
+
+func tuningKubectl() *fakeKubectl {
+	return &fakeKubectl{responses: map[string]string{
+		"get nodes": `{"items": [` + testNode("aks-system-0", "system", "1.30.2", true, false) + `,` + testNode("aks-user-0", "user", "1.29.4", true, false) + `]}`,
+		"get pods": `{"items": [
+			{"metadata": {"name": "queue-0", "namespace": "shop", "labels": {"app": "queue"}}, "spec": {"nodeName": "aks-user-0", "terminationGracePeriodSeconds": 1800}, "status": {"phase": "Running"}},
+			{"metadata": {"name": "web-1", "namespace": "shop", "labels": {"app": "web"}}, "spec": {"nodeName": "aks-user-0"}, "status": {"phase": "Running"}},
+			{"metadata": {"name": "db-0", "namespace": "shop", "labels": {"app": "db"}}, "spec": {"nodeName": "aks-system-0"}, "status": {"phase": "Running"}}]}`,
+		"get poddisruptionbudgets": `{"items": [
+			{"metadata": {"name": "web", "namespace": "shop"}, "spec": {"selector": {"matchLabels": {"app": "web"}}}, "status": {"disruptionsAllowed": 1}},
+			{"metadata": {"name": "db", "namespace": "shop"}, "spec": {"selector": {"matchLabels": {"app": "db"}}}, "status": {"disruptionsAllowed": 0}}]}`,
+	}}
+}
+
+func TestUpgradeTuning(t *testing.T) {
+	start := time.Now().UTC().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+	var events []string
+	events = append(events, poolWrite("system", "c1", "1.29.4", "Succeeded", start, 50)...)
+	events = append(events, poolWrite("system", "c2", "1.30.2", "Succeeded", start.Add(time.Hour), 60)...)
+	events = append(events, poolWrite("user", "c3", "1.29.4", "Succeeded", start, 30)...)
+	events = append(events, poolWrite("user", "c4", "1.30.2", "Failed", start.Add(2*time.Hour), 45)...)
+	api := &tuningARM{cluster: tuningCluster(), events: events}
+
+	output, err := HandleUpgradeTuning(context.Background(), upgradeParams(), api, tuningKubectl(), config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report UpgradeTuningReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Pools) != 2 || len(report.Warnings) != 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+
+	system := report.Pools[0]
+	if system.Current.MaxSurge != "1" || system.Recommended.MaxSurge != "33%" || system.RecommendedSurgeNodes != 2 {
+		t.Errorf("Expected the system pool surge to be raised to 33%%, got %+v", system)
+	}
+	// Only the second write is an upgrade; the first has no known previous version
+	if len(system.Upgrades) != 1 || system.Upgrades[0].Minutes != 60 || system.MinutesPerBatch != 10 ||
+		system.EstimatedMinutes != 60 || system.RecommendedEstimatedMinutes != 30 {
+		t.Errorf("Unexpected system pool estimate %+v", system)
+	}
+	if len(system.BlockingBudgets) != 1 || system.BlockingBudgets[0].Name != "db" {
+		t.Errorf("Expected the db budget to block the system pool, got %+v", system.BlockingBudgets)
+	}
+	if system.Command != "az aks nodepool update --resource-group rg --cluster-name aks --name system --max-surge 33%" {
+		t.Errorf("Unexpected system pool command %q", system.Command)
+	}
+
+	user := report.Pools[1]
+	if user.Recommended.MaxSurge != "50%" || user.Recommended.DrainTimeoutMinutes != 40 || user.Recommended.NodeSoakDurationMinutes != 5 {
+		t.Errorf("Expected a longer drain timeout and a soak for the user pool, got %+v", user.Recommended)
+	}
+	if user.ProtectedPods != 1 || user.LongestGracePeriodSeconds != 1800 || user.MinutesPerBatch != 0 {
+		t.Errorf("Unexpected user pool analysis %+v", user)
+	}
+	if !strings.Contains(strings.Join(user.Reasons, "\n"), "the last upgrade of this pool to 1.30.2 failed") {
+		t.Errorf("Expected the failed upgrade to be explained, got %v", user.Reasons)
+	}
+	if user.Command != "az aks nodepool update --resource-group rg --cluster-name aks --name user --drain-timeout 40 --node-soak-duration 5" {
+		t.Errorf("Unexpected user pool command %q", user.Command)
+	}
+}
+
+func TestUpgradeTuningWithoutClusterAccess(t *testing.T) {
+	api := &tuningARM{cluster: tuningCluster()}
+	kubectl := &fakeKubectl{responses: map[string]string{}}
+	params := upgradeParams()
+	params["history_days"] = 7.0
+	output, err := HandleUpgradeTuning(context.Background(), params, api, kubectl, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report UpgradeTuningReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "PodDisruptionBudgets and pod grace periods were not analyzed") {
+		t.Errorf("Expected a warning about the missing cluster access, got %v", report.Warnings)
+	}
+	if report.Pools[1].Command != "" || report.Pools[1].EstimatedMinutes != 0 {
+		t.Errorf("Expected the user pool settings to be kept without history, got %+v", report.Pools[1])
+	}
+
+	params["history_days"] = 120.0
+	if _, err := HandleUpgradeTuning(context.Background(), params, api, kubectl, config.NewConfig()); err == nil {
+		t.Error("Expected history_days beyond the Activity Log retention to be rejected")
+	}
+}
+
+func TestSurgeNodes(t *testing.T) {
+	tests := []struct {
+		maxSurge string
+		count    int
+		want     int
+	}{
+		{"", 5, 1},
+		{"3", 5, 3},
+		{"33%", 10, 4},
+		{"10%", 3, 1},
+		{"abc", 5, 1},
+	}
+	for _, tt := range tests {
+		if got := surgeNodes(tt.maxSurge, tt.count); got != tt.want {
+			t.Errorf("surgeNodes(%q, %d) = %d, want %d", tt.maxSurge, tt.count, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+type fakeARM struct {
+	clusters []string
+	calls    int
+}
+
+// CallARM returns the configured clusters in order, repeating the last one
+func (f *fakeARM) CallARM(_ context.Context, method, path string) ([]byte, error) {
+	if method != "GET" || !strings.Contains(path, "managedClusters/aks?") {
+		return nil, fmt.Errorf("unexpected request %s %s", method, path)
+	}
+	body := f.clusters[min(f.calls, len(f.clusters)-1)]
+	f.calls++
+	return []byte(body), nil
+}
+
+type fakeKubectl struct {
+	responses map[string]string
+	commands  []string
+}
+
+func (f *fakeKubectl) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	command, _ := params["command"].(string)
+	f.commands = append(f.commands, command)
+	for prefix, output := range f.responses {
+		if strings.HasPrefix(command, prefix) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected command %s", command)
+}
+
+func testCluster(state, poolState string) string {
+	return fmt.Sprintf(`{"properties": {"provisioningState": %q, "kubernetesVersion": "1.30.2", "currentKubernetesVersion": "1.30.2",
+		"agentPoolProfiles": [
+			{"name": "system", "count": 2, "provisioningState": %q, "orchestratorVersion": "1.30.2", "currentOrchestratorVersion": "1.29.4",
+			 "nodeImageVersion": "AKSUbuntu-2204gen2containerd-202406.07.0", "upgradeSettings": {"maxSurge": "1"}},
+			{"name": "user", "count": 1, "provisioningState": "Succeeded", "orchestratorVersion": "1.29.4", "currentOrchestratorVersion": "1.29.4"}]}}`,
+		state, poolState)
+}
+
+func testNode(name, pool, version string, ready, cordoned bool) string {
+	taints := `[]`
+	if cordoned {
+		taints = fmt.Sprintf(`[{"key": "node.kubernetes.io/unschedulable", "effect": "NoSchedule", "timeAdded": %q}]`,
+			time.Now().Add(-40*time.Minute).UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf(`{"metadata": {"name": %q, "labels": {"kubernetes.azure.com/agentpool": %q, "kubernetes.azure.com/node-image-version": "AKSUbuntu-2204gen2containerd-202406.07.0"}},
+		"spec": {"unschedulable": %t, "taints": %s},
+		"status": {"nodeInfo": {"kubeletVersion": "v%s"}, "conditions": [{"type": "Ready", "status": "%s"}]}}`,
+		name, pool, cordoned, taints, version, map[bool]string{true: "True", false: "False"}[ready])
+}
+
+func newUpgradeKubectl() *fakeKubectl {
+	nodes := strings.Join([]string{
+		testNode("aks-system-0", "system", "1.30.2", true, false),
+		testNode("aks-system-1", "system", "1.29.4", true, true),
+		testNode("aks-system-2", "system", "1.29.4", true, true),
+		testNode("aks-system-surge", "system", "1.30.2", false, false),
+		testNode("aks-user-0", "user", "1.29.4", true, false),
+	}, ",")
+	return &fakeKubectl{responses: map[string]string{
+		"get nodes": `{"items": [` + nodes + `]}`,
+		"get pods": `{"items": [
+			{"metadata": {"name": "web-1", "namespace": "shop", "labels": {"app": "web"}}, "spec": {"nodeName": "aks-system-1"}, "status": {"phase": "Running"}},
+			{"metadata": {"name": "db-0", "namespace": "shop", "labels": {"app": "db"}}, "spec": {"nodeName": "aks-system-1"}, "status": {"phase": "Running"}},
+			{"metadata": {"name": "proxy", "namespace": "kube-system", "ownerReferences": [{"kind": "DaemonSet"}]}, "spec": {"nodeName": "aks-system-2"}, "status": {"phase": "Running"}}]}`,
+		"get poddisruptionbudgets": `{"items": [
+			{"metadata": {"name": "web", "namespace": "shop"}, "spec": {"selector": {"matchLabels": {"app": "web"}}}, "status": {"disruptionsAllowed": 0}}]}`,
+		"get events": `{"items": [
+			{"reason": "EvictionFailed", "message": "Cannot evict pod as it would violate the pod's disruption budget.", "involvedObject": {"kind": "Pod", "name": "db-0", "namespace": "shop"}},
+			{"reason": "BackOff", "message": "Back-off restarting failed container", "involvedObject": {"kind": "Pod", "name": "web-1", "namespace": "shop"}}]}`,
+	}}
+}
+
+func upgradeParams() map[string]interface{} {
+	return map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+}
+
+func TestUpgradeProgressSnapshot(t *testing.T) {
+	api := &fakeARM{clusters: []string{testCluster("Succeeded", "Upgrading")}}
+	var messages []string
+	notify := func(_ context.Context, progress, _ float64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	output, err := HandleUpgradeProgress(context.Background(), upgradeParams(), api, newUpgradeKubectl(), notify, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var progress UpgradeProgress
+	if err := json.Unmarshal([]byte(output), &progress); err != nil {
+		t.Fatalf("Failed to parse progress: %v", err)
+	}
+	if !progress.Upgrading || progress.Polls != 1 || progress.StopReason != "single snapshot" || len(progress.Pools) != 2 {
+		t.Fatalf("Unexpected progress %+v", progress)
+	}
+	system := progress.Pools[0]
+	if system.Nodes != 4 || system.Upgraded != 2 || system.Surged != 2 || system.Cordoned != 2 || system.Drained != 1 {
+		t.Errorf("Unexpected system pool progress %+v", system)
+	}
+	if progress.Pools[1].Upgrading || progress.Pools[1].Upgraded != 1 {
+		t.Errorf("Unexpected user pool progress %+v", progress.Pools[1])
+	}
+
+	if len(progress.StuckNodes) != 2 {
+		t.Fatalf("Expected the cordoned node with pods and the NotReady surge node to be stuck, got %+v", progress.StuckNodes)
+	}
+	blocked, surge := progress.StuckNodes[0], progress.StuckNodes[1]
+	reasons := strings.Join(blocked.Reasons, "\n")
+	if blocked.Name != "aks-system-1" || blocked.EvictablePods != 2 || blocked.CordonedMinutes < 39 ||
+		!strings.Contains(reasons, "PodDisruptionBudget shop/web allows no disruptions") || !strings.Contains(reasons, "EvictionFailed") ||
+		strings.Contains(reasons, "BackOff") {
+		t.Errorf("Unexpected stuck node %+v", blocked)
+	}
+	if surge.Name != "aks-system-surge" || !strings.Contains(surge.Reasons[0], "not Ready") {
+		t.Errorf("Unexpected stuck node %+v", surge)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "pool system: 2/4 upgraded, 2 surged, 2 cordoned, 1 drained; 2 stuck nodes") {
+		t.Errorf("Unexpected notifications %v", messages)
+	}
+}
+
+func TestUpgradeProgressIdleClusterSkipsPods(t *testing.T) {
+	kubectl := newUpgradeKubectl()
+	kubectl.responses["get nodes"] = `{"items": [` + testNode("aks-system-0", "system", "1.30.2", true, false) + `]}`
+	api := &fakeARM{clusters: []string{testCluster("Succeeded", "Succeeded")}}
+	params := upgradeParams()
+	params["duration_seconds"] = 600.0
+	output, err := HandleUpgradeProgress(context.Background(), params, api, kubectl, func(context.Context, float64, float64, string) error { return nil }, config.NewConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, `"stopReason": "no upgrade is running"`) {
+		t.Errorf("Expected polling to stop without a running upgrade, got %s", output)
+	}
+	if len(kubectl.commands) != 1 {
+		t.Errorf("Expected only nodes to be listed without cordoned nodes, got %v", kubectl.commands)
+	}
+
+	params["interval_seconds"] = 1.0
+	if _, err := HandleUpgradeProgress(context.Background(), params, api, kubectl, nil, config.NewConfig()); err == nil {
+		t.Error("Expected an interval below the minimum to be rejected")
+	}
+}
+
+func TestPollProgress(t *testing.T) {
+	snapshots := []UpgradeProgress{{Upgrading: true}, {Upgrading: true}, {Upgrading: false}}
+	polls := 0
+	var progressValues []float64
+	last, err := pollProgress(context.Background(), pollOptions{duration: time.Minute, interval: time.Millisecond}, func() (UpgradeProgress, error) {
+		polls++
+		return snapshots[polls-1], nil
+	}, func(_ context.Context, progress, _ float64, _ string) error {
+		progressValues = append(progressValues, progress)
+		return nil
+	})
+	if err != nil || last.Polls != 3 || last.StopReason != "upgrade finished" || len(progressValues) != 3 || progressValues[2] != 3 {
+		t.Errorf("Expected three polls ending with the upgrade, got %+v %v %v", last, progressValues, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	last, err = pollProgress(ctx, pollOptions{duration: time.Minute, interval: time.Second}, func() (UpgradeProgress, error) {
+		return UpgradeProgress{Upgrading: true}, nil
+	}, func(context.Context, float64, float64, string) error { return nil })
+	if err != nil || last.StopReason != "cancelled" {
+		t.Errorf("Expected a cancelled call to stop polling, got %+v %v", last, err)
+	}
+}
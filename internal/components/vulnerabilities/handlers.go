@@ -0,0 +1,259 @@
+// Package vulnerabilities provides a tool that reports vulnerable container images running in AKS clusters.
+package vulnerabilities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// subAssessmentsAPIVersion is the Microsoft.Security API version used to list sub-assessments
+const subAssessmentsAPIVersion = "2019-01-01-preview"
+
+// maxListPages bounds nextLink paging when listing sub-assessments
+const maxListPages = 50
+
+// maxVulnerabilitiesPerImage bounds the findings listed for each image
+const maxVulnerabilitiesPerImage = 20
+
+// defaultMinSeverity is the lowest severity reported when min_severity is not provided
+const defaultMinSeverity = "Medium"
+
+// ImageReport lists the fixable vulnerabilities of one image used by a workload
+type ImageReport struct {
+	Image           string          `json:"image"`
+	Digest          string          `json:"digest,omitempty"`
+	Critical        int             `json:"critical"`
+	High            int             `json:"high"`
+	Medium          int             `json:"medium"`
+	Low             int             `json:"low"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	Truncated       bool            `json:"truncated,omitempty"`
+}
+
+// WorkloadReport groups the vulnerable images of a workload
+type WorkloadReport struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Images []ImageReport `json:"images"`
+}
+
+// NamespaceReport groups the vulnerable workloads of a namespace
+type NamespaceReport struct {
+	Namespace string           `json:"namespace"`
+	Workloads []WorkloadReport `json:"workloads"`
+}
+
+// VulnerabilityReport is the result returned by the scan_image_vulnerabilities tool
+type VulnerabilityReport struct {
+	ClusterName      string            `json:"clusterName"`
+	MinSeverity      string            `json:"minSeverity"`
+	ScannedImages    int               `json:"scannedImages"`
+	VulnerableImages int               `json:"vulnerableImages"`
+	Namespaces       []NamespaceReport `json:"namespaces"`
+	Warnings         []string          `json:"warnings,omitempty"`
+}
+
+// GetScanImageVulnerabilitiesHandler returns a handler for the scan_image_vulnerabilities command
+func GetScanImageVulnerabilitiesHandler(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleScanImageVulnerabilities(params, client, k8s.WrapK8sExecutor(kubectl.NewExecutor()), cfg)
+	})
+}
+
+// HandleScanImageVulnerabilities enumerates running images and cross-references them with Defender for Cloud findings
+func HandleScanImageVulnerabilities(params map[string]interface{}, api common.ARMCaller, kubectlExecutor tools.CommandExecutor, cfg *config.ConfigData) (string, error) {
+	subID, _, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+
+	minSeverity := defaultMinSeverity
+	if value, ok := params["min_severity"].(string); ok && value != "" {
+		minSeverity = normalizeSeverity(value)
+		if minSeverity == "Unknown" {
+			return "", fmt.Errorf("invalid min_severity parameter: %s (expected Critical, High, Medium or Low)", value)
+		}
+	}
+
+	namespaceFlags := common.NamespaceFlags(cfg.AllowNamespaces)
+	if ns, ok := params["namespace"].(string); ok && ns != "" {
+		if !cfg.SecurityConfig.IsNamespaceAllowed(ns) {
+			return "", fmt.Errorf("namespace '%s' is not in the allowed namespaces", ns)
+		}
+		namespaceFlags = []string{"--namespace " + ns}
+	}
+
+	report := VulnerabilityReport{ClusterName: clusterName, MinSeverity: minSeverity, Namespaces: []NamespaceReport{}}
+
+	var images []RunningImage
+	for _, flag := range namespaceFlags {
+		output, err := kubectlExecutor.Execute(map[string]interface{}{"command": "get pods " + flag + " -o json"}, cfg)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to list pods (%s): %v", flag, err))
+			continue
+		}
+		parsed, err := ParsePodImages(output)
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+			continue
+		}
+		images = append(images, parsed...)
+	}
+	report.ScannedImages = countDistinctImages(images)
+
+	index, err := listFindings(context.Background(), api, subID)
+	if err != nil {
+		report.Warnings = append(report.Warnings,
+			fmt.Sprintf("%v (vulnerability assessments require Defender for Containers on the subscription)", err))
+	} else {
+		report.Namespaces, report.VulnerableImages = BuildNamespaceReports(images, index, minSeverity)
+	}
+
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal vulnerability report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// listFindings reads the container image vulnerability sub-assessments of the subscription
+func listFindings(ctx context.Context, api common.ARMCaller, subID string) (*findingIndex, error) {
+	index := newFindingIndex()
+	next := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Security/subAssessments?api-version=%s", subID, subAssessmentsAPIVersion)
+	for page := 0; next != "" && page < maxListPages; page++ {
+		body, err := api.CallARM(ctx, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Defender for Cloud vulnerability assessments: %w", err)
+		}
+		results, nextLink, err := ParseSubAssessments(body)
+		if err != nil {
+			return nil, err
+		}
+		index.add(results)
+		next = nextLink
+	}
+	return index, nil
+}
+
+// BuildNamespaceReports groups vulnerable images by namespace and workload, most urgent first.
+// It returns the reports and the number of distinct vulnerable images.
+func BuildNamespaceReports(images []RunningImage, index *findingIndex, minSeverity string) ([]NamespaceReport, int) {
+	minRank := severityRank[strings.ToLower(minSeverity)]
+	byNamespace := map[string]map[string]*WorkloadReport{}
+	vulnerable := map[string]bool{}
+
+	for _, image := range images {
+		var findings []Vulnerability
+		for _, finding := range index.lookup(image) {
+			if severityRank[strings.ToLower(finding.Severity)] >= minRank {
+				findings = append(findings, finding)
+			}
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		sortVulnerabilities(findings)
+
+		imageReport := ImageReport{Image: image.Image, Digest: image.Digest}
+		for _, finding := range findings {
+			switch finding.Severity {
+			case "Critical":
+				imageReport.Critical++
+			case "High":
+				imageReport.High++
+			case "Medium":
+				imageReport.Medium++
+			case "Low":
+				imageReport.Low++
+			}
+		}
+		if len(findings) > maxVulnerabilitiesPerImage {
+			findings = findings[:maxVulnerabilitiesPerImage]
+			imageReport.Truncated = true
+		}
+		imageReport.Vulnerabilities = findings
+		vulnerable[image.Image+"@"+image.Digest] = true
+
+		workloads := byNamespace[image.Namespace]
+		if workloads == nil {
+			workloads = map[string]*WorkloadReport{}
+			byNamespace[image.Namespace] = workloads
+		}
+		key := image.WorkloadKind + "/" + image.WorkloadName
+		if workloads[key] == nil {
+			workloads[key] = &WorkloadReport{Kind: image.WorkloadKind, Name: image.WorkloadName}
+		}
+		workloads[key].Images = append(workloads[key].Images, imageReport)
+	}
+
+	reports := []NamespaceReport{}
+	for ns, workloads := range byNamespace {
+		nsReport := NamespaceReport{Namespace: ns}
+		for _, workload := range workloads {
+			sort.SliceStable(workload.Images, func(i, j int) bool {
+				return imagePriority(workload.Images[i]).before(imagePriority(workload.Images[j]))
+			})
+			nsReport.Workloads = append(nsReport.Workloads, *workload)
+		}
+		sort.SliceStable(nsReport.Workloads, func(i, j int) bool {
+			pi, pj := workloadPriority(nsReport.Workloads[i]), workloadPriority(nsReport.Workloads[j])
+			if pi != pj {
+				return pi.before(pj)
+			}
+			return nsReport.Workloads[i].Name < nsReport.Workloads[j].Name
+		})
+		reports = append(reports, nsReport)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		pi, pj := workloadPriority(reports[i].Workloads[0]), workloadPriority(reports[j].Workloads[0])
+		if pi != pj {
+			return pi.before(pj)
+		}
+		return reports[i].Namespace < reports[j].Namespace
+	})
+	return reports, len(vulnerable)
+}
+
+// priority orders images by their count of critical, then high, medium and low findings
+type priority [4]int
+
+func (p priority) before(other priority) bool {
+	for i := range p {
+		if p[i] != other[i] {
+			return p[i] > other[i]
+		}
+	}
+	return false
+}
+
+func imagePriority(image ImageReport) priority {
+	return priority{image.Critical, image.High, image.Medium, image.Low}
+}
+
+// workloadPriority is the priority of the workload's most urgent image (images are sorted first)
+func workloadPriority(workload WorkloadReport) priority {
+	if len(workload.Images) == 0 {
+		return priority{}
+	}
+	return imagePriority(workload.Images[0])
+}
+
+// countDistinctImages counts images by reference and digest
+func countDistinctImages(images []RunningImage) int {
+	distinct := map[string]bool{}
+	for _, image := range images {
+		distinct[image.Image+"@"+image.Digest] = true
+	}
+	return len(distinct)
+}
@@ -0,0 +1,293 @@
+package vulnerabilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severities ordered from most to least urgent
+var severityRank = map[string]int{
+	"critical": 4,
+	"high":     3,
+	"medium":   2,
+	"low":      1,
+}
+
+// RunningImage is a container image used by a workload in the cluster
+type RunningImage struct {
+	Namespace    string `json:"namespace"`
+	WorkloadKind string `json:"workloadKind"`
+	WorkloadName string `json:"workloadName"`
+	Image        string `json:"image"`
+	// Digest is the sha256 digest reported by the kubelet for the running container
+	Digest string `json:"digest,omitempty"`
+}
+
+// Vulnerability is a finding reported by Defender for an image
+type Vulnerability struct {
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	Package          string `json:"package,omitempty"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	FixedVersion     string `json:"fixedVersion"`
+}
+
+// imageFindings holds the findings of one image artifact keyed by its registry location
+type imageFindings struct {
+	Repository string
+	Tags       []string
+	Digest     string
+	Findings   []Vulnerability
+}
+
+// ParsePodImages extracts the images of running and pending pods with their owning workload
+func ParsePodImages(output string) ([]RunningImage, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				Labels          map[string]string `json:"labels"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers     []struct{ Name, Image string } `json:"containers"`
+				InitContainers []struct{ Name, Image string } `json:"initContainers"`
+			} `json:"spec"`
+			Status struct {
+				Phase             string `json:"phase"`
+				ContainerStatuses []struct {
+					Name    string `json:"name"`
+					ImageID string `json:"imageID"`
+				} `json:"containerStatuses"`
+				InitContainerStatuses []struct {
+					Name    string `json:"name"`
+					ImageID string `json:"imageID"`
+				} `json:"initContainerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var images []RunningImage
+	for _, pod := range list.Items {
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		kind, name := "Pod", pod.Metadata.Name
+		if len(pod.Metadata.OwnerReferences) > 0 {
+			owner := pod.Metadata.OwnerReferences[0]
+			kind, name = owner.Kind, owner.Name
+			// Pods of a Deployment are owned by a ReplicaSet named <deployment>-<pod-template-hash>
+			if hash := pod.Metadata.Labels["pod-template-hash"]; kind == "ReplicaSet" && strings.HasSuffix(name, "-"+hash) {
+				kind, name = "Deployment", strings.TrimSuffix(name, "-"+hash)
+			}
+		}
+
+		digests := map[string]string{}
+		for _, status := range pod.Status.ContainerStatuses {
+			digests[status.Name] = imageDigest(status.ImageID)
+		}
+		for _, status := range pod.Status.InitContainerStatuses {
+			digests[status.Name] = imageDigest(status.ImageID)
+		}
+		containers := append(pod.Spec.InitContainers, pod.Spec.Containers...)
+		for _, container := range containers {
+			image := RunningImage{
+				Namespace:    pod.Metadata.Namespace,
+				WorkloadKind: kind,
+				WorkloadName: name,
+				Image:        container.Image,
+				Digest:       digests[container.Name],
+			}
+			key := strings.Join([]string{image.Namespace, kind, name, image.Image, image.Digest}, "|")
+			if !seen[key] {
+				seen[key] = true
+				images = append(images, image)
+			}
+		}
+	}
+	return images, nil
+}
+
+// imageDigest returns the sha256 digest of a kubelet image ID such as docker.io/library/nginx@sha256:abc
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	if strings.HasPrefix(imageID, "sha256:") {
+		return imageID
+	}
+	return ""
+}
+
+// ParseSubAssessments parses a page of Defender for Cloud sub-assessments into findings per image.
+// Only unhealthy findings with a fixed version available are kept.
+func ParseSubAssessments(body []byte) ([]imageFindings, string, error) {
+	var page struct {
+		Value []struct {
+			Properties struct {
+				ID     string `json:"id"`
+				Status struct {
+					Code     string `json:"code"`
+					Severity string `json:"severity"`
+				} `json:"status"`
+				AdditionalData struct {
+					ArtifactDetails struct {
+						RegistryHost   string   `json:"registryHost"`
+						RepositoryName string   `json:"repositoryName"`
+						Digest         string   `json:"digest"`
+						Tags           []string `json:"tags"`
+					} `json:"artifactDetails"`
+					SoftwareDetails struct {
+						PackageName  string `json:"packageName"`
+						Version      string `json:"version"`
+						FixedVersion string `json:"fixedVersion"`
+						FixStatus    string `json:"fixStatus"`
+					} `json:"softwareDetails"`
+					VulnerabilityDetails struct {
+						CveID    string `json:"cveId"`
+						Severity string `json:"severity"`
+					} `json:"vulnerabilityDetails"`
+				} `json:"additionalData"`
+			} `json:"properties"`
+		} `json:"value"`
+		NextLink string `json:"nextLink"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, "", fmt.Errorf("failed to parse Defender sub-assessments: %w", err)
+	}
+
+	var results []imageFindings
+	for _, item := range page.Value {
+		props := item.Properties
+		data := props.AdditionalData
+		if !strings.EqualFold(props.Status.Code, "Unhealthy") || data.ArtifactDetails.RepositoryName == "" {
+			continue
+		}
+		software := data.SoftwareDetails
+		if software.FixedVersion == "" || strings.EqualFold(software.FixStatus, "FixNotAvailable") {
+			continue
+		}
+		id := data.VulnerabilityDetails.CveID
+		if id == "" {
+			id = props.ID
+		}
+		severity := data.VulnerabilityDetails.Severity
+		if severity == "" {
+			severity = props.Status.Severity
+		}
+		artifact := data.ArtifactDetails
+		results = append(results, imageFindings{
+			Repository: strings.ToLower(strings.TrimSuffix(artifact.RegistryHost, "/") + "/" + artifact.RepositoryName),
+			Tags:       artifact.Tags,
+			Digest:     artifact.Digest,
+			Findings: []Vulnerability{{
+				ID:               id,
+				Severity:         normalizeSeverity(severity),
+				Package:          software.PackageName,
+				InstalledVersion: software.Version,
+				FixedVersion:     software.FixedVersion,
+			}},
+		})
+	}
+	return results, page.NextLink, nil
+}
+
+// normalizeSeverity returns the severity with the casing used in reports
+func normalizeSeverity(severity string) string {
+	s := strings.ToLower(strings.TrimSpace(severity))
+	if _, ok := severityRank[s]; !ok {
+		return "Unknown"
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// splitImage returns the lowercase registry/repository and the tag of an image reference
+func splitImage(image string) (string, string) {
+	image = strings.ToLower(image)
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	tag := ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, tag = image[:i], image[i+1:]
+	}
+	host := strings.SplitN(image, "/", 2)[0]
+	if !strings.Contains(image, "/") || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		// Docker Hub short names such as nginx or library/nginx
+		if !strings.Contains(image, "/") {
+			image = "library/" + image
+		}
+		image = "docker.io/" + image
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	return image, tag
+}
+
+// findingIndex looks up Defender findings by image digest or by repository and tag
+type findingIndex struct {
+	byDigest map[string][]Vulnerability
+	byTag    map[string][]Vulnerability
+	seen     map[string]bool
+}
+
+func newFindingIndex() *findingIndex {
+	return &findingIndex{
+		byDigest: map[string][]Vulnerability{},
+		byTag:    map[string][]Vulnerability{},
+		seen:     map[string]bool{},
+	}
+}
+
+// add records findings, ignoring duplicates reported by both the registry and runtime assessments
+func (idx *findingIndex) add(results []imageFindings) {
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			key := strings.Join([]string{result.Repository, result.Digest, finding.ID, finding.Package}, "|")
+			if idx.seen[key] {
+				continue
+			}
+			idx.seen[key] = true
+			if result.Digest != "" {
+				idx.byDigest[result.Digest] = append(idx.byDigest[result.Digest], finding)
+			}
+			for _, tag := range result.Tags {
+				repoTag := result.Repository + ":" + strings.ToLower(tag)
+				idx.byTag[repoTag] = append(idx.byTag[repoTag], finding)
+			}
+		}
+	}
+}
+
+// lookup returns the findings for a running image, preferring an exact digest match
+func (idx *findingIndex) lookup(image RunningImage) []Vulnerability {
+	if image.Digest != "" {
+		if findings, ok := idx.byDigest[image.Digest]; ok {
+			return findings
+		}
+	}
+	repo, tag := splitImage(image.Image)
+	return idx.byTag[repo+":"+tag]
+}
+
+// sortVulnerabilities orders findings by severity, then by ID
+func sortVulnerabilities(findings []Vulnerability) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		ri, rj := severityRank[strings.ToLower(findings[i].Severity)], severityRank[strings.ToLower(findings[j].Severity)]
+		if ri != rj {
+			return ri > rj
+		}
+		return findings[i].ID < findings[j].ID
+	})
+}
@@ -0,0 +1,42 @@
+package vulnerabilities
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterScanImageVulnerabilitiesTool registers the scan_image_vulnerabilities tool
+func RegisterScanImageVulnerabilitiesTool() mcp.Tool {
+	description := `Report vulnerable container images running in an AKS cluster.
+
+Enumerates the images of pods in the allowed namespaces with the current kubeconfig context and cross-references
+them with the container image vulnerability assessments of Microsoft Defender for Cloud (ACR registry scans and
+runtime assessments) in the subscription. Images are matched by digest, or by repository and tag when the digest
+is unknown.
+
+Only vulnerabilities with a fixed version available are reported, grouped by namespace and workload with the
+images carrying the most critical and high findings first. Images without findings are either clean or not
+assessed by Defender (for example images from registries Defender does not scan). Requires Defender for Containers.`
+
+	return mcp.NewTool(
+		"scan_image_vulnerabilities",
+		mcp.WithDescription(description),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Only scan images in this namespace (default: all allowed namespaces)"),
+		),
+		mcp.WithString("min_severity",
+			mcp.Description("Lowest severity to report: Critical, High, Medium or Low (default: Medium)"),
+		),
+	)
+}
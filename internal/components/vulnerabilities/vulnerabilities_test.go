@@ -0,0 +1,201 @@
+package vulnerabilities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/security"
+)
+
+type fakeARM struct {
+	pages map[string]string
+	calls []string
+}
+
+func (f *fakeARM) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	f.calls = append(f.calls, path)
+	for key, body := range f.pages {
+		if strings.Contains(path, key) {
+			return []byte(body), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected path %s", path)
+}
+
+type fakeExecutor struct {
+	outputs  map[string]string
+	commands []string
+}
+
+func (f *fakeExecutor) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	cmd, _ := params["command"].(string)
+	f.commands = append(f.commands, cmd)
+	for key, output := range f.outputs {
+		if strings.Contains(cmd, key) {
+			return output, nil
+		}
+	}
+	return `{"items":[]}`, nil
+}
+
+const podsJSON = `{"items":[
+  {"metadata":{"name":"web-7d9f8-abc","namespace":"apps","labels":{"pod-template-hash":"7d9f8"},
+    "ownerReferences":[{"kind":"ReplicaSet","name":"web-7d9f8"}]},
+   "spec":{"containers":[{"name":"web","image":"myacr.azurecr.io/web:v1"}]},
+   "status":{"phase":"Running","containerStatuses":[{"name":"web","imageID":"myacr.azurecr.io/web@sha256:aaa"}]}},
+  {"metadata":{"name":"web-7d9f8-def","namespace":"apps","labels":{"pod-template-hash":"7d9f8"},
+    "ownerReferences":[{"kind":"ReplicaSet","name":"web-7d9f8"}]},
+   "spec":{"containers":[{"name":"web","image":"myacr.azurecr.io/web:v1"}]},
+   "status":{"phase":"Running","containerStatuses":[{"name":"web","imageID":"myacr.azurecr.io/web@sha256:aaa"}]}},
+  {"metadata":{"name":"db-0","namespace":"data","ownerReferences":[{"kind":"StatefulSet","name":"db"}]},
+   "spec":{"containers":[{"name":"db","image":"postgres:15"}]},
+   "status":{"phase":"Running","containerStatuses":[{"name":"db","imageID":""}]}},
+  {"metadata":{"name":"done","namespace":"apps"},
+   "spec":{"containers":[{"name":"job","image":"myacr.azurecr.io/job:v1"}]},
+   "status":{"phase":"Succeeded"}}
+]}`
+
+func subAssessment(repo, digest, tag, cve, severity, fixed string) string {
+	return fmt.Sprintf(`{"properties":{"id":"%s","status":{"code":"Unhealthy","severity":"%s"},"additionalData":{
+	  "artifactDetails":{"registryHost":"%s","repositoryName":"%s","digest":"%s","tags":["%s"]},
+	  "softwareDetails":{"packageName":"openssl","version":"1.0","fixedVersion":"%s"},
+	  "vulnerabilityDetails":{"cveId":"%s","severity":"%s"}}}}`,
+		cve, severity, strings.SplitN(repo, "/", 2)[0], strings.SplitN(repo, "/", 2)[1], digest, tag, fixed, cve, severity)
+}
+
+func TestParsePodImages(t *testing.T) {
+	images, err := ParsePodImages(podsJSON)
+	if err != nil {
+		t.Fatalf("ParsePodImages failed: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("Expected duplicate and completed pods to be skipped, got %+v", images)
+	}
+	if images[0].WorkloadKind != "Deployment" || images[0].WorkloadName != "web" || images[0].Digest != "sha256:aaa" {
+		t.Errorf("Expected ReplicaSet pod to resolve to its Deployment, got %+v", images[0])
+	}
+	if images[1].WorkloadKind != "StatefulSet" || images[1].Digest != "" {
+		t.Errorf("Unexpected StatefulSet image: %+v", images[1])
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := map[string][2]string{
+		"nginx":                         {"docker.io/library/nginx", "latest"},
+		"postgres:15":                   {"docker.io/library/postgres", "15"},
+		"myacr.azurecr.io/web:v1":       {"myacr.azurecr.io/web", "v1"},
+		"localhost:5000/app":            {"localhost:5000/app", "latest"},
+		"myacr.azurecr.io/web@sha256:a": {"myacr.azurecr.io/web", "latest"},
+	}
+	for image, want := range tests {
+		repo, tag := splitImage(image)
+		if repo != want[0] || tag != want[1] {
+			t.Errorf("splitImage(%q) = %q, %q; want %q, %q", image, repo, tag, want[0], want[1])
+		}
+	}
+}
+
+func TestParseSubAssessmentsSkipsUnfixable(t *testing.T) {
+	body := `{"value":[` +
+		subAssessment("myacr.azurecr.io/web", "sha256:aaa", "v1", "CVE-1", "High", "1.1") + `,` +
+		subAssessment("myacr.azurecr.io/web", "sha256:aaa", "v1", "CVE-2", "Critical", "") + `,` +
+		`{"properties":{"status":{"code":"Healthy"},"additionalData":{"artifactDetails":{"repositoryName":"web"}}}}` +
+		`],"nextLink":"https://management.azure.com/next"}`
+	results, next, err := ParseSubAssessments([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseSubAssessments failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Findings[0].ID != "CVE-1" || results[0].Repository != "myacr.azurecr.io/web" {
+		t.Errorf("Expected only the fixable unhealthy finding, got %+v", results)
+	}
+	if next != "https://management.azure.com/next" {
+		t.Errorf("Unexpected nextLink %q", next)
+	}
+}
+
+func TestHandleScanImageVulnerabilities(t *testing.T) {
+	arm := &fakeARM{pages: map[string]string{
+		"/subAssessments": `{"value":[` +
+			subAssessment("myacr.azurecr.io/web", "sha256:aaa", "v0", "CVE-LOW", "Low", "1.1") + `,` +
+			subAssessment("myacr.azurecr.io/web", "sha256:aaa", "v0", "CVE-HIGH", "High", "1.2") + `,` +
+			subAssessment("docker.io/library/postgres", "sha256:other", "15", "CVE-CRIT", "Critical", "15.1") + `,` +
+			subAssessment("docker.io/library/postgres", "sha256:other", "15", "CVE-CRIT", "Critical", "15.1") +
+			`]}`,
+	}}
+	executor := &fakeExecutor{outputs: map[string]string{"get pods": podsJSON}}
+	cfg := config.NewConfig()
+
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleScanImageVulnerabilities(params, arm, executor, cfg)
+	if err != nil {
+		t.Fatalf("HandleScanImageVulnerabilities failed: %v", err)
+	}
+	var report VulnerabilityReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if report.ScannedImages != 2 || report.VulnerableImages != 2 {
+		t.Errorf("Unexpected image counts: %+v", report)
+	}
+	if len(report.Namespaces) != 2 || report.Namespaces[0].Namespace != "data" {
+		t.Fatalf("Expected the namespace with a critical finding first, got %+v", report.Namespaces)
+	}
+	db := report.Namespaces[0].Workloads[0].Images[0]
+	if db.Critical != 1 || len(db.Vulnerabilities) != 1 {
+		t.Errorf("Expected duplicate findings to be merged and matched by tag, got %+v", db)
+	}
+	web := report.Namespaces[1].Workloads[0]
+	if web.Kind != "Deployment" || web.Name != "web" {
+		t.Errorf("Unexpected workload: %+v", web)
+	}
+	if len(web.Images[0].Vulnerabilities) != 1 || web.Images[0].Vulnerabilities[0].ID != "CVE-HIGH" {
+		t.Errorf("Expected digest match filtered by the default Medium severity, got %+v", web.Images[0])
+	}
+	if executor.commands[0] != "get pods --all-namespaces -o json" {
+		t.Errorf("Unexpected kubectl command %q", executor.commands[0])
+	}
+}
+
+func TestHandleScanImageVulnerabilitiesNamespaces(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "apps,data"
+	cfg.SecurityConfig = &security.SecurityConfig{AllowedNamespaces: "apps,data"}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+
+	executor := &fakeExecutor{}
+	arm := &fakeARM{pages: map[string]string{"/subAssessments": `{"value":[]}`}}
+	if _, err := HandleScanImageVulnerabilities(params, arm, executor, cfg); err != nil {
+		t.Fatalf("HandleScanImageVulnerabilities failed: %v", err)
+	}
+	if len(executor.commands) != 2 || executor.commands[1] != "get pods --namespace data -o json" {
+		t.Errorf("Expected pods to be listed per allowed namespace, got %v", executor.commands)
+	}
+
+	params["namespace"] = "kube-system"
+	if _, err := HandleScanImageVulnerabilities(params, arm, executor, cfg); err == nil {
+		t.Error("Expected error for a namespace outside the allowed namespaces")
+	}
+
+	params["namespace"] = ""
+	params["min_severity"] = "urgent"
+	if _, err := HandleScanImageVulnerabilities(params, arm, executor, cfg); err == nil {
+		t.Error("Expected error for invalid min_severity")
+	}
+}
+
+func TestHandleScanImageVulnerabilitiesDefenderUnavailable(t *testing.T) {
+	executor := &fakeExecutor{outputs: map[string]string{"get pods": podsJSON}}
+	params := map[string]interface{}{"subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks"}
+	result, err := HandleScanImageVulnerabilities(params, &fakeARM{}, executor, config.NewConfig())
+	if err != nil {
+		t.Fatalf("HandleScanImageVulnerabilities failed: %v", err)
+	}
+	if !strings.Contains(result, "Defender for Containers") {
+		t.Errorf("Expected a warning about Defender, got %s", result)
+	}
+}
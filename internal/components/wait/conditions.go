@@ -0,0 +1,209 @@
+package wait
+
+import "fmt"
+
+// Conditions the tool can wait for
+const (
+	ConditionRolloutComplete = "rollout_complete"
+	ConditionReady           = "ready"
+	ConditionComplete        = "complete"
+)
+
+// kindConditions maps each supported kind to the conditions it can be waited on, the first being the default
+var kindConditions = map[string][]string{
+	"deployment":  {ConditionRolloutComplete},
+	"statefulset": {ConditionRolloutComplete},
+	"daemonset":   {ConditionRolloutComplete},
+	"pod":         {ConditionReady},
+	"job":         {ConditionComplete},
+}
+
+// Object holds the fields of the watched workloads that the conditions read
+type Object struct {
+	Metadata struct {
+		Name              string `json:"name"`
+		Generation        int64  `json:"generation"`
+		DeletionTimestamp string `json:"deletionTimestamp"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas       *int32 `json:"replicas"`
+		UpdateStrategy struct {
+			Type          string `json:"type"`
+			RollingUpdate *struct {
+				Partition *int32 `json:"partition"`
+			} `json:"rollingUpdate"`
+		} `json:"updateStrategy"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration     int64       `json:"observedGeneration"`
+		Replicas               int32       `json:"replicas"`
+		UpdatedReplicas        int32       `json:"updatedReplicas"`
+		ReadyReplicas          int32       `json:"readyReplicas"`
+		CurrentReplicas        int32       `json:"currentReplicas"`
+		AvailableReplicas      int32       `json:"availableReplicas"`
+		CurrentRevision        string      `json:"currentRevision"`
+		UpdateRevision         string      `json:"updateRevision"`
+		DesiredNumberScheduled int32       `json:"desiredNumberScheduled"`
+		UpdatedNumberScheduled int32       `json:"updatedNumberScheduled"`
+		NumberAvailable        int32       `json:"numberAvailable"`
+		Phase                  string      `json:"phase"`
+		Conditions             []Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+// Condition is an entry of status.conditions
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of evaluating a condition against the current state of an object
+type Result struct {
+	Satisfied bool
+	// Failed is set when the object can no longer satisfy the condition without a change, so waiting stops
+	Failed  bool
+	Message string
+}
+
+// Evaluate checks the condition against an object of the given kind. The rollout checks follow
+// kubectl rollout status.
+func Evaluate(kind, condition string, obj Object) Result {
+	switch condition {
+	case ConditionRolloutComplete:
+		switch kind {
+		case "deployment":
+			return deploymentRollout(obj)
+		case "statefulset":
+			return statefulSetRollout(obj)
+		case "daemonset":
+			return daemonSetRollout(obj)
+		}
+	case ConditionReady:
+		return podReady(obj)
+	case ConditionComplete:
+		return jobComplete(obj)
+	}
+	return Result{Failed: true, Message: fmt.Sprintf("condition %s is not supported for %s", condition, kind)}
+}
+
+func deploymentRollout(obj Object) Result {
+	if obj.Metadata.Generation > obj.Status.ObservedGeneration {
+		return Result{Message: "waiting for the deployment spec update to be observed"}
+	}
+	if c := findCondition(obj.Status.Conditions, "Progressing"); c != nil && c.Reason == "ProgressDeadlineExceeded" {
+		return Result{Failed: true, Message: "deployment exceeded its progress deadline: " + c.Message}
+	}
+	status := obj.Status
+	if obj.Spec.Replicas != nil && status.UpdatedReplicas < *obj.Spec.Replicas {
+		return Result{Message: fmt.Sprintf("%d of %d new replicas have been updated", status.UpdatedReplicas, *obj.Spec.Replicas)}
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return Result{Message: fmt.Sprintf("%d old replicas are pending termination", status.Replicas-status.UpdatedReplicas)}
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return Result{Message: fmt.Sprintf("%d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas)}
+	}
+	return Result{Satisfied: true, Message: "deployment successfully rolled out"}
+}
+
+func statefulSetRollout(obj Object) Result {
+	if obj.Metadata.Generation > obj.Status.ObservedGeneration {
+		return Result{Message: "waiting for the statefulset spec update to be observed"}
+	}
+	status := obj.Status
+	if obj.Spec.Replicas != nil && status.ReadyReplicas < *obj.Spec.Replicas {
+		return Result{Message: fmt.Sprintf("%d of %d pods are ready", status.ReadyReplicas, *obj.Spec.Replicas)}
+	}
+	if obj.Spec.UpdateStrategy.Type == "OnDelete" {
+		return Result{Satisfied: true, Message: "statefulset pods are ready (OnDelete strategy: pods are updated only when deleted)"}
+	}
+	if rolling := obj.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil && obj.Spec.Replicas != nil {
+		pending := *obj.Spec.Replicas - *rolling.Partition
+		if status.UpdatedReplicas < pending {
+			return Result{Message: fmt.Sprintf("partitioned rollout: %d of %d pods updated", status.UpdatedReplicas, pending)}
+		}
+		return Result{Satisfied: true, Message: fmt.Sprintf("partitioned rollout complete: %d new pods updated", status.UpdatedReplicas)}
+	}
+	if status.UpdateRevision != status.CurrentRevision {
+		return Result{Message: fmt.Sprintf("%d pods at revision %s, waiting for the rolling update to complete", status.UpdatedReplicas, status.UpdateRevision)}
+	}
+	return Result{Satisfied: true, Message: fmt.Sprintf("statefulset rolling update complete %d pods at revision %s", status.CurrentReplicas, status.CurrentRevision)}
+}
+
+func daemonSetRollout(obj Object) Result {
+	if obj.Metadata.Generation > obj.Status.ObservedGeneration {
+		return Result{Message: "waiting for the daemonset spec update to be observed"}
+	}
+	status := obj.Status
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+		return Result{Message: fmt.Sprintf("%d of %d updated pods are scheduled", status.UpdatedNumberScheduled, status.DesiredNumberScheduled)}
+	}
+	if status.NumberAvailable < status.DesiredNumberScheduled {
+		return Result{Message: fmt.Sprintf("%d of %d updated pods are available", status.NumberAvailable, status.DesiredNumberScheduled)}
+	}
+	return Result{Satisfied: true, Message: "daemonset successfully rolled out"}
+}
+
+func podReady(obj Object) Result {
+	if obj.Metadata.DeletionTimestamp != "" {
+		return Result{Failed: true, Message: "pod is being deleted"}
+	}
+	switch obj.Status.Phase {
+	case "Failed", "Succeeded":
+		return Result{Failed: true, Message: fmt.Sprintf("pod has terminated with phase %s and will not become Ready", obj.Status.Phase)}
+	}
+	if c := findCondition(obj.Status.Conditions, "Ready"); c != nil && c.Status == "True" {
+		return Result{Satisfied: true, Message: "pod is Ready"}
+	}
+	return Result{Message: podWaitingMessage(obj)}
+}
+
+// podWaitingMessage explains why a pod is not Ready yet from its first false condition
+func podWaitingMessage(obj Object) string {
+	for _, conditionType := range []string{"PodScheduled", "Initialized", "ContainersReady", "Ready"} {
+		c := findCondition(obj.Status.Conditions, conditionType)
+		if c == nil || c.Status == "True" {
+			continue
+		}
+		message := fmt.Sprintf("condition %s is %s", conditionType, c.Status)
+		if c.Reason != "" {
+			message += " (" + c.Reason + ")"
+		}
+		if c.Message != "" {
+			message += ": " + c.Message
+		}
+		return message
+	}
+	if obj.Status.Phase == "" {
+		return "pod has no status yet"
+	}
+	return fmt.Sprintf("pod is %s and not Ready", obj.Status.Phase)
+}
+
+func jobComplete(obj Object) Result {
+	if c := findCondition(obj.Status.Conditions, "Failed"); c != nil && c.Status == "True" {
+		message := "job failed"
+		if c.Reason != "" {
+			message += " (" + c.Reason + ")"
+		}
+		if c.Message != "" {
+			message += ": " + c.Message
+		}
+		return Result{Failed: true, Message: message}
+	}
+	if c := findCondition(obj.Status.Conditions, "Complete"); c != nil && c.Status == "True" {
+		return Result{Satisfied: true, Message: "job completed"}
+	}
+	return Result{Message: "job has not completed"}
+}
+
+func findCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
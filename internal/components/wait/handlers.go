@@ -0,0 +1,376 @@
+// Package wait provides a tool that watches Kubernetes workloads until a condition such as a completed
+// rollout, a Ready pod or a completed job holds, so clients do not have to poll with repeated kubectl calls.
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+)
+
+// Stop reasons of a wait
+const (
+	StopConditionMet = "condition met"
+	StopFailed       = "failed"
+	StopTimedOut     = "timed out"
+	StopCancelled    = "cancelled"
+)
+
+// watchRestartDelay is the pause before a watch closed by the API server is restarted
+const watchRestartDelay = time.Second
+
+// ObjectState is the final state of a watched object
+type ObjectState struct {
+	Name      string `json:"name"`
+	Satisfied bool   `json:"satisfied"`
+	Failed    bool   `json:"failed,omitempty"`
+	Message   string `json:"message"`
+	// Object is the object as last seen, without managedFields
+	Object map[string]interface{} `json:"object"`
+}
+
+// WaitReport is the result returned when the wait ends
+type WaitReport struct {
+	Kind       string        `json:"kind"`
+	Namespace  string        `json:"namespace"`
+	Name       string        `json:"name,omitempty"`
+	Selector   string        `json:"selector,omitempty"`
+	Condition  string        `json:"condition"`
+	Satisfied  bool          `json:"satisfied"`
+	StopReason string        `json:"stopReason"`
+	Summary    string        `json:"summary"`
+	Seconds    float64       `json:"seconds"`
+	Updates    int           `json:"updates"`
+	Objects    []ObjectState `json:"objects"`
+}
+
+// streamer starts kubectl with the given arguments and returns its stdout.
+// The process must stop when ctx is done; wait blocks until it has exited.
+type streamer func(ctx context.Context, args []string) (stdout io.Reader, wait func() error, err error)
+
+// notifier reports a change of an object's state to the client
+type notifier func(ctx context.Context, progress float64, message string) error
+
+// waitOptions holds the parsed tool parameters
+type waitOptions struct {
+	kind      string
+	condition string
+	namespace string
+	name      string
+	selector  string
+	timeout   time.Duration
+}
+
+// trackedObject is the latest state of a watched object and its evaluation
+type trackedObject struct {
+	raw    json.RawMessage
+	result Result
+}
+
+// watchEvent is a line of kubectl get --watch --output-watch-events output
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// GetWaitForConditionHandler returns a handler for the aks_wait_for_condition command.
+// The wait lasts at most the timeout of the call, which the handler receives in its configuration.
+func GetWaitForConditionHandler() tools.ResourceHandler {
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		notify := func(ctx context.Context, progress float64, message string) error {
+			return tools.NotifyProgress(ctx, progress, 0, message)
+		}
+		return HandleWaitForCondition(ctx, params, k8s.WrapK8sExecutor(kubectl.NewExecutor()), k8s.StartKubectl, notify, cfg)
+	})
+}
+
+// HandleWaitForCondition lists the matching objects and watches them until all of them satisfy the
+// condition, one of them fails it, the call's timeout elapses or ctx is cancelled. The watch is
+// restarted from a fresh list when the API server closes it.
+func HandleWaitForCondition(ctx context.Context, params map[string]interface{}, executor tools.CommandExecutor, stream streamer, notify notifier, cfg *config.ConfigData) (string, error) {
+	opts, err := parseWaitOptions(params, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	start := time.Now()
+	w := &waiter{opts: opts, notify: notify, objects: map[string]*trackedObject{}, messages: map[string]string{}}
+	stopReason := ""
+	for stopReason == "" {
+		if waitCtx.Err() != nil {
+			stopReason = StopTimedOut
+			break
+		}
+		if err := w.list(waitCtx, executor, cfg); err != nil {
+			return "", err
+		}
+		if stopReason = w.stopReason(); stopReason != "" {
+			break
+		}
+		if stopReason, err = w.watch(waitCtx, stream); err != nil {
+			return "", err
+		}
+		if stopReason == "" {
+			// The API server closed the watch; pause before listing again
+			select {
+			case <-waitCtx.Done():
+			case <-time.After(watchRestartDelay):
+			}
+		}
+	}
+	if stopReason == StopTimedOut && ctx.Err() != nil {
+		stopReason = StopCancelled
+	}
+
+	report := w.report(stopReason)
+	report.Seconds = time.Since(start).Round(time.Millisecond).Seconds()
+	resultJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal wait report to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// waiter tracks the watched objects of one call
+type waiter struct {
+	opts     waitOptions
+	notify   notifier
+	objects  map[string]*trackedObject
+	messages map[string]string
+	updates  int
+}
+
+// selectorArgs selects the named object, which may not exist yet, or the objects matching the label selector
+func (w *waiter) selectorArgs() []string {
+	if w.opts.name != "" {
+		return []string{"--field-selector", "metadata.name=" + w.opts.name}
+	}
+	return []string{"--selector", w.opts.selector}
+}
+
+// list replaces the tracked objects with the current ones, so objects deleted while no watch was
+// running are dropped
+func (w *waiter) list(ctx context.Context, executor tools.CommandExecutor, cfg *config.ConfigData) error {
+	command := strings.Join(append([]string{"get", w.opts.kind, "--namespace", w.opts.namespace}, append(w.selectorArgs(), "--output", "json")...), " ")
+	output, err := executor.Execute(map[string]interface{}{"command": command}, cfg)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to run kubectl %s: %v", command, err)
+	}
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return fmt.Errorf("failed to parse kubectl output: %v", err)
+	}
+	w.objects = map[string]*trackedObject{}
+	for _, item := range list.Items {
+		if err := w.update(ctx, "ADDED", item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch applies the watch events until the wait can stop, ctx is done or the API server closes the
+// watch, in which case it returns an empty stop reason
+func (w *waiter) watch(ctx context.Context, stream streamer) (string, error) {
+	args := append([]string{"get", w.opts.kind, "--namespace", w.opts.namespace}, w.selectorArgs()...)
+	args = append(args, "--watch", "--output-watch-events", "--output", "json")
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stdout, wait, err := stream(watchCtx, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to start watch: %v", err)
+	}
+	decoder := json.NewDecoder(stdout)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if !errors.Is(err, io.EOF) && watchCtx.Err() == nil {
+				cancel()
+				_ = wait()
+				return "", fmt.Errorf("failed to read watch events: %v", err)
+			}
+			break
+		}
+		if event.Type == "ERROR" {
+			// An expired resource version or similar; restart from a fresh list
+			break
+		}
+		if err := w.update(ctx, event.Type, event.Object); err != nil {
+			cancel()
+			_ = wait()
+			return "", err
+		}
+		if reason := w.stopReason(); reason != "" {
+			cancel()
+			_ = wait()
+			return reason, nil
+		}
+	}
+	cancel()
+	if err := wait(); err != nil && ctx.Err() == nil {
+		return "", fmt.Errorf("watch failed: %v", err)
+	}
+	return "", nil
+}
+
+// update records an added, modified or deleted object and notifies the client when its state changed
+func (w *waiter) update(ctx context.Context, eventType string, raw json.RawMessage) error {
+	var obj Object
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", w.opts.kind, err)
+	}
+	name := obj.Metadata.Name
+	w.updates++
+
+	message := ""
+	if eventType == "DELETED" {
+		delete(w.objects, name)
+		message = "deleted"
+	} else {
+		result := Evaluate(w.opts.kind, w.opts.condition, obj)
+		w.objects[name] = &trackedObject{raw: raw, result: result}
+		message = result.Message
+	}
+	if w.messages[name] == message {
+		return nil
+	}
+	w.messages[name] = message
+	// Notification failures must not end the wait; the final state is in the result
+	_ = w.notify(ctx, float64(w.updates), fmt.Sprintf("%s/%s: %s", w.opts.kind, name, message))
+	return nil
+}
+
+// stopReason returns why the wait can stop, or "" to keep waiting. Waiting stops when any object
+// has failed the condition or when at least one object exists and all of them satisfy it.
+func (w *waiter) stopReason() string {
+	if len(w.objects) == 0 {
+		return ""
+	}
+	satisfied := true
+	for _, obj := range w.objects {
+		if obj.result.Failed {
+			return StopFailed
+		}
+		satisfied = satisfied && obj.result.Satisfied
+	}
+	if satisfied {
+		return StopConditionMet
+	}
+	return ""
+}
+
+// report builds the result from the tracked objects
+func (w *waiter) report(stopReason string) WaitReport {
+	report := WaitReport{
+		Kind:       w.opts.kind,
+		Namespace:  w.opts.namespace,
+		Name:       w.opts.name,
+		Selector:   w.opts.selector,
+		Condition:  w.opts.condition,
+		Satisfied:  stopReason == StopConditionMet,
+		StopReason: stopReason,
+		Updates:    w.updates,
+		Objects:    []ObjectState{},
+	}
+
+	satisfied := 0
+	for name, obj := range w.objects {
+		var object map[string]interface{}
+		if err := json.Unmarshal(obj.raw, &object); err == nil {
+			if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+				delete(metadata, "managedFields")
+			}
+		}
+		if obj.result.Satisfied {
+			satisfied++
+		}
+		report.Objects = append(report.Objects, ObjectState{
+			Name:      name,
+			Satisfied: obj.result.Satisfied,
+			Failed:    obj.result.Failed,
+			Message:   obj.result.Message,
+			Object:    object,
+		})
+	}
+	sort.Slice(report.Objects, func(i, j int) bool { return report.Objects[i].Name < report.Objects[j].Name })
+
+	switch {
+	case len(report.Objects) == 0 && w.opts.name != "":
+		report.Summary = fmt.Sprintf("%s %s does not exist in namespace %s", w.opts.kind, w.opts.name, w.opts.namespace)
+	case len(report.Objects) == 0:
+		report.Summary = fmt.Sprintf("no %s in namespace %s matches %s", w.opts.kind, w.opts.namespace, w.opts.selector)
+	case len(report.Objects) == 1:
+		report.Summary = report.Objects[0].Message
+	default:
+		report.Summary = fmt.Sprintf("%d of %d %ss satisfy %s", satisfied, len(report.Objects), w.opts.kind, w.opts.condition)
+	}
+	return report
+}
+
+// parseWaitOptions validates and parses the tool parameters
+func parseWaitOptions(params map[string]interface{}, cfg *config.ConfigData) (waitOptions, error) {
+	opts := waitOptions{namespace: "default", timeout: time.Duration(cfg.Timeout) * time.Second}
+
+	kind, _ := params["kind"].(string)
+	opts.kind = strings.TrimSuffix(strings.ToLower(kind), "s")
+	conditions, ok := kindConditions[opts.kind]
+	if !ok {
+		return opts, fmt.Errorf("invalid kind parameter '%s': must be deployment, statefulset, daemonset, pod or job", kind)
+	}
+	opts.condition = conditions[0]
+	if condition, _ := params["condition"].(string); condition != "" {
+		supported := false
+		for _, c := range conditions {
+			supported = supported || c == condition
+		}
+		if !supported {
+			return opts, fmt.Errorf("invalid condition parameter '%s' for kind %s: must be %s", condition, opts.kind, strings.Join(conditions, " or "))
+		}
+		opts.condition = condition
+	}
+
+	if namespace, _ := params["namespace"].(string); namespace != "" {
+		opts.namespace = namespace
+	}
+	opts.name, _ = params["name"].(string)
+	opts.selector, _ = params["selector"].(string)
+	if !common.NamespacePattern.MatchString(opts.namespace) {
+		return opts, fmt.Errorf("invalid namespace parameter: %s", opts.namespace)
+	}
+	if !k8s.ConvertConfig(cfg).SecurityConfig.IsNamespaceAllowed(opts.namespace) {
+		return opts, fmt.Errorf("access to namespace '%s' is denied by security configuration", opts.namespace)
+	}
+	switch {
+	case (opts.name == "") == (opts.selector == ""):
+		return opts, fmt.Errorf("exactly one of name or selector is required")
+	case opts.name != "" && !common.NamePattern.MatchString(opts.name):
+		return opts, fmt.Errorf("invalid name parameter: %s", opts.name)
+	case opts.selector != "" && !common.LabelSelectorPattern.MatchString(opts.selector):
+		return opts, fmt.Errorf("invalid selector parameter '%s': only equality-based selectors such as app=web,tier!=cache are supported", opts.selector)
+	}
+
+	if opts.timeout <= 0 {
+		return opts, fmt.Errorf("invalid timeout: %d seconds", cfg.Timeout)
+	}
+	return opts, nil
+}
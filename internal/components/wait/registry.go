@@ -0,0 +1,44 @@
+package wait
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterWaitForConditionTool registers the aks_wait_for_condition tool
+func RegisterWaitForConditionTool() mcp.Tool {
+	description := `Wait server-side until Kubernetes workloads reach a condition, instead of polling with repeated kubectl calls.
+
+Conditions (the default follows from the kind):
+- rollout_complete (deployment, statefulset, daemonset): the checks of kubectl rollout status; a deployment that
+  exceeds its progress deadline fails the wait
+- ready (pod): the pod's Ready condition is True; a pod that terminates fails the wait
+- complete (job): the job's Complete condition is True; a job with the Failed condition fails the wait
+
+Lists the objects selected by name or label selector and watches them until all of them meet the condition, one
+of them fails it, or the call's timeout (timeout_seconds) elapses. A named object that does not exist yet is
+waited for. Every state change is sent as a progress notification (send a progressToken with the call to receive
+them). Returns the outcome with the final state of each object. Uses the current kubeconfig context.`
+
+	return mcp.NewTool(
+		"aks_wait_for_condition",
+		mcp.WithDescription(description),
+		mcp.WithString("kind",
+			mcp.Required(),
+			mcp.Description("Kind of the objects to wait for"),
+			mcp.Enum("deployment", "statefulset", "daemonset", "pod", "job"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of the object to wait for (either name or selector is required)"),
+		),
+		mcp.WithString("selector",
+			mcp.Description("Equality-based label selector of the objects to wait for, e.g. app=web (either name or selector is required)"),
+		),
+		mcp.WithString("namespace",
+			mcp.Description("Namespace of the objects (default: default)"),
+		),
+		mcp.WithString("condition",
+			mcp.Description("Condition to wait for (default: rollout_complete for deployments, statefulsets and daemonsets, ready for pods, complete for jobs)"),
+			mcp.Enum(ConditionRolloutComplete, ConditionReady, ConditionComplete),
+		),
+	)
+}
@@ -0,0 +1,223 @@
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/config"
+)
+
+// fakeLister returns a canned kubectl get list and records the command
+type fakeLister struct {
+	output   string
+	commands []string
+}
+
+func (f *fakeLister) Execute(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+	command, _ := params["command"].(string)
+	f.commands = append(f.commands, command)
+	return f.output, nil
+}
+
+// fakeStreamer replays canned watch output and holds the stream open until ctx is done
+type fakeStreamer struct {
+	output string
+	args   []string
+	calls  int
+}
+
+func (f *fakeStreamer) stream(ctx context.Context, args []string) (io.Reader, func() error, error) {
+	f.args = args
+	f.calls++
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte(f.output))
+		<-ctx.Done()
+		_ = writer.Close()
+	}()
+	return reader, func() error { return nil }, nil
+}
+
+// recorder collects progress notifications
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) notify(_ context.Context, _ float64, message string) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func deployment(replicas, updated, total, available int) string {
+	obj := map[string]interface{}{
+		"kind":     "Deployment",
+		"metadata": map[string]interface{}{"name": "web", "generation": 2, "managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}}},
+		"spec":     map[string]interface{}{"replicas": replicas},
+		"status": map[string]interface{}{"observedGeneration": 2, "replicas": total, "updatedReplicas": updated, "availableReplicas": available,
+			"conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "True", "reason": "ReplicaSetUpdated"}}},
+	}
+	data, _ := json.Marshal(obj)
+	return string(data)
+}
+
+func runWait(t *testing.T, params map[string]interface{}, lister *fakeLister, streamer *fakeStreamer, cfg *config.ConfigData) (WaitReport, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	result, err := HandleWaitForCondition(context.Background(), params, lister, streamer.stream, rec.notify, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report WaitReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	return report, rec
+}
+
+func TestRegisterWaitForConditionTool(t *testing.T) {
+	tool := RegisterWaitForConditionTool()
+	if tool.Name != "aks_wait_for_condition" {
+		t.Errorf("Expected tool name 'aks_wait_for_condition', got '%s'", tool.Name)
+	}
+	if len(tool.InputSchema.Required) != 1 || tool.InputSchema.Required[0] != "kind" {
+		t.Errorf("Expected kind to be the only required parameter, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestWaitForDeploymentRollout(t *testing.T) {
+	lister := &fakeLister{output: `{"items": [` + deployment(3, 1, 4, 3) + `]}`}
+	streamer := &fakeStreamer{output: `{"type": "ADDED", "object": ` + deployment(3, 1, 4, 3) + `}
+{"type": "MODIFIED", "object": ` + deployment(3, 3, 3, 2) + `}
+{"type": "MODIFIED", "object": ` + deployment(3, 3, 3, 3) + `}
+`}
+	report, rec := runWait(t, map[string]interface{}{"kind": "Deployments", "name": "web", "namespace": "apps"}, lister, streamer, config.NewConfig())
+
+	if !report.Satisfied || report.StopReason != StopConditionMet || report.Condition != ConditionRolloutComplete {
+		t.Errorf("Expected the rollout to complete, got %+v", report)
+	}
+	if lister.commands[0] != "get deployment --namespace apps --field-selector metadata.name=web --output json" {
+		t.Errorf("Unexpected list command %q", lister.commands[0])
+	}
+	if args := strings.Join(streamer.args, " "); !strings.Contains(args, "--field-selector metadata.name=web --watch --output-watch-events") {
+		t.Errorf("Unexpected watch arguments %q", args)
+	}
+	want := []string{
+		"deployment/web: 1 of 3 new replicas have been updated",
+		"deployment/web: 2 of 3 updated replicas are available",
+		"deployment/web: deployment successfully rolled out",
+	}
+	if strings.Join(rec.messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected progress messages %v, got %v", want, rec.messages)
+	}
+	if len(report.Objects) != 1 || report.Summary != "deployment successfully rolled out" {
+		t.Fatalf("Unexpected objects %+v", report.Objects)
+	}
+	metadata, _ := report.Objects[0].Object["metadata"].(map[string]interface{})
+	if _, ok := metadata["managedFields"]; ok || metadata["name"] != "web" {
+		t.Errorf("Expected the final object without managedFields, got %v", metadata)
+	}
+}
+
+func TestWaitAlreadySatisfied(t *testing.T) {
+	lister := &fakeLister{output: `{"items": [{"metadata": {"name": "migrate"}, "status": {"conditions": [{"type": "Complete", "status": "True"}]}}]}`}
+	streamer := &fakeStreamer{}
+	report, _ := runWait(t, map[string]interface{}{"kind": "job", "name": "migrate"}, lister, streamer, config.NewConfig())
+
+	if !report.Satisfied || report.Namespace != "default" || streamer.calls != 0 {
+		t.Errorf("Expected the wait to end without a watch, got %+v after %d watches", report, streamer.calls)
+	}
+}
+
+func TestWaitStopsWhenJobFails(t *testing.T) {
+	lister := &fakeLister{output: `{"items": []}`}
+	streamer := &fakeStreamer{output: `{"type": "ADDED", "object": {"metadata": {"name": "migrate"}, "status": {"active": 1}}}
+{"type": "MODIFIED", "object": {"metadata": {"name": "migrate"}, "status": {"conditions": [{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "message": "Job has reached the specified backoff limit"}]}}}
+`}
+	report, _ := runWait(t, map[string]interface{}{"kind": "job", "name": "migrate"}, lister, streamer, config.NewConfig())
+
+	if report.Satisfied || report.StopReason != StopFailed {
+		t.Fatalf("Expected the wait to fail, got %+v", report)
+	}
+	if report.Summary != "job failed (BackoffLimitExceeded): Job has reached the specified backoff limit" {
+		t.Errorf("Unexpected summary %q", report.Summary)
+	}
+}
+
+func TestWaitTimesOut(t *testing.T) {
+	pod := func(name, ready string) string {
+		return `{"metadata": {"name": "` + name + `"}, "status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "` + ready + `"}]}}`
+	}
+	lister := &fakeLister{output: `{"items": [` + pod("web-1", "True") + `,` + pod("web-2", "False") + `]}`}
+	// The watch replays web-1 Ready before web-2, which must not end the wait
+	streamer := &fakeStreamer{output: `{"type": "ADDED", "object": ` + pod("web-1", "True") + `}
+{"type": "ADDED", "object": ` + pod("web-2", "False") + `}
+`}
+	cfg := config.NewConfig()
+	cfg.Timeout = 1
+	report, _ := runWait(t, map[string]interface{}{"kind": "pod", "selector": "app=web"}, lister, streamer, cfg)
+
+	if report.Satisfied || report.StopReason != StopTimedOut {
+		t.Fatalf("Expected the wait to time out, got %+v", report)
+	}
+	if report.Summary != "1 of 2 pods satisfy ready" || report.Objects[1].Message != "condition Ready is False" {
+		t.Errorf("Unexpected summary %q and objects %+v", report.Summary, report.Objects)
+	}
+}
+
+func TestWaitInvalidParameters(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AllowNamespaces = "apps"
+	tests := []struct {
+		params map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"kind": "service", "name": "web"}, "invalid kind"},
+		{map[string]interface{}{"kind": "pod", "name": "web", "selector": "app=web", "namespace": "apps"}, "exactly one of name or selector"},
+		{map[string]interface{}{"kind": "job", "name": "web", "condition": "ready", "namespace": "apps"}, "invalid condition parameter 'ready' for kind job"},
+		{map[string]interface{}{"kind": "pod", "selector": "app in (web)", "namespace": "apps"}, "invalid selector"},
+		{map[string]interface{}{"kind": "pod", "name": "web"}, "access to namespace 'default' is denied"},
+	}
+	for _, tt := range tests {
+		_, err := HandleWaitForCondition(context.Background(), tt.params, &fakeLister{}, (&fakeStreamer{}).stream, (&recorder{}).notify, cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %v, got %v", tt.want, tt.params, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	parse := func(data string) Object {
+		var obj Object
+		if err := json.Unmarshal([]byte(data), &obj); err != nil {
+			t.Fatalf("Failed to parse %s: %v", data, err)
+		}
+		return obj
+	}
+	tests := []struct {
+		kind, condition, object string
+		want                    Result
+	}{
+		{"deployment", ConditionRolloutComplete, `{"metadata": {"generation": 3}, "status": {"observedGeneration": 2}}`,
+			Result{Message: "waiting for the deployment spec update to be observed"}},
+		{"deployment", ConditionRolloutComplete, `{"status": {"conditions": [{"type": "Progressing", "reason": "ProgressDeadlineExceeded", "message": "ReplicaSet \"web-1\" has timed out progressing."}]}}`,
+			Result{Failed: true, Message: "deployment exceeded its progress deadline: ReplicaSet \"web-1\" has timed out progressing."}},
+		{"statefulset", ConditionRolloutComplete, `{"spec": {"replicas": 3, "updateStrategy": {"rollingUpdate": {"partition": 2}}}, "status": {"readyReplicas": 3, "updatedReplicas": 1}}`,
+			Result{Satisfied: true, Message: "partitioned rollout complete: 1 new pods updated"}},
+		{"statefulset", ConditionRolloutComplete, `{"spec": {"replicas": 2}, "status": {"readyReplicas": 2, "updatedReplicas": 1, "currentRevision": "db-1", "updateRevision": "db-2"}}`,
+			Result{Message: "1 pods at revision db-2, waiting for the rolling update to complete"}},
+		{"daemonset", ConditionRolloutComplete, `{"status": {"desiredNumberScheduled": 3, "updatedNumberScheduled": 3, "numberAvailable": 2}}`,
+			Result{Message: "2 of 3 updated pods are available"}},
+		{"pod", ConditionReady, `{"status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/3 nodes are available"}]}}`,
+			Result{Message: "condition PodScheduled is False (Unschedulable): 0/3 nodes are available"}},
+		{"pod", ConditionReady, `{"status": {"phase": "Succeeded"}}`,
+			Result{Failed: true, Message: "pod has terminated with phase Succeeded and will not become Ready"}},
+	}
+	for _, tt := range tests {
+		if got := Evaluate(tt.kind, tt.condition, parse(tt.object)); got != tt.want {
+			t.Errorf("Evaluate(%s, %s) = %+v, want %+v", tt.kind, tt.object, got, tt.want)
+		}
+	}
+}
@@ -5,21 +5,111 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/artifacts"
+	"github.com/Azure/aks-mcp/internal/cloudenv"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/export"
+	"github.com/Azure/aks-mcp/internal/logprofile"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/scanner"
 	"github.com/Azure/aks-mcp/internal/security"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/Azure/aks-mcp/internal/store"
 	"github.com/Azure/aks-mcp/internal/telemetry"
 	"github.com/Azure/aks-mcp/internal/version"
 	flag "github.com/spf13/pflag"
 )
 
+// Tool components that can be selected with --components
+const (
+	ComponentAzAks           = "azaks"
+	ComponentMonitor         = "monitor"
+	ComponentFleet           = "fleet"
+	ComponentNetwork         = "network"
+	ComponentCompute         = "compute"
+	ComponentDetectors       = "detectors"
+	ComponentAdvisor         = "advisor"
+	ComponentIdentity        = "identity"
+	ComponentCertificates    = "certificates"
+	ComponentVulnerabilities = "vulnerabilities"
+	ComponentInspektorGadget = "inspektorgadget"
+	ComponentChaos           = "chaos"
+	ComponentFailover        = "failover"
+	ComponentGPU             = "gpu"
+	ComponentStorage         = "storage"
+	ComponentKubernetes      = "k8s"
+)
+
+// AllComponents lists every tool component in registration order
+var AllComponents = []string{
+	ComponentAzAks,
+	ComponentMonitor,
+	ComponentFleet,
+	ComponentNetwork,
+	ComponentCompute,
+	ComponentDetectors,
+	ComponentAdvisor,
+	ComponentIdentity,
+	ComponentCertificates,
+	ComponentVulnerabilities,
+	ComponentInspektorGadget,
+	ComponentChaos,
+	ComponentFailover,
+	ComponentGPU,
+	ComponentStorage,
+	ComponentKubernetes,
+}
+
+// DefaultMaxTimeout is the longest timeout in seconds a tool call may request unless --max-timeout is set
+const DefaultMaxTimeout = 3600
+
+// DefaultArtifactThreshold is the size in bytes above which a tool output is kept as an artifact
+// resource unless --artifact-threshold is set
+const DefaultArtifactThreshold = 64 * 1024
+
+// Result verbosity profiles selected with --verbosity or a call's verbosity argument
+const (
+	// VerbosityRaw returns tool output as collected, without previews or summaries
+	VerbosityRaw = "raw"
+	// VerbosityStandard returns the default output of each tool
+	VerbosityStandard = "standard"
+	// VerbositySummary reduces tool output to a short summary
+	VerbositySummary = "summary"
+)
+
+// Verbosities lists the result verbosity profiles
+var Verbosities = []string{VerbosityRaw, VerbosityStandard, VerbositySummary}
+
+// DefaultExecAllowedCommands lists the read-only binaries aks_pod_exec may run unless --exec-allowed-commands
+// is set. Shells and launchers such as env are deliberately absent so a command cannot chain further programs,
+// interactive programs such as top are absent because exec runs without a TTY, and ip, mount, curl, wget and
+// printenv are absent because they can change state, write files, send data out or print secrets.
+var DefaultExecAllowedCommands = []string{
+	"cat", "date", "df", "dig", "du", "free", "head", "hostname", "id",
+	"ls", "netstat", "nslookup", "ping", "ps", "ss", "tail",
+}
+
+// DefaultRestAllowedPaths lists the provider path patterns az_rest may call unless --rest-allowed-paths is set
+var DefaultRestAllowedPaths = []string{"Microsoft.ContainerService/*"}
+
 // ConfigData holds the global configuration
 type ConfigData struct {
 	// Command execution timeout in seconds
 	Timeout int
+	// Timeouts in seconds of tool classes, keyed by tool name, tool:operation or a name prefix ending in *
+	ToolTimeouts map[string]int
+	// Longest timeout in seconds a call may request with timeout_seconds
+	MaxTimeout int
 	// Cache timeout for Azure resources
 	CacheTimeout time.Duration
+	// How long az read command output is reused (0 disables the az output cache)
+	AzCacheTTL time.Duration
 	// Security configuration
 	SecurityConfig *security.SecurityConfig
 
@@ -28,6 +118,8 @@ type ConfigData struct {
 	Host        string
 	Port        int
 	AccessLevel string
+	// Return readwrite and admin operations as az CLI scripts, Bicep patches and kubectl manifests instead of executing them
+	ReviewMode bool
 
 	// Kubernetes-specific options
 	// Map of additional tools enabled (helm, cilium)
@@ -38,17 +130,117 @@ type ConfigData struct {
 	// Verbose logging
 	Verbose bool
 
+	// Tool components to register (nil means all components)
+	EnabledComponents map[string]bool
+
+	// Directory of Markdown prompt templates registered as additional prompts (empty means none)
+	PromptsDir string
+	// Directory of YAML node scripts offered by list_node_scripts and run_node_script (empty means none)
+	NodeScriptsDir string
+
 	// OTLP endpoint for OpenTelemetry traces
 	OTLPEndpoint string
 
+	// Turn off all telemetry, including OTLP export (--disable-telemetry)
+	DisableTelemetry bool
+
 	// Telemetry service
 	TelemetryService *telemetry.Service
+
+	// Don't check GitHub for a newer aks-mcp release on startup (--disable-update-check or AKS_MCP_DISABLE_UPDATE_CHECK).
+	// The check is also skipped with DisableTelemetry and in notelemetry builds.
+	DisableUpdateCheck bool
+
+	// Azure cloud environment (public, US Government, China or discovered from ARM metadata)
+	Cloud *cloudenv.Environment
+
+	// Additional federated token file paths allowed for workload identity login
+	// (the AKS projected token path is always allowed)
+	FederatedTokenPaths []string
+
+	// Lease-based leader election so background subsystems run on one replica (HTTP transports only)
+	LeaderElection bool
+	// Namespace of the leader election Lease (defaults to POD_NAMESPACE or "default")
+	LeaderElectionNamespace string
+	// Name of the leader election Lease
+	LeaderElectionLeaseName string
+
+	// Push high-severity cluster findings to SSE clients as MCP notifications
+	PushFindings bool
+	// How often the background scanner checks clusters for findings
+	ScanInterval time.Duration
+	// Serve the scanned cluster inventory and open findings as MCP resources clients can subscribe to
+	InventoryResources bool
+	// Days ahead the background scanner reports expiring TLS secrets and service principal secrets (0 disables)
+	SecretExpiryDays int
+
+	// Binaries aks_pod_exec may run inside containers
+	ExecAllowedCommands []string
+	// Provider path patterns az_rest may call, such as Microsoft.ContainerService/*
+	RestAllowedPaths []string
+
+	// Resource IDs of the clusters whose detector catalogs are fetched at startup and kept fresh
+	PrewarmDetectors []string
+
+	// Persistence of server state across restarts (bolt or memory)
+	StateStore string
+	// Path of the bolt state database (empty means the user cache directory)
+	StatePath string
+	// File holding the key audit records are signed with (empty means AKS_MCP_AUDIT_SIGNING_KEY or unsigned)
+	AuditSigningKeyFile string
+	// Days audit records are kept in the state store before they are pruned (0 keeps every record)
+	AuditRetentionDays int
+	// URL of the sink audit records and scanner findings are streamed to (empty disables export)
+	ExportSink string
+	// Maximum events queued for the export sink before publishers wait and events are dropped
+	ExportQueueSize int
+
+	// Size in bytes above which a tool output is returned as a preview and an artifact resource (0 disables)
+	ArtifactThreshold int
+	// How long artifact resources can be read after they are created
+	ArtifactTTL time.Duration
+	// Result verbosity profile (raw, standard or summary), overridden per call by the verbosity argument
+	Verbosity string
+	// Ask the client to write summaries through MCP sampling when the summary profile is used
+	SamplingSummaries bool
+	// Ephemeral resources holding large tool outputs (set by the server)
+	Artifacts *artifacts.Store
+	// File of named control plane log projection profiles (empty means the builtin profiles only)
+	LogProfilesFile string
+	// Control plane log projection profiles selected by the profile parameter (set by the server)
+	LogProfiles *logprofile.Set
+
+	// Require each HTTP session to supply its own Azure credentials
+	SessionCredentials bool
+	// File of API keys HTTP clients must send, each with its own access level and components (empty means none)
+	APIKeysFile string
+	// Run without the Azure CLI: AKS reads use the Azure SDK and az-backed tools are not registered
+	NoAzCli bool
+	// File tool calls, their results and the az CLI commands they run are appended to (empty means none)
+	Record string
+	// Recording whose tool calls are re-executed instead of serving (empty means serve)
+	Replay string
+	// Answer az CLI commands from the recording during a replay instead of running them
+	ReplayMock bool
+	// Check the registered tools with mock executors and exit instead of serving
+	SelfTest bool
+	// Resolve Entra ID object IDs in guard logs and identity checks to names through Microsoft Graph
+	GraphLookup bool
+	// Credentials of the session serving the current tool call (set per call in session credential mode)
+	Session *session.Credential
+	// API key the current tool call was made with (set per call when API keys are configured)
+	APIKey *apikey.Key
+	// Records the commands and API calls of the current tool call (set per call when explain is requested)
+	Explain *explain.Trace
+	// Collects the write operations of the current tool call instead of executing them (set per call in review mode)
+	Review *review.Plan
 }
 
 // NewConfig creates and returns a new configuration instance
 func NewConfig() *ConfigData {
 	return &ConfigData{
 		Timeout:         60,
+		MaxTimeout:      DefaultMaxTimeout,
 		CacheTimeout:    1 * time.Minute,
 		SecurityConfig:  security.NewSecurityConfig(),
 		Transport:       "stdio",
@@ -56,6 +248,14 @@ func NewConfig() *ConfigData {
 		AccessLevel:     "readonly",
 		AdditionalTools: make(map[string]bool),
 		AllowNamespaces: "",
+		Cloud:           cloudenv.Public(),
+		StateStore:      store.KindMemory,
+
+		ArtifactThreshold:   DefaultArtifactThreshold,
+		ArtifactTTL:         artifacts.DefaultTTL,
+		Verbosity:           VerbosityStandard,
+		ExecAllowedCommands: DefaultExecAllowedCommands,
+		RestAllowedPaths:    DefaultRestAllowedPaths,
 	}
 }
 
@@ -66,20 +266,125 @@ func (cfg *ConfigData) ParseFlags() {
 	flag.StringVar(&cfg.Host, "host", "127.0.0.1", "Host to listen for the server (only used with transport sse or streamable-http)")
 	flag.IntVar(&cfg.Port, "port", 8000, "Port to listen for the server (only used with transport sse or streamable-http)")
 	flag.IntVar(&cfg.Timeout, "timeout", 600, "Timeout for command execution in seconds, default is 600s")
+	toolTimeouts := flag.String("tool-timeouts", "",
+		"Comma-separated tool=seconds timeouts overriding --timeout for a tool, one operation of a tool such as az_aks_operations:upgrade, or a tool class such as kubectl_* (e.g. kubectl_*=15,az_aks_operations=60,az_aks_operations:upgrade=2400)")
+	flag.IntVar(&cfg.MaxTimeout, "max-timeout", DefaultMaxTimeout, "Longest timeout in seconds a tool call may request with timeout_seconds")
+	flag.DurationVar(&cfg.AzCacheTTL, "az-cache-ttl", 0,
+		"How long the output of az read commands is reused before they run again, e.g. 30s; writes to a resource drop its cached reads (0 disables the cache)")
 	// Security settings
 	flag.StringVar(&cfg.AccessLevel, "access-level", "readonly", "Access level (readonly, readwrite, admin)")
 
+	flag.BoolVar(&cfg.ReviewMode, "review-mode", false,
+		"Return readwrite and admin operations as az CLI scripts, Bicep patches or kubectl manifests and diffs to apply through a pipeline instead of executing them")
+
+	flag.BoolVar(&cfg.SessionCredentials, "session-credentials", false,
+		"Require each HTTP session to supply its own Azure access token instead of using process credentials (only used with transport sse or streamable-http)")
+
+	flag.StringVar(&cfg.APIKeysFile, "api-keys-file", "",
+		"JSON file of API keys clients must send in the X-API-Key header, each with its own access level (at most --access-level) and components (only used with transport sse or streamable-http)")
+
+	flag.BoolVar(&cfg.NoAzCli, "no-azcli", false,
+		"Run without the Azure CLI: AKS cluster and node pool reads use the Azure SDK and tools that need az are disabled")
+
+	flag.BoolVar(&cfg.GraphLookup, "graph-lookup", false,
+		"Resolve Entra ID object IDs in guard logs and identity checks to user, group and service principal names through Microsoft Graph (the credential needs directory read permissions; not used with --session-credentials)")
+
+	flag.BoolVar(&cfg.LeaderElection, "leader-election", false,
+		"Enable Lease-based leader election so background subsystems run once across replicas (only used with transport sse or streamable-http)")
+	flag.StringVar(&cfg.LeaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election Lease (defaults to POD_NAMESPACE or \"default\")")
+	flag.StringVar(&cfg.LeaderElectionLeaseName, "leader-election-lease-name", "aks-mcp-leader",
+		"Name of the leader election Lease")
+
+	flag.BoolVar(&cfg.PushFindings, "push-findings", false,
+		"Scan clusters in the background and push failed or unavailable clusters, failed node pools and expiring credentials to connected clients as notifications (only used with transport sse)")
+	flag.DurationVar(&cfg.ScanInterval, "scan-interval", scanner.DefaultInterval,
+		"How often the background scanner checks clusters when --push-findings or --inventory-resources is set")
+	flag.BoolVar(&cfg.InventoryResources, "inventory-resources", false,
+		"Scan clusters in the background and serve the cluster inventory and open findings as aks-mcp://inventory/ resources, notifying subscribed clients when they change (not used with --session-credentials)")
+	flag.IntVar(&cfg.SecretExpiryDays, "secret-expiry-days", 30,
+		"Report TLS secrets, cluster service principal secrets and workload identity app credentials expiring within this many days when --push-findings is set (0 disables)")
+
+	flag.StringVar(&cfg.StateStore, "state-store", store.KindBolt,
+		"Where server state such as async operations and findings is kept (bolt or memory)")
+	flag.StringVar(&cfg.StatePath, "state-path", "",
+		"Path of the bolt state database (defaults to aks-mcp/state.db in the user cache directory)")
+
+	flag.StringVar(&cfg.AuditSigningKeyFile, "audit-signing-key-file", "",
+		"File holding the key audit records are signed with, for example a mounted Kubernetes or Key Vault secret (defaults to the AKS_MCP_AUDIT_SIGNING_KEY value)")
+	flag.IntVar(&cfg.AuditRetentionDays, "audit-retention-days", 0,
+		"Days audit records are kept in the state store; older records are pruned hourly and the hash chain continues from a checkpoint of the last pruned record (0 keeps every record)")
+	flag.StringVar(&cfg.ExportSink, "export-sink", "",
+		"Stream audit records and scanner findings as JSON events to eventhubs://<namespace>.servicebus.windows.net/<event hub> or a Kafka REST proxy at kafka+https://<proxy>/<topic>")
+	flag.IntVar(&cfg.ExportQueueSize, "export-queue-size", export.DefaultQueueSize,
+		"Maximum events queued in the state store for --export-sink while it is unreachable or slow")
+
+	cloudName := flag.String("cloud", "",
+		"Azure cloud to use (AzureCloud, AzureUSGovernment, AzureChinaCloud) or an ARM endpoint URL to discover endpoints from (defaults to AZURE_CLOUD or AzureCloud)")
+
+	federatedTokenPaths := flag.String("federated-token-paths", "",
+		"Comma-separated list of additional federated token file paths allowed for workload identity login (the AKS token path is always allowed)")
+
 	// Kubernetes-specific settings
 	additionalTools := flag.String("additional-tools", "",
 		"Comma-separated list of additional Kubernetes tools to support (kubectl is always enabled). Available: helm,cilium")
 	flag.StringVar(&cfg.AllowNamespaces, "allow-namespaces", "",
 		"Comma-separated list of allowed Kubernetes namespaces (empty means all namespaces)")
+	execAllowedCommands := flag.String("exec-allowed-commands", strings.Join(DefaultExecAllowedCommands, ","),
+		"Comma-separated list of binaries aks_pod_exec may run inside containers (admin access only)")
+	restAllowedPaths := flag.String("rest-allowed-paths", strings.Join(DefaultRestAllowedPaths, ","),
+		"Comma-separated list of ARM provider path patterns az_rest may call, where * matches any characters (empty disables az_rest)")
+
+	// Detector catalogs
+	prewarmDetectors := flag.String("prewarm-detectors", "",
+		"Comma-separated list of AKS cluster resource IDs whose detector catalogs are fetched at startup and refreshed in the background (not used with --session-credentials)")
+
+	// Control plane log profiles
+	flag.StringVar(&cfg.LogProfilesFile, "log-profiles-file", "",
+		"JSON file of named control plane log projection profiles (columns to project, klog parsing and regex field extracts) selected with the profile parameter of control_plane_logs")
+
+	// Component selection
+	components := flag.String("components", "",
+		"Comma-separated list of tool components to enable (defaults to AKS_MCP_COMPONENTS or all). Available: "+strings.Join(AllComponents, ","))
+
+	// Prompt templates
+	flag.StringVar(&cfg.PromptsDir, "prompts-dir", "",
+		"Directory of Markdown prompt templates with name, description and arguments frontmatter to register as prompts (defaults to AKS_MCP_PROMPTS_DIR)")
+
+	// Node script library
+	flag.StringVar(&cfg.NodeScriptsDir, "node-scripts-dir", "",
+		"Directory of parameterized YAML run-command scripts offered by list_node_scripts and run_node_script, such as a mounted ConfigMap (defaults to AKS_MCP_NODE_SCRIPTS_DIR)")
+
+	// Large output settings
+	flag.IntVar(&cfg.ArtifactThreshold, "artifact-threshold", DefaultArtifactThreshold,
+		"Size in bytes above which a tool output is returned as a preview with an aks-mcp://artifacts/ resource link (0 disables)")
+	flag.DurationVar(&cfg.ArtifactTTL, "artifact-ttl", artifacts.DefaultTTL, "How long artifact resources can be read after they are created")
+	flag.StringVar(&cfg.Verbosity, "verbosity", VerbosityStandard,
+		"Default result verbosity of tool calls (raw, standard or summary); a call can override it with its verbosity argument")
+	flag.BoolVar(&cfg.SamplingSummaries, "sampling-summaries", false,
+		"Ask clients that support MCP sampling to write the summaries of summary verbosity calls (falls back to a built-in summary)")
+
+	// Session recording and replay (developer mode)
+	flag.StringVar(&cfg.Record, "record", "",
+		"Append every tool call with its arguments and result, and the az CLI commands it runs with their output, to this file as JSON lines (contains cluster data; for debugging the server with --replay)")
+	flag.StringVar(&cfg.Replay, "replay", "",
+		"Re-execute the tool calls of a recording made with --record instead of serving, print how each result differs from the recorded one and exit")
+	flag.BoolVar(&cfg.ReplayMock, "replay-mock", false,
+		"With --replay, answer az CLI commands from the recording instead of running them (Azure SDK calls and kubectl still run)")
+	flag.BoolVar(&cfg.SelfTest, "self-test", false,
+		"Register the tools at every access level with mock executors, check their descriptions, parameters, access gating and input validation, print a report and exit")
 
 	// Logging settings
 	flag.BoolVarP(&cfg.Verbose, "verbose", "v", false, "Enable verbose logging")
 
 	// OTLP settings
 	flag.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", "OTLP endpoint for OpenTelemetry traces (e.g. localhost:4317)")
+	flag.BoolVar(&cfg.DisableTelemetry, "disable-telemetry", false,
+		"Turn off all telemetry: no Application Insights events, no OTLP export and no device ID (overrides AKS_MCP_COLLECT_TELEMETRY)")
+
+	// Update check settings
+	flag.BoolVar(&cfg.DisableUpdateCheck, "disable-update-check", false,
+		"Don't check GitHub for a newer aks-mcp release on startup, for example in air-gapped environments (defaults to AKS_MCP_DISABLE_UPDATE_CHECK; implied by --disable-telemetry)")
 
 	// Custom help handling
 	var showHelp bool
@@ -120,10 +425,348 @@ func (cfg *ConfigData) ParseFlags() {
 			cfg.AdditionalTools[strings.TrimSpace(tool)] = true
 		}
 	}
+
+	cfg.ExecAllowedCommands = nil
+	for _, command := range strings.Split(*execAllowedCommands, ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			cfg.ExecAllowedCommands = append(cfg.ExecAllowedCommands, command)
+		}
+	}
+
+	cfg.RestAllowedPaths = nil
+	for _, pattern := range strings.Split(*restAllowedPaths, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			cfg.RestAllowedPaths = append(cfg.RestAllowedPaths, pattern)
+		}
+	}
+
+	for _, clusterID := range strings.Split(*prewarmDetectors, ",") {
+		if clusterID = strings.TrimSpace(clusterID); clusterID != "" {
+			cfg.PrewarmDetectors = append(cfg.PrewarmDetectors, clusterID)
+		}
+	}
+
+	// Default the leader election namespace to the pod namespace
+	if cfg.LeaderElectionNamespace == "" {
+		cfg.LeaderElectionNamespace = os.Getenv("POD_NAMESPACE")
+	}
+	if cfg.LeaderElectionNamespace == "" {
+		cfg.LeaderElectionNamespace = "default"
+	}
+
+	cfg.ToolTimeouts, err = ParseToolTimeouts(*toolTimeouts)
+	if err != nil {
+		fmt.Printf("Invalid tool timeouts: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !slices.Contains(Verbosities, cfg.Verbosity) {
+		fmt.Printf("Invalid verbosity '%s': expected %s\n", cfg.Verbosity, strings.Join(Verbosities, ", "))
+		os.Exit(1)
+	}
+
+	if cfg.StateStore != store.KindBolt && cfg.StateStore != store.KindMemory {
+		fmt.Printf("Invalid state store '%s': expected %s or %s\n", cfg.StateStore, store.KindBolt, store.KindMemory)
+		os.Exit(1)
+	}
+
+	// Parse enabled components
+	if *components == "" {
+		*components = os.Getenv("AKS_MCP_COMPONENTS")
+	}
+	enabledComponents, err := ParseComponents(*components)
+	if err != nil {
+		fmt.Printf("Invalid components configuration: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.EnabledComponents = enabledComponents
+
+	if cfg.PromptsDir == "" {
+		cfg.PromptsDir = os.Getenv("AKS_MCP_PROMPTS_DIR")
+	}
+	if cfg.NodeScriptsDir == "" {
+		cfg.NodeScriptsDir = os.Getenv("AKS_MCP_NODE_SCRIPTS_DIR")
+	}
+
+	if !cfg.DisableUpdateCheck {
+		cfg.DisableUpdateCheck, _ = strconv.ParseBool(os.Getenv("AKS_MCP_DISABLE_UPDATE_CHECK"))
+	}
+
+	// Resolve the cloud environment
+	if *cloudName == "" {
+		*cloudName = os.Getenv("AZURE_CLOUD")
+	}
+	cloudEnv, err := cloudenv.Resolve(*cloudName)
+	if err != nil {
+		fmt.Printf("Invalid cloud configuration: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Cloud = cloudEnv
+
+	// Parse federated token paths
+	if *federatedTokenPaths != "" {
+		for _, path := range strings.Split(*federatedTokenPaths, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				cfg.FederatedTokenPaths = append(cfg.FederatedTokenPaths, path)
+			}
+		}
+	}
+}
+
+// ForSession returns a copy of the configuration bound to the given session credential
+func (cfg *ConfigData) ForSession(cred *session.Credential) *ConfigData {
+	sessionCfg := *cfg
+	sessionCfg.Session = cred
+	return &sessionCfg
+}
+
+// ForAPIKey returns a copy of the configuration for a call made with the given API key, limited to the key's access level
+func (cfg *ConfigData) ForAPIKey(key *apikey.Key) *ConfigData {
+	keyCfg := *cfg
+	keyCfg.APIKey = key
+	keyCfg.AccessLevel = key.AccessLevel
+	if cfg.SecurityConfig != nil {
+		securityConfig := *cfg.SecurityConfig
+		securityConfig.AccessLevel = key.AccessLevel
+		keyCfg.SecurityConfig = &securityConfig
+	}
+	return &keyCfg
+}
+
+// ClientName returns the name of the API key the current call was made with, or "" without one
+func (cfg *ConfigData) ClientName() string {
+	if cfg.APIKey == nil {
+		return ""
+	}
+	return cfg.APIKey.Name
+}
+
+// ForTimeout returns a copy of the configuration whose commands and API calls time out after the given seconds
+func (cfg *ConfigData) ForTimeout(seconds int) *ConfigData {
+	timeoutCfg := *cfg
+	timeoutCfg.Timeout = seconds
+	return &timeoutCfg
+}
+
+// ForExplain returns a copy of the configuration that records the call's steps in the given trace
+func (cfg *ConfigData) ForExplain(trace *explain.Trace) *ConfigData {
+	explainCfg := *cfg
+	explainCfg.Explain = trace
+	return &explainCfg
+}
+
+// ForReview returns a copy of the configuration that defers the call's write operations to the given plan
+func (cfg *ConfigData) ForReview(plan *review.Plan) *ConfigData {
+	reviewCfg := *cfg
+	reviewCfg.Review = plan
+	return &reviewCfg
+}
+
+// ForVerbosity returns a copy of the configuration that shapes the call's result with the given verbosity profile
+func (cfg *ConfigData) ForVerbosity(verbosity string) *ConfigData {
+	verbosityCfg := *cfg
+	verbosityCfg.Verbosity = verbosity
+	return &verbosityCfg
+}
+
+// AttachArtifact keeps content as an artifact resource readable by the current session.
+// It returns false when artifacts are not enabled.
+func (cfg *ConfigData) AttachArtifact(name, mimeType, content string) (artifacts.Artifact, bool) {
+	owner := ""
+	if cfg.Session != nil {
+		owner = cfg.Session.SessionID
+	}
+	return cfg.Artifacts.Put(name, mimeType, content, owner)
+}
+
+// minAuditSigningKeyBytes is the shortest audit signing key accepted
+const minAuditSigningKeyBytes = 32
+
+// AuditSigningKey returns the key audit records are signed with, read from AuditSigningKeyFile
+// or the AKS_MCP_AUDIT_SIGNING_KEY environment variable. It returns nil when neither is set.
+func (cfg *ConfigData) AuditSigningKey() ([]byte, error) {
+	var key string
+	if cfg.AuditSigningKeyFile != "" {
+		data, err := os.ReadFile(cfg.AuditSigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit signing key: %w", err)
+		}
+		key = string(data)
+	} else {
+		key = os.Getenv("AKS_MCP_AUDIT_SIGNING_KEY")
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		if cfg.AuditSigningKeyFile != "" {
+			return nil, fmt.Errorf("audit signing key file %s is empty", cfg.AuditSigningKeyFile)
+		}
+		return nil, nil
+	}
+	if len(key) < minAuditSigningKeyBytes {
+		return nil, fmt.Errorf("audit signing key must be at least %d bytes", minAuditSigningKeyBytes)
+	}
+	return []byte(key), nil
+}
+
+// APIKeys returns the API keys read from APIKeysFile, or nil when it is not set. Keys may not be more
+// privileged than the server's access level and may only name known components.
+func (cfg *ConfigData) APIKeys() (*apikey.Keyring, error) {
+	if cfg.APIKeysFile == "" {
+		return nil, nil
+	}
+	keyring, err := apikey.Load(cfg.APIKeysFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keyring.Keys() {
+		if !apikey.LevelIncludes(cfg.AccessLevel, key.AccessLevel) {
+			return nil, fmt.Errorf("API key '%s' has access level '%s', above the server's '%s'", key.Name, key.AccessLevel, cfg.AccessLevel)
+		}
+		for _, component := range key.Components {
+			if !slices.Contains(AllComponents, component) {
+				return nil, fmt.Errorf("API key '%s' names unknown component '%s': available components are %s", key.Name, component, strings.Join(AllComponents, ", "))
+			}
+		}
+	}
+	return keyring, nil
+}
+
+// LoadLogProfiles returns the control plane log profiles read from LogProfilesFile with the builtin
+// profiles, or nil, meaning the builtin profiles only, when it is not set
+func (cfg *ConfigData) LoadLogProfiles() (*logprofile.Set, error) {
+	if cfg.LogProfilesFile == "" {
+		return nil, nil
+	}
+	return logprofile.Load(cfg.LogProfilesFile)
+}
+
+// ParseToolTimeouts parses a comma-separated list of tool=seconds entries. The tool may end in * to
+// match every tool with that prefix, or be tool:operation to match the calls of one operation of a tool.
+func ParseToolTimeouts(value string) (map[string]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	timeouts := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tool, secondsValue, ok := strings.Cut(entry, "=")
+		tool = strings.TrimSpace(tool)
+		if !ok || tool == "" {
+			return nil, fmt.Errorf("invalid entry '%s': expected tool=seconds", entry)
+		}
+		if name, operation, hasOperation := strings.Cut(tool, ":"); hasOperation && (name == "" || operation == "" || strings.Contains(tool, "*")) {
+			return nil, fmt.Errorf("invalid entry '%s': expected tool:operation=seconds naming one tool and operation", entry)
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsValue))
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid timeout for '%s': expected a positive number of seconds", tool)
+		}
+		timeouts[tool] = seconds
+	}
+	return timeouts, nil
+}
+
+// ToolTimeout returns the timeout in seconds of a call of a tool with the given operation argument, which is
+// empty for tools without one: the entry of the tool and operation in ToolTimeouts, else the tool's own entry,
+// else the longest matching class prefix, else Timeout
+func (cfg *ConfigData) ToolTimeout(tool, operation string) int {
+	if seconds, ok := cfg.ToolTimeouts[tool+":"+operation]; ok && operation != "" {
+		return seconds
+	}
+	if seconds, ok := cfg.ToolTimeouts[tool]; ok {
+		return seconds
+	}
+	timeout, longest := cfg.Timeout, -1
+	for pattern, seconds := range cfg.ToolTimeouts {
+		prefix, isClass := strings.CutSuffix(pattern, "*")
+		if isClass && strings.HasPrefix(tool, prefix) && len(prefix) > longest {
+			timeout, longest = seconds, len(prefix)
+		}
+	}
+	return timeout
+}
+
+// ClampTimeout limits a timeout requested by a tool call to MaxTimeout
+func (cfg *ConfigData) ClampTimeout(seconds int) int {
+	if cfg.MaxTimeout > 0 && seconds > cfg.MaxTimeout {
+		return cfg.MaxTimeout
+	}
+	return seconds
+}
+
+// ParseComponents parses a comma-separated component list. An empty list enables all components.
+func ParseComponents(value string) (map[string]bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool, len(AllComponents))
+	for _, name := range AllComponents {
+		known[name] = true
+	}
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown component '%s': available components are %s", name, strings.Join(AllComponents, ", "))
+		}
+		enabled[name] = true
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("no components specified")
+	}
+	return enabled, nil
+}
+
+// ComponentEnabled reports whether the named tool component should be registered
+func (cfg *ConfigData) ComponentEnabled(name string) bool {
+	return cfg.EnabledComponents == nil || cfg.EnabledComponents[name]
+}
+
+// KubernetesAccessEnabled reports whether tools may act on the cluster with the server's kubeconfig.
+// Session credential mode disables them because the kubeconfig does not belong to the calling session.
+func (cfg *ConfigData) KubernetesAccessEnabled() bool {
+	return cfg.ComponentEnabled(ComponentKubernetes) && !cfg.SessionCredentials
+}
+
+// GraphLookupEnabled reports whether object IDs may be resolved through Microsoft Graph. Session credential
+// mode disables it because session access tokens are issued for ARM only.
+func (cfg *ConfigData) GraphLookupEnabled() bool {
+	return cfg.GraphLookup && !cfg.SessionCredentials
+}
+
+// EnabledComponentNames returns the enabled components in registration order
+func (cfg *ConfigData) EnabledComponentNames() []string {
+	var names []string
+	for _, name := range AllComponents {
+		if cfg.ComponentEnabled(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CloudEnvironment returns the configured Azure cloud environment, defaulting to the public cloud
+func (cfg *ConfigData) CloudEnvironment() *cloudenv.Environment {
+	if cfg == nil || cfg.Cloud == nil {
+		return cloudenv.Public()
+	}
+	return cfg.Cloud
 }
 
 // InitializeTelemetry initializes the telemetry service
 func (cfg *ConfigData) InitializeTelemetry(ctx context.Context, serviceName, serviceVersion string) {
+	// With --disable-telemetry the service is never initialized, so every tracking call is a no-op
+	if cfg.DisableTelemetry {
+		cfg.TelemetryService = telemetry.NewService(telemetry.NewDisabledConfig(serviceName, serviceVersion))
+		return
+	}
+
 	// Create telemetry configuration
 	telemetryConfig := telemetry.NewConfig(serviceName, serviceVersion)
 
@@ -132,6 +775,11 @@ func (cfg *ConfigData) InitializeTelemetry(ctx context.Context, serviceName, ser
 		telemetryConfig.SetOTLPEndpoint(cfg.OTLPEndpoint)
 	}
 
+	// Send Application Insights telemetry to the ingestion endpoint of the configured cloud
+	if env := cfg.CloudEnvironment(); !env.IsPublic() {
+		telemetryConfig.SetApplicationInsightsCloud(env.AppInsightsIngestionEndpoint)
+	}
+
 	// Initialize telemetry service
 	cfg.TelemetryService = telemetry.NewService(telemetryConfig)
 	if err := cfg.TelemetryService.Initialize(ctx); err != nil {
@@ -151,4 +799,7 @@ func (cfg *ConfigData) PrintVersion() {
 	fmt.Printf("Git tree state: %s\n", versionInfo["gitTreeState"])
 	fmt.Printf("Go version: %s\n", versionInfo["goVersion"])
 	fmt.Printf("Platform: %s\n", versionInfo["platform"])
+	if !telemetry.ApplicationInsightsCompiledIn {
+		fmt.Println("Telemetry: Application Insights compiled out (notelemetry)")
+	}
 }
@@ -31,8 +31,14 @@ func (v *Validator) isCliInstalled(cliName string) bool {
 func (v *Validator) validateCli() bool {
 	valid := true
 
-	// az is always required
-	if !v.isCliInstalled("az") {
+	// The self-test answers commands with a mock and rejects the calls it makes before they run kubectl,
+	// so it can check a build before the CLIs are installed
+	if v.config.SelfTest {
+		return true
+	}
+
+	// az is required unless the server runs on the Azure SDK alone or replays recorded az output
+	if !v.config.NoAzCli && !v.config.ReplayMock && !v.isCliInstalled("az") {
 		v.errors = append(v.errors, "az is not installed or not found in PATH")
 		valid = false
 	}
@@ -58,12 +64,51 @@ func (v *Validator) validateCli() bool {
 	return valid
 }
 
+// validateLeaderElection checks that leader election is only used with HTTP transports
+func (v *Validator) validateLeaderElection() bool {
+	if v.config.LeaderElection && v.config.Transport == "stdio" {
+		v.errors = append(v.errors, "--leader-election requires the sse or streamable-http transport")
+		return false
+	}
+	return true
+}
+
+// validateReplay checks that recording, replay and self-test options are not combined
+func (v *Validator) validateReplay() bool {
+	valid := true
+	if v.config.Record != "" && v.config.Replay != "" {
+		v.errors = append(v.errors, "--record and --replay cannot be used together")
+		valid = false
+	}
+	if v.config.SelfTest && (v.config.Record != "" || v.config.Replay != "") {
+		v.errors = append(v.errors, "--self-test cannot be used with --record or --replay")
+		valid = false
+	}
+	if v.config.ReplayMock && v.config.Replay == "" {
+		v.errors = append(v.errors, "--replay-mock requires --replay")
+		valid = false
+	}
+	return valid
+}
+
+// validateTelemetry checks that OTLP export is not requested while telemetry is disabled
+func (v *Validator) validateTelemetry() bool {
+	if v.config.DisableTelemetry && v.config.OTLPEndpoint != "" {
+		v.errors = append(v.errors, "--disable-telemetry and --otlp-endpoint cannot be used together")
+		return false
+	}
+	return true
+}
+
 // Validate runs all validation checks
 func (v *Validator) Validate() bool {
 	// Run all validation checks
 	validCli := v.validateCli()
+	validLeaderElection := v.validateLeaderElection()
+	validReplay := v.validateReplay()
+	validTelemetry := v.validateTelemetry()
 
-	return validCli
+	return validCli && validLeaderElection && validReplay && validTelemetry
 }
 
 // GetErrors returns all errors found during validation
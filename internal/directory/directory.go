@@ -0,0 +1,124 @@
+// Package directory resolves Microsoft Entra ID object IDs, such as the user and group IDs in guard
+// authorization logs, to the names of the users, groups and service principals they identify.
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// MaxObjectIDs is the number of object IDs resolved per lookup, the Microsoft Graph getByIds limit
+const MaxObjectIDs = 1000
+
+// getByIDsPath is the Microsoft Graph API resolving object IDs
+const getByIDsPath = "/v1.0/directoryObjects/getByIds"
+
+// objectIDPattern matches object IDs (GUIDs) embedded in log lines and tool results
+var objectIDPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// GraphCaller calls the Microsoft Graph API. *azureclient.AzureClient implements it.
+type GraphCaller interface {
+	CallGraph(ctx context.Context, method, path string, payload interface{}) ([]byte, error)
+}
+
+// Object is a user, group or service principal
+type Object struct {
+	ID string `json:"id"`
+	// Type is user, group or servicePrincipal
+	Type              string `json:"type"`
+	DisplayName       string `json:"displayName"`
+	UserPrincipalName string `json:"userPrincipalName,omitempty"`
+	AppID             string `json:"appId,omitempty"`
+}
+
+// Annotated is a tool result together with the directory objects its object IDs identify
+type Annotated struct {
+	Result           json.RawMessage   `json:"result"`
+	DirectoryObjects map[string]Object `json:"directoryObjects"`
+	LookupError      string            `json:"directoryLookupError,omitempty"`
+}
+
+// ExtractObjectIDs returns the distinct object IDs in text, lowercased, in order of appearance
+func ExtractObjectIDs(text string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, match := range objectIDPattern.FindAllString(text, -1) {
+		id := strings.ToLower(match)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Resolve looks up users, groups and service principals by object ID. IDs that do not identify one of
+// them, such as tenant, subscription or request IDs, are left out of the result.
+func Resolve(ctx context.Context, graph GraphCaller, ids []string) (map[string]Object, error) {
+	objects := map[string]Object{}
+	if len(ids) == 0 {
+		return objects, nil
+	}
+	if len(ids) > MaxObjectIDs {
+		ids = ids[:MaxObjectIDs]
+	}
+	body, err := graph.CallGraph(ctx, http.MethodPost, getByIDsPath, map[string]interface{}{
+		"ids":   ids,
+		"types": []string{"user", "group", "servicePrincipal"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory objects: %w", err)
+	}
+	var response struct {
+		Value []struct {
+			ODataType         string `json:"@odata.type"`
+			ID                string `json:"id"`
+			DisplayName       string `json:"displayName"`
+			UserPrincipalName string `json:"userPrincipalName"`
+			AppID             string `json:"appId"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse directory objects: %w", err)
+	}
+	for _, v := range response.Value {
+		id := strings.ToLower(v.ID)
+		objects[id] = Object{
+			ID:                id,
+			Type:              strings.TrimPrefix(v.ODataType, "#microsoft.graph."),
+			DisplayName:       v.DisplayName,
+			UserPrincipalName: v.UserPrincipalName,
+			AppID:             v.AppID,
+		}
+	}
+	return objects, nil
+}
+
+// Annotate wraps a JSON tool result with the directory objects named by the object IDs it contains.
+// A failed lookup is reported in the result rather than failing it, since the result is useful without names.
+func Annotate(ctx context.Context, graph GraphCaller, result string) (string, error) {
+	raw := json.RawMessage(result)
+	if !json.Valid(raw) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result: %v", err)
+		}
+		raw = data
+	}
+	annotated := Annotated{Result: raw, DirectoryObjects: map[string]Object{}}
+	objects, err := Resolve(ctx, graph, ExtractObjectIDs(result))
+	if err != nil {
+		annotated.LookupError = err.Error()
+	} else {
+		annotated.DirectoryObjects = objects
+	}
+	data, err := json.MarshalIndent(annotated, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal annotated result: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,95 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+const (
+	userID  = "0b1f2a3c-4d5e-6f70-8192-a3b4c5d6e7f8"
+	groupID = "11111111-2222-3333-4444-555555555555"
+	// tenantID is not a user, group or service principal, so Graph returns nothing for it
+	tenantID = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+)
+
+// fakeGraph returns the configured directory objects and records the IDs requested
+type fakeGraph struct {
+	ids []string
+	err error
+}
+
+func (f *fakeGraph) CallGraph(_ context.Context, method, path string, payload interface{}) ([]byte, error) {
+	if method != "POST" || path != getByIDsPath {
+		return nil, fmt.Errorf("unexpected request %s %s", method, path)
+	}
+	f.ids = payload.(map[string]interface{})["ids"].([]string)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte(`{"value": [
+		{"@odata.type": "#microsoft.graph.user", "id": "0B1F2A3C-4D5E-6F70-8192-A3B4C5D6E7F8", "displayName": "Jane Doe", "userPrincipalName": "jane@contoso.com"},
+		{"@odata.type": "#microsoft.graph.group", "id": "11111111-2222-3333-4444-555555555555", "displayName": "aks-admins"}
+	]}`), nil
+}
+
+func TestExtractObjectIDs(t *testing.T) {
+	text := fmt.Sprintf(`user %s in groups [%s %s] of tenant %s denied; user %s`, userID, groupID, userID, tenantID, "0B1F2A3C-4D5E-6F70-8192-A3B4C5D6E7F8")
+	ids := ExtractObjectIDs(text)
+	if len(ids) != 3 || ids[0] != userID || ids[1] != groupID || ids[2] != tenantID {
+		t.Errorf("Expected distinct lowercase IDs in order, got %v", ids)
+	}
+	if ids := ExtractObjectIDs("no ids here 1234"); len(ids) != 0 {
+		t.Errorf("Expected no IDs, got %v", ids)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	graph := &fakeGraph{}
+	objects, err := Resolve(context.Background(), graph, []string{userID, groupID, tenantID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(objects) != 2 || objects[userID].Type != "user" || objects[userID].UserPrincipalName != "jane@contoso.com" ||
+		objects[groupID].DisplayName != "aks-admins" || objects[groupID].Type != "group" {
+		t.Errorf("Unexpected objects %+v", objects)
+	}
+
+	graph.ids = nil
+	if objects, err := Resolve(context.Background(), graph, nil); err != nil || len(objects) != 0 || graph.ids != nil {
+		t.Errorf("Expected no Graph request without IDs, got %v %v %v", objects, err, graph.ids)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	logs := fmt.Sprintf(`[{"TimeGenerated": "2025-01-02T03:04:05Z", "Message": "user %s groups [%s] is not authorized"}]`, userID, groupID)
+	output, err := Annotate(context.Background(), &fakeGraph{}, logs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var annotated Annotated
+	if err := json.Unmarshal([]byte(output), &annotated); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(annotated.Result, &records); err != nil || len(records) != 1 {
+		t.Errorf("Expected the log records to be kept, got %s", annotated.Result)
+	}
+	if annotated.DirectoryObjects[userID].DisplayName != "Jane Doe" || annotated.DirectoryObjects[groupID].DisplayName != "aks-admins" {
+		t.Errorf("Unexpected directory objects %+v", annotated.DirectoryObjects)
+	}
+
+	// A failed lookup keeps the result and reports the failure
+	output, err = Annotate(context.Background(), &fakeGraph{err: fmt.Errorf("API error (403): Insufficient privileges")}, logs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	annotated = Annotated{}
+	if err := json.Unmarshal([]byte(output), &annotated); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if annotated.LookupError == "" || len(annotated.Result) == 0 {
+		t.Errorf("Expected the lookup error to be reported with the result, got %s", output)
+	}
+}
@@ -0,0 +1,84 @@
+// Package errorkb matches common Azure Resource Manager and az CLI errors against a knowledge base
+// of likely causes and next steps, so tool error results tell the model how to recover.
+package errorkb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Entry describes a known error and how to resolve it
+type Entry struct {
+	// Code is the ARM or AKS error code, matched as a whole word (case-insensitive)
+	Code string
+	// Contains lists extra substrings that must all appear (case-insensitive) for the entry to apply.
+	// Entries sharing a code are tried in order, so more specific ones come first.
+	Contains  []string
+	Causes    []string
+	NextSteps []string
+}
+
+// codePatterns holds the compiled code pattern of every knowledge base entry
+var codePatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, entry := range knowledgeBase {
+		patterns[entry.Code] = codePattern(entry.Code)
+	}
+	return patterns
+}()
+
+// matches reports whether the entry applies to the lowercased error message
+func (e Entry) matches(lower string) bool {
+	if !codePatterns[e.Code].MatchString(lower) {
+		return false
+	}
+	for _, s := range e.Contains {
+		if !strings.Contains(lower, strings.ToLower(s)) {
+			return false
+		}
+	}
+	return true
+}
+
+// codePattern matches an error code not embedded in a longer identifier
+func codePattern(code string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^a-z0-9_])` + regexp.QuoteMeta(strings.ToLower(code)) + `($|[^a-z0-9_])`)
+}
+
+// Explain returns the knowledge base entries that apply to an error message,
+// at most one per error code, in knowledge base order
+func Explain(message string) []Entry {
+	lower := strings.ToLower(message)
+	var matched []Entry
+	seen := map[string]bool{}
+	for _, entry := range knowledgeBase {
+		if seen[entry.Code] || !entry.matches(lower) {
+			continue
+		}
+		seen[entry.Code] = true
+		matched = append(matched, entry)
+	}
+	return matched
+}
+
+// Enrich appends the causes and next steps of matching knowledge base entries to an error message.
+// Messages without a known error code are returned unchanged.
+func Enrich(message string) string {
+	entries := Explain(message)
+	if len(entries) == 0 {
+		return message
+	}
+	var b strings.Builder
+	b.WriteString(message)
+	for _, entry := range entries {
+		b.WriteString("\n\n[" + entry.Code + "] Likely causes:")
+		for _, cause := range entry.Causes {
+			b.WriteString("\n- " + cause)
+		}
+		b.WriteString("\nNext steps:")
+		for _, step := range entry.NextSteps {
+			b.WriteString("\n- " + step)
+		}
+	}
+	return b.String()
+}
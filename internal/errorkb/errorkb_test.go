@@ -0,0 +1,84 @@
+package errorkb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+		step    string
+	}{
+		{
+			name:    "quota",
+			message: "(QuotaExceeded) Operation could not be completed as it results in exceeding approved standardDSv5Family Cores quota.",
+			want:    []string{"QuotaExceeded"},
+			step:    "az vm list-usage",
+		},
+		{
+			name:    "system pool",
+			message: "(OperationNotAllowed) Cannot delete the last system node pool. Code: OperationNotAllowed",
+			want:    []string{"OperationNotAllowed"},
+			step:    "--mode System",
+		},
+		{
+			name:    "operation in progress",
+			message: "Code: OperationNotAllowed Message: Operation is not allowed because there's an operation in progress",
+			want:    []string{"OperationNotAllowed"},
+			step:    "provisioningState",
+		},
+		{
+			name:    "drain failure",
+			message: "Code=\"PodDrainFailure\" Message=\"Drain node aks-np-1 failed when evicting pod web-1\"",
+			want:    []string{"PodDrainFailure"},
+			step:    "kubectl get pdb",
+		},
+		{
+			name:    "multiple codes",
+			message: "SkuNotAvailable: size not available; AuthorizationFailed for client",
+			want:    []string{"SkuNotAvailable", "AuthorizationFailed"},
+		},
+		{
+			name:    "code inside longer identifier",
+			message: "ResourceNotFoundException from a downstream service",
+		},
+		{
+			name:    "unknown",
+			message: "failed to parse parameters JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := Explain(tt.message)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Expected %d entries, got %+v", len(tt.want), entries)
+			}
+			for i, entry := range entries {
+				if entry.Code != tt.want[i] {
+					t.Errorf("Expected code %s, got %s", tt.want[i], entry.Code)
+				}
+			}
+			if tt.step != "" && !strings.Contains(strings.Join(entries[0].NextSteps, "\n"), tt.step) {
+				t.Errorf("Expected next steps to mention %q, got %v", tt.step, entries[0].NextSteps)
+			}
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	if got := Enrich("plain failure"); got != "plain failure" {
+		t.Errorf("Expected unknown errors to be unchanged, got %q", got)
+	}
+
+	got := Enrich("(SkuNotAvailable) The requested size is not available")
+	if !strings.HasPrefix(got, "(SkuNotAvailable) The requested size is not available\n\n[SkuNotAvailable] Likely causes:") {
+		t.Errorf("Expected causes after the original message, got %q", got)
+	}
+	if !strings.Contains(got, "\nNext steps:\n- ") {
+		t.Errorf("Expected next steps, got %q", got)
+	}
+}
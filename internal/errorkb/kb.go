@@ -0,0 +1,139 @@
+package errorkb
+
+// knowledgeBase lists known errors. Keep entries for the same code together, most specific first.
+var knowledgeBase = []Entry{
+	{
+		Code: "QuotaExceeded",
+		Causes: []string{
+			"The operation needs more vCPUs of the VM family than the regional quota of the subscription allows",
+			"Scaling or upgrading adds surge nodes, which count against the quota while the operation runs",
+		},
+		NextSteps: []string{
+			"Check usage with: az vm list-usage --location <location> --output table",
+			"Request a quota increase for the VM family in the portal (Subscriptions > Usage + quotas)",
+			"Use a VM size from a family with available quota, or lower max surge on the node pool",
+		},
+	},
+	{
+		Code: "ErrCode_InsufficientVCPUQuota",
+		Causes: []string{
+			"The regional vCPU quota of the subscription is lower than the cores the node pool needs",
+		},
+		NextSteps: []string{
+			"Check usage with: az vm list-usage --location <location> --output table",
+			"Request a quota increase, or reduce the node count or VM size",
+		},
+	},
+	{
+		Code: "SkuNotAvailable",
+		Causes: []string{
+			"The VM size is not offered in the region or availability zone, or is restricted for the subscription",
+		},
+		NextSteps: []string{
+			"List sizes available to the subscription with: az vm list-skus --location <location> --size <size> --output table",
+			"Pick a size without restrictions, or create the node pool without the zones that lack capacity",
+			"Open a support request to lift a subscription restriction on the size",
+		},
+	},
+	{
+		Code:     "OperationNotAllowed",
+		Contains: []string{"system"},
+		Causes: []string{
+			"A cluster must keep at least one System mode node pool, so the last system pool cannot be deleted, scaled to zero or switched to User mode",
+		},
+		NextSteps: []string{
+			"Add another node pool with --mode System before changing this one",
+			"Scale system pools to at least one node; only User pools can scale to zero",
+		},
+	},
+	{
+		Code:     "OperationNotAllowed",
+		Contains: []string{"in progress"},
+		Causes: []string{
+			"Another operation on the cluster or node pool is still running",
+		},
+		NextSteps: []string{
+			"Wait for it to finish; check provisioningState with: az aks show --name <cluster> --resource-group <rg> --query provisioningState",
+		},
+	},
+	{
+		Code: "OperationNotAllowed",
+		Causes: []string{
+			"The cluster or node pool is in a state or configuration that does not allow the requested change",
+		},
+		NextSteps: []string{
+			"Read the rest of the error message for the specific constraint",
+			"Check the cluster and node pool provisioningState and powerState before retrying",
+		},
+	},
+	{
+		Code: "PodDrainFailure",
+		Causes: []string{
+			"A PodDisruptionBudget allows no disruptions, so pods could not be evicted from the node being upgraded or deleted",
+			"Pods take longer to terminate than the drain timeout",
+		},
+		NextSteps: []string{
+			"Find blocking budgets with: kubectl get pdb --all-namespaces (ALLOWED DISRUPTIONS = 0)",
+			"Scale the workload up or relax the PodDisruptionBudget, then retry the operation",
+			"Increase the node pool drain timeout with: az aks nodepool update --drain-timeout <minutes>",
+		},
+	},
+	{
+		Code: "AuthorizationFailed",
+		Causes: []string{
+			"The identity running aks-mcp has no role assignment that grants the action on the scope",
+		},
+		NextSteps: []string{
+			"Check assignments with: az role assignment list --assignee <principal-id> --all --output table",
+			"Grant a role that includes the action in the error message on the resource, resource group or subscription",
+		},
+	},
+	{
+		Code: "SubnetIsFull",
+		Causes: []string{
+			"The node subnet has no free addresses for new nodes (and pods, with Azure CNI)",
+		},
+		NextSteps: []string{
+			"Check address usage of the subnet, then add a node pool on a larger subnet",
+			"Consider Azure CNI Overlay so pods do not consume subnet addresses",
+		},
+	},
+	{
+		Code: "InsufficientSubnetSize",
+		Causes: []string{
+			"The subnet cannot fit the maximum node count times max pods (Azure CNI) plus upgrade surge nodes",
+		},
+		NextSteps: []string{
+			"Use a larger subnet, lower --max-pods or the node count, or switch to Azure CNI Overlay",
+		},
+	},
+	{
+		Code: "VMExtensionProvisioningError",
+		Causes: []string{
+			"Nodes could not finish bootstrapping, most often because outbound connectivity to required endpoints is blocked",
+			"Custom DNS servers cannot resolve the API server or required Azure endpoints",
+		},
+		NextSteps: []string{
+			"Check the exit code in the message against the AKS outbound connectivity troubleshooting guide",
+			"Verify firewall, NSG and UDR rules allow the AKS required outbound network rules",
+		},
+	},
+	{
+		Code: "ResourceGroupNotFound",
+		Causes: []string{
+			"The resource group does not exist in the subscription, or the wrong subscription is selected",
+		},
+		NextSteps: []string{
+			"List resource groups with: az group list --subscription <subscription-id> --output table",
+		},
+	},
+	{
+		Code: "ResourceNotFound",
+		Causes: []string{
+			"The resource name, resource group or subscription is wrong, or the resource was deleted",
+		},
+		NextSteps: []string{
+			"List the clusters in the subscription with: az aks list --output table",
+		},
+	},
+}
@@ -0,0 +1,146 @@
+// Package explain records the commands and API calls made while handling a tool call, so a result
+// can show exactly what the server ran, why, and where each step is documented.
+package explain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Kind identifies how a step reached Azure or the cluster
+type Kind string
+
+const (
+	KindAz      Kind = "az"
+	KindKubectl Kind = "kubectl"
+	KindARM     Kind = "arm"
+)
+
+// Step is a command or API call made during a tool call
+type Step struct {
+	Kind    Kind     `json:"kind"`
+	Command string   `json:"command"`
+	Reason  string   `json:"reason"`
+	Docs    []string `json:"docs,omitempty"`
+	Cached  bool     `json:"cached,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Trace collects the steps of one tool call. A nil Trace records nothing.
+type Trace struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+// New creates an empty trace
+func New() *Trace {
+	return &Trace{}
+}
+
+// Record adds a step for a command or API call and the error it returned, if any
+func (t *Trace) Record(kind Kind, command string, err error) {
+	t.record(kind, command, false, err)
+}
+
+// RecordCached adds a step whose result was served from the output cache
+func (t *Trace) RecordCached(kind Kind, command string) {
+	t.record(kind, command, true, nil)
+}
+
+func (t *Trace) record(kind Kind, command string, cached bool, err error) {
+	if t == nil {
+		return
+	}
+	command = redact(strings.TrimSpace(command))
+	ref := lookup(kind, command)
+	step := Step{Kind: kind, Command: command, Reason: ref.reason, Docs: ref.docs, Cached: cached}
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+// Steps returns the recorded steps in the order they were made
+func (t *Trace) Steps() []Step {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Step(nil), t.steps...)
+}
+
+// Render formats the steps as a numbered list for a tool result
+func (t *Trace) Render() string {
+	steps := t.Steps()
+	if len(steps) == 0 {
+		return "Explanation: no az, kubectl or Azure Resource Manager calls were made; the result was computed from the request and local state."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Explanation (%d step", len(steps))
+	if len(steps) != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString("):")
+	for i, step := range steps {
+		fmt.Fprintf(&b, "\n%d. [%s] %s", i+1, step.Kind, step.Command)
+		if step.Cached {
+			b.WriteString(" (served from cache)")
+		}
+		fmt.Fprintf(&b, "\n   Why: %s", step.Reason)
+		for _, doc := range step.Docs {
+			fmt.Fprintf(&b, "\n   Docs: %s", doc)
+		}
+		if step.Error != "" {
+			fmt.Fprintf(&b, "\n   Error: %s", step.Error)
+		}
+	}
+	return b.String()
+}
+
+// ARMCommand formats an ARM request as METHOD /path?query, without the endpoint host
+func ARMCommand(method, rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		rawURL = parsed.RequestURI()
+	}
+	return method + " " + rawURL
+}
+
+// sensitiveFlags take values that must not be echoed back in explanations
+var sensitiveFlags = []string{"--password", "--client-secret", "--config-protected", "--configuration-protected-settings", "--token"}
+
+// redact replaces the values of sensitive flags, up to the next flag
+func redact(command string) string {
+	fields := strings.Fields(command)
+	var out []string
+	redacted, skipping := false, false
+	for _, field := range fields {
+		if skipping && !strings.HasPrefix(field, "-") {
+			continue
+		}
+		skipping = false
+		for _, flag := range sensitiveFlags {
+			if field == flag {
+				skipping = true
+			} else if strings.HasPrefix(field, flag+"=") {
+				field = flag + "=<redacted>"
+				redacted = true
+			}
+		}
+		out = append(out, field)
+		if skipping {
+			out = append(out, "<redacted>")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return command
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,56 @@
+package explain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTraceRender(t *testing.T) {
+	trace := New()
+	trace.Record(KindAz, "az aks nodepool snapshot create --name s1 --nodepool-id id", nil)
+	trace.RecordCached(KindAz, "az aks show --name aks")
+	trace.Record(KindKubectl, "kubectl get events --namespace default", nil)
+	trace.Record(KindARM, ARMCommand("GET", "https://management.azure.com/subscriptions/sub/providers/Microsoft.ResourceHealth/availabilityStatuses?api-version=2020-05-01"), errors.New("status 403"))
+	trace.Record(KindAz, "az something-new", nil)
+
+	steps := trace.Steps()
+	if len(steps) != 5 {
+		t.Fatalf("Expected 5 steps, got %+v", steps)
+	}
+	if !strings.Contains(steps[0].Docs[0], "node-pool-snapshot") {
+		t.Errorf("Expected the more specific snapshot reference, got %+v", steps[0])
+	}
+	if !steps[1].Cached || !strings.Contains(steps[1].Reason, "managed cluster") {
+		t.Errorf("Unexpected cached step %+v", steps[1])
+	}
+	if steps[3].Command != "GET /subscriptions/sub/providers/Microsoft.ResourceHealth/availabilityStatuses?api-version=2020-05-01" || steps[3].Error != "status 403" {
+		t.Errorf("Unexpected ARM step %+v", steps[3])
+	}
+	if steps[4].Reason != fallbacks[KindAz].reason {
+		t.Errorf("Expected the fallback reason, got %+v", steps[4])
+	}
+
+	rendered := trace.Render()
+	for _, want := range []string{"Explanation (5 steps):", "2. [az] az aks show --name aks (served from cache)", "   Error: status 403"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("Expected %q in %s", want, rendered)
+		}
+	}
+
+	var empty *Trace
+	empty.Record(KindAz, "az aks list", nil)
+	if !strings.HasPrefix(empty.Render(), "Explanation: no az") {
+		t.Errorf("Expected a nil trace to record nothing, got %q", empty.Render())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	got := redact("az k8s-extension create --name backup --config-protected key=secret other=value --no-wait --password=hunter2")
+	if got != "az k8s-extension create --name backup --config-protected <redacted> --no-wait --password=<redacted>" {
+		t.Errorf("Unexpected redaction %q", got)
+	}
+	if got := redact("az aks show --query 'a  b'"); got != "az aks show --query 'a  b'" {
+		t.Errorf("Expected commands without secrets to be unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package explain
+
+import "strings"
+
+// reference explains why a kind of command is run and where it is documented
+type reference struct {
+	// match is a command prefix for az and kubectl, and a case-insensitive path fragment for ARM
+	match  string
+	reason string
+	docs   []string
+}
+
+// references are tried in order, so more specific entries come first
+var references = map[Kind][]reference{
+	KindAz: {
+		{"az aks nodepool snapshot", "Manages node pool snapshots, which capture a node pool's image and configuration for reuse",
+			[]string{"https://learn.microsoft.com/azure/aks/node-pool-snapshot"}},
+		{"az aks nodepool", "Reads or changes node pools, which hold the cluster's nodes",
+			[]string{"https://learn.microsoft.com/cli/azure/aks/nodepool"}},
+		{"az aks trustedaccess", "Manages trusted access role bindings that let Azure services reach the cluster",
+			[]string{"https://learn.microsoft.com/azure/aks/trusted-access-feature"}},
+		{"az aks check-network", "Runs an outbound connectivity check from a node to the endpoints AKS requires",
+			[]string{"https://learn.microsoft.com/azure/aks/outbound-rules-control-egress"}},
+		{"az aks get-versions", "Lists the Kubernetes versions and upgrade paths available in the region",
+			[]string{"https://learn.microsoft.com/azure/aks/supported-kubernetes-versions"}},
+		{"az aks upgrade", "Upgrades the control plane and node pools to a new Kubernetes version or node image",
+			[]string{"https://learn.microsoft.com/azure/aks/upgrade-aks-cluster"}},
+		{"az aks", "Reads or changes the managed cluster resource",
+			[]string{"https://learn.microsoft.com/cli/azure/aks"}},
+		{"az k8s-extension", "Manages cluster extensions, which install Azure-managed components with Helm",
+			[]string{"https://learn.microsoft.com/azure/aks/cluster-extensions"}},
+		{"az fleet", "Reads or changes the Fleet Manager resource that groups member clusters",
+			[]string{"https://learn.microsoft.com/azure/kubernetes-fleet/"}},
+		{"az monitor", "Queries Azure Monitor metrics, logs or the Activity Log for the resource",
+			[]string{"https://learn.microsoft.com/azure/aks/monitor-aks"}},
+		{"az network", "Reads the network resources the cluster depends on",
+			[]string{"https://learn.microsoft.com/azure/aks/concepts-network"}},
+		{"az vmss", "Reads or changes the virtual machine scale sets that back the node pools",
+			[]string{"https://learn.microsoft.com/cli/azure/vmss"}},
+		{"az account", "Selects or lists the subscriptions the Azure CLI signs in to",
+			[]string{"https://learn.microsoft.com/cli/azure/manage-azure-subscriptions-azure-cli"}},
+	},
+	KindKubectl: {
+		{"kubectl get events", "Reads cluster events, which record scheduling, pull and probe failures",
+			[]string{"https://kubernetes.io/docs/reference/kubectl/generated/kubectl_events/"}},
+		{"kubectl describe", "Shows an object's status, conditions and related events",
+			[]string{"https://kubernetes.io/docs/reference/kubectl/generated/kubectl_describe/"}},
+		{"kubectl logs", "Reads container logs",
+			[]string{"https://kubernetes.io/docs/reference/kubectl/generated/kubectl_logs/"}},
+		{"kubectl drain", "Evicts pods from a node while respecting PodDisruptionBudgets",
+			[]string{"https://kubernetes.io/docs/tasks/administer-cluster/safely-drain-node/"}},
+		{"kubectl exec", "Runs a command inside a container",
+			[]string{"https://kubernetes.io/docs/tasks/debug/debug-application/get-shell-running-container/"}},
+		{"kubectl get", "Reads Kubernetes objects from the API server",
+			[]string{"https://kubernetes.io/docs/reference/kubectl/generated/kubectl_get/"}},
+	},
+	KindARM: {
+		{"microsoft.resourcehealth", "Reads Resource Health, which reports platform availability of the resource",
+			[]string{"https://learn.microsoft.com/azure/service-health/resource-health-overview"}},
+		{"microsoft.insights/metrics", "Reads Azure Monitor platform metrics for the resource",
+			[]string{"https://learn.microsoft.com/azure/aks/monitor-aks-reference"}},
+		{"eventtypes/management", "Reads Activity Log entries, which record control plane writes and who made them",
+			[]string{"https://learn.microsoft.com/azure/azure-monitor/essentials/activity-log"}},
+		{"microsoft.insights/diagnosticsettings", "Reads diagnostic settings, which decide where resource logs are sent",
+			[]string{"https://learn.microsoft.com/azure/aks/monitor-aks#resource-logs"}},
+		{"microsoft.alertsmanagement", "Reads fired Azure Monitor alerts",
+			[]string{"https://learn.microsoft.com/azure/azure-monitor/alerts/alerts-overview"}},
+		{"/detectors", "Runs AKS diagnostic detectors, the checks behind Diagnose and Solve Problems",
+			[]string{"https://learn.microsoft.com/azure/aks/aks-diagnostics"}},
+		{"microsoft.containerservice/managedclusters", "Reads or changes the managed cluster resource through Azure Resource Manager",
+			[]string{"https://learn.microsoft.com/rest/api/aks/managed-clusters"}},
+		{"microsoft.network", "Reads the network resources the cluster depends on",
+			[]string{"https://learn.microsoft.com/rest/api/virtualnetwork/"}},
+		{"microsoft.storage/storageaccounts", "Reads the storage account that cluster artifacts such as diagnostics and backups are written to",
+			[]string{"https://learn.microsoft.com/rest/api/storagerp/storage-accounts"}},
+		{"microsoft.compute/virtualmachinescalesets", "Reads the virtual machine scale sets that back the node pools",
+			[]string{"https://learn.microsoft.com/rest/api/compute/virtual-machine-scale-sets"}},
+	},
+}
+
+// fallbacks apply when no reference matches
+var fallbacks = map[Kind]reference{
+	KindAz:      {reason: "Azure CLI command run for the requested operation", docs: []string{"https://learn.microsoft.com/cli/azure/reference-index"}},
+	KindKubectl: {reason: "kubectl command run against the cluster for the requested operation", docs: []string{"https://kubernetes.io/docs/reference/kubectl/"}},
+	KindARM:     {reason: "Azure Resource Manager request made for the requested operation", docs: []string{"https://learn.microsoft.com/rest/api/azure/"}},
+}
+
+// lookup finds the reference of a command
+func lookup(kind Kind, command string) reference {
+	lower := strings.ToLower(command)
+	for _, ref := range references[kind] {
+		if kind == KindARM {
+			if strings.Contains(lower, ref.match) {
+				return ref
+			}
+			continue
+		}
+		if lower == ref.match || strings.HasPrefix(lower, ref.match+" ") {
+			return ref
+		}
+	}
+	return fallbacks[kind]
+}
@@ -0,0 +1,237 @@
+// Package export streams audit records and scanner findings as JSON events to an external sink, such as
+// Azure Event Hubs or a Kafka REST proxy, so they can be ingested by a SIEM. Events are queued in the state
+// store before they are sent and removed only once the sink accepted them, so each event is delivered at
+// least once, also across restarts. The queue is bounded: when the sink falls behind, publishers wait for
+// room and events are dropped, and counted, only when none frees up in time.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+const (
+	// DefaultQueueSize is the number of events queued for the sink when no size is configured
+	DefaultQueueSize = 10000
+	// queueBucket is the state store bucket of the events waiting to be sent, keyed in publish order
+	queueBucket = "export-queue"
+	// batchSize bounds the events sent to the sink in one request
+	batchSize = 100
+	// publishTimeout is how long a publisher waits for room in a full queue before the event is dropped
+	publishTimeout = 5 * time.Second
+	// minBackoff and maxBackoff bound the wait before a failed batch is sent again
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Types of events
+const (
+	TypeAudit   = "audit"
+	TypeFinding = "finding"
+)
+
+// ErrQueueFull is returned when an event is dropped because the queue stayed full for publishTimeout
+var ErrQueueFull = errors.New("export queue is full")
+
+// Event is one exported audit record or finding. ID is unique per replica and orders the events it
+// published; a sink may receive an event more than once and can drop repeats by Source and ID.
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Source is the host name of the replica that published the event
+	Source string          `json:"source"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Sink delivers batches of events. Send returns nil only when the sink accepted every event of the batch.
+type Sink interface {
+	// Name describes the sink in logs without its credentials
+	Name() string
+	Send(ctx context.Context, events []Event) error
+}
+
+// Stats describes the state of an exporter
+type Stats struct {
+	Queued    int    `json:"queued"`
+	Sent      int64  `json:"sent"`
+	Dropped   int64  `json:"dropped"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Exporter queues events and sends them to its sink in order from Run
+type Exporter struct {
+	sink     Sink
+	queue    *store.Repository[Event]
+	maxQueue int
+	source   string
+	now      func() time.Time
+	// publishTimeout is how long Publish waits for room in a full queue
+	publishTimeout time.Duration
+
+	mu    sync.Mutex
+	seq   int
+	stats Stats
+	// room is closed and replaced whenever events leave the queue, waking every waiting publisher
+	room chan struct{}
+	// ready wakes Run when events are queued
+	ready chan struct{}
+}
+
+// New creates an exporter sending to sink, with events queued in st up to maxQueue (DefaultQueueSize when
+// maxQueue is not positive). Events left in the queue by an earlier run are sent first.
+func New(sink Sink, st store.Store, maxQueue int) (*Exporter, error) {
+	if maxQueue <= 0 {
+		maxQueue = DefaultQueueSize
+	}
+	source, _ := os.Hostname()
+	e := &Exporter{
+		sink:           sink,
+		queue:          store.NewRepository[Event](st, queueBucket),
+		maxQueue:       maxQueue,
+		source:         source,
+		now:            time.Now,
+		publishTimeout: publishTimeout,
+		room:           make(chan struct{}),
+		ready:          make(chan struct{}, 1),
+	}
+	pending, err := e.queue.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the export queue: %w", err)
+	}
+	e.stats.Queued = len(pending)
+	if len(pending) > 0 {
+		log.Printf("[EXPORT] %d events queued by an earlier run will be sent to %s", len(pending), sink.Name())
+	}
+	return e, nil
+}
+
+// Publish queues data as an event of the given type. When the queue is full it waits up to publishTimeout
+// for the sink to catch up, then drops the event and returns ErrQueueFull.
+func (e *Exporter) Publish(eventType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	deadline := time.NewTimer(e.publishTimeout)
+	defer deadline.Stop()
+	e.mu.Lock()
+	for e.stats.Queued >= e.maxQueue {
+		room := e.room
+		e.mu.Unlock()
+		select {
+		case <-room:
+		case <-deadline.C:
+			e.mu.Lock()
+			e.stats.Dropped++
+			dropped := e.stats.Dropped
+			e.mu.Unlock()
+			log.Printf("[EXPORT] Dropped %s event: the queue of %d events for %s stayed full (%d dropped)", eventType, e.maxQueue, e.sink.Name(), dropped)
+			return ErrQueueFull
+		}
+		e.mu.Lock()
+	}
+	defer e.mu.Unlock()
+
+	e.seq++
+	now := e.now().UTC()
+	event := Event{
+		// IDs sort in the order events were published
+		ID:     fmt.Sprintf("%020d-%06d", now.UnixNano(), e.seq),
+		Type:   eventType,
+		Time:   now,
+		Source: e.source,
+		Data:   raw,
+	}
+	if err := e.queue.Save(event.ID, event); err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", eventType, err)
+	}
+	e.stats.Queued++
+	select {
+	case e.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends queued events in batches until ctx is cancelled, waiting with a growing backoff after a batch
+// fails. Events still queued when it returns are sent by the next run.
+func (e *Exporter) Run(ctx context.Context) {
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		sent, err := e.Flush(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[EXPORT] Failed to send events to %s, retrying in %s: %v", e.sink.Name(), backoff, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+		if sent == 0 {
+			// The queue is drained: wait for the next event
+			select {
+			case <-ctx.Done():
+			case <-e.ready:
+			}
+		}
+	}
+}
+
+// Flush sends the oldest batch of queued events and removes it from the queue once the sink accepted it.
+// It returns the number of events sent.
+func (e *Exporter) Flush(ctx context.Context) (int, error) {
+	batch, err := e.queue.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the export queue: %w", err)
+	}
+	if len(batch) > batchSize {
+		batch = batch[:batchSize]
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := e.sink.Send(ctx, batch); err != nil {
+		e.mu.Lock()
+		e.stats.LastError = err.Error()
+		e.mu.Unlock()
+		return 0, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stats.LastError = ""
+	for _, event := range batch {
+		// An event that cannot be removed is sent again, which at-least-once delivery allows
+		if err := e.queue.Delete(event.ID); err != nil {
+			log.Printf("[EXPORT] Failed to remove sent event %s from the queue: %v", event.ID, err)
+			continue
+		}
+		e.stats.Queued--
+	}
+	e.stats.Sent += int64(len(batch))
+	close(e.room)
+	e.room = make(chan struct{})
+	return len(batch), nil
+}
+
+// Stats returns the number of queued, sent and dropped events and the last send error
+func (e *Exporter) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
@@ -0,0 +1,223 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// fakeSink records the batches it accepts and fails while failing is set
+type fakeSink struct {
+	mu      sync.Mutex
+	failing bool
+	batches [][]Event
+}
+
+func (f *fakeSink) Name() string { return "fake" }
+
+func (f *fakeSink) Send(_ context.Context, events []Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing {
+		return fmt.Errorf("sink unavailable")
+	}
+	f.batches = append(f.batches, events)
+	return nil
+}
+
+func TestExporterDeliversAtLeastOnce(t *testing.T) {
+	st := store.NewMemoryStore()
+	sink := &fakeSink{failing: true}
+	exporter, err := New(sink, st, 0)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < batchSize+1; i++ {
+		if err := exporter.Publish(TypeAudit, map[string]int{"n": i}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	// A failed batch stays queued, also for the exporter of the next run
+	if _, err := exporter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected the failing sink to fail the flush")
+	}
+	if stats := exporter.Stats(); stats.Queued != batchSize+1 || stats.LastError == "" {
+		t.Errorf("Expected the events to stay queued, got %+v", stats)
+	}
+	restarted, err := New(sink, st, 0)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if queued := restarted.Stats().Queued; queued != batchSize+1 {
+		t.Errorf("Expected the queue to survive a restart, got %d events", queued)
+	}
+
+	sink.failing = false
+	for {
+		sent, err := restarted.Flush(context.Background())
+		if err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if sent == 0 {
+			break
+		}
+	}
+	if len(sink.batches) != 2 || len(sink.batches[0]) != batchSize || len(sink.batches[1]) != 1 {
+		t.Fatalf("Expected two batches, got %d", len(sink.batches))
+	}
+	var first map[string]int
+	if err := json.Unmarshal(sink.batches[0][0].Data, &first); err != nil || first["n"] != 0 || sink.batches[0][0].Type != TypeAudit {
+		t.Errorf("Expected events in publish order, got %+v", sink.batches[0][0])
+	}
+	if stats := restarted.Stats(); stats.Queued != 0 || stats.Sent != batchSize+1 || stats.LastError != "" {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestExporterBackpressure(t *testing.T) {
+	sink := &fakeSink{}
+	exporter, err := New(sink, store.NewMemoryStore(), 2)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	exporter.publishTimeout = 50 * time.Millisecond
+	for i := 0; i < 2; i++ {
+		if err := exporter.Publish(TypeFinding, i); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	// A full queue drops the event once nothing is sent in time
+	if err := exporter.Publish(TypeFinding, 2); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	// A publisher waiting for room continues once the sink catches up
+	exporter.publishTimeout = 5 * time.Second
+	published := make(chan error)
+	go func() { published <- exporter.Publish(TypeFinding, 3) }()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := exporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := <-published; err != nil {
+		t.Errorf("Expected the waiting publisher to succeed, got %v", err)
+	}
+	if stats := exporter.Stats(); stats.Dropped != 1 || stats.Queued != 1 || stats.Sent != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv(ConnectionStringEnv, "")
+	t.Setenv(CredentialsEnv, "")
+	for _, sinkURL := range []string{
+		"eventhubs://ns.servicebus.windows.net",
+		"eventhubs://ns.servicebus.windows.net/hub/extra",
+		"kafka://broker:9092/topic",
+		"://",
+	} {
+		if _, err := Open(sinkURL, func(context.Context) (string, error) { return "t", nil }); err == nil {
+			t.Errorf("%s: expected an error", sinkURL)
+		}
+	}
+	if _, err := Open("eventhubs://ns.servicebus.windows.net/hub", nil); err == nil {
+		t.Error("Expected Event Hubs without a credential to be rejected")
+	}
+
+	sink, err := Open("kafka+https://proxy.example.com:8082/kafka/audit", nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if sink.Name() != "https://proxy.example.com:8082/kafka/topics/audit" {
+		t.Errorf("Unexpected Kafka REST URL %s", sink.Name())
+	}
+}
+
+func TestEventHubSink(t *testing.T) {
+	var authorization, contentType string
+	var messages []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, contentType = r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &messages)
+		if r.URL.Path != "/hub/messages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	t.Setenv(ConnectionStringEnv, "Endpoint=sb://ns.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=secret")
+	sink, err := Open("eventhubs://ns.servicebus.windows.net/hub", nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	eventHub := sink.(*EventHubSink)
+	eventHub.url = server.URL + "/hub/messages"
+
+	events := []Event{{ID: "1", Type: TypeAudit, Data: json.RawMessage(`{"tool":"aks_node_drain"}`)}}
+	if err := eventHub.Send(context.Background(), events); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if !strings.HasPrefix(authorization, "SharedAccessSignature sr=https%3A%2F%2Fns.servicebus.windows.net%2Fhub&sig=") || !strings.Contains(authorization, "&skn=send") {
+		t.Errorf("Unexpected authorization %s", authorization)
+	}
+	if contentType != "application/vnd.microsoft.servicebus.json" || len(messages) != 1 ||
+		!strings.Contains(messages[0]["Body"].(string), "aks_node_drain") || messages[0]["UserProperties"].(map[string]interface{})["type"] != TypeAudit {
+		t.Errorf("Unexpected batch %s %v", contentType, messages)
+	}
+
+	eventHub.url = server.URL + "/other/messages"
+	if err := eventHub.Send(context.Background(), events); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a rejected batch to fail, got %v", err)
+	}
+}
+
+func TestKafkaRESTSink(t *testing.T) {
+	response := `{"offsets": [{"partition": 0, "offset": 1}]}`
+	var user string
+	var records struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value Event  `json:"value"`
+		} `json:"records"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &records)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	t.Setenv(CredentialsEnv, "exporter:secret")
+	sink, err := Open("kafka+http://"+strings.TrimPrefix(server.URL, "http://")+"/findings", nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	events := []Event{{ID: "1", Type: TypeFinding, Data: json.RawMessage(`{}`)}}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if user != "exporter" || len(records.Records) != 1 || records.Records[0].Key != "1" || records.Records[0].Value.Type != TypeFinding {
+		t.Errorf("Unexpected request %s %+v", user, records)
+	}
+
+	response = `{"offsets": [{"error_code": 40403, "error": "topic not found"}]}`
+	if err := sink.Send(context.Background(), events); err == nil || !strings.Contains(err.Error(), "topic not found") {
+		t.Errorf("Expected a rejected record to fail the batch, got %v", err)
+	}
+}
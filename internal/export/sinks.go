@@ -0,0 +1,250 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemeEventHubs selects Azure Event Hubs: eventhubs://<namespace>.servicebus.windows.net/<event hub>
+	SchemeEventHubs = "eventhubs"
+	// SchemeKafkaHTTPS and SchemeKafkaHTTP select a Kafka REST proxy (v2 API): kafka+https://<proxy>/<topic>
+	SchemeKafkaHTTPS = "kafka+https"
+	SchemeKafkaHTTP  = "kafka+http"
+
+	// ConnectionStringEnv holds an Event Hubs connection string with a shared access key; without it the
+	// server's Entra ID credential is used
+	ConnectionStringEnv = "AKS_MCP_EXPORT_CONNECTION_STRING"
+	// CredentialsEnv holds the user:password a Kafka REST proxy is called with, if it requires basic authentication
+	CredentialsEnv = "AKS_MCP_EXPORT_CREDENTIALS"
+
+	// sendTimeout bounds one request to the sink
+	sendTimeout = 30 * time.Second
+	// sasTokenTTL is how long the shared access signatures of Event Hubs requests are valid
+	sasTokenTTL = time.Hour
+	// maxErrorBytes bounds the error responses read from a sink
+	maxErrorBytes = 4096
+)
+
+// TokenSource returns an Entra ID access token for Event Hubs. *azureclient.AzureClient.EventHubsToken implements it.
+type TokenSource func(ctx context.Context) (string, error)
+
+// Open creates the sink a --export-sink URL names. Event Hubs authenticate with the shared access key of
+// ConnectionStringEnv when it is set, otherwise with token, which may be nil only when the key is set.
+func Open(rawURL string, token TokenSource) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export sink %s: %v", rawURL, err)
+	}
+	path := strings.Trim(u.Path, "/")
+	if u.Host == "" || path == "" {
+		return nil, fmt.Errorf("invalid export sink %s: expected %s://<namespace>/<event hub> or %s://<proxy>/<topic>", rawURL, SchemeEventHubs, SchemeKafkaHTTPS)
+	}
+	client := &http.Client{Timeout: sendTimeout}
+
+	switch u.Scheme {
+	case SchemeEventHubs:
+		if strings.Contains(path, "/") {
+			return nil, fmt.Errorf("invalid export sink %s: the path must be the event hub name", rawURL)
+		}
+		sink := &EventHubSink{url: fmt.Sprintf("https://%s/%s/messages", u.Host, path), client: client}
+		if connectionString := strings.TrimSpace(os.Getenv(ConnectionStringEnv)); connectionString != "" {
+			keyName, key, err := parseConnectionString(connectionString)
+			if err != nil {
+				return nil, err
+			}
+			sink.authorize = sasAuthorizer(fmt.Sprintf("https://%s/%s", u.Host, path), keyName, key)
+		} else if token != nil {
+			sink.authorize = func(ctx context.Context) (string, error) {
+				t, err := token(ctx)
+				return "Bearer " + t, err
+			}
+		} else {
+			return nil, fmt.Errorf("export to Event Hubs requires %s or the server's Azure credential", ConnectionStringEnv)
+		}
+		return sink, nil
+	case SchemeKafkaHTTPS, SchemeKafkaHTTP:
+		i := strings.LastIndex(path, "/")
+		base := &url.URL{Scheme: strings.TrimPrefix(u.Scheme, "kafka+"), Host: u.Host, Path: "/" + path[:i+1]}
+		sink := &KafkaRESTSink{url: base.String() + "topics/" + url.PathEscape(path[i+1:]), topic: path[i+1:], client: client}
+		if credentials := os.Getenv(CredentialsEnv); credentials != "" {
+			user, password, ok := strings.Cut(credentials, ":")
+			if !ok {
+				return nil, fmt.Errorf("%s must be user:password", CredentialsEnv)
+			}
+			sink.user, sink.password = user, password
+		}
+		return sink, nil
+	default:
+		return nil, fmt.Errorf("unsupported export sink scheme '%s': expected %s, %s or %s", u.Scheme, SchemeEventHubs, SchemeKafkaHTTPS, SchemeKafkaHTTP)
+	}
+}
+
+// EventHubSink sends batches of events to an event hub through its HTTPS send API. Each event is one
+// message with its type as the "type" application property.
+type EventHubSink struct {
+	url       string
+	client    *http.Client
+	authorize func(ctx context.Context) (string, error)
+}
+
+// Name returns the send URL of the event hub
+func (s *EventHubSink) Name() string {
+	return s.url
+}
+
+// Send posts the events as one batch
+func (s *EventHubSink) Send(ctx context.Context, events []Event) error {
+	type message struct {
+		Body           string            `json:"Body"`
+		UserProperties map[string]string `json:"UserProperties"`
+	}
+	messages := make([]message, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %v", event.ID, err)
+		}
+		messages = append(messages, message{Body: string(data), UserProperties: map[string]string{"type": event.Type}})
+	}
+	body, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %v", err)
+	}
+	authorization, err := s.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/vnd.microsoft.servicebus.json")
+	_, err = send(s.client, req)
+	return err
+}
+
+// KafkaRESTSink produces events to a Kafka topic through a Kafka REST proxy, keyed by event ID
+type KafkaRESTSink struct {
+	url            string
+	topic          string
+	user, password string
+	client         *http.Client
+}
+
+// Name returns the topic URL of the proxy
+func (s *KafkaRESTSink) Name() string {
+	return s.url
+}
+
+// Send produces the events as one request and fails when the proxy reports an error for any of them
+func (s *KafkaRESTSink) Send(ctx context.Context, events []Event) error {
+	type record struct {
+		Key   string `json:"key"`
+		Value Event  `json:"value"`
+	}
+	records := make([]record, 0, len(events))
+	for _, event := range events {
+		records = append(records, record{Key: event.ID, Value: event})
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+	data, err := send(s.client, req)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("failed to parse the Kafka REST proxy response: %v", err)
+	}
+	for i, offset := range response.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected event %s for topic %s: %s (error code %d)", events[i].ID, s.topic, offset.Error, *offset.ErrorCode)
+		}
+	}
+	return nil
+}
+
+// send sends a request and returns the response body, failing on any status that is not 2xx
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "AKS-MCP")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send events: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return nil, fmt.Errorf("sink returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the sink response: %v", err)
+	}
+	return data, nil
+}
+
+// parseConnectionString returns the shared access key name and key of an Event Hubs connection string
+func parseConnectionString(connectionString string) (string, string, error) {
+	var keyName, key string
+	for _, part := range strings.Split(connectionString, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch strings.TrimSpace(name) {
+		case "SharedAccessKeyName":
+			keyName = value
+		case "SharedAccessKey":
+			key = value
+		}
+	}
+	if keyName == "" || key == "" {
+		return "", "", fmt.Errorf("%s must contain SharedAccessKeyName and SharedAccessKey", ConnectionStringEnv)
+	}
+	return keyName, key, nil
+}
+
+// sasAuthorizer signs requests to resource with a shared access signature valid for sasTokenTTL
+func sasAuthorizer(resource, keyName, key string) func(ctx context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		return sasToken(resource, keyName, key, time.Now().Add(sasTokenTTL)), nil
+	}
+}
+
+// sasToken builds the SharedAccessSignature authorization of resource expiring at expiry
+func sasToken(resource, keyName, key string, expiry time.Time) string {
+	encoded := url.QueryEscape(resource)
+	expires := strconv.FormatInt(expiry.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(encoded + "\n" + expires))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s&skn=%s", encoded, url.QueryEscape(signature), expires, url.QueryEscape(keyName))
+}
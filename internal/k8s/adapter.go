@@ -5,6 +5,8 @@ package k8s
 
 import (
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/review"
 	"github.com/Azure/aks-mcp/internal/tools"
 	k8sconfig "github.com/Azure/mcp-kubernetes/pkg/config"
 	k8ssecurity "github.com/Azure/mcp-kubernetes/pkg/security"
@@ -20,16 +22,19 @@ func ConvertConfig(cfg *config.ConfigData) *k8sconfig.ConfigData {
 	k8sSecurityConfig.AccessLevel = k8ssecurity.AccessLevel(cfg.AccessLevel)
 
 	k8sCfg := &k8sconfig.ConfigData{
-		AdditionalTools:  cfg.AdditionalTools,
-		Timeout:          cfg.Timeout,
-		SecurityConfig:   k8sSecurityConfig,
-		Transport:        cfg.Transport,
-		Host:             cfg.Host,
-		Port:             cfg.Port,
-		AccessLevel:      cfg.AccessLevel,
-		AllowNamespaces:  cfg.AllowNamespaces,
-		OTLPEndpoint:     cfg.OTLPEndpoint,
-		TelemetryService: k8stelemetry.TelemetryInterface(cfg.TelemetryService),
+		AdditionalTools: cfg.AdditionalTools,
+		Timeout:         cfg.Timeout,
+		SecurityConfig:  k8sSecurityConfig,
+		Transport:       cfg.Transport,
+		Host:            cfg.Host,
+		Port:            cfg.Port,
+		AccessLevel:     cfg.AccessLevel,
+		AllowNamespaces: cfg.AllowNamespaces,
+		OTLPEndpoint:    cfg.OTLPEndpoint,
+	}
+	// A nil service must stay a nil interface, which mcp-kubernetes checks before tracking calls
+	if cfg.TelemetryService != nil {
+		k8sCfg.TelemetryService = k8stelemetry.TelemetryInterface(cfg.TelemetryService)
 	}
 
 	return k8sCfg
@@ -47,9 +52,35 @@ type executorAdapter struct {
 	k8sExecutor k8stools.CommandExecutor
 }
 
+// deferForReview validates a write command as if it were run, then defers it to the call's review plan with
+// the server-side dry run and live objects read through the wrapped executor
+func (a *executorAdapter) deferForReview(command string, cfg *config.ConfigData, k8sCfg *k8sconfig.ConfigData) error {
+	binary := review.Binary(command)
+	if err := k8ssecurity.NewValidator(k8sCfg.SecurityConfig).ValidateCommand(command, binary); err != nil {
+		return err
+	}
+	change, err := review.ClusterChange(command, func(readCommand string) (string, error) {
+		output, err := a.k8sExecutor.Execute(map[string]interface{}{"command": readCommand}, k8sCfg)
+		cfg.Explain.Record(explain.KindKubectl, "kubectl "+readCommand, err)
+		return output, err
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.Review.Defer(change)
+}
+
 // Execute adapts aks-mcp execution by converting its config
-// and delegating to the wrapped mcp-kubernetes executor.
+// and delegating to the wrapped mcp-kubernetes executor. In review mode
+// write commands are deferred to the call's review plan instead of run.
 func (a *executorAdapter) Execute(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
 	k8sCfg := ConvertConfig(cfg)
-	return a.k8sExecutor.Execute(params, k8sCfg)
+	if command, ok := params["command"].(string); ok && cfg.Review != nil && review.IsClusterWrite(command) {
+		return "", a.deferForReview(command, cfg, k8sCfg)
+	}
+	output, err := a.k8sExecutor.Execute(params, k8sCfg)
+	if command, ok := params["command"].(string); ok {
+		cfg.Explain.Record(explain.KindKubectl, "kubectl "+command, err)
+	}
+	return output, err
 }
@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// StartKubectl starts a long-running kubectl command such as a watch in the background and returns its
+// stdout, killing the process when ctx is done. wait blocks until kubectl has exited and returns its error
+// with the stderr output, or nil when it was stopped by ctx.
+func StartKubectl(ctx context.Context, args []string) (stdout io.Reader, wait func() error, err error) {
+	// #nosec G204: arguments are validated by the callers and passed without a shell
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err = cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	return stdout, func() error {
+		err := cmd.Wait()
+		if err != nil && ctx.Err() == nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%v: %s", err, msg)
+			}
+			return err
+		}
+		return nil
+	}, nil
+}
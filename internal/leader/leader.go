@@ -0,0 +1,123 @@
+// Package leader coordinates background subsystems across server replicas.
+// Tool serving scales horizontally, while background tasks registered with a Coordinator
+// only run on the replica that currently holds leadership.
+package leader
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Task is a background subsystem that runs while the replica is leader.
+// Run must return when ctx is cancelled (on leadership loss or shutdown).
+type Task struct {
+	Name string
+	Run  func(ctx context.Context)
+}
+
+// Elector acquires and holds leadership. Run blocks until ctx is cancelled, calling
+// onStarted with a context that is cancelled when leadership is lost, and onStopped
+// after leadership is lost.
+type Elector interface {
+	Run(ctx context.Context, onStarted func(ctx context.Context), onStopped func())
+	Identity() string
+}
+
+// Status reports the leadership state of the replica
+type Status struct {
+	Enabled  bool     `json:"leaderElection"`
+	Identity string   `json:"identity"`
+	Leader   bool     `json:"leader"`
+	Tasks    []string `json:"tasks"`
+}
+
+// Coordinator runs registered background tasks on the leader replica only
+type Coordinator struct {
+	mu      sync.Mutex
+	elector Elector
+	enabled bool
+	tasks   []Task
+	leading bool
+	wg      sync.WaitGroup
+}
+
+// NewCoordinator creates a coordinator. A nil elector means leader election is disabled
+// and the replica is always leader.
+func NewCoordinator(elector Elector) *Coordinator {
+	c := &Coordinator{elector: elector, enabled: elector != nil}
+	if elector == nil {
+		c.elector = alwaysLeader{}
+	}
+	return c
+}
+
+// Register adds a background task. Tasks must be registered before Start.
+func (c *Coordinator) Register(task Task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tasks = append(c.tasks, task)
+}
+
+// Start runs the elector until ctx is cancelled, starting the registered tasks whenever
+// this replica becomes leader and stopping them when leadership is lost.
+func (c *Coordinator) Start(ctx context.Context) {
+	c.elector.Run(ctx, c.startTasks, c.stopTasks)
+}
+
+// IsLeader reports whether this replica currently holds leadership
+func (c *Coordinator) IsLeader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leading
+}
+
+// Status returns the leadership state and registered task names
+func (c *Coordinator) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.tasks))
+	for _, task := range c.tasks {
+		names = append(names, task.Name)
+	}
+	return Status{Enabled: c.enabled, Identity: c.elector.Identity(), Leader: c.leading, Tasks: names}
+}
+
+// startTasks launches every registered task with the leadership context
+func (c *Coordinator) startTasks(ctx context.Context) {
+	c.mu.Lock()
+	c.leading = true
+	tasks := append([]Task(nil), c.tasks...)
+	c.mu.Unlock()
+
+	log.Printf("[LEADER] %s acquired leadership, starting %d background tasks", c.elector.Identity(), len(tasks))
+	for _, task := range tasks {
+		c.wg.Add(1)
+		go func(task Task) {
+			defer c.wg.Done()
+			task.Run(ctx)
+		}(task)
+	}
+}
+
+// stopTasks waits for the tasks to return after the leadership context is cancelled
+func (c *Coordinator) stopTasks() {
+	c.wg.Wait()
+	c.mu.Lock()
+	c.leading = false
+	c.mu.Unlock()
+	log.Printf("[LEADER] %s stopped leading, background tasks stopped", c.elector.Identity())
+}
+
+// alwaysLeader is the elector used when leader election is disabled (single replica)
+type alwaysLeader struct{}
+
+func (alwaysLeader) Run(ctx context.Context, onStarted func(ctx context.Context), onStopped func()) {
+	onStarted(ctx)
+	<-ctx.Done()
+	onStopped()
+}
+
+func (alwaysLeader) Identity() string {
+	return "standalone"
+}
@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// waitFor polls cond until it is true or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func TestCoordinatorWithoutElection(t *testing.T) {
+	coordinator := NewCoordinator(nil)
+	var running atomic.Int32
+	coordinator.Register(Task{Name: "scan", Run: func(ctx context.Context) {
+		running.Add(1)
+		<-ctx.Done()
+		running.Add(-1)
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		coordinator.Start(ctx)
+		close(done)
+	}()
+
+	waitFor(t, time.Second, func() bool { return running.Load() == 1 })
+	status := coordinator.Status()
+	if status.Enabled || !status.Leader || len(status.Tasks) != 1 || status.Tasks[0] != "scan" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	cancel()
+	<-done
+	if running.Load() != 0 {
+		t.Error("Expected task to stop on shutdown")
+	}
+	if coordinator.IsLeader() {
+		t.Error("Expected leadership to end on shutdown")
+	}
+}
+
+func TestLeaseElectorSingleLeader(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newCoordinator := func(identity string, counter *atomic.Int32) *Coordinator {
+		elector, err := NewLeaseElector(client, LeaseConfig{
+			Namespace:     "default",
+			Name:          "aks-mcp-leader",
+			Identity:      identity,
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create elector: %v", err)
+		}
+		c := NewCoordinator(elector)
+		c.Register(Task{Name: "scan", Run: func(ctx context.Context) {
+			counter.Add(1)
+			<-ctx.Done()
+		}})
+		return c
+	}
+
+	var runsA, runsB atomic.Int32
+	a := newCoordinator("replica-a", &runsA)
+	b := newCoordinator("replica-b", &runsB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Start(ctx)
+	waitFor(t, 3*time.Second, a.IsLeader)
+	go b.Start(ctx)
+
+	// The second replica must not run background tasks while the first holds the lease
+	time.Sleep(300 * time.Millisecond)
+	if b.IsLeader() || runsB.Load() != 0 {
+		t.Error("Expected only one replica to lead")
+	}
+	if runsA.Load() != 1 {
+		t.Errorf("Expected leader to run the task once, got %d", runsA.Load())
+	}
+	if status := b.Status(); !status.Enabled || status.Identity != "replica-b" {
+		t.Errorf("Unexpected follower status: %+v", status)
+	}
+}
+
+func TestNewLeaseElectorValidation(t *testing.T) {
+	if _, err := NewLeaseElector(fake.NewSimpleClientset(), LeaseConfig{Name: "lease"}); err == nil {
+		t.Error("Expected error without namespace")
+	}
+}
+
+func TestConfigMapStore(t *testing.T) {
+	st := NewConfigMapStore(fake.NewSimpleClientset(), "default", "aks-mcp-leader-state")
+	if _, err := st.Get("scanner-findings", "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	id := "/subscriptions/sub/resourcegroups/rg/providers/microsoft.containerservice/managedclusters/aks/cluster_failed"
+	if err := st.Put("scanner-findings", id, []byte(`{"id":"a"}`)); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := st.Put("scanner-inventory", "clusters", []byte(`{}`)); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if value, err := st.Get("scanner-findings", id); err != nil || string(value) != `{"id":"a"}` {
+		t.Errorf("Expected stored value, got %q (%v)", value, err)
+	}
+	records, err := st.List("scanner-findings")
+	if err != nil || len(records) != 1 || string(records[id]) != `{"id":"a"}` {
+		t.Errorf("Expected only the findings bucket, got %v (%v)", records, err)
+	}
+	if err := st.Delete("scanner-findings", id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := st.Get("scanner-findings", id); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected the key to be deleted, got %v", err)
+	}
+	if _, err := st.Get("scanner-inventory", "clusters"); err != nil {
+		t.Errorf("Expected other buckets to be preserved, got %v", err)
+	}
+}
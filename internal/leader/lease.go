@@ -0,0 +1,104 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Default lease timings, matching the Kubernetes controller defaults
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaseConfig configures Lease-based leader election
+type LeaseConfig struct {
+	Namespace     string
+	Name          string
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// leaseElector elects a leader using a coordination.k8s.io Lease
+type leaseElector struct {
+	config leaderelection.LeaderElectionConfig
+}
+
+// NewLeaseElector creates an elector backed by a Lease in the given namespace.
+// Zero timings use the defaults and an empty identity uses DefaultIdentity.
+func NewLeaseElector(client kubernetes.Interface, cfg LeaseConfig) (Elector, error) {
+	if cfg.Namespace == "" || cfg.Name == "" {
+		return nil, fmt.Errorf("leader election requires a lease namespace and name")
+	}
+	if cfg.Identity == "" {
+		cfg.Identity = DefaultIdentity()
+	}
+	if cfg.LeaseDuration == 0 {
+		cfg.LeaseDuration = DefaultLeaseDuration
+	}
+	if cfg.RenewDeadline == 0 {
+		cfg.RenewDeadline = DefaultRenewDeadline
+	}
+	if cfg.RetryPeriod == 0 {
+		cfg.RetryPeriod = DefaultRetryPeriod
+	}
+
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, cfg.Namespace, cfg.Name,
+		client.CoreV1(), client.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: cfg.Identity})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lease lock: %w", err)
+	}
+
+	return &leaseElector{config: leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            cfg.Name,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+	}}, nil
+}
+
+// Run campaigns for leadership until ctx is cancelled, re-campaigning after leadership is lost
+func (e *leaseElector) Run(ctx context.Context, onStarted func(ctx context.Context), onStopped func()) {
+	config := e.config
+	config.Callbacks = leaderelection.LeaderCallbacks{
+		OnStartedLeading: onStarted,
+		OnStoppedLeading: onStopped,
+	}
+	for ctx.Err() == nil {
+		elector, err := leaderelection.NewLeaderElector(config)
+		if err != nil {
+			// Configuration errors are not recoverable by retrying
+			log.Printf("[LEADER] Invalid leader election configuration: %v", err)
+			return
+		}
+		elector.Run(ctx)
+	}
+}
+
+// Identity returns the lock holder identity of this replica
+func (e *leaseElector) Identity() string {
+	return e.config.Lock.Identity()
+}
+
+// DefaultIdentity returns the pod name (POD_NAME) or the hostname, used as the lease holder identity
+func DefaultIdentity() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return fmt.Sprintf("aks-mcp-%d", os.Getpid())
+}
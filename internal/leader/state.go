@@ -0,0 +1,134 @@
+package leader
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// stateTimeout bounds each read or write of the shared state ConfigMap
+const stateTimeout = 10 * time.Second
+
+// configMapStore is a store.Store shared by every replica, kept in the data of a ConfigMap. The leader's
+// background subsystems write their results to it so that whichever replica serves a tool call or resource
+// read sees them. ConfigMaps are limited to 1 MiB, so it suits small results such as findings and the
+// cluster inventory rather than raw output.
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore creates a store shared by all replicas, backed by a ConfigMap created on first write
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) store.Store {
+	return &configMapStore{client: client, namespace: namespace, name: name}
+}
+
+// dataKey returns the ConfigMap data key of key in bucket. Keys are encoded because ConfigMap keys may
+// only hold alphanumerics, '-', '_' and '.'.
+func dataKey(bucket, key string) string {
+	return bucket + "." + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func (c *configMapStore) Get(bucket, key string) ([]byte, error) {
+	data, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := data[dataKey(bucket, key)]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return []byte(value), nil
+}
+
+func (c *configMapStore) Put(bucket, key string, value []byte) error {
+	return c.update(func(data map[string]string) { data[dataKey(bucket, key)] = string(value) })
+}
+
+func (c *configMapStore) Delete(bucket, key string) error {
+	return c.update(func(data map[string]string) { delete(data, dataKey(bucket, key)) })
+}
+
+func (c *configMapStore) List(bucket string) (map[string][]byte, error) {
+	data, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string][]byte)
+	for k, value := range data {
+		encoded, ok := strings.CutPrefix(k, bucket+".")
+		if !ok {
+			continue
+		}
+		key, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		records[string(key)] = []byte(value)
+	}
+	return records, nil
+}
+
+func (c *configMapStore) Close() error {
+	return nil
+}
+
+// read returns the ConfigMap data, empty when the ConfigMap does not exist yet
+func (c *configMapStore) read() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared state %s/%s: %w", c.namespace, c.name, err)
+	}
+	return cm.Data, nil
+}
+
+// update applies mutate to the ConfigMap data, retrying on write conflicts between replicas
+func (c *configMapStore) update(mutate func(map[string]string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	configMaps := c.client.CoreV1().ConfigMaps(c.namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, c.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace},
+				Data:       map[string]string{},
+			}
+			mutate(cm.Data)
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Another replica created it first; retry as an update
+				return apierrors.NewConflict(corev1.Resource("configmaps"), c.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		mutate(cm.Data)
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write shared state %s/%s: %w", c.namespace, c.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,446 @@
+// Package loadtest drives concurrent synthetic tool calls through a fully initialized server over one of its
+// transports, with every az CLI command answered by a mock executor, and reports call latency percentiles,
+// allocations and goroutine counts. It backs the loadtest developer subcommand and the transport benchmarks,
+// so transport and middleware changes can be checked for performance without Azure.
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/server"
+	"github.com/Azure/aks-mcp/internal/store"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Transports the harness can drive
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "streamable-http"
+)
+
+// Defaults of the synthetic calls
+const (
+	DefaultTool        = "az_aks_operations"
+	DefaultConcurrency = 10
+	DefaultCalls       = 1000
+	// DefaultOutput is what the mock executor answers every command with
+	DefaultOutput = `{"name": "loadtest", "provisioningState": "Succeeded"}`
+)
+
+// DefaultArguments are the arguments of the default tool, an az aks show answered by the mock executor
+func DefaultArguments() map[string]interface{} {
+	return map[string]interface{}{"operation": "show", "args": "--name loadtest --resource-group loadtest-rg"}
+}
+
+// goroutineSampleInterval is how often the goroutine count is sampled for its peak
+const goroutineSampleInterval = 5 * time.Millisecond
+
+// Options configures a load test
+type Options struct {
+	Transport string
+	Tool      string
+	Arguments map[string]interface{}
+	// Concurrency is the number of workers calling the tool at the same time. Over sse and streamable-http each
+	// worker has its own MCP session; over stdio they share the one session the transport carries.
+	Concurrency int
+	// Calls is the number of measured calls, after one warm-up call
+	Calls int
+	// Latency is how long the mock executor takes to answer each command
+	Latency time.Duration
+	// Output is what the mock executor answers every command with
+	Output string
+	// AccessLevel is the access level the server runs with
+	AccessLevel string
+}
+
+// withDefaults fills in the unset options and checks the others
+func (o Options) withDefaults() (Options, error) {
+	if o.Transport == "" {
+		o.Transport = TransportStdio
+	}
+	switch o.Transport {
+	case TransportStdio, TransportSSE, TransportStreamableHTTP:
+	default:
+		return o, fmt.Errorf("invalid transport %q (must be %s, %s or %s)", o.Transport, TransportStdio, TransportSSE, TransportStreamableHTTP)
+	}
+	if o.Tool == "" {
+		o.Tool = DefaultTool
+	}
+	if o.Tool == DefaultTool && o.Arguments == nil {
+		o.Arguments = DefaultArguments()
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.Calls == 0 {
+		o.Calls = DefaultCalls
+	}
+	if o.Concurrency < 0 || o.Calls < 0 || o.Latency < 0 {
+		return o, fmt.Errorf("concurrency, calls and latency must not be negative")
+	}
+	if o.Output == "" {
+		o.Output = DefaultOutput
+	}
+	if o.AccessLevel == "" {
+		o.AccessLevel = "readonly"
+	}
+	return o, nil
+}
+
+// LatencyStats are the percentiles of the call latencies
+type LatencyStats struct {
+	Min  time.Duration `json:"min"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+	Mean time.Duration `json:"mean"`
+}
+
+// Report is the result of a load test. Allocations are counted for the whole process, so they include the
+// client side of the calls.
+type Report struct {
+	Transport   string        `json:"transport"`
+	Tool        string        `json:"tool"`
+	Concurrency int           `json:"concurrency"`
+	Calls       int           `json:"calls"`
+	Errors      int           `json:"errors"`
+	FirstError  string        `json:"firstError,omitempty"`
+	Commands    int64         `json:"commands"`
+	Duration    time.Duration `json:"duration"`
+	// Throughput is the number of calls completed per second
+	Throughput    float64      `json:"throughput"`
+	Latency       LatencyStats `json:"latency"`
+	AllocsPerCall float64      `json:"allocsPerCall"`
+	BytesPerCall  float64      `json:"bytesPerCall"`
+	// GoroutinesBefore and GoroutinesAfter are counted with the clients connected, before and after the calls
+	GoroutinesBefore int `json:"goroutinesBefore"`
+	GoroutinesPeak   int `json:"goroutinesPeak"`
+	GoroutinesAfter  int `json:"goroutinesAfter"`
+}
+
+// Write prints the report as text
+func (r Report) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `transport:   %s
+tool:        %s
+calls:       %d (%d errors) with concurrency %d in %s, %.1f calls/s
+commands:    %d answered by the mock executor
+latency:     min %s, p50 %s, p95 %s, p99 %s, max %s, mean %s
+allocations: %.0f allocs and %.0f bytes per call
+goroutines:  %d before, %d peak, %d after
+`,
+		r.Transport, r.Tool, r.Calls, r.Errors, r.Concurrency, r.Duration.Round(time.Millisecond), r.Throughput,
+		r.Commands,
+		r.Latency.Min, r.Latency.P50, r.Latency.P95, r.Latency.P99, r.Latency.Max, r.Latency.Mean,
+		r.AllocsPerCall, r.BytesPerCall,
+		r.GoroutinesBefore, r.GoroutinesPeak, r.GoroutinesAfter)
+	if err == nil && r.FirstError != "" {
+		_, err = fmt.Fprintf(w, "first error: %s\n", r.FirstError)
+	}
+	return err
+}
+
+// MockExecutor answers every az CLI command with a fixed output after a fixed latency. It serves both as the
+// command interceptor of the tools and as the az CLI process used for the login check.
+type MockExecutor struct {
+	Output  string
+	Latency time.Duration
+	count   atomic.Int64
+}
+
+// Run answers a command, implementing azcli.Proc
+func (m *MockExecutor) Run(_ string) (string, error) {
+	m.count.Add(1)
+	if m.Latency > 0 {
+		time.Sleep(m.Latency)
+	}
+	return m.Output, nil
+}
+
+// Intercept answers a command without running it, implementing command.Interceptor
+func (m *MockExecutor) Intercept(cmd string) (string, bool, error) {
+	output, err := m.Run(cmd)
+	return output, true, err
+}
+
+// Observe implements command.Interceptor; every command is intercepted, so none is observed
+func (m *MockExecutor) Observe(_, _ string, _ error) {}
+
+// Commands returns the number of commands answered
+func (m *MockExecutor) Commands() int64 {
+	return m.count.Load()
+}
+
+// Run starts a server with the mock executor, connects clients over the transport and measures the calls.
+// The mock executor is installed as the process-wide command interceptor for the duration of the test.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return Report{}, err
+	}
+
+	mock := &MockExecutor{Output: opts.Output, Latency: opts.Latency}
+	command.SetInterceptor(mock)
+	defer command.SetInterceptor(nil)
+
+	cfg := config.NewConfig()
+	cfg.Transport = opts.Transport
+	cfg.AccessLevel = opts.AccessLevel
+	cfg.StateStore = store.KindMemory
+	// Cached az output would answer most calls without reaching the executor
+	cfg.CacheTimeout = 0
+	service := server.NewService(cfg, server.WithAzCliProcFactory(func(int) azcli.Proc { return mock }))
+	if err := service.Initialize(); err != nil {
+		return Report{}, fmt.Errorf("failed to initialize the server: %w", err)
+	}
+	defer service.Shutdown()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	clients, closeTransport, err := connect(ctx, service, opts)
+	if err != nil {
+		return Report{}, err
+	}
+	defer closeTransport()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = opts.Tool
+	request.Params.Arguments = opts.Arguments
+	if _, err := callTool(ctx, clients[0], request); err != nil {
+		return Report{}, fmt.Errorf("warm-up call of %s failed: %w", opts.Tool, err)
+	}
+
+	report := Report{Transport: opts.Transport, Tool: opts.Tool, Concurrency: opts.Concurrency, Calls: opts.Calls}
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	report.GoroutinesBefore = runtime.NumGoroutine()
+	commandsBefore := mock.Commands()
+
+	peak := atomic.Int64{}
+	peak.Store(int64(report.GoroutinesBefore))
+	stopSampling := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(goroutineSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopSampling:
+				return
+			case <-ticker.C:
+				if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+					peak.Store(n)
+				}
+			}
+		}
+	}()
+
+	latencies := make([]time.Duration, opts.Calls)
+	var errMu sync.Mutex
+	calls := make(chan int)
+	var wg sync.WaitGroup
+	started := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		c := clients[w%len(clients)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range calls {
+				callStarted := time.Now()
+				_, err := callTool(ctx, c, request)
+				latencies[i] = time.Since(callStarted)
+				if err != nil {
+					errMu.Lock()
+					if report.Errors == 0 {
+						report.FirstError = err.Error()
+					}
+					report.Errors++
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < opts.Calls; i++ {
+		calls <- i
+	}
+	close(calls)
+	wg.Wait()
+	report.Duration = time.Since(started)
+	close(stopSampling)
+	<-sampled
+
+	runtime.ReadMemStats(&after)
+	report.GoroutinesPeak = int(peak.Load())
+	report.GoroutinesAfter = runtime.NumGoroutine()
+	report.Commands = mock.Commands() - commandsBefore
+	if opts.Calls > 0 {
+		report.AllocsPerCall = float64(after.Mallocs-before.Mallocs) / float64(opts.Calls)
+		report.BytesPerCall = float64(after.TotalAlloc-before.TotalAlloc) / float64(opts.Calls)
+		report.Throughput = float64(opts.Calls) / report.Duration.Seconds()
+	}
+	report.Latency = latencyStats(latencies)
+	return report, nil
+}
+
+// callTool calls the tool and turns error results into errors
+func callTool(ctx context.Context, c *client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				return result, fmt.Errorf("tool returned an error: %s", text.Text)
+			}
+		}
+		return result, fmt.Errorf("tool returned an error")
+	}
+	return result, nil
+}
+
+// connect serves the transport and returns initialized clients, one per worker for the HTTP transports and a
+// single one for stdio, with a function closing them and the server side of the transport
+func connect(ctx context.Context, service *server.Service, opts Options) ([]*client.Client, func(), error) {
+	var clients []*client.Client
+	var closers []func()
+	closeAll := func() {
+		for _, c := range clients {
+			_ = c.Close()
+		}
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	var newClient func() (*client.Client, error)
+	switch opts.Transport {
+	case TransportStdio:
+		serverIn, clientOut := io.Pipe()
+		clientIn, serverOut := io.Pipe()
+		served := make(chan struct{})
+		go func() {
+			defer close(served)
+			_ = service.ServeStdio(ctx, serverIn, serverOut)
+		}()
+		// Closing the client ends the server's input, and the server's output is closed once it has stopped
+		closers = append(closers, func() {
+			<-served
+			_ = serverOut.Close()
+		})
+		stdio := transport.NewIO(clientIn, clientOut, io.NopCloser(strings.NewReader("")))
+		newClient = func() (*client.Client, error) { return client.NewClient(stdio), nil }
+	case TransportSSE, TransportStreamableHTTP:
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		httpServer, err := service.HTTPServer()
+		if err != nil {
+			_ = listener.Close()
+			return nil, nil, err
+		}
+		go func() { _ = httpServer.Serve(listener) }()
+		closers = append(closers, func() { _ = httpServer.Close() })
+		baseURL := "http://" + listener.Addr().String()
+		newClient = func() (*client.Client, error) {
+			if opts.Transport == TransportSSE {
+				return client.NewSSEMCPClient(baseURL + "/sse")
+			}
+			return client.NewStreamableHttpClient(baseURL + "/mcp")
+		}
+	}
+
+	sessions := opts.Concurrency
+	if opts.Transport == TransportStdio || sessions < 1 {
+		sessions = 1
+	}
+	for i := 0; i < sessions; i++ {
+		c, err := newClient()
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to create %s client: %w", opts.Transport, err)
+		}
+		clients = append(clients, c)
+		if err := initialize(ctx, c); err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to connect over %s: %w", opts.Transport, err)
+		}
+	}
+	return clients, closeAll, nil
+}
+
+// initialize starts a client and performs the MCP handshake
+func initialize(ctx context.Context, c *client.Client) error {
+	if err := c.Start(ctx); err != nil {
+		return err
+	}
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "aks-mcp-loadtest", Version: "1.0.0"}
+	_, err := c.Initialize(ctx, request)
+	return err
+}
+
+// latencyStats returns the percentiles of the latencies, using the nearest-rank method
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		rank := int(float64(len(sorted))*p+0.999999) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	}
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	return LatencyStats{
+		Min:  sorted[0],
+		P50:  percentile(0.50),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  sorted[len(sorted)-1],
+		Mean: total / time.Duration(len(sorted)),
+	}
+}
+
+var (
+	_ azcli.Proc          = (*MockExecutor)(nil)
+	_ command.Interceptor = (*MockExecutor)(nil)
+)
+
+// ParseArguments parses the JSON object of tool arguments given on the command line
+func ParseArguments(raw string) (map[string]interface{}, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var arguments map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+		return nil, fmt.Errorf("invalid tool arguments, expected a JSON object: %w", err)
+	}
+	return arguments, nil
+}
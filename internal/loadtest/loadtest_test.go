@@ -0,0 +1,94 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRun tests driving synthetic calls over every transport
+func TestRun(t *testing.T) {
+	for _, transport := range []string{TransportStdio, TransportSSE, TransportStreamableHTTP} {
+		t.Run(transport, func(t *testing.T) {
+			report, err := Run(context.Background(), Options{Transport: transport, Concurrency: 4, Calls: 40, Latency: time.Millisecond})
+			if err != nil {
+				t.Fatalf("Load test failed: %v", err)
+			}
+			if report.Errors != 0 {
+				t.Fatalf("Expected no errors, got %d: %s", report.Errors, report.FirstError)
+			}
+			if report.Commands < int64(report.Calls) {
+				t.Errorf("Expected every call to reach the mock executor, got %d commands for %d calls", report.Commands, report.Calls)
+			}
+			if report.Latency.P50 < time.Millisecond || report.Latency.P50 > report.Latency.P95 || report.Latency.P95 > report.Latency.Max {
+				t.Errorf("Unexpected latencies %+v", report.Latency)
+			}
+			if report.AllocsPerCall <= 0 || report.GoroutinesPeak < report.GoroutinesBefore {
+				t.Errorf("Unexpected allocation and goroutine stats %+v", report)
+			}
+		})
+	}
+}
+
+// TestRunCountsErrors tests that error results are counted rather than failing the run
+func TestRunCountsErrors(t *testing.T) {
+	arguments := map[string]interface{}{"operation": "delete", "args": "--name loadtest --resource-group loadtest-rg"}
+	if _, err := Run(context.Background(), Options{Tool: DefaultTool, Arguments: arguments, Calls: 5}); err == nil {
+		t.Error("Expected the warm-up call of a readonly-forbidden operation to fail")
+	}
+	if _, err := Run(context.Background(), Options{Transport: "grpc"}); err == nil {
+		t.Error("Expected an unknown transport to be rejected")
+	}
+}
+
+// TestLatencyStats tests the nearest-rank percentiles
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := latencyStats(latencies)
+	want := LatencyStats{Min: time.Millisecond, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond,
+		Max: 100 * time.Millisecond, Mean: 50500 * time.Microsecond}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	if latencyStats(nil) != (LatencyStats{}) {
+		t.Error("Expected empty stats without calls")
+	}
+}
+
+// TestReportWrite tests the text report
+func TestReportWrite(t *testing.T) {
+	var buf bytes.Buffer
+	report := Report{Transport: TransportSSE, Tool: DefaultTool, Calls: 10, Errors: 1, FirstError: "boom", Latency: LatencyStats{P95: 3 * time.Millisecond}}
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for _, want := range []string{"transport:   sse", "10 (1 errors)", "p95 3ms", "first error: boom"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in %s", want, buf.String())
+		}
+	}
+}
+
+// BenchmarkToolCall measures one synthetic call per iteration over each transport
+func BenchmarkToolCall(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	for _, transport := range []string{TransportStdio, TransportSSE, TransportStreamableHTTP} {
+		b.Run(transport, func(b *testing.B) {
+			report, err := Run(context.Background(), Options{Transport: transport, Concurrency: 1, Calls: b.N})
+			if err != nil {
+				b.Fatalf("Load test failed: %v", err)
+			}
+			b.ReportMetric(float64(report.Latency.P95.Microseconds()), "p95-µs")
+			b.ReportMetric(report.AllocsPerCall, "allocs/call")
+		})
+	}
+}
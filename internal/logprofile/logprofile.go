@@ -0,0 +1,263 @@
+// Package logprofile defines named projection profiles for control plane logs. A profile selects the
+// columns a query returns and parses each log line into structured fields, so teams can read the same
+// logs in the shape their runbooks and dashboards expect.
+package logprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Klog fields added to each row by profiles that parse klog lines
+const (
+	FieldKlogSeverity = "KlogSeverity"
+	FieldKlogTime     = "KlogTime"
+	FieldKlogThread   = "KlogThread"
+	FieldKlogSource   = "KlogSource"
+	FieldKlogMessage  = "KlogMessage"
+)
+
+// klogLine matches the klog header, e.g. "I0102 15:04:05.123456       1 controller.go:42] message"
+var klogLine = regexp.MustCompile(`^([IWEF])(\d{4} \d{2}:\d{2}:\d{2}\.\d+)\s+(\d+)\s+([^\]]+)\]\s?(.*)$`)
+
+// klogSeverities names the klog severity letters
+var klogSeverities = map[string]string{"I": "info", "W": "warning", "E": "error", "F": "fatal"}
+
+// identifierPattern matches profile names, KQL column names and extracted field names. Only names matching
+// it are put into queries.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]{0,63}$`)
+
+// columnPattern matches the KQL column names a profile may project
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Extract is a parse rule adding a field to each row whose log line matches its pattern
+type Extract struct {
+	// Field is the name of the added field
+	Field string `json:"field"`
+	// Pattern is a Go regular expression whose first capture group is the value of the field
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// Profile is a named projection of control plane logs
+type Profile struct {
+	// Name selects the profile with the profile parameter of control_plane_logs
+	Name string `json:"name"`
+	// Description tells callers what the profile is for
+	Description string `json:"description,omitempty"`
+	// Categories are the log categories the profile applies to (empty means every category with log lines)
+	Categories []string `json:"categories,omitempty"`
+	// Columns are the table columns to project (empty keeps the default projection of the category)
+	Columns []string `json:"columns,omitempty"`
+	// Klog parses the klog header of each log line into the Klog fields
+	Klog bool `json:"klog,omitempty"`
+	// Extract adds a field per rule from each log line
+	Extract []*Extract `json:"extract,omitempty"`
+}
+
+// Set holds the profiles a server offers. A nil set offers the builtin profiles only.
+type Set struct {
+	profiles map[string]*Profile
+}
+
+// klogCategories are the control plane components that log in klog format
+var klogCategories = []string{
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"cluster-autoscaler",
+	"cloud-controller-manager",
+	"csi-azuredisk-controller",
+	"csi-azurefile-controller",
+	"csi-snapshot-controller",
+}
+
+// Builtin returns the profiles every server offers
+func Builtin() []*Profile {
+	return []*Profile{
+		{
+			Name:        "klog",
+			Description: "Time and the klog severity, time, thread, source file and message of each line",
+			Categories:  klogCategories,
+			Columns:     []string{"TimeGenerated"},
+			Klog:        true,
+		},
+	}
+}
+
+// Load reads a profiles file of the form {"profiles": [{"name": ..., "columns": [...], "klog": ..., "extract": [{"field": ..., "pattern": ...}]}]}
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log profiles file: %w", err)
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log profiles file %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Parse parses and validates the contents of a profiles file. The builtin profiles are added to the set.
+func Parse(data []byte) (*Set, error) {
+	var file struct {
+		Profiles []*Profile `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Profiles) == 0 {
+		return nil, fmt.Errorf("no profiles defined")
+	}
+	set := &Set{profiles: make(map[string]*Profile, len(file.Profiles))}
+	for _, profile := range Builtin() {
+		set.profiles[profile.Name] = profile
+	}
+	for i, profile := range file.Profiles {
+		if err := profile.validate(); err != nil {
+			if profile.Name == "" {
+				return nil, fmt.Errorf("profile %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("profile '%s': %w", profile.Name, err)
+		}
+		if _, exists := set.profiles[profile.Name]; exists {
+			return nil, fmt.Errorf("duplicate profile name '%s'", profile.Name)
+		}
+		set.profiles[profile.Name] = profile
+	}
+	return set, nil
+}
+
+// validate checks that the names of a profile are safe to put into queries and compiles its parse rules
+func (p *Profile) validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if !identifierPattern.MatchString(p.Name) {
+		return fmt.Errorf("name must be letters, digits, underscores or hyphens, starting with a letter")
+	}
+	for i, category := range p.Categories {
+		p.Categories[i] = strings.ToLower(strings.TrimSpace(category))
+	}
+	for _, column := range p.Columns {
+		if !columnPattern.MatchString(column) {
+			return fmt.Errorf("invalid column '%s': expected a KQL column name", column)
+		}
+	}
+	fields := map[string]bool{}
+	for _, extract := range p.Extract {
+		if !columnPattern.MatchString(extract.Field) {
+			return fmt.Errorf("invalid extract field '%s': expected letters, digits or underscores", extract.Field)
+		}
+		if fields[extract.Field] {
+			return fmt.Errorf("field '%s' is extracted twice", extract.Field)
+		}
+		fields[extract.Field] = true
+		re, err := regexp.Compile(extract.Pattern)
+		if err != nil {
+			return fmt.Errorf("field '%s': invalid pattern: %w", extract.Field, err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("field '%s': pattern has no capture group", extract.Field)
+		}
+		extract.re = re
+	}
+	if len(p.Columns) == 0 && !p.Parses() {
+		return fmt.Errorf("profile neither projects columns nor parses log lines")
+	}
+	return nil
+}
+
+// Get returns the profile with the given name, listing the available profiles when there is none
+func (s *Set) Get(name string) (*Profile, error) {
+	for _, profile := range s.Profiles() {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown log profile '%s'. Available profiles: %s", name, strings.Join(s.Names(), ", "))
+}
+
+// Profiles returns the profiles of the set sorted by name
+func (s *Set) Profiles() []*Profile {
+	if s == nil {
+		return Builtin()
+	}
+	profiles := make([]*Profile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// Names returns the names of the profiles of the set
+func (s *Set) Names() []string {
+	var names []string
+	for _, profile := range s.Profiles() {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// AppliesTo reports whether the profile can be used with a log category
+func (p *Profile) AppliesTo(category string) bool {
+	return len(p.Categories) == 0 || slices.Contains(p.Categories, category)
+}
+
+// Parses reports whether the profile parses log lines, so queries must return the message column
+func (p *Profile) Parses() bool {
+	return p.Klog || len(p.Extract) > 0
+}
+
+// Apply parses the message column of each row of an az monitor log-analytics query result into the fields
+// of the profile. The message column is dropped when the profile projects columns without it, as it was
+// only queried to be parsed. Results that are not rows are returned unchanged.
+func (p *Profile) Apply(result, messageColumn string) (string, error) {
+	if !p.Parses() || messageColumn == "" {
+		return result, nil
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &rows); err != nil {
+		return result, nil
+	}
+	dropMessage := len(p.Columns) > 0 && !slices.Contains(p.Columns, messageColumn)
+	for _, row := range rows {
+		message, _ := row[messageColumn].(string)
+		if p.Klog {
+			parseKlog(row, message)
+		}
+		for _, extract := range p.Extract {
+			if match := extract.re.FindStringSubmatch(message); match != nil {
+				row[extract.Field] = match[1]
+			}
+		}
+		if dropMessage {
+			delete(row, messageColumn)
+		}
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal log rows: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseKlog adds the klog fields of a log line to a row. Lines without a klog header, such as continuation
+// lines, keep the whole line as the message.
+func parseKlog(row map[string]interface{}, line string) {
+	match := klogLine.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		row[FieldKlogMessage] = line
+		return
+	}
+	row[FieldKlogSeverity] = klogSeverities[match[1]]
+	row[FieldKlogTime] = match[2]
+	row[FieldKlogThread] = match[3]
+	row[FieldKlogSource] = match[4]
+	row[FieldKlogMessage] = match[5]
+}
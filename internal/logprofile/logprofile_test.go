@@ -0,0 +1,105 @@
+package logprofile
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	set, err := Parse([]byte(`{"profiles": [
+		{"name": "scheduling", "categories": ["Kube-Scheduler"], "columns": ["TimeGenerated", "Level"],
+		 "extract": [{"field": "Pod", "pattern": "pod=\"?([^\" ]+)"}]},
+		{"name": "lines", "columns": ["TimeGenerated", "Message"]}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := strings.Join(set.Names(), ","); got != "klog,lines,scheduling" {
+		t.Errorf("Expected the builtin and file profiles, got %s", got)
+	}
+	scheduling, err := set.Get("scheduling")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !scheduling.AppliesTo("kube-scheduler") || scheduling.AppliesTo("kube-apiserver") || !scheduling.Parses() {
+		t.Errorf("Unexpected profile %+v", scheduling)
+	}
+	if _, err := set.Get("other"); err == nil || !strings.Contains(err.Error(), "klog, lines, scheduling") {
+		t.Errorf("Expected an unknown profile to list the profiles, got %v", err)
+	}
+
+	var builtinOnly *Set
+	if _, err := builtinOnly.Get("klog"); err != nil {
+		t.Errorf("Expected a nil set to offer the builtin profiles, got %v", err)
+	}
+
+	for name, data := range map[string]string{
+		"no profiles":    `{"profiles": []}`,
+		"invalid name":   `{"profiles": [{"name": "a b", "columns": ["TimeGenerated"]}]}`,
+		"builtin name":   `{"profiles": [{"name": "klog", "columns": ["TimeGenerated"]}]}`,
+		"duplicate":      `{"profiles": [{"name": "a", "columns": ["TimeGenerated"]}, {"name": "a", "klog": true}]}`,
+		"column":         `{"profiles": [{"name": "a", "columns": ["TimeGenerated | take 1"]}]}`,
+		"field":          `{"profiles": [{"name": "a", "extract": [{"field": "a-b", "pattern": "(x)"}]}]}`,
+		"pattern":        `{"profiles": [{"name": "a", "extract": [{"field": "A", "pattern": "(x"}]}]}`,
+		"no group":       `{"profiles": [{"name": "a", "extract": [{"field": "A", "pattern": "x"}]}]}`,
+		"twice":          `{"profiles": [{"name": "a", "extract": [{"field": "A", "pattern": "(x)"}, {"field": "A", "pattern": "(y)"}]}]}`,
+		"nothing to do":  `{"profiles": [{"name": "a"}]}`,
+		"invalid json":   `{"profiles": `,
+		"missing fields": `{}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	result := `[
+		{"TimeGenerated": "2024-01-01T00:00:00Z", "Message": "E0101 00:00:00.123456       1 scheduler.go:42] Error scheduling pod=\"default/web-0\" err=\"no nodes\""},
+		{"TimeGenerated": "2024-01-01T00:00:01Z", "Message": "\tgoroutine 1 [running]:"}]`
+
+	builtin, err := (*Set)(nil).Get("klog")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	applied, err := builtin.Apply(result, "Message")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(applied), &rows); err != nil {
+		t.Fatalf("Failed to decode rows: %v", err)
+	}
+	first := rows[0]
+	if first[FieldKlogSeverity] != "error" || first[FieldKlogTime] != "0101 00:00:00.123456" || first[FieldKlogThread] != "1" ||
+		first[FieldKlogSource] != "scheduler.go:42" || !strings.HasPrefix(first[FieldKlogMessage].(string), "Error scheduling") {
+		t.Errorf("Unexpected klog fields %v", first)
+	}
+	if _, ok := first["Message"]; ok {
+		t.Errorf("Expected the message column to be dropped, got %v", first)
+	}
+	if rows[1][FieldKlogMessage] != "\tgoroutine 1 [running]:" || rows[1][FieldKlogSeverity] != nil {
+		t.Errorf("Expected a line without a klog header to be kept as the message, got %v", rows[1])
+	}
+
+	set, err := Parse([]byte(`{"profiles": [{"name": "pods", "extract": [{"field": "Pod", "pattern": "pod=\"?([^\" ]+)"}]}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	pods, _ := set.Get("pods")
+	applied, err = pods.Apply(result, "Message")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	rows = nil
+	if err := json.Unmarshal([]byte(applied), &rows); err != nil {
+		t.Fatalf("Failed to decode rows: %v", err)
+	}
+	if rows[0]["Pod"] != "default/web-0" || rows[0]["Message"] == nil || rows[1]["Pod"] != nil {
+		t.Errorf("Expected the pod to be extracted and the default projection kept, got %v", rows)
+	}
+
+	if unchanged, _ := pods.Apply(`{"error": "x"}`, "Message"); unchanged != `{"error": "x"}` {
+		t.Errorf("Expected a result without rows to be unchanged, got %s", unchanged)
+	}
+}
@@ -0,0 +1,169 @@
+// Package notes keeps free-form operator notes per cluster, such as "this cluster is canary" or
+// "do not scale pool X", in the state store so they survive restarts and are shown with cluster overviews.
+package notes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// Bucket is the state store bucket notes are kept in
+const Bucket = "notes"
+
+// Limits on the notes kept per cluster
+const (
+	MaxNoteLength      = 1000
+	MaxNotesPerCluster = 50
+)
+
+// ErrNotFound is returned for unknown notes and notes of another cluster
+var ErrNotFound = errors.New("note not found")
+
+// Note is an operator note attached to a cluster
+type Note struct {
+	ID string `json:"id"`
+	// ClusterID is the lowercase resource ID of the cluster, see ClusterID
+	ClusterID string    `json:"clusterId"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// String formats the note for overviews
+func (n Note) String() string {
+	by := n.Created.Format("2006-01-02")
+	if n.Author != "" {
+		by = n.Author + ", " + by
+	}
+	return fmt.Sprintf("%s (%s)", n.Text, by)
+}
+
+// Book stores notes
+type Book struct {
+	mu   sync.Mutex
+	repo *store.Repository[Note]
+	now  func() time.Time
+}
+
+// NewBook creates a book whose notes are kept in the state store. A nil store keeps notes in memory.
+func NewBook(s store.Store) *Book {
+	if s == nil {
+		s = store.NewMemoryStore()
+	}
+	return &Book{repo: store.NewRepository[Note](s, Bucket), now: time.Now}
+}
+
+// ClusterID returns the key notes of a cluster are stored under. Resource IDs are case insensitive.
+func ClusterID(subscriptionID, resourceGroup, clusterName string) string {
+	return strings.ToLower(common.ClusterResourceID(subscriptionID, resourceGroup, clusterName))
+}
+
+// Add attaches a note to a cluster
+func (b *Book) Add(clusterID, text, author string) (Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Note{}, fmt.Errorf("note text is empty")
+	}
+	if len(text) > MaxNoteLength {
+		return Note{}, fmt.Errorf("note is %d characters; at most %d are accepted", len(text), MaxNoteLength)
+	}
+	id, err := newID()
+	if err != nil {
+		return Note{}, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	existing, err := b.listLocked(clusterID)
+	if err != nil {
+		return Note{}, err
+	}
+	if len(existing) >= MaxNotesPerCluster {
+		return Note{}, fmt.Errorf("the cluster already has %d notes; delete one first", MaxNotesPerCluster)
+	}
+	note := Note{ID: id, ClusterID: strings.ToLower(clusterID), Text: text, Author: strings.TrimSpace(author), Created: b.now().UTC()}
+	if err := b.repo.Save(id, note); err != nil {
+		return Note{}, fmt.Errorf("failed to save note: %w", err)
+	}
+	return note, nil
+}
+
+// List returns the notes of a cluster, oldest first
+func (b *Book) List(clusterID string) ([]Note, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.listLocked(clusterID)
+}
+
+// Delete removes a note of a cluster
+func (b *Book) Delete(clusterID, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	note, err := b.repo.Load(id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && note.ClusterID != strings.ToLower(clusterID)) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return b.repo.Delete(id)
+}
+
+// ByCluster returns every note keyed by cluster ID, oldest first
+func (b *Book) ByCluster() (map[string][]Note, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	all, err := b.repo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+	sortNotes(all)
+	byCluster := map[string][]Note{}
+	for _, note := range all {
+		byCluster[note.ClusterID] = append(byCluster[note.ClusterID], note)
+	}
+	return byCluster, nil
+}
+
+func (b *Book) listLocked(clusterID string) ([]Note, error) {
+	all, err := b.repo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+	notes := []Note{}
+	for _, note := range all {
+		if note.ClusterID == strings.ToLower(clusterID) {
+			notes = append(notes, note)
+		}
+	}
+	sortNotes(notes)
+	return notes, nil
+}
+
+// sortNotes orders notes oldest first
+func sortNotes(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].Created.Equal(notes[j].Created) {
+			return notes[i].Created.Before(notes[j].Created)
+		}
+		return notes[i].ID < notes[j].ID
+	})
+}
+
+// newID returns a random note ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,138 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// fakeARM records the paths read and fails for clusters the caller cannot read
+type fakeARM struct {
+	paths []string
+	err   error
+}
+
+func (f *fakeARM) CallARM(_ context.Context, _, path string) ([]byte, error) {
+	f.paths = append(f.paths, path)
+	return []byte(`{}`), f.err
+}
+
+func TestBook(t *testing.T) {
+	st := store.NewMemoryStore()
+	book := NewBook(st)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	book.now = func() time.Time { now = now.Add(time.Minute); return now }
+	canary := ClusterID("sub", "rg", "canary")
+
+	first, err := book.Add(canary, "  this cluster is canary ", "platform")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := book.Add(strings.ToUpper(canary), "do not scale pool gpu", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := book.Add(ClusterID("sub", "rg", "prod"), "prod", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := book.Add(canary, " ", ""); err == nil {
+		t.Error("Expected an empty note to be rejected")
+	}
+	if _, err := book.Add(canary, strings.Repeat("x", MaxNoteLength+1), ""); err == nil {
+		t.Error("Expected a long note to be rejected")
+	}
+
+	// A restarted server reads the notes persisted by the previous process
+	restarted := NewBook(st)
+	listed, err := restarted.List(canary)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Text != "this cluster is canary" || listed[1].Text != "do not scale pool gpu" {
+		t.Fatalf("Expected both canary notes oldest first, got %+v", listed)
+	}
+	if listed[0].String() != "this cluster is canary (platform, 2025-01-02)" {
+		t.Errorf("Unexpected note format %q", listed[0].String())
+	}
+
+	if err := restarted.Delete(ClusterID("sub", "rg", "prod"), first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another cluster's note not to be deleted, got %v", err)
+	}
+	if err := restarted.Delete(canary, first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	byCluster, err := restarted.ByCluster()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(byCluster) != 2 || len(byCluster[canary]) != 1 {
+		t.Errorf("Unexpected notes by cluster %+v", byCluster)
+	}
+}
+
+func TestNotesPerClusterLimit(t *testing.T) {
+	book := NewBook(nil)
+	cluster := ClusterID("sub", "rg", "aks")
+	for i := 0; i < MaxNotesPerCluster; i++ {
+		if _, err := book.Add(cluster, fmt.Sprintf("note %d", i), ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := book.Add(cluster, "one too many", ""); err == nil {
+		t.Error("Expected notes above the limit to be rejected")
+	}
+}
+
+func TestHandleClusterNotes(t *testing.T) {
+	book := NewBook(nil)
+	api := &fakeARM{}
+	cfg := config.NewConfig()
+	cfg.AccessLevel = "readwrite"
+	params := map[string]interface{}{"operation": OpAdd, "subscription_id": "sub", "resource_group": "rg", "cluster_name": "aks",
+		"note": "canary", "author": "sre"}
+
+	output, err := HandleClusterNotes(params, book, api, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(result.Notes) != 1 || result.Notes[0].Author != "sre" || !strings.HasPrefix(result.Message, "added note ") {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if !strings.HasPrefix(api.paths[0], "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks?") {
+		t.Errorf("Expected the cluster to be read first, got %v", api.paths)
+	}
+
+	readonly := config.NewConfig()
+	readonly.AccessLevel = "readonly"
+	params["operation"] = OpDelete
+	params["note_id"] = result.Notes[0].ID
+	if _, err := HandleClusterNotes(params, book, api, readonly); err == nil {
+		t.Error("Expected readonly access to be refused deleting notes")
+	}
+	params["operation"] = OpList
+	if _, err := HandleClusterNotes(params, book, api, readonly); err != nil {
+		t.Errorf("Expected readonly access to list notes, got %v", err)
+	}
+
+	api.err = fmt.Errorf("AuthorizationFailed")
+	if _, err := HandleClusterNotes(params, book, api, readonly); err == nil {
+		t.Error("Expected notes of unreadable clusters to be refused")
+	}
+	api.err = nil
+
+	params["operation"] = OpDelete
+	params["note_id"] = "missing"
+	if _, err := HandleClusterNotes(params, book, api, cfg); err == nil || !strings.Contains(err.Error(), "has no note missing") {
+		t.Errorf("Expected an unknown note to be reported, got %v", err)
+	}
+}
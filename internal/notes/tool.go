@@ -0,0 +1,129 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Operations of the aks_cluster_notes tool
+const (
+	OpAdd    = "add"
+	OpList   = "list"
+	OpDelete = "delete"
+)
+
+// clusterAPIVersion is the Microsoft.ContainerService API version used to check the cluster exists
+const clusterAPIVersion = "2024-05-01"
+
+// Result is the result returned by the aks_cluster_notes tool
+type Result struct {
+	Cluster string `json:"cluster"`
+	Notes   []Note `json:"notes"`
+	Message string `json:"message,omitempty"`
+}
+
+// RegisterClusterNotesTool registers the aks_cluster_notes tool
+func RegisterClusterNotesTool() mcp.Tool {
+	return mcp.NewTool(
+		"aks_cluster_notes",
+		mcp.WithDescription(`Record and read free-form operator notes about a cluster, such as "this cluster is canary" or "do not scale pool X".
+
+Notes are kept in the server's state store, so they survive restarts, and are shown with each cluster in aks_estate_overview.
+Check the notes of a cluster before changing it. Adding and deleting notes requires readwrite or admin access.`),
+		mcp.WithString("operation",
+			mcp.Description("Operation to perform: add, list or delete"),
+			mcp.Enum(OpAdd, OpList, OpDelete),
+			mcp.Required(),
+		),
+		mcp.WithString("subscription_id",
+			mcp.Description("Azure Subscription ID"),
+			mcp.Required(),
+		),
+		mcp.WithString("resource_group",
+			mcp.Description("Azure Resource Group containing the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("cluster_name",
+			mcp.Description("Name of the AKS cluster"),
+			mcp.Required(),
+		),
+		mcp.WithString("note",
+			mcp.Description(fmt.Sprintf("Text of the note to add (at most %d characters)", MaxNoteLength)),
+		),
+		mcp.WithString("author",
+			mcp.Description("Who the note is from, such as a team or person (optional)"),
+		),
+		mcp.WithString("note_id",
+			mcp.Description("ID of the note to delete"),
+		),
+	)
+}
+
+// GetClusterNotesHandler returns a handler for the aks_cluster_notes command
+func GetClusterNotesHandler(book *Book, api common.ARMCaller, cfg *config.ConfigData) tools.ResourceHandler {
+	return tools.ResourceHandlerFunc(func(params map[string]interface{}, _ *config.ConfigData) (string, error) {
+		return HandleClusterNotes(params, book, api, cfg)
+	})
+}
+
+// HandleClusterNotes adds, lists or deletes the notes of a cluster. The cluster is read first, so notes can
+// only be read and written for clusters the caller's credential can read.
+func HandleClusterNotes(params map[string]interface{}, book *Book, api common.ARMCaller, cfg *config.ConfigData) (string, error) {
+	operation, _ := params["operation"].(string)
+	if operation != OpAdd && operation != OpList && operation != OpDelete {
+		return "", fmt.Errorf("invalid operation %q: expected %s, %s or %s", operation, OpAdd, OpList, OpDelete)
+	}
+	if operation != OpList && cfg.AccessLevel != "readwrite" && cfg.AccessLevel != "admin" {
+		return "", fmt.Errorf("%s requires readwrite or admin access", operation)
+	}
+	subID, rg, clusterName, err := common.ExtractAKSParameters(params)
+	if err != nil {
+		return "", err
+	}
+	clusterID := ClusterID(subID, rg, clusterName)
+	path := common.ClusterResourceID(subID, rg, clusterName) + "?api-version=" + clusterAPIVersion
+	if _, err := api.CallARM(context.Background(), http.MethodGet, path); err != nil {
+		return "", fmt.Errorf("failed to get cluster %s: %w", clusterName, err)
+	}
+
+	result := Result{Cluster: clusterName}
+	switch operation {
+	case OpAdd:
+		text, _ := params["note"].(string)
+		author, _ := params["author"].(string)
+		note, err := book.Add(clusterID, text, author)
+		if err != nil {
+			return "", err
+		}
+		result.Message = "added note " + note.ID
+	case OpDelete:
+		id, _ := params["note_id"].(string)
+		if id == "" {
+			return "", fmt.Errorf("missing note_id parameter")
+		}
+		if err := book.Delete(clusterID, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return "", fmt.Errorf("cluster %s has no note %s", clusterName, id)
+			}
+			return "", err
+		}
+		result.Message = "deleted note " + id
+	}
+	if result.Notes, err = book.List(clusterID); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cluster notes: %v", err)
+	}
+	return string(data), nil
+}
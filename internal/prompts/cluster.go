@@ -46,6 +46,10 @@ This will show all the AKS clusters (in JSON format) in user pre-configured subs
 
 Compare the control plane FQDN from Step 1 with the FQDNs of the AKS clusters from Step 2,
 figure out the matched AKS cluster, and then respond the AKS cluster's subscriptionID, resourceGroup and name.
+
+If several clusters match, do not pick one: list them with their subscription and resource group and ask the user
+which one to use. Tools given only the FQDN as cluster_name return the candidates, and accept the user's choice as
+cluster_selection.
 `
 
 		return &mcp.GetPromptResult{
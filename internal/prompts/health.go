@@ -12,7 +12,7 @@ import (
 func RegisterHealthPrompts(s *server.MCPServer, cfg *config.ConfigData) {
 	// Prompt: check_cluster_health
 	s.AddPrompt(mcp.NewPrompt("check_cluster_health",
-		mcp.WithPromptDescription("Comprehensive AKS cluster health assessment including platform health, diagnostics, cluster detectors, node health, and connectivity analysis"),
+		mcp.WithPromptDescription("Comprehensive AKS cluster health assessment including platform health, fired alerts, diagnostics, cluster detectors, node health, and connectivity analysis"),
 	), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 		promptContent := `# Comprehensive AKS Cluster Health Assessment
 
@@ -48,7 +48,18 @@ Invoke az_monitoring tool:
 }
 Analyze: Identify any Azure platform incidents, service health issues, or resource degradation events that may impact cluster availability.
 
-### 4. Run Cluster and Control Plane Availability Detectors
+### 4. Check Fired Azure Monitor Alerts
+Invoke az_monitoring tool:
+{
+  "operation": "fired_alerts",
+  "subscription_id": "<SUBSCRIPTION_ID>",
+  "resource_group": "<RESOURCE_GROUP>",
+  "cluster_name": "<CLUSTER_NAME>",
+  "parameters": "{\"time_range\":\"1d\"}"
+}
+Analyze: Review alerts that are currently firing or were recently resolved on the cluster and the resources in its node resource group. Correlate high-severity alerts with the detector findings below.
+
+### 5. Run Cluster and Control Plane Availability Detectors
 Invoke run_detectors_by_category tool:
 {
   "cluster_resource_id": "<AKS_RESOURCE_ID>",
@@ -58,7 +69,7 @@ Invoke run_detectors_by_category tool:
 }
 Analyze: Review API server responsiveness, control plane scaling issues, etcd health, and cluster networking performance problems.
 
-### 5. Run Node Health Detectors
+### 6. Run Node Health Detectors
 Invoke run_detectors_by_category tool:
 {
   "cluster_resource_id": "<AKS_RESOURCE_ID>",
@@ -68,7 +79,7 @@ Invoke run_detectors_by_category tool:
 }
 Analyze: Examine node readiness issues, kubelet problems, container runtime health, disk pressure, memory pressure, and node pool scaling issues.
 
-### 6. Run Connectivity Issue Detectors
+### 7. Run Connectivity Issue Detectors
 Invoke run_detectors_by_category tool:
 {
   "cluster_resource_id": "<AKS_RESOURCE_ID>",
@@ -78,13 +89,13 @@ Invoke run_detectors_by_category tool:
 }
 Analyze: Investigate DNS resolution problems, network policy conflicts, ingress/egress connectivity, load balancer issues, and service mesh problems.
 
-### 7. Generate Comprehensive Health Report
+### 8. Generate Comprehensive Health Report
 
 Generate a comprehensive health report and recommendations based on the findings from the previous steps.
 
 Provide specific commands, configurations, or Azure portal links where applicable for implementing recommendations.
 `
-		return &mcp.GetPromptResult{Description: "Comprehensive AKS cluster health assessment including platform health, diagnostics, availability detectors, node health, and connectivity analysis", Messages: []mcp.PromptMessage{{Role: mcp.RoleAssistant, Content: mcp.TextContent{Type: "text", Text: promptContent}}}}, nil
+		return &mcp.GetPromptResult{Description: "Comprehensive AKS cluster health assessment including platform health, fired alerts, diagnostics, availability detectors, node health, and connectivity analysis", Messages: []mcp.PromptMessage{{Role: mcp.RoleAssistant, Content: mcp.TextContent{Type: "text", Text: promptContent}}}}, nil
 	})
 
 }
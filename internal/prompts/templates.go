@@ -0,0 +1,173 @@
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sigs.k8s.io/yaml"
+)
+
+// builtinPromptNames are taken by the prompts compiled into the server
+var builtinPromptNames = map[string]bool{
+	"query_aks_cluster_metadata_from_kubeconfig": true,
+	"check_cluster_health":                       true,
+}
+
+var (
+	promptNamePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_\-]*$`)
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-]+)\s*\}\}`)
+)
+
+// TemplateArgument is an argument a prompt template accepts
+type TemplateArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// PromptTemplate is a prompt loaded from a Markdown file. The frontmatter holds the name,
+// description and arguments; the body is the prompt text, where {{argument}} is replaced
+// by the argument's value.
+type PromptTemplate struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Arguments   []TemplateArgument `json:"arguments"`
+	Body        string             `json:"-"`
+}
+
+// ParsePromptTemplate parses a Markdown prompt template. The name defaults to the file name without .md.
+func ParsePromptTemplate(path string, content []byte) (PromptTemplate, error) {
+	var tmpl PromptTemplate
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(content, []byte("---\n")) {
+		return tmpl, fmt.Errorf("%s: missing frontmatter: the file must start with a --- line", path)
+	}
+	rest := content[len("---\n"):]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end < 0 {
+		return tmpl, fmt.Errorf("%s: frontmatter is not closed with a --- line", path)
+	}
+	if err := yaml.UnmarshalStrict(rest[:end], &tmpl); err != nil {
+		return tmpl, fmt.Errorf("%s: invalid frontmatter: %v", path, err)
+	}
+	body := rest[end+len("\n---"):]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	tmpl.Body = strings.TrimSpace(string(body))
+
+	if tmpl.Name == "" {
+		tmpl.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if !promptNamePattern.MatchString(tmpl.Name) {
+		return tmpl, fmt.Errorf("%s: invalid prompt name %q: use letters, digits, _ and -", path, tmpl.Name)
+	}
+	if tmpl.Body == "" {
+		return tmpl, fmt.Errorf("%s: the prompt body is empty", path)
+	}
+	seen := make(map[string]bool, len(tmpl.Arguments))
+	for _, arg := range tmpl.Arguments {
+		if !promptNamePattern.MatchString(arg.Name) || seen[arg.Name] {
+			return tmpl, fmt.Errorf("%s: invalid or duplicate argument name %q", path, arg.Name)
+		}
+		seen[arg.Name] = true
+	}
+	return tmpl, nil
+}
+
+// LoadPromptTemplates loads the *.md prompt templates of a directory in file name order.
+// Invalid templates and names that are already taken are logged and skipped, so one bad file
+// does not hide the others.
+func LoadPromptTemplates(dir string) ([]PromptTemplate, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read prompt templates directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	names := make(map[string]bool)
+	var templates []PromptTemplate
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Warning: skipping prompt template %s: %v", path, err)
+			continue
+		}
+		tmpl, err := ParsePromptTemplate(path, content)
+		if err != nil {
+			log.Printf("Warning: skipping prompt template %v", err)
+			continue
+		}
+		if builtinPromptNames[tmpl.Name] || names[tmpl.Name] {
+			log.Printf("Warning: skipping prompt template %s: prompt name %q is already registered", path, tmpl.Name)
+			continue
+		}
+		names[tmpl.Name] = true
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
+
+// Render replaces the {{argument}} placeholders of the body with the given values.
+// Missing required arguments are an error; placeholders of other missing arguments become empty.
+func (t PromptTemplate) Render(values map[string]string) (string, error) {
+	declared := make(map[string]bool, len(t.Arguments))
+	for _, arg := range t.Arguments {
+		declared[arg.Name] = true
+		if arg.Required && strings.TrimSpace(values[arg.Name]) == "" {
+			return "", fmt.Errorf("prompt %s requires argument %q", t.Name, arg.Name)
+		}
+	}
+	return placeholderPattern.ReplaceAllStringFunc(t.Body, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if !declared[name] {
+			// Leave text that only looks like a placeholder alone
+			return match
+		}
+		return values[name]
+	}), nil
+}
+
+// RegisterTemplatePrompts registers the prompt templates of cfg.PromptsDir as MCP prompts
+func RegisterTemplatePrompts(s *server.MCPServer, cfg *config.ConfigData) {
+	templates, err := LoadPromptTemplates(cfg.PromptsDir)
+	if err != nil {
+		log.Printf("Warning: no prompt templates loaded: %v", err)
+		return
+	}
+
+	for _, tmpl := range templates {
+		options := []mcp.PromptOption{mcp.WithPromptDescription(tmpl.Description)}
+		for _, arg := range tmpl.Arguments {
+			argOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+			if arg.Required {
+				argOptions = append(argOptions, mcp.RequiredArgument())
+			}
+			options = append(options, mcp.WithArgument(arg.Name, argOptions...))
+		}
+
+		s.AddPrompt(mcp.NewPrompt(tmpl.Name, options...), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			text, err := tmpl.Render(request.Params.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.GetPromptResult{Description: tmpl.Description, Messages: []mcp.PromptMessage{{Role: mcp.RoleAssistant, Content: mcp.TextContent{Type: "text", Text: text}}}}, nil
+		})
+	}
+	log.Printf("Registered %d prompt templates from %s", len(templates), cfg.PromptsDir)
+}
@@ -0,0 +1,95 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRunbook = `---
+name: node_not_ready_runbook
+description: Team runbook for NotReady nodes
+arguments:
+  - name: node
+    description: Name of the NotReady node
+    required: true
+  - name: nodepool
+    description: Node pool of the node
+---
+# NotReady runbook
+
+Describe node {{node}} in pool {{ nodepool }} and keep {{literal}} as written.
+`
+
+func TestParsePromptTemplate(t *testing.T) {
+	tmpl, err := ParsePromptTemplate("runbook.md", []byte(testRunbook))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tmpl.Name != "node_not_ready_runbook" || tmpl.Description != "Team runbook for NotReady nodes" || len(tmpl.Arguments) != 2 {
+		t.Fatalf("Unexpected template: %+v", tmpl)
+	}
+	if !tmpl.Arguments[0].Required || tmpl.Arguments[1].Required {
+		t.Errorf("Unexpected required flags: %+v", tmpl.Arguments)
+	}
+
+	text, err := tmpl.Render(map[string]string{"node": "aks-np1-0"})
+	if err != nil {
+		t.Fatalf("Unexpected render error: %v", err)
+	}
+	if !strings.HasPrefix(text, "# NotReady runbook") || !strings.Contains(text, "Describe node aks-np1-0 in pool  and keep {{literal}} as written.") {
+		t.Errorf("Unexpected rendered text: %q", text)
+	}
+	if _, err := tmpl.Render(map[string]string{}); err == nil || !strings.Contains(err.Error(), `requires argument "node"`) {
+		t.Errorf("Expected a missing required argument error, got %v", err)
+	}
+
+	unnamed, err := ParsePromptTemplate("/prompts/drain-checklist.md", []byte("---\ndescription: Checklist\n---\nCheck PDBs first.\n"))
+	if err != nil || unnamed.Name != "drain-checklist" || unnamed.Body != "Check PDBs first." {
+		t.Errorf("Expected the name to default to the file name, got %+v, %v", unnamed, err)
+	}
+
+	for name, content := range map[string]string{
+		"no frontmatter":   "# Just markdown\n",
+		"unclosed":         "---\nname: x\n",
+		"unknown field":    "---\nname: x\ntitle: y\n---\nBody\n",
+		"empty body":       "---\nname: x\n---\n",
+		"invalid name":     "---\nname: bad name\n---\nBody\n",
+		"duplicate arg":    "---\nname: x\narguments:\n  - name: a\n  - name: a\n---\nBody\n",
+		"invalid arg name": "---\nname: x\narguments:\n  - name: ''\n---\nBody\n",
+	} {
+		if _, err := ParsePromptTemplate("t.md", []byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadPromptTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a-runbook.md":   testRunbook,
+		"b-broken.md":    "no frontmatter",
+		"c-duplicate.md": strings.Replace(testRunbook, "Team runbook", "Copy", 1),
+		"d-builtin.md":   "---\nname: check_cluster_health\n---\nOverride\n",
+		"e-checklist.md": "---\ndescription: Checklist\n---\nCheck PDBs first.\n",
+		"notes.txt":      "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, err := LoadPromptTemplates(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(templates) != 2 || templates[0].Name != "node_not_ready_runbook" || templates[0].Description != "Team runbook for NotReady nodes" || templates[1].Name != "e-checklist" {
+		t.Errorf("Expected invalid, duplicate and built-in templates to be skipped, got %+v", templates)
+	}
+
+	if _, err := LoadPromptTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the line comparison table; larger results are reported by their first difference
+const maxDiffCells = 4_000_000
+
+// Diff returns a line diff between the recorded and replayed results, with "-" for recorded lines missing
+// from the replay and "+" for replayed lines missing from the recording
+func Diff(recorded, replayed string) []string {
+	a := strings.Split(recorded, "\n")
+	b := strings.Split(replayed, "\n")
+	if len(a)*len(b) > maxDiffCells {
+		return firstDifference(a, b)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+	return lines
+}
+
+// firstDifference reports the first line where two long results differ
+func firstDifference(a, b []string) []string {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return []string{fmt.Sprintf("first difference on line %d of %d recorded and %d replayed lines:", i+1, len(a), len(b)),
+				"- " + a[i], "+ " + b[i]}
+		}
+	}
+	return []string{fmt.Sprintf("recorded result has %d lines, replayed result has %d", len(a), len(b))}
+}
@@ -0,0 +1,257 @@
+// Package replay records the tool calls of a session and re-executes them later, printing how the results
+// differ from the recorded ones. Recordings are JSON lines files holding the tool calls with their arguments
+// and results, and the az CLI commands the calls ran with their outputs, so a replay can answer commands
+// from the recording instead of running them.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Kinds of recorded entries
+const (
+	KindCall    = "call"
+	KindCommand = "command"
+)
+
+// maxEntryBytes bounds a recorded line, which holds a whole tool result or command output
+const maxEntryBytes = 64 << 20
+
+// Entry is a recorded tool call or command
+type Entry struct {
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+
+	// Tool call entries
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Result    string                 `json:"result,omitempty"`
+	IsError   bool                   `json:"isError,omitempty"`
+
+	// Command entries
+	Command string `json:"command,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Recorder appends the tool calls and commands of a session to a recording file. It implements
+// command.Interceptor, observing commands without answering them.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	now  func() time.Time
+}
+
+// NewRecorder opens a recording file for appending. Recordings hold tool arguments and results, so the file
+// is only readable by its owner.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording %s: %w", path, err)
+	}
+	return &Recorder{file: f, now: time.Now}, nil
+}
+
+// Close closes the recording file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Wrap records each call of a tool handler with its arguments and result
+func (r *Recorder) Wrap(tool string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, req)
+		entry := Entry{Kind: KindCall, Tool: tool}
+		entry.Arguments, _ = req.Params.Arguments.(map[string]interface{})
+		switch {
+		case err != nil:
+			entry.Result, entry.IsError = err.Error(), true
+		case result != nil:
+			entry.Result, entry.IsError = ResultText(result), result.IsError
+		}
+		r.write(entry)
+		return result, err
+	}
+}
+
+// Intercept never answers commands while recording
+func (r *Recorder) Intercept(string) (string, bool, error) {
+	return "", false, nil
+}
+
+// Observe records a command that was run and its output
+func (r *Recorder) Observe(command, output string, err error) {
+	entry := Entry{Kind: KindCommand, Command: command, Output: output}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.write(entry)
+}
+
+// write appends an entry to the recording. Failures are only logged, since recording must never fail a tool call.
+func (r *Recorder) write(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.Time = r.now().UTC()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Warning: failed to record %s entry: %v", entry.Kind, err)
+		return
+	}
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Warning: failed to record %s entry: %v", entry.Kind, err)
+	}
+}
+
+// Load reads the entries of a recording
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntryBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid entry on line %d of %s: %w", line, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Player answers commands with the outputs recorded for them, so a replay never runs az CLI.
+// It implements command.Interceptor.
+type Player struct {
+	mu       sync.Mutex
+	commands map[string]Entry
+	missing  []string
+}
+
+// NewPlayer creates a player for the commands of a recording. A command recorded more than once is
+// answered with its last output.
+func NewPlayer(entries []Entry) *Player {
+	p := &Player{commands: map[string]Entry{}}
+	for _, entry := range entries {
+		if entry.Kind == KindCommand {
+			p.commands[entry.Command] = entry
+		}
+	}
+	return p
+}
+
+// Intercept answers a command from the recording, failing commands that were not recorded
+func (p *Player) Intercept(command string) (string, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.commands[command]
+	if !ok {
+		p.missing = append(p.missing, command)
+		return "", true, fmt.Errorf("command not in the recording: %s", command)
+	}
+	if entry.Error != "" {
+		return entry.Output, true, errors.New(entry.Error)
+	}
+	return entry.Output, true, nil
+}
+
+// Observe is not called for answered commands
+func (p *Player) Observe(string, string, error) {}
+
+// Missing returns the commands that were run during the replay but are not in the recording
+func (p *Player) Missing() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.missing...)
+}
+
+// CallFunc calls a tool as an MCP client would
+type CallFunc func(ctx context.Context, tool string, arguments map[string]interface{}) (*mcp.CallToolResult, error)
+
+// Summary counts the replayed calls
+type Summary struct {
+	Calls     int
+	Unchanged int
+	Changed   int
+}
+
+// Run replays the recorded tool calls in order and writes a diff for each result that changed
+func Run(ctx context.Context, entries []Entry, call CallFunc, w io.Writer) Summary {
+	var summary Summary
+	for _, entry := range entries {
+		if entry.Kind != KindCall {
+			continue
+		}
+		summary.Calls++
+		var got string
+		var isError bool
+		result, err := call(ctx, entry.Tool, entry.Arguments)
+		switch {
+		case err != nil:
+			got, isError = err.Error(), true
+		case result != nil:
+			got, isError = ResultText(result), result.IsError
+		}
+
+		if got == entry.Result && isError == entry.IsError {
+			summary.Unchanged++
+			_, _ = fmt.Fprintf(w, "ok      %s %s\n", entry.Tool, formatArguments(entry.Arguments))
+			continue
+		}
+		summary.Changed++
+		_, _ = fmt.Fprintf(w, "CHANGED %s %s\n", entry.Tool, formatArguments(entry.Arguments))
+		if isError != entry.IsError {
+			_, _ = fmt.Fprintf(w, "  error: recorded %t, replayed %t\n", entry.IsError, isError)
+		}
+		for _, line := range Diff(entry.Result, got) {
+			_, _ = fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d calls replayed: %d unchanged, %d changed\n", summary.Calls, summary.Unchanged, summary.Changed)
+	return summary
+}
+
+// ResultText joins the text contents of a tool result
+func ResultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// formatArguments formats call arguments on one line
+func formatArguments(arguments map[string]interface{}) string {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Sprintf("%v", arguments)
+	}
+	return string(data)
+}
@@ -0,0 +1,110 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	command.SetInterceptor(recorder)
+	defer command.SetInterceptor(nil)
+
+	// The handler runs a command, so the recording holds both the call and the command output
+	handler := recorder.Wrap("echo_tool", func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := req.GetArguments()["text"].(string)
+		output, err := command.NewShellProcess("echo", 10).Run(text)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(strings.TrimSpace(output)), nil
+	})
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]interface{}{"text": "hello"}
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Kind != KindCommand || entries[0].Command != "echo hello" || entries[0].Output != "hello\n" {
+		t.Fatalf("Expected the command to be recorded first, got %+v", entries)
+	}
+	if entries[1].Kind != KindCall || entries[1].Tool != "echo_tool" || entries[1].Result != "hello" || entries[1].Arguments["text"] != "hello" {
+		t.Fatalf("Unexpected call entry %+v", entries[1])
+	}
+
+	// Mock replay answers the command from the recording instead of running it
+	player := NewPlayer(entries)
+	command.SetInterceptor(player)
+	call := func(_ context.Context, _ string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+		output, err := command.NewShellProcess("echo", 10).Run(arguments["text"].(string) + " again")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(strings.TrimSpace(output)), nil
+	}
+	var out bytes.Buffer
+	summary := Run(context.Background(), entries, call, &out)
+	if summary.Calls != 1 || summary.Changed != 1 {
+		t.Errorf("Expected the changed command to change the result, got %+v", summary)
+	}
+	if missing := player.Missing(); len(missing) != 1 || missing[0] != "echo hello again" {
+		t.Errorf("Expected the unrecorded command to be reported, got %v", missing)
+	}
+	if !strings.Contains(out.String(), "CHANGED echo_tool") || !strings.Contains(out.String(), "error: recorded false, replayed true") {
+		t.Errorf("Unexpected replay output:\n%s", out.String())
+	}
+
+	out.Reset()
+	summary = Run(context.Background(), entries, func(context.Context, string, map[string]interface{}) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("hello"), nil
+	}, &out)
+	if summary.Unchanged != 1 || !strings.Contains(out.String(), "1 calls replayed: 1 unchanged, 0 changed") {
+		t.Errorf("Unexpected replay output:\n%s", out.String())
+	}
+}
+
+func TestPlayerRecordedErrors(t *testing.T) {
+	player := NewPlayer([]Entry{
+		{Kind: KindCommand, Command: "az aks show", Output: "old"},
+		{Kind: KindCommand, Command: "az aks show", Output: "ERROR: not found", Error: "exit status 3"},
+	})
+	output, handled, err := player.Intercept("az aks show")
+	if !handled || output != "ERROR: not found" || err == nil || err.Error() != "exit status 3" {
+		t.Errorf("Expected the last recorded failure, got %q %v %v", output, handled, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lines := Diff("a\nb\nc\nd", "a\nc\nd\ne")
+	want := []string{"- b", "+ e"}
+	if fmt.Sprint(lines) != fmt.Sprint(want) {
+		t.Errorf("Diff = %v, want %v", lines, want)
+	}
+	if lines := Diff("same", "same"); len(lines) != 0 {
+		t.Errorf("Expected no differences, got %v", lines)
+	}
+
+	long := strings.Repeat("line\n", 3000)
+	lines = Diff(long+"old", long+"new")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "first difference on line 3001") {
+		t.Errorf("Expected the first difference of long results, got %v", lines)
+	}
+}
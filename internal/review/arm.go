@@ -0,0 +1,102 @@
+package review
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// armReadActions are POST actions that only read, such as listing credentials or querying Resource Graph
+var armReadActions = []string{"list", "get", "query", "resources", "validate", "check"}
+
+// IsARMWrite reports whether an ARM request changes resources
+func IsARMWrite(method, rawURL string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		path := rawURL
+		if parsed, err := url.Parse(rawURL); err == nil {
+			path = parsed.Path
+		}
+		action := strings.ToLower(path[strings.LastIndex(path, "/")+1:])
+		for _, prefix := range armReadActions {
+			if strings.HasPrefix(action, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ARMChange builds the change for an ARM write request. PUT and PATCH requests on resource group
+// resources also get a Bicep patch with the properties of the request body.
+func ARMChange(method, rawURL string, body []byte) Change {
+	method = strings.ToUpper(method)
+	command := "az rest --method " + strings.ToLower(method) + " --url " + shellQuote(rawURL)
+	if len(body) > 0 {
+		command += " --body " + shellQuote(string(body))
+	}
+	change := Change{Kind: KindARM, Command: method + " " + rawURL}
+	if method == http.MethodPut || method == http.MethodPatch {
+		if bicep, description, ok := armBicep(rawURL, body); ok {
+			change.Artifacts = append(change.Artifacts, Artifact{Format: FormatBicep, Description: description, Content: bicep})
+		}
+	}
+	change.Artifacts = append(change.Artifacts, Artifact{
+		Format:      FormatAzScript,
+		Description: "Run from a pipeline signed in to the target subscription",
+		Content:     Script(command),
+	})
+	return change
+}
+
+// armBicep returns a Bicep patch setting the body of a PUT or PATCH request on a resource group resource
+func armBicep(rawURL string, body []byte) (string, string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", false
+	}
+	apiVersion := parsed.Query().Get("api-version")
+	// /subscriptions/{id}/resourceGroups/{rg}/providers/{namespace}/{type}/{name}[/{type}/{name}...]
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if apiVersion == "" || len(segments) < 8 || len(segments)%2 != 0 ||
+		!strings.EqualFold(segments[0], "subscriptions") || !strings.EqualFold(segments[2], "resourceGroups") || !strings.EqualFold(segments[4], "providers") {
+		return "", "", false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || len(fields) == 0 {
+		return "", "", false
+	}
+	resourceGroup := segments[3]
+	types := []string{segments[5]}
+	var names []string
+	for i := 6; i < len(segments); i += 2 {
+		types = append(types, segments[i])
+		names = append(names, segments[i+1])
+	}
+
+	resource := map[string]interface{}{"name": strings.Join(names, "/")}
+	for key, value := range fields {
+		switch key {
+		case "id", "name", "type", "etag", "systemData":
+			// Read-only fields of the resource
+		default:
+			resource[key] = value
+		}
+	}
+	bicep := renderBicep(bicepResource{
+		symbol:     "target",
+		typ:        strings.Join(types, "/"),
+		apiVersion: apiVersion,
+		body:       resource,
+	})
+	return bicep, bicepDescription(strings.Join(types[1:], "/")+" "+strings.Join(names, "/"), resourceGroup), true
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,222 @@
+package review
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// azFlagAliases maps the short flags of the commands with a Bicep equivalent to their long names
+var azFlagAliases = map[string]string{
+	"-g": "resource-group",
+	"-n": "name",
+	"-c": "node-count",
+	"-k": "kubernetes-version",
+	"-s": "node-vm-size",
+}
+
+// AzChange builds the change for an az CLI write command. args is the command without the leading "az".
+// Node pool and cluster scale, upgrade, add and autoscaler updates also get a Bicep patch.
+func AzChange(args string) Change {
+	args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), "az "))
+	command := "az " + args
+	change := Change{Kind: KindAz, Command: command}
+	if bicep, description, ok := azBicep(args); ok {
+		change.Artifacts = append(change.Artifacts, Artifact{Format: FormatBicep, Description: description, Content: bicep})
+	}
+	change.Artifacts = append(change.Artifacts, Artifact{
+		Format:      FormatAzScript,
+		Description: "Run from a pipeline signed in to the target subscription",
+		Content:     Script(command),
+	})
+	return change
+}
+
+// Script wraps a command in a shell script that stops at the first error
+func Script(command string) string {
+	return "#!/usr/bin/env bash\nset -euo pipefail\n\n" + command + "\n"
+}
+
+// azBicep returns a Bicep patch equivalent to an az aks command, when it has one
+func azBicep(args string) (string, string, bool) {
+	words, err := shlex.Split(args)
+	if err != nil {
+		return "", "", false
+	}
+	var verbs []string
+	for len(words) > 0 && !strings.HasPrefix(words[0], "-") {
+		verbs = append(verbs, words[0])
+		words = words[1:]
+	}
+	flags := parseAzFlags(words)
+	resourceGroup := flags["resource-group"]
+	if resourceGroup == "" {
+		return "", "", false
+	}
+
+	var cluster, pool string
+	properties := map[string]interface{}{}
+	switch strings.Join(verbs, " ") {
+	case "aks nodepool scale":
+		cluster, pool = flags["cluster-name"], flags["name"]
+		if !setInt(properties, "count", flags["node-count"]) {
+			return "", "", false
+		}
+	case "aks scale":
+		cluster, pool = flags["name"], flags["nodepool-name"]
+		if !setInt(properties, "count", flags["node-count"]) {
+			return "", "", false
+		}
+	case "aks nodepool upgrade":
+		cluster, pool = flags["cluster-name"], flags["name"]
+		if flags["kubernetes-version"] == "" {
+			// Node image upgrades have no property to set
+			return "", "", false
+		}
+		properties["orchestratorVersion"] = flags["kubernetes-version"]
+	case "aks nodepool add", "aks nodepool update":
+		cluster, pool = flags["cluster-name"], flags["name"]
+		if !poolProperties(properties, flags) {
+			return "", "", false
+		}
+	case "aks upgrade":
+		cluster = flags["name"]
+		if cluster == "" || flags["kubernetes-version"] == "" {
+			return "", "", false
+		}
+		properties["kubernetesVersion"] = flags["kubernetes-version"]
+		bicep := renderBicep(bicepResource{
+			symbol:     "cluster",
+			typ:        "Microsoft.ContainerService/managedClusters",
+			apiVersion: containerServiceAPIVersion,
+			body:       map[string]interface{}{"name": cluster, "properties": properties},
+		})
+		return bicep, bicepDescription("cluster "+cluster, resourceGroup) + " Node pools keep their version until their orchestratorVersion is raised.", true
+	default:
+		return "", "", false
+	}
+	if cluster == "" || pool == "" || len(properties) == 0 {
+		return "", "", false
+	}
+	bicep := renderBicep(
+		bicepResource{
+			symbol:     "cluster",
+			typ:        "Microsoft.ContainerService/managedClusters",
+			apiVersion: containerServiceAPIVersion,
+			existing:   true,
+			body:       map[string]interface{}{"name": cluster},
+		},
+		bicepResource{
+			symbol:     "agentPool",
+			typ:        "Microsoft.ContainerService/managedClusters/agentPools",
+			apiVersion: containerServiceAPIVersion,
+			parent:     "cluster",
+			body:       map[string]interface{}{"name": pool, "properties": properties},
+		},
+	)
+	return bicep, bicepDescription("node pool "+pool+" of cluster "+cluster, resourceGroup), true
+}
+
+// bicepDescription says how to apply a Bicep patch of a resource
+func bicepDescription(resource, resourceGroup string) string {
+	return fmt.Sprintf("Merge these properties into the %s in your template; Bicep deploys the whole resource, so keep "+
+		"the properties it already sets. Deploy with az deployment group create --resource-group %s.", resource, resourceGroup)
+}
+
+// poolProperties sets the agent pool properties of az aks nodepool add and update flags, and reports
+// whether every flag given has a property
+func poolProperties(properties map[string]interface{}, flags map[string]string) bool {
+	for flag, value := range flags {
+		switch flag {
+		case "cluster-name", "name", "resource-group", "subscription", "no-wait", "output", "only-show-errors", "yes":
+		case "node-count":
+			if !setInt(properties, "count", value) {
+				return false
+			}
+		case "min-count":
+			if !setInt(properties, "minCount", value) {
+				return false
+			}
+		case "max-count":
+			if !setInt(properties, "maxCount", value) {
+				return false
+			}
+		case "max-pods":
+			if !setInt(properties, "maxPods", value) {
+				return false
+			}
+		case "enable-cluster-autoscaler":
+			properties["enableAutoScaling"] = true
+		case "disable-cluster-autoscaler":
+			properties["enableAutoScaling"] = false
+		case "node-vm-size":
+			properties["vmSize"] = value
+		case "mode":
+			properties["mode"] = value
+		case "kubernetes-version":
+			properties["orchestratorVersion"] = value
+		case "os-sku":
+			properties["osSKU"] = value
+		case "labels":
+			labels := map[string]string{}
+			for _, label := range strings.Fields(value) {
+				key, labelValue, _ := strings.Cut(label, "=")
+				labels[key] = labelValue
+			}
+			properties["nodeLabels"] = labels
+		case "node-taints":
+			var taints []string
+			for _, taint := range strings.Split(value, ",") {
+				if taint = strings.TrimSpace(taint); taint != "" {
+					taints = append(taints, taint)
+				}
+			}
+			properties["nodeTaints"] = taints
+		case "zones":
+			properties["availabilityZones"] = strings.Fields(value)
+		default:
+			// A flag without a property would be silently dropped from the patch
+			return false
+		}
+	}
+	return true
+}
+
+// parseAzFlags parses flags into values keyed by long flag name. Flags without a value are "true".
+func parseAzFlags(words []string) map[string]string {
+	flags := map[string]string{}
+	for i := 0; i < len(words); i++ {
+		name, value, hasValue := strings.Cut(words[i], "=")
+		if long, ok := azFlagAliases[name]; ok {
+			name = long
+		} else {
+			name = strings.TrimPrefix(name, "--")
+		}
+		if !hasValue {
+			value = "true"
+			// Values may be several words, such as --labels a=b c=d and --zones 1 2 3
+			var values []string
+			for i+1 < len(words) && !strings.HasPrefix(words[i+1], "-") {
+				values = append(values, words[i+1])
+				i++
+			}
+			if len(values) > 0 {
+				value = strings.Join(values, " ")
+			}
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// setInt sets an integer property, and reports whether the value was an integer
+func setInt(properties map[string]interface{}, name, value string) bool {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	properties[name] = n
+	return true
+}
@@ -0,0 +1,132 @@
+package review
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// containerServiceAPIVersion is the Microsoft.ContainerService API version of generated Bicep patches
+const containerServiceAPIVersion = "2024-09-01"
+
+// bicepIdentifier matches object keys that need no quotes in Bicep
+var bicepIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// bicepResource is a resource declaration of a Bicep patch
+type bicepResource struct {
+	symbol     string
+	typ        string
+	apiVersion string
+	existing   bool
+	parent     string
+	body       map[string]interface{}
+}
+
+// renderBicep renders resource declarations, separated by blank lines
+func renderBicep(resources ...bicepResource) string {
+	var b strings.Builder
+	for i, resource := range resources {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "resource %s '%s@%s' ", resource.symbol, resource.typ, resource.apiVersion)
+		if resource.existing {
+			b.WriteString("existing ")
+		}
+		b.WriteString("= {\n")
+		if resource.parent != "" {
+			fmt.Fprintf(&b, "  parent: %s\n", resource.parent)
+		}
+		// The name comes first, as in hand-written templates
+		if name, ok := resource.body["name"]; ok {
+			fmt.Fprintf(&b, "  name: %s\n", bicepValue(name, "  "))
+		}
+		for _, key := range sortedKeys(resource.body) {
+			if key != "name" {
+				fmt.Fprintf(&b, "  %s: %s\n", bicepKey(key), bicepValue(resource.body[key], "  "))
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// bicepValue renders a JSON value as a Bicep expression at the given indentation
+func bicepValue(value interface{}, indent string) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		if v == float64(int64(v)) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		// Bicep has no floating point literals
+		return bicepString(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		return bicepString(v)
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return bicepValue(items, indent)
+	case []interface{}:
+		if len(v) == 0 {
+			return "[]"
+		}
+		var b strings.Builder
+		b.WriteString("[\n")
+		for _, item := range v {
+			fmt.Fprintf(&b, "%s  %s\n", indent, bicepValue(item, indent+"  "))
+		}
+		b.WriteString(indent + "]")
+		return b.String()
+	case map[string]string:
+		items := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			items[key] = item
+		}
+		return bicepValue(items, indent)
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "{}"
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(&b, "%s  %s: %s\n", indent, bicepKey(key), bicepValue(v[key], indent+"  "))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	}
+	return bicepString(fmt.Sprint(value))
+}
+
+// bicepKey quotes object keys that are not identifiers
+func bicepKey(key string) string {
+	if bicepIdentifier.MatchString(key) {
+		return key
+	}
+	return bicepString(key)
+}
+
+// bicepString quotes a string, escaping what Bicep would otherwise interpret
+func bicepString(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "${", `\${`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value)
+	return "'" + value + "'"
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package review
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/replay"
+	k8ssecurity "github.com/Azure/mcp-kubernetes/pkg/security"
+	"sigs.k8s.io/yaml"
+)
+
+// manifestVerbs are the kubectl write commands whose server-side dry run prints the changed objects
+var manifestVerbs = []string{"create", "apply", "patch", "replace", "label", "annotate", "set", "expose", "run", "scale", "autoscale", "taint", "rollout"}
+
+// interactiveVerbs are kubectl commands that act on running containers rather than on objects
+var interactiveVerbs = []string{"exec", "cp", "attach", "port-forward"}
+
+// Binary returns the tool a command of the Kubernetes executors runs: helm and cilium commands start with
+// their binary, and kubectl commands may omit it
+func Binary(command string) string {
+	switch first, _, _ := strings.Cut(strings.TrimSpace(command), " "); first {
+	case KindHelm, KindCilium:
+		return first
+	}
+	return KindKubectl
+}
+
+// IsClusterWrite reports whether a kubectl, helm or cilium command changes the cluster
+func IsClusterWrite(command string) bool {
+	binary := Binary(command)
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(command), binary+" "))
+	if len(fields) == 0 {
+		return false
+	}
+	verb := fields[0]
+	switch binary {
+	case KindHelm:
+		return !slices.Contains(k8ssecurity.HelmReadOperations, verb)
+	case KindCilium:
+		return !slices.Contains(k8ssecurity.CiliumReadOperations, verb)
+	}
+	if verb == "rollout" && len(fields) > 1 && (fields[1] == "status" || fields[1] == "history") {
+		return false
+	}
+	return !slices.Contains(k8ssecurity.KubectlReadOperations, verb)
+}
+
+// ClusterChange builds the change for a kubectl, helm or cilium write command. For kubectl commands that
+// change objects, run is used to get the changed objects with a server-side dry run and the live objects,
+// so the change carries the manifest to commit and its diff against the cluster.
+func ClusterChange(command string, run func(command string) (string, error)) (Change, error) {
+	binary := Binary(command)
+	args := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), binary+" "))
+	fields := strings.Fields(args)
+	if binary == KindKubectl && len(fields) > 0 && slices.Contains(interactiveVerbs, fields[0]) {
+		return Change{}, Unsupported("kubectl " + fields[0])
+	}
+
+	change := Change{Kind: binary, Command: binary + " " + args}
+	if binary == KindKubectl && len(fields) > 0 && slices.Contains(manifestVerbs, fields[0]) && run != nil {
+		manifest, diff, notes := kubectlManifest(args, run)
+		if manifest != "" {
+			change.Artifacts = append(change.Artifacts, Artifact{
+				Format:      FormatManifest,
+				Description: "The objects as they are after the change; commit them to the repository your pipeline applies",
+				Content:     manifest,
+			})
+		}
+		change.Diff = diff
+		change.Notes = append(change.Notes, notes...)
+	}
+	change.Artifacts = append(change.Artifacts, Artifact{
+		Format:      FormatScript,
+		Description: "Run from a pipeline with access to the cluster",
+		Content:     Script(change.Command),
+	})
+	return change, nil
+}
+
+// kubectlManifest runs the command as a server-side dry run and returns the changed objects, and their diff
+// against the live object when the command changes a single existing object
+func kubectlManifest(args string, run func(string) (string, error)) (string, []string, []string) {
+	output, err := run(args + " --dry-run=server -o yaml")
+	if err != nil {
+		return "", nil, []string{fmt.Sprintf("The server-side dry run failed, so only the script is returned: %v", err)}
+	}
+	var object map[string]interface{}
+	if err := yaml.Unmarshal([]byte(output), &object); err != nil || object == nil {
+		return "", nil, []string{"The server-side dry run printed no objects, so only the script is returned"}
+	}
+	cleaned := cleanObject(object)
+	manifest, err := yaml.Marshal(cleaned)
+	if err != nil {
+		return "", nil, nil
+	}
+	kind, _ := object["kind"].(string)
+	if kind == "List" {
+		return string(manifest), nil, nil
+	}
+
+	metadata, _ := object["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	apiVersion, _ := object["apiVersion"].(string)
+	if name == "" || kind == "" {
+		return string(manifest), nil, nil
+	}
+	resource := strings.ToLower(kind)
+	if group, _, found := strings.Cut(apiVersion, "/"); found {
+		resource += "." + group
+	}
+	get := "get " + resource + "/" + name
+	if namespace != "" {
+		get += " --namespace " + namespace
+	}
+	live, err := run(get + " -o yaml")
+	if err != nil {
+		return string(manifest), nil, []string{fmt.Sprintf("%s %s does not exist yet; the manifest creates it", kind, name)}
+	}
+	var liveObject map[string]interface{}
+	if err := yaml.Unmarshal([]byte(live), &liveObject); err != nil || liveObject == nil {
+		return string(manifest), nil, nil
+	}
+	liveManifest, err := yaml.Marshal(cleanObject(liveObject))
+	if err != nil {
+		return string(manifest), nil, nil
+	}
+	return string(manifest), replay.Diff(strings.TrimSpace(string(liveManifest)), strings.TrimSpace(string(manifest))), nil
+}
+
+// cleanObject drops the status and the fields the API server sets, which do not belong in a committed manifest
+func cleanObject(object map[string]interface{}) map[string]interface{} {
+	delete(object, "status")
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"} {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			delete(annotations, "deployment.kubernetes.io/revision")
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	if items, ok := object["items"].([]interface{}); ok {
+		for _, item := range items {
+			if itemObject, ok := item.(map[string]interface{}); ok {
+				cleanObject(itemObject)
+			}
+		}
+	}
+	return object
+}
@@ -0,0 +1,109 @@
+// Package review implements review mode. In review mode write operations are not executed: each is
+// returned as the declarative artifacts (az CLI script, Bicep patch, kubectl manifest and diff) that
+// make the same change, for the user to apply through their own pipeline.
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDeferred is returned in place of the result of a write operation deferred for review. The
+// operation stops at its first write, since later steps may depend on what it would have returned.
+var ErrDeferred = errors.New("write operation deferred for review")
+
+// Kinds of changes, by the tool that would have made them
+const (
+	KindAz      = "az"
+	KindARM     = "arm"
+	KindKubectl = "kubectl"
+	KindHelm    = "helm"
+	KindCilium  = "cilium"
+)
+
+// Artifact formats
+const (
+	FormatAzScript = "az-cli-script"
+	FormatBicep    = "bicep"
+	FormatManifest = "kubectl-manifest"
+	FormatScript   = "shell-script"
+)
+
+// Artifact is a declarative or scripted equivalent of a deferred write
+type Artifact struct {
+	Format string `json:"format"`
+	// Description says how to apply the artifact
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content"`
+}
+
+// Change is a write operation deferred for review
+type Change struct {
+	Kind    string `json:"kind"`
+	Command string `json:"command"`
+	// Artifacts make the change, the most declarative first
+	Artifacts []Artifact `json:"artifacts"`
+	// Diff compares the live object with the object after the change (kubectl manifests only)
+	Diff  []string `json:"diff,omitempty"`
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Plan collects the changes deferred during one tool call. A nil Plan means review mode is off.
+type Plan struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// New creates an empty plan
+func New() *Plan {
+	return &Plan{}
+}
+
+// Defer records a change and returns the error that stops the operation in its place
+func (p *Plan) Defer(change Change) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, change)
+	return fmt.Errorf("%w: %s", ErrDeferred, change.Command)
+}
+
+// Changes returns the deferred changes in the order they were made
+func (p *Plan) Changes() []Change {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Change(nil), p.changes...)
+}
+
+// Unsupported is returned for operations that have no declarative equivalent, such as opening a shell
+func Unsupported(operation string) error {
+	return fmt.Errorf("%s has no declarative equivalent and is not available in review mode", operation)
+}
+
+// Report is the result of a tool call whose writes were deferred for review
+type Report struct {
+	Review  bool     `json:"review"`
+	Tool    string   `json:"tool"`
+	Changes []Change `json:"changes"`
+	Next    string   `json:"next"`
+}
+
+// Report renders the deferred changes of a tool call as its result
+func (p *Plan) Report(tool string) (string, error) {
+	report := Report{
+		Review:  true,
+		Tool:    tool,
+		Changes: p.Changes(),
+		Next: "Review mode is on, so nothing was changed. Apply the artifacts through your pipeline. The call stopped at " +
+			"its first write; call the tool again after the change is applied to review any later steps.",
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal review report: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,218 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPlanDeferAndReport(t *testing.T) {
+	var off *Plan
+	if off.Changes() != nil {
+		t.Fatalf("Expected a nil plan to have no changes")
+	}
+
+	plan := New()
+	err := plan.Defer(AzChange("aks stop -g rg -n cluster"))
+	if !errors.Is(err, ErrDeferred) {
+		t.Fatalf("Expected ErrDeferred, got %v", err)
+	}
+	report, err := plan.Report("az_aks_operations")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(report), &decoded); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if !decoded.Review || decoded.Tool != "az_aks_operations" || len(decoded.Changes) != 1 {
+		t.Fatalf("Unexpected report: %+v", decoded)
+	}
+	change := decoded.Changes[0]
+	if change.Command != "az aks stop -g rg -n cluster" || len(change.Artifacts) != 1 || change.Artifacts[0].Format != FormatAzScript {
+		t.Errorf("Expected only an az script for a command without a Bicep equivalent, got %+v", change)
+	}
+	if !strings.Contains(change.Artifacts[0].Content, "set -euo pipefail\n\naz aks stop -g rg -n cluster\n") {
+		t.Errorf("Unexpected script: %q", change.Artifacts[0].Content)
+	}
+}
+
+func TestAzChangeBicep(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected []string
+	}{
+		{
+			name:     "nodepool scale",
+			args:     "aks nodepool scale -g rg --cluster-name cluster -n pool1 -c 5",
+			expected: []string{"resource cluster 'Microsoft.ContainerService/managedClusters@2024-09-01' existing = {", "parent: cluster", "name: 'pool1'", "count: 5"},
+		},
+		{
+			name:     "cluster scale",
+			args:     "aks scale --resource-group rg --name cluster --nodepool-name system --node-count 2",
+			expected: []string{"name: 'system'", "count: 2"},
+		},
+		{
+			name:     "cluster upgrade",
+			args:     "aks upgrade -g rg -n cluster -k 1.30.4 --yes",
+			expected: []string{"resource cluster 'Microsoft.ContainerService/managedClusters@2024-09-01' = {", "kubernetesVersion: '1.30.4'"},
+		},
+		{
+			name:     "nodepool add with labels and zones",
+			args:     "aks nodepool add -g rg --cluster-name cluster -n gpu --node-vm-size Standard_NC6s_v3 --labels team=ml tier=gpu --zones 1 2 --enable-cluster-autoscaler --min-count 1 --max-count 3",
+			expected: []string{"vmSize: 'Standard_NC6s_v3'", "enableAutoScaling: true", "minCount: 1", "maxCount: 3", "team: 'ml'", "tier: 'gpu'", "'1'\n", "'2'\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := AzChange(tt.args)
+			if len(change.Artifacts) != 2 || change.Artifacts[0].Format != FormatBicep || change.Artifacts[1].Format != FormatAzScript {
+				t.Fatalf("Expected a Bicep patch and an az script, got %+v", change.Artifacts)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(change.Artifacts[0].Content, expected) {
+					t.Errorf("Expected Bicep to contain %q, got:\n%s", expected, change.Artifacts[0].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestAzChangeWithoutBicep(t *testing.T) {
+	for _, args := range []string{
+		"aks nodepool upgrade -g rg --cluster-name cluster -n pool1 --node-image-only",
+		"aks nodepool update -g rg --cluster-name cluster -n pool1 --update-cluster-autoscaler --max-count 5",
+		"aks scale -g rg -n cluster --node-count 3",
+		"aks nodepool scale --cluster-name cluster -n pool1 -c 5",
+	} {
+		change := AzChange(args)
+		if len(change.Artifacts) != 1 || change.Artifacts[0].Format != FormatAzScript {
+			t.Errorf("Expected only an az script for %q, got %+v", args, change.Artifacts)
+		}
+	}
+}
+
+func TestIsARMWrite(t *testing.T) {
+	tests := []struct {
+		method   string
+		url      string
+		expected bool
+	}{
+		{"GET", "https://management.azure.com/subscriptions/s/resourceGroups/rg?api-version=2021-04-01", false},
+		{"PUT", "https://management.azure.com/subscriptions/s/resourceGroups/rg?api-version=2021-04-01", true},
+		{"DELETE", "https://management.azure.com/subscriptions/s/resourceGroups/rg?api-version=2021-04-01", true},
+		{"POST", "https://management.azure.com/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/c/listClusterUserCredential?api-version=2024-09-01", false},
+		{"POST", "https://management.azure.com/providers/Microsoft.ResourceGraph/resources?api-version=2021-03-01", false},
+		{"POST", "https://management.azure.com/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/c/stop?api-version=2024-09-01", true},
+	}
+	for _, tt := range tests {
+		if got := IsARMWrite(tt.method, tt.url); got != tt.expected {
+			t.Errorf("IsARMWrite(%s, %s) = %v, expected %v", tt.method, tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestARMChange(t *testing.T) {
+	url := "https://management.azure.com/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg/securityRules/allow-https?api-version=2023-09-01"
+	body := []byte(`{"id":"x","properties":{"priority":100,"access":"Allow","destinationPortRange":"443"}}`)
+	change := ARMChange("put", url, body)
+	if change.Kind != KindARM || change.Command != "PUT "+url {
+		t.Fatalf("Unexpected change: %+v", change)
+	}
+	if len(change.Artifacts) != 2 || change.Artifacts[0].Format != FormatBicep {
+		t.Fatalf("Expected a Bicep patch and a script, got %+v", change.Artifacts)
+	}
+	bicep := change.Artifacts[0].Content
+	for _, expected := range []string{
+		"resource target 'Microsoft.Network/networkSecurityGroups/securityRules@2023-09-01' = {",
+		"name: 'nsg/allow-https'",
+		"priority: 100",
+		"access: 'Allow'",
+	} {
+		if !strings.Contains(bicep, expected) {
+			t.Errorf("Expected Bicep to contain %q, got:\n%s", expected, bicep)
+		}
+	}
+	if strings.Contains(bicep, "id:") {
+		t.Errorf("Expected read-only fields to be dropped, got:\n%s", bicep)
+	}
+	if !strings.Contains(change.Artifacts[1].Content, "az rest --method put --url '"+url+"' --body '") {
+		t.Errorf("Unexpected script: %s", change.Artifacts[1].Content)
+	}
+
+	deletion := ARMChange("DELETE", url, nil)
+	if len(deletion.Artifacts) != 1 || deletion.Artifacts[0].Format != FormatAzScript {
+		t.Errorf("Expected only a script for a DELETE, got %+v", deletion.Artifacts)
+	}
+}
+
+func TestIsClusterWrite(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected bool
+	}{
+		{"kubectl get pods -A", false},
+		{"get pods -A", false},
+		{"kubectl rollout status deployment/web", false},
+		{"kubectl rollout restart deployment/web", true},
+		{"kubectl scale deployment/web --replicas 3", true},
+		{"delete pod web-0", true},
+		{"helm list -A", false},
+		{"helm upgrade web ./chart", true},
+		{"cilium status", false},
+		{"cilium install", true},
+	}
+	for _, tt := range tests {
+		if got := IsClusterWrite(tt.command); got != tt.expected {
+			t.Errorf("IsClusterWrite(%q) = %v, expected %v", tt.command, got, tt.expected)
+		}
+	}
+}
+
+func TestClusterChange(t *testing.T) {
+	var commands []string
+	run := func(command string) (string, error) {
+		commands = append(commands, command)
+		if strings.HasPrefix(command, "get ") {
+			return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: default\n  resourceVersion: \"42\"\nspec:\n  replicas: 2\nstatus:\n  readyReplicas: 2\n", nil
+		}
+		return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: default\n  resourceVersion: \"43\"\nspec:\n  replicas: 3\n", nil
+	}
+
+	change, err := ClusterChange("kubectl scale deployment/web --replicas 3 -n default", run)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedCommands := []string{
+		"scale deployment/web --replicas 3 -n default --dry-run=server -o yaml",
+		"get deployment.apps/web --namespace default -o yaml",
+	}
+	if fmt.Sprint(commands) != fmt.Sprint(expectedCommands) {
+		t.Errorf("Expected commands %v, got %v", expectedCommands, commands)
+	}
+	if len(change.Artifacts) != 2 || change.Artifacts[0].Format != FormatManifest || change.Artifacts[1].Format != FormatScript {
+		t.Fatalf("Expected a manifest and a script, got %+v", change.Artifacts)
+	}
+	if strings.Contains(change.Artifacts[0].Content, "resourceVersion") {
+		t.Errorf("Expected server-set fields to be dropped, got:\n%s", change.Artifacts[0].Content)
+	}
+	diff := strings.Join(change.Diff, "\n")
+	if !strings.Contains(diff, "replicas: 2") || !strings.Contains(diff, "replicas: 3") || strings.Contains(diff, "readyReplicas") {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+
+	helm, err := ClusterChange("helm uninstall web", run)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if helm.Kind != KindHelm || len(helm.Artifacts) != 1 || helm.Artifacts[0].Content != Script("helm uninstall web") {
+		t.Errorf("Expected only a script for helm, got %+v", helm)
+	}
+
+	if _, err := ClusterChange("exec web-0 -- sh", run); err == nil || !strings.Contains(err.Error(), "not available in review mode") {
+		t.Errorf("Expected exec to be refused, got %v", err)
+	}
+}
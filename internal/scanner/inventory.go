@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// inventoryBucket is the store bucket of the cluster inventory of the last scan, under inventoryKey
+const (
+	inventoryBucket = "scanner-inventory"
+	inventoryKey    = "clusters"
+)
+
+// Parts of the scan state passed to the change hook
+const (
+	ChangedInventory = "inventory"
+	ChangedFindings  = "findings"
+)
+
+// Inventory is the clusters the last scan found, with their provisioning and power state
+type Inventory struct {
+	Clusters  []ClusterState `json:"clusters"`
+	ScannedAt time.Time      `json:"scannedAt"`
+}
+
+// ClusterState is the state of one cluster in the inventory
+type ClusterState struct {
+	ID                string          `json:"id"`
+	Name              string          `json:"name"`
+	SubscriptionID    string          `json:"subscriptionId"`
+	ResourceGroup     string          `json:"resourceGroup"`
+	ProvisioningState string          `json:"provisioningState"`
+	PowerState        string          `json:"powerState"`
+	NodePools         []NodePoolState `json:"nodePools"`
+}
+
+// NodePoolState is the state of one node pool of a cluster in the inventory
+type NodePoolState struct {
+	Name              string `json:"name"`
+	ProvisioningState string `json:"provisioningState"`
+	Count             int    `json:"count"`
+}
+
+// WithChangeHook makes the scanner call changed with ChangedInventory when a scan finds clusters or cluster
+// states that differ from the previous scan, and with ChangedFindings when findings open or clear.
+func WithChangeHook(changed func(part string)) Option {
+	return func(s *Scanner) {
+		s.changed = changed
+	}
+}
+
+// BuildInventory builds the inventory clusters from Resource Graph cluster rows, sorted by ID
+func BuildInventory(clusters []map[string]interface{}) []ClusterState {
+	states := make([]ClusterState, 0, len(clusters))
+	for _, row := range clusters {
+		state := ClusterState{
+			ID:                rowString(row, "id"),
+			Name:              rowString(row, "name"),
+			SubscriptionID:    rowString(row, "subscriptionId"),
+			ResourceGroup:     rowString(row, "resourceGroup"),
+			ProvisioningState: rowString(row, "provisioningState"),
+			PowerState:        rowString(row, "powerState"),
+			NodePools:         []NodePoolState{},
+		}
+		pools, _ := row["agentPools"].([]interface{})
+		for _, raw := range pools {
+			pool, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			count, _ := pool["count"].(float64)
+			state.NodePools = append(state.NodePools, NodePoolState{
+				Name:              rowString(pool, "name"),
+				ProvisioningState: rowString(pool, "provisioningState"),
+				Count:             int(count),
+			})
+		}
+		sort.Slice(state.NodePools, func(i, j int) bool { return state.NodePools[i].Name < state.NodePools[j].Name })
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return strings.ToLower(states[i].ID) < strings.ToLower(states[j].ID) })
+	return states
+}
+
+// Inventory returns the cluster inventory of the last scan, and false when no scan completed yet
+func (s *Scanner) Inventory() (Inventory, bool, error) {
+	inventory, err := s.inventory.Load(inventoryKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return Inventory{}, false, nil
+	}
+	return inventory, err == nil, err
+}
+
+// OpenFindings returns the findings that are open, ordered by ID
+func (s *Scanner) OpenFindings() ([]Finding, error) {
+	return s.findings.List()
+}
+
+// saveInventory stores the inventory of a scan and reports whether its clusters changed since the last scan
+func (s *Scanner) saveInventory(clusters []ClusterState, now time.Time) (bool, error) {
+	previous, found, err := s.Inventory()
+	if err != nil {
+		return false, err
+	}
+	if err := s.inventory.Save(inventoryKey, Inventory{Clusters: clusters, ScannedAt: now}); err != nil {
+		return false, err
+	}
+	return !found || !reflect.DeepEqual(previous.Clusters, clusters), nil
+}
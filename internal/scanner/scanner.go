@@ -0,0 +1,260 @@
+// Package scanner periodically checks the AKS clusters the server's credential can read for high-severity
+// problems, such as a failed cluster or node pool or a cluster Resource Health reports unavailable, and
+// optionally for credentials nearing expiry, and reports each problem once until it clears.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+// DefaultInterval is how often clusters are scanned when no interval is configured
+const DefaultInterval = 5 * time.Minute
+
+// findingsBucket is the store bucket of the open findings, keyed by finding ID
+const findingsBucket = "scanner-findings"
+
+// SeverityHigh is the severity of the findings the scanner reports
+const SeverityHigh = "high"
+
+// Kinds of findings
+const (
+	KindClusterFailed      = "cluster_failed"
+	KindClusterUnavailable = "cluster_unavailable"
+	KindNodePoolFailed     = "nodepool_failed"
+)
+
+// clusterQuery lists every AKS cluster with its provisioning state and the provisioning state of its node pools
+const clusterQuery = `resources
+| where type =~ 'microsoft.containerservice/managedclusters'
+| project id, name, subscriptionId, resourceGroup,
+  provisioningState = tostring(properties.provisioningState),
+  powerState = tostring(properties.powerState.code),
+  servicePrincipal = tostring(properties.servicePrincipalProfile.clientId),
+  agentPools = properties.agentPoolProfiles`
+
+// healthQuery lists the clusters Resource Health reports unavailable
+const healthQuery = `healthresources
+| where type =~ 'microsoft.resourcehealth/availabilitystatuses'
+| where tostring(properties.targetResourceType) =~ 'microsoft.containerservice/managedclusters'
+| where tostring(properties.availabilityState) =~ 'Unavailable'
+| project targetResourceId = tolower(tostring(properties.targetResourceId)),
+  summary = tostring(properties.summary)`
+
+// Reader queries Azure Resource Graph. *azureclient.AzureClient implements it.
+type Reader interface {
+	QueryResourceGraph(ctx context.Context, query string, subscriptions []string) ([]map[string]interface{}, error)
+}
+
+// Finding is a problem on a cluster. Findings about the cluster of the server's kubeconfig have no ClusterID
+// and carry the kubeconfig context as ClusterName.
+type Finding struct {
+	ID            string `json:"id"`
+	Kind          string `json:"kind"`
+	Severity      string `json:"severity"`
+	ClusterID     string `json:"clusterId"`
+	ClusterName   string `json:"clusterName"`
+	ResourceGroup string `json:"resourceGroup"`
+	NodePool      string `json:"nodePool,omitempty"`
+	// Namespace and Resource name the secret or application of a credential expiry finding
+	Namespace  string     `json:"namespace,omitempty"`
+	Resource   string     `json:"resource,omitempty"`
+	Message    string     `json:"message"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	DetectedAt time.Time  `json:"detectedAt"`
+}
+
+// Scanner finds high-severity problems and passes each new one to its notify function. Open findings are
+// kept in the store, so a problem is reported again only after it cleared or when the store was reset.
+type Scanner struct {
+	reader   Reader
+	findings *store.Repository[Finding]
+	// inventory holds the clusters of the last scan
+	inventory *store.Repository[Inventory]
+	interval  time.Duration
+	notify    func(Finding)
+	now       func() time.Time
+	// secrets configures the credential expiry scan; nil disables it
+	secrets *SecretExpiry
+	// changed is called when the inventory or the open findings change; nil disables it
+	changed func(part string)
+}
+
+// New creates a scanner that scans every interval and calls notify for each new finding
+func New(reader Reader, st store.Store, interval time.Duration, notify func(Finding), opts ...Option) *Scanner {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	s := &Scanner{
+		reader:    reader,
+		findings:  store.NewRepository[Finding](st, findingsBucket),
+		inventory: store.NewRepository[Inventory](st, inventoryBucket),
+		interval:  interval,
+		notify:    notify,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run scans right away and then every interval until ctx is cancelled
+func (s *Scanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Scan(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[SCANNER] scan failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan checks the clusters once, notifies the findings that were not open before and returns them.
+// Open findings that were not found again are removed, unless their source could not be read. The
+// clusters found are kept as the inventory.
+func (s *Scanner) Scan(ctx context.Context) ([]Finding, error) {
+	clusters, err := s.reader.QueryResourceGraph(ctx, clusterQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AKS clusters: %w", err)
+	}
+	// failed holds the kinds of findings whose source could not be read; their open findings are kept
+	failed := map[string]bool{}
+	unavailable, err := s.reader.QueryResourceGraph(ctx, healthQuery, nil)
+	if err != nil {
+		// Failed clusters and node pools are still reported without Resource Health
+		log.Printf("[SCANNER] failed to read Resource Health: %v", err)
+		unavailable = nil
+		failed[KindClusterUnavailable] = true
+	}
+
+	now := s.now()
+	inventoryChanged, err := s.saveInventory(BuildInventory(clusters), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the cluster inventory: %w", err)
+	}
+	if inventoryChanged && s.changed != nil {
+		s.changed(ChangedInventory)
+	}
+	current := DetectFindings(clusters, unavailable, now)
+	if s.secrets != nil {
+		expiring, failedKinds := s.scanSecrets(ctx, clusters, now)
+		current = append(current, expiring...)
+		for kind := range failedKinds {
+			failed[kind] = true
+		}
+	}
+	open, err := s.findings.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open findings: %w", err)
+	}
+	known := make(map[string]bool, len(open))
+	for _, finding := range open {
+		known[finding.ID] = true
+	}
+
+	var added []Finding
+	cleared := 0
+	defer func() {
+		if (len(added) > 0 || cleared > 0) && s.changed != nil {
+			s.changed(ChangedFindings)
+		}
+	}()
+	seen := make(map[string]bool, len(current))
+	for _, finding := range current {
+		seen[finding.ID] = true
+		if known[finding.ID] {
+			continue
+		}
+		if err := s.findings.Save(finding.ID, finding); err != nil {
+			return added, err
+		}
+		added = append(added, finding)
+		if s.notify != nil {
+			s.notify(finding)
+		}
+	}
+	for _, finding := range open {
+		if !seen[finding.ID] && !failed[finding.Kind] {
+			if err := s.findings.Delete(finding.ID); err != nil {
+				return added, err
+			}
+			cleared++
+		}
+	}
+	return added, nil
+}
+
+// DetectFindings builds the findings from Resource Graph cluster rows and the rows of clusters
+// Resource Health reports unavailable. Stopped clusters are skipped.
+func DetectFindings(clusters, unavailable []map[string]interface{}, now time.Time) []Finding {
+	health := make(map[string]string, len(unavailable))
+	for _, row := range unavailable {
+		health[strings.ToLower(rowString(row, "targetResourceId"))] = rowString(row, "summary")
+	}
+
+	var findings []Finding
+	for _, row := range clusters {
+		if strings.EqualFold(rowString(row, "powerState"), "Stopped") {
+			continue
+		}
+		id := rowString(row, "id")
+		finding := func(kind, pool, message string) Finding {
+			findingID := strings.ToLower(id) + "/" + kind
+			if pool != "" {
+				findingID += "/" + strings.ToLower(pool)
+			}
+			return Finding{
+				ID:            findingID,
+				Kind:          kind,
+				Severity:      SeverityHigh,
+				ClusterID:     id,
+				ClusterName:   rowString(row, "name"),
+				ResourceGroup: rowString(row, "resourceGroup"),
+				NodePool:      pool,
+				Message:       message,
+				DetectedAt:    now,
+			}
+		}
+
+		if strings.EqualFold(rowString(row, "provisioningState"), "Failed") {
+			findings = append(findings, finding(KindClusterFailed, "",
+				fmt.Sprintf("The last operation on cluster %s failed", rowString(row, "name"))))
+		}
+		if summary, ok := health[strings.ToLower(id)]; ok {
+			message := fmt.Sprintf("Resource Health reports cluster %s unavailable", rowString(row, "name"))
+			if summary != "" {
+				message += ": " + summary
+			}
+			findings = append(findings, finding(KindClusterUnavailable, "", message))
+		}
+		pools, _ := row["agentPools"].([]interface{})
+		for _, raw := range pools {
+			pool, ok := raw.(map[string]interface{})
+			if !ok || !strings.EqualFold(rowString(pool, "provisioningState"), "Failed") {
+				continue
+			}
+			name := rowString(pool, "name")
+			findings = append(findings, finding(KindNodePoolFailed, name,
+				fmt.Sprintf("The last operation on node pool %s of cluster %s failed", name, rowString(row, "name"))))
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].ID < findings[j].ID })
+	return findings
+}
+
+func rowString(row map[string]interface{}, key string) string {
+	value, _ := row[key].(string)
+	return value
+}
@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+type fakeReader struct {
+	clusters    []map[string]interface{}
+	unavailable []map[string]interface{}
+	healthErr   error
+}
+
+func (f *fakeReader) QueryResourceGraph(_ context.Context, query string, _ []string) ([]map[string]interface{}, error) {
+	if strings.HasPrefix(query, "healthresources") {
+		return f.unavailable, f.healthErr
+	}
+	return f.clusters, nil
+}
+
+func clusterRow(name, state string, pools ...map[string]interface{}) map[string]interface{} {
+	agentPools := make([]interface{}, len(pools))
+	for i, pool := range pools {
+		agentPools[i] = pool
+	}
+	return map[string]interface{}{
+		"id":                "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/" + name,
+		"name":              name,
+		"resourceGroup":     "rg",
+		"provisioningState": state,
+		"powerState":        "Running",
+		"agentPools":        agentPools,
+	}
+}
+
+func TestDetectFindings(t *testing.T) {
+	clusters := []map[string]interface{}{
+		clusterRow("healthy", "Succeeded", map[string]interface{}{"name": "np1", "provisioningState": "Succeeded"}),
+		clusterRow("broken", "Failed", map[string]interface{}{"name": "np1", "provisioningState": "Failed"}),
+		clusterRow("down", "Succeeded"),
+	}
+	stopped := clusterRow("stopped", "Failed")
+	stopped["powerState"] = "Stopped"
+	clusters = append(clusters, stopped)
+	unavailable := []map[string]interface{}{{
+		"targetResourceId": strings.ToLower(clusterRow("down", "")["id"].(string)),
+		"summary":          "The API server is not reachable",
+	}}
+
+	findings := DetectFindings(clusters, unavailable, time.Now())
+	kinds := map[string]string{}
+	for _, finding := range findings {
+		if finding.Severity != SeverityHigh {
+			t.Errorf("Expected high severity, got %+v", finding)
+		}
+		kinds[finding.ClusterName+"/"+finding.NodePool+"/"+finding.Kind] = finding.Message
+	}
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %+v", findings)
+	}
+	for _, key := range []string{"broken//" + KindClusterFailed, "broken/np1/" + KindNodePoolFailed, "down//" + KindClusterUnavailable} {
+		if _, ok := kinds[key]; !ok {
+			t.Errorf("Expected finding %s, got %v", key, kinds)
+		}
+	}
+	if !strings.Contains(kinds["down//"+KindClusterUnavailable], "not reachable") {
+		t.Errorf("Expected the Resource Health summary in the message, got %q", kinds["down//"+KindClusterUnavailable])
+	}
+}
+
+func TestScanNotifiesOnce(t *testing.T) {
+	reader := &fakeReader{clusters: []map[string]interface{}{clusterRow("broken", "Failed")}, healthErr: fmt.Errorf("forbidden")}
+	var notified []Finding
+	sc := New(reader, store.NewMemoryStore(), 0, func(finding Finding) { notified = append(notified, finding) })
+	if sc.interval != DefaultInterval {
+		t.Errorf("Expected the default interval, got %v", sc.interval)
+	}
+
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(notified) != 1 || notified[0].Kind != KindClusterFailed {
+		t.Fatalf("Expected one cluster_failed notification, got %+v", notified)
+	}
+
+	// An open finding is not notified again
+	if added, err := sc.Scan(context.Background()); err != nil || len(added) != 0 || len(notified) != 1 {
+		t.Fatalf("Expected no new findings, got %+v (%v)", added, err)
+	}
+
+	// A finding that cleared is notified again when it recurs
+	reader.clusters = []map[string]interface{}{clusterRow("broken", "Succeeded")}
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reader.clusters = []map[string]interface{}{clusterRow("broken", "Failed")}
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(notified) != 2 {
+		t.Errorf("Expected the recurring finding to be notified again, got %+v", notified)
+	}
+}
+
+func TestScanKeepsInventory(t *testing.T) {
+	pool := map[string]interface{}{"name": "np1", "provisioningState": "Succeeded", "count": float64(3)}
+	reader := &fakeReader{clusters: []map[string]interface{}{clusterRow("b", "Succeeded", pool), clusterRow("a", "Succeeded")}}
+	var changes []string
+	sc := New(reader, store.NewMemoryStore(), 0, nil, WithChangeHook(func(part string) { changes = append(changes, part) }))
+	if _, found, err := sc.Inventory(); found || err != nil {
+		t.Fatalf("Expected no inventory before the first scan, got %v (%v)", found, err)
+	}
+
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	inventory, found, err := sc.Inventory()
+	if !found || err != nil {
+		t.Fatalf("Expected an inventory, got %v (%v)", found, err)
+	}
+	if len(inventory.Clusters) != 2 || inventory.Clusters[0].Name != "a" || inventory.Clusters[1].NodePools[0].Count != 3 {
+		t.Errorf("Unexpected inventory %+v", inventory)
+	}
+
+	// An unchanged scan reports no change; a cluster that fails changes the inventory and opens a finding
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reader.clusters[0] = clusterRow("b", "Failed", pool)
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(changes, ",") != "inventory,inventory,findings" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+	if open, err := sc.OpenFindings(); err != nil || len(open) != 1 {
+		t.Errorf("Expected one open finding, got %+v (%v)", open, err)
+	}
+}
@@ -0,0 +1,388 @@
+package scanner
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/directory"
+)
+
+// DefaultSecretExpiryWindow is how far ahead expiring credentials are reported when no window is configured
+const DefaultSecretExpiryWindow = 30 * 24 * time.Hour
+
+// SeverityMedium is the severity of credentials that expire within the window but have not expired yet
+const SeverityMedium = "medium"
+
+// Kinds of credential expiry findings
+const (
+	KindTLSSecretExpiring                  = "tls_secret_expiring"
+	KindServicePrincipalSecretExpiring     = "service_principal_secret_expiring"
+	KindWorkloadIdentityCredentialExpiring = "workload_identity_credential_expiring"
+)
+
+// tlsSecretColumns selects the namespace, name, certificate and cert-manager certificate of each TLS secret,
+// so private keys are never read from the API server
+const tlsSecretColumns = `custom-columns=NAMESPACE:.metadata.namespace,NAME:.metadata.name,CERT:.data.tls\.crt,MANAGED:.metadata.annotations.cert-manager\.io/certificate-name`
+
+// serviceAccountColumns selects the workload identity client ID of each service account
+const serviceAccountColumns = `custom-columns=NAMESPACE:.metadata.namespace,NAME:.metadata.name,CLIENT:.metadata.annotations.azure\.workload\.identity/client-id`
+
+// clientIDPattern matches application (client) IDs
+var clientIDPattern = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// SecretExpiry configures the scan for expiring credentials
+type SecretExpiry struct {
+	// Window is how far ahead expiring credentials are reported
+	Window time.Duration
+	// Graph reads the credentials of service principals and app registrations; nil skips them
+	Graph directory.GraphCaller
+	// Kubectl runs a kubectl command against the server's kubeconfig; nil skips the in-cluster credentials
+	Kubectl func(args string) (string, error)
+	// NamespaceFlags are the kubectl flags covering the namespaces the server may read
+	NamespaceFlags []string
+}
+
+// Option configures a Scanner
+type Option func(*Scanner)
+
+// WithSecretExpiry adds a scan for TLS secrets, cluster service principal secrets and the credentials of
+// workload identity app registrations that expire within the window
+func WithSecretExpiry(expiry SecretExpiry) Option {
+	return func(s *Scanner) {
+		if expiry.Window <= 0 {
+			expiry.Window = DefaultSecretExpiryWindow
+		}
+		if len(expiry.NamespaceFlags) == 0 {
+			expiry.NamespaceFlags = []string{"--all-namespaces"}
+		}
+		s.secrets = &expiry
+	}
+}
+
+// Expiry is a credential and when it expires
+type Expiry struct {
+	Namespace string
+	Name      string
+	// ManagedBy names what renews the credential, such as a cert-manager Certificate
+	ManagedBy string
+	NotAfter  time.Time
+}
+
+// AppCredentials are the client secrets and certificates of an app registration or service principal
+type AppCredentials struct {
+	DisplayName         string       `json:"displayName"`
+	PasswordCredentials []credential `json:"passwordCredentials"`
+	KeyCredentials      []credential `json:"keyCredentials"`
+}
+
+type credential struct {
+	EndDateTime time.Time `json:"endDateTime"`
+}
+
+// newest returns the latest end date of the credentials, or false when there are none
+func newest(credentials []credential) (time.Time, bool) {
+	var latest time.Time
+	for _, c := range credentials {
+		if c.EndDateTime.After(latest) {
+			latest = c.EndDateTime
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// scanSecrets returns the credential expiry findings and the kinds whose source could not be read, so their
+// open findings are kept rather than cleared
+func (s *Scanner) scanSecrets(ctx context.Context, clusters []map[string]interface{}, now time.Time) ([]Finding, map[string]bool) {
+	expiry := s.secrets
+	failed := map[string]bool{}
+	var findings []Finding
+
+	if expiry.Kubectl != nil {
+		kubeContext := ""
+		if output, err := expiry.Kubectl("config current-context"); err == nil {
+			kubeContext = strings.TrimSpace(output)
+		}
+
+		var secrets []Expiry
+		for _, flag := range expiry.NamespaceFlags {
+			output, err := expiry.Kubectl("get secrets " + flag + " --field-selector type=kubernetes.io/tls --no-headers -o '" + tlsSecretColumns + "'")
+			if err != nil {
+				log.Printf("[SCANNER] failed to list TLS secrets: %v", err)
+				failed[KindTLSSecretExpiring] = true
+				break
+			}
+			secrets = append(secrets, ParseTLSSecrets(output)...)
+		}
+		if !failed[KindTLSSecretExpiring] {
+			findings = append(findings, TLSSecretFindings(kubeContext, secrets, expiry.Window, now)...)
+		}
+
+		if expiry.Graph != nil {
+			accounts := map[string][]string{}
+			for _, flag := range expiry.NamespaceFlags {
+				output, err := expiry.Kubectl("get serviceaccounts " + flag + " --no-headers -o '" + serviceAccountColumns + "'")
+				if err != nil {
+					log.Printf("[SCANNER] failed to list workload identity service accounts: %v", err)
+					failed[KindWorkloadIdentityCredentialExpiring] = true
+					break
+				}
+				for clientID, names := range ParseWorkloadIdentityAccounts(output) {
+					accounts[clientID] = append(accounts[clientID], names...)
+				}
+			}
+			for _, clientID := range sortedKeys(accounts) {
+				if failed[KindWorkloadIdentityCredentialExpiring] {
+					break
+				}
+				app, err := lookupCredentials(ctx, expiry.Graph, "applications", clientID)
+				if err != nil {
+					log.Printf("[SCANNER] failed to read the credentials of application %s: %v", clientID, err)
+					failed[KindWorkloadIdentityCredentialExpiring] = true
+					break
+				}
+				// Managed identities have no app registration and no credentials that expire
+				if app != nil {
+					findings = append(findings, workloadIdentityFindings(kubeContext, clientID, accounts[clientID], *app, expiry.Window, now)...)
+				}
+			}
+		}
+	}
+
+	if expiry.Graph != nil {
+		byClient := map[string][]map[string]interface{}{}
+		for _, row := range clusters {
+			clientID := rowString(row, "servicePrincipal")
+			if strings.EqualFold(rowString(row, "powerState"), "Stopped") || !clientIDPattern.MatchString(clientID) {
+				continue
+			}
+			byClient[strings.ToLower(clientID)] = append(byClient[strings.ToLower(clientID)], row)
+		}
+		for _, clientID := range sortedKeys(byClient) {
+			app, err := lookupCredentials(ctx, expiry.Graph, "applications", clientID)
+			if err == nil && app == nil {
+				// The service principal of an app registered in another tenant carries its own credentials
+				app, err = lookupCredentials(ctx, expiry.Graph, "servicePrincipals", clientID)
+			}
+			if err != nil {
+				log.Printf("[SCANNER] failed to read the credentials of service principal %s: %v", clientID, err)
+				failed[KindServicePrincipalSecretExpiring] = true
+				break
+			}
+			if app == nil {
+				continue
+			}
+			for _, row := range byClient[clientID] {
+				if finding, ok := servicePrincipalFinding(row, clientID, *app, expiry.Window, now); ok {
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+	return findings, failed
+}
+
+// lookupCredentials reads the credentials of the app registration or service principal (collection is
+// applications or servicePrincipals) with the client ID. It returns nil when there is none.
+func lookupCredentials(ctx context.Context, graph directory.GraphCaller, collection, clientID string) (*AppCredentials, error) {
+	path := "/v1.0/" + collection + "?$filter=" + url.PathEscape("appId eq '"+clientID+"'") + "&$select=displayName,passwordCredentials,keyCredentials"
+	body, err := graph.CallGraph(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Value []AppCredentials `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", collection, err)
+	}
+	if len(response.Value) == 0 {
+		return nil, nil
+	}
+	return &response.Value[0], nil
+}
+
+// ParseTLSSecrets extracts the leaf certificate expiry of each secret from kubectl get secrets output in the
+// tlsSecretColumns format (without headers). Secrets without a readable certificate are skipped.
+func ParseTLSSecrets(output string) []Expiry {
+	var secrets []Expiry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[2] == "<none>" {
+			continue
+		}
+		pemData, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			continue
+		}
+		for {
+			var block *pem.Block
+			block, pemData = pem.Decode(pemData)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				secret := Expiry{Namespace: fields[0], Name: fields[1], NotAfter: cert.NotAfter.UTC()}
+				if fields[3] != "<none>" {
+					secret.ManagedBy = "cert-manager Certificate " + fields[3]
+				}
+				secrets = append(secrets, secret)
+			}
+			break
+		}
+	}
+	return secrets
+}
+
+// ParseWorkloadIdentityAccounts maps the lowercased client IDs in kubectl get serviceaccounts output in the
+// serviceAccountColumns format (without headers) to the "namespace/name" of the service accounts using them
+func ParseWorkloadIdentityAccounts(output string) map[string][]string {
+	accounts := map[string][]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !clientIDPattern.MatchString(fields[2]) {
+			continue
+		}
+		clientID := strings.ToLower(fields[2])
+		accounts[clientID] = append(accounts[clientID], fields[0]+"/"+fields[1])
+	}
+	return accounts
+}
+
+// TLSSecretFindings reports the TLS secrets of the cluster of the kubeconfig context whose certificate
+// expires within the window
+func TLSSecretFindings(kubeContext string, secrets []Expiry, window time.Duration, now time.Time) []Finding {
+	var findings []Finding
+	for _, secret := range secrets {
+		severity, state, ok := expiryState(secret.NotAfter, window, now)
+		if !ok {
+			continue
+		}
+		message := fmt.Sprintf("The certificate in TLS secret %s/%s %s", secret.Namespace, secret.Name, describeExpiry(secret.NotAfter, now))
+		if secret.ManagedBy != "" {
+			message += fmt.Sprintf("; it is managed by %s, which should have renewed it, so check the Certificate's status", secret.ManagedBy)
+		}
+		notAfter := secret.NotAfter
+		findings = append(findings, Finding{
+			ID:          fmt.Sprintf("kubeconfig/%s/%s/%s/%s/%s", strings.ToLower(kubeContext), KindTLSSecretExpiring, secret.Namespace, secret.Name, state),
+			Kind:        KindTLSSecretExpiring,
+			Severity:    severity,
+			ClusterName: kubeContext,
+			Namespace:   secret.Namespace,
+			Resource:    secret.Name,
+			Message:     message,
+			ExpiresAt:   &notAfter,
+			DetectedAt:  now,
+		})
+	}
+	return findings
+}
+
+// servicePrincipalFinding reports a cluster whose service principal has no client secret valid beyond the window
+func servicePrincipalFinding(row map[string]interface{}, clientID string, app AppCredentials, window time.Duration, now time.Time) (Finding, bool) {
+	notAfter, ok := newest(app.PasswordCredentials)
+	if !ok {
+		return Finding{}, false
+	}
+	severity, state, ok := expiryState(notAfter, window, now)
+	if !ok {
+		return Finding{}, false
+	}
+	name := appName(app, clientID)
+	id := rowString(row, "id")
+	return Finding{
+		ID:            fmt.Sprintf("%s/%s/%s", strings.ToLower(id), KindServicePrincipalSecretExpiring, state),
+		Kind:          KindServicePrincipalSecretExpiring,
+		Severity:      severity,
+		ClusterID:     id,
+		ClusterName:   rowString(row, "name"),
+		ResourceGroup: rowString(row, "resourceGroup"),
+		Resource:      clientID,
+		Message: fmt.Sprintf("The newest client secret of service principal %s used by cluster %s %s; reset the credential with az aks update-credentials",
+			name, rowString(row, "name"), describeExpiry(notAfter, now)),
+		ExpiresAt:  &notAfter,
+		DetectedAt: now,
+	}, true
+}
+
+// workloadIdentityFindings reports the client secrets and certificates of an app registration used through
+// workload identity that have no newer credential of the same type valid beyond the window. Federated
+// credentials themselves do not expire, but workloads and pipelines often fall back to these credentials.
+func workloadIdentityFindings(kubeContext, clientID string, accounts []string, app AppCredentials, window time.Duration, now time.Time) []Finding {
+	var findings []Finding
+	for _, credentials := range []struct {
+		kind string
+		list []credential
+	}{{"client secret", app.PasswordCredentials}, {"certificate", app.KeyCredentials}} {
+		notAfter, ok := newest(credentials.list)
+		if !ok {
+			continue
+		}
+		severity, state, ok := expiryState(notAfter, window, now)
+		if !ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			ID:          fmt.Sprintf("kubeconfig/%s/%s/%s/%s/%s", strings.ToLower(kubeContext), KindWorkloadIdentityCredentialExpiring, clientID, strings.ReplaceAll(credentials.kind, " ", "-"), state),
+			Kind:        KindWorkloadIdentityCredentialExpiring,
+			Severity:    severity,
+			ClusterName: kubeContext,
+			Resource:    clientID,
+			Message: fmt.Sprintf("The newest %s of app registration %s, used through workload identity by service accounts %s, %s",
+				credentials.kind, appName(app, clientID), strings.Join(accounts, ", "), describeExpiry(notAfter, now)),
+			ExpiresAt:  &notAfter,
+			DetectedAt: now,
+		})
+	}
+	return findings
+}
+
+// expiryState returns the severity and ID suffix of a credential expiring at notAfter, or false when it is
+// valid beyond the window. The suffix changes when the credential expires, so that is reported again.
+func expiryState(notAfter time.Time, window time.Duration, now time.Time) (string, string, bool) {
+	switch {
+	case !notAfter.After(now):
+		return SeverityHigh, "expired", true
+	case notAfter.Before(now.Add(window)):
+		return SeverityMedium, "expiring", true
+	}
+	return "", "", false
+}
+
+func describeExpiry(notAfter, now time.Time) string {
+	if !notAfter.After(now) {
+		return "expired on " + notAfter.UTC().Format(time.DateOnly)
+	}
+	days := int(math.Floor(notAfter.Sub(now).Hours() / 24))
+	return fmt.Sprintf("expires on %s (in %d days)", notAfter.UTC().Format(time.DateOnly), days)
+}
+
+func appName(app AppCredentials, clientID string) string {
+	if app.DisplayName == "" {
+		return clientID
+	}
+	return fmt.Sprintf("%s (%s)", app.DisplayName, clientID)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/store"
+)
+
+var testNow = time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+// certBase64 creates a base64-encoded self-signed certificate PEM expiring at notAfter, as in a secret's tls.crt
+func certBase64(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "web.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// fakeGraph answers credential lookups by client ID and records the paths requested
+type fakeGraph struct {
+	apps  map[string]string
+	sps   map[string]string
+	err   error
+	paths []string
+}
+
+func (f *fakeGraph) CallGraph(_ context.Context, _, path string, _ interface{}) ([]byte, error) {
+	f.paths = append(f.paths, path)
+	if f.err != nil {
+		return nil, f.err
+	}
+	objects := f.apps
+	if strings.HasPrefix(path, "/v1.0/servicePrincipals") {
+		objects = f.sps
+	}
+	for clientID, object := range objects {
+		if strings.Contains(path, clientID) {
+			return []byte(`{"value": [` + object + `]}`), nil
+		}
+	}
+	return []byte(`{"value": []}`), nil
+}
+
+// fakeKubectl returns canned output for commands starting with a prefix
+type fakeKubectl map[string]string
+
+func (f fakeKubectl) run(args string) (string, error) {
+	for prefix, output := range f {
+		if strings.HasPrefix(args, prefix) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected command %s", args)
+}
+
+func credentials(name string, secretEnds ...time.Time) string {
+	var secrets []string
+	for _, end := range secretEnds {
+		secrets = append(secrets, `{"endDateTime": "`+end.Format(time.RFC3339)+`"}`)
+	}
+	return `{"displayName": "` + name + `", "passwordCredentials": [` + strings.Join(secrets, ",") + `], "keyCredentials": []}`
+}
+
+const (
+	clusterSP = "11111111-1111-1111-1111-111111111111"
+	wiApp     = "22222222-2222-2222-2222-222222222222"
+	wiMI      = "33333333-3333-3333-3333-333333333333"
+)
+
+func secretScanner(t *testing.T, graph *fakeGraph, kubectl fakeKubectl, reader *fakeReader) *Scanner {
+	t.Helper()
+	sc := New(reader, store.NewMemoryStore(), 0, nil, WithSecretExpiry(SecretExpiry{Graph: graph, Kubectl: kubectl.run}))
+	sc.now = func() time.Time { return testNow }
+	return sc
+}
+
+func TestScanSecretExpiry(t *testing.T) {
+	day := 24 * time.Hour
+	kubectl := fakeKubectl{
+		"config current-context": "prod-aks\n",
+		"get secrets": fmt.Sprintf("shop web-tls %s web-cert\nshop api-tls %s <none>\nshop old-tls %s <none>\nshop broken <none> <none>\n",
+			certBase64(t, testNow.Add(5*day)), certBase64(t, testNow.Add(200*day)), certBase64(t, testNow.Add(-day))),
+		"get serviceaccounts": "shop web " + wiApp + "\nshop worker " + wiMI + "\nshop default <none>\n",
+	}
+	graph := &fakeGraph{
+		apps: map[string]string{wiApp: credentials("shop-web", testNow.Add(10*day))},
+		// The cluster's service principal belongs to an app registered in another tenant
+		sps: map[string]string{clusterSP: credentials("aks-sp", testNow.Add(-3*day), testNow.Add(20*day))},
+	}
+	cluster := clusterRow("legacy", "Succeeded")
+	cluster["servicePrincipal"] = clusterSP
+	msi := clusterRow("modern", "Succeeded")
+	msi["servicePrincipal"] = "msi"
+	sc := secretScanner(t, graph, kubectl, &fakeReader{clusters: []map[string]interface{}{cluster, msi}})
+
+	added, err := sc.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	byResource := map[string]Finding{}
+	for _, finding := range added {
+		byResource[finding.Kind+":"+finding.Resource] = finding
+	}
+	if len(added) != 4 {
+		t.Fatalf("Expected 4 findings, got %+v", added)
+	}
+
+	web := byResource[KindTLSSecretExpiring+":web-tls"]
+	if web.Severity != SeverityMedium || web.ClusterName != "prod-aks" || web.Namespace != "shop" ||
+		!strings.Contains(web.Message, "in 5 days") || !strings.Contains(web.Message, "cert-manager Certificate web-cert") {
+		t.Errorf("Unexpected TLS secret finding %+v", web)
+	}
+	if old := byResource[KindTLSSecretExpiring+":old-tls"]; old.Severity != SeverityHigh || !strings.Contains(old.Message, "expired on 2025-06-09") {
+		t.Errorf("Expected the expired secret to be high severity, got %+v", old)
+	}
+	sp := byResource[KindServicePrincipalSecretExpiring+":"+clusterSP]
+	if sp.ClusterName != "legacy" || sp.Severity != SeverityMedium || !strings.Contains(sp.Message, "aks-sp") || sp.ExpiresAt == nil || !sp.ExpiresAt.Equal(testNow.Add(20*day)) {
+		t.Errorf("Expected the newest secret of the cluster service principal, got %+v", sp)
+	}
+	wi := byResource[KindWorkloadIdentityCredentialExpiring+":"+wiApp]
+	if !strings.Contains(wi.Message, "service accounts shop/web") || !strings.Contains(wi.Message, "client secret") {
+		t.Errorf("Unexpected workload identity finding %+v", wi)
+	}
+	for _, path := range graph.paths {
+		if strings.Contains(path, " ") {
+			t.Errorf("Expected an escaped Graph path, got %q", path)
+		}
+	}
+
+	// When the secret expires, the finding is reported again as expired
+	sc.now = func() time.Time { return testNow.Add(6 * day) }
+	added, err = sc.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(added) != 1 || added[0].Resource != "web-tls" || added[0].Severity != SeverityHigh {
+		t.Errorf("Expected the expired web-tls secret to be reported again, got %+v", added)
+	}
+}
+
+func TestScanKeepsFindingsOfUnreadableSources(t *testing.T) {
+	cluster := clusterRow("legacy", "Succeeded")
+	cluster["servicePrincipal"] = clusterSP
+	graph := &fakeGraph{apps: map[string]string{clusterSP: credentials("aks-sp", testNow.Add(24*time.Hour))}}
+	sc := secretScanner(t, graph, nil, &fakeReader{clusters: []map[string]interface{}{cluster}})
+	sc.secrets.Kubectl = nil
+
+	if added, err := sc.Scan(context.Background()); err != nil || len(added) != 1 {
+		t.Fatalf("Expected one service principal finding, got %+v (%v)", added, err)
+	}
+	graph.err = fmt.Errorf("insufficient privileges")
+	if _, err := sc.Scan(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	open, err := sc.findings.List()
+	if err != nil || len(open) != 1 {
+		t.Errorf("Expected the finding to stay open while Graph cannot be read, got %+v (%v)", open, err)
+	}
+}
@@ -34,10 +34,17 @@ var (
 		"az aks operation",
 		"az aks snapshot list",
 		"az aks snapshot show",
+		"az aks nodepool snapshot list",
+		"az aks nodepool snapshot show",
 
 		// Trusted access commands
 		"az aks trustedaccess rolebinding list",
 		"az aks trustedaccess rolebinding show",
+		"az aks trustedaccess role list",
+
+		// Cluster extension commands
+		"az k8s-extension list",
+		"az k8s-extension show",
 
 		// Other read operations
 		"az aks install-cli",
@@ -70,6 +77,22 @@ var (
 		"az fleet updatestrategy list",
 		"az fleet updatestrategy show",
 
+		// Role assignment commands (read-only)
+		"az role assignment list",
+
+		// Key Vault commands (read-only)
+		"az keyvault show",
+
+		// Azure Policy commands (read-only)
+		"az policy assignment list",
+		"az policy assignment show",
+
+		// Managed identity commands (read-only)
+		"az identity list",
+		"az identity show",
+		"az identity federated-credential list",
+		"az identity federated-credential show",
+
 		// Other general commands
 		"az find",
 		"az version",
@@ -223,6 +246,11 @@ func (v *Validator) validateAccessLevel(command string, readOperations []string)
 	return nil
 }
 
+// IsReadOperation reports whether a command is a read operation for the given command type
+func (v *Validator) IsReadOperation(command, commandType string) bool {
+	return v.isReadOperation(command, v.getReadOperationsList(commandType))
+}
+
 // isReadOperation checks if a command is a read operation
 func (v *Validator) isReadOperation(command string, allowedOperations []string) bool {
 	// Check if the command contains help flags - these are always read-only
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/audit"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolScope is the component a tool belongs to and the access level it requires, checked against the
+// scope of the API key a call is made with
+type toolScope struct {
+	// component is empty for tools outside any component, which every key may use
+	component string
+	// accessLevel is empty for tools available at every access level
+	accessLevel string
+}
+
+// inComponent runs register with the tools it adds scoped to a component
+func (s *Service) inComponent(component string, register func()) {
+	previous := s.scope
+	s.scope = toolScope{component: component}
+	register()
+	s.scope = previous
+}
+
+// requireAccessLevel scopes the tools added until the returned function is called to the access level
+// they are registered at, for tools the server only registers at that level or above
+func (s *Service) requireAccessLevel(level string) func() {
+	previous := s.scope.accessLevel
+	s.scope.accessLevel = level
+	return func() { s.scope.accessLevel = previous }
+}
+
+// kubectlAccessLevel returns the lowest access level kubectl tools are registered at that includes the tool
+func kubectlAccessLevel(name string) string {
+	for _, level := range apikey.AccessLevels {
+		for _, tool := range kubectl.RegisterKubectlTools(level) {
+			if tool.Name == name {
+				return level
+			}
+		}
+	}
+	return apikey.AccessLevels[len(apikey.AccessLevels)-1]
+}
+
+// allows reports whether calls made with the key may use a tool of the scope
+func (scope toolScope) allows(key *apikey.Key) bool {
+	return key.AllowsComponent(scope.component) && key.AllowsAccessLevel(scope.accessLevel)
+}
+
+// denial explains why calls made with the key may not use a tool of the scope
+func (scope toolScope) denial(key *apikey.Key, tool string) string {
+	if !key.AllowsComponent(scope.component) {
+		return fmt.Sprintf("API key '%s' may not use %s: it belongs to the %s component, and the key is limited to %s", key.Name, tool, scope.component, strings.Join(key.Components, ", "))
+	}
+	return fmt.Sprintf("API key '%s' may not use %s: it requires %s access, and the key has %s access", key.Name, tool, scope.accessLevel, key.AccessLevel)
+}
+
+// filterToolsForAPIKey lists only the tools the API key of the request may call
+func (s *Service) filterToolsForAPIKey(ctx context.Context, registered []mcp.Tool) []mcp.Tool {
+	key := apikey.FromContext(ctx)
+	if key == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(registered), func(tool mcp.Tool) bool {
+		return !s.toolScopes[tool.Name].allows(key)
+	})
+}
+
+// authorizeTool rejects calls whose API key does not cover the tool's component or access level, and
+// audits the rejection under the key's name
+func (s *Service) authorizeTool(name string, scope toolScope, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if s.apiKeys == nil {
+		return next
+	}
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key := apikey.FromContext(ctx)
+		if key == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("an API key is required: send it in the %s header", apikey.HeaderAPIKey)), nil
+		}
+		if scope.allows(key) {
+			return next(ctx, req)
+		}
+		message := scope.denial(key, name)
+		if s.auditLog != nil {
+			s.auditLog.Log(audit.Record{Tool: name, Action: "call", Outcome: audit.OutcomeDenied, Error: message, Client: key.Name})
+		}
+		return mcp.NewToolResultError(message), nil
+	}
+}
+
+// requireAPIKey rejects HTTP requests without a valid API key when API keys are configured, and attaches
+// the key to the request context so tool listing and calls are limited to its scope
+func (s *Service) requireAPIKey(next http.Handler) http.Handler {
+	if s.apiKeys == nil {
+		return next
+	}
+	// Session credential mode sends the Azure access token in the Authorization header
+	bearer := !s.cfg.SessionCredentials
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := s.apiKeys.Authenticate(apikey.FromRequest(r, bearer))
+		if key == nil {
+			log.Printf("Rejected request to %s from %s: missing or unknown API key", r.URL.Path, r.RemoteAddr)
+			if bearer {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, fmt.Sprintf("a valid API key is required: send it in the %s header", apikey.HeaderAPIKey), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(apikey.WithKey(r.Context(), key)))
+	})
+}
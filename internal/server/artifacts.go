@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/artifacts"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// artifactSweepInterval is how often expired artifacts are removed
+const artifactSweepInterval = time.Minute
+
+// registerArtifactResources creates the artifact store large tool outputs are kept in and registers
+// the resource template they are read through. Nothing is registered when --artifact-threshold is 0.
+func (s *Service) registerArtifactResources() {
+	if s.cfg.ArtifactThreshold <= 0 {
+		return
+	}
+	s.cfg.Artifacts = artifacts.NewStore(s.cfg.ArtifactTTL)
+
+	log.Printf("Registering artifact resources (%s)", artifacts.URITemplate)
+	template := mcp.NewResourceTemplate(artifacts.URITemplate, "Tool output artifact",
+		mcp.WithTemplateDescription(fmt.Sprintf(
+			"Full output of a tool call larger than %d bytes, linked from the call's result and readable for %s",
+			s.cfg.ArtifactThreshold, s.cfg.ArtifactTTL)),
+	)
+	s.mcpServer.AddResourceTemplate(template, s.readArtifact)
+
+	pageTemplate := mcp.NewResourceTemplate(artifacts.PageURITemplate, "Tool output artifact page",
+		mcp.WithTemplateDescription(fmt.Sprintf(
+			"Page of an artifact larger than %d bytes, numbered from 0; artifacts of that size are only readable by page",
+			artifacts.PageBytes)),
+	)
+	s.mcpServer.AddResourceTemplate(pageTemplate, s.readArtifact)
+}
+
+// readArtifact returns the content of an artifact, or of one of its pages. Artifacts larger than a page
+// are only returned by page, so a read never loads more than a page of a spooled artifact. In session
+// credential mode an artifact can only be read by the session whose tool call created it.
+func (s *Service) readArtifact(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	owner := ""
+	if cred := session.FromContext(ctx); cred != nil {
+		owner = cred.SessionID
+	}
+	uri, page, paged := artifacts.SplitPageURI(req.Params.URI)
+	if !paged {
+		uri = req.Params.URI
+	}
+	artifact, ok := s.cfg.Artifacts.Get(uri, owner)
+	if !ok {
+		return nil, fmt.Errorf("artifact %s not found or expired; run the tool again to recreate it", uri)
+	}
+
+	var text string
+	var err error
+	switch {
+	case paged:
+		text, err = artifact.ReadPage(page)
+	case artifact.Pages() > 1:
+		return nil, fmt.Errorf("artifact %s is %d bytes; read it in %d pages from %s to %s",
+			uri, artifact.Size, artifact.Pages(), artifact.PageURI(0), artifact.PageURI(artifact.Pages()-1))
+	default:
+		text, err = artifact.Read()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", req.Params.URI, err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: req.Params.URI, MIMEType: artifact.MIMEType, Text: text},
+	}, nil
+}
+
+// sweepArtifacts periodically removes expired artifacts until ctx is cancelled
+func (s *Service) sweepArtifacts(ctx context.Context) {
+	ticker := time.NewTicker(artifactSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cfg.Artifacts.Sweep()
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Azure/aks-mcp/internal/components/common"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// takesClusterParameters reports whether a tool identifies a cluster by subscription, resource group and name
+func takesClusterParameters(tool mcp.Tool) bool {
+	for _, name := range []string{"subscription_id", "resource_group", "cluster_name"} {
+		if _, ok := tool.InputSchema.Properties[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// withInferredClusterParameters makes subscription_id and resource_group optional in the tool schema
+func withInferredClusterParameters(tool mcp.Tool) mcp.Tool {
+	properties := make(map[string]interface{}, len(tool.InputSchema.Properties))
+	for name, property := range tool.InputSchema.Properties {
+		properties[name] = property
+	}
+	appendDescription(properties, "subscription_id", "(inferred from cluster_name when omitted)")
+	appendDescription(properties, "resource_group", "(inferred from cluster_name when omitted)")
+	appendDescription(properties, "cluster_name", "(a cluster name, API server FQDN or full resource ID)")
+	properties[common.ClusterSelectionParam] = map[string]interface{}{
+		"type": "string",
+		"description": "Which cluster to use when cluster_name matches several clusters: the index or id of a candidate " +
+			"listed by the previous call. The choice is remembered for the rest of the session",
+	}
+	tool.InputSchema.Properties = properties
+
+	var required []string
+	for _, name := range tool.InputSchema.Required {
+		if name != "subscription_id" && name != "resource_group" {
+			required = append(required, name)
+		}
+	}
+	tool.InputSchema.Required = required
+	return tool
+}
+
+// appendDescription replaces a schema property with a copy whose description ends with note
+func appendDescription(properties map[string]interface{}, name, note string) {
+	property, ok := properties[name].(map[string]interface{})
+	if !ok {
+		return
+	}
+	copied := make(map[string]interface{}, len(property))
+	for k, v := range property {
+		copied[k] = v
+	}
+	description, _ := copied["description"].(string)
+	copied["description"] = strings.TrimSpace(description + " " + note)
+	properties[name] = copied
+}
+
+// clusterSelectionResponse is returned instead of running a tool when its cluster name matches several clusters
+type clusterSelectionResponse struct {
+	Status      string                    `json:"status"`
+	ClusterName string                    `json:"clusterName"`
+	Matches     int                       `json:"matches"`
+	Candidates  []common.ClusterCandidate `json:"candidates"`
+	Message     string                    `json:"message"`
+}
+
+// clusterSelectionResult builds the tool result asking the caller to choose one of the candidates
+func clusterSelectionResult(ambiguous *common.AmbiguousClusterError) *mcp.CallToolResult {
+	message := fmt.Sprintf("Several AKS clusters match %q. Ask the user which one they mean, then call the tool again with the same "+
+		"arguments and %s set to the index or id of their choice. The choice is remembered for %q for the rest of the session.",
+		ambiguous.Name, common.ClusterSelectionParam, ambiguous.Name)
+	if ambiguous.Selection != "" {
+		message = fmt.Sprintf("%s %q matches none of the candidates. %s", common.ClusterSelectionParam, ambiguous.Selection, message)
+	}
+	listed := ambiguous.Listed()
+	if len(listed) < len(ambiguous.Candidates) {
+		message += fmt.Sprintf(" Only the first %d candidates are listed; pass subscription_id or resource_group to narrow the search.", len(listed))
+	}
+	response := clusterSelectionResponse{
+		Status:      "cluster_selection_required",
+		ClusterName: ambiguous.Name,
+		Matches:     len(ambiguous.Candidates),
+		Candidates:  listed,
+		Message:     message,
+	}
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(ambiguous.Error())
+	}
+	return mcp.NewToolResultError(string(data))
+}
+
+// clusterChoice is the cluster a session chose for an ambiguous cluster name
+type clusterChoice struct {
+	subscriptionID string
+	resourceGroup  string
+	name           string
+}
+
+// clusterChoices remembers, per MCP session, the cluster chosen for each ambiguous cluster name, so later
+// calls naming the same cluster do not ask again
+type clusterChoices struct {
+	mu        sync.Mutex
+	bySession map[string]map[string]clusterChoice
+}
+
+// remember records the cluster a session chose for a name
+func (c *clusterChoices) remember(sessionID, name string, choice clusterChoice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bySession == nil {
+		c.bySession = make(map[string]map[string]clusterChoice)
+	}
+	if c.bySession[sessionID] == nil {
+		c.bySession[sessionID] = make(map[string]clusterChoice)
+	}
+	c.bySession[sessionID][strings.ToLower(name)] = choice
+}
+
+// lookup returns the cluster a session chose for a name
+func (c *clusterChoices) lookup(sessionID, name string) (clusterChoice, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	choice, ok := c.bySession[sessionID][strings.ToLower(name)]
+	return choice, ok
+}
+
+// release forgets the choices of a closed session
+func (c *clusterChoices) release(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.bySession, sessionID)
+}
+
+// applyClusterChoice fills in the cluster a session chose earlier for the cluster name of params, unless a given
+// subscription_id or resource_group rules it out, and reports whether it did
+func (s *Service) applyClusterChoice(sessionID string, params map[string]interface{}) bool {
+	name, _ := params["cluster_name"].(string)
+	choice, ok := s.clusterChoices.lookup(sessionID, strings.TrimSpace(name))
+	if !ok {
+		return false
+	}
+	if subID, _ := params["subscription_id"].(string); subID != "" && !strings.EqualFold(subID, choice.subscriptionID) {
+		return false
+	}
+	if rg, _ := params["resource_group"].(string); rg != "" && !strings.EqualFold(rg, choice.resourceGroup) {
+		return false
+	}
+	params["subscription_id"], params["resource_group"], params["cluster_name"] = choice.subscriptionID, choice.resourceGroup, choice.name
+	return true
+}
+
+// resolveClusterParameters wraps a tool handler so calls that name a cluster without its subscription or
+// resource group have them looked up with Resource Graph before the handler runs. When several clusters
+// match, the caller gets the candidates to choose from, and the choice is remembered for the session.
+func (s *Service) resolveClusterParameters(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, ok := req.Params.Arguments.(map[string]interface{})
+		if !ok {
+			return handler(ctx, req)
+		}
+		resolved := make(map[string]interface{}, len(args))
+		for k, v := range args {
+			resolved[k] = v
+		}
+		selection, selected := resolved[common.ClusterSelectionParam]
+		delete(resolved, common.ClusterSelectionParam)
+		req.Params.Arguments = resolved
+		if s.azClient == nil || !common.NeedsClusterResolution(resolved) {
+			return handler(ctx, req)
+		}
+
+		sessionID := ""
+		if clientSession := server.ClientSessionFromContext(ctx); clientSession != nil {
+			sessionID = clientSession.SessionID()
+		}
+		name := strings.TrimSpace(fmt.Sprint(resolved["cluster_name"]))
+		if !selected && sessionID != "" && s.applyClusterChoice(sessionID, resolved) {
+			if s.cfg.Verbose {
+				log.Printf("[RESOLVE] cluster %s was chosen earlier in this session: subscription %v, resource group %v", name, resolved["subscription_id"], resolved["resource_group"])
+			}
+			return handler(ctx, req)
+		}
+
+		cred := session.FromContext(ctx)
+		if s.cfg.SessionCredentials && cred == nil {
+			// The handler reports the missing session credentials
+			return handler(ctx, req)
+		}
+		client, err := s.azClient.ForSession(cred)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if selected {
+			resolved[common.ClusterSelectionParam] = selection
+		}
+		if err := common.ResolveClusterParameters(ctx, resolved, client); err != nil {
+			var ambiguous *common.AmbiguousClusterError
+			if errors.As(err, &ambiguous) {
+				return clusterSelectionResult(ambiguous), nil
+			}
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if selected && sessionID != "" {
+			s.clusterChoices.remember(sessionID, name, clusterChoice{
+				subscriptionID: fmt.Sprint(resolved["subscription_id"]),
+				resourceGroup:  fmt.Sprint(resolved["resource_group"]),
+				name:           fmt.Sprint(resolved["cluster_name"]),
+			})
+		}
+		if s.cfg.Verbose {
+			log.Printf("[RESOLVE] cluster %v is in subscription %v, resource group %v", resolved["cluster_name"], resolved["subscription_id"], resolved["resource_group"])
+		}
+		return handler(ctx, req)
+	}
+}
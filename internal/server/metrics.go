@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/command"
+)
+
+// handleMetrics reports the shell subprocess counts in the Prometheus text format
+func (s *Service) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(formatMetrics(command.Stats())))
+}
+
+// formatMetrics formats subprocess counts in the Prometheus text format
+func formatMetrics(stats command.ProcessStats) string {
+	var sb strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("aks_mcp_subprocesses_running", "gauge", "Shell subprocesses currently running, by command.")
+	commands := make([]string, 0, len(stats.Running))
+	for name := range stats.Running {
+		commands = append(commands, name)
+	}
+	sort.Strings(commands)
+	for _, name := range commands {
+		fmt.Fprintf(&sb, "aks_mcp_subprocesses_running{command=%q} %d\n", name, stats.Running[name])
+	}
+	metric("aks_mcp_subprocesses_started_total", "counter", "Shell subprocesses started.")
+	fmt.Fprintf(&sb, "aks_mcp_subprocesses_started_total %d\n", stats.Started)
+	metric("aks_mcp_subprocesses_timed_out_total", "counter", "Shell subprocesses that ran past their timeout.")
+	fmt.Fprintf(&sb, "aks_mcp_subprocesses_timed_out_total %d\n", stats.TimedOut)
+	metric("aks_mcp_subprocess_trees_killed_total", "counter", "Subprocess trees killed on timeout or shutdown.")
+	fmt.Fprintf(&sb, "aks_mcp_subprocess_trees_killed_total %d\n", stats.Killed)
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/Azure/aks-mcp/internal/replay"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Replay re-executes the tool calls of the --replay recording through the registered tools, writing how each
+// result differs from the recorded one. With --replay-mock az CLI commands are answered from the recording.
+func (s *Service) Replay(ctx context.Context, w io.Writer) (replay.Summary, error) {
+	entries, err := replay.Load(s.cfg.Replay)
+	if err != nil {
+		return replay.Summary{}, err
+	}
+	var player *replay.Player
+	if s.cfg.ReplayMock {
+		player = replay.NewPlayer(entries)
+		command.SetInterceptor(player)
+		defer command.SetInterceptor(nil)
+	}
+
+	summary := replay.Run(ctx, entries, s.callTool, w)
+	if player != nil {
+		for _, missing := range player.Missing() {
+			_, _ = fmt.Fprintf(w, "not in the recording: %s\n", missing)
+		}
+	}
+	return summary, nil
+}
+
+// callTool calls a registered tool through the MCP server, as a client would
+func (s *Service) callTool(ctx context.Context, tool string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      1,
+		"method":  string(mcp.MethodToolsCall),
+		"params":  map[string]interface{}{"name": tool, "arguments": arguments},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s call: %w", tool, err)
+	}
+	data, err := json.Marshal(s.mcpServer.HandleMessage(ctx, request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s response: %w", tool, err)
+	}
+	var response struct {
+		Result *json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", tool, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("%s", response.Error.Message)
+	}
+	return mcp.ParseCallToolResult(response.Result)
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/scanner"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// URIs of the inventory resources
+const (
+	inventoryClustersURI = "aks-mcp://inventory/clusters"
+	inventoryFindingsURI = "aks-mcp://inventory/findings"
+)
+
+// Methods of resource subscription requests, which the MCP SDK does not define
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// resourceUpdateDebounce is how long changes to a resource are collected before its subscribers are
+// notified, so a burst of changes sends one notification per resource
+const resourceUpdateDebounce = 10 * time.Second
+
+// inventoryURIs maps the scan state parts passed to the scanner's change hook to the resource showing them
+var inventoryURIs = map[string]string{
+	scanner.ChangedInventory: inventoryClustersURI,
+	scanner.ChangedFindings:  inventoryFindingsURI,
+}
+
+// resourceSubscriptions tracks the sessions subscribed to each resource and notifies them with
+// notifications/resources/updated when it changes. Changes are debounced: the first change of a resource
+// starts a window of debounce, and every change within it is sent as one notification when it ends.
+type resourceSubscriptions struct {
+	debounce time.Duration
+	// notify sends the update notification of uri to a session
+	notify func(sessionID, uri string) error
+
+	mu          sync.Mutex
+	subscribers map[string]map[string]bool
+	pending     map[string]bool
+	timer       *time.Timer
+}
+
+// newResourceSubscriptions creates resource subscriptions whose update notifications are sent with notify
+func newResourceSubscriptions(debounce time.Duration, notify func(sessionID, uri string) error) *resourceSubscriptions {
+	return &resourceSubscriptions{
+		debounce:    debounce,
+		notify:      notify,
+		subscribers: map[string]map[string]bool{},
+		pending:     map[string]bool{},
+	}
+}
+
+// subscribe subscribes a session to uri
+func (r *resourceSubscriptions) subscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subscribers[uri] == nil {
+		r.subscribers[uri] = map[string]bool{}
+	}
+	r.subscribers[uri][sessionID] = true
+}
+
+// unsubscribe removes the subscription of a session to uri
+func (r *resourceSubscriptions) unsubscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscribers[uri], sessionID)
+}
+
+// release removes every subscription of a session
+func (r *resourceSubscriptions) release(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sessions := range r.subscribers {
+		delete(sessions, sessionID)
+	}
+}
+
+// changed records a change of uri, whose subscribers are notified when the debounce window ends
+func (r *resourceSubscriptions) changed(uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.subscribers[uri]) == 0 {
+		return
+	}
+	r.pending[uri] = true
+	if r.timer == nil {
+		r.timer = time.AfterFunc(r.debounce, r.flush)
+	}
+}
+
+// flush notifies the subscribers of every resource that changed in the window that just ended. Sessions
+// that are gone are unsubscribed.
+func (r *resourceSubscriptions) flush() {
+	r.mu.Lock()
+	type update struct{ sessionID, uri string }
+	var updates []update
+	for uri := range r.pending {
+		for sessionID := range r.subscribers[uri] {
+			updates = append(updates, update{sessionID, uri})
+		}
+	}
+	r.pending = map[string]bool{}
+	r.timer = nil
+	r.mu.Unlock()
+
+	for _, u := range updates {
+		if err := r.notify(u.sessionID, u.uri); err != nil {
+			log.Printf("[RESOURCES] Dropping the subscription of session %s to %s: %v", u.sessionID, u.uri, err)
+			r.unsubscribe(u.sessionID, u.uri)
+		}
+	}
+}
+
+// observeRequest records resources/subscribe and resources/unsubscribe requests. The MCP SDK does not route
+// these methods and answers them with a method not found error, so they are picked up before it does.
+func (r *resourceSubscriptions) observeRequest(ctx context.Context, _ any, message any) error {
+	raw, ok := message.(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	var request struct {
+		Method string `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(raw, &request); err != nil || request.Params.URI == "" {
+		return nil
+	}
+	clientSession := server.ClientSessionFromContext(ctx)
+	if clientSession == nil {
+		return nil
+	}
+	switch request.Method {
+	case methodResourcesSubscribe:
+		r.subscribe(clientSession.SessionID(), request.Params.URI)
+	case methodResourcesUnsubscribe:
+		r.unsubscribe(clientSession.SessionID(), request.Params.URI)
+	}
+	return nil
+}
+
+// observeRead subscribes a session to the inventory resources it reads
+func (r *resourceSubscriptions) observeRead(ctx context.Context, _ any, request *mcp.ReadResourceRequest, _ *mcp.ReadResourceResult) {
+	if clientSession := server.ClientSessionFromContext(ctx); clientSession != nil {
+		for _, uri := range inventoryURIs {
+			if request.Params.URI == uri {
+				r.subscribe(clientSession.SessionID(), uri)
+			}
+		}
+	}
+}
+
+// registerInventoryResources registers the resources showing the cluster inventory and the open findings
+// of the background scanner
+func (s *Service) registerInventoryResources(sc *scanner.Scanner) {
+	log.Printf("Registering inventory resources (%s, %s)", inventoryClustersURI, inventoryFindingsURI)
+	clusters := mcp.NewResource(inventoryClustersURI, "AKS cluster inventory",
+		mcp.WithResourceDescription("The AKS clusters the background scanner found with their provisioning and power state and node pools; subscribers are notified when it changes"),
+		mcp.WithMIMEType("application/json"),
+	)
+	s.mcpServer.AddResource(clusters, func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		inventory, found, err := sc.Inventory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the cluster inventory: %w", err)
+		}
+		if !found {
+			return nil, fmt.Errorf("the cluster inventory is not available yet: the first background scan has not completed")
+		}
+		return jsonResource(inventoryClustersURI, inventory)
+	})
+
+	findings := mcp.NewResource(inventoryFindingsURI, "AKS cluster findings",
+		mcp.WithResourceDescription("The open high-severity findings of the background scanner, such as failed clusters and node pools; subscribers are notified when findings open or clear"),
+		mcp.WithMIMEType("application/json"),
+	)
+	s.mcpServer.AddResource(findings, func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		open, err := sc.OpenFindings()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the open findings: %w", err)
+		}
+		return jsonResource(inventoryFindingsURI, map[string]any{"findings": open})
+	})
+}
+
+// jsonResource returns v as the indented JSON content of the resource at uri
+func jsonResource(uri string, v any) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
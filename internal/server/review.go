@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// reviewKubectlCall returns the result of a kubectl tool call in review mode: a write operation is returned
+// as its manifest, diff and script instead of executed. It returns nil when the call is not in review mode
+// or does not write, so the call runs as usual.
+func (s *Service) reviewKubectlCall(toolName string, args map[string]interface{}, cfg *config.ConfigData) *mcp.CallToolResult {
+	if value := args[tools.ReviewParam]; !s.cfg.ReviewMode && value != true && value != "true" {
+		return nil
+	}
+	operation, _ := args["operation"].(string)
+	resource, _ := args["resource"].(string)
+	kubectlArgs, _ := args["args"].(string)
+	command := kubectl.NewKubectlToolExecutor().GetCommandForValidation(operation, resource, kubectlArgs, toolName)
+	if !review.IsClusterWrite(command) {
+		return nil
+	}
+
+	plan := review.New()
+	// The executor validates the command as if it were run, then defers it
+	if _, err := k8s.WrapK8sExecutor(kubectl.NewExecutor()).Execute(map[string]interface{}{"command": command}, cfg.ForReview(plan)); err != nil && len(plan.Changes()) == 0 {
+		return mcp.NewToolResultError(err.Error())
+	}
+	report, err := plan.Report(toolName)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+	return mcp.NewToolResultText(report)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Azure/aks-mcp/internal/components/azrest"
+	"github.com/Azure/aks-mcp/internal/components/bulk"
+	"github.com/Azure/aks-mcp/internal/components/certificates"
+	"github.com/Azure/aks-mcp/internal/components/changes"
+	"github.com/Azure/aks-mcp/internal/components/compute"
+	"github.com/Azure/aks-mcp/internal/components/cost"
+	"github.com/Azure/aks-mcp/internal/components/estate"
+	"github.com/Azure/aks-mcp/internal/components/events"
+	"github.com/Azure/aks-mcp/internal/components/failover"
+	"github.com/Azure/aks-mcp/internal/components/gpu"
+	"github.com/Azure/aks-mcp/internal/components/hubble"
+	"github.com/Azure/aks-mcp/internal/components/identity"
+	"github.com/Azure/aks-mcp/internal/components/jobs"
+	"github.com/Azure/aks-mcp/internal/components/network"
+	"github.com/Azure/aks-mcp/internal/components/nodes"
+	"github.com/Azure/aks-mcp/internal/components/storage"
+	"github.com/Azure/aks-mcp/internal/components/supportbundle"
+	"github.com/Azure/aks-mcp/internal/components/tags"
+	"github.com/Azure/aks-mcp/internal/components/trafficmatrix"
+	"github.com/Azure/aks-mcp/internal/components/triage"
+	"github.com/Azure/aks-mcp/internal/components/upgrade"
+	"github.com/Azure/aks-mcp/internal/components/vulnerabilities"
+	"github.com/Azure/aks-mcp/internal/components/wait"
+	"github.com/Azure/aks-mcp/internal/version"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// schemaDocumentVersion is bumped when the layout of the schema document changes
+const schemaDocumentVersion = "1"
+
+// SchemaDocument describes the input and result schemas of every registered tool
+type SchemaDocument struct {
+	SchemaVersion string       `json:"schemaVersion"`
+	Server        string       `json:"server"`
+	Version       string       `json:"version"`
+	AccessLevel   string       `json:"accessLevel"`
+	Tools         []ToolSchema `json:"tools"`
+}
+
+// ToolSchema is the published schema of one tool. ResultSchema describes the JSON document in the
+// text content of a successful call; it is omitted for tools whose result is free-form text or
+// whose shape depends on the operation.
+type ToolSchema struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Annotations  mcp.ToolAnnotation `json:"annotations"`
+	InputSchema  json.RawMessage    `json:"inputSchema"`
+	ResultSchema json.RawMessage    `json:"resultSchema,omitempty"`
+}
+
+// resultSchemas maps the tools that always return one report type to the JSON Schema of that type
+var resultSchemas = map[string]json.RawMessage{
+	"check_certificate_expiry":      resultSchema[certificates.CertificateReport](),
+	"check_identity_permissions":    resultSchema[identity.PermissionReport](),
+	"diagnose_workload_identity":    resultSchema[identity.WorkloadIdentityDiagnosis](),
+	"aks_job_failures":              resultSchema[jobs.JobsReport](),
+	"scan_image_vulnerabilities":    resultSchema[vulnerabilities.VulnerabilityReport](),
+	"aks_recent_changes":            resultSchema[changes.ChangesReport](),
+	"aks_triage":                    resultSchema[triage.TriageReport](),
+	"aks_node_drain":                resultSchema[nodes.DrainReport](),
+	"aks_namespace_bulk":            resultSchema[bulk.Result](),
+	"aks_noisy_neighbors":           resultSchema[nodes.NoisyNeighborReport](),
+	"aks_watch_events":              resultSchema[events.WatchReport](),
+	"aks_wait_for_condition":        resultSchema[wait.WaitReport](),
+	"cilium_dropped_flows":          resultSchema[hubble.DroppedFlowsReport](),
+	"network_traffic_matrix":        resultSchema[trafficmatrix.TrafficMatrix](),
+	"diagnose_gpu_workloads":        resultSchema[gpu.GPUReport](),
+	"aks_estate_overview":           resultSchema[estate.EstateReport](),
+	"aks_deprecated_features":       resultSchema[estate.DeprecationReport](),
+	"aks_cost_breakdown":            resultSchema[cost.CostReport](),
+	"az_aks_tags":                   resultSchema[tags.TagReport](),
+	"az_rest":                       resultSchema[azrest.Result](),
+	"aks_upgrade_progress":          resultSchema[upgrade.UpgradeProgress](),
+	"aks_upgrade_tuning":            resultSchema[upgrade.UpgradeTuningReport](),
+	"aks_ingress_health":            resultSchema[network.IngressReport](),
+	"aks_dns_validation":            resultSchema[network.DNSReport](),
+	"aks_egress_firewall_analysis":  resultSchema[network.EgressReport](),
+	"aks_network_migration_advisor": resultSchema[network.MigrationReport](),
+	"aks_orphaned_lb_resources":     resultSchema[network.OrphanReport](),
+	"check_failover_readiness":      resultSchema[failover.ReadinessReport](),
+	"az_storage_artifacts":          resultSchema[storage.ArtifactsResult](),
+	"generate_support_bundle":       resultSchema[supportbundle.BundleResult](),
+	"list_node_scripts":             resultSchema[compute.NodeScriptList](),
+	"run_node_script":               resultSchema[compute.NodeScriptResult](),
+}
+
+// resultSchema reflects a result type into a JSON Schema the same way mcp-go generates output schemas.
+// The schemas are published in the schema document only: declaring an outputSchema in tools/list would
+// oblige the server to return structured content.
+func resultSchema[T any]() json.RawMessage {
+	return mcp.NewTool("", mcp.WithOutputSchema[T]()).RawOutputSchema
+}
+
+// schemaDocument builds the schema document from the tools as registered, including the arguments
+// added by the shared explain, verbosity and timeout handling
+func (s *Service) schemaDocument() (SchemaDocument, error) {
+	doc := SchemaDocument{
+		SchemaVersion: schemaDocumentVersion,
+		Server:        "aks-mcp",
+		Version:       version.GetVersion(),
+		AccessLevel:   s.cfg.AccessLevel,
+		Tools:         make([]ToolSchema, 0, len(s.registeredTools)),
+	}
+	for _, tool := range s.registeredTools {
+		input := tool.RawInputSchema
+		if input == nil {
+			var err error
+			if input, err = json.Marshal(tool.InputSchema); err != nil {
+				return SchemaDocument{}, err
+			}
+		}
+		doc.Tools = append(doc.Tools, ToolSchema{
+			Name:         tool.Name,
+			Description:  tool.Description,
+			Annotations:  tool.Annotations,
+			InputSchema:  input,
+			ResultSchema: resultSchemas[tool.Name],
+		})
+	}
+	return doc, nil
+}
+
+// handleToolSchemas serves the schema document of the registered tools
+func (s *Service) handleToolSchemas(w http.ResponseWriter, _ *http.Request) {
+	doc, err := s.schemaDocument()
+	if err != nil {
+		http.Error(w, "Failed to build schema document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/command"
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// selfTestCallTimeout bounds each call of a self-test, so a tool that goes on to run despite invalid input
+// cannot hang the test
+const selfTestCallTimeout = 30 * time.Second
+
+// selfTestInvalidValue is passed for enum parameters to check that tools reject values outside the enum
+const selfTestInvalidValue = "aks-mcp-self-test-invalid"
+
+// toolNamePattern is the tool name format MCP clients accept
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// selfTestSamples are the values passed for the cluster parameters, so no call resolves the cluster
+var selfTestSamples = map[string]interface{}{
+	"subscription_id": "00000000-0000-0000-0000-000000000000",
+	"resource_group":  "aks-mcp-self-test-rg",
+	"cluster_name":    "aks-mcp-self-test",
+}
+
+// SelfTestSummary counts the tools checked by a self-test, at every access level they are registered at
+type SelfTestSummary struct {
+	Checked int
+	Passed  int
+	Failed  int
+}
+
+// selfTestExecutor answers every command with an empty JSON object and counts them, so a self-test
+// never reaches Azure and can tell whether a call ran commands
+type selfTestExecutor struct {
+	count atomic.Int64
+}
+
+func (e *selfTestExecutor) Run(_ string) (string, error) {
+	e.count.Add(1)
+	return "{}", nil
+}
+
+func (e *selfTestExecutor) Intercept(cmd string) (string, bool, error) {
+	output, err := e.Run(cmd)
+	return output, true, err
+}
+
+func (e *selfTestExecutor) Observe(_, _ string, _ error) {}
+
+// toolInput is the part of a tool's input schema the self-test checks
+type toolInput struct {
+	Properties map[string]struct {
+		Type        string        `json:"type"`
+		Description string        `json:"description"`
+		Enum        []interface{} `json:"enum"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// SelfTest registers the tools at every access level with a mock az CLI and checks, for each tool and level,
+// that the tool has a valid name and a description, that every parameter is described and every required
+// parameter declared, that the tool is scoped to the lowest access level it is registered at and is still
+// registered at the levels above, and that calls missing required parameters or passing a value outside an
+// enum are rejected without running commands. Writes are deferred as in review mode, so a tool that runs
+// despite invalid input changes nothing. It writes one line per tool and level and a summary.
+func SelfTest(ctx context.Context, cfg *config.ConfigData, w io.Writer) (SelfTestSummary, error) {
+	executor := &selfTestExecutor{}
+	command.SetInterceptor(executor)
+	defer command.SetInterceptor(nil)
+
+	services := make(map[string]*Service, len(apikey.AccessLevels))
+	for _, level := range apikey.AccessLevels {
+		service := NewService(selfTestConfig(cfg, level), WithAzCliProcFactory(func(int) azcli.Proc { return executor }))
+		if err := service.Initialize(); err != nil {
+			return SelfTestSummary{}, fmt.Errorf("failed to register the tools at %s access: %w", level, err)
+		}
+		defer service.Shutdown()
+		services[level] = service
+	}
+
+	// The lowest access level each tool is registered at
+	lowest := map[string]string{}
+	for i := len(apikey.AccessLevels) - 1; i >= 0; i-- {
+		for _, tool := range services[apikey.AccessLevels[i]].registeredTools {
+			lowest[tool.Name] = apikey.AccessLevels[i]
+		}
+	}
+
+	var summary SelfTestSummary
+	for i, level := range apikey.AccessLevels {
+		service := services[level]
+		tools := slices.Clone(service.registeredTools)
+		sort.Slice(tools, func(a, b int) bool { return tools[a].Name < tools[b].Name })
+		for _, tool := range tools {
+			problems := checkToolDefinition(tool)
+			if scope := service.toolScopes[tool.Name].accessLevel; scope != lowest[tool.Name] && (scope != "" || lowest[tool.Name] != apikey.AccessLevels[0]) {
+				problems = append(problems, fmt.Sprintf("registered from %s access but scoped to %s access", lowest[tool.Name], scopeName(scope)))
+			}
+			for _, higher := range apikey.AccessLevels[i+1:] {
+				if !slices.ContainsFunc(services[higher].registeredTools, func(t mcp.Tool) bool { return t.Name == tool.Name }) {
+					problems = append(problems, fmt.Sprintf("not registered at %s access", higher))
+				}
+			}
+			problems = append(problems, service.checkToolValidation(ctx, tool, executor)...)
+
+			summary.Checked++
+			if len(problems) == 0 {
+				summary.Passed++
+				_, _ = fmt.Fprintf(w, "ok   %-9s %s\n", level, tool.Name)
+				continue
+			}
+			summary.Failed++
+			_, _ = fmt.Fprintf(w, "FAIL %-9s %s\n", level, tool.Name)
+			for _, problem := range problems {
+				_, _ = fmt.Fprintf(w, "  %s\n", problem)
+			}
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d tool registrations checked: %d passed, %d failed\n", summary.Checked, summary.Passed, summary.Failed)
+	return summary, nil
+}
+
+// selfTestConfig returns a copy of the configuration registering the tools at the given access level with
+// state in memory, writes deferred and nothing recorded
+func selfTestConfig(cfg *config.ConfigData, level string) *config.ConfigData {
+	testCfg := *cfg
+	testCfg.AccessLevel = level
+	if cfg.SecurityConfig != nil {
+		securityConfig := *cfg.SecurityConfig
+		securityConfig.AccessLevel = level
+		testCfg.SecurityConfig = &securityConfig
+	}
+	testCfg.StateStore = store.KindMemory
+	testCfg.ReviewMode = true
+	testCfg.LeaderElection = false
+	testCfg.PushFindings = false
+	testCfg.Record = ""
+	testCfg.Replay = ""
+	testCfg.APIKeysFile = ""
+	return &testCfg
+}
+
+// scopeName names the access level of a tool scope, where tools outside a level are available at every level
+func scopeName(accessLevel string) string {
+	if accessLevel == "" {
+		return "every"
+	}
+	return accessLevel
+}
+
+// readToolInput decodes the input schema of a tool, which is either raw JSON or built with mcp-go options
+func readToolInput(tool mcp.Tool) (toolInput, error) {
+	raw := tool.RawInputSchema
+	if raw == nil {
+		var err error
+		if raw, err = json.Marshal(tool.InputSchema); err != nil {
+			return toolInput{}, err
+		}
+	}
+	var input toolInput
+	err := json.Unmarshal(raw, &input)
+	return input, err
+}
+
+// checkToolDefinition returns the problems of a tool's name, description and parameters
+func checkToolDefinition(tool mcp.Tool) []string {
+	var problems []string
+	if !toolNamePattern.MatchString(tool.Name) {
+		problems = append(problems, fmt.Sprintf("name %q is not 1 to 64 letters, digits, underscores or hyphens", tool.Name))
+	}
+	if tool.Description == "" {
+		problems = append(problems, "has no description")
+	}
+	input, err := readToolInput(tool)
+	if err != nil {
+		return append(problems, fmt.Sprintf("input schema is not valid JSON Schema: %v", err))
+	}
+	for _, name := range sortedPropertyNames(input) {
+		if input.Properties[name].Description == "" {
+			problems = append(problems, fmt.Sprintf("parameter %s has no description", name))
+		}
+	}
+	for _, name := range input.Required {
+		if _, ok := input.Properties[name]; !ok {
+			problems = append(problems, fmt.Sprintf("required parameter %s is not declared", name))
+		}
+	}
+	return problems
+}
+
+// checkToolValidation calls a tool without its required parameters, and with a value outside each enum,
+// and returns the calls that were not rejected or ran commands before they were
+func (s *Service) checkToolValidation(ctx context.Context, tool mcp.Tool, executor *selfTestExecutor) []string {
+	input, err := readToolInput(tool)
+	if err != nil {
+		return nil
+	}
+	var problems []string
+	if len(input.Required) > 0 {
+		if problem := s.expectRejected(ctx, tool.Name, map[string]interface{}{}, executor); problem != "" {
+			problems = append(problems, "without required parameters: "+problem)
+		}
+	}
+	for _, name := range sortedPropertyNames(input) {
+		if len(input.Properties[name].Enum) == 0 {
+			continue
+		}
+		arguments := sampleArguments(input)
+		arguments[name] = selfTestInvalidValue
+		if problem := s.expectRejected(ctx, tool.Name, arguments, executor); problem != "" {
+			problems = append(problems, fmt.Sprintf("with %s outside its enum: %s", name, problem))
+		}
+	}
+	return problems
+}
+
+// expectRejected calls a tool and describes how the call failed to be rejected before running commands,
+// or returns "" when it was
+func (s *Service) expectRejected(ctx context.Context, tool string, arguments map[string]interface{}, executor *selfTestExecutor) string {
+	ctx, cancel := context.WithTimeout(ctx, selfTestCallTimeout)
+	defer cancel()
+	before := executor.count.Load()
+	result, err := s.callTool(ctx, tool, arguments)
+	ran := executor.count.Load() - before
+	switch {
+	case err != nil:
+		return fmt.Sprintf("call failed instead of returning an error result: %v", err)
+	case !result.IsError:
+		return "accepted"
+	case ran > 0:
+		return fmt.Sprintf("rejected after running %d commands", ran)
+	}
+	return ""
+}
+
+// sampleArguments returns valid-looking values for the required and cluster parameters of a tool
+func sampleArguments(input toolInput) map[string]interface{} {
+	arguments := map[string]interface{}{}
+	for name, value := range selfTestSamples {
+		if _, ok := input.Properties[name]; ok {
+			arguments[name] = value
+		}
+	}
+	for _, name := range input.Required {
+		if _, ok := arguments[name]; ok {
+			continue
+		}
+		property := input.Properties[name]
+		switch {
+		case len(property.Enum) > 0:
+			arguments[name] = property.Enum[0]
+		case property.Type == "integer" || property.Type == "number":
+			arguments[name] = 1
+		case property.Type == "boolean":
+			arguments[name] = false
+		case property.Type == "array":
+			arguments[name] = []interface{}{}
+		case property.Type == "object":
+			arguments[name] = map[string]interface{}{}
+		default:
+			arguments[name] = "aks-mcp-self-test"
+		}
+	}
+	return arguments
+}
+
+func sortedPropertyNames(input toolInput) []string {
+	names := make([]string, 0, len(input.Properties))
+	for name := range input.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,32 +1,77 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/approval"
+	"github.com/Azure/aks-mcp/internal/audit"
 	"github.com/Azure/aks-mcp/internal/azcli"
 	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/command"
 	"github.com/Azure/aks-mcp/internal/components/advisor"
+	"github.com/Azure/aks-mcp/internal/components/apply"
 	"github.com/Azure/aks-mcp/internal/components/azaks"
+	"github.com/Azure/aks-mcp/internal/components/azrest"
+	"github.com/Azure/aks-mcp/internal/components/bulk"
+	"github.com/Azure/aks-mcp/internal/components/certificates"
+	"github.com/Azure/aks-mcp/internal/components/changes"
+	"github.com/Azure/aks-mcp/internal/components/chaos"
+	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/components/compute"
+	"github.com/Azure/aks-mcp/internal/components/cost"
 	"github.com/Azure/aks-mcp/internal/components/detectors"
+	"github.com/Azure/aks-mcp/internal/components/estate"
+	"github.com/Azure/aks-mcp/internal/components/events"
+	"github.com/Azure/aks-mcp/internal/components/failover"
 	"github.com/Azure/aks-mcp/internal/components/fleet"
+	"github.com/Azure/aks-mcp/internal/components/gpu"
+	"github.com/Azure/aks-mcp/internal/components/hubble"
+	"github.com/Azure/aks-mcp/internal/components/identity"
 	"github.com/Azure/aks-mcp/internal/components/inspektorgadget"
+	"github.com/Azure/aks-mcp/internal/components/jobs"
 	"github.com/Azure/aks-mcp/internal/components/monitor"
 	"github.com/Azure/aks-mcp/internal/components/network"
+	"github.com/Azure/aks-mcp/internal/components/nodes"
+	"github.com/Azure/aks-mcp/internal/components/podaccess"
+	"github.com/Azure/aks-mcp/internal/components/storage"
+	"github.com/Azure/aks-mcp/internal/components/supportbundle"
+	"github.com/Azure/aks-mcp/internal/components/tags"
+	"github.com/Azure/aks-mcp/internal/components/trafficmatrix"
+	"github.com/Azure/aks-mcp/internal/components/triage"
+	"github.com/Azure/aks-mcp/internal/components/upgrade"
+	"github.com/Azure/aks-mcp/internal/components/vulnerabilities"
+	"github.com/Azure/aks-mcp/internal/components/wait"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/export"
 	"github.com/Azure/aks-mcp/internal/k8s"
+	"github.com/Azure/aks-mcp/internal/leader"
+	"github.com/Azure/aks-mcp/internal/notes"
 	"github.com/Azure/aks-mcp/internal/prompts"
+	"github.com/Azure/aks-mcp/internal/replay"
+	"github.com/Azure/aks-mcp/internal/scanner"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/Azure/aks-mcp/internal/store"
 	"github.com/Azure/aks-mcp/internal/tools"
 	"github.com/Azure/aks-mcp/internal/version"
 	"github.com/Azure/mcp-kubernetes/pkg/cilium"
 	"github.com/Azure/mcp-kubernetes/pkg/helm"
 	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
 	k8stools "github.com/Azure/mcp-kubernetes/pkg/tools"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
 )
 
 // Service represents the AKS MCP service
@@ -35,8 +80,57 @@ type Service struct {
 	mcpServer        *server.MCPServer
 	azClient         *azureclient.AzureClient
 	azcliProcFactory func(timeout int) azcli.Proc
+	// coordinator runs background subsystems on the leader replica only
+	coordinator *leader.Coordinator
+	// sharedStore holds the results of background subsystems for every replica; nil without leader election
+	sharedStore store.Store
+	// backgroundMu guards stopBackground and backgroundDone, set by Run and read by Shutdown
+	backgroundMu sync.Mutex
+	// stopBackground cancels background subsystems and releases leadership
+	stopBackground context.CancelFunc
+	// backgroundDone is closed once the coordinator has stopped
+	backgroundDone chan struct{}
+	// tokenVerifier checks session access tokens (session credential mode only)
+	tokenVerifier *session.Verifier
+	// store persists server state that must survive restarts
+	store store.Store
+	// auditLog records privileged tool invocations
+	auditLog *audit.Logger
+	// exporter streams audit records and findings to the --export-sink (nil when export is disabled)
+	exporter *export.Exporter
+	// approvals holds the previewed changes waiting for confirmation
+	approvals *approval.Manager
+	// notes holds the operator notes recorded per cluster
+	notes *notes.Book
+	// portForwards holds the port-forward sessions torn down on shutdown
+	portForwards *podaccess.PortForwardManager
+	// recorder appends tool calls and az CLI commands to the --record file
+	recorder *replay.Recorder
+	// registeredTools are the tools as registered, in registration order, published by the schema document
+	registeredTools []mcp.Tool
+	// updateStatus is the newer release found by the startup update check, nil when there is none
+	updateStatus atomic.Pointer[version.UpdateStatus]
+	// clusterChoices remembers the clusters sessions chose for ambiguous cluster names
+	clusterChoices clusterChoices
+	// apiKeys are the API keys HTTP clients authenticate with, nil when --api-keys-file is not set
+	apiKeys *apikey.Keyring
+	// scope is the component and access level of the tools being registered
+	scope toolScope
+	// toolScopes holds the scope of each registered tool, checked against the API key of a call
+	toolScopes map[string]toolScope
+	// subscriptions tracks the sessions subscribed to the inventory resources, nil when they are not served
+	subscriptions *resourceSubscriptions
 }
 
+// Session credential state is evicted after this much inactivity, checked every sessionSweepInterval
+const (
+	sessionIdleTimeout   = 30 * time.Minute
+	sessionSweepInterval = 5 * time.Minute
+)
+
+// auditPruneInterval is how often audit records past --audit-retention-days are removed
+const auditPruneInterval = time.Hour
+
 // ServiceOption defines a function that configures the AKS MCP service
 type ServiceOption func(*Service)
 
@@ -64,6 +158,13 @@ func (s *Service) Initialize() error {
 	if err := s.initializeInfrastructure(); err != nil {
 		return err
 	}
+	if err := s.initializeLeaderElection(); err != nil {
+		return err
+	}
+	if err := s.initializeStore(); err != nil {
+		return err
+	}
+	s.initializeScanner()
 
 	// Phase 2: Register all component tools
 	s.registerAllComponents()
@@ -82,8 +183,27 @@ func (s *Service) initializeInfrastructure() error {
 	s.azClient = azClient
 	log.Println("Azure client initialized successfully")
 
+	if s.cfg.Record != "" {
+		recorder, err := replay.NewRecorder(s.cfg.Record)
+		if err != nil {
+			return err
+		}
+		s.recorder = recorder
+		command.SetInterceptor(recorder)
+		log.Printf("Recording tool calls and az CLI commands to %s", s.cfg.Record)
+	}
+
 	// Ensure Azure CLI exists and is logged in
-	if s.azcliProcFactory != nil {
+	if s.cfg.SessionCredentials {
+		// Process-wide credentials must never be used when each session supplies its own
+		log.Println("Session credential mode enabled, skipping process-wide Azure CLI login")
+		env := s.cfg.CloudEnvironment()
+		s.tokenVerifier = session.NewVerifier(env.ResourceManagerEndpoint, env.ResourceManagerAudience)
+	} else if s.cfg.NoAzCli {
+		log.Println("Running without the Azure CLI (--no-azcli), skipping Azure CLI login")
+	} else if s.cfg.ReplayMock {
+		log.Println("Replaying recorded az CLI output (--replay-mock), skipping Azure CLI login")
+	} else if s.azcliProcFactory != nil {
 		// Use injected factory to create an azcli.Proc
 		proc := s.azcliProcFactory(s.cfg.Timeout)
 		if loginType, err := azcli.EnsureAzCliLoginWithProc(proc, s.cfg); err != nil {
@@ -100,29 +220,392 @@ func (s *Service) initializeInfrastructure() error {
 	}
 
 	// Create MCP server
-	s.mcpServer = server.NewMCPServer(
-		"AKS MCP",
-		version.GetVersion(),
+	serverOpts := []server.ServerOption{
 		server.WithResourceCapabilities(true, true),
 		server.WithPromptCapabilities(true),
 		server.WithLogging(),
 		server.WithRecovery(),
-	)
+		server.WithInstructions(componentInstructions(s.cfg)),
+	}
+	apiKeys, err := s.cfg.APIKeys()
+	if err != nil {
+		return err
+	}
+	if s.cfg.LogProfiles, err = s.cfg.LoadLogProfiles(); err != nil {
+		return err
+	}
+	if s.cfg.LogProfiles != nil {
+		log.Printf("Loaded control plane log profiles %s from %s", strings.Join(s.cfg.LogProfiles.Names(), ", "), s.cfg.LogProfilesFile)
+	}
+	if apiKeys != nil {
+		// Clients only see the tools their key may call
+		s.apiKeys = apiKeys
+		serverOpts = append(serverOpts, server.WithToolFilter(s.filterToolsForAPIKey))
+		log.Printf("API key authentication enabled with %d keys from %s", len(apiKeys.Keys()), s.cfg.APIKeysFile)
+	}
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(s.addUpdateNotice)
+	hooks.AddOnUnregisterSession(func(_ context.Context, clientSession server.ClientSession) {
+		s.clusterChoices.release(clientSession.SessionID())
+	})
+	if s.cfg.SessionCredentials {
+		// Drop per-session SDK clients and az CLI state as soon as a session closes
+		hooks.AddOnUnregisterSession(func(_ context.Context, clientSession server.ClientSession) {
+			s.releaseSession(clientSession.SessionID())
+		})
+	}
+	if s.cfg.InventoryResources && !s.cfg.SessionCredentials {
+		// Sessions subscribe to inventory resources by reading them or with resources/subscribe
+		s.subscriptions = newResourceSubscriptions(resourceUpdateDebounce, func(sessionID, uri string) error {
+			return s.mcpServer.SendNotificationToSpecificClient(sessionID, string(mcp.MethodNotificationResourceUpdated), map[string]any{"uri": uri})
+		})
+		hooks.AddOnRequestInitialization(s.subscriptions.observeRequest)
+		hooks.AddAfterReadResource(s.subscriptions.observeRead)
+		hooks.AddOnUnregisterSession(func(_ context.Context, clientSession server.ClientSession) {
+			s.subscriptions.release(clientSession.SessionID())
+		})
+	}
+	serverOpts = append(serverOpts, server.WithHooks(hooks))
+	s.mcpServer = server.NewMCPServer("AKS MCP", version.GetVersion(), serverOpts...)
+	if s.cfg.SamplingSummaries {
+		// Summary verbosity calls ask the client's model for their summary
+		s.mcpServer.EnableSampling()
+	}
 	log.Println("MCP server initialized successfully")
 
 	return nil
 }
 
-// registerAllComponents registers all component tools organized by category
+// initializeLeaderElection sets up the background task coordinator.
+// Without leader election the replica is always leader.
+func (s *Service) initializeLeaderElection() error {
+	if !s.cfg.LeaderElection {
+		s.coordinator = leader.NewCoordinator(nil)
+		return nil
+	}
+
+	restConfig, err := genericclioptions.NewConfigFlags(true).ToRESTConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig for leader election: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client for leader election: %w", err)
+	}
+	elector, err := leader.NewLeaseElector(client, leader.LeaseConfig{
+		Namespace: s.cfg.LeaderElectionNamespace,
+		Name:      s.cfg.LeaderElectionLeaseName,
+	})
+	if err != nil {
+		return err
+	}
+	s.coordinator = leader.NewCoordinator(elector)
+	s.sharedStore = leader.NewConfigMapStore(client, s.cfg.LeaderElectionNamespace, s.cfg.LeaderElectionLeaseName+"-state")
+	log.Printf("Leader election enabled (lease %s/%s, identity %s)",
+		s.cfg.LeaderElectionNamespace, s.cfg.LeaderElectionLeaseName, elector.Identity())
+	return nil
+}
+
+// initializeStore opens the state store. When the bolt database cannot be opened, for example
+// because another aks-mcp process holds its lock, state is kept in memory for this process.
+// A configured audit signing key that cannot be read stops startup rather than leaving records unsigned.
+func (s *Service) initializeStore() error {
+	st, err := store.Open(s.cfg.StateStore, s.cfg.StatePath)
+	if err != nil {
+		log.Printf("Warning: %v; server state will not persist across restarts", err)
+		st = store.NewMemoryStore()
+	}
+	s.store = st
+
+	key, err := s.cfg.AuditSigningKey()
+	if err != nil {
+		return err
+	}
+	var opts []audit.Option
+	if key != nil {
+		log.Println("Audit records are signed with the configured key")
+		opts = append(opts, audit.WithSigningKey(key))
+	}
+	if s.cfg.AuditRetentionDays > 0 {
+		opts = append(opts, audit.WithRetention(time.Duration(s.cfg.AuditRetentionDays)*24*time.Hour))
+	}
+	if err := s.initializeExport(); err != nil {
+		return err
+	}
+	if s.exporter != nil {
+		opts = append(opts, audit.WithObserver(func(record audit.Record) {
+			s.publish(export.TypeAudit, record)
+		}))
+	}
+	s.auditLog = audit.NewLogger(st, opts...)
+	s.approvals = approval.NewManager(st, 0)
+	s.notes = notes.NewBook(st)
+	return nil
+}
+
+// initializeExport creates the exporter of audit records and findings when --export-sink is set. Event Hubs
+// use the server's Azure credential unless a connection string is configured; in session credential mode
+// there is no process-wide credential, so only the connection string can be used.
+func (s *Service) initializeExport() error {
+	if s.cfg.ExportSink == "" {
+		return nil
+	}
+	var token export.TokenSource
+	if !s.cfg.SessionCredentials && s.azClient != nil {
+		token = s.azClient.EventHubsToken
+	}
+	sink, err := export.Open(s.cfg.ExportSink, token)
+	if err != nil {
+		return err
+	}
+	exporter, err := export.New(sink, s.store, s.cfg.ExportQueueSize)
+	if err != nil {
+		return err
+	}
+	s.exporter = exporter
+	log.Printf("Exporting audit records and findings to %s", sink.Name())
+	return nil
+}
+
+// publish queues an event for the export sink. Export failures are logged but never fail the action
+// that produced the event; events dropped from a full queue are already logged by the exporter.
+func (s *Service) publish(eventType string, data interface{}) {
+	if err := s.exporter.Publish(eventType, data); err != nil && !errors.Is(err, export.ErrQueueFull) {
+		log.Printf("[EXPORT] %v", err)
+	}
+}
+
+// findingNotification is the method of the notifications that carry scanner findings
+const findingNotification = "notifications/aks/finding"
+
+// initializeScanner registers the background finding scanner with the coordinator when findings are pushed
+// to clients or exported, or the inventory resources are served. Findings go out as notifications to every
+// connected client of the replica running the scanner, and to the export sink. Exported findings are scanned
+// with the server's credential, so they are not scanned in session credential mode.
+//
+// With leader election only the leader scans. It keeps the open findings and the inventory in the shared
+// store, so every replica serves them, but only clients connected to the leader receive pushed findings and
+// inventory change notifications.
+func (s *Service) initializeScanner() {
+	exportFindings := s.exporter != nil && !s.cfg.SessionCredentials
+	if (!s.cfg.PushFindings && !exportFindings && s.subscriptions == nil) || s.azClient == nil {
+		return
+	}
+	scanStore := s.store
+	if s.sharedStore != nil {
+		scanStore = s.sharedStore
+		if s.cfg.PushFindings || s.subscriptions != nil {
+			log.Println("Leader election is enabled: findings and inventory updates are pushed only to clients connected to the leader replica")
+		}
+	}
+	var opts []scanner.Option
+	if s.subscriptions != nil {
+		opts = append(opts, scanner.WithChangeHook(func(part string) {
+			s.subscriptions.changed(inventoryURIs[part])
+		}))
+	}
+	if s.cfg.SecretExpiryDays > 0 {
+		kubectlExecutor := k8s.WrapK8sExecutor(kubectl.NewExecutor())
+		opts = append(opts, scanner.WithSecretExpiry(scanner.SecretExpiry{
+			Window: time.Duration(s.cfg.SecretExpiryDays) * 24 * time.Hour,
+			Graph:  s.azClient,
+			Kubectl: func(args string) (string, error) {
+				return kubectlExecutor.Execute(map[string]interface{}{"command": args}, s.cfg)
+			},
+			NamespaceFlags: common.NamespaceFlags(s.cfg.AllowNamespaces),
+		}))
+	}
+	sc := scanner.New(s.azClient, scanStore, s.cfg.ScanInterval, func(finding scanner.Finding) {
+		log.Printf("[SCANNER] %s: %s", finding.Kind, finding.Message)
+		if s.cfg.PushFindings {
+			s.mcpServer.SendNotificationToAllClients(findingNotification, map[string]any{"finding": finding})
+		}
+		if exportFindings {
+			s.publish(export.TypeFinding, finding)
+		}
+	}, opts...)
+	if s.subscriptions != nil {
+		s.registerInventoryResources(sc)
+	}
+	s.coordinator.Register(leader.Task{Name: "finding-scanner", Run: sc.Run})
+}
+
+// Store returns the store that subsystems persist their state in
+func (s *Service) Store() store.Store {
+	return s.store
+}
+
+// Coordinator returns the coordinator that background subsystems register with
+func (s *Service) Coordinator() *leader.Coordinator {
+	return s.coordinator
+}
+
+// startBackground starts the coordinator so registered background tasks run while this replica leads.
+// Work whose state is per replica runs on every replica: the idle session and artifact sweeps, detector
+// catalog prewarming and the export of the replica's audit records. Detached Inspektor Gadget gadgets buffer
+// their events in the cluster's DaemonSet rather than in a replica, so they need no task here.
+func (s *Service) startBackground() {
+	if s.coordinator == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.backgroundMu.Lock()
+	s.stopBackground, s.backgroundDone = cancel, done
+	s.backgroundMu.Unlock()
+	if s.cfg.SessionCredentials {
+		go s.sweepIdleSessions(ctx)
+	}
+	if s.cfg.Artifacts != nil {
+		go s.sweepArtifacts(ctx)
+	}
+	if s.auditLog != nil && s.auditLog.Retention() > 0 {
+		go s.pruneAuditLog(ctx)
+	}
+	if len(s.cfg.PrewarmDetectors) > 0 && !s.cfg.SessionCredentials && s.azClient != nil && s.cfg.ComponentEnabled(config.ComponentDetectors) {
+		// Detector catalogs are cached per replica, so each replica keeps its own warm
+		go detectors.KeepCatalogsWarm(ctx, s.azClient, s.cfg.PrewarmDetectors)
+	}
+	go func() {
+		defer close(done)
+		// Audit records are written on every replica, so each replica exports its own queue
+		var exporting sync.WaitGroup
+		if s.exporter != nil {
+			exporting.Add(1)
+			go func() {
+				defer exporting.Done()
+				s.exporter.Run(ctx)
+			}()
+		}
+		s.coordinator.Start(ctx)
+		exporting.Wait()
+	}()
+}
+
+// Shutdown stops background subsystems and releases leadership, waiting briefly
+// so the Lease is released before the process exits, kills running subprocesses
+// and then closes the state store
+func (s *Service) Shutdown() {
+	s.backgroundMu.Lock()
+	stop, done := s.stopBackground, s.backgroundDone
+	s.backgroundMu.Unlock()
+	if stop != nil {
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			log.Println("Timed out waiting for background subsystems to stop")
+		}
+	}
+	if s.portForwards != nil {
+		s.portForwards.Close()
+	}
+	if killed := command.KillAll(); killed > 0 {
+		log.Printf("Killed %d running subprocesses", killed)
+	}
+	if s.store != nil {
+		if err := s.store.Close(); err != nil {
+			log.Printf("Failed to close state store: %v", err)
+		}
+	}
+	if s.recorder != nil {
+		command.SetInterceptor(nil)
+		if err := s.recorder.Close(); err != nil {
+			log.Printf("Failed to close recording: %v", err)
+		}
+	}
+}
+
+// handleLeaderStatus reports the leadership state of this replica
+func (s *Service) handleLeaderStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	status := leader.Status{}
+	if s.coordinator != nil {
+		status = s.coordinator.Status()
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// componentInstructions reports the enabled tool components to clients in the initialize response
+func componentInstructions(cfg *config.ConfigData) string {
+	instructions := fmt.Sprintf("AKS MCP server (access level: %s). Enabled components: %s.",
+		cfg.AccessLevel, strings.Join(cfg.EnabledComponentNames(), ", "))
+	if cfg.NoAzCli {
+		instructions += " The server runs without the Azure CLI, so only tools backed by the Azure SDK are available."
+	}
+	return instructions
+}
+
+// azCliComponents are the Azure components whose tools all run the Azure CLI
+var azCliComponents = map[string]bool{
+	config.ComponentMonitor:  true,
+	config.ComponentFleet:    true,
+	config.ComponentAdvisor:  true,
+	config.ComponentIdentity: true,
+	config.ComponentGPU:      true,
+}
+
+// azureComponentEnabled reports whether an Azure component should be registered.
+// Components that need the Azure CLI are skipped when the server runs without it.
+func (s *Service) azureComponentEnabled(name string) bool {
+	if !s.cfg.ComponentEnabled(name) {
+		return false
+	}
+	if s.cfg.NoAzCli && azCliComponents[name] {
+		log.Printf("Skipping %s component: it requires the Azure CLI (--no-azcli)", name)
+		return false
+	}
+	return true
+}
+
+// registerAllComponents registers the enabled component tools organized by category
 func (s *Service) registerAllComponents() {
+	log.Printf("Enabled components: %s", strings.Join(s.cfg.EnabledComponentNames(), ", "))
+
 	// Azure Components
 	s.registerAzureComponents()
 
+	// Audit log verification
+	s.registerAuditComponent()
+
+	// Operator notes per cluster
+	s.registerNotesComponent()
+
 	// Kubernetes Components
-	s.registerKubernetesComponents()
+	if s.cfg.KubernetesAccessEnabled() {
+		s.inComponent(config.ComponentKubernetes, s.registerKubernetesComponents)
+	} else if s.cfg.SessionCredentials {
+		log.Println("Session credential mode enabled, skipping Kubernetes tools that would use the server kubeconfig")
+	}
 
 	// Prompts
 	s.registerPrompts()
+
+	// Large tool outputs
+	s.registerArtifactResources()
+}
+
+// registerAuditComponent registers the audit log verification tool
+func (s *Service) registerAuditComponent() {
+	if s.auditLog == nil {
+		return
+	}
+	log.Println("Registering audit tool: verify_audit_log")
+	s.addTool(audit.RegisterVerifyAuditLogTool(), tools.CreateResourceHandler(audit.GetVerifyAuditLogHandler(s.auditLog), s.cfg))
+}
+
+// registerNotesComponent registers the operator notes tool. Notes are read and written with the caller's
+// credential, so the tool is also available in session credential mode.
+func (s *Service) registerNotesComponent() {
+	if s.notes == nil {
+		return
+	}
+	log.Println("Registering notes tool: aks_cluster_notes")
+	s.addTool(notes.RegisterClusterNotesTool(), tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return notes.GetClusterNotesHandler(s.notes, c, cfg)
+	}), s.cfg))
 }
 
 // registerPrompts registers all available prompts
@@ -134,6 +617,11 @@ func (s *Service) registerPrompts() {
 
 	log.Println("Registering health prompts (check_cluster_health)")
 	prompts.RegisterHealthPrompts(s.mcpServer, s.cfg)
+
+	if s.cfg.PromptsDir != "" {
+		log.Printf("Registering prompt templates from %s", s.cfg.PromptsDir)
+		prompts.RegisterTemplatePrompts(s.mcpServer, s.cfg)
+	}
 }
 
 // createCustomHTTPServerWithHelp404 creates a custom HTTP server that provides
@@ -141,6 +629,15 @@ func (s *Service) registerPrompts() {
 func (s *Service) createCustomHTTPServerWithHelp404(addr string) *http.Server {
 	mux := http.NewServeMux()
 
+	// Leadership status for readiness checks and debugging of replicated deployments
+	mux.HandleFunc("/leader", s.handleLeaderStatus)
+
+	// Machine-readable input and result schemas of the registered tools
+	mux.HandleFunc("/schema", s.handleToolSchemas)
+
+	// Subprocess counts for monitoring
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
 	// Handle all other paths with a helpful 404 response
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/mcp" {
@@ -155,6 +652,8 @@ func (s *Service) createCustomHTTPServerWithHelp404(addr string) *http.Server {
 					"requests":   "POST /mcp - Send MCP requests (requires Mcp-Session-Id header)",
 					"listen":     "GET /mcp - Listen for notifications (requires Mcp-Session-Id header)",
 					"terminate":  "DELETE /mcp - Terminate session (requires Mcp-Session-Id header)",
+					"schema":     "GET /schema - Input and result schemas of the registered tools",
+					"metrics":    "GET /metrics - Subprocess counts in the Prometheus text format",
 				},
 			}
 
@@ -177,8 +676,11 @@ func (s *Service) createCustomSSEServerWithHelp404(sseServer *server.SSEServer,
 	mux := http.NewServeMux()
 
 	// Register SSE and Message handlers
-	mux.Handle("/sse", sseServer.SSEHandler())
-	mux.Handle("/message", sseServer.MessageHandler())
+	mux.Handle("/sse", s.requireAPIKey(s.requireSessionCredential(sseServer.SSEHandler())))
+	mux.Handle("/message", s.requireAPIKey(s.requireSessionCredential(sseServer.MessageHandler())))
+	mux.HandleFunc("/leader", s.handleLeaderStatus)
+	mux.HandleFunc("/schema", s.handleToolSchemas)
+	mux.HandleFunc("/metrics", s.handleMetrics)
 
 	// Handle all other paths with a helpful 404 response
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -192,6 +694,8 @@ func (s *Service) createCustomSSEServerWithHelp404(sseServer *server.SSEServer,
 				"endpoints": map[string]string{
 					"sse":     "GET /sse - Establish SSE connection for real-time notifications",
 					"message": "POST /message - Send MCP JSON-RPC messages",
+					"schema":  "GET /schema - Input and result schemas of the registered tools",
+					"metrics": "GET /metrics - Subprocess counts in the Prometheus text format",
 				},
 			}
 
@@ -211,17 +715,49 @@ func (s *Service) createCustomSSEServerWithHelp404(sseServer *server.SSEServer,
 // Run starts the service with the specified transport
 func (s *Service) Run() error {
 	log.Println("AKS MCP version:", version.GetVersion())
+	s.checkForUpdate()
+
+	if s.cfg.SessionCredentials && s.cfg.Transport == "stdio" {
+		return fmt.Errorf("session credential mode requires the sse or streamable-http transport")
+	}
+	if s.cfg.APIKeysFile != "" && s.cfg.Transport == "stdio" {
+		return fmt.Errorf("--api-keys-file requires the sse or streamable-http transport")
+	}
+	if s.cfg.PushFindings && (s.cfg.Transport != "sse" || s.cfg.SessionCredentials) {
+		return fmt.Errorf("--push-findings requires the sse transport without session credentials")
+	}
+
+	// Start background subsystems (leader replica only when leader election is enabled)
+	s.startBackground()
 
 	// Start the server
-	switch s.cfg.Transport {
-	case "stdio":
+	if s.cfg.Transport == "stdio" {
 		log.Println("Listening for requests on STDIO...")
 		return server.ServeStdio(s.mcpServer)
-	case "sse":
-		addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	}
+	customServer, err := s.HTTPServer()
+	if err != nil {
+		return err
+	}
+	return customServer.ListenAndServe()
+}
 
+// HTTPServer returns the HTTP server of the sse or streamable-http transport, listening on the configured
+// host and port, with the endpoints and middleware Run serves. The load test harness serves it on its own listener.
+func (s *Service) HTTPServer() (*http.Server, error) {
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	switch s.cfg.Transport {
+	case "sse":
 		// Create SSE server first
-		sse := server.NewSSEServer(s.mcpServer)
+		var sseOpts []server.SSEOption
+		if s.cfg.SessionCredentials {
+			sseOpts = append(sseOpts, server.WithSSEContextFunc(s.sessionCredentialContext))
+		}
+		if s.cfg.PushFindings {
+			// Keep idle connections open so findings reach clients between tool calls
+			sseOpts = append(sseOpts, server.WithKeepAlive(true))
+		}
+		sse := server.NewSSEServer(s.mcpServer, sseOpts...)
 
 		// Create custom HTTP server with helpful 404 responses
 		customServer := s.createCustomSSEServerWithHelp404(sse, addr)
@@ -231,32 +767,178 @@ func (s *Service) Run() error {
 		log.Printf("Message endpoint available at: http://%s/message", addr)
 		log.Printf("Connect to /sse for real-time events, send JSON-RPC to /message")
 
-		return customServer.ListenAndServe()
+		return customServer, nil
 	case "streamable-http":
-		addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
-
 		// Create a custom HTTP server with helpful 404 responses
 		customServer := s.createCustomHTTPServerWithHelp404(addr)
 
 		// Create the streamable HTTP server with the custom HTTP server
-		streamableServer := server.NewStreamableHTTPServer(
-			s.mcpServer,
-			server.WithStreamableHTTPServer(customServer),
-		)
+		streamableOpts := []server.StreamableHTTPOption{server.WithStreamableHTTPServer(customServer)}
+		if s.cfg.SessionCredentials {
+			streamableOpts = append(streamableOpts, server.WithHTTPContextFunc(s.sessionCredentialContext))
+		}
+		streamableServer := server.NewStreamableHTTPServer(s.mcpServer, streamableOpts...)
 
 		// Update the mux to use the actual streamable server as the MCP handler
 		if mux, ok := customServer.Handler.(*http.ServeMux); ok {
-			mux.Handle("/mcp", streamableServer)
+			mux.Handle("/mcp", s.requireAPIKey(s.requireSessionCredential(streamableServer)))
 		}
 
 		log.Printf("Streamable HTTP server listening on %s", addr)
 		log.Printf("MCP endpoint available at: http://%s/mcp", addr)
 		log.Printf("Send POST requests to /mcp to initialize session and obtain Mcp-Session-Id")
 
-		return customServer.ListenAndServe()
+		return customServer, nil
 	default:
-		return fmt.Errorf("invalid transport type: %s (must be 'stdio', 'sse' or 'streamable-http')", s.cfg.Transport)
+		return nil, fmt.Errorf("invalid transport type: %s (must be 'stdio', 'sse' or 'streamable-http')", s.cfg.Transport)
+	}
+}
+
+// ServeStdio serves the stdio transport on the given streams until ctx is cancelled or in is closed. The load
+// test harness uses it to drive the server through pipes.
+func (s *Service) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	return server.NewStdioServer(s.mcpServer).Listen(ctx, in, out)
+}
+
+// sessionCredentialContext attaches the Azure credentials supplied with an HTTP request to the request context
+func (s *Service) sessionCredentialContext(ctx context.Context, r *http.Request) context.Context {
+	cred := session.FromRequest(r)
+	if cred == nil {
+		return ctx
+	}
+	if clientSession := server.ClientSessionFromContext(ctx); clientSession != nil {
+		cred.SessionID = clientSession.SessionID()
+	}
+	return session.WithCredential(ctx, cred)
+}
+
+// requireSessionCredential rejects HTTP requests whose session access token is missing or invalid,
+// so no tool call in session credential mode runs without verified caller credentials
+func (s *Service) requireSessionCredential(next http.Handler) http.Handler {
+	if !s.cfg.SessionCredentials {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cred := session.FromRequest(r)
+		if cred == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, fmt.Sprintf("session credentials are required: send an Azure access token in the %s header as 'Bearer <token>'", session.HeaderAuthorization), http.StatusUnauthorized)
+			return
+		}
+		if err := cred.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.tokenVerifier.Verify(r.Context(), cred); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, fmt.Sprintf("invalid session access token: %v", err), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// releaseSession removes the SDK clients, az CLI state and artifacts held for a session
+func (s *Service) releaseSession(sessionID string) {
+	if s.azClient != nil {
+		s.azClient.ReleaseSession(sessionID)
+	}
+	azcli.ReleaseSession(sessionID)
+	s.cfg.Artifacts.ReleaseOwner(sessionID)
+}
+
+// sweepIdleSessions periodically evicts the state of sessions that stopped sending requests
+// without closing, until ctx is cancelled
+func (s *Service) sweepIdleSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.azClient != nil {
+				s.azClient.SweepSessions(sessionIdleTimeout)
+			}
+			azcli.SweepSessions(sessionIdleTimeout)
+		}
+	}
+}
+
+// pruneAuditLog removes audit records past the retention right away and then every auditPruneInterval,
+// until ctx is cancelled
+func (s *Service) pruneAuditLog(ctx context.Context) {
+	ticker := time.NewTicker(auditPruneInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.auditLog.Prune(); err != nil {
+			log.Printf("Failed to prune the audit log: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sessionAwareHandler builds a resource handler with the shared Azure client. In session credential
+// mode the handler is instead built per call with a session-scoped Azure client and configuration,
+// so SDK and az CLI calls only ever use the credentials of the calling session. Calls that ask
+// for an explanation are also built per call, with a client that records their ARM requests, as
+// are calls in review mode, calls with their own timeout or verbosity, and calls made with an API key,
+// whose configuration carries the key's access level. Built handlers that need the call context get it.
+func (s *Service) sessionAwareHandler(build func(client *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler) tools.ResourceHandler {
+	var shared tools.ResourceHandler
+	if !s.cfg.SessionCredentials {
+		shared = build(s.azClient, s.cfg)
+	}
+	return tools.ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		handler := shared
+		if handler == nil || cfg.Explain != nil || cfg.Review != nil || cfg.APIKey != nil || cfg.Timeout != s.cfg.Timeout || cfg.Verbosity != s.cfg.Verbosity {
+			client, err := s.azClient.ForSession(cfg.Session)
+			if err != nil {
+				return "", err
+			}
+			// Explained calls get a client that records its ARM requests, calls in review mode a client that
+			// defers its ARM writes, and calls with their own timeout a client whose ARM requests use it
+			handler = build(client.ForExplain(cfg.Explain).ForReview(cfg.Review).ForTimeout(cfg.Timeout), cfg)
+		}
+		if contextHandler, ok := handler.(tools.ContextResourceHandler); ok {
+			return contextHandler.HandleContext(ctx, params, cfg)
+		}
+		return handler.Handle(params, cfg)
+	})
+}
+
+// addTool registers an aks-mcp tool with the explain, verbosity and review arguments handled by the shared tool
+// handlers. The review argument is only offered when the server may write and does not already run in review mode.
+// Tools that take subscription_id, resource_group and cluster_name also resolve the first two from
+// the cluster name when they are omitted.
+func (s *Service) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if takesClusterParameters(tool) {
+		tool = withInferredClusterParameters(tool)
+		handler = s.resolveClusterParameters(handler)
+	}
+	if s.cfg.AccessLevel != "readonly" && !s.cfg.ReviewMode {
+		tool = tools.WithReview(tool)
+	}
+	tool, handler = tools.WithTimeout(tools.WithVerbosity(tools.WithExplain(tool)), handler, s.cfg)
+	if s.recorder != nil {
+		handler = s.recorder.Wrap(tool.Name, handler)
 	}
+	s.registerTool(tool, handler)
+}
+
+// registerTool adds a tool to the MCP server and the schema document, scoped to the component and access
+// level being registered so calls are checked against the caller's API key
+func (s *Service) registerTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if s.toolScopes == nil {
+		s.toolScopes = make(map[string]toolScope)
+	}
+	s.toolScopes[tool.Name] = s.scope
+	s.registeredTools = append(s.registeredTools, tool)
+	s.mcpServer.AddTool(tool, s.authorizeTool(tool.Name, s.scope, handler))
 }
 
 // registerAzureComponents registers all Azure tools (AKS operations, monitoring, fleet, network, compute, detectors, advisor)
@@ -264,28 +946,86 @@ func (s *Service) registerAzureComponents() {
 	log.Println("Registering Azure Components...")
 
 	// AKS Operations Component
-	s.registerAksOpsComponent()
+	if s.azureComponentEnabled(config.ComponentAzAks) {
+		s.inComponent(config.ComponentAzAks, func() {
+			s.registerAksOpsComponent()
+			s.registerEstateComponent()
+			s.registerTagsComponent()
+			s.registerRestComponent()
+			s.registerUpgradeComponent()
+			s.registerSupportBundleComponent()
+		})
+	}
 
 	// Monitoring Component
-	s.registerMonitoringComponent()
+	if s.azureComponentEnabled(config.ComponentMonitor) {
+		s.inComponent(config.ComponentMonitor, s.registerMonitoringComponent)
+	}
 
 	// Fleet Management Component
-	s.registerFleetComponent()
+	if s.azureComponentEnabled(config.ComponentFleet) {
+		s.inComponent(config.ComponentFleet, s.registerFleetComponent)
+	}
 
 	// Network Resources Component
-	s.registerNetworkComponent()
+	if s.azureComponentEnabled(config.ComponentNetwork) {
+		s.inComponent(config.ComponentNetwork, s.registerNetworkComponent)
+	}
 
 	// Compute Resources Component
-	s.registerComputeComponent()
+	if s.azureComponentEnabled(config.ComponentCompute) {
+		s.inComponent(config.ComponentCompute, s.registerComputeComponent)
+	}
 
 	// Detector Resources Component
-	s.registerDetectorComponent()
+	if s.azureComponentEnabled(config.ComponentDetectors) {
+		s.inComponent(config.ComponentDetectors, s.registerDetectorComponent)
+	}
 
 	// Azure Advisor Component
-	s.registerAdvisorComponent()
+	if s.azureComponentEnabled(config.ComponentAdvisor) {
+		s.inComponent(config.ComponentAdvisor, s.registerAdvisorComponent)
+	}
+
+	// Identity Permissions Component
+	if s.azureComponentEnabled(config.ComponentIdentity) {
+		s.inComponent(config.ComponentIdentity, s.registerIdentityComponent)
+	}
+
+	// Certificate Expiry Component (reads cluster secrets with the server kubeconfig)
+	if s.cfg.ComponentEnabled(config.ComponentCertificates) && !s.cfg.SessionCredentials {
+		s.inComponent(config.ComponentCertificates, s.registerCertificatesComponent)
+	}
+
+	// Image Vulnerability Component (lists pods with the server kubeconfig)
+	if s.cfg.ComponentEnabled(config.ComponentVulnerabilities) && !s.cfg.SessionCredentials {
+		s.inComponent(config.ComponentVulnerabilities, s.registerVulnerabilitiesComponent)
+	}
+
+	// Register Inspektor Gadget tools for observability (uses the server kubeconfig)
+	if s.cfg.ComponentEnabled(config.ComponentInspektorGadget) && !s.cfg.SessionCredentials {
+		s.inComponent(config.ComponentInspektorGadget, s.registerInspektorGadgetComponent)
+	}
 
-	// Register Inspektor Gadget tools for observability
-	s.registerInspektorGadgetComponent()
+	// Chaos Studio Experiments Component
+	if s.cfg.ComponentEnabled(config.ComponentChaos) {
+		s.inComponent(config.ComponentChaos, s.registerChaosComponent)
+	}
+
+	// Regional Failover Readiness Component
+	if s.azureComponentEnabled(config.ComponentFailover) {
+		s.inComponent(config.ComponentFailover, s.registerFailoverComponent)
+	}
+
+	// GPU Diagnostics Component (reads nodes and pods with the server kubeconfig)
+	if s.azureComponentEnabled(config.ComponentGPU) && !s.cfg.SessionCredentials {
+		s.inComponent(config.ComponentGPU, s.registerGPUComponent)
+	}
+
+	// Storage Artifacts Component (session tokens are ARM tokens, which Blob storage does not accept)
+	if s.azureComponentEnabled(config.ComponentStorage) && !s.cfg.SessionCredentials {
+		s.inComponent(config.ComponentStorage, s.registerStorageComponent)
+	}
 
 	log.Println("Azure Components registered successfully")
 }
@@ -297,6 +1037,36 @@ func (s *Service) registerKubernetesComponents() {
 	// Core Kubernetes Component (kubectl)
 	s.registerKubectlComponent()
 
+	// Node cordon/drain orchestration
+	s.registerNodesComponent()
+
+	// Container exec and port-forward sessions
+	s.registerPodAccessComponent()
+
+	// Server-side apply of manifests after a confirmed diff
+	s.registerApplyComponent()
+
+	// Namespace-wide deployment restarts, scaling and job cleanup after a confirmed preview
+	s.registerBulkComponent()
+
+	// Bounded event watch streamed as progress notifications
+	s.registerEventsComponent()
+
+	// Server-side wait for rollouts, pod readiness and job completion
+	s.registerWaitComponent()
+
+	// Job and CronJob failure analysis
+	s.registerJobsComponent()
+
+	// Summary of recent cluster changes
+	s.registerChangesComponent()
+
+	// First incident triage checks in one call
+	s.registerTriageComponent()
+
+	// Cost per namespace and workload
+	s.registerCostComponent()
+
 	// Optional Kubernetes Components (based on configuration)
 	s.registerOptionalKubernetesComponents()
 
@@ -319,10 +1089,169 @@ func (s *Service) registerKubectlComponent() {
 	// Register each kubectl tool
 	for _, tool := range kubectlTools {
 		log.Printf("Registering kubectl tool: %s", tool.Name)
-		// Create a handler that injects the tool name into params
-		handler := k8stools.CreateToolHandlerWithName(kubectlExecutor, k8sCfg, tool.Name)
-		s.mcpServer.AddTool(tool, handler)
+		// Create a handler that injects the tool name into params and runs kubectl with the call's timeout
+		toolName := tool.Name
+		handler := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			callCfg := *k8sCfg
+			cfg := s.cfg
+			if key := apikey.FromContext(ctx); key != nil {
+				// Calls made with an API key are validated at the key's access level
+				cfg = s.cfg.ForAPIKey(key)
+				callCfg = *k8s.ConvertConfig(cfg)
+			}
+			callCfg.Timeout = tools.CallTimeout(ctx, s.cfg)
+			if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
+				if result := s.reviewKubectlCall(toolName, args, cfg.ForTimeout(callCfg.Timeout)); result != nil {
+					return result, nil
+				}
+			}
+			return k8stools.CreateToolHandlerWithName(kubectlExecutor, &callCfg, toolName)(ctx, req)
+		}
+		if s.cfg.AccessLevel != "readonly" && !s.cfg.ReviewMode {
+			tool = tools.WithReview(tool)
+		}
+		tool, timeoutHandler := tools.WithTimeout(tool, handler, s.cfg)
+		restore := s.requireAccessLevel(kubectlAccessLevel(tool.Name))
+		s.registerTool(tool, timeoutHandler)
+		restore()
+	}
+}
+
+// registerNodesComponent registers the node resource usage and noisy neighbor tools and the guarded node cordon
+// and drain tool.
+// kubectl cordon, uncordon and drain are admin operations, so the drain tool requires admin access.
+func (s *Service) registerNodesComponent() {
+	log.Println("Registering nodes tool: aks_resource_usage")
+	usageTool := nodes.RegisterResourceUsageTool()
+	s.addTool(usageTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return nodes.GetResourceUsageHandler(cfg)
+	}), s.cfg))
+
+	log.Println("Registering nodes tool: aks_noisy_neighbors")
+	noisyNeighborsTool := nodes.RegisterNoisyNeighborsTool()
+	s.addTool(noisyNeighborsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return nodes.GetNoisyNeighborsHandler(cfg)
+	}), s.cfg))
+
+	if s.cfg.AccessLevel != "admin" {
+		return
+	}
+	defer s.requireAccessLevel("admin")()
+	log.Println("Registering nodes tool: aks_node_drain")
+	drainTool := nodes.RegisterNodeDrainTool()
+	s.addTool(drainTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return nodes.GetNodeDrainHandler(cfg)
+	}), s.cfg))
+}
+
+// registerPodAccessComponent registers the one-shot exec and port-forward tools.
+// They give interactive access to workloads, so they require admin access.
+func (s *Service) registerPodAccessComponent() {
+	if s.cfg.AccessLevel != "admin" {
+		return
 	}
+	defer s.requireAccessLevel("admin")()
+	log.Println("Registering pod access tool: aks_pod_exec")
+	execTool := podaccess.RegisterPodExecTool(s.cfg)
+	s.addTool(execTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return podaccess.GetPodExecHandler(s.auditLog, cfg)
+	}), s.cfg))
+
+	log.Println("Registering pod access tool: aks_port_forward")
+	s.portForwards = podaccess.NewPortForwardManager(s.auditLog)
+	forwardTool := podaccess.RegisterPortForwardTool()
+	s.addTool(forwardTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return podaccess.GetPortForwardHandler(s.portForwards, cfg)
+	}), s.cfg))
+}
+
+// registerApplyComponent registers the manifest apply tool. Applying changes cluster state, so it requires
+// readwrite or admin access, and it needs the approval workflow and audit log set up by initializeStore.
+func (s *Service) registerApplyComponent() {
+	if s.cfg.AccessLevel != "readwrite" && s.cfg.AccessLevel != "admin" {
+		return
+	}
+	if s.approvals == nil || s.auditLog == nil {
+		return
+	}
+	defer s.requireAccessLevel("readwrite")()
+	log.Println("Registering apply tool: k8s_apply")
+	applyTool := apply.RegisterApplyTool()
+	s.addTool(applyTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return apply.GetApplyHandler(s.approvals, s.auditLog, cfg)
+	}), s.cfg))
+}
+
+// registerBulkComponent registers the namespace bulk operations tool. Like k8s_apply it changes cluster state
+// only after a confirmed preview, so it requires readwrite or admin access and the approval workflow.
+func (s *Service) registerBulkComponent() {
+	if s.cfg.AccessLevel != "readwrite" && s.cfg.AccessLevel != "admin" {
+		return
+	}
+	if s.approvals == nil || s.auditLog == nil {
+		return
+	}
+	defer s.requireAccessLevel("readwrite")()
+	log.Println("Registering bulk tool: aks_namespace_bulk")
+	bulkTool := bulk.RegisterNamespaceBulkTool()
+	s.addTool(bulkTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return bulk.GetNamespaceBulkHandler(s.approvals, s.auditLog, cfg)
+	}), s.cfg))
+}
+
+// registerEventsComponent registers the Kubernetes event watch tool.
+// The handler needs the call context, so it is not wrapped by sessionAwareHandler;
+// Kubernetes tools are never registered in session credential mode.
+func (s *Service) registerEventsComponent() {
+	log.Println("Registering events tool: aks_watch_events")
+	eventsTool := events.RegisterWatchEventsTool()
+	s.addTool(eventsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return events.GetWatchEventsHandler(cfg)
+	}), s.cfg))
+}
+
+// registerWaitComponent registers the wait-for-condition tool. Like the event watch it needs the call
+// context, so it is not wrapped by sessionAwareHandler.
+func (s *Service) registerWaitComponent() {
+	log.Println("Registering wait tool: aks_wait_for_condition")
+	waitTool := wait.RegisterWaitForConditionTool()
+	s.addTool(waitTool, tools.CreateResourceHandler(wait.GetWaitForConditionHandler(), s.cfg))
+}
+
+// registerJobsComponent registers the Job and CronJob failure analysis tool
+func (s *Service) registerJobsComponent() {
+	log.Println("Registering jobs tool: aks_job_failures")
+	jobsTool := jobs.RegisterJobFailuresTool()
+	s.addTool(jobsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return jobs.GetJobFailuresHandler(cfg)
+	}), s.cfg))
+}
+
+// registerChangesComponent registers the recent change summary tool
+func (s *Service) registerChangesComponent() {
+	log.Println("Registering changes tool: aks_recent_changes")
+	changesTool := changes.RegisterRecentChangesTool()
+	s.addTool(changesTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return changes.GetRecentChangesHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerTriageComponent registers the incident triage tool
+func (s *Service) registerTriageComponent() {
+	log.Println("Registering triage tool: aks_triage")
+	triageTool := triage.RegisterTriageTool()
+	s.addTool(triageTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return triage.GetTriageHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerCostComponent registers the namespace and workload cost estimate tool
+func (s *Service) registerCostComponent() {
+	log.Println("Registering cost tool: aks_cost_breakdown")
+	costTool := cost.RegisterCostBreakdownTool()
+	s.addTool(costTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return cost.GetCostBreakdownHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerOptionalKubernetesComponents registers optional Kubernetes tools based on configuration
@@ -348,35 +1277,212 @@ func (s *Service) registerInspektorGadgetComponent() {
 	// Register Inspektor Gadget tool
 	log.Println("Registering Inspektor Gadget Observability tool: inspektor_gadget_observability")
 	inspektorGadget := inspektorgadget.RegisterInspektorGadgetTool()
-	s.mcpServer.AddTool(inspektorGadget, tools.CreateResourceHandler(inspektorgadget.InspektorGadgetHandler(gadgetMgr, s.cfg), s.cfg))
+	s.addTool(inspektorGadget, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return inspektorgadget.InspektorGadgetHandler(gadgetMgr, cfg)
+	}), s.cfg))
+
+	log.Println("Registering Inspektor Gadget Observability tool: network_traffic_matrix")
+	s.addTool(trafficmatrix.RegisterTrafficMatrixTool(), tools.CreateResourceHandler(trafficmatrix.GetTrafficMatrixHandler(gadgetMgr), s.cfg))
 }
 
 // registerAksOpsComponent registers AKS operations tools
 func (s *Service) registerAksOpsComponent() {
+	if s.cfg.NoAzCli {
+		log.Println("Registering AKS operations tool: az_aks_operations (Azure SDK)")
+		s.addTool(azaks.RegisterAzAksSDKOperations(), tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return azaks.GetAksSDKOperationsHandler(c, cfg)
+		}), s.cfg))
+		return
+	}
+
 	log.Println("Registering AKS operations tool: az_aks_operations")
 	aksOperationsTool := azaks.RegisterAzAksOperations(s.cfg)
-	s.mcpServer.AddTool(aksOperationsTool, tools.CreateToolHandler(azaks.NewAksOperationsExecutor(), s.cfg))
+	s.addTool(aksOperationsTool, tools.CreateToolHandler(azaks.NewAksOperationsExecutor(), s.cfg))
+}
+
+// registerEstateComponent registers the subscription-wide AKS estate overview and deprecated features tools.
+// They only use Resource Graph and ARM, so they are available without the Azure CLI and in session credential mode.
+func (s *Service) registerEstateComponent() {
+	log.Println("Registering estate tool: aks_estate_overview")
+	estateTool := estate.RegisterEstateOverviewTool()
+	s.addTool(estateTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return estate.GetEstateOverviewHandler(c, s.notes, cfg)
+	}), s.cfg))
+
+	log.Println("Registering deprecated features tool: aks_deprecated_features")
+	deprecationsTool := estate.RegisterDeprecatedFeaturesTool()
+	s.addTool(deprecationsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return estate.GetDeprecatedFeaturesHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerTagsComponent registers the cluster and node resource group tagging tool. It only uses ARM,
+// so it is available without the Azure CLI; applying tags requires readwrite or admin access.
+func (s *Service) registerTagsComponent() {
+	log.Println("Registering tags tool: az_aks_tags")
+	tagsTool := tags.RegisterTagsTool()
+	s.addTool(tagsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return tags.GetTagsHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerRestComponent registers the ARM passthrough tool. It only uses ARM, so it is available without the
+// Azure CLI; PUT and POST requests require readwrite or admin access. Every call is audited, so it needs the
+// audit log set up by initializeStore, and it is not registered when --rest-allowed-paths is empty.
+func (s *Service) registerRestComponent() {
+	if len(s.cfg.RestAllowedPaths) == 0 || s.auditLog == nil {
+		return
+	}
+	log.Println("Registering ARM passthrough tool: az_rest")
+	restTool := azrest.RegisterRestTool(s.cfg.RestAllowedPaths)
+	s.addTool(restTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return azrest.GetRestHandler(c, s.auditLog, cfg)
+	}), s.cfg))
+}
+
+// registerUpgradeComponent registers the upgrade progress and tuning tools. They read nodes and pods with the server
+// kubeconfig and use the call context, so they are registered without the session-aware wrapper.
+func (s *Service) registerUpgradeComponent() {
+	if !s.cfg.KubernetesAccessEnabled() {
+		return
+	}
+	log.Println("Registering upgrade tool: aks_upgrade_progress")
+	s.addTool(upgrade.RegisterUpgradeProgressTool(), tools.CreateResourceHandler(upgrade.GetUpgradeProgressHandler(s.azClient), s.cfg))
+	log.Println("Registering upgrade tool: aks_upgrade_tuning")
+	s.addTool(upgrade.RegisterUpgradeTuningTool(), tools.CreateResourceHandler(upgrade.GetUpgradeTuningHandler(s.azClient), s.cfg))
+}
+
+// registerSupportBundleComponent registers the support bundle tool. Bundles copy cluster configuration and logs
+// out of the cluster, so the tool requires admin access; it collects with the server kubeconfig and uploads
+// with the server credential, so it is not registered in session credential mode.
+func (s *Service) registerSupportBundleComponent() {
+	if s.cfg.AccessLevel != "admin" || s.cfg.SessionCredentials {
+		return
+	}
+	defer s.requireAccessLevel("admin")()
+	log.Println("Registering support bundle tool: generate_support_bundle")
+	bundleTool := supportbundle.RegisterGenerateSupportBundleTool()
+	s.addTool(bundleTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return supportbundle.GetGenerateSupportBundleHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerMonitoringComponent registers Azure monitoring tools
 func (s *Service) registerMonitoringComponent() {
 	log.Println("Registering monitoring tool: az_monitoring")
 	monitoringTool := monitor.RegisterAzMonitoring()
-	s.mcpServer.AddTool(monitoringTool, tools.CreateResourceHandler(monitor.GetAzMonitoringHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(monitoringTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return monitor.GetAzMonitoringHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerFleetComponent registers Azure fleet management tools
 func (s *Service) registerFleetComponent() {
 	log.Println("Registering fleet tool: az_fleet")
 	fleetTool := fleet.RegisterFleet()
-	s.mcpServer.AddTool(fleetTool, tools.CreateToolHandler(azcli.NewFleetExecutor(), s.cfg))
+	s.addTool(fleetTool, tools.CreateToolHandler(azcli.NewFleetExecutor(), s.cfg))
+
+	log.Println("Registering fleet tool: az_fleet_upgrade")
+	upgradeTool := fleet.RegisterFleetUpgradeTool()
+	s.addTool(upgradeTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return fleet.GetFleetUpgradeHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerAdvisorComponent registers Azure advisor tools
 func (s *Service) registerAdvisorComponent() {
 	log.Println("Registering advisor tool: az_advisor_recommendation")
 	advisorTool := advisor.RegisterAdvisorRecommendationTool()
-	s.mcpServer.AddTool(advisorTool, tools.CreateResourceHandler(advisor.GetAdvisorRecommendationHandler(s.cfg), s.cfg))
+	s.addTool(advisorTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return advisor.GetAdvisorRecommendationHandler(cfg)
+	}), s.cfg))
+}
+
+// registerIdentityComponent registers cluster identity permission tools
+func (s *Service) registerIdentityComponent() {
+	log.Println("Registering identity tool: check_identity_permissions")
+	identityTool := identity.RegisterCheckIdentityPermissionsTool()
+	s.addTool(identityTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return identity.GetCheckIdentityPermissionsHandler(c, cfg)
+	}), s.cfg))
+
+	// Workload identity setup makes changes, so it is only available with readwrite or admin access
+	if s.cfg.AccessLevel == "readwrite" || s.cfg.AccessLevel == "admin" {
+		log.Println("Registering identity tool: setup_workload_identity")
+		restore := s.requireAccessLevel("readwrite")
+		workloadIdentityTool := identity.RegisterSetupWorkloadIdentityTool()
+		s.addTool(workloadIdentityTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return identity.GetSetupWorkloadIdentityHandler(cfg)
+		}), s.cfg))
+		restore()
+	}
+
+	// Workload identity diagnostics read pods and service accounts with the server kubeconfig
+	if s.cfg.KubernetesAccessEnabled() {
+		log.Println("Registering identity tool: diagnose_workload_identity")
+		s.addTool(identity.RegisterDiagnoseWorkloadIdentityTool(), tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return identity.GetDiagnoseWorkloadIdentityHandler(cfg)
+		}), s.cfg))
+	}
+}
+
+// registerCertificatesComponent registers certificate expiry and TLS health tools
+func (s *Service) registerCertificatesComponent() {
+	log.Println("Registering certificates tool: check_certificate_expiry")
+	certificatesTool := certificates.RegisterCheckCertificateExpiryTool()
+	s.addTool(certificatesTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return certificates.GetCheckCertificateExpiryHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerChaosComponent registers Chaos Studio experiment tools.
+// Experiments inject faults into the cluster, so the tool requires admin access.
+func (s *Service) registerChaosComponent() {
+	if s.cfg.AccessLevel != "admin" {
+		return
+	}
+	defer s.requireAccessLevel("admin")()
+	log.Println("Registering chaos tool: az_chaos_experiments")
+	chaosTool := chaos.RegisterChaosExperimentsTool()
+	s.addTool(chaosTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return chaos.GetChaosExperimentsHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerFailoverComponent registers the regional failover readiness tool
+func (s *Service) registerFailoverComponent() {
+	log.Println("Registering failover tool: check_failover_readiness")
+	failoverTool := failover.RegisterCheckFailoverReadinessTool()
+	s.addTool(failoverTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return failover.GetCheckFailoverReadinessHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerGPUComponent registers the GPU node pool diagnostics tool
+func (s *Service) registerGPUComponent() {
+	log.Println("Registering gpu tool: diagnose_gpu_workloads")
+	gpuTool := gpu.RegisterDiagnoseGPUTool()
+	s.addTool(gpuTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return gpu.GetDiagnoseGPUHandler(cfg)
+	}), s.cfg))
+}
+
+// registerStorageComponent registers the storage artifacts tool
+func (s *Service) registerStorageComponent() {
+	log.Println("Registering storage tool: az_storage_artifacts")
+	storageTool := storage.RegisterStorageArtifactsTool()
+	s.addTool(storageTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return storage.GetStorageArtifactsHandler(c, cfg)
+	}), s.cfg))
+}
+
+// registerVulnerabilitiesComponent registers the running image vulnerability scan tool
+func (s *Service) registerVulnerabilitiesComponent() {
+	log.Println("Registering vulnerabilities tool: scan_image_vulnerabilities")
+	vulnerabilitiesTool := vulnerabilities.RegisterScanImageVulnerabilitiesTool()
+	s.addTool(vulnerabilitiesTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return vulnerabilities.GetScanImageVulnerabilitiesHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerNetworkComponent registers network-related Azure resource tools
@@ -386,7 +1492,49 @@ func (s *Service) registerNetworkComponent() {
 	// Register network resources tool
 	log.Println("Registering network tool: az_network_resources")
 	networkTool := network.RegisterAzNetworkResources()
-	s.mcpServer.AddTool(networkTool, tools.CreateResourceHandler(network.GetAzNetworkResourcesHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(networkTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return network.GetAzNetworkResourcesHandler(c, cfg)
+	}), s.cfg))
+
+	// Register ingress controller health, DNS validation and the orphaned load balancer resource check, which read the cluster with the server kubeconfig
+	if s.cfg.KubernetesAccessEnabled() {
+		log.Println("Registering network tool: aks_ingress_health")
+		ingressTool := network.RegisterIngressHealth()
+		s.addTool(ingressTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return network.GetIngressHealthHandler(c, cfg)
+		}), s.cfg))
+
+		log.Println("Registering network tool: aks_dns_validation")
+		dnsTool := network.RegisterDNSValidation()
+		s.addTool(dnsTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return network.GetDNSValidationHandler(c, cfg)
+		}), s.cfg))
+
+		log.Println("Registering network tool: aks_orphaned_lb_resources")
+		orphansTool := network.RegisterOrphanedLBResources()
+		s.addTool(orphansTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return network.GetOrphanedLBResourcesHandler(c, cfg)
+		}), s.cfg))
+	}
+
+	// The migration advisor and egress firewall analysis run the Azure CLI
+	if s.cfg.NoAzCli {
+		return
+	}
+
+	// Register network plugin migration advisor
+	log.Println("Registering network tool: aks_network_migration_advisor")
+	migrationTool := network.RegisterNetworkMigrationAdvisor()
+	s.addTool(migrationTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return network.GetNetworkMigrationAdvisorHandler(cfg)
+	}), s.cfg))
+
+	// Register egress firewall analysis, which queries the firewall logs with the Azure CLI
+	log.Println("Registering network tool: aks_egress_firewall_analysis")
+	egressTool := network.RegisterEgressFirewallAnalysis()
+	s.addTool(egressTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return network.GetEgressFirewallAnalysisHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerComputeComponent registers compute-related Azure resource tools (VMSS/VM)
@@ -396,12 +1544,45 @@ func (s *Service) registerComputeComponent() {
 	// Register AKS VMSS info tool (supports both single node pool and all node pools)
 	log.Println("Registering compute tool: get_aks_vmss_info")
 	vmssInfoTool := compute.RegisterAKSVMSSInfoTool()
-	s.mcpServer.AddTool(vmssInfoTool, tools.CreateResourceHandler(compute.GetAKSVMSSInfoHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(vmssInfoTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return compute.GetAKSVMSSInfoHandler(c, cfg)
+	}), s.cfg))
+
+	log.Println("Registering compute tool: get_aks_node_serial_log")
+	serialLogTool := compute.RegisterNodeSerialLogTool()
+	s.addTool(serialLogTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return compute.GetNodeSerialLogHandler(c, cfg)
+	}), s.cfg))
+
+	log.Println("Registering compute tool: diagnose_aks_node_bootstrap")
+	bootstrapTool := compute.RegisterNodeBootstrapDiagnosticsTool()
+	s.addTool(bootstrapTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return compute.GetNodeBootstrapDiagnosticsHandler(c, cfg)
+	}), s.cfg))
+
+	// Node script library, read from the configured directory on every call
+	if s.cfg.NodeScriptsDir != "" {
+		log.Println("Registering compute tool: list_node_scripts")
+		s.addTool(compute.RegisterListNodeScriptsTool(), tools.CreateResourceHandler(s.sessionAwareHandler(func(_ *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return compute.GetListNodeScriptsHandler(cfg)
+		}), s.cfg))
+
+		log.Println("Registering compute tool: run_node_script")
+		runScriptTool := compute.RegisterRunNodeScriptTool()
+		s.addTool(runScriptTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+			return compute.GetRunNodeScriptHandler(c, s.auditLog, cfg)
+		}), s.cfg))
+	}
+
+	// The compute operations tool runs the Azure CLI
+	if s.cfg.NoAzCli {
+		return
+	}
 
 	// Register unified compute operations tool
 	log.Println("Registering compute tool: az_compute_operations")
 	computeOperationsTool := compute.RegisterAzComputeOperations(s.cfg)
-	s.mcpServer.AddTool(computeOperationsTool, tools.CreateToolHandler(compute.NewComputeOperationsExecutor(), s.cfg))
+	s.addTool(computeOperationsTool, tools.CreateToolHandler(compute.NewComputeOperationsExecutor(s.auditLog), s.cfg))
 }
 
 // registerDetectorComponent registers detector-related Azure resource tools
@@ -411,17 +1592,30 @@ func (s *Service) registerDetectorComponent() {
 	// Register list detectors tool
 	log.Println("Registering detector tool: list_detectors")
 	listTool := detectors.RegisterListDetectorsTool()
-	s.mcpServer.AddTool(listTool, tools.CreateResourceHandler(detectors.GetListDetectorsHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(listTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return detectors.GetListDetectorsHandler(c, cfg)
+	}), s.cfg))
 
 	// Register run detector tool
 	log.Println("Registering detector tool: run_detector")
 	runTool := detectors.RegisterRunDetectorTool()
-	s.mcpServer.AddTool(runTool, tools.CreateResourceHandler(detectors.GetRunDetectorHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(runTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return detectors.GetRunDetectorHandler(c, cfg)
+	}), s.cfg))
 
 	// Register run detectors by category tool
 	log.Println("Registering detector tool: run_detectors_by_category")
 	categoryTool := detectors.RegisterRunDetectorsByCategoryTool()
-	s.mcpServer.AddTool(categoryTool, tools.CreateResourceHandler(detectors.GetRunDetectorsByCategoryHandler(s.azClient, s.cfg), s.cfg))
+	s.addTool(categoryTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return detectors.GetRunDetectorsByCategoryHandler(c, cfg)
+	}), s.cfg))
+
+	// Register multi-cluster detectors tool
+	log.Println("Registering detector tool: run_detectors_multi_cluster")
+	multiClusterTool := detectors.RegisterRunDetectorsMultiClusterTool()
+	s.addTool(multiClusterTool, tools.CreateResourceHandler(s.sessionAwareHandler(func(c *azureclient.AzureClient, cfg *config.ConfigData) tools.ResourceHandler {
+		return detectors.GetRunDetectorsMultiClusterHandler(c, cfg)
+	}), s.cfg))
 }
 
 // registerHelmComponent registers helm tools if enabled
@@ -430,7 +1624,7 @@ func (s *Service) registerHelmComponent() {
 		log.Println("Registering Kubernetes tool: helm")
 		helmTool := helm.RegisterHelm()
 		helmExecutor := k8s.WrapK8sExecutor(helm.NewExecutor())
-		s.mcpServer.AddTool(helmTool, tools.CreateToolHandler(helmExecutor, s.cfg))
+		s.addTool(helmTool, tools.CreateToolHandler(helmExecutor, s.cfg))
 	}
 }
 
@@ -440,6 +1634,10 @@ func (s *Service) registerCiliumComponent() {
 		log.Println("Registering Kubernetes tool: cilium")
 		ciliumTool := cilium.RegisterCilium()
 		ciliumExecutor := k8s.WrapK8sExecutor(cilium.NewExecutor())
-		s.mcpServer.AddTool(ciliumTool, tools.CreateToolHandler(ciliumExecutor, s.cfg))
+		s.addTool(ciliumTool, tools.CreateToolHandler(ciliumExecutor, s.cfg))
+
+		log.Println("Registering Kubernetes tool: cilium_dropped_flows")
+		droppedFlowsTool := hubble.RegisterDroppedFlowsTool()
+		s.addTool(droppedFlowsTool, tools.CreateResourceHandler(hubble.GetDroppedFlowsHandler(), s.cfg))
 	}
 }
@@ -1,17 +1,36 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Azure/aks-mcp/internal/apikey"
 	"github.com/Azure/aks-mcp/internal/azcli"
+	"github.com/Azure/aks-mcp/internal/azureclient"
+	"github.com/Azure/aks-mcp/internal/command"
 	"github.com/Azure/aks-mcp/internal/components/azaks"
+	"github.com/Azure/aks-mcp/internal/components/common"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/replay"
+	"github.com/Azure/aks-mcp/internal/session"
+	"github.com/Azure/aks-mcp/internal/store"
+	"github.com/Azure/aks-mcp/internal/telemetry"
+	"github.com/Azure/aks-mcp/internal/tools"
+	"github.com/Azure/aks-mcp/internal/version"
 	"github.com/Azure/mcp-kubernetes/pkg/kubectl"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -144,7 +163,7 @@ func TestService(t *testing.T) {
 				optionalToolsCount++
 			}
 			if tt.additionalTools["cilium"] {
-				optionalToolsCount++
+				optionalToolsCount += 2 // cilium, cilium_dropped_flows
 			}
 
 			expectedTotalK8sTools := expectedKubectlCount + optionalToolsCount
@@ -205,10 +224,10 @@ func TestComponentToolCounts(t *testing.T) {
 		}{
 			{"AKS Operations", 1, "az_aks_operations tool"},
 			{"Monitoring", 1, "az_monitoring tool"},
-			{"Fleet", 1, "az_fleet tool"},
+			{"Fleet", 2, "az_fleet, az_fleet_upgrade"},
 			{"Network", 1, "az_network_resources tool"},
 			{"Advisor", 1, "az_advisor_recommendation tool"},
-			{"Detectors", 3, "list_detectors, run_detector, run_detectors_by_category"},
+			{"Detectors", 4, "list_detectors, run_detector, run_detectors_by_category, run_detectors_multi_cluster"},
 			{"Inspektor Gadget", 1, "inspektor_gadget_observability tool"},
 		}
 
@@ -250,6 +269,7 @@ func TestComponentToolCounts(t *testing.T) {
 		t.Logf("  1. list_detectors - Lists all available AKS cluster detectors")
 		t.Logf("  2. run_detector - Runs a specific AKS detector")
 		t.Logf("  3. run_detectors_by_category - Runs all detectors in a specific category")
+		t.Logf("  4. run_detectors_multi_cluster - Runs a detector category against several clusters or a fleet")
 	})
 }
 
@@ -334,7 +354,7 @@ func TestExpectedToolsByAccessLevel(t *testing.T) {
 			t.Logf("Azure Tools:")
 			t.Logf("  - AKS Operations: 1")
 			t.Logf("  - Monitoring: 1")
-			t.Logf("  - Fleet: 1")
+			t.Logf("  - Fleet: 2 (az_fleet, az_fleet_upgrade)")
 			t.Logf("  - Network: 1")
 			t.Logf("  - Compute: 2 (get_aks_vmss_info, az_compute_operations)")
 			t.Logf("  - Detectors: 3")
@@ -354,6 +374,269 @@ func TestExpectedToolsByAccessLevel(t *testing.T) {
 	}
 }
 
+// TestComponentSelection verifies that only enabled components register tools
+func TestComponentSelection(t *testing.T) {
+	cfg := createTestConfig("readonly", map[string]bool{})
+	cfg.EnabledComponents = map[string]bool{config.ComponentMonitor: true, config.ComponentDetectors: true}
+
+	service := NewService(cfg)
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	service.registerAllComponents()
+
+	resp := service.mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal tools/list response: %v", err)
+	}
+	var result struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse tools/list response: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, tool := range result.Result.Tools {
+		names[tool.Name] = true
+	}
+	for _, want := range []string{"az_monitoring", "list_detectors", "run_detector", "run_detectors_by_category", "run_detectors_multi_cluster"} {
+		if !names[want] {
+			t.Errorf("Expected tool %s to be registered", want)
+		}
+	}
+	for _, unwanted := range []string{"az_aks_operations", "az_fleet", "az_network_resources", "kubectl_resources"} {
+		if names[unwanted] {
+			t.Errorf("Expected tool %s not to be registered", unwanted)
+		}
+	}
+
+	if got := componentInstructions(cfg); !strings.Contains(got, "monitor, detectors") {
+		t.Errorf("Expected instructions to list enabled components, got %q", got)
+	}
+}
+
+// TestSessionModeSkipsKubeconfigTools verifies that tools using the server kubeconfig are not registered
+// when each session supplies its own credentials
+func TestSessionModeSkipsKubeconfigTools(t *testing.T) {
+	cfg := createTestConfig("admin", map[string]bool{"helm": true, "cilium": true})
+	cfg.SessionCredentials = true
+
+	service := NewService(cfg)
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	service.registerAllComponents()
+
+	resp := service.mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal tools/list response: %v", err)
+	}
+	for _, unwanted := range []string{"kubectl_resources", "aks_node_drain", "aks_resource_usage", "aks_noisy_neighbors", "aks_pod_exec", "aks_port_forward", "aks_watch_events", "aks_wait_for_condition", "helm", "cilium", "cilium_dropped_flows", "inspektor_gadget_observability", "check_certificate_expiry", "scan_image_vulnerabilities", "diagnose_workload_identity", "aks_job_failures", "aks_recent_changes", "aks_cost_breakdown", "diagnose_gpu_workloads", "az_storage_artifacts", "aks_upgrade_progress", "aks_upgrade_tuning", "aks_dns_validation", "aks_orphaned_lb_resources"} {
+		if strings.Contains(string(data), `"name":"`+unwanted+`"`) {
+			t.Errorf("Expected tool %s not to be registered in session credential mode", unwanted)
+		}
+	}
+	if !strings.Contains(string(data), `"name":"az_monitoring"`) {
+		t.Error("Expected Azure tools to stay registered in session credential mode")
+	}
+}
+
+// TestNoAzCliModeSkipsAzTools verifies that only SDK-backed Azure tools are registered without the Azure CLI
+func TestNoAzCliModeSkipsAzTools(t *testing.T) {
+	cfg := createTestConfig("readonly", map[string]bool{})
+	cfg.NoAzCli = true
+
+	service := NewService(cfg)
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	service.registerAllComponents()
+
+	resp := service.mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal tools/list response: %v", err)
+	}
+	for _, unwanted := range []string{"az_monitoring", "az_fleet", "az_advisor_recommendation", "check_identity_permissions", "az_compute_operations", "aks_network_migration_advisor", "aks_egress_firewall_analysis"} {
+		if strings.Contains(string(data), `"name":"`+unwanted+`"`) {
+			t.Errorf("Expected tool %s not to be registered without the Azure CLI", unwanted)
+		}
+	}
+	for _, want := range []string{"az_aks_operations", "aks_estate_overview", "aks_deprecated_features", "az_aks_tags", "az_network_resources", "get_aks_vmss_info", "list_detectors"} {
+		if !strings.Contains(string(data), `"name":"`+want+`"`) {
+			t.Errorf("Expected tool %s to be registered without the Azure CLI", want)
+		}
+	}
+	if !strings.Contains(string(data), "through the Azure SDK") {
+		t.Error("Expected az_aks_operations to describe the SDK-backed operations")
+	}
+}
+
+// TestRequireSessionCredential verifies that session credential mode rejects requests without a valid token
+func TestRequireSessionCredential(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	cfg := createTestConfig("readonly", map[string]bool{})
+	service := NewService(cfg)
+	rec := httptest.NewRecorder()
+	service.requireSessionCredential(next).ServeHTTP(rec, httptest.NewRequest("POST", "/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected requests to pass through without session credential mode, got %d", rec.Code)
+	}
+
+	cfg = createTestConfig("readonly", map[string]bool{})
+	cfg.SessionCredentials = true
+	service = NewService(cfg)
+	service.tokenVerifier = session.NewVerifier("https://management.azure.com", "https://management.core.windows.net/")
+	handler := service.requireSessionCredential(next)
+
+	for name, auth := range map[string]string{"missing": "", "opaque": "Bearer x"} {
+		req := httptest.NewRequest("POST", "/mcp", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	// A client ID that is not a GUID is refused before the token is verified
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	req.Header.Set(session.HeaderClientID, "x --password secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), session.HeaderClientID) {
+		t.Errorf("Expected an invalid client ID to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestSessionAwareHandlerContext tests that handlers needing the call context get it
+func TestSessionAwareHandlerContext(t *testing.T) {
+	service := NewService(createTestConfig("readonly", map[string]bool{}))
+	type ctxKey struct{}
+	handler := service.sessionAwareHandler(func(_ *azureclient.AzureClient, _ *config.ConfigData) tools.ResourceHandler {
+		return tools.ContextResourceHandlerFunc(func(ctx context.Context, _ map[string]interface{}, _ *config.ConfigData) (string, error) {
+			value, _ := ctx.Value(ctxKey{}).(string)
+			return value, nil
+		})
+	})
+	contextHandler, ok := handler.(tools.ContextResourceHandler)
+	if !ok {
+		t.Fatal("Expected the session aware handler to take the call context")
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "call")
+	if result, err := contextHandler.HandleContext(ctx, nil, service.cfg); err != nil || result != "call" {
+		t.Errorf("Expected the handler to see the call context, got %q (%v)", result, err)
+	}
+}
+
+// TestAPIKeys tests that API keys are required on the HTTP transports and limit the tools a client lists and
+// calls to the key's components and access level, auditing rejected calls under the key's name
+func TestAPIKeys(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys := fmt.Sprintf(`{"keys": [
+		{"name": "monitoring-bot", "sha256": "%s", "accessLevel": "readonly", "components": ["monitor"]},
+		{"name": "sre-assistant", "sha256": "%s", "accessLevel": "readwrite"}]}`, apikey.Hash("bot-key"), apikey.Hash("sre-key"))
+	if err := os.WriteFile(keysFile, []byte(keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := createTestConfig("admin", map[string]bool{})
+	cfg.Transport = "streamable-http"
+	cfg.StateStore = store.KindMemory
+	cfg.APIKeysFile = keysFile
+	service := NewService(cfg, WithAzCliProcFactory(func(int) azcli.Proc { return &fakeProc{} }))
+	if err := service.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	httpServer, err := service.HTTPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(httpServer.Handler)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without an API key, got %d", resp.StatusCode)
+	}
+
+	ctx := context.Background()
+	connect := func(key string) (*client.Client, map[string]bool) {
+		c, err := client.NewStreamableHttpClient(ts.URL+"/mcp", transport.WithHTTPHeaders(map[string]string{apikey.HeaderAPIKey: key}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+		request := mcp.InitializeRequest{}
+		request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		if _, err := c.Initialize(ctx, request); err != nil {
+			t.Fatalf("Initialize with %s failed: %v", key, err)
+		}
+		listed, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := map[string]bool{}
+		for _, tool := range listed.Tools {
+			names[tool.Name] = true
+		}
+		return c, names
+	}
+	call := func(c *client.Client, tool string, args map[string]interface{}) string {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		request.Params.Arguments = args
+		result, err := c.CallTool(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError {
+			t.Fatalf("Expected %s to be rejected", tool)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	bot, botTools := connect("bot-key")
+	if !botTools["az_monitoring"] || !botTools["verify_audit_log"] || botTools["az_aks_operations"] || botTools["kubectl_resources"] {
+		t.Errorf("Expected only monitoring tools and tools outside any component, got %v", botTools)
+	}
+	if text := call(bot, "az_aks_operations", map[string]interface{}{"operation": "show", "args": "--name a --resource-group b"}); !strings.Contains(text, "azaks component") {
+		t.Errorf("Unexpected rejection %q", text)
+	}
+
+	sre, sreTools := connect("sre-key")
+	if !sreTools["az_aks_operations"] || !sreTools["k8s_apply"] || sreTools["aks_pod_exec"] || sreTools["az_chaos_experiments"] {
+		t.Errorf("Expected the readwrite tools of every component, got %v", sreTools)
+	}
+	if text := call(sre, "aks_pod_exec", map[string]interface{}{"namespace": "default", "pod": "web", "command": "ls"}); !strings.Contains(text, "requires admin access") {
+		t.Errorf("Unexpected rejection %q", text)
+	}
+	// Operations of a permitted tool are checked against the key's access level
+	if text := call(sre, "az_aks_operations", map[string]interface{}{"operation": "get-credentials", "args": "--name a --resource-group b"}); !strings.Contains(text, "admin") {
+		t.Errorf("Expected get-credentials to need admin access, got %q", text)
+	}
+
+	records, err := service.auditLog.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]string{}
+	for _, record := range records {
+		clients[record.Client] = record.Tool
+	}
+	if clients["monitoring-bot"] != "az_aks_operations" || clients["sre-assistant"] != "aks_pod_exec" {
+		t.Errorf("Expected rejected calls audited per key, got %+v", records)
+	}
+}
+
 // createTestConfig creates a test configuration
 func createTestConfig(accessLevel string, additionalTools map[string]bool) *config.ConfigData {
 	cfg := config.NewConfig()
@@ -396,6 +679,119 @@ func BenchmarkServiceInitialization(b *testing.B) {
 }
 
 // TestCreateCustomHTTPServerWithHelp404 tests the custom HTTP server creation for streamable-http transport
+func TestWithInferredClusterParameters(t *testing.T) {
+	tool := mcp.NewTool("aks_test",
+		mcp.WithString("subscription_id", mcp.Required(), mcp.Description("Azure Subscription ID")),
+		mcp.WithString("resource_group", mcp.Required(), mcp.Description("Azure Resource Group")),
+		mcp.WithString("cluster_name", mcp.Required(), mcp.Description("AKS cluster name")),
+	)
+	if !takesClusterParameters(tool) || takesClusterParameters(mcp.NewTool("other", mcp.WithString("cluster_name"))) {
+		t.Fatal("Expected only tools with all three cluster parameters to be resolved")
+	}
+
+	inferred := withInferredClusterParameters(tool)
+	if len(inferred.InputSchema.Required) != 1 || inferred.InputSchema.Required[0] != "cluster_name" {
+		t.Errorf("Expected only cluster_name to stay required, got %v", inferred.InputSchema.Required)
+	}
+	description := inferred.InputSchema.Properties["resource_group"].(map[string]interface{})["description"]
+	if description != "Azure Resource Group (inferred from cluster_name when omitted)" {
+		t.Errorf("Unexpected description %q", description)
+	}
+	if tool.InputSchema.Properties["resource_group"].(map[string]interface{})["description"] != "Azure Resource Group" {
+		t.Error("Expected the original tool schema to be left unchanged")
+	}
+	if inferred.InputSchema.Properties[common.ClusterSelectionParam] == nil || tool.InputSchema.Properties[common.ClusterSelectionParam] != nil {
+		t.Error("Expected cluster_selection to be added to the inferred schema only")
+	}
+}
+
+// TestClusterSelection tests the disambiguation response and the per-session memory of chosen clusters
+func TestClusterSelection(t *testing.T) {
+	ambiguous := &common.AmbiguousClusterError{Name: "aks-prod", Candidates: []common.ClusterCandidate{
+		{Index: 1, Name: "aks-prod", SubscriptionID: "sub-1", ResourceGroup: "rg-prod", Location: "eastus"},
+		{Index: 2, Name: "aks-prod", SubscriptionID: "sub-2", ResourceGroup: "rg-dr", Location: "westus"},
+	}}
+	result := clusterSelectionResult(ambiguous)
+	var response clusterSelectionResponse
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Failed to parse the selection response: %v", err)
+	}
+	if !result.IsError || response.Status != "cluster_selection_required" || response.Matches != 2 || response.Candidates[1].Location != "westus" {
+		t.Errorf("Unexpected selection response %+v", response)
+	}
+
+	service := NewService(createTestConfig("readonly", map[string]bool{}))
+	service.clusterChoices.remember("session-1", "AKS-Prod", clusterChoice{subscriptionID: "sub-2", resourceGroup: "rg-dr", name: "aks-prod"})
+
+	params := map[string]interface{}{"cluster_name": "aks-prod"}
+	if !service.applyClusterChoice("session-1", params) || params["subscription_id"] != "sub-2" || params["resource_group"] != "rg-dr" {
+		t.Errorf("Expected the remembered choice to be applied, got %v", params)
+	}
+	if service.applyClusterChoice("session-2", map[string]interface{}{"cluster_name": "aks-prod"}) {
+		t.Error("Expected choices to be remembered per session")
+	}
+	if service.applyClusterChoice("session-1", map[string]interface{}{"cluster_name": "aks-prod", "subscription_id": "sub-1"}) {
+		t.Error("Expected a conflicting subscription_id to rule out the remembered choice")
+	}
+	service.clusterChoices.release("session-1")
+	if service.applyClusterChoice("session-1", map[string]interface{}{"cluster_name": "aks-prod"}) {
+		t.Error("Expected the choices of a closed session to be forgotten")
+	}
+}
+
+// TestToolSchemaEndpoint verifies that /schema publishes the registered tools with their final input schemas
+func TestToolSchemaEndpoint(t *testing.T) {
+	cfg := createTestConfig("readwrite", map[string]bool{})
+	cfg.NoAzCli = true
+
+	service := NewService(cfg)
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	service.registerAllComponents()
+
+	rec := httptest.NewRecorder()
+	service.createCustomHTTPServerWithHelp404(":0").Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/schema", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON schema document, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc SchemaDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse schema document: %v", err)
+	}
+	if doc.AccessLevel != "readwrite" || len(doc.Tools) != len(service.registeredTools) {
+		t.Fatalf("Unexpected schema document with %d tools for %d registered", len(doc.Tools), len(service.registeredTools))
+	}
+
+	tools := map[string]ToolSchema{}
+	for _, tool := range doc.Tools {
+		tools[tool.Name] = tool
+	}
+	tagsSchema, ok := tools["az_aks_tags"]
+	if !ok {
+		t.Fatal("Expected az_aks_tags in the schema document")
+	}
+	var input struct {
+		Properties map[string]interface{} `json:"properties"`
+		Required   []string               `json:"required"`
+	}
+	if err := json.Unmarshal(tagsSchema.InputSchema, &input); err != nil {
+		t.Fatalf("Failed to parse input schema: %v", err)
+	}
+	if input.Properties["explain"] == nil || input.Properties["tags"] == nil || strings.Contains(strings.Join(input.Required, ","), "resource_group") {
+		t.Errorf("Expected the input schema as registered, with shared arguments and inferred cluster parameters, got %+v", input)
+	}
+	if !strings.Contains(string(tagsSchema.ResultSchema), `"outOfSync"`) {
+		t.Errorf("Expected the tag report result schema, got %s", tagsSchema.ResultSchema)
+	}
+	if tools["az_aks_operations"].ResultSchema != nil {
+		t.Error("Expected no result schema for a tool whose result depends on the operation")
+	}
+	for name, schema := range resultSchemas {
+		if !strings.Contains(string(schema), `"properties"`) {
+			t.Errorf("Expected an object result schema for %s, got %s", name, schema)
+		}
+	}
+}
+
 func TestCreateCustomHTTPServerWithHelp404(t *testing.T) {
 	cfg := createTestConfig("readonly", map[string]bool{})
 	service := NewService(cfg)
@@ -757,3 +1153,214 @@ func TestJSONResponseFormat(t *testing.T) {
 		})
 	}
 }
+
+// TestReplay tests that recorded calls are replayed through the registered tool handlers
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := replay.NewRecorder(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := config.NewConfig()
+	service := NewService(cfg)
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	service.recorder = recorder
+	calls := 0
+	service.addTool(mcp.NewTool("count_calls", mcp.WithString("name")), func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText(fmt.Sprintf("%s call %d", req.GetString("name", ""), calls)), nil
+	})
+
+	// Record a call, then replay it: the handler now returns a different count
+	if _, err := service.callTool(context.Background(), "count_calls", map[string]interface{}{"name": "first"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.Replay = path
+	var out strings.Builder
+	summary, err := service.Replay(context.Background(), &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Calls != 1 || summary.Changed != 1 || !strings.Contains(out.String(), "- first call 1\n  + first call 2") {
+		t.Errorf("Unexpected replay %+v:\n%s", summary, out.String())
+	}
+
+	if _, err := service.callTool(context.Background(), "missing_tool", nil); err == nil {
+		t.Error("Expected calling an unknown tool to fail")
+	}
+}
+
+// TestSelfTest tests that the tools registered at every access level pass the self-test
+func TestSelfTest(t *testing.T) {
+	var out strings.Builder
+	summary, err := SelfTest(context.Background(), config.NewConfig(), &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Checked == 0 || summary.Failed != 0 || summary.Passed != summary.Checked {
+		t.Errorf("Unexpected self-test %+v:\n%s", summary, out.String())
+	}
+	for _, expected := range []string{"ok   readonly  az_aks_operations\n", "ok   admin     aks_node_drain\n"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the report to contain %q", expected)
+		}
+	}
+}
+
+// TestSelfTestChecks tests that the self-test reports inconsistent tool definitions and input validation
+func TestSelfTestChecks(t *testing.T) {
+	tool := mcp.NewTool("bad tool",
+		mcp.WithString("mode", mcp.Enum("fast", "slow")),
+	)
+	tool.InputSchema.Required = []string{"name"}
+	problems := checkToolDefinition(tool)
+	expected := []string{
+		`name "bad tool" is not 1 to 64 letters, digits, underscores or hyphens`,
+		"has no description",
+		"parameter mode has no description",
+		"required parameter name is not declared",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems %q, got %q", expected, problems)
+	}
+
+	service := NewService(config.NewConfig())
+	service.mcpServer = server.NewMCPServer("AKS MCP", "test")
+	executor := &selfTestExecutor{}
+	command.SetInterceptor(executor)
+	defer command.SetInterceptor(nil)
+	// The tool runs a command before it checks its arguments, and never checks the enum
+	service.registerTool(mcp.NewTool("lax_tool", mcp.WithString("name", mcp.Required()), mcp.WithString("mode", mcp.Enum("fast", "slow"))),
+		func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if _, err := command.NewShellProcess("az", 10).Run("version"); err != nil {
+				return nil, err
+			}
+			if req.GetString("name", "") == "" {
+				return mcp.NewToolResultError("missing name"), nil
+			}
+			return mcp.NewToolResultText("done"), nil
+		})
+	problems = service.checkToolValidation(context.Background(), service.registeredTools[0], executor)
+	expected = []string{
+		"without required parameters: rejected after running 1 commands",
+		"with mode outside its enum: accepted",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems %q, got %q", expected, problems)
+	}
+}
+
+// TestAddUpdateNotice tests that an available update is added to the instructions of initializing clients
+func TestAddUpdateNotice(t *testing.T) {
+	s := NewService(config.NewConfig())
+	result := &mcp.InitializeResult{Instructions: "AKS MCP server."}
+	s.addUpdateNotice(context.Background(), 1, nil, result)
+	if result.Instructions != "AKS MCP server." {
+		t.Errorf("Expected the instructions unchanged without an update, got %q", result.Instructions)
+	}
+
+	s.updateStatus.Store(&version.UpdateStatus{Current: "v0.0.9", Latest: "v0.0.10", URL: "https://github.com/Azure/aks-mcp/releases/tag/v0.0.10"})
+	s.addUpdateNotice(context.Background(), 1, nil, result)
+	if !strings.Contains(result.Instructions, "aks-mcp v0.0.10 is available (running v0.0.9): https://github.com/Azure/aks-mcp/releases/tag/v0.0.10") {
+		t.Errorf("Expected the update notice, got %q", result.Instructions)
+	}
+}
+
+// TestUpdateCheckDisabled tests that turning off telemetry, or building without it, also skips the update check
+func TestUpdateCheckDisabled(t *testing.T) {
+	cfg := config.NewConfig()
+	if updateCheckDisabled(cfg) != !telemetry.ApplicationInsightsCompiledIn {
+		t.Errorf("Expected the update check to run only in builds with telemetry")
+	}
+	cfg.DisableTelemetry = true
+	if !updateCheckDisabled(cfg) {
+		t.Error("Expected --disable-telemetry to skip the update check")
+	}
+	cfg = config.NewConfig()
+	cfg.DisableUpdateCheck = true
+	if !updateCheckDisabled(cfg) {
+		t.Error("Expected --disable-update-check to skip the update check")
+	}
+
+	// checkForUpdate returns without starting a check, so nothing is ever stored
+	s := NewService(config.NewConfig())
+	s.cfg.DisableTelemetry = true
+	s.checkForUpdate()
+	if s.updateStatus.Load() != nil {
+		t.Error("Expected no update status with telemetry disabled")
+	}
+}
+
+// TestFormatMetrics tests the Prometheus text format of the subprocess counts
+func TestFormatMetrics(t *testing.T) {
+	metrics := formatMetrics(command.ProcessStats{Running: map[string]int{"kubectl": 1, "az": 2}, Started: 7, TimedOut: 1, Killed: 1})
+	for _, want := range []string{
+		"# TYPE aks_mcp_subprocesses_running gauge\naks_mcp_subprocesses_running{command=\"az\"} 2\naks_mcp_subprocesses_running{command=\"kubectl\"} 1\n",
+		"aks_mcp_subprocesses_started_total 7\n",
+		"aks_mcp_subprocesses_timed_out_total 1\n",
+		"# TYPE aks_mcp_subprocess_trees_killed_total counter\naks_mcp_subprocess_trees_killed_total 1\n",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, metrics)
+		}
+	}
+}
+
+func TestResourceSubscriptions(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	subscriptions := newResourceSubscriptions(20*time.Millisecond, func(sessionID, uri string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if sessionID == "gone" {
+			return server.ErrSessionNotFound
+		}
+		sent = append(sent, sessionID+" "+uri)
+		return nil
+	})
+	subscriptions.subscribe("a", inventoryClustersURI)
+	subscriptions.subscribe("gone", inventoryClustersURI)
+	raw := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"` + inventoryFindingsURI + `"}}`)
+	ctx := server.NewMCPServer("test", "1.0").WithContext(context.Background(), &testClientSession{id: "b"})
+	if err := subscriptions.observeRequest(ctx, 1, raw); err != nil {
+		t.Fatalf("observeRequest failed: %v", err)
+	}
+
+	// A burst of changes sends one notification per subscriber and resource
+	for i := 0; i < 5; i++ {
+		subscriptions.changed(inventoryClustersURI)
+		subscriptions.changed(inventoryFindingsURI)
+	}
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	sort.Strings(sent)
+	got := strings.Join(sent, ",")
+	sent = nil
+	mu.Unlock()
+	if got != "a "+inventoryClustersURI+",b "+inventoryFindingsURI {
+		t.Errorf("Unexpected notifications %s", got)
+	}
+
+	// Sessions that are gone or released are no longer notified
+	subscriptions.release("a")
+	subscriptions.changed(inventoryClustersURI)
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 0 || len(subscriptions.subscribers[inventoryClustersURI]) != 0 {
+		t.Errorf("Expected no subscribers left, got %v %v", sent, subscriptions.subscribers)
+	}
+}
+
+// testClientSession is a client session that is identified by its ID only
+type testClientSession struct {
+	id string
+}
+
+func (c *testClientSession) Initialize()                                         {}
+func (c *testClientSession) Initialized() bool                                   { return true }
+func (c *testClientSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (c *testClientSession) SessionID() string                                   { return c.id }
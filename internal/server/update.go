@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/store"
+	"github.com/Azure/aks-mcp/internal/telemetry"
+	"github.com/Azure/aks-mcp/internal/version"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// updateBucket holds the result of the last update check, so restarts don't ask GitHub again
+const updateBucket = "update-check"
+
+// updateStatusKey is the key of the last update check in updateBucket
+const updateStatusKey = "latest"
+
+// storeUpdateCache keeps the last update check in the state store
+type storeUpdateCache struct {
+	repo *store.Repository[version.UpdateStatus]
+}
+
+func (c storeUpdateCache) Load() (version.UpdateStatus, error) {
+	return c.repo.Load(updateStatusKey)
+}
+
+func (c storeUpdateCache) Save(status version.UpdateStatus) error {
+	return c.repo.Save(updateStatusKey, status)
+}
+
+// updateCheckDisabled reports whether the server must not ask GitHub for the latest release: with
+// --disable-update-check, and also with --disable-telemetry or in notelemetry builds, which promise no phone-home
+func updateCheckDisabled(cfg *config.ConfigData) bool {
+	return cfg.DisableUpdateCheck || cfg.DisableTelemetry || !telemetry.ApplicationInsightsCompiledIn
+}
+
+// checkForUpdate asks GitHub for the latest release in the background, unless disabled, and logs when a newer
+// one is available. Clients that initialize afterwards are told in the server instructions.
+func (s *Service) checkForUpdate() {
+	if updateCheckDisabled(s.cfg) {
+		return
+	}
+	var cache version.UpdateCache
+	if s.store != nil {
+		cache = storeUpdateCache{repo: store.NewRepository[version.UpdateStatus](s.store, updateBucket)}
+	}
+	checker := version.NewUpdateChecker(cache)
+	go func() {
+		status, err := checker.Check(context.Background())
+		if err != nil {
+			if s.cfg.Verbose {
+				log.Printf("Update check failed (disable it with --disable-update-check): %v", err)
+			}
+			return
+		}
+		if status == nil || !status.Available() {
+			return
+		}
+		s.updateStatus.Store(status)
+		log.Println(updateNotice(*status))
+	}()
+}
+
+// updateNotice describes an available update
+func updateNotice(status version.UpdateStatus) string {
+	notice := fmt.Sprintf("aks-mcp %s is available (running %s)", status.Latest, status.Current)
+	if status.URL != "" {
+		notice += ": " + status.URL
+	}
+	return notice
+}
+
+// addUpdateNotice appends an available update to the instructions returned to an initializing client
+func (s *Service) addUpdateNotice(_ context.Context, _ any, _ *mcp.InitializeRequest, result *mcp.InitializeResult) {
+	status := s.updateStatus.Load()
+	if status == nil || result == nil {
+		return
+	}
+	result.Instructions += " Update available: " + updateNotice(*status) + "."
+}
@@ -0,0 +1,149 @@
+// Package session provides per-session Azure credentials for multi-tenant HTTP transports.
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// HTTP headers used by clients to supply session credentials
+const (
+	// HeaderAuthorization carries the ARM access token as "Bearer <token>"
+	HeaderAuthorization = "Authorization"
+	// HeaderTenantID, HeaderClientID and HeaderFederatedToken let az CLI log in on behalf of the session
+	HeaderTenantID       = "X-Azure-Tenant-Id"
+	HeaderClientID       = "X-Azure-Client-Id"
+	HeaderFederatedToken = "X-Azure-Federated-Token" // #nosec G101 -- header name, not a credential
+)
+
+// guidPattern matches the tenant and client IDs a session may supply
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// federatedTokenPattern matches the base64url segments and dots of a JWT
+var federatedTokenPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// defaultTokenLifetime is used when the access token expiry cannot be decoded
+const defaultTokenLifetime = time.Hour
+
+type contextKey struct{}
+
+// Credential holds the Azure credentials supplied by a single MCP session.
+// It implements azcore.TokenCredential so it can be used directly by SDK clients.
+type Credential struct {
+	SessionID      string
+	AccessToken    string
+	ExpiresOn      time.Time
+	TenantID       string
+	ClientID       string
+	FederatedToken string
+}
+
+var _ azcore.TokenCredential = (*Credential)(nil)
+
+// FromRequest extracts session credentials from HTTP request headers.
+// Returns nil when the request carries no access token.
+func FromRequest(r *http.Request) *Credential {
+	auth := strings.TrimSpace(r.Header.Get(HeaderAuthorization))
+	token, found := strings.CutPrefix(auth, "Bearer ")
+	token = strings.TrimSpace(token)
+	if !found || token == "" {
+		return nil
+	}
+
+	return &Credential{
+		AccessToken:    token,
+		ExpiresOn:      tokenExpiry(token),
+		TenantID:       strings.TrimSpace(r.Header.Get(HeaderTenantID)),
+		ClientID:       strings.TrimSpace(r.Header.Get(HeaderClientID)),
+		FederatedToken: strings.TrimSpace(r.Header.Get(HeaderFederatedToken)),
+	}
+}
+
+// WithCredential returns a context carrying the session credential
+func WithCredential(ctx context.Context, cred *Credential) context.Context {
+	return context.WithValue(ctx, contextKey{}, cred)
+}
+
+// FromContext returns the session credential stored in the context, if any
+func FromContext(ctx context.Context) *Credential {
+	cred, _ := ctx.Value(contextKey{}).(*Credential)
+	return cred
+}
+
+// GetToken implements azcore.TokenCredential by returning the session access token
+func (c *Credential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	if c == nil || c.AccessToken == "" {
+		return azcore.AccessToken{}, fmt.Errorf("session has no access token")
+	}
+	if c.Expired() {
+		return azcore.AccessToken{}, fmt.Errorf("session access token expired at %s", c.ExpiresOn.Format(time.RFC3339))
+	}
+	return azcore.AccessToken{Token: c.AccessToken, ExpiresOn: c.ExpiresOn}, nil
+}
+
+// Expired reports whether the access token has expired
+func (c *Credential) Expired() bool {
+	return !c.ExpiresOn.IsZero() && time.Now().After(c.ExpiresOn)
+}
+
+// Validate checks the identity headers a session supplied. The tenant and client IDs must be GUIDs and the
+// federated token a JWT, since they are passed to az login and must not carry extra arguments.
+func (c *Credential) Validate() error {
+	if c.TenantID != "" && !guidPattern.MatchString(c.TenantID) {
+		return fmt.Errorf("invalid %s header: expected a tenant ID GUID", HeaderTenantID)
+	}
+	if c.ClientID != "" && !guidPattern.MatchString(c.ClientID) {
+		return fmt.Errorf("invalid %s header: expected a client ID GUID", HeaderClientID)
+	}
+	if c.FederatedToken != "" && !federatedTokenPattern.MatchString(c.FederatedToken) {
+		return fmt.Errorf("invalid %s header: expected a JWT", HeaderFederatedToken)
+	}
+	return nil
+}
+
+// SupportsAzCli reports whether the session supplied enough information for az CLI to log in
+func (c *Credential) SupportsAzCli() bool {
+	return c != nil && c.TenantID != "" && c.ClientID != "" && c.FederatedToken != ""
+}
+
+// Key returns a stable identifier for isolating per-session state such as caches and CLI config directories.
+// It is derived from the session ID and the tenant and client identity only, so token refreshes within a
+// session reuse the same state instead of creating new entries.
+func (c *Credential) Key() string {
+	h := sha256.New()
+	for _, part := range []string{c.SessionID, c.TenantID, c.ClientID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// tokenExpiry decodes the exp claim of a JWT access token without validating it
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Now().Add(defaultTokenLifetime)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Now().Add(defaultTokenLifetime)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Now().Add(defaultTokenLifetime)
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+func makeJWT(exp int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	return header + "." + payload + ".sig"
+}
+
+func TestFromRequest(t *testing.T) {
+	exp := time.Now().Add(30 * time.Minute).Unix()
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	req.Header.Set(HeaderAuthorization, "Bearer "+makeJWT(exp))
+	req.Header.Set(HeaderTenantID, "tenant")
+	req.Header.Set(HeaderClientID, "client")
+	req.Header.Set(HeaderFederatedToken, "federated")
+
+	cred := FromRequest(req)
+	if cred == nil {
+		t.Fatal("Expected credential to be extracted")
+	}
+	if cred.ExpiresOn.Unix() != exp {
+		t.Errorf("Expected expiry %d, got %d", exp, cred.ExpiresOn.Unix())
+	}
+	if !cred.SupportsAzCli() {
+		t.Error("Expected credential with federated token to support az CLI")
+	}
+
+	token, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token.Token != cred.AccessToken {
+		t.Error("Expected GetToken to return the session access token")
+	}
+}
+
+func TestFromRequest_NoToken(t *testing.T) {
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	if cred := FromRequest(req); cred != nil {
+		t.Error("Expected no credential without Authorization header")
+	}
+
+	req.Header.Set(HeaderAuthorization, "Basic abc")
+	if cred := FromRequest(req); cred != nil {
+		t.Error("Expected no credential for non-bearer Authorization header")
+	}
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	cred := &Credential{AccessToken: "opaque", ExpiresOn: time.Now().Add(-time.Minute)}
+	if !cred.Expired() {
+		t.Error("Expected credential to be expired")
+	}
+	if _, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{}); err == nil {
+		t.Error("Expected GetToken to fail for an expired token")
+	}
+
+	// Opaque tokens fall back to the default lifetime
+	if exp := tokenExpiry("opaque"); exp.Before(time.Now()) {
+		t.Error("Expected opaque token expiry in the future")
+	}
+}
+
+func TestCredentialValidate(t *testing.T) {
+	valid := &Credential{TenantID: "72f988bf-86f1-41af-91ab-2d7cd011db47", ClientID: "00000000-0000-0000-0000-000000000001", FederatedToken: makeJWT(1)}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (&Credential{AccessToken: "t"}).Validate(); err != nil {
+		t.Errorf("Expected a credential without az identity to be valid, got %v", err)
+	}
+
+	for name, cred := range map[string]*Credential{
+		"tenant name":       {TenantID: "contoso.onmicrosoft.com"},
+		"tenant with flags": {TenantID: "72f988bf-86f1-41af-91ab-2d7cd011db47 --password x"},
+		"client with flags": {ClientID: "00000000-0000-0000-0000-000000000001 --debug"},
+		"quoted client":     {ClientID: "'a b'"},
+		"spaced token":      {FederatedToken: "a.b.c --allow-no-subscriptions"},
+	} {
+		if err := cred.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCredentialKey(t *testing.T) {
+	a := &Credential{SessionID: "s1", TenantID: "tenant", AccessToken: "t1"}
+	b := &Credential{SessionID: "s1", TenantID: "tenant", AccessToken: "t2", FederatedToken: "f2"}
+	c := &Credential{SessionID: "s2", TenantID: "tenant", AccessToken: "t1"}
+	d := &Credential{SessionID: "s1", TenantID: "other", AccessToken: "t1"}
+
+	if a.Key() != b.Key() {
+		t.Error("Expected token refreshes within a session to keep the same key")
+	}
+	if a.Key() == c.Key() || a.Key() == d.Key() {
+		t.Error("Expected different sessions or tenants to produce different keys")
+	}
+}
+
+func TestContextRoundTrip(t *testing.T) {
+	cred := &Credential{AccessToken: "t"}
+	ctx := WithCredential(context.Background(), cred)
+	if FromContext(ctx) != cred {
+		t.Error("Expected credential to round-trip through context")
+	}
+	if FromContext(context.Background()) != nil {
+		t.Error("Expected no credential in empty context")
+	}
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// verifyAPIVersion is the ARM API version used to check that a session token is accepted
+const verifyAPIVersion = "2022-12-01"
+
+// verifyTimeout bounds the ARM round trip made when a token is first seen
+const verifyTimeout = 15 * time.Second
+
+// Verifier checks session access tokens before any tool call uses them.
+// A token must be a well-formed JWT issued for Azure Resource Manager in the session's tenant,
+// and ARM must accept it. Accepted tokens are remembered until they expire.
+type Verifier struct {
+	endpoint   string
+	audience   string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	verified map[string]time.Time
+}
+
+// NewVerifier creates a verifier for tokens issued for the given ARM endpoint and audience
+func NewVerifier(resourceManagerEndpoint, audience string) *Verifier {
+	return &Verifier{
+		endpoint:   strings.TrimSuffix(resourceManagerEndpoint, "/"),
+		audience:   audience,
+		httpClient: &http.Client{Timeout: verifyTimeout},
+		verified:   make(map[string]time.Time),
+	}
+}
+
+// tokenClaims is the subset of access token claims checked by the verifier
+type tokenClaims struct {
+	Audience string `json:"aud"`
+	TenantID string `json:"tid"`
+	Exp      int64  `json:"exp"`
+}
+
+// Verify returns an error unless the credential carries a valid, unexpired ARM access token
+func (v *Verifier) Verify(ctx context.Context, cred *Credential) error {
+	if cred == nil || cred.AccessToken == "" {
+		return fmt.Errorf("session has no access token")
+	}
+
+	claims, err := parseClaims(cred.AccessToken)
+	if err != nil {
+		return err
+	}
+	expiresOn := time.Unix(claims.Exp, 0)
+	if claims.Exp == 0 || time.Now().After(expiresOn) {
+		return fmt.Errorf("access token has expired")
+	}
+	if !v.audienceMatches(claims.Audience) {
+		return fmt.Errorf("access token audience '%s' is not Azure Resource Manager", claims.Audience)
+	}
+	if cred.TenantID != "" && !strings.EqualFold(claims.TenantID, cred.TenantID) {
+		return fmt.Errorf("access token tenant does not match the %s header", HeaderTenantID)
+	}
+
+	key := tokenHash(cred.AccessToken)
+	v.mu.Lock()
+	_, known := v.verified[key]
+	v.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	if err := v.checkWithARM(ctx, cred.AccessToken); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	for k, exp := range v.verified {
+		if now.After(exp) {
+			delete(v.verified, k)
+		}
+	}
+	v.verified[key] = expiresOn
+	return nil
+}
+
+// audienceMatches accepts both the classic management audience and the ARM endpoint URL
+func (v *Verifier) audienceMatches(aud string) bool {
+	aud = strings.TrimSuffix(strings.ToLower(aud), "/")
+	return aud != "" && (aud == strings.TrimSuffix(strings.ToLower(v.audience), "/") ||
+		aud == strings.ToLower(v.endpoint))
+}
+
+// checkWithARM makes a read-only ARM call with the token so its signature and validity are checked by Entra ID
+func (v *Verifier) checkWithARM(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/subscriptions?api-version=%s", v.endpoint, verifyAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create token verification request: %w", err)
+	}
+	req.Header.Set(HeaderAuthorization, "Bearer "+token)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to verify access token with Azure Resource Manager: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("access token was rejected by Azure Resource Manager (status %d)", resp.StatusCode)
+	}
+	return nil
+}
+
+// parseClaims decodes the payload of a JWT access token
+func parseClaims(token string) (tokenClaims, error) {
+	var claims tokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, fmt.Errorf("access token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, fmt.Errorf("access token payload is not valid base64: %w", err)
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("access token payload is not valid JSON: %w", err)
+	}
+	return claims, nil
+}
+
+// tokenHash identifies a token without keeping it in memory as a map key
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
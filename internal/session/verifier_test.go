@@ -0,0 +1,72 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func makeARMToken(aud, tid string, exp int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"aud":%q,"tid":%q,"exp":%d}`, aud, tid, exp)))
+	return header + "." + payload + ".sig"
+}
+
+func newTestVerifier(t *testing.T, accept string) (*Verifier, *int) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get(HeaderAuthorization) != "Bearer "+accept {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return NewVerifier(srv.URL, "https://management.core.windows.net/"), &calls
+}
+
+func TestVerifier_AcceptsValidToken(t *testing.T) {
+	token := makeARMToken("https://management.core.windows.net/", "tenant", time.Now().Add(time.Hour).Unix())
+	v, calls := newTestVerifier(t, token)
+	cred := &Credential{AccessToken: token, TenantID: "tenant"}
+
+	for i := 0; i < 2; i++ {
+		if err := v.Verify(context.Background(), cred); err != nil {
+			t.Fatalf("Expected token to be accepted, got %v", err)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("Expected verified token to be remembered, got %d ARM calls", *calls)
+	}
+}
+
+func TestVerifier_RejectsInvalidTokens(t *testing.T) {
+	valid := makeARMToken("https://management.core.windows.net/", "tenant", time.Now().Add(time.Hour).Unix())
+	v, _ := newTestVerifier(t, valid)
+
+	tests := []struct {
+		name string
+		cred *Credential
+		want string
+	}{
+		{"opaque", &Credential{AccessToken: "x"}, "not a JWT"},
+		{"expired", &Credential{AccessToken: makeARMToken("https://management.core.windows.net/", "tenant", time.Now().Add(-time.Minute).Unix())}, "expired"},
+		{"wrong audience", &Credential{AccessToken: makeARMToken("https://graph.microsoft.com", "tenant", time.Now().Add(time.Hour).Unix())}, "audience"},
+		{"wrong tenant", &Credential{AccessToken: valid, TenantID: "other"}, "tenant"},
+		{"rejected by ARM", &Credential{AccessToken: makeARMToken("https://management.core.windows.net/", "tenant", time.Now().Add(2*time.Hour).Unix())}, "rejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(context.Background(), tt.cred)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
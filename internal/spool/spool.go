@@ -0,0 +1,222 @@
+// Package spool buffers large outputs, such as gadget results and exported logs, in memory up to a
+// threshold and overflows the rest to a temporary file, so a multi-megabyte result does not have to be
+// held in memory while it is produced or while it is kept as an artifact. The content is read back in
+// pages, or streamed, without loading all of it.
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultThreshold is the number of bytes a Writer keeps in memory before it overflows to disk
+const DefaultThreshold = 1 << 20
+
+// ErrClosed is returned when a closed Writer is written or read
+var ErrClosed = errors.New("spool is closed")
+
+// Writer collects output in memory up to its threshold and in a temporary file beyond it.
+// It is safe for concurrent use; Close removes the temporary file.
+type Writer struct {
+	mu        sync.Mutex
+	threshold int
+	buf       []byte
+	file      *os.File
+	size      int64
+	closed    bool
+}
+
+// New creates a Writer keeping up to threshold bytes in memory (DefaultThreshold when threshold is not positive)
+func New(threshold int) *Writer {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Writer{threshold: threshold}
+}
+
+// FromString creates a Writer holding s, overflowing to disk when s is larger than threshold
+func FromString(s string, threshold int) (*Writer, error) {
+	w := New(threshold)
+	if _, err := w.WriteString(s); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p, moving the content to a temporary file once it exceeds the threshold
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.file == nil && len(w.buf)+len(p) > w.threshold {
+		file, err := os.CreateTemp("", "aks-mcp-spool-*")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create spool file: %w", err)
+		}
+		if _, err := file.Write(w.buf); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return 0, fmt.Errorf("failed to write spool file: %w", err)
+		}
+		w.file = file
+		w.buf = nil
+	}
+	if w.file != nil {
+		n, err := w.file.Write(p)
+		w.size += int64(n)
+		if err != nil {
+			return n, fmt.Errorf("failed to write spool file: %w", err)
+		}
+		return n, nil
+	}
+	w.buf = append(w.buf, p...)
+	w.size += int64(len(p))
+	return len(p), nil
+}
+
+// WriteString appends s
+func (w *Writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Len returns the number of bytes written
+func (w *Writer) Len() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// Spilled reports whether the content overflowed to disk
+func (w *Writer) Spilled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file != nil
+}
+
+// ReadAt reads len(p) bytes at off, implementing io.ReaderAt
+func (w *Writer) ReadAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= w.size {
+		return 0, io.EOF
+	}
+	if w.file != nil {
+		n, err := w.file.ReadAt(p[:min(int64(len(p)), w.size-off)], off)
+		if err == nil && n < len(p) {
+			err = io.EOF
+		}
+		return n, err
+	}
+	n := copy(p, w.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Reader returns a reader streaming the content written so far
+func (w *Writer) Reader() io.Reader {
+	return io.NewSectionReader(w, 0, w.Len())
+}
+
+// String returns the whole content. Use Page or Reader for content that may not fit in memory.
+func (w *Writer) String() (string, error) {
+	data, err := io.ReadAll(w.Reader())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Slice returns up to size bytes starting at off, with both ends moved back to the start of a UTF-8
+// character so the text is never cut inside one
+func (w *Writer) Slice(off int64, size int) (string, error) {
+	start, err := w.runeStart(off)
+	if err != nil {
+		return "", err
+	}
+	end, err := w.runeStart(min(off+int64(size), w.Len()))
+	if err != nil {
+		return "", err
+	}
+	if end <= start {
+		return "", nil
+	}
+	data := make([]byte, end-start)
+	n, err := w.ReadAt(data, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return string(data[:n]), nil
+}
+
+// Page returns page n (from 0) of the content split in pages of pageSize bytes, and the number of pages
+func (w *Writer) Page(n, pageSize int) (string, int, error) {
+	pages := Pages(w.Len(), pageSize)
+	if n < 0 || n >= pages {
+		return "", pages, fmt.Errorf("page %d is out of range: the content has %d pages", n, pages)
+	}
+	text, err := w.Slice(int64(n)*int64(pageSize), pageSize)
+	return text, pages, err
+}
+
+// Pages returns the number of pages of pageSize bytes needed for size bytes; empty content has one empty page
+func Pages(size int64, pageSize int) int {
+	if size <= 0 || pageSize <= 0 {
+		return 1
+	}
+	return int((size + int64(pageSize) - 1) / int64(pageSize))
+}
+
+// Close releases the content and removes the temporary file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.buf = nil
+	if w.file == nil {
+		return nil
+	}
+	name := w.file.Name()
+	err := w.file.Close()
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// runeStart moves off back to the start of the UTF-8 character it falls in
+func (w *Writer) runeStart(off int64) (int64, error) {
+	if off <= 0 || off >= w.Len() {
+		return max(0, min(off, w.Len())), nil
+	}
+	// A UTF-8 character is at most 4 bytes, so its start is within the 3 bytes before off
+	from := max(0, off-(utf8.UTFMax-1))
+	window := make([]byte, off-from+1)
+	n, err := w.ReadAt(window, from)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	for i := n - 1; i >= 0; i-- {
+		if utf8.RuneStart(window[i]) {
+			return from + int64(i), nil
+		}
+	}
+	return off, nil
+}
@@ -0,0 +1,68 @@
+package spool
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriterOverflowsToDisk(t *testing.T) {
+	w := New(8)
+	defer func() { _ = w.Close() }()
+	if _, err := w.WriteString("abcd"); err != nil || w.Spilled() {
+		t.Fatalf("Expected content below the threshold to stay in memory (%v)", err)
+	}
+	if _, err := w.WriteString("efghij"); err != nil || !w.Spilled() || w.Len() != 10 {
+		t.Fatalf("Expected the content to overflow to disk, got %d bytes (%v)", w.Len(), err)
+	}
+	if content, err := w.String(); err != nil || content != "abcdefghij" {
+		t.Errorf("Unexpected content %q (%v)", content, err)
+	}
+	buf := make([]byte, 4)
+	if n, err := w.ReadAt(buf, 8); n != 2 || string(buf[:n]) != "ij" || !errors.Is(err, io.EOF) {
+		t.Errorf("Unexpected read at the end: %q (%v)", buf[:n], err)
+	}
+	data, err := io.ReadAll(w.Reader())
+	if err != nil || string(data) != "abcdefghij" {
+		t.Errorf("Unexpected streamed content %q (%v)", data, err)
+	}
+
+	name := w.file.Name()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("Expected the spool file to be removed, got %v", err)
+	}
+	if _, err := w.WriteString("x"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected writes after Close to fail, got %v", err)
+	}
+}
+
+func TestWriterPages(t *testing.T) {
+	for _, threshold := range []int{4, 1024} {
+		w, err := FromString("aaé"+strings.Repeat("b", 5), threshold)
+		if err != nil {
+			t.Fatalf("Failed to spool: %v", err)
+		}
+		// Pages of 3 bytes: the two-byte character starting at offset 2 moves to the second page
+		first, pages, err := w.Page(0, 3)
+		if err != nil || pages != 3 || first != "aa" {
+			t.Errorf("threshold %d: unexpected first page %q of %d (%v)", threshold, first, pages, err)
+		}
+		second, _, _ := w.Page(1, 3)
+		third, _, _ := w.Page(2, 3)
+		if second != "ébb" || first+second+third != "aaébbbbb" {
+			t.Errorf("threshold %d: unexpected pages %q %q %q", threshold, first, second, third)
+		}
+		if _, _, err := w.Page(3, 3); err == nil {
+			t.Errorf("threshold %d: expected a page past the end to be rejected", threshold)
+		}
+		_ = w.Close()
+	}
+	if Pages(0, 10) != 1 || Pages(10, 10) != 1 || Pages(11, 10) != 2 {
+		t.Error("Unexpected page counts")
+	}
+}
@@ -0,0 +1,126 @@
+// Package store provides the persistence layer for server state that must survive restarts,
+// such as async operations, approvals, audit records and scan findings.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Store kinds selectable with --state-store
+const (
+	KindBolt   = "bolt"
+	KindMemory = "memory"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// Store is a key/value store partitioned into named buckets.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key in bucket, or ErrNotFound
+	Get(bucket, key string) ([]byte, error)
+	// Put stores value under key in bucket, replacing any existing value
+	Put(bucket, key string, value []byte) error
+	// Delete removes key from bucket. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// List returns every key and value in bucket
+	List(bucket string) (map[string][]byte, error)
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// Open creates the store of the given kind. path is the database file of the bolt store;
+// an empty path selects DefaultPath.
+func Open(kind, path string) (Store, error) {
+	switch kind {
+	case KindMemory:
+		return NewMemoryStore(), nil
+	case KindBolt, "":
+		if path == "" {
+			defaultPath, err := DefaultPath()
+			if err != nil {
+				return nil, err
+			}
+			path = defaultPath
+		}
+		return OpenBolt(path)
+	default:
+		return nil, fmt.Errorf("unknown state store '%s': expected %s or %s", kind, KindBolt, KindMemory)
+	}
+}
+
+// DefaultPath returns the default bolt database file in the user cache directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine state directory: %w", err)
+	}
+	return filepath.Join(dir, "aks-mcp", "state.db"), nil
+}
+
+// Repository stores records of type T as JSON in a single bucket
+type Repository[T any] struct {
+	store  Store
+	bucket string
+}
+
+// NewRepository creates a repository for records of type T in the named bucket
+func NewRepository[T any](s Store, bucket string) *Repository[T] {
+	return &Repository[T]{store: s, bucket: bucket}
+}
+
+// Save stores record under id
+func (r *Repository[T]) Save(id string, record T) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record %s: %w", r.bucket, id, err)
+	}
+	return r.store.Put(r.bucket, id, data)
+}
+
+// Load returns the record stored under id, or ErrNotFound
+func (r *Repository[T]) Load(id string) (T, error) {
+	var record T
+	data, err := r.store.Get(r.bucket, id)
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("failed to decode %s record %s: %w", r.bucket, id, err)
+	}
+	return record, nil
+}
+
+// Delete removes the record stored under id
+func (r *Repository[T]) Delete(id string) error {
+	return r.store.Delete(r.bucket, id)
+}
+
+// List returns every record in the repository ordered by id
+func (r *Repository[T]) List() ([]T, error) {
+	values, err := r.store.List(r.bucket)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	records := make([]T, 0, len(ids))
+	for _, id := range ids {
+		var record T
+		if err := json.Unmarshal(values[id], &record); err != nil {
+			return nil, fmt.Errorf("failed to decode %s record %s: %w", r.bucket, id, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type testRecord struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestStores(t *testing.T) {
+	bolt, err := OpenBolt(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("OpenBolt failed: %v", err)
+	}
+	for name, s := range map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() { _ = s.Close() }()
+
+			if _, err := s.Get("ops", "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound, got %v", err)
+			}
+			if err := s.Put("ops", "a", []byte("1")); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if err := s.Put("findings", "a", []byte("2")); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if value, err := s.Get("ops", "a"); err != nil || string(value) != "1" {
+				t.Errorf("Expected stored value, got %q err=%v", value, err)
+			}
+			if values, err := s.List("ops"); err != nil || len(values) != 1 {
+				t.Errorf("Expected buckets to be separate, got %v err=%v", values, err)
+			}
+			if err := s.Delete("ops", "a"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := s.Delete("unknown", "a"); err != nil {
+				t.Errorf("Expected deleting from a missing bucket to succeed, got %v", err)
+			}
+			if _, err := s.Get("ops", "a"); !errors.Is(err, ErrNotFound) {
+				t.Error("Expected key to be deleted")
+			}
+		})
+	}
+}
+
+func TestBoltStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.db")
+	s, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("OpenBolt failed: %v", err)
+	}
+	if err := s.Put("ops", "a", []byte("running")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	if value, err := reopened.Get("ops", "a"); err != nil || string(value) != "running" {
+		t.Errorf("Expected value to survive reopen, got %q err=%v", value, err)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	repo := NewRepository[testRecord](NewMemoryStore(), "ops")
+	for _, rec := range []testRecord{{ID: "b", Status: "done"}, {ID: "a", Status: "running"}} {
+		if err := repo.Save(rec.ID, rec); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	rec, err := repo.Load("a")
+	if err != nil || rec.Status != "running" {
+		t.Errorf("Unexpected record %+v err=%v", rec, err)
+	}
+	records, err := repo.List()
+	if err != nil || len(records) != 2 || records[0].ID != "a" {
+		t.Errorf("Expected records ordered by id, got %+v err=%v", records, err)
+	}
+	if err := repo.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestOpenUnknownKind(t *testing.T) {
+	if _, err := Open("sqlite", ""); err == nil {
+		t.Error("Expected error for unknown store kind")
+	}
+}
@@ -0,0 +1,52 @@
+//go:build !notelemetry
+
+package telemetry
+
+import (
+	"time"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+)
+
+// defaultInstrumentationKey is the Application Insights resource AKS MCP reports to unless
+// APPLICATIONINSIGHTS_INSTRUMENTATION_KEY names another one
+const defaultInstrumentationKey = "c301e561-daea-42d9-b9d1-65fca4166704"
+
+// ApplicationInsightsCompiledIn reports whether the binary contains the Application Insights client.
+// Building with the notelemetry tag leaves it and the default instrumentation key out.
+const ApplicationInsightsCompiledIn = true
+
+// appInsightsSink sends events to Application Insights
+type appInsightsSink struct {
+	client appinsights.TelemetryClient
+}
+
+// newAppInsightsSink creates an Application Insights client that adds commonProps to every event
+func newAppInsightsSink(instrumentationKey, endpoint string, commonProps map[string]string) eventSink {
+	config := appinsights.NewTelemetryConfiguration(instrumentationKey)
+	if endpoint != "" {
+		config.EndpointUrl = endpoint
+	}
+	client := appinsights.NewTelemetryClientFromConfig(config)
+	for name, value := range commonProps {
+		client.Context().CommonProperties[name] = value
+	}
+	return &appInsightsSink{client: client}
+}
+
+// Track sends an information trace with the given properties
+func (a *appInsightsSink) Track(name string, properties map[string]string) {
+	trace := appinsights.NewTraceTelemetry(name, appinsights.Information)
+	for key, value := range properties {
+		trace.Properties[key] = value
+	}
+	a.client.Track(trace)
+}
+
+// Close flushes pending events and closes the channel
+func (a *appInsightsSink) Close() {
+	a.client.Channel().Flush()
+	// Wait a bit for the data to be sent
+	time.Sleep(2 * time.Second)
+	a.client.Channel().Close()
+}
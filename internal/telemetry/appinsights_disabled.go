@@ -0,0 +1,16 @@
+//go:build notelemetry
+
+package telemetry
+
+// defaultInstrumentationKey is empty: binaries built with the notelemetry tag have no built-in
+// Application Insights resource
+const defaultInstrumentationKey = ""
+
+// ApplicationInsightsCompiledIn reports whether the binary contains the Application Insights client.
+// Building with the notelemetry tag leaves it and the default instrumentation key out.
+const ApplicationInsightsCompiledIn = false
+
+// newAppInsightsSink returns nil, so no event is ever sent to Application Insights
+func newAppInsightsSink(string, string, map[string]string) eventSink {
+	return nil
+}
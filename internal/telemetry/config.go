@@ -8,10 +8,6 @@ import (
 	"strconv"
 )
 
-const (
-	defaultInstrumentationKey = "c301e561-daea-42d9-b9d1-65fca4166704"
-)
-
 // Config represents the telemetry configuration
 type Config struct {
 	// Enabled controls whether telemetry collection is active
@@ -20,6 +16,8 @@ type Config struct {
 	DeviceID string
 	// instrumentationKey for Azure application insights
 	instrumentationKey string
+	// appInsightsEndpoint overrides the Application Insights ingestion URL (sovereign clouds)
+	appInsightsEndpoint string
 	// OTLPEndpoint for OpenTelemetry Protocol export
 	OTLPEndpoint string
 	// ServiceName identifies the service in telemetry
@@ -53,6 +51,15 @@ func NewConfig(serviceName, serviceVersion string) *Config {
 	return cfg
 }
 
+// NewDisabledConfig creates a configuration with all telemetry off, for --disable-telemetry. Unlike
+// AKS_MCP_COLLECT_TELEMETRY=false it also turns off OTLP export, and reads no environment variables.
+func NewDisabledConfig(serviceName, serviceVersion string) *Config {
+	return &Config{
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+	}
+}
+
 // generateDeviceID creates a hashed MAC address for device identification
 func generateDeviceID() string {
 	interfaces, err := net.Interfaces()
@@ -72,8 +79,13 @@ func generateDeviceID() string {
 	return fmt.Sprintf("%x", sha256.Sum256([]byte("aks-mcp-no-mac")))
 }
 
-// getApplicationInsightsInstrumentationKey retrieves the instrumentation key from environment
+// getApplicationInsightsInstrumentationKey retrieves the instrumentation key from environment.
+// Binaries built without the Application Insights client ignore it.
 func getApplicationInsightsInstrumentationKey() string {
+	if !ApplicationInsightsCompiledIn {
+		return ""
+	}
+
 	// Check for explicit instrumentation key first
 	if instrKey := os.Getenv("APPLICATIONINSIGHTS_INSTRUMENTATION_KEY"); instrKey != "" {
 		return instrKey
@@ -97,3 +109,13 @@ func (c *Config) HasApplicationInsights() bool {
 func (c *Config) SetOTLPEndpoint(endpoint string) {
 	c.OTLPEndpoint = endpoint
 }
+
+// SetApplicationInsightsCloud routes Application Insights telemetry to a sovereign cloud ingestion endpoint.
+// The built-in instrumentation key belongs to the public cloud, so Application Insights export is
+// disabled unless APPLICATIONINSIGHTS_INSTRUMENTATION_KEY names a resource in that cloud.
+func (c *Config) SetApplicationInsightsCloud(endpoint string) {
+	c.appInsightsEndpoint = endpoint
+	if endpoint == "" || c.instrumentationKey == defaultInstrumentationKey {
+		c.instrumentationKey = ""
+	}
+}
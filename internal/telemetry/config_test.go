@@ -1,6 +1,7 @@
 package telemetry
 
 import (
+	"context"
 	"os"
 	"testing"
 )
@@ -26,6 +27,9 @@ func TestNewConfig(t *testing.T) {
 }
 
 func TestConfigHasAzureMonitor(t *testing.T) {
+	if !ApplicationInsightsCompiledIn {
+		t.Skip("Application Insights is compiled out")
+	}
 	config := NewConfig("test", "v1.0.0")
 
 	// Should be true with default connection string (valid connection string)
@@ -156,3 +160,60 @@ func TestTelemetryEnableViaEnvironment(t *testing.T) {
 		t.Error("Expected device ID to be generated when telemetry is enabled")
 	}
 }
+
+func TestSetApplicationInsightsCloud(t *testing.T) {
+	if !ApplicationInsightsCompiledIn {
+		t.Skip("Application Insights is compiled out")
+	}
+	t.Setenv("AKS_MCP_COLLECT_TELEMETRY", "true")
+	t.Setenv("APPLICATIONINSIGHTS_INSTRUMENTATION_KEY", "")
+
+	// The built-in public cloud key is dropped in sovereign clouds
+	config := NewConfig("test-service", "v1.0.0")
+	config.SetApplicationInsightsCloud("https://dc.applicationinsights.us/v2/track")
+	if config.HasApplicationInsights() {
+		t.Error("Expected Application Insights to be disabled with the default key in a sovereign cloud")
+	}
+
+	// An explicit key is kept and sent to the sovereign endpoint
+	t.Setenv("APPLICATIONINSIGHTS_INSTRUMENTATION_KEY", "gov-key")
+	config = NewConfig("test-service", "v1.0.0")
+	config.SetApplicationInsightsCloud("https://dc.applicationinsights.us/v2/track")
+	if !config.HasApplicationInsights() {
+		t.Error("Expected Application Insights to stay enabled with an explicit key")
+	}
+	if config.appInsightsEndpoint != "https://dc.applicationinsights.us/v2/track" {
+		t.Errorf("Unexpected ingestion endpoint: %s", config.appInsightsEndpoint)
+	}
+}
+
+func TestNewDisabledConfig(t *testing.T) {
+	t.Setenv("AKS_MCP_COLLECT_TELEMETRY", "true")
+	t.Setenv("APPLICATIONINSIGHTS_INSTRUMENTATION_KEY", "custom-key")
+
+	config := NewDisabledConfig("test-service", "v1.0.0")
+	if config.Enabled || config.DeviceID != "" || config.HasApplicationInsights() || config.HasOTLP() {
+		t.Errorf("Expected all telemetry to be off regardless of the environment, got %+v", config)
+	}
+
+	// An uninitialized service tracks nothing and shuts down cleanly
+	service := NewService(config)
+	service.TrackServiceStartup(context.Background())
+	service.TrackToolInvocation(context.Background(), "az_aks_operations", "show", true)
+	if service.IsInitialized() {
+		t.Error("Expected the disabled service to stay uninitialized")
+	}
+	if err := service.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestInstrumentationKeyWithoutApplicationInsights(t *testing.T) {
+	t.Setenv("AKS_MCP_COLLECT_TELEMETRY", "true")
+	t.Setenv("APPLICATIONINSIGHTS_INSTRUMENTATION_KEY", "")
+
+	config := NewConfig("test-service", "v1.0.0")
+	if config.HasApplicationInsights() != ApplicationInsightsCompiledIn {
+		t.Errorf("Expected Application Insights export only when compiled in (compiled in: %t)", ApplicationInsightsCompiledIn)
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
-	"github.com/microsoft/ApplicationInsights-Go/appinsights"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -16,13 +14,19 @@ import (
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
+// eventSink receives telemetry events; the Application Insights client is the only implementation
+type eventSink interface {
+	Track(name string, properties map[string]string)
+	Close()
+}
+
 // Service provides telemetry functionality for AKS MCP
 type Service struct {
-	config            *Config
-	tracer            oteltrace.Tracer
-	tracerProvider    *trace.TracerProvider
-	appInsightsClient appinsights.TelemetryClient
-	isInitialized     bool
+	config         *Config
+	tracer         oteltrace.Tracer
+	tracerProvider *trace.TracerProvider
+	appInsights    eventSink
+	isInitialized  bool
 }
 
 // NewService creates a new telemetry service
@@ -110,15 +114,11 @@ func (s *Service) initializeApplicationInsights() {
 		return
 	}
 
-	// Create TelemetryConfiguration
-	config := appinsights.NewTelemetryConfiguration(s.config.instrumentationKey)
-	s.appInsightsClient = appinsights.NewTelemetryClientFromConfig(config)
-
-	// Add common properties
-	commonProps := s.appInsightsClient.Context().CommonProperties
-	commonProps["service.name"] = s.config.ServiceName
-	commonProps["service.version"] = s.config.ServiceVersion
-	commonProps["device.id"] = s.config.DeviceID
+	s.appInsights = newAppInsightsSink(s.config.instrumentationKey, s.config.appInsightsEndpoint, map[string]string{
+		"service.name":    s.config.ServiceName,
+		"service.version": s.config.ServiceVersion,
+		"device.id":       s.config.DeviceID,
+	})
 }
 
 // StartActivity starts a new telemetry activity (span)
@@ -150,13 +150,12 @@ func (s *Service) TrackToolInvocation(ctx context.Context, toolName string, oper
 	}
 
 	// Application Insights trace
-	if s.config.HasApplicationInsights() && s.appInsightsClient != nil {
-		trace := appinsights.NewTraceTelemetry("ToolInvocation", appinsights.Information)
-		trace.Properties["tool.name"] = toolName
-		trace.Properties["tool.operation"] = operation
-		trace.Properties["tool.success"] = fmt.Sprintf("%t", success)
-
-		s.appInsightsClient.Track(trace)
+	if s.config.HasApplicationInsights() && s.appInsights != nil {
+		s.appInsights.Track("ToolInvocation", map[string]string{
+			"tool.name":      toolName,
+			"tool.operation": operation,
+			"tool.success":   fmt.Sprintf("%t", success),
+		})
 	}
 }
 
@@ -179,13 +178,12 @@ func (s *Service) TrackServiceStartup(ctx context.Context) {
 	}
 
 	// Application Insights trace
-	if s.config.HasApplicationInsights() && s.appInsightsClient != nil {
-		trace := appinsights.NewTraceTelemetry("ServiceStartup", appinsights.Information)
-		trace.Properties["service.name"] = s.config.ServiceName
-		trace.Properties["service.version"] = s.config.ServiceVersion
-		trace.Properties["device.id"] = s.config.DeviceID
-
-		s.appInsightsClient.Track(trace)
+	if s.config.HasApplicationInsights() && s.appInsights != nil {
+		s.appInsights.Track("ServiceStartup", map[string]string{
+			"service.name":    s.config.ServiceName,
+			"service.version": s.config.ServiceVersion,
+			"device.id":       s.config.DeviceID,
+		})
 	}
 }
 
@@ -196,11 +194,8 @@ func (s *Service) Shutdown(ctx context.Context) error {
 	}
 
 	// Shutdown Application Insights and wait for data to be sent
-	if s.appInsightsClient != nil {
-		s.appInsightsClient.Channel().Flush()
-		// Wait a bit for the data to be sent
-		time.Sleep(2 * time.Second)
-		s.appInsightsClient.Channel().Close()
+	if s.appInsights != nil {
+		s.appInsights.Close()
 	}
 
 	// Shutdown OpenTelemetry tracer provider
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"context"
+
 	"github.com/Azure/aks-mcp/internal/config"
 )
 
@@ -37,3 +39,25 @@ var _ ResourceHandler = ResourceHandlerFunc(nil)
 func (f ResourceHandlerFunc) Handle(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
 	return f(params, cfg)
 }
+
+// ContextResourceHandler is a ResourceHandler that needs the tool call context, for example to
+// stop when the client cancels the call or to send progress notifications with NotifyProgress
+type ContextResourceHandler interface {
+	ResourceHandler
+	HandleContext(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error)
+}
+
+// ContextResourceHandlerFunc is a function type that implements ContextResourceHandler
+type ContextResourceHandlerFunc func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error)
+
+var _ ContextResourceHandler = ContextResourceHandlerFunc(nil)
+
+// Handle implements the ResourceHandler interface with a background context
+func (f ContextResourceHandlerFunc) Handle(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	return f(context.Background(), params, cfg)
+}
+
+// HandleContext implements the ContextResourceHandler interface for ContextResourceHandlerFunc
+func (f ContextResourceHandlerFunc) HandleContext(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+	return f(ctx, params, cfg)
+}
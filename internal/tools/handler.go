@@ -5,8 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Azure/aks-mcp/internal/apikey"
+	"github.com/Azure/aks-mcp/internal/artifacts"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/errorkb"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/Azure/aks-mcp/internal/review"
+	"github.com/Azure/aks-mcp/internal/session"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -31,6 +39,177 @@ func logToolResult(toolName string, result string, err error) {
 	}
 }
 
+// resolveCallConfig returns the configuration for a single tool call.
+// In session credential mode the call must carry session credentials, which are bound to a copy of the configuration.
+// Calls made through WithTimeout also get a copy that carries the call's timeout, and calls made with an
+// API key a copy limited to the key's access level.
+func resolveCallConfig(ctx context.Context, cfg *config.ConfigData) (*config.ConfigData, error) {
+	if key := apikey.FromContext(ctx); key != nil {
+		cfg = cfg.ForAPIKey(key)
+	}
+	if seconds := CallTimeout(ctx, cfg); seconds != cfg.Timeout {
+		cfg = cfg.ForTimeout(seconds)
+	}
+	if !cfg.SessionCredentials {
+		return cfg, nil
+	}
+	cred := session.FromContext(ctx)
+	if cred == nil {
+		return nil, fmt.Errorf("session credentials are required: send an Azure access token in the %s header as 'Bearer <token>'", session.HeaderAuthorization)
+	}
+	if cred.Expired() {
+		return nil, fmt.Errorf("session access token has expired, please supply a new token")
+	}
+	return cfg.ForSession(cred), nil
+}
+
+// ExplainParam is the tool argument that asks for the commands and API calls of the call to be explained
+const ExplainParam = "explain"
+
+// WithExplain adds the explain argument to a tool's input schema
+func WithExplain(tool mcp.Tool) mcp.Tool {
+	if tool.RawInputSchema != nil {
+		return tool
+	}
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = map[string]interface{}{}
+	}
+	tool.InputSchema.Properties[ExplainParam] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Also return the exact az, kubectl and Azure Resource Manager calls made, why each was made, and links to their documentation",
+	}
+	return tool
+}
+
+// splitExplain removes the explain argument and returns a trace when it was set to true
+func splitExplain(args map[string]interface{}) (map[string]interface{}, *explain.Trace) {
+	value, ok := args[ExplainParam]
+	if !ok {
+		return args, nil
+	}
+	rest := make(map[string]interface{}, len(args)-1)
+	for k, v := range args {
+		if k != ExplainParam {
+			rest[k] = v
+		}
+	}
+	if value == true || value == "true" {
+		return rest, explain.New()
+	}
+	return rest, nil
+}
+
+// withExplanation appends the rendered trace to a tool result as a separate text content
+func withExplanation(result *mcp.CallToolResult, trace *explain.Trace) *mcp.CallToolResult {
+	if trace != nil {
+		result.Content = append(result.Content, mcp.NewTextContent(trace.Render()))
+	}
+	return result
+}
+
+// ReviewParam is the tool argument that asks for the call's write operations to be returned for review
+const ReviewParam = "review"
+
+// WithReview adds the review argument to a tool's input schema
+func WithReview(tool mcp.Tool) mcp.Tool {
+	if tool.RawInputSchema != nil {
+		return tool
+	}
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = map[string]interface{}{}
+	}
+	tool.InputSchema.Properties[ReviewParam] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Do not execute write operations: return them as az CLI scripts, Bicep patches or kubectl manifests and diffs to apply through a pipeline",
+	}
+	return tool
+}
+
+// splitReview removes the review argument and returns a plan when the call is in review mode, because the
+// server runs with --review-mode or the argument is true. The argument cannot turn off --review-mode.
+func splitReview(args map[string]interface{}, cfg *config.ConfigData) (map[string]interface{}, *review.Plan) {
+	value, ok := args[ReviewParam]
+	if ok {
+		rest := make(map[string]interface{}, len(args)-1)
+		for k, v := range args {
+			if k != ReviewParam {
+				rest[k] = v
+			}
+		}
+		args = rest
+	}
+	if cfg.ReviewMode || value == true || value == "true" {
+		return args, review.New()
+	}
+	return args, nil
+}
+
+// reviewResult returns the deferred changes of a call in review mode as its result, or nil when the call
+// made no write
+func reviewResult(toolName string, plan *review.Plan) *mcp.CallToolResult {
+	if len(plan.Changes()) == 0 {
+		return nil
+	}
+	report, err := plan.Report(toolName)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+	return mcp.NewToolResultText(report)
+}
+
+// artifactPreviewBytes is how much of an output kept as an artifact is returned inline
+const artifactPreviewBytes = 4096
+
+// textResult returns a tool output as text. Outputs larger than the artifact threshold are kept as an
+// artifact resource and returned as a preview with a link to the resource holding the full output.
+func textResult(toolName, result string, cfg *config.ConfigData) *mcp.CallToolResult {
+	if cfg.ArtifactThreshold <= 0 || len(result) <= cfg.ArtifactThreshold {
+		return mcp.NewToolResultText(result)
+	}
+	mimeType := "text/plain"
+	if json.Valid([]byte(result)) {
+		mimeType = "application/json"
+	}
+	artifact, ok := cfg.AttachArtifact(toolName+" output", mimeType, result)
+	if !ok {
+		return mcp.NewToolResultText(result)
+	}
+
+	// Cut the preview at a character boundary
+	cut := min(artifactPreviewBytes, cfg.ArtifactThreshold)
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	preview := result[:cut]
+	note := fmt.Sprintf("\n\n[Output truncated: showing %d of %d bytes. The full output is available as %s until %s.]",
+		len(preview), len(result), artifactLocation(artifact), artifact.Expires.UTC().Format(time.RFC3339))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(preview + note),
+			artifactLink(toolName, artifact),
+		},
+	}
+}
+
+// artifactLocation describes where an artifact is read: its URI, or the range of page URIs when it is
+// larger than a page
+func artifactLocation(artifact artifacts.Artifact) string {
+	if pages := artifact.Pages(); pages > 1 {
+		return fmt.Sprintf("%d pages, the resources %s to %s,", pages, artifact.PageURI(0), artifact.PageURI(pages-1))
+	}
+	return "the resource " + artifact.URI
+}
+
+// artifactLink links an artifact, or its first page when it is larger than a page
+func artifactLink(toolName string, artifact artifacts.Artifact) mcp.ResourceLink {
+	if pages := artifact.Pages(); pages > 1 {
+		description := fmt.Sprintf("Page 1 of %d of the full %s output (%d bytes)", pages, toolName, artifact.Size)
+		return mcp.NewResourceLink(artifact.PageURI(0), artifact.Name, description, artifact.MIMEType)
+	}
+	description := fmt.Sprintf("Full %s output (%d bytes)", toolName, artifact.Size)
+	return mcp.NewResourceLink(artifact.URI, artifact.Name, description, artifact.MIMEType)
+}
+
 // CreateToolHandler creates an adapter that converts CommandExecutor to the format expected by MCP server
 func CreateToolHandler(executor CommandExecutor, cfg *config.ConfigData) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -48,7 +227,33 @@ func CreateToolHandler(executor CommandExecutor, cfg *config.ConfigData) func(ct
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		result, err := executor.Execute(args, cfg)
+		callCfg, err := resolveCallConfig(ctx, cfg)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Record the commands and API calls of the call when an explanation is requested
+		args, trace := splitExplain(args)
+		if trace != nil {
+			callCfg = callCfg.ForExplain(trace)
+		}
+
+		// Shape the result with the verbosity profile the call asks for
+		args, verbosity, err := splitVerbosity(args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if verbosity != "" {
+			callCfg = callCfg.ForVerbosity(verbosity)
+		}
+
+		// Defer write operations for review when the server or the call asks for it
+		args, plan := splitReview(args, cfg)
+		if plan != nil {
+			callCfg = callCfg.ForReview(plan)
+		}
+
+		result, err := executor.Execute(args, callCfg)
 		if cfg.TelemetryService != nil {
 			operation, _ := args["operation"].(string)
 			cfg.TelemetryService.TrackToolInvocation(ctx, req.Params.Name, operation, err == nil)
@@ -58,11 +263,17 @@ func CreateToolHandler(executor CommandExecutor, cfg *config.ConfigData) func(ct
 			logToolResult(req.Params.Name, result, err)
 		}
 
+		// Deferred writes replace the result, including the error that stopped the call at the first of them
+		if reviewed := reviewResult(req.Params.Name, plan); reviewed != nil {
+			return withExplanation(reviewed, trace), nil
+		}
+
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			// Append known causes and next steps for common ARM and az CLI error codes
+			return withExplanation(mcp.NewToolResultError(errorkb.Enrich(err.Error())), trace), nil
 		}
 
-		return mcp.NewToolResultText(result), nil
+		return withExplanation(shapeResult(ctx, req.Params.Name, result, callCfg), trace), nil
 	}
 }
 
@@ -83,7 +294,38 @@ func CreateResourceHandler(handler ResourceHandler, cfg *config.ConfigData) func
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		result, err := handler.Handle(args, cfg)
+		callCfg, err := resolveCallConfig(ctx, cfg)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Record the commands and API calls of the call when an explanation is requested
+		args, trace := splitExplain(args)
+		if trace != nil {
+			callCfg = callCfg.ForExplain(trace)
+		}
+
+		// Shape the result with the verbosity profile the call asks for
+		args, verbosity, err := splitVerbosity(args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if verbosity != "" {
+			callCfg = callCfg.ForVerbosity(verbosity)
+		}
+
+		// Defer write operations for review when the server or the call asks for it
+		args, plan := splitReview(args, cfg)
+		if plan != nil {
+			callCfg = callCfg.ForReview(plan)
+		}
+
+		var result string
+		if contextHandler, ok := handler.(ContextResourceHandler); ok {
+			result, err = contextHandler.HandleContext(withProgressToken(ctx, req), args, callCfg)
+		} else {
+			result, err = handler.Handle(args, callCfg)
+		}
 
 		// Track tool invocation with minimal data
 		if cfg.TelemetryService != nil {
@@ -95,10 +337,16 @@ func CreateResourceHandler(handler ResourceHandler, cfg *config.ConfigData) func
 			logToolResult(req.Params.Name, result, err)
 		}
 
+		// Deferred writes replace the result, including the error that stopped the call at the first of them
+		if reviewed := reviewResult(req.Params.Name, plan); reviewed != nil {
+			return withExplanation(reviewed, trace), nil
+		}
+
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			// Append known causes and next steps for common ARM and az CLI error codes
+			return withExplanation(mcp.NewToolResultError(errorkb.Enrich(err.Error())), trace), nil
 		}
 
-		return mcp.NewToolResultText(result), nil
+		return withExplanation(shapeResult(ctx, req.Params.Name, result, callCfg), trace), nil
 	}
 }
@@ -1,9 +1,15 @@
 package tools
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/Azure/aks-mcp/internal/artifacts"
 	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/Azure/aks-mcp/internal/explain"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestResourceHandlerInterface(t *testing.T) {
@@ -43,3 +49,253 @@ func TestCommandExecutorStillWorks(t *testing.T) {
 		t.Errorf("Expected 'command result', got: %s", result)
 	}
 }
+
+func TestCreateResourceHandlerEnrichesKnownErrors(t *testing.T) {
+	handler := ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		return "", errors.New("(SkuNotAvailable) The requested VM size is not available in location 'eastus'")
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "az_aks_operations"
+	req.Params.Arguments = map[string]interface{}{}
+	result, err := CreateResourceHandler(handler, config.NewConfig())(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.IsError || len(result.Content) == 0 {
+		t.Fatalf("Expected an error result, got: %+v", result)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "eastus") || !strings.Contains(text, "az vm list-skus") {
+		t.Errorf("Expected the original error followed by next steps, got: %s", text)
+	}
+}
+
+func TestCreateResourceHandlerPassesContext(t *testing.T) {
+	var gotToken interface{}
+	handler := ContextResourceHandlerFunc(func(ctx context.Context, params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		gotToken = ctx.Value(progressTokenKey{})
+		// Without an MCP server in the context there is no client to notify
+		return "watched", NotifyProgress(ctx, 1, 0, "event")
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "aks_watch_events"
+	req.Params.Arguments = map[string]interface{}{}
+	req.Params.Meta = &mcp.Meta{ProgressToken: "token-1"}
+	result, err := CreateResourceHandler(handler, config.NewConfig())(context.Background(), req)
+	if err != nil || result.IsError {
+		t.Fatalf("Expected a successful result, got %+v (%v)", result, err)
+	}
+	if gotToken != "token-1" {
+		t.Errorf("Expected the progress token in the handler context, got %v", gotToken)
+	}
+}
+
+func TestCreateResourceHandlerExplain(t *testing.T) {
+	var gotParams map[string]interface{}
+	handler := ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		gotParams = params
+		cfg.Explain.Record(explain.KindAz, "az aks show --name aks --resource-group rg", nil)
+		return "shown", nil
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]interface{}{"operation": "show", ExplainParam: true}
+	result, err := CreateResourceHandler(handler, config.NewConfig())(context.Background(), req)
+	if err != nil || result.IsError {
+		t.Fatalf("Expected a successful result, got %+v (%v)", result, err)
+	}
+	if _, ok := gotParams[ExplainParam]; ok {
+		t.Error("Expected the explain argument to be removed before the handler runs")
+	}
+	if len(result.Content) != 2 {
+		t.Fatalf("Expected the result and an explanation, got %+v", result.Content)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != "shown" {
+		t.Errorf("Expected the result to be unchanged, got %q", text)
+	}
+	explanation := result.Content[1].(mcp.TextContent).Text
+	if !strings.Contains(explanation, "1. [az] az aks show --name aks --resource-group rg") || !strings.Contains(explanation, "Docs: https://") {
+		t.Errorf("Unexpected explanation %q", explanation)
+	}
+
+	// Without explain the result has a single content
+	req.Params.Arguments = map[string]interface{}{"operation": "show"}
+	result, _ = CreateResourceHandler(handler, config.NewConfig())(context.Background(), req)
+	if len(result.Content) != 1 {
+		t.Errorf("Expected no explanation, got %+v", result.Content)
+	}
+}
+
+func TestCreateResourceHandlerAttachesLargeOutput(t *testing.T) {
+	output := `{"detectors":"` + strings.Repeat("é", 5000) + `"}`
+	handler := ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		return output, nil
+	})
+	cfg := config.NewConfig()
+	cfg.Artifacts = artifacts.NewStore(0)
+	cfg.ArtifactThreshold = 8192
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "run_detectors"
+	req.Params.Arguments = map[string]interface{}{}
+	result, err := CreateResourceHandler(handler, cfg)(context.Background(), req)
+	if err != nil || result.IsError || len(result.Content) != 2 {
+		t.Fatalf("Expected a preview and a resource link, got %+v (%v)", result, err)
+	}
+	preview := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(preview, `{"detectors":"é`) || !strings.Contains(preview, "Output truncated") || len(preview) > artifactPreviewBytes+200 {
+		t.Errorf("Unexpected preview of %d bytes: %.100s", len(preview), preview)
+	}
+	link := result.Content[1].(mcp.ResourceLink)
+	if !strings.HasPrefix(link.URI, artifacts.URIPrefix) || link.MIMEType != "application/json" {
+		t.Fatalf("Unexpected resource link %+v", link)
+	}
+	artifact, ok := cfg.Artifacts.Get(link.URI, "")
+	if content, err := artifact.Read(); !ok || err != nil || content != output {
+		t.Error("Expected the full output in the artifact store")
+	}
+
+	// Small outputs and a disabled threshold return the output inline
+	cfg.ArtifactThreshold = 0
+	result, _ = CreateResourceHandler(handler, cfg)(context.Background(), req)
+	if len(result.Content) != 1 || result.Content[0].(mcp.TextContent).Text != output {
+		t.Errorf("Expected the output inline when artifacts are disabled, got %+v", result.Content)
+	}
+}
+
+func TestWithExplain(t *testing.T) {
+	tool := WithExplain(mcp.NewTool("aks_test"))
+	if _, ok := tool.InputSchema.Properties[ExplainParam]; !ok {
+		t.Errorf("Expected the explain argument in the schema, got %+v", tool.InputSchema.Properties)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.ToolTimeouts = map[string]int{"kubectl_*": 15, "az_aks_operations": 2400, "az_aks_operations:show": 20}
+	cfg.MaxTimeout = 1800
+
+	var gotTimeout int
+	var gotParams map[string]interface{}
+	handler := CreateResourceHandler(ResourceHandlerFunc(func(params map[string]interface{}, callCfg *config.ConfigData) (string, error) {
+		gotTimeout, gotParams = callCfg.Timeout, params
+		return "done", nil
+	}), cfg)
+
+	tool, wrapped := WithTimeout(mcp.NewTool("az_aks_operations", mcp.WithString("operation")), handler, cfg)
+	property, ok := tool.InputSchema.Properties[TimeoutParam].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected the timeout argument in the schema, got %+v", tool.InputSchema.Properties)
+	}
+	if description, _ := property["description"].(string); !strings.Contains(description, "defaults to 2400s; show 20s") {
+		t.Errorf("Expected the operation timeouts in the description, got %q", description)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want int
+	}{
+		{"tool class timeout", map[string]interface{}{"operation": "upgrade"}, 2400},
+		{"operation timeout", map[string]interface{}{"operation": "show"}, 20},
+		{"per call timeout", map[string]interface{}{"operation": "show", TimeoutParam: float64(30)}, 30},
+		{"per call timeout clamped", map[string]interface{}{"operation": "upgrade", TimeoutParam: "7200"}, 1800},
+	}
+	for _, tt := range tests {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "az_aks_operations"
+		req.Params.Arguments = tt.args
+		if result, err := wrapped(context.Background(), req); err != nil || result.IsError {
+			t.Fatalf("%s: expected a successful result, got %+v (%v)", tt.name, result, err)
+		}
+		if gotTimeout != tt.want {
+			t.Errorf("%s: expected timeout %d, got %d", tt.name, tt.want, gotTimeout)
+		}
+		if _, ok := gotParams[TimeoutParam]; ok {
+			t.Errorf("%s: expected the timeout argument to be removed before the handler runs", tt.name)
+		}
+	}
+	if cfg.Timeout != 60 {
+		t.Errorf("Expected the server configuration to be unchanged, got timeout %d", cfg.Timeout)
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]interface{}{TimeoutParam: "-5"}
+	if result, _ := wrapped(context.Background(), req); !result.IsError {
+		t.Error("Expected a negative timeout to be rejected")
+	}
+
+	// A tool with its own timeout_seconds argument keeps it and gets the configured timeout
+	drainTool, drainHandler := WithTimeout(mcp.NewTool("kubectl_drain", mcp.WithString(TimeoutParam)), handler, cfg)
+	req.Params.Arguments = map[string]interface{}{TimeoutParam: "300"}
+	if _, err := drainHandler(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if gotTimeout != 15 || gotParams[TimeoutParam] != "300" || drainTool.InputSchema.Properties[TimeoutParam].(map[string]interface{})["type"] != "string" {
+		t.Errorf("Expected the tool's own timeout argument to pass through with the class timeout, got %d and %+v", gotTimeout, gotParams)
+	}
+}
+
+func TestCreateResourceHandlerVerbosity(t *testing.T) {
+	output := `{"clusters":[{"name":"a"},{"name":"b"}],"counts":{"failed":1,"running":4},"note":"` + strings.Repeat("x", 300) + `","region":"eastus"}`
+	var gotVerbosity string
+	var gotParams map[string]interface{}
+	handler := ResourceHandlerFunc(func(params map[string]interface{}, cfg *config.ConfigData) (string, error) {
+		gotVerbosity, gotParams = cfg.Verbosity, params
+		return output, nil
+	})
+	cfg := config.NewConfig()
+	cfg.Artifacts = artifacts.NewStore(0)
+	cfg.ArtifactThreshold = 64
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "aks_estate_overview"
+	req.Params.Arguments = map[string]interface{}{VerbosityParam: config.VerbositySummary}
+	result, err := CreateResourceHandler(handler, cfg)(context.Background(), req)
+	if err != nil || result.IsError || len(result.Content) != 2 {
+		t.Fatalf("Expected a summary and a resource link, got %+v (%v)", result, err)
+	}
+	if _, ok := gotParams[VerbosityParam]; ok || gotVerbosity != config.VerbositySummary {
+		t.Errorf("Expected the verbosity argument to move to the call configuration, got %q and %+v", gotVerbosity, gotParams)
+	}
+	summary := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(summary, "The tool returned 2 clusters; counts (failed 1, running 4); region eastus.") || !strings.Contains(summary, artifacts.URIPrefix) {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+
+	// Raw output is returned whole even above the artifact threshold
+	req.Params.Arguments = map[string]interface{}{VerbosityParam: config.VerbosityRaw}
+	result, _ = CreateResourceHandler(handler, cfg)(context.Background(), req)
+	if len(result.Content) != 1 || result.Content[0].(mcp.TextContent).Text != output {
+		t.Errorf("Expected the whole output with raw verbosity, got %+v", result.Content)
+	}
+
+	req.Params.Arguments = map[string]interface{}{VerbosityParam: "verbose"}
+	if result, _ := CreateResourceHandler(handler, cfg)(context.Background(), req); !result.IsError {
+		t.Error("Expected an unknown verbosity to be rejected")
+	}
+	if cfg.Verbosity != config.VerbosityStandard {
+		t.Errorf("Expected the server configuration to be unchanged, got verbosity %q", cfg.Verbosity)
+	}
+}
+
+func TestSummarizeOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"summary field", `{"summary":"All 3 clusters are healthy.","clusters":[]}`, "All 3 clusters are healthy."},
+		{"headline field", `{"headline":"2 changes in the last hour.","changes":[{},{}]}`, "2 changes in the last hour."},
+		{"list", `[1,2,3]`, "The tool returned 3 items."},
+		{"text", "line one\n\nline two\nline three\nline four", "line one line two line three (5 lines in total)"},
+		{"empty", "  ", "The tool returned no output."},
+	}
+	for _, tt := range tests {
+		if got := SummarizeOutput(tt.output); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressTokenKey is the context key of the progress token sent with a tool call
+type progressTokenKey struct{}
+
+// withProgressToken stores the progress token of the request, if any, in the context
+func withProgressToken(ctx context.Context, req mcp.CallToolRequest) context.Context {
+	if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTokenKey{}, req.Params.Meta.ProgressToken)
+}
+
+// NotifyProgress sends a progress notification for the current tool call to the client.
+// progress must increase with every call; a total of 0 means the total is unknown.
+// Nothing is sent when the client did not include a progress token in the request.
+func NotifyProgress(ctx context.Context, progress, total float64, message string) error {
+	token := ctx.Value(progressTokenKey{})
+	mcpServer := server.ServerFromContext(ctx)
+	if token == nil || mcpServer == nil {
+		return nil
+	}
+	params := map[string]any{
+		"progressToken": token,
+		"progress":      progress,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	return mcpServer.SendNotificationToClient(ctx, "notifications/progress", params)
+}
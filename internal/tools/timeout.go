@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TimeoutParam is the tool argument that sets the timeout of a single call
+const TimeoutParam = "timeout_seconds"
+
+// callTimeoutKey is the context key of the timeout in seconds of the current tool call
+type callTimeoutKey struct{}
+
+// WithTimeout applies the configured timeout of the tool class, or of the call's operation argument, to every
+// call and adds the timeout_seconds argument to the tool's input schema. A timeout_seconds value is clamped
+// to the configured maximum.
+// Tools that already take timeout_seconds for their own purpose (such as a per-node drain timeout)
+// keep it and get the configured timeout only.
+func WithTimeout(tool mcp.Tool, handler server.ToolHandlerFunc, cfg *config.ConfigData) (mcp.Tool, server.ToolHandlerFunc) {
+	_, ownsParam := tool.InputSchema.Properties[TimeoutParam]
+	if !ownsParam && tool.RawInputSchema == nil {
+		properties := make(map[string]interface{}, len(tool.InputSchema.Properties)+1)
+		for name, property := range tool.InputSchema.Properties {
+			properties[name] = property
+		}
+		properties[TimeoutParam] = map[string]interface{}{
+			"type":        "number",
+			"description": fmt.Sprintf("Timeout of this call in seconds (defaults to %ds%s, at most %ds)", cfg.ToolTimeout(tool.Name, ""), operationTimeouts(tool.Name, cfg), cfg.MaxTimeout),
+		}
+		tool.InputSchema.Properties = properties
+	}
+
+	return tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, _ := req.Params.Arguments.(map[string]interface{})
+		operation, _ := args["operation"].(string)
+		seconds := cfg.ToolTimeout(tool.Name, operation)
+		if args != nil && !ownsParam {
+			if value, ok := args[TimeoutParam]; ok {
+				requested, err := parseTimeout(value)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				seconds = cfg.ClampTimeout(requested)
+
+				rest := make(map[string]interface{}, len(args)-1)
+				for k, v := range args {
+					if k != TimeoutParam {
+						rest[k] = v
+					}
+				}
+				req.Params.Arguments = rest
+			}
+		}
+		return handler(context.WithValue(ctx, callTimeoutKey{}, seconds), req)
+	}
+}
+
+// CallTimeout returns the timeout in seconds of the current tool call, or the configured timeout
+// when the call was not made through WithTimeout
+func CallTimeout(ctx context.Context, cfg *config.ConfigData) int {
+	if seconds, ok := ctx.Value(callTimeoutKey{}).(int); ok {
+		return seconds
+	}
+	return cfg.Timeout
+}
+
+// operationTimeouts describes the configured timeouts of single operations of a tool, such as "; upgrade 2400s"
+func operationTimeouts(tool string, cfg *config.ConfigData) string {
+	var entries []string
+	for key, seconds := range cfg.ToolTimeouts {
+		if operation, ok := strings.CutPrefix(key, tool+":"); ok {
+			entries = append(entries, fmt.Sprintf("%s %ds", operation, seconds))
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Strings(entries)
+	return "; " + strings.Join(entries, ", ")
+}
+
+// parseTimeout reads a timeout_seconds argument sent as a number or a string
+func parseTimeout(value interface{}) (int, error) {
+	var seconds int
+	switch v := value.(type) {
+	case float64:
+		seconds = int(v)
+	case int:
+		seconds = v
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s '%s': expected a number of seconds", TimeoutParam, v)
+		}
+		seconds = n
+	default:
+		return 0, fmt.Errorf("invalid %s: expected a number of seconds", TimeoutParam)
+	}
+	if seconds <= 0 {
+		return 0, fmt.Errorf("invalid %s %d: expected a positive number of seconds", TimeoutParam, seconds)
+	}
+	return seconds, nil
+}
@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Azure/aks-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// VerbosityParam is the tool argument that selects the result verbosity profile of a single call
+const VerbosityParam = "verbosity"
+
+const (
+	// maxSampledBytes bounds the tool output sent to the client for a sampled summary
+	maxSampledBytes = 32 * 1024
+	// summaryMaxTokens bounds the length of a sampled summary
+	summaryMaxTokens = 300
+	// maxSummaryFields bounds the top-level fields described by a built-in summary
+	maxSummaryFields = 8
+	// maxSummaryLines bounds the lines of text output kept by a built-in summary
+	maxSummaryLines = 3
+)
+
+// summarySystemPrompt asks the client's model for a summary a non-specialist can act on
+const summarySystemPrompt = "You summarize the output of Azure Kubernetes Service diagnostic tools for engineering managers. " +
+	"Reply with one short paragraph of plain text: the overall state, the most important problems with the affected resources, " +
+	"and what should happen next. Do not invent facts that are not in the output."
+
+// WithVerbosity adds the verbosity argument to a tool's input schema
+func WithVerbosity(tool mcp.Tool) mcp.Tool {
+	if tool.RawInputSchema != nil {
+		return tool
+	}
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = map[string]interface{}{}
+	}
+	tool.InputSchema.Properties[VerbosityParam] = map[string]interface{}{
+		"type": "string",
+		"enum": config.Verbosities,
+		"description": "Result depth: raw returns the data as collected, standard the default result and summary a short paragraph " +
+			"with the full result linked as a resource when it is available",
+	}
+	return tool
+}
+
+// splitVerbosity removes the verbosity argument and returns the profile it selects (empty when it was not set)
+func splitVerbosity(args map[string]interface{}) (map[string]interface{}, string, error) {
+	value, ok := args[VerbosityParam]
+	if !ok {
+		return args, "", nil
+	}
+	verbosity, _ := value.(string)
+	if !slices.Contains(config.Verbosities, verbosity) {
+		return nil, "", fmt.Errorf("invalid %s %v: expected %s", VerbosityParam, value, strings.Join(config.Verbosities, ", "))
+	}
+	rest := make(map[string]interface{}, len(args)-1)
+	for k, v := range args {
+		if k != VerbosityParam {
+			rest[k] = v
+		}
+	}
+	return rest, verbosity, nil
+}
+
+// shapeResult returns a tool output shaped by the call's verbosity profile. Raw output is returned whole,
+// standard output goes through the artifact preview and summary output is reduced to a paragraph with the
+// full output kept as an artifact.
+func shapeResult(ctx context.Context, toolName, result string, cfg *config.ConfigData) *mcp.CallToolResult {
+	switch cfg.Verbosity {
+	case config.VerbosityRaw:
+		return mcp.NewToolResultText(result)
+	case config.VerbositySummary:
+		return summaryResult(ctx, toolName, result, cfg)
+	default:
+		return textResult(toolName, result, cfg)
+	}
+}
+
+// summaryResult summarizes a tool output, through the client's model when sampling summaries are enabled
+func summaryResult(ctx context.Context, toolName, result string, cfg *config.ConfigData) *mcp.CallToolResult {
+	summary := ""
+	if cfg.SamplingSummaries {
+		summary = sampleSummary(ctx, toolName, result)
+	}
+	if summary == "" {
+		summary = SummarizeOutput(result)
+	}
+
+	mimeType := "text/plain"
+	if json.Valid([]byte(result)) {
+		mimeType = "application/json"
+	}
+	artifact, ok := cfg.AttachArtifact(toolName+" output", mimeType, result)
+	if !ok {
+		return mcp.NewToolResultText(fmt.Sprintf("%s\n\n[Summary of %d bytes of output. Call again with verbosity standard for the details.]", summary, len(result)))
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf("%s\n\n[Summary of %d bytes of output. The full output is available as %s.]", summary, len(result), artifactLocation(artifact))),
+			artifactLink(toolName, artifact),
+		},
+	}
+}
+
+// sampleSummary asks the client's model to summarize a tool output. It returns an empty string when the
+// client does not support sampling or the request fails, so the caller can fall back to a built-in summary.
+func sampleSummary(ctx context.Context, toolName, result string) string {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return ""
+	}
+	output := result
+	if len(output) > maxSampledBytes {
+		cut := maxSampledBytes
+		for cut > 0 && !utf8.RuneStart(output[cut]) {
+			cut--
+		}
+		output = output[:cut] + "\n[output truncated]"
+	}
+	response, err := srv.RequestSampling(ctx, mcp.CreateMessageRequest{
+		CreateMessageParams: mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{{
+				Role:    mcp.RoleUser,
+				Content: mcp.NewTextContent(fmt.Sprintf("Summarize this output of the %s tool:\n\n%s", toolName, output)),
+			}},
+			SystemPrompt: summarySystemPrompt,
+			MaxTokens:    summaryMaxTokens,
+		},
+	})
+	if err != nil || response == nil {
+		return ""
+	}
+	switch content := response.Content.(type) {
+	case mcp.TextContent:
+		return strings.TrimSpace(content.Text)
+	case *mcp.TextContent:
+		return strings.TrimSpace(content.Text)
+	case map[string]interface{}:
+		text, _ := content["text"].(string)
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
+
+// SummarizeOutput describes a tool output in a few sentences without a model. A JSON object is described
+// by its summary or headline field when it has one, otherwise by its top-level fields; text is reduced to
+// its first lines.
+func SummarizeOutput(result string) string {
+	trimmed := strings.TrimSpace(result)
+	if trimmed == "" {
+		return "The tool returned no output."
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+		lines := strings.Split(trimmed, "\n")
+		var kept []string
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" && len(kept) < maxSummaryLines {
+				kept = append(kept, line)
+			}
+		}
+		summary := strings.Join(kept, " ")
+		if len(lines) > len(kept) {
+			summary += fmt.Sprintf(" (%d lines in total)", len(lines))
+		}
+		return summary
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		return fmt.Sprintf("The tool returned %d items.", len(v))
+	case map[string]interface{}:
+		for _, key := range []string{"summary", "headline"} {
+			if text, ok := v[key].(string); ok && text != "" {
+				return text
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var parts []string
+		for _, key := range keys {
+			if part := describeField(key, v[key]); part != "" {
+				parts = append(parts, part)
+			}
+			if len(parts) == maxSummaryFields {
+				break
+			}
+		}
+		if len(parts) == 0 {
+			return "The tool returned an empty result."
+		}
+		return "The tool returned " + strings.Join(parts, "; ") + "."
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// describeField describes one top-level field of a JSON result, or returns an empty string for fields
+// that are too large to describe in a sentence
+func describeField(key string, value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		return fmt.Sprintf("%d %s", len(v), key)
+	case string:
+		if v == "" || len(v) > 120 {
+			return ""
+		}
+		return key + " " + v
+	case float64, bool:
+		return fmt.Sprintf("%s %v", key, v)
+	case map[string]interface{}:
+		// Flat maps of counts, such as findings by severity, are listed in full
+		keys := make([]string, 0, len(v))
+		for k, count := range v {
+			if _, ok := count.(float64); !ok {
+				return ""
+			}
+			keys = append(keys, k)
+		}
+		if len(keys) == 0 {
+			return ""
+		}
+		sort.Strings(keys)
+		counts := make([]string, len(keys))
+		for i, k := range keys {
+			counts[i] = fmt.Sprintf("%s %v", k, v[k])
+		}
+		return key + " (" + strings.Join(counts, ", ") + ")"
+	}
+	return ""
+}
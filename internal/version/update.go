@@ -0,0 +1,204 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatestReleaseURL is the GitHub API endpoint of the latest aks-mcp release
+const LatestReleaseURL = "https://api.github.com/repos/Azure/aks-mcp/releases/latest"
+
+// UpdateCheckInterval is how often GitHub is asked for the latest release. Checks in between, including
+// after restarts, return the cached result, which keeps well within the unauthenticated GitHub API rate limit.
+const UpdateCheckInterval = 24 * time.Hour
+
+// maxReleaseBytes bounds the release document read from GitHub
+const maxReleaseBytes = 1 << 20
+
+// UpdateStatus is the result of an update check
+type UpdateStatus struct {
+	// Current is the running version
+	Current string `json:"current"`
+	// Latest is the tag of the latest release, empty when the last check failed
+	Latest string `json:"latest,omitempty"`
+	// URL is the release page of the latest release
+	URL       string    `json:"url,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Available reports whether the latest release is newer than the running version
+func (u UpdateStatus) Available() bool {
+	return u.Latest != "" && CompareVersions(u.Latest, u.Current) > 0
+}
+
+// UpdateCache keeps the last update check across restarts
+type UpdateCache interface {
+	Load() (UpdateStatus, error)
+	Save(status UpdateStatus) error
+}
+
+// UpdateChecker asks GitHub for the latest release at most once per UpdateCheckInterval
+type UpdateChecker struct {
+	URL    string
+	Client *http.Client
+	// Cache is optional; without it every check asks GitHub
+	Cache UpdateCache
+	now   func() time.Time
+}
+
+// NewUpdateChecker creates an update checker for the aks-mcp releases on GitHub
+func NewUpdateChecker(cache UpdateCache) *UpdateChecker {
+	return &UpdateChecker{
+		URL:    LatestReleaseURL,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Cache:  cache,
+		now:    time.Now,
+	}
+}
+
+// Check compares the running version with the latest release. Builds whose version is not a release, such as
+// dev, are not checked and return nil. A failed check is cached too, so an unreachable GitHub is only tried
+// once per interval.
+func (c *UpdateChecker) Check(ctx context.Context) (*UpdateStatus, error) {
+	current := GitVersion
+	if _, _, ok := parseVersion(current); !ok {
+		return nil, nil
+	}
+	now := c.now()
+	if c.Cache != nil {
+		if cached, err := c.Cache.Load(); err == nil && !now.Before(cached.CheckedAt) && now.Sub(cached.CheckedAt) < UpdateCheckInterval {
+			cached.Current = current
+			return &cached, nil
+		}
+	}
+
+	status := UpdateStatus{Current: current, CheckedAt: now}
+	latest, url, err := c.latestRelease(ctx)
+	status.Latest, status.URL = latest, url
+	if c.Cache != nil {
+		// A cache that can't be written only means GitHub is asked again on the next start
+		_ = c.Cache.Save(status)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// latestRelease returns the tag and page of the latest release
+func (c *UpdateChecker) latestRelease(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "aks-mcp/"+GitVersion)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to check for updates: GitHub returned status %d", resp.StatusCode)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReleaseBytes)).Decode(&release); err != nil {
+		return "", "", fmt.Errorf("failed to read the latest release: %w", err)
+	}
+	if _, _, ok := parseVersion(release.TagName); !ok {
+		return "", "", fmt.Errorf("the latest release has an unexpected tag %q", release.TagName)
+	}
+	return release.TagName, release.HTMLURL, nil
+}
+
+// CompareVersions compares two release versions such as v1.2.3 or 1.3.0-rc.1, returning -1, 0 or 1.
+// A pre-release sorts before its release, and pre-releases are ordered as in Semantic Versioning §11.
+// Versions that can't be parsed compare as equal.
+func CompareVersions(a, b string) int {
+	coreA, preA, okA := parseVersion(a)
+	coreB, preB, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0
+	}
+	for i := range coreA {
+		if coreA[i] != coreB[i] {
+			if coreA[i] < coreB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return comparePrerelease(preA, preB)
+}
+
+// comparePrerelease compares two pre-releases such as rc.2 and rc.10 identifier by identifier. Numeric
+// identifiers compare numerically and sort before alphanumeric ones, and a pre-release with fewer
+// identifiers sorts first when the others are equal.
+func comparePrerelease(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		numA, errA := strconv.ParseUint(idsA[i], 10, 64)
+		numB, errB := strconv.ParseUint(idsB[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(idsA[i], idsB[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(idsA) < len(idsB):
+		return -1
+	case len(idsA) > len(idsB):
+		return 1
+	}
+	return 0
+}
+
+// parseVersion splits a version into its major, minor and patch numbers and its pre-release, ignoring
+// a leading v and build metadata
+func parseVersion(version string) ([3]int, string, bool) {
+	var core [3]int
+	version, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), "+")
+	version, pre, _ := strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return core, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return core, "", false
+		}
+		core[i] = n
+	}
+	return core, pre, true
+}
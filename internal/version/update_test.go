@@ -0,0 +1,112 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// memoryCache keeps one update status
+type memoryCache struct {
+	status *UpdateStatus
+}
+
+func (c *memoryCache) Load() (UpdateStatus, error) {
+	if c.status == nil {
+		return UpdateStatus{}, fmt.Errorf("not found")
+	}
+	return *c.status, nil
+}
+
+func (c *memoryCache) Save(status UpdateStatus) error {
+	c.status = &status
+	return nil
+}
+
+func withGitVersion(t *testing.T, v string) {
+	t.Helper()
+	previous := GitVersion
+	GitVersion = v
+	t.Cleanup(func() { GitVersion = previous })
+}
+
+// TestUpdateCheckerCheck tests comparing with the latest release and caching checks for the interval
+func TestUpdateCheckerCheck(t *testing.T) {
+	withGitVersion(t, "v0.0.9")
+	requests := 0
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("User-Agent") != "aks-mcp/v0.0.9" {
+			t.Errorf("Unexpected User-Agent %q", r.Header.Get("User-Agent"))
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"tag_name": "v0.0.10", "html_url": "https://github.com/Azure/aks-mcp/releases/tag/v0.0.10"}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	cache := &memoryCache{}
+	checker := NewUpdateChecker(cache)
+	checker.URL = server.URL
+	checker.now = func() time.Time { return now }
+
+	result, err := checker.Check(context.Background())
+	if err != nil || result == nil || !result.Available() || result.Latest != "v0.0.10" || result.URL == "" {
+		t.Fatalf("Expected v0.0.10 to be available, got %+v (%v)", result, err)
+	}
+
+	// Within the interval the cached result is returned without asking GitHub
+	now = now.Add(time.Hour)
+	if result, err := checker.Check(context.Background()); err != nil || !result.Available() || requests != 1 {
+		t.Errorf("Expected the cached result, got %+v (%v) after %d requests", result, err, requests)
+	}
+
+	// A failed check is cached too, so GitHub is not asked again until the interval passes
+	now = now.Add(UpdateCheckInterval)
+	status = http.StatusForbidden
+	if _, err := checker.Check(context.Background()); err == nil || requests != 2 {
+		t.Errorf("Expected the rate limited check to fail, got %v after %d requests", err, requests)
+	}
+	if result, err := checker.Check(context.Background()); err != nil || result.Available() || requests != 2 {
+		t.Errorf("Expected the cached failed check, got %+v (%v) after %d requests", result, err, requests)
+	}
+
+	// Development builds are not checked
+	withGitVersion(t, "dev")
+	if result, err := checker.Check(context.Background()); result != nil || err != nil {
+		t.Errorf("Expected no check for a dev build, got %+v (%v)", result, err)
+	}
+}
+
+// TestCompareVersions tests release ordering, including pre-releases and build metadata
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v0.0.10", "v0.0.9", 1},
+		{"v1.2.0", "1.2.0", 0},
+		{"v1.2", "v1.2.1", -1},
+		{"v1.3.0-rc.1", "v1.3.0", -1},
+		{"v1.3.0", "v1.3.0-rc.1", 1},
+		{"v1.3.0-rc.2", "v1.3.0-rc.1", 1},
+		{"v1.3.0-rc.10", "v1.3.0-rc.2", 1},
+		{"v1.0.0", "v1.0.0-rc.1", 1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-alpha.beta", "v1.0.0-beta", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.11", -1},
+		{"v1.0.0-rc.1", "v1.0.0-rc.1", 0},
+		{"v1.3.0+build.5", "v1.3.0", 0},
+		{"dev", "v1.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}